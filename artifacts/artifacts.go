@@ -0,0 +1,91 @@
+// Package artifacts provides the store for the binary tool outputs,
+// such as generated files, with the local disk and S3 backends.
+// The tools persist the files and return the artifact IDs or signed URLs to the LLM,
+// the artifacts are also available to MCP clients as artifact://<id> resources.
+package artifacts
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/xlog"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "artifacts")
+
+// ErrNotFound is returned when the artifact is not found.
+var ErrNotFound = errors.New("artifact not found")
+
+// URIScheme is the scheme of the artifact URI.
+const URIScheme = "artifact://"
+
+// DefaultURLExpiry is the default expiry of the signed URL.
+const DefaultURLExpiry = time.Hour
+
+// Artifact describes the stored file.
+type Artifact struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// URI returns the artifact URI, to be used with MCP resources.
+func (a *Artifact) URI() string {
+	return URIScheme + a.ID
+}
+
+// Store is an interface for storing the artifacts.
+// The supplied context must have ChatContext with tenantID,
+// the artifacts are scoped to the tenant.
+type Store interface {
+	// Put stores the content and returns the artifact.
+	Put(ctx context.Context, name, contentType string, content io.Reader) (*Artifact, error)
+	// Get returns the artifact and its content by ID, or ErrNotFound.
+	// The caller must close the content.
+	Get(ctx context.Context, id string) (*Artifact, io.ReadCloser, error)
+	// List returns the artifacts of the tenant.
+	List(ctx context.Context) ([]*Artifact, error)
+	// SignedURL returns the URL to download the artifact without credentials,
+	// valid for the expires duration.
+	SignedURL(ctx context.Context, id string, expires time.Duration) (string, error)
+}
+
+// ParseURI returns the artifact ID from the artifact URI.
+func ParseURI(uri string) (string, bool) {
+	id, ok := strings.CutPrefix(uri, URIScheme)
+	if !ok || !isValidID(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// NewID returns a new artifact ID.
+func NewID() string {
+	return "artifact_" + chatmodel.NewChatID()
+}
+
+var reID = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+func isValidID(id string) bool {
+	return reID.MatchString(id)
+}
+
+// tenantID returns the tenant ID from the context, escaped to be used in a path
+func tenantID(ctx context.Context) (string, error) {
+	tenantID, _, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if tenantID == "" || tenantID == "." || tenantID == ".." {
+		return "", errors.Newf("invalid tenant ID: %q", tenantID)
+	}
+	return url.PathEscape(tenantID), nil
+}
@@ -0,0 +1,247 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/xlog"
+)
+
+// DiskStore is the Store on the local disk,
+// the artifacts are stored in <dir>/<tenant>/<id> with <id>.json metadata.
+// The signed URLs are served by DiskStore.Handler.
+type DiskStore struct {
+	dir     string
+	baseURL string
+	secret  []byte
+	now     func() time.Time
+}
+
+var _ Store = (*DiskStore)(nil)
+
+// NewDiskStore returns a new DiskStore in the dir.
+// The baseURL is the public URL where DiskStore.Handler is mounted,
+// and the secret is the key to sign the URLs.
+func NewDiskStore(dir, baseURL string, secret []byte) (*DiskStore, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret is required to sign the URLs")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create artifacts folder")
+	}
+	return &DiskStore{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  secret,
+		now:     time.Now,
+	}, nil
+}
+
+func (s *DiskStore) Put(ctx context.Context, name, contentType string, content io.Reader) (*Artifact, error) {
+	tenant, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	folder := filepath.Join(s.dir, tenant)
+	if err = os.MkdirAll(folder, 0o700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create artifacts folder")
+	}
+
+	a := &Artifact{
+		ID:          NewID(),
+		Name:        name,
+		ContentType: contentType,
+		CreatedAt:   s.now().UTC(),
+	}
+
+	tmp, err := os.CreateTemp(folder, a.ID+".tmp*")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create artifact")
+	}
+	defer os.Remove(tmp.Name())
+
+	a.Size, err = io.Copy(tmp, content)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to write artifact")
+	}
+
+	meta, err := json.Marshal(a)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = os.WriteFile(filepath.Join(folder, a.ID+".json"), meta, 0o600); err != nil {
+		return nil, errors.Wrapf(err, "failed to write artifact metadata")
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(folder, a.ID)); err != nil {
+		return nil, errors.Wrapf(err, "failed to write artifact")
+	}
+	return a, nil
+}
+
+func (s *DiskStore) Get(ctx context.Context, id string) (*Artifact, io.ReadCloser, error) {
+	tenant, err := tenantID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.open(tenant, id)
+}
+
+func (s *DiskStore) open(tenant, id string) (*Artifact, io.ReadCloser, error) {
+	a, err := s.load(tenant, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(filepath.Join(s.dir, tenant, id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, errors.Wrapf(ErrNotFound, "%s", id)
+		}
+		return nil, nil, errors.Wrapf(err, "failed to open artifact")
+	}
+	return a, f, nil
+}
+
+func (s *DiskStore) load(tenant, id string) (*Artifact, error) {
+	if !isValidID(id) {
+		return nil, errors.Wrapf(ErrNotFound, "%s", id)
+	}
+	meta, err := os.ReadFile(filepath.Join(s.dir, tenant, id+".json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errors.Wrapf(ErrNotFound, "%s", id)
+		}
+		return nil, errors.Wrapf(err, "failed to read artifact metadata")
+	}
+	a := new(Artifact)
+	if err = json.Unmarshal(meta, a); err != nil {
+		return nil, errors.Wrapf(err, "failed to read artifact metadata")
+	}
+	return a, nil
+}
+
+func (s *DiskStore) List(ctx context.Context) ([]*Artifact, error) {
+	tenant, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(s.dir, tenant))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to list artifacts")
+	}
+
+	var list []*Artifact
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		a, err := s.load(tenant, id)
+		if err != nil {
+			logger.ContextKV(ctx, xlog.WARNING, "reason", "load", "id", id, "err", err.Error())
+			continue
+		}
+		list = append(list, a)
+	}
+	slices.SortFunc(list, func(a, b *Artifact) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return list, nil
+}
+
+func (s *DiskStore) SignedURL(ctx context.Context, id string, expires time.Duration) (string, error) {
+	tenant, err := tenantID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if _, err = s.load(tenant, id); err != nil {
+		return "", err
+	}
+	if expires <= 0 {
+		expires = DefaultURLExpiry
+	}
+	exp := strconv.FormatInt(s.now().Add(expires).Unix(), 10)
+
+	q := url.Values{}
+	q.Set("expires", exp)
+	q.Set("signature", s.sign(tenant, id, exp))
+	return s.baseURL + "/" + tenant + "/" + id + "?" + q.Encode(), nil
+}
+
+// sign returns HMAC of the tenant, id and expiry
+func (s *DiskStore) sign(tenant, id, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	_, _ = mac.Write([]byte(tenant + "/" + id + "/" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Handler returns the http.Handler to download the artifacts by the signed URLs,
+// it must be mounted at the path of the baseURL, for example:
+//
+//	mux.Handle("/artifacts/", http.StripPrefix("/artifacts", store.Handler()))
+func (s *DiskStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		tenant, id, ok := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+		if !ok || tenant == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		exp := r.URL.Query().Get("expires")
+		sig := r.URL.Query().Get("signature")
+		if !hmac.Equal([]byte(sig), []byte(s.sign(tenant, id, exp))) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		if ts, err := strconv.ParseInt(exp, 10, 64); err != nil || s.now().Unix() > ts {
+			http.Error(w, "URL expired", http.StatusForbidden)
+			return
+		}
+
+		a, content, err := s.open(tenant, id)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, "failed to open artifact", http.StatusInternalServerError)
+			return
+		}
+		defer content.Close()
+
+		if a.ContentType != "" {
+			w.Header().Set("Content-Type", a.ContentType)
+		}
+		if a.Name != "" {
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+		}
+		http.ServeContent(w, r, "", a.CreatedAt, content.(io.ReadSeeker))
+	})
+}
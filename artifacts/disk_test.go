@@ -0,0 +1,93 @@
+package artifacts_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/artifacts"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DiskStore(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	_, err := artifacts.NewDiskStore(t.TempDir(), srv.URL, nil)
+	assert.EqualError(t, err, "secret is required to sign the URLs")
+
+	store, err := artifacts.NewDiskStore(t.TempDir(), srv.URL+"/artifacts/", []byte("secret"))
+	require.NoError(t, err)
+	mux.Handle("/artifacts/", http.StripPrefix("/artifacts", store.Handler()))
+
+	ctx1 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+	ctx2 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant2", "chat1", nil))
+
+	_, err = store.Put(context.Background(), "report.csv", "text/csv", strings.NewReader("a,b"))
+	assert.EqualError(t, err, "invalid chat context")
+
+	a, err := store.Put(ctx1, "report.csv", "text/csv", strings.NewReader("a,b\n1,2\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "report.csv", a.Name)
+	assert.Equal(t, int64(8), a.Size)
+	assert.Equal(t, "artifact://"+a.ID, a.URI())
+
+	id, ok := artifacts.ParseURI(a.URI())
+	require.True(t, ok)
+	assert.Equal(t, a.ID, id)
+	_, ok = artifacts.ParseURI("artifact://../secret")
+	assert.False(t, ok)
+
+	got, content, err := store.Get(ctx1, a.ID)
+	require.NoError(t, err)
+	data, err := io.ReadAll(content)
+	require.NoError(t, err)
+	require.NoError(t, content.Close())
+	assert.Equal(t, "a,b\n1,2\n", string(data))
+	assert.Equal(t, "text/csv", got.ContentType)
+
+	// the artifacts are scoped to the tenant
+	_, _, err = store.Get(ctx2, a.ID)
+	assert.ErrorIs(t, err, artifacts.ErrNotFound)
+	_, _, err = store.Get(ctx1, "../tenant2")
+	assert.ErrorIs(t, err, artifacts.ErrNotFound)
+
+	list, err := store.List(ctx1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, a.ID, list[0].ID)
+	list, err = store.List(ctx2)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	signed, err := store.SignedURL(ctx1, a.ID, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(signed, srv.URL+"/artifacts/tenant1/"+a.ID+"?"), signed)
+
+	resp, err := http.Get(signed)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "a,b\n1,2\n", string(body))
+	assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=report.csv`, resp.Header.Get("Content-Disposition"))
+
+	// the signature covers the tenant
+	resp, err = http.Get(strings.Replace(signed, "/tenant1/", "/tenant2/", 1))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, err = store.SignedURL(ctx2, a.ID, time.Minute)
+	assert.ErrorIs(t, err, artifacts.ErrNotFound)
+}
@@ -0,0 +1,271 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	s3MetaName    = "X-Amz-Meta-Name"
+	s3MetaCreated = "X-Amz-Meta-Created"
+)
+
+// S3Store is the Store in the S3 bucket,
+// the artifacts are stored as <prefix><tenant>/<id> objects,
+// and the signed URLs are the presigned S3 URLs.
+type S3Store struct {
+	cfg      aws.Config
+	bucket   string
+	prefix   string
+	endpoint string
+	signer   *v4.Signer
+	now      func() time.Time
+}
+
+var _ Store = (*S3Store)(nil)
+
+// NewS3Store returns a new S3Store in the bucket.
+// The cfg provides the region, credentials and HTTP client,
+// if cfg.BaseEndpoint is set, for example for S3 compatible storage,
+// the path-style URLs are used.
+func NewS3Store(cfg aws.Config, bucket, prefix string) (*S3Store, error) {
+	if bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+	if cfg.Credentials == nil {
+		return nil, errors.New("credentials are required")
+	}
+	endpoint := "https://" + bucket + ".s3." + cfg.Region + ".amazonaws.com"
+	if cfg.BaseEndpoint != nil && *cfg.BaseEndpoint != "" {
+		endpoint = strings.TrimSuffix(*cfg.BaseEndpoint, "/") + "/" + bucket
+	}
+	return &S3Store{
+		cfg:      cfg,
+		bucket:   bucket,
+		prefix:   prefix,
+		endpoint: endpoint,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			o.DisableURIPathEscaping = true
+		}),
+		now: time.Now,
+	}, nil
+}
+
+func (s *S3Store) key(tenant, id string) string {
+	return s.prefix + tenant + "/" + id
+}
+
+func (s *S3Store) Put(ctx context.Context, name, contentType string, content io.Reader) (*Artifact, error) {
+	tenant, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read artifact")
+	}
+
+	a := &Artifact{
+		ID:          NewID(),
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(body)),
+		CreatedAt:   s.now().UTC(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.endpoint+"/"+s.key(tenant, a.ID), bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.ContentLength = a.Size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set(s3MetaName, url.QueryEscape(name))
+	req.Header.Set(s3MetaCreated, a.CreatedAt.Format(time.RFC3339Nano))
+
+	sum := sha256.Sum256(body)
+	resp, err := s.do(ctx, req, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to put artifact")
+	}
+	resp.Body.Close()
+	return a, nil
+}
+
+func (s *S3Store) Get(ctx context.Context, id string) (*Artifact, io.ReadCloser, error) {
+	tenant, err := tenantID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isValidID(id) {
+		return nil, nil, errors.Wrapf(ErrNotFound, "%s", id)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"/"+s.key(tenant, id), nil)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	resp, err := s.do(ctx, req, emptyPayloadHash)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed to get artifact")
+	}
+
+	a := &Artifact{
+		ID:          id,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}
+	a.Name, _ = url.QueryUnescape(resp.Header.Get(s3MetaName))
+	if ts, err := time.Parse(time.RFC3339Nano, resp.Header.Get(s3MetaCreated)); err == nil {
+		a.CreatedAt = ts
+	} else if ts, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		a.CreatedAt = ts
+	}
+	return a, resp.Body, nil
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the artifacts of the tenant,
+// the Name and ContentType are not returned by the S3 listing,
+// use Get to retrieve them.
+func (s *S3Store) List(ctx context.Context) ([]*Artifact, error) {
+	tenant, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := s.key(tenant, "")
+
+	var list []*Artifact
+	token := ""
+	for {
+		q := url.Values{}
+		q.Set("list-type", "2")
+		q.Set("prefix", prefix)
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"/?"+q.Encode(), nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		resp, err := s.do(ctx, req, emptyPayloadHash)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to list artifacts")
+		}
+
+		var res s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode artifacts list")
+		}
+
+		for _, c := range res.Contents {
+			id := strings.TrimPrefix(c.Key, prefix)
+			if !isValidID(id) {
+				continue
+			}
+			list = append(list, &Artifact{
+				ID:        id,
+				Size:      c.Size,
+				CreatedAt: c.LastModified,
+			})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return list, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+func (s *S3Store) SignedURL(ctx context.Context, id string, expires time.Duration) (string, error) {
+	tenant, err := tenantID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !isValidID(id) {
+		return "", errors.Wrapf(ErrNotFound, "%s", id)
+	}
+	if expires <= 0 {
+		expires = DefaultURLExpiry
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"/"+s.key(tenant, id), nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	q := req.URL.Query()
+	q.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	req.URL.RawQuery = q.Encode()
+
+	creds, err := s.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to retrieve credentials")
+	}
+	signed, _, err := s.signer.PresignHTTP(ctx, creds, req, unsignedPayload, "s3", s.cfg.Region, s.now())
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to presign URL")
+	}
+	return signed, nil
+}
+
+const (
+	// emptyPayloadHash is SHA256 of the empty payload
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+)
+
+// do signs and sends the request, and returns the successful response,
+// the 404 response is returned as ErrNotFound
+func (s *S3Store) do(ctx context.Context, req *http.Request, payloadHash string) (*http.Response, error) {
+	creds, err := s.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve credentials")
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err = s.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", s.cfg.Region, s.now()); err != nil {
+		return nil, errors.Wrapf(err, "failed to sign request")
+	}
+
+	var client aws.HTTPClient = http.DefaultClient
+	if s.cfg.HTTPClient != nil {
+		client = s.cfg.HTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, errors.Wrapf(ErrNotFound, "%s", req.URL.Path)
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, errors.Newf("S3 request failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
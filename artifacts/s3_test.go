@@ -0,0 +1,127 @@
+package artifacts_test
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/effective-security/gogentic/artifacts"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type s3Object struct {
+	header http.Header
+	data   []byte
+}
+
+// fakeS3 is the minimal path-style S3 server
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string]*s3Object
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch {
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = &s3Object{header: r.Header.Clone(), data: data}
+	case r.URL.Query().Get("list-type") == "2":
+		type content struct {
+			Key          string
+			Size         int64
+			LastModified time.Time
+		}
+		var res struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Contents []content
+		}
+		prefix := r.URL.Query().Get("prefix")
+		for k, o := range f.objects {
+			if strings.HasPrefix(k, prefix) {
+				res.Contents = append(res.Contents, content{Key: k, Size: int64(len(o.data)), LastModified: time.Now().UTC()})
+			}
+		}
+		_ = xml.NewEncoder(w).Encode(res)
+	default:
+		o, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", o.header.Get("Content-Type"))
+		w.Header().Set("X-Amz-Meta-Name", o.header.Get("X-Amz-Meta-Name"))
+		w.Header().Set("X-Amz-Meta-Created", o.header.Get("X-Amz-Meta-Created"))
+		_, _ = w.Write(o.data)
+	}
+}
+
+func Test_S3Store(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(&fakeS3{objects: map[string]*s3Object{}})
+	defer srv.Close()
+
+	cfg := aws.Config{
+		Region:       "us-west-2",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+	}
+	_, err := artifacts.NewS3Store(cfg, "", "")
+	assert.EqualError(t, err, "bucket is required")
+
+	store, err := artifacts.NewS3Store(cfg, "bucket", "artifacts/")
+	require.NoError(t, err)
+
+	ctx1 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+	ctx2 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant2", "chat1", nil))
+
+	a, err := store.Put(ctx1, "chart 1.png", "image/png", strings.NewReader("\x89PNG"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), a.Size)
+
+	got, content, err := store.Get(ctx1, a.ID)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(content)
+	content.Close()
+	assert.Equal(t, "\x89PNG", string(data))
+	assert.Equal(t, "chart 1.png", got.Name)
+	assert.Equal(t, "image/png", got.ContentType)
+	assert.True(t, a.CreatedAt.Equal(got.CreatedAt))
+
+	_, _, err = store.Get(ctx2, a.ID)
+	assert.ErrorIs(t, err, artifacts.ErrNotFound)
+
+	list, err := store.List(ctx1)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, a.ID, list[0].ID)
+	assert.Equal(t, int64(4), list[0].Size)
+
+	signed, err := store.SignedURL(ctx1, a.ID, 10*time.Minute)
+	require.NoError(t, err)
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/bucket/artifacts/tenant1/"+a.ID, u.Path)
+	assert.Equal(t, "600", u.Query().Get("X-Amz-Expires"))
+	assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+}
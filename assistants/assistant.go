@@ -11,6 +11,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/chatmodel"
 	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/events"
 	"github.com/effective-security/gogentic/mcp"
 	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/effective-security/gogentic/pkg/llmutils"
@@ -19,9 +20,11 @@ import (
 	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/effective-security/gogentic/skills"
 	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/gogentic/tools/artifact"
 	"github.com/effective-security/x/slices"
 	"github.com/effective-security/x/values"
 	"github.com/effective-security/xlog"
+	"github.com/invopop/jsonschema"
 )
 
 // Assistant class for chat assistants.
@@ -33,6 +36,8 @@ type Assistant[O chatmodel.ContentProvider] struct {
 
 	toolsByName map[string]tools.ITool
 	toolsNames  []string
+	// toolsParams is the parameters schema of the tools by lowercase name
+	toolsParams map[string]*jsonschema.Schema
 	tools       []tools.ITool
 	llmToolDefs []llms.Tool
 
@@ -46,6 +51,9 @@ type Assistant[O chatmodel.ContentProvider] struct {
 	onSkills     ProvideSkillsPromptFunc
 	skills       skills.Skills
 	skillsPrompt string
+
+	// grammarErr is returned by Run, if the output grammar can not be created
+	grammarErr error
 }
 
 var (
@@ -69,12 +77,31 @@ func NewAssistant[O chatmodel.ContentProvider](
 	}
 
 	var output O
-	ret.OutputParser, _ = encoding.NewTypedOutputParser(output, ret.cfg.Mode)
+	parser, _ := encoding.NewTypedOutputParser(output, ret.cfg.Mode)
+	if parser != nil {
+		parser.WithSchemaValidation(ret.cfg.ValidateOutputSchema)
+	}
+	ret.OutputParser = parser
 
-	prov := llmModel.GetProviderType()
-	strict := ret.cfg.Mode == encoding.ModeJSONSchemaStrict && prov.Supports(llms.CapabilityJSONSchemaStrict)
+	caps := llms.ModelCapabilities(llmModel, llmModel.GetProviderType())
+	strict := ret.cfg.Mode == encoding.ModeJSONSchemaStrict && caps.Supports(llms.CapabilityJSONSchemaStrict)
 	jsonSchema := (ret.cfg.Mode == encoding.ModeJSONSchema || ret.cfg.Mode == encoding.ModeJSONSchemaStrict) &&
-		prov.Supports(llms.CapabilityJSONSchema)
+		caps.Supports(llms.CapabilityJSONSchema)
+	if jsonSchema && ret.cfg.Constraint == nil && caps.Supports(llms.CapabilityConstrainedDecoding) {
+		// the grammar is enforced by the decoder,
+		// the output schema instructions are added to the system prompt
+		grammar, err := schema.NewGrammar(reflect.TypeOf(output))
+		if err != nil {
+			logger.KV(xlog.ERROR,
+				"status", "failed_to_create_grammar",
+				"err", err.Error(),
+			)
+			ret.grammarErr = errors.WithMessage(err, "failed to create the output grammar")
+		} else {
+			ret.cfg.Constraint = &llms.Constraint{Type: llms.ConstraintGrammar, Value: grammar}
+			jsonSchema = false
+		}
+	}
 	if jsonSchema {
 		rf, err := schema.NewResponseFormat(reflect.TypeOf(output), strict)
 		if err != nil {
@@ -84,6 +111,12 @@ func NewAssistant[O chatmodel.ContentProvider](
 			)
 		}
 		ret.cfg.ResponseFormat = rf
+	} else if (ret.cfg.Mode == encoding.ModeJSONSchema || ret.cfg.Mode == encoding.ModeJSONSchemaStrict) &&
+		ret.cfg.ResponseFormat == nil && ret.cfg.Constraint == nil &&
+		caps.Supports(llms.CapabilityJSONResponse) {
+		// the provider supports only JSON mode,
+		// the output schema instructions are added to the system prompt
+		ret.cfg.ResponseFormat = schema.NewResponseFormatJSONObject()
 	}
 
 	return ret
@@ -137,13 +170,16 @@ func (a *Assistant[O]) GetTools() []tools.ITool {
 func (a *Assistant[O]) WithTools(list ...tools.ITool) *Assistant[O] {
 	if a.toolsByName == nil {
 		a.toolsByName = make(map[string]tools.ITool)
+		a.toolsParams = make(map[string]*jsonschema.Schema)
 	}
 	for _, tool := range list {
 		name := tool.Name()
 		// use lowercase for the key
 		nameLowerCase := strings.ToLower(name)
 		if a.toolsByName[nameLowerCase] == nil {
+			params := tool.Parameters()
 			a.toolsByName[nameLowerCase] = tool
+			a.toolsParams[nameLowerCase] = params
 			a.toolsNames = append(a.toolsNames, name)
 			a.tools = append(a.tools, tool)
 			t := llms.Tool{
@@ -151,7 +187,7 @@ func (a *Assistant[O]) WithTools(list ...tools.ITool) *Assistant[O] {
 				Function: &llms.FunctionDefinition{
 					Name:        name,
 					Description: tool.Description(),
-					Parameters:  tool.Parameters(),
+					Parameters:  params,
 				},
 			}
 			a.llmToolDefs = append(a.llmToolDefs, t)
@@ -203,20 +239,40 @@ func (a *Assistant[O]) WithPromptInputProvider(cb ProvidePromptInputsFunc) {
 }
 
 // GetSystemPrompt generates the system prompt for the Assistant.
+// The output schema section is included, also when it is sent as a separate message,
+// see WithOutputSchemaPlacement.
 func (a *Assistant[O]) GetSystemPrompt(ctx context.Context, input string, promptInputs map[string]any) (string, error) {
+	systemPrompt, outputSchema, err := a.getSystemPrompt(ctx, a.sysprompt, input, promptInputs)
+	if err != nil {
+		return "", err
+	}
+	if outputSchema != "" {
+		systemPrompt += "\n\n" + outputSchema
+	}
+	return systemPrompt, nil
+}
+
+// getSystemPrompt generates the system prompt from the sysprompt template,
+// which is either the Assistant's one, or the selected variant.
+// The output schema section is returned separately, when it must be sent as a separate message.
+func (a *Assistant[O]) getSystemPrompt(ctx context.Context, sysprompt prompts.FormatPrompter, input string, promptInputs map[string]any) (string, string, error) {
 	if a.onPrompt != nil {
 		extra, err := a.onPrompt(ctx, input)
 		if err != nil {
-			return "", errors.WithMessage(err, "failed to get prompt inputs")
+			return "", "", errors.WithMessage(err, "failed to get prompt inputs")
 		}
 		if len(extra) > 0 {
 			promptInputs = llmutils.MergeInputs(promptInputs, extra)
 		}
 	}
 
-	promptValue, err := a.FormatPrompt(promptInputs)
+	inputs := llmutils.MergeInputs(a.cfg.PromptInput, promptInputs)
+	if _, ok := inputs[OutputSchemaPromptInput]; !ok {
+		inputs[OutputSchemaPromptInput] = outputSchemaMarker
+	}
+	promptValue, err := sysprompt.FormatPrompt(inputs)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// Convert the prompt value to a string.
@@ -226,12 +282,12 @@ func (a *Assistant[O]) GetSystemPrompt(ctx context.Context, input string, prompt
 		if a.onSkills != nil {
 			a.skillsPrompt, err = a.onSkills(ctx, a.skills)
 			if err != nil {
-				return "", errors.WithMessage(err, "failed to get skills prompt")
+				return "", "", errors.WithMessage(err, "failed to get skills prompt")
 			}
 		} else {
 			a.skillsPrompt, err = DefaultPromptProvider(ctx, a.skills)
 			if err != nil {
-				return "", errors.WithMessage(err, "failed to get skills prompt")
+				return "", "", errors.WithMessage(err, "failed to get skills prompt")
 			}
 		}
 		a.skillsPrompt = strings.Trim(a.skillsPrompt, "\n")
@@ -241,17 +297,19 @@ func (a *Assistant[O]) GetSystemPrompt(ctx context.Context, input string, prompt
 		systemPrompt += "\n\n" + a.skillsPrompt
 	}
 
-	if a.cfg.ResponseFormat == nil {
-		// if provider supports json response, but not json_schema,
-		// we need to add the output schema to the system prompt
-		// Get the output schema instructions and trim any trailing newlines.
-		outputSchema := strings.TrimRight(a.OutputParser.GetFormatInstructions(), "\n")
-		if outputSchema != "" {
-			// Append the output schema to the system prompt with a separating newline.
-			systemPrompt = fmt.Sprintf("%s\n\n# OUTPUT SCHEMA\n%s", systemPrompt, outputSchema)
-		}
+	outputSchema := a.outputSchemaSection()
+	if a.cfg.OutputSchemaPlacement == OutputSchemaMessage {
+		systemPrompt = strings.ReplaceAll(systemPrompt, outputSchemaMarker, "")
+		return systemPrompt, outputSchema, nil
 	}
-	return systemPrompt, nil
+	if strings.Contains(systemPrompt, outputSchemaMarker) {
+		// the template has the output_schema placeholder
+		systemPrompt = strings.ReplaceAll(systemPrompt, outputSchemaMarker, outputSchema)
+	} else if outputSchema != "" {
+		// Append the output schema to the system prompt with a separating newline.
+		systemPrompt += "\n\n" + outputSchema
+	}
+	return systemPrompt, "", nil
 }
 
 func (a *Assistant[O]) RegisterMCP(registrator McpServerRegistrator) error {
@@ -289,6 +347,46 @@ func (a *Assistant[O]) Call(ctx context.Context, input *CallInput) (*Response, e
 }
 
 func (a *Assistant[O]) Run(ctx context.Context, input *CallInput, optionalOutputType *O) (*Response, error) {
+	cfg := a.GetCallConfig(input.Options...)
+	if cfg.EventBus == nil {
+		return a.runMiddlewares(ctx, cfg.Middlewares, input, optionalOutputType)
+	}
+
+	if cfg.Model == "" {
+		cfg.Model = a.LLM.GetName()
+	}
+	started := time.Now()
+	cfg.publish(ctx, a, &events.Event{Type: events.RunStarted})
+	resp, err := a.runMiddlewares(ctx, cfg.Middlewares, input, optionalOutputType)
+	cfg.publishRunFinished(ctx, a, resp, err, started)
+	return resp, err
+}
+
+func (a *Assistant[O]) runMiddlewares(ctx context.Context, middlewares []Middleware, input *CallInput, optionalOutputType *O) (*Response, error) {
+	if len(middlewares) == 0 {
+		return a.runWithRetry(ctx, input, optionalOutputType)
+	}
+
+	runner := ChainRunner(func(ctx context.Context, input *CallInput, output any) (*Response, error) {
+		typed, ok := output.(*O)
+		if output != nil && !ok {
+			return nil, errors.Newf("assistant %s: unexpected output type %T", a.Name(), output)
+		}
+		return a.runWithRetry(ctx, input, typed)
+	}, middlewares...)
+
+	ctx = withAssistant(ctx, a)
+	if optionalOutputType == nil {
+		return runner(ctx, input, nil)
+	}
+	return runner(ctx, input, optionalOutputType)
+}
+
+func (a *Assistant[O]) runWithRetry(ctx context.Context, input *CallInput, optionalOutputType *O) (*Response, error) {
+	if a.grammarErr != nil {
+		return nil, errors.WithMessagef(a.grammarErr, "assistant %s", a.Name())
+	}
+
 	orgID := chatmodel.GetOrgID(ctx)
 	started := time.Now()
 	defer metricskey.PerfAssistantCall.MeasureSince(started, a.Name(), a.LLM.GetName(), orgID)
@@ -300,46 +398,116 @@ func (a *Assistant[O]) Run(ctx context.Context, input *CallInput, optionalOutput
 		cfg.modelSet = true
 	}
 
+	variant, err := cfg.selectPromptVariant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg.promptVariant = variant
+
+	if cfg.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Deadline)
+		defer cancel()
+	}
+	ctx = cfg.withIdempotency(ctx, input)
+
+	// the run state is shared by the tools of the nested assistants
+	if chatmodel.GetRunState(ctx) == nil {
+		ctx = chatmodel.WithRunState(ctx, chatmodel.NewRunState())
+	}
+	cfg.resolveRunState = DelegationDepth(ctx) == 0
+
 	callback := cfg.CallbackHandler
 	if callback != nil {
-		callback.OnAssistantStart(ctx, a, input.Input)
+		callback.OnAssistantStart(ctx, &AssistantStartEvent{Assistant: a, Input: input.Input})
+	}
+	// report the result of the run with the selected variant
+	onVariantEnd := func(err error) {
+		if pc, ok := callback.(PromptVariantCallback); ok && variant != nil {
+			pc.OnAssistantPromptVariant(ctx, &PromptVariantEvent{Assistant: a, Variant: variant, Err: err})
+		}
 	}
 
 	var (
 		resp           *Response
 		messageHistory llms.Messages
-		err            error
 	)
 
-	for range 2 {
+	for attempt := 0; ; attempt++ {
 		resp, messageHistory, err = a.run(ctx, orgID, cfg, input, optionalOutputType)
 		if err != nil {
 			metricskey.StatsAssistantCallsFailed.IncrCounter(1, a.Name(), cfg.Model, orgID)
+			if variant != nil {
+				metricskey.StatsAssistantPromptVariantFailed.IncrCounter(1, a.Name(), variant.Name, variant.Version, orgID)
+			}
 			if callback != nil {
-				callback.OnAssistantError(ctx, a, input.Input, err, messageHistory)
+				callback.OnAssistantError(ctx, &AssistantErrorEvent{
+					Assistant: a,
+					Input:     input.Input,
+					Err:       err,
+					Messages:  messageHistory,
+					Latency:   time.Since(started),
+				})
+			}
+			if !errors.Is(err, chatmodel.ErrFailedUnmarshalOutput) {
+				onVariantEnd(err)
+				return nil, err
 			}
 			// Sometimes the LLM returns Text vs JSON
-			if errors.Is(err, chatmodel.ErrFailedUnmarshalOutput) {
+			event := &RecoveryEvent{
+				Reason:   RetryReasonParseError,
+				Attempt:  attempt + 1,
+				Limit:    MaxParseErrorRetries,
+				Exceeded: attempt >= MaxParseErrorRetries,
+				Err:      err,
+				Messages: messageHistory,
+			}
+			// the limit applies also when the strategy continues the run
+			if rec := cfg.recoverFrom(ctx, a, event); !rec.Aborts(event) && !event.Exceeded {
 				metricskey.StatsAssistantCallsRetried.IncrCounter(1, a.Name(), cfg.Model, orgID)
+				if rc, ok := callback.(RecoveryCallback); ok {
+					rc.OnRetry(ctx, &RetryEvent{Assistant: a, Reason: RetryReasonParseError, Attempt: attempt + 1, Err: err})
+				}
 
 				input.Input = "Return the response in JSON format as requested."
+				var verr *schema.ValidationError
+				if errors.As(err, &verr) {
+					input.Input = "The response does not match the JSON schema:\n" + verr.Details() +
+						"Fix the violations and return the response in JSON format as requested."
+				}
+				var rp chatmodel.RepairPrompter
+				if errors.As(err, &rp) {
+					input.Input = rp.RepairPrompt()
+				}
+				if rec.Action == RecoveryRewrite && rec.Prompt != "" {
+					input.Input = rec.Prompt
+				}
 				// remove the tools
 				cfg.Tools = nil
 
 				continue
 			}
+			onVariantEnd(err)
 			return nil, err
 		}
 		break
 	}
 
-	if err != nil {
-		return nil, err
-	}
-
 	metricskey.StatsAssistantCallsSucceeded.IncrCounter(1, a.Name(), cfg.Model, orgID)
+	if variant != nil {
+		metricskey.StatsAssistantPromptVariantSucceeded.IncrCounter(1, a.Name(), variant.Name, variant.Version, orgID)
+	}
+	onVariantEnd(nil)
+	// the wall time of the run, the nested runs are included
+	resp.Usage.Duration = time.Since(started)
 	if callback != nil {
-		callback.OnAssistantEnd(ctx, a, input.Input, resp, messageHistory)
+		callback.OnAssistantEnd(ctx, &AssistantEndEvent{
+			Assistant: a,
+			Input:     input.Input,
+			Response:  resp,
+			Messages:  messageHistory,
+			Latency:   resp.Usage.Duration,
+		})
 	}
 	return resp, nil
 }
@@ -358,6 +526,7 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 	runID := chatCtx.GetRunID()
 	actionID := chatmodel.GetActionID(ctx)
 	assistantName := a.Name()
+	started := time.Now()
 
 	source := &llms.MessageSource{
 		Name:     assistantName,
@@ -371,11 +540,39 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 		return list
 	}
 
-	systemPrompt, err := a.GetSystemPrompt(ctx, input.Input, input.PromptInputs)
+	if cfg.Redactor != nil {
+		// the callbacks sharing the redactor mask the sensitive arguments of the tools
+		for name, params := range a.toolsParams {
+			cfg.Redactor.RegisterTool(name, params)
+		}
+	}
+
+	sysprompt := a.sysprompt
+	if cfg.promptVariant != nil {
+		sysprompt = cfg.promptVariant.Prompt
+	}
+	systemPrompt, outputSchema, err := a.getSystemPrompt(ctx, sysprompt, input.Input, input.PromptInputs)
 	if err != nil {
 		return nil, nil, errors.WithMessage(err, "failed to format system prompt")
 	}
 
+	fetchTool := cfg.fetchArtifactTool()
+	hasTools := len(a.llmToolDefs) > 0 || fetchTool != nil
+	var prov llms.ProviderType
+	var caps llms.Capability
+	if hasTools {
+		prov = a.LLM.GetProviderType()
+		caps = llms.ModelCapabilities(a.LLM, prov)
+	}
+	react := hasTools && cfg.useReAct(caps)
+	if react {
+		reactTools := a.tools
+		if fetchTool != nil && a.toolsByName[artifact.FetchToolName] == nil {
+			reactTools = append(append([]tools.ITool{}, reactTools...), fetchTool)
+		}
+		systemPrompt += "\n\n" + ReActPrompt(reactTools)
+	}
+
 	// `messageHistory` contains ALL the messages including:
 	//   1. the system prompt
 	//   2. the previous messages from the store
@@ -384,8 +581,24 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 	//   5. additional input messages
 	// Response.Messages are returned to the caller, which are added to the message history Store.
 
-	resp = &Response{}
-	messageHistory = appendWithSource(messageHistory, llms.MessageFromTextParts(llms.RoleSystem, systemPrompt))
+	resp = &Response{
+		PromptVariant: cfg.promptVariant,
+	}
+	systemRole := llms.RoleSystem
+	if cfg.DeveloperPrompt {
+		systemRole = llms.RoleDeveloper
+	}
+	messageHistory = appendWithSource(messageHistory, llms.MessageFromTextParts(systemRole, systemPrompt))
+	if cfg.DeveloperInstructions != "" {
+		messageHistory = appendWithSource(messageHistory, llms.MessageFromTextParts(llms.RoleDeveloper, cfg.DeveloperInstructions))
+	}
+	if outputSchema != "" {
+		schemaRole := llms.RoleSystem
+		if llms.ModelCapabilities(a.LLM, a.LLM.GetProviderType()).Supports(llms.CapabilityDeveloperRole) {
+			schemaRole = llms.RoleDeveloper
+		}
+		messageHistory = appendWithSource(messageHistory, llms.MessageFromTextParts(schemaRole, outputSchema))
+	}
 
 	if cfg.Store != nil {
 		prevMessages := cfg.Store.Messages(ctx)
@@ -401,11 +614,13 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 		messageHistory = appendWithSource(messageHistory, llms.MessageFromTextParts(llms.RoleAI, llmutils.AddComment("assistant", "example", "answer", example.Completion)))
 	}
 
+	// the current turn starts with the input of the run, see WithContextRecovery
+	turnStart := len(messageHistory)
 	parsedInput := input.Input
 
 	var userMessage llms.Message
-	if parsedInput != "" {
-		if a.inputParser != nil {
+	if parsedInput != "" || len(input.Attachments) > 0 {
+		if parsedInput != "" && a.inputParser != nil {
 			parsedInput, err = a.inputParser(parsedInput)
 			if err != nil {
 				return nil, messageHistory, errors.WithMessage(err, "failed to parse input")
@@ -415,9 +630,21 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 		role := llms.RoleHuman
 		if cfg.IsGeneric {
 			role = llms.RoleGeneric
-			parsedInput = llmutils.AddComment("assistant", assistantName, "question", parsedInput)
+			if parsedInput != "" {
+				parsedInput = llmutils.AddComment("assistant", assistantName, "question", parsedInput)
+			}
+		}
+		userMessage = llms.Message{Role: role}
+		if parsedInput != "" {
+			userMessage.Parts = append(userMessage.Parts, llms.TextPart(parsedInput))
+		}
+		if len(input.Attachments) > 0 {
+			parts, err := cfg.attachmentParts(ctx, llms.ModelCapabilities(a.LLM, a.LLM.GetProviderType()), input.Attachments)
+			if err != nil {
+				return nil, messageHistory, errors.WithMessage(err, "failed to add attachments")
+			}
+			userMessage.Parts = append(userMessage.Parts, parts...)
 		}
-		userMessage = llms.MessageFromTextParts(role, parsedInput)
 		resp.Messages = appendWithSource(resp.Messages, userMessage)
 		messageHistory = appendWithSource(messageHistory, userMessage)
 	}
@@ -427,50 +654,216 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 		messageHistory = appendWithSource(messageHistory, input.Messages...)
 	}
 
+	var forcedChoice llms.CallOption
+	if cfg.ForcedTool != "" {
+		tool := a.toolsByName[strings.ToLower(cfg.ForcedTool)]
+		if tool == nil {
+			return nil, messageHistory, errors.Newf("assistant %s: the forced tool %s is not found", assistantName, cfg.ForcedTool)
+		}
+		if react {
+			return nil, messageHistory, errors.Newf("assistant %s: the forced tool requires function calling", assistantName)
+		}
+		forcedChoice = llms.WithToolChoice(llms.FunctionToolChoice(tool.Name()))
+	}
+
 	var extraOptions []Option
-	if len(a.llmToolDefs) > 0 {
-		prov := a.LLM.GetProviderType()
-		if !prov.Supports(llms.CapabilityFunctionCalling) {
-			return nil, messageHistory, errors.Newf("assistant %s: the %s provider does not support function calling", assistantName, string(prov))
+	if react {
+		// the output is ReAct text
+		extraOptions = append(extraOptions, WithConstraint(nil))
+		if !cfg.stopWordsSet {
+			// stop before the hallucinated observation
+			extraOptions = append(extraOptions, WithStopWords([]string{"\n" + ReActObservationPrefix}))
+		}
+	} else {
+		if len(a.llmToolDefs) > 0 {
+			if !caps.Supports(llms.CapabilityFunctionCalling) {
+				return nil, messageHistory, errors.Newf("assistant %s: the %s provider does not support function calling", assistantName, string(prov))
+			}
+			extraOptions = append(extraOptions, WithTools(a.llmToolDefs))
+		}
+		if fetchTool != nil && caps.Supports(llms.CapabilityFunctionCalling) {
+			extraOptions = append(extraOptions, WithTool(fetchArtifactToolDef(fetchTool)))
 		}
-		extraOptions = append(extraOptions, WithTools(a.llmToolDefs))
 	}
 	callOpts := cfg.GetCallOptions(extraOptions...)
+	if rc, ok := cfg.CallbackHandler.(RecoveryCallback); ok {
+		callOpts = append(callOpts, llms.WithRateLimitFunc(func(ctx context.Context, attempt int, wait time.Duration) {
+			rc.OnRateLimit(ctx, &RateLimitEvent{Assistant: a, LLM: a.LLM, Attempt: attempt, Wait: wait})
+		}))
+	}
+	if mc, ok := cfg.CallbackHandler.(MessageOrderRepairCallback); ok {
+		callOpts = append(callOpts, llms.WithMessageOrderRepairFunc(func(ctx context.Context, repair llms.MessageOrderRepair) {
+			mc.OnMessageOrderRepair(ctx, &MessageOrderRepairEvent{Assistant: a, LLM: a.LLM, Repair: repair})
+		}))
+	}
+	if dc, ok := cfg.CallbackHandler.(ToolCallDeltaCallback); ok && cfg.StreamingFunc != nil {
+		callOpts = append(callOpts, llms.WithStreamingToolCallFunc(func(ctx context.Context, delta llms.ToolCallDelta) error {
+			dc.OnToolCallDelta(ctx, &ToolCallDeltaEvent{Assistant: a, Delta: delta})
+			return nil
+		}))
+	}
+
+	// the rounds are cancelled before the run deadline,
+	// the rest of the time is reserved for the final answer
+	roundCtx, cancelRounds := cfg.roundContext(ctx)
+	defer cancelRounds()
+	finalRound := false
 
 	modelName := cfg.Model
 	var totalToolExecuted int
-	maxRetries := DefaultMaxRetries
 	retryCount := 0
 	consecutiveNotFoundCount := 0
+	// the history is reduced once per run, see WithContextRecovery
+	contextReduced := false
 
 	bytesLimit := uint64(values.NumbersCoalesce(cfg.MaxLength, DefaultMaxContentSize))
 	toolsLimit := values.NumbersCoalesce(cfg.MaxToolCalls, DefaultMaxToolCalls)
+	// zero limits disable the retries, the defaults are set by NewConfig
+	maxRetries := cfg.MaxEmptyRetries
+	notFoundLimit := cfg.MaxNotFoundTools
+
+	// recoverFrom returns true, if the run must be aborted,
+	// otherwise adds the rewritten prompt to the message history, if any
+	recoverFrom := func(reason RetryReason, attempt, limit int, exceeded bool) bool {
+		event := &RecoveryEvent{
+			Reason:   reason,
+			Attempt:  attempt,
+			Limit:    limit,
+			Exceeded: exceeded,
+			Messages: messageHistory,
+		}
+		rec := cfg.recoverFrom(ctx, a, event)
+		if rec.Aborts(event) {
+			return true
+		}
+		if rec.Action == RecoveryRewrite && rec.Prompt != "" {
+			messageHistory = appendWithSource(messageHistory, llms.MessageFromTextParts(llms.RoleHuman, rec.Prompt))
+		}
+		return false
+	}
 	for {
-		if len(messageHistory) >= cfg.MaxMessages {
+		if !finalRound && deadlineReached(ctx, roundCtx) {
+			logger.ContextKV(ctx, xlog.WARNING,
+				"assistant", assistantName,
+				"model", modelName,
+				"status", "deadline_reached",
+				"round", resp.Usage.LlmCallCount,
+				"tool_calls", totalToolExecuted,
+			)
+			finalRound = true
+			resp.DeadlineReached = true
+			messageHistory = appendWithSource(messageHistory, llms.MessageFromTextParts(llms.RoleHuman, DeadlineFinalAnswerPrompt))
+		}
+
+		// the transformers are applied first, so the checks and the callbacks
+		// see the messages that are sent to the LLM, the history of the run is not changed
+		messages := messageHistory
+		for _, transform := range cfg.MessageTransformers {
+			messages, err = transform(ctx, messages)
+			if err != nil {
+				return nil, messageHistory, errors.WithMessagef(err, "assistant %s: failed to transform messages", assistantName)
+			}
+		}
+
+		if repaired, report := llmutils.RepairHistory(messages, cfg.HistoryRepair); report.Changed() {
+			logger.ContextKV(ctx, xlog.WARNING,
+				"assistant", assistantName,
+				"chat_id", chatID,
+				"reason", "repaired_history",
+				"dropped_responses", report.DroppedToolResponses,
+				"dropped_calls", report.DroppedToolCalls,
+				"stubbed_calls", report.StubbedToolCalls,
+				"stubbed_responses", report.StubbedToolResponses,
+				"reordered_responses", report.ReorderedToolResponses)
+			for _, d := range report.Dropped {
+				logger.ContextKV(ctx, xlog.WARNING,
+					"assistant", assistantName,
+					"chat_id", chatID,
+					"reason", "dropped_tool_message",
+					"tool_call_id", d.ToolCallID,
+					"tool", d.Name,
+					"response", d.Response)
+			}
+			messages = repaired
+		}
+
+		if len(messages) >= cfg.MaxMessages {
 			return nil, messageHistory, errors.Newf("assistant %s: the messages count exceeded limit", assistantName)
 		}
-		bytesSent := llmutils.CountMessagesContentSize(messageHistory)
+		bytesSent := llmutils.CountMessagesContentSize(messages)
 		if bytesSent > bytesLimit {
 			return nil, messageHistory, errors.Newf("assistant %s: the content size exceeded limit", assistantName)
 		}
 
+		roundOpts := callOpts
+		callCtx := roundCtx
+		switch {
+		case finalRound:
+			// the tools stay defined, as the history has the tool calls
+			roundOpts = append(callOpts[:len(callOpts):len(callOpts)], llms.WithToolChoice(llms.ToolChoiceNone))
+			callCtx = ctx
+		case forcedChoice != nil && totalToolExecuted == 0:
+			// the run starts with the forced tool call, then the model decides
+			roundOpts = append(callOpts[:len(callOpts):len(callOpts)], forcedChoice)
+		}
+		if cfg.idempotency != nil {
+			roundOpts = append(roundOpts[:len(roundOpts):len(roundOpts)], llms.WithIdempotencyKey(cfg.idempotency.llmCallKey()))
+		}
+
+		if cfg.DryRun {
+			resp.PreparedCall = newPreparedCall(a.LLM, cfg.Model, messages, roundOpts)
+			return resp, messageHistory, nil
+		}
+
 		if cfg.CallbackHandler != nil {
-			cfg.CallbackHandler.OnAssistantLLMCallStart(ctx, a, a.LLM, messageHistory)
+			cfg.CallbackHandler.OnAssistantLLMCallStart(ctx, &LLMCallStartEvent{
+				Assistant: a,
+				LLM:       a.LLM,
+				Messages:  messages,
+				Round:     int(resp.Usage.LlmCallCount) + 1,
+			})
 		}
 
-		metricskey.StatsLLMMessagesSent.IncrCounter(float64(len(messageHistory)), assistantName, modelName, orgID)
+		metricskey.StatsLLMMessagesSent.IncrCounter(float64(len(messages)), assistantName, modelName, orgID)
 		metricskey.StatsLLMBytesSent.IncrCounter(float64(bytesSent), assistantName, modelName, orgID)
 
+		if err := checkDelegationBudget(ctx); err != nil {
+			return nil, messageHistory, errors.WithMessagef(err, "assistant %s", assistantName)
+		}
+
 		resp.Usage.BytesOut += bytesSent
 		resp.Usage.LlmCallCount++
 
-		llmresp, err := a.LLM.GenerateContent(ctx, messageHistory, callOpts...)
+		callStarted := time.Now()
+		llmresp, llm, err := a.generateContent(callCtx, cfg, messages, roundOpts)
+		// the fallback is per round, the next round starts with the primary model
+		modelName = cfg.Model
+		if llm != a.LLM {
+			modelName = llm.GetName()
+		}
 		if err != nil {
+			if !finalRound && deadlineReached(ctx, roundCtx) {
+				continue
+			}
+			if !contextReduced && errors.Is(err, llms.ErrContextLengthExceeded) {
+				contextReduced = true
+				if reduced, ok := a.reduceContext(ctx, cfg, messageHistory, turnStart, err); ok {
+					messageHistory = reduced
+					continue
+				}
+			}
 			return nil, messageHistory, errors.Wrapf(err, "assistant %s: model %s: failed to generate content from LLM", assistantName, modelName)
 		}
+		metricskey.LLMCallLatency.ObserveSince(callStarted, assistantName, modelName, orgID)
 
 		if cfg.CallbackHandler != nil {
-			cfg.CallbackHandler.OnAssistantLLMCallEnd(ctx, a, a.LLM, llmresp)
+			cfg.CallbackHandler.OnAssistantLLMCallEnd(ctx, &LLMCallEndEvent{
+				Assistant: a,
+				LLM:       llm,
+				Response:  llmresp,
+				Round:     int(resp.Usage.LlmCallCount),
+				Latency:   time.Since(callStarted),
+			})
 		}
 		resp.Choices = llmresp.Choices
 
@@ -486,16 +879,20 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 		metricskey.StatsLLMCachedReadTokens.IncrCounter(float64(stats.CacheReadTokens), assistantName, modelName, orgID)
 		metricskey.StatsLLMTotalTokens.IncrCounter(float64(stats.TotalTokens), assistantName, modelName, orgID)
 		resp.Usage.Usage.Add(stats)
+		if cfg.CostFunc != nil {
+			resp.Usage.Cost += cfg.CostFunc(modelName, stats)
+		}
+		chargeDelegationBudget(ctx, modelName, stats)
 
 		// Check for empty response and retry if needed
 		if len(resp.Choices) == 0 {
 			retryCount++
-			if retryCount >= maxRetries {
+			if recoverFrom(RetryReasonEmptyResponse, retryCount, maxRetries, retryCount >= maxRetries) {
 				logger.ContextKV(ctx, xlog.ERROR,
 					"assistant", assistantName,
 					"model", modelName,
 					"status", "max_retries_exceeded",
-					"input", slices.StringUpto(parsedInput, 64),
+					"input", slices.StringUpto(cfg.Redactor.String(parsedInput), 64),
 					"retry_count", retryCount,
 				)
 				return nil, messageHistory, errors.Newf("assistant %s: LLM returned empty response after %d retries", assistantName, retryCount)
@@ -506,39 +903,89 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 				"status", "retrying_empty_response",
 				"retry_count", retryCount,
 			)
+			if rc, ok := cfg.CallbackHandler.(RecoveryCallback); ok {
+				rc.OnRetry(ctx, &RetryEvent{Assistant: a, Reason: RetryReasonEmptyResponse, Attempt: retryCount})
+			}
 			continue
 		}
 
+		if finalRound {
+			if react {
+				// the final answer without the action
+				step := ParseReAct(resp.Choices[0].Content)
+				if step.Action == "" {
+					final := *resp.Choices[0]
+					final.Content = step.FinalAnswer
+					resp.Choices = []*llms.ContentChoice{&final}
+				}
+			}
+			break
+		}
+
+		if len(cfg.StopConditions) > 0 {
+			state := &StopState{
+				Round:     int(resp.Usage.LlmCallCount),
+				Started:   started,
+				Model:     modelName,
+				Response:  resp,
+				ToolCalls: totalToolExecuted,
+			}
+			if idx := cfg.stopConditionMet(ctx, state); idx >= 0 {
+				logger.ContextKV(ctx, xlog.DEBUG,
+					"assistant", assistantName,
+					"model", modelName,
+					"status", "stop_condition_met",
+					"condition", idx,
+					"round", state.Round,
+				)
+				break
+			}
+		}
+
 		// Perform Tool call
 		var toolExecuted int
 		var notFoundCount int
-		toolExecuted, notFoundCount, messageHistory, err = a.executeToolCalls(ctx, orgID, cfg, messageHistory, resp, input.Options...)
+		if react {
+			toolExecuted, notFoundCount, messageHistory, err = a.executeReActStep(roundCtx, orgID, cfg, messageHistory, resp, input.Options...)
+		} else {
+			toolExecuted, notFoundCount, messageHistory, err = a.executeToolCalls(roundCtx, orgID, cfg, messageHistory, resp, input.Options...)
+		}
 		if err != nil {
+			if deadlineReached(ctx, roundCtx) {
+				// the cancelled calls are reported in the history
+				continue
+			}
 			return nil, messageHistory, err
 		}
 
 		if toolExecuted == 0 {
 			break
 		}
+		resp.Usage.ToolRounds++
+		resp.Usage.ToolCallCount += uint32(toolExecuted)
 		consecutiveNotFoundCount += notFoundCount
 		totalToolExecuted += toolExecuted
-		if consecutiveNotFoundCount > 3 {
+		if consecutiveNotFoundCount > 0 &&
+			recoverFrom(RetryReasonToolNotFound, consecutiveNotFoundCount, notFoundLimit, consecutiveNotFoundCount > notFoundLimit) {
 			return nil, messageHistory, errors.Newf("assistant %s: the number of not found tools is exceeded", assistantName)
 		}
 		// reset
 		consecutiveNotFoundCount = 0
-		if totalToolExecuted >= toolsLimit {
+		if totalToolExecuted >= toolsLimit &&
+			recoverFrom(RetryReasonToolCallsLimit, totalToolExecuted, toolsLimit, true) {
 			return nil, messageHistory, errors.Newf("assistant %s: the tool calls limit is exceeded", assistantName)
 		}
 	}
 
+	metricskey.AssistantLoopIterations.Observe(float64(resp.Usage.LlmCallCount), assistantName, modelName, orgID)
+
 	choices := resp.Choices
 	if len(choices) < 1 {
 		logger.ContextKV(ctx, xlog.ERROR,
 			"assistant", assistantName,
 			"model", modelName,
 			"status", "empty_choices",
-			"input", slices.StringUpto(parsedInput, 64),
+			"input", slices.StringUpto(cfg.Redactor.String(parsedInput), 64),
 		)
 		return nil, messageHistory, errors.Newf("assistant %s: LLM returned empty response with no choices", assistantName)
 	}
@@ -552,6 +999,12 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 		"tool_calls", totalToolExecuted,
 	)
 
+	if len(cfg.PostProcessors) > 0 {
+		for _, choice := range choices {
+			choice.Content = llmutils.PostProcess(choice.Content, cfg.PostProcessors...)
+		}
+	}
+
 	result := choices[0].Content
 	if len(choices) > 1 {
 		// Handle multiple choices by combining their content
@@ -566,7 +1019,18 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 		result = combinedContent.String()
 	}
 
+	// the message history keeps the run state references,
+	// the outermost assistant resolves them in the final answer
+	answer := result
+	if state := chatmodel.GetRunState(ctx); state != nil && cfg.resolveRunState {
+		answer = state.Resolve(result)
+		for _, choice := range choices {
+			choice.Content = state.Resolve(choice.Content)
+		}
+	}
+
 	addResultToMessageHistory := func(result string) {
+		result = cfg.spill(ctx, assistantName, result)
 		messageHistory = appendWithSource(messageHistory, llms.MessageFromTextParts(llms.RoleAI, result))
 
 		if cfg.IsGeneric {
@@ -594,7 +1058,7 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 	}
 
 	if optionalOutputType != nil {
-		finalOutput, err := a.OutputParser.Parse(result)
+		finalOutput, err := a.OutputParser.Parse(answer)
 		if err != nil {
 			// add unparsed result to the message history
 			addResultToMessageHistory(result)
@@ -610,15 +1074,27 @@ func (a *Assistant[O]) run(ctx context.Context, orgID string, cfg *Config, input
 			)
 
 			if cfg.CallbackHandler != nil {
-				cfg.CallbackHandler.OnAssistantLLMParseError(ctx, a, input.Input, result, err)
+				cfg.CallbackHandler.OnAssistantLLMParseError(ctx, &LLMParseErrorEvent{
+					Assistant: a,
+					Input:     input.Input,
+					Response:  result,
+					Err:       err,
+				})
 			}
+			cfg.publish(ctx, a, &events.Event{Type: events.ParseError, Model: modelName, Error: err.Error()})
 
 			return resp, messageHistory, err
 		}
+		if setter, ok := (any)(finalOutput).(chatmodel.ICitationsResult); ok {
+			if citations := resp.Citations(); len(citations) > 0 {
+				setter.SetCitations(citations)
+			}
+		}
 		*optionalOutputType = *finalOutput
 
-		if prov, ok := (any)(finalOutput).(chatmodel.ContentProvider); ok {
-			// add parsed result to the message history
+		if prov, ok := (any)(finalOutput).(chatmodel.ContentProvider); ok && answer == result {
+			// add parsed result to the message history,
+			// unless it contains the resolved run state values
 			result = prov.GetContent()
 		}
 	}
@@ -647,9 +1123,12 @@ func (a *Assistant[O]) executeToolCalls(ctx context.Context, orgID string, cfg *
 		response string
 		err      error
 		index    int // Index in the original toolCalls slice
+		// cancelled is true if the call was aborted
+		cancelled bool
 	}
 
 	var toolCalls []llms.ToolCall
+	fetchTool := cfg.fetchArtifactTool()
 
 	// Collect all tool calls first and add them to message history
 	for _, choice := range resp.Choices {
@@ -681,7 +1160,16 @@ func (a *Assistant[O]) executeToolCalls(ctx context.Context, orgID string, cfg *
 		}
 
 		toolCalls = append(toolCalls, choiceToolCalls...)
-		assistantResponse := llms.MessageFromToolCalls(llms.RoleAI, choiceToolCalls...).WithSource(&llms.MessageSource{
+		assistantResponse := llms.MessageFromToolCalls(llms.RoleAI, choiceToolCalls...)
+		if len(choice.Thinking) > 0 {
+			// the thinking blocks must precede the tool calls
+			parts := make([]llms.ContentPart, 0, len(choice.Thinking)+len(assistantResponse.Parts))
+			for _, t := range choice.Thinking {
+				parts = append(parts, t)
+			}
+			assistantResponse.Parts = append(parts, assistantResponse.Parts...)
+		}
+		assistantResponse = assistantResponse.WithSource(&llms.MessageSource{
 			Name:     a.name,
 			RunID:    runID,
 			ActionID: actionID,
@@ -698,118 +1186,390 @@ func (a *Assistant[O]) executeToolCalls(ctx context.Context, orgID string, cfg *
 		return executedCount, notFoundCount, messageHistory, nil
 	}
 
-	// Channel to collect results - buffered to prevent deadlock
-	resultChan := make(chan toolCallResult, len(toolCalls))
+	batch := newToolBatchTracker(ctx, a, cfg, toolCalls)
+	batch.start()
+
+	// toolCtx is cancelled when the caller cancels the context,
+	// or a tool fails with chatmodel.ErrToolFatal, to abort the remaining tool calls.
+	toolCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// The results are protected by reportLock,
+	// each call is settled once: by its goroutine, or as cancelled when the calls are aborted.
+	// The callbacks are called after reportLock is released,
+	// so a callback blocked by the caller does not delay the cancellation.
+	var reportLock sync.Mutex
+	results := make([]toolCallResult, len(toolCalls))
+	settled := make([]bool, len(toolCalls))
+	// usage of the nested assistants, added to resp.Usage after the calls are settled
+	usage := make([]*llms.UsageStats, len(toolCalls))
+	var fatalErr error
+	cancelledCount := 0
+
+	settle := func(result toolCallResult) {
+		settled[result.index] = true
+		results[result.index] = result
+	}
+
+	// cancelCall settles the call as cancelled, reportLock must be held,
+	// and the returned report must be called after reportLock is released
+	cancelCall := func(index int, partial string) (report func()) {
+		tc := toolCalls[index]
+		cause := context.Cause(toolCtx)
+		cancelledCount++
+
+		response := fmt.Sprintf("Tool call cancelled: %s", cause.Error())
+		if partial != "" {
+			response += "\nPartial result:\n" + partial
+		}
+		settle(toolCallResult{
+			toolCall:  tc,
+			response:  response,
+			cancelled: true,
+			index:     index,
+		})
+
+		return func() {
+			toolName := tc.GetFunctionCallName()
+			metricskey.StatsToolCallsCancelled.IncrCounter(1, toolName, cfg.Model, orgID)
+			if rc, ok := cfg.CallbackHandler.(RecoveryCallback); ok {
+				rc.OnToolCancelled(ctx, &ToolCancelledEvent{
+					Assistant: a,
+					Tool:      toolName,
+					Input:     tc.GetFunctionCallArguments(),
+					Partial:   partial,
+					CallID:    tc.ID,
+					Cause:     cause,
+				})
+			}
+			batch.update(index, ToolCallCancelled, cause)
+
+			logger.ContextKV(ctx, xlog.WARNING,
+				"assistant", a.name,
+				"status", "tool_call_cancelled",
+				"tool_call_id", tc.ID,
+				"tool_name", toolName,
+				"cause", cause,
+			)
+		}
+	}
 
 	// Create a wait group to ensure all tool calls complete
 	var wg sync.WaitGroup
 	wg.Add(len(toolCalls))
 
+	// prev is closed when the previous call returns,
+	// to execute the calls one by one in the order requested by the LLM, see WithSequentialToolCalls
+	var prev chan struct{}
+
 	// Launch goroutines for each tool call
 	for i, toolCall := range toolCalls {
-		go func(index int, tc llms.ToolCall) {
+		var wait, next chan struct{}
+		if cfg.SequentialToolCalls {
+			wait, next = prev, make(chan struct{})
+			prev = next
+		}
+		go func(index int, tc llms.ToolCall, wait, next chan struct{}) {
 			defer wg.Done()
+			if next != nil {
+				defer close(next)
+			}
+			if wait != nil {
+				// the previous call may ignore the cancellation
+				select {
+				case <-wait:
+				case <-toolCtx.Done():
+				}
+			}
 			toolName := tc.GetFunctionCallName()
 			toolArgs := tc.GetFunctionCallArguments()
 
 			// use lowercase for the key
 			tool := a.toolsByName[strings.ToLower(toolName)]
+			if tool == nil && fetchTool != nil && strings.EqualFold(toolName, artifact.FetchToolName) {
+				tool = fetchTool
+			}
+
+			// the invalid arguments and the calls denied by the policies
+			// are reported to the LLM without calling the tool
+			var verr error
+			if tool != nil && !cfg.SkipToolArgsValidation {
+				verr = validateToolArgs(toolName, a.toolsParams[strings.ToLower(toolName)], toolArgs)
+			}
+			if tool != nil && verr == nil && toolCtx.Err() == nil {
+				verr = checkToolPolicies(ctx, cfg.ToolPolicies, toolName, toolArgs)
+			}
+
+			reportLock.Lock()
+			if settled[index] {
+				reportLock.Unlock()
+				return
+			}
+			if toolCtx.Err() != nil {
+				report := cancelCall(index, "")
+				reportLock.Unlock()
+				report()
+				return
+			}
+
 			if tool == nil {
 				lock.Lock()
 				notFoundCount++
 				lock.Unlock()
+				availableTools := strings.Join(a.toolsNames, ", ")
+				settle(toolCallResult{
+					toolCall: tc,
+					response: fmt.Sprintf("Tool `%s` not found. Please check the tool name and try again with exact match. Available tools: %s", toolName, availableTools),
+					index:    index,
+				})
+				reportLock.Unlock()
+
 				metricskey.StatsToolCallsNotFound.IncrCounter(1, toolName, cfg.Model, orgID)
 				if cfg.CallbackHandler != nil {
-					cfg.CallbackHandler.OnToolNotFound(ctx, a, toolName)
+					cfg.CallbackHandler.OnToolNotFound(ctx, &ToolNotFoundEvent{Assistant: a, Tool: toolName, CallID: tc.ID})
 				}
+				batch.update(index, ToolCallNotFound, nil)
 
-				availableTools := strings.Join(a.toolsNames, ", ")
 				logger.ContextKV(ctx, xlog.WARNING,
 					"assistant", a.name,
 					"status", "tool_not_found",
 					"tool_name", toolName,
 					"available_tools", availableTools,
 				)
+				return
+			}
 
-				resultChan <- toolCallResult{
+			if verr != nil {
+				settle(toolCallResult{
 					toolCall: tc,
-					response: fmt.Sprintf("Tool `%s` not found. Please check the tool name and try again with exact match. Available tools: %s", toolName, availableTools),
+					err:      verr,
 					index:    index,
+				})
+				reportLock.Unlock()
+
+				metricskey.StatsToolCallsFailed.IncrCounter(1, toolName, cfg.Model, orgID)
+				if cfg.CallbackHandler != nil {
+					cfg.CallbackHandler.OnToolError(ctx, &tools.ToolErrorEvent{
+						Tool:      tool,
+						Assistant: a.Name(),
+						Input:     toolArgs,
+						Err:       verr,
+						CallID:    tc.ID,
+					})
 				}
+				batch.update(index, ToolCallFailed, verr)
 				return
 			}
+			reportLock.Unlock()
 
 			if cfg.CallbackHandler != nil {
-				cfg.CallbackHandler.OnToolStart(ctx, tool, a.Name(), toolArgs)
+				cfg.CallbackHandler.OnToolStart(ctx, &tools.ToolStartEvent{
+					Tool:      tool,
+					Assistant: a.Name(),
+					Input:     toolArgs,
+					CallID:    tc.ID,
+					Attempt:   1,
+				})
 			}
+			batch.update(index, ToolCallRunning, nil)
 
 			started := time.Now()
 
+			// the tools with the side effects get the key of the call,
+			// and the repeated call of the repeated run returns the recorded result
+			callCtx := toolCtx
+			var callKey string
+			if cfg.idempotency != nil {
+				callKey = cfg.idempotency.toolCallKey(a.name, toolName, toolArgs)
+				callCtx = chatmodel.WithIdempotencyKey(toolCtx, callKey)
+			}
+
+			// Propagate the callback handler to the nested assistant so the
+			// whole run tree reports to the same handler (e.g. Scratchpad).
+			// Usage is aggregated into resp.Usage below for the returned
+			// Response, while the handler accumulates usage at the LLM-call
+			// boundary, so there is no double counting.
+			subOptions := options
+			if cfg.CallbackHandler != nil {
+				subOptions = append([]Option{WithCallback(cfg.CallbackHandler)}, subOptions...)
+			}
+			if cfg.EventBus != nil {
+				subOptions = append([]Option{WithEventBus(cfg.EventBus)}, subOptions...)
+			}
+
 			var res string
 			var err error
 			var stats *llms.UsageStats
-			if assistant, ok := tool.(IAssistantTool); ok {
-				// Propagate the callback handler to the nested assistant so the
-				// whole run tree reports to the same handler (e.g. Scratchpad).
-				// Usage is aggregated into resp.Usage below for the returned
-				// Response, while the handler accumulates usage at the LLM-call
-				// boundary, so there is no double counting.
-				subOptions := options
+			deduped := false
+			if callKey != "" && cfg.DedupeStore != nil {
+				res, deduped = cfg.DedupeStore.Get(ctx, callKey)
+				if deduped {
+					metricskey.StatsToolCallsDeduped.IncrCounter(1, toolName, cfg.Model, orgID)
+					logger.ContextKV(ctx, xlog.DEBUG,
+						"assistant", a.name,
+						"status", "tool_call_deduped",
+						"tool_call_id", tc.ID,
+						"tool_name", toolName,
+					)
+				}
+			}
+			attempt := 1
+			for ; !deduped; attempt++ {
+				if assistant, ok := tool.(IAssistantTool); ok {
+					var callStats *llms.UsageStats
+					res, callStats, err = assistant.CallAssistant(callCtx, toolArgs, subOptions...)
+					if stats == nil {
+						stats = callStats
+					} else if callStats != nil {
+						stats.Add(callStats)
+					}
+				} else {
+					res, err = tool.Call(callCtx, toolArgs)
+				}
+				// only the transient errors are retried, the others are reported to the LLM
+				if err == nil || attempt > cfg.MaxToolRetries || toolCtx.Err() != nil ||
+					chatmodel.GetToolErrorCategory(err) != chatmodel.ToolErrorTransient {
+					break
+				}
+
+				reportLock.Lock()
+				aborted := settled[index]
+				reportLock.Unlock()
+				if aborted {
+					break
+				}
+				metricskey.StatsToolCallsRetried.IncrCounter(1, toolName, cfg.Model, orgID)
+				if rc, ok := cfg.CallbackHandler.(RecoveryCallback); ok {
+					rc.OnRetry(ctx, &RetryEvent{
+						Assistant: a,
+						Reason:    RetryReasonToolTransient,
+						Attempt:   attempt,
+						Err:       err,
+						Tool:      toolName,
+						CallID:    tc.ID,
+					})
+				}
 				if cfg.CallbackHandler != nil {
-					subOptions = append([]Option{WithCallback(cfg.CallbackHandler)}, options...)
+					cfg.CallbackHandler.OnToolStart(ctx, &tools.ToolStartEvent{
+						Tool:      tool,
+						Assistant: a.Name(),
+						Input:     toolArgs,
+						CallID:    tc.ID,
+						Attempt:   attempt + 1,
+					})
 				}
-				res, stats, err = assistant.CallAssistant(ctx, toolArgs, subOptions...)
-				if stats != nil {
-					lock.Lock()
-					resp.Usage.Add(stats)
-					lock.Unlock()
+			}
+			latency := time.Since(started)
+			if !deduped {
+				metricskey.ToolLatency.ObserveSince(started, toolName, cfg.Model, orgID)
+				if err == nil && callKey != "" && cfg.DedupeStore != nil {
+					// the side effects are done, even if the calls are aborted
+					cfg.DedupeStore.Set(ctx, callKey, res)
 				}
-			} else {
-				res, err = tool.Call(ctx, toolArgs)
 			}
-			metricskey.PerfToolCall.MeasureSince(started, toolName, cfg.Model, orgID)
+
+			reportLock.Lock()
+			if settled[index] {
+				// the calls are aborted, the result and the usage are dropped
+				reportLock.Unlock()
+				return
+			}
+			usage[index] = stats
+
+			if err != nil && toolCtx.Err() != nil && !errors.Is(err, chatmodel.ErrToolFatal) {
+				report := cancelCall(index, res)
+				reportLock.Unlock()
+				report()
+				return
+			}
 
 			if err != nil {
+				if errors.Is(err, chatmodel.ErrToolFatal) && fatalErr == nil {
+					fatalErr = errors.WithMessagef(err, "failed to call tool %s", toolName)
+					cancel(fatalErr)
+				}
+				settle(toolCallResult{
+					toolCall: tc,
+					err:      err,
+					index:    index,
+				})
+				reportLock.Unlock()
+
 				metricskey.StatsToolCallsFailed.IncrCounter(1, toolName, cfg.Model, orgID)
 
 				if cfg.CallbackHandler != nil {
-					cfg.CallbackHandler.OnToolError(ctx, tool, a.Name(), toolArgs, err)
+					cfg.CallbackHandler.OnToolError(ctx, &tools.ToolErrorEvent{
+						Tool:      tool,
+						Assistant: a.Name(),
+						Input:     toolArgs,
+						Err:       err,
+						CallID:    tc.ID,
+						Attempt:   attempt,
+						Latency:   latency,
+					})
 				}
 
-				if errors.Is(err, chatmodel.ErrFailedUnmarshalInput) {
-					res = llmutils.AddComment("assistant", a.Name(), "error", "Failed to unmarshal input, check the JSON schema and try again.")
-				} else {
-					resultChan <- toolCallResult{
-						toolCall: tc,
-						err:      errors.WithMessagef(err, "failed to call tool %s", toolName),
-						index:    index,
-					}
-					return
-				}
+				batch.update(index, ToolCallFailed, err)
+				return
 			}
+			settle(toolCallResult{
+				toolCall: tc,
+				response: res,
+				index:    index,
+			})
+			reportLock.Unlock()
+
 			metricskey.StatsToolCallsSucceeded.IncrCounter(1, toolName, cfg.Model, orgID)
+			metricskey.ToolOutputSize.Observe(float64(len(res)), toolName, cfg.Model, orgID)
 
 			if cfg.CallbackHandler != nil {
-				cfg.CallbackHandler.OnToolEnd(ctx, tool, a.Name(), toolArgs, res)
+				cfg.CallbackHandler.OnToolEnd(ctx, &tools.ToolEndEvent{
+					Tool:      tool,
+					Assistant: a.Name(),
+					Input:     toolArgs,
+					Output:    res,
+					CallID:    tc.ID,
+					Attempt:   attempt,
+					Latency:   latency,
+				})
 			}
+			batch.update(index, ToolCallSucceeded, nil)
+		}(i, toolCall, wait, next)
+	}
 
-			resultChan <- toolCallResult{
-				toolCall: tc,
-				response: res,
-				index:    index,
+	// Wait for all tool calls to complete, or the cancellation
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-toolCtx.Done():
+		// wait for the tools to return the partial results,
+		// the tools that ignore the context continue in background,
+		// and their results are dropped
+		select {
+		case <-done:
+		case <-time.After(ToolCancelGracePeriod):
+		}
+		var reports []func()
+		reportLock.Lock()
+		for i := range toolCalls {
+			if !settled[i] {
+				reports = append(reports, cancelCall(i, ""))
 			}
-		}(i, toolCall)
+		}
+		reportLock.Unlock()
+		for _, report := range reports {
+			report()
+		}
 	}
+	batch.end()
 
-	// Wait for all tool calls to complete
-	wg.Wait()
-	close(resultChan)
-
-	// Collect results in order using the index
-	results := make([]toolCallResult, len(toolCalls))
-	for result := range resultChan {
-		if result.index >= 0 && result.index < len(results) {
-			results[result.index] = result
+	for _, stats := range usage {
+		if stats != nil {
+			resp.Usage.Add(stats)
 		}
 	}
 
@@ -820,7 +1580,6 @@ func (a *Assistant[O]) executeToolCalls(ctx context.Context, orgID string, cfg *
 			toolCall := toolCalls[i]
 			results[i] = toolCallResult{
 				toolCall: toolCall,
-				response: "Tool call failed: No response received",
 				err:      errors.New("no response received from tool"),
 				index:    i,
 			}
@@ -834,16 +1593,19 @@ func (a *Assistant[O]) executeToolCalls(ctx context.Context, orgID string, cfg *
 	}
 
 	// Process results in the same order as the original tool calls
+	var sanitizeErr error
 	for _, result := range results {
 		var content string
 		if result.err != nil {
-			// Format error as a message for the LLM
-			content = fmt.Sprintf("Tool call failed: %s", result.err.Error())
+			// Format error as a structured message for the LLM
+			toolErr := chatmodel.NewToolError(result.toolCall.GetFunctionCallName(), result.err)
+			content = toolErr.String()
 			// Log the error for monitoring
 			logger.ContextKV(ctx, xlog.WARNING,
 				"assistant", a.name,
 				"status", "tool_call_failed",
 				"tool", result.toolCall.FunctionCall.Name,
+				"category", toolErr.Category,
 				"err", result.err.Error(),
 			)
 		} else {
@@ -852,6 +1614,24 @@ func (a *Assistant[O]) executeToolCalls(ctx context.Context, orgID string, cfg *
 
 		// Create tool call response using the ID from the original tool call
 		toolName := result.toolCall.GetFunctionCallName()
+		rejected := false
+		if result.err == nil && !result.cancelled && cfg.ToolOutputSanitizer != nil {
+			sanitized, err := cfg.ToolOutputSanitizer(ctx, toolName, content)
+			if err != nil {
+				// the response is still recorded to keep the history valid
+				content = chatmodel.NewToolError(toolName, err).String()
+				rejected = true
+				if sanitizeErr == nil {
+					sanitizeErr = errors.WithMessagef(err, "tool %s output rejected", toolName)
+				}
+			} else {
+				content = sanitized
+			}
+		}
+		if result.err == nil && !result.cancelled && !rejected && !strings.EqualFold(toolName, artifact.FetchToolName) {
+			// the artifact content is never spilled again
+			content = cfg.spill(ctx, a.name+"/"+toolName, content)
+		}
 		toolCallResponse := llms.MessageFromToolResponse(llms.RoleTool, llms.ToolCallResponse{
 			ToolCallID: result.toolCall.ID, // Use the ID from the original tool call
 			Name:       toolName,
@@ -879,5 +1659,14 @@ func (a *Assistant[O]) executeToolCalls(ctx context.Context, orgID string, cfg *
 		}
 	}
 
+	if fatalErr != nil {
+		return executedCount, notFoundCount, messageHistory, errors.WithMessagef(fatalErr, "assistant %s", a.name)
+	}
+	if cancelledCount > 0 {
+		return executedCount, notFoundCount, messageHistory, errors.WithMessagef(context.Cause(toolCtx), "assistant %s: tool calls are cancelled", a.name)
+	}
+	if sanitizeErr != nil {
+		return executedCount, notFoundCount, messageHistory, errors.WithMessagef(sanitizeErr, "assistant %s", a.name)
+	}
 	return executedCount, notFoundCount, messageHistory, nil
 }
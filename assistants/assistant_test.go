@@ -10,7 +10,9 @@ import (
 	"github.com/effective-security/gogentic/mocks/mockllms"
 	"github.com/effective-security/gogentic/mocks/mocktools"
 	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
 	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -296,3 +298,107 @@ func Test_Assistant_Run_ToolCallEdgeCases(t *testing.T) {
 	_, err = assistant.Run(ctx, &assistants.CallInput{Input: "input"}, nil)
 	assert.NoError(t, err)
 }
+
+func Test_Assistant_Run_RepairsHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	systemPrompt := prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", []string{})
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(4)
+
+	memstore := store.NewMemoryStore()
+	chatCtx := chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil)
+	ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+
+	// the tool call message was truncated from the stored history
+	err := memstore.Add(ctx,
+		llms.MessageFromToolResponse(llms.RoleTool, llms.ToolCallResponse{ToolCallID: "1", Name: "tool", Content: "result"}),
+		llms.MessageFromTextParts(llms.RoleAI, "previous answer"),
+	)
+	require.NoError(t, err)
+
+	var sent []llms.Message
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			sent = messages
+			return &llms.ContentResponse{
+				Choices: []*llms.ContentChoice{{Content: "answer"}},
+			}, nil
+		}).Times(2)
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM, systemPrompt,
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithMessageStore(memstore),
+		assistants.WithHistoryRepair(llmutils.HistoryRepairDrop),
+	)
+	_, err = ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.NoError(t, err)
+	require.Len(t, sent, 3)
+	assert.Equal(t, llms.RoleSystem, sent[0].Role)
+	assert.Equal(t, llms.RoleAI, sent[1].Role)
+	assert.Equal(t, llms.RoleHuman, sent[2].Role)
+	assert.NoError(t, llmutils.ValidateHistory(sent))
+
+	// stub mode keeps the tool response with a synthetic tool call
+	_, err = ag.Call(ctx, &assistants.CallInput{
+		Input:   "input",
+		Options: []assistants.Option{assistants.WithHistoryRepair(llmutils.HistoryRepairStub)},
+	})
+	require.NoError(t, err)
+	require.Greater(t, len(sent), 3)
+	assert.Equal(t, llms.RoleAI, sent[1].Role)
+	assert.IsType(t, llms.ToolCall{}, sent[1].Parts[0])
+	assert.Equal(t, llms.RoleTool, sent[2].Role)
+	assert.NoError(t, llmutils.ValidateHistory(sent))
+}
+
+func Test_Assistant_Run_Usage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usage := llms.Usage{InputTokens: 100, OutputTokens: 10, CacheReadTokens: 50, TotalTokens: 110}
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{
+					{ID: "call_1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "search_tool", Arguments: `{}`}},
+				},
+				Usage: usage,
+			}},
+		}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "sunny", Usage: usage}},
+		}, nil),
+	)
+
+	tool := newCancelTool(ctrl, "search_tool", func(_ context.Context, _ string) (string, error) {
+		return "sunny", nil
+	})
+
+	var costModels []string
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithCostFunc(func(model string, usage *llms.Usage) float64 {
+			costModels = append(costModels, model)
+			return float64(usage.TotalTokens) / 1000
+		}),
+	).WithTools(tool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "weather?"})
+	require.NoError(t, err)
+
+	assert.Equal(t, llms.Usage{InputTokens: 200, OutputTokens: 20, CacheReadTokens: 100, TotalTokens: 220}, resp.Usage.Usage)
+	assert.EqualValues(t, 2, resp.Usage.LlmCallCount)
+	assert.EqualValues(t, 1, resp.Usage.ToolCallCount)
+	assert.EqualValues(t, 1, resp.Usage.ToolRounds)
+	assert.InDelta(t, 0.22, resp.Usage.Cost, 1e-9)
+	assert.Positive(t, resp.Usage.Duration)
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o"}, costModels)
+}
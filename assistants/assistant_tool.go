@@ -26,6 +26,7 @@ type AssistantTool[I chatmodel.ContentProvider, O chatmodel.ContentProvider] str
 	name        string
 	description string
 	funcParams  *jsonschema.Schema
+	budget      *DelegationBudget
 }
 
 func NewAssistantTool[I chatmodel.ContentProvider, O chatmodel.ContentProvider](assistant TypeableAssistant[O]) (TypeableAssistantTool[I, O], error) {
@@ -54,6 +55,14 @@ func (a *AssistantTool[I, O]) WithDescription(description string) *AssistantTool
 	a.description = description
 	return a
 }
+
+// WithBudget sets the budget of the delegate assistant,
+// enforced across its own LLM calls and the nested assistant tools.
+func (a *AssistantTool[I, O]) WithBudget(budget *DelegationBudget) *AssistantTool[I, O] {
+	a.budget = budget
+	return a
+}
+
 func (t *AssistantTool[I, O]) Name() string {
 	return t.name
 }
@@ -84,11 +93,17 @@ func (t *AssistantTool[I, O]) CallAssistant(ctx context.Context, input string, o
 		}
 	}
 
-	var res O
-	resp, err := t.assistant.Run(ctx, &CallInput{
-		Input:   tin.GetContent(),
-		Options: options,
-	}, &res)
+	var (
+		res  O
+		resp *Response
+	)
+	ctx, err := withDelegation(ctx, t.name, t.budget)
+	if err == nil {
+		resp, err = t.assistant.Run(ctx, &CallInput{
+			Input:   tin.GetContent(),
+			Options: options,
+		}, &res)
+	}
 	if err != nil {
 		if val, ok := (any)(&res).(chatmodel.IBaseResult); ok {
 			val.SetClarification(llmutils.AddComment("tool", t.Name(), "error", err.Error()))
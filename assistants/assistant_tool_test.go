@@ -779,7 +779,12 @@ func Test_Assistant_ToolCallWithScratchpad(t *testing.T) {
 	// The scratchpad accumulates usage at the LLM-call boundary across the whole
 	// run tree, so it must match the aggregated top-level Response.Usage exactly,
 	// without double counting the nested assistant.
-	assert.Equal(t, apiResp.Usage, stats.Usage)
+	// The tool calls and the wall time are aggregated by the assistant only.
+	assert.Equal(t, apiResp.Usage.Usage, stats.Usage.Usage)
+	assert.Equal(t, apiResp.Usage.LlmCallCount, stats.Usage.LlmCallCount)
+	assert.Equal(t, apiResp.Usage.BytesOut, stats.Usage.BytesOut)
+	assert.Equal(t, apiResp.Usage.BytesIn, stats.Usage.BytesIn)
+	assert.Equal(t, 1, int(apiResp.Usage.ToolCallCount))
 
 	// The inner-assistant tool was called exactly once and succeeded.
 	assert.Equal(t, 1, int(stats.ToolsCalls))
@@ -791,3 +796,129 @@ func Test_Assistant_ToolCallWithScratchpad(t *testing.T) {
 	assert.Equal(t, 2, int(stats.AssistantCallsSucceeded))
 	assert.Equal(t, 0, int(stats.AssistantCallsFailed))
 }
+
+func Test_AssistantTool_Budget(t *testing.T) {
+	tcases := []struct {
+		name   string
+		budget *assistants.DelegationBudget
+		expErr string
+	}{
+		{
+			name:   "tokens",
+			budget: &assistants.DelegationBudget{MaxTokens: 100},
+			expErr: "assistant Generic Assistant: assistant tool looper: used 120 of 100 tokens: budget exceeded",
+		},
+		{
+			name: "cost",
+			budget: &assistants.DelegationBudget{
+				MaxCost: 0.015,
+				CostFunc: func(model string, usage *llms.Usage) float64 {
+					assert.Equal(t, "gpt-4o", model)
+					return float64(usage.TotalTokens) / 6000
+				},
+			},
+			expErr: "assistant Generic Assistant: assistant tool looper: used 0.0200 of 0.0150 cost: budget exceeded",
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockLLM := mockllms.NewMockModel(ctrl)
+			mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+			mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+			// the delegate never stops calling the tool
+			mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+				&llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{
+						ToolCalls: []llms.ToolCall{{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "echo", Arguments: "{}"}}},
+						Usage:     llms.Usage{TotalTokens: 60},
+					}},
+				}, nil,
+			).Times(2)
+
+			echo := mocktools.NewMockTool[any, any](ctrl)
+			echo.EXPECT().Name().Return("echo").Times(1)
+			echo.EXPECT().Description().Return("echo").Times(1)
+			echo.EXPECT().Parameters().Return(nil).Times(1)
+			echo.EXPECT().Call(gomock.Any(), gomock.Any()).Return("again", nil).Times(2)
+
+			delegate := assistants.NewAssistant[testOutput](mockLLM, prompts.NewPromptTemplate("loop", nil)).WithTools(echo)
+			tool, err := assistants.NewAssistantTool[testInput](delegate)
+			require.NoError(t, err)
+			tool.(*assistants.AssistantTool[testInput, testOutput]).WithName("looper").WithBudget(tc.budget)
+
+			ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+			_, _, err = tool.CallAssistant(ctx, `{"content":"loop"}`)
+			assert.ErrorIs(t, err, assistants.ErrBudgetExceeded)
+			assert.EqualError(t, err, tc.expErr)
+		})
+	}
+}
+
+func Test_AssistantTool_BudgetInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	delegate := assistants.NewAssistant[testOutput](mockLLM, prompts.NewPromptTemplate("delegate", nil))
+	tool, err := assistants.NewAssistantTool[testInput](delegate)
+	require.NoError(t, err)
+	tool.(*assistants.AssistantTool[testInput, testOutput]).WithName("delegate").WithBudget(&assistants.DelegationBudget{MaxCost: 1})
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	_, _, err = tool.CallAssistant(ctx, `{"content":"go"}`)
+	assert.EqualError(t, err, "assistant tool delegate: invalid budget: MaxCost requires CostFunc")
+
+	assert.NoError(t, (&assistants.DelegationBudget{MaxTokens: 100}).Validate())
+	assert.EqualError(t, (&assistants.DelegationBudget{MaxDepth: -1}).Validate(), "limits must not be negative")
+}
+
+func Test_AssistantTool_BudgetDepth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nestedLLM := mockllms.NewMockModel(ctrl)
+	nestedLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	nestedLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	nested := assistants.NewAssistant[testOutput](nestedLLM, prompts.NewPromptTemplate("nested", nil)).WithName("nested")
+	nestedTool, err := assistants.NewAssistantTool[testInput](nested)
+	require.NoError(t, err)
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+			&llms.ContentResponse{
+				Choices: []*llms.ContentChoice{{
+					ToolCalls: []llms.ToolCall{{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "nested", Arguments: `{"content":"go deeper"}`}}},
+				}},
+			}, nil,
+		),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				last := messages[len(messages)-1].Parts[0].(llms.ToolCallResponse)
+				assert.Contains(t, last.Content, "assistant tool nested: delegation depth of delegate exceeds 1: budget exceeded")
+				return &llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{Content: `{"Content":"done"}`}},
+				}, nil
+			}),
+	)
+
+	delegate := assistants.NewAssistant[testOutput](mockLLM, prompts.NewPromptTemplate("delegate", nil)).WithTools(nestedTool)
+	tool, err := assistants.NewAssistantTool[testInput](delegate)
+	require.NoError(t, err)
+	tool.(*assistants.AssistantTool[testInput, testOutput]).WithName("delegate").WithBudget(&assistants.DelegationBudget{MaxDepth: 1})
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	assert.Equal(t, 0, assistants.DelegationDepth(ctx))
+	res, _, err := tool.CallAssistant(ctx, `{"content":"go"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "done", res)
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/effective-security/gogentic/chatmodel"
 	"github.com/effective-security/gogentic/mcp"
 	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/prompts"
 	"github.com/effective-security/gogentic/skills"
 	"github.com/effective-security/gogentic/tools"
 	"github.com/effective-security/x/format"
@@ -51,8 +53,30 @@ type Response struct {
 	Choices []*llms.ContentChoice
 	// Messages is the messages that are created from the run and added to the Message History Store.
 	Messages []llms.Message
-	// Usage is the usage stats for the response.
+	// Usage is the usage stats of the run, aggregated over the LLM calls,
+	// including the LLM calls of the nested assistants, see WithCostFunc for the cost estimation.
 	Usage llms.UsageStats
+	// PromptVariant is the system prompt variant used for the run, see WithPromptVariant.
+	PromptVariant *prompts.Variant
+	// PreparedCall is the assembled LLM call in the dry-run mode, see WithDryRun.
+	PreparedCall *PreparedCall
+	// DeadlineReached is true when the run deadline was nearly exhausted,
+	// and the final answer was requested without the tools, see WithDeadline.
+	DeadlineReached bool
+}
+
+// Citations returns the citations from all choices, without duplicated URLs.
+func (r *Response) Citations() []llms.Citation {
+	if r == nil {
+		return nil
+	}
+	lists := make([][]llms.Citation, 0, len(r.Choices))
+	for _, c := range r.Choices {
+		if c != nil {
+			lists = append(lists, c.Citations)
+		}
+	}
+	return llms.MergeCitations(lists...)
 }
 
 type CallInput struct {
@@ -64,6 +88,14 @@ type CallInput struct {
 	Options []Option
 	// Messages is additional content to be sent to the LLM.
 	Messages []llms.Message
+	// Attachments are the images, files and URLs added to the user message,
+	// see Attachment for the conversion per the provider capabilities.
+	Attachments []*Attachment
+	// IdempotencyKey is the unique key of the run, such as the webhook delivery ID.
+	// The keys of the LLM calls are sent to the providers that support the idempotent requests,
+	// and the tools get the derived key of the call with chatmodel.GetIdempotencyKey,
+	// the repeated run with the same key returns the recorded tool results, see WithDedupeStore.
+	IdempotencyKey string
 	// Args is additional arguments to be passed to the assistant on run.
 	// This can be used by assistants that implement IAssistant and have a custom implementation of Run.
 	Args map[string]string
@@ -104,17 +136,120 @@ type TypeableAssistant[O chatmodel.ContentProvider] interface {
 	Run(ctx context.Context, input *CallInput, optionalOutputType *O) (*Response, error)
 }
 
+// Callback receives the events of the assistant run, and of the tool calls.
+// The events are shared with the other callbacks, and must not be modified.
+// The new fields may be added to the events, use FromLegacyCallback
+// for the implementations of the previous interface with the positional arguments.
 type Callback interface {
 	tools.Callback
-	OnAssistantStart(ctx context.Context, a IAssistant, input string)
-	OnAssistantEnd(ctx context.Context, a IAssistant, input string, resp *Response, messageHistory llms.Messages)
-	OnAssistantError(ctx context.Context, a IAssistant, input string, err error, messageHistory llms.Messages)
-	OnAssistantLLMCallStart(ctx context.Context, a IAssistant, llm llms.Model, payload llms.Messages)
-	OnAssistantLLMCallEnd(ctx context.Context, a IAssistant, llm llms.Model, resp *llms.ContentResponse)
-	OnAssistantLLMParseError(ctx context.Context, a IAssistant, input string, response string, err error)
-	OnToolNotFound(ctx context.Context, a IAssistant, tool string)
+	OnAssistantStart(ctx context.Context, e *AssistantStartEvent)
+	OnAssistantEnd(ctx context.Context, e *AssistantEndEvent)
+	OnAssistantError(ctx context.Context, e *AssistantErrorEvent)
+	OnAssistantLLMCallStart(ctx context.Context, e *LLMCallStartEvent)
+	OnAssistantLLMCallEnd(ctx context.Context, e *LLMCallEndEvent)
+	OnAssistantLLMParseError(ctx context.Context, e *LLMParseErrorEvent)
+	OnToolNotFound(ctx context.Context, e *ToolNotFoundEvent)
+}
+
+// AssistantStartEvent is reported when the run of the assistant starts.
+type AssistantStartEvent struct {
+	Assistant IAssistant
+	Input     string
+}
+
+// AssistantEndEvent is reported when the run of the assistant succeeded.
+type AssistantEndEvent struct {
+	Assistant IAssistant
+	Input     string
+	Response  *Response
+	// Messages is the message history of the run.
+	Messages llms.Messages
+	// Latency is the duration of the run, including the retries.
+	Latency time.Duration
+}
+
+// AssistantErrorEvent is reported when the run of the assistant failed.
+type AssistantErrorEvent struct {
+	Assistant IAssistant
+	Input     string
+	Err       error
+	// Messages is the message history of the run.
+	Messages llms.Messages
+	// Latency is the duration of the run, including the retries.
+	Latency time.Duration
+}
+
+// LLMCallStartEvent is reported before the LLM call.
+type LLMCallStartEvent struct {
+	Assistant IAssistant
+	LLM       llms.Model
+	// Messages is the payload of the call.
+	Messages llms.Messages
+	// Round is the LLM round of the run, starting from 1.
+	Round int
+}
+
+// LLMCallEndEvent is reported when the LLM call succeeded.
+type LLMCallEndEvent struct {
+	Assistant IAssistant
+	// LLM is the model of the response, or the fallback model, see WithFallbackModels.
+	LLM      llms.Model
+	Response *llms.ContentResponse
+	// Round is the LLM round of the run, starting from 1.
+	Round   int
+	Latency time.Duration
+}
+
+// LLMParseErrorEvent is reported when the LLM response failed to parse to the output type.
+type LLMParseErrorEvent struct {
+	Assistant IAssistant
+	Input     string
+	// Response is the content of the LLM response.
+	Response string
+	Err      error
+}
+
+// ToolNotFoundEvent is reported when the LLM called the tool that is not found.
+type ToolNotFoundEvent struct {
+	Assistant IAssistant
+	Tool      string
+	// CallID is the ID of the tool call requested by the LLM.
+	CallID string
 }
 
+// ToolCallDeltaCallback is an optional interface of the Callback,
+// to receive the tool call deltas while the LLM response is streamed,
+// so the UI can show which tool the model is about to invoke before the stream finishes.
+// It is called only when the streaming is enabled, see WithStreamingFunc.
+type ToolCallDeltaCallback interface {
+	OnToolCallDelta(ctx context.Context, e *ToolCallDeltaEvent)
+}
+
+// ToolCallDeltaEvent is reported for the tool call delta of the streamed LLM response.
+type ToolCallDeltaEvent struct {
+	Assistant IAssistant
+	Delta     llms.ToolCallDelta
+}
+
+// RetryReason is the reason of the LLM call retry.
+type RetryReason string
+
+const (
+	// RetryReasonEmptyResponse is the retry of the LLM response with no choices.
+	RetryReasonEmptyResponse RetryReason = "empty_response"
+	// RetryReasonParseError is the retry of the run, when the LLM response failed to parse to the output type.
+	RetryReasonParseError RetryReason = "parse_error"
+	// RetryReasonToolNotFound is the failure, when the LLM calls the tools that are not found, see WithMaxNotFoundTools.
+	RetryReasonToolNotFound RetryReason = "tool_not_found"
+	// RetryReasonToolCallsLimit is the failure, when the tool calls limit is exceeded, see WithMaxToolCalls.
+	RetryReasonToolCallsLimit RetryReason = "tool_calls_limit"
+	// RetryReasonToolTransient is the retry of the tool call failed with the transient error, see WithMaxToolRetries.
+	RetryReasonToolTransient RetryReason = "tool_transient"
+	// RetryReasonContextLength is the retry of the LLM call with the reduced message history,
+	// when the provider fails with llms.ErrContextLengthExceeded, see WithContextRecovery.
+	RetryReasonContextLength RetryReason = "context_length"
+)
+
 // IMCPAssistant is an interface that extends IAssistant to include functionality for
 // registering the assistant with an MCP server.
 // The RegisterMCP method allows the assistant to be registered with a given
@@ -3,13 +3,13 @@ package assistants_test
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/errors"
-	tavilyModels "github.com/diverged/tavily-go/models"
 	"github.com/effective-security/gogentic/assistants"
 	"github.com/effective-security/gogentic/callbacks"
 	"github.com/effective-security/gogentic/chatmodel"
@@ -52,7 +52,7 @@ func Test_Assistant_Defined(t *testing.T) {
 		}
 		if strings.Contains(input, "weather") {
 			return llmutils.ToJSON(tavily.SearchResult{
-				Results: []tavilyModels.SearchResult{
+				Results: []tavily.Result{
 					{
 						Title: "Weather in Europe",
 						URL:   "https://weather.com/europe",
@@ -67,7 +67,7 @@ func Test_Assistant_Defined(t *testing.T) {
 		}
 		if strings.Contains(input, "capital") {
 			return llmutils.ToJSON(tavily.SearchResult{
-				Results: []tavilyModels.SearchResult{
+				Results: []tavily.Result{
 					{
 						Title: "Capital of France",
 						URL:   "https://france.com/capital",
@@ -81,7 +81,7 @@ func Test_Assistant_Defined(t *testing.T) {
 			}), nil
 		}
 		return llmutils.ToJSON(tavily.SearchResult{
-			Results: []tavilyModels.SearchResult{
+			Results: []tavily.Result{
 				{
 					Title: "Search result 1",
 					URL:   "https://example.com/1",
@@ -246,7 +246,7 @@ func Test_Assistant_Chat(t *testing.T) {
 		}
 		if strings.Contains(input, "weather") {
 			return llmutils.ToJSON(tavily.SearchResult{
-				Results: []tavilyModels.SearchResult{
+				Results: []tavily.Result{
 					{
 						Title: "Weather in Europe",
 						URL:   "https://weather.com/europe",
@@ -261,7 +261,7 @@ func Test_Assistant_Chat(t *testing.T) {
 		}
 		if strings.Contains(input, "capital") {
 			return llmutils.ToJSON(tavily.SearchResult{
-				Results: []tavilyModels.SearchResult{
+				Results: []tavily.Result{
 					{
 						Title: "Capital of France",
 						URL:   "https://france.com/capital",
@@ -275,7 +275,7 @@ func Test_Assistant_Chat(t *testing.T) {
 			}), nil
 		}
 		return llmutils.ToJSON(tavily.SearchResult{
-			Results: []tavilyModels.SearchResult{
+			Results: []tavily.Result{
 				{
 					Title: "Search result 1",
 					URL:   "https://example.com/1",
@@ -414,129 +414,131 @@ AI: The weather in Europe is generally mild.
 }
 
 func Test_Assistant_FailtedParseToolInput(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
 	systemPrompt := prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", []string{})
 
 	t.Setenv("TAVILY_API_KEY", "test-key")
 	tavilyTool, err := tavily.New()
 	require.NoError(t, err)
 
-	// This will simulate a tool that fails to unmarshal input the first time, then succeeds
-	callCount := 0
-	mockTool := mocktools.NewMockTool[tavily.SearchRequest, tavily.SearchResult](ctrl)
-	mockTool.EXPECT().Name().Return(tavilyTool.Name()).AnyTimes()
-	mockTool.EXPECT().Description().Return(tavilyTool.Description()).AnyTimes()
-	mockTool.EXPECT().Parameters().Return(tavilyTool.Parameters()).AnyTimes()
-	mockTool.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, input string) (string, error) {
-		callCount++
-		if callCount == 1 {
-			// Simulate failed unmarshal
-			return "", chatmodel.ErrFailedUnmarshalInput
-		}
-		// On retry, succeed
-		return llmutils.ToJSON(tavily.SearchResult{
-			Results: []tavilyModels.SearchResult{
-				{
-					Title: "Weather in Europe",
-					URL:   "https://weather.com/europe",
-				},
-			},
-			Answer: "The weather in Europe is generally mild.",
-		}), nil
-	}).Times(2)
+	tcases := []struct {
+		name string
+		// args of the first tool call
+		args string
+		// toolFails is true if the tool fails to unmarshal the first input
+		toolFails bool
+		calls     int
+	}{
+		// The invalid input is rejected before dispatch, so the tool is called only once with the valid input
+		{name: "schema", args: `not a json`, calls: 1},
+		// The input passes the schema validation, but the tool fails to unmarshal it the first time, then succeeds
+		{name: "tool", args: `{"Query":"weather"}`, toolFails: true, calls: 2},
+	}
 
-	// LLM mock: first returns a tool call with invalid input, then with valid input, then the final answer
-	llmCall := 0
-	mockLLM := mockllms.NewMockModel(ctrl)
-	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).AnyTimes()
-	mockLLM.EXPECT().GetName().Return("gpt-4o").AnyTimes()
-	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
-		func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
-			llmCall++
-			if llmCall == 1 {
-				// First, LLM issues a tool call with invalid input
-				return &llms.ContentResponse{
-					Choices: []*llms.ContentChoice{
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			callCount := 0
+			mockTool := mocktools.NewMockTool[tavily.SearchRequest, tavily.SearchResult](ctrl)
+			// the name is resolved once more for each dispatched call
+			mockTool.EXPECT().Name().Return(tavilyTool.Name()).Times(3 + tc.calls)
+			mockTool.EXPECT().Description().Return(tavilyTool.Description()).Times(1)
+			mockTool.EXPECT().Parameters().Return(tavilyTool.Parameters()).Times(1)
+			mockTool.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, input string) (string, error) {
+				callCount++
+				if tc.toolFails && callCount == 1 {
+					// Simulate failed unmarshal
+					return "", chatmodel.ErrFailedUnmarshalInput
+				}
+				return llmutils.ToJSON(tavily.SearchResult{
+					Results: []tavily.Result{
 						{
-							ToolCalls: []llms.ToolCall{
-								{
-									ID:   "tavily-search",
-									Type: "function",
-									FunctionCall: &llms.FunctionCall{
-										Name:      tavilyTool.Name(),
-										Arguments: `not a json`,
-									},
-								},
-							},
+							Title: "Weather in Europe",
+							URL:   "https://weather.com/europe",
 						},
 					},
-				}, nil
-			}
-			if llmCall == 2 {
-				// After error, LLM retries with valid JSON input
-				return &llms.ContentResponse{
-					Choices: []*llms.ContentChoice{
-						{
-							ToolCalls: []llms.ToolCall{
+					Answer: "The weather in Europe is generally mild.",
+				}), nil
+			}).Times(tc.calls)
+
+			// LLM mock: first returns a tool call with the failing input, then with valid input, then the final answer
+			llmCall := 0
+			mockLLM := mockllms.NewMockModel(ctrl)
+			mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+			mockLLM.EXPECT().GetName().Return("gpt-4o").Times(8)
+			mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+					llmCall++
+					if llmCall <= 2 {
+						args := tc.args
+						if llmCall == 2 {
+							// After error, LLM retries with valid JSON input
+							args = `{"Query":"weather in Europe"}`
+						}
+						return &llms.ContentResponse{
+							Choices: []*llms.ContentChoice{
 								{
-									ID:   "tavily-search",
-									Type: "function",
-									FunctionCall: &llms.FunctionCall{
-										Name:      tavilyTool.Name(),
-										Arguments: `{"Query":"weather in Europe"}`,
+									ToolCalls: []llms.ToolCall{
+										{
+											ID:   "tavily-search",
+											Type: "function",
+											FunctionCall: &llms.FunctionCall{
+												Name:      tavilyTool.Name(),
+												Arguments: args,
+											},
+										},
 									},
 								},
 							},
+						}, nil
+					}
+					// Final, LLM returns the answer
+					return &llms.ContentResponse{
+						Choices: []*llms.ContentChoice{
+							{
+								Content: `{"Content":"The weather in Europe is generally mild."}`,
+							},
 						},
-					},
-				}, nil
+					}, nil
+				}).Times(3)
+
+			memstore := store.NewMemoryStore()
+			var buf strings.Builder
+			acfg := []assistants.Option{
+				assistants.WithMode(encoding.ModeJSONSchemaStrict),
+				assistants.WithMessageStore(memstore),
+				assistants.WithCallback(callbacks.NewPrinter(&buf, callbacks.ModeVerbose)),
 			}
-			// Final, LLM returns the answer
-			return &llms.ContentResponse{
-				Choices: []*llms.ContentChoice{
-					{
-						Content: `{"Content":"The weather in Europe is generally mild."}`,
-					},
-				},
-			}, nil
-		}).Times(3)
-
-	memstore := store.NewMemoryStore()
-	var buf strings.Builder
-	acfg := []assistants.Option{
-		assistants.WithMode(encoding.ModeJSONSchemaStrict),
-		assistants.WithMessageStore(memstore),
-		assistants.WithCallback(callbacks.NewPrinter(&buf, callbacks.ModeVerbose)),
-	}
 
-	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM, systemPrompt, acfg...).
-		WithTools(mockTool)
+			ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM, systemPrompt, acfg...).
+				WithTools(mockTool)
 
-	chatCtx := chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil)
-	ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+			chatCtx := chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil)
+			ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
 
-	var output chatmodel.OutputResult
-	req := &assistants.CallInput{
-		Input: "Search for weather in Europe",
+			var output chatmodel.OutputResult
+			req := &assistants.CallInput{
+				Input: "Search for weather in Europe",
+			}
+			apiResp, err := ag.Run(ctx, req, &output)
+			require.NoError(t, err)
+			assert.NotEmpty(t, output.Content)
+			assert.NotEmpty(t, apiResp.Choices)
+			assert.Contains(t, output.Content, "weather")
+
+			history := memstore.Messages(ctx)
+			assert.NotEmpty(t, history)
+			buf.Reset()
+			llmutils.PrintMessages(&buf, history)
+			chat := buf.String()
+			// The final answer should be present
+			assert.Contains(t, chat, "The weather in Europe is generally mild.")
+
+			// // The error message should be present in the chat history
+			// assert.Contains(t, chat, "Failed to unmarshal input, check the JSON schema and try again.")
+		})
 	}
-	apiResp, err := ag.Run(ctx, req, &output)
-	require.NoError(t, err)
-	assert.NotEmpty(t, output.Content)
-	assert.NotEmpty(t, apiResp.Choices)
-	assert.Contains(t, output.Content, "weather")
-
-	history := memstore.Messages(ctx)
-	assert.NotEmpty(t, history)
-	buf.Reset()
-	llmutils.PrintMessages(&buf, history)
-	chat := buf.String()
-	// The final answer should be present
-	assert.Contains(t, chat, "The weather in Europe is generally mild.")
-
-	// // The error message should be present in the chat history
-	// assert.Contains(t, chat, "Failed to unmarshal input, check the JSON schema and try again.")
 }
 
 func Test_Assistant_ParallelToolCalls(t *testing.T) {
@@ -738,23 +740,19 @@ func Test_Assistant_MultipleParallelToolCalls(t *testing.T) {
 
 	// Create a mock callback to track tool calls
 	mockCallback := mockassitants.NewMockCallback(ctrl)
-	mockCallback.EXPECT().OnToolStart(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
-	mockCallback.EXPECT().OnToolEnd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
-		func(ctx context.Context, tool tools.ITool, assistantName, input string, output string) {
+	mockCallback.EXPECT().OnToolStart(gomock.Any(), gomock.Any()).Times(7)
+	mockCallback.EXPECT().OnToolEnd(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, e *tools.ToolEndEvent) {
 			mu.Lock()
-			processedToolCalls[strings.ToLower(tool.Name())] = true
-			toolCallResults[strings.ToLower(tool.Name())] = output
+			processedToolCalls[strings.ToLower(e.Tool.Name())] = true
+			toolCallResults[strings.ToLower(e.Tool.Name())] = e.Output
 			mu.Unlock()
 		},
 	).AnyTimes()
-	mockCallback.EXPECT().OnToolError(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
-	mockCallback.EXPECT().OnAssistantStart(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
-	mockCallback.EXPECT().OnAssistantEnd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
-	mockCallback.EXPECT().OnAssistantError(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
-	mockCallback.EXPECT().OnAssistantLLMCallStart(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
-	mockCallback.EXPECT().OnAssistantLLMCallEnd(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
-	mockCallback.EXPECT().OnAssistantLLMParseError(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
-	mockCallback.EXPECT().OnToolNotFound(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	mockCallback.EXPECT().OnAssistantStart(gomock.Any(), gomock.Any()).Times(1)
+	mockCallback.EXPECT().OnAssistantEnd(gomock.Any(), gomock.Any()).Times(1)
+	mockCallback.EXPECT().OnAssistantLLMCallStart(gomock.Any(), gomock.Any()).Times(2)
+	mockCallback.EXPECT().OnAssistantLLMCallEnd(gomock.Any(), gomock.Any()).Times(2)
 
 	// Create assistant with all tools and callback
 	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM, systemPrompt, assistants.WithCallback(mockCallback)).
@@ -965,3 +963,179 @@ I'm here to help you.
 `
 	assert.Equal(t, exp, cr.String())
 }
+
+func Test_Assistant_Citations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderPerplexity).Times(1)
+	mockLLM.EXPECT().GetName().Return("sonar").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				Content:   `{"content":"Go 1.24 is released [1]."}`,
+				Citations: []llms.Citation{{URL: "https://go.dev/blog", Title: "Go Blog"}, {URL: "https://go.dev/doc"}},
+			},
+		},
+	}, nil).Times(1)
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM, prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil))
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var output chatmodel.OutputResult
+	resp, err := ag.Run(ctx, &assistants.CallInput{Input: "what is new in Go?"}, &output)
+	require.NoError(t, err)
+	assert.Equal(t, "Go 1.24 is released [1].", output.Content)
+	assert.Equal(t, []llms.Citation{{URL: "https://go.dev/blog", Title: "Go Blog"}, {URL: "https://go.dev/doc"}}, output.Citations)
+	assert.Equal(t, output.Citations, resp.Citations())
+}
+
+func Test_Assistant_ThinkingToolCalls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	thinking := llms.ThinkingContent{Thinking: "Need the weather.", Signature: "sig"}
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderAnthropic).Times(2)
+	mockLLM.EXPECT().GetName().Return("claude-sonnet-4-5").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+				var opts llms.CallOptions
+				for _, opt := range options {
+					opt(&opts)
+				}
+				assert.Equal(t, 2000, opts.ThinkingBudget)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+					Thinking:  []llms.ThinkingContent{thinking},
+					ToolCalls: []llms.ToolCall{{ID: "call_1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "weather", Arguments: "{}"}}},
+				}}}, nil
+			}),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				var ai *llms.Message
+				for i := range messages {
+					if messages[i].Role == llms.RoleAI {
+						ai = &messages[i]
+					}
+				}
+				require.NotNil(t, ai)
+				require.Len(t, ai.Parts, 2)
+				assert.Equal(t, thinking, ai.Parts[0])
+				assert.IsType(t, llms.ToolCall{}, ai.Parts[1])
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"content":"Sunny"}`}}}, nil
+			}),
+	)
+
+	tool := mocktools.NewMockTool[any, any](ctrl)
+	tool.EXPECT().Name().Return("weather").Times(1)
+	tool.EXPECT().Description().Return("desc").Times(1)
+	tool.EXPECT().Parameters().Return(nil).Times(1)
+	tool.EXPECT().Call(gomock.Any(), gomock.Any()).Return("sunny", nil).Times(1)
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithThinkingBudget(2000),
+	).WithTools(tool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var output chatmodel.OutputResult
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "weather in Boston?"}, &output)
+	require.NoError(t, err)
+	assert.Equal(t, "Sunny", output.Content)
+}
+
+func Test_Assistant_MessageTransformer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(4)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+			last := messages[len(messages)-1]
+			assert.Equal(t, "my password is [REDACTED]", last.Parts[0].(llms.TextContent).Text)
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"content":"Hello"}`}}}, nil
+		}).Times(1)
+
+	redact := func(_ context.Context, messages []llms.Message) ([]llms.Message, error) {
+		result := make([]llms.Message, 0, len(messages))
+		for _, msg := range messages {
+			if text, ok := msg.Parts[0].(llms.TextContent); ok && msg.Role == llms.RoleHuman {
+				msg = llms.MessageFromTextParts(msg.Role, strings.ReplaceAll(text.Text, "secret", "[REDACTED]"))
+			}
+			result = append(result, msg)
+		}
+		return result, nil
+	}
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMessageTransformer(redact),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var output chatmodel.OutputResult
+	resp, err := ag.Run(ctx, &assistants.CallInput{Input: "my password is secret"}, &output)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", output.Content)
+	// the message history is not changed
+	assert.Equal(t, "my password is secret", resp.Messages[0].Parts[0].(llms.TextContent).Text)
+
+	_, err = ag.Run(ctx, &assistants.CallInput{
+		Input: "hello",
+		Options: []assistants.Option{assistants.WithMessageTransformer(func(context.Context, []llms.Message) ([]llms.Message, error) {
+			return nil, errors.New("translation failed")
+		})},
+	}, &output)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to transform messages: translation failed")
+}
+
+func Test_Assistant_MessageTransformer_Repair(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var started llms.Messages
+	cb := mockassitants.NewMockCallback(ctrl)
+	cb.EXPECT().OnAssistantStart(gomock.Any(), gomock.Any()).Times(1)
+	cb.EXPECT().OnAssistantLLMCallStart(gomock.Any(), gomock.Any()).Do(
+		func(_ context.Context, e *assistants.LLMCallStartEvent) {
+			started = e.Messages
+		}).Times(1)
+	cb.EXPECT().OnAssistantLLMCallEnd(gomock.Any(), gomock.Any()).Times(1)
+	cb.EXPECT().OnAssistantEnd(gomock.Any(), gomock.Any()).Times(1)
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+			// the orphan tool response added by the transformer is dropped
+			assert.NoError(t, llmutils.ValidateHistory(messages))
+			assert.Equal(t, started, llms.Messages(messages))
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"content":"Hello"}`}}}, nil
+		}).Times(1)
+
+	orphan := func(_ context.Context, messages []llms.Message) ([]llms.Message, error) {
+		return append(slices.Clone(messages), llms.MessageFromToolResponse(llms.RoleTool, llms.ToolCallResponse{
+			ToolCallID: "call_missing", Name: "search", Content: "result",
+		})), nil
+	}
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMessageTransformer(orphan),
+		assistants.WithHistoryRepair(llmutils.HistoryRepairDrop),
+		assistants.WithCallback(cb),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var output chatmodel.OutputResult
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "hello"}, &output)
+	require.NoError(t, err)
+	require.Len(t, started, 2)
+}
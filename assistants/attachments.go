@@ -0,0 +1,136 @@
+package assistants
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/artifacts"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// Attachment is the image, file or URL added to the user message of the call.
+//
+// The attachment is converted to the content part supported by the provider:
+// the inline data is sent as llms.BinaryContent with llms.CapabilityInlineImage or llms.CapabilityInlineFile,
+// the image URL is sent as llms.ImageURLContent with llms.CapabilityVision,
+// otherwise the data is uploaded to the artifact store, see WithArtifactStore,
+// and the URL is sent as the text reference.
+type Attachment struct {
+	// Name is the file name, used in the text reference and to detect the MIME type.
+	Name string
+	// MIMEType is the content type, detected from the Name, the URL or the Data if not set.
+	MIMEType string
+	// Data is the inline content.
+	Data []byte
+	// URL is the URL of the content, used when the Data can not be sent inline.
+	// It is set to the signed URL, when the Data is uploaded to the artifact store.
+	URL string
+}
+
+// NewImageAttachment returns the attachment of the image data,
+// the MIME type is detected if empty.
+func NewImageAttachment(data []byte, mimeType string) *Attachment {
+	return &Attachment{MIMEType: mimeType, Data: data}
+}
+
+// NewFileAttachment returns the attachment of the file,
+// the MIME type is detected if empty.
+func NewFileAttachment(name, mimeType string, data []byte) *Attachment {
+	return &Attachment{Name: name, MIMEType: mimeType, Data: data}
+}
+
+// NewURLAttachment returns the attachment of the URL,
+// the MIME type is detected from the URL path if empty.
+func NewURLAttachment(u, mimeType string) *Attachment {
+	return &Attachment{MIMEType: mimeType, URL: u}
+}
+
+// WithArtifactStore is an option to upload the attachments of the call,
+// that the LLM provider can not take inline, to the artifact store.
+// The signed URLs of the artifacts are sent to the LLM instead of the data.
+func WithArtifactStore(store artifacts.Store) Option {
+	return func(o *Config) {
+		o.ArtifactStore = store
+	}
+}
+
+// ContentType returns the MIME type of the attachment.
+func (a *Attachment) ContentType() string {
+	if a.MIMEType != "" {
+		return a.MIMEType
+	}
+	name := a.Name
+	if name == "" && a.URL != "" {
+		if u, err := url.Parse(a.URL); err == nil {
+			name = u.Path
+		}
+	}
+	if ext := path.Ext(name); ext != "" {
+		if typ := mime.TypeByExtension(ext); typ != "" {
+			return typ
+		}
+	}
+	if len(a.Data) > 0 {
+		return http.DetectContentType(a.Data)
+	}
+	return "application/octet-stream"
+}
+
+// IsImage returns true for the image attachment.
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType(), "image/")
+}
+
+// attachmentParts returns the content parts of the attachments supported by the provider
+func (cfg *Config) attachmentParts(ctx context.Context, caps llms.Capability, list []*Attachment) ([]llms.ContentPart, error) {
+	parts := make([]llms.ContentPart, 0, len(list))
+	for i, a := range list {
+		if a == nil || (len(a.Data) == 0 && a.URL == "") {
+			return nil, errors.Newf("attachment %d has no data or URL", i+1)
+		}
+		contentType := a.ContentType()
+		image := strings.HasPrefix(contentType, "image/")
+
+		if len(a.Data) > 0 && ((image && caps.Supports(llms.CapabilityInlineImage)) ||
+			(!image && caps.Supports(llms.CapabilityInlineFile))) {
+			parts = append(parts, llms.BinaryContent{MIMEType: contentType, Data: a.Data})
+			continue
+		}
+
+		if a.URL == "" {
+			if cfg.ArtifactStore == nil {
+				return nil, errors.Newf("attachment %d: the provider does not support the inline %s, the artifact store is required", i+1, contentType)
+			}
+			name := a.Name
+			if name == "" {
+				name = fmt.Sprintf("attachment_%d", i+1)
+			}
+			art, err := cfg.ArtifactStore.Put(ctx, name, contentType, bytes.NewReader(a.Data))
+			if err != nil {
+				return nil, errors.WithMessagef(err, "attachment %d: failed to upload", i+1)
+			}
+			a.URL, err = cfg.ArtifactStore.SignedURL(ctx, art.ID, artifacts.DefaultURLExpiry)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "attachment %d: failed to sign URL", i+1)
+			}
+		}
+
+		if image && caps.Supports(llms.CapabilityVision) {
+			parts = append(parts, llms.ImageURLContent{URL: a.URL})
+			continue
+		}
+		ref := "Attachment"
+		if a.Name != "" {
+			ref += " " + a.Name
+		}
+		parts = append(parts, llms.TextPart(fmt.Sprintf("%s (%s): %s", ref, contentType, a.URL)))
+	}
+	return parts, nil
+}
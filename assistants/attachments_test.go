@@ -0,0 +1,139 @@
+package assistants_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/artifacts"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAttachment_ContentType(t *testing.T) {
+	t.Parallel()
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tcases := []struct {
+		a   *assistants.Attachment
+		exp string
+	}{
+		{assistants.NewImageAttachment(png, ""), "image/png"},
+		{assistants.NewImageAttachment(png, "image/webp"), "image/webp"},
+		{assistants.NewFileAttachment("report.pdf", "", []byte("%PDF-1.7")), "application/pdf"},
+		{assistants.NewURLAttachment("https://example.com/cat.jpg?size=large", ""), "image/jpeg"},
+		{assistants.NewURLAttachment("https://example.com/page", ""), "application/octet-stream"},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.exp, tc.a.ContentType())
+	}
+	assert.True(t, assistants.NewImageAttachment(png, "").IsImage())
+}
+
+func Test_Assistant_Attachments(t *testing.T) {
+	t.Parallel()
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdf := []byte("%PDF-1.7")
+
+	arts, err := artifacts.NewDiskStore(t.TempDir(), "http://localhost/artifacts", []byte("secret"))
+	require.NoError(t, err)
+
+	tcases := []struct {
+		name     string
+		provider llms.ProviderType
+		store    artifacts.Store
+		check    func(t *testing.T, parts []llms.ContentPart)
+		err      string
+	}{
+		{
+			name:     "inline",
+			provider: llms.ProviderGoogleAI,
+			check: func(t *testing.T, parts []llms.ContentPart) {
+				assert.Equal(t, llms.BinaryContent{MIMEType: "image/png", Data: png}, parts[1])
+				assert.Equal(t, llms.BinaryContent{MIMEType: "application/pdf", Data: pdf}, parts[2])
+				assert.Equal(t, llms.ImageURLContent{URL: "https://example.com/cat.jpg"}, parts[3])
+			},
+		},
+		{
+			name:     "inline images only",
+			provider: llms.ProviderAnthropic,
+			store:    arts,
+			check: func(t *testing.T, parts []llms.ContentPart) {
+				assert.Equal(t, llms.BinaryContent{MIMEType: "image/png", Data: png}, parts[1])
+				text := parts[2].(llms.TextContent).Text
+				assert.True(t, strings.HasPrefix(text, "Attachment report.pdf (application/pdf): http://localhost/artifacts/"), text)
+				// no vision
+				assert.Equal(t, llms.TextPart("Attachment (image/jpeg): https://example.com/cat.jpg"), parts[3])
+			},
+		},
+		{
+			name:     "uploaded",
+			provider: llms.ProviderCohere,
+			store:    arts,
+			check: func(t *testing.T, parts []llms.ContentPart) {
+				img, ok := parts[1].(llms.ImageURLContent)
+				require.True(t, ok)
+				assert.True(t, strings.HasPrefix(img.URL, "http://localhost/artifacts/"), img.URL)
+				assert.IsType(t, llms.TextContent{}, parts[2])
+				assert.Equal(t, llms.ImageURLContent{URL: "https://example.com/cat.jpg"}, parts[3])
+			},
+		},
+		{
+			name:     "no store",
+			provider: llms.ProviderCohere,
+			err:      "failed to add attachments: attachment 1: the provider does not support the inline image/png, the artifact store is required",
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockLLM := mockllms.NewMockModel(ctrl)
+			// the provider is checked for each attachment, the run fails at the first unsupported one
+			providerCalls := 3
+			if tc.err != "" {
+				providerCalls = 2
+			}
+			mockLLM.EXPECT().GetProviderType().Return(tc.provider).Times(providerCalls)
+			mockLLM.EXPECT().GetName().Return("model").Times(2)
+
+			opts := []assistants.Option{assistants.WithMode(encoding.ModePlainText)}
+			if tc.store != nil {
+				opts = append(opts, assistants.WithArtifactStore(tc.store))
+			}
+			ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+				prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil), opts...)
+
+			ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant", chatmodel.NewChatID(), nil))
+			resp, err := ag.Call(ctx, &assistants.CallInput{
+				Input: "Describe the attachments.",
+				Attachments: []*assistants.Attachment{
+					assistants.NewImageAttachment(png, ""),
+					assistants.NewFileAttachment("report.pdf", "", pdf),
+					assistants.NewURLAttachment("https://example.com/cat.jpg", ""),
+				},
+				Options: []assistants.Option{assistants.WithDryRun()},
+			})
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			msg := resp.PreparedCall.Messages[len(resp.PreparedCall.Messages)-1]
+			assert.Equal(t, llms.RoleHuman, msg.Role)
+			require.Len(t, msg.Parts, 4)
+			assert.Equal(t, llms.TextPart("Describe the attachments."), msg.Parts[0])
+			tc.check(t, msg.Parts)
+		})
+	}
+}
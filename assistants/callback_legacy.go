@@ -0,0 +1,251 @@
+package assistants
+
+import (
+	"context"
+	"time"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/tools"
+)
+
+// ensure that the adapter implements the optional interfaces
+var (
+	_ Callback                   = (*legacyCallback)(nil)
+	_ RecoveryCallback           = (*legacyCallback)(nil)
+	_ MessageOrderRepairCallback = (*legacyCallback)(nil)
+	_ ToolCallDeltaCallback      = (*legacyCallback)(nil)
+	_ ToolBatchCallback          = (*legacyCallback)(nil)
+	_ FallbackCallback           = (*legacyCallback)(nil)
+	_ PlannerCallback            = (*legacyCallback)(nil)
+	_ PromptVariantCallback      = (*legacyCallback)(nil)
+)
+
+// LegacyCallback is the previous Callback interface with the positional arguments,
+// see FromLegacyCallback.
+type LegacyCallback interface {
+	OnToolStart(ctx context.Context, tool tools.ITool, assistantName, input string)
+	OnToolEnd(ctx context.Context, tool tools.ITool, assistantName, input string, output string)
+	OnToolError(ctx context.Context, tool tools.ITool, assistantName, input string, err error)
+	OnAssistantStart(ctx context.Context, a IAssistant, input string)
+	OnAssistantEnd(ctx context.Context, a IAssistant, input string, resp *Response, messageHistory llms.Messages)
+	OnAssistantError(ctx context.Context, a IAssistant, input string, err error, messageHistory llms.Messages)
+	OnAssistantLLMCallStart(ctx context.Context, a IAssistant, llm llms.Model, payload llms.Messages)
+	OnAssistantLLMCallEnd(ctx context.Context, a IAssistant, llm llms.Model, resp *llms.ContentResponse)
+	OnAssistantLLMParseError(ctx context.Context, a IAssistant, input string, response string, err error)
+	OnToolNotFound(ctx context.Context, a IAssistant, tool string)
+}
+
+// the methods of the optional interfaces with the positional arguments,
+// forwarded by the adapter one by one
+type (
+	legacyToolCancelled interface {
+		OnToolCancelled(ctx context.Context, a IAssistant, tool, input, partial string, cause error)
+	}
+	legacyRetry interface {
+		OnRetry(ctx context.Context, a IAssistant, reason RetryReason, attempt int, err error)
+	}
+	legacyRateLimit interface {
+		OnRateLimit(ctx context.Context, a IAssistant, llm llms.Model, attempt int, wait time.Duration)
+	}
+	legacyMessageOrderRepair interface {
+		OnMessageOrderRepair(ctx context.Context, a IAssistant, llm llms.Model, repair llms.MessageOrderRepair)
+	}
+	legacyToolCallDelta interface {
+		OnToolCallDelta(ctx context.Context, a IAssistant, delta llms.ToolCallDelta)
+	}
+	legacyToolBatchStart interface {
+		OnToolBatchStart(ctx context.Context, a IAssistant, batch *ToolBatch)
+	}
+	legacyToolBatchUpdate interface {
+		OnToolBatchUpdate(ctx context.Context, a IAssistant, batch *ToolBatch, call *ToolBatchCall)
+	}
+	legacyToolBatchEnd interface {
+		OnToolBatchEnd(ctx context.Context, a IAssistant, batch *ToolBatch)
+	}
+	legacyModelFallback interface {
+		OnModelFallback(ctx context.Context, a IAssistant, failed, next llms.Model, err error)
+	}
+	legacyPlanCreated interface {
+		OnPlanCreated(ctx context.Context, a IAssistant, plan *Plan, revision int)
+	}
+	legacyPlanStepStart interface {
+		OnPlanStepStart(ctx context.Context, a IAssistant, step *PlanStep)
+	}
+	legacyPlanStepEnd interface {
+		OnPlanStepEnd(ctx context.Context, a IAssistant, result *PlanStepResult)
+	}
+	legacyPromptVariant interface {
+		OnAssistantPromptVariant(ctx context.Context, a IAssistant, variant *prompts.Variant, err error)
+	}
+)
+
+// FromLegacyCallback adapts the implementation of LegacyCallback to Callback,
+// the fields of the events that are not in the positional arguments are dropped.
+// The optional interfaces implemented by the callback, such as RecoveryCallback, are forwarded,
+// and so are the methods of the optional interfaces with the positional arguments,
+// such as OnRetry(ctx, a, reason, attempt, err), each of them is forwarded if implemented.
+func FromLegacyCallback(cb LegacyCallback) Callback {
+	return &legacyCallback{cb: cb}
+}
+
+type legacyCallback struct {
+	cb LegacyCallback
+}
+
+func (l *legacyCallback) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
+	l.cb.OnToolStart(ctx, e.Tool, e.Assistant, e.Input)
+}
+
+func (l *legacyCallback) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
+	l.cb.OnToolEnd(ctx, e.Tool, e.Assistant, e.Input, e.Output)
+}
+
+func (l *legacyCallback) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
+	l.cb.OnToolError(ctx, e.Tool, e.Assistant, e.Input, e.Err)
+}
+
+func (l *legacyCallback) OnAssistantStart(ctx context.Context, e *AssistantStartEvent) {
+	l.cb.OnAssistantStart(ctx, e.Assistant, e.Input)
+}
+
+func (l *legacyCallback) OnAssistantEnd(ctx context.Context, e *AssistantEndEvent) {
+	l.cb.OnAssistantEnd(ctx, e.Assistant, e.Input, e.Response, e.Messages)
+}
+
+func (l *legacyCallback) OnAssistantError(ctx context.Context, e *AssistantErrorEvent) {
+	l.cb.OnAssistantError(ctx, e.Assistant, e.Input, e.Err, e.Messages)
+}
+
+func (l *legacyCallback) OnAssistantLLMCallStart(ctx context.Context, e *LLMCallStartEvent) {
+	l.cb.OnAssistantLLMCallStart(ctx, e.Assistant, e.LLM, e.Messages)
+}
+
+func (l *legacyCallback) OnAssistantLLMCallEnd(ctx context.Context, e *LLMCallEndEvent) {
+	l.cb.OnAssistantLLMCallEnd(ctx, e.Assistant, e.LLM, e.Response)
+}
+
+func (l *legacyCallback) OnAssistantLLMParseError(ctx context.Context, e *LLMParseErrorEvent) {
+	l.cb.OnAssistantLLMParseError(ctx, e.Assistant, e.Input, e.Response, e.Err)
+}
+
+func (l *legacyCallback) OnToolNotFound(ctx context.Context, e *ToolNotFoundEvent) {
+	l.cb.OnToolNotFound(ctx, e.Assistant, e.Tool)
+}
+
+func (l *legacyCallback) OnToolCancelled(ctx context.Context, e *ToolCancelledEvent) {
+	switch cb := l.cb.(type) {
+	case RecoveryCallback:
+		cb.OnToolCancelled(ctx, e)
+	case legacyToolCancelled:
+		cb.OnToolCancelled(ctx, e.Assistant, e.Tool, e.Input, e.Partial, e.Cause)
+	}
+}
+
+func (l *legacyCallback) OnRetry(ctx context.Context, e *RetryEvent) {
+	switch cb := l.cb.(type) {
+	case RecoveryCallback:
+		cb.OnRetry(ctx, e)
+	case legacyRetry:
+		cb.OnRetry(ctx, e.Assistant, e.Reason, e.Attempt, e.Err)
+	}
+}
+
+func (l *legacyCallback) OnRateLimit(ctx context.Context, e *RateLimitEvent) {
+	switch cb := l.cb.(type) {
+	case RecoveryCallback:
+		cb.OnRateLimit(ctx, e)
+	case legacyRateLimit:
+		cb.OnRateLimit(ctx, e.Assistant, e.LLM, e.Attempt, e.Wait)
+	}
+}
+
+func (l *legacyCallback) OnMessageOrderRepair(ctx context.Context, e *MessageOrderRepairEvent) {
+	switch cb := l.cb.(type) {
+	case MessageOrderRepairCallback:
+		cb.OnMessageOrderRepair(ctx, e)
+	case legacyMessageOrderRepair:
+		cb.OnMessageOrderRepair(ctx, e.Assistant, e.LLM, e.Repair)
+	}
+}
+
+func (l *legacyCallback) OnToolCallDelta(ctx context.Context, e *ToolCallDeltaEvent) {
+	switch cb := l.cb.(type) {
+	case ToolCallDeltaCallback:
+		cb.OnToolCallDelta(ctx, e)
+	case legacyToolCallDelta:
+		cb.OnToolCallDelta(ctx, e.Assistant, e.Delta)
+	}
+}
+
+func (l *legacyCallback) OnToolBatchStart(ctx context.Context, e *ToolBatchEvent) {
+	switch cb := l.cb.(type) {
+	case ToolBatchCallback:
+		cb.OnToolBatchStart(ctx, e)
+	case legacyToolBatchStart:
+		cb.OnToolBatchStart(ctx, e.Assistant, e.Batch)
+	}
+}
+
+func (l *legacyCallback) OnToolBatchUpdate(ctx context.Context, e *ToolBatchEvent) {
+	switch cb := l.cb.(type) {
+	case ToolBatchCallback:
+		cb.OnToolBatchUpdate(ctx, e)
+	case legacyToolBatchUpdate:
+		cb.OnToolBatchUpdate(ctx, e.Assistant, e.Batch, e.Call)
+	}
+}
+
+func (l *legacyCallback) OnToolBatchEnd(ctx context.Context, e *ToolBatchEvent) {
+	switch cb := l.cb.(type) {
+	case ToolBatchCallback:
+		cb.OnToolBatchEnd(ctx, e)
+	case legacyToolBatchEnd:
+		cb.OnToolBatchEnd(ctx, e.Assistant, e.Batch)
+	}
+}
+
+func (l *legacyCallback) OnModelFallback(ctx context.Context, e *ModelFallbackEvent) {
+	switch cb := l.cb.(type) {
+	case FallbackCallback:
+		cb.OnModelFallback(ctx, e)
+	case legacyModelFallback:
+		cb.OnModelFallback(ctx, e.Assistant, e.Failed, e.Next, e.Err)
+	}
+}
+
+func (l *legacyCallback) OnPlanCreated(ctx context.Context, e *PlanCreatedEvent) {
+	switch cb := l.cb.(type) {
+	case PlannerCallback:
+		cb.OnPlanCreated(ctx, e)
+	case legacyPlanCreated:
+		cb.OnPlanCreated(ctx, e.Assistant, e.Plan, e.Revision)
+	}
+}
+
+func (l *legacyCallback) OnPlanStepStart(ctx context.Context, e *PlanStepStartEvent) {
+	switch cb := l.cb.(type) {
+	case PlannerCallback:
+		cb.OnPlanStepStart(ctx, e)
+	case legacyPlanStepStart:
+		cb.OnPlanStepStart(ctx, e.Assistant, e.Step)
+	}
+}
+
+func (l *legacyCallback) OnPlanStepEnd(ctx context.Context, e *PlanStepEndEvent) {
+	switch cb := l.cb.(type) {
+	case PlannerCallback:
+		cb.OnPlanStepEnd(ctx, e)
+	case legacyPlanStepEnd:
+		cb.OnPlanStepEnd(ctx, e.Assistant, e.Result)
+	}
+}
+
+func (l *legacyCallback) OnAssistantPromptVariant(ctx context.Context, e *PromptVariantEvent) {
+	switch cb := l.cb.(type) {
+	case PromptVariantCallback:
+		cb.OnAssistantPromptVariant(ctx, e)
+	case legacyPromptVariant:
+		cb.OnAssistantPromptVariant(ctx, e.Assistant, e.Variant, e.Err)
+	}
+}
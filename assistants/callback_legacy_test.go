@@ -0,0 +1,192 @@
+package assistants_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// legacyRecorder implements the callback with the positional arguments.
+type legacyRecorder struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (r *legacyRecorder) add(event string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *legacyRecorder) OnToolStart(_ context.Context, tool tools.ITool, _, _ string) {
+	r.add("tool_start:" + tool.Name())
+}
+
+func (r *legacyRecorder) OnToolEnd(_ context.Context, tool tools.ITool, _, _ string, output string) {
+	r.add("tool_end:" + tool.Name() + ":" + output)
+}
+
+func (r *legacyRecorder) OnToolError(_ context.Context, tool tools.ITool, _, _ string, _ error) {
+	r.add("tool_error:" + tool.Name())
+}
+
+func (r *legacyRecorder) OnAssistantStart(_ context.Context, _ assistants.IAssistant, input string) {
+	r.add("assistant_start:" + input)
+}
+
+func (r *legacyRecorder) OnAssistantEnd(_ context.Context, _ assistants.IAssistant, _ string, resp *assistants.Response, _ llms.Messages) {
+	r.add("assistant_end:" + resp.String())
+}
+
+func (r *legacyRecorder) OnAssistantError(_ context.Context, _ assistants.IAssistant, _ string, _ error, _ llms.Messages) {
+	r.add("assistant_error")
+}
+
+func (r *legacyRecorder) OnAssistantLLMCallStart(_ context.Context, _ assistants.IAssistant, llm llms.Model, _ llms.Messages) {
+	r.add("llm_call_start:" + llm.GetName())
+}
+
+func (r *legacyRecorder) OnAssistantLLMCallEnd(_ context.Context, _ assistants.IAssistant, llm llms.Model, _ *llms.ContentResponse) {
+	r.add("llm_call_end:" + llm.GetName())
+}
+
+func (r *legacyRecorder) OnAssistantLLMParseError(_ context.Context, _ assistants.IAssistant, _ string, _ string, _ error) {
+	r.add("llm_parse_error")
+}
+
+func (r *legacyRecorder) OnToolNotFound(_ context.Context, _ assistants.IAssistant, tool string) {
+	r.add("tool_not_found:" + tool)
+}
+
+// OnRetry implements the optional RecoveryCallback.
+func (r *legacyRecorder) OnRetry(_ context.Context, _ assistants.IAssistant, reason assistants.RetryReason, _ int, _ error) {
+	r.add("retry:" + string(reason))
+}
+
+// toolEventsRecorder records the typed events of the tool calls.
+type toolEventsRecorder struct {
+	callbacks.Noop
+	lock    sync.Mutex
+	starts  []tools.ToolStartEvent
+	ends    []tools.ToolEndEvent
+	retries []assistants.RetryEvent
+	rounds  []int
+}
+
+func (r *toolEventsRecorder) OnToolStart(_ context.Context, e *tools.ToolStartEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.starts = append(r.starts, *e)
+}
+
+func (r *toolEventsRecorder) OnToolEnd(_ context.Context, e *tools.ToolEndEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.ends = append(r.ends, *e)
+}
+
+func (r *toolEventsRecorder) OnRetry(_ context.Context, e *assistants.RetryEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.retries = append(r.retries, *e)
+}
+
+func (r *toolEventsRecorder) OnAssistantLLMCallEnd(_ context.Context, e *assistants.LLMCallEndEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.rounds = append(r.rounds, e.Round)
+}
+
+func Test_Assistant_CallbackEvents(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(6)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "flaky_tool", Arguments: "{}"}}},
+			}},
+		}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "done"}},
+		}, nil),
+	)
+
+	flakyCalls := 0
+	flakyTool := mocktools.NewMockTool[any, any](ctrl)
+	flakyTool.EXPECT().Name().Return("flaky_tool").Times(6)
+	flakyTool.EXPECT().Description().Return("desc").Times(1)
+	flakyTool.EXPECT().Parameters().Return(nil).Times(1)
+	flakyTool.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string) (string, error) {
+		flakyCalls++
+		if flakyCalls == 1 {
+			return "", errors.Mark(errors.New("connection reset"), chatmodel.ErrToolTransient)
+		}
+		return "ok", nil
+	}).Times(2)
+
+	legacy := &legacyRecorder{}
+	rec := &toolEventsRecorder{}
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithCallbacks(assistants.FromLegacyCallback(legacy), rec),
+		assistants.WithMaxToolRetries(1),
+	).WithTools(flakyTool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.Choices[0].Content)
+
+	// the start is reported for each attempt of the call
+	require.Len(t, rec.starts, 2)
+	for i, e := range rec.starts {
+		assert.Equal(t, "flaky_tool", e.Tool.Name())
+		assert.Equal(t, ag.Name(), e.Assistant)
+		assert.Equal(t, "{}", e.Input)
+		assert.Equal(t, "call_1", e.CallID)
+		assert.Equal(t, i+1, e.Attempt)
+	}
+	require.Len(t, rec.ends, 1)
+	assert.Equal(t, "call_1", rec.ends[0].CallID)
+	assert.Equal(t, "ok", rec.ends[0].Output)
+	assert.Equal(t, 2, rec.ends[0].Attempt)
+	assert.Positive(t, rec.ends[0].Latency)
+	assert.Equal(t, []int{1, 2}, rec.rounds)
+	require.Len(t, rec.retries, 1)
+	assert.Equal(t, assistants.RetryReasonToolTransient, rec.retries[0].Reason)
+	assert.Equal(t, "flaky_tool", rec.retries[0].Tool)
+	assert.Equal(t, "call_1", rec.retries[0].CallID)
+	assert.Equal(t, 1, rec.retries[0].Attempt)
+
+	assert.Equal(t, []string{
+		"assistant_start:input",
+		"llm_call_start:gpt-4o",
+		"llm_call_end:gpt-4o",
+		"tool_start:flaky_tool",
+		"retry:tool_transient",
+		"tool_start:flaky_tool",
+		"tool_end:flaky_tool:ok",
+		"llm_call_start:gpt-4o",
+		"llm_call_end:gpt-4o",
+		"assistant_end:done",
+	}, legacy.events)
+}
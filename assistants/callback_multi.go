@@ -0,0 +1,244 @@
+package assistants
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/xlog"
+)
+
+// ensure that the composite callback implements the optional interfaces
+var (
+	_ Callback                   = multiCallback(nil)
+	_ RecoveryCallback           = multiCallback(nil)
+	_ MessageOrderRepairCallback = multiCallback(nil)
+	_ ToolCallDeltaCallback      = multiCallback(nil)
+	_ ToolBatchCallback          = multiCallback(nil)
+	_ FallbackCallback           = multiCallback(nil)
+	_ PlannerCallback            = multiCallback(nil)
+	_ PromptVariantCallback      = multiCallback(nil)
+)
+
+// WithCallbacks sets the Callback Handler, that forwards the events to all the callbacks in order,
+// see MultiCallback. It replaces the handler set by WithCallback.
+func WithCallbacks(callbacks ...Callback) Option {
+	return func(o *Config) {
+		m := newMultiCallback(callbacks)
+		if len(m) == 0 {
+			o.CallbackHandler = nil
+			return
+		}
+		o.CallbackHandler = m
+	}
+}
+
+// MultiCallback returns the Callback, that forwards the events to all the callbacks in order,
+// including the optional interfaces, such as RecoveryCallback or ToolBatchCallback,
+// implemented by the callbacks. The nil callbacks are skipped.
+// The panic in a callback is recovered and logged,
+// so one faulty handler does not fail the run, or skip the other callbacks.
+func MultiCallback(callbacks ...Callback) Callback {
+	return newMultiCallback(callbacks)
+}
+
+type multiCallback []Callback
+
+func newMultiCallback(callbacks []Callback) multiCallback {
+	var m multiCallback
+	for _, cb := range callbacks {
+		switch c := cb.(type) {
+		case nil:
+		case multiCallback:
+			m = append(m, c...)
+		default:
+			m = append(m, c)
+		}
+	}
+	return m
+}
+
+// each calls the fn for each callback, and recovers the panic of the callback.
+func (m multiCallback) each(ctx context.Context, event string, fn func(cb Callback)) {
+	for _, cb := range m {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.ContextKV(ctx, xlog.ERROR,
+						"status", "callback_panic",
+						"callback", fmt.Sprintf("%T", cb),
+						"event", event,
+						"panic", r,
+						"stack", string(debug.Stack()),
+					)
+				}
+			}()
+			fn(cb)
+		}()
+	}
+}
+
+func (m multiCallback) OnAssistantStart(ctx context.Context, e *AssistantStartEvent) {
+	m.each(ctx, "OnAssistantStart", func(cb Callback) {
+		cb.OnAssistantStart(ctx, e)
+	})
+}
+
+func (m multiCallback) OnAssistantEnd(ctx context.Context, e *AssistantEndEvent) {
+	m.each(ctx, "OnAssistantEnd", func(cb Callback) {
+		cb.OnAssistantEnd(ctx, e)
+	})
+}
+
+func (m multiCallback) OnAssistantError(ctx context.Context, e *AssistantErrorEvent) {
+	m.each(ctx, "OnAssistantError", func(cb Callback) {
+		cb.OnAssistantError(ctx, e)
+	})
+}
+
+func (m multiCallback) OnAssistantLLMCallStart(ctx context.Context, e *LLMCallStartEvent) {
+	m.each(ctx, "OnAssistantLLMCallStart", func(cb Callback) {
+		cb.OnAssistantLLMCallStart(ctx, e)
+	})
+}
+
+func (m multiCallback) OnAssistantLLMCallEnd(ctx context.Context, e *LLMCallEndEvent) {
+	m.each(ctx, "OnAssistantLLMCallEnd", func(cb Callback) {
+		cb.OnAssistantLLMCallEnd(ctx, e)
+	})
+}
+
+func (m multiCallback) OnAssistantLLMParseError(ctx context.Context, e *LLMParseErrorEvent) {
+	m.each(ctx, "OnAssistantLLMParseError", func(cb Callback) {
+		cb.OnAssistantLLMParseError(ctx, e)
+	})
+}
+
+func (m multiCallback) OnToolNotFound(ctx context.Context, e *ToolNotFoundEvent) {
+	m.each(ctx, "OnToolNotFound", func(cb Callback) {
+		cb.OnToolNotFound(ctx, e)
+	})
+}
+
+func (m multiCallback) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
+	m.each(ctx, "OnToolStart", func(cb Callback) {
+		cb.OnToolStart(ctx, e)
+	})
+}
+
+func (m multiCallback) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
+	m.each(ctx, "OnToolEnd", func(cb Callback) {
+		cb.OnToolEnd(ctx, e)
+	})
+}
+
+func (m multiCallback) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
+	m.each(ctx, "OnToolError", func(cb Callback) {
+		cb.OnToolError(ctx, e)
+	})
+}
+
+func (m multiCallback) OnToolCancelled(ctx context.Context, e *ToolCancelledEvent) {
+	m.each(ctx, "OnToolCancelled", func(cb Callback) {
+		if rc, ok := cb.(RecoveryCallback); ok {
+			rc.OnToolCancelled(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnRetry(ctx context.Context, e *RetryEvent) {
+	m.each(ctx, "OnRetry", func(cb Callback) {
+		if rc, ok := cb.(RecoveryCallback); ok {
+			rc.OnRetry(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnRateLimit(ctx context.Context, e *RateLimitEvent) {
+	m.each(ctx, "OnRateLimit", func(cb Callback) {
+		if rc, ok := cb.(RecoveryCallback); ok {
+			rc.OnRateLimit(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnMessageOrderRepair(ctx context.Context, e *MessageOrderRepairEvent) {
+	m.each(ctx, "OnMessageOrderRepair", func(cb Callback) {
+		if mc, ok := cb.(MessageOrderRepairCallback); ok {
+			mc.OnMessageOrderRepair(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnToolCallDelta(ctx context.Context, e *ToolCallDeltaEvent) {
+	m.each(ctx, "OnToolCallDelta", func(cb Callback) {
+		if dc, ok := cb.(ToolCallDeltaCallback); ok {
+			dc.OnToolCallDelta(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnToolBatchStart(ctx context.Context, e *ToolBatchEvent) {
+	m.each(ctx, "OnToolBatchStart", func(cb Callback) {
+		if bc, ok := cb.(ToolBatchCallback); ok {
+			bc.OnToolBatchStart(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnToolBatchUpdate(ctx context.Context, e *ToolBatchEvent) {
+	m.each(ctx, "OnToolBatchUpdate", func(cb Callback) {
+		if bc, ok := cb.(ToolBatchCallback); ok {
+			bc.OnToolBatchUpdate(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnToolBatchEnd(ctx context.Context, e *ToolBatchEvent) {
+	m.each(ctx, "OnToolBatchEnd", func(cb Callback) {
+		if bc, ok := cb.(ToolBatchCallback); ok {
+			bc.OnToolBatchEnd(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnModelFallback(ctx context.Context, e *ModelFallbackEvent) {
+	m.each(ctx, "OnModelFallback", func(cb Callback) {
+		if fc, ok := cb.(FallbackCallback); ok {
+			fc.OnModelFallback(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnPlanCreated(ctx context.Context, e *PlanCreatedEvent) {
+	m.each(ctx, "OnPlanCreated", func(cb Callback) {
+		if pc, ok := cb.(PlannerCallback); ok {
+			pc.OnPlanCreated(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnPlanStepStart(ctx context.Context, e *PlanStepStartEvent) {
+	m.each(ctx, "OnPlanStepStart", func(cb Callback) {
+		if pc, ok := cb.(PlannerCallback); ok {
+			pc.OnPlanStepStart(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnPlanStepEnd(ctx context.Context, e *PlanStepEndEvent) {
+	m.each(ctx, "OnPlanStepEnd", func(cb Callback) {
+		if pc, ok := cb.(PlannerCallback); ok {
+			pc.OnPlanStepEnd(ctx, e)
+		}
+	})
+}
+
+func (m multiCallback) OnAssistantPromptVariant(ctx context.Context, e *PromptVariantEvent) {
+	m.each(ctx, "OnAssistantPromptVariant", func(cb Callback) {
+		if pc, ok := cb.(PromptVariantCallback); ok {
+			pc.OnAssistantPromptVariant(ctx, e)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package assistants_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type panicCallback struct {
+	callbacks.Noop
+}
+
+func (p *panicCallback) OnAssistantStart(context.Context, *assistants.AssistantStartEvent) {
+	panic("faulty handler")
+}
+
+func (p *panicCallback) OnAssistantLLMCallEnd(context.Context, *assistants.LLMCallEndEvent) {
+	panic("faulty handler")
+}
+
+func Test_Assistant_WithCallbacks(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(4)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: "answer"}},
+	}, nil).Times(1)
+
+	var buf bytes.Buffer
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithCallbacks(&panicCallback{}, callbacks.NewPrinter(&buf, callbacks.ModeVerbose)),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "answer", resp.Choices[0].Content)
+	assert.Contains(t, buf.String(), "Assistant Start: "+ag.Name())
+	assert.Contains(t, buf.String(), "Assistant End: "+ag.Name())
+
+	assert.Nil(t, ag.GetCallConfig(assistants.WithCallbacks()).CallbackHandler)
+	assert.Nil(t, ag.GetCallConfig(assistants.WithCallbacks(nil)).CallbackHandler)
+}
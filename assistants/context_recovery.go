@@ -0,0 +1,220 @@
+package assistants
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/xlog"
+)
+
+// ErrContextNotReduced is returned by the ContextReducer,
+// when the messages can not be reduced any further.
+var ErrContextNotReduced = errors.New("context can not be reduced")
+
+// ContextSummaryPrefix is the prefix of the system message with the summary
+// of the earlier turns, see SummarizeContext.
+const ContextSummaryPrefix = "Summary of the earlier conversation:\n"
+
+// ContextReducer returns the reduced message history,
+// when the LLM call fails with llms.ErrContextLengthExceeded.
+// The turnStart is the index of the first message of the current run, the input of the caller.
+// The messages from turnStart, including the messages added by the run,
+// such as the ReAct observations or the final answer prompt, must be kept in order.
+// The messages must not be modified, the reducer returns the new slice.
+type ContextReducer func(ctx context.Context, messages []llms.Message, turnStart int) ([]llms.Message, error)
+
+// WithContextRecovery is an option to reduce the message history with the reducer
+// and retry the LLM call once, when the provider fails with llms.ErrContextLengthExceeded.
+// The reduced history replaces the history of the run, the message store is not changed.
+// By default TruncateContext is used, nil disables the recovery.
+func WithContextRecovery(reducer ContextReducer) Option {
+	return func(o *Config) {
+		o.ContextReducer = reducer
+		o.contextReducerSet = true
+	}
+}
+
+// contextReducer returns the configured reducer, or TruncateContext by default.
+func (cfg *Config) contextReducer() ContextReducer {
+	if !cfg.contextReducerSet {
+		return TruncateContext
+	}
+	return cfg.ContextReducer
+}
+
+// TruncateContext is the ContextReducer that keeps the leading system and developer messages,
+// drops the earlier turns before turnStart, and removes the content of the tool responses
+// and the ReAct observations of the current turn, except the responses to the last tool calls.
+func TruncateContext(_ context.Context, messages []llms.Message, turnStart int) ([]llms.Message, error) {
+	system, earlier, current := splitTurns(messages, turnStart)
+	current, truncated := truncateToolResponses(current)
+	if len(earlier) == 0 && !truncated {
+		return nil, errors.WithStack(ErrContextNotReduced)
+	}
+	reduced := make([]llms.Message, 0, len(system)+len(current))
+	reduced = append(reduced, system...)
+	return append(reduced, current...), nil
+}
+
+// SummarizeContext returns the ContextReducer that replaces the earlier turns before turnStart
+// with the system message of their summary returned by the fn, and removes the content of the tool responses
+// of the current turn as TruncateContext.
+func SummarizeContext(fn SummarizeFunc) ContextReducer {
+	return func(ctx context.Context, messages []llms.Message, turnStart int) ([]llms.Message, error) {
+		system, earlier, current := splitTurns(messages, turnStart)
+		current, truncated := truncateToolResponses(current)
+		if len(earlier) == 0 && !truncated {
+			return nil, errors.WithStack(ErrContextNotReduced)
+		}
+
+		reduced := make([]llms.Message, 0, len(system)+len(current)+1)
+		reduced = append(reduced, system...)
+		if len(earlier) > 0 {
+			summary, err := fn(ctx, renderTurns(earlier))
+			if err != nil {
+				return nil, errors.WithMessage(err, "failed to summarize the context")
+			}
+			reduced = append(reduced, llms.MessageFromTextParts(llms.RoleSystem, ContextSummaryPrefix+summary))
+		}
+		return append(reduced, current...), nil
+	}
+}
+
+// splitTurns splits the messages to the leading system and developer messages,
+// the earlier turns, and the current turn starting at turnStart.
+func splitTurns(messages []llms.Message, turnStart int) (system, earlier, current []llms.Message) {
+	turnStart = min(max(turnStart, 0), len(messages))
+	start := 0
+	for start < turnStart && (messages[start].Role == llms.RoleSystem || messages[start].Role == llms.RoleDeveloper) {
+		start++
+	}
+	return messages[:start], messages[start:turnStart], messages[turnStart:]
+}
+
+// truncateToolResponses returns the copy of the messages with the content of the tool responses
+// and the ReAct observations removed, except the responses after the last tool calls,
+// and true if any response is truncated.
+func truncateToolResponses(messages []llms.Message) ([]llms.Message, bool) {
+	lastCalls, lastObservation := -1, -1
+	for i := len(messages) - 1; i >= 0 && (lastCalls < 0 || lastObservation < 0); i-- {
+		switch {
+		case lastCalls < 0 && messages[i].Role == llms.RoleAI && hasToolCalls(messages[i]):
+			lastCalls = i
+		case lastObservation < 0 && isReActObservation(messages[i]):
+			lastObservation = i
+		}
+	}
+
+	truncated := false
+	result := make([]llms.Message, len(messages))
+	copy(result, messages)
+	for i := range result {
+		var parts []llms.ContentPart
+		switch {
+		case i < lastCalls && result[i].Role == llms.RoleTool:
+			for j, part := range result[i].Parts {
+				resp, ok := part.(llms.ToolCallResponse)
+				if !ok || resp.Content == "" || strings.HasPrefix(resp.Content, truncatedToolResponse) {
+					continue
+				}
+				if parts == nil {
+					parts = make([]llms.ContentPart, len(result[i].Parts))
+					copy(parts, result[i].Parts)
+				}
+				resp.Content = truncatedNote(len(resp.Content))
+				parts[j] = resp
+			}
+		case i < lastObservation && isReActObservation(result[i]):
+			text := result[i].Parts[0].(llms.TextContent).Text
+			observation := strings.TrimSpace(strings.TrimPrefix(text, ReActObservationPrefix))
+			if !strings.HasPrefix(observation, truncatedToolResponse) {
+				parts = []llms.ContentPart{llms.TextPart(ReActObservationPrefix + " " + truncatedNote(len(observation)))}
+			}
+		}
+		if parts != nil {
+			result[i].Parts = parts
+			truncated = true
+		}
+	}
+	return result, truncated
+}
+
+// truncatedToolResponse is the content of the tool response removed by truncateToolResponses
+const truncatedToolResponse = "[the tool output is removed to fit the context window"
+
+func truncatedNote(size int) string {
+	return fmt.Sprintf("%s: %d bytes]", truncatedToolResponse, size)
+}
+
+// isReActObservation returns true for the human message with the observation of the ReAct tool call.
+func isReActObservation(msg llms.Message) bool {
+	if msg.Role != llms.RoleHuman || len(msg.Parts) != 1 {
+		return false
+	}
+	text, ok := msg.Parts[0].(llms.TextContent)
+	return ok && strings.HasPrefix(text.Text, ReActObservationPrefix)
+}
+
+func hasToolCalls(msg llms.Message) bool {
+	for _, part := range msg.Parts {
+		if _, ok := part.(llms.ToolCall); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// renderTurns returns the text of the messages to be summarized,
+// the binary content is omitted.
+func renderTurns(messages []llms.Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		sb.WriteString(string(msg.Role))
+		sb.WriteString(": ")
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				sb.WriteString(p.Text)
+			case llms.ToolCall:
+				if p.FunctionCall != nil {
+					fmt.Fprintf(&sb, "[tool call %s: %s]", p.FunctionCall.Name, p.FunctionCall.Arguments)
+				}
+			case llms.ToolCallResponse:
+				fmt.Fprintf(&sb, "[tool response %s: %s]", p.Name, p.Content)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// reduceContext returns the reduced message history, and true if the LLM call should be retried.
+// The turnStart is the index of the input message of the run in the messages.
+func (a *Assistant[O]) reduceContext(ctx context.Context, cfg *Config, messages llms.Messages, turnStart int, cause error) (llms.Messages, bool) {
+	reducer := cfg.contextReducer()
+	if reducer == nil {
+		return nil, false
+	}
+	reduced, err := reducer(ctx, messages, turnStart)
+	if err != nil {
+		logger.ContextKV(ctx, xlog.WARNING,
+			"assistant", a.Name(),
+			"reason", "context_not_reduced",
+			"messages", len(messages),
+			"err", err.Error())
+		return nil, false
+	}
+
+	logger.ContextKV(ctx, xlog.WARNING,
+		"assistant", a.Name(),
+		"status", "context_reduced",
+		"messages", len(messages),
+		"reduced", len(reduced))
+	if rc, ok := cfg.CallbackHandler.(RecoveryCallback); ok {
+		rc.OnRetry(ctx, &RetryEvent{Assistant: a, Reason: RetryReasonContextLength, Attempt: 1, Err: cause})
+	}
+	return reduced, true
+}
@@ -0,0 +1,369 @@
+package assistants_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func toolCall(id string) llms.Message {
+	return llms.Message{Role: llms.RoleAI, Parts: []llms.ContentPart{llms.ToolCall{
+		ID: id, Type: "function", FunctionCall: &llms.FunctionCall{Name: "search", Arguments: `{"q":"` + id + `"}`},
+	}}}
+}
+
+func toolResponse(id, content string) llms.Message {
+	return llms.MessageFromToolResponse(llms.RoleTool, llms.ToolCallResponse{ToolCallID: id, Name: "search", Content: content})
+}
+
+// printMessages returns the role and the text, the tool call ID or the tool response of each message
+func printMessages(messages []llms.Message) []string {
+	var res []string
+	for _, msg := range messages {
+		var parts []string
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				parts = append(parts, p.Text)
+			case llms.ToolCall:
+				parts = append(parts, "call "+p.ID)
+			case llms.ToolCallResponse:
+				parts = append(parts, p.ToolCallID+" "+p.Content)
+			}
+		}
+		res = append(res, string(msg.Role)+": "+strings.Join(parts, ","))
+	}
+	return res
+}
+
+func Test_TruncateContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	history := []llms.Message{
+		llms.MessageFromTextParts(llms.RoleSystem, "system"),
+		llms.MessageFromTextParts(llms.RoleHuman, "first question"),
+		llms.MessageFromTextParts(llms.RoleAI, "first answer"),
+		llms.MessageFromTextParts(llms.RoleHuman, "question"),
+		toolCall("1"),
+		toolResponse("1", "large output"),
+		toolCall("2"),
+		toolResponse("2", "last output"),
+		llms.MessageFromTextParts(llms.RoleHuman, assistants.DeadlineFinalAnswerPrompt),
+	}
+	orig := printMessages(history)
+
+	tcases := []struct {
+		name      string
+		messages  []llms.Message
+		turnStart int
+		exp       []string
+	}{
+		{
+			name:      "tool calls",
+			messages:  history,
+			turnStart: 3,
+			exp: []string{
+				"system: system",
+				"human: question",
+				"ai: call 1",
+				"tool: 1 [the tool output is removed to fit the context window: 12 bytes]",
+				"ai: call 2",
+				"tool: 2 last output",
+				"human: " + assistants.DeadlineFinalAnswerPrompt,
+			},
+		},
+		{
+			name:      "earlier turns",
+			messages:  history[:4],
+			turnStart: 3,
+			exp:       []string{"system: system", "human: question"},
+		},
+		{
+			name: "react",
+			messages: []llms.Message{
+				llms.MessageFromTextParts(llms.RoleSystem, "system"),
+				llms.MessageFromTextParts(llms.RoleHuman, "question"),
+				llms.MessageFromTextParts(llms.RoleAI, "Action: search"),
+				llms.MessageFromTextParts(llms.RoleHuman, "Observation: large output"),
+				llms.MessageFromTextParts(llms.RoleAI, "Action: search"),
+				llms.MessageFromTextParts(llms.RoleHuman, "Observation: last output"),
+			},
+			turnStart: 1,
+			exp: []string{
+				"system: system",
+				"human: question",
+				"ai: Action: search",
+				"human: Observation: [the tool output is removed to fit the context window: 12 bytes]",
+				"ai: Action: search",
+				"human: Observation: last output",
+			},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			reduced, err := assistants.TruncateContext(ctx, tc.messages, tc.turnStart)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, printMessages(reduced))
+
+			// nothing left to reduce
+			_, err = assistants.TruncateContext(ctx, reduced, 1)
+			assert.ErrorIs(t, err, assistants.ErrContextNotReduced)
+		})
+	}
+	assert.Equal(t, orig, printMessages(history), "the messages are not modified")
+
+	// the turn start out of range
+	reduced, err := assistants.TruncateContext(ctx, history[:3], 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"system: system"}, printMessages(reduced))
+	_, err = assistants.TruncateContext(ctx, history[:3], -1)
+	assert.ErrorIs(t, err, assistants.ErrContextNotReduced)
+}
+
+func Test_SummarizeContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	history := []llms.Message{
+		llms.MessageFromTextParts(llms.RoleSystem, "system"),
+		llms.MessageFromTextParts(llms.RoleHuman, "first question"),
+		toolCall("1"),
+		toolResponse("1", "first output"),
+		llms.MessageFromTextParts(llms.RoleAI, "first answer"),
+		llms.MessageFromTextParts(llms.RoleHuman, "question"),
+		llms.MessageFromTextParts(llms.RoleHuman, "Observation: output"),
+	}
+
+	var summarized string
+	reducer := assistants.SummarizeContext(func(_ context.Context, content string) (string, error) {
+		summarized = content
+		return "the user asked the first question", nil
+	})
+	reduced, err := reducer(ctx, history, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "human: first question\n"+
+		"ai: [tool call search: {\"q\":\"1\"}]\n"+
+		"tool: [tool response search: first output]\n"+
+		"ai: first answer\n", summarized)
+	assert.Equal(t, []string{
+		"system: system",
+		"system: " + assistants.ContextSummaryPrefix + "the user asked the first question",
+		"human: question",
+		"human: Observation: output",
+	}, printMessages(reduced))
+
+	_, err = reducer(ctx, reduced, 2)
+	assert.ErrorIs(t, err, assistants.ErrContextNotReduced)
+
+	failing := assistants.SummarizeContext(func(context.Context, string) (string, error) {
+		return "", errors.New("summarizer failed")
+	})
+	_, err = failing(ctx, history, 5)
+	assert.EqualError(t, err, "failed to summarize the context: summarizer failed")
+}
+
+func Test_Assistant_ContextRecovery(t *testing.T) {
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	contextErr := &llms.ProviderError{Kind: llms.ErrContextLengthExceeded, Message: "prompt is too long"}
+
+	newModel := func(ctrl *gomock.Controller, provider llms.ProviderType, providerCalls, nameCalls int) *mockllms.MockModel {
+		m := mockllms.NewMockModel(ctrl)
+		m.EXPECT().GetProviderType().Return(provider).Times(providerCalls)
+		m.EXPECT().GetName().Return("primary").Times(nameCalls)
+		return m
+	}
+	newAssistant := func(llm llms.Model, opts ...assistants.Option) *assistants.Assistant[chatmodel.String] {
+		return assistants.NewAssistant[chatmodel.String](llm,
+			prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+			append([]assistants.Option{assistants.WithMode(encoding.ModePlainText)}, opts...)...,
+		)
+	}
+	examples := assistants.WithExamples(chatmodel.FewShotExamples{{Prompt: "example", Completion: "answer"}})
+	done := &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "done"}}}
+
+	t.Run("truncate", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		llm := newModel(ctrl, llms.ProviderOpenAI, 1, 2)
+		var sent [][]llms.Message
+		gomock.InOrder(
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+					sent = append(sent, messages)
+					return nil, contextErr
+				}),
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+					sent = append(sent, messages)
+					return done, nil
+				}),
+		)
+
+		rec := &retryRecorder{}
+		ag := newAssistant(llm, assistants.WithCallback(rec), examples)
+		var output chatmodel.String
+		_, err := ag.Run(ctx, &assistants.CallInput{Input: "input"}, &output)
+		require.NoError(t, err)
+		assert.Equal(t, "done", output.String())
+		assert.Equal(t, []retryEvent{{reason: assistants.RetryReasonContextLength, attempt: 1, err: true}}, rec.retries)
+		require.Len(t, sent, 2)
+		// the examples are dropped
+		assert.Len(t, sent[0], 4)
+		assert.Equal(t, []string{"human: input"}, printMessages(sent[1][1:]))
+	})
+
+	t.Run("once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		llm := newModel(ctrl, llms.ProviderOpenAI, 1, 2)
+		llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, contextErr).Times(2)
+
+		ag := newAssistant(llm, examples)
+		_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		assert.ErrorIs(t, err, llms.ErrContextLengthExceeded)
+		assert.ErrorContains(t, err, "model primary: failed to generate content from LLM")
+	})
+
+	t.Run("not_reduced", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		llm := newModel(ctrl, llms.ProviderOpenAI, 1, 2)
+		llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, contextErr).Times(1)
+
+		rec := &retryRecorder{}
+		ag := newAssistant(llm, assistants.WithCallback(rec))
+		_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		assert.ErrorIs(t, err, llms.ErrContextLengthExceeded)
+		assert.Empty(t, rec.retries)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		llm := newModel(ctrl, llms.ProviderOpenAI, 1, 2)
+		llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, contextErr).Times(1)
+
+		ag := newAssistant(llm, examples, assistants.WithContextRecovery(nil))
+		_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		assert.ErrorIs(t, err, llms.ErrContextLengthExceeded)
+	})
+
+	t.Run("summarize", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		llm := newModel(ctrl, llms.ProviderOpenAI, 1, 2)
+		var sent [][]llms.Message
+		gomock.InOrder(
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(nil, errors.Wrap(contextErr, "call")),
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+					sent = append(sent, messages)
+					return done, nil
+				}),
+		)
+
+		ag := newAssistant(llm, examples, assistants.WithContextRecovery(assistants.SummarizeContext(
+			func(context.Context, string) (string, error) {
+				return "examples", nil
+			})))
+		_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		require.NoError(t, err)
+		require.Len(t, sent, 1)
+		require.Len(t, sent[0], 3)
+		assert.Equal(t, llms.RoleSystem, sent[0][0].Role)
+		assert.Equal(t, []string{
+			"system: " + assistants.ContextSummaryPrefix + "examples",
+			"human: input",
+		}, printMessages(sent[0][1:]))
+	})
+
+	t.Run("react", func(t *testing.T) {
+		// Cloudflare does not support function calling
+		ctrl := gomock.NewController(t)
+		llm := newModel(ctrl, llms.ProviderCloudflare, 2, 2)
+		tool := mocktools.NewMockTool[any, any](ctrl)
+		tool.EXPECT().Name().Return("search").Times(2)
+		tool.EXPECT().Description().Return("Searches the web.").Times(2)
+		tool.EXPECT().Parameters().Return(nil).Times(2)
+		gomock.InOrder(
+			tool.EXPECT().Call(gomock.Any(), `{"q":"first"}`).Return("large output", nil),
+			tool.EXPECT().Call(gomock.Any(), `{"q":"second"}`).Return("last output", nil),
+		)
+
+		action := func(q string) *llms.ContentResponse {
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+				Content: "Action: search\nAction Input: {\"q\":\"" + q + "\"}",
+			}}}
+		}
+		var sent []llms.Message
+		gomock.InOrder(
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(action("first"), nil),
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(action("second"), nil),
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, contextErr),
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+					sent = messages
+					return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "Final Answer: done"}}}, nil
+				}),
+		)
+
+		ag := newAssistant(llm, examples).WithTools(tool)
+		resp, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		require.NoError(t, err)
+		assert.Equal(t, "done", resp.String())
+		require.NotEmpty(t, sent)
+		// the question is kept, the observations are human messages
+		assert.Equal(t, []string{
+			"human: input",
+			"ai: Action: search\nAction Input: {\"q\":\"first\"}",
+			"human: Observation: [the tool output is removed to fit the context window: 12 bytes]",
+			"ai: Action: search\nAction Input: {\"q\":\"second\"}",
+			"human: Observation: last output",
+		}, printMessages(sent[1:]))
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		llm := newModel(ctrl, llms.ProviderOpenAI, 1, 2)
+		var sent []llms.Message
+		gomock.InOrder(
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, _ []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}),
+			// the final round
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, contextErr),
+			llm.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+					sent = messages
+					return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "best effort answer"}}}, nil
+				}),
+		)
+
+		ag := newAssistant(llm, examples, assistants.WithDeadline(500*time.Millisecond))
+		resp, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		require.NoError(t, err)
+		assert.True(t, resp.DeadlineReached)
+		assert.Equal(t, "best effort answer", resp.String())
+		require.NotEmpty(t, sent)
+		// the question is kept with the final answer prompt
+		assert.Equal(t, []string{
+			"human: input",
+			"human: " + assistants.DeadlineFinalAnswerPrompt,
+		}, printMessages(sent[1:]))
+	})
+}
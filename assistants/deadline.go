@@ -0,0 +1,47 @@
+package assistants
+
+import (
+	"context"
+	"time"
+)
+
+// DeadlineReserveRatio is the fraction of the run deadline reserved for the final answer,
+// see WithDeadline.
+var DeadlineReserveRatio = 0.2
+
+// DeadlineFinalAnswerPrompt is added to the message history,
+// when the run deadline is nearly exhausted, to request the final answer.
+var DeadlineFinalAnswerPrompt = "The time budget for this task is exhausted. " +
+	"Do not call any tools. Provide the best possible final answer now, based on the information gathered so far, " +
+	"and state briefly what remains incomplete."
+
+// WithDeadline sets the wall-clock budget of the whole run, including the LLM rounds and the tool calls.
+// The LLM rounds and the tool calls are cancelled when the DeadlineReserveRatio of the budget is left,
+// then the LLM is asked for the best effort final answer without the tools,
+// instead of failing the run with the context deadline exceeded error.
+// Response.DeadlineReached is set when the final answer is requested.
+func WithDeadline(d time.Duration) Option {
+	return func(o *Config) {
+		o.Deadline = d
+	}
+}
+
+// roundContext returns the context of the LLM rounds and the tool calls,
+// that expires before the run deadline, to leave the time for the final answer.
+func (c *Config) roundContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Deadline <= 0 {
+		return ctx, func() {}
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}
+	}
+	reserve := time.Duration(float64(c.Deadline) * DeadlineReserveRatio)
+	return context.WithDeadline(ctx, deadline.Add(-reserve))
+}
+
+// deadlineReached returns true when the rounds context expired,
+// but the run has still the time for the final answer.
+func deadlineReached(ctx, roundCtx context.Context) bool {
+	return roundCtx.Err() != nil && ctx.Err() == nil
+}
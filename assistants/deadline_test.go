@@ -0,0 +1,98 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_Deadline(t *testing.T) {
+	t.Parallel()
+
+	finalAnswer := func(t *testing.T) func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+		return func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			opts := llms.CallOptions{}
+			for _, opt := range options {
+				opt(&opts)
+			}
+			assert.Equal(t, llms.ToolChoiceNone, opts.ToolChoice)
+			assert.NoError(t, ctx.Err())
+			last := messages[len(messages)-1]
+			assert.Equal(t, llms.RoleHuman, last.Role)
+			assert.Equal(t, assistants.DeadlineFinalAnswerPrompt, last.Parts[0].(llms.TextContent).Text)
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "best effort answer"}}}, nil
+		}
+	}
+
+	slowLLM := func(ctx context.Context, _ []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	toolCall := &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			ToolCalls: []llms.ToolCall{{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "slow_tool", Arguments: "{}"}}},
+		}},
+	}
+
+	tcases := []struct {
+		name     string
+		slowTool bool
+	}{
+		{name: "slow tool", slowTool: true},
+		{name: "slow LLM"},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockLLM := mockllms.NewMockModel(ctrl)
+			mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+
+			var list []tools.ITool
+			if tc.slowTool {
+				mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+				list = append(list, newCancelTool(ctrl, "slow_tool", func(ctx context.Context, _ string) (string, error) {
+					<-ctx.Done()
+					return "partial", ctx.Err()
+				}))
+				gomock.InOrder(
+					mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(toolCall, nil),
+					mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(finalAnswer(t)),
+				)
+			} else {
+				mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+				gomock.InOrder(
+					mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(slowLLM),
+					mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(finalAnswer(t)),
+				)
+			}
+
+			ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+				prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+				assistants.WithMode(encoding.ModePlainText),
+				assistants.WithDeadline(500*time.Millisecond),
+			).WithTools(list...)
+
+			ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+			started := time.Now()
+			resp, err := ag.Call(ctx, &assistants.CallInput{Input: "Research the topic."})
+			require.NoError(t, err)
+			assert.True(t, resp.DeadlineReached)
+			require.NotEmpty(t, resp.Choices)
+			assert.Equal(t, "best effort answer", resp.Choices[0].Content)
+			assert.Less(t, time.Since(started), 500*time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package assistants
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// CostFunc returns the cost of the LLM call with the usage.
+type CostFunc func(model string, usage *llms.Usage) float64
+
+// DelegationBudget limits the resources used by the delegate assistant of AssistantTool.
+// The budget is enforced recursively: the LLM calls of the nested delegates
+// are charged to the budgets of all the parent delegates.
+// Zero value of the limit means no limit.
+type DelegationBudget struct {
+	// MaxTokens is the maximum number of the total tokens.
+	MaxTokens uint64
+	// MaxCost is the maximum cost, CostFunc must be provided.
+	MaxCost float64
+	// CostFunc returns the cost of the LLM call.
+	CostFunc CostFunc
+	// MaxDepth is the maximum depth of the delegation, including the delegate itself.
+	// 1 means that the delegate can not call other assistant tools.
+	MaxDepth int
+}
+
+// Validate returns an error if the budget is misconfigured.
+func (b *DelegationBudget) Validate() error {
+	if b.MaxCost > 0 && b.CostFunc == nil {
+		return errors.New("MaxCost requires CostFunc")
+	}
+	if b.MaxCost < 0 || b.MaxDepth < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+// delegationUsage is the usage charged to the budget.
+type delegationUsage struct {
+	tokens uint64
+	cost   float64
+}
+
+type delegationKey struct{}
+
+// delegation is the node of the delegation chain in the context,
+// created by AssistantTool on each call of the delegate.
+type delegation struct {
+	parent *delegation
+	name   string
+	depth  int
+	budget *DelegationBudget
+
+	lock  sync.Mutex
+	usage delegationUsage
+}
+
+func delegationFromContext(ctx context.Context) *delegation {
+	d, _ := ctx.Value(delegationKey{}).(*delegation)
+	return d
+}
+
+// DelegationDepth returns the number of the nested assistant tool calls in the context.
+func DelegationDepth(ctx context.Context) int {
+	if d := delegationFromContext(ctx); d != nil {
+		return d.depth
+	}
+	return 0
+}
+
+// withDelegation returns the context for the delegate call,
+// or an error if the budget is misconfigured, or the depth of any parent budget is exceeded.
+func withDelegation(ctx context.Context, name string, budget *DelegationBudget) (context.Context, error) {
+	if budget != nil {
+		if err := budget.Validate(); err != nil {
+			return ctx, errors.WithMessagef(err, "assistant tool %s: invalid budget", name)
+		}
+	}
+	parent := delegationFromContext(ctx)
+	d := &delegation{
+		parent: parent,
+		name:   name,
+		depth:  1,
+		budget: budget,
+	}
+	if parent != nil {
+		d.depth = parent.depth + 1
+	}
+	for n := d; n != nil; n = n.parent {
+		if n.budget != nil && n.budget.MaxDepth > 0 && d.depth-n.depth >= n.budget.MaxDepth {
+			return ctx, errors.Wrapf(ErrBudgetExceeded, "assistant tool %s: delegation depth of %s exceeds %d", name, n.name, n.budget.MaxDepth)
+		}
+	}
+	return context.WithValue(ctx, delegationKey{}, d), nil
+}
+
+// checkDelegationBudget returns an error if any budget in the delegation chain is exhausted.
+func checkDelegationBudget(ctx context.Context) error {
+	for n := delegationFromContext(ctx); n != nil; n = n.parent {
+		if n.budget == nil {
+			continue
+		}
+		n.lock.Lock()
+		usage := n.usage
+		n.lock.Unlock()
+
+		if n.budget.MaxTokens > 0 && usage.tokens >= n.budget.MaxTokens {
+			return errors.Wrapf(ErrBudgetExceeded, "assistant tool %s: used %d of %d tokens", n.name, usage.tokens, n.budget.MaxTokens)
+		}
+		if n.budget.MaxCost > 0 && usage.cost >= n.budget.MaxCost {
+			return errors.Wrapf(ErrBudgetExceeded, "assistant tool %s: used %.4f of %.4f cost", n.name, usage.cost, n.budget.MaxCost)
+		}
+	}
+	return nil
+}
+
+// chargeDelegationBudget charges the LLM call to all the budgets in the delegation chain.
+func chargeDelegationBudget(ctx context.Context, model string, usage *llms.Usage) {
+	for n := delegationFromContext(ctx); n != nil; n = n.parent {
+		n.lock.Lock()
+		n.usage.tokens += usage.TotalTokens
+		if n.budget != nil && n.budget.CostFunc != nil {
+			n.usage.cost += n.budget.CostFunc(model, usage)
+		}
+		n.lock.Unlock()
+	}
+}
@@ -0,0 +1,45 @@
+package assistants
+
+import (
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// PreparedCall is the LLM call assembled by the run in the dry-run mode, see WithDryRun.
+type PreparedCall struct {
+	// Model is the name of the model.
+	Model string
+	// Provider is the provider of the model.
+	Provider llms.ProviderType
+	// Messages are the messages sent to the LLM,
+	// after the message transformers and the history repair are applied.
+	Messages []llms.Message
+	// Tools are the tool definitions sent to the LLM, empty in ReAct mode,
+	// where the tools are described in the system prompt.
+	Tools []llms.Tool
+	// Options are the resolved call options,
+	// including the callback functions set by the assistant.
+	Options llms.CallOptions
+}
+
+// WithDryRun is an option to assemble the LLM call without calling the provider.
+// The run returns the Response with PreparedCall, the message history is not updated,
+// and the output is not parsed.
+// It is useful for debugging the prompt assembly and for the snapshot tests.
+func WithDryRun() Option {
+	return func(o *Config) {
+		o.DryRun = true
+	}
+}
+
+func newPreparedCall(llm llms.Model, model string, messages []llms.Message, options []llms.CallOption) *PreparedCall {
+	call := &PreparedCall{
+		Model:    model,
+		Provider: llm.GetProviderType(),
+		Messages: messages,
+	}
+	for _, opt := range options {
+		opt(&call.Options)
+	}
+	call.Tools = call.Options.Tools
+	return call
+}
@@ -0,0 +1,79 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_DryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// GenerateContent is not expected
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(4)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(4)
+
+	tool := mocktools.NewMockTool[any, any](ctrl)
+	tool.EXPECT().Name().Return("search_tool").Times(1)
+	tool.EXPECT().Description().Return("desc").Times(1)
+	tool.EXPECT().Parameters().Return(nil).Times(1)
+
+	memstore := store.NewMemoryStore()
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithMessageStore(memstore),
+		assistants.WithTemperature(0.2),
+	).WithTools(tool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{
+		Input:   "weather?",
+		Options: []assistants.Option{assistants.WithDryRun()},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Choices)
+	assert.EqualValues(t, 0, resp.Usage.LlmCallCount)
+
+	call := resp.PreparedCall
+	require.NotNil(t, call)
+	assert.Equal(t, "gpt-4o", call.Model)
+	assert.Equal(t, llms.ProviderOpenAI, call.Provider)
+	require.Len(t, call.Messages, 2)
+	assert.Equal(t, llms.RoleSystem, call.Messages[0].Role)
+	require.IsType(t, llms.TextContent{}, call.Messages[0].Parts[0])
+	assert.Contains(t, call.Messages[0].Parts[0].(llms.TextContent).Text, "You are helpful and friendly AI assistant.")
+	assert.Equal(t, llms.RoleHuman, call.Messages[1].Role)
+	assert.Equal(t, llms.TextPart("weather?"), call.Messages[1].Parts[0])
+	require.Len(t, call.Tools, 1)
+	assert.Equal(t, "search_tool", call.Tools[0].Function.Name)
+	assert.Equal(t, "gpt-4o", call.Options.Model)
+	assert.Equal(t, 0.2, call.Options.Temperature)
+
+	// the message history is not updated
+	assert.Empty(t, memstore.Messages(ctx))
+
+	// without the dry-run the call is assembled in the same way
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			assert.Equal(t, call.Messages, messages)
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "sunny"}}}, nil
+		})
+	resp, err = ag.Call(ctx, &assistants.CallInput{Input: "weather?"})
+	require.NoError(t, err)
+	assert.Nil(t, resp.PreparedCall)
+	assert.Equal(t, "sunny", resp.Choices[0].Content)
+}
@@ -0,0 +1,71 @@
+package assistants
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/events"
+	"github.com/effective-security/xlog"
+)
+
+// WithEventBus is an option to publish the lifecycle events of the runs to the bus,
+// in addition to the Callback, see events.Type.
+// The bus is propagated to the nested assistants, as the Callback.
+func WithEventBus(bus events.Bus) Option {
+	return func(o *Config) {
+		o.EventBus = bus
+	}
+}
+
+// publish sets the common fields of the event, and publishes it to the bus.
+// The publishing errors are logged, and do not fail the run.
+func (c *Config) publish(ctx context.Context, a IAssistant, event *events.Event) {
+	if c.EventBus == nil {
+		return
+	}
+	event.ID = chatmodel.NewChatID()
+	event.Time = time.Now().UTC()
+	event.Assistant = a.Name()
+	if event.Model == "" {
+		event.Model = c.Model
+	}
+	if chatCtx := chatmodel.GetChatContext(ctx); chatCtx != nil {
+		event.TenantID = chatCtx.GetTenantID()
+		event.ChatID = chatCtx.GetChatID()
+		event.RunID = chatCtx.GetRunID()
+	}
+	if err := c.EventBus.Publish(ctx, event); err != nil {
+		logger.ContextKV(ctx, xlog.WARNING,
+			"assistant", event.Assistant,
+			"status", "event_publish_failed",
+			"event", event.Type,
+			"err", err.Error(),
+		)
+	}
+}
+
+// publishRunFinished publishes the finished run, and the exceeded budget.
+func (c *Config) publishRunFinished(ctx context.Context, a IAssistant, resp *Response, err error, started time.Time) {
+	if c.EventBus == nil {
+		return
+	}
+	event := &events.Event{
+		Type:     events.RunFinished,
+		Status:   "succeeded",
+		Duration: time.Since(started),
+	}
+	if resp != nil {
+		usage := resp.Usage
+		event.Usage = &usage
+	}
+	if err != nil {
+		if errors.Is(err, ErrBudgetExceeded) {
+			c.publish(ctx, a, &events.Event{Type: events.BudgetExceeded, Error: err.Error()})
+		}
+		event.Status = "failed"
+		event.Error = err.Error()
+	}
+	c.publish(ctx, a, event)
+}
@@ -0,0 +1,96 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/events"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_EventBus(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(4)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "search", Arguments: "{}"}}},
+			}},
+		}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "answer"}},
+		}, nil),
+	)
+
+	bus := events.NewChannelBus()
+	ch, unsubscribe := bus.Subscribe(10)
+	defer unsubscribe()
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithEventBus(bus),
+		assistants.WithMiddleware(assistants.NewBudget(0, 2).Wrap),
+	).WithTools(newCancelTool(ctrl, "search", func(context.Context, string) (string, error) {
+		return "found", nil
+	}))
+
+	chatCtx := chatmodel.NewChatContext("tenant1", "chat1", nil)
+	ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+
+	_, err := ag.Call(ctx, &assistants.CallInput{Input: "Research the topic."})
+	require.NoError(t, err)
+
+	// the budget of LLM calls is exhausted by the first run
+	_, err = ag.Call(ctx, &assistants.CallInput{Input: "Research the topic."})
+	require.ErrorIs(t, err, assistants.ErrBudgetExceeded)
+
+	var list []*events.Event
+	for range 6 {
+		list = append(list, <-ch)
+	}
+	assert.Empty(t, ch)
+	assert.Zero(t, bus.Dropped())
+
+	var types []events.Type
+	for _, e := range list {
+		types = append(types, e.Type)
+		assert.NotEmpty(t, e.ID)
+		assert.False(t, e.Time.IsZero())
+		assert.Equal(t, ag.Name(), e.Assistant)
+		assert.Equal(t, "gpt-4o", e.Model)
+		assert.Equal(t, "tenant1", e.TenantID)
+		assert.Equal(t, "chat1", e.ChatID)
+	}
+	assert.Equal(t, []events.Type{
+		events.RunStarted, events.ToolCall, events.RunFinished,
+		events.RunStarted, events.BudgetExceeded, events.RunFinished,
+	}, types)
+
+	toolCall := list[1]
+	assert.Equal(t, "search", toolCall.Tool)
+	assert.Equal(t, "call_1", toolCall.ToolCallID)
+	assert.Equal(t, "succeeded", toolCall.Status)
+
+	finished := list[2]
+	assert.Equal(t, "succeeded", finished.Status)
+	require.NotNil(t, finished.Usage)
+	assert.EqualValues(t, 2, finished.Usage.LlmCallCount)
+
+	failed := list[5]
+	assert.Equal(t, "failed", failed.Status)
+	assert.Equal(t, err.Error(), failed.Error)
+	assert.Equal(t, err.Error(), list[4].Error)
+}
@@ -0,0 +1,207 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/schema"
+	xslices "github.com/effective-security/x/slices"
+	"github.com/invopop/jsonschema"
+)
+
+// ExportFormat is the format of the exported assistant definition.
+type ExportFormat string
+
+const (
+	// ExportOpenAIAssistant is the OpenAI Assistants API create request.
+	ExportOpenAIAssistant ExportFormat = "openai_assistant"
+	// ExportOpenAIAgent is the OpenAI Agents SDK agent definition.
+	ExportOpenAIAgent ExportFormat = "openai_agent"
+)
+
+// Definition is a provider neutral definition of the configured Assistant,
+// that can be exported to other agent frameworks.
+type Definition struct {
+	Name         string
+	Description  string
+	Instructions string
+	Model        string
+	Temperature  *float64
+	TopP         *float64
+	MaxTokens    int
+	Tools        []llms.Tool
+	// ResponseFormat is set when the provider supports the structured output,
+	// otherwise the output schema is included in the Instructions.
+	ResponseFormat *schema.ResponseFormat
+}
+
+// Definition returns the definition of the Assistant,
+// with the system prompt rendered with the prompt inputs.
+func (a *Assistant[O]) Definition(ctx context.Context, promptInputs map[string]any) (*Definition, error) {
+	instructions, err := a.GetSystemPrompt(ctx, "", promptInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := a.cfg
+	def := &Definition{
+		Name:           a.Name(),
+		Description:    a.Description(),
+		Instructions:   instructions,
+		Model:          cfg.Model,
+		MaxTokens:      cfg.MaxTokens,
+		Tools:          slices.Concat(cfg.Tools, a.llmToolDefs),
+		ResponseFormat: cfg.ResponseFormat,
+	}
+	if def.Model == "" {
+		def.Model = a.LLM.GetName()
+	}
+	if cfg.temperatureSet {
+		def.Temperature = &cfg.Temperature
+	}
+	if cfg.toppSet {
+		def.TopP = &cfg.TopP
+	}
+	return def, nil
+}
+
+// Export returns the JSON definition in the specified format.
+func (d *Definition) Export(format ExportFormat) ([]byte, error) {
+	var v any
+	switch format {
+	case ExportOpenAIAssistant:
+		v = d.toOpenAIAssistant()
+	case ExportOpenAIAgent:
+		v = d.toOpenAIAgent()
+	default:
+		return nil, errors.Newf("unsupported export format: %s", format)
+	}
+	js, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal definition")
+	}
+	return js, nil
+}
+
+// OpenAIAssistant is the OpenAI Assistants API create request.
+type OpenAIAssistant struct {
+	Model          string                 `json:"model"`
+	Name           string                 `json:"name,omitempty"`
+	Description    string                 `json:"description,omitempty"`
+	Instructions   string                 `json:"instructions,omitempty"`
+	Tools          []llms.Tool            `json:"tools"`
+	Temperature    *float64               `json:"temperature,omitempty"`
+	TopP           *float64               `json:"top_p,omitempty"`
+	ResponseFormat *schema.ResponseFormat `json:"response_format,omitempty"`
+}
+
+func (d *Definition) toOpenAIAssistant() *OpenAIAssistant {
+	res := &OpenAIAssistant{
+		Model:          d.Model,
+		Name:           d.Name,
+		Description:    xslices.StringUpto(d.Description, 512),
+		Instructions:   d.Instructions,
+		Tools:          []llms.Tool{},
+		Temperature:    d.Temperature,
+		TopP:           d.TopP,
+		ResponseFormat: d.ResponseFormat,
+	}
+	for _, t := range d.Tools {
+		if t.Function == nil {
+			continue
+		}
+		fn := *t.Function
+		fn.Parameters = toolParameters(fn.Parameters)
+		res.Tools = append(res.Tools, llms.Tool{
+			Type:     "function",
+			Function: &fn,
+		})
+	}
+	return res
+}
+
+// OpenAIAgent is the OpenAI Agents SDK agent definition.
+type OpenAIAgent struct {
+	Name               string                   `json:"name"`
+	HandoffDescription string                   `json:"handoff_description,omitempty"`
+	Instructions       string                   `json:"instructions,omitempty"`
+	Model              string                   `json:"model"`
+	ModelSettings      *OpenAIAgentSettings     `json:"model_settings,omitempty"`
+	Tools              []*OpenAIAgentTool       `json:"tools"`
+	OutputType         *OpenAIAgentOutputSchema `json:"output_type,omitempty"`
+}
+
+// OpenAIAgentSettings is the model settings of the OpenAI Agents SDK agent.
+type OpenAIAgentSettings struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+}
+
+// OpenAIAgentTool is the function tool of the OpenAI Agents SDK agent.
+type OpenAIAgentTool struct {
+	Type             string             `json:"type"`
+	Name             string             `json:"name"`
+	Description      string             `json:"description"`
+	ParamsJSONSchema *jsonschema.Schema `json:"params_json_schema"`
+	StrictJSONSchema bool               `json:"strict_json_schema"`
+}
+
+// OpenAIAgentOutputSchema is the output type of the OpenAI Agents SDK agent.
+type OpenAIAgentOutputSchema struct {
+	Name       string                                   `json:"name"`
+	JSONSchema *schema.ResponseFormatJSONSchemaProperty `json:"json_schema"`
+	Strict     bool                                     `json:"strict"`
+}
+
+func (d *Definition) toOpenAIAgent() *OpenAIAgent {
+	res := &OpenAIAgent{
+		Name:               d.Name,
+		HandoffDescription: d.Description,
+		Instructions:       d.Instructions,
+		Model:              d.Model,
+		Tools:              []*OpenAIAgentTool{},
+	}
+	if d.Temperature != nil || d.TopP != nil || d.MaxTokens > 0 {
+		res.ModelSettings = &OpenAIAgentSettings{
+			Temperature: d.Temperature,
+			TopP:        d.TopP,
+			MaxTokens:   d.MaxTokens,
+		}
+	}
+	for _, t := range d.Tools {
+		if t.Function == nil {
+			continue
+		}
+		res.Tools = append(res.Tools, &OpenAIAgentTool{
+			Type:             "function",
+			Name:             t.Function.Name,
+			Description:      t.Function.Description,
+			ParamsJSONSchema: toolParameters(t.Function.Parameters),
+			StrictJSONSchema: t.Function.Strict,
+		})
+	}
+	if d.ResponseFormat != nil && d.ResponseFormat.JSONSchema != nil {
+		res.OutputType = &OpenAIAgentOutputSchema{
+			Name:       d.ResponseFormat.JSONSchema.Name,
+			JSONSchema: d.ResponseFormat.JSONSchema.Schema,
+			Strict:     d.ResponseFormat.JSONSchema.Strict,
+		}
+	}
+	return res
+}
+
+// toolParameters returns the parameters schema,
+// OpenAI requires an object schema even for tools without parameters.
+func toolParameters(params *jsonschema.Schema) *jsonschema.Schema {
+	if params != nil {
+		return params
+	}
+	return &jsonschema.Schema{
+		Type:       "object",
+		Properties: jsonschema.NewProperties(),
+	}
+}
@@ -0,0 +1,138 @@
+package assistants_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type weatherInput struct {
+	City string `json:"city" jsonschema:"title=City,description=The city name."`
+}
+
+func newExportAssistant(t *testing.T, provider llms.ProviderType) *assistants.Assistant[chatmodel.OutputResult] {
+	ctrl := gomock.NewController(t)
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(provider).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(1)
+
+	weather := mocktools.NewMockTool[any, any](ctrl)
+	weather.EXPECT().Name().Return("get_weather").Times(1)
+	weather.EXPECT().Description().Return("Returns the weather.").Times(1)
+	weather.EXPECT().Parameters().Return(schema.JSONSchema(reflect.TypeOf(weatherInput{}))).Times(1)
+
+	noParams := mocktools.NewMockTool[any, any](ctrl)
+	noParams.EXPECT().Name().Return("get_time").Times(1)
+	noParams.EXPECT().Description().Return("Returns the time.").Times(1)
+	noParams.EXPECT().Parameters().Return(nil).Times(1)
+
+	sysprompt := prompts.NewPromptTemplate("You are a weather assistant for {{.region}}.", []string{"region"})
+	return assistants.NewAssistant[chatmodel.OutputResult](mockLLM, sysprompt,
+		assistants.WithMode(encoding.ModeJSONSchema),
+		assistants.WithTemperature(0.2),
+		assistants.WithMaxTokens(1000),
+	).
+		WithName("weather").
+		WithDescription("Answers weather questions.").
+		WithTools(weather, noParams)
+}
+
+func Test_Assistant_Export(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	a := newExportAssistant(t, llms.ProviderOpenAI)
+
+	def, err := a.Definition(ctx, map[string]any{"region": "Europe"})
+	require.NoError(t, err)
+	assert.Equal(t, "weather", def.Name)
+	assert.Equal(t, "gpt-4o", def.Model)
+	assert.Equal(t, "You are a weather assistant for Europe.", def.Instructions)
+	require.NotNil(t, def.Temperature)
+	assert.Equal(t, 0.2, *def.Temperature)
+	assert.Nil(t, def.TopP)
+	assert.Len(t, def.Tools, 2)
+	require.NotNil(t, def.ResponseFormat)
+
+	t.Run("openai_assistant", func(t *testing.T) {
+		js, err := def.Export(assistants.ExportOpenAIAssistant)
+		require.NoError(t, err)
+
+		var res map[string]any
+		require.NoError(t, json.Unmarshal(js, &res))
+		assert.Equal(t, "gpt-4o", res["model"])
+		assert.Equal(t, "weather", res["name"])
+		assert.Equal(t, "Answers weather questions.", res["description"])
+		assert.Equal(t, "You are a weather assistant for Europe.", res["instructions"])
+		assert.Equal(t, 0.2, res["temperature"])
+		assert.NotContains(t, res, "top_p")
+
+		tools := res["tools"].([]any)
+		require.Len(t, tools, 2)
+		tool := tools[0].(map[string]any)
+		assert.Equal(t, "function", tool["type"])
+		fn := tool["function"].(map[string]any)
+		assert.Equal(t, "get_weather", fn["name"])
+		assert.Contains(t, fn["parameters"].(map[string]any)["properties"], "city")
+		params := tools[1].(map[string]any)["function"].(map[string]any)["parameters"].(map[string]any)
+		assert.Equal(t, "object", params["type"])
+
+		rf := res["response_format"].(map[string]any)
+		assert.Equal(t, "json_schema", rf["type"])
+	})
+
+	t.Run("openai_agent", func(t *testing.T) {
+		js, err := def.Export(assistants.ExportOpenAIAgent)
+		require.NoError(t, err)
+
+		var res map[string]any
+		require.NoError(t, json.Unmarshal(js, &res))
+		assert.Equal(t, "weather", res["name"])
+		assert.Equal(t, "Answers weather questions.", res["handoff_description"])
+		assert.Equal(t, "gpt-4o", res["model"])
+		assert.Equal(t, map[string]any{"temperature": 0.2, "max_tokens": float64(1000)}, res["model_settings"])
+
+		tools := res["tools"].([]any)
+		require.Len(t, tools, 2)
+		tool := tools[0].(map[string]any)
+		assert.Equal(t, "get_weather", tool["name"])
+		assert.Equal(t, "Returns the weather.", tool["description"])
+		assert.Contains(t, tool["params_json_schema"].(map[string]any)["properties"], "city")
+		assert.Equal(t, false, tool["strict_json_schema"])
+
+		out := res["output_type"].(map[string]any)
+		assert.NotEmpty(t, out["name"])
+		assert.Equal(t, "object", out["json_schema"].(map[string]any)["type"])
+	})
+
+	_, err = def.Export("unknown")
+	assert.EqualError(t, err, "unsupported export format: unknown")
+}
+
+func Test_Assistant_Export_NoStructuredOutput(t *testing.T) {
+	t.Parallel()
+
+	// Cloudflare does not support JSON schema, the output schema is in the instructions
+	a := newExportAssistant(t, llms.ProviderCloudflare)
+	def, err := a.Definition(context.Background(), map[string]any{"region": "Asia"})
+	require.NoError(t, err)
+	assert.Nil(t, def.ResponseFormat)
+	assert.Contains(t, def.Instructions, "# OUTPUT SCHEMA")
+
+	js, err := def.Export(assistants.ExportOpenAIAgent)
+	require.NoError(t, err)
+	assert.NotContains(t, string(js), "output_type")
+}
@@ -0,0 +1,126 @@
+package assistants
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/x/slices"
+	"github.com/effective-security/xlog"
+)
+
+// FallbackCallback is an optional interface of the Callback,
+// to receive the model fallbacks, see WithFallbackModels.
+type FallbackCallback interface {
+	// OnModelFallback is called when the LLM call failed with the model,
+	// before it is retried with the next fallback model.
+	OnModelFallback(ctx context.Context, e *ModelFallbackEvent)
+}
+
+// ModelFallbackEvent is reported when the failed LLM call is retried with the fallback model.
+type ModelFallbackEvent struct {
+	Assistant IAssistant
+	// Failed is the model of the failed call.
+	Failed llms.Model
+	// Next is the fallback model of the retried call.
+	Next llms.Model
+	Err  error
+}
+
+// FallbackPolicy returns true, if the failed LLM call should be retried with the fallback model.
+type FallbackPolicy func(err error) bool
+
+// WithFallbackModels is an option to retry the failed LLM call with the fallback models, in order.
+// The fallback models must support the tools and the response format of the assistant,
+// as they are configured for the primary model.
+// The name of the model that generated the response is set in the GenerationInfo
+// of the choices with the llms.GenerationInfoModel key.
+func WithFallbackModels(models ...llms.Model) Option {
+	return func(o *Config) {
+		o.FallbackModels = models
+	}
+}
+
+// WithFallbackPolicy is an option to decide which errors are retried with the fallback models,
+// by default IsFallbackError.
+func WithFallbackPolicy(policy FallbackPolicy) Option {
+	return func(o *Config) {
+		o.FallbackPolicy = policy
+	}
+}
+
+// serverErrorRe matches the 5xx and 429 status codes in the errors of the providers
+var serverErrorRe = regexp.MustCompile(`(?:status code:? |error |": )(?:429|5\d\d)\b`)
+
+// fallbackErrors are the messages of the errors that are not classified by the providers
+var fallbackErrors = []string{"timeout", "overloaded"}
+
+// IsFallbackError returns true for the errors that may succeed with another model:
+// the server errors, the rate limits, the timeouts, the exceeded context length
+// and the content filter blocks, see llms.ProviderError.
+// The cancelled calls, the bad requests and the authentication errors are not retried.
+func IsFallbackError(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled),
+		errors.Is(err, llms.ErrBadRequest), errors.Is(err, llms.ErrAuth):
+		return false
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, llms.ErrRateLimited),
+		errors.Is(err, llms.ErrServerOverloaded),
+		errors.Is(err, llms.ErrContextLengthExceeded),
+		errors.Is(err, llms.ErrContentFiltered):
+		return true
+	}
+	// the errors of the providers that are not classified
+	msg := strings.ToLower(err.Error())
+	return serverErrorRe.MatchString(msg) || slices.StringContainsOneOf(msg, fallbackErrors)
+}
+
+// generateContent calls the LLM, and the fallback models if the call fails,
+// it returns the response and the model that generated it, or the last failed model.
+func (a *Assistant[O]) generateContent(ctx context.Context, cfg *Config, messages []llms.Message, options []llms.CallOption) (*llms.ContentResponse, llms.Model, error) {
+	llm := a.LLM
+	resp, err := llm.GenerateContent(ctx, messages, options...)
+	for _, next := range cfg.FallbackModels {
+		if err == nil || ctx.Err() != nil || !cfg.shouldFallback(err) {
+			break
+		}
+
+		logger.ContextKV(ctx, xlog.WARNING,
+			"assistant", a.Name(),
+			"reason", "model_fallback",
+			"model", llm.GetName(),
+			"fallback", next.GetName(),
+			"err", err.Error(),
+		)
+		if fc, ok := cfg.CallbackHandler.(FallbackCallback); ok {
+			fc.OnModelFallback(ctx, &ModelFallbackEvent{Assistant: a, Failed: llm, Next: next, Err: err})
+		}
+
+		llm = next
+		// the model of the call options is the primary model
+		resp, err = llm.GenerateContent(ctx, messages, append(options[:len(options):len(options)], llms.WithModel(llm.GetName()))...)
+	}
+	if err != nil {
+		return nil, llm, err
+	}
+
+	if len(cfg.FallbackModels) > 0 {
+		for _, choice := range resp.Choices {
+			if choice.GenerationInfo == nil {
+				choice.GenerationInfo = map[string]any{}
+			}
+			choice.GenerationInfo[llms.GenerationInfoModel] = llm.GetName()
+		}
+	}
+	return resp, llm, nil
+}
+
+func (c *Config) shouldFallback(err error) bool {
+	if c.FallbackPolicy != nil {
+		return c.FallbackPolicy(err)
+	}
+	return IsFallbackError(err)
+}
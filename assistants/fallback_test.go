@@ -0,0 +1,137 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/llmratelimit"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type fallbackRecorder struct {
+	callbacks.Noop
+	fallbacks []string
+}
+
+func (r *fallbackRecorder) OnModelFallback(_ context.Context, e *assistants.ModelFallbackEvent) {
+	r.fallbacks = append(r.fallbacks, e.Failed.GetName()+"->"+e.Next.GetName())
+}
+
+func Test_IsFallbackError(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		name string
+		err  error
+		exp  bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"deadline", errors.Wrap(context.DeadlineExceeded, "call"), true},
+		{"rate_limited", errors.Wrap(llmratelimit.ErrRateLimited, "call"), true},
+		{"500", errors.New("API returned unexpected status code: 500: internal error"), true},
+		{"429", errors.New("API returned unexpected status code: 429: too many requests"), true},
+		{"overloaded", errors.New("Overloaded"), true},
+		{"content_filter", errors.Wrap(&llms.ContentFilterError{Categories: []string{llms.ContentFilterHate}}, "call"), true},
+		{"400", errors.New("API returned unexpected status code: 400: invalid request"), false},
+		{"auth", errors.New("invalid api key"), false},
+		{"provider_rate_limited", errors.Wrap(&llms.ProviderError{Kind: llms.ErrRateLimited}, "call"), true},
+		{"provider_overloaded", &llms.ProviderError{Kind: llms.ErrServerOverloaded, Err: errors.New("overloaded_error")}, true},
+		{"provider_context_length", &llms.ProviderError{Kind: llms.ErrContextLengthExceeded}, true},
+		{"provider_auth", &llms.ProviderError{Kind: llms.ErrAuth, Err: errors.New("status code: 401")}, false},
+		// the message is not checked for the classified errors
+		{"provider_bad_request", &llms.ProviderError{Kind: llms.ErrBadRequest, Err: errors.New("timeout must be positive")}, false},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, assistants.IsFallbackError(tc.err))
+		})
+	}
+}
+
+func Test_Assistant_FallbackModels(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+
+	newModel := func(name string, providerCalls, nameCalls int) *mockllms.MockModel {
+		m := mockllms.NewMockModel(ctrl)
+		m.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(providerCalls)
+		m.EXPECT().GetName().Return(name).Times(nameCalls)
+		return m
+	}
+	newAssistant := func(llm llms.Model, opts ...assistants.Option) *assistants.Assistant[chatmodel.String] {
+		return assistants.NewAssistant[chatmodel.String](llm,
+			prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+			append([]assistants.Option{assistants.WithMode(encoding.ModePlainText)}, opts...)...,
+		)
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		primary := newModel("primary", 1, 4)
+		primary.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("API returned unexpected status code: 503: unavailable")).Times(1)
+		second := newModel("second", 0, 5)
+		second.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("Overloaded")).Times(1)
+		third := newModel("third", 0, 5)
+		third.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+				opts := llms.CallOptions{}
+				for _, opt := range options {
+					opt(&opts)
+				}
+				assert.Equal(t, "third", opts.Model)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "done"}}}, nil
+			}).Times(1)
+
+		rec := &fallbackRecorder{}
+		ag := newAssistant(primary, assistants.WithCallback(rec), assistants.WithFallbackModels(second, third))
+
+		var output chatmodel.String
+		resp, err := ag.Run(ctx, &assistants.CallInput{Input: "input"}, &output)
+		require.NoError(t, err)
+		assert.Equal(t, "done", output.String())
+		assert.Equal(t, "third", resp.Choices[0].GenerationInfo[llms.GenerationInfoModel])
+		assert.Equal(t, []string{"primary->second", "second->third"}, rec.fallbacks)
+	})
+
+	t.Run("not_fallback_error", func(t *testing.T) {
+		primary := newModel("primary", 1, 2)
+		primary.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("invalid api key")).Times(1)
+		second := newModel("second", 0, 0)
+
+		ag := newAssistant(primary, assistants.WithFallbackModels(second))
+		_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		assert.ErrorContains(t, err, "model primary: failed to generate content from LLM: invalid api key")
+	})
+
+	t.Run("policy", func(t *testing.T) {
+		primary := newModel("primary", 1, 3)
+		primary.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("invalid api key")).Times(1)
+		second := newModel("second", 0, 4)
+		second.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "done"}}}, nil).Times(1)
+
+		ag := newAssistant(primary,
+			assistants.WithFallbackModels(second),
+			assistants.WithFallbackPolicy(func(error) bool { return true }),
+		)
+		resp, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		require.NoError(t, err)
+		assert.Equal(t, "second", resp.Choices[0].GenerationInfo[llms.GenerationInfoModel])
+	})
+}
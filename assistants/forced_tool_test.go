@@ -0,0 +1,83 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_WithForcedTool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var choices []any
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			opts := llms.CallOptions{}
+			for _, opt := range options {
+				opt(&opts)
+			}
+			choices = append(choices, opts.ToolChoice)
+			if len(choices) == 1 {
+				return &llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{
+						ToolCalls: []llms.ToolCall{
+							{ID: "call_1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "route_tool", Arguments: `{}`}},
+						},
+					}},
+				}, nil
+			}
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "routed"}}}, nil
+		}).Times(2)
+
+	routeTool := newCancelTool(ctrl, "Route_Tool", func(_ context.Context, _ string) (string, error) {
+		return "billing", nil
+	})
+	// the forced tool is resolved by the name before the first round
+	routeTool.EXPECT().Name().Return("Route_Tool").Times(1)
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithForcedTool("route_tool"),
+	).WithTools(routeTool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "my invoice is wrong"})
+	require.NoError(t, err)
+	assert.Equal(t, "routed", resp.Choices[0].Content)
+	// only the first round is forced
+	assert.Equal(t, []any{llms.FunctionToolChoice("Route_Tool"), nil}, choices)
+}
+
+func Test_Assistant_WithForcedTool_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithForcedTool("unknown_tool"),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the forced tool unknown_tool is not found")
+}
@@ -0,0 +1,115 @@
+package assistants
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/gogentic/chatmodel"
+)
+
+// DedupeStore records the results of the tool calls by the idempotency key of the call,
+// so the repeated run with the same CallInput.IdempotencyKey returns the recorded results,
+// instead of repeating the side effects, such as the created tickets.
+// The keys are scoped by the tenant and the chat of the run,
+// so the same idempotency key of another tenant or chat does not return the recorded results,
+// the implementation must not drop or rewrite the keys, and must not share the results by the other means.
+// The implementation must be safe for the concurrent use.
+type DedupeStore interface {
+	// Get returns the recorded result of the tool call.
+	Get(ctx context.Context, key string) (string, bool)
+	// Set records the result of the tool call.
+	Set(ctx context.Context, key, result string)
+}
+
+// NewMemoryDedupeStore returns in-memory DedupeStore,
+// the results expire after ttl, if ttl is not zero.
+// When the store has more than maxEntries, the oldest results are evicted,
+// if maxEntries is zero, DefaultMaxCacheEntries is used.
+// The results are not shared between the processes,
+// use a persistent store to dedupe the runs retried by another instance.
+func NewMemoryDedupeStore(ttl time.Duration, maxEntries int) DedupeStore {
+	return newMemoryCache[string](ttl, maxEntries)
+}
+
+// WithDedupeStore is an option to record the successful tool results of the runs with CallInput.IdempotencyKey,
+// the repeated tool call of the repeated run returns the recorded result without calling the tool.
+// The failed calls are not recorded, and are called again.
+func WithDedupeStore(store DedupeStore) Option {
+	return func(o *Config) {
+		o.DedupeStore = store
+	}
+}
+
+// idempotency derives the keys of the LLM and the tool calls from the key of the run.
+// The keys are the same for the repeated run, as long as the LLM requests the same calls.
+type idempotency struct {
+	key string
+	// tenantID and chatID scope the keys of the tool calls
+	tenantID string
+	chatID   string
+
+	lock     sync.Mutex
+	llmCalls int
+	// toolCalls is the number of the calls by the call hash,
+	// so the repeated call within the run gets the new key
+	toolCalls map[string]int
+}
+
+func newIdempotency(key, tenantID, chatID string) *idempotency {
+	return &idempotency{
+		key:       key,
+		tenantID:  tenantID,
+		chatID:    chatID,
+		toolCalls: make(map[string]int),
+	}
+}
+
+// withIdempotency sets the idempotency key of the run from the input,
+// or from the tool call of the parent assistant.
+func (c *Config) withIdempotency(ctx context.Context, input *CallInput) context.Context {
+	key := input.IdempotencyKey
+	if key == "" {
+		key = chatmodel.GetIdempotencyKey(ctx)
+	}
+	if key == "" {
+		return ctx
+	}
+	// the chat context is checked by the run
+	tenantID, chatID, _ := chatmodel.GetTenantAndChatID(ctx)
+	c.idempotency = newIdempotency(key, tenantID, chatID)
+	return chatmodel.WithIdempotencyKey(ctx, key)
+}
+
+// llmCallKey returns the key of the next LLM call of the run.
+func (s *idempotency) llmCallKey() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.llmCalls++
+	return fmt.Sprintf("%s:llm:%d", s.key, s.llmCalls)
+}
+
+// toolCallKey returns the key of the tool call,
+// derived from the key of the run, the tenant and the chat, the tool name, the arguments,
+// and the number of the same calls in the run.
+// The key has only letters, digits and '-', and is at most 64 characters.
+func (s *idempotency) toolCallKey(assistant, tool, args string) string {
+	// the arguments differ in the formatting only
+	var compact bytes.Buffer
+	if json.Compact(&compact, []byte(args)) == nil {
+		args = compact.String()
+	}
+	h := sha256.Sum256([]byte(strings.Join([]string{s.tenantID, s.chatID, s.key, assistant, strings.ToLower(tool), args}, "\x00")))
+	id := hex.EncodeToString(h[:16])
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.toolCalls[id]++
+	return fmt.Sprintf("%s-%d", id, s.toolCalls[id])
+}
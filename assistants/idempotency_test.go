@@ -0,0 +1,108 @@
+package assistants_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_IdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(6)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(10)
+
+	var lock sync.Mutex
+	var llmKeys []string
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			opts := llms.CallOptions{}
+			for _, opt := range options {
+				opt(&opts)
+			}
+			lock.Lock()
+			llmKeys = append(llmKeys, opts.IdempotencyKey)
+			lock.Unlock()
+			if messages[len(messages)-1].Role == llms.RoleTool {
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "ticket created"}}}, nil
+			}
+			return &llms.ContentResponse{
+				Choices: []*llms.ContentChoice{{
+					ToolCalls: []llms.ToolCall{{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "create_ticket", Arguments: `{"title": "Crash"}`}}},
+				}},
+			}, nil
+		}).Times(10)
+
+	var toolKeys []string
+	tool := mocktools.NewMockTool[any, any](ctrl)
+	tool.EXPECT().Name().Return("create_ticket").Times(1)
+	tool.EXPECT().Description().Return("desc").Times(1)
+	tool.EXPECT().Parameters().Return(nil).Times(1)
+	tool.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, _ string) (string, error) {
+		lock.Lock()
+		toolKeys = append(toolKeys, chatmodel.GetIdempotencyKey(ctx))
+		lock.Unlock()
+		return `{"key":"OPS-1"}`, nil
+	}).Times(4)
+
+	dedupe := assistants.NewMemoryDedupeStore(time.Minute, 0)
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithDedupeStore(dedupe),
+	).WithTools(tool)
+
+	tenant := chatmodel.NewChatContext("tenant-a", "chat-1", nil)
+	run := func(chatCtx chatmodel.ChatContext, key string) *assistants.Response {
+		ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+		resp, err := ag.Call(ctx, &assistants.CallInput{Input: "Open the ticket for the alert.", IdempotencyKey: key})
+		require.NoError(t, err)
+		return resp
+	}
+
+	// the first delivery of the webhook
+	resp := run(tenant, "hook-1")
+	assert.Equal(t, "ticket created", resp.Choices[0].Content)
+	assert.Equal(t, []string{"hook-1:llm:1", "hook-1:llm:2"}, llmKeys)
+	require.Len(t, toolKeys, 1)
+	assert.Regexp(t, `^[0-9a-f]{32}-1$`, toolKeys[0])
+
+	// the repeated delivery returns the recorded result
+	llmKeys = nil
+	resp = run(tenant, "hook-1")
+	assert.Equal(t, "ticket created", resp.Choices[0].Content)
+	assert.Equal(t, []string{"hook-1:llm:1", "hook-1:llm:2"}, llmKeys)
+	assert.Len(t, toolKeys, 1)
+
+	// another delivery calls the tool
+	run(tenant, "hook-2")
+	require.Len(t, toolKeys, 2)
+	assert.NotEqual(t, toolKeys[0], toolKeys[1])
+
+	// the same key of another tenant does not return the recorded result
+	resp = run(chatmodel.NewChatContext("tenant-b", "chat-1", nil), "hook-1")
+	assert.Equal(t, "ticket created", resp.Choices[0].Content)
+	require.Len(t, toolKeys, 3)
+	assert.NotEqual(t, toolKeys[0], toolKeys[2])
+
+	// no key, no dedupe
+	llmKeys = nil
+	run(tenant, "")
+	assert.Equal(t, []string{"", ""}, llmKeys)
+	require.Len(t, toolKeys, 4)
+	assert.Empty(t, toolKeys[3])
+}
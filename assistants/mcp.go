@@ -0,0 +1,104 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/mcp"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/xlog"
+)
+
+// ChatURIScheme is the scheme of the chat resources, see RegisterAllMCP.
+const ChatURIScheme = "chat://"
+
+// MCPServer is the MCP server to register the assistant with its tools and message store,
+// implemented by mcp.Server.
+type MCPServer interface {
+	McpServerRegistrator
+	tools.McpServerRegistrator
+	RegisterResourceTemplate(uriTemplate string, name string, description string, mimeType string) error
+	RegisterResourceProvider(uriPrefix string, provider mcp.ResourceProvider) error
+}
+
+// RegisterAllMCP registers the assistant as the prompt, its tools as the MCP tools,
+// and its message store as the chat://{id} resources.
+// The tools that do not implement tools.IMCPTool are skipped.
+// The context of the requests must have ChatContext with tenantID.
+func RegisterAllMCP(server MCPServer, assistant IMCPAssistant) error {
+	if err := assistant.RegisterMCP(server); err != nil {
+		return errors.Wrapf(err, "failed to register assistant %s", assistant.Name())
+	}
+
+	for _, t := range assistant.GetTools() {
+		mt, ok := t.(tools.IMCPTool)
+		if !ok {
+			logger.KV(xlog.DEBUG,
+				"assistant", assistant.Name(),
+				"tool", t.Name(),
+				"reason", "not_mcp_tool",
+			)
+			continue
+		}
+		if err := mt.RegisterMCP(server); err != nil {
+			return errors.Wrapf(err, "failed to register tool %s", t.Name())
+		}
+	}
+
+	if cp, ok := assistant.(interface{ GetCallConfig(opts ...Option) *Config }); ok {
+		if ms := cp.GetCallConfig().Store; ms != nil {
+			err := server.RegisterResourceTemplate(ChatURIScheme+"{id}", "chat", "Chat history of "+assistant.Name(), "application/json")
+			if err != nil {
+				return err
+			}
+			return server.RegisterResourceProvider(ChatURIScheme, NewMessageStoreProvider(ms))
+		}
+	}
+	return nil
+}
+
+type messageStoreProvider struct {
+	store store.MessageStore
+}
+
+// NewMessageStoreProvider returns the ResourceProvider of the chats in the message store,
+// the chat is returned as store.ChatInfo in JSON.
+func NewMessageStoreProvider(ms store.MessageStore) mcp.ResourceProvider {
+	return &messageStoreProvider{store: ms}
+}
+
+func (p *messageStoreProvider) ListResources(ctx context.Context) ([]*mcp.ResourceSchema, error) {
+	ids, err := p.store.ListChatIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mimeType := "application/json"
+	res := make([]*mcp.ResourceSchema, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, &mcp.ResourceSchema{
+			Name:     id,
+			Uri:      ChatURIScheme + id,
+			MimeType: &mimeType,
+		})
+	}
+	return res, nil
+}
+
+func (p *messageStoreProvider) ReadResource(ctx context.Context, uri string) (*mcp.ResourceResponse, error) {
+	id, ok := strings.CutPrefix(uri, ChatURIScheme)
+	if !ok || id == "" {
+		return nil, errors.Errorf("invalid chat URI: %s", uri)
+	}
+	info, err := p.store.GetChatInfo(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal chat")
+	}
+	return mcp.NewResourceResponse(mcp.NewTextEmbeddedResource(uri, string(data), "application/json")), nil
+}
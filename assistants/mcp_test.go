@@ -0,0 +1,87 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mcp"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_RegisterAllMCP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+
+	server := mcp.NewServer(nil)
+
+	mcpTool := mocktools.NewMockIMCPTool(ctrl)
+	mcpTool.EXPECT().Name().Return("mcp_tool").Times(1)
+	mcpTool.EXPECT().Description().Return("MCP tool").Times(1)
+	mcpTool.EXPECT().Parameters().Return(nil).Times(1)
+	mcpTool.EXPECT().RegisterMCP(server).Return(nil).Times(1)
+
+	plainTool := mocktools.NewMockITool(ctrl)
+	plainTool.EXPECT().Name().Return("plain_tool").Times(2)
+	plainTool.EXPECT().Description().Return("Plain tool").Times(1)
+	plainTool.EXPECT().Parameters().Return(nil).Times(1)
+
+	memstore := store.NewMemoryStore()
+	systemPrompt := prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", []string{})
+	assistant := assistants.NewAssistant[chatmodel.OutputResult](mockLLM, systemPrompt,
+		assistants.WithMessageStore(memstore)).
+		WithName("helper").
+		WithTools(mcpTool, plainTool)
+
+	require.NoError(t, assistants.RegisterAllMCP(server, assistant))
+	assert.True(t, server.CheckPromptRegistered("helper"))
+	assert.True(t, server.CheckResourceTemplateRegistered("chat://{id}"))
+	assert.True(t, server.CheckResourceProviderRegistered(assistants.ChatURIScheme))
+	assert.False(t, server.CheckToolRegistered("plain_tool"))
+
+	t.Run("tool error", func(t *testing.T) {
+		failing := mocktools.NewMockIMCPTool(ctrl)
+		failing.EXPECT().Name().Return("failing_tool").Times(2)
+		failing.EXPECT().Description().Return("Failing tool").Times(1)
+		failing.EXPECT().Parameters().Return(nil).Times(1)
+		failing.EXPECT().RegisterMCP(gomock.Any()).Return(assert.AnError).Times(1)
+
+		a := assistants.NewAssistant[chatmodel.OutputResult](mockLLM, systemPrompt).WithTools(failing)
+		err := assistants.RegisterAllMCP(mcp.NewServer(nil), a)
+		assert.ErrorContains(t, err, "failed to register tool failing_tool")
+	})
+}
+
+func Test_MessageStoreProvider(t *testing.T) {
+	memstore := store.NewMemoryStore()
+	chatCtx := chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil)
+	ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+	require.NoError(t, memstore.Add(ctx, llms.MessageFromTextParts(llms.RoleHuman, "hello")))
+
+	provider := assistants.NewMessageStoreProvider(memstore)
+
+	list, err := provider.ListResources(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	uri := assistants.ChatURIScheme + chatCtx.GetChatID()
+	assert.Equal(t, uri, list[0].Uri)
+
+	resp, err := provider.ReadResource(ctx, uri)
+	require.NoError(t, err)
+	require.Len(t, resp.Contents, 1)
+	assert.Contains(t, resp.Contents[0].TextResourceContents.Text, "hello")
+
+	_, err = provider.ReadResource(ctx, "artifact://123")
+	assert.EqualError(t, err, "invalid chat URI: artifact://123")
+}
@@ -0,0 +1,486 @@
+package assistants
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/xlog"
+)
+
+var (
+	// ErrGuardrailViolation is returned when the input or the output is rejected by a guardrail.
+	ErrGuardrailViolation = errors.New("guardrail violation")
+	// ErrBudgetExceeded is returned when the budget of the assistant is exhausted.
+	ErrBudgetExceeded = errors.New("budget exceeded")
+)
+
+// Runner runs the assistant with the input,
+// the response is parsed to the output, if the output is not nil.
+type Runner func(ctx context.Context, input *CallInput, output any) (*Response, error)
+
+// Middleware wraps the Runner of the assistant, similar to http middleware,
+// to add a cross-cutting behavior, such as caching, guardrails, budget enforcement or tracing,
+// without modifying the assistant.
+// The middlewares are applied around Call and Run of Assistant with WithMiddleware,
+// or around any IAssistant with Wrap.
+// The assistant being run is available to the middleware with GetAssistant.
+type Middleware func(next Runner) Runner
+
+// ChainRunner wraps the runner with the middlewares,
+// the first middleware is the outermost one.
+func ChainRunner(runner Runner, middlewares ...Middleware) Runner {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			runner = middlewares[i](runner)
+		}
+	}
+	return runner
+}
+
+type assistantKey struct{}
+
+// withAssistant returns the context with the assistant being run.
+func withAssistant(ctx context.Context, a IAssistant) context.Context {
+	return context.WithValue(ctx, assistantKey{}, a)
+}
+
+// GetAssistant returns the assistant being run by the middleware chain,
+// or nil if the context is not created by the chain.
+func GetAssistant(ctx context.Context) IAssistant {
+	a, _ := ctx.Value(assistantKey{}).(IAssistant)
+	return a
+}
+
+func assistantName(ctx context.Context) string {
+	if a := GetAssistant(ctx); a != nil {
+		return a.Name()
+	}
+	return ""
+}
+
+// Wrap wraps the assistant with the middlewares, applied around Call,
+// the first middleware is the outermost one.
+// Unlike WithMiddleware, it can wrap any IAssistant, including mocks and orchestrators.
+func Wrap(a IAssistant, middlewares ...Middleware) IAssistant {
+	return &Wrapped{
+		IAssistant: a,
+		runner: ChainRunner(func(ctx context.Context, input *CallInput, _ any) (*Response, error) {
+			return a.Call(ctx, input)
+		}, middlewares...),
+	}
+}
+
+// Wrapped is IAssistant that runs Call of the wrapped assistant through the middlewares,
+// all other methods are forwarded to the wrapped assistant.
+type Wrapped struct {
+	IAssistant
+	runner Runner
+}
+
+// Call calls the wrapped assistant through the middlewares.
+func (w *Wrapped) Call(ctx context.Context, input *CallInput) (*Response, error) {
+	return w.runner(withAssistant(ctx, w.IAssistant), input, nil)
+}
+
+// Unwrap returns the wrapped assistant.
+func (w *Wrapped) Unwrap() IAssistant {
+	return w.IAssistant
+}
+
+// WrapTyped wraps the typed assistant with the middlewares, applied around Call and Run,
+// the first middleware is the outermost one.
+// Use it to wrap the assistants passed to NewAssistantTool, which calls the typed Run.
+func WrapTyped[O chatmodel.ContentProvider](a TypeableAssistant[O], middlewares ...Middleware) TypeableAssistant[O] {
+	return &WrappedTyped[O]{
+		TypeableAssistant: a,
+		runner: ChainRunner(func(ctx context.Context, input *CallInput, output any) (*Response, error) {
+			if output == nil {
+				return a.Run(ctx, input, nil)
+			}
+			typed, ok := output.(*O)
+			if !ok {
+				return nil, errors.Newf("assistant %s: unexpected output type %T", a.Name(), output)
+			}
+			return a.Run(ctx, input, typed)
+		}, middlewares...),
+	}
+}
+
+// WrappedTyped is TypeableAssistant that runs Call and Run of the wrapped assistant through the middlewares,
+// all other methods are forwarded to the wrapped assistant.
+type WrappedTyped[O chatmodel.ContentProvider] struct {
+	TypeableAssistant[O]
+	runner Runner
+}
+
+// Call calls the wrapped assistant through the middlewares.
+func (w *WrappedTyped[O]) Call(ctx context.Context, input *CallInput) (*Response, error) {
+	var output O
+	return w.Run(ctx, input, &output)
+}
+
+// Run runs the wrapped assistant through the middlewares.
+func (w *WrappedTyped[O]) Run(ctx context.Context, input *CallInput, optionalOutputType *O) (*Response, error) {
+	ctx = withAssistant(ctx, w.TypeableAssistant)
+	if optionalOutputType == nil {
+		return w.runner(ctx, input, nil)
+	}
+	return w.runner(ctx, input, optionalOutputType)
+}
+
+// Unwrap returns the wrapped assistant.
+func (w *WrappedTyped[O]) Unwrap() IAssistant {
+	return w.TypeableAssistant
+}
+
+// Unwrap returns the innermost assistant, removing all the middlewares.
+func Unwrap(a IAssistant) IAssistant {
+	for {
+		w, ok := a.(interface{ Unwrap() IAssistant })
+		if !ok {
+			return a
+		}
+		a = w.Unwrap()
+	}
+}
+
+// DefaultMaxCacheEntries is the default maximum number of entries in the in-memory ResponseCache.
+const DefaultMaxCacheEntries = 1000
+
+// CachedResponse is the cached response of the assistant.
+type CachedResponse struct {
+	Response *Response `json:"response"`
+	// Output is the JSON encoded output of the typed Run, if any.
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// ResponseCache is the cache for the assistant responses.
+type ResponseCache interface {
+	Get(ctx context.Context, key string) (*CachedResponse, bool)
+	Set(ctx context.Context, key string, resp *CachedResponse)
+}
+
+// NewMemoryResponseCache returns in-memory ResponseCache,
+// the entries expire after ttl, if ttl is not zero.
+// When the cache has more than maxEntries, the oldest entries are evicted,
+// if maxEntries is zero, DefaultMaxCacheEntries is used.
+func NewMemoryResponseCache(ttl time.Duration, maxEntries int) ResponseCache {
+	return newMemoryCache[*CachedResponse](ttl, maxEntries)
+}
+
+func newMemoryCache[V any](ttl time.Duration, maxEntries int) *memoryCache[V] {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxCacheEntries
+	}
+	return &memoryCache[V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+type cacheEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+// memoryCache is the in-memory cache with the TTL and the LRU eviction.
+type memoryCache[V any] struct {
+	lock       sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	// order is the list of the entries, the oldest at the front
+	order *list.List
+}
+
+func (c *memoryCache[V]) Get(_ context.Context, key string) (V, bool) {
+	var zero V
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	e := el.Value.(*cacheEntry[V])
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(el)
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (c *memoryCache[V]) Set(_ context.Context, key string, value V) {
+	e := &cacheEntry[V]{key: key, value: value}
+	if c.ttl > 0 {
+		e.expires = time.Now().Add(c.ttl)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.order.PushBack(e)
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Front())
+	}
+}
+
+// remove deletes the entry, the caller must hold the lock.
+func (c *memoryCache[V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry[V]).key)
+}
+
+// Cache returns a Middleware that returns the cached responses for the same input
+// in the same chat, the cache is scoped to the tenant and the chat.
+// If the history store is provided, the key includes the digest of the chat history,
+// so the same input in a later turn is not served from the cache,
+// and the cached turn is added to the history on a hit.
+// The calls with Options are not cached, as the options can not be compared.
+// The cached response has empty Usage, as no LLM calls are made.
+func Cache(cache ResponseCache, history store.MessageStore) Middleware {
+	return func(next Runner) Runner {
+		return func(ctx context.Context, input *CallInput, output any) (*Response, error) {
+			if len(input.Options) > 0 {
+				return next(ctx, input, output)
+			}
+			name := assistantName(ctx)
+			var messages []llms.Message
+			if history != nil {
+				messages = history.Messages(ctx)
+			}
+			key, err := cacheKey(ctx, name, input, messages)
+			if err != nil {
+				return next(ctx, input, output)
+			}
+
+			if cached, ok := cache.Get(ctx, key); ok && (output == nil || len(cached.Output) > 0) {
+				if output != nil {
+					if err := json.Unmarshal(cached.Output, output); err != nil {
+						return nil, errors.Wrapf(err, "assistant %s: failed to decode cached output", name)
+					}
+				}
+				if history != nil && len(cached.Response.Messages) > 0 {
+					if err := history.Add(ctx, cached.Response.Messages...); err != nil {
+						return nil, err
+					}
+				}
+				logger.ContextKV(ctx, xlog.DEBUG,
+					"assistant", name,
+					"status", "cache_hit",
+				)
+				return &Response{
+					Choices:  cached.Response.Choices,
+					Messages: cached.Response.Messages,
+				}, nil
+			}
+
+			resp, err := next(ctx, input, output)
+			if err != nil {
+				return nil, err
+			}
+			entry := &CachedResponse{Response: resp}
+			if output != nil {
+				if entry.Output, err = json.Marshal(output); err != nil {
+					// the response is valid, only the cache is skipped
+					return resp, nil
+				}
+			}
+			cache.Set(ctx, key, entry)
+			return resp, nil
+		}
+	}
+}
+
+func cacheKey(ctx context.Context, name string, input *CallInput, history []llms.Message) (string, error) {
+	tenantID, chatID, _ := chatmodel.GetTenantAndChatID(ctx)
+	historyJS, err := json.Marshal(history)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	historyDigest := sha256.Sum256(historyJS)
+
+	js, err := json.Marshal(struct {
+		TenantID     string
+		ChatID       string
+		Assistant    string
+		History      string
+		Input        string
+		PromptInputs map[string]any
+		Messages     any
+		Args         map[string]string
+	}{tenantID, chatID, name, hex.EncodeToString(historyDigest[:]), input.Input, input.PromptInputs, input.Messages, input.Args})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	h := sha256.Sum256(js)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// GuardrailFunc checks the text, and returns an error if the text is not allowed.
+type GuardrailFunc func(ctx context.Context, text string) error
+
+// Guardrails returns a Middleware that checks the input before the call,
+// and the output after the call.
+// The returned error wraps ErrGuardrailViolation.
+func Guardrails(inputChecks []GuardrailFunc, outputChecks []GuardrailFunc) Middleware {
+	return func(next Runner) Runner {
+		return func(ctx context.Context, input *CallInput, output any) (*Response, error) {
+			for _, check := range inputChecks {
+				if err := check(ctx, input.Input); err != nil {
+					return nil, errors.Wrapf(ErrGuardrailViolation, "assistant %s: input rejected: %s", assistantName(ctx), err.Error())
+				}
+			}
+			resp, err := next(ctx, input, output)
+			if err != nil {
+				return nil, err
+			}
+			text := resp.String()
+			for _, check := range outputChecks {
+				if err := check(ctx, text); err != nil {
+					return nil, errors.Wrapf(ErrGuardrailViolation, "assistant %s: output rejected: %s", assistantName(ctx), err.Error())
+				}
+			}
+			return resp, nil
+		}
+	}
+}
+
+// Budget limits the total tokens and LLM calls used by the wrapped assistant across all calls,
+// its Wrap method is the Middleware.
+// The call is rejected with ErrBudgetExceeded when the budget is exhausted,
+// the call in progress is not interrupted.
+type Budget struct {
+	lock      sync.Mutex
+	maxTokens int
+	maxCalls  int
+	tokens    int
+	calls     int
+}
+
+// NewBudget returns a new Budget, zero value means no limit.
+func NewBudget(maxTokens, maxLLMCalls int) *Budget {
+	return &Budget{
+		maxTokens: maxTokens,
+		maxCalls:  maxLLMCalls,
+	}
+}
+
+// Used returns the used tokens and LLM calls.
+func (b *Budget) Used() (tokens int, llmCalls int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.tokens, b.calls
+}
+
+// Wrap is the Middleware that enforces the budget.
+func (b *Budget) Wrap(next Runner) Runner {
+	return func(ctx context.Context, input *CallInput, output any) (*Response, error) {
+		if err := b.check(assistantName(ctx)); err != nil {
+			return nil, err
+		}
+		resp, err := next(ctx, input, output)
+		if resp != nil {
+			b.lock.Lock()
+			b.tokens += int(resp.Usage.TotalTokens)
+			b.calls += int(resp.Usage.LlmCallCount)
+			b.lock.Unlock()
+		}
+		return resp, err
+	}
+}
+
+func (b *Budget) check(name string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.maxTokens > 0 && b.tokens >= b.maxTokens {
+		return errors.Wrapf(ErrBudgetExceeded, "assistant %s: used %d of %d tokens", name, b.tokens, b.maxTokens)
+	}
+	if b.maxCalls > 0 && b.calls >= b.maxCalls {
+		return errors.Wrapf(ErrBudgetExceeded, "assistant %s: used %d of %d LLM calls", name, b.calls, b.maxCalls)
+	}
+	return nil
+}
+
+// Span is the tracing span of the assistant call.
+type Span interface {
+	// End ends the span with the response or the error.
+	End(resp *Response, err error)
+}
+
+// Tracer starts the spans of the assistant calls,
+// the assistant is nil if it is not known to the middleware chain.
+type Tracer interface {
+	Start(ctx context.Context, a IAssistant, input *CallInput) (context.Context, Span)
+}
+
+// Tracing returns a Middleware that traces the assistant calls,
+// if tracer is nil, the calls are traced to the log.
+func Tracing(tracer Tracer) Middleware {
+	if tracer == nil {
+		tracer = logTracer{}
+	}
+	return func(next Runner) Runner {
+		return func(ctx context.Context, input *CallInput, output any) (*Response, error) {
+			ctx, span := tracer.Start(ctx, GetAssistant(ctx), input)
+			resp, err := next(ctx, input, output)
+			span.End(resp, err)
+			return resp, err
+		}
+	}
+}
+
+type logTracer struct{}
+
+func (logTracer) Start(ctx context.Context, _ IAssistant, _ *CallInput) (context.Context, Span) {
+	return ctx, &logSpan{
+		ctx:     ctx,
+		name:    assistantName(ctx),
+		started: time.Now(),
+	}
+}
+
+type logSpan struct {
+	ctx     context.Context
+	name    string
+	started time.Time
+}
+
+func (s *logSpan) End(resp *Response, err error) {
+	runID := ""
+	if chatCtx := chatmodel.GetChatContext(s.ctx); chatCtx != nil {
+		runID = chatCtx.GetRunID()
+	}
+	if err != nil {
+		logger.ContextKV(s.ctx, xlog.DEBUG,
+			"assistant", s.name,
+			"run_id", runID,
+			"status", "call_failed",
+			"duration", time.Since(s.started).String(),
+			"err", err.Error(),
+		)
+		return
+	}
+	logger.ContextKV(s.ctx, xlog.DEBUG,
+		"assistant", s.name,
+		"run_id", runID,
+		"status", "call_completed",
+		"duration", time.Since(s.started).String(),
+		"llm_calls", resp.Usage.LlmCallCount,
+		"tool_calls", resp.Usage.ToolCallCount,
+		"total_tokens", resp.Usage.TotalTokens,
+		"cost", resp.Usage.Cost,
+	)
+}
@@ -0,0 +1,371 @@
+package assistants_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mocks/mockassitants"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newMockAssistant(ctrl *gomock.Controller, nameCalls int) *mockassitants.MockIAssistant {
+	m := mockassitants.NewMockIAssistant(ctrl)
+	m.EXPECT().Name().Return("mock").Times(nameCalls)
+	return m
+}
+
+func mockResponse(content string, tokens uint64) *assistants.Response {
+	resp := assistants.NewResponse(content)
+	resp.Usage.TotalTokens = tokens
+	resp.Usage.LlmCallCount = 1
+	return resp
+}
+
+func Test_Middleware_Wrap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := newMockAssistant(ctrl, 3)
+	mock.EXPECT().Description().Return("mock assistant").Times(1)
+	mock.EXPECT().Call(gomock.Any(), gomock.Any()).Return(mockResponse("ok", 1), nil).Times(1)
+
+	var order []string
+	mw := func(name string) assistants.Middleware {
+		return func(next assistants.Runner) assistants.Runner {
+			return func(ctx context.Context, input *assistants.CallInput, output any) (*assistants.Response, error) {
+				order = append(order, name+":"+assistants.GetAssistant(ctx).Name())
+				return next(ctx, input, output)
+			}
+		}
+	}
+
+	a := assistants.Wrap(mock, mw("first"), nil, mw("second"))
+	assert.Equal(t, "mock", a.Name())
+	assert.Equal(t, "mock assistant", a.Description())
+
+	resp, err := a.Call(context.Background(), &assistants.CallInput{Input: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp.String())
+	assert.Equal(t, []string{"first:mock", "second:mock"}, order)
+	assert.Same(t, mock, assistants.Unwrap(a))
+	assert.Same(t, mock, assistants.Unwrap(mock))
+}
+
+func Test_Middleware_Cache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := newMockAssistant(ctrl, 8)
+	mock.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *assistants.CallInput) (*assistants.Response, error) {
+			resp := mockResponse("answer: "+input.Input, 10)
+			resp.Messages = []llms.Message{
+				llms.MessageFromTextParts(llms.RoleHuman, input.Input),
+				llms.MessageFromTextParts(llms.RoleAI, "answer: "+input.Input),
+			}
+			return resp, nil
+		}).Times(7)
+
+	history := store.NewMemoryStore()
+	a := assistants.Wrap(mock, assistants.Cache(assistants.NewMemoryResponseCache(time.Minute, 0), nil))
+	stateful := assistants.Wrap(mock, assistants.Cache(assistants.NewMemoryResponseCache(time.Minute, 0), history))
+
+	ctx1 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+	ctx2 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant2", "chat1", nil))
+	ctx3 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat2", nil))
+
+	// miss
+	resp, err := a.Call(ctx1, &assistants.CallInput{Input: "q1"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), resp.Usage.TotalTokens)
+	// hit
+	resp, err = a.Call(ctx1, &assistants.CallInput{Input: "q1"})
+	require.NoError(t, err)
+	assert.Equal(t, "answer: q1", resp.String())
+	assert.Zero(t, resp.Usage.TotalTokens)
+	// different input
+	_, err = a.Call(ctx1, &assistants.CallInput{Input: "q2"})
+	require.NoError(t, err)
+	// different tenant
+	_, err = a.Call(ctx2, &assistants.CallInput{Input: "q1"})
+	require.NoError(t, err)
+	// different chat
+	_, err = a.Call(ctx3, &assistants.CallInput{Input: "q1"})
+	require.NoError(t, err)
+	// options are not cached
+	_, err = a.Call(ctx1, &assistants.CallInput{Input: "q1", Options: []assistants.Option{assistants.WithTemperature(0)}})
+	require.NoError(t, err)
+
+	// the history is part of the key, and the cached turn is added to the history
+	require.NoError(t, history.Add(ctx1, llms.MessageFromTextParts(llms.RoleHuman, "previous")))
+	_, err = stateful.Call(ctx1, &assistants.CallInput{Input: "q1"})
+	require.NoError(t, err)
+	// the mock does not update the history, so the next call is a hit
+	_, err = stateful.Call(ctx1, &assistants.CallInput{Input: "q1"})
+	require.NoError(t, err)
+	assert.Len(t, history.Messages(ctx1), 3)
+	// the history has changed, so the next call is a miss
+	_, err = stateful.Call(ctx1, &assistants.CallInput{Input: "q1"})
+	require.NoError(t, err)
+}
+
+func Test_Middleware_CacheTyped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	var llmCalls int
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+			llmCalls++
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"content":"Hello"}`}}}, nil
+		}).Times(1)
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+	)
+	tracer := &testTracer{}
+	a := assistants.WrapTyped[chatmodel.OutputResult](ag, assistants.Tracing(tracer), assistants.Cache(assistants.NewMemoryResponseCache(0, 0), nil))
+	assert.Same(t, ag, assistants.Unwrap(a))
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	for range 2 {
+		var output chatmodel.OutputResult
+		_, err := a.Run(ctx, &assistants.CallInput{Input: "hello"}, &output)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", output.Content)
+	}
+	_, err := a.Call(ctx, &assistants.CallInput{Input: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, llmCalls)
+	assert.Equal(t, 3, tracer.started)
+}
+
+func Test_MemoryResponseCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	entry := &assistants.CachedResponse{Response: mockResponse("x", 0)}
+
+	// expiration
+	cache := assistants.NewMemoryResponseCache(time.Nanosecond, 0)
+	cache.Set(ctx, "key", entry)
+	time.Sleep(time.Millisecond)
+	_, ok := cache.Get(ctx, "key")
+	assert.False(t, ok)
+
+	// eviction of the oldest entries
+	cache = assistants.NewMemoryResponseCache(0, 2)
+	cache.Set(ctx, "key1", entry)
+	cache.Set(ctx, "key2", entry)
+	cache.Set(ctx, "key1", entry)
+	cache.Set(ctx, "key3", entry)
+	_, ok = cache.Get(ctx, "key2")
+	assert.False(t, ok)
+	_, ok = cache.Get(ctx, "key1")
+	assert.True(t, ok)
+	_, ok = cache.Get(ctx, "key3")
+	assert.True(t, ok)
+}
+
+func Test_Middleware_Guardrails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := newMockAssistant(ctrl, 2)
+	mock.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *assistants.CallInput) (*assistants.Response, error) {
+			return mockResponse("answer: "+input.Input, 1), nil
+		}).Times(2)
+
+	deny := func(word string) assistants.GuardrailFunc {
+		return func(_ context.Context, text string) error {
+			if strings.Contains(text, word) {
+				return errors.Newf("%q is not allowed", word)
+			}
+			return nil
+		}
+	}
+	a := assistants.Wrap(mock, assistants.Guardrails(
+		[]assistants.GuardrailFunc{deny("password")},
+		[]assistants.GuardrailFunc{deny("secret")},
+	))
+
+	ctx := context.Background()
+	_, err := a.Call(ctx, &assistants.CallInput{Input: "my password"})
+	require.ErrorIs(t, err, assistants.ErrGuardrailViolation)
+	assert.EqualError(t, err, `assistant mock: input rejected: "password" is not allowed: guardrail violation`)
+
+	_, err = a.Call(ctx, &assistants.CallInput{Input: "the secret"})
+	require.ErrorIs(t, err, assistants.ErrGuardrailViolation)
+	assert.EqualError(t, err, `assistant mock: output rejected: "secret" is not allowed: guardrail violation`)
+
+	resp, err := a.Call(ctx, &assistants.CallInput{Input: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "answer: hello", resp.String())
+}
+
+func Test_Middleware_Budget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := newMockAssistant(ctrl, 3)
+	mock.EXPECT().Call(gomock.Any(), gomock.Any()).Return(mockResponse("ok", 60), nil).Times(2)
+
+	budget := assistants.NewBudget(100, 0)
+	a := assistants.Wrap(mock, budget.Wrap)
+
+	ctx := context.Background()
+	for range 2 {
+		_, err := a.Call(ctx, &assistants.CallInput{Input: "hi"})
+		require.NoError(t, err)
+	}
+	_, err := a.Call(ctx, &assistants.CallInput{Input: "hi"})
+	require.ErrorIs(t, err, assistants.ErrBudgetExceeded)
+	assert.EqualError(t, err, "assistant mock: used 120 of 100 tokens: budget exceeded")
+
+	tokens, calls := budget.Used()
+	assert.Equal(t, 120, tokens)
+	assert.Equal(t, 2, calls)
+
+	mock2 := newMockAssistant(ctrl, 2)
+	mock2.EXPECT().Call(gomock.Any(), gomock.Any()).Return(mockResponse("ok", 1), nil).Times(1)
+	a = assistants.Wrap(mock2, assistants.NewBudget(0, 1).Wrap)
+	_, err = a.Call(ctx, &assistants.CallInput{Input: "hi"})
+	require.NoError(t, err)
+	_, err = a.Call(ctx, &assistants.CallInput{Input: "hi"})
+	assert.EqualError(t, err, "assistant mock: used 1 of 1 LLM calls: budget exceeded")
+}
+
+type testTracer struct {
+	started int
+	ended   []error
+}
+
+type testSpan struct {
+	t *testTracer
+}
+
+func (s testSpan) End(_ *assistants.Response, err error) {
+	s.t.ended = append(s.t.ended, err)
+}
+
+func (tr *testTracer) Start(ctx context.Context, _ assistants.IAssistant, _ *assistants.CallInput) (context.Context, assistants.Span) {
+	tr.started++
+	return ctx, testSpan{t: tr}
+}
+
+func Test_Middleware_Tracing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mock := newMockAssistant(ctrl, 2)
+	gomock.InOrder(
+		mock.EXPECT().Call(gomock.Any(), gomock.Any()).Return(mockResponse("ok", 1), nil),
+		mock.EXPECT().Call(gomock.Any(), gomock.Any()).Return(nil, assert.AnError),
+	)
+
+	tracer := &testTracer{}
+	a := assistants.Wrap(mock, assistants.Tracing(tracer))
+	ctx := context.Background()
+	_, err := a.Call(ctx, &assistants.CallInput{Input: "hi"})
+	require.NoError(t, err)
+	_, err = a.Call(ctx, &assistants.CallInput{Input: "hi"})
+	require.Error(t, err)
+	assert.Equal(t, 2, tracer.started)
+	assert.Equal(t, []error{nil, assert.AnError}, tracer.ended)
+
+	// log tracer
+	mock.EXPECT().Call(gomock.Any(), gomock.Any()).Return(mockResponse("ok", 1), nil)
+	mock.EXPECT().Call(gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+	a = assistants.Wrap(mock, assistants.Tracing(nil))
+	_, err = a.Call(ctx, &assistants.CallInput{Input: "hi"})
+	require.NoError(t, err)
+	_, err = a.Call(ctx, &assistants.CallInput{Input: "hi"})
+	require.Error(t, err)
+}
+
+func Test_Assistant_WithMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(4)
+	var llmCalls int
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+			llmCalls++
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"content":"Hello"}`}}}, nil
+		}).Times(2)
+
+	var order []string
+	mw := func(name string) assistants.Middleware {
+		return func(next assistants.Runner) assistants.Runner {
+			return func(ctx context.Context, input *assistants.CallInput, output any) (*assistants.Response, error) {
+				order = append(order, name+":before")
+				resp, err := next(ctx, input, output)
+				order = append(order, name+":after")
+				return resp, err
+			}
+		}
+	}
+	blocked := func(next assistants.Runner) assistants.Runner {
+		return func(ctx context.Context, input *assistants.CallInput, output any) (*assistants.Response, error) {
+			if input.Input == "blocked" {
+				return nil, errors.Wrap(assistants.ErrGuardrailViolation, "blocked")
+			}
+			return next(ctx, input, output)
+		}
+	}
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMiddleware(mw("outer"), blocked),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "blocked"}, nil)
+	require.ErrorIs(t, err, assistants.ErrGuardrailViolation)
+	assert.Equal(t, []string{"outer:before", "outer:after"}, order)
+	assert.Zero(t, llmCalls)
+
+	order = nil
+	var output chatmodel.OutputResult
+	_, err = ag.Run(ctx, &assistants.CallInput{
+		Input:   "hello",
+		Options: []assistants.Option{assistants.WithMiddleware(mw("inner"))},
+	}, &output)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", output.Content)
+	assert.Equal(t, []string{"outer:before", "inner:before", "inner:after", "outer:after"}, order)
+	assert.Equal(t, 1, llmCalls)
+
+	// the built-in middlewares are applied around Call
+	budget := assistants.NewBudget(0, 1)
+	tracer := &testTracer{}
+	ag = assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMiddleware(assistants.Tracing(tracer), budget.Wrap),
+	).WithName("greeter")
+	_, err = ag.Call(ctx, &assistants.CallInput{Input: "hello"})
+	require.NoError(t, err)
+	_, err = ag.Call(ctx, &assistants.CallInput{Input: "hello"})
+	assert.EqualError(t, err, "assistant greeter: used 1 of 1 LLM calls: budget exceeded")
+	assert.Equal(t, 2, llmCalls)
+	assert.Equal(t, 2, tracer.started)
+}
@@ -2,10 +2,16 @@ package assistants
 
 import (
 	"context"
+	"slices"
+	"time"
 
+	"github.com/effective-security/gogentic/artifacts"
 	"github.com/effective-security/gogentic/chatmodel"
 	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/events"
 	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/prompts"
 	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/effective-security/gogentic/store"
 )
@@ -13,11 +19,16 @@ import (
 // Option is a function that can be used to modify the behavior of the Agent Config.
 type Option func(*Config)
 
+// MessageTransformer transforms the messages before they are sent to the LLM.
+// The transformer must not modify the provided messages in place, and should return the new slice.
+type MessageTransformer func(ctx context.Context, messages []llms.Message) ([]llms.Message, error)
+
 const (
 	DefaultMaxToolCalls   = 50
 	DefaultMaxMessages    = 100
 	DefaultMaxContentSize = 500000
 	DefaultMaxRetries     = 2
+	DefaultMaxNotFound    = 3
 )
 
 type Config struct {
@@ -71,12 +82,24 @@ type Config struct {
 	// ToolChoice is the choice of tool to use, it can either be "none", "auto" (the default behavior), or a specific tool as described in the ToolChoice type.
 	ToolChoice    any
 	toolChoiceSet bool
+	// ForcedTool is the name of the tool the run is forced to start with, see WithForcedTool.
+	ForcedTool string
+	// ParallelToolCalls enables or disables the parallel tool calls in the LLM response,
+	// nil is the provider default.
+	ParallelToolCalls *bool
+	// SequentialToolCalls is a flag to execute the tool calls of the LLM response one by one,
+	// in the requested order, instead of in parallel.
+	SequentialToolCalls bool
 
 	// ResponseFormat is a custom response format.
 	// If it's not set the response MIME type is text/plain.
 	// Otherwise, from response format the JSON mode is derived.
 	ResponseFormat *schema.ResponseFormat
 
+	// Constraint is the constrained decoding of the output,
+	// it is not sent with the tools, as it would prevent the tool calls.
+	Constraint *llms.Constraint
+
 	//
 	// Below are the options for the Agent, not related to LLM call
 	//
@@ -91,12 +114,27 @@ type Config struct {
 	// Mode is the encoding mode to use.
 	// If ModeJSON then JSON schema instructions are added to the system prompt.
 	// If ModeJSONSchema or ModeJSONSchemaStrict and the Model supports it,
-	// then the response format is set to json_object.
+	// then the response format is set to json_schema,
+	// or to json_object if the Model supports only JSON mode.
 	Mode encoding.Mode
+	// ValidateOutputSchema enables the validation of the LLM output against the JSON schema,
+	// the violations are fed back to the LLM for the repair.
+	ValidateOutputSchema bool
+	// PostProcessors is the ordered pipeline applied to the LLM response
+	// before the output parsing.
+	PostProcessors []llmutils.PostProcessor
+	// StopConditions are the predicates evaluated after each LLM round,
+	// the run loop stops when any of them is met.
+	StopConditions []StopCondition
 	// SkipMessageHistory is a flag to skip adding Assistant messages to History.
 	SkipMessageHistory bool
 	// SkipToolHistory is a flag to skip adding Tool messages to History.
 	SkipToolHistory bool
+	// SkipToolArgsValidation is a flag to skip the validation of the tool arguments
+	// against the tool parameters schema before the tool is called.
+	SkipToolArgsValidation bool
+	// ToolPolicies are checked after the arguments validation, before the tool is called.
+	ToolPolicies []ToolPolicy
 	// IsGeneric is a flag to indicate that the assistant should add a generic message to the history,
 	// instead of the human
 	IsGeneric bool
@@ -106,18 +144,115 @@ type Config struct {
 	MaxToolCalls int
 	// MaxMessages is the maximum number of messages per run.
 	MaxMessages int
+	// Deadline is the wall-clock budget of the run, see WithDeadline.
+	Deadline time.Duration
+	// DedupeStore records the tool results of the runs with the idempotency key, see WithDedupeStore.
+	DedupeStore DedupeStore
+	// Runs is the manager of the asynchronous runs, see WithRuns.
+	Runs *Runs
+	// EventBus is the bus of the lifecycle events, see WithEventBus.
+	EventBus events.Bus
+	// MaxEmptyRetries is the maximum number of the empty LLM responses per run.
+	MaxEmptyRetries int
+	// MaxNotFoundTools is the maximum number of the not found tools in the LLM response.
+	MaxNotFoundTools int
+	// MaxToolRetries is the maximum number of the retries of the tool call,
+	// that failed with the transient error, see chatmodel.ToolErrorTransient.
+	MaxToolRetries int
+	// RecoveryStrategy decides how to recover from the failures of the run.
+	RecoveryStrategy RecoveryStrategy
+	// Middlewares are applied around the run, the first one is the outermost.
+	Middlewares []Middleware
+	// MessageTransformers are applied to the messages right before each LLM call.
+	MessageTransformers []MessageTransformer
+
+	// PromptRegistry is the registry of the system prompt variants, see WithPromptVariant.
+	PromptRegistry *prompts.Registry
+	// PromptName is the name of the system prompt in PromptRegistry.
+	PromptName string
+	// PromptVersion is the version of the system prompt,
+	// if empty the version is selected by the chat ID.
+	PromptVersion string
+	// idempotency derives the keys of the LLM and the tool calls of the run with the idempotency key.
+	idempotency *idempotency
+	// promptVariant is the variant selected for the run.
+	promptVariant *prompts.Variant
+	// resolveRunState is true for the outermost assistant,
+	// that resolves the run state references in the final answer.
+	resolveRunState bool
 
 	ReasoningEffort llms.ReasoningEffort
+	// ThinkingBudget is the maximum number of the thinking tokens,
+	// for the providers with llms.CapabilityThinkingBudget.
+	ThinkingBudget int
+
+	// DeveloperPrompt is a flag to send the system prompt as the developer message,
+	// for the OpenAI reasoning models. Other providers fold it into the system prompt.
+	DeveloperPrompt bool
+	// DeveloperInstructions are sent as the developer message after the system prompt,
+	// see WithDeveloperInstructions.
+	DeveloperInstructions string
 
 	// PromptCachePolicy configures provider-native prompt caching for the underlying llm call.
 	PromptCachePolicy *llms.PromptCachePolicy
+
+	// HistoryRepair defines how orphaned tool messages are repaired
+	// in the message history before each LLM call.
+	HistoryRepair llmutils.HistoryRepairMode
+
+	// BlobStore is the store for the tool outputs and LLM responses
+	// that exceed SpilloverThreshold bytes.
+	BlobStore store.BlobStore
+	// SpilloverThreshold is the size in bytes, above which the content
+	// is replaced by the artifact reference in the message history.
+	SpilloverThreshold int
+	// SpilloverSummarizer returns the summary of the spilled content.
+	SpilloverSummarizer SummarizeFunc
+	// ArtifactStore is the store for the attachments of the call,
+	// that the LLM provider can not take inline, see WithArtifactStore.
+	ArtifactStore artifacts.Store
+
+	// Redactor masks the secrets in the logs of the assistant, see WithRedactor.
+	Redactor *llmutils.Redactor
+
+	// ToolOutputSanitizer checks the tool outputs before they are sent to the LLM,
+	// see WithToolOutputSanitizer.
+	ToolOutputSanitizer ToolOutputSanitizer
+
+	// FallbackModels are called in order, when the LLM call fails, see WithFallbackModels.
+	FallbackModels []llms.Model
+	// FallbackPolicy decides which errors are retried with the fallback models,
+	// by default IsFallbackError.
+	FallbackPolicy FallbackPolicy
+	// ContextReducer reduces the message history, when the LLM call fails
+	// with llms.ErrContextLengthExceeded, see WithContextRecovery.
+	ContextReducer ContextReducer
+	// contextReducerSet is true, when ContextReducer is set by WithContextRecovery.
+	contextReducerSet bool
+
+	// ToolCallingMode defines how the tools are provided to the LLM,
+	// by default ReAct is used when the provider does not support function calling.
+	ToolCallingMode ToolCallingMode
+
+	// DryRun is a flag to return the assembled LLM call without calling the provider, see WithDryRun.
+	DryRun bool
+
+	// CostFunc returns the estimated cost of the LLM call, added to Response.Usage.Cost.
+	CostFunc CostFunc
+
+	// OutputSchemaHeader is the header of the output schema section, see WithOutputSchemaHeader.
+	OutputSchemaHeader string
+	// OutputSchemaPlacement defines how the output schema section is added to the prompt.
+	OutputSchemaPlacement OutputSchemaPlacement
 }
 
 func NewConfig(opts ...Option) *Config {
 	cfg := &Config{
-		Mode:         encoding.ModeDefault,
-		MaxToolCalls: DefaultMaxToolCalls,
-		MaxMessages:  DefaultMaxMessages,
+		Mode:             encoding.ModeDefault,
+		MaxToolCalls:     DefaultMaxToolCalls,
+		MaxMessages:      DefaultMaxMessages,
+		MaxEmptyRetries:  DefaultMaxRetries,
+		MaxNotFoundTools: DefaultMaxNotFound,
 	}
 	return cfg.Apply(opts...)
 }
@@ -137,6 +272,42 @@ func WithReasoningEffort(effort llms.ReasoningEffort) Option {
 	}
 }
 
+// WithThinkingBudget is an option to set the maximum number of the thinking tokens,
+// it takes precedence over the reasoning effort for the providers with llms.CapabilityThinkingBudget.
+func WithThinkingBudget(tokens int) Option {
+	return func(o *Config) {
+		o.ThinkingBudget = tokens
+	}
+}
+
+// WithRedactor is an option to mask the secrets in the logs of the assistant.
+// The parameters of the assistant tools are registered in the redactor,
+// so the callbacks with the same redactor, such as callbacks.RedactSecrets,
+// mask the tool arguments marked with the x-sensitive schema extension.
+func WithRedactor(r *llmutils.Redactor) Option {
+	return func(o *Config) {
+		o.Redactor = r
+	}
+}
+
+// WithDeveloperPrompt is an option to send the system prompt as the developer message,
+// when targeting the reasoning models.
+func WithDeveloperPrompt(val bool) Option {
+	return func(o *Config) {
+		o.DeveloperPrompt = val
+	}
+}
+
+// WithDeveloperInstructions is an option to send the instructions as the developer message
+// after the system prompt, for the instruction hierarchy of the models with llms.CapabilityDeveloperRole:
+// the system prompt has the priority over the developer instructions, and both over the user input.
+// The providers without the developer role fold the instructions into the system prompt.
+func WithDeveloperInstructions(instructions string) Option {
+	return func(o *Config) {
+		o.DeveloperInstructions = instructions
+	}
+}
+
 // WithPromptCachePolicy configures provider-native prompt caching for the underlying llm call.
 func WithPromptCachePolicy(promptCachePolicy *llms.PromptCachePolicy) Option {
 	return func(o *Config) {
@@ -144,12 +315,29 @@ func WithPromptCachePolicy(promptCachePolicy *llms.PromptCachePolicy) Option {
 	}
 }
 
+// WithHistoryRepair is an option that allows to specify how orphaned tool messages
+// are repaired in the message history before each LLM call.
+// The repair is disabled by default, and every dropped message is logged.
+func WithHistoryRepair(mode llmutils.HistoryRepairMode) Option {
+	return func(o *Config) {
+		o.HistoryRepair = mode
+	}
+}
+
 func WithResponseFormat(responseFormat *schema.ResponseFormat) Option {
 	return func(o *Config) {
 		o.ResponseFormat = responseFormat
 	}
 }
 
+// WithConstraint is an option to constrain the output to the grammar or the regular expression,
+// for the providers that support the constrained decoding.
+func WithConstraint(constraint *llms.Constraint) Option {
+	return func(o *Config) {
+		o.Constraint = constraint
+	}
+}
+
 func WithMaxToolCalls(maxToolCalls int) Option {
 	return func(o *Config) {
 		o.MaxToolCalls = maxToolCalls
@@ -162,6 +350,71 @@ func WithMaxMessages(maxMessages int) Option {
 	}
 }
 
+// WithMiddleware is an option that adds the middlewares applied around Call and Run,
+// the middlewares of the call options are applied inside the ones of the assistant.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *Config) {
+		o.Middlewares = append(slices.Clip(o.Middlewares), middlewares...)
+	}
+}
+
+// WithMessageTransformer is an option that adds the transformer applied to the messages
+// right before each LLM call, for example to redact the secrets, or to inject the retrieval context.
+// The transformers are applied in order, and do not change the message history of the run.
+func WithMessageTransformer(transformers ...MessageTransformer) Option {
+	return func(o *Config) {
+		o.MessageTransformers = append(slices.Clip(o.MessageTransformers), transformers...)
+	}
+}
+
+// WithPromptVariant is an option to replace the system prompt of the assistant
+// with the named prompt from the registry.
+// If the version is empty, it is selected per run by the deterministic bucketing on the chat ID,
+// so the prompt experiments can be measured by the variant of the Response.
+func WithPromptVariant(registry *prompts.Registry, name, version string) Option {
+	return func(o *Config) {
+		o.PromptRegistry = registry
+		o.PromptName = name
+		o.PromptVersion = version
+	}
+}
+
+// WithMaxEmptyRetries is an option that allows to specify the maximum number
+// of the empty LLM responses per run, before the run is aborted.
+// Zero disables the retries.
+func WithMaxEmptyRetries(maxRetries int) Option {
+	return func(o *Config) {
+		o.MaxEmptyRetries = maxRetries
+	}
+}
+
+// WithMaxNotFoundTools is an option that allows to specify the maximum number
+// of the not found tools in the LLM response, before the run is aborted.
+// Zero aborts the run on the first not found tool.
+func WithMaxNotFoundTools(maxNotFound int) Option {
+	return func(o *Config) {
+		o.MaxNotFoundTools = maxNotFound
+	}
+}
+
+// WithMaxToolRetries is an option that allows to specify the maximum number
+// of the retries of the tool call, that failed with the transient error.
+// The other categories are reported to the LLM, see chatmodel.ToolError.
+// Zero, the default, disables the retries.
+func WithMaxToolRetries(maxRetries int) Option {
+	return func(o *Config) {
+		o.MaxToolRetries = maxRetries
+	}
+}
+
+// WithRecoveryStrategy is an option that allows to decide whether to continue,
+// rewrite the prompt, or abort the run on the failures, see RecoveryStrategy.
+func WithRecoveryStrategy(strategy RecoveryStrategy) Option {
+	return func(o *Config) {
+		o.RecoveryStrategy = strategy
+	}
+}
+
 // WithMessageStore is an option that allows to specify the message store.
 func WithMessageStore(store store.MessageStore) Option {
 	return func(o *Config) {
@@ -176,6 +429,55 @@ func WithMode(mode encoding.Mode) Option {
 	}
 }
 
+// WithOutputSchemaValidation is an option to validate the LLM output against the JSON schema
+// of the output type, including the enum and format constraints, before it is parsed.
+// The violations are fed back to the LLM on the retry.
+func WithOutputSchemaValidation(validate bool) Option {
+	return func(o *Config) {
+		o.ValidateOutputSchema = validate
+	}
+}
+
+// WithPostProcessors is an option to set the ordered pipeline of the post-processors,
+// applied to the LLM response before the output parsing and adding to the message history,
+// for example:
+//
+//	assistants.WithPostProcessors(llmutils.StripThinking, llmutils.ExtractCodeBlock("json"))
+func WithPostProcessors(processors ...llmutils.PostProcessor) Option {
+	return func(o *Config) {
+		o.PostProcessors = processors
+	}
+}
+
+// WithStopConditions is an option to add the predicates evaluated after each LLM round,
+// to stop the run loop with the last LLM response as the final answer,
+// for finer control than WithMaxToolCalls and WithMaxMessages, for example:
+//
+//	assistants.WithStopConditions(assistants.StopOnContent("FINAL_ANSWER"), assistants.StopAfter(time.Minute))
+func WithStopConditions(conditions ...StopCondition) Option {
+	return func(o *Config) {
+		o.StopConditions = append(o.StopConditions, conditions...)
+	}
+}
+
+// WithSkipToolArgsValidation is an option to skip the validation of the tool arguments
+// against the tool parameters schema. By default, the invalid arguments are reported
+// to the LLM as the invalid_input ToolError, without calling the tool.
+func WithSkipToolArgsValidation(skip bool) Option {
+	return func(o *Config) {
+		o.SkipToolArgsValidation = skip
+	}
+}
+
+// WithToolPolicies is an option to check the tool calls with the policies before the tool is called,
+// see toolpolicy.Policy. The denied calls are reported to the LLM as the ToolError,
+// without calling the tool.
+func WithToolPolicies(policies ...ToolPolicy) Option {
+	return func(o *Config) {
+		o.ToolPolicies = append(o.ToolPolicies, policies...)
+	}
+}
+
 // WithEnableFunctionCalls is an option to indicate that the assistant should enable legacy function calls.
 func WithEnableFunctionCalls(val bool) Option {
 	return func(o *Config) {
@@ -219,6 +521,14 @@ func WithPromptInput(input map[string]any) Option {
 	}
 }
 
+// WithCostFunc is an option to estimate the cost of the LLM calls of the run,
+// for example with modelinfo.Cost, the cost is returned in Response.Usage.Cost.
+func WithCostFunc(fn CostFunc) Option {
+	return func(o *Config) {
+		o.CostFunc = fn
+	}
+}
+
 // WithModel is an option for LLM.Call.
 func WithModel(model string) Option {
 	return func(o *Config) {
@@ -360,6 +670,32 @@ func WithTool(tool llms.Tool) Option {
 	}
 }
 
+// WithForcedTool is an option to force the run to start with the call of the tool,
+// for example for the router-style assistants.
+// The tool choice is forced until the first tool call is executed, then the model decides.
+// The tool must be registered with WithTools, and the model must support function calling.
+func WithForcedTool(name string) Option {
+	return func(o *Config) {
+		o.ForcedTool = name
+	}
+}
+
+// WithParallelToolCalls is an option to enable or disable the parallel tool calls in the LLM response,
+// for the models that produce incompatible parallel tool calls.
+func WithParallelToolCalls(enabled bool) Option {
+	return func(o *Config) {
+		o.ParallelToolCalls = &enabled
+	}
+}
+
+// WithSequentialToolCalls is an option to execute the tool calls one by one,
+// in the order requested by the LLM, even if the model returns several calls in the response.
+func WithSequentialToolCalls(sequential bool) Option {
+	return func(o *Config) {
+		o.SequentialToolCalls = sequential
+	}
+}
+
 // WithToolChoice is an option for LLM.Call.
 func WithToolChoice(choice any) Option {
 	return func(o *Config) {
@@ -411,9 +747,15 @@ func (cfg *Config) GetCallOptions(options ...Option) []llms.CallOption {
 	if c.toolChoiceSet {
 		chainCallOption = append(chainCallOption, llms.WithToolChoice(c.ToolChoice))
 	}
+	if c.ParallelToolCalls != nil {
+		chainCallOption = append(chainCallOption, llms.WithParallelToolCalls(*c.ParallelToolCalls))
+	}
 	if c.ResponseFormat != nil {
 		chainCallOption = append(chainCallOption, llms.WithResponseFormat(c.ResponseFormat))
 	}
+	if c.Constraint != nil && len(c.Tools) == 0 {
+		chainCallOption = append(chainCallOption, llms.WithConstraint(c.Constraint))
+	}
 
 	if c.StreamingFunc != nil {
 		chainCallOption = append(chainCallOption, llms.WithStreamingFunc(c.StreamingFunc))
@@ -422,6 +764,9 @@ func (cfg *Config) GetCallOptions(options ...Option) []llms.CallOption {
 	if c.ReasoningEffort != llms.ReasoningEffortDefault {
 		chainCallOption = append(chainCallOption, llms.WithReasoningEffort(c.ReasoningEffort))
 	}
+	if c.ThinkingBudget > 0 {
+		chainCallOption = append(chainCallOption, llms.WithThinkingBudget(c.ThinkingBudget))
+	}
 	if c.PromptCachePolicy != nil {
 		chainCallOption = append(chainCallOption, llms.WithPromptCachePolicy(c.PromptCachePolicy))
 	}
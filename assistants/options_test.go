@@ -1,15 +1,21 @@
 package assistants_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
 	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
 	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
 	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
 	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
 func Test_ChainCallOptions(t *testing.T) {
@@ -50,6 +56,11 @@ func Test_ChainCallOptions(t *testing.T) {
 		assistants.WithRepetitionPenalty(1.2),
 		assistants.WithMaxToolCalls(10),
 		assistants.WithMaxMessages(100),
+		assistants.WithMaxEmptyRetries(3),
+		assistants.WithMaxNotFoundTools(1),
+		assistants.WithRecoveryStrategy(func(context.Context, assistants.IAssistant, *assistants.RecoveryEvent) assistants.Recovery {
+			return assistants.Recovery{Action: assistants.RecoveryAbort}
+		}),
 		assistants.WithEnableFunctionCalls(true),
 		assistants.WithGeneric(true),
 		assistants.WithSkipMessageHistory(true),
@@ -86,6 +97,7 @@ func Test_ChainCallOptions(t *testing.T) {
 		assistants.WithPromptInput(map[string]any{"Input": "input"}),
 		//assistants.WithCallback(callbacks.StreamLogHandler{}),
 		assistants.WithReasoningEffort(llms.ReasoningEffortLow),
+		assistants.WithThinkingBudget(2000),
 		assistants.WithPromptCachePolicy(&llms.PromptCachePolicy{
 			Request: &llms.PromptCacheRequestPolicy{
 				Key:       "test",
@@ -94,7 +106,7 @@ func Test_ChainCallOptions(t *testing.T) {
 		}),
 	)
 	llmOpts = cfg.GetCallOptions()
-	assert.Equal(t, 16, len(llmOpts))
+	assert.Equal(t, 17, len(llmOpts))
 }
 
 func Test_ChainCallOptions_PromptCachePolicy(t *testing.T) {
@@ -119,3 +131,202 @@ func Test_ChainCallOptions_PromptCachePolicy(t *testing.T) {
 	require.NotNil(t, got.PromptCachePolicy)
 	assert.Same(t, policy, got.PromptCachePolicy)
 }
+
+func Test_Assistant_DeveloperPrompt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("o3-mini").Times(4)
+
+	var roles []llms.Role
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+			roles = append(roles, messages[0].Role)
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "ok"}}}, nil
+		}).Times(2)
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	_, err := ag.Call(ctx, &assistants.CallInput{Input: "hi"})
+	require.NoError(t, err)
+	_, err = ag.Call(ctx, &assistants.CallInput{
+		Input:   "hi",
+		Options: []assistants.Option{assistants.WithDeveloperPrompt(true)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []llms.Role{llms.RoleSystem, llms.RoleDeveloper}, roles)
+}
+
+func Test_Assistant_DeveloperInstructions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("o3-mini").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+			require.Len(t, messages, 3)
+			assert.Equal(t, llms.RoleSystem, messages[0].Role)
+			assert.Equal(t, llms.RoleDeveloper, messages[1].Role)
+			assert.Equal(t, llms.TextPart("Answer in French."), messages[1].Parts[0])
+			assert.Equal(t, llms.RoleHuman, messages[2].Role)
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "ok"}}}, nil
+		}).Times(1)
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithDeveloperInstructions("Answer in French."),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	_, err := ag.Call(ctx, &assistants.CallInput{Input: "hi"})
+	require.NoError(t, err)
+}
+
+func Test_Assistant_ConstrainedDecoding(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderVLLM).Times(1)
+	mockLLM.EXPECT().GetName().Return("qwen").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			opts := llms.CallOptions{}
+			for _, opt := range options {
+				opt(&opts)
+			}
+			assert.Nil(t, opts.ResponseFormat)
+			require.NotNil(t, opts.Constraint)
+			assert.Equal(t, llms.ConstraintGrammar, opts.Constraint.Type)
+			assert.Contains(t, opts.Constraint.Value, `root ::= "{" ws "\"content\"" ws ":" ws string`)
+			// the schema instructions are in the system prompt
+			assert.Contains(t, messages[0].Parts[0].(llms.TextContent).Text, "# OUTPUT SCHEMA")
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"content":"ok"}`}}}, nil
+		}).Times(1)
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var out chatmodel.OutputResult
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "hi"}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", out.Content)
+
+	// the constraint is not sent with the tools
+	cfg := assistants.NewConfig(
+		assistants.WithConstraint(&llms.Constraint{Type: llms.ConstraintRegex, Value: "[a-z]+"}),
+	)
+	opts := llms.CallOptions{}
+	for _, opt := range cfg.GetCallOptions() {
+		opt(&opts)
+	}
+	assert.Equal(t, "[a-z]+", opts.Constraint.Value)
+
+	opts = llms.CallOptions{}
+	for _, opt := range cfg.GetCallOptions(assistants.WithTool(llms.Tool{Type: "function", Function: &llms.FunctionDefinition{Name: "search"}})) {
+		opt(&opts)
+	}
+	assert.Nil(t, opts.Constraint)
+}
+
+func Test_Assistant_JSONMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// GoogleAI supports only JSON mode
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderGoogleAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gemini").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			opts := llms.CallOptions{}
+			for _, opt := range options {
+				opt(&opts)
+			}
+			require.NotNil(t, opts.ResponseFormat)
+			assert.Equal(t, schema.ResponseFormatTypeJSONObject, opts.ResponseFormat.Type)
+			assert.Nil(t, opts.ResponseFormat.JSONSchema)
+			// the schema instructions are in the system prompt
+			assert.Contains(t, messages[0].Parts[0].(llms.TextContent).Text, "# OUTPUT SCHEMA")
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "Sure:\n```json\n{\"content\":\"ok\"}\n```\nLet me know [if] you need more {help}."}}}, nil
+		}).Times(1)
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var out chatmodel.OutputResult
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "hi"}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", out.Content)
+}
+
+func Test_Assistant_PromptVariant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(14)
+
+	var sysprompts []string
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+			sysprompts = append(sysprompts, messages[0].Parts[0].(llms.TextContent).Text)
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "ok"}}}, nil
+		}).Times(3)
+
+	registry := prompts.NewRegistry()
+	require.NoError(t, registry.Register("support", "v1", prompts.NewPromptTemplate("You are v1.", nil), 1))
+	require.NoError(t, registry.Register("support", "v2", prompts.NewPromptTemplate("You are v2.", nil), 0))
+
+	var buf bytes.Buffer
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithCallback(callbacks.NewPrinter(&buf, callbacks.ModeVerbose)),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "hi"})
+	require.NoError(t, err)
+	assert.Nil(t, resp.PromptVariant)
+	assert.NotContains(t, buf.String(), "Prompt Variant")
+
+	resp, err = ag.Call(ctx, &assistants.CallInput{
+		Input:   "hi",
+		Options: []assistants.Option{assistants.WithPromptVariant(registry, "support", "")},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.PromptVariant)
+	assert.Equal(t, "v1", resp.PromptVariant.Version)
+
+	resp, err = ag.Call(ctx, &assistants.CallInput{
+		Input:   "hi",
+		Options: []assistants.Option{assistants.WithPromptVariant(registry, "support", "v2")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", resp.PromptVariant.Version)
+
+	_, err = ag.Call(ctx, &assistants.CallInput{
+		Input:   "hi",
+		Options: []assistants.Option{assistants.WithPromptVariant(registry, "support", "v3")},
+	})
+	assert.ErrorIs(t, err, prompts.ErrPromptNotFound)
+
+	assert.Equal(t, []string{"You are helpful and friendly AI assistant.", "You are v1.", "You are v2."}, sysprompts)
+	assert.Contains(t, buf.String(), "Prompt Variant Succeeded: Generic Assistant: support@v1\n")
+	assert.Contains(t, buf.String(), "Prompt Variant Succeeded: Generic Assistant: support@v2\n")
+}
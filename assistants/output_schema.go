@@ -0,0 +1,68 @@
+package assistants
+
+import (
+	"strings"
+
+	"github.com/effective-security/gogentic/pkg/schema"
+)
+
+// DefaultOutputSchemaHeader is the header of the output schema section of the system prompt.
+const DefaultOutputSchemaHeader = "# OUTPUT SCHEMA"
+
+// OutputSchemaPromptInput is the name of the prompt input, replaced with the output schema section,
+// for example {{.output_schema}} in the Go template of the system prompt.
+// If the template does not have the placeholder, the section is appended to the system prompt.
+const OutputSchemaPromptInput = "output_schema"
+
+// outputSchemaMarker is the value of the output_schema prompt input,
+// replaced with the output schema section after the system prompt is rendered.
+const outputSchemaMarker = "__gogentic_output_schema__"
+
+// OutputSchemaPlacement defines how the output schema section is added to the prompt,
+// see WithOutputSchemaPlacement.
+type OutputSchemaPlacement int
+
+const (
+	// OutputSchemaInline adds the output schema section to the system prompt,
+	// at the output_schema placeholder or at the end.
+	OutputSchemaInline OutputSchemaPlacement = iota
+	// OutputSchemaMessage sends the output schema section as a separate message after the system prompt,
+	// with the developer role for the providers with llms.CapabilityDeveloperRole.
+	OutputSchemaMessage
+)
+
+// WithOutputSchemaHeader is an option to customize the header of the output schema section,
+// for example to match the language of the system prompt.
+// The default is DefaultOutputSchemaHeader.
+func WithOutputSchemaHeader(header string) Option {
+	return func(o *Config) {
+		o.OutputSchemaHeader = header
+	}
+}
+
+// WithOutputSchemaPlacement is an option to define how the output schema section is added to the prompt.
+func WithOutputSchemaPlacement(placement OutputSchemaPlacement) Option {
+	return func(o *Config) {
+		o.OutputSchemaPlacement = placement
+	}
+}
+
+// outputSchemaSection returns the output schema section with the header,
+// or empty string if the schema is not needed in the prompt.
+func (a *Assistant[O]) outputSchemaSection() string {
+	if a.cfg.ResponseFormat != nil && a.cfg.ResponseFormat.Type == schema.ResponseFormatTypeJSONSchema {
+		// the schema is sent in the response format
+		return ""
+	}
+	// if provider supports json response, but not json_schema,
+	// we need to add the output schema to the system prompt
+	outputSchema := strings.TrimRight(a.OutputParser.GetFormatInstructions(), "\n")
+	if outputSchema == "" {
+		return ""
+	}
+	header := a.cfg.OutputSchemaHeader
+	if header == "" {
+		header = DefaultOutputSchemaHeader
+	}
+	return header + "\n" + outputSchema
+}
@@ -0,0 +1,93 @@
+package assistants_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_OutputSchemaPlaceholder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(3)
+
+	ctx := context.Background()
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("Answer the question.\n\n{{.output_schema}}\n\nBe brief.", nil),
+		assistants.WithMode(encoding.ModeJSON),
+		assistants.WithOutputSchemaHeader("# FORMAT DE SORTIE"),
+	)
+	prompt, err := ag.GetSystemPrompt(ctx, "", nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(prompt, "Answer the question.\n\n# FORMAT DE SORTIE\n"), prompt)
+	assert.True(t, strings.HasSuffix(prompt, "\n\nBe brief."), prompt)
+	assert.NotContains(t, prompt, "# OUTPUT SCHEMA")
+
+	// without the placeholder the section is appended
+	ag = assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("Answer the question.", nil),
+		assistants.WithMode(encoding.ModeJSON),
+	)
+	prompt, err = ag.GetSystemPrompt(ctx, "", nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(prompt, "Answer the question.\n\n"+assistants.DefaultOutputSchemaHeader+"\n"), prompt)
+
+	// the placeholder is removed, when the schema is not needed
+	plain := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("Answer the question.{{.output_schema}}", nil),
+		assistants.WithMode(encoding.ModePlainText),
+	)
+	prompt, err = plain.GetSystemPrompt(ctx, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Answer the question.", prompt)
+}
+
+func Test_Assistant_OutputSchemaMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+			require.Len(t, messages, 3)
+			assert.Equal(t, llms.RoleSystem, messages[0].Role)
+			assert.Equal(t, llms.TextPart("Answer the question."), messages[0].Parts[0])
+			// the developer role is supported by OpenAI
+			assert.Equal(t, llms.RoleDeveloper, messages[1].Role)
+			assert.Contains(t, messages[1].Parts[0].(llms.TextContent).Text, assistants.DefaultOutputSchemaHeader)
+			assert.Equal(t, llms.RoleHuman, messages[2].Role)
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"content":"ok"}`}}}, nil
+		}).Times(1)
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("Answer the question.", nil),
+		assistants.WithMode(encoding.ModeJSON),
+		assistants.WithOutputSchemaPlacement(assistants.OutputSchemaMessage),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var out chatmodel.OutputResult
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "hi"}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", out.Content)
+
+	// the exported prompt includes the schema
+	prompt, err := ag.GetSystemPrompt(ctx, "", nil)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, assistants.DefaultOutputSchemaHeader)
+}
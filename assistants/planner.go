@@ -0,0 +1,389 @@
+package assistants
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/skills"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/x/format"
+	"github.com/effective-security/xlog"
+)
+
+// DefaultMaxReplans is the default number of times the plan can be revised after a failed step.
+const DefaultMaxReplans = 2
+
+// PlanStep is a step of the Plan.
+type PlanStep struct {
+	ID          int    `json:"id" yaml:"id" jsonschema:"title=ID,description=The sequential number of the step starting from 1."`
+	Description string `json:"description" yaml:"description" jsonschema:"title=Description,description=What needs to be done in the step."`
+	Executor    string `json:"executor,omitempty" yaml:"executor,omitempty" jsonschema:"title=Executor,description=The name of the assistant or tool to execute the step. Empty to use the default executor."`
+	Input       string `json:"input,omitempty" yaml:"input,omitempty" jsonschema:"title=Input,description=The input for the executor. JSON object for tools."`
+}
+
+// Plan is the multi-step plan produced by the planner LLM.
+type Plan struct {
+	Goal  string     `json:"goal" yaml:"goal" jsonschema:"title=Goal,description=The goal of the plan."`
+	Steps []PlanStep `json:"steps" yaml:"steps" jsonschema:"title=Steps,description=The steps to achieve the goal, in the order of execution."`
+}
+
+func (p Plan) GetContent() string {
+	return llmutils.ToJSON(p)
+}
+
+// PlanStepResult is the result of the executed step.
+type PlanStepResult struct {
+	Step   PlanStep `json:"step"`
+	Output string   `json:"output,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// PlannerCallback is an optional interface of the Callback,
+// to receive the progress of the PlannerAssistant.
+type PlannerCallback interface {
+	// OnPlanCreated is called when the plan is created, or revised after the failed step.
+	OnPlanCreated(ctx context.Context, e *PlanCreatedEvent)
+	OnPlanStepStart(ctx context.Context, e *PlanStepStartEvent)
+	OnPlanStepEnd(ctx context.Context, e *PlanStepEndEvent)
+}
+
+// PlanCreatedEvent is reported when the plan is created or revised.
+type PlanCreatedEvent struct {
+	Assistant IAssistant
+	Plan      *Plan
+	// Revision is zero for the initial plan.
+	Revision int
+}
+
+// PlanStepStartEvent is reported before the step is executed.
+type PlanStepStartEvent struct {
+	Assistant IAssistant
+	Step      *PlanStep
+}
+
+// PlanStepEndEvent is reported when the step is executed.
+type PlanStepEndEvent struct {
+	Assistant IAssistant
+	Result    *PlanStepResult
+}
+
+const plannerPrompt = `You are a planner. Break down the request of the user into a short sequence of steps to achieve the goal.
+Each step must be executed by one of the executors below, or by the default executor if the executor is empty.
+Use as few steps as possible. Do not add steps to summarize the results, the final answer is produced after the last step.
+
+# EXECUTORS
+{{.executors}}`
+
+const plannerSynthesisPrompt = `You are a helpful assistant. Answer the request of the user using the results of the executed plan.`
+
+const plannerExecutorPrompt = `You are a helpful assistant. Execute the step of the plan, using the results of the previous steps.`
+
+// PlannerAssistant is the plan-and-execute assistant.
+// It asks the LLM to produce a typed Plan, executes the steps sequentially
+// with the sub-assistants, tools or the default executor,
+// revises the plan when a step fails, and synthesizes the final answer.
+//
+// The internal LLM calls do not add messages to the message store.
+type PlannerAssistant struct {
+	name        string
+	description string
+
+	planner     *Assistant[Plan]
+	executor    *Assistant[chatmodel.String]
+	synthesizer *Assistant[chatmodel.String]
+
+	assistants     map[string]IAssistant
+	assistantNames []string
+	toolsByName    map[string]tools.ITool
+	tools          []tools.ITool
+
+	maxReplans int
+}
+
+var _ IAssistant = (*PlannerAssistant)(nil)
+
+// NewPlannerAssistant creates a new PlannerAssistant,
+// the options are applied to the planner, executor and synthesis calls,
+// the executor and synthesis calls always use the plain text mode.
+func NewPlannerAssistant(llmModel llms.Model, options ...Option) *PlannerAssistant {
+	textOptions := append(append([]Option{}, options...), WithMode(encoding.ModePlainText))
+	return &PlannerAssistant{
+		name:        "Planner Assistant",
+		description: "An AI assistant that plans and executes multi-step tasks.",
+		planner: NewAssistant[Plan](llmModel, prompts.NewPromptTemplate(plannerPrompt, []string{"executors"}), options...).
+			WithName("Planner"),
+		executor: NewAssistant[chatmodel.String](llmModel, prompts.NewPromptTemplate(plannerExecutorPrompt, nil), textOptions...).
+			WithName("Plan Executor"),
+		synthesizer: NewAssistant[chatmodel.String](llmModel, prompts.NewPromptTemplate(plannerSynthesisPrompt, nil), textOptions...).
+			WithName("Plan Synthesizer"),
+		assistants:  make(map[string]IAssistant),
+		toolsByName: make(map[string]tools.ITool),
+		maxReplans:  DefaultMaxReplans,
+	}
+}
+
+// WithName sets the name of the Assistant, when used in a prompt of another Agents or LLMs.
+func (p *PlannerAssistant) WithName(name string) *PlannerAssistant {
+	p.name = name
+	return p
+}
+
+// WithDescription sets the description of the Assistant, to be used in the prompt of other Agents or LLMs.
+func (p *PlannerAssistant) WithDescription(description string) *PlannerAssistant {
+	p.description = description
+	return p
+}
+
+// WithAssistants adds the sub-assistants that can execute the steps.
+func (p *PlannerAssistant) WithAssistants(list ...IAssistant) *PlannerAssistant {
+	for _, a := range list {
+		if _, ok := p.assistants[a.Name()]; !ok {
+			p.assistantNames = append(p.assistantNames, a.Name())
+		}
+		p.assistants[a.Name()] = a
+	}
+	return p
+}
+
+// WithTools adds the tools that can execute the steps,
+// the tools are also available to the default executor.
+func (p *PlannerAssistant) WithTools(list ...tools.ITool) *PlannerAssistant {
+	for _, t := range list {
+		if _, ok := p.toolsByName[t.Name()]; ok {
+			continue
+		}
+		p.toolsByName[t.Name()] = t
+		p.tools = append(p.tools, t)
+	}
+	p.executor.WithTools(list...)
+	return p
+}
+
+// WithMaxReplans sets the number of times the plan can be revised after a failed step.
+func (p *PlannerAssistant) WithMaxReplans(n int) *PlannerAssistant {
+	p.maxReplans = n
+	return p
+}
+
+// Name returns the name of the Assistant.
+func (p *PlannerAssistant) Name() string {
+	return p.name
+}
+
+// Description returns the description of the Assistant.
+func (p *PlannerAssistant) Description() string {
+	return p.description
+}
+
+func (p *PlannerAssistant) GetTools() []tools.ITool {
+	return p.tools
+}
+
+func (p *PlannerAssistant) GetSkills() skills.Skills {
+	return nil
+}
+
+func (p *PlannerAssistant) FormatPrompt(values map[string]any) (llms.PromptValue, error) {
+	return p.planner.FormatPrompt(values)
+}
+
+func (p *PlannerAssistant) GetPromptInputVariables() []string {
+	return p.planner.GetPromptInputVariables()
+}
+
+// Call creates the plan, executes the steps and returns the synthesized answer.
+// The Usage of the response includes all LLM calls of the run.
+func (p *PlannerAssistant) Call(ctx context.Context, input *CallInput) (*Response, error) {
+	cfg := NewConfig(input.Options...)
+	callback := cfg.CallbackHandler
+	if callback != nil {
+		callback.OnAssistantStart(ctx, &AssistantStartEvent{Assistant: p, Input: input.Input})
+	}
+
+	started := time.Now()
+	resp, err := p.run(ctx, cfg, input)
+	if err != nil {
+		if callback != nil {
+			callback.OnAssistantError(ctx, &AssistantErrorEvent{Assistant: p, Input: input.Input, Err: err, Latency: time.Since(started)})
+		}
+		return nil, err
+	}
+	if callback != nil {
+		callback.OnAssistantEnd(ctx, &AssistantEndEvent{Assistant: p, Input: input.Input, Response: resp, Latency: time.Since(started)})
+	}
+	return resp, nil
+}
+
+func (p *PlannerAssistant) run(ctx context.Context, cfg *Config, input *CallInput) (*Response, error) {
+	var usage llms.UsageStats
+	planCb, _ := cfg.CallbackHandler.(PlannerCallback)
+	options := append(append([]Option{}, input.Options...), WithSkipMessageHistory(true))
+
+	plan, err := p.plan(ctx, input.Input, nil, nil, options, &usage)
+	if err != nil {
+		return nil, err
+	}
+	if planCb != nil {
+		planCb.OnPlanCreated(ctx, &PlanCreatedEvent{Assistant: p, Plan: plan})
+	}
+
+	var results []PlanStepResult
+	revision := 0
+	steps := plan.Steps
+	for len(steps) > 0 {
+		step := steps[0]
+		steps = steps[1:]
+
+		if planCb != nil {
+			planCb.OnPlanStepStart(ctx, &PlanStepStartEvent{Assistant: p, Step: &step})
+		}
+		if input.OnProgress != nil {
+			input.OnProgress(ctx, p, fmt.Sprintf("Step %d", step.ID), step.Description)
+		}
+
+		output, err := p.executeStep(ctx, input.Input, &step, results, options, &usage)
+		result := PlanStepResult{Step: step, Output: output}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		if planCb != nil {
+			planCb.OnPlanStepEnd(ctx, &PlanStepEndEvent{Assistant: p, Result: &result})
+		}
+		if err == nil {
+			results = append(results, result)
+			continue
+		}
+
+		logger.ContextKV(ctx, xlog.DEBUG,
+			"assistant", p.name,
+			"status", "plan_step_failed",
+			"step", step.ID,
+			"executor", step.Executor,
+			"err", err.Error(),
+		)
+
+		if revision >= p.maxReplans {
+			return nil, errors.WithMessagef(err, "assistant %s: step %d failed", p.name, step.ID)
+		}
+		revision++
+
+		plan, err = p.plan(ctx, input.Input, results, &result, options, &usage)
+		if err != nil {
+			return nil, err
+		}
+		if planCb != nil {
+			planCb.OnPlanCreated(ctx, &PlanCreatedEvent{Assistant: p, Plan: plan, Revision: revision})
+		}
+		steps = plan.Steps
+	}
+
+	resp, err := p.synthesizer.Call(ctx, &CallInput{
+		Input:    planResultsPrompt(input.Input, results),
+		Messages: input.Messages,
+		Options:  options,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Usage.Add(&usage)
+	return resp, nil
+}
+
+// plan asks the LLM for the plan, or the revised plan of the remaining steps if the step has failed.
+func (p *PlannerAssistant) plan(ctx context.Context, request string, results []PlanStepResult, failed *PlanStepResult, options []Option, usage *llms.UsageStats) (*Plan, error) {
+	in := request
+	if failed != nil {
+		in = fmt.Sprintf("%s\n\n# FAILED STEP\nStep %d: %s\nError: %s\n\nReturn the revised plan with the remaining steps only.",
+			planResultsPrompt(request, results), failed.Step.ID, failed.Step.Description, failed.Error)
+	}
+
+	var plan Plan
+	resp, err := p.planner.Run(ctx, &CallInput{
+		Input:        in,
+		PromptInputs: map[string]any{"executors": p.executorsPrompt()},
+		Options:      options,
+	}, &plan)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "assistant %s: failed to create plan", p.name)
+	}
+	usage.Add(&resp.Usage)
+	return &plan, nil
+}
+
+// executeStep executes the step with the sub-assistant or the tool,
+// or with the default executor when the executor is not found.
+func (p *PlannerAssistant) executeStep(ctx context.Context, request string, step *PlanStep, results []PlanStepResult, options []Option, usage *llms.UsageStats) (string, error) {
+	stepInput := step.Input
+	if stepInput == "" {
+		stepInput = step.Description
+	}
+
+	if a, ok := p.assistants[step.Executor]; ok {
+		resp, err := a.Call(ctx, &CallInput{Input: stepInput, Options: options})
+		if err != nil {
+			return "", err
+		}
+		usage.Add(&resp.Usage)
+		return responseContent(resp), nil
+	}
+
+	if tool, ok := p.toolsByName[step.Executor]; ok {
+		return tool.Call(ctx, stepInput)
+	}
+
+	in := fmt.Sprintf("%s\n\n# STEP\n%s", planResultsPrompt(request, results), step.Description)
+	if step.Input != "" {
+		in += "\n\n" + step.Input
+	}
+	resp, err := p.executor.Call(ctx, &CallInput{Input: in, Options: options})
+	if err != nil {
+		return "", err
+	}
+	usage.Add(&resp.Usage)
+	return responseContent(resp), nil
+}
+
+func (p *PlannerAssistant) executorsPrompt() string {
+	var b strings.Builder
+	for _, name := range p.assistantNames {
+		_, _ = fmt.Fprintf(&b, "- Name: %s\n  Type: assistant\n  Description: %s\n", name, format.TextOneLine(p.assistants[name].Description()))
+	}
+	for _, tool := range p.tools {
+		_, _ = fmt.Fprintf(&b, "- Name: %s\n  Type: tool\n  Description: %s\n", tool.Name(), format.TextOneLine(tool.Description()))
+		if params := tool.Parameters(); params != nil {
+			_, _ = fmt.Fprintf(&b, "  Input schema: %s\n", llmutils.ToJSON(params))
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("Only the default executor is available.\n")
+	}
+	return b.String()
+}
+
+func planResultsPrompt(request string, results []PlanStepResult) string {
+	var b strings.Builder
+	b.WriteString("# REQUEST\n")
+	b.WriteString(request)
+	if len(results) > 0 {
+		b.WriteString("\n\n# COMPLETED STEPS\n")
+		for _, r := range results {
+			_, _ = fmt.Fprintf(&b, "## Step %d: %s\n%s\n", r.Step.ID, r.Step.Description, r.Output)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func responseContent(resp *Response) string {
+	if resp == nil || len(resp.Choices) == 0 {
+		return ""
+	}
+	return resp.Choices[0].Content
+}
@@ -0,0 +1,162 @@
+package assistants_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mocks/mockassitants"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type planRecorder struct {
+	batchRecorder
+
+	plans []int
+	steps []string
+}
+
+func (r *planRecorder) OnPlanCreated(_ context.Context, e *assistants.PlanCreatedEvent) {
+	r.plans = append(r.plans, e.Revision)
+}
+
+func (r *planRecorder) OnPlanStepStart(_ context.Context, e *assistants.PlanStepStartEvent) {
+	r.steps = append(r.steps, "start:"+e.Step.Description)
+}
+
+func (r *planRecorder) OnPlanStepEnd(_ context.Context, e *assistants.PlanStepEndEvent) {
+	if e.Result.Error != "" {
+		r.steps = append(r.steps, "failed:"+e.Result.Step.Description)
+		return
+	}
+	r.steps = append(r.steps, "end:"+e.Result.Step.Description)
+}
+
+func textResponse(text string) *llms.ContentResponse {
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			Content: text,
+			Usage:   llms.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
+		}},
+	}
+}
+
+func Test_PlannerAssistant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(4)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(8)
+
+	weather := mocktools.NewMockTool[any, any](ctrl)
+	weather.EXPECT().Name().Return("weather").Times(5)
+	weather.EXPECT().Description().Return("Returns the weather in the city.").Times(3)
+	weather.EXPECT().Parameters().Return(nil).Times(3)
+	weather.EXPECT().Call(gomock.Any(), `{"city":"Seattle"}`).Return("", errors.New("service unavailable")).Times(1)
+
+	forecaster := mockassitants.NewMockIAssistant(ctrl)
+	forecaster.EXPECT().Name().Return("forecaster").Times(3)
+	forecaster.EXPECT().Description().Return("Forecasts the weather.").Times(2)
+	forecaster.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, input *assistants.CallInput) (*assistants.Response, error) {
+			assert.Equal(t, "Seattle", input.Input)
+			return &assistants.Response{
+				Choices: []*llms.ContentChoice{{Content: "rainy"}},
+				Usage:   llms.UsageStats{Usage: llms.Usage{TotalTokens: 100}, LlmCallCount: 1},
+			}, nil
+		}).Times(1)
+
+	var calls []string
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			sys := messages[0].Parts[0].(llms.TextContent).Text
+			human := messages[len(messages)-1].Parts[0].(llms.TextContent).Text
+			switch {
+			case strings.HasPrefix(sys, "You are a planner"):
+				assert.Contains(t, sys, "- Name: forecaster\n  Type: assistant")
+				assert.Contains(t, sys, "- Name: weather\n  Type: tool")
+				if strings.Contains(human, "# FAILED STEP") {
+					calls = append(calls, "replan")
+					assert.Contains(t, human, "Error: service unavailable")
+					return textResponse(`{"goal":"weather","steps":[{"id":2,"description":"forecast","executor":"forecaster","input":"Seattle"},{"id":3,"description":"advise"}]}`), nil
+				}
+				calls = append(calls, "plan")
+				return textResponse(`{"goal":"weather","steps":[{"id":1,"description":"get weather","executor":"weather","input":"{\"city\":\"Seattle\"}"}]}`), nil
+			case strings.HasPrefix(sys, "You are a helpful assistant. Execute"):
+				calls = append(calls, "execute")
+				assert.Contains(t, human, "## Step 2: forecast\nrainy")
+				assert.Contains(t, human, "# STEP\nadvise")
+				return textResponse("take an umbrella"), nil
+			default:
+				calls = append(calls, "synthesize")
+				assert.Contains(t, human, "# REQUEST\nWhat to wear in Seattle?")
+				assert.Contains(t, human, "## Step 3: advise\ntake an umbrella")
+				return textResponse("It is rainy, take an umbrella."), nil
+			}
+		}).Times(4)
+
+	rec := &planRecorder{}
+	ag := assistants.NewPlannerAssistant(mockLLM).
+		WithAssistants(forecaster).
+		WithTools(weather)
+	assert.Equal(t, "Planner Assistant", ag.Name())
+	assert.Len(t, ag.GetTools(), 1)
+
+	var progress []string
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{
+		Input:   "What to wear in Seattle?",
+		Options: []assistants.Option{assistants.WithCallback(rec)},
+		OnProgress: func(_ context.Context, _ assistants.IAssistant, title, _ string) {
+			progress = append(progress, title)
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "It is rainy, take an umbrella.", resp.Choices[0].Content)
+	assert.Equal(t, []string{"plan", "replan", "execute", "synthesize"}, calls)
+	assert.Equal(t, []int{0, 1}, rec.plans)
+	assert.Equal(t, []string{
+		"start:get weather", "failed:get weather",
+		"start:forecast", "end:forecast",
+		"start:advise", "end:advise",
+	}, rec.steps)
+	assert.Equal(t, []string{"Step 1", "Step 2", "Step 3"}, progress)
+	assert.Equal(t, uint64(4*15+100), resp.Usage.TotalTokens)
+	assert.Equal(t, uint32(5), resp.Usage.LlmCallCount)
+}
+
+func Test_PlannerAssistant_MaxReplans(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(3)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(textResponse(`{"goal":"weather","steps":[{"id":1,"description":"get weather","executor":"weather","input":"{}"}]}`), nil).
+		Times(1)
+
+	weather := mocktools.NewMockTool[any, any](ctrl)
+	weather.EXPECT().Name().Return("weather").Times(4)
+	weather.EXPECT().Description().Return("Returns the weather in the city.").Times(2)
+	weather.EXPECT().Parameters().Return(nil).Times(2)
+	weather.EXPECT().Call(gomock.Any(), "{}").Return("", errors.New("service unavailable")).Times(1)
+
+	ag := assistants.NewPlannerAssistant(mockLLM).
+		WithName("weather planner").
+		WithTools(weather).
+		WithMaxReplans(0)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	_, err := ag.Call(ctx, &assistants.CallInput{Input: "weather?"})
+	assert.EqualError(t, err, "assistant weather planner: step 1 failed: service unavailable")
+}
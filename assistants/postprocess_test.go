@@ -0,0 +1,47 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_PostProcessors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: "<think>\nthe user wants a haiku\n</think>\n\nOld pond  \n\n\n\nfrog jumps in\n"}},
+	}, nil)
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithPostProcessors(llmutils.StripThinking, llmutils.NormalizeMarkdown),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+
+	var output chatmodel.String
+	resp, err := ag.Run(ctx, &assistants.CallInput{Input: "write a haiku"}, &output)
+	require.NoError(t, err)
+
+	exp := "Old pond\n\nfrog jumps in"
+	assert.Equal(t, exp, output.GetContent())
+	assert.Equal(t, exp, resp.Choices[0].Content)
+	last := resp.Messages[len(resp.Messages)-1]
+	assert.Equal(t, exp, last.Parts[0].(llms.TextContent).Text)
+}
@@ -0,0 +1,55 @@
+package assistants
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/xlog"
+)
+
+// PromptVariantCallback is an optional interface of the Callback,
+// to receive the result of the run with the selected system prompt variant.
+type PromptVariantCallback interface {
+	// OnAssistantPromptVariant is called when the run with the variant ends.
+	OnAssistantPromptVariant(ctx context.Context, e *PromptVariantEvent)
+}
+
+// PromptVariantEvent is reported when the run with the system prompt variant ends.
+type PromptVariantEvent struct {
+	Assistant IAssistant
+	Variant   *prompts.Variant
+	// Err is nil on success, or the final error of the run.
+	Err error
+}
+
+// selectPromptVariant returns the system prompt variant for the run,
+// or nil if WithPromptVariant is not configured.
+func (c *Config) selectPromptVariant(ctx context.Context) (*prompts.Variant, error) {
+	if c.PromptRegistry == nil || c.PromptName == "" {
+		return nil, nil
+	}
+	if c.PromptVersion != "" {
+		return c.PromptRegistry.Get(c.PromptName, c.PromptVersion)
+	}
+
+	chatCtx := chatmodel.GetChatContext(ctx)
+	if chatCtx == nil {
+		return nil, errors.WithStack(chatmodel.ErrInvalidChatContext)
+	}
+	chatID := chatCtx.GetChatID()
+	if chatID == "" {
+		return nil, errors.New("invalid chat ID")
+	}
+	v, err := c.PromptRegistry.Select(c.PromptName, chatID)
+	if err != nil {
+		return nil, err
+	}
+	logger.ContextKV(ctx, xlog.DEBUG,
+		"prompt", v.Name,
+		"variant", v.Version,
+		"chat_id", chatID,
+	)
+	return v, nil
+}
@@ -0,0 +1,214 @@
+package assistants
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/x/format"
+	"github.com/effective-security/xlog"
+)
+
+// ToolCallingMode defines how the tools are provided to the LLM.
+type ToolCallingMode string
+
+const (
+	// ToolCallingAuto uses the native function calling if the provider supports it,
+	// otherwise ReAct. This is the default mode.
+	ToolCallingAuto ToolCallingMode = ""
+	// ToolCallingNative uses the native function calling of the provider.
+	ToolCallingNative ToolCallingMode = "native"
+	// ToolCallingReAct uses ReAct-formatted Thought/Action/Observation text,
+	// for the models without the native function calling.
+	ToolCallingReAct ToolCallingMode = "react"
+)
+
+// WithToolCallingMode is an option that allows to specify how the tools are provided to the LLM.
+func WithToolCallingMode(mode ToolCallingMode) Option {
+	return func(o *Config) {
+		o.ToolCallingMode = mode
+	}
+}
+
+// useReAct returns true if the ReAct mode should be used for the model capabilities.
+func (cfg *Config) useReAct(caps llms.Capability) bool {
+	switch cfg.ToolCallingMode {
+	case ToolCallingReAct:
+		return true
+	case ToolCallingNative:
+		return false
+	default:
+		return !caps.Supports(llms.CapabilityFunctionCalling)
+	}
+}
+
+// ReActObservationPrefix is the prefix of the tool output in the message history.
+const ReActObservationPrefix = "Observation:"
+
+// ReActPrompt returns the system prompt instructions for the ReAct mode.
+func ReActPrompt(list []tools.ITool) string {
+	var b strings.Builder
+	b.WriteString("# TOOLS\n")
+	b.WriteString("You have access to the following tools:\n")
+	for _, tool := range list {
+		_, _ = fmt.Fprintf(&b, "- Name: %s\n", tool.Name())
+		_, _ = fmt.Fprintf(&b, "  Description: %s\n", format.TextOneLine(tool.Description()))
+		if params := tool.Parameters(); params != nil {
+			_, _ = fmt.Fprintf(&b, "  Input schema: %s\n", llmutils.ToJSON(params))
+		}
+	}
+	b.WriteString(`
+To use a tool, respond in the following format, and stop after the Action Input:
+Thought: your reasoning about what to do next
+Action: the tool name, exactly one of the tools above
+Action Input: the tool input as a single line JSON object
+
+The tool result will be provided to you as:
+Observation: the tool result
+
+Repeat Thought/Action/Action Input/Observation as many times as needed.
+When you have the answer, respond in the following format:
+Thought: I know the final answer
+Final Answer: the answer to the original question`)
+	return b.String()
+}
+
+// ReActStep is the parsed ReAct response of the LLM.
+type ReActStep struct {
+	Thought     string
+	Action      string
+	ActionInput string
+	// FinalAnswer is set when the LLM returned the answer.
+	FinalAnswer string
+}
+
+var (
+	reReActAction      = regexp.MustCompile(`(?m)^\s*Action\s*:\s*(.+?)\s*$`)
+	reReActActionInput = regexp.MustCompile(`(?m)^\s*Action\s+Input\s*:`)
+	reReActFinal       = regexp.MustCompile(`(?m)^\s*Final\s+Answer\s*:`)
+	reReActThought     = regexp.MustCompile(`(?m)^\s*Thought\s*:\s*`)
+	reReActObservation = regexp.MustCompile(`(?m)^\s*Observation\s*:`)
+)
+
+// ParseReAct parses the ReAct response of the LLM.
+// If the response has neither Action nor Final Answer,
+// the whole text is returned as the final answer.
+func ParseReAct(text string) *ReActStep {
+	step := &ReActStep{}
+
+	if loc := reReActFinal.FindStringIndex(text); loc != nil {
+		step.Thought = reActThought(text[:loc[0]])
+		step.FinalAnswer = strings.TrimSpace(text[loc[1]:])
+		return step
+	}
+
+	actionLoc := reReActAction.FindStringSubmatchIndex(text)
+	inputLoc := reReActActionInput.FindStringIndex(text)
+	if actionLoc == nil || inputLoc == nil || inputLoc[0] < actionLoc[1] {
+		step.FinalAnswer = strings.TrimSpace(text)
+		return step
+	}
+
+	step.Thought = reActThought(text[:actionLoc[0]])
+	step.Action = strings.Trim(text[actionLoc[2]:actionLoc[3]], "`\"' ")
+
+	input := text[inputLoc[1]:]
+	// the models often hallucinate the observation, ignore it
+	if loc := reReActObservation.FindStringIndex(input); loc != nil {
+		input = input[:loc[0]]
+	}
+	step.ActionInput = string(llmutils.CleanJSON([]byte(strings.TrimSpace(input))))
+	if step.ActionInput == "" {
+		step.ActionInput = "{}"
+	}
+	return step
+}
+
+func reActThought(text string) string {
+	return strings.TrimSpace(reReActThought.ReplaceAllString(text, ""))
+}
+
+// executeReActStep parses the ReAct response, and executes the requested tool.
+// The tool call and the tool response are added to the message history as text messages,
+// as the provider may not support the tool messages.
+func (a *Assistant[O]) executeReActStep(ctx context.Context, orgID string, cfg *Config, messageHistory llms.Messages, resp *Response, options ...Option) (int, int, llms.Messages, error) {
+	choice := resp.Choices[0]
+	step := ParseReAct(choice.Content)
+	if step.Action == "" {
+		// replace the choices with the final answer
+		final := *choice
+		final.Content = step.FinalAnswer
+		resp.Choices = []*llms.ContentChoice{&final}
+		return 0, 0, messageHistory, nil
+	}
+
+	logger.ContextKV(ctx, xlog.DEBUG,
+		"assistant", a.name,
+		"status", "react_action",
+		"action", step.Action,
+	)
+
+	// execute the action as the tool call
+	toolCall := *choice
+	toolCall.Content = ""
+	toolCall.ToolCalls = []llms.ToolCall{{
+		ID:   fmt.Sprintf("react_%d", resp.Usage.LlmCallCount),
+		Type: "function",
+		FunctionCall: &llms.FunctionCall{
+			Name:      step.Action,
+			Arguments: step.ActionInput,
+		},
+	}}
+	resp.Choices = []*llms.ContentChoice{&toolCall}
+
+	historyLen := len(messageHistory)
+	respLen := len(resp.Messages)
+	executed, notFound, messageHistory, err := a.executeToolCalls(ctx, orgID, cfg, messageHistory, resp, options...)
+	if err != nil {
+		return executed, notFound, messageHistory, err
+	}
+
+	// convert the tool messages to the text
+	messageHistory = append(messageHistory[:historyLen], reActMessages(choice.Content, messageHistory[historyLen:])...)
+	if len(resp.Messages) > respLen {
+		resp.Messages = append(resp.Messages[:respLen], reActMessages(choice.Content, resp.Messages[respLen:])...)
+	}
+	return executed, notFound, messageHistory, nil
+}
+
+// reActMessages converts the tool call and the tool response messages
+// to the AI text with the action, and the human text with the observation.
+func reActMessages(content string, msgs []llms.Message) []llms.Message {
+	// cut the hallucinated observation
+	if loc := reReActObservation.FindStringIndex(content); loc != nil {
+		content = content[:loc[0]]
+	}
+	content = strings.TrimSpace(content)
+
+	res := make([]llms.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		converted := llms.Message{Source: msg.Source}
+		switch msg.Role {
+		case llms.RoleTool:
+			var b strings.Builder
+			for _, part := range msg.Parts {
+				if tr, ok := part.(llms.ToolCallResponse); ok {
+					b.WriteString(ReActObservationPrefix)
+					b.WriteString(" ")
+					b.WriteString(tr.Content)
+				}
+			}
+			converted.Role = llms.RoleHuman
+			converted.Parts = []llms.ContentPart{llms.TextContent{Text: b.String()}}
+		default:
+			converted.Role = llms.RoleAI
+			converted.Parts = []llms.ContentPart{llms.TextContent{Text: content}}
+		}
+		res = append(res, converted)
+	}
+	return res
+}
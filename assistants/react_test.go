@@ -0,0 +1,148 @@
+package assistants_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_ParseReAct(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		name string
+		text string
+		exp  assistants.ReActStep
+	}{
+		{
+			name: "action",
+			text: "Thought: I need to search\nAction: web_search\nAction Input: {\"query\": \"go\"}",
+			exp:  assistants.ReActStep{Thought: "I need to search", Action: "web_search", ActionInput: `{"query": "go"}`},
+		},
+		{
+			name: "fenced input with hallucinated observation",
+			text: "Thought: search\nAction: `web_search`\nAction Input:\n```json\n{\"query\": \"go\"}\n```\nObservation: made up",
+			exp:  assistants.ReActStep{Thought: "search", Action: "web_search", ActionInput: `{"query": "go"}`},
+		},
+		{
+			name: "empty input",
+			text: "Action: now\nAction Input:",
+			exp:  assistants.ReActStep{Action: "now", ActionInput: "{}"},
+		},
+		{
+			name: "final answer",
+			text: "Thought: I know the final answer\nFinal Answer: 42\nis the answer",
+			exp:  assistants.ReActStep{Thought: "I know the final answer", FinalAnswer: "42\nis the answer"},
+		},
+		{
+			name: "plain text",
+			text: " The answer is 42 ",
+			exp:  assistants.ReActStep{FinalAnswer: "The answer is 42"},
+		},
+		{
+			name: "action without input",
+			text: "Action: web_search",
+			exp:  assistants.ReActStep{FinalAnswer: "Action: web_search"},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, *assistants.ParseReAct(tc.text))
+		})
+	}
+}
+
+func Test_Assistant_ReAct(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	systemPrompt := prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", []string{})
+	mockLLM := mockllms.NewMockModel(ctrl)
+	// Cloudflare does not support function calling
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderCloudflare).Times(3)
+	mockLLM.EXPECT().GetName().Return("llama").Times(4)
+
+	mockTool := mocktools.NewMockTool[any, any](ctrl)
+	mockTool.EXPECT().Name().Return("weather").Times(2)
+	mockTool.EXPECT().Description().Return("Returns the weather in the city.").Times(2)
+	mockTool.EXPECT().Parameters().Return(nil).Times(2)
+	mockTool.EXPECT().Call(gomock.Any(), `{"city":"Seattle"}`).Return("rainy", nil).Times(1)
+
+	memstore := store.NewMemoryStore()
+	chatCtx := chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil)
+	ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+
+	call := 0
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			call++
+			opts := llms.CallOptions{}
+			for _, opt := range options {
+				opt(&opts)
+			}
+			assert.Empty(t, opts.Tools)
+			assert.Equal(t, []string{"\nObservation:"}, opts.StopWords)
+
+			sys := messages[0].Parts[0].(llms.TextContent).Text
+			assert.Contains(t, sys, "# TOOLS")
+			assert.Contains(t, sys, "- Name: weather")
+
+			// only text messages are sent
+			for _, msg := range messages {
+				for _, part := range msg.Parts {
+					assert.IsType(t, llms.TextContent{}, part)
+				}
+			}
+
+			if call == 1 {
+				return &llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{
+						Content: "Thought: I need the weather\nAction: weather\nAction Input: {\"city\":\"Seattle\"}",
+					}},
+				}, nil
+			}
+
+			last := messages[len(messages)-1]
+			assert.Equal(t, llms.RoleHuman, last.Role)
+			assert.Equal(t, "Observation: rainy", last.Parts[0].(llms.TextContent).Text)
+			return &llms.ContentResponse{
+				Choices: []*llms.ContentChoice{{
+					Content: "Thought: I know the final answer\nFinal Answer: It is rainy in Seattle.",
+				}},
+			}, nil
+		}).Times(2)
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM, systemPrompt,
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithMessageStore(memstore),
+	).WithTools(mockTool)
+
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "What is the weather in Seattle?"})
+	require.NoError(t, err)
+	assert.Equal(t, "It is rainy in Seattle.", resp.String())
+
+	var roles []string
+	for _, msg := range memstore.Messages(ctx) {
+		roles = append(roles, string(msg.Role))
+	}
+	assert.Equal(t, "human,ai,human,ai", strings.Join(roles, ","))
+
+	// native mode fails for the provider
+	_, err = ag.Call(ctx, &assistants.CallInput{
+		Input:   "What is the weather in Seattle?",
+		Options: []assistants.Option{assistants.WithToolCallingMode(assistants.ToolCallingNative)},
+	})
+	assert.EqualError(t, err, "assistant Generic Assistant: the CLOUDFLARE provider does not support function calling")
+}
@@ -0,0 +1,144 @@
+package assistants
+
+import (
+	"context"
+	"time"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// RecoveryAction is the decision of the RecoveryStrategy.
+type RecoveryAction int
+
+const (
+	// RecoveryDefault applies the default policy:
+	// retry until the limit is exceeded, then abort the run.
+	RecoveryDefault RecoveryAction = iota
+	// RecoveryContinue continues the run, even if the limit is exceeded.
+	RecoveryContinue
+	// RecoveryRewrite adds Recovery.Prompt to the message history, and continues the run.
+	RecoveryRewrite
+	// RecoveryAbort aborts the run with the error.
+	RecoveryAbort
+)
+
+// MaxParseErrorRetries is the limit of the run retries on the parse error.
+// The limit applies regardless of the RecoveryStrategy decision,
+// RecoveryContinue and RecoveryRewrite do not extend it.
+var MaxParseErrorRetries = 5
+
+// RecoveryCallback is an optional interface of the Callback,
+// to receive the cancelled tool calls, the retries and the rate limit delays.
+type RecoveryCallback interface {
+	// OnToolCancelled is called when the tool call is aborted,
+	// because the caller cancelled the context or a sibling tool failed with chatmodel.ErrToolFatal.
+	OnToolCancelled(ctx context.Context, e *ToolCancelledEvent)
+	// OnRetry is called before the LLM call, the run or the tool call is retried, see RetryReason.
+	OnRetry(ctx context.Context, e *RetryEvent)
+	// OnRateLimit is called when the LLM call is delayed by the rate limit,
+	// the client side limiter or the provider backoff, see llms.WithRateLimitFunc.
+	OnRateLimit(ctx context.Context, e *RateLimitEvent)
+}
+
+// ToolCancelledEvent is reported when the tool call is aborted.
+type ToolCancelledEvent struct {
+	Assistant IAssistant
+	Tool      string
+	Input     string
+	// Partial is the output returned by the tool before the cancellation, if any.
+	Partial string
+	// CallID is the ID of the tool call requested by the LLM.
+	CallID string
+	// Cause is the cause of the cancellation.
+	Cause error
+}
+
+// RetryEvent is reported before the retry.
+type RetryEvent struct {
+	Assistant IAssistant
+	Reason    RetryReason
+	// Attempt is the number of the failed attempts, starting from 1.
+	Attempt int
+	// Err is the error of the failed attempt, if any.
+	Err error
+	// Tool and CallID are the tool call retried with RetryReasonToolTransient.
+	Tool   string
+	CallID string
+}
+
+// RateLimitEvent is reported when the LLM call is delayed by the rate limit.
+type RateLimitEvent struct {
+	Assistant IAssistant
+	LLM       llms.Model
+	// Attempt is the number of the delayed attempts, starting from 1.
+	Attempt int
+	Wait    time.Duration
+}
+
+// MessageOrderRepairCallback is an optional interface of the Callback,
+// to receive the warnings when the provider repairs the order of the messages,
+// see llms.WithMessageOrderRepairFunc.
+type MessageOrderRepairCallback interface {
+	// OnMessageOrderRepair is called when the provider merged the adjacent messages with the same role,
+	// or inserted the placeholder user message, instead of failing the request.
+	OnMessageOrderRepair(ctx context.Context, e *MessageOrderRepairEvent)
+}
+
+// MessageOrderRepairEvent is reported when the provider repaired the order of the messages.
+type MessageOrderRepairEvent struct {
+	Assistant IAssistant
+	LLM       llms.Model
+	Repair    llms.MessageOrderRepair
+}
+
+// RecoveryEvent describes the failure of the run.
+type RecoveryEvent struct {
+	// Reason is the failure reason.
+	Reason RetryReason
+	// Attempt is the number of the failures with the same reason, starting from 1.
+	Attempt int
+	// Limit is the configured limit, see WithMaxEmptyRetries, WithMaxNotFoundTools, WithMaxToolCalls
+	// and MaxParseErrorRetries.
+	Limit int
+	// Exceeded is true, when the default policy aborts the run.
+	Exceeded bool
+	// Err is the parse error, if any.
+	Err error
+	// Messages is the message history of the run.
+	Messages llms.Messages
+}
+
+// Recovery is the decision of the RecoveryStrategy.
+type Recovery struct {
+	Action RecoveryAction
+	// Prompt is the human message for RecoveryRewrite.
+	// For RetryReasonParseError, it replaces the input of the retried run.
+	Prompt string
+}
+
+// RecoveryStrategy decides whether to continue, rewrite the prompt, or abort the run,
+// when the LLM returns the empty response, calls the tools that are not found,
+// exceeds the tool calls limit, or the response fails to parse.
+// Note that RecoveryContinue and RecoveryRewrite ignore the limits,
+// the strategy is responsible to abort the run eventually,
+// except the parse error retries that are limited by MaxParseErrorRetries.
+type RecoveryStrategy func(ctx context.Context, a IAssistant, event *RecoveryEvent) Recovery
+
+// recoverFrom returns the decision of the strategy, or the default one.
+func (c *Config) recoverFrom(ctx context.Context, a IAssistant, event *RecoveryEvent) Recovery {
+	if c.RecoveryStrategy == nil {
+		return Recovery{}
+	}
+	return c.RecoveryStrategy(ctx, a, event)
+}
+
+// Aborts returns true, if the run must be aborted with the decision.
+func (r Recovery) Aborts(event *RecoveryEvent) bool {
+	switch r.Action {
+	case RecoveryAbort:
+		return true
+	case RecoveryDefault:
+		return event.Exceeded
+	}
+	return false
+}
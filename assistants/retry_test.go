@@ -0,0 +1,333 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type retryEvent struct {
+	reason  assistants.RetryReason
+	attempt int
+	err     bool
+}
+
+type rateLimitEvent struct {
+	model   string
+	attempt int
+	wait    time.Duration
+}
+
+type retryRecorder struct {
+	callbacks.Noop
+	retries    []retryEvent
+	rateLimits []rateLimitEvent
+}
+
+func (r *retryRecorder) OnRetry(_ context.Context, e *assistants.RetryEvent) {
+	r.retries = append(r.retries, retryEvent{reason: e.Reason, attempt: e.Attempt, err: e.Err != nil})
+}
+
+func (r *retryRecorder) OnRateLimit(_ context.Context, e *assistants.RateLimitEvent) {
+	r.rateLimits = append(r.rateLimits, rateLimitEvent{model: e.LLM.GetName(), attempt: e.Attempt, wait: e.Wait})
+}
+
+func Test_Assistant_Retries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(3)
+	gomock.InOrder(
+		// empty response
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&llms.ContentResponse{}, nil),
+		// rate limited, and failed to parse
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, _ []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+				var opts llms.CallOptions
+				for _, opt := range options {
+					opt(&opts)
+				}
+				require.NotNil(t, opts.RateLimitFunc)
+				opts.RateLimitFunc(ctx, 1, time.Second)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "not a JSON"}}}, nil
+			}),
+		// the run is retried
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				last := messages[len(messages)-1]
+				assert.Equal(t, llms.RoleHuman, last.Role)
+				assert.Equal(t, "Return the response in JSON format as requested.", last.Parts[0].(llms.TextContent).Text)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"content":"Fixed"}`}}}, nil
+			}),
+	)
+
+	cb := &retryRecorder{}
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithCallback(cb),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var output chatmodel.OutputResult
+	resp, err := ag.Run(ctx, &assistants.CallInput{Input: "hello"}, &output)
+	require.NoError(t, err)
+	assert.Equal(t, "Fixed", output.Content)
+	assert.Equal(t, uint32(1), resp.Usage.LlmCallCount)
+
+	assert.Equal(t, []retryEvent{
+		{reason: assistants.RetryReasonEmptyResponse, attempt: 1},
+		{reason: assistants.RetryReasonParseError, attempt: 1, err: true},
+	}, cb.retries)
+	assert.Equal(t, []rateLimitEvent{{model: "gpt-4o", attempt: 1, wait: time.Second}}, cb.rateLimits)
+}
+
+func Test_Assistant_MaxEmptyRetries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&llms.ContentResponse{}, nil).Times(1)
+
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMaxEmptyRetries(1),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var output chatmodel.OutputResult
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "hello"}, &output)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "LLM returned empty response after 1 retries")
+}
+
+func Test_Assistant_ZeroLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		option assistants.Option
+		resp   *llms.ContentResponse
+		err    string
+	}{
+		{
+			name:   "empty retries",
+			option: assistants.WithMaxEmptyRetries(0),
+			resp:   &llms.ContentResponse{},
+			err:    "LLM returned empty response",
+		},
+		{
+			name:   "not found tools",
+			option: assistants.WithMaxNotFoundTools(0),
+			resp: &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "missing", Arguments: "{}"}}},
+			}}},
+			err: "the number of not found tools is exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockLLM := mockllms.NewMockModel(ctrl)
+			mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+			mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+			mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.resp, nil).Times(1)
+
+			ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+				prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+				tt.option,
+			)
+
+			ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+			var output chatmodel.OutputResult
+			_, err := ag.Run(ctx, &assistants.CallInput{Input: "hello"}, &output)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func Test_Assistant_ParseErrorRetriesLimit(t *testing.T) {
+	tcases := []struct {
+		name   string
+		action assistants.RecoveryAction
+	}{
+		{name: "default", action: assistants.RecoveryDefault},
+		// the limit applies also when the strategy continues the run
+		{name: "continue", action: assistants.RecoveryContinue},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockLLM := mockllms.NewMockModel(ctrl)
+			mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+			mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+			mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(&llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "not a JSON"}}}, nil).
+				Times(assistants.MaxParseErrorRetries + 1)
+
+			var events []assistants.RecoveryEvent
+			ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+				prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+				assistants.WithRecoveryStrategy(func(_ context.Context, _ assistants.IAssistant, e *assistants.RecoveryEvent) assistants.Recovery {
+					events = append(events, *e)
+					return assistants.Recovery{Action: tc.action}
+				}),
+			)
+
+			ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+			var output chatmodel.OutputResult
+			_, err := ag.Run(ctx, &assistants.CallInput{Input: "hello"}, &output)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalOutput)
+
+			// the limit is exceeded by the last failure only
+			require.Len(t, events, assistants.MaxParseErrorRetries+1)
+			for i, e := range events {
+				assert.Equal(t, assistants.RetryReasonParseError, e.Reason)
+				assert.Equal(t, i+1, e.Attempt)
+				assert.Equal(t, assistants.MaxParseErrorRetries, e.Limit)
+				assert.Equal(t, i == assistants.MaxParseErrorRetries, e.Exceeded, "attempt %d", e.Attempt)
+			}
+		})
+	}
+}
+
+type priorityOutput struct {
+	Priority string `json:"priority" jsonschema:"enum=low,enum=high"`
+}
+
+func (o priorityOutput) GetContent() string {
+	return o.Priority
+}
+
+func Test_Assistant_OutputSchemaValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"priority":"urgent"}`}}}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				last := messages[len(messages)-1]
+				assert.Equal(t, llms.RoleHuman, last.Role)
+				assert.Contains(t, last.Parts[0].(llms.TextContent).Text, `- /priority: value must be one of ["low","high"]`)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: `{"priority":"high"}`}}}, nil
+			}),
+	)
+
+	ag := assistants.NewAssistant[priorityOutput](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithOutputSchemaValidation(true),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var output priorityOutput
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "hello"}, &output)
+	require.NoError(t, err)
+	assert.Equal(t, "high", output.Priority)
+}
+
+func Test_Assistant_TableRepair(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "| Region |\n|---|\n| EMEA |"}}}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				last := messages[len(messages)-1]
+				assert.Equal(t, llms.RoleHuman, last.Role)
+				assert.Contains(t, last.Parts[0].(llms.TextContent).Text, `- column "Revenue" is missing`)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "| Region | Revenue |\n|---|---|\n| EMEA | 10 |"}}}, nil
+			}),
+	)
+
+	ag := assistants.NewAssistant[encoding.Table](mockLLM,
+		prompts.NewPromptTemplate("You are a reporting assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+	).WithOutputParser(encoding.NewTableOutputParser(encoding.TableMarkdown, "Region", "Revenue"))
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var output encoding.Table
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "revenue by region"}, &output)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"EMEA", "10"}}, output.Rows)
+}
+
+func Test_Assistant_RecoveryStrategy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(&llms.ContentResponse{}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				last := messages[len(messages)-1]
+				assert.Equal(t, llms.RoleHuman, last.Role)
+				assert.Equal(t, "Please answer.", last.Parts[0].(llms.TextContent).Text)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "not a JSON"}}}, nil
+			}),
+	)
+
+	var events []assistants.RecoveryEvent
+	ag := assistants.NewAssistant[chatmodel.OutputResult](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMaxEmptyRetries(1),
+		assistants.WithRecoveryStrategy(func(_ context.Context, _ assistants.IAssistant, event *assistants.RecoveryEvent) assistants.Recovery {
+			events = append(events, *event)
+			if event.Reason == assistants.RetryReasonEmptyResponse {
+				return assistants.Recovery{Action: assistants.RecoveryRewrite, Prompt: "Please answer."}
+			}
+			return assistants.Recovery{Action: assistants.RecoveryAbort}
+		}),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	var output chatmodel.OutputResult
+	_, err := ag.Run(ctx, &assistants.CallInput{Input: "hello"}, &output)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalOutput)
+
+	require.Len(t, events, 2)
+	assert.Equal(t, assistants.RetryReasonEmptyResponse, events[0].Reason)
+	assert.Equal(t, 1, events[0].Attempt)
+	assert.Equal(t, 1, events[0].Limit)
+	assert.True(t, events[0].Exceeded)
+	assert.NotEmpty(t, events[0].Messages)
+	assert.Equal(t, assistants.RetryReasonParseError, events[1].Reason)
+	assert.False(t, events[1].Exceeded)
+	assert.Error(t, events[1].Err)
+}
@@ -0,0 +1,71 @@
+package assistants_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_RunState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	report := strings.Repeat("row,", 1000)
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{
+					{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "report_tool", Arguments: "{}"}},
+				},
+			}},
+		}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				tr := messages[len(messages)-1].Parts[0].(llms.ToolCallResponse)
+				assert.Equal(t, "The report is stored as [[state:report]]", tr.Content)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "Report:\n[[state:report]]"}}}, nil
+			}),
+	)
+
+	reportTool := newCancelTool(ctrl, "report_tool", func(ctx context.Context, _ string) (string, error) {
+		state := chatmodel.GetRunState(ctx)
+		require.NotNil(t, state)
+		return "The report is stored as " + state.Set("report", report), nil
+	})
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+	).WithTools(reportTool)
+
+	state := chatmodel.NewRunState()
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	ctx = chatmodel.WithRunState(ctx, state)
+
+	var output chatmodel.String
+	resp, err := ag.Run(ctx, &assistants.CallInput{Input: "build the report"}, &output)
+	require.NoError(t, err)
+
+	// the final answer has the resolved value
+	assert.Equal(t, "Report:\n"+report, output.GetContent())
+	assert.Equal(t, "Report:\n"+report, resp.Choices[0].Content)
+
+	// the message history keeps the reference
+	last := resp.Messages[len(resp.Messages)-1]
+	assert.Equal(t, "Report:\n[[state:report]]", last.Parts[0].(llms.TextContent).Text)
+	assert.Equal(t, []string{"report"}, state.Keys())
+}
@@ -0,0 +1,597 @@
+package assistants
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/xlog"
+)
+
+// DefaultRunsPollInterval is the default interval of the status polling of the asynchronous runs,
+// used by Runs.Wait and to check the cancellation requested by another process.
+const DefaultRunsPollInterval = 500 * time.Millisecond
+
+const (
+	// DefaultWebhookTimeout is the default timeout of the webhook request.
+	DefaultWebhookTimeout = 10 * time.Second
+	// DefaultWebhookRetries is the default number of the retries of the failed webhook.
+	DefaultWebhookRetries = 3
+)
+
+// webhookBackoff is the wait before the first retry of the webhook, doubled for the next retries.
+const webhookBackoff = 500 * time.Millisecond
+
+var (
+	// ErrRunNotFound is returned when the asynchronous run is not found, or expired.
+	ErrRunNotFound = errors.New("run not found")
+	// ErrRunCancelled is the cause of the context of the cancelled asynchronous run.
+	ErrRunCancelled = errors.New("run cancelled")
+)
+
+// RunStatus is the status of the asynchronous run.
+type RunStatus string
+
+const (
+	// RunQueued is the status of the run waiting for a worker.
+	RunQueued RunStatus = "queued"
+	// RunRunning is the status of the run executed by a worker.
+	RunRunning RunStatus = "running"
+	// RunSucceeded is the status of the run completed with the response.
+	RunSucceeded RunStatus = "succeeded"
+	// RunFailed is the status of the run completed with the error.
+	RunFailed RunStatus = "failed"
+	// RunCancelled is the status of the run cancelled with Runs.Cancel.
+	RunCancelled RunStatus = "cancelled"
+)
+
+// Done returns true if the run is completed.
+func (s RunStatus) Done() bool {
+	return s == RunSucceeded || s == RunFailed || s == RunCancelled
+}
+
+// RunInput is the part of CallInput kept in the queue with the asynchronous run.
+type RunInput struct {
+	Input          string            `json:"input"`
+	PromptInputs   map[string]any    `json:"prompt_inputs,omitempty"`
+	Messages       []llms.Message    `json:"messages,omitempty"`
+	Attachments    []*Attachment     `json:"attachments,omitempty"`
+	Args           map[string]string `json:"args,omitempty"`
+	IdempotencyKey string            `json:"idempotency_key,omitempty"`
+}
+
+func (in *RunInput) callInput() *CallInput {
+	return &CallInput{
+		Input:          in.Input,
+		PromptInputs:   in.PromptInputs,
+		Messages:       in.Messages,
+		Attachments:    in.Attachments,
+		Args:           in.Args,
+		IdempotencyKey: in.IdempotencyKey,
+	}
+}
+
+// AsyncRun is the asynchronous run of the assistant, see Runs.
+type AsyncRun struct {
+	// ID is the ID of the run, it is also the RunID of the ChatContext of the run.
+	ID string `json:"id"`
+	// Assistant is the name of the assistant registered in Runs.
+	Assistant string    `json:"assistant"`
+	Status    RunStatus `json:"status"`
+	Input     *RunInput `json:"input"`
+
+	// The ChatContext of the submitter, the AppData and the metadata are not kept.
+	TenantID string `json:"tenant_id"`
+	ChatID   string `json:"chat_id"`
+	OrgID    string `json:"org_id,omitempty"`
+	UserID   string `json:"user_id,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+
+	// WebhookURL is called with POST of the run, when the run is completed, see WithWebhook.
+	WebhookURL string `json:"webhook_url,omitempty"`
+
+	// Response is the response of the succeeded run.
+	Response *Response `json:"response,omitempty"`
+	// Error is the error of the failed or cancelled run.
+	Error string `json:"error,omitempty"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+}
+
+// chatContext returns the context with the ChatContext of the submitter, and the ID of the run.
+func (r *AsyncRun) chatContext(ctx context.Context) context.Context {
+	chatCtx := chatmodel.NewChatContext(r.TenantID, r.ChatID, nil)
+	chatCtx.SetRunID(r.ID)
+	if r.OrgID != "" {
+		chatCtx.SetOrgID(r.OrgID)
+	}
+	chatCtx.SetUserID(r.UserID)
+	chatCtx.SetLocale(r.Locale)
+	chatCtx.SetTimezone(r.Timezone)
+	return chatmodel.WithChatContext(ctx, chatCtx)
+}
+
+// RunQueue is the queue and the state of the asynchronous runs,
+// see NewMemoryRunQueue and NewRedisRunQueue.
+// The implementation must be safe for the concurrent use.
+type RunQueue interface {
+	// Enqueue saves the run, and adds it to the queue.
+	Enqueue(ctx context.Context, run *AsyncRun) error
+	// Dequeue returns the next run from the queue, waiting for the run until the ctx is done.
+	// The run is kept by the queue until Ack, the queue may return the run again,
+	// when the worker executing it does not call Extend, for example when the process crashed.
+	Dequeue(ctx context.Context) (*AsyncRun, error)
+	// Ack removes the dequeued run, when it is completed.
+	Ack(ctx context.Context, id string) error
+	// Extend extends the lease of the dequeued run, while it is executed.
+	Extend(ctx context.Context, id string) error
+	// Get returns the run by ID, or ErrRunNotFound.
+	Get(ctx context.Context, id string) (*AsyncRun, error)
+	// Update saves the run.
+	Update(ctx context.Context, run *AsyncRun) error
+	// RequestCancel marks the run to be cancelled by the worker executing it.
+	RequestCancel(ctx context.Context, id string) error
+	// CancelRequested returns true if the cancellation of the run is requested.
+	CancelRequested(ctx context.Context, id string) (bool, error)
+}
+
+// SubmitOption configures the submitted run.
+type SubmitOption func(*AsyncRun)
+
+// WithWebhook is the option to POST the run as JSON to the url, when the run is completed.
+// The failed request is retried with the backoff, see WithRunsWebhook,
+// and the receiver should dedupe by the run ID.
+// The delivery is not persisted, the webhook is lost when all the attempts failed,
+// or the process stopped, the caller should poll the status with Runs.Get in this case.
+func WithWebhook(url string) SubmitOption {
+	return func(r *AsyncRun) {
+		r.WebhookURL = url
+	}
+}
+
+// RunsOption configures Runs.
+type RunsOption func(*Runs)
+
+// WithRunsPollInterval sets the interval of the status polling, see DefaultRunsPollInterval.
+func WithRunsPollInterval(interval time.Duration) RunsOption {
+	return func(r *Runs) {
+		r.pollInterval = interval
+	}
+}
+
+// WithRunsHTTPClient sets the HTTP client of the webhooks.
+func WithRunsHTTPClient(client *http.Client) RunsOption {
+	return func(r *Runs) {
+		r.httpClient = client
+	}
+}
+
+// WithRunsWebhook sets the timeout of each webhook request, and the number of the retries
+// of the failed request, see DefaultWebhookTimeout and DefaultWebhookRetries.
+// The request is retried on the network error, the timeout, and the status codes 429 and 5xx.
+func WithRunsWebhook(timeout time.Duration, retries int) RunsOption {
+	return func(r *Runs) {
+		r.webhookTimeout = timeout
+		r.webhookRetries = retries
+	}
+}
+
+// WithRuns is the option to submit the asynchronous runs of the assistant to the Runs manager,
+// see Assistant.Submit.
+func WithRuns(runs *Runs) Option {
+	return func(o *Config) {
+		o.Runs = runs
+	}
+}
+
+// Runs manages the asynchronous runs of the assistants, for the long tasks triggered from the HTTP handlers.
+// The runs are submitted to the queue, and executed by the workers started with Start,
+// in this or another process sharing the queue, with the same assistants registered by name.
+// The caller polls the status with Get or Wait, cancels the run with Cancel,
+// or receives the webhook when the run is completed.
+// The run is executed at least once with the queue recovering the runs of the crashed workers,
+// such as NewRedisRunQueue, the idempotent tools should use CallInput.IdempotencyKey.
+type Runs struct {
+	queue          RunQueue
+	pollInterval   time.Duration
+	httpClient     *http.Client
+	webhookTimeout time.Duration
+	webhookRetries int
+
+	lock       sync.Mutex
+	assistants map[string]IAssistant
+	// active are the cancel functions of the runs executed by the workers of this manager
+	active map[string]context.CancelCauseFunc
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewRuns returns the Runs manager with the queue,
+// use Register to add the assistants, and Start to execute the runs.
+func NewRuns(queue RunQueue, opts ...RunsOption) *Runs {
+	ctx, stop := context.WithCancel(context.Background())
+	r := &Runs{
+		queue:          queue,
+		pollInterval:   DefaultRunsPollInterval,
+		httpClient:     http.DefaultClient,
+		webhookTimeout: DefaultWebhookTimeout,
+		webhookRetries: DefaultWebhookRetries,
+		assistants:     make(map[string]IAssistant),
+		active:         make(map[string]context.CancelCauseFunc),
+		ctx:            ctx,
+		stop:           stop,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds the assistants by name, the assistant with the same name is replaced.
+func (r *Runs) Register(list ...IAssistant) *Runs {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, a := range list {
+		r.assistants[a.Name()] = a
+	}
+	return r
+}
+
+func (r *Runs) assistant(name string) IAssistant {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.assistants[name]
+}
+
+// Submit adds the run of the registered assistant to the queue, and returns the ID of the run.
+// The ctx must have ChatContext, the run is executed in the same chat.
+// The Options and OnProgress of the input can not be queued, and are rejected.
+func (r *Runs) Submit(ctx context.Context, assistant string, input *CallInput, opts ...SubmitOption) (string, error) {
+	if r.assistant(assistant) == nil {
+		return "", errors.Newf("assistant %s is not registered", assistant)
+	}
+	if len(input.Options) > 0 || input.OnProgress != nil {
+		return "", errors.Newf("assistant %s: options and progress callback are not supported by the asynchronous run", assistant)
+	}
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return "", err
+	}
+	chatCtx := chatmodel.GetChatContext(ctx)
+
+	run := &AsyncRun{
+		ID:        chatmodel.NewChatID(),
+		Assistant: assistant,
+		Status:    RunQueued,
+		Input: &RunInput{
+			Input:          input.Input,
+			PromptInputs:   input.PromptInputs,
+			Messages:       input.Messages,
+			Attachments:    input.Attachments,
+			Args:           input.Args,
+			IdempotencyKey: input.IdempotencyKey,
+		},
+		TenantID:  tenantID,
+		ChatID:    chatID,
+		OrgID:     chatCtx.GetOrgID(),
+		UserID:    chatCtx.GetUserID(),
+		Locale:    chatCtx.GetLocale(),
+		Timezone:  chatCtx.GetTimezone(),
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, opt := range opts {
+		opt(run)
+	}
+	if err := r.queue.Enqueue(ctx, run); err != nil {
+		return "", errors.WithMessagef(err, "assistant %s: failed to submit run", assistant)
+	}
+
+	logger.ContextKV(ctx, xlog.DEBUG,
+		"assistant", assistant,
+		"status", "run_submitted",
+		"run_id", run.ID,
+	)
+	return run.ID, nil
+}
+
+// Get returns the run by ID, or ErrRunNotFound.
+func (r *Runs) Get(ctx context.Context, id string) (*AsyncRun, error) {
+	return r.queue.Get(ctx, id)
+}
+
+// Wait returns the run, when it is completed, or the ctx error.
+func (r *Runs) Wait(ctx context.Context, id string) (*AsyncRun, error) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	for {
+		run, err := r.queue.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if run.Status.Done() {
+			return run, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.WithStack(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel cancels the run, the queued run is not executed,
+// and the running run is cancelled by the worker, in this or another process.
+// The completed run is not changed.
+func (r *Runs) Cancel(ctx context.Context, id string) error {
+	run, err := r.queue.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if run.Status.Done() {
+		return nil
+	}
+	if err := r.queue.RequestCancel(ctx, id); err != nil {
+		return errors.WithMessagef(err, "run %s: failed to cancel", id)
+	}
+
+	r.lock.Lock()
+	cancel, active := r.active[id]
+	r.lock.Unlock()
+	if active {
+		cancel(ErrRunCancelled)
+		return nil
+	}
+	if run.Status == RunQueued {
+		r.finish(ctx, run, nil, ErrRunCancelled)
+	}
+	return nil
+}
+
+// Start starts the workers executing the runs from the queue, until Close is called.
+func (r *Runs) Start(workers int) {
+	for range max(1, workers) {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			for {
+				run, err := r.queue.Dequeue(r.ctx)
+				if r.ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					logger.KV(xlog.ERROR,
+						"status", "runs_dequeue_failed",
+						"err", err.Error(),
+					)
+					// the queue backend is not available
+					select {
+					case <-r.ctx.Done():
+						return
+					case <-time.After(r.pollInterval):
+					}
+					continue
+				}
+				r.execute(run)
+			}
+		}()
+	}
+}
+
+// Close stops the workers, the runs being executed are cancelled and fail.
+func (r *Runs) Close() error {
+	r.stop()
+	r.wg.Wait()
+	return nil
+}
+
+// execute runs the assistant, and saves the result.
+// The run in RunRunning status is the run of the crashed worker returned to the queue.
+func (r *Runs) execute(run *AsyncRun) {
+	ctx := run.chatContext(r.ctx)
+	if run.Status.Done() {
+		// the cancelled run
+		r.ack(ctx, run)
+		return
+	}
+	if cancelled, err := r.queue.CancelRequested(ctx, run.ID); err == nil && cancelled {
+		r.finish(ctx, run, nil, ErrRunCancelled)
+		return
+	}
+
+	a := r.assistant(run.Assistant)
+	if a == nil {
+		r.finish(ctx, run, nil, errors.Newf("assistant %s is not registered", run.Assistant))
+		return
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	r.lock.Lock()
+	r.active[run.ID] = cancel
+	r.lock.Unlock()
+	defer func() {
+		r.lock.Lock()
+		delete(r.active, run.ID)
+		r.lock.Unlock()
+	}()
+
+	run.Status = RunRunning
+	run.StartedAt = time.Now().UTC()
+	if err := r.queue.Update(ctx, run); err != nil {
+		logger.ContextKV(ctx, xlog.WARNING,
+			"assistant", run.Assistant,
+			"status", "run_update_failed",
+			"run_id", run.ID,
+			"err", err.Error(),
+		)
+	}
+
+	// the cancellation can be requested by another process,
+	// and the lease of the run is extended while it is executed
+	go func() {
+		ticker := time.NewTicker(r.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				if cancelled, err := r.queue.CancelRequested(runCtx, run.ID); err == nil && cancelled {
+					cancel(ErrRunCancelled)
+					return
+				}
+				if err := r.queue.Extend(runCtx, run.ID); err != nil && runCtx.Err() == nil {
+					logger.ContextKV(ctx, xlog.WARNING,
+						"assistant", run.Assistant,
+						"status", "run_extend_failed",
+						"run_id", run.ID,
+						"err", err.Error(),
+					)
+				}
+			}
+		}
+	}()
+
+	resp, err := a.Call(runCtx, run.Input.callInput())
+	if err != nil && errors.Is(context.Cause(runCtx), ErrRunCancelled) {
+		err = ErrRunCancelled
+	}
+	r.finish(ctx, run, resp, err)
+}
+
+// finish saves the completed run, and calls the webhook.
+func (r *Runs) finish(ctx context.Context, run *AsyncRun, resp *Response, err error) {
+	ctx = context.WithoutCancel(ctx)
+	run.FinishedAt = time.Now().UTC()
+	switch {
+	case errors.Is(err, ErrRunCancelled):
+		run.Status = RunCancelled
+		run.Error = err.Error()
+	case err != nil:
+		run.Status = RunFailed
+		run.Error = err.Error()
+	default:
+		run.Status = RunSucceeded
+		run.Response = resp
+	}
+
+	logger.ContextKV(ctx, xlog.DEBUG,
+		"assistant", run.Assistant,
+		"status", "run_"+string(run.Status),
+		"run_id", run.ID,
+	)
+	if uerr := r.queue.Update(ctx, run); uerr != nil {
+		logger.ContextKV(ctx, xlog.ERROR,
+			"assistant", run.Assistant,
+			"status", "run_update_failed",
+			"run_id", run.ID,
+			"err", uerr.Error(),
+		)
+	} else {
+		r.ack(ctx, run)
+	}
+	if run.WebhookURL != "" {
+		if werr := r.notify(ctx, run); werr != nil {
+			logger.ContextKV(ctx, xlog.WARNING,
+				"assistant", run.Assistant,
+				"status", "run_webhook_failed",
+				"run_id", run.ID,
+				"err", werr.Error(),
+			)
+		}
+	}
+}
+
+// ack removes the completed run from the queue.
+func (r *Runs) ack(ctx context.Context, run *AsyncRun) {
+	if err := r.queue.Ack(ctx, run.ID); err != nil {
+		logger.ContextKV(ctx, xlog.WARNING,
+			"assistant", run.Assistant,
+			"status", "run_ack_failed",
+			"run_id", run.ID,
+			"err", err.Error(),
+		)
+	}
+}
+
+// notify posts the run to the webhook, and retries the failed request with the backoff,
+// until the manager is closed.
+func (r *Runs) notify(ctx context.Context, run *AsyncRun) error {
+	body, err := json.Marshal(run)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	wait := webhookBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := r.post(ctx, run.WebhookURL, body)
+		if err == nil || !retry || attempt >= r.webhookRetries {
+			return err
+		}
+		logger.ContextKV(ctx, xlog.DEBUG,
+			"assistant", run.Assistant,
+			"status", "run_webhook_retry",
+			"run_id", run.ID,
+			"attempt", attempt+1,
+			"err", err.Error(),
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+			return errors.WithMessage(err, "runs manager is closed")
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
+
+// post sends the webhook request with the timeout,
+// and returns true if the failed request should be retried.
+func (r *Runs) post(ctx context.Context, url string, body []byte) (bool, error) {
+	if r.webhookTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.webhookTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := r.httpClient.Do(req)
+	if err != nil {
+		return true, errors.WithStack(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+		return retry, errors.Newf("webhook returned unexpected status code: %d", res.StatusCode)
+	}
+	return false, nil
+}
+
+// Submit submits the asynchronous run of the assistant to the Runs manager configured with WithRuns,
+// and returns the ID of the run, see Runs.Submit.
+// The assistant is registered in the manager, to be executed by its workers.
+func (a *Assistant[O]) Submit(ctx context.Context, input *CallInput, opts ...SubmitOption) (string, error) {
+	runs := a.GetCallConfig().Runs
+	if runs == nil {
+		return "", errors.Newf("assistant %s: runs manager is not configured", a.Name())
+	}
+	if runs.assistant(a.Name()) == nil {
+		runs.Register(a)
+	}
+	return runs.Submit(ctx, a.Name(), input, opts...)
+}
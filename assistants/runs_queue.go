@@ -0,0 +1,334 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/xlog"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRunsRetention is the default time the completed runs are kept in the queue.
+const DefaultRunsRetention = 24 * time.Hour
+
+// NewMemoryRunQueue returns in-memory RunQueue, for the workers in the same process.
+// The completed runs are removed after the retention, if zero DefaultRunsRetention is used.
+func NewMemoryRunQueue(retention time.Duration) RunQueue {
+	if retention <= 0 {
+		retention = DefaultRunsRetention
+	}
+	return &memoryRunQueue{
+		retention: retention,
+		runs:      make(map[string]*AsyncRun),
+		cancels:   make(map[string]bool),
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+type memoryRunQueue struct {
+	retention time.Duration
+
+	lock    sync.Mutex
+	runs    map[string]*AsyncRun
+	cancels map[string]bool
+	pending []string
+	// notify wakes up a worker waiting in Dequeue
+	notify chan struct{}
+}
+
+func (q *memoryRunQueue) Enqueue(_ context.Context, run *AsyncRun) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.expire()
+	cp := *run
+	q.runs[run.ID] = &cp
+	q.pending = append(q.pending, run.ID)
+	q.wakeup()
+	return nil
+}
+
+func (q *memoryRunQueue) Dequeue(ctx context.Context) (*AsyncRun, error) {
+	for {
+		q.lock.Lock()
+		for len(q.pending) > 0 {
+			id := q.pending[0]
+			q.pending = q.pending[1:]
+			if run, ok := q.runs[id]; ok {
+				if len(q.pending) > 0 {
+					q.wakeup()
+				}
+				cp := *run
+				q.lock.Unlock()
+				return &cp, nil
+			}
+		}
+		q.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.WithStack(ctx.Err())
+		case <-q.notify:
+		}
+	}
+}
+
+func (q *memoryRunQueue) Get(_ context.Context, id string) (*AsyncRun, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	run, ok := q.runs[id]
+	if !ok {
+		return nil, errors.WithMessagef(ErrRunNotFound, "run %s", id)
+	}
+	cp := *run
+	return &cp, nil
+}
+
+func (q *memoryRunQueue) Update(_ context.Context, run *AsyncRun) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	cp := *run
+	q.runs[run.ID] = &cp
+	return nil
+}
+
+// Ack is no-op, the runs of the in-memory queue are not recovered.
+func (q *memoryRunQueue) Ack(_ context.Context, _ string) error {
+	return nil
+}
+
+// Extend is no-op, the runs of the in-memory queue are not recovered.
+func (q *memoryRunQueue) Extend(_ context.Context, _ string) error {
+	return nil
+}
+
+func (q *memoryRunQueue) RequestCancel(_ context.Context, id string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.cancels[id] = true
+	return nil
+}
+
+func (q *memoryRunQueue) CancelRequested(_ context.Context, id string) (bool, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.cancels[id], nil
+}
+
+// wakeup notifies a waiting worker, the caller must hold the lock.
+func (q *memoryRunQueue) wakeup() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// expire removes the completed runs after the retention, the caller must hold the lock.
+func (q *memoryRunQueue) expire() {
+	deadline := time.Now().Add(-q.retention)
+	for id, run := range q.runs {
+		if run.Status.Done() && run.FinishedAt.Before(deadline) {
+			delete(q.runs, id)
+			delete(q.cancels, id)
+		}
+	}
+}
+
+// redisDequeueTimeout is the timeout of the blocking move,
+// to check the cancellation of the context and to reclaim the expired runs.
+const redisDequeueTimeout = time.Second
+
+// redisRunLease is the lease of the dequeued run, extended by the worker executing it.
+const redisRunLease = time.Minute
+
+// reclaimRunsScript returns the runs of the processing list KEYS[1] with the lease in KEYS[2]
+// expired before ARGV[1] to the head of the queue KEYS[3], and returns the number of the reclaimed runs.
+// The run without the lease, just moved by another worker, gets the lease until ARGV[2].
+var reclaimRunsScript = redis.NewScript(`
+local n = 0
+for _, id in ipairs(redis.call('LRANGE', KEYS[1], 0, -1)) do
+	local deadline = redis.call('ZSCORE', KEYS[2], id)
+	if not deadline then
+		redis.call('ZADD', KEYS[2], ARGV[2], id)
+	elseif tonumber(deadline) < tonumber(ARGV[1]) then
+		redis.call('LREM', KEYS[1], 1, id)
+		redis.call('ZREM', KEYS[2], id)
+		redis.call('RPUSH', KEYS[3], id)
+		n = n + 1
+	end
+end
+return n
+`)
+
+// NewRedisRunQueue returns RunQueue backed by Redis, for the workers in multiple processes.
+// The completed runs expire after the retention, if zero DefaultRunsRetention is used.
+// The dequeued run is moved to the processing list with the lease of one minute,
+// extended by the worker executing it, and removed when the run is completed.
+// The run of the crashed worker is returned to the queue, when its lease is expired,
+// and executed again by another worker, so the run is executed at least once.
+// The leases use the clock of the workers, which must be synchronized.
+// The keys namespace is organized as follows:
+// - `/<prefix>/runs/queue` for the list of the queued run IDs
+// - `/<prefix>/runs/processing` for the list of the dequeued run IDs
+// - `/<prefix>/runs/leases` for the sorted set of the dequeued run IDs by the lease deadline
+// - `/<prefix>/runs/run/<runID>` for the run
+// - `/<prefix>/runs/cancel/<runID>` for the cancellation request of the run
+func NewRedisRunQueue(client *redis.Client, prefix string, retention time.Duration) RunQueue {
+	if retention <= 0 {
+		retention = DefaultRunsRetention
+	}
+	return &redisRunQueue{
+		client:    client,
+		prefix:    prefix,
+		retention: retention,
+	}
+}
+
+type redisRunQueue struct {
+	client    *redis.Client
+	prefix    string
+	retention time.Duration
+}
+
+func (q *redisRunQueue) queueKey() string {
+	return path.Join(q.prefix, "runs", "queue")
+}
+
+func (q *redisRunQueue) processingKey() string {
+	return path.Join(q.prefix, "runs", "processing")
+}
+
+func (q *redisRunQueue) leasesKey() string {
+	return path.Join(q.prefix, "runs", "leases")
+}
+
+func (q *redisRunQueue) runKey(id string) string {
+	return path.Join(q.prefix, "runs", "run", id)
+}
+
+func (q *redisRunQueue) cancelKey(id string) string {
+	return path.Join(q.prefix, "runs", "cancel", id)
+}
+
+func (q *redisRunQueue) Enqueue(ctx context.Context, run *AsyncRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, q.runKey(run.ID), data, 0)
+		pipe.LPush(ctx, q.queueKey(), run.ID)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+func (q *redisRunQueue) Dequeue(ctx context.Context) (*AsyncRun, error) {
+	for {
+		if err := q.reclaim(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.WithStack(ctx.Err())
+			}
+			return nil, err
+		}
+		id, err := q.client.BLMove(ctx, q.queueKey(), q.processingKey(), "RIGHT", "LEFT", redisDequeueTimeout).Result()
+		if ctx.Err() != nil {
+			return nil, errors.WithStack(ctx.Err())
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		deadline := time.Now().Add(redisRunLease).UnixMilli()
+		if err := q.client.ZAdd(ctx, q.leasesKey(), redis.Z{Score: float64(deadline), Member: id}).Err(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		run, err := q.Get(ctx, id)
+		if errors.Is(err, ErrRunNotFound) {
+			// the run expired in the queue
+			if err := q.Ack(ctx, id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return run, err
+	}
+}
+
+// reclaim returns the dequeued runs with the expired lease to the queue.
+func (q *redisRunQueue) reclaim(ctx context.Context) error {
+	now := time.Now()
+	n, err := reclaimRunsScript.Run(ctx, q.client,
+		[]string{q.processingKey(), q.leasesKey(), q.queueKey()},
+		now.UnixMilli(), now.Add(redisRunLease).UnixMilli(),
+	).Int()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if n > 0 {
+		logger.ContextKV(ctx, xlog.WARNING,
+			"status", "runs_reclaimed",
+			"count", n,
+		)
+	}
+	return nil
+}
+
+func (q *redisRunQueue) Ack(ctx context.Context, id string) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, q.processingKey(), 1, id)
+		pipe.ZRem(ctx, q.leasesKey(), id)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+func (q *redisRunQueue) Extend(ctx context.Context, id string) error {
+	// the lease of the acknowledged run is not added
+	deadline := time.Now().Add(redisRunLease).UnixMilli()
+	return errors.WithStack(q.client.ZAddXX(ctx, q.leasesKey(), redis.Z{Score: float64(deadline), Member: id}).Err())
+}
+
+func (q *redisRunQueue) Get(ctx context.Context, id string) (*AsyncRun, error) {
+	data, err := q.client.Get(ctx, q.runKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errors.WithMessagef(ErrRunNotFound, "run %s", id)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	run := new(AsyncRun)
+	if err := json.Unmarshal(data, run); err != nil {
+		return nil, errors.Wrapf(err, "run %s: failed to decode", id)
+	}
+	return run, nil
+}
+
+func (q *redisRunQueue) Update(ctx context.Context, run *AsyncRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var ttl time.Duration
+	if run.Status.Done() {
+		ttl = q.retention
+	}
+	return errors.WithStack(q.client.Set(ctx, q.runKey(run.ID), data, ttl).Err())
+}
+
+func (q *redisRunQueue) RequestCancel(ctx context.Context, id string) error {
+	return errors.WithStack(q.client.Set(ctx, q.cancelKey(id), "1", q.retention).Err())
+}
+
+func (q *redisRunQueue) CancelRequested(ctx context.Context, id string) (bool, error) {
+	n, err := q.client.Exists(ctx, q.cancelKey(id)).Result()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,298 @@
+package assistants_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Runs(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(6)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+			input := messages[len(messages)-1].Parts[0].(llms.TextContent).Text
+			if input == "slow" {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			chatCtx := chatmodel.GetChatContext(ctx)
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+				Content: "report for " + chatCtx.GetTenantID() + "/" + chatCtx.GetUserID(),
+			}}}, nil
+		}).Times(3)
+
+	webhooks := make(chan *assistants.AsyncRun, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		run := new(assistants.AsyncRun)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(run))
+		webhooks <- run
+	}))
+	defer srv.Close()
+
+	runs := assistants.NewRuns(assistants.NewMemoryRunQueue(0), assistants.WithRunsPollInterval(10*time.Millisecond))
+	defer func() { _ = runs.Close() }()
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithRuns(runs),
+	)
+
+	chatCtx := chatmodel.NewChatContext("tenant1", "chat1", nil)
+	chatCtx.SetUserID("user1")
+	ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+
+	// the queued run is cancelled before the workers are started
+	id, err := ag.Submit(ctx, &assistants.CallInput{Input: "never"})
+	require.NoError(t, err)
+	run, err := runs.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, assistants.RunQueued, run.Status)
+	require.NoError(t, runs.Cancel(ctx, id))
+	run, err = runs.Wait(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, assistants.RunCancelled, run.Status)
+	assert.Equal(t, "run cancelled", run.Error)
+
+	runs.Start(2)
+
+	t.Run("succeeded", func(t *testing.T) {
+		id, err := ag.Submit(ctx, &assistants.CallInput{Input: "Research the topic."}, assistants.WithWebhook(srv.URL))
+		require.NoError(t, err)
+
+		run, err := runs.Wait(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, assistants.RunSucceeded, run.Status)
+		assert.Equal(t, ag.Name(), run.Assistant)
+		require.NotNil(t, run.Response)
+		assert.Equal(t, "report for tenant1/user1", run.Response.Choices[0].Content)
+		assert.False(t, run.StartedAt.IsZero())
+		assert.False(t, run.FinishedAt.Before(run.StartedAt))
+
+		select {
+		case hook := <-webhooks:
+			assert.Equal(t, id, hook.ID)
+			assert.Equal(t, assistants.RunSucceeded, hook.Status)
+			assert.Equal(t, "report for tenant1/user1", hook.Response.Choices[0].Content)
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook is not called")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		id, err := ag.Submit(ctx, &assistants.CallInput{Input: "slow"})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			run, err := runs.Get(ctx, id)
+			return err == nil && run.Status == assistants.RunRunning
+		}, 5*time.Second, 10*time.Millisecond)
+
+		require.NoError(t, runs.Cancel(ctx, id))
+		run, err := runs.Wait(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, assistants.RunCancelled, run.Status)
+		assert.Nil(t, run.Response)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := runs.Get(ctx, "unknown")
+		assert.True(t, errors.Is(err, assistants.ErrRunNotFound))
+
+		_, err = runs.Submit(ctx, "unknown", &assistants.CallInput{Input: "hi"})
+		assert.EqualError(t, err, "assistant unknown is not registered")
+
+		_, err = runs.Submit(ctx, ag.Name(), &assistants.CallInput{Input: "hi", Options: []assistants.Option{assistants.WithMaxToolCalls(1)}})
+		assert.EqualError(t, err, "assistant "+ag.Name()+": options and progress callback are not supported by the asynchronous run")
+
+		_, err = runs.Submit(context.Background(), ag.Name(), &assistants.CallInput{Input: "hi"})
+		assert.Error(t, err)
+
+		noRuns := assistants.NewAssistant[chatmodel.String](mockLLM, prompts.NewPromptTemplate("You are helpful.", nil))
+		_, err = noRuns.Submit(ctx, &assistants.CallInput{Input: "hi"})
+		assert.EqualError(t, err, "assistant "+noRuns.Name()+": runs manager is not configured")
+
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		id, err := ag.Submit(ctx, &assistants.CallInput{Input: "slow"})
+		require.NoError(t, err)
+		_, err = runs.Wait(waitCtx, id)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		require.NoError(t, runs.Cancel(ctx, id))
+	})
+}
+
+func Test_RunsQueue_Memory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	q := assistants.NewMemoryRunQueue(time.Millisecond)
+
+	require.NoError(t, q.Enqueue(ctx, &assistants.AsyncRun{ID: "1", Status: assistants.RunQueued}))
+	require.NoError(t, q.Enqueue(ctx, &assistants.AsyncRun{ID: "2", Status: assistants.RunQueued}))
+
+	run, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "1", run.ID)
+
+	// the completed run expires after the retention
+	run.Status = assistants.RunSucceeded
+	run.FinishedAt = time.Now()
+	require.NoError(t, q.Update(ctx, run))
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, q.Enqueue(ctx, &assistants.AsyncRun{ID: "3", Status: assistants.RunQueued}))
+	_, err = q.Get(ctx, "1")
+	assert.EqualError(t, err, "run 1: run not found")
+
+	run, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "2", run.ID)
+
+	cancelled, err := q.CancelRequested(ctx, "3")
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+	require.NoError(t, q.RequestCancel(ctx, "3"))
+	cancelled, err = q.CancelRequested(ctx, "3")
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+
+	run, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "3", run.ID)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = q.Dequeue(waitCtx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func Test_Runs_Webhook(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(3)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(6)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "report"}}}, nil).
+		Times(3)
+
+	chatCtx := chatmodel.NewChatContext("tenant1", "chat1", nil)
+	ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+
+	newRuns := func(t *testing.T, queue assistants.RunQueue, opts ...assistants.RunsOption) (*assistants.Runs, assistants.IAssistant) {
+		runs := assistants.NewRuns(queue, append(opts, assistants.WithRunsPollInterval(10*time.Millisecond))...)
+		t.Cleanup(func() { _ = runs.Close() })
+		ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+			prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+			assistants.WithMode(encoding.ModePlainText),
+		)
+		runs.Register(ag)
+		return runs, ag
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		var calls atomic.Int32
+		delivered := make(chan string, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			run := new(assistants.AsyncRun)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(run))
+			delivered <- run.ID
+		}))
+		defer srv.Close()
+
+		runs, ag := newRuns(t, assistants.NewMemoryRunQueue(0))
+		runs.Start(1)
+		id, err := runs.Submit(ctx, ag.Name(), &assistants.CallInput{Input: "Research the topic."}, assistants.WithWebhook(srv.URL))
+		require.NoError(t, err)
+
+		select {
+		case hook := <-delivered:
+			assert.Equal(t, id, hook)
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook is not retried")
+		}
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		var calls atomic.Int32
+		hung := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			select {
+			case <-r.Context().Done():
+			case <-hung:
+			}
+		}))
+		defer srv.Close()
+		defer close(hung)
+
+		runs, ag := newRuns(t, assistants.NewMemoryRunQueue(0), assistants.WithRunsWebhook(50*time.Millisecond, 0))
+		runs.Start(1)
+		id, err := runs.Submit(ctx, ag.Name(), &assistants.CallInput{Input: "Research the topic."}, assistants.WithWebhook(srv.URL))
+		require.NoError(t, err)
+		run, err := runs.Wait(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, assistants.RunSucceeded, run.Status)
+
+		// the worker is not blocked by the hung receiver
+		done := make(chan struct{})
+		go func() {
+			_ = runs.Close()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook blocks the worker")
+		}
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("reclaimed", func(t *testing.T) {
+		// the run of the crashed worker is returned to the queue in the running status
+		queue := assistants.NewMemoryRunQueue(0)
+		runs, ag := newRuns(t, queue)
+		require.NoError(t, queue.Enqueue(ctx, &assistants.AsyncRun{
+			ID:        "reclaimed",
+			Assistant: ag.Name(),
+			Status:    assistants.RunRunning,
+			Input:     &assistants.RunInput{Input: "Research the topic."},
+			TenantID:  "tenant1",
+			ChatID:    "chat1",
+		}))
+		runs.Start(1)
+
+		require.Eventually(t, func() bool {
+			run, err := runs.Get(ctx, "reclaimed")
+			return err == nil && run.Status.Done()
+		}, 5*time.Second, 10*time.Millisecond)
+		run, err := runs.Get(ctx, "reclaimed")
+		require.NoError(t, err)
+		assert.Equal(t, assistants.RunSucceeded, run.Status)
+	})
+}
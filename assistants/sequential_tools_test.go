@@ -0,0 +1,91 @@
+package assistants_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_SequentialToolCalls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var parallel []*bool
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			opts := llms.CallOptions{}
+			for _, opt := range options {
+				opt(&opts)
+			}
+			parallel = append(parallel, opts.ParallelToolCalls)
+			if len(parallel) == 1 {
+				return &llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{
+						ToolCalls: []llms.ToolCall{
+							{ID: "call_1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "slow_tool", Arguments: `{}`}},
+							{ID: "call_2", Type: "function", FunctionCall: &llms.FunctionCall{Name: "fast_tool", Arguments: `{}`}},
+							{ID: "call_3", Type: "function", FunctionCall: &llms.FunctionCall{Name: "fast_tool2", Arguments: `{}`}},
+						},
+					}},
+				}, nil
+			}
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "done"}}}, nil
+		}).Times(2)
+
+	var lock sync.Mutex
+	var order []string
+	var running, maxRunning atomic.Int32
+	call := func(name string, delay time.Duration) func(context.Context, string) (string, error) {
+		return func(_ context.Context, _ string) (string, error) {
+			if n := running.Add(1); n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+			defer running.Add(-1)
+			time.Sleep(delay)
+			lock.Lock()
+			order = append(order, name)
+			lock.Unlock()
+			return name, nil
+		}
+	}
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithParallelToolCalls(false),
+		assistants.WithSequentialToolCalls(true),
+	).WithTools(
+		newCancelTool(ctrl, "slow_tool", call("slow_tool", 50*time.Millisecond)),
+		newCancelTool(ctrl, "fast_tool", call("fast_tool", 0)),
+		newCancelTool(ctrl, "fast_tool2", call("fast_tool2", 0)),
+	)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.Choices[0].Content)
+
+	assert.Equal(t, []string{"slow_tool", "fast_tool", "fast_tool2"}, order)
+	assert.EqualValues(t, 1, maxRunning.Load())
+
+	require.Len(t, parallel, 2)
+	for _, p := range parallel {
+		require.NotNil(t, p)
+		assert.False(t, *p)
+	}
+}
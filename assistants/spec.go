@@ -0,0 +1,208 @@
+package assistants
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/x/configloader"
+)
+
+// DefaultSpecOutput is the output of the Spec, when not set, the plain text.
+const DefaultSpecOutput = "string"
+
+// Spec is the declarative definition of the assistant, loaded from YAML or JSON with LoadSpec:
+//
+//	name: researcher
+//	description: Researches the topics on the web.
+//	models: [gpt-4o, claude-sonnet-4]
+//	prompt: |
+//	  You are a research assistant. Today is {{.today}}.
+//	tools: [tavily, calculator]
+//	output: string
+//	limits:
+//	  max_tool_calls: 10
+//	  max_tokens: 2000
+type Spec struct {
+	// Name is the name of the assistant.
+	Name string `json:"name" yaml:"name"`
+	// Description is the description of the assistant, for the other assistants.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Models are the preferred models, see llmfactory.Factory.AssistantModel.
+	Models []string `json:"models,omitempty" yaml:"models,omitempty"`
+	// Prompt is the system prompt template.
+	Prompt string `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	// PromptFile is the file of the system prompt template, relative to the spec file,
+	// used when Prompt is not set.
+	PromptFile string `json:"prompt_file,omitempty" yaml:"prompt_file,omitempty"`
+	// Tools are the names of the tools of the assistant.
+	Tools []string `json:"tools,omitempty" yaml:"tools,omitempty"`
+	// Output is the name of the output type registered with RegisterOutput,
+	// DefaultSpecOutput if not set.
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+	// Mode is the encoding mode of the output, see WithMode.
+	// The plain text for the string output, and JSON for the others, if not set.
+	Mode encoding.Mode `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// Temperature is the temperature for sampling, the model default if not set.
+	Temperature *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	// Limits are the limits of the run.
+	Limits SpecLimits `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
+
+// SpecLimits are the limits of the run, zero values are the defaults.
+type SpecLimits struct {
+	// MaxTokens is the maximum number of tokens to generate, see WithMaxTokens.
+	MaxTokens int `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	// MaxToolCalls is the maximum number of the tool calls per run, see WithMaxToolCalls.
+	MaxToolCalls int `json:"max_tool_calls,omitempty" yaml:"max_tool_calls,omitempty"`
+	// MaxMessages is the maximum number of messages per run, see WithMaxMessages.
+	MaxMessages int `json:"max_messages,omitempty" yaml:"max_messages,omitempty"`
+	// MaxToolRetries is the maximum number of the retries of the failed tool call, see WithMaxToolRetries.
+	MaxToolRetries int `json:"max_tool_retries,omitempty" yaml:"max_tool_retries,omitempty"`
+}
+
+// LoadSpec loads the spec of the assistant from the YAML or JSON file,
+// the environment variables in the values are expanded.
+func LoadSpec(file string) (*Spec, error) {
+	spec := new(Spec)
+	err := configloader.UnmarshalAndExpand(file, spec)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Prompt == "" && spec.PromptFile != "" {
+		path := spec.PromptFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		prompt, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to read the prompt file")
+		}
+		spec.Prompt = string(prompt)
+	}
+	if err = spec.Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "invalid spec %s", file)
+	}
+	return spec, nil
+}
+
+// Validate returns error if the spec is incomplete or references an unknown output.
+func (s *Spec) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	if s.Prompt == "" {
+		return errors.New("prompt is required")
+	}
+	if _, err := outputFactory(s.Output); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Options returns the options of the assistant from the spec.
+func (s *Spec) Options() []Option {
+	var opts []Option
+	switch {
+	case s.Mode != "":
+		opts = append(opts, WithMode(s.Mode))
+	case s.Output == "" || s.Output == DefaultSpecOutput:
+		opts = append(opts, WithMode(encoding.ModePlainText))
+	}
+	if s.Temperature != nil {
+		opts = append(opts, WithTemperature(*s.Temperature))
+	}
+	if s.Limits.MaxTokens > 0 {
+		opts = append(opts, WithMaxTokens(s.Limits.MaxTokens))
+	}
+	if s.Limits.MaxToolCalls > 0 {
+		opts = append(opts, WithMaxToolCalls(s.Limits.MaxToolCalls))
+	}
+	if s.Limits.MaxMessages > 0 {
+		opts = append(opts, WithMaxMessages(s.Limits.MaxMessages))
+	}
+	if s.Limits.MaxToolRetries > 0 {
+		opts = append(opts, WithMaxToolRetries(s.Limits.MaxToolRetries))
+	}
+	return opts
+}
+
+// NewFromSpec creates the assistant from the spec, the tools are looked up by name in the toolset.
+// The options are applied after the options of the spec.
+func NewFromSpec(spec *Spec, llm llms.Model, toolset map[string]tools.ITool, opts ...Option) (IAssistant, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	factory, _ := outputFactory(spec.Output)
+
+	list := make([]tools.ITool, 0, len(spec.Tools))
+	for _, name := range spec.Tools {
+		tool, ok := toolset[name]
+		if !ok {
+			return nil, errors.Errorf("tool %q not found", name)
+		}
+		list = append(list, tool)
+	}
+
+	return factory(spec, llm, list, append(spec.Options(), opts...)), nil
+}
+
+type specFactory func(spec *Spec, llm llms.Model, list []tools.ITool, opts []Option) IAssistant
+
+var (
+	outputs = map[string]specFactory{
+		DefaultSpecOutput: newSpecAssistant[chatmodel.String],
+		"output_result":   newSpecAssistant[chatmodel.OutputResult],
+	}
+	outputsMu sync.RWMutex
+)
+
+// RegisterOutput registers the output type by name, to be referenced in Spec.Output.
+// The built-in outputs are "string", the plain text, and "output_result", chatmodel.OutputResult.
+func RegisterOutput[O chatmodel.ContentProvider](name string) {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+	outputs[name] = newSpecAssistant[O]
+}
+
+// Outputs returns the names of the registered output types, sorted.
+func Outputs() []string {
+	outputsMu.RLock()
+	defer outputsMu.RUnlock()
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func outputFactory(name string) (specFactory, error) {
+	if name == "" {
+		name = DefaultSpecOutput
+	}
+	outputsMu.RLock()
+	defer outputsMu.RUnlock()
+	factory, ok := outputs[name]
+	if !ok {
+		return nil, errors.Errorf("output %q is not registered", name)
+	}
+	return factory, nil
+}
+
+func newSpecAssistant[O chatmodel.ContentProvider](spec *Spec, llm llms.Model, list []tools.ITool, opts []Option) IAssistant {
+	ast := NewAssistant[O](llm, prompts.NewPromptTemplate(spec.Prompt, nil), opts...).
+		WithName(spec.Name).
+		WithTools(list...)
+	if spec.Description != "" {
+		ast.WithDescription(spec.Description)
+	}
+	return ast
+}
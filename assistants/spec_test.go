@@ -0,0 +1,85 @@
+package assistants_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/gogentic/tools/calc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLoadSpec(t *testing.T) {
+	t.Parallel()
+
+	spec, err := assistants.LoadSpec("testdata/researcher.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "researcher", spec.Name)
+	assert.Equal(t, []string{"gpt-4o", "claude-sonnet-4"}, spec.Models)
+	assert.Equal(t, "You are a research assistant. Today is {{.today}}.\n", spec.Prompt)
+	assert.Equal(t, []string{"calculator"}, spec.Tools)
+
+	cfg := assistants.NewConfig(spec.Options()...)
+	assert.Equal(t, encoding.ModePlainText, cfg.Mode)
+	assert.Equal(t, 0.2, cfg.Temperature)
+	assert.Equal(t, 2000, cfg.MaxTokens)
+	assert.Equal(t, 10, cfg.MaxToolCalls)
+	assert.Equal(t, 40, cfg.MaxMessages)
+	assert.Equal(t, 1, cfg.MaxToolRetries)
+
+	ctrl := gomock.NewController(t)
+	llm := mockllms.NewMockModel(ctrl)
+	llm.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+
+	calculator := calc.New()
+	ast, err := assistants.NewFromSpec(spec, llm, map[string]tools.ITool{"calculator": calculator})
+	require.NoError(t, err)
+	assert.Equal(t, "researcher", ast.Name())
+	assert.Equal(t, "Researches the topics on the web.", ast.Description())
+	assert.Equal(t, []tools.ITool{calculator}, ast.GetTools())
+
+	prompt, err := ast.FormatPrompt(map[string]any{"today": "Monday"})
+	require.NoError(t, err)
+	assert.Contains(t, prompt.String(), "Today is Monday.")
+
+	_, err = assistants.NewFromSpec(spec, llm, nil)
+	assert.EqualError(t, err, `tool "calculator" not found`)
+}
+
+func TestLoadSpec_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := assistants.LoadSpec("testdata/invalid_spec.yaml")
+	assert.EqualError(t, err, `invalid spec testdata/invalid_spec.yaml: output "report" is not registered`)
+
+	_, err = assistants.LoadSpec("testdata/missing.yaml")
+	assert.Error(t, err)
+
+	tcases := []struct {
+		spec *assistants.Spec
+		exp  string
+	}{
+		{&assistants.Spec{Prompt: "You are helpful."}, "name is required"},
+		{&assistants.Spec{Name: "helper"}, "prompt is required"},
+		{&assistants.Spec{Name: "helper", Prompt: "You are helpful.", Output: "output_result"}, ""},
+	}
+	for _, tc := range tcases {
+		err := tc.spec.Validate()
+		if tc.exp == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tc.exp)
+		}
+	}
+	assert.Contains(t, assistants.Outputs(), assistants.DefaultSpecOutput)
+
+	assistants.RegisterOutput[chatmodel.OutputResult]("spec_test_result")
+	assert.Contains(t, assistants.Outputs(), "spec_test_result")
+	assert.NoError(t, (&assistants.Spec{Name: "helper", Prompt: "You are helpful.", Output: "spec_test_result"}).Validate())
+}
@@ -0,0 +1,126 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/gogentic/tools/artifact"
+	"github.com/effective-security/x/slices"
+	"github.com/effective-security/xlog"
+)
+
+// DefaultSpilloverPreviewSize is the size of the content preview in bytes,
+// included in the artifact reference when no summarizer is configured.
+const DefaultSpilloverPreviewSize = 512
+
+// SummarizeFunc returns a short summary of the content,
+// that is included in the artifact reference.
+type SummarizeFunc func(ctx context.Context, content string) (string, error)
+
+// WithSpillover is an option to store the tool outputs and LLM responses
+// larger than threshold bytes in the blob store.
+// The content in the message history is replaced by the artifact reference
+// and the summary, and the fetch_artifact tool is provided to the LLM to read the full content.
+// The full LLM response is still returned to the caller in Response.Choices.
+func WithSpillover(blobs store.BlobStore, threshold int) Option {
+	return func(o *Config) {
+		o.BlobStore = blobs
+		o.SpilloverThreshold = threshold
+	}
+}
+
+// WithSpilloverSummarizer is an option to provide the summary of the spilled content,
+// by default the beginning of the content is used as a preview.
+func WithSpilloverSummarizer(fn SummarizeFunc) Option {
+	return func(o *Config) {
+		o.SpilloverSummarizer = fn
+	}
+}
+
+func (cfg *Config) spilloverEnabled() bool {
+	return cfg.BlobStore != nil && cfg.SpilloverThreshold > 0
+}
+
+// fetchArtifactTool returns the fetch_artifact tool, if the spillover is enabled.
+func (cfg *Config) fetchArtifactTool() tools.ITool {
+	if !cfg.spilloverEnabled() {
+		return nil
+	}
+	return artifact.NewFetchTool(cfg.BlobStore).WithLimit(cfg.SpilloverThreshold)
+}
+
+// fetchArtifactToolDef returns the LLM definition of the fetch_artifact tool.
+func fetchArtifactToolDef(tool tools.ITool) llms.Tool {
+	return llms.Tool{
+		Type: "function",
+		Function: &llms.FunctionDefinition{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  tool.Parameters(),
+		},
+	}
+}
+
+// spill stores the content in the blob store if it exceeds the threshold,
+// and returns the artifact reference to be used in the message history.
+// On failure the original content is returned.
+func (cfg *Config) spill(ctx context.Context, source, content string) string {
+	if !cfg.spilloverEnabled() || len(content) <= cfg.SpilloverThreshold {
+		return content
+	}
+
+	contentType := "text/plain"
+	if json.Valid([]byte(content)) {
+		contentType = "application/json"
+	}
+
+	id, err := cfg.BlobStore.Put(ctx, contentType, []byte(content))
+	if err != nil {
+		logger.ContextKV(ctx, xlog.WARNING,
+			"reason", "spillover_failed",
+			"source", source,
+			"size", len(content),
+			"err", err.Error(),
+		)
+		return content
+	}
+
+	summary := slices.StringUpto(content, DefaultSpilloverPreviewSize)
+	if cfg.SpilloverSummarizer != nil {
+		s, err := cfg.SpilloverSummarizer(ctx, content)
+		if err != nil {
+			logger.ContextKV(ctx, xlog.WARNING,
+				"reason", "spillover_summary_failed",
+				"source", source,
+				"artifact_id", id,
+				"err", err.Error(),
+			)
+		} else {
+			summary = s
+		}
+	}
+
+	logger.ContextKV(ctx, xlog.DEBUG,
+		"status", "spillover",
+		"source", source,
+		"artifact_id", id,
+		"size", len(content),
+	)
+
+	return ArtifactReference(id, contentType, len(content), summary)
+}
+
+// ArtifactReference returns the text that replaces the spilled content in the message history.
+func ArtifactReference(id, contentType string, size int, summary string) string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "<!-- @artifact_id=%s @content_type=%s @size=%d -->\n", id, contentType, size)
+	_, _ = fmt.Fprintf(&b, "The content is too large and was stored as artifact `%s`. Use the `%s` tool with the artifact ID to read the full content.\n", id, artifact.FetchToolName)
+	b.WriteString("Summary:\n")
+	b.WriteString(strings.TrimSpace(summary))
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package assistants_test
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/gogentic/tools/artifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_Spillover(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	systemPrompt := prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", []string{})
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+
+	largeOutput := strings.Repeat("tool data ", 100)
+	largeAnswer := strings.Repeat("final answer ", 100)
+
+	mockTool := mocktools.NewMockTool[any, any](ctrl)
+	mockTool.EXPECT().Name().Return("big_tool").Times(1)
+	mockTool.EXPECT().Description().Return("desc").Times(1)
+	mockTool.EXPECT().Parameters().Return(nil).Times(1)
+	mockTool.EXPECT().Call(gomock.Any(), gomock.Any()).Return(largeOutput, nil).Times(1)
+
+	blobs := store.NewMemoryBlobStore()
+	memstore := store.NewMemoryStore()
+	chatCtx := chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil)
+	ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+
+	reID := regexp.MustCompile(`@artifact_id=(\S+)`)
+	var artifactID string
+	call := 0
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			call++
+			opts := llms.CallOptions{}
+			for _, opt := range options {
+				opt(&opts)
+			}
+			var toolNames []string
+			for _, tool := range opts.Tools {
+				toolNames = append(toolNames, tool.Function.Name)
+			}
+			assert.Contains(t, toolNames, artifact.FetchToolName)
+
+			last := messages[len(messages)-1]
+			switch call {
+			case 1:
+				return &llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{
+						ToolCalls: []llms.ToolCall{{
+							ID:           "call_1",
+							FunctionCall: &llms.FunctionCall{Name: "big_tool", Arguments: "{}"},
+						}},
+					}},
+				}, nil
+			case 2:
+				resp := last.Parts[0].(llms.ToolCallResponse)
+				assert.NotContains(t, resp.Content, largeOutput)
+				m := reID.FindStringSubmatch(resp.Content)
+				require.Len(t, m, 2)
+				artifactID = m[1]
+				return &llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{
+						ToolCalls: []llms.ToolCall{{
+							ID:           "call_2",
+							FunctionCall: &llms.FunctionCall{Name: artifact.FetchToolName, Arguments: `{"id":"` + artifactID + `"}`},
+						}},
+					}},
+				}, nil
+			default:
+				resp := last.Parts[0].(llms.ToolCallResponse)
+				var fetched artifact.FetchResponse
+				require.NoError(t, json.Unmarshal([]byte(resp.Content), &fetched))
+				// the fetched part is limited by the threshold
+				assert.Equal(t, largeOutput[:500], fetched.Content)
+				assert.Equal(t, 500, fetched.NextOffset)
+				assert.Equal(t, "text/plain", fetched.ContentType)
+				return &llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{Content: largeAnswer}},
+				}, nil
+			}
+		}).Times(3)
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM, systemPrompt,
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithMessageStore(memstore),
+		assistants.WithSpillover(blobs, 500),
+	).WithTools(mockTool)
+
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.NoError(t, err)
+	// the caller gets the full response
+	assert.Equal(t, largeAnswer, resp.Choices[0].Content)
+
+	// the stored answer is the reference
+	msgs := memstore.Messages(ctx)
+	require.NotEmpty(t, msgs)
+	answer := msgs[len(msgs)-1].Parts[0].(llms.TextContent).Text
+	assert.Contains(t, answer, "@artifact_id=")
+	assert.NotContains(t, answer, largeAnswer)
+
+	m := reID.FindStringSubmatch(answer)
+	require.Len(t, m, 2)
+	blob, err := blobs.Get(ctx, m[1])
+	require.NoError(t, err)
+	assert.Equal(t, largeAnswer, string(blob.Content))
+}
+
+func Test_ArtifactReference(t *testing.T) {
+	t.Parallel()
+	ref := assistants.ArtifactReference("artifact_1", "application/json", 1000, " summary \n")
+	assert.Equal(t, "<!-- @artifact_id=artifact_1 @content_type=application/json @size=1000 -->\n"+
+		"The content is too large and was stored as artifact `artifact_1`. Use the `fetch_artifact` tool with the artifact ID to read the full content.\n"+
+		"Summary:\nsummary", ref)
+}
@@ -0,0 +1,74 @@
+package assistants
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// StopState is the state of the run, passed to the StopCondition after each LLM round.
+type StopState struct {
+	// Round is the number of the LLM calls in the run, starting from 1.
+	Round int
+	// Started is the start time of the run.
+	Started time.Time
+	// Model is the name of the model.
+	Model string
+	// Response is the response of the run so far,
+	// the Choices are of the last LLM call, and the Usage is accumulated.
+	Response *Response
+	// ToolCalls is the number of the tool calls executed in the run so far.
+	ToolCalls int
+}
+
+// StopCondition is the predicate evaluated after each LLM round,
+// before the requested tool calls are executed.
+// It returns true to stop the run loop: the last LLM response is used as the final answer,
+// and the pending tool calls are not executed.
+type StopCondition func(ctx context.Context, state *StopState) bool
+
+// StopOnContent returns the StopCondition that stops the run,
+// when the content of the LLM response contains the marker, for example "FINAL_ANSWER".
+func StopOnContent(marker string) StopCondition {
+	return func(_ context.Context, state *StopState) bool {
+		for _, choice := range state.Response.Choices {
+			if choice != nil && strings.Contains(choice.Content, marker) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// StopOnCost returns the StopCondition that stops the run,
+// when the cost of the LLM calls in the run reaches maxCost.
+func StopOnCost(maxCost float64, costFunc CostFunc) StopCondition {
+	return func(_ context.Context, state *StopState) bool {
+		return costFunc(state.Model, &state.Response.Usage.Usage) >= maxCost
+	}
+}
+
+// StopAfter returns the StopCondition that stops the run,
+// when the wall-clock time of the run exceeds d.
+func StopAfter(d time.Duration) StopCondition {
+	return func(_ context.Context, state *StopState) bool {
+		return time.Since(state.Started) >= d
+	}
+}
+
+// StopAfterRounds returns the StopCondition that stops the run after n LLM calls.
+func StopAfterRounds(n int) StopCondition {
+	return func(_ context.Context, state *StopState) bool {
+		return state.Round >= n
+	}
+}
+
+// stopConditionMet returns the index of the first met stop condition, or -1
+func (c *Config) stopConditionMet(ctx context.Context, state *StopState) int {
+	for i, cond := range c.StopConditions {
+		if cond(ctx, state) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,95 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_StopConditions(t *testing.T) {
+	ctx := context.Background()
+	state := &assistants.StopState{
+		Round:   2,
+		Started: time.Now().Add(-time.Minute),
+		Model:   "gpt-4o",
+		Response: &assistants.Response{
+			Choices: []*llms.ContentChoice{{Content: "thinking... FINAL_ANSWER: 42"}},
+			Usage:   llms.UsageStats{Usage: llms.Usage{TotalTokens: 1000}},
+		},
+	}
+	costFunc := func(model string, usage *llms.Usage) float64 {
+		assert.Equal(t, "gpt-4o", model)
+		return float64(usage.TotalTokens) / 1000
+	}
+
+	assert.True(t, assistants.StopOnContent("FINAL_ANSWER")(ctx, state))
+	assert.False(t, assistants.StopOnContent("DONE")(ctx, state))
+	assert.True(t, assistants.StopOnCost(1, costFunc)(ctx, state))
+	assert.False(t, assistants.StopOnCost(1.5, costFunc)(ctx, state))
+	assert.True(t, assistants.StopAfter(time.Second)(ctx, state))
+	assert.False(t, assistants.StopAfter(time.Hour)(ctx, state))
+	assert.True(t, assistants.StopAfterRounds(2)(ctx, state))
+	assert.False(t, assistants.StopAfterRounds(3)(ctx, state))
+}
+
+func Test_Assistant_WithStopConditions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	toolCall := func(id, content string) *llms.ContentResponse {
+		return &llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				Content: content,
+				ToolCalls: []llms.ToolCall{
+					{ID: id, Type: "function", FunctionCall: &llms.FunctionCall{Name: "search_tool", Arguments: `{}`}},
+				},
+			}},
+		}
+	}
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(toolCall("call_1", ""), nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(toolCall("call_2", "FINAL_ANSWER: sunny"), nil),
+	)
+
+	calls := 0
+	tool := newCancelTool(ctrl, "search_tool", func(_ context.Context, _ string) (string, error) {
+		calls++
+		return "sunny", nil
+	})
+
+	var rounds []int
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithStopConditions(
+			func(_ context.Context, state *assistants.StopState) bool {
+				rounds = append(rounds, state.Round)
+				return false
+			},
+			assistants.StopOnContent("FINAL_ANSWER"),
+		),
+	).WithTools(tool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "weather?"})
+	require.NoError(t, err)
+	assert.Equal(t, "FINAL_ANSWER: sunny", resp.Choices[0].Content)
+	// the tool call of the last round is not executed
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []int{1, 2}, rounds)
+	assert.EqualValues(t, 2, resp.Usage.LlmCallCount)
+}
@@ -0,0 +1,205 @@
+package assistants
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/events"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// ToolCancelGracePeriod is the time to wait for the tool calls to return
+// after the cancellation, before they are reported as cancelled without the partial results.
+var ToolCancelGracePeriod = 500 * time.Millisecond
+
+// ToolCallStatus is the status of the tool call in the batch.
+type ToolCallStatus string
+
+const (
+	ToolCallPending   ToolCallStatus = "pending"
+	ToolCallRunning   ToolCallStatus = "running"
+	ToolCallSucceeded ToolCallStatus = "succeeded"
+	ToolCallFailed    ToolCallStatus = "failed"
+	ToolCallNotFound  ToolCallStatus = "not_found"
+	ToolCallCancelled ToolCallStatus = "cancelled"
+)
+
+// IsDone returns true if the status is final.
+func (s ToolCallStatus) IsDone() bool {
+	return s == ToolCallSucceeded || s == ToolCallFailed || s == ToolCallNotFound || s == ToolCallCancelled
+}
+
+// ToolBatchCall is the state of the tool call in the batch.
+type ToolBatchCall struct {
+	// ID is the tool call ID.
+	ID string
+	// Index is the position of the call in the LLM response.
+	Index  int
+	Name   string
+	Input  string
+	Status ToolCallStatus
+	Error  string
+	// Completed is the completion order of the call, starting from 1,
+	// zero if the call is not completed.
+	Completed int
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// ToolBatch is the snapshot of the tool calls requested by the LLM in one response,
+// and executed in parallel.
+type ToolBatch struct {
+	ID        string
+	Assistant string
+	Calls     []ToolBatchCall
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// Completed returns the number of the completed calls.
+func (b *ToolBatch) Completed() int {
+	n := 0
+	for _, c := range b.Calls {
+		if c.Status.IsDone() {
+			n++
+		}
+	}
+	return n
+}
+
+// ToolBatchCallback is an optional interface of the Callback,
+// to receive the status transitions of the tool calls executed in parallel,
+// grouped by the batch.
+// The events of the batch are delivered in order, and never concurrently,
+// the batch and the call are the snapshots and can be retained.
+type ToolBatchCallback interface {
+	OnToolBatchStart(ctx context.Context, e *ToolBatchEvent)
+	OnToolBatchUpdate(ctx context.Context, e *ToolBatchEvent)
+	OnToolBatchEnd(ctx context.Context, e *ToolBatchEvent)
+}
+
+// ToolBatchEvent is reported when the batch starts, ends, or the status of its call changes.
+type ToolBatchEvent struct {
+	Assistant IAssistant
+	Batch     *ToolBatch
+	// Call is the updated call, nil for OnToolBatchStart and OnToolBatchEnd.
+	Call *ToolBatchCall
+}
+
+// toolBatchTracker tracks the batch state, reports the transitions to the callback,
+// and publishes the completed calls to the event bus.
+type toolBatchTracker struct {
+	lock      sync.Mutex
+	ctx       context.Context
+	assistant IAssistant
+	cfg       *Config
+	// cb is nil if the callback does not implement ToolBatchCallback
+	cb        ToolBatchCallback
+	batch     ToolBatch
+	completed int
+}
+
+// newToolBatchTracker returns nil if the callback does not implement ToolBatchCallback,
+// and the event bus is not configured.
+func newToolBatchTracker(ctx context.Context, a IAssistant, cfg *Config, toolCalls []llms.ToolCall) *toolBatchTracker {
+	batchCb, _ := cfg.CallbackHandler.(ToolBatchCallback)
+	if batchCb == nil && cfg.EventBus == nil || len(toolCalls) == 0 {
+		return nil
+	}
+	t := &toolBatchTracker{
+		ctx:       ctx,
+		assistant: a,
+		cfg:       cfg,
+		cb:        batchCb,
+		batch: ToolBatch{
+			ID:        "batch_" + chatmodel.NewChatID(),
+			Assistant: a.Name(),
+			Calls:     make([]ToolBatchCall, len(toolCalls)),
+			StartedAt: time.Now(),
+		},
+	}
+	for i, tc := range toolCalls {
+		t.batch.Calls[i] = ToolBatchCall{
+			ID:     tc.ID,
+			Index:  i,
+			Name:   tc.GetFunctionCallName(),
+			Input:  tc.GetFunctionCallArguments(),
+			Status: ToolCallPending,
+		}
+	}
+	return t
+}
+
+func (t *toolBatchTracker) snapshot() *ToolBatch {
+	b := t.batch
+	b.Calls = slices.Clone(t.batch.Calls)
+	return &b
+}
+
+func (t *toolBatchTracker) start() {
+	if t == nil || t.cb == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.cb.OnToolBatchStart(t.ctx, &ToolBatchEvent{Assistant: t.assistant, Batch: t.snapshot()})
+}
+
+func (t *toolBatchTracker) update(index int, status ToolCallStatus, err error) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	call := &t.batch.Calls[index]
+	if call.Status.IsDone() || !t.batch.EndedAt.IsZero() {
+		// the late report of the call cancelled by the batch
+		return
+	}
+	call.Status = status
+	now := time.Now()
+	if status == ToolCallRunning {
+		call.StartedAt = now
+	}
+	if status.IsDone() {
+		t.completed++
+		call.Completed = t.completed
+		call.EndedAt = now
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	updated := *call
+	if t.cb != nil {
+		t.cb.OnToolBatchUpdate(t.ctx, &ToolBatchEvent{Assistant: t.assistant, Batch: t.snapshot(), Call: &updated})
+	}
+	if status.IsDone() {
+		event := &events.Event{
+			Type:       events.ToolCall,
+			Tool:       call.Name,
+			ToolCallID: call.ID,
+			Status:     string(status),
+			Error:      call.Error,
+		}
+		if !call.StartedAt.IsZero() {
+			event.Duration = call.EndedAt.Sub(call.StartedAt)
+		}
+		t.cfg.publish(t.ctx, t.assistant, event)
+	}
+}
+
+func (t *toolBatchTracker) end() {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.batch.EndedAt = time.Now()
+	if t.cb != nil {
+		t.cb.OnToolBatchEnd(t.ctx, &ToolBatchEvent{Assistant: t.assistant, Batch: t.snapshot()})
+	}
+}
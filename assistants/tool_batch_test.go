@@ -0,0 +1,124 @@
+package assistants_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type batchRecorder struct {
+	callbacks.Noop
+
+	lock    sync.Mutex
+	started []*assistants.ToolBatch
+	updates []assistants.ToolBatchCall
+	ended   []*assistants.ToolBatch
+}
+
+func (r *batchRecorder) OnToolBatchStart(_ context.Context, e *assistants.ToolBatchEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.started = append(r.started, e.Batch)
+}
+
+func (r *batchRecorder) OnToolBatchUpdate(_ context.Context, e *assistants.ToolBatchEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.updates = append(r.updates, *e.Call)
+}
+
+func (r *batchRecorder) OnToolBatchEnd(_ context.Context, e *assistants.ToolBatchEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.ended = append(r.ended, e.Batch)
+}
+
+func Test_Assistant_ToolBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	systemPrompt := prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", []string{})
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+
+	okTool := mocktools.NewMockTool[any, any](ctrl)
+	okTool.EXPECT().Name().Return("ok_tool").Times(1)
+	okTool.EXPECT().Description().Return("desc").Times(1)
+	okTool.EXPECT().Parameters().Return(nil).Times(1)
+	okTool.EXPECT().Call(gomock.Any(), gomock.Any()).Return("result", nil).Times(1)
+
+	badInputTool := mocktools.NewMockTool[any, any](ctrl)
+	badInputTool.EXPECT().Name().Return("bad_tool").Times(1)
+	badInputTool.EXPECT().Description().Return("desc").Times(1)
+	badInputTool.EXPECT().Parameters().Return(nil).Times(1)
+	badInputTool.EXPECT().Call(gomock.Any(), gomock.Any()).Return("", chatmodel.ErrFailedUnmarshalInput).Times(1)
+
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{
+					{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "ok_tool", Arguments: "{}"}},
+					{ID: "call_2", FunctionCall: &llms.FunctionCall{Name: "bad_tool", Arguments: "{"}},
+					{ID: "call_3", FunctionCall: &llms.FunctionCall{Name: "missing_tool", Arguments: "{}"}},
+				},
+			}},
+		}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "done"}},
+		}, nil),
+	)
+
+	rec := &batchRecorder{}
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM, systemPrompt,
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithCallback(rec),
+	).WithTools(okTool, badInputTool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.String())
+
+	require.Len(t, rec.started, 1)
+	require.Len(t, rec.ended, 1)
+	start := rec.started[0]
+	end := rec.ended[0]
+	assert.Equal(t, start.ID, end.ID)
+	assert.Equal(t, ag.Name(), start.Assistant)
+	require.Len(t, start.Calls, 3)
+	for _, call := range start.Calls {
+		assert.Equal(t, assistants.ToolCallPending, call.Status)
+	}
+
+	require.Len(t, end.Calls, 3)
+	assert.Equal(t, 3, end.Completed())
+	assert.Equal(t, "call_1", end.Calls[0].ID)
+	assert.Equal(t, assistants.ToolCallSucceeded, end.Calls[0].Status)
+	assert.Equal(t, assistants.ToolCallFailed, end.Calls[1].Status)
+	assert.Equal(t, chatmodel.ErrFailedUnmarshalInput.Error(), end.Calls[1].Error)
+	assert.Equal(t, assistants.ToolCallNotFound, end.Calls[2].Status)
+	assert.False(t, end.EndedAt.IsZero())
+
+	// each found call is running then done, the not found call is done
+	assert.Len(t, rec.updates, 5)
+	var orders []int
+	for _, u := range rec.updates {
+		if u.Status.IsDone() {
+			orders = append(orders, u.Completed)
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3}, orders)
+}
@@ -0,0 +1,75 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type deltaRecorder struct {
+	callbacks.Noop
+	deltas []llms.ToolCallDelta
+}
+
+func (r *deltaRecorder) OnToolCallDelta(_ context.Context, e *assistants.ToolCallDeltaEvent) {
+	r.deltas = append(r.deltas, e.Delta)
+}
+
+func Test_Assistant_OnToolCallDelta(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sent := llms.ToolCallDelta{Index: 0, ID: "call_1", Name: "search", ArgumentsDelta: `{"q`, Arguments: `{"q`}
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(4)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			opts := llms.CallOptions{}
+			for _, opt := range options {
+				opt(&opts)
+			}
+			if opts.StreamingToolCallFunc != nil {
+				require.NoError(t, opts.StreamingToolCallFunc(ctx, sent))
+			}
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "done"}}}, nil
+		}).Times(2)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+
+	t.Run("streaming", func(t *testing.T) {
+		rec := &deltaRecorder{}
+		ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+			prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+			assistants.WithMode(encoding.ModePlainText),
+			assistants.WithCallback(rec),
+			assistants.WithStreamingFunc(func(context.Context, []byte) error { return nil }),
+		)
+		_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		require.NoError(t, err)
+		assert.Equal(t, []llms.ToolCallDelta{sent}, rec.deltas)
+	})
+
+	t.Run("no_streaming", func(t *testing.T) {
+		rec := &deltaRecorder{}
+		ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+			prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+			assistants.WithMode(encoding.ModePlainText),
+			assistants.WithCallback(rec),
+		)
+		_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		require.NoError(t, err)
+		assert.Empty(t, rec.deltas)
+	})
+}
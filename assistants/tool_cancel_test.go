@@ -0,0 +1,238 @@
+package assistants_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type cancelledCall struct {
+	tool    string
+	input   string
+	partial string
+	cause   error
+}
+
+type cancelRecorder struct {
+	batchRecorder
+
+	cancelled []cancelledCall
+}
+
+func (r *cancelRecorder) OnToolCancelled(_ context.Context, e *assistants.ToolCancelledEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cancelled = append(r.cancelled, cancelledCall{tool: e.Tool, input: e.Input, partial: e.Partial, cause: e.Cause})
+}
+
+func newCancelTool(ctrl *gomock.Controller, name string, call func(ctx context.Context, input string) (string, error)) *mocktools.MockTool[any, any] {
+	tool := mocktools.NewMockTool[any, any](ctrl)
+	tool.EXPECT().Name().Return(name).Times(1)
+	tool.EXPECT().Description().Return("desc").Times(1)
+	tool.EXPECT().Parameters().Return(nil).Times(1)
+	tool.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(call).Times(1)
+	return tool
+}
+
+func Test_Assistant_ToolCancelled_Fatal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			ToolCalls: []llms.ToolCall{
+				{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "slow_tool", Arguments: `{"q":"slow"}`}},
+				{ID: "call_2", FunctionCall: &llms.FunctionCall{Name: "stuck_tool", Arguments: "{}"}},
+				{ID: "call_3", FunctionCall: &llms.FunctionCall{Name: "fatal_tool", Arguments: "{}"}},
+			},
+		}},
+	}, nil).Times(1)
+
+	slowStarted := make(chan struct{})
+	stuckStarted := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	slowTool := newCancelTool(ctrl, "slow_tool", func(ctx context.Context, _ string) (string, error) {
+		close(slowStarted)
+		<-ctx.Done()
+		return "partial", ctx.Err()
+	})
+	// stuck tool ignores the context
+	stuckTool := newCancelTool(ctrl, "stuck_tool", func(_ context.Context, _ string) (string, error) {
+		close(stuckStarted)
+		<-release
+		return "late", nil
+	})
+	fatalTool := newCancelTool(ctrl, "fatal_tool", func(_ context.Context, _ string) (string, error) {
+		<-slowStarted
+		<-stuckStarted
+		return "", errors.Mark(errors.New("database is down"), chatmodel.ErrToolFatal)
+	})
+
+	rec := &cancelRecorder{}
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithCallback(rec),
+	).WithTools(slowTool, stuckTool, fatalTool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, chatmodel.ErrToolFatal))
+	assert.Contains(t, err.Error(), "failed to call tool fatal_tool: database is down")
+
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+
+	require.Len(t, rec.cancelled, 2)
+	byTool := map[string]cancelledCall{}
+	for _, c := range rec.cancelled {
+		byTool[c.tool] = c
+		assert.True(t, errors.Is(c.cause, chatmodel.ErrToolFatal))
+	}
+	assert.Equal(t, `{"q":"slow"}`, byTool["slow_tool"].input)
+	assert.Equal(t, "partial", byTool["slow_tool"].partial)
+	assert.Empty(t, byTool["stuck_tool"].partial)
+
+	require.Len(t, rec.ended, 1)
+	end := rec.ended[0]
+	assert.Equal(t, 3, end.Completed())
+	assert.Equal(t, assistants.ToolCallCancelled, end.Calls[0].Status)
+	assert.Equal(t, assistants.ToolCallCancelled, end.Calls[1].Status)
+	assert.Equal(t, assistants.ToolCallFailed, end.Calls[2].Status)
+}
+
+func Test_Assistant_ToolCancelled_Caller(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			ToolCalls: []llms.ToolCall{
+				{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "ok_tool", Arguments: "{}"}},
+				{ID: "call_2", FunctionCall: &llms.FunctionCall{Name: "slow_tool", Arguments: "{}"}},
+			},
+		}},
+	}, nil).Times(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = chatmodel.WithChatContext(ctx, chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+
+	okDone := make(chan struct{})
+	okTool := newCancelTool(ctrl, "ok_tool", func(_ context.Context, _ string) (string, error) {
+		defer close(okDone)
+		return "result", nil
+	})
+	slowTool := newCancelTool(ctrl, "slow_tool", func(ctx context.Context, _ string) (string, error) {
+		<-okDone
+		cancel()
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	rec := &cancelRecorder{}
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithCallback(rec),
+	).WithTools(okTool, slowTool)
+
+	_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Contains(t, err.Error(), "tool calls are cancelled")
+
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	require.Len(t, rec.cancelled, 1)
+	assert.Equal(t, "slow_tool", rec.cancelled[0].tool)
+
+	require.Len(t, rec.ended, 1)
+	assert.Equal(t, assistants.ToolCallSucceeded, rec.ended[0].Calls[0].Status)
+	assert.Equal(t, assistants.ToolCallCancelled, rec.ended[0].Calls[1].Status)
+}
+
+// blockingRecorder blocks in OnToolEnd until released
+type blockingRecorder struct {
+	cancelRecorder
+
+	ended   chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRecorder) OnToolEnd(_ context.Context, _ *tools.ToolEndEvent) {
+	close(r.ended)
+	<-r.release
+}
+
+func Test_Assistant_ToolCancelled_BlockedCallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			ToolCalls: []llms.ToolCall{
+				{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "ok_tool", Arguments: "{}"}},
+				{ID: "call_2", FunctionCall: &llms.FunctionCall{Name: "fatal_tool", Arguments: "{}"}},
+			},
+		}},
+	}, nil).Times(1)
+
+	rec := &blockingRecorder{ended: make(chan struct{}), release: make(chan struct{})}
+	defer close(rec.release)
+
+	okTool := newCancelTool(ctrl, "ok_tool", func(_ context.Context, _ string) (string, error) {
+		return "result", nil
+	})
+	// the fatal error cancels the calls, while the callback of the completed call is blocked
+	fatalTool := newCancelTool(ctrl, "fatal_tool", func(_ context.Context, _ string) (string, error) {
+		<-rec.ended
+		return "", errors.Mark(errors.New("database is down"), chatmodel.ErrToolFatal)
+	})
+
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithCallback(rec),
+	).WithTools(okTool, fatalTool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	done := make(chan error, 1)
+	go func() {
+		_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, chatmodel.ErrToolFatal))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the blocked callback delays the cancellation")
+	}
+}
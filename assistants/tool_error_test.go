@@ -0,0 +1,103 @@
+package assistants_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_Assistant_ToolError_Categories(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	toolResponses := map[string]string{}
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{
+					{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "flaky_tool", Arguments: "{}"}},
+					{ID: "call_2", FunctionCall: &llms.FunctionCall{Name: "denied_tool", Arguments: "{}"}},
+					{ID: "call_3", FunctionCall: &llms.FunctionCall{Name: "invalid_tool", Arguments: "{}"}},
+				},
+			}},
+		}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				for _, m := range messages {
+					for _, p := range m.Parts {
+						if tr, ok := p.(llms.ToolCallResponse); ok {
+							toolResponses[tr.Name] = tr.Content
+						}
+					}
+				}
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "done"}}}, nil
+			}),
+	)
+
+	flakyCalls := 0
+	flakyTool := mocktools.NewMockTool[any, any](ctrl)
+	flakyTool.EXPECT().Name().Return("flaky_tool").Times(1)
+	flakyTool.EXPECT().Description().Return("desc").Times(1)
+	flakyTool.EXPECT().Parameters().Return(nil).Times(1)
+	flakyTool.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string) (string, error) {
+		flakyCalls++
+		if flakyCalls == 1 {
+			return "", errors.Mark(errors.New("connection reset"), chatmodel.ErrToolTransient)
+		}
+		return "ok", nil
+	}).Times(2)
+
+	deniedTool := newCancelTool(ctrl, "denied_tool", func(_ context.Context, _ string) (string, error) {
+		return "", errors.Mark(errors.New("access denied"), chatmodel.ErrToolPermission)
+	})
+	invalidTool := newCancelTool(ctrl, "invalid_tool", func(_ context.Context, _ string) (string, error) {
+		return "", &chatmodel.ToolError{Category: chatmodel.ToolErrorInvalidInput, Message: "missing query"}
+	})
+
+	rec := &retryRecorder{}
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithCallback(rec),
+		assistants.WithMaxToolRetries(1),
+	).WithTools(flakyTool, deniedTool, invalidTool)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.Choices[0].Content)
+
+	assert.Equal(t, []retryEvent{{reason: assistants.RetryReasonToolTransient, attempt: 1, err: true}}, rec.retries)
+	assert.Equal(t, "ok", toolResponses["flaky_tool"])
+
+	var denied chatmodel.ToolError
+	require.NoError(t, json.Unmarshal([]byte(toolResponses["denied_tool"]), &denied))
+	assert.Equal(t, chatmodel.ToolError{
+		Category: chatmodel.ToolErrorPermission,
+		Tool:     "denied_tool",
+		Message:  "access denied",
+	}, denied)
+
+	var invalid chatmodel.ToolError
+	require.NoError(t, json.Unmarshal([]byte(toolResponses["invalid_tool"]), &invalid))
+	assert.Equal(t, chatmodel.ToolError{
+		Category: chatmodel.ToolErrorInvalidInput,
+		Tool:     "invalid_tool",
+		Message:  "missing query",
+	}, invalid)
+}
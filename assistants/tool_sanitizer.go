@@ -0,0 +1,105 @@
+package assistants
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/xlog"
+)
+
+// ErrPromptInjection is returned when the run is aborted by the sanitizer,
+// because the tool output contains the prompt injection.
+var ErrPromptInjection = errors.New("prompt injection detected")
+
+// ToolOutputSanitizer checks the tool output before it is added to the message history,
+// and returns the content to send to the LLM.
+// The returned error aborts the run, after the results of the current tool calls are recorded.
+type ToolOutputSanitizer func(ctx context.Context, toolName, content string) (string, error)
+
+// InjectionAction defines how the tool output with the prompt injection is handled,
+// see InjectionSanitizer.
+type InjectionAction int
+
+const (
+	// InjectionQuarantine wraps the tool output in the quarantine tags,
+	// so the LLM treats it as data.
+	InjectionQuarantine InjectionAction = iota
+	// InjectionStrip removes the injection fragments from the tool output.
+	InjectionStrip
+	// InjectionAbort aborts the run with ErrPromptInjection.
+	InjectionAbort
+)
+
+// String returns the name of the action.
+func (a InjectionAction) String() string {
+	switch a {
+	case InjectionQuarantine:
+		return "quarantine"
+	case InjectionStrip:
+		return "strip"
+	case InjectionAbort:
+		return "abort"
+	default:
+		return fmt.Sprintf("InjectionAction(%d)", int(a))
+	}
+}
+
+// WithToolOutputSanitizer is an option to check the tool outputs before they are sent to the LLM,
+// for example with InjectionSanitizer.
+// The failed tool calls are not checked, as their content is produced by the assistant.
+func WithToolOutputSanitizer(fn ToolOutputSanitizer) Option {
+	return func(o *Config) {
+		o.ToolOutputSanitizer = fn
+	}
+}
+
+// InjectionSanitizer returns the ToolOutputSanitizer that detects the prompt injection
+// in the tool output with the detector, and handles it with the action.
+// The output without the injection is returned as is.
+// If the detector is nil, the detector with llmutils.DefaultInjectionPatterns is used.
+func InjectionSanitizer(detector *llmutils.InjectionDetector, action InjectionAction) ToolOutputSanitizer {
+	if detector == nil {
+		detector = llmutils.NewInjectionDetector()
+	}
+	return func(ctx context.Context, toolName, content string) (string, error) {
+		matches := detector.Detect(content)
+		if len(matches) == 0 {
+			return content, nil
+		}
+
+		logger.ContextKV(ctx, xlog.WARNING,
+			"reason", "prompt_injection",
+			"tool", toolName,
+			"pattern", matches[0].Pattern,
+			"matches", len(matches),
+			"action", action.String(),
+		)
+
+		switch action {
+		case InjectionStrip:
+			stripped, _ := detector.Strip(content)
+			return stripped, nil
+		case InjectionAbort:
+			return "", errors.Wrapf(ErrPromptInjection, "%s pattern", matches[0].Pattern)
+		default:
+			return llmutils.Quarantine(toolName, content), nil
+		}
+	}
+}
+
+// InjectionGuardrail returns the GuardrailFunc that rejects the text with the prompt injection,
+// for example to check the retrieved documents in the input with Guardrails.
+// If the detector is nil, the detector with llmutils.DefaultInjectionPatterns is used.
+func InjectionGuardrail(detector *llmutils.InjectionDetector) GuardrailFunc {
+	if detector == nil {
+		detector = llmutils.NewInjectionDetector()
+	}
+	return func(_ context.Context, text string) error {
+		if matches := detector.Detect(text); len(matches) > 0 {
+			return errors.Wrapf(ErrPromptInjection, "%s pattern", matches[0].Pattern)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package assistants
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/invopop/jsonschema"
+)
+
+// ToolPolicy checks the tool call before the tool is called,
+// and returns the error if the call is not allowed, see toolpolicy.Policy.
+// The error should be marked with the tool error category, like chatmodel.ErrToolPermission,
+// as it is reported to the LLM.
+type ToolPolicy interface {
+	Check(ctx context.Context, tool string, args string) error
+}
+
+// checkToolPolicies returns the error of the first policy, that denies the call.
+func checkToolPolicies(ctx context.Context, policies []ToolPolicy, toolName, args string) error {
+	for _, p := range policies {
+		if err := p.Check(ctx, toolName, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateToolArgs validates the LLM-provided arguments against the tool parameters,
+// and returns the invalid_input ToolError with the violations, or nil if the arguments are valid.
+func validateToolArgs(toolName string, params *jsonschema.Schema, args string) error {
+	if params == nil {
+		return nil
+	}
+	if strings.TrimSpace(args) == "" {
+		args = "{}"
+	}
+
+	err := schema.Validate(params, []byte(args))
+	if err == nil {
+		return nil
+	}
+
+	msg := "the arguments are not a valid JSON object, check the JSON schema and try again"
+	var verr *schema.ValidationError
+	if errors.As(err, &verr) {
+		msg = "the arguments do not match the parameters schema, fix the arguments and try again:\n" + verr.Details()
+	}
+	return &chatmodel.ToolError{
+		Category: chatmodel.ToolErrorInvalidInput,
+		Tool:     toolName,
+		Message:  msg,
+	}
+}
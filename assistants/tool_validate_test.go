@@ -0,0 +1,215 @@
+package assistants_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/mocks/mocktools"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newValidatedTool(ctrl *gomock.Controller, calls *[]string) *mocktools.MockTool[any, any] {
+	tool := mocktools.NewMockTool[any, any](ctrl)
+	tool.EXPECT().Name().Return("search_tool").Times(1)
+	tool.EXPECT().Description().Return("desc").Times(1)
+	tool.EXPECT().Parameters().Return(schema.MustFromAny(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string"},
+		},
+		"required": []string{"query"},
+	})).Times(1)
+	tool.EXPECT().Call(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, input string) (string, error) {
+		*calls = append(*calls, input)
+		return "found", nil
+	}).Times(1)
+	return tool
+}
+
+func Test_Assistant_ToolArgsValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var toolResponses []string
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	collect := func(messages []llms.Message) {
+		toolResponses = toolResponses[:0]
+		for _, m := range messages {
+			for _, p := range m.Parts {
+				if tr, ok := p.(llms.ToolCallResponse); ok {
+					toolResponses = append(toolResponses, tr.Content)
+				}
+			}
+		}
+	}
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{
+					{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "search_tool", Arguments: `{"query":42}`}},
+					{ID: "call_2", FunctionCall: &llms.FunctionCall{Name: "search_tool", Arguments: `not a json`}},
+					{ID: "call_3", FunctionCall: &llms.FunctionCall{Name: "search_tool", Arguments: ``}},
+				},
+			}},
+		}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				collect(messages)
+				return &llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{
+						ToolCalls: []llms.ToolCall{
+							{ID: "call_4", FunctionCall: &llms.FunctionCall{Name: "search_tool", Arguments: `{"query":"weather"}`}},
+						},
+					}},
+				}, nil
+			}),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "done"}},
+		}, nil),
+	)
+
+	var calls []string
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+	).WithTools(newValidatedTool(ctrl, &calls))
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	resp, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.Choices[0].Content)
+
+	// only the valid call reaches the tool
+	assert.Equal(t, []string{`{"query":"weather"}`}, calls)
+
+	require.Len(t, toolResponses, 3)
+	for _, content := range toolResponses {
+		var te chatmodel.ToolError
+		require.NoError(t, json.Unmarshal([]byte(content), &te), content)
+		assert.Equal(t, chatmodel.ToolErrorInvalidInput, te.Category)
+		assert.Equal(t, "search_tool", te.Tool)
+		assert.False(t, te.Retryable)
+	}
+	assert.Contains(t, toolResponses[0], "do not match the parameters schema")
+	assert.Contains(t, toolResponses[1], "not a valid JSON")
+	assert.Contains(t, toolResponses[2], "do not match the parameters schema")
+}
+
+func Test_Assistant_SkipToolArgsValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{
+					{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "search_tool", Arguments: `{"q":"weather"}`}},
+				},
+			}},
+		}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: "done"}},
+		}, nil),
+	)
+
+	var calls []string
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithSkipToolArgsValidation(true),
+	).WithTools(newValidatedTool(ctrl, &calls))
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"q":"weather"}`}, calls)
+}
+
+type toolPolicyFunc func(ctx context.Context, tool string, args string) error
+
+func (f toolPolicyFunc) Check(ctx context.Context, tool string, args string) error {
+	return f(ctx, tool, args)
+}
+
+func Test_Assistant_ToolPolicies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var toolResponses []string
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(2)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2)
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).Return(&llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{
+				ToolCalls: []llms.ToolCall{
+					{ID: "call_1", FunctionCall: &llms.FunctionCall{Name: "search_tool", Arguments: `{"query":"secrets"}`}},
+					{ID: "call_2", FunctionCall: &llms.FunctionCall{Name: "search_tool", Arguments: `{"query":"weather"}`}},
+				},
+			}},
+		}, nil),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+				for _, m := range messages {
+					for _, p := range m.Parts {
+						if tr, ok := p.(llms.ToolCallResponse); ok {
+							toolResponses = append(toolResponses, tr.Content)
+						}
+					}
+				}
+				return &llms.ContentResponse{
+					Choices: []*llms.ContentChoice{{Content: "done"}},
+				}, nil
+			}),
+	)
+
+	var checked []string
+	policy := toolPolicyFunc(func(ctx context.Context, tool string, args string) error {
+		assert.NotNil(t, chatmodel.GetChatContext(ctx))
+		checked = append(checked, tool+" "+args)
+		if args == `{"query":"secrets"}` {
+			return errors.Mark(errors.New("policy denied: secrets are not allowed"), chatmodel.ErrToolPermission)
+		}
+		return nil
+	})
+
+	var calls []string
+	ag := assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+		assistants.WithSequentialToolCalls(true),
+		assistants.WithToolPolicies(policy),
+	).WithTools(newValidatedTool(ctrl, &calls))
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(chatmodel.NewChatID(), chatmodel.NewChatID(), nil))
+	_, err := ag.Call(ctx, &assistants.CallInput{Input: "input"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`search_tool {"query":"secrets"}`, `search_tool {"query":"weather"}`}, checked)
+	// only the allowed call reaches the tool
+	assert.Equal(t, []string{`{"query":"weather"}`}, calls)
+
+	require.Len(t, toolResponses, 2)
+	var te chatmodel.ToolError
+	require.NoError(t, json.Unmarshal([]byte(toolResponses[0]), &te), toolResponses[0])
+	assert.Equal(t, chatmodel.ToolErrorPermission, te.Category)
+	assert.Equal(t, "search_tool", te.Tool)
+	assert.Equal(t, "policy denied: secrets are not allowed", te.Message)
+	assert.Equal(t, "found", toolResponses[1])
+}
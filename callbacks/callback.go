@@ -9,10 +9,11 @@ import (
 	"sync"
 
 	"github.com/effective-security/gogentic/assistants"
-	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
 	"github.com/effective-security/gogentic/tools"
 	"github.com/effective-security/x/slices"
 	"github.com/effective-security/xlog"
+	"github.com/invopop/jsonschema"
 )
 
 // ensure that the callbacks implement the correct interfaces
@@ -25,6 +26,11 @@ var (
 	_ tools.Callback      = (*PackageLogger)(nil)
 	_ assistants.Callback = (*Fanout)(nil)
 	_ tools.Callback      = (*Fanout)(nil)
+
+	_ assistants.RecoveryCallback = (*Noop)(nil)
+	_ assistants.RecoveryCallback = (*Printer)(nil)
+	_ assistants.RecoveryCallback = (*PackageLogger)(nil)
+	_ assistants.RecoveryCallback = (*Fanout)(nil)
 )
 
 // Mode defines the mode for callback printing
@@ -37,7 +43,14 @@ const (
 	ModeVerbose
 )
 
+// Multi returns the callback handler that forwards the events to multiple callbacks,
+// with the panic isolation per callback, see assistants.MultiCallback.
+func Multi(callbacks ...assistants.Callback) assistants.Callback {
+	return assistants.MultiCallback(callbacks...)
+}
+
 // Fanout is a callback handler that forwards the events to multiple callbacks.
+// The panic in a callback is not recovered, use Multi for the panic isolation.
 type Fanout struct {
 	callbacks []assistants.Callback
 }
@@ -50,63 +63,95 @@ func (l *Fanout) Add(callback assistants.Callback) {
 	l.callbacks = append(l.callbacks, callback)
 }
 
-func (l *Fanout) OnAssistantStart(ctx context.Context, assistant assistants.IAssistant, input string) {
+func (l *Fanout) OnAssistantStart(ctx context.Context, e *assistants.AssistantStartEvent) {
+	for _, callback := range l.callbacks {
+		callback.OnAssistantStart(ctx, e)
+	}
+}
+
+func (l *Fanout) OnAssistantEnd(ctx context.Context, e *assistants.AssistantEndEvent) {
+	for _, callback := range l.callbacks {
+		callback.OnAssistantEnd(ctx, e)
+	}
+}
+
+func (l *Fanout) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnAssistantStart(ctx, assistant, input)
+		callback.OnToolStart(ctx, e)
 	}
 }
 
-func (l *Fanout) OnAssistantEnd(ctx context.Context, assistant assistants.IAssistant, input string, resp *assistants.Response, messageHistory llms.Messages) {
+func (l *Fanout) OnAssistantLLMParseError(ctx context.Context, e *assistants.LLMParseErrorEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnAssistantEnd(ctx, assistant, input, resp, messageHistory)
+		callback.OnAssistantLLMParseError(ctx, e)
 	}
 }
 
-func (l *Fanout) OnToolStart(ctx context.Context, tool tools.ITool, assistantName, input string) {
+func (l *Fanout) OnAssistantError(ctx context.Context, e *assistants.AssistantErrorEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnToolStart(ctx, tool, assistantName, input)
+		callback.OnAssistantError(ctx, e)
 	}
 }
 
-func (l *Fanout) OnAssistantLLMParseError(ctx context.Context, a assistants.IAssistant, input string, response string, err error) {
+func (l *Fanout) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnAssistantLLMParseError(ctx, a, input, response, err)
+		callback.OnToolEnd(ctx, e)
 	}
 }
 
-func (l *Fanout) OnAssistantError(ctx context.Context, assistant assistants.IAssistant, input string, err error, messages []llms.Message) {
+func (l *Fanout) OnToolNotFound(ctx context.Context, e *assistants.ToolNotFoundEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnAssistantError(ctx, assistant, input, err, messages)
+		callback.OnToolNotFound(ctx, e)
 	}
 }
 
-func (l *Fanout) OnToolEnd(ctx context.Context, tool tools.ITool, assistantName, input string, output string) {
+func (l *Fanout) OnToolCancelled(ctx context.Context, e *assistants.ToolCancelledEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnToolEnd(ctx, tool, assistantName, input, output)
+		if cb, ok := callback.(assistants.RecoveryCallback); ok {
+			cb.OnToolCancelled(ctx, e)
+		}
+	}
+}
+
+func (l *Fanout) OnRetry(ctx context.Context, e *assistants.RetryEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.RecoveryCallback); ok {
+			cb.OnRetry(ctx, e)
+		}
+	}
+}
+
+func (l *Fanout) OnRateLimit(ctx context.Context, e *assistants.RateLimitEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.RecoveryCallback); ok {
+			cb.OnRateLimit(ctx, e)
+		}
 	}
 }
 
-func (l *Fanout) OnToolNotFound(ctx context.Context, agent assistants.IAssistant, tool string) {
+func (l *Fanout) OnMessageOrderRepair(ctx context.Context, e *assistants.MessageOrderRepairEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnToolNotFound(ctx, agent, tool)
+		if cb, ok := callback.(assistants.MessageOrderRepairCallback); ok {
+			cb.OnMessageOrderRepair(ctx, e)
+		}
 	}
 }
 
-func (l *Fanout) OnToolError(ctx context.Context, tool tools.ITool, assistantName, input string, err error) {
+func (l *Fanout) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnToolError(ctx, tool, assistantName, input, err)
+		callback.OnToolError(ctx, e)
 	}
 }
 
-func (l *Fanout) OnAssistantLLMCallStart(ctx context.Context, agent assistants.IAssistant, llm llms.Model, payload []llms.Message) {
+func (l *Fanout) OnAssistantLLMCallStart(ctx context.Context, e *assistants.LLMCallStartEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnAssistantLLMCallStart(ctx, agent, llm, payload)
+		callback.OnAssistantLLMCallStart(ctx, e)
 	}
 }
 
-func (l *Fanout) OnAssistantLLMCallEnd(ctx context.Context, agent assistants.IAssistant, llm llms.Model, resp *llms.ContentResponse) {
+func (l *Fanout) OnAssistantLLMCallEnd(ctx context.Context, e *assistants.LLMCallEndEvent) {
 	for _, callback := range l.callbacks {
-		callback.OnAssistantLLMCallEnd(ctx, agent, llm, resp)
+		callback.OnAssistantLLMCallEnd(ctx, e)
 	}
 }
 
@@ -121,24 +166,30 @@ func NewNoop() *Noop {
 
 var _ assistants.Callback = (*Noop)(nil)
 
-func (l *Noop) OnAssistantStart(ctx context.Context, assistant assistants.IAssistant, input string) {
+func (l *Noop) OnAssistantStart(ctx context.Context, e *assistants.AssistantStartEvent) {
+}
+func (l *Noop) OnAssistantEnd(ctx context.Context, e *assistants.AssistantEndEvent) {
+}
+func (l *Noop) OnAssistantError(ctx context.Context, e *assistants.AssistantErrorEvent) {
 }
-func (l *Noop) OnAssistantEnd(ctx context.Context, assistant assistants.IAssistant, input string, resp *assistants.Response, messageHistory llms.Messages) {
+func (l *Noop) OnAssistantLLMParseError(ctx context.Context, e *assistants.LLMParseErrorEvent) {
 }
-func (l *Noop) OnAssistantError(ctx context.Context, assistant assistants.IAssistant, input string, err error, messageHistory llms.Messages) {
+func (l *Noop) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {}
+func (l *Noop) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
 }
-func (l *Noop) OnAssistantLLMParseError(ctx context.Context, a assistants.IAssistant, input string, response string, err error) {
+func (l *Noop) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
 }
-func (l *Noop) OnToolStart(ctx context.Context, tool tools.ITool, assistantName, input string) {}
-func (l *Noop) OnToolEnd(ctx context.Context, tool tools.ITool, assistantName, input string, output string) {
+func (l *Noop) OnAssistantLLMCallStart(ctx context.Context, e *assistants.LLMCallStartEvent) {
 }
-func (l *Noop) OnToolError(ctx context.Context, tool tools.ITool, assistantName, input string, err error) {
+func (l *Noop) OnAssistantLLMCallEnd(ctx context.Context, e *assistants.LLMCallEndEvent) {
 }
-func (l *Noop) OnAssistantLLMCallStart(ctx context.Context, agent assistants.IAssistant, llm llms.Model, payload []llms.Message) {
+func (l *Noop) OnToolNotFound(ctx context.Context, e *assistants.ToolNotFoundEvent) {
 }
-func (l *Noop) OnAssistantLLMCallEnd(ctx context.Context, agent assistants.IAssistant, llm llms.Model, resp *llms.ContentResponse) {
+func (l *Noop) OnToolCancelled(ctx context.Context, e *assistants.ToolCancelledEvent) {
 }
-func (l *Noop) OnToolNotFound(ctx context.Context, agent assistants.IAssistant, tool string) {
+func (l *Noop) OnRetry(ctx context.Context, e *assistants.RetryEvent) {
+}
+func (l *Noop) OnRateLimit(ctx context.Context, e *assistants.RateLimitEvent) {
 }
 func (l *Noop) OnProgress(ctx context.Context, agent assistants.IAssistant, title, message string) {
 	if l.onProgress != nil {
@@ -149,209 +200,472 @@ func (l *Noop) WithProgress(cb assistants.OnProgressFunc) {
 	l.onProgress = cb
 }
 
-// Printer is a callback handler that prints to the Writer.
+// Printer is a callback handler that prints to the Writer,
+// as the human readable text, or as JSON for the log pipelines, see Format.
 type Printer struct {
 	Out  io.Writer
 	Mode Mode
+	// Format is the output format, FormatText by default.
+	Format Format
+	// Timestamps prefixes the events with the time in FormatText.
+	Timestamps bool
+	// Color highlights the events in FormatText with the ANSI colors.
+	Color bool
+	// Redactor masks the secrets in the printed text, if set.
+	Redactor *llmutils.Redactor
 
 	lock sync.Mutex
 }
 
+// NewPrinter returns the Printer in FormatText,
+// the colors are enabled if the out is a terminal.
 func NewPrinter(out io.Writer, mode Mode) *Printer {
-	return &Printer{Out: out, Mode: mode}
+	return &Printer{Out: out, Mode: mode, Color: isColorTerminal(out)}
+}
+
+// WithRedactor sets the Redactor to mask the secrets and the sensitive tool arguments.
+func (l *Printer) WithRedactor(r *llmutils.Redactor) *Printer {
+	l.Redactor = r
+	return l
 }
 
 var _ assistants.Callback = (*Printer)(nil)
 
-func (l *Printer) OnAssistantStart(ctx context.Context, assistant assistants.IAssistant, input string) {
+func (l *Printer) OnAssistantStart(ctx context.Context, e *assistants.AssistantStartEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Assistant Start: %s\n", assistant.Name())
-	skillList := assistant.GetSkills()
+	skillList := e.Assistant.GetSkills()
+	if l.structured() {
+		r := &PrintRecord{
+			Event:     "assistant_start",
+			Assistant: e.Assistant.Name(),
+			Input:     l.Redactor.String(e.Input),
+		}
+		if len(skillList) > 0 {
+			r.Details = map[string]any{"skills": skillList.Names()}
+		}
+		l.record(r)
+		return
+	}
+	l.printf(colorBlue, "Assistant Start", "%s", e.Assistant.Name())
 	if len(skillList) > 0 {
 		_, _ = fmt.Fprintf(l.Out, "Skills: %s\n", strings.Join(skillList.Names(), ", "))
 	}
-	_, _ = fmt.Fprintf(l.Out, "Input: %s\n", input)
+	_, _ = fmt.Fprintf(l.Out, "Input: %s\n", l.Redactor.String(e.Input))
 }
 
-func (l *Printer) OnAssistantEnd(ctx context.Context, assistant assistants.IAssistant, input string, resp *assistants.Response, messageHistory llms.Messages) {
+func (l *Printer) OnAssistantEnd(ctx context.Context, e *assistants.AssistantEndEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Assistant End: %s\n", assistant.Name())
-	if l.Mode == ModeVerbose {
-		_, _ = fmt.Fprintf(l.Out, "Output:\n")
-		for _, choice := range resp.Choices {
+	var output []string
+	if e.Response != nil {
+		for _, choice := range e.Response.Choices {
 			if choice.Content != "" {
-				_, _ = fmt.Fprintln(l.Out, choice.Content)
+				output = append(output, l.Redactor.String(choice.Content))
 			}
 		}
 	}
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:      "assistant_end",
+			Assistant:  e.Assistant.Name(),
+			Output:     l.verbose(strings.Join(output, "\n")),
+			DurationMS: durationMS(e.Latency),
+		})
+		return
+	}
+	l.printf(colorBlue, "Assistant End", "%s%s", e.Assistant.Name(), inDuration(e.Latency))
+	if l.Mode == ModeVerbose {
+		_, _ = fmt.Fprintf(l.Out, "Output:\n")
+		for _, content := range output {
+			_, _ = fmt.Fprintln(l.Out, content)
+		}
+	}
 }
 
-func (l *Printer) OnAssistantError(ctx context.Context, assistant assistants.IAssistant, input string, err error, messages []llms.Message) {
+func (l *Printer) OnAssistantError(ctx context.Context, e *assistants.AssistantErrorEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Assistant Error: %s: %s\n", assistant.Name(), err.Error())
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:      "assistant_error",
+			Assistant:  e.Assistant.Name(),
+			Error:      l.Redactor.String(e.Err.Error()),
+			DurationMS: durationMS(e.Latency),
+		})
+		return
+	}
+	l.printf(colorRed, "Assistant Error", "%s: %s", e.Assistant.Name(), l.Redactor.String(e.Err.Error()))
 }
 
-func (l *Printer) OnAssistantLLMParseError(ctx context.Context, assistant assistants.IAssistant, input string, response string, err error) {
+func (l *Printer) OnAssistantLLMParseError(ctx context.Context, e *assistants.LLMParseErrorEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Assistant LLM Parse Error: %s: %s\n", assistant.Name(), err.Error())
-	_, _ = fmt.Fprintf(l.Out, "Response: %s\n", response)
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "assistant_llm_parse_error",
+			Assistant: e.Assistant.Name(),
+			Output:    l.Redactor.String(e.Response),
+			Error:     l.Redactor.String(e.Err.Error()),
+		})
+		return
+	}
+	l.printf(colorRed, "Assistant LLM Parse Error", "%s: %s", e.Assistant.Name(), l.Redactor.String(e.Err.Error()))
+	_, _ = fmt.Fprintf(l.Out, "Response: %s\n", l.Redactor.String(e.Response))
 }
 
-func (l *Printer) OnToolStart(ctx context.Context, tool tools.ITool, assistantName, input string) {
+func (l *Printer) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Tool Start: %s (%s)\n", tool.Name(), assistantName)
+	input := l.Redactor.Arguments(toolParameters(l.Redactor, e.Tool), e.Input)
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "tool_start",
+			Assistant: e.Assistant,
+			Tool:      e.Tool.Name(),
+			CallID:    e.CallID,
+			Attempt:   e.Attempt,
+			Input:     input,
+		})
+		return
+	}
+	l.printf(colorCyan, "Tool Start", "%s (%s)", e.Tool.Name(), e.Assistant)
 	_, _ = fmt.Fprintf(l.Out, "Input: %s\n", input)
 }
 
-func (l *Printer) OnToolEnd(ctx context.Context, tool tools.ITool, assistantName, input string, output string) {
+func (l *Printer) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Tool End: %s (%s)\n", tool.Name(), assistantName)
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:      "tool_end",
+			Assistant:  e.Assistant,
+			Tool:       e.Tool.Name(),
+			CallID:     e.CallID,
+			Attempt:    e.Attempt,
+			Output:     l.verbose(l.Redactor.String(e.Output)),
+			DurationMS: durationMS(e.Latency),
+		})
+		return
+	}
+	l.printf(colorCyan, "Tool End", "%s (%s)%s", e.Tool.Name(), e.Assistant, inDuration(e.Latency))
 	if l.Mode == ModeVerbose {
-		_, _ = fmt.Fprintf(l.Out, "Output: %s\n", output)
+		_, _ = fmt.Fprintf(l.Out, "Output: %s\n", l.Redactor.String(e.Output))
 	}
 }
 
-func (l *Printer) OnToolError(ctx context.Context, tool tools.ITool, assistantName, input string, err error) {
+func (l *Printer) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Tool Error: %s (%s): %s\n", tool.Name(), assistantName, err.Error())
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:      "tool_error",
+			Assistant:  e.Assistant,
+			Tool:       e.Tool.Name(),
+			CallID:     e.CallID,
+			Attempt:    e.Attempt,
+			Error:      l.Redactor.String(e.Err.Error()),
+			DurationMS: durationMS(e.Latency),
+		})
+		return
+	}
+	l.printf(colorRed, "Tool Error", "%s (%s): %s", e.Tool.Name(), e.Assistant, l.Redactor.String(e.Err.Error()))
 }
 
-func (l *Printer) OnAssistantLLMCallStart(ctx context.Context, agent assistants.IAssistant, llm llms.Model, payload []llms.Message) {
+func (l *Printer) OnAssistantLLMCallStart(ctx context.Context, e *assistants.LLMCallStartEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Assistant LLM Call: %s: %s model, %d messages\n", agent.Name(), llm.GetName(), len(payload))
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "assistant_llm_call_start",
+			Assistant: e.Assistant.Name(),
+			Model:     e.LLM.GetName(),
+			Round:     e.Round,
+			Details:   map[string]any{"messages": len(e.Messages)},
+		})
+		return
+	}
+	l.printf(colorNone, "Assistant LLM Call", "%s: %s model, %d messages", e.Assistant.Name(), e.LLM.GetName(), len(e.Messages))
 	// if l.Mode == ModeVerbose {
-	// 	llmutils.PrintMessageContents(l.Out, payload)
+	// 	llmutils.PrintMessageContents(l.Out, e.Messages)
 	// }
 }
 
-func (l *Printer) OnAssistantLLMCallEnd(ctx context.Context, agent assistants.IAssistant, llm llms.Model, resp *llms.ContentResponse) {
+func (l *Printer) OnAssistantLLMCallEnd(ctx context.Context, e *assistants.LLMCallEndEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:      "assistant_llm_call_end",
+			Assistant:  e.Assistant.Name(),
+			Model:      e.LLM.GetName(),
+			Round:      e.Round,
+			DurationMS: durationMS(e.Latency),
+			Details:    map[string]any{"choices": len(e.Response.Choices)},
+		})
+		return
+	}
+	l.printf(colorNone, "Assistant LLM Call End", "%s: %s model, %d messages%s",
+		e.Assistant.Name(), e.LLM.GetName(), len(e.Response.Choices), inDuration(e.Latency))
+}
+
+func (l *Printer) OnToolNotFound(ctx context.Context, e *assistants.ToolNotFoundEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "tool_not_found",
+			Assistant: e.Assistant.Name(),
+			Tool:      e.Tool,
+			CallID:    e.CallID,
+		})
+		return
+	}
+	l.printf(colorYellow, "Tool Not Found", "%s", e.Tool)
+}
+
+func (l *Printer) OnToolCancelled(ctx context.Context, e *assistants.ToolCancelledEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "tool_cancelled",
+			Assistant: e.Assistant.Name(),
+			Tool:      e.Tool,
+			Output:    l.verbose(l.Redactor.String(e.Partial)),
+			Error:     l.Redactor.String(e.Cause.Error()),
+		})
+		return
+	}
+	l.printf(colorYellow, "Tool Cancelled", "%s: %s", e.Tool, l.Redactor.String(e.Cause.Error()))
+}
+
+func (l *Printer) OnRetry(ctx context.Context, e *assistants.RetryEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		r := &PrintRecord{
+			Event:     "assistant_retry",
+			Assistant: e.Assistant.Name(),
+			Attempt:   e.Attempt,
+			Details:   map[string]any{"reason": e.Reason},
+		}
+		if e.Err != nil {
+			r.Error = l.Redactor.String(e.Err.Error())
+		}
+		l.record(r)
+		return
+	}
+	l.printf(colorYellow, "Assistant Retry", "%s: %s, attempt %d", e.Assistant.Name(), e.Reason, e.Attempt)
+}
+
+func (l *Printer) OnRateLimit(ctx context.Context, e *assistants.RateLimitEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Assistant LLM Call End: %s: %s model, %d messages\n", agent.Name(), llm.GetName(), len(resp.Choices))
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "assistant_rate_limit",
+			Assistant: e.Assistant.Name(),
+			Model:     e.LLM.GetName(),
+			Attempt:   e.Attempt,
+			Details:   map[string]any{"wait_ms": durationMS(e.Wait)},
+		})
+		return
+	}
+	l.printf(colorYellow, "Assistant Rate Limit", "%s: %s model, attempt %d, wait %s", e.Assistant.Name(), e.LLM.GetName(), e.Attempt, e.Wait)
 }
 
-func (l *Printer) OnToolNotFound(ctx context.Context, agent assistants.IAssistant, tool string) {
+func (l *Printer) OnMessageOrderRepair(ctx context.Context, e *assistants.MessageOrderRepairEvent) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	_, _ = fmt.Fprintf(l.Out, "Tool Not Found: %s\n", tool)
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "assistant_message_order_repair",
+			Assistant: e.Assistant.Name(),
+			Model:     e.LLM.GetName(),
+			Details: map[string]any{
+				"merged":   e.Repair.MergedMessages,
+				"inserted": e.Repair.InsertedMessages,
+			},
+		})
+		return
+	}
+	l.printf(colorYellow, "Assistant Message Order Repair", "%s: %s model, merged %d, inserted %d",
+		e.Assistant.Name(), e.LLM.GetName(), e.Repair.MergedMessages, e.Repair.InsertedMessages)
 }
 
 // PackageLogger is a callback handler that prints to the logger.
 type PackageLogger struct {
-	logger *xlog.PackageLogger
+	logger   *xlog.PackageLogger
+	redactor *llmutils.Redactor
 }
 
 func NewPackageLogger(logger *xlog.PackageLogger) *PackageLogger {
 	return &PackageLogger{logger: logger}
 }
 
+// WithRedactor sets the Redactor to mask the secrets and the sensitive tool arguments.
+func (l *PackageLogger) WithRedactor(r *llmutils.Redactor) *PackageLogger {
+	l.redactor = r
+	return l
+}
+
 var _ assistants.Callback = (*PackageLogger)(nil)
 
-func (l *PackageLogger) OnAssistantStart(ctx context.Context, assistant assistants.IAssistant, input string) {
+func (l *PackageLogger) OnAssistantStart(ctx context.Context, e *assistants.AssistantStartEvent) {
 	l.logger.ContextKV(ctx, xlog.DEBUG,
 		"event", "assistant_start",
-		"assistant", assistant.Name(),
-		"input", input,
+		"assistant", e.Assistant.Name(),
+		"input", l.redactor.String(e.Input),
 	)
 }
 
-func (l *PackageLogger) OnAssistantEnd(ctx context.Context, assistant assistants.IAssistant, input string, resp *assistants.Response, messageHistory llms.Messages) {
+func (l *PackageLogger) OnAssistantEnd(ctx context.Context, e *assistants.AssistantEndEvent) {
 	l.logger.ContextKV(ctx, xlog.DEBUG,
 		"event", "assistant_end",
-		"assistant", assistant.Name())
-	for _, choice := range resp.Choices {
+		"assistant", e.Assistant.Name())
+	for _, choice := range e.Response.Choices {
 		if choice.Content != "" {
-			l.logger.ContextKV(ctx, xlog.DEBUG, "result", choice.Content)
+			l.logger.ContextKV(ctx, xlog.DEBUG, "result", l.redactor.String(choice.Content))
 		}
 	}
 }
 
-func (l *PackageLogger) OnAssistantError(ctx context.Context, assistant assistants.IAssistant, input string, err error, messageHistory llms.Messages) {
+func (l *PackageLogger) OnAssistantError(ctx context.Context, e *assistants.AssistantErrorEvent) {
 	level := xlog.ERROR
-	if IsTimeout(err) {
+	if IsTimeout(e.Err) {
 		level = xlog.WARNING
 	}
 	l.logger.ContextKV(ctx, level,
 		"event", "assistant_error",
-		"assistant", assistant.Name(),
-		"err", err.Error(),
+		"assistant", e.Assistant.Name(),
+		"err", l.redactor.String(e.Err.Error()),
 	)
 }
 
-func (l *PackageLogger) OnAssistantLLMParseError(ctx context.Context, assistant assistants.IAssistant, input string, response string, err error) {
+func (l *PackageLogger) OnAssistantLLMParseError(ctx context.Context, e *assistants.LLMParseErrorEvent) {
 	l.logger.ContextKV(ctx, xlog.DEBUG,
 		"event", "assistant_llm_parse_error",
-		"assistant", assistant.Name(),
-		"err", err.Error(),
-		"response", response,
+		"assistant", e.Assistant.Name(),
+		"err", l.redactor.String(e.Err.Error()),
+		"response", l.redactor.String(e.Response),
 	)
 }
 
-func (l *PackageLogger) OnToolStart(ctx context.Context, tool tools.ITool, assistantName, input string) {
+func (l *PackageLogger) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
 	l.logger.ContextKV(ctx, xlog.DEBUG,
 		"event", "tool_start",
-		"assistant", assistantName,
-		"tool", tool.Name(),
-		"input", input,
+		"assistant", e.Assistant,
+		"tool", e.Tool.Name(),
+		"tool_call_id", e.CallID,
+		"attempt", e.Attempt,
+		"input", l.redactor.Arguments(toolParameters(l.redactor, e.Tool), e.Input),
 	)
 }
 
-func (l *PackageLogger) OnToolEnd(ctx context.Context, tool tools.ITool, assistantName, input string, output string) {
+func (l *PackageLogger) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
 	l.logger.ContextKV(ctx, xlog.DEBUG,
 		"event", "tool_end",
-		"assistant", assistantName,
-		"tool", tool.Name(),
-		"output", output,
+		"assistant", e.Assistant,
+		"tool", e.Tool.Name(),
+		"tool_call_id", e.CallID,
+		"latency", e.Latency,
+		"output", l.redactor.String(e.Output),
 	)
 }
 
-func (l *PackageLogger) OnToolError(ctx context.Context, tool tools.ITool, assistantName, input string, err error) {
+func (l *PackageLogger) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
 	level := xlog.ERROR
-	if IsTimeout(err) {
+	if IsTimeout(e.Err) {
 		level = xlog.WARNING
 	}
 	l.logger.ContextKV(ctx, level,
 		"event", "tool_error",
-		"assistant", assistantName,
-		"tool", tool.Name(),
-		"err", err.Error(),
+		"assistant", e.Assistant,
+		"tool", e.Tool.Name(),
+		"tool_call_id", e.CallID,
+		"latency", e.Latency,
+		"err", l.redactor.String(e.Err.Error()),
 	)
 }
 
-func (l *PackageLogger) OnAssistantLLMCallStart(ctx context.Context, agent assistants.IAssistant, llm llms.Model, payload []llms.Message) {
+func (l *PackageLogger) OnAssistantLLMCallStart(ctx context.Context, e *assistants.LLMCallStartEvent) {
 	l.logger.ContextKV(ctx, xlog.DEBUG,
 		"event", "assistant_llm_call_start",
-		"assistant", agent.Name(),
-		"model", llm.GetName(),
-		"messages", len(payload),
+		"assistant", e.Assistant.Name(),
+		"model", e.LLM.GetName(),
+		"messages", len(e.Messages),
 	)
 }
 
-func (l *PackageLogger) OnAssistantLLMCallEnd(ctx context.Context, agent assistants.IAssistant, llm llms.Model, resp *llms.ContentResponse) {
+func (l *PackageLogger) OnAssistantLLMCallEnd(ctx context.Context, e *assistants.LLMCallEndEvent) {
 	l.logger.ContextKV(ctx, xlog.DEBUG,
 		"event", "assistant_llm_call_end",
-		"assistant", agent.Name(),
-		"model", llm.GetName(),
-		"messages", len(resp.Choices),
+		"assistant", e.Assistant.Name(),
+		"model", e.LLM.GetName(),
+		"messages", len(e.Response.Choices),
 	)
 }
 
-func (l *PackageLogger) OnToolNotFound(ctx context.Context, agent assistants.IAssistant, tool string) {
+func (l *PackageLogger) OnToolNotFound(ctx context.Context, e *assistants.ToolNotFoundEvent) {
 	l.logger.ContextKV(ctx, xlog.DEBUG,
 		"event", "tool_not_found",
-		"assistant", agent.Name(),
-		"tool", tool,
+		"assistant", e.Assistant.Name(),
+		"tool", e.Tool,
+	)
+}
+
+func (l *PackageLogger) OnToolCancelled(ctx context.Context, e *assistants.ToolCancelledEvent) {
+	l.logger.ContextKV(ctx, xlog.DEBUG,
+		"event", "tool_cancelled",
+		"assistant", e.Assistant.Name(),
+		"tool", e.Tool,
+		"cause", l.redactor.String(e.Cause.Error()),
+	)
+}
+
+func (l *PackageLogger) OnRetry(ctx context.Context, e *assistants.RetryEvent) {
+	kv := []any{
+		"event", "assistant_retry",
+		"assistant", e.Assistant.Name(),
+		"reason", e.Reason,
+		"attempt", e.Attempt,
+	}
+	if e.Err != nil {
+		kv = append(kv, "err", l.redactor.String(e.Err.Error()))
+	}
+	l.logger.ContextKV(ctx, xlog.WARNING, kv...)
+}
+
+func (l *PackageLogger) OnRateLimit(ctx context.Context, e *assistants.RateLimitEvent) {
+	l.logger.ContextKV(ctx, xlog.WARNING,
+		"event", "assistant_rate_limit",
+		"assistant", e.Assistant.Name(),
+		"model", e.LLM.GetName(),
+		"attempt", e.Attempt,
+		"wait", e.Wait,
 	)
 }
 
+func (l *PackageLogger) OnMessageOrderRepair(ctx context.Context, e *assistants.MessageOrderRepairEvent) {
+	l.logger.ContextKV(ctx, xlog.WARNING,
+		"event", "assistant_message_order_repair",
+		"assistant", e.Assistant.Name(),
+		"model", e.LLM.GetName(),
+		"merged", e.Repair.MergedMessages,
+		"inserted", e.Repair.InsertedMessages,
+	)
+}
+
+// toolParameters returns the parameters of the tool, to mask the sensitive arguments,
+// or nil if the redactor is not set.
+func toolParameters(r *llmutils.Redactor, tool tools.ITool) *jsonschema.Schema {
+	if r == nil {
+		return nil
+	}
+	return tool.Parameters()
+}
+
 // IsTimeout returns true for timeout error
 func IsTimeout(err error) bool {
 	if err == nil {
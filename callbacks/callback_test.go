@@ -3,7 +3,11 @@ package callbacks_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/assistants"
@@ -16,6 +20,7 @@ import (
 	"github.com/effective-security/x/values"
 	"github.com/invopop/jsonschema"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCallback(t *testing.T) {
@@ -25,8 +30,8 @@ func TestCallback(t *testing.T) {
 	ast := &fakeAssistant{name: "test-assistant"}
 	tool := &fakeTool{name: "test-tool"}
 
-	cb.OnAssistantStart(context.Background(), ast, "test input")
-	cb.OnAssistantEnd(context.Background(), ast, "test input", &assistants.Response{
+	cb.OnAssistantStart(context.Background(), &assistants.AssistantStartEvent{Assistant: ast, Input: "test input"})
+	cb.OnAssistantEnd(context.Background(), &assistants.AssistantEndEvent{Assistant: ast, Input: "test input", Response: &assistants.Response{
 		Choices: []*llms.ContentChoice{
 			{
 				Content: "test output",
@@ -40,11 +45,11 @@ func TestCallback(t *testing.T) {
 				},
 			},
 		},
-	}, llms.Messages{})
-	cb.OnAssistantError(context.Background(), ast, "test input", errors.New("test error"), []llms.Message{})
-	cb.OnToolStart(context.Background(), tool, "test-assistant", "test input")
-	cb.OnToolEnd(context.Background(), tool, "test-assistant", "test input", "test output")
-	cb.OnToolError(context.Background(), tool, "test-assistant", "test input", errors.New("test error"))
+	}})
+	cb.OnAssistantError(context.Background(), &assistants.AssistantErrorEvent{Assistant: ast, Input: "test input", Err: errors.New("test error")})
+	cb.OnToolStart(context.Background(), &tools.ToolStartEvent{Tool: tool, Assistant: "test-assistant", Input: "test input"})
+	cb.OnToolEnd(context.Background(), &tools.ToolEndEvent{Tool: tool, Assistant: "test-assistant", Input: "test input", Output: "test output"})
+	cb.OnToolError(context.Background(), &tools.ToolErrorEvent{Tool: tool, Assistant: "test-assistant", Input: "test input", Err: errors.New("test error")})
 
 	res := buf.String()
 	assert.Contains(t, res, "Assistant Start: test-assistant")
@@ -56,6 +61,122 @@ func TestCallback(t *testing.T) {
 	assert.Contains(t, res, "Tool Error: test-tool (test-assistant): test error")
 }
 
+func TestPrinter_Redactor(t *testing.T) {
+	params := &jsonschema.Schema{
+		Type:       "object",
+		Properties: jsonschema.NewProperties(),
+	}
+	params.Properties.Set("user", &jsonschema.Schema{Type: "string"})
+	params.Properties.Set("password", &jsonschema.Schema{Type: "string", Extras: map[string]any{"x-sensitive": true}})
+
+	var buf bytes.Buffer
+	cb := callbacks.NewPrinter(&buf, callbacks.ModeVerbose).
+		WithRedactor(llmutils.NewRedactor(llmutils.WithMask(llmutils.MaskPartial(2))))
+
+	ast := &fakeAssistant{name: "test-assistant"}
+	tool := &sensitiveTool{fakeTool: fakeTool{name: "login"}, params: params}
+
+	cb.OnAssistantStart(context.Background(), &assistants.AssistantStartEvent{Assistant: ast, Input: "my key is sk-ant-REDACTED"})
+	cb.OnToolStart(context.Background(), &tools.ToolStartEvent{Tool: tool, Assistant: "test-assistant", Input: `{"user":"bob","password":"hunter2hunter2"}`})
+	cb.OnToolError(context.Background(), &tools.ToolErrorEvent{Tool: tool, Assistant: "test-assistant", Err: errors.New("x-api-key: 0123456789abcdef is invalid")})
+
+	res := buf.String()
+	assert.Contains(t, res, "Input: my key is ****op\n")
+	assert.Contains(t, res, `Input: {"password":"****r2","user":"bob"}`)
+	assert.Contains(t, res, "Tool Error: login (test-assistant): x-api-key: ****ef is invalid")
+}
+
+func TestPrinter_Formats(t *testing.T) {
+	ast := &fakeAssistant{name: "test-assistant"}
+	tool := &fakeTool{name: "test-tool"}
+	llm := &fakeModel{name: "gpt-4o"}
+
+	emit := func(cb *callbacks.Printer) {
+		ctx := context.Background()
+		cb.OnAssistantStart(ctx, &assistants.AssistantStartEvent{Assistant: ast, Input: "test input"})
+		cb.OnAssistantLLMCallEnd(ctx, &assistants.LLMCallEndEvent{Assistant: ast, LLM: llm, Round: 1, Latency: 1500 * time.Millisecond,
+			Response: &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "<b>"}}}})
+		cb.OnToolEnd(ctx, &tools.ToolEndEvent{Tool: tool, Assistant: "test-assistant", CallID: "call_1", Attempt: 2, Output: "<b>", Latency: 25 * time.Millisecond})
+		cb.OnToolError(ctx, &tools.ToolErrorEvent{Tool: tool, Assistant: "test-assistant", CallID: "call_2", Attempt: 1, Err: errors.New("test error")})
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		cb := callbacks.NewPrinter(&buf, callbacks.ModeVerbose)
+		assert.False(t, cb.Color)
+		emit(cb)
+
+		res := buf.String()
+		assert.Contains(t, res, "Assistant LLM Call End: test-assistant: "+llm.GetName()+" model, 1 messages in 1.5s\n")
+		assert.Contains(t, res, "Tool End: test-tool (test-assistant) in 25ms\n")
+		assert.Contains(t, res, "Tool Error: test-tool (test-assistant): test error\n")
+		assert.NotContains(t, res, "\x1b[")
+	})
+
+	t.Run("text_color_timestamps", func(t *testing.T) {
+		var buf bytes.Buffer
+		emit(callbacks.NewPrinter(&buf, callbacks.ModeDefault).WithColor(true).WithTimestamps(true))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Regexp(t, `^\d{2}:\d{2}:\d{2}\.\d{3} \x1b\[34mAssistant Start\x1b\[0m: test-assistant$`, lines[0])
+		assert.Contains(t, buf.String(), "\x1b[31mTool Error\x1b[0m: test-tool (test-assistant): test error\n")
+		// the continuation lines are not prefixed
+		assert.Equal(t, "Input: test input", lines[1])
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		var buf bytes.Buffer
+		emit(callbacks.NewPrinter(&buf, callbacks.ModeVerbose).WithFormat(callbacks.FormatNDJSON).WithColor(true))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 4)
+		var records []callbacks.PrintRecord
+		for _, line := range lines {
+			var r callbacks.PrintRecord
+			require.NoError(t, json.Unmarshal([]byte(line), &r), line)
+			assert.False(t, r.Time.IsZero())
+			records = append(records, r)
+		}
+		assert.Equal(t, "assistant_start", records[0].Event)
+		assert.Equal(t, "test input", records[0].Input)
+		assert.Equal(t, "assistant_llm_call_end", records[1].Event)
+		assert.Equal(t, 1, records[1].Round)
+		assert.Equal(t, 1500.0, records[1].DurationMS)
+		assert.Equal(t, "tool_end", records[2].Event)
+		assert.Equal(t, "call_1", records[2].CallID)
+		assert.Equal(t, 2, records[2].Attempt)
+		assert.Equal(t, "<b>", records[2].Output)
+		assert.Equal(t, 25.0, records[2].DurationMS)
+		assert.Equal(t, "tool_error", records[3].Event)
+		assert.Equal(t, "test error", records[3].Error)
+		assert.Contains(t, lines[2], `"output":"<b>"`)
+		assert.NotContains(t, buf.String(), "\x1b[")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		emit(callbacks.NewPrinter(&buf, callbacks.ModeDefault).WithFormat(callbacks.FormatJSON))
+
+		dec := json.NewDecoder(&buf)
+		var events []string
+		for dec.More() {
+			var r callbacks.PrintRecord
+			require.NoError(t, dec.Decode(&r))
+			// the output is printed in the verbose mode only
+			assert.Empty(t, r.Output)
+			events = append(events, r.Event)
+		}
+		assert.Equal(t, []string{"assistant_start", "assistant_llm_call_end", "tool_end", "tool_error"}, events)
+	})
+
+	t.Run("not_terminal", func(t *testing.T) {
+		f, err := os.CreateTemp(t.TempDir(), "printer")
+		require.NoError(t, err)
+		defer func() { _ = f.Close() }()
+		assert.False(t, callbacks.NewPrinter(f, callbacks.ModeDefault).Color)
+	})
+}
+
 func TestDescriptions(t *testing.T) {
 	tool1 := &fakeTool{name: "test-tool1", description: "test tool 1\nLine 1"}
 	tool2 := &fakeTool{name: "test-tool2", description: "test tool 2\nLine 2"}
@@ -191,12 +312,12 @@ func TestFanoutCallback(t *testing.T) {
 	tool := &fakeTool{name: "test-tool"}
 
 	// Test OnAssistantStart
-	fanout.OnAssistantStart(context.Background(), ast, "test input")
+	fanout.OnAssistantStart(context.Background(), &assistants.AssistantStartEvent{Assistant: ast, Input: "test input"})
 	assert.Contains(t, buf1.String(), "Assistant Start: test-assistant")
 	assert.Contains(t, buf2.String(), "Assistant Start: test-assistant")
 
 	// Test OnAssistantEnd
-	fanout.OnAssistantEnd(context.Background(), ast, "test input", &assistants.Response{
+	fanout.OnAssistantEnd(context.Background(), &assistants.AssistantEndEvent{Assistant: ast, Input: "test input", Response: &assistants.Response{
 		Choices: []*llms.ContentChoice{
 			{
 				Content: "test output",
@@ -210,42 +331,63 @@ func TestFanoutCallback(t *testing.T) {
 				},
 			},
 		},
-	}, llms.Messages{})
+	}})
 	assert.Contains(t, buf1.String(), "Assistant End: test-assistant")
 	assert.Contains(t, buf2.String(), "Assistant End: test-assistant")
 
 	// Test OnAssistantError
-	fanout.OnAssistantError(context.Background(), ast, "test input", errors.New("test error"), []llms.Message{})
+	fanout.OnAssistantError(context.Background(), &assistants.AssistantErrorEvent{Assistant: ast, Input: "test input", Err: errors.New("test error")})
 	assert.Contains(t, buf1.String(), "Assistant Error: test-assistant")
 	assert.Contains(t, buf2.String(), "Assistant Error: test-assistant")
 
 	// Test OnToolStart
-	fanout.OnToolStart(context.Background(), tool, "test-assistant", "test input")
+	fanout.OnToolStart(context.Background(), &tools.ToolStartEvent{Tool: tool, Assistant: "test-assistant", Input: "test input"})
 	assert.Contains(t, buf1.String(), "Tool Start: test-tool (test-assistant)")
 	assert.Contains(t, buf2.String(), "Tool Start: test-tool (test-assistant)")
 
 	// Test OnToolEnd
-	fanout.OnToolEnd(context.Background(), tool, "test-assistant", "test input", "test output")
+	fanout.OnToolEnd(context.Background(), &tools.ToolEndEvent{Tool: tool, Assistant: "test-assistant", Input: "test input", Output: "test output"})
 	assert.Contains(t, buf1.String(), "Tool End: test-tool (test-assistant)")
 	assert.Contains(t, buf2.String(), "Tool End: test-tool (test-assistant)")
 
 	// Test OnToolError
-	fanout.OnToolError(context.Background(), tool, "test-assistant", "test input", errors.New("test error"))
+	fanout.OnToolError(context.Background(), &tools.ToolErrorEvent{Tool: tool, Assistant: "test-assistant", Input: "test input", Err: errors.New("test error")})
 	assert.Contains(t, buf1.String(), "Tool Error: test-tool (test-assistant)")
 	assert.Contains(t, buf2.String(), "Tool Error: test-tool (test-assistant)")
 
 	// Test OnAssistantLLMCall
-	fanout.OnAssistantLLMCallStart(context.Background(), ast, &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, []llms.Message{})
+	fanout.OnAssistantLLMCallStart(context.Background(), &assistants.LLMCallStartEvent{Assistant: ast, LLM: &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}})
 	assert.Contains(t, buf1.String(), "Assistant LLM Call: test-assistant")
 	assert.Contains(t, buf2.String(), "Assistant LLM Call: test-assistant")
 
 	// Test OnToolNotFound
-	fanout.OnToolNotFound(context.Background(), ast, "missing-tool")
+	fanout.OnToolNotFound(context.Background(), &assistants.ToolNotFoundEvent{Assistant: ast, Tool: "missing-tool"})
 	assert.Contains(t, buf1.String(), "Tool Not Found: missing-tool")
 	assert.Contains(t, buf2.String(), "Tool Not Found: missing-tool")
 
+	// Test OnToolCancelled
+	fanout.OnToolCancelled(context.Background(), &assistants.ToolCancelledEvent{Assistant: ast, Tool: "slow-tool", Input: "test input", Partial: "partial", Cause: context.Canceled})
+	assert.Contains(t, buf1.String(), "Tool Cancelled: slow-tool: context canceled")
+	assert.Contains(t, buf2.String(), "Tool Cancelled: slow-tool: context canceled")
+
+	// Test OnRetry
+	fanout.OnRetry(context.Background(), &assistants.RetryEvent{Assistant: ast, Reason: assistants.RetryReasonEmptyResponse, Attempt: 1})
+	assert.Contains(t, buf1.String(), "Assistant Retry: test-assistant: empty_response, attempt 1")
+	assert.Contains(t, buf2.String(), "Assistant Retry: test-assistant: empty_response, attempt 1")
+
+	// Test OnRateLimit
+	fanout.OnRateLimit(context.Background(), &assistants.RateLimitEvent{Assistant: ast, LLM: &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, Attempt: 2, Wait: time.Second})
+	assert.Contains(t, buf1.String(), "Assistant Rate Limit: test-assistant: gpt-4o model, attempt 2, wait 1s")
+	assert.Contains(t, buf2.String(), "Assistant Rate Limit: test-assistant: gpt-4o model, attempt 2, wait 1s")
+
+	// Test OnMessageOrderRepair
+	fanout.OnMessageOrderRepair(context.Background(), &assistants.MessageOrderRepairEvent{Assistant: ast, LLM: &fakeModel{name: "claude", provider: llms.ProviderAnthropic},
+		Repair: llms.MessageOrderRepair{MergedMessages: 2, InsertedMessages: 1}})
+	assert.Contains(t, buf1.String(), "Assistant Message Order Repair: test-assistant: claude model, merged 2, inserted 1")
+	assert.Contains(t, buf2.String(), "Assistant Message Order Repair: test-assistant: claude model, merged 2, inserted 1")
+
 	// Test OnAssistantLLMParseError
-	fanout.OnAssistantLLMParseError(context.Background(), ast, "test input", "test response", errors.New("parse error"))
+	fanout.OnAssistantLLMParseError(context.Background(), &assistants.LLMParseErrorEvent{Assistant: ast, Input: "test input", Response: "test response", Err: errors.New("parse error")})
 	assert.Contains(t, buf1.String(), "Assistant LLM Parse Error: test-assistant")
 	assert.Contains(t, buf2.String(), "Assistant LLM Parse Error: test-assistant")
 
@@ -253,25 +395,78 @@ func TestFanoutCallback(t *testing.T) {
 	var buf3 bytes.Buffer
 	cb3 := callbacks.NewPrinter(&buf3, callbacks.ModeVerbose)
 	fanout.Add(cb3)
-	fanout.OnAssistantStart(context.Background(), ast, "test input")
+	fanout.OnAssistantStart(context.Background(), &assistants.AssistantStartEvent{Assistant: ast, Input: "test input"})
 	assert.Contains(t, buf3.String(), "Assistant Start: test-assistant")
 }
 
+type panicCallback struct {
+	callbacks.Noop
+}
+
+func (p *panicCallback) OnAssistantStart(context.Context, *assistants.AssistantStartEvent) {
+	panic("faulty handler")
+}
+
+func (p *panicCallback) OnToolBatchStart(context.Context, *assistants.ToolBatchEvent) {
+	panic("faulty handler")
+}
+
+func TestMultiCallback(t *testing.T) {
+	var buf1, buf2 bytes.Buffer
+	cb1 := callbacks.NewPrinter(&buf1, callbacks.ModeVerbose)
+	cb2 := callbacks.NewPrinter(&buf2, callbacks.ModeVerbose)
+
+	// the nested callbacks are flattened, and nil is skipped
+	multi := callbacks.Multi(&panicCallback{}, callbacks.Multi(cb1, nil), cb2)
+
+	ast := &fakeAssistant{name: "test-assistant"}
+	assert.NotPanics(t, func() {
+		multi.OnAssistantStart(context.Background(), &assistants.AssistantStartEvent{Assistant: ast, Input: "test input"})
+	})
+	assert.Contains(t, buf1.String(), "Assistant Start: test-assistant")
+	assert.Contains(t, buf2.String(), "Assistant Start: test-assistant")
+
+	// the optional interfaces are forwarded to the callbacks implementing them
+	batchCb, ok := multi.(assistants.ToolBatchCallback)
+	if assert.True(t, ok) {
+		batch := &assistants.ToolBatch{
+			ID:        "batch_1",
+			Assistant: "test-assistant",
+			Calls:     []assistants.ToolBatchCall{{ID: "call_1", Name: "search", Status: assistants.ToolCallPending}},
+		}
+		assert.NotPanics(t, func() {
+			batchCb.OnToolBatchStart(context.Background(), &assistants.ToolBatchEvent{Assistant: ast, Batch: batch})
+		})
+		assert.Contains(t, buf1.String(), "Tool Batch Start: batch_1 (test-assistant): 1 calls")
+		assert.Contains(t, buf2.String(), "Tool Batch Start: batch_1 (test-assistant): 1 calls")
+	}
+
+	rc, ok := multi.(assistants.RecoveryCallback)
+	if assert.True(t, ok) {
+		rc.OnRetry(context.Background(), &assistants.RetryEvent{Assistant: ast, Reason: assistants.RetryReasonEmptyResponse, Attempt: 1})
+		assert.Contains(t, buf1.String(), "Assistant Retry: test-assistant: empty_response, attempt 1")
+		assert.Contains(t, buf2.String(), "Assistant Retry: test-assistant: empty_response, attempt 1")
+	}
+}
+
 func TestNoopCallback(t *testing.T) {
 	noop := callbacks.NewNoop()
 	ast := &fakeAssistant{name: "test-assistant"}
 	tool := &fakeTool{name: "test-tool"}
 
 	// Test all methods - they should not panic
-	noop.OnAssistantStart(context.Background(), ast, "test input")
-	noop.OnAssistantEnd(context.Background(), ast, "test input", &assistants.Response{}, llms.Messages{})
-	noop.OnAssistantError(context.Background(), ast, "test input", errors.New("test error"), llms.Messages{})
-	noop.OnAssistantLLMParseError(context.Background(), ast, "test input", "test response", errors.New("parse error"))
-	noop.OnToolStart(context.Background(), tool, "test-assistant", "test input")
-	noop.OnToolEnd(context.Background(), tool, "test-assistant", "test input", "test output")
-	noop.OnToolError(context.Background(), tool, "test-assistant", "test input", errors.New("test error"))
-	noop.OnAssistantLLMCallStart(context.Background(), ast, &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, []llms.Message{})
-	noop.OnToolNotFound(context.Background(), ast, "missing-tool")
+	noop.OnAssistantStart(context.Background(), &assistants.AssistantStartEvent{Assistant: ast, Input: "test input"})
+	noop.OnAssistantEnd(context.Background(), &assistants.AssistantEndEvent{Assistant: ast, Input: "test input", Response: &assistants.Response{}})
+	noop.OnAssistantError(context.Background(), &assistants.AssistantErrorEvent{Assistant: ast, Input: "test input", Err: errors.New("test error")})
+	noop.OnAssistantLLMParseError(context.Background(), &assistants.LLMParseErrorEvent{Assistant: ast, Input: "test input", Response: "test response", Err: errors.New("parse error")})
+	noop.OnToolStart(context.Background(), &tools.ToolStartEvent{Tool: tool, Assistant: "test-assistant", Input: "test input"})
+	noop.OnToolEnd(context.Background(), &tools.ToolEndEvent{Tool: tool, Assistant: "test-assistant", Input: "test input", Output: "test output"})
+	noop.OnToolError(context.Background(), &tools.ToolErrorEvent{Tool: tool, Assistant: "test-assistant", Input: "test input", Err: errors.New("test error")})
+	noop.OnAssistantLLMCallStart(context.Background(), &assistants.LLMCallStartEvent{Assistant: ast, LLM: &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}})
+	noop.OnToolNotFound(context.Background(), &assistants.ToolNotFoundEvent{Assistant: ast, Tool: "missing-tool"})
+	noop.OnToolCancelled(context.Background(), &assistants.ToolCancelledEvent{Assistant: ast, Tool: "slow-tool", Input: "test input", Cause: context.Canceled})
+	noop.OnRetry(context.Background(), &assistants.RetryEvent{Assistant: ast, Reason: assistants.RetryReasonParseError, Attempt: 1, Err: errors.New("parse error")})
+	noop.OnRateLimit(context.Background(), &assistants.RateLimitEvent{Assistant: ast, LLM: &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, Attempt: 1, Wait: time.Second})
 }
 
 type fakeAssistant struct {
@@ -329,6 +524,15 @@ func (f *fakeTool) Call(context.Context, string) (string, error) {
 	return "", nil
 }
 
+type sensitiveTool struct {
+	fakeTool
+	params *jsonschema.Schema
+}
+
+func (f *sensitiveTool) Parameters() *jsonschema.Schema {
+	return f.params
+}
+
 type fakeModel struct {
 	name     string
 	provider llms.ProviderType
@@ -0,0 +1,43 @@
+package callbacks
+
+import (
+	"context"
+
+	"github.com/effective-security/gogentic/assistants"
+)
+
+var (
+	_ assistants.FallbackCallback = (*Noop)(nil)
+	_ assistants.FallbackCallback = (*Printer)(nil)
+	_ assistants.FallbackCallback = (*Fanout)(nil)
+)
+
+func (l *Fanout) OnModelFallback(ctx context.Context, e *assistants.ModelFallbackEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.FallbackCallback); ok {
+			cb.OnModelFallback(ctx, e)
+		}
+	}
+}
+
+func (l *Noop) OnModelFallback(ctx context.Context, e *assistants.ModelFallbackEvent) {
+}
+
+func (l *Printer) OnModelFallback(ctx context.Context, e *assistants.ModelFallbackEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		r := &PrintRecord{
+			Event:     "assistant_model_fallback",
+			Assistant: e.Assistant.Name(),
+			Model:     e.Failed.GetName(),
+			Details:   map[string]any{"next": e.Next.GetName()},
+		}
+		if e.Err != nil {
+			r.Error = l.Redactor.String(e.Err.Error())
+		}
+		l.record(r)
+		return
+	}
+	l.printf(colorYellow, "Model Fallback", "%s -> %s: %v", e.Failed.GetName(), e.Next.GetName(), e.Err)
+}
@@ -0,0 +1,107 @@
+package callbacks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/effective-security/gogentic/assistants"
+)
+
+var (
+	_ assistants.PlannerCallback = (*Noop)(nil)
+	_ assistants.PlannerCallback = (*Printer)(nil)
+	_ assistants.PlannerCallback = (*Fanout)(nil)
+)
+
+func (l *Fanout) OnPlanCreated(ctx context.Context, e *assistants.PlanCreatedEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.PlannerCallback); ok {
+			cb.OnPlanCreated(ctx, e)
+		}
+	}
+}
+
+func (l *Fanout) OnPlanStepStart(ctx context.Context, e *assistants.PlanStepStartEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.PlannerCallback); ok {
+			cb.OnPlanStepStart(ctx, e)
+		}
+	}
+}
+
+func (l *Fanout) OnPlanStepEnd(ctx context.Context, e *assistants.PlanStepEndEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.PlannerCallback); ok {
+			cb.OnPlanStepEnd(ctx, e)
+		}
+	}
+}
+
+func (l *Noop) OnPlanCreated(ctx context.Context, e *assistants.PlanCreatedEvent) {
+}
+func (l *Noop) OnPlanStepStart(ctx context.Context, e *assistants.PlanStepStartEvent) {
+}
+func (l *Noop) OnPlanStepEnd(ctx context.Context, e *assistants.PlanStepEndEvent) {
+}
+
+func (l *Printer) OnPlanCreated(ctx context.Context, e *assistants.PlanCreatedEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "plan_created",
+			Assistant: e.Assistant.Name(),
+			Details: map[string]any{
+				"revision": e.Revision,
+				"goal":     e.Plan.Goal,
+				"steps":    e.Plan.Steps,
+			},
+		})
+		return
+	}
+	l.printf(colorBlue, "Plan Created", "%s: revision %d: %s", e.Assistant.Name(), e.Revision, e.Plan.Goal)
+	for _, step := range e.Plan.Steps {
+		_, _ = fmt.Fprintf(l.Out, "  %d. %s", step.ID, step.Description)
+		if step.Executor != "" {
+			_, _ = fmt.Fprintf(l.Out, " [%s]", step.Executor)
+		}
+		_, _ = fmt.Fprintln(l.Out)
+	}
+}
+
+func (l *Printer) OnPlanStepStart(ctx context.Context, e *assistants.PlanStepStartEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "plan_step_start",
+			Assistant: e.Assistant.Name(),
+			Details:   map[string]any{"step": e.Step},
+		})
+		return
+	}
+	l.printf(colorBlue, "Plan Step Start", "%s: %d. %s", e.Assistant.Name(), e.Step.ID, e.Step.Description)
+}
+
+func (l *Printer) OnPlanStepEnd(ctx context.Context, e *assistants.PlanStepEndEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "plan_step_end",
+			Assistant: e.Assistant.Name(),
+			Output:    l.verbose(e.Result.Output),
+			Error:     e.Result.Error,
+			Details:   map[string]any{"step": e.Result.Step},
+		})
+		return
+	}
+	if e.Result.Error != "" {
+		l.printf(colorRed, "Plan Step Failed", "%s: %d: %s", e.Assistant.Name(), e.Result.Step.ID, e.Result.Error)
+		return
+	}
+	l.printf(colorBlue, "Plan Step End", "%s: %d", e.Assistant.Name(), e.Result.Step.ID)
+	if l.Mode == ModeVerbose {
+		_, _ = fmt.Fprintf(l.Out, "Output: %s\n", e.Result.Output)
+	}
+}
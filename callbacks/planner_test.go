@@ -0,0 +1,47 @@
+package callbacks_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlannerPrinter(t *testing.T) {
+	var buf1, buf2 bytes.Buffer
+	cb := callbacks.NewFanout(
+		callbacks.NewPrinter(&buf1, callbacks.ModeVerbose),
+		callbacks.NewPrinter(&buf2, callbacks.ModeDefault),
+		callbacks.NewNoop(),
+	)
+
+	ctx := context.Background()
+	ast := &fakeAssistant{name: "planner"}
+	plan := &assistants.Plan{
+		Goal: "weather report",
+		Steps: []assistants.PlanStep{
+			{ID: 1, Description: "get weather", Executor: "weather"},
+			{ID: 2, Description: "write report"},
+		},
+	}
+	cb.OnPlanCreated(ctx, &assistants.PlanCreatedEvent{Assistant: ast, Plan: plan})
+	cb.OnPlanStepStart(ctx, &assistants.PlanStepStartEvent{Assistant: ast, Step: &plan.Steps[0]})
+	cb.OnPlanStepEnd(ctx, &assistants.PlanStepEndEvent{Assistant: ast, Result: &assistants.PlanStepResult{Step: plan.Steps[0], Output: "rainy"}})
+	cb.OnPlanStepStart(ctx, &assistants.PlanStepStartEvent{Assistant: ast, Step: &plan.Steps[1]})
+	cb.OnPlanStepEnd(ctx, &assistants.PlanStepEndEvent{Assistant: ast, Result: &assistants.PlanStepResult{Step: plan.Steps[1], Error: "failed"}})
+
+	exp := `Plan Created: planner: revision 0: weather report
+  1. get weather [weather]
+  2. write report
+Plan Step Start: planner: 1. get weather
+Plan Step End: planner: 1
+Output: rainy
+Plan Step Start: planner: 2. write report
+Plan Step Failed: planner: 2: failed
+`
+	assert.Equal(t, exp, buf1.String())
+	assert.NotContains(t, buf2.String(), "Output: rainy")
+}
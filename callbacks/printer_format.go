@@ -0,0 +1,146 @@
+package callbacks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Format defines the output format of the Printer
+type Format int
+
+const (
+	// FormatText prints the events as the human readable text
+	FormatText Format = iota
+	// FormatJSON prints each event as the indented JSON object, see PrintRecord
+	FormatJSON
+	// FormatNDJSON prints each event as the JSON object on a single line,
+	// for the log pipelines, see PrintRecord
+	FormatNDJSON
+)
+
+// PrintRecord is the event printed by the Printer in the JSON formats.
+type PrintRecord struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Assistant string    `json:"assistant,omitempty"`
+	Tool      string    `json:"tool,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	CallID    string    `json:"call_id,omitempty"`
+	Attempt   int       `json:"attempt,omitempty"`
+	Round     int       `json:"round,omitempty"`
+	Input     string    `json:"input,omitempty"`
+	// Output is printed in ModeVerbose only
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// DurationMS is the latency of the call in milliseconds
+	DurationMS float64 `json:"duration_ms,omitempty"`
+	// Details are the fields specific to the event
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// color is the ANSI escape sequence of the text color
+type color string
+
+const (
+	colorNone   color = ""
+	colorRed    color = "\x1b[31m"
+	colorGreen  color = "\x1b[32m"
+	colorYellow color = "\x1b[33m"
+	colorBlue   color = "\x1b[34m"
+	colorCyan   color = "\x1b[36m"
+	colorReset        = "\x1b[0m"
+)
+
+// WithFormat sets the output format, FormatText by default.
+func (l *Printer) WithFormat(f Format) *Printer {
+	l.Format = f
+	return l
+}
+
+// WithTimestamps enables the timestamps in FormatText,
+// the JSON formats always include the time of the event.
+func (l *Printer) WithTimestamps(enabled bool) *Printer {
+	l.Timestamps = enabled
+	return l
+}
+
+// WithColor enables or disables the colors in FormatText,
+// by default the colors are enabled when the output is a terminal.
+func (l *Printer) WithColor(enabled bool) *Printer {
+	l.Color = enabled
+	return l
+}
+
+// structured returns true if the events are printed as JSON
+func (l *Printer) structured() bool {
+	return l.Format == FormatJSON || l.Format == FormatNDJSON
+}
+
+// verbose returns the value in ModeVerbose, or empty string otherwise
+func (l *Printer) verbose(s string) string {
+	if l.Mode != ModeVerbose {
+		return ""
+	}
+	return s
+}
+
+// record prints the event in the JSON format, the caller must hold the lock.
+func (l *Printer) record(r *PrintRecord) {
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	enc := json.NewEncoder(l.Out)
+	enc.SetEscapeHTML(false)
+	if l.Format == FormatJSON {
+		enc.SetIndent("", "  ")
+	}
+	_ = enc.Encode(r)
+}
+
+// printf prints the title of the event followed by the formatted text,
+// the caller must hold the lock.
+func (l *Printer) printf(c color, title, format string, args ...any) {
+	if l.Timestamps {
+		_, _ = fmt.Fprintf(l.Out, "%s ", time.Now().Format("15:04:05.000"))
+	}
+	if l.Color && c != colorNone {
+		title = string(c) + title + colorReset
+	}
+	_, _ = fmt.Fprintf(l.Out, title+": "+format+"\n", args...)
+}
+
+// durationMS returns the duration in milliseconds
+func durationMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// inDuration returns the suffix for the text with the duration, if known
+func inDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	if d >= time.Millisecond {
+		d = d.Round(time.Millisecond)
+	}
+	return " in " + d.String()
+}
+
+// isColorTerminal returns true if the writer is a terminal,
+// and the colors are not disabled by NO_COLOR or TERM=dumb environment variables.
+func isColorTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || f == nil {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,49 @@
+package callbacks
+
+import (
+	"context"
+
+	"github.com/effective-security/gogentic/assistants"
+)
+
+var (
+	_ assistants.PromptVariantCallback = (*Noop)(nil)
+	_ assistants.PromptVariantCallback = (*Printer)(nil)
+	_ assistants.PromptVariantCallback = (*Fanout)(nil)
+)
+
+func (l *Fanout) OnAssistantPromptVariant(ctx context.Context, e *assistants.PromptVariantEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.PromptVariantCallback); ok {
+			cb.OnAssistantPromptVariant(ctx, e)
+		}
+	}
+}
+
+func (l *Noop) OnAssistantPromptVariant(ctx context.Context, e *assistants.PromptVariantEvent) {
+}
+
+func (l *Printer) OnAssistantPromptVariant(ctx context.Context, e *assistants.PromptVariantEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		r := &PrintRecord{
+			Event:     "assistant_prompt_variant",
+			Assistant: e.Assistant.Name(),
+			Details: map[string]any{
+				"name":    e.Variant.Name,
+				"version": e.Variant.Version,
+			},
+		}
+		if e.Err != nil {
+			r.Error = e.Err.Error()
+		}
+		l.record(r)
+		return
+	}
+	if e.Err != nil {
+		l.printf(colorRed, "Prompt Variant Failed", "%s: %s@%s: %s", e.Assistant.Name(), e.Variant.Name, e.Variant.Version, e.Err.Error())
+		return
+	}
+	l.printf(colorGreen, "Prompt Variant Succeeded", "%s: %s@%s", e.Assistant.Name(), e.Variant.Name, e.Variant.Version)
+}
@@ -17,7 +17,10 @@ import (
 )
 
 // ensure ScratchpadCallback implements assistants.Callback
-var _ assistants.Callback = (*Scratchpad)(nil)
+var (
+	_ assistants.Callback         = (*Scratchpad)(nil)
+	_ assistants.RecoveryCallback = (*Scratchpad)(nil)
+)
 
 var TimeNowFn = time.Now
 
@@ -35,6 +38,9 @@ type RunStats struct {
 	ToolsCallsSucceeded     uint32
 	ToolsCallsFailed        uint32
 	ToolNotFound            uint32
+	ToolsCallsCancelled     uint32
+	LLMRetries              uint32
+	LLMRateLimited          uint32
 }
 
 // ScratchpadCallback is a callback handler that prints to the Writer.
@@ -88,11 +94,18 @@ func (l *Scratchpad) EndRun(ctx context.Context) (*RunStats, []byte) {
 		stats.AssistantCalls,
 		stats.AssistantCallsFailed,
 	))
-	run.printEntry(fmt.Sprintf("Tool calls: %d, Failed: %d, Not Found: %d",
+	run.printEntry(fmt.Sprintf("Tool calls: %d, Failed: %d, Not Found: %d, Cancelled: %d",
 		stats.ToolsCalls,
 		stats.ToolsCallsFailed,
 		stats.ToolNotFound,
+		stats.ToolsCallsCancelled,
 	))
+	if stats.LLMRetries > 0 || stats.LLMRateLimited > 0 {
+		run.printEntry(fmt.Sprintf("LLM retries: %d, Rate limited: %d",
+			stats.LLMRetries,
+			stats.LLMRateLimited,
+		))
+	}
 	run.printEntry(fmt.Sprintf("LLM calls: %d, Messages: %d, Bytes Out: %d, Bytes In: %d, Bytes Total: %d, Input Tokens: %d, Output Tokens: %d, Total Tokens: %d",
 		stats.Usage.LlmCallCount,
 		stats.TotalMessages,
@@ -127,7 +140,7 @@ func (l *Scratchpad) getRun(ctx context.Context) *run {
 	return l.runs[chatCtx.GetChatID()]
 }
 
-func (l *Scratchpad) OnAssistantStart(ctx context.Context, assistant assistants.IAssistant, input string) {
+func (l *Scratchpad) OnAssistantStart(ctx context.Context, e *assistants.AssistantStartEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -136,18 +149,18 @@ func (l *Scratchpad) OnAssistantStart(ctx context.Context, assistant assistants.
 	defer run.lock.Unlock()
 
 	atomic.AddUint32(&run.stats.AssistantCalls, 1)
-	name := assistant.Name()
+	name := e.Assistant.Name()
 	actionID := chatmodel.GetActionID(ctx)
 	run.printEntry(actionID, name, "*** Assistant Start ***")
-	skillList := assistant.GetSkills()
+	skillList := e.Assistant.GetSkills()
 	if len(skillList) > 0 {
 		run.printEntry(actionID, name, "Skills:", strings.Join(skillList.Names(), ", "))
 	}
 	run.printEntry(actionID, name, "Input:")
-	run.printNewLine(input)
+	run.printNewLine(e.Input)
 }
 
-func (l *Scratchpad) OnAssistantEnd(ctx context.Context, assistant assistants.IAssistant, input string, resp *assistants.Response, messageHistory llms.Messages) {
+func (l *Scratchpad) OnAssistantEnd(ctx context.Context, e *assistants.AssistantEndEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -156,30 +169,30 @@ func (l *Scratchpad) OnAssistantEnd(ctx context.Context, assistant assistants.IA
 	defer run.lock.Unlock()
 
 	atomic.AddUint32(&run.stats.AssistantCallsSucceeded, 1)
-	// NOTE: usage is intentionally NOT accumulated here. resp.Usage is the
+	// NOTE: usage is intentionally NOT accumulated here. e.Response.Usage is the
 	// aggregated subtree total (it already includes nested assistants/tools
 	// invoked via tool.CallAssistant), so adding it per assistant would double
 	// count when the callback is propagated to nested assistants. Usage is
 	// accumulated at the LLM-call boundary instead (OnAssistantLLMCallStart and
 	// OnAssistantLLMCallEnd), where each call is counted exactly once.
 
-	name := assistant.Name()
+	name := e.Assistant.Name()
 	actionID := chatmodel.GetActionID(ctx)
 	if l.mode == ModeVerbose {
 		run.printEntry(actionID, name, "Assistant Output:")
-		for _, choice := range resp.Choices {
+		for _, choice := range e.Response.Choices {
 			if choice.Content != "" {
 				run.printNewLine(choice.Content)
 			}
 		}
 	}
 	if l.mode == ModeVerbose {
-		run.printEntry(actionID, name, l.printMessages(messageHistory))
+		run.printEntry(actionID, name, l.printMessages(e.Messages))
 	}
 	run.printEntry(actionID, name, "*** Assistant End ***")
 }
 
-func (l *Scratchpad) OnAssistantError(ctx context.Context, assistant assistants.IAssistant, input string, err error, messageHistory llms.Messages) {
+func (l *Scratchpad) OnAssistantError(ctx context.Context, e *assistants.AssistantErrorEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -188,10 +201,10 @@ func (l *Scratchpad) OnAssistantError(ctx context.Context, assistant assistants.
 	defer run.lock.Unlock()
 
 	atomic.AddUint32(&run.stats.AssistantCallsFailed, 1)
-	name := assistant.Name()
+	name := e.Assistant.Name()
 	actionID := chatmodel.GetActionID(ctx)
-	run.printEntry(actionID, name, "*** Error ***", err.Error())
-	run.printEntry(actionID, name, l.printMessages(messageHistory))
+	run.printEntry(actionID, name, "*** Error ***", e.Err.Error())
+	run.printEntry(actionID, name, l.printMessages(e.Messages))
 }
 
 func (l *Scratchpad) printMessages(messages []llms.Message) string {
@@ -254,7 +267,7 @@ func stringUpto(s string, maxLen int) string {
 	return normalized
 }
 
-func (l *Scratchpad) OnAssistantLLMCallStart(ctx context.Context, agent assistants.IAssistant, llm llms.Model, payload []llms.Message) {
+func (l *Scratchpad) OnAssistantLLMCallStart(ctx context.Context, e *assistants.LLMCallStartEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -262,24 +275,24 @@ func (l *Scratchpad) OnAssistantLLMCallStart(ctx context.Context, agent assistan
 	run.lock.Lock()
 	defer run.lock.Unlock()
 
-	count := uint32(len(payload))
+	count := uint32(len(e.Messages))
 	atomic.AddUint32(&run.stats.TotalMessages, count)
 
 	// Accumulate at the LLM-call boundary so each call is counted exactly once,
 	// regardless of nesting depth or whether the callback is propagated to
 	// nested assistants. This mirrors the accounting done in Assistant.run.
 	run.stats.Usage.LlmCallCount++
-	run.stats.Usage.BytesOut += llmutils.CountMessagesContentSize(payload)
+	run.stats.Usage.BytesOut += llmutils.CountMessagesContentSize(e.Messages)
 
-	name := agent.Name()
+	name := e.Assistant.Name()
 	actionID := chatmodel.GetActionID(ctx)
-	run.printEntry(actionID, name, "*** LLM Call ***", fmt.Sprintf("%s model, %d messages", llm.GetName(), count))
+	run.printEntry(actionID, name, "*** LLM Call ***", fmt.Sprintf("%s model, %d messages", e.LLM.GetName(), count))
 	if l.mode == ModeVerbose {
-		run.printEntry(actionID, name, l.printMessages(payload))
+		run.printEntry(actionID, name, l.printMessages(e.Messages))
 	}
 }
 
-func (l *Scratchpad) OnAssistantLLMCallEnd(ctx context.Context, agent assistants.IAssistant, llm llms.Model, resp *llms.ContentResponse) {
+func (l *Scratchpad) OnAssistantLLMCallEnd(ctx context.Context, e *assistants.LLMCallEndEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -287,19 +300,19 @@ func (l *Scratchpad) OnAssistantLLMCallEnd(ctx context.Context, agent assistants
 	run.lock.Lock()
 	defer run.lock.Unlock()
 
-	stats := resp.Usage()
+	stats := e.Response.Usage()
 	// Accumulate token usage and received bytes once per LLM call. stats only
 	// carries token fields (no BytesIn/LlmCallCount), so we add BytesIn here and
 	// the call count is incremented in OnAssistantLLMCallStart.
 	run.stats.Usage.Usage.Add(stats)
-	run.stats.Usage.BytesIn += resp.ContentSize()
+	run.stats.Usage.BytesIn += e.Response.ContentSize()
 
 	actionID := chatmodel.GetActionID(ctx)
-	run.printEntry(actionID, agent.Name(), "*** LLM Call End ***", fmt.Sprintf("%s model, %d input tokens, %d output tokens, %d total tokens",
-		llm.GetName(), stats.InputTokens, stats.OutputTokens, stats.TotalTokens))
+	run.printEntry(actionID, e.Assistant.Name(), "*** LLM Call End ***", fmt.Sprintf("%s model, %d input tokens, %d output tokens, %d total tokens",
+		e.LLM.GetName(), stats.InputTokens, stats.OutputTokens, stats.TotalTokens))
 }
 
-func (l *Scratchpad) OnAssistantLLMParseError(ctx context.Context, assistant assistants.IAssistant, input string, response string, err error) {
+func (l *Scratchpad) OnAssistantLLMParseError(ctx context.Context, e *assistants.LLMParseErrorEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -308,13 +321,13 @@ func (l *Scratchpad) OnAssistantLLMParseError(ctx context.Context, assistant ass
 	defer run.lock.Unlock()
 
 	atomic.AddUint32(&run.stats.AssistantCallsFailed, 1)
-	name := assistant.Name()
+	name := e.Assistant.Name()
 	actionID := chatmodel.GetActionID(ctx)
-	run.printEntry(actionID, name, "*** LLM Parse Error ***", err.Error())
-	run.printEntry(actionID, name, " Response:", response)
+	run.printEntry(actionID, name, "*** LLM Parse Error ***", e.Err.Error())
+	run.printEntry(actionID, name, " Response:", e.Response)
 }
 
-func (l *Scratchpad) OnToolStart(ctx context.Context, tool tools.ITool, assistantName, input string) {
+func (l *Scratchpad) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -322,15 +335,20 @@ func (l *Scratchpad) OnToolStart(ctx context.Context, tool tools.ITool, assistan
 	run.lock.Lock()
 	defer run.lock.Unlock()
 
-	atomic.AddUint32(&run.stats.ToolsCalls, 1)
-	tname := tool.Name()
+	tname := e.Tool.Name()
 	actionID := chatmodel.GetActionID(ctx)
-	run.printEntry(actionID, assistantName, tname, "*** Tool Start ***")
-	run.printEntry(actionID, assistantName, tname, "Tool Input:")
-	run.printNewLine(input)
+	if e.Attempt > 1 {
+		// the retry of the call is not counted as the new call
+		run.printEntry(actionID, e.Assistant, tname, "*** Tool Start ***", fmt.Sprintf("attempt %d", e.Attempt))
+	} else {
+		atomic.AddUint32(&run.stats.ToolsCalls, 1)
+		run.printEntry(actionID, e.Assistant, tname, "*** Tool Start ***")
+	}
+	run.printEntry(actionID, e.Assistant, tname, "Tool Input:")
+	run.printNewLine(e.Input)
 }
 
-func (l *Scratchpad) OnToolEnd(ctx context.Context, tool tools.ITool, assistantName, input string, output string) {
+func (l *Scratchpad) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -339,17 +357,18 @@ func (l *Scratchpad) OnToolEnd(ctx context.Context, tool tools.ITool, assistantN
 	defer run.lock.Unlock()
 
 	atomic.AddUint32(&run.stats.ToolsCallsSucceeded, 1)
-	tname := tool.Name()
+	tname := e.Tool.Name()
 	actionID := chatmodel.GetActionID(ctx)
-	run.printEntry(actionID, assistantName, tname, "Tool Output:")
+	run.printEntry(actionID, e.Assistant, tname, "Tool Output:")
+	output := e.Output
 	if l.mode != ModeVerbose {
 		output = stringUpto(output, 160)
 	}
 	run.printNewLine(output)
-	run.printEntry(actionID, assistantName, tname, "*** Tool End ***")
+	run.printEntry(actionID, e.Assistant, tname, "*** Tool End ***")
 }
 
-func (l *Scratchpad) OnToolError(ctx context.Context, tool tools.ITool, assistantName, input string, err error) {
+func (l *Scratchpad) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -358,12 +377,12 @@ func (l *Scratchpad) OnToolError(ctx context.Context, tool tools.ITool, assistan
 	defer run.lock.Unlock()
 
 	atomic.AddUint32(&run.stats.ToolsCallsFailed, 1)
-	tname := tool.Name()
+	tname := e.Tool.Name()
 	actionID := chatmodel.GetActionID(ctx)
-	run.printEntry(actionID, assistantName, tname, "*** Tool Error ***", err.Error())
+	run.printEntry(actionID, e.Assistant, tname, "*** Tool Error ***", e.Err.Error())
 }
 
-func (l *Scratchpad) OnToolNotFound(ctx context.Context, agent assistants.IAssistant, tool string) {
+func (l *Scratchpad) OnToolNotFound(ctx context.Context, e *assistants.ToolNotFoundEvent) {
 	run := l.getRun(ctx)
 	if run == nil {
 		return
@@ -373,7 +392,50 @@ func (l *Scratchpad) OnToolNotFound(ctx context.Context, agent assistants.IAssis
 
 	atomic.AddUint32(&run.stats.ToolNotFound, 1)
 	actionID := chatmodel.GetActionID(ctx)
-	run.printEntry(actionID, agent.Name(), "*** Tool Not Found ***", tool)
+	run.printEntry(actionID, e.Assistant.Name(), "*** Tool Not Found ***", e.Tool)
+}
+
+func (l *Scratchpad) OnToolCancelled(ctx context.Context, e *assistants.ToolCancelledEvent) {
+	run := l.getRun(ctx)
+	if run == nil {
+		return
+	}
+	run.lock.Lock()
+	defer run.lock.Unlock()
+
+	atomic.AddUint32(&run.stats.ToolsCallsCancelled, 1)
+	actionID := chatmodel.GetActionID(ctx)
+	run.printEntry(actionID, e.Assistant.Name(), e.Tool, "*** Tool Cancelled ***", e.Cause.Error())
+}
+
+func (l *Scratchpad) OnRetry(ctx context.Context, e *assistants.RetryEvent) {
+	run := l.getRun(ctx)
+	if run == nil {
+		return
+	}
+	run.lock.Lock()
+	defer run.lock.Unlock()
+
+	atomic.AddUint32(&run.stats.LLMRetries, 1)
+	actionID := chatmodel.GetActionID(ctx)
+	msg := fmt.Sprintf("%s, attempt %d", e.Reason, e.Attempt)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	run.printEntry(actionID, e.Assistant.Name(), "*** LLM Retry ***", msg)
+}
+
+func (l *Scratchpad) OnRateLimit(ctx context.Context, e *assistants.RateLimitEvent) {
+	run := l.getRun(ctx)
+	if run == nil {
+		return
+	}
+	run.lock.Lock()
+	defer run.lock.Unlock()
+
+	atomic.AddUint32(&run.stats.LLMRateLimited, 1)
+	actionID := chatmodel.GetActionID(ctx)
+	run.printEntry(actionID, e.Assistant.Name(), "*** LLM Rate Limit ***", fmt.Sprintf("%s model, attempt %d, wait %s", e.LLM.GetName(), e.Attempt, e.Wait))
 }
 
 type run struct {
@@ -134,11 +134,11 @@ func TestScratchpad_OnCallbacks(t *testing.T) {
 	}
 
 	// Test various callbacks
-	sp.OnAssistantStart(ctx, ast, "input")
-	sp.OnAssistantLLMCallStart(ctx, ast, &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, []llms.Message{
+	sp.OnAssistantStart(ctx, &assistants.AssistantStartEvent{Assistant: ast, Input: "input"})
+	sp.OnAssistantLLMCallStart(ctx, &assistants.LLMCallStartEvent{Assistant: ast, LLM: &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, Messages: []llms.Message{
 		{Source: src, Role: llms.RoleHuman, Parts: []llms.ContentPart{llms.TextContent{Text: "foo"}}},
-	})
-	sp.OnAssistantLLMCallEnd(ctx, ast, &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, &llms.ContentResponse{
+	}})
+	sp.OnAssistantLLMCallEnd(ctx, &assistants.LLMCallEndEvent{Assistant: ast, LLM: &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, Response: &llms.ContentResponse{
 		Choices: []*llms.ContentChoice{
 			{
 				Content: "Answer 1",
@@ -149,16 +149,19 @@ func TestScratchpad_OnCallbacks(t *testing.T) {
 				},
 			},
 		},
-	})
-	sp.OnAssistantLLMParseError(ctx, ast, "input", "output", errors.New("parseerr"))
-	sp.OnAssistantError(ctx, ast, "input", errors.New("fail"), []llms.Message{
+	}})
+	sp.OnAssistantLLMParseError(ctx, &assistants.LLMParseErrorEvent{Assistant: ast, Input: "input", Response: "output", Err: errors.New("parseerr")})
+	sp.OnAssistantError(ctx, &assistants.AssistantErrorEvent{Assistant: ast, Input: "input", Err: errors.New("fail"), Messages: []llms.Message{
 		{Source: src, Role: llms.RoleHuman, Parts: []llms.ContentPart{llms.TextContent{Text: "foo"}}},
-	})
-	sp.OnToolStart(ctx, tool, "A1", "tinput")
-	sp.OnToolEnd(ctx, tool, "A1", "tinput", "toutput")
-	sp.OnToolError(ctx, tool, "A1", "tinput", errors.New("terr"))
-	sp.OnToolNotFound(ctx, ast, "T2")
-	sp.OnAssistantEnd(ctx, ast, "input", resp, resp.Messages)
+	}})
+	sp.OnToolStart(ctx, &tools.ToolStartEvent{Tool: tool, Assistant: "A1", Input: "tinput"})
+	sp.OnToolEnd(ctx, &tools.ToolEndEvent{Tool: tool, Assistant: "A1", Input: "tinput", Output: "toutput"})
+	sp.OnToolError(ctx, &tools.ToolErrorEvent{Tool: tool, Assistant: "A1", Input: "tinput", Err: errors.New("terr")})
+	sp.OnToolNotFound(ctx, &assistants.ToolNotFoundEvent{Assistant: ast, Tool: "T2"})
+	sp.OnToolCancelled(ctx, &assistants.ToolCancelledEvent{Assistant: ast, Tool: "T3", Input: "tinput", Cause: errors.New("cancelled")})
+	sp.OnRetry(ctx, &assistants.RetryEvent{Assistant: ast, Reason: assistants.RetryReasonParseError, Attempt: 1, Err: errors.New("parseerr")})
+	sp.OnRateLimit(ctx, &assistants.RateLimitEvent{Assistant: ast, LLM: &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, Attempt: 1, Wait: time.Second})
+	sp.OnAssistantEnd(ctx, &assistants.AssistantEndEvent{Assistant: ast, Input: "input", Response: resp, Messages: resp.Messages})
 
 	// EndRun shows these calls
 	stats, output := sp.EndRun(ctx)
@@ -194,6 +197,9 @@ toutput
 2024-01-01 12:00:00 run1: step1 A1 T1 *** Tool End ***
 2024-01-01 12:00:00 run1: step1 A1 T1 *** Tool Error *** terr
 2024-01-01 12:00:00 run1: step1 A1 *** Tool Not Found *** T2
+2024-01-01 12:00:00 run1: step1 A1 T3 *** Tool Cancelled *** cancelled
+2024-01-01 12:00:00 run1: step1 A1 *** LLM Retry *** parse_error, attempt 1: parseerr
+2024-01-01 12:00:00 run1: step1 A1 *** LLM Rate Limit *** gpt-4o model, attempt 1, wait 1s
 2024-01-01 12:00:00 run1: step1 A1 Assistant Output:
 Answer 1
 2024-01-01 12:00:00 run1: step1 A1 Messages:
@@ -204,7 +210,8 @@ Answer 1
 
 2024-01-01 12:00:00 run1: step1 A1 *** Assistant End ***
 2024-01-01 12:00:00 run1: Assistant calls: 1, Failed: 2
-2024-01-01 12:00:00 run1: Tool calls: 1, Failed: 1, Not Found: 1
+2024-01-01 12:00:00 run1: Tool calls: 1, Failed: 1, Not Found: 1, Cancelled: 1
+2024-01-01 12:00:00 run1: LLM retries: 1, Rate limited: 1
 2024-01-01 12:00:00 run1: LLM calls: 1, Messages: 1, Bytes Out: 8, Bytes In: 8, Bytes Total: 16, Input Tokens: 10, Output Tokens: 11, Total Tokens: 21
 2024-01-01 12:00:00 run1: === Run Ended. Duration: 0s ===
 `
@@ -215,22 +222,22 @@ Answer 1
 	resp.Messages = append(resp.Messages, llms.Message{Source: src, Role: llms.RoleHuman, Parts: []llms.ContentPart{llms.TextContent{Text: "foo"}}})
 
 	// test callback methods again: should still work if no run
-	sp.OnAssistantStart(ctx, ast, "input")
-	sp.OnAssistantEnd(ctx, ast, "input", resp, resp.Messages)
-	sp.OnAssistantLLMCallStart(ctx, ast, &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, []llms.Message{
+	sp.OnAssistantStart(ctx, &assistants.AssistantStartEvent{Assistant: ast, Input: "input"})
+	sp.OnAssistantEnd(ctx, &assistants.AssistantEndEvent{Assistant: ast, Input: "input", Response: resp, Messages: resp.Messages})
+	sp.OnAssistantLLMCallStart(ctx, &assistants.LLMCallStartEvent{Assistant: ast, LLM: &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, Messages: []llms.Message{
 		{Source: src, Role: llms.RoleHuman, Parts: []llms.ContentPart{llms.TextContent{Text: "foo"}}},
-	})
-	sp.OnAssistantLLMParseError(ctx, ast, "input", "output", errors.New("parse2"))
-	sp.OnAssistantError(ctx, ast, "input", errors.New("fail2"), []llms.Message{
+	}})
+	sp.OnAssistantLLMParseError(ctx, &assistants.LLMParseErrorEvent{Assistant: ast, Input: "input", Response: "output", Err: errors.New("parse2")})
+	sp.OnAssistantError(ctx, &assistants.AssistantErrorEvent{Assistant: ast, Input: "input", Err: errors.New("fail2"), Messages: []llms.Message{
 		{Source: src, Role: llms.RoleHuman, Parts: []llms.ContentPart{llms.TextContent{Text: "foo"}}},
-	})
-	sp.OnToolStart(ctx, tool, "A1", "tinput")
-	sp.OnToolEnd(ctx, tool, "A1", "tinput", "toutput")
-	sp.OnToolError(ctx, tool, "A1", "tinput", errors.New("terr2"))
-	sp.OnToolNotFound(ctx, ast, "T3")
-	sp.OnAssistantLLMCallEnd(ctx, ast, &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, &llms.ContentResponse{
+	}})
+	sp.OnToolStart(ctx, &tools.ToolStartEvent{Tool: tool, Assistant: "A1", Input: "tinput"})
+	sp.OnToolEnd(ctx, &tools.ToolEndEvent{Tool: tool, Assistant: "A1", Input: "tinput", Output: "toutput"})
+	sp.OnToolError(ctx, &tools.ToolErrorEvent{Tool: tool, Assistant: "A1", Input: "tinput", Err: errors.New("terr2")})
+	sp.OnToolNotFound(ctx, &assistants.ToolNotFoundEvent{Assistant: ast, Tool: "T3"})
+	sp.OnAssistantLLMCallEnd(ctx, &assistants.LLMCallEndEvent{Assistant: ast, LLM: &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}, Response: &llms.ContentResponse{
 		Choices: resp.Choices,
-	})
+	}})
 }
 
 func Test_run_print_format(t *testing.T) {
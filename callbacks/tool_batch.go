@@ -0,0 +1,139 @@
+package callbacks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/gogentic/assistants"
+)
+
+var (
+	_ assistants.ToolBatchCallback = (*Noop)(nil)
+	_ assistants.ToolBatchCallback = (*Printer)(nil)
+	_ assistants.ToolBatchCallback = (*Fanout)(nil)
+)
+
+// ToolBatchChecklist renders the batch as a checklist,
+// with the calls in the order requested by the LLM.
+func ToolBatchChecklist(batch *assistants.ToolBatch) string {
+	var b strings.Builder
+	for _, call := range batch.Calls {
+		mark := " "
+		switch call.Status {
+		case assistants.ToolCallRunning:
+			mark = "~"
+		case assistants.ToolCallSucceeded:
+			mark = "x"
+		case assistants.ToolCallFailed, assistants.ToolCallNotFound:
+			mark = "!"
+		}
+		_, _ = fmt.Fprintf(&b, "[%s] %s: %s", mark, call.Name, call.Status)
+		if call.Completed > 0 {
+			_, _ = fmt.Fprintf(&b, " #%d", call.Completed)
+		}
+		if call.Error != "" {
+			_, _ = fmt.Fprintf(&b, ": %s", call.Error)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (l *Fanout) OnToolBatchStart(ctx context.Context, e *assistants.ToolBatchEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.ToolBatchCallback); ok {
+			cb.OnToolBatchStart(ctx, e)
+		}
+	}
+}
+
+func (l *Fanout) OnToolBatchUpdate(ctx context.Context, e *assistants.ToolBatchEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.ToolBatchCallback); ok {
+			cb.OnToolBatchUpdate(ctx, e)
+		}
+	}
+}
+
+func (l *Fanout) OnToolBatchEnd(ctx context.Context, e *assistants.ToolBatchEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.ToolBatchCallback); ok {
+			cb.OnToolBatchEnd(ctx, e)
+		}
+	}
+}
+
+func (l *Noop) OnToolBatchStart(ctx context.Context, e *assistants.ToolBatchEvent) {
+}
+func (l *Noop) OnToolBatchUpdate(ctx context.Context, e *assistants.ToolBatchEvent) {
+}
+func (l *Noop) OnToolBatchEnd(ctx context.Context, e *assistants.ToolBatchEvent) {
+}
+
+func (l *Printer) OnToolBatchStart(ctx context.Context, e *assistants.ToolBatchEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "tool_batch_start",
+			Assistant: e.Batch.Assistant,
+			Details: map[string]any{
+				"batch": e.Batch.ID,
+				"calls": len(e.Batch.Calls),
+			},
+		})
+		return
+	}
+	l.printf(colorCyan, "Tool Batch Start", "%s (%s): %d calls", e.Batch.ID, e.Batch.Assistant, len(e.Batch.Calls))
+}
+
+func (l *Printer) OnToolBatchUpdate(ctx context.Context, e *assistants.ToolBatchEvent) {
+	if l.Mode != ModeVerbose {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		r := &PrintRecord{
+			Event:     "tool_batch_update",
+			Assistant: e.Batch.Assistant,
+			Tool:      e.Call.Name,
+			CallID:    e.Call.ID,
+			Error:     e.Call.Error,
+			Details: map[string]any{
+				"batch":  e.Batch.ID,
+				"status": e.Call.Status,
+			},
+		}
+		if !e.Call.EndedAt.IsZero() {
+			r.DurationMS = durationMS(e.Call.EndedAt.Sub(e.Call.StartedAt))
+		}
+		l.record(r)
+		return
+	}
+	l.printf(colorNone, "Tool Batch Update", "%s: %s: %s", e.Batch.ID, e.Call.Name, e.Call.Status)
+}
+
+func (l *Printer) OnToolBatchEnd(ctx context.Context, e *assistants.ToolBatchEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		r := &PrintRecord{
+			Event:     "tool_batch_end",
+			Assistant: e.Batch.Assistant,
+			Details: map[string]any{
+				"batch":     e.Batch.ID,
+				"calls":     len(e.Batch.Calls),
+				"completed": e.Batch.Completed(),
+			},
+		}
+		if !e.Batch.EndedAt.IsZero() {
+			r.DurationMS = durationMS(e.Batch.EndedAt.Sub(e.Batch.StartedAt))
+		}
+		l.record(r)
+		return
+	}
+	l.printf(colorCyan, "Tool Batch End", "%s (%s): %d/%d completed", e.Batch.ID, e.Batch.Assistant, e.Batch.Completed(), len(e.Batch.Calls))
+	_, _ = fmt.Fprint(l.Out, ToolBatchChecklist(e.Batch))
+}
@@ -0,0 +1,58 @@
+package callbacks_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToolBatchPrinter(t *testing.T) {
+	var buf1, buf2 bytes.Buffer
+	cb := callbacks.NewFanout(
+		callbacks.NewPrinter(&buf1, callbacks.ModeVerbose),
+		callbacks.NewPrinter(&buf2, callbacks.ModeDefault),
+		callbacks.NewNoop(),
+	)
+
+	ctx := context.Background()
+	ast := &fakeAssistant{name: "test-assistant"}
+	batch := &assistants.ToolBatch{
+		ID:        "batch_1",
+		Assistant: "test-assistant",
+		Calls: []assistants.ToolBatchCall{
+			{ID: "1", Index: 0, Name: "search", Status: assistants.ToolCallPending},
+			{ID: "2", Index: 1, Name: "fetch", Status: assistants.ToolCallPending},
+			{ID: "3", Index: 2, Name: "missing", Status: assistants.ToolCallPending},
+			{ID: "4", Index: 3, Name: "calc", Status: assistants.ToolCallPending},
+		},
+	}
+	cb.OnToolBatchStart(ctx, &assistants.ToolBatchEvent{Assistant: ast, Batch: batch})
+
+	batch.Calls[0].Status = assistants.ToolCallRunning
+	cb.OnToolBatchUpdate(ctx, &assistants.ToolBatchEvent{Assistant: ast, Batch: batch, Call: &batch.Calls[0]})
+
+	batch.Calls[1].Status = assistants.ToolCallSucceeded
+	batch.Calls[1].Completed = 1
+	batch.Calls[2].Status = assistants.ToolCallNotFound
+	batch.Calls[2].Completed = 2
+	batch.Calls[3].Status = assistants.ToolCallFailed
+	batch.Calls[3].Completed = 3
+	batch.Calls[3].Error = "division by zero"
+	cb.OnToolBatchEnd(ctx, &assistants.ToolBatchEvent{Assistant: ast, Batch: batch})
+
+	exp := `Tool Batch Start: batch_1 (test-assistant): 4 calls
+Tool Batch Update: batch_1: search: running
+Tool Batch End: batch_1 (test-assistant): 3/4 completed
+[~] search: running
+[x] fetch: succeeded #1
+[!] missing: not_found #2
+[!] calc: failed #3: division by zero
+`
+	assert.Equal(t, exp, buf1.String())
+	assert.NotContains(t, buf2.String(), "Tool Batch Update")
+	assert.Contains(t, buf2.String(), "Tool Batch End: batch_1 (test-assistant): 3/4 completed")
+}
@@ -0,0 +1,46 @@
+package callbacks
+
+import (
+	"context"
+
+	"github.com/effective-security/gogentic/assistants"
+)
+
+var (
+	_ assistants.ToolCallDeltaCallback = (*Noop)(nil)
+	_ assistants.ToolCallDeltaCallback = (*Printer)(nil)
+	_ assistants.ToolCallDeltaCallback = (*Fanout)(nil)
+)
+
+func (l *Fanout) OnToolCallDelta(ctx context.Context, e *assistants.ToolCallDeltaEvent) {
+	for _, callback := range l.callbacks {
+		if cb, ok := callback.(assistants.ToolCallDeltaCallback); ok {
+			cb.OnToolCallDelta(ctx, e)
+		}
+	}
+}
+
+func (l *Noop) OnToolCallDelta(ctx context.Context, e *assistants.ToolCallDeltaEvent) {
+}
+
+func (l *Printer) OnToolCallDelta(ctx context.Context, e *assistants.ToolCallDeltaEvent) {
+	if l.Mode != ModeVerbose {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.structured() {
+		l.record(&PrintRecord{
+			Event:     "tool_call_delta",
+			Assistant: e.Assistant.Name(),
+			Tool:      e.Delta.Name,
+			CallID:    e.Delta.ID,
+			Details: map[string]any{
+				"index": e.Delta.Index,
+				"delta": e.Delta.ArgumentsDelta,
+			},
+		})
+		return
+	}
+	l.printf(colorNone, "Tool Call Delta", "%s #%d: %s", e.Delta.Name, e.Delta.Index, e.Delta.ArgumentsDelta)
+}
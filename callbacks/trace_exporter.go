@@ -0,0 +1,532 @@
+package callbacks
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/xlog"
+	"github.com/google/uuid"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "callbacks")
+
+// ensure TraceExporter implements assistants.Callback
+var _ assistants.Callback = (*TraceExporter)(nil)
+
+// SpanKind defines the kind of the exported span
+type SpanKind string
+
+const (
+	// SpanRun is the run of the assistant
+	SpanRun SpanKind = "run"
+	// SpanGeneration is the LLM call
+	SpanGeneration SpanKind = "generation"
+	// SpanTool is the tool call
+	SpanTool SpanKind = "tool"
+)
+
+// SpanRef references the ancestor of the span.
+type SpanRef struct {
+	ID        string
+	StartTime time.Time
+}
+
+// TraceSpan is the completed run, LLM call or tool call exported by TraceExporter.
+// The run of the top-level assistant is the root span of the trace, with the ID equal to TraceID.
+type TraceSpan struct {
+	ID      string
+	TraceID string
+	// ParentID is the ID of the parent span, empty for the root span.
+	ParentID string
+	// Ancestors are the spans from the root to the parent.
+	Ancestors []SpanRef
+	Kind      SpanKind
+	Name      string
+	// Model is the name of the LLM, for SpanGeneration.
+	Model string
+	// Input is the input string of the run or the tool call, or the messages of the LLM call.
+	Input any
+	// Output is the output string of the run or the tool call, or the choices of the LLM call.
+	Output    any
+	Error     string
+	StartTime time.Time
+	EndTime   time.Time
+	// Usage is the token usage of SpanGeneration and SpanRun.
+	Usage *llms.Usage
+	// SessionID is the chat ID from the chat context.
+	SessionID string
+	// UserID is the user ID from the chat context.
+	UserID   string
+	Metadata map[string]any
+}
+
+// TraceBackend exports the spans to the tracing service, such as Langfuse or LangSmith.
+type TraceBackend interface {
+	Export(ctx context.Context, spans []*TraceSpan) error
+}
+
+const (
+	// DefaultTraceBatchSize is the default number of the spans exported in one request.
+	DefaultTraceBatchSize = 100
+	// DefaultTraceFlushInterval is the default interval of exporting the pending spans.
+	DefaultTraceFlushInterval = 5 * time.Second
+	// DefaultTraceExportTimeout is the default timeout of exporting one batch of the spans.
+	DefaultTraceExportTimeout = 30 * time.Second
+)
+
+// TraceOption configures TraceExporter.
+type TraceOption func(*TraceExporter)
+
+// WithTraceBatchSize sets the number of the spans exported in one request,
+// the export is started when the batch is full, or on the flush interval.
+func WithTraceBatchSize(size int) TraceOption {
+	return func(l *TraceExporter) {
+		if size > 0 {
+			l.batchSize = size
+		}
+	}
+}
+
+// WithTraceFlushInterval sets the interval of exporting the pending spans.
+func WithTraceFlushInterval(interval time.Duration) TraceOption {
+	return func(l *TraceExporter) {
+		if interval > 0 {
+			l.flushInterval = interval
+		}
+	}
+}
+
+// WithTraceExportTimeout sets the timeout of exporting one batch of the spans,
+// so the unavailable backend does not block Flush and Close.
+func WithTraceExportTimeout(timeout time.Duration) TraceOption {
+	return func(l *TraceExporter) {
+		if timeout > 0 {
+			l.exportTimeout = timeout
+		}
+	}
+}
+
+// WithTraceSampleRate sets the share of the traces to export, from 0 to 1.
+// The decision is made for the top-level run, so the trace is exported as a whole.
+// By default all the traces are exported.
+func WithTraceSampleRate(rate float64) TraceOption {
+	return func(l *TraceExporter) {
+		l.sampleRate = min(max(rate, 0), 1)
+	}
+}
+
+// WithTraceRedactor sets the Redactor to mask the secrets and the sensitive tool arguments.
+func WithTraceRedactor(r *llmutils.Redactor) TraceOption {
+	return func(l *TraceExporter) {
+		l.redactor = r
+	}
+}
+
+// TraceExporter is a callback handler that exports the runs of the assistants,
+// the LLM calls as the generations, and the tool calls as the spans to the TraceBackend,
+// such as Langfuse or LangSmith, see NewLangfuseBackend and NewLangSmithBackend.
+// The spans are correlated by the run ID of the chat context,
+// the events without the chat context are not exported.
+// The completed spans are exported in batches in the background,
+// Close must be called to export the pending spans on shutdown.
+type TraceExporter struct {
+	backend       TraceBackend
+	batchSize     int
+	flushInterval time.Duration
+	exportTimeout time.Duration
+	sampleRate    float64
+	redactor      *llmutils.Redactor
+
+	lock    sync.Mutex
+	traces  map[string]*traceState
+	pending []*TraceSpan
+
+	// exportLock serializes the exports
+	exportLock sync.Mutex
+	flush      chan struct{}
+	stop       chan struct{}
+	stopped    chan struct{}
+	closeOnce  sync.Once
+}
+
+// traceState is the state of the trace in progress.
+type traceState struct {
+	traceID   string
+	sampled   bool
+	sessionID string
+	userID    string
+	// runs are the open runs of the assistants, the innermost is the last
+	runs []*TraceSpan
+	// generations are the open LLM calls by the assistant name
+	generations map[string]*TraceSpan
+	// tools are the open tool calls by the call ID
+	tools map[string]*TraceSpan
+}
+
+// NewTraceExporter returns TraceExporter, and starts the background export.
+func NewTraceExporter(backend TraceBackend, opts ...TraceOption) *TraceExporter {
+	l := &TraceExporter{
+		backend:       backend,
+		batchSize:     DefaultTraceBatchSize,
+		flushInterval: DefaultTraceFlushInterval,
+		exportTimeout: DefaultTraceExportTimeout,
+		sampleRate:    1,
+		traces:        make(map[string]*traceState),
+		flush:         make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	go l.loop()
+	return l
+}
+
+func (l *TraceExporter) loop() {
+	defer close(l.stopped)
+
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		case <-l.flush:
+		}
+		_ = l.Flush(context.Background())
+	}
+}
+
+// Flush exports the pending spans, the spans failed to export are dropped.
+// Each batch is exported with the export timeout, see WithTraceExportTimeout.
+func (l *TraceExporter) Flush(ctx context.Context) error {
+	l.exportLock.Lock()
+	defer l.exportLock.Unlock()
+
+	l.lock.Lock()
+	spans := l.pending
+	l.pending = nil
+	l.lock.Unlock()
+
+	var firstErr error
+	for len(spans) > 0 {
+		n := min(len(spans), l.batchSize)
+		if err := l.export(ctx, spans[:n]); err != nil {
+			logger.ContextKV(ctx, xlog.WARNING,
+				"status", "trace_export_failed",
+				"spans", n,
+				"err", err.Error(),
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		spans = spans[n:]
+	}
+	return firstErr
+}
+
+func (l *TraceExporter) export(ctx context.Context, spans []*TraceSpan) error {
+	ctx, cancel := context.WithTimeout(ctx, l.exportTimeout)
+	defer cancel()
+	return l.backend.Export(ctx, spans)
+}
+
+// Close stops the background export, and exports the pending spans.
+func (l *TraceExporter) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.stop)
+		<-l.stopped
+	})
+	return l.Flush(context.Background())
+}
+
+func (l *TraceExporter) sample() bool {
+	return l.sampleRate >= 1 || (l.sampleRate > 0 && rand.Float64() < l.sampleRate)
+}
+
+// trace returns the key and the state of the trace in progress, the caller must hold the lock.
+func (l *TraceExporter) trace(ctx context.Context) (string, *traceState) {
+	chatCtx := chatmodel.GetChatContext(ctx)
+	if chatCtx == nil {
+		return "", nil
+	}
+	key := chatCtx.GetRunID()
+	if key == "" {
+		key = chatCtx.GetChatID()
+	}
+	return key, l.traces[key]
+}
+
+// enqueue adds the completed span to the pending, the caller must hold the lock.
+func (l *TraceExporter) enqueue(state *traceState, span *TraceSpan) {
+	if !state.sampled {
+		return
+	}
+	l.pending = append(l.pending, span)
+	if len(l.pending) >= l.batchSize {
+		select {
+		case l.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *traceState) newSpan(parent *TraceSpan, kind SpanKind, name string) *TraceSpan {
+	span := &TraceSpan{
+		ID:        uuid.NewString(),
+		TraceID:   s.traceID,
+		Kind:      kind,
+		Name:      name,
+		StartTime: TimeNowFn().UTC(),
+		SessionID: s.sessionID,
+		UserID:    s.userID,
+		Metadata:  map[string]any{},
+	}
+	if parent != nil {
+		span.ParentID = parent.ID
+		span.Ancestors = make([]SpanRef, 0, len(parent.Ancestors)+1)
+		span.Ancestors = append(span.Ancestors, parent.Ancestors...)
+		span.Ancestors = append(span.Ancestors, SpanRef{ID: parent.ID, StartTime: parent.StartTime})
+	}
+	return span
+}
+
+// run returns the innermost open run of the assistant
+func (s *traceState) run(assistant string) *TraceSpan {
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		if s.runs[i].Name == assistant {
+			return s.runs[i]
+		}
+	}
+	return nil
+}
+
+func toolCallKey(assistant, tool, callID string) string {
+	if callID != "" {
+		return callID
+	}
+	return assistant + "/" + tool
+}
+
+func (l *TraceExporter) OnAssistantStart(ctx context.Context, e *assistants.AssistantStartEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	key, state := l.trace(ctx)
+	if key == "" {
+		return
+	}
+	if state == nil {
+		chatCtx := chatmodel.GetChatContext(ctx)
+		state = &traceState{
+			traceID:     uuid.NewString(),
+			sampled:     l.sample(),
+			sessionID:   chatCtx.GetChatID(),
+			userID:      chatCtx.GetUserID(),
+			generations: make(map[string]*TraceSpan),
+			tools:       make(map[string]*TraceSpan),
+		}
+		l.traces[key] = state
+	}
+
+	var parent *TraceSpan
+	if n := len(state.runs); n > 0 {
+		parent = state.runs[n-1]
+	}
+	span := state.newSpan(parent, SpanRun, e.Assistant.Name())
+	if parent == nil {
+		span.ID = state.traceID
+	}
+	span.Input = l.redactor.String(e.Input)
+	state.runs = append(state.runs, span)
+}
+
+// endRun removes the innermost open run of the assistant, and exports it, the caller must hold the lock.
+// The LLM call of the assistant still open, such as the failed one, is exported with the error of the run.
+func (l *TraceExporter) endRun(ctx context.Context, assistant string, fn func(span *TraceSpan)) {
+	key, state := l.trace(ctx)
+	if state == nil {
+		return
+	}
+	for i := len(state.runs) - 1; i >= 0; i-- {
+		span := state.runs[i]
+		if span.Name != assistant {
+			continue
+		}
+		state.runs = append(state.runs[:i], state.runs[i+1:]...)
+		if len(state.runs) == 0 {
+			delete(l.traces, key)
+		}
+		span.EndTime = TimeNowFn().UTC()
+		fn(span)
+		if gen := state.generations[assistant]; gen != nil {
+			delete(state.generations, assistant)
+			gen.EndTime = span.EndTime
+			gen.Error = span.Error
+			l.enqueue(state, gen)
+		}
+		l.enqueue(state, span)
+		return
+	}
+}
+
+func (l *TraceExporter) OnAssistantEnd(ctx context.Context, e *assistants.AssistantEndEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.endRun(ctx, e.Assistant.Name(), func(span *TraceSpan) {
+		if e.Response != nil {
+			span.Output = l.redactor.String(e.Response.String())
+			usage := e.Response.Usage.Usage
+			span.Usage = &usage
+		}
+	})
+}
+
+func (l *TraceExporter) OnAssistantError(ctx context.Context, e *assistants.AssistantErrorEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.endRun(ctx, e.Assistant.Name(), func(span *TraceSpan) {
+		span.Error = l.redactor.String(errorString(e.Err))
+	})
+}
+
+func (l *TraceExporter) OnAssistantLLMCallStart(ctx context.Context, e *assistants.LLMCallStartEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	_, state := l.trace(ctx)
+	if state == nil {
+		return
+	}
+	parent := state.run(e.Assistant.Name())
+	if parent == nil {
+		return
+	}
+	span := state.newSpan(parent, SpanGeneration, e.LLM.GetName())
+	span.Model = e.LLM.GetName()
+	span.Metadata["round"] = e.Round
+	if state.sampled {
+		span.Input = l.redactor.Messages(cloneMessages(e.Messages))
+	}
+	state.generations[e.Assistant.Name()] = span
+}
+
+func (l *TraceExporter) OnAssistantLLMCallEnd(ctx context.Context, e *assistants.LLMCallEndEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	_, state := l.trace(ctx)
+	if state == nil {
+		return
+	}
+	span := state.generations[e.Assistant.Name()]
+	if span == nil {
+		return
+	}
+	delete(state.generations, e.Assistant.Name())
+	span.EndTime = TimeNowFn().UTC()
+	if e.Response != nil {
+		if state.sampled {
+			span.Output = l.redactor.Choices(cloneChoices(e.Response.Choices))
+		}
+		span.Usage = e.Response.Usage()
+	}
+	l.enqueue(state, span)
+}
+
+func (l *TraceExporter) OnAssistantLLMParseError(ctx context.Context, e *assistants.LLMParseErrorEvent) {
+}
+
+func (l *TraceExporter) OnToolNotFound(ctx context.Context, e *assistants.ToolNotFoundEvent) {
+}
+
+func (l *TraceExporter) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	_, state := l.trace(ctx)
+	if state == nil {
+		return
+	}
+	key := toolCallKey(e.Assistant, e.Tool.Name(), e.CallID)
+	if span := state.tools[key]; span != nil {
+		// the retry of the tool call is reported in the same span
+		span.Metadata["attempt"] = e.Attempt
+		return
+	}
+	parent := state.run(e.Assistant)
+	if parent == nil {
+		return
+	}
+	span := state.newSpan(parent, SpanTool, e.Tool.Name())
+	span.Input = l.redactor.Arguments(toolParameters(l.redactor, e.Tool), e.Input)
+	span.Metadata["call_id"] = e.CallID
+	span.Metadata["attempt"] = e.Attempt
+	state.tools[key] = span
+}
+
+// endTool removes the open tool call, and exports it, the caller must hold the lock.
+func (l *TraceExporter) endTool(ctx context.Context, tool tools.ITool, assistant, callID string, fn func(span *TraceSpan)) {
+	_, state := l.trace(ctx)
+	if state == nil {
+		return
+	}
+	key := toolCallKey(assistant, tool.Name(), callID)
+	span := state.tools[key]
+	if span == nil {
+		return
+	}
+	delete(state.tools, key)
+	span.EndTime = TimeNowFn().UTC()
+	fn(span)
+	l.enqueue(state, span)
+}
+
+func (l *TraceExporter) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.endTool(ctx, e.Tool, e.Assistant, e.CallID, func(span *TraceSpan) {
+		span.Output = l.redactor.String(e.Output)
+		span.Metadata["attempt"] = e.Attempt
+	})
+}
+
+func (l *TraceExporter) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	_, state := l.trace(ctx)
+	if state == nil {
+		return
+	}
+	key := toolCallKey(e.Assistant, e.Tool.Name(), e.CallID)
+	if state.tools[key] == nil {
+		// the call is rejected by the validation before the start
+		parent := state.run(e.Assistant)
+		if parent == nil {
+			return
+		}
+		span := state.newSpan(parent, SpanTool, e.Tool.Name())
+		span.StartTime = span.StartTime.Add(-e.Latency)
+		span.Input = l.redactor.Arguments(toolParameters(l.redactor, e.Tool), e.Input)
+		span.Metadata["call_id"] = e.CallID
+		state.tools[key] = span
+	}
+	l.endTool(ctx, e.Tool, e.Assistant, e.CallID, func(span *TraceSpan) {
+		span.Error = l.redactor.String(errorString(e.Err))
+		span.Metadata["attempt"] = e.Attempt
+	})
+}
@@ -0,0 +1,323 @@
+package callbacks_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emitTrace emits the events of the planner run, that calls the LLM, the tools,
+// and the nested researcher assistant.
+func emitTrace(ctx context.Context, cb assistants.Callback) {
+	planner := &fakeAssistant{name: "planner"}
+	researcher := &fakeAssistant{name: "researcher"}
+	search := &fakeTool{name: "search"}
+	llm := &fakeModel{name: "gpt-4o"}
+
+	cb.OnAssistantStart(ctx, &assistants.AssistantStartEvent{Assistant: planner, Input: "plan the trip, key sk-ant-REDACTED"})
+	cb.OnAssistantLLMCallStart(ctx, &assistants.LLMCallStartEvent{Assistant: planner, LLM: llm, Round: 1, Messages: llms.Messages{
+		llms.MessageFromTextParts(llms.RoleHuman, "plan the trip"),
+	}})
+	cb.OnAssistantLLMCallEnd(ctx, &assistants.LLMCallEndEvent{Assistant: planner, LLM: llm, Round: 1, Response: &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: "search", Usage: llms.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15}}},
+	}})
+	cb.OnToolStart(ctx, &tools.ToolStartEvent{Tool: search, Assistant: "planner", Input: `{"q":"paris"}`, CallID: "call_1", Attempt: 1})
+	cb.OnToolStart(ctx, &tools.ToolStartEvent{Tool: search, Assistant: "planner", Input: `{"q":"paris"}`, CallID: "call_1", Attempt: 2})
+	cb.OnToolEnd(ctx, &tools.ToolEndEvent{Tool: search, Assistant: "planner", Input: `{"q":"paris"}`, Output: "found", CallID: "call_1", Attempt: 2})
+	cb.OnAssistantStart(ctx, &assistants.AssistantStartEvent{Assistant: researcher, Input: "research paris"})
+	cb.OnAssistantEnd(ctx, &assistants.AssistantEndEvent{Assistant: researcher, Input: "research paris", Response: &assistants.Response{
+		Choices: []*llms.ContentChoice{{Content: "paris is nice"}},
+	}})
+	cb.OnToolError(ctx, &tools.ToolErrorEvent{Tool: search, Assistant: "planner", Input: "{}", CallID: "call_2", Err: errors.New("invalid arguments")})
+	cb.OnAssistantEnd(ctx, &assistants.AssistantEndEvent{Assistant: planner, Input: "plan the trip", Response: &assistants.Response{
+		Choices: []*llms.ContentChoice{{Content: "go to paris"}},
+	}})
+}
+
+func traceContext() context.Context {
+	chatCtx := chatmodel.NewChatContext("tenant1", "chat1", nil)
+	chatCtx.SetUserID("user1")
+	return chatmodel.WithChatContext(context.Background(), chatCtx)
+}
+
+// spansRecorder records the exported spans
+type spansRecorder struct {
+	lock    sync.Mutex
+	batches [][]*callbacks.TraceSpan
+	err     error
+}
+
+func (r *spansRecorder) Export(_ context.Context, spans []*callbacks.TraceSpan) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.batches = append(r.batches, spans)
+	return r.err
+}
+
+func (r *spansRecorder) spans() []*callbacks.TraceSpan {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var res []*callbacks.TraceSpan
+	for _, b := range r.batches {
+		res = append(res, b...)
+	}
+	return res
+}
+
+func TestTraceExporter(t *testing.T) {
+	t.Parallel()
+
+	rec := &spansRecorder{}
+	exp := callbacks.NewTraceExporter(rec,
+		callbacks.WithTraceBatchSize(2),
+		callbacks.WithTraceRedactor(llmutils.NewRedactor()),
+	)
+	emitTrace(traceContext(), exp)
+	// the events without the chat context are ignored
+	emitTrace(context.Background(), exp)
+	require.NoError(t, exp.Close())
+	require.NoError(t, exp.Close())
+
+	spans := rec.spans()
+	require.Len(t, spans, 5)
+	for _, b := range rec.batches {
+		assert.LessOrEqual(t, len(b), 2)
+	}
+
+	gen, tool, researcher, rejected, root := spans[0], spans[1], spans[2], spans[3], spans[4]
+
+	assert.Equal(t, callbacks.SpanRun, root.Kind)
+	assert.Equal(t, "planner", root.Name)
+	assert.Equal(t, root.TraceID, root.ID)
+	assert.Empty(t, root.ParentID)
+	assert.Equal(t, "chat1", root.SessionID)
+	assert.Equal(t, "user1", root.UserID)
+	assert.NotContains(t, root.Input, "abcdefghijklmnop")
+	assert.Equal(t, "go to paris", root.Output)
+	assert.False(t, root.EndTime.Before(root.StartTime))
+
+	assert.Equal(t, callbacks.SpanGeneration, gen.Kind)
+	assert.Equal(t, "gpt-4o", gen.Model)
+	assert.Equal(t, root.ID, gen.ParentID)
+	assert.Equal(t, 1, gen.Metadata["round"])
+	assert.Equal(t, uint64(15), gen.Usage.TotalTokens)
+	assert.Len(t, gen.Input, 1)
+
+	// the retry is reported in the same span
+	assert.Equal(t, callbacks.SpanTool, tool.Kind)
+	assert.Equal(t, root.ID, tool.ParentID)
+	assert.Equal(t, "found", tool.Output)
+	assert.Equal(t, 2, tool.Metadata["attempt"])
+	assert.Equal(t, "call_1", tool.Metadata["call_id"])
+
+	assert.Equal(t, callbacks.SpanRun, researcher.Kind)
+	assert.Equal(t, root.ID, researcher.ParentID)
+	assert.Equal(t, root.TraceID, researcher.TraceID)
+	require.Len(t, researcher.Ancestors, 1)
+	assert.Equal(t, root.ID, researcher.Ancestors[0].ID)
+
+	assert.Equal(t, "invalid arguments", rejected.Error)
+	assert.Equal(t, "call_2", rejected.Metadata["call_id"])
+
+	t.Run("sampled_out", func(t *testing.T) {
+		rec := &spansRecorder{}
+		exp := callbacks.NewTraceExporter(rec, callbacks.WithTraceSampleRate(0))
+		emitTrace(traceContext(), exp)
+		require.NoError(t, exp.Close())
+		assert.Empty(t, rec.spans())
+	})
+
+	t.Run("export_error", func(t *testing.T) {
+		rec := &spansRecorder{err: errors.New("unavailable")}
+		exp := callbacks.NewTraceExporter(rec, callbacks.WithTraceFlushInterval(time.Hour))
+		emitTrace(traceContext(), exp)
+		assert.EqualError(t, exp.Flush(context.Background()), "unavailable")
+		// the failed spans are dropped
+		require.NoError(t, exp.Close())
+		assert.Len(t, rec.spans(), 5)
+	})
+
+	t.Run("failed_generation", func(t *testing.T) {
+		rec := &spansRecorder{}
+		exp := callbacks.NewTraceExporter(rec)
+		ctx := traceContext()
+		planner := &fakeAssistant{name: "planner"}
+		cb := assistants.Callback(exp)
+		cb.OnAssistantStart(ctx, &assistants.AssistantStartEvent{Assistant: planner, Input: "plan the trip"})
+		cb.OnAssistantLLMCallStart(ctx, &assistants.LLMCallStartEvent{Assistant: planner, LLM: &fakeModel{name: "gpt-4o"}, Round: 1})
+		cb.OnAssistantError(ctx, &assistants.AssistantErrorEvent{Assistant: planner, Input: "plan the trip", Err: errors.New("rate limited")})
+		require.NoError(t, exp.Close())
+
+		spans := rec.spans()
+		require.Len(t, spans, 2)
+		gen, root := spans[0], spans[1]
+		assert.Equal(t, callbacks.SpanGeneration, gen.Kind)
+		assert.Equal(t, root.ID, gen.ParentID)
+		assert.Equal(t, "rate limited", gen.Error)
+		assert.Equal(t, root.EndTime, gen.EndTime)
+		assert.Equal(t, "rate limited", root.Error)
+	})
+
+	t.Run("export_timeout", func(t *testing.T) {
+		exp := callbacks.NewTraceExporter(blockingBackend{},
+			callbacks.WithTraceFlushInterval(time.Hour),
+			callbacks.WithTraceExportTimeout(50*time.Millisecond),
+		)
+		emitTrace(traceContext(), exp)
+		err := exp.Close()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+// blockingBackend blocks the export until the context is done
+type blockingBackend struct{}
+
+func (blockingBackend) Export(ctx context.Context, _ []*callbacks.TraceSpan) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTraceExporter_Langfuse(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lock  sync.Mutex
+		batch []map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/public/ingestion", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "pk-lf-1", user)
+		assert.Equal(t, "sk-lf-1", pass)
+
+		var req struct {
+			Batch []map[string]any `json:"batch"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		lock.Lock()
+		batch = append(batch, req.Batch...)
+		lock.Unlock()
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`{"successes":[],"errors":[]}`))
+	}))
+	defer srv.Close()
+
+	exp := callbacks.NewTraceExporter(callbacks.NewLangfuseBackend(srv.URL+"/", "pk-lf-1", "sk-lf-1").WithHTTPClient(srv.Client()))
+	emitTrace(traceContext(), exp)
+	require.NoError(t, exp.Close())
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, batch, 5)
+
+	types := make([]string, 0, len(batch))
+	for _, e := range batch {
+		types = append(types, e["type"].(string))
+	}
+	assert.Equal(t, []string{"generation-create", "span-create", "span-create", "span-create", "trace-create"}, types)
+
+	trace := batch[4]["body"].(map[string]any)
+	assert.Equal(t, "planner", trace["name"])
+	assert.Equal(t, "chat1", trace["sessionId"])
+	assert.Equal(t, "user1", trace["userId"])
+
+	gen := batch[0]["body"].(map[string]any)
+	assert.Equal(t, trace["id"], gen["traceId"])
+	assert.NotContains(t, gen, "parentObservationId")
+	assert.Equal(t, "gpt-4o", gen["model"])
+	assert.Equal(t, map[string]any{"input": 10.0, "output": 5.0, "total": 15.0, "unit": "TOKENS"}, gen["usage"])
+
+	rejected := batch[3]["body"].(map[string]any)
+	assert.Equal(t, "ERROR", rejected["level"])
+	assert.Equal(t, "invalid arguments", rejected["statusMessage"])
+
+	t.Run("rejected_events", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = w.Write([]byte(`{"successes":[],"errors":[{"id":"1","status":400,"message":"invalid body"}]}`))
+		}))
+		defer srv.Close()
+
+		b := callbacks.NewLangfuseBackend(srv.URL, "pk", "sk")
+		err := b.Export(context.Background(), []*callbacks.TraceSpan{{ID: "1", TraceID: "1"}})
+		assert.EqualError(t, err, "langfuse rejected 1 of 1 events: invalid body")
+	})
+}
+
+func TestTraceExporter_LangSmith(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lock sync.Mutex
+		runs []map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "lsv2-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "/runs/batch", r.URL.Path)
+		var req struct {
+			Post []map[string]any `json:"post"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		lock.Lock()
+		runs = append(runs, req.Post...)
+		lock.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	exp := callbacks.NewTraceExporter(callbacks.NewLangSmithBackend(srv.URL, "lsv2-1", "gogentic"))
+	emitTrace(traceContext(), exp)
+	require.NoError(t, exp.Close())
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, runs, 5)
+
+	runTypes := make([]string, 0, len(runs))
+	for _, run := range runs {
+		runTypes = append(runTypes, run["run_type"].(string))
+		assert.Equal(t, "gogentic", run["session_name"])
+	}
+	assert.Equal(t, []string{"llm", "tool", "chain", "tool", "chain"}, runTypes)
+
+	root := runs[4]
+	assert.Equal(t, root["id"], root["trace_id"])
+	assert.NotContains(t, root, "parent_run_id")
+	rootOrder := root["dotted_order"].(string)
+	assert.True(t, strings.HasSuffix(rootOrder, "Z"+root["id"].(string)), rootOrder)
+
+	// the dotted order of the children starts with the order of the root
+	for _, run := range runs[:4] {
+		assert.Equal(t, root["id"], run["parent_run_id"])
+		assert.True(t, strings.HasPrefix(run["dotted_order"].(string), rootOrder+"."))
+	}
+
+	gen := runs[0]
+	assert.Equal(t, map[string]any{"input_tokens": 10.0, "output_tokens": 5.0, "total_tokens": 15.0}, gen["outputs"].(map[string]any)["usage_metadata"])
+	assert.Equal(t, "gpt-4o", gen["extra"].(map[string]any)["metadata"].(map[string]any)["ls_model_name"])
+	assert.Equal(t, "invalid arguments", runs[3]["error"])
+
+	b := callbacks.NewLangSmithBackend(srv.URL, "invalid", "")
+	err := b.Export(context.Background(), []*callbacks.TraceSpan{{ID: "1", TraceID: "1"}})
+	assert.EqualError(t, err, "langsmith returned unexpected status code: 401: ")
+}
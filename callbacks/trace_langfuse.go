@@ -0,0 +1,167 @@
+package callbacks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+)
+
+// DefaultLangfuseHost is the host of the Langfuse cloud.
+const DefaultLangfuseHost = "https://cloud.langfuse.com"
+
+// ensure LangfuseBackend implements TraceBackend
+var _ TraceBackend = (*LangfuseBackend)(nil)
+
+// LangfuseBackend exports the spans to the Langfuse ingestion API.
+// The root span is exported as the trace, the runs of the nested assistants
+// and the tool calls as the spans, and the LLM calls as the generations.
+type LangfuseBackend struct {
+	host       string
+	publicKey  string
+	secretKey  string
+	httpClient *http.Client
+}
+
+// NewLangfuseBackend returns TraceBackend for Langfuse,
+// if the host is empty DefaultLangfuseHost is used.
+func NewLangfuseBackend(host, publicKey, secretKey string) *LangfuseBackend {
+	if host == "" {
+		host = DefaultLangfuseHost
+	}
+	return &LangfuseBackend{
+		host:       strings.TrimSuffix(host, "/"),
+		publicKey:  publicKey,
+		secretKey:  secretKey,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the ingestion calls.
+func (b *LangfuseBackend) WithHTTPClient(client *http.Client) *LangfuseBackend {
+	b.httpClient = client
+	return b
+}
+
+type langfuseEvent struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	Timestamp time.Time      `json:"timestamp"`
+	Body      map[string]any `json:"body"`
+}
+
+type langfuseResponse struct {
+	Errors []struct {
+		ID      string `json:"id"`
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Export sends the spans to the ingestion API.
+func (b *LangfuseBackend) Export(ctx context.Context, spans []*TraceSpan) error {
+	batch := make([]langfuseEvent, 0, len(spans))
+	for _, span := range spans {
+		batch = append(batch, langfuseEventOf(span))
+	}
+	body, err := json.Marshal(map[string]any{"batch": batch})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.host+"/api/public/ingestion", bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(b.publicKey, b.secretKey)
+	res, err := b.httpClient.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return errors.Newf("langfuse returned unexpected status code: %d: %s", res.StatusCode, string(msg))
+	}
+	// the ingestion returns 207 with the errors of the individual events
+	var resp langfuseResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err == nil && len(resp.Errors) > 0 {
+		return errors.Newf("langfuse rejected %d of %d events: %s", len(resp.Errors), len(batch), resp.Errors[0].Message)
+	}
+	return nil
+}
+
+func langfuseEventOf(span *TraceSpan) langfuseEvent {
+	metadata := make(map[string]any, len(span.Metadata)+1)
+	for k, v := range span.Metadata {
+		metadata[k] = v
+	}
+	if span.Error != "" {
+		metadata["error"] = span.Error
+	}
+
+	if span.ParentID == "" {
+		return langfuseEvent{
+			ID:        uuid.NewString(),
+			Type:      "trace-create",
+			Timestamp: span.EndTime,
+			Body: map[string]any{
+				"id":        span.TraceID,
+				"timestamp": span.StartTime,
+				"name":      span.Name,
+				"input":     span.Input,
+				"output":    span.Output,
+				"sessionId": span.SessionID,
+				"userId":    span.UserID,
+				"metadata":  metadata,
+			},
+		}
+	}
+
+	body := map[string]any{
+		"id":        span.ID,
+		"traceId":   span.TraceID,
+		"name":      span.Name,
+		"startTime": span.StartTime,
+		"endTime":   span.EndTime,
+		"input":     span.Input,
+		"output":    span.Output,
+		"metadata":  metadata,
+	}
+	// the children of the root are attached to the trace
+	if span.ParentID != span.TraceID {
+		body["parentObservationId"] = span.ParentID
+	}
+	if span.Error != "" {
+		body["level"] = "ERROR"
+		body["statusMessage"] = span.Error
+	}
+
+	typ := "span-create"
+	if span.Kind == SpanGeneration {
+		typ = "generation-create"
+		body["model"] = span.Model
+		if span.Usage != nil {
+			body["usage"] = map[string]any{
+				"input":  span.Usage.InputTokens,
+				"output": span.Usage.OutputTokens,
+				"total":  span.Usage.TotalTokens,
+				"unit":   "TOKENS",
+			}
+		}
+	}
+	return langfuseEvent{
+		ID:        uuid.NewString(),
+		Type:      typ,
+		Timestamp: span.EndTime,
+		Body:      body,
+	}
+}
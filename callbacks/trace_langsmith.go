@@ -0,0 +1,173 @@
+package callbacks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultLangSmithEndpoint is the endpoint of the LangSmith cloud.
+const DefaultLangSmithEndpoint = "https://api.smith.langchain.com"
+
+// ensure LangSmithBackend implements TraceBackend
+var _ TraceBackend = (*LangSmithBackend)(nil)
+
+// LangSmithBackend exports the spans to the LangSmith runs API.
+// The runs of the assistants are exported as the chain runs,
+// the LLM calls as the llm runs, and the tool calls as the tool runs.
+type LangSmithBackend struct {
+	endpoint   string
+	apiKey     string
+	project    string
+	httpClient *http.Client
+}
+
+// NewLangSmithBackend returns TraceBackend for LangSmith,
+// if the endpoint is empty DefaultLangSmithEndpoint is used,
+// if the project is empty the runs are added to the default project.
+func NewLangSmithBackend(endpoint, apiKey, project string) *LangSmithBackend {
+	if endpoint == "" {
+		endpoint = DefaultLangSmithEndpoint
+	}
+	return &LangSmithBackend{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		apiKey:     apiKey,
+		project:    project,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the runs API calls.
+func (b *LangSmithBackend) WithHTTPClient(client *http.Client) *LangSmithBackend {
+	b.httpClient = client
+	return b
+}
+
+type langSmithRun struct {
+	ID          string         `json:"id"`
+	TraceID     string         `json:"trace_id"`
+	DottedOrder string         `json:"dotted_order"`
+	ParentRunID string         `json:"parent_run_id,omitempty"`
+	Name        string         `json:"name"`
+	RunType     string         `json:"run_type"`
+	Inputs      map[string]any `json:"inputs"`
+	Outputs     map[string]any `json:"outputs,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	StartTime   time.Time      `json:"start_time"`
+	EndTime     time.Time      `json:"end_time"`
+	SessionName string         `json:"session_name,omitempty"`
+	Extra       map[string]any `json:"extra,omitempty"`
+}
+
+// Export sends the spans to the batch ingestion of the runs.
+func (b *LangSmithBackend) Export(ctx context.Context, spans []*TraceSpan) error {
+	runs := make([]langSmithRun, 0, len(spans))
+	for _, span := range spans {
+		runs = append(runs, b.runOf(span))
+	}
+	body, err := json.Marshal(map[string]any{"post": runs})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint+"/runs/batch", bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", b.apiKey)
+	res, err := b.httpClient.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return errors.Newf("langsmith returned unexpected status code: %d: %s", res.StatusCode, string(msg))
+	}
+	return nil
+}
+
+func (b *LangSmithBackend) runOf(span *TraceSpan) langSmithRun {
+	metadata := make(map[string]any, len(span.Metadata)+2)
+	for k, v := range span.Metadata {
+		metadata[k] = v
+	}
+	if span.SessionID != "" {
+		metadata["session_id"] = span.SessionID
+	}
+	if span.UserID != "" {
+		metadata["user_id"] = span.UserID
+	}
+
+	run := langSmithRun{
+		ID:          span.ID,
+		TraceID:     span.TraceID,
+		DottedOrder: langSmithDottedOrder(span),
+		ParentRunID: span.ParentID,
+		Name:        span.Name,
+		Error:       span.Error,
+		StartTime:   span.StartTime,
+		EndTime:     span.EndTime,
+		SessionName: b.project,
+	}
+
+	switch span.Kind {
+	case SpanGeneration:
+		run.RunType = "llm"
+		run.Inputs = map[string]any{"messages": span.Input}
+		if span.Output != nil {
+			run.Outputs = map[string]any{"choices": span.Output}
+		}
+		if span.Usage != nil {
+			if run.Outputs == nil {
+				run.Outputs = map[string]any{}
+			}
+			run.Outputs["usage_metadata"] = map[string]any{
+				"input_tokens":  span.Usage.InputTokens,
+				"output_tokens": span.Usage.OutputTokens,
+				"total_tokens":  span.Usage.TotalTokens,
+			}
+		}
+		metadata["ls_model_name"] = span.Model
+	case SpanTool:
+		run.RunType = "tool"
+		run.Inputs = map[string]any{"input": span.Input}
+		if span.Error == "" {
+			run.Outputs = map[string]any{"output": span.Output}
+		}
+	default:
+		run.RunType = "chain"
+		run.Inputs = map[string]any{"input": span.Input}
+		if span.Error == "" {
+			run.Outputs = map[string]any{"output": span.Output}
+		}
+	}
+	run.Extra = map[string]any{"metadata": metadata}
+	return run
+}
+
+// langSmithDottedOrder returns the order of the run in the trace,
+// the start time and the ID of the ancestors and the run separated by the dot.
+func langSmithDottedOrder(span *TraceSpan) string {
+	var b strings.Builder
+	for _, ref := range span.Ancestors {
+		b.WriteString(langSmithOrderPart(ref.StartTime, ref.ID))
+		b.WriteString(".")
+	}
+	b.WriteString(langSmithOrderPart(span.StartTime, span.ID))
+	return b.String()
+}
+
+func langSmithOrderPart(t time.Time, id string) string {
+	t = t.UTC()
+	return fmt.Sprintf("%s%06dZ%s", t.Format("20060102T150405"), t.Nanosecond()/1000, id)
+}
@@ -0,0 +1,353 @@
+package callbacks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/tools"
+)
+
+// ensure TranscriptRecorder implements assistants.Callback
+var (
+	_ assistants.Callback         = (*TranscriptRecorder)(nil)
+	_ assistants.RecoveryCallback = (*TranscriptRecorder)(nil)
+)
+
+// TranscriptFormat defines the serialization format of the transcript.
+type TranscriptFormat int
+
+const (
+	// TranscriptJSONL writes one compact JSON record per line.
+	TranscriptJSONL TranscriptFormat = iota
+	// TranscriptJSONIndent writes indented JSON records separated by a new line.
+	TranscriptJSONIndent
+)
+
+// Transcript event names
+const (
+	EventAssistantStart = "assistant_start"
+	EventAssistantEnd   = "assistant_end"
+	EventAssistantError = "assistant_error"
+	EventLLMCallStart   = "llm_call_start"
+	EventLLMCallEnd     = "llm_call_end"
+	EventLLMParseError  = "llm_parse_error"
+	EventToolStart      = "tool_start"
+	EventToolEnd        = "tool_end"
+	EventToolError      = "tool_error"
+	EventToolNotFound   = "tool_not_found"
+	EventToolCancelled  = "tool_cancelled"
+	EventRetry          = "retry"
+	EventRateLimit      = "rate_limit"
+)
+
+// TranscriptRecord is a single entry of the transcript.
+// Messages are serialized with the llms.Message JSON marshaling,
+// so the transcript can be loaded back as llms.Messages.
+type TranscriptRecord struct {
+	Time      time.Time             `json:"time"`
+	Event     string                `json:"event"`
+	ChatID    string                `json:"chat_id,omitempty"`
+	RunID     string                `json:"run_id,omitempty"`
+	ActionID  string                `json:"action_id,omitempty"`
+	Assistant string                `json:"assistant,omitempty"`
+	Model     string                `json:"model,omitempty"`
+	Tool      string                `json:"tool,omitempty"`
+	Input     string                `json:"input,omitempty"`
+	Output    string                `json:"output,omitempty"`
+	Error     string                `json:"error,omitempty"`
+	Messages  []llms.Message        `json:"messages,omitempty"`
+	Choices   []*llms.ContentChoice `json:"choices,omitempty"`
+	Usage     *llms.Usage           `json:"usage,omitempty"`
+	Reason    string                `json:"reason,omitempty"`
+	Attempt   int                   `json:"attempt,omitempty"`
+	Wait      time.Duration         `json:"wait,omitempty"`
+}
+
+// RedactFunc is called for every record before it is written.
+// The record owns copies of the messages and choices, so it is safe to modify them.
+type RedactFunc func(rec *TranscriptRecord)
+
+// TranscriptRecorder is a callback handler that serializes every LLM request,
+// response, tool call, and error to the Writer, for offline debugging and dataset building.
+type TranscriptRecorder struct {
+	out     io.Writer
+	format  TranscriptFormat
+	redacts []RedactFunc
+
+	lock sync.Mutex
+}
+
+// NewTranscriptRecorder returns a new TranscriptRecorder writing to w.
+func NewTranscriptRecorder(w io.Writer, format TranscriptFormat) *TranscriptRecorder {
+	return &TranscriptRecorder{
+		out:    w,
+		format: format,
+	}
+}
+
+// WithRedaction adds redaction hooks, applied in order to every record before it is written.
+func (l *TranscriptRecorder) WithRedaction(fns ...RedactFunc) *TranscriptRecorder {
+	l.redacts = append(l.redacts, fns...)
+	return l
+}
+
+// RedactStrings returns RedactFunc that applies fn to the textual content of the record:
+// input, output, error, text parts, tool call arguments, tool responses, and choices.
+func RedactStrings(fn func(string) string) RedactFunc {
+	return func(rec *TranscriptRecord) {
+		rec.Input = fn(rec.Input)
+		rec.Output = fn(rec.Output)
+		rec.Error = fn(rec.Error)
+		for i := range rec.Messages {
+			parts := rec.Messages[i].Parts
+			for j, part := range parts {
+				switch p := part.(type) {
+				case llms.TextContent:
+					p.Text = fn(p.Text)
+					parts[j] = p
+				case llms.ToolCall:
+					if p.FunctionCall != nil {
+						fc := *p.FunctionCall
+						fc.Arguments = fn(fc.Arguments)
+						p.FunctionCall = &fc
+					}
+					parts[j] = p
+				case llms.ToolCallResponse:
+					p.Content = fn(p.Content)
+					parts[j] = p
+				}
+			}
+		}
+		for _, choice := range rec.Choices {
+			choice.Content = fn(choice.Content)
+			choice.ReasoningContent = fn(choice.ReasoningContent)
+			for j, tc := range choice.ToolCalls {
+				if tc.FunctionCall != nil {
+					fc := *tc.FunctionCall
+					fc.Arguments = fn(fc.Arguments)
+					choice.ToolCalls[j].FunctionCall = &fc
+				}
+			}
+		}
+	}
+}
+
+// RedactSecrets returns RedactFunc that masks the secrets with the redactor:
+// the API keys and the credentials in the textual content of the record,
+// and the sensitive arguments of the tools registered with llmutils.WithToolSchema.
+func RedactSecrets(r *llmutils.Redactor) RedactFunc {
+	return func(rec *TranscriptRecord) {
+		if rec.Tool != "" {
+			rec.Input = r.ToolArguments(rec.Tool, rec.Input)
+		} else {
+			rec.Input = r.String(rec.Input)
+		}
+		rec.Output = r.String(rec.Output)
+		rec.Error = r.String(rec.Error)
+		rec.Messages = r.Messages(rec.Messages)
+		rec.Choices = r.Choices(rec.Choices)
+	}
+}
+
+func (l *TranscriptRecorder) newRecord(ctx context.Context, event string) *TranscriptRecord {
+	rec := &TranscriptRecord{
+		Time:     TimeNowFn().UTC(),
+		Event:    event,
+		ActionID: chatmodel.GetActionID(ctx),
+	}
+	if chatCtx := chatmodel.GetChatContext(ctx); chatCtx != nil {
+		rec.ChatID = chatCtx.GetChatID()
+		rec.RunID = chatCtx.GetRunID()
+	}
+	return rec
+}
+
+func (l *TranscriptRecorder) write(rec *TranscriptRecord) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, fn := range l.redacts {
+		fn(rec)
+	}
+
+	var (
+		js  []byte
+		err error
+	)
+	if l.format == TranscriptJSONIndent {
+		js, err = json.MarshalIndent(rec, "", "  ")
+	} else {
+		js, err = json.Marshal(rec)
+	}
+	if err != nil {
+		js, _ = json.Marshal(&TranscriptRecord{
+			Time:  rec.Time,
+			Event: rec.Event,
+			Error: "failed to marshal transcript record: " + err.Error(),
+		})
+	}
+	_, _ = l.out.Write(js)
+	_, _ = l.out.Write([]byte("\n"))
+}
+
+func (l *TranscriptRecorder) OnAssistantStart(ctx context.Context, e *assistants.AssistantStartEvent) {
+	rec := l.newRecord(ctx, EventAssistantStart)
+	rec.Assistant = e.Assistant.Name()
+	rec.Input = e.Input
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnAssistantEnd(ctx context.Context, e *assistants.AssistantEndEvent) {
+	rec := l.newRecord(ctx, EventAssistantEnd)
+	rec.Assistant = e.Assistant.Name()
+	rec.Input = e.Input
+	if e.Response != nil {
+		rec.Output = e.Response.String()
+		rec.Messages = cloneMessages(e.Response.Messages)
+		usage := e.Response.Usage.Usage
+		rec.Usage = &usage
+	}
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnAssistantError(ctx context.Context, e *assistants.AssistantErrorEvent) {
+	rec := l.newRecord(ctx, EventAssistantError)
+	rec.Assistant = e.Assistant.Name()
+	rec.Input = e.Input
+	rec.Error = errorString(e.Err)
+	rec.Messages = cloneMessages(e.Messages)
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnAssistantLLMCallStart(ctx context.Context, e *assistants.LLMCallStartEvent) {
+	rec := l.newRecord(ctx, EventLLMCallStart)
+	rec.Assistant = e.Assistant.Name()
+	rec.Model = e.LLM.GetName()
+	rec.Messages = cloneMessages(e.Messages)
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnAssistantLLMCallEnd(ctx context.Context, e *assistants.LLMCallEndEvent) {
+	rec := l.newRecord(ctx, EventLLMCallEnd)
+	rec.Assistant = e.Assistant.Name()
+	rec.Model = e.LLM.GetName()
+	if e.Response != nil {
+		rec.Choices = cloneChoices(e.Response.Choices)
+		rec.Usage = e.Response.Usage()
+	}
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnAssistantLLMParseError(ctx context.Context, e *assistants.LLMParseErrorEvent) {
+	rec := l.newRecord(ctx, EventLLMParseError)
+	rec.Assistant = e.Assistant.Name()
+	rec.Input = e.Input
+	rec.Output = e.Response
+	rec.Error = errorString(e.Err)
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
+	rec := l.newRecord(ctx, EventToolStart)
+	rec.Assistant = e.Assistant
+	rec.Tool = e.Tool.Name()
+	rec.Input = e.Input
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
+	rec := l.newRecord(ctx, EventToolEnd)
+	rec.Assistant = e.Assistant
+	rec.Tool = e.Tool.Name()
+	rec.Input = e.Input
+	rec.Output = e.Output
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
+	rec := l.newRecord(ctx, EventToolError)
+	rec.Assistant = e.Assistant
+	rec.Tool = e.Tool.Name()
+	rec.Input = e.Input
+	rec.Error = errorString(e.Err)
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnToolNotFound(ctx context.Context, e *assistants.ToolNotFoundEvent) {
+	rec := l.newRecord(ctx, EventToolNotFound)
+	rec.Assistant = e.Assistant.Name()
+	rec.Tool = e.Tool
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnToolCancelled(ctx context.Context, e *assistants.ToolCancelledEvent) {
+	rec := l.newRecord(ctx, EventToolCancelled)
+	rec.Assistant = e.Assistant.Name()
+	rec.Tool = e.Tool
+	rec.Input = e.Input
+	rec.Output = e.Partial
+	rec.Error = errorString(e.Cause)
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnRetry(ctx context.Context, e *assistants.RetryEvent) {
+	rec := l.newRecord(ctx, EventRetry)
+	rec.Assistant = e.Assistant.Name()
+	rec.Reason = string(e.Reason)
+	rec.Attempt = e.Attempt
+	rec.Error = errorString(e.Err)
+	l.write(rec)
+}
+
+func (l *TranscriptRecorder) OnRateLimit(ctx context.Context, e *assistants.RateLimitEvent) {
+	rec := l.newRecord(ctx, EventRateLimit)
+	rec.Assistant = e.Assistant.Name()
+	rec.Model = e.LLM.GetName()
+	rec.Attempt = e.Attempt
+	rec.Wait = e.Wait
+	l.write(rec)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// cloneMessages returns a copy of the messages with copied parts,
+// so the redaction hooks do not modify the message history.
+func cloneMessages(msgs []llms.Message) []llms.Message {
+	if len(msgs) == 0 {
+		return nil
+	}
+	res := make([]llms.Message, len(msgs))
+	for i, m := range msgs {
+		res[i] = m
+		res[i].Parts = append([]llms.ContentPart(nil), m.Parts...)
+	}
+	return res
+}
+
+func cloneChoices(choices []*llms.ContentChoice) []*llms.ContentChoice {
+	if len(choices) == 0 {
+		return nil
+	}
+	res := make([]*llms.ContentChoice, 0, len(choices))
+	for _, c := range choices {
+		if c == nil {
+			continue
+		}
+		cc := *c
+		cc.ToolCalls = append([]llms.ToolCall(nil), c.ToolCalls...)
+		res = append(res, &cc)
+	}
+	return res
+}
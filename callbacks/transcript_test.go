@@ -0,0 +1,200 @@
+package callbacks_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/callbacks"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	cb := callbacks.NewTranscriptRecorder(&buf, callbacks.TranscriptJSONL)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+	ctx = chatmodel.WithActionID(ctx, "action1")
+
+	ast := &fakeAssistant{name: "test-assistant"}
+	tool := &fakeTool{name: "test-tool"}
+	model := &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}
+
+	history := []llms.Message{
+		llms.MessageFromTextParts(llms.RoleSystem, "You are a helpful assistant."),
+		llms.MessageFromTextParts(llms.RoleHuman, "test input"),
+	}
+
+	cb.OnAssistantStart(ctx, &assistants.AssistantStartEvent{Assistant: ast, Input: "test input"})
+	cb.OnAssistantLLMCallStart(ctx, &assistants.LLMCallStartEvent{Assistant: ast, LLM: model, Messages: history})
+	cb.OnAssistantLLMCallEnd(ctx, &assistants.LLMCallEndEvent{Assistant: ast, LLM: model, Response: &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				Content: "test output",
+				Usage:   llms.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
+			},
+		},
+	}})
+	cb.OnToolStart(ctx, &tools.ToolStartEvent{Tool: tool, Assistant: "test-assistant", Input: "tool input"})
+	cb.OnToolEnd(ctx, &tools.ToolEndEvent{Tool: tool, Assistant: "test-assistant", Input: "tool input", Output: "tool output"})
+	cb.OnToolError(ctx, &tools.ToolErrorEvent{Tool: tool, Assistant: "test-assistant", Input: "tool input", Err: errors.New("tool error")})
+	cb.OnToolNotFound(ctx, &assistants.ToolNotFoundEvent{Assistant: ast, Tool: "missing-tool"})
+	cb.OnToolCancelled(ctx, &assistants.ToolCancelledEvent{Assistant: ast, Tool: "slow-tool", Input: "tool input", Partial: "partial output", Cause: context.Canceled})
+	cb.OnAssistantLLMParseError(ctx, &assistants.LLMParseErrorEvent{Assistant: ast, Input: "test input", Response: "bad output", Err: errors.New("parse error")})
+	cb.OnAssistantError(ctx, &assistants.AssistantErrorEvent{Assistant: ast, Input: "test input", Err: errors.New("test error"), Messages: history})
+	cb.OnAssistantEnd(ctx, &assistants.AssistantEndEvent{Assistant: ast, Input: "test input", Response: &assistants.Response{
+		Choices: []*llms.ContentChoice{
+			{Content: "test output"},
+		},
+		Messages: history,
+	}, Messages: history})
+	cb.OnRetry(ctx, &assistants.RetryEvent{Assistant: ast, Reason: assistants.RetryReasonEmptyResponse, Attempt: 1})
+	cb.OnRateLimit(ctx, &assistants.RateLimitEvent{Assistant: ast, LLM: model, Attempt: 2, Wait: time.Second})
+
+	var recs []callbacks.TranscriptRecord
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var rec callbacks.TranscriptRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		recs = append(recs, rec)
+	}
+	require.Len(t, recs, 13)
+
+	events := make([]string, len(recs))
+	for i, rec := range recs {
+		events[i] = rec.Event
+		assert.Equal(t, "chat1", rec.ChatID)
+		assert.Equal(t, "action1", rec.ActionID)
+		assert.False(t, rec.Time.IsZero())
+	}
+	assert.Equal(t, []string{
+		callbacks.EventAssistantStart,
+		callbacks.EventLLMCallStart,
+		callbacks.EventLLMCallEnd,
+		callbacks.EventToolStart,
+		callbacks.EventToolEnd,
+		callbacks.EventToolError,
+		callbacks.EventToolNotFound,
+		callbacks.EventToolCancelled,
+		callbacks.EventLLMParseError,
+		callbacks.EventAssistantError,
+		callbacks.EventAssistantEnd,
+		callbacks.EventRetry,
+		callbacks.EventRateLimit,
+	}, events)
+
+	start := recs[1]
+	assert.Equal(t, "gpt-4o", start.Model)
+	require.Len(t, start.Messages, 2)
+	assert.Equal(t, llms.RoleHuman, start.Messages[1].Role)
+	assert.Equal(t, llms.TextContent{Text: "test input"}, start.Messages[1].Parts[0])
+
+	end := recs[2]
+	require.Len(t, end.Choices, 1)
+	assert.Equal(t, "test output", end.Choices[0].Content)
+	require.NotNil(t, end.Usage)
+	assert.EqualValues(t, 15, end.Usage.TotalTokens)
+
+	assert.Equal(t, "test-tool", recs[5].Tool)
+	assert.Equal(t, "tool error", recs[5].Error)
+	assert.Equal(t, "missing-tool", recs[6].Tool)
+	assert.Equal(t, "slow-tool", recs[7].Tool)
+	assert.Equal(t, "partial output", recs[7].Output)
+	assert.Equal(t, "context canceled", recs[7].Error)
+	assert.Equal(t, "test output", recs[10].Output)
+	assert.Equal(t, "empty_response", recs[11].Reason)
+	assert.Equal(t, 1, recs[11].Attempt)
+	assert.Equal(t, "gpt-4o", recs[12].Model)
+	assert.Equal(t, 2, recs[12].Attempt)
+	assert.Equal(t, time.Second, recs[12].Wait)
+}
+
+func TestTranscriptRecorder_Redaction(t *testing.T) {
+	var buf bytes.Buffer
+	cb := callbacks.NewTranscriptRecorder(&buf, callbacks.TranscriptJSONIndent).
+		WithRedaction(callbacks.RedactStrings(func(s string) string {
+			return strings.ReplaceAll(s, "secret", "[REDACTED]")
+		}))
+
+	ast := &fakeAssistant{name: "test-assistant"}
+	model := &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}
+
+	history := []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "my secret is 42"),
+		llms.MessageFromToolCalls(llms.RoleAI, llms.ToolCall{
+			ID:   "call1",
+			Type: "function",
+			FunctionCall: &llms.FunctionCall{
+				Name:      "lookup",
+				Arguments: `{"q":"secret"}`,
+			},
+		}),
+		llms.MessageFromToolResponse(llms.RoleTool, llms.ToolCallResponse{
+			ToolCallID: "call1",
+			Name:       "lookup",
+			Content:    "the secret",
+		}),
+	}
+
+	cb.OnAssistantLLMCallStart(context.Background(), &assistants.LLMCallStartEvent{Assistant: ast, LLM: model, Messages: history})
+	cb.OnToolStart(context.Background(), &tools.ToolStartEvent{Tool: &fakeTool{name: "lookup"}, Assistant: "test-assistant", Input: `{"q":"secret"}`})
+
+	res := buf.String()
+	assert.NotContains(t, res, "secret")
+	assert.Contains(t, res, "[REDACTED]")
+	assert.Contains(t, res, "\n  \"event\"")
+
+	// the original history must not be modified
+	assert.Equal(t, llms.TextContent{Text: "my secret is 42"}, history[0].Parts[0])
+	assert.Equal(t, `{"q":"secret"}`, history[1].Parts[0].(llms.ToolCall).FunctionCall.Arguments)
+	assert.Equal(t, "the secret", history[2].Parts[0].(llms.ToolCallResponse).Content)
+}
+
+func TestTranscriptRecorder_RedactSecrets(t *testing.T) {
+	params := &jsonschema.Schema{
+		Type:       "object",
+		Properties: jsonschema.NewProperties(),
+	}
+	params.Properties.Set("user", &jsonschema.Schema{Type: "string"})
+	params.Properties.Set("token", &jsonschema.Schema{Type: "string", Extras: map[string]any{"x-sensitive": "true"}})
+
+	var buf bytes.Buffer
+	cb := callbacks.NewTranscriptRecorder(&buf, callbacks.TranscriptJSONL).
+		WithRedaction(callbacks.RedactSecrets(llmutils.NewRedactor(llmutils.WithToolSchema("login", params))))
+
+	ast := &fakeAssistant{name: "test-assistant"}
+	model := &fakeModel{name: "gpt-4o", provider: llms.ProviderOpenAI}
+
+	args := `{"user":"bob","token":"t0ps3cr3t"}`
+	cb.OnAssistantStart(context.Background(), &assistants.AssistantStartEvent{Assistant: ast, Input: "use the key sk-proj-abcdefghijklmnopqrstuv"})
+	cb.OnAssistantLLMCallEnd(context.Background(), &assistants.LLMCallEndEvent{Assistant: ast, LLM: model, Response: &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			ToolCalls: []llms.ToolCall{{
+				ID:           "call1",
+				Type:         "function",
+				FunctionCall: &llms.FunctionCall{Name: "login", Arguments: args},
+			}},
+		}},
+	}})
+	cb.OnToolStart(context.Background(), &tools.ToolStartEvent{Tool: &fakeTool{name: "login"}, Assistant: "test-assistant", Input: args})
+	cb.OnToolError(context.Background(), &tools.ToolErrorEvent{Tool: &fakeTool{name: "login"}, Assistant: "test-assistant", Input: args, Err: errors.New("401: Authorization: Bearer abcdefgh12345678")})
+
+	res := buf.String()
+	assert.NotContains(t, res, "sk-proj-abcdefghijklmnopqrstuv")
+	assert.NotContains(t, res, "t0ps3cr3t")
+	assert.NotContains(t, res, "abcdefgh12345678")
+	assert.Contains(t, res, "bob")
+	assert.Contains(t, res, llmutils.DefaultMask)
+}
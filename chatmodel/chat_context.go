@@ -2,11 +2,10 @@ package chatmodel
 
 import (
 	"context"
-	"strconv"
 	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
-	"github.com/effective-security/xdb/pkg/flake"
 )
 
 var (
@@ -41,6 +40,20 @@ type ChatContext interface {
 	GetOrgID() string
 	// SetOrgID updates the org ID in the context
 	SetOrgID(id string)
+	// GetUserID retrieves the ID of the user on whose behalf the chat runs
+	GetUserID() string
+	// SetUserID updates the user ID in the context
+	SetUserID(id string)
+	// GetLocale retrieves the BCP 47 locale of the user, for example "en-US"
+	GetLocale() string
+	// SetLocale updates the locale in the context
+	SetLocale(locale string)
+	// GetTimezone retrieves the IANA time zone of the user, for example "America/New_York"
+	GetTimezone() string
+	// SetTimezone updates the time zone in the context
+	SetTimezone(tz string)
+	// Metadata returns a copy of all metadata
+	Metadata() map[string]any
 }
 
 type chatContext struct {
@@ -48,6 +61,9 @@ type chatContext struct {
 	tenantID string
 	chatID   string
 	runID    string
+	userID   string
+	locale   string
+	timezone string
 	metadata sync.Map
 	appData  any
 }
@@ -81,9 +97,34 @@ func (c *chatContext) SetOrgID(id string) {
 	c.orgID = id
 }
 
+func (c *chatContext) GetUserID() string {
+	return c.userID
+}
+
+func (c *chatContext) SetUserID(id string) {
+	c.userID = id
+}
+
+func (c *chatContext) GetLocale() string {
+	return c.locale
+}
+
+func (c *chatContext) SetLocale(locale string) {
+	c.locale = locale
+}
+
+func (c *chatContext) GetTimezone() string {
+	return c.timezone
+}
+
+func (c *chatContext) SetTimezone(tz string) {
+	c.timezone = tz
+}
+
 func (c *chatContext) AppData() any {
 	return c.appData
 }
+
 func (c *chatContext) GetMetadata(key string) (value any, ok bool) {
 	return c.metadata.Load(key)
 }
@@ -92,6 +133,17 @@ func (c *chatContext) SetMetadata(key string, value any) {
 	c.metadata.Store(key, value)
 }
 
+func (c *chatContext) Metadata() map[string]any {
+	m := map[string]any{}
+	c.metadata.Range(func(k, v any) bool {
+		if key, ok := k.(string); ok {
+			m[key] = v
+		}
+		return true
+	})
+	return m
+}
+
 func NewChatContext(tenantID, chatID string, appData any) ChatContext {
 	if tenantID == "" {
 		tenantID = NewChatID()
@@ -114,6 +166,7 @@ type contextKey int
 const (
 	keyChatContext contextKey = iota
 	keyActionID
+	keyIdempotencyKey
 )
 
 // WithChatContext returns a new context with ChatContext value
@@ -127,6 +180,14 @@ func WithActionID(ctx context.Context, actionID string) context.Context {
 	return context.WithValue(ctx, keyActionID, actionID)
 }
 
+// WithIdempotencyKey returns a new context with the idempotency key.
+// The assistant sets the key of the run, and the derived key of each tool call,
+// so the tools with the side effects can pass it to the external services,
+// and the repeated run does not repeat the side effects.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyIdempotencyKey, key)
+}
+
 // GetChatContext retrieves the ChatContext from the context
 func GetChatContext(ctx context.Context) ChatContext {
 	if v, ok := ctx.Value(keyChatContext).(ChatContext); ok {
@@ -143,6 +204,14 @@ func GetActionID(ctx context.Context) string {
 	return ""
 }
 
+// GetIdempotencyKey retrieves the idempotency key from the context
+func GetIdempotencyKey(ctx context.Context) string {
+	if v, ok := ctx.Value(keyIdempotencyKey).(string); ok {
+		return v
+	}
+	return ""
+}
+
 // NewFromContext returns new Background context with ChatContext from incoming context.
 // This is useful for passing the chat context to the background context of a service.
 func NewFromContext(ctx context.Context) context.Context {
@@ -153,9 +222,15 @@ func NewFromContext(ctx context.Context) context.Context {
 	return WithChatContext(context.Background(), chatCtx)
 }
 
+// SetChatID updates the chat ID of the ChatContext in the context,
+// the ID is normalized with the current format, see NormalizeID.
 func SetChatID(ctx context.Context, chatID string) (context.Context, error) {
 	if v, ok := ctx.Value(keyChatContext).(ChatContext); ok {
-		v.SetChatID(chatID)
+		id, err := NormalizeID(chatID)
+		if err != nil {
+			return nil, errors.WithMessage(err, "chat ID")
+		}
+		v.SetChatID(id)
 		return ctx, nil
 	}
 	return nil, errors.WithStack(ErrInvalidChatContext)
@@ -179,7 +254,38 @@ func GetOrgID(ctx context.Context) string {
 	return "main"
 }
 
-// NewChatID generates a new chat ID using the flake ID generator.
+// GetUserID retrieves the user ID from the provided context.
+// If the context does not contain a ChatContext, it returns empty string.
+func GetUserID(ctx context.Context) string {
+	if v, ok := ctx.Value(keyChatContext).(ChatContext); ok {
+		return v.GetUserID()
+	}
+	return ""
+}
+
+// GetLocale retrieves the locale from the provided context.
+// If the context does not contain a ChatContext, it returns empty string.
+func GetLocale(ctx context.Context) string {
+	if v, ok := ctx.Value(keyChatContext).(ChatContext); ok {
+		return v.GetLocale()
+	}
+	return ""
+}
+
+// GetLocation returns the time zone location of the user from the provided context.
+// If the context does not contain a ChatContext, or the time zone is not set or invalid,
+// it returns UTC.
+func GetLocation(ctx context.Context) *time.Location {
+	if v, ok := ctx.Value(keyChatContext).(ChatContext); ok && v.GetTimezone() != "" {
+		if loc, err := time.LoadLocation(v.GetTimezone()); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// NewChatID generates a new chat ID using the current ID format,
+// by default the flake ID generator. See SetIDFormat.
 func NewChatID() string {
-	return strconv.FormatUint(flake.DefaultIDGenerator.NextID(), 10)
+	return GetIDFormat().NewID()
 }
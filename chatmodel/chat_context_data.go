@@ -0,0 +1,115 @@
+package chatmodel
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ChatContextData is the serializable form of ChatContext,
+// used to pass the chat context across the process boundaries,
+// for example in the _meta of MCP requests or in HTTP headers.
+// AppData is not serialized, as it is specific to the process.
+type ChatContextData struct {
+	OrgID    string         `json:"org_id,omitempty" yaml:"org_id,omitempty"`
+	TenantID string         `json:"tenant_id" yaml:"tenant_id"`
+	ChatID   string         `json:"chat_id" yaml:"chat_id"`
+	RunID    string         `json:"run_id,omitempty" yaml:"run_id,omitempty"`
+	UserID   string         `json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	Locale   string         `json:"locale,omitempty" yaml:"locale,omitempty"`
+	Timezone string         `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// ExportChatContext returns the serializable data of the chat context.
+// The metadata values must be JSON serializable to be encoded.
+func ExportChatContext(c ChatContext) *ChatContextData {
+	data := &ChatContextData{
+		OrgID:    c.GetOrgID(),
+		TenantID: c.GetTenantID(),
+		ChatID:   c.GetChatID(),
+		RunID:    c.GetRunID(),
+		UserID:   c.GetUserID(),
+		Locale:   c.GetLocale(),
+		Timezone: c.GetTimezone(),
+	}
+	if md := c.Metadata(); len(md) > 0 {
+		data.Metadata = md
+	}
+	return data
+}
+
+// ChatContext returns a new ChatContext from the data with the app data.
+// The missing tenant and chat IDs are generated as in NewChatContext,
+// the run ID is preserved when provided.
+func (d *ChatContextData) ChatContext(appData any) ChatContext {
+	c := NewChatContext(d.TenantID, d.ChatID, appData).(*chatContext)
+	if d.OrgID != "" {
+		c.orgID = d.OrgID
+	}
+	if d.RunID != "" {
+		c.runID = d.RunID
+	}
+	c.userID = d.UserID
+	c.locale = d.Locale
+	c.timezone = d.Timezone
+	for k, v := range d.Metadata {
+		c.metadata.Store(k, v)
+	}
+	return c
+}
+
+// Encode returns the base64 URL encoded JSON of the data,
+// suitable for HTTP headers and gRPC metadata.
+func (d *ChatContextData) Encode() (string, error) {
+	js, err := json.Marshal(d)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal chat context")
+	}
+	return base64.RawURLEncoding.EncodeToString(js), nil
+}
+
+// DecodeChatContextData decodes the data returned by ChatContextData.Encode
+func DecodeChatContextData(s string) (*ChatContextData, error) {
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode chat context")
+	}
+	data := new(ChatContextData)
+	if err = json.Unmarshal(js, data); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal chat context")
+	}
+	if data.TenantID == "" {
+		return nil, errors.WithStack(ErrInvalidChatContext)
+	}
+	if err = data.Normalize(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Normalize normalizes the tenant ID and the chat ID, if provided, with the current format,
+// see NormalizeID.
+func (d *ChatContextData) Normalize() error {
+	var err error
+	if d.TenantID, err = NormalizeID(d.TenantID); err != nil {
+		return errors.WithMessage(err, "tenant ID")
+	}
+	if d.ChatID != "" {
+		if d.ChatID, err = NormalizeID(d.ChatID); err != nil {
+			return errors.WithMessage(err, "chat ID")
+		}
+	}
+	return nil
+}
+
+// GetChatContextData returns the serializable data of the ChatContext from the context,
+// or nil if the context does not contain a ChatContext.
+func GetChatContextData(ctx context.Context) *ChatContextData {
+	if c := GetChatContext(ctx); c != nil {
+		return ExportChatContext(c)
+	}
+	return nil
+}
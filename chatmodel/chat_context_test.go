@@ -3,7 +3,9 @@ package chatmodel
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -51,10 +53,13 @@ func TestContextPlumbing(t *testing.T) {
 	got := GetChatContext(ctx)
 	assert.Equal(t, c, got)
 
-	// SetChatID successful
-	newctx, err := SetChatID(ctx, "bar")
+	// SetChatID successful, the ID is normalized
+	newctx, err := SetChatID(ctx, " bar ")
 	require.NoError(t, err)
 	assert.Equal(t, "bar", GetChatContext(newctx).GetChatID())
+	_, err = SetChatID(ctx, " ")
+	assert.True(t, errors.Is(err, ErrInvalidID))
+	assert.Equal(t, "bar", GetChatContext(ctx).GetChatID())
 
 	// GetTenantAndChatID
 	tenant, chat, err := GetTenantAndChatID(ctx)
@@ -69,6 +74,10 @@ func TestContextPlumbing(t *testing.T) {
 	// Nil context returns background
 	bc := NewFromContext(context.Background())
 	assert.Nil(t, GetChatContext(bc))
+
+	// idempotency key
+	assert.Empty(t, GetIdempotencyKey(ctx))
+	assert.Equal(t, "run-1", GetIdempotencyKey(WithIdempotencyKey(ctx, "run-1")))
 }
 
 func TestGetSetChatID_Error(t *testing.T) {
@@ -87,3 +96,97 @@ func TestNewChatID_Unique(t *testing.T) {
 	id2 := NewChatID()
 	assert.NotEqual(t, id1, id2)
 }
+
+func TestChatContext_User(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	assert.Empty(t, GetUserID(ctx))
+	assert.Empty(t, GetLocale(ctx))
+	assert.Equal(t, time.UTC, GetLocation(ctx))
+
+	c := NewChatContext("tid", "cid", nil)
+	c.SetUserID("uid")
+	c.SetLocale("fr-FR")
+	c.SetTimezone("Europe/Paris")
+	ctx = WithChatContext(ctx, c)
+
+	assert.Equal(t, "uid", GetUserID(ctx))
+	assert.Equal(t, "fr-FR", GetLocale(ctx))
+	assert.Equal(t, "Europe/Paris", GetLocation(ctx).String())
+
+	c.SetTimezone("Invalid/Zone")
+	assert.Equal(t, time.UTC, GetLocation(ctx))
+}
+
+func TestChatContextData(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, GetChatContextData(context.Background()))
+
+	c := NewChatContext("tid", "cid", "app")
+	c.SetOrgID("org")
+	c.SetUserID("uid")
+	c.SetLocale("en-GB")
+	c.SetTimezone("Europe/London")
+	c.SetMetadata("plan", "pro")
+	c.SetMetadata("seats", 5)
+
+	data := GetChatContextData(WithChatContext(context.Background(), c))
+	require.NotNil(t, data)
+	assert.Equal(t, &ChatContextData{
+		OrgID:    "org",
+		TenantID: "tid",
+		ChatID:   "cid",
+		RunID:    c.GetRunID(),
+		UserID:   "uid",
+		Locale:   "en-GB",
+		Timezone: "Europe/London",
+		Metadata: map[string]any{"plan": "pro", "seats": 5},
+	}, data)
+
+	s, err := data.Encode()
+	require.NoError(t, err)
+
+	decoded, err := DecodeChatContextData(s)
+	require.NoError(t, err)
+	assert.Equal(t, data.UserID, decoded.UserID)
+	// JSON numbers are decoded as float64
+	assert.Equal(t, map[string]any{"plan": "pro", "seats": float64(5)}, decoded.Metadata)
+
+	c2 := decoded.ChatContext("app2")
+	assert.Equal(t, "org", c2.GetOrgID())
+	assert.Equal(t, "tid", c2.GetTenantID())
+	assert.Equal(t, "cid", c2.GetChatID())
+	assert.Equal(t, c.GetRunID(), c2.GetRunID())
+	assert.Equal(t, "uid", c2.GetUserID())
+	assert.Equal(t, "en-GB", c2.GetLocale())
+	assert.Equal(t, "Europe/London", c2.GetTimezone())
+	assert.Equal(t, "app2", c2.AppData())
+	v, ok := c2.GetMetadata("plan")
+	assert.True(t, ok)
+	assert.Equal(t, "pro", v)
+
+	// defaults
+	c3 := (&ChatContextData{TenantID: "tid"}).ChatContext(nil)
+	assert.Equal(t, "main", c3.GetOrgID())
+	assert.NotEmpty(t, c3.GetChatID())
+	assert.NotEmpty(t, c3.GetRunID())
+	assert.Empty(t, c3.Metadata())
+
+	tcases := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{"base64", "!!", "failed to decode chat context"},
+		{"json", "bm90IGpzb24", "failed to unmarshal chat context"},
+		{"tenant", "e30", "invalid chat context"},
+		{"chat", "eyJ0ZW5hbnRfaWQiOiJ0IiwiY2hhdF9pZCI6IiAifQ", "chat ID: default: empty ID: invalid ID"},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeChatContextData(tc.in)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package chatmodel
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/xdb/pkg/flake"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidID = errors.New("invalid ID")
+)
+
+// IDFormat defines how chat, tenant and run IDs are generated and validated.
+type IDFormat interface {
+	// Name returns the name of the format
+	Name() string
+	// NewID generates a new ID
+	NewID() string
+	// Normalize validates the ID and returns its canonical form
+	Normalize(id string) (string, error)
+}
+
+var idFormat atomic.Value
+
+func init() {
+	idFormat.Store(idFormatHolder{DefaultIDFormat})
+}
+
+// idFormatHolder is needed as atomic.Value requires the same concrete type
+type idFormatHolder struct {
+	IDFormat
+}
+
+// SetIDFormat sets the format used by NewChatID, NormalizeID and NewChatContextFromIDs,
+// nil restores DefaultIDFormat.
+// It is expected to be called once on the service start.
+func SetIDFormat(f IDFormat) {
+	if f == nil {
+		f = DefaultIDFormat
+	}
+	idFormat.Store(idFormatHolder{f})
+}
+
+// GetIDFormat returns the current ID format.
+func GetIDFormat() IDFormat {
+	return idFormat.Load().(idFormatHolder).IDFormat
+}
+
+// NormalizeID validates the ID with the current format and returns its canonical form.
+// The IDs supplied by the external systems are normalized by SetChatID, DecodeChatContextData,
+// the MCP server and the message stores, so the stores and the metrics use the same keys.
+func NormalizeID(id string) (string, error) {
+	return GetIDFormat().Normalize(id)
+}
+
+// NewChatContextFromIDs returns a new ChatContext for IDs supplied by an external system.
+// The non-empty IDs are normalized with the current format, the empty IDs are generated.
+func NewChatContextFromIDs(tenantID, chatID string, appData any) (ChatContext, error) {
+	var err error
+	if tenantID != "" {
+		if tenantID, err = NormalizeID(tenantID); err != nil {
+			return nil, errors.WithMessage(err, "tenant ID")
+		}
+	}
+	if chatID != "" {
+		if chatID, err = NormalizeID(chatID); err != nil {
+			return nil, errors.WithMessage(err, "chat ID")
+		}
+	}
+	return NewChatContext(tenantID, chatID, appData), nil
+}
+
+// FlakeIDFormat generates numeric IDs with the flake ID generator,
+// and accepts only the numeric IDs.
+var FlakeIDFormat IDFormat = flakeIDFormat{}
+
+// DefaultIDFormat generates the flake IDs, and accepts any non-empty ID,
+// so the IDs of the existing chats remain valid.
+// This is the default format, use SetIDFormat to enforce the IDs of the host system.
+var DefaultIDFormat = NewCustomIDFormat("default", FlakeIDFormat.NewID, nil)
+
+type flakeIDFormat struct{}
+
+func (flakeIDFormat) Name() string {
+	return "flake"
+}
+
+func (flakeIDFormat) NewID() string {
+	return strconv.FormatUint(flake.DefaultIDGenerator.NextID(), 10)
+}
+
+func (flakeIDFormat) Normalize(id string) (string, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(id), 10, 64)
+	if err != nil || n == 0 {
+		return "", errors.Wrapf(ErrInvalidID, "expected flake ID: %q", id)
+	}
+	return strconv.FormatUint(n, 10), nil
+}
+
+// UUIDv7Format generates time-ordered UUIDv7 IDs,
+// and accepts any UUID normalized to the lower case canonical form.
+var UUIDv7Format IDFormat = uuidV7Format{}
+
+type uuidV7Format struct{}
+
+func (uuidV7Format) Name() string {
+	return "uuidv7"
+}
+
+func (uuidV7Format) NewID() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		// fallback to random, only fails if the random source fails
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+func (uuidV7Format) Normalize(id string) (string, error) {
+	u, err := uuid.Parse(strings.TrimSpace(id))
+	if err != nil || u == uuid.Nil {
+		return "", errors.Wrapf(ErrInvalidID, "expected UUID: %q", id)
+	}
+	return u.String(), nil
+}
+
+// ULIDFormat generates time-ordered ULID IDs,
+// normalized to the upper case Crockford's base32 form.
+var ULIDFormat IDFormat = ulidFormat{}
+
+type ulidFormat struct{}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func (ulidFormat) Name() string {
+	return "ulid"
+}
+
+func (ulidFormat) NewID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+	_, _ = rand.Read(b[6:])
+	return encodeULID(b)
+}
+
+func (ulidFormat) Normalize(id string) (string, error) {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if len(id) != 26 || id[0] > '7' {
+		return "", errors.Wrapf(ErrInvalidID, "expected ULID: %q", id)
+	}
+	var sb strings.Builder
+	sb.Grow(26)
+	for _, c := range id {
+		// Crockford's base32 aliases
+		switch c {
+		case 'I', 'L':
+			c = '1'
+		case 'O':
+			c = '0'
+		}
+		if !strings.ContainsRune(crockford, c) {
+			return "", errors.Wrapf(ErrInvalidID, "expected ULID: %q", id)
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String(), nil
+}
+
+// encodeULID encodes 128 bits as 26 characters of Crockford's base32,
+// the first character holds the 3 most significant bits.
+func encodeULID(b [16]byte) string {
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// NewCustomIDFormat returns IDFormat for IDs of an external system.
+// The generate function is used to create new IDs,
+// and the optional normalize function validates and returns the canonical form of the ID.
+// If normalize is nil, the ID is only trimmed and checked to be non-empty.
+func NewCustomIDFormat(name string, generate func() string, normalize func(string) (string, error)) IDFormat {
+	return &customIDFormat{
+		name:      name,
+		generate:  generate,
+		normalize: normalize,
+	}
+}
+
+type customIDFormat struct {
+	name      string
+	generate  func() string
+	normalize func(string) (string, error)
+}
+
+func (f *customIDFormat) Name() string {
+	return f.name
+}
+
+func (f *customIDFormat) NewID() string {
+	return f.generate()
+}
+
+func (f *customIDFormat) Normalize(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if f.normalize != nil {
+		res, err := f.normalize(id)
+		if err != nil {
+			return "", errors.Wrapf(ErrInvalidID, "%s: %q: %s", f.name, id, err.Error())
+		}
+		return res, nil
+	}
+	if id == "" {
+		return "", errors.Wrapf(ErrInvalidID, "%s: empty ID", f.name)
+	}
+	return id, nil
+}
@@ -0,0 +1,158 @@
+package chatmodel
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDFormats(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		format  IDFormat
+		name    string
+		valid   map[string]string
+		invalid []string
+	}{
+		{
+			format: DefaultIDFormat,
+			name:   "default",
+			valid: map[string]string{
+				"123456789": "123456789",
+				" chat-1 ":  "chat-1",
+			},
+			invalid: []string{"", " "},
+		},
+		{
+			format: FlakeIDFormat,
+			name:   "flake",
+			valid: map[string]string{
+				"123456789":   "123456789",
+				" 000123456 ": "123456",
+			},
+			invalid: []string{"", "0", "abc", "-1", "12.5"},
+		},
+		{
+			format: UUIDv7Format,
+			name:   "uuidv7",
+			valid: map[string]string{
+				"0190A4F8-1C2B-7D3E-8F40-123456789ABC":   "0190a4f8-1c2b-7d3e-8f40-123456789abc",
+				"{0190a4f8-1c2b-7d3e-8f40-123456789abc}": "0190a4f8-1c2b-7d3e-8f40-123456789abc",
+			},
+			invalid: []string{"", "123", "00000000-0000-0000-0000-000000000000"},
+		},
+		{
+			format: ULIDFormat,
+			name:   "ulid",
+			valid: map[string]string{
+				"01arz3ndektsv4rrffq69g5fav": "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+				"01ARZ3NDEKTSV4RRFFQ69G5FAO": "01ARZ3NDEKTSV4RRFFQ69G5FA0",
+			},
+			invalid: []string{"", "01ARZ3NDEKTSV4RRFFQ69G5FA", "81ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FAU"},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.name, tc.format.Name())
+
+			id1 := tc.format.NewID()
+			id2 := tc.format.NewID()
+			assert.NotEqual(t, id1, id2)
+			norm, err := tc.format.Normalize(id1)
+			require.NoError(t, err)
+			assert.Equal(t, id1, norm)
+
+			for in, exp := range tc.valid {
+				norm, err := tc.format.Normalize(in)
+				require.NoError(t, err, in)
+				assert.Equal(t, exp, norm)
+			}
+			for _, in := range tc.invalid {
+				_, err := tc.format.Normalize(in)
+				assert.True(t, errors.Is(err, ErrInvalidID), in)
+			}
+		})
+	}
+}
+
+func TestULIDFormat_Ordered(t *testing.T) {
+	t.Parallel()
+
+	id := ULIDFormat.NewID()
+	assert.Len(t, id, 26)
+	assert.LessOrEqual(t, id[0], byte('7'))
+	// the first 10 characters are the timestamp
+	assert.LessOrEqual(t, id[:10], ULIDFormat.NewID()[:10])
+}
+
+func TestCustomIDFormat(t *testing.T) {
+	t.Parallel()
+
+	f := NewCustomIDFormat("ext", func() string { return "ext-1" }, func(id string) (string, error) {
+		if !strings.HasPrefix(strings.ToLower(id), "ext-") {
+			return "", errors.New("missing prefix")
+		}
+		return strings.ToLower(id), nil
+	})
+	assert.Equal(t, "ext", f.Name())
+	assert.Equal(t, "ext-1", f.NewID())
+
+	id, err := f.Normalize(" EXT-42 ")
+	require.NoError(t, err)
+	assert.Equal(t, "ext-42", id)
+
+	_, err = f.Normalize("42")
+	assert.True(t, errors.Is(err, ErrInvalidID))
+	assert.Contains(t, err.Error(), "missing prefix")
+
+	plain := NewCustomIDFormat("plain", func() string { return "x" }, nil)
+	id, err = plain.Normalize(" abc ")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", id)
+	_, err = plain.Normalize(" ")
+	assert.True(t, errors.Is(err, ErrInvalidID))
+}
+
+// not parallel: changes the global ID format
+func TestSetIDFormat(t *testing.T) {
+	defer SetIDFormat(nil)
+
+	assert.Equal(t, "default", GetIDFormat().Name())
+
+	SetIDFormat(UUIDv7Format)
+	assert.Equal(t, "uuidv7", GetIDFormat().Name())
+	_, err := NormalizeID(NewChatID())
+	require.NoError(t, err)
+
+	c, err := NewChatContextFromIDs("0190A4F8-1C2B-7D3E-8F40-123456789ABC", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "0190a4f8-1c2b-7d3e-8f40-123456789abc", c.GetTenantID())
+	_, err = NormalizeID(c.GetChatID())
+	assert.NoError(t, err)
+	_, err = NormalizeID(c.GetRunID())
+	assert.NoError(t, err)
+
+	_, err = NewChatContextFromIDs("tenant", "", nil)
+	assert.EqualError(t, err, `tenant ID: expected UUID: "tenant": invalid ID`)
+	_, err = NewChatContextFromIDs("", "chat", nil)
+	assert.EqualError(t, err, `chat ID: expected UUID: "chat": invalid ID`)
+
+	// the external IDs are normalized at the entry points
+	ctx, err := SetChatID(WithChatContext(context.Background(), c), "0190A4F8-1C2B-7D3E-8F40-123456789ABD")
+	require.NoError(t, err)
+	assert.Equal(t, "0190a4f8-1c2b-7d3e-8f40-123456789abd", GetChatContext(ctx).GetChatID())
+	_, err = SetChatID(ctx, "chat")
+	assert.True(t, errors.Is(err, ErrInvalidID))
+	data := &ChatContextData{TenantID: "0190A4F8-1C2B-7D3E-8F40-123456789ABC", ChatID: "chat"}
+	assert.EqualError(t, data.Normalize(), `chat ID: expected UUID: "chat": invalid ID`)
+	assert.Equal(t, "0190a4f8-1c2b-7d3e-8f40-123456789abc", data.TenantID)
+
+	SetIDFormat(nil)
+	assert.Equal(t, "default", GetIDFormat().Name())
+}
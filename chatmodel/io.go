@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 
 	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/effective-security/gogentic/pkg/llmutils"
 	"github.com/invopop/jsonschema"
 )
@@ -71,10 +72,18 @@ func NewInputRequest(chatMessage string) *InputRequest {
 	}
 }
 
+// ICitationsResult is implemented by the output types that accept the citations
+// returned by the search-grounded models, for example Perplexity.
+type ICitationsResult interface {
+	SetCitations(citations []llms.Citation)
+}
+
 // OutputResult represents the response generated by the chat assistant.
 type OutputResult struct {
 	// contains the markdown-enabled response generated by the chat assistant.
 	Content string `json:"content" yaml:"content" jsonschema:"title=Response Content,description=The content returned by assistant or tool."`
+	// Citations is the list of the sources returned by the model, not generated by the model.
+	Citations []llms.Citation `json:"citations,omitempty" yaml:"citations,omitempty" jsonschema:"-"`
 }
 
 // GetContent gets the content of the message for the chat history
@@ -82,6 +91,11 @@ func (o OutputResult) GetContent() string {
 	return o.Content
 }
 
+// SetCitations sets the citations returned by the model.
+func (o *OutputResult) SetCitations(citations []llms.Citation) {
+	o.Citations = citations
+}
+
 // NewOutputResult returns a new OutputResult
 func NewOutputResult(chatMessage string) *OutputResult {
 	return &OutputResult{
@@ -89,6 +103,25 @@ func NewOutputResult(chatMessage string) *OutputResult {
 	}
 }
 
+// AnswerWithData is the output with the human-readable answer and the typed data,
+// for the callers that need both, use it with the JSON modes:
+//
+//	ag := assistants.NewAssistant[chatmodel.AnswerWithData[Invoice]](llm, prompt,
+//		assistants.WithMode(encoding.ModeJSONSchema))
+//
+// The answer is added to the chat history, and Run returns both the answer and the data.
+type AnswerWithData[T any] struct {
+	// Answer is the markdown-enabled answer to the user.
+	Answer string `json:"answer" yaml:"answer" jsonschema:"required,title=Answer,description=The human-readable answer to the user."`
+	// Data is the structured data of the answer.
+	Data T `json:"data" yaml:"data" jsonschema:"required,title=Data,description=The structured data of the answer."`
+}
+
+// GetContent gets the answer for the chat history
+func (o AnswerWithData[T]) GetContent() string {
+	return o.Answer
+}
+
 type BaseClarificationResult struct {
 	Confidence    string `json:"confidence,omitempty" yaml:"confidence" jsonschema:"title=Confidence Level,description=The confidence level of the response,enum=Low,enum=Medium,enum=High"`
 	Clarification string `json:"clarification,omitempty" yaml:"clarification" jsonschema:"title=Clarification,description=Clarification is returned when the assistant is not sure about the answer and needs to ask for more information."`
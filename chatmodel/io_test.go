@@ -1,8 +1,10 @@
 package chatmodel
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/invopop/jsonschema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -65,6 +67,29 @@ func TestOutputResult(t *testing.T) {
 
 	nr := NewOutputResult("baz")
 	assert.Equal(t, "baz", nr.Content)
+
+	var setter ICitationsResult = nr
+	setter.SetCitations([]llms.Citation{{URL: "https://go.dev"}})
+	assert.Equal(t, []llms.Citation{{URL: "https://go.dev"}}, nr.Citations)
+
+	// the citations are not generated by the model
+	schema := jsonschema.Reflect(&OutputResult{})
+	def := schema.Definitions["OutputResult"]
+	require.NotNil(t, def)
+	_, ok := def.Properties.Get("citations")
+	assert.False(t, ok)
+}
+
+func TestAnswerWithData(t *testing.T) {
+	t.Parallel()
+
+	type invoice struct {
+		Total float64 `json:"total"`
+	}
+	var r AnswerWithData[invoice]
+	require.NoError(t, json.Unmarshal([]byte(`{"answer":"The total is $10.","data":{"total":10}}`), &r))
+	assert.Equal(t, "The total is $10.", r.GetContent())
+	assert.Equal(t, 10.0, r.Data.Total)
 }
 
 func TestBaseClarificationResultSetters(t *testing.T) {
@@ -9,8 +9,17 @@ import (
 var (
 	ErrFailedUnmarshalInput  = errors.New("failed to unmarshal input: check the schema and try again")
 	ErrFailedUnmarshalOutput = errors.New("failed to unmarshal output: check the schema and try again")
+	// ErrToolFatal marks the tool error that must abort the other tool calls
+	// executed in parallel, and fail the assistant call, use errors.Mark(err, ErrToolFatal).
+	ErrToolFatal = errors.New("fatal tool error")
 )
 
+// RepairPrompter is implemented by the output parse errors that describe how to fix the output,
+// the prompt is fed back to the LLM when the run is retried.
+type RepairPrompter interface {
+	RepairPrompt() string
+}
+
 // OutputParser is an interface for parsing the output of an LLM call.
 type OutputParser[T any] interface {
 	// Parse parses the output of an LLM call.
@@ -0,0 +1,123 @@
+package chatmodel
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// RunState is the key-value scratchpad shared between the tools within a run.
+// The tools stash the large artifacts, such as files or dataframes,
+// and return only the references to the LLM, see RunState.Ref.
+// The outermost assistant resolves the references in the final answer,
+// so the artifacts do not flow through the message history.
+type RunState struct {
+	lock   sync.RWMutex
+	values map[string]any
+}
+
+type runStateKey struct{}
+
+// NewRunState returns an empty RunState.
+func NewRunState() *RunState {
+	return &RunState{
+		values: make(map[string]any),
+	}
+}
+
+// WithRunState returns a new context with the RunState.
+func WithRunState(ctx context.Context, state *RunState) context.Context {
+	return context.WithValue(ctx, runStateKey{}, state)
+}
+
+// GetRunState retrieves the RunState from the context, or nil.
+func GetRunState(ctx context.Context) *RunState {
+	if v, ok := ctx.Value(runStateKey{}).(*RunState); ok {
+		return v
+	}
+	return nil
+}
+
+var reRunStateRef = regexp.MustCompile(`\[\[state:([A-Za-z0-9_.\-]+)\]\]`)
+
+// Ref returns the reference to the key, to be returned to the LLM instead of the value,
+// the key must contain only letters, digits, '_', '.' and '-'.
+func (s *RunState) Ref(key string) string {
+	return "[[state:" + key + "]]"
+}
+
+// Set stores the value by key, and returns the reference to the key.
+func (s *RunState) Set(key string, value any) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.values[key] = value
+	return s.Ref(key)
+}
+
+// Add stores the value by the new key, and returns the reference to the key.
+func (s *RunState) Add(value any) string {
+	return s.Set(uuid.NewString(), value)
+}
+
+// Get returns the value by key.
+func (s *RunState) Get(key string) (any, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Delete removes the value by key.
+func (s *RunState) Delete(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.values, key)
+}
+
+// Keys returns the sorted keys.
+func (s *RunState) Keys() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Resolve replaces the references in the text with the values,
+// the unknown references are left as is.
+func (s *RunState) Resolve(text string) string {
+	return reRunStateRef.ReplaceAllStringFunc(text, func(ref string) string {
+		v, ok := s.Get(reRunStateRef.FindStringSubmatch(ref)[1])
+		if !ok {
+			return ref
+		}
+		switch val := v.(type) {
+		case string:
+			return val
+		case []byte:
+			return string(val)
+		}
+		return Stringify(v)
+	})
+}
+
+// GetRunStateValue returns the typed value by key from the RunState in the context.
+func GetRunStateValue[T any](ctx context.Context, key string) (T, bool) {
+	var zero T
+	state := GetRunState(ctx)
+	if state == nil {
+		return zero, false
+	}
+	v, ok := state.Get(key)
+	if !ok {
+		return zero, false
+	}
+	t, ok := v.(T)
+	return t, ok
+}
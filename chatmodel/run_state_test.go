@@ -0,0 +1,42 @@
+package chatmodel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunState(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, GetRunState(ctx))
+	_, ok := GetRunStateValue[string](ctx, "csv")
+	assert.False(t, ok)
+
+	state := NewRunState()
+	ctx = WithRunState(ctx, state)
+	require.Same(t, state, GetRunState(ctx))
+
+	ref := state.Set("csv", "a,b\n1,2")
+	assert.Equal(t, "[[state:csv]]", ref)
+	state.Set("data", map[string]int{"rows": 1})
+	state.Set("bin", []byte("raw"))
+	id := state.Add(InputRequest{Input: "content"})
+	assert.Len(t, state.Keys(), 4)
+
+	v, ok := GetRunStateValue[string](ctx, "csv")
+	require.True(t, ok)
+	assert.Equal(t, "a,b\n1,2", v)
+	_, ok = GetRunStateValue[int](ctx, "csv")
+	assert.False(t, ok)
+
+	assert.Equal(t, "file:\na,b\n1,2\ndata: {\"rows\":1} raw content [[state:missing]]",
+		state.Resolve("file:\n[[state:csv]]\ndata: [[state:data]] [[state:bin]] "+id+" [[state:missing]]"))
+
+	state.Delete("csv")
+	assert.Len(t, state.Keys(), 3)
+	assert.NotContains(t, state.Keys(), "csv")
+	_, ok = state.Get("csv")
+	assert.False(t, ok)
+}
@@ -0,0 +1,108 @@
+package chatmodel
+
+import (
+	"encoding/json"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ToolErrorCategory is the machine-readable category of the tool error.
+type ToolErrorCategory string
+
+const (
+	// ToolErrorInvalidInput is the error of the tool input, the LLM should fix the input and try again.
+	ToolErrorInvalidInput ToolErrorCategory = "invalid_input"
+	// ToolErrorTransient is the temporary failure, the call can be retried with the same input.
+	ToolErrorTransient ToolErrorCategory = "transient"
+	// ToolErrorPermission is the access denied error, the call must not be retried.
+	ToolErrorPermission ToolErrorCategory = "permission"
+	// ToolErrorFatal is the error that aborts the assistant call, see ErrToolFatal.
+	ToolErrorFatal ToolErrorCategory = "fatal"
+)
+
+var (
+	// ErrToolInvalidInput marks the tool error caused by the input, use errors.Mark(err, ErrToolInvalidInput).
+	ErrToolInvalidInput = errors.New("invalid tool input")
+	// ErrToolTransient marks the temporary tool error, use errors.Mark(err, ErrToolTransient).
+	ErrToolTransient = errors.New("transient tool error")
+	// ErrToolPermission marks the access denied tool error, use errors.Mark(err, ErrToolPermission).
+	ErrToolPermission = errors.New("tool permission denied")
+)
+
+// ToolError is the structured tool error,
+// serialized as JSON into the tool response message for the LLM.
+// The tool can return *ToolError, or the error marked with
+// ErrToolInvalidInput, ErrToolTransient, ErrToolPermission or ErrToolFatal.
+type ToolError struct {
+	Category ToolErrorCategory `json:"category" yaml:"category"`
+	Tool     string            `json:"tool,omitempty" yaml:"tool,omitempty"`
+	Message  string            `json:"message" yaml:"message"`
+	// Retryable is true, if the call can be retried with the same input.
+	Retryable bool `json:"retryable" yaml:"retryable"`
+}
+
+// NewToolError returns the structured error of the tool call.
+// The errors that are not marked are reported as transient.
+func NewToolError(tool string, err error) *ToolError {
+	var te *ToolError
+	if errors.As(err, &te) {
+		res := *te
+		if res.Tool == "" {
+			res.Tool = tool
+		}
+		res.Retryable = res.Category == ToolErrorTransient
+		return &res
+	}
+
+	category := GetToolErrorCategory(err)
+	return &ToolError{
+		Category:  category,
+		Tool:      tool,
+		Message:   err.Error(),
+		Retryable: category == ToolErrorTransient,
+	}
+}
+
+// GetToolErrorCategory returns the category of the tool error.
+func GetToolErrorCategory(err error) ToolErrorCategory {
+	var te *ToolError
+	switch {
+	case errors.As(err, &te):
+		return te.Category
+	case errors.Is(err, ErrToolFatal):
+		return ToolErrorFatal
+	case errors.Is(err, ErrToolPermission):
+		return ToolErrorPermission
+	case errors.Is(err, ErrToolInvalidInput), errors.Is(err, ErrFailedUnmarshalInput):
+		return ToolErrorInvalidInput
+	}
+	// ErrToolTransient, context.DeadlineExceeded and the unknown errors
+	return ToolErrorTransient
+}
+
+// Error implements the error interface.
+func (e *ToolError) Error() string {
+	return string(e.Category) + ": " + e.Message
+}
+
+// Is returns true for the marker of the category,
+// so the fatal *ToolError aborts the other tool calls as ErrToolFatal.
+func (e *ToolError) Is(target error) bool {
+	switch e.Category {
+	case ToolErrorFatal:
+		return target == ErrToolFatal
+	case ToolErrorPermission:
+		return target == ErrToolPermission
+	case ToolErrorInvalidInput:
+		return target == ErrToolInvalidInput
+	case ToolErrorTransient:
+		return target == ErrToolTransient
+	}
+	return false
+}
+
+// String returns the JSON of the error for the tool response message.
+func (e *ToolError) String() string {
+	js, _ := json.Marshal(e)
+	return string(js)
+}
@@ -0,0 +1,70 @@
+package chatmodel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewToolError(t *testing.T) {
+	tcases := []struct {
+		name string
+		err  error
+		exp  ToolError
+	}{
+		{
+			name: "unknown",
+			err:  errors.New("boom"),
+			exp:  ToolError{Category: ToolErrorTransient, Tool: "tool", Message: "boom", Retryable: true},
+		},
+		{
+			name: "deadline",
+			err:  errors.Wrap(context.DeadlineExceeded, "search"),
+			exp:  ToolError{Category: ToolErrorTransient, Tool: "tool", Message: "search: context deadline exceeded", Retryable: true},
+		},
+		{
+			name: "unmarshal",
+			err:  errors.WithStack(ErrFailedUnmarshalInput),
+			exp:  ToolError{Category: ToolErrorInvalidInput, Tool: "tool", Message: ErrFailedUnmarshalInput.Error()},
+		},
+		{
+			name: "invalid",
+			err:  errors.Mark(errors.New("missing query"), ErrToolInvalidInput),
+			exp:  ToolError{Category: ToolErrorInvalidInput, Tool: "tool", Message: "missing query"},
+		},
+		{
+			name: "permission",
+			err:  errors.Mark(errors.New("access denied"), ErrToolPermission),
+			exp:  ToolError{Category: ToolErrorPermission, Tool: "tool", Message: "access denied"},
+		},
+		{
+			name: "fatal",
+			err:  errors.Mark(errors.New("database is down"), ErrToolFatal),
+			exp:  ToolError{Category: ToolErrorFatal, Tool: "tool", Message: "database is down"},
+		},
+		{
+			name: "typed",
+			err:  errors.WithMessage(&ToolError{Category: ToolErrorPermission, Tool: "other", Message: "read only", Retryable: true}, "call"),
+			exp:  ToolError{Category: ToolErrorPermission, Tool: "other", Message: "read only"},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			te := NewToolError("tool", tc.err)
+			assert.Equal(t, tc.exp, *te)
+			assert.Equal(t, tc.exp.Category, GetToolErrorCategory(tc.err))
+		})
+	}
+}
+
+func TestToolError(t *testing.T) {
+	err := error(&ToolError{Category: ToolErrorFatal, Message: "database is down"})
+	assert.True(t, errors.Is(err, ErrToolFatal))
+	assert.False(t, errors.Is(err, ErrToolPermission))
+	assert.EqualError(t, err, "fatal: database is down")
+
+	te := &ToolError{Category: ToolErrorTransient, Tool: "search", Message: "timeout", Retryable: true}
+	assert.Equal(t, `{"category":"transient","tool":"search","message":"timeout","retryable":true}`, te.String())
+}
@@ -0,0 +1,53 @@
+// Command providergen generates the skeleton of a new LLM provider package.
+//
+// Usage:
+//
+//	go run ./cmd/providergen -manifest mistral.yaml -out pkg/llms/mistral
+//
+// See pkg/llms/providergen/testdata/manifest.yaml for the manifest example.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms/providergen"
+)
+
+func main() {
+	manifest := flag.String("manifest", "", "path to the provider manifest file")
+	out := flag.String("out", "", "output folder, by default pkg/llms/<package>")
+	force := flag.Bool("force", false, "overwrite the existing files")
+	flag.Parse()
+
+	if err := run(*manifest, *out, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(manifest, out string, force bool) error {
+	if manifest == "" {
+		return errors.New("-manifest is required")
+	}
+	m, err := providergen.LoadManifest(manifest)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		out = filepath.Join("pkg", "llms", m.Package)
+	}
+
+	files, err := providergen.Generate(m, out, force)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Println(f)
+	}
+	fmt.Printf("\nAdd %s provider to pkg/llmfactory to create it from the configuration.\n", m.Provider)
+	return nil
+}
@@ -0,0 +1,44 @@
+// Command schemadoc generates the registration of the doc comments of the Go types in the package,
+// used as the descriptions in the JSON schemas of the tools and the outputs.
+//
+// Usage, in the package with the tool request types:
+//
+//	//go:generate go run github.com/effective-security/gogentic/cmd/schemadoc
+//
+// The comments are registered with schema.RegisterComments in schema_comments.gen.go.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/schema/schemadoc"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "package folder")
+	pkg := flag.String("pkg", "", "import path of the package, by default detected from go.mod")
+	out := flag.String("out", schemadoc.DefaultOutput, "output file name in the package folder")
+	flag.Parse()
+
+	if err := run(*dir, *pkg, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, pkg, out string) error {
+	src, err := schemadoc.Generate(dir, pkg)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, out)
+	if err = os.WriteFile(path, src, 0o644); err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println(path)
+	return nil
+}
@@ -5,15 +5,17 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/schema"
 )
 
 // TypedOutputParser parses output from an LLM into Go structs.
 // By providing the NewDefined constructor with a struct, one or more TypeScript interfaces
 // are generated to help LLMs format responses with the desired JSON structure.
 type TypedOutputParser[T any] struct {
-	enc      SchemaEncoder
-	name     string
-	validate bool
+	enc            SchemaEncoder
+	name           string
+	validate       bool
+	validateSchema bool
 }
 
 var _ chatmodel.OutputParser[any] = (*TypedOutputParser[any])(nil)
@@ -39,8 +41,24 @@ func (p *TypedOutputParser[T]) WithValidation(validate bool) {
 	p.validate = validate
 }
 
+// WithSchemaValidation enables the validation of the raw output against the JSON schema,
+// including the enum and format constraints, before it is unmarshaled.
+// The violations are returned as *schema.ValidationError marked with chatmodel.ErrFailedUnmarshalOutput,
+// so the assistant feeds them back to the LLM for the repair.
+func (p *TypedOutputParser[T]) WithSchemaValidation(validate bool) {
+	p.validateSchema = validate
+}
+
 // Parse parses the output of an LLM call.
 func (p *TypedOutputParser[T]) Parse(text string) (*T, error) {
+	if validator, ok := p.enc.(SchemaValidator); ok && p.validateSchema {
+		var verr *schema.ValidationError
+		// the invalid JSON is reported by Unmarshal
+		if err := validator.ValidateSchema([]byte(text)); errors.As(err, &verr) {
+			return nil, errors.Mark(verr, chatmodel.ErrFailedUnmarshalOutput)
+		}
+	}
+
 	var target T
 	if err := p.enc.Unmarshal([]byte(text), &target); err != nil {
 		return nil, errors.WithStack(chatmodel.ErrFailedUnmarshalOutput)
@@ -6,6 +6,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/chatmodel"
 	"github.com/effective-security/gogentic/encoding/dummy"
+	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -74,6 +75,30 @@ func TestTypedOutputParser_WithValidation(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to validate")
 }
 
+func TestTypedOutputParser_WithSchemaValidation(t *testing.T) {
+	t.Parallel()
+	parser, err := NewTypedOutputParser(testStruct{}, ModeJSON)
+	require.NoError(t, err)
+	parser.WithSchemaValidation(true)
+
+	result, err := parser.Parse("```json\n{\"field1\": \"foo\", \"field2\": 42}\n```")
+	require.NoError(t, err)
+	assert.Equal(t, 42, result.Field2)
+
+	_, err = parser.Parse(`{"field1": "foo", "field2": 4.2}`)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, chatmodel.ErrFailedUnmarshalOutput))
+	var verr *schema.ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, []schema.Violation{{Path: "/field2", Message: "expected integer, got number"}}, verr.Violations)
+
+	// the invalid JSON is reported by Unmarshal
+	_, err = parser.Parse("{bad json}")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, chatmodel.ErrFailedUnmarshalOutput))
+	assert.False(t, errors.As(err, &verr))
+}
+
 type badValidator struct{ dummy.Encoder }
 
 func (badValidator) Validate(any) error            { return errors.New("fail validate") }
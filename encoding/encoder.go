@@ -7,6 +7,7 @@ import (
 	dummyenc "github.com/effective-security/gogentic/encoding/dummy"
 	jsonenc "github.com/effective-security/gogentic/encoding/json"
 	tomlenc "github.com/effective-security/gogentic/encoding/toml"
+	xmlenc "github.com/effective-security/gogentic/encoding/xml"
 	yamlenc "github.com/effective-security/gogentic/encoding/yaml"
 )
 
@@ -21,6 +22,11 @@ type Validator interface {
 	Validate(any) error
 }
 
+// SchemaValidator validates the raw output against the schema, before it is unmarshaled.
+type SchemaValidator interface {
+	ValidateSchema([]byte) error
+}
+
 type SchemaStreamEncoder interface {
 	Read(context.Context, <-chan string) <-chan any
 	GetFormatInstructions() string
@@ -34,6 +40,7 @@ const (
 	ModeJSONSchema       Mode = "json_schema"
 	ModeJSONSchemaStrict Mode = "json_schema_strict" // Not all providers support this and all props must be required
 	ModeYAML             Mode = "yaml"
+	ModeXML              Mode = "xml"
 	ModeTOML             Mode = "toml"
 	ModePlainText        Mode = "plain_text"
 	ModeCustom           Mode = "custom"
@@ -53,6 +60,8 @@ func PredefinedSchemaEncoder(mode Mode, req any) (SchemaEncoder, error) {
 		enc, err = jsonenc.NewEncoder(req)
 	case ModeYAML:
 		enc = yamlenc.NewEncoder(req)
+	case ModeXML:
+		enc = xmlenc.NewEncoder(req)
 	case ModeTOML:
 		enc = tomlenc.NewEncoder(req)
 	case ModePlainText:
@@ -87,8 +96,11 @@ var (
 	_ SchemaEncoder = (*dummyenc.Encoder)(nil)
 	_ SchemaEncoder = (*jsonenc.Encoder)(nil)
 	_ SchemaEncoder = (*tomlenc.Encoder)(nil)
+	_ SchemaEncoder = (*xmlenc.Encoder)(nil)
 	_ SchemaEncoder = (*yamlenc.Encoder)(nil)
 
+	_ SchemaValidator = (*jsonenc.Encoder)(nil)
+
 	// _ SchemaStreamEncoder = (*dummyenc.StreamEncoder)(nil)
 	// _ SchemaStreamEncoder = (*jsonenc.StreamEncoder)(nil)
 	// _ SchemaStreamEncoder = (*tomlenc.StreamEncoder)(nil)
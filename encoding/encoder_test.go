@@ -76,6 +76,30 @@ Make sure to return an instance of the YAML, not the schema itself.
 	assert.Equal(t, exp, e.GetFormatInstructions())
 }
 
+func Test_XML_Encoding(t *testing.T) {
+	e, err := encoding.PredefinedSchemaEncoder(encoding.ModeXML, Search{})
+	require.NoError(t, err)
+
+	exp := `
+Respond with XML in the following XML schema:
+` + "```xml" + `
+<Search>
+  <Topic>golang</Topic>
+  <Query>what is golang</Query>
+  <Type>web</Type>
+</Search>
+` + "```" + `
+Make sure to return an instance of the XML, not the schema itself.
+Escape the special characters in the text, like & as &amp; and < as &lt;.
+`
+
+	assert.Equal(t, exp, e.GetFormatInstructions())
+
+	var res Search
+	require.NoError(t, e.Unmarshal([]byte("<Search><Topic>golang</Topic><Query>what is golang</Query></Search>"), &res))
+	assert.Equal(t, "what is golang", res.Query)
+}
+
 func Test_TOML_Encoding(t *testing.T) {
 	e, err := encoding.PredefinedSchemaEncoder(encoding.ModeTOML, Search{})
 	require.NoError(t, err)
@@ -40,6 +40,12 @@ func (e *Encoder) Validate(req any) error {
 	return validate.Struct(req)
 }
 
+// ValidateSchema validates the raw JSON against the schema,
+// and returns *schema.ValidationError with the violations.
+func (e *Encoder) ValidateSchema(bs []byte) error {
+	return e.schema.Validate(llmutils.CleanJSON(bs))
+}
+
 func (e *Encoder) GetFormatInstructions() string {
 	var b bytes.Buffer
 	b.WriteString("\nRespond with JSON in the following JSON schema:\n")
@@ -3,6 +3,7 @@ package json
 import (
 	"testing"
 
+	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -91,3 +92,41 @@ Use the exact field names as they are defined in the schema.
 
 	assert.Equal(t, exp, enc.GetFormatInstructions())
 }
+
+type animal interface {
+	Sound() string
+}
+
+type dog struct {
+	Name string `json:"name"`
+}
+
+func (d dog) Sound() string { return d.Name + ": woof" }
+
+type cat struct {
+	Lives int `json:"lives"`
+}
+
+func (c *cat) Sound() string { return "meow" }
+
+func TestJson_Union(t *testing.T) {
+	require.NoError(t, schema.RegisterUnion[animal]("kind", map[string]animal{
+		"dog": dog{},
+		"cat": &cat{},
+	}))
+
+	type zoo struct {
+		Animals []schema.OneOf[animal] `json:"animals"`
+	}
+
+	enc, err := NewEncoder(zoo{})
+	require.NoError(t, err)
+	assert.Contains(t, enc.GetFormatInstructions(), `"anyOf"`)
+
+	var res zoo
+	err = enc.Unmarshal([]byte("```json\n{\"animals\":[{\"kind\":\"dog\",\"name\":\"Rex\"},{\"kind\":\"cat\",\"lives\":9}]}\n```"), &res)
+	require.NoError(t, err)
+	require.Len(t, res.Animals, 2)
+	assert.Equal(t, dog{Name: "Rex"}, res.Animals[0].Value)
+	assert.Equal(t, &cat{Lives: 9}, res.Animals[1].Value)
+}
@@ -0,0 +1,524 @@
+package encoding
+
+import (
+	textencoding "encoding"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+)
+
+// TableFormat is the format of the tabular output of the LLM.
+type TableFormat string
+
+const (
+	// TableMarkdown is the markdown table, with the header and the separator rows.
+	TableMarkdown TableFormat = "markdown"
+	// TableCSV is the CSV block, the first record is the header.
+	TableCSV TableFormat = "csv"
+)
+
+// maxTableProblems limits the number of the problems fed back to the LLM
+const maxTableProblems = 10
+
+// Table is the tabular output of the LLM.
+type Table struct {
+	Header []string   `json:"header" yaml:"header"`
+	Rows   [][]string `json:"rows" yaml:"rows"`
+}
+
+// GetContent returns the table as markdown, for the chat history.
+func (t Table) GetContent() string {
+	return t.Markdown()
+}
+
+// Markdown returns the table as markdown.
+func (t Table) Markdown() string {
+	if len(t.Header) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	writeMarkdownRow(&b, t.Header)
+	sep := make([]string, len(t.Header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	writeMarkdownRow(&b, sep)
+	for _, row := range t.Rows {
+		writeMarkdownRow(&b, row)
+	}
+	return b.String()
+}
+
+func writeMarkdownRow(b *strings.Builder, cells []string) {
+	b.WriteString("|")
+	for _, c := range cells {
+		c = strings.ReplaceAll(c, "|", `\|`)
+		c = strings.ReplaceAll(c, "\n", " ")
+		b.WriteString(" " + c + " |")
+	}
+	b.WriteString("\n")
+}
+
+// TableOf is the table with the rows decoded to T.
+type TableOf[T any] struct {
+	Table
+	Items []T `json:"items" yaml:"items"`
+}
+
+// TableError is returned by the table parsers with the problems of the table,
+// marked with chatmodel.ErrFailedUnmarshalOutput,
+// so the assistant feeds the problems back to the LLM for the repair.
+type TableError struct {
+	Format   TableFormat
+	Problems []string
+}
+
+func (e *TableError) Error() string {
+	return fmt.Sprintf("invalid %s table: %s", e.Format, strings.Join(e.Problems, "; "))
+}
+
+// RepairPrompt returns the prompt to fix the table.
+func (e *TableError) RepairPrompt() string {
+	var b strings.Builder
+	b.WriteString("The " + string(e.Format) + " table in the response is invalid:\n")
+	for _, p := range e.Problems {
+		b.WriteString("- " + p + "\n")
+	}
+	b.WriteString("Fix the problems and return the complete table as requested.")
+	return b.String()
+}
+
+func (e *TableError) add(format string, args ...any) {
+	if len(e.Problems) < maxTableProblems {
+		e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+	}
+}
+
+func (e *TableError) err() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return errors.Mark(e, chatmodel.ErrFailedUnmarshalOutput)
+}
+
+// TableOutputParser parses the markdown table or the CSV block from the output of an LLM.
+// The text around the table is ignored.
+// Use it with encoding.ModePlainText, and assistant.WithOutputParser.
+type TableOutputParser struct {
+	format  TableFormat
+	columns []string
+}
+
+var _ chatmodel.OutputParser[Table] = (*TableOutputParser)(nil)
+
+// NewTableOutputParser returns the parser of the table in the format,
+// the columns are required in the header, if provided.
+func NewTableOutputParser(format TableFormat, columns ...string) *TableOutputParser {
+	return &TableOutputParser{
+		format:  format,
+		columns: columns,
+	}
+}
+
+// Parse parses the table, the problems are returned as *TableError.
+func (p *TableOutputParser) Parse(text string) (*Table, error) {
+	return parseTable(p.format, text, p.columns)
+}
+
+// GetFormatInstructions returns a string describing the format of the output.
+func (p *TableOutputParser) GetFormatInstructions() string {
+	return tableFormatInstructions(p.format, p.columns)
+}
+
+// Type returns the string type key uniquely identifying this class of parser
+func (p *TableOutputParser) Type() string {
+	return string(p.format) + "_table_parser"
+}
+
+// parseTable parses the table, and checks the cells count and the required columns
+func parseTable(format TableFormat, text string, columns []string) (*Table, error) {
+	terr := &TableError{Format: format}
+	var records [][]string
+	switch format {
+	case TableCSV:
+		records = parseCSV(text, terr)
+	default:
+		records = parseMarkdownTable(text, terr)
+	}
+	if err := terr.err(); err != nil {
+		return nil, err
+	}
+
+	t := &Table{Header: records[0], Rows: records[1:]}
+	for i, name := range t.Header {
+		if name == "" {
+			terr.add("column %d has no name in the header", i+1)
+		}
+	}
+	for i, row := range t.Rows {
+		if len(row) != len(t.Header) {
+			terr.add("row %d has %d cells, expected %d", i+1, len(row), len(t.Header))
+		}
+	}
+	for _, col := range columns {
+		if t.columnIndex(col) < 0 {
+			terr.add("column %q is missing", col)
+		}
+	}
+	if err := terr.err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func tableFormatInstructions(format TableFormat, columns []string) string {
+	var b strings.Builder
+	switch format {
+	case TableCSV:
+		b.WriteString("\nRespond with CSV, the first record is the header")
+		if len(columns) > 0 {
+			b.WriteString(" with the following columns:\n```csv\n")
+			b.WriteString(strings.Join(columns, ","))
+			b.WriteString("\n```")
+		} else {
+			b.WriteString(".")
+		}
+		b.WriteString("\nQuote the values with commas, quotes or line breaks in double quotes.\n")
+	default:
+		b.WriteString("\nRespond with a markdown table")
+		if len(columns) > 0 {
+			b.WriteString(" with the following columns:\n")
+			b.WriteString(Table{Header: columns}.Markdown())
+		} else {
+			b.WriteString(", the first row is the header.\n")
+		}
+		b.WriteString("Escape the pipes in the cells as \\|.\n")
+	}
+	b.WriteString("Add one row per item, and every row must have a value for each column.\n")
+	return b.String()
+}
+
+// columnIndex returns the index of the column, the names are compared
+// case-insensitively without the spaces and punctuation, or -1
+func (t *Table) columnIndex(name string) int {
+	key := columnKey(name)
+	for i, h := range t.Header {
+		if columnKey(h) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+var reNonAlnum = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+func columnKey(name string) string {
+	return strings.ToLower(reNonAlnum.ReplaceAllString(name, ""))
+}
+
+var reSeparatorCell = regexp.MustCompile(`^:?-+:?$`)
+
+// parseMarkdownTable returns the rows of the first markdown table in the text,
+// the separator rows are skipped
+func parseMarkdownTable(text string, terr *TableError) [][]string {
+	var records [][]string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.Contains(line, "|") {
+			if len(records) > 0 {
+				// the end of the table
+				break
+			}
+			continue
+		}
+		cells := splitMarkdownRow(line)
+		if isSeparatorRow(cells) {
+			continue
+		}
+		records = append(records, cells)
+	}
+	if len(records) == 0 {
+		terr.add("no markdown table found, the first row must be the header")
+	}
+	return records
+}
+
+// splitMarkdownRow splits the row by the unescaped pipes,
+// the leading and trailing pipes are optional
+func splitMarkdownRow(line string) []string {
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, cleanCell(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, cleanCell(cell.String()))
+}
+
+func isSeparatorRow(cells []string) bool {
+	for _, c := range cells {
+		if !reSeparatorCell.MatchString(strings.ReplaceAll(c, " ", "")) {
+			return false
+		}
+	}
+	return true
+}
+
+// cleanCell trims the spaces and the markdown emphasis of the cell
+func cleanCell(s string) string {
+	s = strings.TrimSpace(s)
+	for _, m := range []string{"**", "__", "`"} {
+		if len(s) > 2*len(m) && strings.HasPrefix(s, m) && strings.HasSuffix(s, m) {
+			s = strings.TrimSpace(s[len(m) : len(s)-len(m)])
+		}
+	}
+	return s
+}
+
+// parseCSV returns the records of the CSV block in the text,
+// or of the whole text if there is no such block.
+// The delimiter is detected from the header, one of comma, semicolon or tab.
+func parseCSV(text string, terr *TableError) [][]string {
+	data := text
+	for _, b := range llmutils.ExtractCodeBlocks(text) {
+		if strings.EqualFold(b.Language, "csv") || b.Language == "" {
+			data = b.Code
+			break
+		}
+	}
+	data = strings.TrimSpace(data)
+
+	header, _, _ := strings.Cut(data, "\n")
+	comma := ','
+	for _, d := range []rune{';', '\t'} {
+		if strings.Count(header, string(d)) > strings.Count(header, string(comma)) {
+			comma = d
+		}
+	}
+
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		terr.add("%s", err.Error())
+		return nil
+	}
+	if len(records) == 0 {
+		terr.add("no CSV found, the first record must be the header")
+		return nil
+	}
+	for _, rec := range records {
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+		}
+	}
+	return records
+}
+
+// RowsOutputParser parses the table from the output of an LLM, and decodes the rows to T.
+// The columns are mapped to the fields of T by the table tag, or the json tag, or the field name,
+// compared case-insensitively without the spaces and punctuation.
+// The columns are required, unless the table tag has the optional flag: `table:"Notes,optional"`.
+// The fields can be strings, numbers, bools, pointers to them, or implement encoding.TextUnmarshaler.
+type RowsOutputParser[T any] struct {
+	format TableFormat
+	fields []rowField
+}
+
+var _ chatmodel.OutputParser[TableOf[any]] = (*RowsOutputParser[any])(nil)
+
+type rowField struct {
+	column   string
+	index    int
+	optional bool
+}
+
+var textUnmarshalerType = reflect.TypeFor[textencoding.TextUnmarshaler]()
+
+// NewRowsOutputParser returns the parser of the table in the format, with the rows decoded to T,
+// T must be a struct.
+func NewRowsOutputParser[T any](format TableFormat) (*RowsOutputParser[T], error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return nil, errors.Errorf("expected struct, got %s", t.Kind())
+	}
+
+	var fields []rowField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("table"), ",")
+		if name == "" {
+			name, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if !isCellType(field.Type) {
+			return nil, errors.Errorf("unsupported type of the field %s: %s", field.Name, field.Type)
+		}
+		fields = append(fields, rowField{column: name, index: i, optional: opts == "optional"})
+	}
+	if len(fields) == 0 {
+		return nil, errors.Errorf("no columns in %s", t)
+	}
+
+	return &RowsOutputParser[T]{
+		format: format,
+		fields: fields,
+	}, nil
+}
+
+func isCellType(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return isCellType(t.Elem())
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// Parse parses the table and decodes the rows, the problems are returned as *TableError.
+func (p *RowsOutputParser[T]) Parse(text string) (*TableOf[T], error) {
+	var required []string
+	for _, f := range p.fields {
+		if !f.optional {
+			required = append(required, f.column)
+		}
+	}
+	table, err := parseTable(p.format, text, required)
+	if err != nil {
+		return nil, err
+	}
+
+	terr := &TableError{Format: p.format}
+	res := &TableOf[T]{
+		Table: *table,
+		Items: make([]T, len(table.Rows)),
+	}
+	for _, f := range p.fields {
+		col := table.columnIndex(f.column)
+		if col < 0 {
+			continue
+		}
+		for i, row := range table.Rows {
+			v := reflect.ValueOf(&res.Items[i]).Elem().Field(f.index)
+			if err := setCell(v, row[col]); err != nil {
+				terr.add("row %d, column %q: %s", i+1, f.column, err.Error())
+			}
+		}
+	}
+	if err := terr.err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetFormatInstructions returns a string describing the format of the output.
+func (p *RowsOutputParser[T]) GetFormatInstructions() string {
+	columns := make([]string, len(p.fields))
+	for i, f := range p.fields {
+		columns[i] = f.column
+	}
+	return tableFormatInstructions(p.format, columns)
+}
+
+// Type returns the string type key uniquely identifying this class of parser
+func (p *RowsOutputParser[T]) Type() string {
+	return fmt.Sprintf("%T parser", *new(T))
+}
+
+// setCell sets the value from the cell, the empty cell is the zero value
+func setCell(v reflect.Value, s string) error {
+	if s == "" {
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(textencoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return errors.Errorf("invalid value %q: %s", s, err.Error())
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		ptr := reflect.New(v.Type().Elem())
+		if err := setCell(ptr.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(ptr)
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		switch strings.ToLower(s) {
+		case "yes", "y":
+			v.SetBool(true)
+		case "no", "n":
+			v.SetBool(false)
+		default:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return errors.Errorf("expected true or false, got %q", s)
+			}
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(trimNumber(s), 10, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("expected an integer, got %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(trimNumber(s), 10, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("expected a non-negative integer, got %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(trimNumber(s), v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("expected a number, got %q", s)
+		}
+		v.SetFloat(n)
+	}
+	return nil
+}
+
+// trimNumber removes the thousands separators
+func trimNumber(s string) string {
+	return strings.NewReplacer(",", "", "_", "", " ", "").Replace(s)
+}
@@ -0,0 +1,162 @@
+package encoding_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableOutputParser(t *testing.T) {
+	t.Parallel()
+
+	exp := &encoding.Table{
+		Header: []string{"Name", "Total"},
+		Rows:   [][]string{{"Acme | Co", "1,200"}, {"Globex", "15.5"}},
+	}
+
+	tests := []struct {
+		name   string
+		format encoding.TableFormat
+		input  string
+	}{
+		{
+			name:   "markdown",
+			format: encoding.TableMarkdown,
+			input:  "| Name | Total |\n|---|---:|\n| Acme \\| Co | 1,200 |\n| Globex | 15.5 |",
+		},
+		{
+			name:   "markdown with text and no outer pipes",
+			format: encoding.TableMarkdown,
+			input:  "Here is the report:\n\nName | **Total**\n:--- | ---\nAcme \\| Co | 1,200\nGlobex | 15.5\n\nLet me know if you need more.",
+		},
+		{
+			name:   "csv block",
+			format: encoding.TableCSV,
+			input:  "Here is the report:\n```csv\nName,Total\n\"Acme | Co\",\"1,200\"\nGlobex, 15.5\n```",
+		},
+		{
+			name:   "csv semicolon",
+			format: encoding.TableCSV,
+			input:  "Name;Total\nAcme | Co;1,200\nGlobex;15.5\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			table, err := encoding.NewTableOutputParser(tc.format, "name", "total").Parse(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, exp, table)
+		})
+	}
+
+	assert.Equal(t, "| Name | Total |\n| --- | --- |\n| Acme \\| Co | 1,200 |\n| Globex | 15.5 |\n", exp.GetContent())
+}
+
+func TestTableOutputParser_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		format   encoding.TableFormat
+		input    string
+		problems []string
+	}{
+		{
+			name:     "no table",
+			format:   encoding.TableMarkdown,
+			input:    "I could not find any data.",
+			problems: []string{"no markdown table found, the first row must be the header"},
+		},
+		{
+			name:   "cells and columns",
+			format: encoding.TableMarkdown,
+			input:  "| Name | |\n|---|---|\n| Acme | 1 |\n| Globex |",
+			problems: []string{
+				"column 2 has no name in the header",
+				"row 2 has 1 cells, expected 2",
+				`column "Total" is missing`,
+			},
+		},
+		{
+			name:     "csv",
+			format:   encoding.TableCSV,
+			input:    "Name,Total\nAcme,1,2",
+			problems: []string{"row 1 has 3 cells, expected 2"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := encoding.NewTableOutputParser(tc.format, "Total").Parse(tc.input)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, chatmodel.ErrFailedUnmarshalOutput))
+
+			var terr *encoding.TableError
+			require.True(t, errors.As(err, &terr))
+			assert.Equal(t, tc.problems, terr.Problems)
+
+			var rp chatmodel.RepairPrompter
+			require.True(t, errors.As(err, &rp))
+			assert.Contains(t, rp.RepairPrompt(), "- "+tc.problems[0]+"\n")
+		})
+	}
+}
+
+type invoiceRow struct {
+	Customer string     `json:"customer"`
+	Total    float64    `table:"Total Amount"`
+	Count    int        `json:"count"`
+	Paid     bool       `json:"paid"`
+	Due      *time.Time `table:"Due Date,optional"`
+	Notes    string     `table:"Notes,optional"`
+}
+
+func TestRowsOutputParser(t *testing.T) {
+	t.Parallel()
+
+	p, err := encoding.NewRowsOutputParser[invoiceRow](encoding.TableMarkdown)
+	require.NoError(t, err)
+	assert.Equal(t, "encoding_test.invoiceRow parser", p.Type())
+	assert.Equal(t, `
+Respond with a markdown table with the following columns:
+| customer | Total Amount | count | paid | Due Date | Notes |
+| --- | --- | --- | --- | --- | --- |
+Escape the pipes in the cells as \|.
+Add one row per item, and every row must have a value for each column.
+`, p.GetFormatInstructions())
+
+	res, err := p.Parse("| Customer | Total amount | Count | Paid | Due date |\n|---|---|---|---|---|\n" +
+		"| Acme | 1,200.50 | 3 | yes | 2026-01-02T00:00:00Z |\n| Globex | 15 | | false | |")
+	require.NoError(t, err)
+	require.Len(t, res.Items, 2)
+	assert.Equal(t, "Acme", res.Items[0].Customer)
+	assert.Equal(t, 1200.5, res.Items[0].Total)
+	assert.Equal(t, 3, res.Items[0].Count)
+	assert.True(t, res.Items[0].Paid)
+	require.NotNil(t, res.Items[0].Due)
+	assert.Equal(t, 2026, res.Items[0].Due.Year())
+	assert.Equal(t, 0, res.Items[1].Count)
+	assert.Nil(t, res.Items[1].Due)
+	assert.Len(t, res.Rows, 2)
+
+	_, err = p.Parse("| Customer | Total amount | Count | Paid |\n|---|---|---|---|\n| Acme | $12 | 1.5 | maybe |")
+	require.Error(t, err)
+	var terr *encoding.TableError
+	require.True(t, errors.As(err, &terr))
+	assert.Equal(t, []string{
+		`row 1, column "Total Amount": expected a number, got "$12"`,
+		`row 1, column "count": expected an integer, got "1.5"`,
+		`row 1, column "paid": expected true or false, got "maybe"`,
+	}, terr.Problems)
+
+	_, err = encoding.NewRowsOutputParser[string](encoding.TableCSV)
+	assert.EqualError(t, err, "expected struct, got string")
+
+	_, err = encoding.NewRowsOutputParser[struct{ Tags []string }](encoding.TableCSV)
+	assert.EqualError(t, err, "unsupported type of the field Tags: []string")
+}
@@ -0,0 +1,2 @@
+// Package xml encoder/decoder
+package xml
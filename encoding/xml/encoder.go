@@ -0,0 +1,93 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/go-playground/validator/v10"
+)
+
+// Encoder encodes the structs with encoding/xml,
+// the element names are taken from the xml tags, or the field names.
+// The root element is named by the XMLName field, or the type name.
+type Encoder struct {
+	reqType reflect.Type
+}
+
+func NewEncoder(req any) *Encoder {
+	t := reflect.TypeOf(req)
+	return &Encoder{
+		reqType: t,
+	}
+}
+
+func (e *Encoder) Marshal(v any) ([]byte, error) {
+	return xml.MarshalIndent(v, "", "  ")
+}
+
+// Unmarshal parses the XML leniently: the markdown fences and the text around the root element are ignored,
+// the unescaped ampersands, the HTML entities and the unclosed HTML elements are tolerated.
+func (e *Encoder) Unmarshal(bs []byte, ret any) error {
+	data := trimXML(llmutils.BytesTrimBackticks(bs))
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	return dec.Decode(ret)
+}
+
+func (e *Encoder) Validate(req any) error {
+	validate := validator.New()
+	return validate.Struct(req)
+}
+
+func (e *Encoder) GetFormatInstructions() string {
+	tValue := reflect.New(e.reqType)
+	instance := tValue.Interface()
+	if f, ok := tValue.Elem().Interface().(schema.Faker); ok {
+		instance = f.Fake()
+	} else {
+		_ = gofakeit.Struct(instance)
+		resetXMLName(tValue.Elem())
+	}
+	bs, err := e.Marshal(instance)
+	if err != nil {
+		return ""
+	}
+	var b bytes.Buffer
+	b.WriteString("\nRespond with XML in the following XML schema:\n")
+	b.WriteString("```xml\n")
+	b.Write(bs)
+	b.WriteString("\n```")
+	b.WriteString("\nMake sure to return an instance of the XML, not the schema itself.\n")
+	b.WriteString("Escape the special characters in the text, like & as &amp; and < as &lt;.\n")
+	return b.String()
+}
+
+var xmlNameType = reflect.TypeFor[xml.Name]()
+
+// resetXMLName resets the faked XMLName field of the struct,
+// so the root element is named by the tag or the type name
+func resetXMLName(v reflect.Value) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	if f, ok := v.Type().FieldByName("XMLName"); ok && f.Type == xmlNameType && len(f.Index) == 1 {
+		v.Field(f.Index[0]).Set(reflect.Zero(xmlNameType))
+	}
+}
+
+// trimXML removes the text before the first element and after the last one,
+// like "Sure, here you go:"
+func trimXML(bs []byte) []byte {
+	start := bytes.IndexByte(bs, '<')
+	end := bytes.LastIndexByte(bs, '>')
+	if start == -1 || end < start {
+		return bs
+	}
+	return bs[start : end+1]
+}
@@ -0,0 +1,110 @@
+package xml
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Details struct {
+	Location string `xml:"location" fake:"Beijing"`
+	Gender   string `xml:"gender" fake:"male"`
+}
+
+type Person struct {
+	XMLName    xml.Name  `xml:"person"`
+	Name       string    `xml:"name" fake:"Syd Xu"`
+	Age        *int      `xml:"age" fake:"24"`
+	Details    *Details  `xml:"details"`
+	DetailList []Details `xml:"details_list>details" fakesize:"1"`
+}
+
+func TestXML_GetFormatInstructions(t *testing.T) {
+	t.Parallel()
+
+	enc := NewEncoder(Person{})
+	exp := `
+Respond with XML in the following XML schema:
+` + "```xml" + `
+<person>
+  <name>Syd Xu</name>
+  <age>24</age>
+  <details>
+    <location>Beijing</location>
+    <gender>male</gender>
+  </details>
+  <details_list>
+    <details>
+      <location>Beijing</location>
+      <gender>male</gender>
+    </details>
+  </details_list>
+</person>
+` + "```" + `
+Make sure to return an instance of the XML, not the schema itself.
+Escape the special characters in the text, like & as &amp; and < as &lt;.
+`
+	assert.Equal(t, exp, enc.GetFormatInstructions())
+}
+
+func TestXML_Unmarshal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		exp   string
+	}{
+		{
+			name:  "plain",
+			input: "<person><name>John</name><age>30</age></person>",
+			exp:   "John",
+		},
+		{
+			name:  "fenced",
+			input: "```xml\n<?xml version=\"1.0\"?>\n<person>\n  <name>John</name>\n  <age>30</age>\n</person>\n```",
+			exp:   "John",
+		},
+		{
+			name:  "prose",
+			input: "Sure, here you go:\n<person><name>John</name><age>30</age></person>\nLet me know if you need more.",
+			exp:   "John",
+		},
+		{
+			name:  "unescaped ampersand",
+			input: "<person><name>John & Jane&nbsp;Doe</name><age>30</age></person>",
+			exp:   "John & Jane Doe",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var p Person
+			require.NoError(t, NewEncoder(p).Unmarshal([]byte(tc.input), &p))
+			assert.Equal(t, tc.exp, p.Name)
+			require.NotNil(t, p.Age)
+			assert.Equal(t, 30, *p.Age)
+		})
+	}
+
+	var p Person
+	err := NewEncoder(p).Unmarshal([]byte("not xml"), &p)
+	require.Error(t, err)
+}
+
+func TestXML_MarshalValidate(t *testing.T) {
+	t.Parallel()
+
+	type Req struct {
+		Name string `xml:"name" validate:"required"`
+	}
+	enc := NewEncoder(Req{})
+	bs, err := enc.Marshal(Req{Name: "John"})
+	require.NoError(t, err)
+	assert.Equal(t, "<Req>\n  <name>John</name>\n</Req>", string(bs))
+
+	require.NoError(t, enc.Validate(Req{Name: "John"}))
+	require.Error(t, enc.Validate(Req{}))
+}
@@ -2,6 +2,7 @@ package yaml
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -13,6 +14,7 @@ import (
 	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v3"
+	k8syaml "sigs.k8s.io/yaml"
 )
 
 type CommentStyle int
@@ -27,6 +29,9 @@ const (
 type Encoder struct {
 	reqType      reflect.Type
 	commentStyle CommentStyle
+	// jsonTags is true for the types with the json tags only,
+	// the field names are taken from the json tags then
+	jsonTags bool
 }
 
 func NewEncoder(req any) *Encoder {
@@ -34,11 +39,15 @@ func NewEncoder(req any) *Encoder {
 	return &Encoder{
 		reqType:      t,
 		commentStyle: NoComment,
+		jsonTags:     hasJSONTagsOnly(t),
 	}
 }
 
 func (e *Encoder) Marshal(v any) ([]byte, error) {
 	if e.commentStyle == NoComment {
+		if e.jsonTags {
+			return marshalJSONTags(v)
+		}
 		return yaml.Marshal(v)
 	}
 	node, err := e.structToYAMLWithComments(v)
@@ -48,8 +57,13 @@ func (e *Encoder) Marshal(v any) ([]byte, error) {
 	return yaml.Marshal(node)
 }
 
+// Unmarshal parses the YAML leniently: the markdown fences, the document markers
+// and the tabs in the indentation, which are not allowed by YAML, are tolerated.
 func (e *Encoder) Unmarshal(bs []byte, ret any) error {
-	data := llmutils.BytesTrimBackticks(bs)
+	data := cleanYAML(llmutils.BytesTrimBackticks(bs))
+	if e.jsonTags {
+		return k8syaml.Unmarshal(data, ret)
+	}
 	return yaml.Unmarshal(data, ret)
 }
 
@@ -108,8 +122,8 @@ func (e *Encoder) structToYAMLWithComments(v any) (*yaml.Node, error) {
 		field := typ.Field(i)
 
 		// Get the YAML key
-		yamlKey := field.Tag.Get("yaml")
-		if yamlKey == "" || yamlKey == "-" {
+		yamlKey := fieldName(field)
+		if yamlKey == "" {
 			continue // Skip unexported fields
 		}
 
@@ -203,6 +217,83 @@ func (e *Encoder) sliceToYAMLNode(v reflect.Value) *yaml.Node {
 	return node
 }
 
+// marshalJSONTags marshals the value with the json tags,
+// the JSON is parsed as YAML to keep the order of the fields
+func marshalJSONTags(v any) ([]byte, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var node yaml.Node
+	if err = yaml.Unmarshal(js, &node); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resetStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// resetStyle resets the flow style of the parsed JSON to the block style
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		resetStyle(n)
+	}
+}
+
+// fieldName returns the name of the field from the yaml tag, or the json tag,
+// or empty if the field is skipped
+func fieldName(field reflect.StructField) string {
+	tag, ok := field.Tag.Lookup("yaml")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// hasJSONTagsOnly returns true if the struct fields have the json tags, but no yaml tags
+func hasJSONTagsOnly(t reflect.Type) bool {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	jsonTags := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup("yaml"); ok {
+			return false
+		}
+		if _, ok := field.Tag.Lookup("json"); ok {
+			jsonTags = true
+		}
+	}
+	return jsonTags
+}
+
+// cleanYAML removes the document markers and replaces the tabs in the indentation
+func cleanYAML(bs []byte) []byte {
+	lines := strings.Split(strings.TrimSpace(string(bs)), "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		lines = lines[1:]
+	}
+	if n := len(lines); n > 0 && strings.TrimSpace(lines[n-1]) == "..." {
+		lines = lines[:n-1]
+	}
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(trimmed)]
+		if strings.Contains(indent, "\t") {
+			lines[i] = strings.ReplaceAll(indent, "\t", "  ") + trimmed
+		}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
 // Parse description from jsonschema
 func extractDescription(tag string) string {
 	re := regexp.MustCompile(`description=([^,]+)`)
@@ -102,6 +102,53 @@ func TestEncoder_Unmarshal(t *testing.T) {
 	}
 }
 
+func TestEncoder_Lenient(t *testing.T) {
+	type Item struct {
+		ItemName string `json:"item_name"`
+	}
+	type TestStruct struct {
+		FullName string `json:"full_name"`
+		Age      int    `json:"age,omitempty"`
+		Items    []Item `json:"items"`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "json tags",
+			input: "full_name: John\nage: 30\nitems:\n  - item_name: book",
+		},
+		{
+			name:  "document markers",
+			input: "---\nfull_name: John\nage: 30\nitems:\n  - item_name: book\n...",
+		},
+		{
+			name:  "tabs",
+			input: "Here it is:\n```yaml\nfull_name: John\nage: 30\nitems:\n\t- item_name: book\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder := NewEncoder(TestStruct{})
+			var result TestStruct
+			err := encoder.Unmarshal([]byte(tt.input), &result)
+			require.NoError(t, err)
+			assert.Equal(t, TestStruct{FullName: "John", Age: 30, Items: []Item{{ItemName: "book"}}}, result)
+		})
+	}
+
+	bs, err := NewEncoder(TestStruct{}).Marshal(TestStruct{FullName: "John", Items: []Item{{ItemName: "12"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "full_name: John\nitems:\n    - item_name: \"12\"\n", string(bs))
+
+	bs, err = NewEncoder(TestStruct{}).WithCommentStyle(LineComment).Marshal(TestStruct{FullName: "John"})
+	require.NoError(t, err)
+	assert.Contains(t, string(bs), "full_name: John\n")
+}
+
 func TestEncoder_Validate(t *testing.T) {
 	type TestStruct struct {
 		Name string `yaml:"name" validate:"required"`
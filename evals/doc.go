@@ -0,0 +1,6 @@
+// Package evals provides an evaluation harness for assistants.
+// Test cases define the input and the expected criteria, the outputs are graded
+// by programmatic assertions and an LLM-as-judge, and the results are collected
+// in a report with pass rates and token usage. The RunT helper integrates the
+// evaluation with go test.
+package evals
@@ -0,0 +1,312 @@
+package evals
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/xlog"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "evals")
+
+// DefaultPassThreshold is the default minimum score for a case to pass.
+const DefaultPassThreshold = 0.7
+
+// Case is a single evaluation test case.
+type Case struct {
+	// Name is the name of the case, used in the report and as the go test subtest name.
+	Name string `json:"name" yaml:"name"`
+	// Input is the input to the assistant.
+	Input string `json:"input" yaml:"input"`
+	// PromptInputs is prompt inputs to be rendered in the system prompt.
+	PromptInputs map[string]any `json:"prompt_inputs,omitempty" yaml:"prompt_inputs,omitempty"`
+	// Expected is an optional reference answer for the judge.
+	Expected string `json:"expected,omitempty" yaml:"expected,omitempty"`
+	// Criteria is a list of criteria the output must satisfy, evaluated by the judge.
+	Criteria []string `json:"criteria,omitempty" yaml:"criteria,omitempty"`
+
+	// Assertions is a list of programmatic assertions for the output.
+	Assertions []Assertion `json:"-" yaml:"-"`
+	// Options is additional options to be passed to the assistant on run.
+	Options []assistants.Option `json:"-" yaml:"-"`
+}
+
+// Grade is the result of a single check of the output.
+type Grade struct {
+	// Name is the name of the grader.
+	Name string `json:"name"`
+	// Passed is true if the check passed.
+	Passed bool `json:"passed"`
+	// Score is the score between 0 and 1.
+	Score float64 `json:"score"`
+	// Reason explains the grade.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Result is the result of the evaluation of a single case.
+type Result struct {
+	Name   string  `json:"name"`
+	Input  string  `json:"input"`
+	Output string  `json:"output"`
+	Passed bool    `json:"passed"`
+	Score  float64 `json:"score"`
+	Grades []Grade `json:"grades,omitempty"`
+	// Error is set if the assistant or the judge failed.
+	Error string `json:"error,omitempty"`
+	// Usage is the token usage of the assistant.
+	Usage llms.UsageStats `json:"usage"`
+	// JudgeUsage is the token usage of the judge.
+	JudgeUsage llms.Usage    `json:"judge_usage"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// Report is the evaluation report.
+type Report struct {
+	Assistant string    `json:"assistant"`
+	Results   []*Result `json:"results"`
+	Total     int       `json:"total"`
+	Passed    int       `json:"passed"`
+	Failed    int       `json:"failed"`
+	Errors    int       `json:"errors"`
+	// Usage is the total token usage of the assistant.
+	Usage llms.UsageStats `json:"usage"`
+	// JudgeUsage is the total token usage of the judge.
+	JudgeUsage llms.Usage    `json:"judge_usage"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// PassRate returns the ratio of passed cases.
+func (r *Report) PassRate() float64 {
+	if r == nil || r.Total == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(r.Total)
+}
+
+// TotalTokens returns the total number of tokens used by the assistant and the judge.
+func (r *Report) TotalTokens() uint64 {
+	if r == nil {
+		return 0
+	}
+	return r.Usage.TotalTokens + r.JudgeUsage.TotalTokens
+}
+
+// Print prints the report in a human readable format.
+func (r *Report) Print(w io.Writer) {
+	for _, res := range r.Results {
+		if res == nil {
+			continue
+		}
+		status := "PASS"
+		if res.Error != "" {
+			status = "ERROR"
+		} else if !res.Passed {
+			status = "FAIL"
+		}
+		_, _ = fmt.Fprintf(w, "%s: %s (score: %.2f, tokens: %d)\n", status, res.Name, res.Score, res.Usage.TotalTokens+res.JudgeUsage.TotalTokens)
+		if res.Error != "" {
+			_, _ = fmt.Fprintf(w, "  Error: %s\n", res.Error)
+		}
+		for _, g := range res.Grades {
+			if !g.Passed {
+				_, _ = fmt.Fprintf(w, "  %s: %s\n", g.Name, g.Reason)
+			}
+		}
+	}
+	_, _ = fmt.Fprintf(w, "Assistant: %s\nPassed: %d/%d (%.1f%%), Errors: %d\nTokens: %d (assistant: %d, judge: %d)\nDuration: %s\n",
+		r.Assistant,
+		r.Passed, r.Total, r.PassRate()*100, r.Errors,
+		r.TotalTokens(), r.Usage.TotalTokens, r.JudgeUsage.TotalTokens,
+		r.Duration.Round(time.Millisecond))
+}
+
+// Option is a function that configures the Runner.
+type Option func(*Runner)
+
+// WithJudge sets the judge to grade the outputs of the cases with Criteria or Expected.
+func WithJudge(judge Judge) Option {
+	return func(r *Runner) {
+		r.judge = judge
+	}
+}
+
+// WithConcurrency sets the number of cases evaluated in parallel.
+func WithConcurrency(n int) Option {
+	return func(r *Runner) {
+		if n > 0 {
+			r.concurrency = n
+		}
+	}
+}
+
+// WithPassThreshold sets the minimum score for a case to pass.
+func WithPassThreshold(threshold float64) Option {
+	return func(r *Runner) {
+		r.passThreshold = threshold
+	}
+}
+
+// WithTenantID sets the tenant ID of the chat context created for each case.
+func WithTenantID(tenantID string) Option {
+	return func(r *Runner) {
+		r.tenantID = tenantID
+	}
+}
+
+// Runner runs the evaluation cases against an assistant.
+type Runner struct {
+	assistant     assistants.IAssistant
+	judge         Judge
+	concurrency   int
+	passThreshold float64
+	tenantID      string
+}
+
+// NewRunner returns a new evaluation Runner for the assistant.
+func NewRunner(assistant assistants.IAssistant, opts ...Option) *Runner {
+	r := &Runner{
+		assistant:     assistant,
+		concurrency:   1,
+		passThreshold: DefaultPassThreshold,
+		tenantID:      "evals",
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run evaluates the cases and returns the report.
+// The error is returned only if the context is cancelled, together with the partial report,
+// where the cases that were not started have nil results and are counted as errors.
+// The failures of the individual cases are reported in the results.
+func (r *Runner) Run(ctx context.Context, cases []Case) (*Report, error) {
+	started := time.Now()
+	results := make([]*Result, len(cases))
+
+	sem := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i := range cases {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(idx int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[idx] = r.RunCase(ctx, &cases[idx])
+		}(i)
+	}
+	wg.Wait()
+
+	report := r.newReport(results, started)
+	if err := ctx.Err(); err != nil {
+		return report, errors.WithStack(err)
+	}
+
+	logger.ContextKV(ctx, xlog.DEBUG,
+		"assistant", report.Assistant,
+		"total", report.Total,
+		"passed", report.Passed,
+		"errors", report.Errors,
+		"tokens", report.TotalTokens(),
+	)
+	return report, nil
+}
+
+func (r *Runner) newReport(results []*Result, started time.Time) *Report {
+	report := &Report{
+		Assistant: r.assistant.Name(),
+		Results:   results,
+		Total:     len(results),
+		Duration:  time.Since(started),
+	}
+	for _, res := range results {
+		switch {
+		case res == nil:
+			// not started, the context was cancelled
+			report.Errors++
+			report.Failed++
+			continue
+		case res.Error != "":
+			report.Errors++
+			report.Failed++
+		case res.Passed:
+			report.Passed++
+		default:
+			report.Failed++
+		}
+		report.Usage.Add(&res.Usage)
+		report.JudgeUsage.Add(&res.JudgeUsage)
+	}
+	return report
+}
+
+// RunCase evaluates a single case in a new chat context.
+func (r *Runner) RunCase(ctx context.Context, c *Case) *Result {
+	started := time.Now()
+	res := &Result{
+		Name:  c.Name,
+		Input: c.Input,
+	}
+	defer func() {
+		res.Duration = time.Since(started)
+	}()
+
+	ctx = chatmodel.WithChatContext(ctx, chatmodel.NewChatContext(r.tenantID, chatmodel.NewChatID(), nil))
+
+	resp, err := r.assistant.Call(ctx, &assistants.CallInput{
+		Input:        c.Input,
+		PromptInputs: c.PromptInputs,
+		Options:      c.Options,
+	})
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Output = resp.String()
+	res.Usage = resp.Usage
+
+	for _, assertion := range c.Assertions {
+		res.Grades = append(res.Grades, assertion.Check(ctx, c, res.Output))
+	}
+
+	if r.judge != nil && (len(c.Criteria) > 0 || c.Expected != "") {
+		grade, usage, err := r.judge.Grade(ctx, c, res.Output)
+		if usage != nil {
+			res.JudgeUsage.Add(usage)
+		}
+		if err != nil {
+			res.Error = errors.WithMessage(err, "judge failed").Error()
+			return res
+		}
+		res.Grades = append(res.Grades, *grade)
+	}
+
+	res.Passed = true
+	res.Score = 1
+	if len(res.Grades) > 0 {
+		var total float64
+		for _, g := range res.Grades {
+			total += g.Score
+			if !g.Passed {
+				res.Passed = false
+			}
+		}
+		res.Score = total / float64(len(res.Grades))
+	}
+	if res.Score < r.passThreshold {
+		res.Passed = false
+	}
+	return res
+}
@@ -0,0 +1,204 @@
+package evals_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/evals"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func lastText(messages []llms.Message) string {
+	m := messages[len(messages)-1]
+	if tc, ok := m.Parts[0].(llms.TextContent); ok {
+		return tc.Text
+	}
+	return ""
+}
+
+// newAssistant returns the assistant that expects the number of the LLM calls.
+func newAssistant(t *testing.T, calls int) assistants.IAssistant {
+	ctrl := gomock.NewController(t)
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2 * calls)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			answer := "I don't know."
+			if strings.Contains(lastText(messages), "capital of France") {
+				answer = "The capital of France is Paris."
+			}
+			return &llms.ContentResponse{
+				Choices: []*llms.ContentChoice{{
+					Content: answer,
+					Usage:   llms.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
+				}},
+			}, nil
+		}).Times(calls)
+
+	return assistants.NewAssistant[chatmodel.String](mockLLM,
+		prompts.NewPromptTemplate("You are helpful and friendly AI assistant.", nil),
+		assistants.WithMode(encoding.ModePlainText),
+	).WithName("geo")
+}
+
+// newJudge returns the judge that expects the number of the LLM calls.
+func newJudge(t *testing.T, calls int) *evals.LLMJudge {
+	ctrl := gomock.NewController(t)
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(1)
+	mockLLM.EXPECT().GetName().Return("gpt-4o").Times(2 * calls)
+	mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			verdict := `{"passed": false, "score": 0.1, "reason": "the answer is missing"}`
+			if strings.Contains(lastText(messages), "Paris") {
+				verdict = `{"passed": true, "score": 0.9, "reason": "correct"}`
+			}
+			return &llms.ContentResponse{
+				Choices: []*llms.ContentChoice{{
+					Content: verdict,
+					Usage:   llms.Usage{InputTokens: 20, OutputTokens: 10, TotalTokens: 30},
+				}},
+			}, nil
+		}).Times(calls)
+	return evals.NewLLMJudge(mockLLM)
+}
+
+func testCases() []evals.Case {
+	return []evals.Case{
+		{
+			Name:       "capital",
+			Input:      "What is the capital of France?",
+			Criteria:   []string{"The answer names Paris"},
+			Assertions: []evals.Assertion{evals.Contains("paris"), evals.NotContains("London")},
+		},
+		{
+			Name:       "unknown",
+			Input:      "What is the capital of Atlantis?",
+			Expected:   "Poseidonia",
+			Assertions: []evals.Assertion{evals.Matches(`(?i)don't know`)},
+		},
+	}
+}
+
+func TestRunner(t *testing.T) {
+	t.Parallel()
+
+	runner := evals.NewRunner(newAssistant(t, 2),
+		evals.WithJudge(newJudge(t, 2)),
+		evals.WithConcurrency(2),
+	)
+	report, err := runner.Run(context.Background(), testCases())
+	require.NoError(t, err)
+
+	assert.Equal(t, "geo", report.Assistant)
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 0, report.Errors)
+	assert.Equal(t, 0.5, report.PassRate())
+	assert.EqualValues(t, 30, report.Usage.TotalTokens)
+	assert.EqualValues(t, 60, report.JudgeUsage.TotalTokens)
+	assert.EqualValues(t, 90, report.TotalTokens())
+
+	capital := report.Results[0]
+	assert.True(t, capital.Passed)
+	assert.Equal(t, "The capital of France is Paris.", capital.Output)
+	require.Len(t, capital.Grades, 3)
+	assert.Equal(t, "judge", capital.Grades[2].Name)
+	assert.InDelta(t, (1+1+0.9)/3, capital.Score, 0.001)
+
+	unknown := report.Results[1]
+	assert.False(t, unknown.Passed)
+	require.Len(t, unknown.Grades, 2)
+	assert.True(t, unknown.Grades[0].Passed)
+	assert.False(t, unknown.Grades[1].Passed)
+	assert.Equal(t, "the answer is missing", unknown.Grades[1].Reason)
+
+	var b strings.Builder
+	report.Print(&b)
+	out := b.String()
+	assert.Contains(t, out, "PASS: capital")
+	assert.Contains(t, out, "FAIL: unknown")
+	assert.Contains(t, out, "judge: the answer is missing")
+	assert.Contains(t, out, "Passed: 1/2 (50.0%)")
+}
+
+func TestRunner_NoJudge(t *testing.T) {
+	t.Parallel()
+
+	runner := evals.NewRunner(newAssistant(t, 3), evals.WithPassThreshold(1))
+	report, err := runner.Run(context.Background(), []evals.Case{
+		{
+			Name:       "equals",
+			Input:      "What is the capital of France?",
+			Assertions: []evals.Assertion{evals.Equals("The capital of France is Paris.")},
+		},
+		{
+			Name:       "json",
+			Input:      "What is the capital of France?",
+			Assertions: []evals.Assertion{evals.ValidJSON()},
+		},
+		{
+			Name:  "no assertions",
+			Input: "hello",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Passed)
+	assert.True(t, report.Results[0].Passed)
+	assert.False(t, report.Results[1].Passed)
+	assert.Equal(t, "output is not a valid JSON", report.Results[1].Grades[0].Reason)
+	assert.True(t, report.Results[2].Passed)
+	assert.Zero(t, report.JudgeUsage.TotalTokens)
+}
+
+func TestRunner_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := evals.NewRunner(newAssistant(t, 0)).Run(ctx, testCases())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the partial report counts the cases that were not started as errors
+	require.NotNil(t, report)
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, 2, report.Errors)
+	assert.Equal(t, 2, report.Failed)
+	assert.Zero(t, report.Passed)
+	assert.Equal(t, []*evals.Result{nil, nil}, report.Results)
+
+	var b strings.Builder
+	report.Print(&b)
+	assert.Contains(t, b.String(), "Passed: 0/2 (0.0%), Errors: 2")
+}
+
+func TestRunT(t *testing.T) {
+	t.Parallel()
+
+	runner := evals.NewRunner(newAssistant(t, 1), evals.WithJudge(newJudge(t, 1)))
+	report := runner.RunT(t, testCases()[:1])
+	evals.RequirePassRate(t, report, 1)
+}
+
+func TestFormatJudgeInput(t *testing.T) {
+	t.Parallel()
+
+	c := &evals.Case{
+		Input:    "question",
+		Criteria: []string{"first", "second"},
+		Expected: "answer",
+	}
+	exp := "# INPUT\nquestion\n\n# CRITERIA\n- first\n- second\n\n# EXPECTED\nanswer\n\n# RESPONSE\nresponse\n"
+	assert.Equal(t, exp, evals.FormatJudgeInput(c, "response"))
+}
@@ -0,0 +1,187 @@
+package evals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/invopop/jsonschema"
+)
+
+// Judge grades the output of a case.
+type Judge interface {
+	// Grade returns the grade of the output, and the token usage of the judge.
+	Grade(ctx context.Context, c *Case, output string) (*Grade, *llms.Usage, error)
+}
+
+// Verdict is the structured output of the LLM judge.
+type Verdict struct {
+	Passed bool    `json:"passed" yaml:"passed" jsonschema:"title=Passed,description=True if the response satisfies all the criteria."`
+	Score  float64 `json:"score" yaml:"score" jsonschema:"title=Score,description=The score of the response between 0 and 1."`
+	Reason string  `json:"reason" yaml:"reason" jsonschema:"title=Reason,description=A short explanation of the verdict."`
+}
+
+func (v Verdict) JSONSchemaExtend(schema *jsonschema.Schema) {
+	schema.Title = "Verdict"
+	schema.Description = "The verdict of the judge on the assistant response."
+}
+
+// GetContent gets the content of the message for the chat history
+func (v Verdict) GetContent() string {
+	return llmutils.ToJSON(v)
+}
+
+// DefaultJudgePrompt is the default system prompt of the LLM judge.
+const DefaultJudgePrompt = `You are an impartial judge evaluating the response of an AI assistant.
+Evaluate the RESPONSE to the INPUT against each of the CRITERIA,
+and against the EXPECTED reference answer, if provided.
+The response passes only if all the criteria are satisfied.
+Score the response between 0 and 1, where 1 means the response fully satisfies the criteria.`
+
+// LLMJudge is a Judge that uses an LLM to grade the outputs.
+type LLMJudge struct {
+	assistant *assistants.Assistant[Verdict]
+}
+
+// NewLLMJudge returns a new LLM-as-judge with the default prompt.
+func NewLLMJudge(model llms.Model, options ...assistants.Option) *LLMJudge {
+	return NewLLMJudgeWithPrompt(model, prompts.NewPromptTemplate(DefaultJudgePrompt, nil), options...)
+}
+
+// NewLLMJudgeWithPrompt returns a new LLM-as-judge with a custom system prompt.
+func NewLLMJudgeWithPrompt(model llms.Model, sysprompt prompts.FormatPrompter, options ...assistants.Option) *LLMJudge {
+	opts := append([]assistants.Option{
+		assistants.WithMode(encoding.ModeJSONSchema),
+		assistants.WithTemperature(0),
+	}, options...)
+	return &LLMJudge{
+		assistant: assistants.NewAssistant[Verdict](model, sysprompt, opts...).
+			WithName("Evaluation Judge").
+			WithDescription("Grades the responses of AI assistants."),
+	}
+}
+
+// Grade returns the grade of the output.
+func (j *LLMJudge) Grade(ctx context.Context, c *Case, output string) (*Grade, *llms.Usage, error) {
+	var verdict Verdict
+	resp, err := j.assistant.Run(ctx, &assistants.CallInput{
+		Input: FormatJudgeInput(c, output),
+	}, &verdict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	usage := resp.Usage.Usage
+	return &Grade{
+		Name:   "judge",
+		Passed: verdict.Passed,
+		Score:  min(max(verdict.Score, 0), 1),
+		Reason: verdict.Reason,
+	}, &usage, nil
+}
+
+// FormatJudgeInput returns the judge input for the case and the output.
+func FormatJudgeInput(c *Case, output string) string {
+	var b strings.Builder
+	b.WriteString("# INPUT\n")
+	b.WriteString(llmutils.EnsureEndsWithNewline(c.Input))
+	if len(c.Criteria) > 0 {
+		b.WriteString("\n# CRITERIA\n")
+		for _, cr := range c.Criteria {
+			b.WriteString("- ")
+			b.WriteString(llmutils.EnsureEndsWithNewline(cr))
+		}
+	}
+	if c.Expected != "" {
+		b.WriteString("\n# EXPECTED\n")
+		b.WriteString(llmutils.EnsureEndsWithNewline(c.Expected))
+	}
+	b.WriteString("\n# RESPONSE\n")
+	b.WriteString(llmutils.EnsureEndsWithNewline(output))
+	return b.String()
+}
+
+// Assertion is a programmatic check of the output.
+type Assertion interface {
+	Check(ctx context.Context, c *Case, output string) Grade
+}
+
+// AssertionFunc is a function that implements Assertion.
+// Returned error means the check failed.
+type AssertionFunc func(ctx context.Context, c *Case, output string) error
+
+// Assert returns a named Assertion from the function.
+func Assert(name string, fn AssertionFunc) Assertion {
+	return &namedAssertion{name: name, fn: fn}
+}
+
+type namedAssertion struct {
+	name string
+	fn   AssertionFunc
+}
+
+func (a *namedAssertion) Check(ctx context.Context, c *Case, output string) Grade {
+	if err := a.fn(ctx, c, output); err != nil {
+		return Grade{Name: a.name, Passed: false, Score: 0, Reason: err.Error()}
+	}
+	return Grade{Name: a.name, Passed: true, Score: 1}
+}
+
+// Contains asserts that the output contains the substring, case insensitive.
+func Contains(substr string) Assertion {
+	return Assert(fmt.Sprintf("contains %q", substr), func(_ context.Context, _ *Case, output string) error {
+		if !strings.Contains(strings.ToLower(output), strings.ToLower(substr)) {
+			return errors.Newf("output does not contain %q", substr)
+		}
+		return nil
+	})
+}
+
+// NotContains asserts that the output does not contain the substring, case insensitive.
+func NotContains(substr string) Assertion {
+	return Assert(fmt.Sprintf("not contains %q", substr), func(_ context.Context, _ *Case, output string) error {
+		if strings.Contains(strings.ToLower(output), strings.ToLower(substr)) {
+			return errors.Newf("output contains %q", substr)
+		}
+		return nil
+	})
+}
+
+// Equals asserts that the trimmed output equals the expected value.
+func Equals(expected string) Assertion {
+	return Assert(fmt.Sprintf("equals %q", expected), func(_ context.Context, _ *Case, output string) error {
+		if strings.TrimSpace(output) != strings.TrimSpace(expected) {
+			return errors.Newf("output does not equal %q", expected)
+		}
+		return nil
+	})
+}
+
+// Matches asserts that the output matches the regular expression.
+func Matches(pattern string) Assertion {
+	re := regexp.MustCompile(pattern)
+	return Assert(fmt.Sprintf("matches %q", pattern), func(_ context.Context, _ *Case, output string) error {
+		if !re.MatchString(output) {
+			return errors.Newf("output does not match %q", pattern)
+		}
+		return nil
+	})
+}
+
+// ValidJSON asserts that the output is a valid JSON.
+func ValidJSON() Assertion {
+	return Assert("valid JSON", func(_ context.Context, _ *Case, output string) error {
+		if !json.Valid(llmutils.CleanJSON([]byte(output))) {
+			return errors.New("output is not a valid JSON")
+		}
+		return nil
+	})
+}
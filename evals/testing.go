@@ -0,0 +1,56 @@
+package evals
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RunT evaluates the cases as subtests of t, and returns the report.
+// Each failed case fails its subtest, with the grades explaining the failure.
+func (r *Runner) RunT(t *testing.T, cases []Case) *Report {
+	t.Helper()
+
+	started := time.Now()
+	ctx := t.Context()
+	results := make([]*Result, len(cases))
+	for i := range cases {
+		c := &cases[i]
+		t.Run(c.Name, func(t *testing.T) {
+			res := r.RunCase(ctx, c)
+			results[i] = res
+			if res.Error != "" {
+				t.Errorf("case %q failed: %s", c.Name, res.Error)
+				return
+			}
+			if !res.Passed {
+				t.Errorf("case %q did not pass: score %.2f\n%s", c.Name, res.Score, failedGrades(res))
+			}
+		})
+	}
+	return r.newReport(results, started)
+}
+
+// RequirePassRate fails the test if the pass rate of the report is below the minimum.
+func RequirePassRate(t testing.TB, report *Report, minRate float64) {
+	t.Helper()
+	if rate := report.PassRate(); rate < minRate {
+		var b strings.Builder
+		report.Print(&b)
+		t.Fatalf("pass rate %.2f is below %.2f\n%s", rate, minRate, b.String())
+	}
+}
+
+func failedGrades(res *Result) string {
+	var b strings.Builder
+	for _, g := range res.Grades {
+		if !g.Passed {
+			b.WriteString("  ")
+			b.WriteString(g.Name)
+			b.WriteString(": ")
+			b.WriteString(g.Reason)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
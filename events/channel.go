@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ChannelBus is the in-process Bus, that delivers the events to the Go channels of the subscribers.
+// The events are not delivered to the subscriber with the full channel, see Dropped,
+// so the slow subscriber does not block the runs.
+type ChannelBus struct {
+	lock    sync.RWMutex
+	subs    map[int]*subscription
+	next    int
+	dropped atomic.Int64
+}
+
+type subscription struct {
+	ch    chan *Event
+	types []Type
+}
+
+// NewChannelBus returns a new ChannelBus.
+func NewChannelBus() *ChannelBus {
+	return &ChannelBus{
+		subs: make(map[int]*subscription),
+	}
+}
+
+// Subscribe returns the channel of the events of the types, or of all events if no types are provided,
+// and the function to unsubscribe, that closes the channel.
+// The buffer is the capacity of the channel.
+func (b *ChannelBus) Subscribe(buffer int, types ...Type) (<-chan *Event, func()) {
+	sub := &subscription{
+		ch:    make(chan *Event, buffer),
+		types: types,
+	}
+
+	b.lock.Lock()
+	id := b.next
+	b.next++
+	b.subs[id] = sub
+	b.lock.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.lock.Lock()
+			delete(b.subs, id)
+			b.lock.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// Publish delivers the event to the subscribers, it never blocks.
+// The subscribers share the event, and must not modify it.
+func (b *ChannelBus) Publish(_ context.Context, event *Event) error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, sub := range b.subs {
+		if len(sub.types) > 0 && !slices.Contains(sub.types, event.Type) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of the events not delivered to the subscribers with the full channel.
+func (b *ChannelBus) Dropped() int64 {
+	return b.dropped.Load()
+}
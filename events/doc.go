@@ -0,0 +1,6 @@
+// Package events provides the bus of the assistant lifecycle events,
+// such as the run started and finished, the tool calls, the parse errors and the exceeded budgets,
+// so multiple consumers, such as the audit, the billing or the UI, subscribe independently,
+// in addition to the assistants.Callback.
+// The bus is configured with assistants.WithEventBus, the adapters publish to Go channels, NATS and Kafka.
+package events
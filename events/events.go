@@ -0,0 +1,134 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// Type is the type of the lifecycle event.
+type Type string
+
+const (
+	// RunStarted is published when the run of the assistant starts.
+	RunStarted Type = "run_started"
+	// RunFinished is published when the run of the assistant finishes, with the usage and the error if failed.
+	RunFinished Type = "run_finished"
+	// ToolCall is published when the tool call is completed, with the status of the call.
+	ToolCall Type = "tool_call"
+	// ParseError is published when the LLM response fails to parse to the output type.
+	ParseError Type = "parse_error"
+	// BudgetExceeded is published when the run is rejected by the exhausted budget.
+	BudgetExceeded Type = "budget_exceeded"
+)
+
+// Event is the lifecycle event of the assistant.
+type Event struct {
+	// ID is the unique ID of the event.
+	ID   string    `json:"id"`
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	// Assistant is the name of the assistant.
+	Assistant string `json:"assistant"`
+	// The IDs from the ChatContext of the run.
+	TenantID string `json:"tenant_id,omitempty"`
+	ChatID   string `json:"chat_id,omitempty"`
+	RunID    string `json:"run_id,omitempty"`
+	Model    string `json:"model,omitempty"`
+	// Tool is the name of the tool of ToolCall event.
+	Tool string `json:"tool,omitempty"`
+	// ToolCallID is the ID of the tool call of ToolCall event.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// Status is the status of the finished run: succeeded or failed,
+	// or of the tool call: succeeded, failed, not_found or cancelled.
+	Status string `json:"status,omitempty"`
+	// Error is the error of the failed run or tool call.
+	Error string `json:"error,omitempty"`
+	// Duration is the duration of the run or the tool call.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Usage is the usage of the finished run.
+	Usage *llms.UsageStats `json:"usage,omitempty"`
+}
+
+// Bus publishes the events to the subscribers.
+// The implementation must be safe for the concurrent use,
+// and should not block the run of the assistant.
+type Bus interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// Multi returns the Bus that publishes the events to all the buses.
+func Multi(buses ...Bus) Bus {
+	return multiBus(buses)
+}
+
+type multiBus []Bus
+
+func (m multiBus) Publish(ctx context.Context, event *Event) error {
+	var errs []error
+	for _, b := range m {
+		if err := b.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NATSPublisher is the connection publishing to NATS, such as *nats.Conn.
+type NATSPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// NewNATSBus returns the Bus that publishes the JSON events to NATS,
+// with the subject of the prefix and the event type, for example "gogentic.events.tool_call",
+// so the consumers subscribe to the types with the wildcards.
+func NewNATSBus(conn NATSPublisher, subjectPrefix string) Bus {
+	return &natsBus{conn: conn, prefix: subjectPrefix}
+}
+
+type natsBus struct {
+	conn   NATSPublisher
+	prefix string
+}
+
+func (b *natsBus) Publish(_ context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	subject := string(event.Type)
+	if b.prefix != "" {
+		subject = b.prefix + "." + subject
+	}
+	return errors.WithMessagef(b.conn.Publish(subject, data), "failed to publish %s event to NATS", event.Type)
+}
+
+// KafkaProduceFunc produces the message to the Kafka topic,
+// for example with the kafka-go Writer or the franz-go client.
+type KafkaProduceFunc func(ctx context.Context, topic string, key, value []byte) error
+
+// NewKafkaBus returns the Bus that produces the JSON events to the Kafka topic,
+// the key of the message is the run ID, so the events of the run are ordered in the partition.
+func NewKafkaBus(produce KafkaProduceFunc, topic string) Bus {
+	return &kafkaBus{produce: produce, topic: topic}
+}
+
+type kafkaBus struct {
+	produce KafkaProduceFunc
+	topic   string
+}
+
+func (b *kafkaBus) Publish(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	key := event.RunID
+	if key == "" {
+		key = event.ID
+	}
+	return errors.WithMessagef(b.produce(ctx, b.topic, []byte(key), data), "failed to produce %s event to Kafka", event.Type)
+}
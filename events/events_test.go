@@ -0,0 +1,121 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelBus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bus := events.NewChannelBus()
+
+	all, unsubscribeAll := bus.Subscribe(2)
+	tools, unsubscribeTools := bus.Subscribe(1, events.ToolCall)
+
+	require.NoError(t, bus.Publish(ctx, &events.Event{ID: "1", Type: events.RunStarted}))
+	require.NoError(t, bus.Publish(ctx, &events.Event{ID: "2", Type: events.ToolCall}))
+	// both channels are full
+	require.NoError(t, bus.Publish(ctx, &events.Event{ID: "3", Type: events.ToolCall}))
+	assert.EqualValues(t, 2, bus.Dropped())
+
+	assert.Equal(t, "1", (<-all).ID)
+	assert.Equal(t, "2", (<-all).ID)
+	assert.Equal(t, "2", (<-tools).ID)
+
+	unsubscribeTools()
+	unsubscribeTools()
+	_, ok := <-tools
+	assert.False(t, ok)
+
+	require.NoError(t, bus.Publish(ctx, &events.Event{ID: "4", Type: events.ToolCall}))
+	assert.Equal(t, "4", (<-all).ID)
+	assert.EqualValues(t, 2, bus.Dropped())
+
+	unsubscribeAll()
+	require.NoError(t, bus.Publish(ctx, &events.Event{ID: "5", Type: events.RunFinished}))
+	assert.EqualValues(t, 2, bus.Dropped())
+}
+
+type natsConn struct {
+	subjects []string
+	err      error
+}
+
+func (c *natsConn) Publish(subject string, data []byte) error {
+	c.subjects = append(c.subjects, subject)
+	return c.err
+}
+
+func TestNATSBus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	conn := &natsConn{}
+	require.NoError(t, events.NewNATSBus(conn, "gogentic.events").Publish(ctx, &events.Event{Type: events.ToolCall}))
+	require.NoError(t, events.NewNATSBus(conn, "").Publish(ctx, &events.Event{Type: events.RunStarted}))
+	assert.Equal(t, []string{"gogentic.events.tool_call", "run_started"}, conn.subjects)
+
+	conn.err = errors.New("connection closed")
+	err := events.NewNATSBus(conn, "gogentic").Publish(ctx, &events.Event{Type: events.RunFinished})
+	assert.EqualError(t, err, "failed to publish run_finished event to NATS: connection closed")
+}
+
+func TestKafkaBus(t *testing.T) {
+	t.Parallel()
+
+	type message struct {
+		topic string
+		key   string
+		event events.Event
+	}
+	var messages []message
+	produce := func(_ context.Context, topic string, key, value []byte) error {
+		var event events.Event
+		if err := json.Unmarshal(value, &event); err != nil {
+			return err
+		}
+		messages = append(messages, message{topic: topic, key: string(key), event: event})
+		return nil
+	}
+
+	ctx := context.Background()
+	bus := events.NewKafkaBus(produce, "assistant-events")
+	require.NoError(t, bus.Publish(ctx, &events.Event{ID: "evt1", RunID: "run1", Type: events.ToolCall, Tool: "search"}))
+	require.NoError(t, bus.Publish(ctx, &events.Event{ID: "evt2", Type: events.RunStarted}))
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, "assistant-events", messages[0].topic)
+	assert.Equal(t, "run1", messages[0].key)
+	assert.Equal(t, "search", messages[0].event.Tool)
+	assert.Equal(t, "evt2", messages[1].key)
+
+	failed := events.NewKafkaBus(func(context.Context, string, []byte, []byte) error {
+		return errors.New("broker not available")
+	}, "assistant-events")
+	err := failed.Publish(ctx, &events.Event{Type: events.RunFinished})
+	assert.EqualError(t, err, "failed to produce run_finished event to Kafka: broker not available")
+}
+
+func TestMulti(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bus := events.NewChannelBus()
+	ch, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	conn := &natsConn{err: errors.New("connection closed")}
+	err := events.Multi(events.NewNATSBus(conn, "gogentic"), bus).Publish(ctx, &events.Event{ID: "1", Type: events.ParseError})
+	assert.EqualError(t, err, "failed to publish parse_error event to NATS: connection closed")
+	assert.Equal(t, "1", (<-ch).ID)
+
+	require.NoError(t, events.Multi().Publish(ctx, &events.Event{Type: events.RunStarted}))
+}
@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/artifacts"
+)
+
+// RegisterArtifacts registers the artifacts store as the provider
+// of the artifact://<id> resources, so the files generated by the tools
+// are retrievable by MCP clients.
+// The context of the requests must have ChatContext with tenantID.
+func (s *Server) RegisterArtifacts(store artifacts.Store) error {
+	err := s.RegisterResourceTemplate(artifacts.URIScheme+"{id}", "artifact", "File generated by a tool", "")
+	if err != nil {
+		return err
+	}
+	return s.RegisterResourceProvider(artifacts.URIScheme, NewArtifactsProvider(store))
+}
+
+type artifactsProvider struct {
+	store artifacts.Store
+}
+
+// NewArtifactsProvider returns the ResourceProvider of the artifacts store.
+func NewArtifactsProvider(store artifacts.Store) ResourceProvider {
+	return &artifactsProvider{store: store}
+}
+
+func (p *artifactsProvider) ListResources(ctx context.Context) ([]*ResourceSchema, error) {
+	list, err := p.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*ResourceSchema, 0, len(list))
+	for _, a := range list {
+		r := &ResourceSchema{
+			Name: a.Name,
+			Uri:  a.URI(),
+		}
+		if r.Name == "" {
+			r.Name = a.ID
+		}
+		if a.ContentType != "" {
+			r.MimeType = &a.ContentType
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+func (p *artifactsProvider) ReadResource(ctx context.Context, uri string) (*ResourceResponse, error) {
+	id, ok := artifacts.ParseURI(uri)
+	if !ok {
+		return nil, errors.Wrapf(artifacts.ErrNotFound, "%s", uri)
+	}
+	a, content, err := p.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read artifact")
+	}
+	if isTextMimeType(a.ContentType) {
+		return NewResourceResponse(NewTextEmbeddedResource(uri, string(data), a.ContentType)), nil
+	}
+	return NewResourceResponse(NewBlobEmbeddedResource(uri, base64.StdEncoding.EncodeToString(data), a.ContentType)), nil
+}
+
+func isTextMimeType(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mt, "text/") ||
+		mt == "application/json" ||
+		mt == "application/xml" ||
+		mt == "application/yaml" ||
+		strings.HasSuffix(mt, "+json") ||
+		strings.HasSuffix(mt, "+xml")
+}
@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/artifacts"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mcp/internal/protocol"
+	"github.com/effective-security/gogentic/mcp/internal/testingutils"
+	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerArtifacts(t *testing.T) {
+	store, err := artifacts.NewDiskStore(t.TempDir(), "http://localhost/artifacts", []byte("secret"))
+	require.NoError(t, err)
+
+	server := NewServer(testingutils.NewMockTransport())
+	require.NoError(t, server.Serve())
+	require.NoError(t, server.RegisterArtifacts(store))
+	assert.True(t, server.CheckResourceProviderRegistered(artifacts.URIScheme))
+	assert.True(t, server.CheckResourceTemplateRegistered("artifact://{id}"))
+
+	err = server.RegisterResource("test://resource", "test-resource", "Test resource", "text/plain", func() (*ResourceResponse, error) {
+		return NewResourceResponse(NewTextEmbeddedResource("test://resource", "test content", "text/plain")), nil
+	})
+	require.NoError(t, err)
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+	csv, err := store.Put(ctx, "report.csv", "text/csv", strings.NewReader("a,b"))
+	require.NoError(t, err)
+	png, err := store.Put(ctx, "chart.png", "image/png", strings.NewReader("\x89PNG"))
+	require.NoError(t, err)
+
+	resp, err := server.handleListResources(ctx, &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	list := resp.(ListResourcesResponse).Resources
+	require.Len(t, list, 3)
+	var names []string
+	for _, r := range list {
+		names = append(names, r.Name)
+	}
+	assert.ElementsMatch(t, []string{"test-resource", "report.csv", "chart.png"}, names)
+
+	// the listing without the tenant returns only the static resources
+	resp, err = server.handleListResources(context.Background(), &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.Len(t, resp.(ListResourcesResponse).Resources, 1)
+
+	read := func(uri string) map[string]any {
+		params, _ := json.Marshal(readResourceRequestParams{Uri: uri})
+		resp, err := server.handleResourceCalls(ctx, &transport.BaseJSONRPCRequest{Params: params}, protocol.RequestHandlerExtra{})
+		require.NoError(t, err)
+		js, err := json.Marshal(resp)
+		require.NoError(t, err)
+		var res map[string]any
+		require.NoError(t, json.Unmarshal(js, &res))
+		return res["contents"].([]any)[0].(map[string]any)
+	}
+
+	res := read(csv.URI())
+	assert.Equal(t, "a,b", res["text"])
+	assert.Equal(t, "text/csv", res["mimeType"])
+
+	res = read(png.URI())
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("\x89PNG")), res["blob"])
+	assert.Equal(t, "image/png", res["mimeType"])
+
+	res = read("artifact://artifact_unknown")
+	assert.Equal(t, "artifact_unknown: artifact not found", res["text"])
+	assert.Equal(t, "artifact://artifact_unknown", res["uri"])
+
+	require.NoError(t, server.DeregisterResourceProvider(artifacts.URIScheme))
+	assert.False(t, server.CheckResourceProviderRegistered(artifacts.URIScheme))
+}
@@ -0,0 +1,52 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+)
+
+// ChatContextMetaKey is the key of chatmodel.ChatContextData in the _meta of the requests.
+// The client sends the ChatContext of the request context with tools/call and prompts/get,
+// and the server restores it in the context of the handler,
+// unless the context already has the ChatContext, for example set by the transport.
+const ChatContextMetaKey = "gogentic/chatContext"
+
+// requestMeta is the _meta of the request params
+type requestMeta map[string]json.RawMessage
+
+// newRequestMeta returns the _meta with the ChatContext of the ctx,
+// or nil if the ctx does not have the ChatContext
+func newRequestMeta(ctx context.Context) (requestMeta, error) {
+	data := chatmodel.GetChatContextData(ctx)
+	if data == nil {
+		return nil, nil
+	}
+	js, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal chat context")
+	}
+	return requestMeta{ChatContextMetaKey: js}, nil
+}
+
+// withChatContext returns the ctx with the ChatContext from the _meta,
+// the IDs are normalized with the current format, see chatmodel.NormalizeID.
+func (m requestMeta) withChatContext(ctx context.Context) (context.Context, error) {
+	js, ok := m[ChatContextMetaKey]
+	if !ok || chatmodel.GetChatContext(ctx) != nil {
+		return ctx, nil
+	}
+	var data chatmodel.ChatContextData
+	if err := json.Unmarshal(js, &data); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal chat context")
+	}
+	if data.TenantID == "" {
+		return nil, errors.WithStack(chatmodel.ErrInvalidChatContext)
+	}
+	if err := data.Normalize(); err != nil {
+		return nil, err
+	}
+	return chatmodel.WithChatContext(ctx, data.ChatContext(nil)), nil
+}
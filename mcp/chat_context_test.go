@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mcp/internal/testingutils"
+	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatContextMeta(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	require.NoError(t, server.Serve())
+
+	type args struct {
+		Message string `json:"message"`
+	}
+	var got chatmodel.ChatContext
+	err := server.RegisterTool("ctx-tool", "Tool with chat context", func(ctx context.Context, _ args) (*ToolResponse, error) {
+		got = chatmodel.GetChatContext(ctx)
+		return NewToolResponse(NewTextContent("ok")), nil
+	})
+	require.NoError(t, err)
+
+	cc := chatmodel.NewChatContext("tenant1", "chat1", nil)
+	cc.SetUserID("user1")
+	cc.SetLocale("en-US")
+	cc.SetTimezone("America/New_York")
+	cc.SetMetadata("plan", "pro")
+
+	meta, err := newRequestMeta(chatmodel.WithChatContext(context.Background(), cc))
+	require.NoError(t, err)
+	params, err := json.Marshal(&baseCallToolRequestParams{
+		Name:      "ctx-tool",
+		Arguments: json.RawMessage(`{"message":"hi"}`),
+		Meta:      meta,
+	})
+	require.NoError(t, err)
+
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{Params: params}, RequestHandlerExtra{})
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "tenant1", got.GetTenantID())
+	assert.Equal(t, "chat1", got.GetChatID())
+	assert.Equal(t, cc.GetRunID(), got.GetRunID())
+	assert.Equal(t, "user1", got.GetUserID())
+	assert.Equal(t, "en-US", got.GetLocale())
+	assert.Equal(t, "America/New_York", got.GetTimezone())
+	assert.Equal(t, map[string]any{"plan": "pro"}, got.Metadata())
+
+	// the chat context of the request context is preserved
+	local := chatmodel.NewChatContext("local", "", nil)
+	_, err = server.handleToolCalls(chatmodel.WithChatContext(context.Background(), local),
+		&transport.BaseJSONRPCRequest{Params: params}, RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.Equal(t, local, got)
+
+	// no meta
+	got = nil
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"ctx-tool","arguments":{}}`),
+	}, RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"ctx-tool","arguments":{},"_meta":{"gogentic/chatContext":{"chat_id":"c"}}}`),
+	}, RequestHandlerExtra{})
+	assert.EqualError(t, err, "invalid chat context")
+
+	// the IDs are normalized
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"ctx-tool","arguments":{},"_meta":{"gogentic/chatContext":{"tenant_id":" t1 ","chat_id":" c1 "}}}`),
+	}, RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.Equal(t, "t1", got.GetTenantID())
+	assert.Equal(t, "c1", got.GetChatID())
+
+	_, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"ctx-tool","arguments":{},"_meta":{"gogentic/chatContext":{"tenant_id":"t1","chat_id":" "}}}`),
+	}, RequestHandlerExtra{})
+	assert.EqualError(t, err, "chat ID: default: empty ID: invalid ID")
+
+	meta, err = newRequestMeta(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, meta)
+}
@@ -124,9 +124,15 @@ func (c *Client) CallTool(ctx context.Context, name string, arguments any) (*Too
 		return nil, errors.Wrap(err, "failed to marshal arguments")
 	}
 
+	meta, err := newRequestMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	params := baseCallToolRequestParams{
 		Name:      name,
 		Arguments: argumentsJson,
+		Meta:      meta,
 	}
 
 	response, err := c.protocol.Request(ctx, "tools/call", params, nil)
@@ -188,9 +194,15 @@ func (c *Client) GetPrompt(ctx context.Context, name string, arguments any) (*Pr
 		return nil, errors.Wrap(err, "failed to marshal arguments")
 	}
 
+	meta, err := newRequestMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	params := baseGetPromptRequestParamsArguments{
 		Name:      name,
 		Arguments: argumentsJson,
+		Meta:      meta,
 	}
 
 	response, err := c.protocol.Request(ctx, "prompts/get", params, nil)
@@ -284,6 +296,22 @@ func (c *Client) Ping(ctx context.Context) error {
 	return nil
 }
 
+// SetLogLevel sets the minimum level of the log messages sent by the server
+func (c *Client) SetLogLevel(ctx context.Context, level LoggingLevel) error {
+	if !c.initialized {
+		return errors.New("client not initialized")
+	}
+
+	_, err := c.protocol.Request(ctx, "logging/setLevel", map[string]any{
+		"level": level,
+	}, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to set log level")
+	}
+
+	return nil
+}
+
 // GetCapabilities returns the server capabilities obtained during initialization
 func (c *Client) GetCapabilities() *ServerCapabilities {
 	return c.capabilities
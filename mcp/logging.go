@@ -0,0 +1,182 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/effective-security/xlog"
+)
+
+// LoggingLevel is the severity of the log message, as defined by RFC 5424
+type LoggingLevel string
+
+// Logging levels, in the order of the severity
+const (
+	LoggingLevelDebug     LoggingLevel = "debug"
+	LoggingLevelInfo      LoggingLevel = "info"
+	LoggingLevelNotice    LoggingLevel = "notice"
+	LoggingLevelWarning   LoggingLevel = "warning"
+	LoggingLevelError     LoggingLevel = "error"
+	LoggingLevelCritical  LoggingLevel = "critical"
+	LoggingLevelAlert     LoggingLevel = "alert"
+	LoggingLevelEmergency LoggingLevel = "emergency"
+)
+
+var loggingLevels = []LoggingLevel{
+	LoggingLevelDebug,
+	LoggingLevelInfo,
+	LoggingLevelNotice,
+	LoggingLevelWarning,
+	LoggingLevelError,
+	LoggingLevelCritical,
+	LoggingLevelAlert,
+	LoggingLevelEmergency,
+}
+
+// IsValid returns true if the level is one of the defined levels
+func (l LoggingLevel) IsValid() bool {
+	return slices.Contains(loggingLevels, l)
+}
+
+// Enabled returns true if the messages with the level are sent when the minimum level is min
+func (l LoggingLevel) Enabled(min LoggingLevel) bool {
+	return slices.Index(loggingLevels, l) >= slices.Index(loggingLevels, min)
+}
+
+func (l LoggingLevel) xlogLevel() xlog.LogLevel {
+	switch l {
+	case LoggingLevelDebug:
+		return xlog.DEBUG
+	case LoggingLevelInfo:
+		return xlog.INFO
+	case LoggingLevelNotice:
+		return xlog.NOTICE
+	case LoggingLevelWarning:
+		return xlog.WARNING
+	case LoggingLevelError:
+		return xlog.ERROR
+	default:
+		return xlog.CRITICAL
+	}
+}
+
+// LoggingMessageParams is the params of the notifications/message notification
+type LoggingMessageParams struct {
+	// The severity of this log message.
+	Level LoggingLevel `json:"level" yaml:"level" mapstructure:"level"`
+	// An optional name of the logger issuing this message.
+	Logger string `json:"logger,omitempty" yaml:"logger,omitempty" mapstructure:"logger,omitempty"`
+	// The data to be logged, such as a string message or an object.
+	Data any `json:"data" yaml:"data" mapstructure:"data"`
+}
+
+// WithLogLevel sets the minimum level of the messages sent to the client,
+// until the client sets it with logging/setLevel request, by default LoggingLevelInfo.
+func WithLogLevel(level LoggingLevel) ServerOptions {
+	return func(s *Server) {
+		s.logging.level = level
+	}
+}
+
+type loggingState struct {
+	lock  sync.RWMutex
+	level LoggingLevel
+}
+
+func (s *loggingState) get() LoggingLevel {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.level
+}
+
+func (s *loggingState) set(level LoggingLevel) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.level = level
+}
+
+// LogLevel returns the minimum level of the messages sent to the client
+func (s *Server) LogLevel() LoggingLevel {
+	return s.logging.get()
+}
+
+func (s *Server) handleSetLogLevel(ctx context.Context, req *transport.BaseJSONRPCRequest, _ RequestHandlerExtra) (transport.JsonRpcBody, error) {
+	var params struct {
+		Level LoggingLevel `json:"level"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal arguments")
+	}
+	if !params.Level.IsValid() {
+		return nil, errors.Errorf("invalid logging level: %s", params.Level)
+	}
+
+	s.logging.set(params.Level)
+	logger.ContextKV(ctx, xlog.DEBUG, "reason", "set_log_level", "level", params.Level)
+	return map[string]any{}, nil
+}
+
+// Logger returns the Logger with the name, that sends the messages to the client
+func (s *Server) Logger(name string) *Logger {
+	return &Logger{server: s, name: name}
+}
+
+// Logger sends the log messages to the connected client with notifications/message,
+// and writes them to the package logger of the server,
+// so the tool logs appear in the console of the MCP client and in the server logs.
+// The messages below the level set by the client are not sent, but they are still written to the logs.
+type Logger struct {
+	server *Server
+	name   string
+}
+
+// Log sends the data to the client, the data must be JSON serializable
+func (l *Logger) Log(ctx context.Context, level LoggingLevel, data any) error {
+	logger.ContextKV(ctx, level.xlogLevel(), "logger", l.name, "data", data)
+
+	s := l.server
+	if !s.isRunning || !level.Enabled(s.LogLevel()) {
+		return nil
+	}
+	return s.protocol.Notification("notifications/message", &LoggingMessageParams{
+		Level:  level,
+		Logger: l.name,
+		Data:   data,
+	})
+}
+
+// Debug sends the debug message with the key-value pairs
+func (l *Logger) Debug(ctx context.Context, msg string, kv ...any) {
+	l.logKV(ctx, LoggingLevelDebug, msg, kv)
+}
+
+// Info sends the info message with the key-value pairs
+func (l *Logger) Info(ctx context.Context, msg string, kv ...any) {
+	l.logKV(ctx, LoggingLevelInfo, msg, kv)
+}
+
+// Warning sends the warning message with the key-value pairs
+func (l *Logger) Warning(ctx context.Context, msg string, kv ...any) {
+	l.logKV(ctx, LoggingLevelWarning, msg, kv)
+}
+
+// Error sends the error message with the key-value pairs
+func (l *Logger) Error(ctx context.Context, msg string, kv ...any) {
+	l.logKV(ctx, LoggingLevelError, msg, kv)
+}
+
+func (l *Logger) logKV(ctx context.Context, level LoggingLevel, msg string, kv []any) {
+	data := map[string]any{"message": msg}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if key, ok := kv[i].(string); ok {
+			data[key] = kv[i+1]
+		}
+	}
+	if err := l.Log(ctx, level, data); err != nil {
+		logger.ContextKV(ctx, xlog.WARNING, "reason", "log_notification", "err", err.Error())
+	}
+}
@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/effective-security/gogentic/mcp/internal/testingutils"
+	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggingLevel_Enabled(t *testing.T) {
+	tcases := []struct {
+		level LoggingLevel
+		min   LoggingLevel
+		exp   bool
+	}{
+		{LoggingLevelDebug, LoggingLevelDebug, true},
+		{LoggingLevelDebug, LoggingLevelInfo, false},
+		{LoggingLevelError, LoggingLevelWarning, true},
+		{LoggingLevelWarning, LoggingLevelError, false},
+		{LoggingLevelEmergency, LoggingLevelAlert, true},
+	}
+	for _, tc := range tcases {
+		t.Run(string(tc.level)+"_"+string(tc.min), func(t *testing.T) {
+			assert.Equal(t, tc.exp, tc.level.Enabled(tc.min))
+		})
+	}
+	assert.True(t, LoggingLevelNotice.IsValid())
+	assert.False(t, LoggingLevel("verbose").IsValid())
+}
+
+func TestServerLogging(t *testing.T) {
+	mockTransport := testingutils.NewMockTransport()
+	server := NewServer(mockTransport)
+	require.NoError(t, server.Serve())
+	assert.Equal(t, LoggingLevelInfo, server.LogLevel())
+	assert.NotNil(t, server.generateCapabilities().Logging)
+
+	ctx := context.Background()
+	l := server.Logger("tools")
+	l.Debug(ctx, "not sent")
+	l.Info(ctx, "sent", "tool", "search", "count", 2)
+
+	messages := mockTransport.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "notifications/message", messages[0].JsonRpcNotification.Method)
+	assert.JSONEq(t,
+		`{"level":"info","logger":"tools","data":{"message":"sent","tool":"search","count":2}}`,
+		string(messages[0].JsonRpcNotification.Params))
+
+	_, err := server.handleSetLogLevel(ctx, &transport.BaseJSONRPCRequest{
+		Params: json.RawMessage(`{"level":"verbose"}`),
+	}, RequestHandlerExtra{})
+	assert.EqualError(t, err, "invalid logging level: verbose")
+
+	resp, err := server.handleSetLogLevel(ctx, &transport.BaseJSONRPCRequest{
+		Params: json.RawMessage(`{"level":"error"}`),
+	}, RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{}, resp)
+	assert.Equal(t, LoggingLevelError, server.LogLevel())
+
+	l.Warning(ctx, "not sent")
+	require.NoError(t, l.Log(ctx, LoggingLevelCritical, "disk full"))
+
+	messages = mockTransport.GetMessages()
+	require.Len(t, messages, 2)
+	assert.JSONEq(t,
+		`{"level":"critical","logger":"tools","data":"disk full"}`,
+		string(messages[1].JsonRpcNotification.Params))
+}
@@ -8,6 +8,9 @@ type baseGetPromptRequestParamsArguments struct {
 
 	// The name of the prompt or prompt template.
 	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// Meta is the metadata of the request, see ChatContextMetaKey.
+	Meta requestMeta `json:"_meta,omitempty" yaml:"_meta,omitempty" mapstructure:"_meta,omitempty"`
 }
 
 // The server's response to a prompts/list request from the client.
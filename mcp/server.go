@@ -8,10 +8,12 @@ import (
 	"runtime/debug"
 	"slices"
 	"sort"
+	"strings"
 
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/mcp/internal/protocol"
 	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/effective-security/x/maps"
 	"github.com/effective-security/xlog"
 	"github.com/invopop/jsonschema"
@@ -131,9 +133,12 @@ type Server struct {
 	prompts            *maps.SyncMap[string, *prompt]
 	resources          *maps.SyncMap[string, *resource]
 	resourceTemplates  *maps.SyncMap[string, *resourceTemplate]
+	resourceProviders  *maps.SyncMap[string, ResourceProvider]
 	serverInstructions *string
 	serverName         string
 	serverVersion      string
+	toolMiddleware     []ToolMiddleware
+	logging            loggingState
 }
 
 type prompt struct {
@@ -144,10 +149,11 @@ type prompt struct {
 }
 
 type tool struct {
-	Name            string
-	Description     string
-	Handler         func(context.Context, baseCallToolRequestParams) *toolResponseSent
-	ToolInputSchema *jsonschema.Schema
+	Name             string
+	Description      string
+	Handler          ToolHandlerFunc
+	ToolInputSchema  *jsonschema.Schema
+	ToolOutputSchema *jsonschema.Schema
 }
 
 type resource struct {
@@ -207,15 +213,32 @@ func NewServer(transport transport.Transport, options ...ServerOptions) *Server
 		prompts:           new(maps.SyncMap[string, *prompt]),
 		resources:         new(maps.SyncMap[string, *resource]),
 		resourceTemplates: new(maps.SyncMap[string, *resourceTemplate]),
+		resourceProviders: new(maps.SyncMap[string, ResourceProvider]),
 	}
+	server.logging.level = LoggingLevelInfo
 	for _, option := range options {
 		option(server)
 	}
 	return server
 }
 
-// RegisterTool registers a new tool with the server
+// RegisterTool registers a new tool with the server.
+// The handler is a function with one of the signatures:
+//
+//	func(args Args) (*ToolResponse, error)
+//	func(ctx context.Context, args Args) (*ToolResponse, error)
+//	func(ctx context.Context, extra RequestHandlerExtra, args Args) (*ToolResponse, error)
+//
+// The handler may return the pointer to the struct instead of *ToolResponse,
+// then the output schema of the tool is reflected from the struct,
+// and the result is returned as the structured content, see NewStructuredToolResponse.
 func (s *Server) RegisterTool(name string, description string, handler any) error {
+	return s.RegisterToolWithMiddleware(name, description, handler)
+}
+
+// RegisterToolWithMiddleware registers a new tool with the middleware,
+// the middleware is applied after the middleware of the server, see WithToolMiddleware.
+func (s *Server) RegisterToolWithMiddleware(name string, description string, handler any, middleware ...ToolMiddleware) error {
 	err := validateToolHandler(handler)
 	if err != nil {
 		return err
@@ -223,10 +246,11 @@ func (s *Server) RegisterTool(name string, description string, handler any) erro
 	inputSchema := createJsonSchemaFromHandler(handler)
 
 	s.tools.Store(name, &tool{
-		Name:            name,
-		Description:     description,
-		Handler:         createWrappedToolHandler(handler),
-		ToolInputSchema: inputSchema,
+		Name:             name,
+		Description:      description,
+		Handler:          chainToolMiddleware(createWrappedToolHandler(handler), middleware),
+		ToolInputSchema:  inputSchema,
+		ToolOutputSchema: createOutputSchemaFromHandler(handler),
 	})
 
 	return s.sendToolListChangedNotification()
@@ -358,6 +382,32 @@ func (s *Server) DeregisterResourceTemplate(uriTemplate string) error {
 	return s.sendResourceListChangedNotification()
 }
 
+// ResourceProvider provides the dynamic resources with the URI prefix,
+// such as the artifacts generated by the tools.
+type ResourceProvider interface {
+	// ListResources returns the resources available in the context.
+	ListResources(ctx context.Context) ([]*ResourceSchema, error)
+	// ReadResource returns the resource by URI.
+	ReadResource(ctx context.Context, uri string) (*ResourceResponse, error)
+}
+
+// RegisterResourceProvider registers the provider of the resources,
+// which URI starts with the uriPrefix.
+func (s *Server) RegisterResourceProvider(uriPrefix string, provider ResourceProvider) error {
+	s.resourceProviders.Store(uriPrefix, provider)
+	return s.sendResourceListChangedNotification()
+}
+
+func (s *Server) CheckResourceProviderRegistered(uriPrefix string) bool {
+	_, ok := s.resourceProviders.Load(uriPrefix)
+	return ok
+}
+
+func (s *Server) DeregisterResourceProvider(uriPrefix string) error {
+	s.resourceProviders.Delete(uriPrefix)
+	return s.sendResourceListChangedNotification()
+}
+
 func (s *Server) RegisterPrompt(name string, description string, handler any) error {
 	err := validatePromptHandler(handler)
 	if err != nil {
@@ -518,78 +568,78 @@ func validatePromptHandler(handler any) error {
 	return nil
 }
 
-// Creates a full JSON schema from a user provided handler by introspecting the arguments
+// Creates a full JSON schema from a user provided handler by introspecting the arguments,
+// the arguments are the last parameter of the handler
 func createJsonSchemaFromHandler(handler any) *jsonschema.Schema {
-	handlerValue := reflect.ValueOf(handler)
-	handlerType := handlerValue.Type()
-	var argumentType reflect.Type
-	if handlerType.NumIn() == 2 {
-		argumentType = handlerType.In(1)
-	} else if handlerType.NumIn() == 1 {
-		argumentType = handlerType.In(0)
-	}
+	handlerType := reflect.TypeOf(handler)
+	argumentType := handlerType.In(handlerType.NumIn() - 1)
 	inputSchema := jsonSchemaReflector.ReflectFromType(argumentType)
+	schema.AddTagExamples(argumentType, inputSchema)
 	return inputSchema
 }
 
+// Creates the JSON schema of the structured output from the result type of the handler,
+// or nil if the handler returns *ToolResponse
+func createOutputSchemaFromHandler(handler any) *jsonschema.Schema {
+	outputType := reflect.TypeOf(handler).Out(0)
+	if !isStructuredOutput(outputType) {
+		return nil
+	}
+	outputSchema := jsonSchemaReflector.ReflectFromType(outputType.Elem())
+	schema.AddTagExamples(outputType.Elem(), outputSchema)
+	return outputSchema
+}
+
+// isStructuredOutput returns true if the handler returns the pointer to the struct other than ToolResponse
+func isStructuredOutput(outputType reflect.Type) bool {
+	return outputType != toolResponseType &&
+		outputType.Kind() == reflect.Pointer &&
+		outputType.Elem().Kind() == reflect.Struct
+}
+
+var toolResponseType = reflect.PointerTo(reflect.TypeOf(ToolResponse{}))
+
 // This takes a user provided handler and returns a wrapped handler which can be used to actually answer requests
-// Concretely, it will deserialize the arguments and call the user provided handler and then serialize the response
+// Concretely, it will deserialize the arguments and call the user provided handler
 // If the handler returns an error, it will be serialized and sent back as a tool error rather than a protocol error
-func createWrappedToolHandler(userHandler any) func(context.Context, baseCallToolRequestParams) *toolResponseSent {
+func createWrappedToolHandler(userHandler any) ToolHandlerFunc {
 	handlerValue := reflect.ValueOf(userHandler)
 	handlerType := handlerValue.Type()
-	var argumentType reflect.Type
-	if handlerType.NumIn() == 2 {
-		argumentType = handlerType.In(1)
-	} else if handlerType.NumIn() == 1 {
-		argumentType = handlerType.In(0)
-	}
-	return func(ctx context.Context, arguments baseCallToolRequestParams) (resp *toolResponseSent) {
-		defer func() {
-			if r := recover(); r != nil {
-				var err error
-				switch recovered := r.(type) {
-				case error:
-					err = recovered
-				default:
-					err = errors.Errorf("%v", recovered)
-				}
-				resp = newToolResponseSentError(errors.Wrap(err, "internal error: tool panicked"))
-
-				logger.ContextKV(ctx, xlog.ERROR,
-					"reason", "panic",
-					"err", err.Error(),
-					"stack", string(debug.Stack()))
-			}
-		}()
+	numIn := handlerType.NumIn()
+	argumentType := handlerType.In(numIn - 1)
+	structured := isStructuredOutput(handlerType.Out(0))
 
+	return func(ctx context.Context, call *ToolCall) (*ToolResponse, error) {
 		// Instantiate a struct of the type of the arguments
 		rt := reflect.New(argumentType)
 		if !rt.CanInterface() {
-			return newToolResponseSentError(errors.Errorf("arguments must be a struct"))
+			return nil, errors.Errorf("arguments must be a struct")
 		}
 		unmarshaledArguments := rt.Interface()
 
-		if len(arguments.Arguments) > 0 {
+		if len(call.Arguments) > 0 {
 			// Unmarshal the JSON into the correct type
-			err := json.Unmarshal(arguments.Arguments, &unmarshaledArguments)
+			err := json.Unmarshal(call.Arguments, &unmarshaledArguments)
 			if err != nil {
-				return newToolResponseSentError(errors.Wrap(err, "failed to unmarshal arguments"))
+				return nil, errors.Wrap(err, "failed to unmarshal arguments")
 			}
 		}
 		// Need to dereference the unmarshaled arguments
 		of := reflect.ValueOf(unmarshaledArguments)
 		if of.Kind() != reflect.Pointer {
-			return newToolResponseSentError(errors.Errorf("arguments must be a struct"))
+			return nil, errors.Errorf("arguments must be a struct")
 		}
 		elem := of.Elem()
 		if !elem.CanInterface() {
-			return newToolResponseSentError(errors.Errorf("arguments must be a struct"))
+			return nil, errors.Errorf("arguments must be a struct")
 		}
 		var args []reflect.Value
-		if handlerType.NumIn() == 2 {
+		switch numIn {
+		case 3:
+			args = []reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(call.Extra), elem}
+		case 2:
 			args = []reflect.Value{reflect.ValueOf(ctx), elem}
-		} else {
+		default:
 			args = []reflect.Value{elem}
 		}
 
@@ -597,21 +647,27 @@ func createWrappedToolHandler(userHandler any) func(context.Context, baseCallToo
 		output := handlerValue.Call(args)
 
 		if len(output) != 2 {
-			return newToolResponseSentError(errors.Errorf("handler must return exactly two values, got %d", len(output)))
+			return nil, errors.Errorf("handler must return exactly two values, got %d", len(output))
 		}
 
 		if !output[0].CanInterface() {
-			return newToolResponseSentError(errors.Errorf("handler must return a struct, got %s", output[0].Type().Name()))
+			return nil, errors.Errorf("handler must return a struct, got %s", output[0].Type().Name())
 		}
 		tool := output[0].Interface()
 		if !output[1].CanInterface() {
-			return newToolResponseSentError(errors.Errorf("handler must return an error, got %s", output[1].Type().Name()))
+			return nil, errors.Errorf("handler must return an error, got %s", output[1].Type().Name())
 		}
 		errorOut := output[1].Interface()
-		if errorOut == nil {
-			return newToolResponseSent(tool.(*ToolResponse))
+		if errorOut != nil {
+			return nil, errors.Wrap(errorOut.(error), "failed to handle tool call")
 		}
-		return newToolResponseSentError(errors.Wrap(errorOut.(error), "failed to handle tool call"))
+		if structured {
+			if output[0].IsNil() {
+				return nil, errors.Errorf("handler returned nil result")
+			}
+			return NewStructuredToolResponse(tool)
+		}
+		return tool.(*ToolResponse), nil
 	}
 }
 
@@ -629,6 +685,7 @@ func (s *Server) Serve() error {
 	pr.SetRequestHandler("resources/list", s.handleListResources)
 	pr.SetRequestHandler("resources/templates/list", s.handleListResourceTemplates)
 	pr.SetRequestHandler("resources/read", s.handleResourceCalls)
+	pr.SetRequestHandler("logging/setLevel", s.handleSetLogLevel)
 	err := pr.Connect(s.transport)
 	if err != nil {
 		return err
@@ -707,11 +764,15 @@ func (s *Server) handleListTools(ctx context.Context, request *transport.BaseJSO
 	toolsToReturn := make([]ToolRetType, 0)
 
 	for i := startPosition; i < endPosition; i++ {
-		toolsToReturn = append(toolsToReturn, ToolRetType{
+		ret := ToolRetType{
 			Name:        orderedTools[i].Name,
 			Description: &orderedTools[i].Description,
 			InputSchema: orderedTools[i].ToolInputSchema,
-		})
+		}
+		if orderedTools[i].ToolOutputSchema != nil {
+			ret.OutputSchema = orderedTools[i].ToolOutputSchema
+		}
+		toolsToReturn = append(toolsToReturn, ret)
 	}
 
 	return ToolsResponse{
@@ -726,7 +787,7 @@ func (s *Server) handleListTools(ctx context.Context, request *transport.BaseJSO
 	}, nil
 }
 
-func (s *Server) handleToolCalls(ctx context.Context, req *transport.BaseJSONRPCRequest, _ protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
+func (s *Server) handleToolCalls(ctx context.Context, req *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
 	params := baseCallToolRequestParams{}
 	// Instantiate a struct of the type of the arguments
 	err := json.Unmarshal(req.Params, &params)
@@ -738,12 +799,53 @@ func (s *Server) handleToolCalls(ctx context.Context, req *transport.BaseJSONRPC
 	if toolToUse == nil || !ok {
 		return nil, errors.Errorf("unknown tool: %s", params.Name)
 	}
-	return toolToUse.Handler(ctx, params), nil
+
+	ctx, err = params.Meta.withChatContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	call := &ToolCall{
+		Name:      params.Name,
+		RequestID: req.Id,
+		Arguments: params.Arguments,
+		Extra:     extra,
+	}
+	return callTool(ctx, chainToolMiddleware(toolToUse.Handler, s.toolMiddleware), call), nil
+}
+
+// callTool calls the handler, the errors and the panics are sent to the client as the tool errors
+func callTool(ctx context.Context, handler ToolHandlerFunc, call *ToolCall) (resp *toolResponseSent) {
+	defer func() {
+		if r := recover(); r != nil {
+			var err error
+			switch recovered := r.(type) {
+			case error:
+				err = recovered
+			default:
+				err = errors.Errorf("%v", recovered)
+			}
+			resp = newToolResponseSentError(errors.Wrap(err, "internal error: tool panicked"))
+
+			logger.ContextKV(ctx, xlog.ERROR,
+				"tool", call.Name,
+				"reason", "panic",
+				"err", err.Error(),
+				"stack", string(debug.Stack()))
+		}
+	}()
+
+	res, err := handler(ctx, call)
+	if err != nil {
+		return newToolResponseSentError(err)
+	}
+	return newToolResponseSent(res)
 }
 
 func (s *Server) generateCapabilities() ServerCapabilities {
 	t := false
 	return ServerCapabilities{
+		Logging: &ServerCapabilitiesLogging{},
 		Tools: func() *ServerCapabilitiesTools {
 			return &ServerCapabilitiesTools{
 				ListChanged: &t,
@@ -842,9 +944,27 @@ func (s *Server) handleListResources(ctx context.Context, request *transport.Bas
 	}
 
 	// Order by URI for pagination
-	var orderedResources []*resource
+	var orderedResources []*ResourceSchema
 	s.resources.Range(func(k string, r *resource) bool {
-		orderedResources = append(orderedResources, r)
+		orderedResources = append(orderedResources, &ResourceSchema{
+			Description: &r.Description,
+			MimeType:    &r.mimeType,
+			Name:        r.Name,
+			Uri:         r.Uri,
+		})
+		return true
+	})
+	s.resourceProviders.Range(func(prefix string, p ResourceProvider) bool {
+		list, err := p.ListResources(ctx)
+		if err != nil {
+			logger.ContextKV(ctx, xlog.WARNING,
+				"reason", "list_resources",
+				"prefix", prefix,
+				"err", err.Error(),
+			)
+			return true
+		}
+		orderedResources = append(orderedResources, list...)
 		return true
 	})
 	sort.Slice(orderedResources, func(i, j int) bool {
@@ -876,16 +996,7 @@ func (s *Server) handleListResources(ctx context.Context, request *transport.Bas
 	}
 
 	resourcesToReturn := make([]*ResourceSchema, 0)
-	for i := startPosition; i < endPosition; i++ {
-		r := orderedResources[i]
-		resourcesToReturn = append(resourcesToReturn, &ResourceSchema{
-			Annotations: nil,
-			Description: &r.Description,
-			MimeType:    &r.mimeType,
-			Name:        r.Name,
-			Uri:         r.Uri,
-		})
-	}
+	resourcesToReturn = append(resourcesToReturn, orderedResources[startPosition:endPosition]...)
 
 	return ListResourcesResponse{
 		Resources: resourcesToReturn,
@@ -991,6 +1102,11 @@ func (s *Server) handlePromptCalls(ctx context.Context, req *transport.BaseJSONR
 	if promptToUse == nil {
 		return nil, errors.Wrapf(err, "unknown prompt: %s", req.Method)
 	}
+
+	ctx, err = params.Meta.withChatContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 	return promptToUse.Handler(ctx, params), nil
 }
 
@@ -1011,10 +1127,29 @@ func (s *Server) handleResourceCalls(ctx context.Context, req *transport.BaseJSO
 		return false
 	})
 
-	if resourceToUse == nil {
+	if resourceToUse != nil {
+		return resourceToUse.Handler(ctx), nil
+	}
+
+	// the longest matching prefix wins
+	var provider ResourceProvider
+	matched := ""
+	s.resourceProviders.Range(func(prefix string, p ResourceProvider) bool {
+		if strings.HasPrefix(params.Uri, prefix) && len(prefix) > len(matched) {
+			provider = p
+			matched = prefix
+		}
+		return true
+	})
+	if provider == nil {
 		return nil, errors.Wrapf(err, "unknown prompt: %s", req.Method)
 	}
-	return resourceToUse.Handler(ctx), nil
+
+	resp, err := provider.ReadResource(ctx, params.Uri)
+	if err != nil {
+		return &resourceResponseSent{Uri: params.Uri, Error: err}, nil
+	}
+	return newResourceResponseSent(resp), nil
 }
 
 func (s *Server) handlePing(ctx context.Context, request *transport.BaseJSONRPCRequest, extra protocol.RequestHandlerExtra) (transport.JsonRpcBody, error) {
@@ -1025,25 +1160,32 @@ func validateToolHandler(handler any) error {
 	handlerValue := reflect.ValueOf(handler)
 	handlerType := handlerValue.Type()
 
-	// We allow the handler to take a context.Context as the first argument optionally
-	if handlerType.NumIn() != 1 && handlerType.NumIn() != 2 {
-		return errors.Errorf("handler must take exactly one or two arguments, got %d", handlerType.NumIn())
+	// We allow the handler to take a context.Context as the first argument,
+	// and RequestHandlerExtra as the second argument optionally
+	if handlerType.NumIn() < 1 || handlerType.NumIn() > 3 {
+		return errors.Errorf("handler must take one, two or three arguments, got %d", handlerType.NumIn())
 	}
 
 	if handlerType.NumOut() != 2 {
 		return errors.Errorf("handler must return exactly two values, got %d", handlerType.NumOut())
 	}
 
-	if handlerType.NumIn() == 2 {
+	if handlerType.NumIn() >= 2 {
 		// Check that the first argument is a context.Context
 		if handlerType.In(0) != reflect.TypeOf((*context.Context)(nil)).Elem() {
-			return errors.Errorf("when a handler has 2 arguments, handler must take context.Context as the first argument, got %s", handlerType.In(0).Name())
+			return errors.Errorf("when a handler has %d arguments, handler must take context.Context as the first argument, got %s", handlerType.NumIn(), handlerType.In(0).Name())
+		}
+	}
+	if handlerType.NumIn() == 3 {
+		// Check that the second argument is a RequestHandlerExtra
+		if handlerType.In(1) != reflect.TypeOf(RequestHandlerExtra{}) {
+			return errors.Errorf("when a handler has 3 arguments, handler must take RequestHandlerExtra as the second argument, got %s", handlerType.In(1).Name())
 		}
 	}
 
-	// Check that the output type is *tools.ToolResponse
-	if handlerType.Out(0) != reflect.PointerTo(reflect.TypeOf(ToolResponse{})) {
-		return errors.Errorf("handler must return *tools.ToolResponse, got %s", handlerType.Out(0).Name())
+	// Check that the output type is *tools.ToolResponse, or the pointer to the struct of the structured output
+	if handlerType.Out(0) != toolResponseType && !isStructuredOutput(handlerType.Out(0)) {
+		return errors.Errorf("handler must return *tools.ToolResponse or pointer to struct, got %s", handlerType.Out(0).Name())
 	}
 
 	// Check that the output type is error
@@ -1071,5 +1213,6 @@ var (
 		KeyNamer:                   nil,
 		AdditionalFields:           nil,
 		CommentMap:                 nil,
+		LookupComment:              schema.LookupComment,
 	}
 )
@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/effective-security/gogentic/mcp/internal/protocol"
@@ -555,3 +556,74 @@ func TestHandleListResourceTemplatesPagination(t *testing.T) {
 	assert.Len(t, templatesResp.Templates, 5, "Expected 5 templates without pagination")
 	assert.Nil(t, templatesResp.NextCursor, "Expected no next cursor when pagination is disabled")
 }
+
+func TestStructuredToolOutput(t *testing.T) {
+	server := NewServer(testingutils.NewMockTransport())
+	require.NoError(t, server.Serve())
+
+	type args struct {
+		City string `json:"city" jsonschema:"required"`
+	}
+	type weather struct {
+		City        string  `json:"city" jsonschema:"required"`
+		Temperature float64 `json:"temperature" jsonschema:"required"`
+	}
+
+	err := server.RegisterTool("weather", "Weather tool", func(ctx context.Context, args args) (*weather, error) {
+		if args.City == "" {
+			return nil, nil
+		}
+		return &weather{City: args.City, Temperature: 21.5}, nil
+	})
+	require.NoError(t, err)
+	err = server.RegisterTool("text", "Text tool", func(args args) (*ToolResponse, error) {
+		return NewToolResponse(NewTextContent(args.City)), nil
+	})
+	require.NoError(t, err)
+
+	resp, err := server.handleListTools(context.Background(), &transport.BaseJSONRPCRequest{}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	list := resp.(ToolsResponse)
+	require.Len(t, list.Tools, 2)
+	assert.Equal(t, "text", list.Tools[0].Name)
+	assert.Nil(t, list.Tools[0].OutputSchema)
+	assert.Equal(t, "weather", list.Tools[1].Name)
+	require.NotNil(t, list.Tools[1].OutputSchema)
+
+	js, err := json.Marshal(list.Tools[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(js), "outputSchema")
+	js, err = json.Marshal(list.Tools[1].OutputSchema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"city": {"type": "string"},
+			"temperature": {"type": "number"}
+		},
+		"required": ["city", "temperature"]
+	}`, string(js))
+
+	resp, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"weather","arguments":{"city":"Seattle"}}`),
+	}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	js, err = json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"content": [{"type":"text","text":"{\"city\":\"Seattle\",\"temperature\":21.5}"}],
+		"structuredContent": {"city":"Seattle","temperature":21.5}
+	}`, string(js))
+
+	resp, err = server.handleToolCalls(context.Background(), &transport.BaseJSONRPCRequest{
+		Params: []byte(`{"name":"weather","arguments":{}}`),
+	}, protocol.RequestHandlerExtra{})
+	require.NoError(t, err)
+	assert.EqualError(t, resp.(*toolResponseSent).Error, "handler returned nil result")
+
+	err = server.RegisterTool("invalid", "Invalid tool", func(args args) (string, error) {
+		return "", nil
+	})
+	assert.EqualError(t, err, "handler must return *tools.ToolResponse or pointer to struct, got string")
+}
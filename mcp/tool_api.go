@@ -1,5 +1,11 @@
 package mcp
 
+import (
+	"encoding/json"
+
+	"github.com/cockroachdb/errors"
+)
+
 // This is a union type of all the different ToolResponse that can be sent back to the client.
 // We allow creation through constructors only to make sure that the ToolResponse is valid.
 type ToolResponse struct {
@@ -18,6 +24,16 @@ func NewToolResponse(content ...*Content) *ToolResponse {
 	}
 }
 
+// NewStructuredToolResponse returns the ToolResponse with the structured content,
+// and its JSON serialized in the text content for the clients without the structured output support.
+func NewStructuredToolResponse(v any) (*ToolResponse, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal structured content")
+	}
+	return NewToolResponse(NewTextContent(string(data))).WithStructuredContent(v), nil
+}
+
 func (t *ToolResponse) WithStructuredContent(resp any) *ToolResponse {
 	t.StructuredContent = resp
 	return t
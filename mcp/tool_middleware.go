@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/effective-security/gogentic/mcp/internal/protocol"
+	"github.com/effective-security/gogentic/mcp/transport"
+)
+
+// RequestHandlerExtra is the extra information of the request,
+// passed to the tool handlers with the signature:
+//
+//	func(ctx context.Context, extra RequestHandlerExtra, args Args) (*ToolResponse, error)
+type RequestHandlerExtra = protocol.RequestHandlerExtra
+
+// ToolCall describes the call of the tool
+type ToolCall struct {
+	// Name is the name of the tool
+	Name string
+	// RequestID is the ID of the JSON-RPC request
+	RequestID transport.RequestId
+	// Arguments are the JSON arguments of the call, before they are unmarshaled to the handler arguments
+	Arguments json.RawMessage
+	// Extra is the extra information of the request
+	Extra RequestHandlerExtra
+}
+
+// ToolHandlerFunc handles the tool call
+type ToolHandlerFunc func(ctx context.Context, call *ToolCall) (*ToolResponse, error)
+
+// ToolMiddleware wraps the tool handler, for example to authorize, rate limit or log the calls.
+// The error returned by the middleware is sent to the client as the tool error.
+type ToolMiddleware func(next ToolHandlerFunc) ToolHandlerFunc
+
+// WithToolMiddleware is an option to apply the middleware to all tools,
+// before the middleware of the tool, see RegisterToolWithMiddleware.
+// The first middleware is the outermost.
+func WithToolMiddleware(middleware ...ToolMiddleware) ServerOptions {
+	return func(s *Server) {
+		s.toolMiddleware = append(s.toolMiddleware, middleware...)
+	}
+}
+
+// chainToolMiddleware wraps the handler with the middleware, the first middleware is the outermost
+func chainToolMiddleware(handler ToolHandlerFunc, middleware []ToolMiddleware) ToolHandlerFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/mcp/internal/testingutils"
+	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey string
+
+func TestToolMiddleware(t *testing.T) {
+	type args struct {
+		Message string `json:"message" jsonschema:"required"`
+	}
+
+	var calls []string
+	record := func(name string) ToolMiddleware {
+		return func(next ToolHandlerFunc) ToolHandlerFunc {
+			return func(ctx context.Context, call *ToolCall) (*ToolResponse, error) {
+				calls = append(calls, name+":"+call.Name)
+				return next(ctx, call)
+			}
+		}
+	}
+	deny := func(next ToolHandlerFunc) ToolHandlerFunc {
+		return func(ctx context.Context, call *ToolCall) (*ToolResponse, error) {
+			return nil, errors.New("access denied")
+		}
+	}
+
+	server := NewServer(testingutils.NewMockTransport(), WithToolMiddleware(record("server")))
+	require.NoError(t, server.Serve())
+
+	err := server.RegisterToolWithMiddleware("echo", "Echo tool",
+		func(ctx context.Context, extra RequestHandlerExtra, args args) (*ToolResponse, error) {
+			assert.Equal(t, "value", ctx.Value(ctxKey("key")))
+			assert.NotNil(t, extra.Context)
+			return NewToolResponse(NewTextContent(args.Message)), nil
+		},
+		record("tool1"), record("tool2"))
+	require.NoError(t, err)
+
+	err = server.RegisterToolWithMiddleware("denied", "Denied tool", func(args args) (*ToolResponse, error) {
+		require.Fail(t, "should not be called")
+		return nil, nil
+	}, deny)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), ctxKey("key"), "value")
+	extra := RequestHandlerExtra{Context: ctx}
+
+	resp, err := server.handleToolCalls(ctx, &transport.BaseJSONRPCRequest{
+		Id:     1,
+		Params: []byte(`{"name":"echo","arguments":{"message":"hello"}}`),
+	}, extra)
+	require.NoError(t, err)
+	toolResp := resp.(*toolResponseSent)
+	require.NoError(t, toolResp.Error)
+	assert.Equal(t, "hello", toolResp.Response.Content[0].TextContent.Text)
+	assert.Equal(t, []string{"server:echo", "tool1:echo", "tool2:echo"}, calls)
+
+	calls = nil
+	resp, err = server.handleToolCalls(ctx, &transport.BaseJSONRPCRequest{
+		Id:     2,
+		Params: []byte(`{"name":"denied","arguments":{"message":"hello"}}`),
+	}, extra)
+	require.NoError(t, err)
+	toolResp = resp.(*toolResponseSent)
+	assert.EqualError(t, toolResp.Error, "access denied")
+	assert.Equal(t, []string{"server:denied"}, calls)
+}
+
+func TestValidateToolHandler(t *testing.T) {
+	type args struct {
+		Message string `json:"message"`
+	}
+
+	tcases := []struct {
+		name    string
+		handler any
+		err     string
+	}{
+		{name: "args", handler: func(args) (*ToolResponse, error) { return nil, nil }},
+		{name: "ctx", handler: func(context.Context, args) (*ToolResponse, error) { return nil, nil }},
+		{name: "extra", handler: func(context.Context, RequestHandlerExtra, args) (*ToolResponse, error) { return nil, nil }},
+		{
+			name:    "no args",
+			handler: func() (*ToolResponse, error) { return nil, nil },
+			err:     "handler must take one, two or three arguments, got 0",
+		},
+		{
+			name:    "no ctx",
+			handler: func(string, RequestHandlerExtra, args) (*ToolResponse, error) { return nil, nil },
+			err:     "when a handler has 3 arguments, handler must take context.Context as the first argument, got string",
+		},
+		{
+			name:    "no extra",
+			handler: func(context.Context, string, args) (*ToolResponse, error) { return nil, nil },
+			err:     "when a handler has 3 arguments, handler must take RequestHandlerExtra as the second argument, got string",
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateToolHandler(tc.handler)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
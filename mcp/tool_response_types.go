@@ -14,7 +14,7 @@ type ServerCapabilities struct {
 	Experimental ServerCapabilitiesExperimental `json:"experimental,omitempty" yaml:"experimental,omitempty" mapstructure:"experimental,omitempty"`
 
 	// Present if the server supports sending log messages to the client.
-	Logging ServerCapabilitiesLogging `json:"logging,omitempty" yaml:"logging,omitempty" mapstructure:"logging,omitempty"`
+	Logging *ServerCapabilitiesLogging `json:"logging,omitempty" yaml:"logging,omitempty" mapstructure:"logging,omitempty"`
 
 	// Present if the server offers any prompt templates.
 	Prompts *ServerCapabilitiesPrompts `json:"prompts,omitempty" yaml:"prompts,omitempty" mapstructure:"prompts,omitempty"`
@@ -144,6 +144,9 @@ type baseCallToolRequestParams struct {
 
 	// Name corresponds to the JSON schema field "name".
 	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// Meta is the metadata of the request, see ChatContextMetaKey.
+	Meta requestMeta `json:"_meta,omitempty" yaml:"_meta,omitempty" mapstructure:"_meta,omitempty"`
 }
 
 // Definition for a tool the client can call.
@@ -156,6 +159,10 @@ type ToolRetType struct {
 
 	// The name of the tool.
 	Name string `json:"name" yaml:"name" mapstructure:"name"`
+
+	// An optional JSON Schema object defining the structure of the tool's output
+	// returned in the structuredContent field of the result.
+	OutputSchema any `json:"outputSchema,omitempty" yaml:"outputSchema,omitempty" mapstructure:"outputSchema,omitempty"`
 }
 type ToolsResponse struct {
 	Tools      []ToolRetType `json:"tools" yaml:"tools" mapstructure:"tools"`
@@ -0,0 +1,182 @@
+package grpctransport
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/mcp/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ClientTransport implements the client side of the session over the gRPC stream.
+//
+// The credentials and the load balancing are configured on the connection,
+// for example with grpc.WithTransportCredentials for mTLS,
+// the session is bound to the server that accepted the stream.
+type ClientTransport struct {
+	conn     grpc.ClientConnInterface
+	callOpts []grpc.CallOption
+
+	mu        sync.Mutex
+	stream    grpc.ClientStream
+	cancel    context.CancelFunc
+	closed    bool
+	onClose   func()
+	onError   func(error)
+	onMessage func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+
+	sendLock sync.Mutex
+}
+
+var _ transport.Transport = (*ClientTransport)(nil)
+
+// NewClientTransport returns a new ClientTransport over the connection,
+// the call options are applied to the stream.
+func NewClientTransport(conn grpc.ClientConnInterface, opts ...grpc.CallOption) *ClientTransport {
+	return &ClientTransport{
+		conn:     conn,
+		callOpts: opts,
+	}
+}
+
+// Start opens the stream, the stream is closed when the ctx is cancelled
+// or its deadline is exceeded.
+func (t *ClientTransport) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return errors.New("transport is closed")
+	}
+	if t.stream != nil {
+		return errors.New("transport already started")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := t.conn.NewStream(ctx, &ServiceDesc.Streams[0], StreamMethod, t.callOpts...)
+	if err != nil {
+		cancel()
+		return errors.Wrap(err, "failed to open stream")
+	}
+	t.stream = stream
+	t.cancel = cancel
+
+	go t.recvLoop(ctx, stream)
+	return nil
+}
+
+// Send sends a JSON-RPC message
+func (t *ClientTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	msg, err := encodeMessage(message)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return errors.Wrap(err, "send cancelled")
+	}
+
+	t.mu.Lock()
+	stream, closed := t.stream, t.closed
+	t.mu.Unlock()
+	if closed {
+		return errors.New("transport is closed")
+	}
+	if stream == nil {
+		return errors.New("transport is not started")
+	}
+
+	// SendMsg is not safe to call from the concurrent goroutines
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+	if err = stream.SendMsg(msg); err != nil {
+		return errors.Wrap(err, "failed to send message")
+	}
+	return nil
+}
+
+// Close closes the stream and calls the close handler
+func (t *ClientTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	cancel := t.cancel
+	handler := t.onClose
+	t.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if handler != nil {
+		handler()
+	}
+	return nil
+}
+
+// SetCloseHandler sets the handler for close events
+func (t *ClientTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onClose = handler
+}
+
+// SetErrorHandler sets the handler for error events
+func (t *ClientTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onError = handler
+}
+
+// SetMessageHandler sets the handler for incoming messages
+func (t *ClientTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onMessage = handler
+}
+
+func (t *ClientTransport) recvLoop(ctx context.Context, stream grpc.ClientStream) {
+	defer func() {
+		_ = t.Close()
+	}()
+
+	for {
+		msg := new(wrapperspb.BytesValue)
+		if err := stream.RecvMsg(msg); err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				t.handleError(errors.Wrap(err, "receive error"))
+			}
+			return
+		}
+		t.handleMessage(ctx, msg.GetValue())
+	}
+}
+
+func (t *ClientTransport) handleError(err error) {
+	t.mu.Lock()
+	handler := t.onError
+	t.mu.Unlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
+func (t *ClientTransport) handleMessage(ctx context.Context, data []byte) {
+	message, err := decodeMessage(data)
+	if err != nil {
+		t.handleError(err)
+		return
+	}
+
+	t.mu.Lock()
+	handler := t.onMessage
+	t.mu.Unlock()
+
+	if handler != nil {
+		handler(ctx, message)
+	}
+}
@@ -0,0 +1,6 @@
+// Package grpctransport implements gRPC transport for MCP, enabling agent-to-agent communication inside a cluster
+// with mTLS, deadlines and load balancing provided by gRPC.
+//
+// Each session is a bidirectional stream of the gogentic.mcp.Transport/Stream method,
+// the messages are the JSON-RPC envelopes wrapped in google.protobuf.BytesValue.
+package grpctransport
@@ -0,0 +1,257 @@
+package grpctransport
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/effective-security/xlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic/mcp/transport", "grpctransport")
+
+// SessionFunc is called to serve the new session, for example:
+//
+//	func(t transport.Transport) error {
+//		server := mcp.NewServer(t)
+//		// register the tools
+//		return server.Serve()
+//	}
+//
+// The transport is started by the server.
+type SessionFunc func(t transport.Transport) error
+
+// Server serves the MCP sessions over the gRPC streams, one session per stream.
+//
+// The credentials, the interceptors and the keepalive are configured on the grpc.Server,
+// for example with grpc.Creds for mTLS.
+// The context of the messages is the context of the stream,
+// with the deadline of the client and the peer, see peer.FromContext.
+type Server struct {
+	onSession SessionFunc
+
+	lock     sync.RWMutex
+	sessions map[*ServerTransport]struct{}
+}
+
+var _ TransportServer = (*Server)(nil)
+
+// NewServer returns a new Server
+func NewServer(onSession SessionFunc) *Server {
+	return &Server{
+		onSession: onSession,
+		sessions:  map[*ServerTransport]struct{}{},
+	}
+}
+
+// Register registers the transport service
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	r.RegisterService(&ServiceDesc, s)
+}
+
+// Sessions returns the number of the open sessions
+func (s *Server) Sessions() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.sessions)
+}
+
+// Close closes all sessions
+func (s *Server) Close() error {
+	s.lock.RLock()
+	sessions := make([]*ServerTransport, 0, len(s.sessions))
+	for t := range s.sessions {
+		sessions = append(sessions, t)
+	}
+	s.lock.RUnlock()
+
+	for _, t := range sessions {
+		_ = t.Close()
+	}
+	return nil
+}
+
+// Stream implements TransportServer
+func (s *Server) Stream(stream grpc.ServerStream) error {
+	ctx := stream.Context()
+	t := newServerTransport(stream)
+
+	s.lock.Lock()
+	s.sessions[t] = struct{}{}
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		delete(s.sessions, t)
+		s.lock.Unlock()
+	}()
+
+	if err := s.onSession(t); err != nil {
+		logger.ContextKV(ctx, xlog.ERROR, "reason", "serve", "err", err.Error())
+		_ = t.Close()
+		return status.Error(codes.Internal, "failed to start session")
+	}
+
+	select {
+	case <-ctx.Done():
+		_ = t.Close()
+		return status.FromContextError(ctx.Err()).Err()
+	case <-t.done:
+		return nil
+	}
+}
+
+// ServerTransport implements the server side of the session over the gRPC stream
+type ServerTransport struct {
+	stream grpc.ServerStream
+
+	mu        sync.Mutex
+	started   bool
+	closed    bool
+	onClose   func()
+	onError   func(error)
+	onMessage func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+
+	sendLock sync.Mutex
+	done     chan struct{}
+}
+
+var _ transport.Transport = (*ServerTransport)(nil)
+
+func newServerTransport(stream grpc.ServerStream) *ServerTransport {
+	return &ServerTransport{
+		stream: stream,
+		done:   make(chan struct{}),
+	}
+}
+
+// Start begins receiving the messages from the stream,
+// the ctx is ignored, as the session is bound to the stream.
+func (t *ServerTransport) Start(_ context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return errors.New("transport is closed")
+	}
+	if t.started {
+		return errors.New("transport already started")
+	}
+	t.started = true
+
+	go t.recvLoop()
+	return nil
+}
+
+// Send sends a JSON-RPC message
+func (t *ServerTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	msg, err := encodeMessage(message)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return errors.Wrap(err, "send cancelled")
+	}
+
+	t.mu.Lock()
+	closed := t.closed
+	t.mu.Unlock()
+	if closed {
+		return errors.New("transport is closed")
+	}
+
+	// SendMsg is not safe to call from the concurrent goroutines
+	t.sendLock.Lock()
+	defer t.sendLock.Unlock()
+	if err = t.stream.SendMsg(msg); err != nil {
+		return errors.Wrap(err, "failed to send message")
+	}
+	return nil
+}
+
+// Close ends the stream and calls the close handler
+func (t *ServerTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	handler := t.onClose
+	t.mu.Unlock()
+
+	close(t.done)
+	if handler != nil {
+		handler()
+	}
+	return nil
+}
+
+// SetCloseHandler sets the handler for close events
+func (t *ServerTransport) SetCloseHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onClose = handler
+}
+
+// SetErrorHandler sets the handler for error events
+func (t *ServerTransport) SetErrorHandler(handler func(error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onError = handler
+}
+
+// SetMessageHandler sets the handler for incoming messages
+func (t *ServerTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onMessage = handler
+}
+
+func (t *ServerTransport) recvLoop() {
+	defer func() {
+		_ = t.Close()
+	}()
+
+	ctx := t.stream.Context()
+	for {
+		msg := new(wrapperspb.BytesValue)
+		if err := t.stream.RecvMsg(msg); err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				t.handleError(errors.Wrap(err, "receive error"))
+			}
+			return
+		}
+		t.handleMessage(ctx, msg.GetValue())
+	}
+}
+
+func (t *ServerTransport) handleError(err error) {
+	t.mu.Lock()
+	handler := t.onError
+	t.mu.Unlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
+func (t *ServerTransport) handleMessage(ctx context.Context, data []byte) {
+	message, err := decodeMessage(data)
+	if err != nil {
+		t.handleError(err)
+		return
+	}
+
+	t.mu.Lock()
+	handler := t.onMessage
+	t.mu.Unlock()
+
+	if handler != nil {
+		handler(ctx, message)
+	}
+}
@@ -0,0 +1,86 @@
+package grpctransport
+
+import (
+	"encoding/json"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/mcp/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// ServiceName is the name of the gRPC service
+	ServiceName = "gogentic.mcp.Transport"
+	// StreamMethod is the full name of the bidirectional stream method
+	StreamMethod = "/" + ServiceName + "/Stream"
+)
+
+// TransportServer is the server API of the gRPC service
+type TransportServer interface {
+	// Stream serves the session over the bidirectional stream
+	Stream(stream grpc.ServerStream) error
+}
+
+// ServiceDesc is the grpc.ServiceDesc of the transport service,
+// the messages are google.protobuf.BytesValue with the JSON-RPC envelope.
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*TransportServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Stream",
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				return srv.(TransportServer).Stream(stream)
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
+
+func encodeMessage(message *transport.BaseJsonRpcMessage) (*wrapperspb.BytesValue, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal message")
+	}
+	return wrapperspb.Bytes(data), nil
+}
+
+func decodeMessage(data []byte) (*transport.BaseJsonRpcMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal message")
+	}
+
+	_, hasMethod := fields["method"]
+	_, hasID := fields["id"]
+	_, hasError := fields["error"]
+
+	switch {
+	case hasMethod && hasID:
+		var req transport.BaseJSONRPCRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal request")
+		}
+		return transport.NewBaseMessageRequest(&req), nil
+	case hasMethod:
+		var notification transport.BaseJSONRPCNotification
+		if err := json.Unmarshal(data, &notification); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal notification")
+		}
+		return transport.NewBaseMessageNotification(&notification), nil
+	case hasError:
+		var rpcErr transport.BaseJSONRPCError
+		if err := json.Unmarshal(data, &rpcErr); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal error")
+		}
+		return transport.NewBaseMessageError(&rpcErr), nil
+	default:
+		var resp transport.BaseJSONRPCResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal response")
+		}
+		return transport.NewBaseMessageResponse(&resp), nil
+	}
+}
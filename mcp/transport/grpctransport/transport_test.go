@@ -0,0 +1,204 @@
+package grpctransport_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/effective-security/gogentic/mcp/transport/grpctransport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// echoSession responds to the requests with the method name
+func echoSession(t transport.Transport) error {
+	t.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			return
+		}
+		_ = t.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Id:      message.JsonRpcRequest.Id,
+			Jsonrpc: "2.0",
+			Result:  []byte(`"` + message.JsonRpcRequest.Method + `"`),
+		}))
+	})
+	return t.Start(context.Background())
+}
+
+func startServer(t *testing.T, onSession grpctransport.SessionFunc) (*grpctransport.Server, *grpc.ClientConn) {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	gs := grpc.NewServer()
+	srv := grpctransport.NewServer(onSession)
+	srv.Register(gs)
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return srv, conn
+}
+
+func startClient(t *testing.T, conn *grpc.ClientConn) (*grpctransport.ClientTransport, chan *transport.BaseJsonRpcMessage) {
+	t.Helper()
+
+	received := make(chan *transport.BaseJsonRpcMessage, 10)
+	client := grpctransport.NewClientTransport(conn)
+	client.SetMessageHandler(func(_ context.Context, message *transport.BaseJsonRpcMessage) {
+		received <- message
+	})
+	require.NoError(t, client.Start(context.Background()))
+	return client, received
+}
+
+func request(id int, method string) *transport.BaseJsonRpcMessage {
+	return transport.NewBaseMessageRequest(&transport.BaseJSONRPCRequest{
+		Id:      transport.RequestId(id),
+		Jsonrpc: "2.0",
+		Method:  method,
+	})
+}
+
+func receive(t *testing.T, ch chan *transport.BaseJsonRpcMessage) *transport.BaseJsonRpcMessage {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout waiting for message")
+		return nil
+	}
+}
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	srv, conn := startServer(t, echoSession)
+
+	client1, received1 := startClient(t, conn)
+	client2, received2 := startClient(t, conn)
+	assert.Eventually(t, func() bool {
+		return srv.Sessions() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	ctx := context.Background()
+	require.NoError(t, client1.Send(ctx, request(1, "first")))
+	require.NoError(t, client2.Send(ctx, request(2, "second")))
+
+	msg := receive(t, received1)
+	require.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, msg.Type)
+	assert.Equal(t, transport.RequestId(1), msg.JsonRpcResponse.Id)
+	assert.JSONEq(t, `"first"`, string(msg.JsonRpcResponse.Result))
+
+	msg = receive(t, received2)
+	require.Equal(t, transport.BaseMessageTypeJSONRPCResponseType, msg.Type)
+	assert.Equal(t, transport.RequestId(2), msg.JsonRpcResponse.Id)
+	assert.JSONEq(t, `"second"`, string(msg.JsonRpcResponse.Result))
+
+	// the client closes the session
+	require.NoError(t, client1.Close())
+	assert.Eventually(t, func() bool {
+		return srv.Sessions() == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Error(t, client1.Send(ctx, request(3, "closed")))
+
+	// the server closes the session
+	closed := make(chan struct{})
+	client2.SetCloseHandler(func() { close(closed) })
+	require.NoError(t, srv.Close())
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout waiting for close")
+	}
+	assert.Equal(t, 0, srv.Sessions())
+}
+
+func TestTransport_Messages(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan *transport.BaseJsonRpcMessage, 10)
+	_, conn := startServer(t, func(t transport.Transport) error {
+		t.SetMessageHandler(func(_ context.Context, message *transport.BaseJsonRpcMessage) {
+			received <- message
+		})
+		return t.Start(context.Background())
+	})
+	client, _ := startClient(t, conn)
+	defer client.Close()
+
+	ctx := context.Background()
+	tcases := []struct {
+		name string
+		msg  *transport.BaseJsonRpcMessage
+	}{
+		{"request", request(1, "tools/list")},
+		{"notification", transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+			Jsonrpc: "2.0",
+			Method:  "notifications/initialized",
+		})},
+		{"response", transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Id:      2,
+			Jsonrpc: "2.0",
+			Result:  []byte(`{}`),
+		})},
+		{"error", transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+			Id:      3,
+			Jsonrpc: "2.0",
+			Error:   transport.BaseJSONRPCErrorInner{Code: -32601, Message: "method not found"},
+		})},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, client.Send(ctx, tc.msg))
+			msg := receive(t, received)
+			assert.Equal(t, tc.msg.Type, msg.Type)
+		})
+	}
+}
+
+func TestTransport_SessionError(t *testing.T) {
+	t.Parallel()
+
+	srv, conn := startServer(t, func(transport.Transport) error {
+		return assert.AnError
+	})
+
+	client := grpctransport.NewClientTransport(conn)
+	errs := make(chan error, 1)
+	client.SetErrorHandler(func(err error) { errs <- err })
+	require.NoError(t, client.Start(context.Background()))
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "failed to start session")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout waiting for error")
+	}
+	assert.Equal(t, 0, srv.Sessions())
+}
+
+func TestClientTransport_NotStarted(t *testing.T) {
+	t.Parallel()
+
+	_, conn := startServer(t, echoSession)
+	client := grpctransport.NewClientTransport(conn)
+	assert.EqualError(t, client.Send(context.Background(), request(1, "ping")), "transport is not started")
+
+	require.NoError(t, client.Close())
+	assert.EqualError(t, client.Start(context.Background()), "transport is closed")
+}
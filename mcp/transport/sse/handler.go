@@ -0,0 +1,327 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/effective-security/xlog"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic/mcp/transport", "sse")
+
+const (
+	// SessionQueryParam is the query parameter with the session ID,
+	// sent to the client in the endpoint event.
+	SessionQueryParam = "session"
+	// SessionIDHeader is the header with the session ID, an alternative to the query parameter.
+	SessionIDHeader = "Mcp-Session-Id"
+
+	// DefaultIdleTimeout is the default timeout of the session without the messages.
+	DefaultIdleTimeout = 30 * time.Minute
+)
+
+// SessionFunc is called to serve the new session, for example:
+//
+//	func(t transport.Transport) error {
+//		server := mcp.NewServer(t)
+//		// register the tools
+//		return server.Serve()
+//	}
+//
+// The transport is started by the server.
+type SessionFunc func(t transport.Transport) error
+
+// HandlerOption configures the Handler
+type HandlerOption func(*Handler)
+
+// WithIdleTimeout sets the timeout after which the session without the messages is closed,
+// zero disables the eviction.
+func WithIdleTimeout(timeout time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.idleTimeout = timeout
+	}
+}
+
+// WithTransportOptions sets the options of the SSE transports of the sessions.
+func WithTransportOptions(opts ...Option) HandlerOption {
+	return func(h *Handler) {
+		h.transportOptions = opts
+	}
+}
+
+// Handler is the http.Handler that serves many concurrent MCP sessions over SSE.
+//
+// The GET request opens the SSE stream of a new session, or resumes the stream of the session
+// with the Last-Event-ID, when the session ID is provided.
+// The POST request delivers the message to the session, identified by the session query parameter
+// of the endpoint, or by the Mcp-Session-Id header.
+// The sessions without the messages are closed after the idle timeout.
+type Handler struct {
+	endpoint         string
+	onSession        SessionFunc
+	idleTimeout      time.Duration
+	transportOptions []Option
+
+	lock     sync.RWMutex
+	sessions map[string]*session
+}
+
+// NewHandler returns a new Handler, the endpoint is the URL of the POST requests
+// sent to the clients, usually the path of the handler.
+func NewHandler(endpoint string, onSession SessionFunc, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		endpoint:    endpoint,
+		onSession:   onSession,
+		idleTimeout: DefaultIdleTimeout,
+		sessions:    map[string]*session{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.handleStream(w, r)
+	case http.MethodPost:
+		h.handlePost(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Sessions returns the number of the open sessions
+func (h *Handler) Sessions() int {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return len(h.sessions)
+}
+
+// Close closes all sessions
+func (h *Handler) Close() error {
+	h.lock.RLock()
+	sessions := make([]*session, 0, len(h.sessions))
+	for _, s := range h.sessions {
+		sessions = append(sessions, s)
+	}
+	h.lock.RUnlock()
+
+	var errs []error
+	for _, s := range sessions {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *Handler) handleStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if id := sessionID(r); id != "" {
+		s := h.get(id)
+		if s == nil {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		if err := s.sse.Resume(r, w); err != nil {
+			logger.ContextKV(ctx, xlog.WARNING, "session", id, "reason", "resume", "err", err.Error())
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrReplayUnavailable) {
+				status = http.StatusGone
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		s.touch()
+		s.wait(ctx)
+		return
+	}
+
+	tr, err := NewSSEServerTransport(h.endpoint, w, h.transportOptions...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s := h.newSession(ctx, tr)
+	w.Header().Set(SessionIDHeader, s.id)
+
+	if err := h.onSession(s); err != nil {
+		logger.ContextKV(ctx, xlog.ERROR, "session", s.id, "reason", "serve", "err", err.Error())
+		if s.started {
+			_ = s.Close()
+			return
+		}
+		h.remove(s.id)
+		s.closed()
+		http.Error(w, "failed to start session", http.StatusInternalServerError)
+		return
+	}
+	s.wait(ctx)
+}
+
+func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
+	id := sessionID(r)
+	if id == "" {
+		http.Error(w, "session is required", http.StatusBadRequest)
+		return
+	}
+	s := h.get(id)
+	if s == nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	s.touch()
+	if err := s.sse.HandlePostMessage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handler) newSession(ctx context.Context, tr *SSEServerTransport) *session {
+	sctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s := &session{
+		id:     tr.SessionID(),
+		sse:    tr,
+		ctx:    sctx,
+		cancel: cancel,
+		stream: ctx,
+		done:   make(chan struct{}),
+	}
+	if h.idleTimeout > 0 {
+		s.idleTimeout = h.idleTimeout
+		s.idle = time.AfterFunc(h.idleTimeout, func() {
+			logger.KV(xlog.DEBUG, "session", s.id, "reason", "idle_timeout")
+			_ = s.Close()
+		})
+	}
+	tr.SetCloseHandler(func() {
+		h.remove(s.id)
+		s.closed()
+	})
+
+	h.lock.Lock()
+	h.sessions[s.id] = s
+	h.lock.Unlock()
+	return s
+}
+
+func (h *Handler) get(id string) *session {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.sessions[id]
+}
+
+func (h *Handler) remove(id string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.sessions, id)
+}
+
+func sessionID(r *http.Request) string {
+	if id := r.URL.Query().Get(SessionQueryParam); id != "" {
+		return id
+	}
+	return r.Header.Get(SessionIDHeader)
+}
+
+// session adapts SSEServerTransport to transport.Transport
+type session struct {
+	id  string
+	sse *SSEServerTransport
+	// ctx is cancelled when the session is closed, it is passed to the message handler,
+	// as the messages are processed after the POST request is accepted
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stream is the context of the GET request that opened the session
+	stream  context.Context
+	started bool
+
+	idle        *time.Timer
+	idleTimeout time.Duration
+
+	lock         sync.Mutex
+	closeHandler func()
+	done         chan struct{}
+}
+
+var _ transport.Transport = (*session)(nil)
+
+// Start starts the SSE stream of the GET request that opened the session,
+// the ctx is ignored, as the server starts the transport with the background context.
+func (s *session) Start(_ context.Context) error {
+	if err := s.sse.Start(s.stream); err != nil {
+		return err
+	}
+	s.started = true
+	return nil
+}
+
+// Send implements Transport.Send
+func (s *session) Send(_ context.Context, message *transport.BaseJsonRpcMessage) error {
+	s.touch()
+	return s.sse.Send(message)
+}
+
+// Close implements Transport.Close
+func (s *session) Close() error {
+	return s.sse.Close()
+}
+
+// SetCloseHandler implements Transport.SetCloseHandler
+func (s *session) SetCloseHandler(handler func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closeHandler = handler
+}
+
+// SetErrorHandler implements Transport.SetErrorHandler
+func (s *session) SetErrorHandler(handler func(error)) {
+	s.sse.SetErrorHandler(handler)
+}
+
+// SetMessageHandler implements Transport.SetMessageHandler
+func (s *session) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	s.sse.SetMessageHandler(func(message *transport.BaseJsonRpcMessage) {
+		handler(s.ctx, message)
+	})
+}
+
+func (s *session) touch() {
+	if s.idle != nil {
+		s.idle.Reset(s.idleTimeout)
+	}
+}
+
+// wait blocks until the stream or the session is closed
+func (s *session) wait(stream context.Context) {
+	select {
+	case <-stream.Done():
+	case <-s.done:
+	}
+}
+
+// closed is called once by the SSE transport, when the session is closed
+func (s *session) closed() {
+	if s.idle != nil {
+		s.idle.Stop()
+	}
+	s.cancel()
+	close(s.done)
+
+	s.lock.Lock()
+	handler := s.closeHandler
+	s.lock.Unlock()
+	if handler != nil {
+		handler()
+	}
+}
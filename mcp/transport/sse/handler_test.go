@@ -0,0 +1,178 @@
+package sse_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/mcp/transport"
+	"github.com/effective-security/gogentic/mcp/transport/sse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoSession responds to the requests with the method name
+func echoSession(t transport.Transport) error {
+	t.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type != transport.BaseMessageTypeJSONRPCRequestType {
+			return
+		}
+		_ = t.Send(ctx, transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Id:      message.JsonRpcRequest.Id,
+			Jsonrpc: "2.0",
+			Result:  []byte(`"` + message.JsonRpcRequest.Method + `"`),
+		}))
+	})
+	return t.Start(context.Background())
+}
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if ev.event != "" {
+				return ev
+			}
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func openStream(t *testing.T, url string) (*http.Response, *bufio.Reader, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	r := bufio.NewReader(resp.Body)
+	ev := readEvent(t, r)
+	require.Equal(t, "endpoint", ev.event)
+	return resp, r, ev.data
+}
+
+func post(t *testing.T, url, body string) int {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	h := sse.NewHandler("/mcp", echoSession)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp1, r1, endpoint1 := openStream(t, srv.URL+"/mcp")
+	resp2, r2, endpoint2 := openStream(t, srv.URL+"/mcp")
+	assert.NotEqual(t, endpoint1, endpoint2)
+	assert.NotEmpty(t, resp1.Header.Get(sse.SessionIDHeader))
+	assert.NotEqual(t, resp1.Header.Get(sse.SessionIDHeader), resp2.Header.Get(sse.SessionIDHeader))
+	assert.Equal(t, 2, h.Sessions())
+
+	assert.Equal(t, http.StatusAccepted, post(t, srv.URL+endpoint1, `{"jsonrpc":"2.0","id":1,"method":"first"}`))
+	assert.Equal(t, http.StatusAccepted, post(t, srv.URL+endpoint2, `{"jsonrpc":"2.0","id":2,"method":"second"}`))
+
+	ev := readEvent(t, r1)
+	assert.Equal(t, "message", ev.event)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"first"}`, ev.data)
+
+	ev = readEvent(t, r2)
+	assert.Equal(t, "message", ev.event)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"result":"second"}`, ev.data)
+
+	t.Run("errors", func(t *testing.T) {
+		tcases := []struct {
+			name   string
+			method string
+			url    string
+			status int
+		}{
+			{"no session", http.MethodPost, "/mcp", http.StatusBadRequest},
+			{"unknown session", http.MethodPost, "/mcp?session=unknown", http.StatusNotFound},
+			{"resume unknown session", http.MethodGet, "/mcp?session=unknown", http.StatusNotFound},
+			{"method", http.MethodDelete, "/mcp", http.StatusMethodNotAllowed},
+		}
+		for _, tc := range tcases {
+			t.Run(tc.name, func(t *testing.T) {
+				req, err := http.NewRequest(tc.method, srv.URL+tc.url, strings.NewReader(`{}`))
+				require.NoError(t, err)
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				assert.Equal(t, tc.status, resp.StatusCode)
+			})
+		}
+	})
+
+	t.Run("session header", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":3,"method":"header"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(sse.SessionIDHeader, resp1.Header.Get(sse.SessionIDHeader))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		ev := readEvent(t, r1)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":3,"result":"header"}`, ev.data)
+	})
+
+	require.NoError(t, h.Close())
+	assert.Equal(t, 0, h.Sessions())
+	assert.Equal(t, http.StatusNotFound, post(t, srv.URL+endpoint1, `{"jsonrpc":"2.0","id":4,"method":"closed"}`))
+}
+
+func TestHandler_IdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	h := sse.NewHandler("/mcp", echoSession, sse.WithIdleTimeout(50*time.Millisecond))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	openStream(t, srv.URL+"/mcp")
+	assert.Equal(t, 1, h.Sessions())
+	assert.Eventually(t, func() bool {
+		return h.Sessions() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHandler_SessionError(t *testing.T) {
+	t.Parallel()
+
+	h := sse.NewHandler("/mcp", func(transport.Transport) error {
+		return assert.AnError
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/mcp")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, 0, h.Sessions())
+}
@@ -22,7 +22,12 @@
 	   - Built-in error handling and reporting
 	   - Message size limits for security
 
-	 4. Security Features:
+	 4. Resume:
+	   - Monotonically increasing IDs of the message events
+	   - The last events are buffered per session, see WithReplayBuffer
+	   - The dropped stream is resumed with the Last-Event-ID, see Resume and WithReconnectTimeout
+
+	 5. Security Features:
 	   - Content-type validation
 	   - Message size limits (4MB default)
 	   - Error handling for malformed messages
@@ -62,7 +67,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/mcp/transport"
@@ -71,8 +78,42 @@ import (
 
 const (
 	MaxMessageSize = 4 * 1024 * 1024 // 4MB
+
+	// DefaultReplayBufferSize is the default number of the events buffered for the resume
+	DefaultReplayBufferSize = 100
+	// LastEventIDHeader is the header sent by the client on reconnect
+	LastEventIDHeader = "Last-Event-ID"
 )
 
+// ErrReplayUnavailable is returned by Resume, when the events after the Last-Event-ID
+// are no longer buffered, and the client must start a new session.
+var ErrReplayUnavailable = errors.New("events are no longer available for replay")
+
+// Option configures the SSE transport
+type Option func(*SSETransport)
+
+// WithReplayBuffer sets the number of the last events buffered for the resume,
+// zero disables the buffering.
+func WithReplayBuffer(size int) Option {
+	return func(t *SSETransport) {
+		t.bufferSize = max(size, 0)
+	}
+}
+
+// WithReconnectTimeout sets how long the session waits for the client to reconnect,
+// when the stream is dropped. The messages sent while the client is disconnected are buffered.
+// By default the session is closed when the stream is dropped.
+func WithReconnectTimeout(timeout time.Duration) Option {
+	return func(t *SSETransport) {
+		t.reconnectTimeout = timeout
+	}
+}
+
+type event struct {
+	id   uint64
+	data string
+}
+
 // SSETransport implements a Server-Sent Events transport for JSON-RPC messages
 type SSETransport struct {
 	endpoint    string
@@ -82,6 +123,16 @@ type SSETransport struct {
 	mu          sync.Mutex
 	isConnected bool
 
+	// Resume
+	bufferSize       int
+	reconnectTimeout time.Duration
+	lastEventID      uint64
+	events           []event
+	// stream is incremented on each Start or Resume,
+	// so the drop of the replaced stream is ignored
+	stream         uint64
+	reconnectTimer *time.Timer
+
 	// Callbacks
 	closeHandler   func()
 	errorHandler   func(error)
@@ -89,18 +140,23 @@ type SSETransport struct {
 }
 
 // NewSSETransport creates a new SSE transport with the given endpoint and response writer
-func NewSSETransport(endpoint string, w http.ResponseWriter) (*SSETransport, error) {
+func NewSSETransport(endpoint string, w http.ResponseWriter, opts ...Option) (*SSETransport, error) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		return nil, errors.New("streaming not supported")
 	}
 
-	return &SSETransport{
-		endpoint:  endpoint,
-		sessionID: uuid.New().String(),
-		writer:    w,
-		flusher:   flusher,
-	}, nil
+	t := &SSETransport{
+		endpoint:   endpoint,
+		sessionID:  uuid.New().String(),
+		writer:     w,
+		flusher:    flusher,
+		bufferSize: DefaultReplayBufferSize,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
 }
 
 // Start initializes the SSE connection
@@ -112,28 +168,115 @@ func (t *SSETransport) Start(ctx context.Context) error {
 		return errors.New("SSE transport already started")
 	}
 
-	// Set SSE headers
+	if err := t.startStream(); err != nil {
+		return err
+	}
+
+	t.isConnected = true
+	t.watchStream(ctx)
+	return nil
+}
+
+// Resume attaches the reconnected stream of the client to the session,
+// and replays the buffered events after lastEventID, the value of the Last-Event-ID header.
+// The empty lastEventID replays all buffered events.
+func (t *SSETransport) Resume(ctx context.Context, w http.ResponseWriter, lastEventID string) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New("streaming not supported")
+	}
+
+	var after uint64
+	if lastEventID != "" {
+		var err error
+		after, err = strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return errors.Newf("invalid %s: %q", LastEventIDHeader, lastEventID)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.isConnected {
+		return errors.New("not connected")
+	}
+	if after > t.lastEventID {
+		return errors.Newf("invalid %s: %d is not sent", LastEventIDHeader, after)
+	}
+	// the events after the Last-Event-ID must be buffered
+	if after < t.lastEventID && (len(t.events) == 0 || t.events[0].id > after+1) {
+		return errors.Wrapf(ErrReplayUnavailable, "%s: %d", LastEventIDHeader, after)
+	}
+
+	if t.reconnectTimer != nil {
+		t.reconnectTimer.Stop()
+		t.reconnectTimer = nil
+	}
+	t.writer = w
+	t.flusher = flusher
+	if err := t.startStream(); err != nil {
+		return err
+	}
+	for _, e := range t.events {
+		if e.id > after {
+			if err := t.writeMessage(e); err != nil {
+				return err
+			}
+		}
+	}
+
+	t.watchStream(ctx)
+	return nil
+}
+
+// startStream sets the SSE headers and sends the endpoint event
+func (t *SSETransport) startStream() error {
 	h := t.writer.Header()
 	h.Set("Content-Type", "text/event-stream")
 	h.Set("Cache-Control", "no-cache")
 	h.Set("Connection", "keep-alive")
 	h.Set("Access-Control-Allow-Origin", "*")
 
-	// Send the endpoint event
 	endpointURL := fmt.Sprintf("%s?session=%s", t.endpoint, t.sessionID)
-	if err := t.writeEvent("endpoint", endpointURL); err != nil {
-		return err
-	}
-
-	t.isConnected = true
+	return t.writeEvent("endpoint", endpointURL)
+}
 
-	// Handle context cancellation
+// watchStream handles the context cancellation of the current stream
+func (t *SSETransport) watchStream(ctx context.Context) {
+	t.stream++
+	stream := t.stream
 	go func() {
 		<-ctx.Done()
-		_ = t.Close()
+		t.dropStream(stream)
 	}()
+}
 
-	return nil
+// dropStream closes the session, or waits for the reconnect if WithReconnectTimeout is set
+func (t *SSETransport) dropStream(stream uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if stream != t.stream {
+		// resumed with the new stream
+		return
+	}
+	if t.reconnectTimeout <= 0 {
+		t.close()
+		return
+	}
+	if !t.isConnected {
+		return
+	}
+	t.writer = nil
+	t.flusher = nil
+	t.reconnectTimer = time.AfterFunc(t.reconnectTimeout, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if stream == t.stream {
+			t.close()
+		}
+	})
 }
 
 // HandleMessage processes an incoming message
@@ -202,23 +345,43 @@ func (t *SSETransport) Send(msg *transport.BaseJsonRpcMessage) error {
 		return err
 	}
 
-	return t.writeEvent("message", string(data))
+	t.lastEventID++
+	e := event{id: t.lastEventID, data: string(data)}
+	if t.bufferSize > 0 {
+		if len(t.events) >= t.bufferSize {
+			t.events = append(t.events[:0], t.events[len(t.events)-t.bufferSize+1:]...)
+		}
+		t.events = append(t.events, e)
+	}
+	if t.writer == nil {
+		// the client is reconnecting, the message is replayed on Resume
+		return nil
+	}
+	return t.writeMessage(e)
 }
 
 // Close closes the SSE connection
 func (t *SSETransport) Close() error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.close()
+	return nil
+}
 
+// close closes the session, the caller must hold the lock
+func (t *SSETransport) close() {
 	if !t.isConnected {
-		return nil
+		return
 	}
 
 	t.isConnected = false
+	if t.reconnectTimer != nil {
+		t.reconnectTimer.Stop()
+		t.reconnectTimer = nil
+	}
 	if t.closeHandler != nil {
 		t.closeHandler()
 	}
-	return nil
 }
 
 // SetCloseHandler sets the callback for when the connection is closed
@@ -247,6 +410,15 @@ func (t *SSETransport) SessionID() string {
 	return t.sessionID
 }
 
+// writeMessage writes the message event with the ID
+func (t *SSETransport) writeMessage(e event) error {
+	if _, err := fmt.Fprintf(t.writer, "id: %d\nevent: message\ndata: %s\n\n", e.id, e.data); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
 // writeEvent writes an SSE event with the given event type and data
 func (t *SSETransport) writeEvent(event, data string) error {
 	if _, err := fmt.Fprintf(t.writer, "event: %s\ndata: %s\n\n", event, data); err != nil {
@@ -336,3 +336,112 @@ func TestSSETransport_WriteEvent(t *testing.T) {
 		assert.True(t, w.flushed)
 	})
 }
+
+func newResponse(id int64) *transport.BaseJsonRpcMessage {
+	return &transport.BaseJsonRpcMessage{
+		Type: transport.BaseMessageTypeJSONRPCResponseType,
+		JsonRpcResponse: &transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      transport.RequestId(id),
+			Result:  json.RawMessage(`{}`),
+		},
+	}
+}
+
+func TestSSETransport_Resume(t *testing.T) {
+	t.Run("event ids and replay", func(t *testing.T) {
+		w := newMockResponseWriter()
+		tr, err := sse.NewSSETransport("/messages", w)
+		require.NoError(t, err)
+		require.NoError(t, tr.Start(context.Background()))
+
+		require.NoError(t, tr.Send(newResponse(1)))
+		require.NoError(t, tr.Send(newResponse(2)))
+		assert.Contains(t, w.Body.String(), "id: 1\nevent: message\ndata: ")
+		assert.Contains(t, w.Body.String(), "id: 2\nevent: message\ndata: ")
+
+		w2 := newMockResponseWriter()
+		require.NoError(t, tr.Resume(context.Background(), w2, "1"))
+		body := w2.Body.String()
+		assert.Contains(t, body, "event: endpoint")
+		assert.Contains(t, body, tr.SessionID())
+		assert.NotContains(t, body, "id: 1\n")
+		assert.Contains(t, body, "id: 2\nevent: message\ndata: ")
+
+		// the new messages are sent to the resumed stream
+		require.NoError(t, tr.Send(newResponse(3)))
+		assert.Contains(t, w2.Body.String(), "id: 3\n")
+		assert.NotContains(t, w.Body.String(), "id: 3\n")
+	})
+
+	t.Run("buffered while reconnecting", func(t *testing.T) {
+		w := newMockResponseWriter()
+		tr, err := sse.NewSSETransport("/messages", w, sse.WithReconnectTimeout(time.Minute))
+		require.NoError(t, err)
+		var closed bool
+		tr.SetCloseHandler(func() { closed = true })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, tr.Start(ctx))
+		require.NoError(t, tr.Send(newResponse(1)))
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+
+		require.NoError(t, tr.Send(newResponse(2)))
+		assert.NotContains(t, w.Body.String(), "id: 2\n")
+
+		w2 := newMockResponseWriter()
+		require.NoError(t, tr.Resume(context.Background(), w2, "1"))
+		assert.Contains(t, w2.Body.String(), "id: 2\nevent: message\ndata: ")
+		assert.False(t, closed)
+	})
+
+	t.Run("reconnect timeout", func(t *testing.T) {
+		w := newMockResponseWriter()
+		tr, err := sse.NewSSETransport("/messages", w, sse.WithReconnectTimeout(10*time.Millisecond))
+		require.NoError(t, err)
+		closed := make(chan struct{})
+		tr.SetCloseHandler(func() { close(closed) })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, tr.Start(ctx))
+		cancel()
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("session is not closed")
+		}
+		err = tr.Resume(context.Background(), newMockResponseWriter(), "")
+		assert.EqualError(t, err, "not connected")
+	})
+
+	t.Run("replay unavailable", func(t *testing.T) {
+		w := newMockResponseWriter()
+		tr, err := sse.NewSSETransport("/messages", w, sse.WithReplayBuffer(1))
+		require.NoError(t, err)
+		require.NoError(t, tr.Start(context.Background()))
+		for i := range 3 {
+			require.NoError(t, tr.Send(newResponse(int64(i))))
+		}
+
+		err = tr.Resume(context.Background(), newMockResponseWriter(), "1")
+		assert.ErrorIs(t, err, sse.ErrReplayUnavailable)
+
+		w2 := newMockResponseWriter()
+		require.NoError(t, tr.Resume(context.Background(), w2, "2"))
+		assert.Contains(t, w2.Body.String(), "id: 3\n")
+	})
+
+	t.Run("invalid last event id", func(t *testing.T) {
+		w := newMockResponseWriter()
+		tr, err := sse.NewSSETransport("/messages", w)
+		require.NoError(t, err)
+		require.NoError(t, tr.Start(context.Background()))
+
+		err = tr.Resume(context.Background(), newMockResponseWriter(), "abc")
+		assert.EqualError(t, err, `invalid Last-Event-ID: "abc"`)
+		err = tr.Resume(context.Background(), newMockResponseWriter(), "5")
+		assert.EqualError(t, err, "invalid Last-Event-ID: 5 is not sent")
+	})
+}
@@ -4,20 +4,44 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/mcp/transport"
 	sse2 "github.com/effective-security/gogentic/mcp/transport/sse/internal/sse"
 )
 
+// LastEventIDHeader is the header sent by the client on reconnect
+const LastEventIDHeader = sse2.LastEventIDHeader
+
+// ErrReplayUnavailable is returned by Resume, when the events after the Last-Event-ID
+// are no longer buffered, and the client must start a new session.
+var ErrReplayUnavailable = sse2.ErrReplayUnavailable
+
+// Option configures the SSE server transport
+type Option = sse2.Option
+
+// WithReplayBuffer sets the number of the last message events buffered for the resume,
+// by default 100, zero disables the buffering.
+func WithReplayBuffer(size int) Option {
+	return sse2.WithReplayBuffer(size)
+}
+
+// WithReconnectTimeout sets how long the session waits for the client to reconnect
+// with the Last-Event-ID, when the stream is dropped.
+// By default the session is closed when the stream is dropped.
+func WithReconnectTimeout(timeout time.Duration) Option {
+	return sse2.WithReconnectTimeout(timeout)
+}
+
 // SSEServerTransport implements a server-side SSE transport
 type SSEServerTransport struct {
 	transport *sse2.SSETransport
 }
 
 // NewSSEServerTransport creates a new SSE server transport
-func NewSSEServerTransport(endpoint string, w http.ResponseWriter) (*SSEServerTransport, error) {
-	transport, err := sse2.NewSSETransport(endpoint, w)
+func NewSSEServerTransport(endpoint string, w http.ResponseWriter, opts ...Option) (*SSEServerTransport, error) {
+	transport, err := sse2.NewSSETransport(endpoint, w, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +56,12 @@ func (s *SSEServerTransport) Start(ctx context.Context) error {
 	return s.transport.Start(ctx)
 }
 
+// Resume attaches the reconnected GET request of the session to the transport,
+// and replays the message events after the Last-Event-ID header of the request.
+func (s *SSEServerTransport) Resume(r *http.Request, w http.ResponseWriter) error {
+	return s.transport.Resume(r.Context(), w, r.Header.Get(LastEventIDHeader))
+}
+
 // HandlePostMessage processes an incoming POST request containing a JSON-RPC message
 func (s *SSEServerTransport) HandlePostMessage(r *http.Request) error {
 	if r.Method != http.MethodPost {
@@ -754,3 +754,33 @@ func TestSSEServerTransport_MessageSizeLimit(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestSSEServerTransport_Resume(t *testing.T) {
+	w := httptest.NewRecorder()
+	sseTransport, err := sse.NewSSEServerTransport("/messages", w, sse.WithReplayBuffer(10))
+	require.NoError(t, err)
+	require.NoError(t, sseTransport.Start(context.Background()))
+
+	for i := 1; i <= 3; i++ {
+		err = sseTransport.Send(&transport.BaseJsonRpcMessage{
+			Type: transport.BaseMessageTypeJSONRPCResponseType,
+			JsonRpcResponse: &transport.BaseJSONRPCResponse{
+				Jsonrpc: "2.0",
+				Id:      transport.RequestId(i),
+				Result:  json.RawMessage(`{"status":"ok"}`),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/sse?session="+sseTransport.SessionID(), nil)
+	r.Header.Set(sse.LastEventIDHeader, "2")
+	w2 := httptest.NewRecorder()
+	require.NoError(t, sseTransport.Resume(r, w2))
+
+	body := w2.Body.String()
+	assert.Contains(t, body, "event: endpoint")
+	assert.NotContains(t, body, `"id":2`)
+	assert.Contains(t, body, "id: 3\nevent: message\n")
+	assert.Contains(t, body, `"id":3`)
+}
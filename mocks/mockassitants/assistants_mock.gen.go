@@ -451,123 +451,159 @@ func (m *MockCallback) EXPECT() *MockCallbackMockRecorder {
 }
 
 // OnAssistantEnd mocks base method.
-func (m *MockCallback) OnAssistantEnd(ctx context.Context, a assistants.IAssistant, input string, resp *assistants.Response, messageHistory llms.Messages) {
+func (m *MockCallback) OnAssistantEnd(ctx context.Context, e *assistants.AssistantEndEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnAssistantEnd", ctx, a, input, resp, messageHistory)
+	m.ctrl.Call(m, "OnAssistantEnd", ctx, e)
 }
 
 // OnAssistantEnd indicates an expected call of OnAssistantEnd.
-func (mr *MockCallbackMockRecorder) OnAssistantEnd(ctx, a, input, resp, messageHistory any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnAssistantEnd(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantEnd", reflect.TypeOf((*MockCallback)(nil).OnAssistantEnd), ctx, a, input, resp, messageHistory)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantEnd", reflect.TypeOf((*MockCallback)(nil).OnAssistantEnd), ctx, e)
 }
 
 // OnAssistantError mocks base method.
-func (m *MockCallback) OnAssistantError(ctx context.Context, a assistants.IAssistant, input string, err error, messageHistory llms.Messages) {
+func (m *MockCallback) OnAssistantError(ctx context.Context, e *assistants.AssistantErrorEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnAssistantError", ctx, a, input, err, messageHistory)
+	m.ctrl.Call(m, "OnAssistantError", ctx, e)
 }
 
 // OnAssistantError indicates an expected call of OnAssistantError.
-func (mr *MockCallbackMockRecorder) OnAssistantError(ctx, a, input, err, messageHistory any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnAssistantError(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantError", reflect.TypeOf((*MockCallback)(nil).OnAssistantError), ctx, a, input, err, messageHistory)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantError", reflect.TypeOf((*MockCallback)(nil).OnAssistantError), ctx, e)
 }
 
 // OnAssistantLLMCallEnd mocks base method.
-func (m *MockCallback) OnAssistantLLMCallEnd(ctx context.Context, a assistants.IAssistant, llm llms.Model, resp *llms.ContentResponse) {
+func (m *MockCallback) OnAssistantLLMCallEnd(ctx context.Context, e *assistants.LLMCallEndEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnAssistantLLMCallEnd", ctx, a, llm, resp)
+	m.ctrl.Call(m, "OnAssistantLLMCallEnd", ctx, e)
 }
 
 // OnAssistantLLMCallEnd indicates an expected call of OnAssistantLLMCallEnd.
-func (mr *MockCallbackMockRecorder) OnAssistantLLMCallEnd(ctx, a, llm, resp any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnAssistantLLMCallEnd(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantLLMCallEnd", reflect.TypeOf((*MockCallback)(nil).OnAssistantLLMCallEnd), ctx, a, llm, resp)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantLLMCallEnd", reflect.TypeOf((*MockCallback)(nil).OnAssistantLLMCallEnd), ctx, e)
 }
 
 // OnAssistantLLMCallStart mocks base method.
-func (m *MockCallback) OnAssistantLLMCallStart(ctx context.Context, a assistants.IAssistant, llm llms.Model, payload llms.Messages) {
+func (m *MockCallback) OnAssistantLLMCallStart(ctx context.Context, e *assistants.LLMCallStartEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnAssistantLLMCallStart", ctx, a, llm, payload)
+	m.ctrl.Call(m, "OnAssistantLLMCallStart", ctx, e)
 }
 
 // OnAssistantLLMCallStart indicates an expected call of OnAssistantLLMCallStart.
-func (mr *MockCallbackMockRecorder) OnAssistantLLMCallStart(ctx, a, llm, payload any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnAssistantLLMCallStart(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantLLMCallStart", reflect.TypeOf((*MockCallback)(nil).OnAssistantLLMCallStart), ctx, a, llm, payload)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantLLMCallStart", reflect.TypeOf((*MockCallback)(nil).OnAssistantLLMCallStart), ctx, e)
 }
 
 // OnAssistantLLMParseError mocks base method.
-func (m *MockCallback) OnAssistantLLMParseError(ctx context.Context, a assistants.IAssistant, input, response string, err error) {
+func (m *MockCallback) OnAssistantLLMParseError(ctx context.Context, e *assistants.LLMParseErrorEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnAssistantLLMParseError", ctx, a, input, response, err)
+	m.ctrl.Call(m, "OnAssistantLLMParseError", ctx, e)
 }
 
 // OnAssistantLLMParseError indicates an expected call of OnAssistantLLMParseError.
-func (mr *MockCallbackMockRecorder) OnAssistantLLMParseError(ctx, a, input, response, err any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnAssistantLLMParseError(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantLLMParseError", reflect.TypeOf((*MockCallback)(nil).OnAssistantLLMParseError), ctx, a, input, response, err)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantLLMParseError", reflect.TypeOf((*MockCallback)(nil).OnAssistantLLMParseError), ctx, e)
 }
 
 // OnAssistantStart mocks base method.
-func (m *MockCallback) OnAssistantStart(ctx context.Context, a assistants.IAssistant, input string) {
+func (m *MockCallback) OnAssistantStart(ctx context.Context, e *assistants.AssistantStartEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnAssistantStart", ctx, a, input)
+	m.ctrl.Call(m, "OnAssistantStart", ctx, e)
 }
 
 // OnAssistantStart indicates an expected call of OnAssistantStart.
-func (mr *MockCallbackMockRecorder) OnAssistantStart(ctx, a, input any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnAssistantStart(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantStart", reflect.TypeOf((*MockCallback)(nil).OnAssistantStart), ctx, a, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAssistantStart", reflect.TypeOf((*MockCallback)(nil).OnAssistantStart), ctx, e)
 }
 
 // OnToolEnd mocks base method.
-func (m *MockCallback) OnToolEnd(ctx context.Context, tool tools.ITool, assistantName, input, output string) {
+func (m *MockCallback) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnToolEnd", ctx, tool, assistantName, input, output)
+	m.ctrl.Call(m, "OnToolEnd", ctx, e)
 }
 
 // OnToolEnd indicates an expected call of OnToolEnd.
-func (mr *MockCallbackMockRecorder) OnToolEnd(ctx, tool, assistantName, input, output any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnToolEnd(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolEnd", reflect.TypeOf((*MockCallback)(nil).OnToolEnd), ctx, tool, assistantName, input, output)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolEnd", reflect.TypeOf((*MockCallback)(nil).OnToolEnd), ctx, e)
 }
 
 // OnToolError mocks base method.
-func (m *MockCallback) OnToolError(ctx context.Context, tool tools.ITool, assistantName, input string, err error) {
+func (m *MockCallback) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnToolError", ctx, tool, assistantName, input, err)
+	m.ctrl.Call(m, "OnToolError", ctx, e)
 }
 
 // OnToolError indicates an expected call of OnToolError.
-func (mr *MockCallbackMockRecorder) OnToolError(ctx, tool, assistantName, input, err any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnToolError(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolError", reflect.TypeOf((*MockCallback)(nil).OnToolError), ctx, tool, assistantName, input, err)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolError", reflect.TypeOf((*MockCallback)(nil).OnToolError), ctx, e)
 }
 
 // OnToolNotFound mocks base method.
-func (m *MockCallback) OnToolNotFound(ctx context.Context, a assistants.IAssistant, tool string) {
+func (m *MockCallback) OnToolNotFound(ctx context.Context, e *assistants.ToolNotFoundEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnToolNotFound", ctx, a, tool)
+	m.ctrl.Call(m, "OnToolNotFound", ctx, e)
 }
 
 // OnToolNotFound indicates an expected call of OnToolNotFound.
-func (mr *MockCallbackMockRecorder) OnToolNotFound(ctx, a, tool any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnToolNotFound(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolNotFound", reflect.TypeOf((*MockCallback)(nil).OnToolNotFound), ctx, a, tool)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolNotFound", reflect.TypeOf((*MockCallback)(nil).OnToolNotFound), ctx, e)
 }
 
 // OnToolStart mocks base method.
-func (m *MockCallback) OnToolStart(ctx context.Context, tool tools.ITool, assistantName, input string) {
+func (m *MockCallback) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnToolStart", ctx, tool, assistantName, input)
+	m.ctrl.Call(m, "OnToolStart", ctx, e)
 }
 
 // OnToolStart indicates an expected call of OnToolStart.
-func (mr *MockCallbackMockRecorder) OnToolStart(ctx, tool, assistantName, input any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnToolStart(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolStart", reflect.TypeOf((*MockCallback)(nil).OnToolStart), ctx, tool, assistantName, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolStart", reflect.TypeOf((*MockCallback)(nil).OnToolStart), ctx, e)
+}
+
+// MockToolCallDeltaCallback is a mock of ToolCallDeltaCallback interface.
+type MockToolCallDeltaCallback struct {
+	ctrl     *gomock.Controller
+	recorder *MockToolCallDeltaCallbackMockRecorder
+	isgomock struct{}
+}
+
+// MockToolCallDeltaCallbackMockRecorder is the mock recorder for MockToolCallDeltaCallback.
+type MockToolCallDeltaCallbackMockRecorder struct {
+	mock *MockToolCallDeltaCallback
+}
+
+// NewMockToolCallDeltaCallback creates a new mock instance.
+func NewMockToolCallDeltaCallback(ctrl *gomock.Controller) *MockToolCallDeltaCallback {
+	mock := &MockToolCallDeltaCallback{ctrl: ctrl}
+	mock.recorder = &MockToolCallDeltaCallbackMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockToolCallDeltaCallback) EXPECT() *MockToolCallDeltaCallbackMockRecorder {
+	return m.recorder
+}
+
+// OnToolCallDelta mocks base method.
+func (m *MockToolCallDeltaCallback) OnToolCallDelta(ctx context.Context, e *assistants.ToolCallDeltaEvent) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnToolCallDelta", ctx, e)
+}
+
+// OnToolCallDelta indicates an expected call of OnToolCallDelta.
+func (mr *MockToolCallDeltaCallbackMockRecorder) OnToolCallDelta(ctx, e any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolCallDelta", reflect.TypeOf((*MockToolCallDeltaCallback)(nil).OnToolCallDelta), ctx, e)
 }
 
 // MockIMCPAssistant is a mock of IMCPAssistant interface.
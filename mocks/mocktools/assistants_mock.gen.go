@@ -163,39 +163,39 @@ func (m *MockCallback) EXPECT() *MockCallbackMockRecorder {
 }
 
 // OnToolEnd mocks base method.
-func (m *MockCallback) OnToolEnd(ctx context.Context, tool tools.ITool, assistantName, input, output string) {
+func (m *MockCallback) OnToolEnd(ctx context.Context, e *tools.ToolEndEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnToolEnd", ctx, tool, assistantName, input, output)
+	m.ctrl.Call(m, "OnToolEnd", ctx, e)
 }
 
 // OnToolEnd indicates an expected call of OnToolEnd.
-func (mr *MockCallbackMockRecorder) OnToolEnd(ctx, tool, assistantName, input, output any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnToolEnd(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolEnd", reflect.TypeOf((*MockCallback)(nil).OnToolEnd), ctx, tool, assistantName, input, output)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolEnd", reflect.TypeOf((*MockCallback)(nil).OnToolEnd), ctx, e)
 }
 
 // OnToolError mocks base method.
-func (m *MockCallback) OnToolError(ctx context.Context, tool tools.ITool, assistantName, input string, err error) {
+func (m *MockCallback) OnToolError(ctx context.Context, e *tools.ToolErrorEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnToolError", ctx, tool, assistantName, input, err)
+	m.ctrl.Call(m, "OnToolError", ctx, e)
 }
 
 // OnToolError indicates an expected call of OnToolError.
-func (mr *MockCallbackMockRecorder) OnToolError(ctx, tool, assistantName, input, err any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnToolError(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolError", reflect.TypeOf((*MockCallback)(nil).OnToolError), ctx, tool, assistantName, input, err)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolError", reflect.TypeOf((*MockCallback)(nil).OnToolError), ctx, e)
 }
 
 // OnToolStart mocks base method.
-func (m *MockCallback) OnToolStart(ctx context.Context, tool tools.ITool, assistantName, input string) {
+func (m *MockCallback) OnToolStart(ctx context.Context, e *tools.ToolStartEvent) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnToolStart", ctx, tool, assistantName, input)
+	m.ctrl.Call(m, "OnToolStart", ctx, e)
 }
 
 // OnToolStart indicates an expected call of OnToolStart.
-func (mr *MockCallbackMockRecorder) OnToolStart(ctx, tool, assistantName, input any) *gomock.Call {
+func (mr *MockCallbackMockRecorder) OnToolStart(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolStart", reflect.TypeOf((*MockCallback)(nil).OnToolStart), ctx, tool, assistantName, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnToolStart", reflect.TypeOf((*MockCallback)(nil).OnToolStart), ctx, e)
 }
 
 // MockTool is a mock of Tool interface.
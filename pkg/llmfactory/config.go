@@ -3,6 +3,7 @@ package llmfactory
 import (
 	"slices"
 
+	"github.com/effective-security/gogentic/pkg/llms/openrouter"
 	"github.com/effective-security/gogentic/skills"
 	"github.com/effective-security/x/configloader"
 )
@@ -28,11 +29,14 @@ type Config struct {
 
 // ProviderConfig for the OpenAI provider
 type ProviderConfig struct {
-	Name            string       `json:"name" yaml:"name"`
-	Token           string       `json:"token,omitempty" yaml:"token,omitempty"`
-	DefaultModel    string       `json:"default_model,omitempty" yaml:"default_model,omitempty"`
-	AvailableModels []string     `json:"available_models,omitempty" yaml:"available_models,omitempty"`
-	OpenAI          OpenAIConfig `json:"open_ai" yaml:"open_ai"`
+	Name            string   `json:"name" yaml:"name"`
+	Token           string   `json:"token,omitempty" yaml:"token,omitempty"`
+	DefaultModel    string   `json:"default_model,omitempty" yaml:"default_model,omitempty"`
+	AvailableModels []string `json:"available_models,omitempty" yaml:"available_models,omitempty"`
+	// MaxTokens specifies the maximum number of the completion tokens requested from the models,
+	// it is validated against the model metadata in modelinfo.Default.
+	MaxTokens int          `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	OpenAI    OpenAIConfig `json:"open_ai" yaml:"open_ai"`
 }
 
 // OpenAIConfig specifies options config
@@ -40,11 +44,20 @@ type OpenAIConfig struct {
 	BaseURL    string `json:"base_url,omitempty" yaml:"base_url,omitempty"`
 	APIVersion string `json:"api_version,omitempty" yaml:"api_version,omitempty"`
 	// APIType specifies the type of API to use:
-	// OPENAI|AZURE|AZURE_AD|CLOUDFLARE|ANTHROPIC|GOOGLEAI|BEDROCK|PERPLEXITY
+	// OPENAI|AZURE|AZURE_AD|AZURE_AI|CLOUDFLARE|COHERE|ANTHROPIC|GOOGLEAI|VERTEX_AI|BEDROCK|PERPLEXITY|VLLM|LLAMACPP|GROQ|DEEPSEEK|OPENROUTER
 	APIType string `json:"api_type,omitempty" yaml:"api_type,omitempty"`
 	// OrgID specifies which organization's quota and billing should be used when making API requests.
 	OrgID            string `json:"org_id,omitempty" yaml:"org_id,omitempty"`
 	AssistantVersion string `json:"assistant_version,omitempty" yaml:"assistant_version,omitempty"`
+	// ProviderRouting specifies the OpenRouter provider routing preferences.
+	ProviderRouting *openrouter.ProviderPreferences `json:"provider_routing,omitempty" yaml:"provider_routing,omitempty"`
+	// Project specifies the GCP cloud project of Vertex AI.
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
+	// Location specifies the GCP cloud location of Vertex AI, for example us-central1.
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+	// CredentialsFile specifies the service account key file of Vertex AI,
+	// the Application Default Credentials are used if not set.
+	CredentialsFile string `json:"credentials_file,omitempty" yaml:"credentials_file,omitempty"`
 }
 
 func (c *ProviderConfig) FindModel(models ...string) string {
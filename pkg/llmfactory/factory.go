@@ -9,10 +9,17 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/effective-security/gogentic/pkg/llms/anthropic"
+	"github.com/effective-security/gogentic/pkg/llms/azureai"
 	"github.com/effective-security/gogentic/pkg/llms/bedrock"
 	"github.com/effective-security/gogentic/pkg/llms/cloudflare"
+	"github.com/effective-security/gogentic/pkg/llms/cohere"
+	"github.com/effective-security/gogentic/pkg/llms/deepseek"
 	"github.com/effective-security/gogentic/pkg/llms/googleai"
+	"github.com/effective-security/gogentic/pkg/llms/groq"
+	"github.com/effective-security/gogentic/pkg/llms/modelinfo"
 	"github.com/effective-security/gogentic/pkg/llms/openai"
+	"github.com/effective-security/gogentic/pkg/llms/openrouter"
+	"github.com/effective-security/gogentic/pkg/llms/vertexai"
 	"github.com/effective-security/gogentic/skills"
 	"github.com/effective-security/xlog"
 )
@@ -27,7 +34,7 @@ type Factory interface {
 	// DefaultModel returns the default LLM model.
 	DefaultModel() (llms.Model, error)
 	// ModelByType returns an LLM model by its type, e.g.
-	// OPENAI, AZURE, AZURE_AD, CLOUDFLARE, ANTHROPIC, GOOGLEAI, BEDROCK, PERPLEXITY
+	// OPENAI, AZURE, AZURE_AD, AZURE_AI, CLOUDFLARE, COHERE, ANTHROPIC, GOOGLEAI, VERTEX_AI, BEDROCK, PERPLEXITY, VLLM, LLAMACPP, GROQ, DEEPSEEK, OPENROUTER
 	ModelByType(providerType llms.ProviderType) (llms.Model, error)
 	// ModelByName returns an LLM model by its name,
 	// if the model is not found, it will return the default model.
@@ -36,12 +43,27 @@ type Factory interface {
 	ToolModel(toolName string, preferredModels ...string) (llms.Model, error)
 	// AssistantModel returns an assistant model by its name.
 	AssistantModel(assistantName string, preferredModels ...string) (llms.Model, error)
-
 	// Skills returns all loaded skills for the given agent sorted alphabetically by name.
 	// Use tags to filter skills by tags. The Skill must have all the tags provided.
 	Skills(agent string, tags ...string) skills.Skills
 }
 
+// AudioFactory is an optional interface of the Factory,
+// to create the speech to text and text to speech models.
+type AudioFactory interface {
+	// Transcriber returns the speech to text model by its name, e.g. whisper-1,
+	// the model must be in the available models of the provider.
+	Transcriber(modelNames ...string) (llms.Transcriber, error)
+	// Speech returns the text to speech model by its name, e.g. tts-1,
+	// the model must be in the available models of the provider.
+	Speech(modelNames ...string) (llms.Speech, error)
+}
+
+var (
+	_ Factory      = (*factory)(nil)
+	_ AudioFactory = (*factory)(nil)
+)
+
 // Load returns OpenAI factory
 func Load(location string) (Factory, error) {
 	cfg, err := LoadConfig(location)
@@ -116,30 +138,63 @@ func New(cfg *Config, opts ...Option) Factory {
 	return f
 }
 
+// CreateLLM creates the LLM of the provider with the first available preferred model,
+// or the default model of the provider.
+// The MaxTokens of the provider is validated against the model metadata.
 func CreateLLM(cfg *ProviderConfig, preferredModels []string, opts ...Option) (llms.Model, error) {
+	model, err := createLLM(cfg, preferredModels, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxTokens > 0 {
+		if err = modelinfo.ValidateMaxTokens(model.GetName(), cfg.MaxTokens); err != nil {
+			return nil, errors.WithMessagef(err, "provider %s", cfg.Name)
+		}
+	}
+	return model, nil
+}
+
+func createLLM(cfg *ProviderConfig, preferredModels []string, opts ...Option) (llms.Model, error) {
 	provType := strings.ToUpper(cfg.OpenAI.APIType)
 	switch provType {
 	case string(llms.ProviderOpenAI), "OPEN_AI":
 		return newOpenAI(cfg, preferredModels, opts...)
 	case string(llms.ProviderPerplexity):
 		return newPerplexity(cfg, preferredModels, opts...)
+	case string(llms.ProviderVLLM):
+		return newSelfHosted(cfg, openai.ProviderVLLM, preferredModels, opts...)
+	case string(llms.ProviderLlamaCpp):
+		return newSelfHosted(cfg, openai.ProviderLlamaCpp, preferredModels, opts...)
+	case string(llms.ProviderGroq):
+		return newGroq(cfg, preferredModels, opts...)
+	case string(llms.ProviderDeepSeek):
+		return newDeepSeek(cfg, preferredModels, opts...)
+	case string(llms.ProviderOpenRouter):
+		return newOpenRouter(cfg, preferredModels, opts...)
 	case string(llms.ProviderAzure), string(llms.ProviderAzureAD):
 		return newAzure(cfg, preferredModels, opts...)
+	case string(llms.ProviderAzureAI):
+		return newAzureAI(cfg, preferredModels, opts...)
 	case string(llms.ProviderAnthropic):
 		return newAnthropic(cfg, preferredModels, opts...)
 	case string(llms.ProviderGoogleAI):
 		return newGoogleAI(cfg, preferredModels, opts...)
+	case string(llms.ProviderVertexAI):
+		return newVertexAI(cfg, preferredModels, opts...)
 	case string(llms.ProviderBedrock):
 		return newBedrock(cfg, preferredModels, opts...)
 	case string(llms.ProviderAnthropicBedrock):
 		return newAnthropicBedrock(cfg, preferredModels, opts...)
 	case string(llms.ProviderCloudflare):
 		return newCloudflare(cfg, preferredModels, opts...)
+	case string(llms.ProviderCohere):
+		return newCohere(cfg, preferredModels, opts...)
 	}
 	return nil, errors.Errorf("unsupported provider type: %s", provType)
 }
 
 func newOpenAI(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
 	var opts []openai.Option
 	model := cfg.FindModel(preferredModels...)
 	opts = append(opts, openai.WithProvider(openai.ProviderOpenAI), openai.WithModel(model))
@@ -150,10 +205,14 @@ func newOpenAI(cfg *ProviderConfig, preferredModels []string, options ...Option)
 	if cfg.OpenAI.BaseURL != "" {
 		opts = append(opts, openai.WithBaseURL(cfg.OpenAI.BaseURL))
 	}
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, openai.WithHTTPClient(client))
+	}
 	return openai.New(opts...)
 }
 
 func newPerplexity(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
 	var opts []openai.Option
 	model := cfg.FindModel(preferredModels...)
 	opts = append(opts, openai.WithProvider(openai.ProviderPerplexity), openai.WithModel(model))
@@ -164,10 +223,131 @@ func newPerplexity(cfg *ProviderConfig, preferredModels []string, options ...Opt
 	if cfg.OpenAI.BaseURL != "" {
 		opts = append(opts, openai.WithBaseURL(cfg.OpenAI.BaseURL))
 	}
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, openai.WithHTTPClient(client))
+	}
 	return openai.New(opts...)
 }
 
+// newSelfHosted creates the OpenAI compatible client of the self-hosted server.
+func newSelfHosted(cfg *ProviderConfig, provider openai.ProviderType, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
+	var opts []openai.Option
+	model := cfg.FindModel(preferredModels...)
+	opts = append(opts, openai.WithProvider(provider), openai.WithModel(model))
+
+	if cfg.Token != "" {
+		opts = append(opts, openai.WithToken(cfg.Token))
+	}
+	if cfg.OpenAI.BaseURL != "" {
+		opts = append(opts, openai.WithBaseURL(cfg.OpenAI.BaseURL))
+	}
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, openai.WithHTTPClient(client))
+	}
+	return openai.New(opts...)
+}
+
+func newGroq(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
+	var opts []groq.Option
+	if model := cfg.FindModel(preferredModels...); model != "" {
+		opts = append(opts, groq.WithModel(model))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, groq.WithToken(cfg.Token))
+	}
+	if cfg.OpenAI.BaseURL != "" {
+		opts = append(opts, groq.WithBaseURL(cfg.OpenAI.BaseURL))
+	}
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, groq.WithHTTPClient(client))
+	}
+	return groq.New(opts...)
+}
+
+func newDeepSeek(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
+	var opts []deepseek.Option
+	if model := cfg.FindModel(preferredModels...); model != "" {
+		opts = append(opts, deepseek.WithModel(model))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, deepseek.WithToken(cfg.Token))
+	}
+	if cfg.OpenAI.BaseURL != "" {
+		opts = append(opts, deepseek.WithBaseURL(cfg.OpenAI.BaseURL))
+	}
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, deepseek.WithHTTPClient(client))
+	}
+	return deepseek.New(opts...)
+}
+
+func newOpenRouter(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
+	var opts []openrouter.Option
+	if model := cfg.FindModel(preferredModels...); model != "" {
+		opts = append(opts, openrouter.WithModel(model))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, openrouter.WithToken(cfg.Token))
+	}
+	if cfg.OpenAI.BaseURL != "" {
+		opts = append(opts, openrouter.WithBaseURL(cfg.OpenAI.BaseURL))
+	}
+	if cfg.OpenAI.ProviderRouting != nil {
+		opts = append(opts, openrouter.WithProviderPreferences(cfg.OpenAI.ProviderRouting))
+	}
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, openrouter.WithHTTPClient(client))
+	}
+	return openrouter.New(opts...)
+}
+
+func newCohere(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
+	var opts []cohere.Option
+	if model := cfg.FindModel(preferredModels...); model != "" {
+		opts = append(opts, cohere.WithModel(model))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, cohere.WithToken(cfg.Token))
+	}
+	if cfg.OpenAI.BaseURL != "" {
+		opts = append(opts, cohere.WithBaseURL(cfg.OpenAI.BaseURL))
+	}
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, cohere.WithHTTPClient(client))
+	}
+	return cohere.New(opts...)
+}
+
+func newAzureAI(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
+	var opts []azureai.Option
+	if model := cfg.FindModel(preferredModels...); model != "" {
+		opts = append(opts, azureai.WithModel(model))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, azureai.WithAPIKey(cfg.Token))
+	} else if o.AzureTokenFunc != nil {
+		opts = append(opts, azureai.WithEntraToken(o.AzureTokenFunc))
+	}
+	if cfg.OpenAI.BaseURL != "" {
+		opts = append(opts, azureai.WithEndpoint(cfg.OpenAI.BaseURL))
+	}
+	if cfg.OpenAI.APIVersion != "" {
+		opts = append(opts, azureai.WithAPIVersion(cfg.OpenAI.APIVersion))
+	}
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, azureai.WithHTTPClient(client))
+	}
+	return azureai.New(opts...)
+}
+
 func newAzure(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
 	var opts []openai.Option
 	model := cfg.FindModel(preferredModels...)
 	opts = append(opts, openai.WithAPIVersion(cfg.OpenAI.APIVersion), openai.WithModel(model))
@@ -183,6 +363,9 @@ func newAzure(cfg *ProviderConfig, preferredModels []string, options ...Option)
 	if cfg.OpenAI.BaseURL != "" {
 		opts = append(opts, openai.WithBaseURL(cfg.OpenAI.BaseURL))
 	}
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, openai.WithHTTPClient(client))
+	}
 	return openai.New(opts...)
 }
 
@@ -194,22 +377,53 @@ func newAnthropic(cfg *ProviderConfig, preferredModels []string, options ...Opti
 	if cfg.Token != "" {
 		opts = append(opts, anthropic.WithToken(cfg.Token))
 	}
-	if o.HTTPClient != nil {
-		opts = append(opts, anthropic.WithHTTPClient(o.HTTPClient))
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, anthropic.WithHTTPClient(client))
 	}
 	return anthropic.New(opts...)
 }
 
 func newGoogleAI(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
 	var opts []googleai.Option
 	model := cfg.FindModel(preferredModels...)
 	opts = append(opts, googleai.WithDefaultModel(model))
 	if cfg.Token != "" {
 		opts = append(opts, googleai.WithAPIKey(cfg.Token))
 	}
+	if len(o.HTTPMiddlewares) > 0 {
+		opts = append(opts, googleai.WithHTTPMiddleware(o.HTTPMiddlewares...))
+	}
 	return googleai.New(context.Background(), opts...)
 }
 
+func newVertexAI(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
+	o := NewOptions(options...)
+	var opts []vertexai.Option
+	if model := cfg.FindModel(preferredModels...); model != "" {
+		opts = append(opts, vertexai.WithModel(model))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, vertexai.WithAPIKey(cfg.Token))
+	}
+	if cfg.OpenAI.Project != "" {
+		opts = append(opts, vertexai.WithProject(cfg.OpenAI.Project))
+	}
+	if cfg.OpenAI.Location != "" {
+		opts = append(opts, vertexai.WithLocation(cfg.OpenAI.Location))
+	}
+	if cfg.OpenAI.CredentialsFile != "" {
+		opts = append(opts, vertexai.WithCredentialsFile(cfg.OpenAI.CredentialsFile))
+	}
+	if cfg.OpenAI.BaseURL != "" {
+		opts = append(opts, vertexai.WithBaseURL(cfg.OpenAI.BaseURL))
+	}
+	if len(o.HTTPMiddlewares) > 0 {
+		opts = append(opts, vertexai.WithHTTPMiddleware(o.HTTPMiddlewares...))
+	}
+	return vertexai.New(context.Background(), opts...)
+}
+
 func newBedrock(cfg *ProviderConfig, preferredModels []string, options ...Option) (llms.Model, error) {
 	o := NewOptions(options...)
 	var opts []bedrock.Option
@@ -225,6 +439,9 @@ func newBedrock(cfg *ProviderConfig, preferredModels []string, options ...Option
 		}
 		opts = append(opts, bedrock.WithConfig(cfg))
 	}
+	if len(o.HTTPMiddlewares) > 0 {
+		opts = append(opts, bedrock.WithHTTPMiddleware(o.HTTPMiddlewares...))
+	}
 
 	return bedrock.New(opts...)
 }
@@ -244,6 +461,9 @@ func newAnthropicBedrock(cfg *ProviderConfig, preferredModels []string, options
 		}
 		opts = append(opts, anthropic.WithConfig(cfg))
 	}
+	if len(o.HTTPMiddlewares) > 0 {
+		opts = append(opts, anthropic.WithHTTPMiddleware(o.HTTPMiddlewares...))
+	}
 	return anthropic.NewBedrock(opts...)
 }
 
@@ -258,8 +478,8 @@ func newCloudflare(cfg *ProviderConfig, preferredModels []string, options ...Opt
 	if cfg.OpenAI.BaseURL != "" {
 		opts = append(opts, cloudflare.WithServerURL(cfg.OpenAI.BaseURL))
 	}
-	if o.HTTPClient != nil {
-		opts = append(opts, cloudflare.WithHTTPClient(o.HTTPClient))
+	if client := o.httpClient(); client != nil {
+		opts = append(opts, cloudflare.WithHTTPClient(client))
 	}
 	return cloudflare.New(opts...)
 }
@@ -391,3 +611,65 @@ func (f *factory) AssistantModel(assistantName string, preferredModels ...string
 	// Fallback to default provider
 	return f.ModelByName(preferredModels...)
 }
+
+// Transcriber returns the speech to text model by its name.
+func (f *factory) Transcriber(modelNames ...string) (llms.Transcriber, error) {
+	m, err := f.audioModel(modelNames...)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := m.(llms.Transcriber)
+	if !ok {
+		return nil, errors.Errorf("model %s does not support transcription", m.GetName())
+	}
+	return &transcriber{Transcriber: t, model: m.GetName()}, nil
+}
+
+// Speech returns the text to speech model by its name.
+func (f *factory) Speech(modelNames ...string) (llms.Speech, error) {
+	m, err := f.audioModel(modelNames...)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := m.(llms.Speech)
+	if !ok {
+		return nil, errors.Errorf("model %s does not support speech", m.GetName())
+	}
+	return &speech{Speech: s, model: m.GetName()}, nil
+}
+
+// audioModel returns the model by its name, without falling back to the default model,
+// as the chat models can not be used for the audio.
+func (f *factory) audioModel(modelNames ...string) (llms.Model, error) {
+	m, err := f.ModelByName(modelNames...)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range modelNames {
+		parts := strings.Split(name, "/")
+		if parts[len(parts)-1] == m.GetName() {
+			return m, nil
+		}
+	}
+	return nil, errors.Errorf("audio model not found: %v", modelNames)
+}
+
+// transcriber sets the model of the factory, as the provider's LLM defaults to its own transcription model.
+type transcriber struct {
+	llms.Transcriber
+	model string
+}
+
+func (t *transcriber) Transcribe(ctx context.Context, audio llms.BinaryContent, options ...llms.TranscribeOption) (*llms.Transcription, error) {
+	return t.Transcriber.Transcribe(ctx, audio, append([]llms.TranscribeOption{llms.WithTranscribeModel(t.model)}, options...)...)
+}
+
+// speech sets the model of the factory, as the provider's LLM defaults to its own speech model.
+type speech struct {
+	llms.Speech
+	model string
+}
+
+func (s *speech) GenerateSpeech(ctx context.Context, text string, options ...llms.SpeechOption) (*llms.BinaryContent, error) {
+	return s.Speech.GenerateSpeech(ctx, text, append([]llms.SpeechOption{llms.WithSpeechModel(s.model)}, options...)...)
+}
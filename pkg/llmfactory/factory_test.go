@@ -2,10 +2,14 @@ package llmfactory_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/effective-security/gogentic/pkg/llmfactory"
 	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/modelinfo"
+	"github.com/effective-security/gogentic/pkg/llms/openrouter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -796,3 +800,223 @@ func (f *fakeLLM) GetProviderType() llms.ProviderType {
 func (f *fakeLLM) GetName() string {
 	return f.model
 }
+
+func Test_CreateLLM_SelfHosted(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+
+	cfg := &llmfactory.ProviderConfig{
+		Name: "local",
+		OpenAI: llmfactory.OpenAIConfig{
+			APIType: "VLLM",
+			BaseURL: "http://localhost:8000/v1",
+		},
+		AvailableModels: []string{"qwen2.5-7b"},
+		DefaultModel:    "qwen2.5-7b",
+	}
+
+	for _, typ := range []llms.ProviderType{llms.ProviderVLLM, llms.ProviderLlamaCpp} {
+		cfg.OpenAI.APIType = string(typ)
+		model, err := llmfactory.CreateLLM(cfg, nil)
+		require.NoError(t, err)
+		assert.Equal(t, typ, model.GetProviderType())
+		assert.Equal(t, "qwen2.5-7b", model.GetName())
+		assert.True(t, typ.Supports(llms.CapabilityConstrainedDecoding))
+	}
+}
+
+func Test_AudioModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/audio/transcriptions":
+			assert.NoError(t, r.ParseMultipartForm(1<<20))
+			assert.Equal(t, "whisper-1", r.FormValue("model"))
+			_, _ = w.Write([]byte(`{"text": "Hello"}`))
+		case "/audio/speech":
+			_, _ = w.Write([]byte("audio"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	f := llmfactory.New(&llmfactory.Config{
+		Providers: []*llmfactory.ProviderConfig{
+			{
+				Name:            "OPENAI",
+				Token:           "fakekey",
+				DefaultModel:    "gpt-5",
+				AvailableModels: []string{"gpt-5", "whisper-1", "tts-1"},
+				OpenAI:          llmfactory.OpenAIConfig{APIType: "OPENAI", BaseURL: srv.URL},
+			},
+			{
+				Name:            "ANTHROPIC",
+				Token:           "fakekey",
+				DefaultModel:    "claude-sonnet-4-6",
+				AvailableModels: []string{"claude-sonnet-4-6"},
+				OpenAI:          llmfactory.OpenAIConfig{APIType: "ANTHROPIC"},
+			},
+		},
+	})
+
+	af, ok := f.(llmfactory.AudioFactory)
+	require.True(t, ok)
+
+	tr, err := af.Transcriber("OPENAI/whisper-1")
+	require.NoError(t, err)
+	res, err := tr.Transcribe(context.Background(), llms.BinaryContent{MIMEType: "audio/wav", Data: []byte("RIFF")})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", res.Text)
+
+	sp, err := af.Speech("tts-1")
+	require.NoError(t, err)
+	audio, err := sp.GenerateSpeech(context.Background(), "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "audio/mpeg", audio.MIMEType)
+	assert.Equal(t, []byte("audio"), audio.Data)
+
+	_, err = af.Transcriber("whisper-2")
+	assert.EqualError(t, err, "audio model not found: [whisper-2]")
+	_, err = af.Speech("claude-sonnet-4-6")
+	assert.EqualError(t, err, "model claude-sonnet-4-6 does not support speech")
+}
+
+func Test_CreateLLM_Groq(t *testing.T) {
+	cfg := &llmfactory.ProviderConfig{
+		Name:  "groq",
+		Token: "fakekey",
+		OpenAI: llmfactory.OpenAIConfig{
+			APIType: "GROQ",
+		},
+		AvailableModels: []string{"llama-3.3-70b-versatile", "openai/gpt-oss-120b"},
+		DefaultModel:    "llama-3.3-70b-versatile",
+	}
+
+	model, err := llmfactory.CreateLLM(cfg, []string{"openai/gpt-oss-120b"})
+	require.NoError(t, err)
+	assert.Equal(t, llms.ProviderGroq, model.GetProviderType())
+	assert.Equal(t, "openai/gpt-oss-120b", model.GetName())
+
+	model, err = llmfactory.CreateLLM(cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "llama-3.3-70b-versatile", model.GetName())
+}
+
+func Test_CreateLLM_DeepSeek(t *testing.T) {
+	cfg := &llmfactory.ProviderConfig{
+		Name:  "deepseek",
+		Token: "fakekey",
+		OpenAI: llmfactory.OpenAIConfig{
+			APIType: "DEEPSEEK",
+		},
+		AvailableModels: []string{"deepseek-chat", "deepseek-reasoner"},
+		DefaultModel:    "deepseek-chat",
+	}
+
+	model, err := llmfactory.CreateLLM(cfg, []string{"deepseek-reasoner"})
+	require.NoError(t, err)
+	assert.Equal(t, llms.ProviderDeepSeek, model.GetProviderType())
+	assert.Equal(t, "deepseek-reasoner", model.GetName())
+}
+
+func Test_CreateLLM_OpenRouter(t *testing.T) {
+	cfg := &llmfactory.ProviderConfig{
+		Name:  "openrouter",
+		Token: "fakekey",
+		OpenAI: llmfactory.OpenAIConfig{
+			APIType: "OPENROUTER",
+			ProviderRouting: &openrouter.ProviderPreferences{
+				Order: []string{"anthropic"},
+			},
+		},
+		AvailableModels: []string{"openai/gpt-4o", "anthropic/claude-sonnet-4.5"},
+		DefaultModel:    "openai/gpt-4o",
+	}
+
+	model, err := llmfactory.CreateLLM(cfg, []string{"anthropic/claude-sonnet-4.5"})
+	require.NoError(t, err)
+	assert.Equal(t, llms.ProviderOpenRouter, model.GetProviderType())
+	assert.Equal(t, "anthropic/claude-sonnet-4.5", model.GetName())
+}
+
+func Test_CreateLLM_VertexAI(t *testing.T) {
+	cfg := &llmfactory.ProviderConfig{
+		Name:  "vertex",
+		Token: "fakekey",
+		OpenAI: llmfactory.OpenAIConfig{
+			APIType: "VERTEX_AI",
+		},
+		AvailableModels: []string{"gemini-2.5-pro", "gemini-2.5-flash"},
+		DefaultModel:    "gemini-2.5-pro",
+	}
+
+	model, err := llmfactory.CreateLLM(cfg, []string{"gemini-2.5-flash"})
+	require.NoError(t, err)
+	assert.Equal(t, llms.ProviderVertexAI, model.GetProviderType())
+	assert.Equal(t, "gemini-2.5-flash", model.GetName())
+}
+
+func Test_CreateLLM_AzureAI(t *testing.T) {
+	cfg := &llmfactory.ProviderConfig{
+		Name:  "azure-ai",
+		Token: "fakekey",
+		OpenAI: llmfactory.OpenAIConfig{
+			APIType: "AZURE_AI",
+			BaseURL: "https://example.services.ai.azure.com/models",
+		},
+		AvailableModels: []string{"Llama-3.3-70B-Instruct", "Phi-4"},
+		DefaultModel:    "Llama-3.3-70B-Instruct",
+	}
+
+	model, err := llmfactory.CreateLLM(cfg, []string{"Phi-4"})
+	require.NoError(t, err)
+	assert.Equal(t, llms.ProviderAzureAI, model.GetProviderType())
+	assert.Equal(t, "Phi-4", model.GetName())
+
+	cfg.Token = ""
+	t.Setenv("AZURE_AI_API_KEY", "")
+	_, err = llmfactory.CreateLLM(cfg, nil)
+	require.Error(t, err)
+
+	model, err = llmfactory.CreateLLM(cfg, nil, llmfactory.WithAzureTokenFunc(func(context.Context) (string, error) {
+		return "token", nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "Llama-3.3-70B-Instruct", model.GetName())
+}
+
+func Test_CreateLLM_Cohere(t *testing.T) {
+	cfg := &llmfactory.ProviderConfig{
+		Name:  "cohere",
+		Token: "fakekey",
+		OpenAI: llmfactory.OpenAIConfig{
+			APIType: "COHERE",
+		},
+		AvailableModels: []string{"command-a-03-2025", "command-r-plus"},
+		DefaultModel:    "command-a-03-2025",
+	}
+
+	model, err := llmfactory.CreateLLM(cfg, []string{"command-r-plus"})
+	require.NoError(t, err)
+	assert.Equal(t, llms.ProviderCohere, model.GetProviderType())
+	assert.Equal(t, "command-r-plus", model.GetName())
+}
+
+func Test_CreateLLM_MaxTokens(t *testing.T) {
+	cfg := &llmfactory.ProviderConfig{
+		Name:  "deepseek",
+		Token: "fakekey",
+		OpenAI: llmfactory.OpenAIConfig{
+			APIType: "DEEPSEEK",
+		},
+		AvailableModels: []string{"deepseek-chat", "deepseek-reasoner"},
+		DefaultModel:    "deepseek-chat",
+		MaxTokens:       32000,
+	}
+
+	_, err := llmfactory.CreateLLM(cfg, nil)
+	assert.ErrorIs(t, err, modelinfo.ErrMaxTokensExceeded)
+
+	model, err := llmfactory.CreateLLM(cfg, []string{"deepseek-reasoner"})
+	require.NoError(t, err)
+	assert.Equal(t, "deepseek-reasoner", model.GetName())
+}
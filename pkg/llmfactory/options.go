@@ -1,16 +1,22 @@
 package llmfactory
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
 )
 
 type Options struct {
 	// HTTPClient is used to create a new HTTP client.
 	HTTPClient HTTPClient
+	// HTTPMiddlewares wrap the HTTP client of the providers.
+	HTTPMiddlewares []llmhttp.Middleware
 	// AwsConfigFactory is used to create a new AWS config.
 	AwsConfigFactory func() (*aws.Config, error)
+	// AzureTokenFunc is used to get the Microsoft Entra ID token for Azure AI.
+	AzureTokenFunc func(ctx context.Context) (string, error)
 }
 
 type Option func(*Options)
@@ -29,6 +35,14 @@ func WithAWSConfigFactory(factory func() (*aws.Config, error)) Option {
 	}
 }
 
+// WithAzureTokenFunc sets the function that returns the Microsoft Entra ID token
+// for the AZURE_AI provider, when the token is not set in the config.
+func WithAzureTokenFunc(fn func(ctx context.Context) (string, error)) Option {
+	return func(opts *Options) {
+		opts.AzureTokenFunc = fn
+	}
+}
+
 // WithHTTPClient allows setting a custom HTTP client. If not set, the default value
 // is http.DefaultClient.
 func WithHTTPClient(client HTTPClient) Option {
@@ -37,6 +51,24 @@ func WithHTTPClient(client HTTPClient) Option {
 	}
 }
 
+// WithHTTPMiddleware wraps the HTTP client of the providers with the middlewares,
+// for the corporate proxies, the request signing, the extra headers or the WAF tokens,
+// see llmhttp.Middleware. The first middleware is the outermost one.
+func WithHTTPMiddleware(middlewares ...llmhttp.Middleware) Option {
+	return func(opts *Options) {
+		opts.HTTPMiddlewares = append(opts.HTTPMiddlewares, middlewares...)
+	}
+}
+
+// httpClient returns the HTTP client wrapped with the middlewares,
+// or nil if neither is set.
+func (o Options) httpClient() HTTPClient {
+	if len(o.HTTPMiddlewares) == 0 {
+		return o.HTTPClient
+	}
+	return llmhttp.WrapDoer(o.HTTPClient, o.HTTPMiddlewares...)
+}
+
 // HTTPClient is primarily used to describe an [*http.Client], but also
 // supports custom implementations.
 //
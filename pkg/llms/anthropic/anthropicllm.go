@@ -5,17 +5,22 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/bedrock"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
 	"github.com/anthropics/anthropic-sdk-go/shared/constant"
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
+	"github.com/effective-security/gogentic/pkg/llmutils"
 	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/effective-security/x/values"
 )
@@ -35,6 +40,8 @@ const (
 	RoleSystem    = "system"
 
 	DefaultMaxTokens = 4096
+	// MinThinkingBudget is the minimal budget of the extended thinking tokens.
+	MinThinkingBudget = 1024
 )
 
 // modelIsOlderThan4_6 returns true for models that are 4.5-era or older (e.g. claude-sonnet-4-5,
@@ -153,9 +160,14 @@ func NewBedrock(opts ...Option) (*LLM, error) {
 }
 
 func newClient(options *Options) (*anthropic.Client, error) {
+	if len(options.HTTPMiddlewares) > 0 {
+		// the wrapped client is also used by the Files API calls
+		options.HttpClient = llmhttp.WrapDoer(options.HttpClient, options.HTTPMiddlewares...)
+	}
+
 	// Build SDK options
 	sdkOpts := []option.RequestOption{
-		option.WithMaxRetries(2),
+		option.WithMaxRetries(maxRetries),
 		option.WithRequestTimeout(5 * time.Minute),
 	}
 	if options.AWSCfg != nil {
@@ -236,6 +248,18 @@ func (o *LLM) GenerateContent(ctx context.Context, messages []llms.Message, opti
 // messages, converts tools to the Anthropic format, and handles both streaming
 // and non-streaming responses.
 func GenerateMessagesContent(ctx context.Context, o *LLM, messages []llms.Message, opts *llms.CallOptions) (*llms.ContentResponse, error) {
+	// the large images are rejected by the API, the message indexes are kept
+	messages, _ = llmutils.FitImages(messages, llmutils.ImagePolicyFor(o.GetProviderType()))
+
+	// The breakpoints reference the original message indexes,
+	// so the history is compacted only without the message breakpoints.
+	if !hasMessagePartBreakpoints(opts.PromptCachePolicy) {
+		messages, _ = llmutils.CompactHistory(messages, &llmutils.CompactOptions{Provider: o.GetProviderType()})
+	} else {
+		// remapping of the tool call IDs keeps the message indexes
+		messages, _ = llmutils.RemapToolCallIDs(messages, llmutils.ToolCallIDFormatFor(o.GetProviderType()))
+	}
+
 	// Keep system blocks separate (Anthropic top-level `system`) and track original
 	// message/part -> Anthropic block locations so explicit prompt-cache breakpoints
 	// can be applied later.
@@ -243,6 +267,10 @@ func GenerateMessagesContent(ctx context.Context, o *LLM, messages []llms.Messag
 	if err != nil {
 		return nil, errors.Wrap(err, "anthropic: failed to process messages")
 	}
+	sdkMessages, repair := normalizeMessageOrder(sdkMessages, partLocations)
+	if repair.Changed() && opts.MessageOrderRepairFunc != nil {
+		opts.MessageOrderRepairFunc(ctx, repair)
+	}
 
 	tools := ToTools(opts.Tools, opts.Model)
 
@@ -258,17 +286,12 @@ func GenerateMessagesContent(ctx context.Context, o *LLM, messages []llms.Messag
 		}
 	}
 
-	reasoningTokens := int64(0)
-	switch opts.ReasoningEffort {
-	case llms.ReasoningEffortLow:
-		reasoningTokens = 1000
-	case llms.ReasoningEffortMedium:
-		reasoningTokens = 5000
-	case llms.ReasoningEffortHigh:
-		reasoningTokens = 10000
-	}
-
+	reasoningTokens := thinkingBudget(opts)
 	if reasoningTokens > 0 {
+		// max_tokens includes the thinking tokens, and must be greater than the budget
+		if params.MaxTokens <= reasoningTokens {
+			params.MaxTokens = reasoningTokens + DefaultMaxTokens
+		}
 		params.Thinking = anthropic.ThinkingConfigParamUnion{
 			OfEnabled: &anthropic.ThinkingConfigEnabledParam{
 				BudgetTokens: reasoningTokens,
@@ -280,7 +303,8 @@ func GenerateMessagesContent(ctx context.Context, o *LLM, messages []llms.Messag
 		params.System = systemBlocks
 	}
 
-	if opts.Temperature > 0 {
+	// the temperature is not compatible with the extended thinking
+	if opts.Temperature > 0 && reasoningTokens == 0 {
 		params.Temperature = anthropic.Float(opts.Temperature)
 	}
 
@@ -294,12 +318,24 @@ func GenerateMessagesContent(ctx context.Context, o *LLM, messages []llms.Messag
 
 	if len(tools) > 0 {
 		params.Tools = tools
+		if opts.ToolChoice != nil || opts.ParallelToolCalls != nil {
+			params.ToolChoice = toToolChoice(opts.ToolChoice, opts.ParallelToolCalls)
+		}
 	}
 
 	requestOpts, err := applyPromptCachePolicyToRequest(o, &params, opts, partLocations)
 	if err != nil {
 		return nil, err
 	}
+	if opts.RateLimitFunc != nil {
+		requestOpts = append(requestOpts, option.WithMiddleware(rateLimitMiddleware(opts.RateLimitFunc)))
+	}
+	if opts.IdempotencyKey != "" {
+		requestOpts = append(requestOpts, option.WithHeader("Idempotency-Key", opts.IdempotencyKey))
+	}
+	if hasFileDocuments(messages) {
+		requestOpts = append(requestOpts, option.WithMiddleware(betaHeaderMiddleware(FilesAPIBeta)))
+	}
 
 	if opts.ResponseFormat != nil {
 		outputConfig := toAnthropicOutputConfig(opts.ResponseFormat, opts.Model)
@@ -316,7 +352,7 @@ func GenerateMessagesContent(ctx context.Context, o *LLM, messages []llms.Messag
 	// Non-streaming message creation
 	result, err := o.Client.Messages.New(ctx, params, requestOpts...)
 	if err != nil {
-		return nil, errors.Wrap(err, "anthropic: failed to create message")
+		return nil, errors.Wrap(o.providerError(err), "anthropic: failed to create message")
 	}
 
 	// Merge all content blocks into a single ContentChoice.
@@ -327,11 +363,16 @@ func GenerateMessagesContent(ctx context.Context, o *LLM, messages []llms.Messag
 
 	var textParts []string
 	var toolCalls []llms.ToolCall
+	var thinking []llms.ThinkingContent
 
 	for _, contentBlock := range result.Content {
 		switch content := contentBlock.AsAny().(type) {
 		case anthropic.TextBlock:
 			textParts = append(textParts, content.Text)
+		case anthropic.ThinkingBlock:
+			thinking = append(thinking, llms.ThinkingContent{Thinking: content.Thinking, Signature: content.Signature})
+		case anthropic.RedactedThinkingBlock:
+			thinking = append(thinking, llms.ThinkingContent{RedactedData: content.Data})
 		case anthropic.ToolUseBlock:
 			argumentsJSON, err := json.Marshal(content.Input)
 			if err != nil {
@@ -386,9 +427,11 @@ func GenerateMessagesContent(ctx context.Context, o *LLM, messages []llms.Messag
 	}
 
 	choice := &llms.ContentChoice{
-		Content:    strings.Join(textParts, ""),
-		ToolCalls:  toolCalls,
-		StopReason: string(result.StopReason),
+		Content:          strings.Join(textParts, ""),
+		ToolCalls:        toolCalls,
+		Thinking:         thinking,
+		ReasoningContent: thinkingText(thinking),
+		StopReason:       string(result.StopReason),
 		Usage: llms.Usage{
 			InputTokens:      uint64(result.Usage.InputTokens),
 			OutputTokens:     uint64(result.Usage.OutputTokens),
@@ -401,6 +444,10 @@ func GenerateMessagesContent(ctx context.Context, o *LLM, messages []llms.Messag
 		},
 	}
 
+	if err := o.refusalError(choice); err != nil {
+		return nil, err
+	}
+
 	resp := &llms.ContentResponse{
 		Choices: []*llms.ContentChoice{choice},
 	}
@@ -427,6 +474,8 @@ func GenerateStreamingContent(ctx context.Context, o *LLM, params anthropic.Mess
 	var content strings.Builder
 	var toolCalls []llms.ToolCall
 	var currentToolCall *llms.ToolCall
+	var thinking []llms.ThinkingContent
+	var currentThinking *llms.ThinkingContent
 	var stopReason string
 	var inputTokens, outputTokens, cacheWriteTokens, cacheReadTokens int64
 
@@ -445,6 +494,10 @@ func GenerateStreamingContent(ctx context.Context, o *LLM, params anthropic.Mess
 						Name: block.Name,
 					},
 				}
+			case anthropic.ThinkingBlock:
+				currentThinking = &llms.ThinkingContent{Thinking: block.Thinking, Signature: block.Signature}
+			case anthropic.RedactedThinkingBlock:
+				currentThinking = &llms.ThinkingContent{RedactedData: block.Data}
 			}
 		case anthropic.ContentBlockDeltaEvent:
 			switch delta := evt.Delta.AsAny().(type) {
@@ -460,12 +513,24 @@ func GenerateStreamingContent(ctx context.Context, o *LLM, params anthropic.Mess
 				if currentToolCall != nil {
 					currentToolCall.FunctionCall.Arguments += delta.PartialJSON
 				}
+			case anthropic.ThinkingDelta:
+				if currentThinking != nil {
+					currentThinking.Thinking += delta.Thinking
+				}
+			case anthropic.SignatureDelta:
+				if currentThinking != nil {
+					currentThinking.Signature += delta.Signature
+				}
 			}
 		case anthropic.ContentBlockStopEvent:
 			if currentToolCall != nil {
 				toolCalls = append(toolCalls, *currentToolCall)
 				currentToolCall = nil
 			}
+			if currentThinking != nil {
+				thinking = append(thinking, *currentThinking)
+				currentThinking = nil
+			}
 		case anthropic.MessageDeltaEvent:
 			stopReason = string(evt.Delta.StopReason)
 			outputTokens = evt.Usage.OutputTokens
@@ -475,15 +540,17 @@ func GenerateStreamingContent(ctx context.Context, o *LLM, params anthropic.Mess
 	}
 
 	if err := stream.Err(); err != nil {
-		return nil, errors.Wrap(err, "anthropic: streaming error")
+		return nil, errors.Wrap(o.providerError(err), "anthropic: streaming error")
 	}
 
 	// Produce a single merged choice (text + tool calls) to match the non-streaming path and
 	// to ensure Choices[0] always carries both Content and ToolCalls when the model emits both.
 	choice := &llms.ContentChoice{
-		Content:    content.String(),
-		ToolCalls:  toolCalls,
-		StopReason: stopReason,
+		Content:          content.String(),
+		ToolCalls:        toolCalls,
+		Thinking:         thinking,
+		ReasoningContent: thinkingText(thinking),
+		StopReason:       stopReason,
 		Usage: llms.Usage{
 			InputTokens:      uint64(inputTokens),
 			OutputTokens:     uint64(outputTokens),
@@ -493,11 +560,57 @@ func GenerateStreamingContent(ctx context.Context, o *LLM, params anthropic.Mess
 		},
 	}
 
+	if err := o.refusalError(choice); err != nil {
+		return nil, err
+	}
+
 	return &llms.ContentResponse{
 		Choices: []*llms.ContentChoice{choice},
 	}, nil
 }
 
+// stopReasonRefusal is the stop reason of the response declined by the safety classifiers
+const stopReasonRefusal = "refusal"
+
+// refusalError returns the ContentFilterError, if the response is declined
+// by the safety classifiers, the partial content is returned as the message.
+func (o *LLM) refusalError(choice *llms.ContentChoice) error {
+	if choice.StopReason != stopReasonRefusal {
+		return nil
+	}
+	return &llms.ContentFilterError{
+		Provider:   o.GetProviderType(),
+		Categories: []string{llms.ContentFilterRefusal},
+		Message:    choice.Content,
+	}
+}
+
+// thinkingBudget returns the extended thinking budget tokens,
+// or zero if the thinking is disabled.
+func thinkingBudget(opts *llms.CallOptions) int64 {
+	if opts.ThinkingBudget > 0 {
+		return max(int64(opts.ThinkingBudget), MinThinkingBudget)
+	}
+	switch opts.ReasoningEffort {
+	case llms.ReasoningEffortLow:
+		return MinThinkingBudget
+	case llms.ReasoningEffortMedium:
+		return 5000
+	case llms.ReasoningEffortHigh:
+		return 10000
+	}
+	return 0
+}
+
+// thinkingText returns the text of the thinking blocks.
+func thinkingText(thinking []llms.ThinkingContent) string {
+	var b strings.Builder
+	for _, t := range thinking {
+		b.WriteString(t.Thinking)
+	}
+	return b.String()
+}
+
 // toAnthropicOutputConfig converts schema.ResponseFormat to Anthropic's OutputConfigParam
 // for structured JSON outputs. Returns nil if the response format is not a valid json_schema.
 func toAnthropicOutputConfig(rf *schema.ResponseFormat, model string) *anthropic.OutputConfigParam {
@@ -561,6 +674,25 @@ func convertToAnthropicSchema(prop *schema.ResponseFormatJSONSchemaProperty) map
 	return result
 }
 
+// toToolChoice converts the tool choice to the Anthropic format,
+// the parallel tool use is disabled if parallel is false.
+func toToolChoice(choice any, parallel *bool) anthropic.ToolChoiceUnionParam {
+	var disableParallel param.Opt[bool]
+	if parallel != nil && !*parallel {
+		disableParallel = anthropic.Bool(true)
+	}
+	switch mode, function := llms.ParseToolChoice(choice); mode {
+	case llms.ToolChoiceNone:
+		return anthropic.ToolChoiceUnionParam{OfNone: &anthropic.ToolChoiceNoneParam{}}
+	case llms.ToolChoiceRequired:
+		return anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{DisableParallelToolUse: disableParallel}}
+	case llms.ToolChoiceFunction:
+		return anthropic.ToolChoiceUnionParam{OfTool: &anthropic.ToolChoiceToolParam{Name: function, DisableParallelToolUse: disableParallel}}
+	default:
+		return anthropic.ToolChoiceUnionParam{OfAuto: &anthropic.ToolChoiceAutoParam{DisableParallelToolUse: disableParallel}}
+	}
+}
+
 // ToTools converts LLM tool definitions to Anthropic SDK tool parameters.
 //
 // This function transforms the generic llms.Tool format into the specific
@@ -643,8 +775,13 @@ func ToTools(tools []llms.Tool, model string) []anthropic.ToolUnionParam {
 //   - Tool message conversion (tool call responses)
 //   - Error handling for unsupported message types
 //
+// The messages are compacted with llmutils.CompactHistory first, to merge the consecutive
+// messages of the same role and drop the duplicate system reminders,
+// then the order is repaired to alternate the user and the assistant messages.
+//
 // Returns the converted messages, extracted system prompt, and any error encountered.
 func ProcessMessages(messages []llms.Message) ([]anthropic.MessageParam, string, error) {
+	messages, _ = llmutils.CompactHistory(messages, &llmutils.CompactOptions{Provider: llms.ProviderAnthropic})
 	chatMessages := make([]anthropic.MessageParam, 0, len(messages))
 	systemPrompt := ""
 	for _, msg := range messages {
@@ -652,7 +789,7 @@ func ProcessMessages(messages []llms.Message) ([]anthropic.MessageParam, string,
 			continue
 		}
 		switch msg.Role {
-		case llms.RoleSystem:
+		case llms.RoleSystem, llms.RoleDeveloper:
 			content, err := HandleSystemMessage(msg)
 			if err != nil {
 				return nil, "", errors.Wrap(err, "anthropic: failed to handle system message")
@@ -684,6 +821,7 @@ func ProcessMessages(messages []llms.Message) ([]anthropic.MessageParam, string,
 			return nil, "", errors.WithMessagef(ErrUnsupportedMessageType, "anthropic: %v", msg.Role)
 		}
 	}
+	chatMessages, _ = normalizeMessageOrder(chatMessages, nil)
 	return chatMessages, systemPrompt, nil
 }
 
@@ -705,6 +843,7 @@ func HandleSystemMessage(msg llms.Message) (string, error) {
 // user message format. It supports:
 //   - Text content
 //   - Image content (PNG, JPEG, GIF, WebP)
+//   - Document content (PDF, plain text, or file uploaded to the Files API)
 //   - Base64 encoding for binary content
 //   - Multiple content parts in a single message
 //
@@ -717,12 +856,25 @@ func HandleHumanMessage(msg llms.Message) (anthropic.MessageParam, error) {
 		case llms.TextContent:
 			contents = append(contents, anthropic.NewTextBlock(p.Text))
 		case llms.BinaryContent:
-			if strings.HasPrefix(p.MIMEType, "image/") {
+			switch {
+			case strings.HasPrefix(p.MIMEType, "image/"):
 				encodedData := base64.StdEncoding.EncodeToString(p.Data)
 				contents = append(contents, anthropic.NewImageBlockBase64(p.MIMEType, encodedData))
-			} else {
+			case p.MIMEType == "application/pdf":
+				block, err := documentBlock(llms.DocumentContent{MIMEType: p.MIMEType, Data: p.Data})
+				if err != nil {
+					return anthropic.MessageParam{}, err
+				}
+				contents = append(contents, block)
+			default:
 				return anthropic.MessageParam{}, errors.Errorf("anthropic: unsupported binary content type: %s", p.MIMEType)
 			}
+		case llms.DocumentContent:
+			block, err := documentBlock(p)
+			if err != nil {
+				return anthropic.MessageParam{}, err
+			}
+			contents = append(contents, block)
 		default:
 			return anthropic.MessageParam{}, errors.Errorf("anthropic: unsupported human message part type: %T", part)
 		}
@@ -742,6 +894,7 @@ func HandleHumanMessage(msg llms.Message) (anthropic.MessageParam, error) {
 //   - Text responses from the assistant
 //   - Tool calls with function names and JSON arguments
 //   - Mixed content (text + tool calls)
+//   - Thinking blocks, which must be passed back with the tool calls
 //
 // Tool call arguments are validated as proper JSON before conversion.
 func HandleAIMessage(msg llms.Message) (anthropic.MessageParam, error) {
@@ -762,6 +915,12 @@ func HandleAIMessage(msg llms.Message) (anthropic.MessageParam, error) {
 			))
 		case llms.TextContent:
 			contents = append(contents, anthropic.NewTextBlock(p.Text))
+		case llms.ThinkingContent:
+			if p.RedactedData != "" {
+				contents = append(contents, anthropic.NewRedactedThinkingBlock(p.RedactedData))
+			} else {
+				contents = append(contents, anthropic.NewThinkingBlock(p.Signature, p.Thinking))
+			}
 		default:
 			return anthropic.MessageParam{}, errors.Errorf("anthropic: unsupported AI message part type: %T", part)
 		}
@@ -803,3 +962,68 @@ func HandleToolMessage(msg llms.Message) (anthropic.MessageParam, error) {
 
 	return anthropic.NewUserMessage(contents...), nil
 }
+
+// maxRetries is the number of the SDK retries.
+const maxRetries = 2
+
+// rateLimitMiddleware reports the rate limited and overloaded responses,
+// which are retried by the SDK with the backoff.
+// The response of the final attempt is not retried, and not reported.
+func rateLimitMiddleware(notify func(context.Context, int, time.Duration)) option.Middleware {
+	sent := 0
+	attempt := 0
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		res, err := next(req)
+		sent++
+		if err == nil && res != nil && sent <= maxRetries &&
+			(res.StatusCode == http.StatusTooManyRequests || res.StatusCode == statusOverloaded) &&
+			res.Header.Get("X-Should-Retry") != "false" {
+			attempt++
+			notify(req.Context(), attempt, retryAfter(res.Header, attempt))
+		}
+		return res, err
+	}
+}
+
+// statusOverloaded is returned by Anthropic API when the service is overloaded.
+const statusOverloaded = 529
+
+// retryAfter returns the wait duration from the response headers,
+// or the SDK exponential backoff estimate.
+func retryAfter(h http.Header, attempt int) time.Duration {
+	if v, err := strconv.ParseFloat(h.Get("Retry-After-Ms"), 64); err == nil {
+		return time.Duration(v * float64(time.Millisecond))
+	}
+	if v, err := strconv.ParseFloat(h.Get("Retry-After"), 64); err == nil {
+		return time.Duration(v * float64(time.Second))
+	}
+	return min(time.Duration(float64(500*time.Millisecond)*math.Pow(2, float64(attempt-1))), 8*time.Second)
+}
+
+// providerError returns the API error classified by the status code and the error type,
+// see llms.ProviderError, the other errors are returned as is.
+func (o *LLM) providerError(err error) error {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	pe := &llms.ProviderError{
+		Provider:   o.GetProviderType(),
+		StatusCode: apiErr.StatusCode,
+		Err:        err,
+	}
+	if apiErr.Response != nil {
+		pe.RetryAfter = llms.RetryAfterHeader(apiErr.Response.Header)
+	}
+	var body struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(apiErr.RawJSON()), &body) == nil {
+		pe.Code = body.Error.Type
+		pe.Message = body.Error.Message
+	}
+	return pe.Classify()
+}
@@ -3,6 +3,7 @@ package anthropic
 import (
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
 )
 
 const (
@@ -14,6 +15,8 @@ type Options struct {
 	Model      string
 	BaseURL    string
 	HttpClient option.HTTPClient
+	// HTTPMiddlewares wrap the HttpClient, see WithHTTPMiddleware.
+	HTTPMiddlewares []llmhttp.Middleware
 
 	// If supplied, the 'anthropic-beta' header will be added to the request with the given value.
 	AnthropicBetaHeader string
@@ -55,6 +58,15 @@ func WithHTTPClient(client option.HTTPClient) Option {
 	}
 }
 
+// WithHTTPMiddleware wraps the HTTP client with the middlewares,
+// for the proxies, the request signing or the extra headers, see llmhttp.Middleware.
+// The first middleware is the outermost one.
+func WithHTTPMiddleware(middlewares ...llmhttp.Middleware) Option {
+	return func(opts *Options) {
+		opts.HTTPMiddlewares = append(opts.HTTPMiddlewares, middlewares...)
+	}
+}
+
 // WithAnthropicBetaHeader adds the Anthropic Beta header to support extended options.
 func WithAnthropicBetaHeader(value string) Option {
 	return func(opts *Options) {
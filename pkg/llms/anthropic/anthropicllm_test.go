@@ -181,6 +181,22 @@ func TestProcessMessages(t *testing.T) {
 			wantSystem:   "You are a helpful assistant.\nAlways be polite and respectful.",
 			wantErr:      false,
 		},
+		{
+			name: "developer message folded into system",
+			messages: []llms.Message{
+				{
+					Role:  llms.RoleSystem,
+					Parts: []llms.ContentPart{llms.TextPart("You are a helpful assistant.")},
+				},
+				{
+					Role:  llms.RoleDeveloper,
+					Parts: []llms.ContentPart{llms.TextPart("Use markdown.")},
+				},
+			},
+			wantMessages: 0,
+			wantSystem:   "You are a helpful assistant.\nUse markdown.",
+			wantErr:      false,
+		},
 		{
 			name: "human message with text",
 			messages: []llms.Message{
@@ -224,7 +240,8 @@ func TestProcessMessages(t *testing.T) {
 					},
 				},
 			},
-			wantMessages: 1,
+			// the placeholder user message is inserted before the assistant message
+			wantMessages: 2,
 			wantSystem:   "",
 			wantErr:      false,
 		},
@@ -253,7 +270,44 @@ func TestProcessMessages(t *testing.T) {
 					Parts: []llms.ContentPart{llms.TextPart("Generic message")},
 				},
 			},
-			wantMessages: 1,
+			// the placeholder user message is inserted before the assistant message
+			wantMessages: 2,
+			wantSystem:   "",
+			wantErr:      false,
+		},
+		{
+			name: "consecutive messages merged",
+			messages: []llms.Message{
+				llms.MessageFromTextParts(llms.RoleSystem, "Be brief."),
+				llms.MessageFromTextParts(llms.RoleHuman, "Hello"),
+				llms.MessageFromTextParts(llms.RoleHuman, "How are you?"),
+				llms.MessageFromTextParts(llms.RoleSystem, "Be brief."),
+				llms.MessageFromTextParts(llms.RoleAI, "Fine"),
+			},
+			wantMessages: 2,
+			wantSystem:   "Be brief.",
+			wantErr:      false,
+		},
+		{
+			name: "tool result and human message merged",
+			messages: []llms.Message{
+				llms.MessageFromTextParts(llms.RoleHuman, "Weather?"),
+				{
+					Role: llms.RoleAI,
+					Parts: []llms.ContentPart{
+						llms.ToolCall{
+							ID:           "call_123",
+							FunctionCall: &llms.FunctionCall{Name: "get_weather", Arguments: `{}`},
+						},
+					},
+				},
+				{
+					Role:  llms.RoleTool,
+					Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "call_123", Content: "sunny"}},
+				},
+				llms.MessageFromTextParts(llms.RoleHuman, "And tomorrow?"),
+			},
+			wantMessages: 3,
 			wantSystem:   "",
 			wantErr:      false,
 		},
@@ -497,15 +551,46 @@ func TestHandleHumanMessage(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "unsupported binary type",
+			name: "pdf binary",
 			msg: llms.Message{
 				Parts: []llms.ContentPart{
 					llms.BinaryPart("application/pdf", []byte("pdf-data")),
 				},
 			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported binary type",
+			msg: llms.Message{
+				Parts: []llms.ContentPart{
+					llms.BinaryPart("application/zip", []byte("zip-data")),
+				},
+			},
 			wantErr:     true,
 			errContains: "unsupported binary content type",
 		},
+		{
+			name: "documents",
+			msg: llms.Message{
+				Parts: []llms.ContentPart{
+					llms.TextPart("Summarize the contracts."),
+					llms.DocumentPart("application/pdf", []byte("pdf-data"), "contract.pdf"),
+					llms.DocumentPart("text/plain", []byte("Terms and conditions"), ""),
+					llms.FileDocumentPart("file_01", "amendment.pdf"),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported document type",
+			msg: llms.Message{
+				Parts: []llms.ContentPart{
+					llms.DocumentPart("application/msword", []byte("doc-data"), ""),
+				},
+			},
+			wantErr:     true,
+			errContains: "unsupported document content type",
+		},
 		{
 			name: "empty parts",
 			msg: llms.Message{
@@ -563,6 +648,23 @@ func TestHandleAIMessage(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "thinking with tool call",
+			msg: llms.Message{
+				Parts: []llms.ContentPart{
+					llms.ThinkingContent{Thinking: "Need the weather", Signature: "sig"},
+					llms.ThinkingContent{RedactedData: "encrypted"},
+					llms.ToolCall{
+						ID: "call_123",
+						FunctionCall: &llms.FunctionCall{
+							Name:      "get_weather",
+							Arguments: `{"location": "Boston"}`,
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid JSON in tool call",
 			msg: llms.Message{
@@ -0,0 +1,232 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+const (
+	// FilesAPIBeta is the anthropic-beta token required by the Files API,
+	// and by the messages referencing the uploaded files.
+	FilesAPIBeta = "files-api-2025-04-14"
+
+	apiVersion = "2023-06-01"
+)
+
+// ErrFilesUnsupported is returned when the Files API is used with Bedrock.
+var ErrFilesUnsupported = errors.New("anthropic: files API is not supported for this provider")
+
+// File is the file uploaded to Anthropic Files API.
+type File struct {
+	ID           string    `json:"id"`
+	Filename     string    `json:"filename"`
+	MIMEType     string    `json:"mime_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+	Downloadable bool      `json:"downloadable"`
+}
+
+type fileList struct {
+	Data    []File `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// UploadFile uploads the file, for example PDF, to use it in the messages
+// with llms.FileDocumentPart and the returned file ID.
+func (o *LLM) UploadFile(ctx context.Context, name, mimeType string, content io.Reader) (*File, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	if mimeType != "" {
+		h.Set("Content-Type", mimeType)
+	}
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return nil, errors.Wrap(err, "create form file")
+	}
+	if _, err = io.Copy(part, content); err != nil {
+		return nil, errors.Wrap(err, "write form file")
+	}
+	if err = w.Close(); err != nil {
+		return nil, errors.Wrap(err, "close form")
+	}
+
+	var file File
+	if err = o.doFiles(ctx, http.MethodPost, "/v1/files", w.FormDataContentType(), &buf, &file); err != nil {
+		return nil, errors.WithMessage(err, "anthropic: failed to upload file")
+	}
+	return &file, nil
+}
+
+// ListFiles returns the uploaded files.
+func (o *LLM) ListFiles(ctx context.Context) ([]File, error) {
+	var files []File
+	after := ""
+	for {
+		suffix := "/v1/files?limit=1000"
+		if after != "" {
+			suffix += "&after_id=" + url.QueryEscape(after)
+		}
+		var res fileList
+		if err := o.doFiles(ctx, http.MethodGet, suffix, "", nil, &res); err != nil {
+			return nil, errors.WithMessage(err, "anthropic: failed to list files")
+		}
+		files = append(files, res.Data...)
+		if !res.HasMore || len(res.Data) == 0 {
+			return files, nil
+		}
+		after = res.LastID
+		if after == "" {
+			after = res.Data[len(res.Data)-1].ID
+		}
+	}
+}
+
+// DeleteFile deletes the uploaded file.
+func (o *LLM) DeleteFile(ctx context.Context, fileID string) error {
+	if fileID == "" {
+		return errors.New("invalid argument: fileID is required")
+	}
+	if err := o.doFiles(ctx, http.MethodDelete, "/v1/files/"+url.PathEscape(fileID), "", nil, nil); err != nil {
+		return errors.WithMessage(err, "anthropic: failed to delete file")
+	}
+	return nil
+}
+
+func (o *LLM) doFiles(ctx context.Context, method, suffix, contentType string, body io.Reader, out any) error {
+	if o.Options.AWSCfg != nil {
+		return errors.WithStack(ErrFilesUnsupported)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(o.Options.BaseURL, "/")+suffix, body)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	req.Header.Set("X-Api-Key", o.Options.Token)
+	req.Header.Set("Anthropic-Version", apiVersion)
+	req.Header.Set("Anthropic-Beta", withBetaToken(o.Options.AnthropicBetaHeader, FilesAPIBeta))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	var client option.HTTPClient = http.DefaultClient
+	if o.Options.HttpClient != nil {
+		client = o.Options.HttpClient
+	}
+	r, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send request")
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	if r.StatusCode != http.StatusOK {
+		msg := fmt.Sprintf("API returned unexpected status code: %d", r.StatusCode)
+
+		var errResp errorResponse
+		if err := json.NewDecoder(r.Body).Decode(&errResp); err != nil || errResp.Error.Message == "" {
+			return errors.New(msg) // nolint:goerr113
+		}
+		return errors.Errorf("%s: %s", msg, errResp.Error.Message) // nolint:goerr113
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "decode response")
+	}
+	return nil
+}
+
+// documentBlock converts the document to Anthropic document content block.
+// PDF and plain text documents are supported inline,
+// any document uploaded to the Files API is supported by the file ID.
+func documentBlock(doc llms.DocumentContent) (anthropic.ContentBlockParamUnion, error) {
+	var block anthropic.DocumentBlockParam
+	switch {
+	case doc.FileID != "":
+		// the file source is not modelled by the messages params,
+		// it's sent as is with the files API beta
+		block.SetExtraFields(map[string]any{
+			"source": map[string]any{
+				"type":    "file",
+				"file_id": doc.FileID,
+			},
+		})
+	case doc.MIMEType == "application/pdf":
+		block.Source = anthropic.DocumentBlockParamSourceUnion{
+			OfBase64: &anthropic.Base64PDFSourceParam{Data: base64.StdEncoding.EncodeToString(doc.Data)},
+		}
+	case strings.HasPrefix(doc.MIMEType, "text/"):
+		block.Source = anthropic.DocumentBlockParamSourceUnion{
+			OfText: &anthropic.PlainTextSourceParam{Data: string(doc.Data)},
+		}
+	default:
+		return anthropic.ContentBlockParamUnion{}, errors.Errorf("anthropic: unsupported document content type: %s", doc.MIMEType)
+	}
+	if doc.Title != "" {
+		block.Title = anthropic.String(doc.Title)
+	}
+	return anthropic.ContentBlockParamUnion{OfDocument: &block}, nil
+}
+
+// hasFileDocuments reports whether any message references the file uploaded to the Files API.
+func hasFileDocuments(messages []llms.Message) bool {
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if doc, ok := part.(llms.DocumentContent); ok && doc.FileID != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// betaHeaderMiddleware adds the token to anthropic-beta header of the request,
+// preserving the tokens set by the client or other request options.
+func betaHeaderMiddleware(token string) option.Middleware {
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		req.Header.Set("Anthropic-Beta", withBetaToken(req.Header.Get("Anthropic-Beta"), token))
+		return next(req)
+	}
+}
+
+// withBetaToken appends the token to comma-separated anthropic-beta header value,
+// if not present.
+func withBetaToken(headerValue, token string) string {
+	headerValue = strings.TrimSpace(headerValue)
+	switch {
+	case headerValue == "":
+		return token
+	case containsBetaHeaderToken(headerValue, token):
+		return headerValue
+	default:
+		return headerValue + "," + token
+	}
+}
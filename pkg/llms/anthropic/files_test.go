@@ -0,0 +1,113 @@
+package anthropic_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/anthropic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lock  sync.Mutex
+		calls []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		lock.Unlock()
+		assert.Equal(t, "test-token", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, "2023-06-01", r.Header.Get("Anthropic-Version"))
+		assert.Equal(t, "beta-feature-1,"+anthropic.FilesAPIBeta, r.Header.Get("Anthropic-Beta"))
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/files":
+			f, h, err := r.FormFile("file")
+			assert.NoError(t, err)
+			data, _ := io.ReadAll(f)
+			assert.Equal(t, "contract.pdf", h.Filename)
+			assert.Equal(t, "application/pdf", h.Header.Get("Content-Type"))
+			assert.Equal(t, "%PDF-1.7", string(data))
+			_, _ = w.Write([]byte(`{"id":"file_01","type":"file","filename":"contract.pdf","mime_type":"application/pdf",
+				"size_bytes":8,"created_at":"2025-04-14T10:00:00Z","downloadable":false}`))
+		case "GET /v1/files":
+			if r.URL.Query().Get("after_id") == "" {
+				_, _ = w.Write([]byte(`{"data":[{"id":"file_01"}],"has_more":true,"last_id":"file_01"}`))
+				return
+			}
+			assert.Equal(t, "file_01", r.URL.Query().Get("after_id"))
+			_, _ = w.Write([]byte(`{"data":[{"id":"file_02"}],"has_more":false}`))
+		case "DELETE /v1/files/file_01":
+			_, _ = w.Write([]byte(`{"id":"file_01","type":"file_deleted"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"not_found_error","message":"File not found"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	llm, err := anthropic.New(
+		anthropic.WithToken("test-token"),
+		anthropic.WithModel("claude-sonnet-4-5"),
+		anthropic.WithBaseURL(srv.URL),
+		anthropic.WithAnthropicBetaHeader("beta-feature-1"),
+	)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	file, err := llm.UploadFile(ctx, "contract.pdf", "application/pdf", strings.NewReader("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Equal(t, "file_01", file.ID)
+	assert.Equal(t, int64(8), file.SizeBytes)
+	assert.Equal(t, 2025, file.CreatedAt.Year())
+
+	files, err := llm.ListFiles(ctx)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "file_02", files[1].ID)
+
+	require.NoError(t, llm.DeleteFile(ctx, file.ID))
+
+	err = llm.DeleteFile(ctx, "file_404")
+	assert.EqualError(t, err, "anthropic: failed to delete file: API returned unexpected status code: 404: File not found")
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{
+		"POST /v1/files", "GET /v1/files", "GET /v1/files", "DELETE /v1/files/file_01", "DELETE /v1/files/file_404",
+	}, calls)
+}
+
+func TestHandleHumanMessage_Documents(t *testing.T) {
+	t.Parallel()
+
+	msg, err := anthropic.HandleHumanMessage(llms.Message{
+		Role: llms.RoleHuman,
+		Parts: []llms.ContentPart{
+			llms.DocumentPart("application/pdf", []byte("%PDF-1.7"), "contract.pdf"),
+			llms.FileDocumentPart("file_01", ""),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, msg.Content, 2)
+
+	js, err := json.Marshal(msg.Content[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"document","title":"contract.pdf",
+		"source":{"type":"base64","media_type":"application/pdf","data":"JVBERi0xLjc="}}`, string(js))
+
+	js, err = json.Marshal(msg.Content[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"document","source":{"type":"file","file_id":"file_01"}}`, string(js))
+}
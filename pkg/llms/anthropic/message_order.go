@@ -0,0 +1,63 @@
+package anthropic
+
+import (
+	sdkanthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// normalizeMessageOrder repairs the order of the messages rejected by Anthropic,
+// instead of failing the request:
+//   - the adjacent messages with the same role, such as the tool results followed by the user message,
+//     are merged into one message;
+//   - the placeholder user message is inserted if the conversation starts with the assistant message.
+//
+// The locations of the message parts are updated in-place to the repaired messages,
+// locations can be nil.
+func normalizeMessageOrder(msgs []sdkanthropic.MessageParam,
+	locations map[promptCachePartKey]promptCachePartLocation,
+) ([]sdkanthropic.MessageParam, llms.MessageOrderRepair) {
+	var repair llms.MessageOrderRepair
+	if len(msgs) == 0 {
+		return msgs, repair
+	}
+
+	res := make([]sdkanthropic.MessageParam, 0, len(msgs)+1)
+	if msgs[0].Role == sdkanthropic.MessageParamRoleAssistant {
+		res = append(res, sdkanthropic.NewUserMessage(sdkanthropic.NewTextBlock(llms.MessageOrderPlaceholder)))
+		repair.InsertedMessages++
+	}
+
+	// newIndex maps the original message index to the repaired message index and the content offset
+	type newIndex struct {
+		message int
+		offset  int
+	}
+	moved := make([]newIndex, len(msgs))
+	for i, msg := range msgs {
+		if len(res) > 0 && res[len(res)-1].Role == msg.Role {
+			prev := &res[len(res)-1]
+			moved[i] = newIndex{message: len(res) - 1, offset: len(prev.Content)}
+			content := make([]sdkanthropic.ContentBlockParamUnion, 0, len(prev.Content)+len(msg.Content))
+			prev.Content = append(append(content, prev.Content...), msg.Content...)
+			repair.MergedMessages++
+			continue
+		}
+		moved[i] = newIndex{message: len(res)}
+		res = append(res, msg)
+	}
+
+	if !repair.Changed() {
+		return msgs, repair
+	}
+
+	for key, loc := range locations {
+		if loc.IsSystem || loc.MessageIndex < 0 || loc.MessageIndex >= len(moved) {
+			continue
+		}
+		m := moved[loc.MessageIndex]
+		loc.MessageIndex = m.message
+		loc.ContentIndex += m.offset
+		locations[key] = loc
+	}
+	return res, repair
+}
@@ -0,0 +1,72 @@
+package anthropic
+
+import (
+	"testing"
+
+	sdkanthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeMessageOrder(t *testing.T) {
+	t.Parallel()
+
+	messages := []llms.Message{
+		llms.MessageFromTextParts(llms.RoleSystem, "system"),
+		{
+			Role: llms.RoleAI,
+			Parts: []llms.ContentPart{llms.ToolCall{
+				ID:           "call1",
+				Type:         "function",
+				FunctionCall: &llms.FunctionCall{Name: "search", Arguments: `{}`},
+			}},
+		},
+		{
+			Role:  llms.RoleTool,
+			Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "call1", Name: "search", Content: "result"}},
+		},
+		llms.MessageFromTextParts(llms.RoleHuman, "hello"),
+	}
+
+	chatMessages, _, partLocations, err := processMessagesForRequest(messages)
+	require.NoError(t, err)
+	require.Len(t, chatMessages, 3)
+
+	chatMessages, repair := normalizeMessageOrder(chatMessages, partLocations)
+	assert.Equal(t, llms.MessageOrderRepair{MergedMessages: 1, InsertedMessages: 1}, repair)
+	assert.True(t, repair.Changed())
+
+	require.Len(t, chatMessages, 3)
+	assert.Equal(t, sdkanthropic.MessageParamRoleUser, chatMessages[0].Role)
+	require.Len(t, chatMessages[0].Content, 1)
+	require.NotNil(t, chatMessages[0].Content[0].OfText)
+	assert.Equal(t, llms.MessageOrderPlaceholder, chatMessages[0].Content[0].OfText.Text)
+
+	assert.Equal(t, sdkanthropic.MessageParamRoleAssistant, chatMessages[1].Role)
+
+	assert.Equal(t, sdkanthropic.MessageParamRoleUser, chatMessages[2].Role)
+	require.Len(t, chatMessages[2].Content, 2)
+	assert.NotNil(t, chatMessages[2].Content[0].OfToolResult)
+	require.NotNil(t, chatMessages[2].Content[1].OfText)
+	assert.Equal(t, "hello", chatMessages[2].Content[1].OfText.Text)
+
+	// the locations follow the repaired messages
+	loc := partLocations[promptCachePartKey{MessageIndex: 0, PartIndex: 0}]
+	assert.True(t, loc.IsSystem)
+	loc = partLocations[promptCachePartKey{MessageIndex: 1, PartIndex: 0}]
+	assert.Equal(t, promptCachePartLocation{MessageIndex: 1, ContentIndex: 0}, loc)
+	loc = partLocations[promptCachePartKey{MessageIndex: 2, PartIndex: 0}]
+	assert.Equal(t, promptCachePartLocation{MessageIndex: 2, ContentIndex: 0}, loc)
+	loc = partLocations[promptCachePartKey{MessageIndex: 3, PartIndex: 0}]
+	assert.Equal(t, promptCachePartLocation{MessageIndex: 2, ContentIndex: 1}, loc)
+
+	// the valid order is returned as is
+	valid := []sdkanthropic.MessageParam{
+		sdkanthropic.NewUserMessage(sdkanthropic.NewTextBlock("hello")),
+		sdkanthropic.NewAssistantMessage(sdkanthropic.NewTextBlock("hi")),
+	}
+	res, repair := normalizeMessageOrder(valid, nil)
+	assert.False(t, repair.Changed())
+	assert.Equal(t, valid, res)
+}
@@ -44,6 +44,19 @@ type promptCacheTargetDedupKey struct {
 	ToolIndex    int
 }
 
+// hasMessagePartBreakpoints returns true if the policy has the breakpoints on the message parts.
+func hasMessagePartBreakpoints(policy *llms.PromptCachePolicy) bool {
+	if policy == nil {
+		return false
+	}
+	for _, bp := range policy.Breakpoints {
+		if bp.Target.Kind == llms.PromptCacheTargetMessagePart {
+			return true
+		}
+	}
+	return false
+}
+
 // processMessagesForRequest converts gogentic messages into Anthropic request params while also
 // returning a reverse lookup map from original message/part indexes to Anthropic block indexes.
 func processMessagesForRequest(messages []llms.Message) ([]sdkanthropic.MessageParam, []sdkanthropic.TextBlockParam,
@@ -59,7 +72,7 @@ func processMessagesForRequest(messages []llms.Message) ([]sdkanthropic.MessageP
 		}
 
 		switch msg.Role {
-		case llms.RoleSystem:
+		case llms.RoleSystem, llms.RoleDeveloper:
 			for partIndex, part := range msg.Parts {
 				text, err := HandleSystemMessage(llms.Message{
 					Parts: []llms.ContentPart{part},
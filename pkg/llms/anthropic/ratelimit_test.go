@@ -0,0 +1,96 @@
+package anthropic_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/anthropic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitFunc(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After-Ms", "10")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"rate limited"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"id": "msg_1",
+			"type": "message",
+			"role": "assistant",
+			"model": "claude-sonnet-4-5",
+			"content": [{"type": "text", "text": "Hello"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 10, "output_tokens": 2}
+		}`))
+	}))
+	defer srv.Close()
+
+	llm, err := anthropic.New(
+		anthropic.WithToken("test-token"),
+		anthropic.WithModel("claude-sonnet-4-5"),
+		anthropic.WithBaseURL(srv.URL),
+	)
+	require.NoError(t, err)
+
+	type event struct {
+		attempt int
+		wait    time.Duration
+	}
+	var events []event
+	resp, err := llm.GenerateContent(context.Background(),
+		[]llms.Message{llms.MessageFromTextParts(llms.RoleHuman, "Hi")},
+		llms.WithRateLimitFunc(func(_ context.Context, attempt int, wait time.Duration) {
+			events = append(events, event{attempt: attempt, wait: wait})
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", resp.Choices[0].Content)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, []event{{attempt: 1, wait: 10 * time.Millisecond}}, events)
+}
+
+func TestRateLimitFunc_FinalAttempt(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After-Ms", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"rate limited"}}`))
+	}))
+	defer srv.Close()
+
+	llm, err := anthropic.New(
+		anthropic.WithToken("test-token"),
+		anthropic.WithModel("claude-sonnet-4-5"),
+		anthropic.WithBaseURL(srv.URL),
+	)
+	require.NoError(t, err)
+
+	var attempts []int
+	_, err = llm.GenerateContent(context.Background(),
+		[]llms.Message{llms.MessageFromTextParts(llms.RoleHuman, "Hi")},
+		llms.WithRateLimitFunc(func(_ context.Context, attempt int, _ time.Duration) {
+			attempts = append(attempts, attempt)
+		}),
+	)
+	require.Error(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+	// the final attempt is not retried
+	assert.Equal(t, []int{1, 2}, attempts)
+}
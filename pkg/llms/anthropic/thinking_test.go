@@ -0,0 +1,134 @@
+package anthropic_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/anthropic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newThinkingServer(t *testing.T, requests *[]map[string]any, body func(stream bool) string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		*requests = append(*requests, req)
+
+		stream, _ := req["stream"].(bool)
+		if stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		_, _ = w.Write([]byte(body(stream)))
+	}))
+}
+
+const thinkingResponse = `{
+	"id": "msg_1",
+	"type": "message",
+	"role": "assistant",
+	"model": "claude-sonnet-4-5",
+	"content": [
+		{"type": "thinking", "thinking": "Let me check the weather.", "signature": "sig1"},
+		{"type": "redacted_thinking", "data": "encrypted"},
+		{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"location": "Boston"}}
+	],
+	"stop_reason": "tool_use",
+	"usage": {"input_tokens": 10, "output_tokens": 20}
+}`
+
+const thinkingStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"stop_reason":null,"usage":{"input_tokens":10,"output_tokens":0}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"think."}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig1"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Sunny"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":20}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func TestThinking(t *testing.T) {
+	t.Parallel()
+
+	var requests []map[string]any
+	srv := newThinkingServer(t, &requests, func(stream bool) string {
+		if stream {
+			return thinkingStream
+		}
+		return thinkingResponse
+	})
+	defer srv.Close()
+
+	llm, err := anthropic.New(
+		anthropic.WithToken("test-token"),
+		anthropic.WithModel("claude-sonnet-4-5"),
+		anthropic.WithBaseURL(srv.URL),
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	messages := []llms.Message{llms.MessageFromTextParts(llms.RoleHuman, "What is the weather in Boston?")}
+
+	resp, err := llm.GenerateContent(ctx, messages,
+		llms.WithThinkingBudget(2000),
+		llms.WithMaxTokens(1000),
+		llms.WithTemperature(0.5),
+	)
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, map[string]any{"type": "enabled", "budget_tokens": float64(2000)}, requests[0]["thinking"])
+	assert.Equal(t, float64(2000+anthropic.DefaultMaxTokens), requests[0]["max_tokens"])
+	assert.NotContains(t, requests[0], "temperature")
+
+	choice := resp.Choices[0]
+	assert.Equal(t, []llms.ThinkingContent{
+		{Thinking: "Let me check the weather.", Signature: "sig1"},
+		{RedactedData: "encrypted"},
+	}, choice.Thinking)
+	assert.Equal(t, "Let me check the weather.", choice.ReasoningContent)
+	require.Len(t, choice.ToolCalls, 1)
+
+	resp, err = llm.GenerateContent(ctx, messages,
+		llms.WithReasoningEffort(llms.ReasoningEffortLow),
+		llms.WithStreamingFunc(func(_ context.Context, _ []byte) error { return nil }),
+	)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Equal(t, map[string]any{"type": "enabled", "budget_tokens": float64(anthropic.MinThinkingBudget)}, requests[1]["thinking"])
+
+	choice = resp.Choices[0]
+	assert.Equal(t, "Sunny", choice.Content)
+	assert.Equal(t, []llms.ThinkingContent{{Thinking: "Let me think.", Signature: "sig1"}}, choice.Thinking)
+	assert.Equal(t, "Let me think.", choice.ReasoningContent)
+}
@@ -0,0 +1,177 @@
+package llms
+
+import (
+	"context"
+	"time"
+)
+
+// Transcriber is an interface for models that can transcribe the speech to text.
+// Callers obtain a Transcriber by type-asserting against a concrete provider's *LLM,
+// the providers advertise it with CapabilityAudioTranscription.
+type Transcriber interface {
+	// Transcribe returns the text of the speech in the audio,
+	// the MIME type of the audio, such as audio/mpeg or audio/wav, must be set.
+	Transcribe(ctx context.Context, audio BinaryContent, options ...TranscribeOption) (*Transcription, error)
+}
+
+// Transcription is the result of Transcribe.
+type Transcription struct {
+	// Text is the transcribed text.
+	Text string `json:"text"`
+	// Language is the detected language of the speech, if reported by the provider.
+	Language string `json:"language,omitempty"`
+	// Duration is the duration of the audio, if reported by the provider.
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// TranscribeOptions is a set of options for Transcribe.
+type TranscribeOptions struct {
+	// Model is the transcription model to use, if empty the provider's default model is used.
+	Model string `json:"model,omitempty"`
+	// Language is the ISO-639-1 language of the speech, improves the accuracy and the latency.
+	Language string `json:"language,omitempty"`
+	// Prompt is the text to guide the style, or to continue the previous segment.
+	Prompt string `json:"prompt,omitempty"`
+	// Temperature is the sampling temperature, between 0 and 1.
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+// TranscribeOption is a function that configures TranscribeOptions.
+type TranscribeOption func(*TranscribeOptions)
+
+// WithTranscribeModel specifies the transcription model to use.
+func WithTranscribeModel(model string) TranscribeOption {
+	return func(o *TranscribeOptions) {
+		o.Model = model
+	}
+}
+
+// WithTranscribeLanguage specifies the language of the speech.
+func WithTranscribeLanguage(language string) TranscribeOption {
+	return func(o *TranscribeOptions) {
+		o.Language = language
+	}
+}
+
+// WithTranscribePrompt specifies the text to guide the transcription.
+func WithTranscribePrompt(prompt string) TranscribeOption {
+	return func(o *TranscribeOptions) {
+		o.Prompt = prompt
+	}
+}
+
+// WithTranscribeTemperature specifies the sampling temperature.
+func WithTranscribeTemperature(temperature float64) TranscribeOption {
+	return func(o *TranscribeOptions) {
+		o.Temperature = temperature
+	}
+}
+
+// NewTranscribeOptions returns TranscribeOptions with the options applied.
+func NewTranscribeOptions(options ...TranscribeOption) *TranscribeOptions {
+	opts := &TranscribeOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	return opts
+}
+
+// Speech is an interface for models that can synthesize the speech from text.
+// Callers obtain a Speech by type-asserting against a concrete provider's *LLM.
+type Speech interface {
+	// GenerateSpeech returns the audio of the text.
+	GenerateSpeech(ctx context.Context, text string, options ...SpeechOption) (*BinaryContent, error)
+}
+
+// SpeechFormat is the format of the generated audio.
+type SpeechFormat string
+
+const (
+	SpeechFormatMP3  SpeechFormat = "mp3"
+	SpeechFormatOpus SpeechFormat = "opus"
+	SpeechFormatAAC  SpeechFormat = "aac"
+	SpeechFormatFLAC SpeechFormat = "flac"
+	SpeechFormatWAV  SpeechFormat = "wav"
+	SpeechFormatPCM  SpeechFormat = "pcm"
+)
+
+// MIMEType returns the MIME type of the audio format.
+func (f SpeechFormat) MIMEType() string {
+	switch f {
+	case SpeechFormatOpus:
+		return "audio/ogg"
+	case SpeechFormatAAC:
+		return "audio/aac"
+	case SpeechFormatFLAC:
+		return "audio/flac"
+	case SpeechFormatWAV:
+		return "audio/wav"
+	case SpeechFormatPCM:
+		return "audio/pcm"
+	}
+	return "audio/mpeg"
+}
+
+// SpeechOptions is a set of options for GenerateSpeech.
+type SpeechOptions struct {
+	// Model is the speech model to use, if empty the provider's default model is used.
+	Model string `json:"model,omitempty"`
+	// Voice is the voice to use, if empty the provider's default voice is used.
+	Voice string `json:"voice,omitempty"`
+	// Format is the format of the audio, default is mp3.
+	Format SpeechFormat `json:"format,omitempty"`
+	// Speed is the speed of the speech, 1.0 is the normal speed.
+	Speed float64 `json:"speed,omitempty"`
+	// Instructions control the tone and the style of the speech,
+	// ignored by the models that do not support it.
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// SpeechOption is a function that configures SpeechOptions.
+type SpeechOption func(*SpeechOptions)
+
+// WithSpeechModel specifies the speech model to use.
+func WithSpeechModel(model string) SpeechOption {
+	return func(o *SpeechOptions) {
+		o.Model = model
+	}
+}
+
+// WithVoice specifies the voice to use.
+func WithVoice(voice string) SpeechOption {
+	return func(o *SpeechOptions) {
+		o.Voice = voice
+	}
+}
+
+// WithSpeechFormat specifies the format of the audio.
+func WithSpeechFormat(format SpeechFormat) SpeechOption {
+	return func(o *SpeechOptions) {
+		o.Format = format
+	}
+}
+
+// WithSpeechSpeed specifies the speed of the speech.
+func WithSpeechSpeed(speed float64) SpeechOption {
+	return func(o *SpeechOptions) {
+		o.Speed = speed
+	}
+}
+
+// WithSpeechInstructions specifies the tone and the style of the speech.
+func WithSpeechInstructions(instructions string) SpeechOption {
+	return func(o *SpeechOptions) {
+		o.Instructions = instructions
+	}
+}
+
+// NewSpeechOptions returns SpeechOptions with the options applied.
+func NewSpeechOptions(options ...SpeechOption) *SpeechOptions {
+	opts := &SpeechOptions{
+		Format: SpeechFormatMP3,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+	return opts
+}
@@ -0,0 +1,157 @@
+// Package azureai provides the Azure AI Foundry models, such as Llama, Phi and Mistral,
+// deployed as the serverless APIs (models as a service).
+//
+// The models are served with the Azure AI model inference API, that is close to the OpenAI
+// chat completions API, but is not the Azure OpenAI API shape: there are no deployments
+// in the URL, and the model is selected in the request body.
+// The requests are authenticated with the key, or with the Microsoft Entra ID token.
+package azureai
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai"
+)
+
+const (
+	// Provider is the provider type of the Azure AI LLM.
+	Provider = llms.ProviderAzureAI
+	// TokenEnvVarName is the environment variable with the API key.
+	TokenEnvVarName = "AZURE_AI_API_KEY" //nolint:gosec
+	// EndpointEnvVarName is the environment variable with the endpoint.
+	EndpointEnvVarName = "AZURE_AI_ENDPOINT"
+	// DefaultAPIVersion is the version of the Azure AI model inference API.
+	DefaultAPIVersion = "2024-05-01-preview"
+	// EntraScope is the scope of the Microsoft Entra ID token for the Azure AI services.
+	EntraScope = "https://cognitiveservices.azure.com/.default"
+)
+
+var (
+	// ErrMissingEndpoint is returned when the endpoint is not set.
+	ErrMissingEndpoint = errors.New("missing the Azure AI endpoint, set it in the AZURE_AI_ENDPOINT environment variable")
+	// ErrMissingCredentials is returned when neither the key, nor the Entra ID token is set.
+	ErrMissingCredentials = errors.New("missing the Azure AI key, set it in the AZURE_AI_API_KEY environment variable, or use WithEntraToken")
+)
+
+// TokenFunc returns the Microsoft Entra ID access token for the EntraScope,
+// for example from the azidentity credential.
+// The function is called for each request, and should cache the token until it expires.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Doer performs a HTTP request.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type options struct {
+	apiKey     string
+	endpoint   string
+	model      string
+	apiVersion string
+	token      TokenFunc
+	httpClient Doer
+}
+
+// Option is a functional option for the Azure AI LLM.
+type Option func(*options)
+
+// WithAPIKey passes the key of the endpoint. If not set, the key
+// is read from the AZURE_AI_API_KEY environment variable.
+func WithAPIKey(apiKey string) Option {
+	return func(opts *options) {
+		opts.apiKey = apiKey
+	}
+}
+
+// WithEndpoint sets the endpoint, for example https://<resource>.services.ai.azure.com/models
+// or the serverless endpoint https://<name>.<region>.models.ai.azure.com.
+// If not set, the endpoint is read from the AZURE_AI_ENDPOINT environment variable.
+func WithEndpoint(endpoint string) Option {
+	return func(opts *options) {
+		opts.endpoint = endpoint
+	}
+}
+
+// WithModel sets the model, for example Llama-3.3-70B-Instruct or Phi-4.
+// The serverless endpoint serves one model, and ignores it.
+func WithModel(model string) Option {
+	return func(opts *options) {
+		opts.model = model
+	}
+}
+
+// WithAPIVersion sets the version of the API, by default DefaultAPIVersion.
+func WithAPIVersion(apiVersion string) Option {
+	return func(opts *options) {
+		opts.apiVersion = apiVersion
+	}
+}
+
+// WithEntraToken authenticates the requests with the Microsoft Entra ID token,
+// instead of the key.
+func WithEntraToken(token TokenFunc) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// WithHTTPClient allows setting a custom HTTP client. If not set, the default value
+// is http.DefaultClient.
+func WithHTTPClient(client Doer) Option {
+	return func(opts *options) {
+		opts.httpClient = client
+	}
+}
+
+// New returns the Azure AI LLM.
+func New(opts ...Option) (*openai.LLM, error) {
+	o := &options{
+		apiKey:     os.Getenv(TokenEnvVarName),
+		endpoint:   os.Getenv(EndpointEnvVarName),
+		apiVersion: DefaultAPIVersion,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.endpoint == "" {
+		return nil, ErrMissingEndpoint
+	}
+	if o.apiKey == "" && o.token == nil {
+		return nil, ErrMissingCredentials
+	}
+
+	return openai.New(
+		openai.WithProvider(openai.ProviderAzureAI),
+		openai.WithToken(o.apiKey),
+		openai.WithModel(o.model),
+		openai.WithBaseURL(o.endpoint),
+		openai.WithAPIVersion(o.apiVersion),
+		openai.WithHTTPClient(&authDoer{Doer: o.httpClient, token: o.token}),
+	)
+}
+
+// authDoer is the Doer that sets the Entra ID token,
+// and asks the API to drop the parameters not supported by the model.
+type authDoer struct {
+	Doer
+
+	token TokenFunc
+}
+
+func (d *authDoer) Do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("extra-parameters", "drop")
+	if d.token != nil {
+		token, err := d.token(req.Context())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the Entra ID token")
+		}
+		req.Header.Del("api-key")
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return d.Doer.Do(req)
+}
@@ -0,0 +1,97 @@
+package azureai_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/azureai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Setenv(azureai.TokenEnvVarName, "")
+	t.Setenv(azureai.EndpointEnvVarName, "")
+
+	_, err := azureai.New(azureai.WithAPIKey("test-key"))
+	assert.ErrorIs(t, err, azureai.ErrMissingEndpoint)
+
+	_, err = azureai.New(azureai.WithEndpoint("https://example.services.ai.azure.com/models"))
+	assert.ErrorIs(t, err, azureai.ErrMissingCredentials)
+
+	llm, err := azureai.New(
+		azureai.WithEndpoint("https://example.services.ai.azure.com/models"),
+		azureai.WithAPIKey("test-key"),
+		azureai.WithModel("Phi-4"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "Phi-4", llm.GetName())
+	assert.Equal(t, llms.ProviderAzureAI, llm.GetProviderType())
+}
+
+func TestGenerateContent(t *testing.T) {
+	tcases := []struct {
+		name string
+		opt  azureai.Option
+		auth func(t *testing.T, r *http.Request)
+	}{
+		{
+			name: "key",
+			opt:  azureai.WithAPIKey("test-key"),
+			auth: func(t *testing.T, r *http.Request) {
+				assert.Equal(t, "test-key", r.Header.Get("api-key"))
+				assert.Empty(t, r.Header.Get("Authorization"))
+			},
+		},
+		{
+			name: "entra",
+			opt: azureai.WithEntraToken(func(context.Context) (string, error) {
+				return "test-token", nil
+			}),
+			auth: func(t *testing.T, r *http.Request) {
+				assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+				assert.Empty(t, r.Header.Values("api-key"))
+			},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/models/chat/completions", r.URL.Path)
+				assert.Equal(t, azureai.DefaultAPIVersion, r.URL.Query().Get("api-version"))
+				assert.Equal(t, "drop", r.Header.Get("extra-parameters"))
+				tc.auth(t, r)
+
+				var req map[string]any
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, "Llama-3.3-70B-Instruct", req["model"])
+				assert.Equal(t, 100.0, req["max_tokens"])
+				assert.NotContains(t, req, "max_completion_tokens")
+
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"Llama-3.3-70B-Instruct",
+					"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],
+					"usage":{"prompt_tokens":8,"completion_tokens":2,"total_tokens":10}}`))
+			}))
+			defer srv.Close()
+
+			llm, err := azureai.New(
+				azureai.WithEndpoint(srv.URL+"/models"),
+				azureai.WithModel("Llama-3.3-70B-Instruct"),
+				tc.opt,
+			)
+			require.NoError(t, err)
+
+			resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+				llms.MessageFromTextParts(llms.RoleHuman, "hello"),
+			}, llms.WithMaxTokens(100))
+			require.NoError(t, err)
+			require.Len(t, resp.Choices, 1)
+			assert.Equal(t, "Hello", resp.Choices[0].Content)
+		})
+	}
+}
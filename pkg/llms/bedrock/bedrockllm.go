@@ -3,14 +3,22 @@ package bedrock
 import (
 	"context"
 
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/effective-security/gogentic/pkg/llms/bedrock/internal/bedrockclient"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
+	"github.com/effective-security/gogentic/pkg/llmutils"
 )
 
-const defaultModel = ModelAmazonTitanTextLiteV1
+const (
+	defaultModel      = ModelAmazonTitanTextLiteV1
+	defaultImageModel = ModelAmazonTitanImageGeneratorV2
+)
+
+var _ llms.ImageModel = (*LLM)(nil)
 
 // LLM is a Bedrock LLM implementation.
 type LLM struct {
@@ -50,6 +58,11 @@ func newClient(opts ...Option) (*options, *bedrockclient.Client, error) {
 			options.client = bedrockruntime.NewFromConfig(cfg)
 		}
 	}
+	if len(options.httpMiddlewares) > 0 {
+		options.client = bedrockruntime.New(options.client.Options(), func(o *bedrockruntime.Options) {
+			o.HTTPClient = llmhttp.WrapDoer(o.HTTPClient, options.httpMiddlewares...)
+		})
+	}
 
 	return options, bedrockclient.NewClient(options.client), nil
 }
@@ -73,6 +86,10 @@ func (l *LLM) GenerateContent(ctx context.Context, messages []llms.Message, opti
 		opt(&opts)
 	}
 
+	// the tool call IDs of the conversation started with another provider can be rejected
+	messages, _ = llmutils.RemapToolCallIDs(messages, llmutils.ToolCallIDFormatFor(l.GetProviderType()))
+	// the large images are rejected by the API
+	messages, _ = llmutils.FitImages(messages, llmutils.ImagePolicyFor(l.GetProviderType()))
 	m, err := processMessages(messages)
 	if err != nil {
 		return nil, err
@@ -80,20 +97,62 @@ func (l *LLM) GenerateContent(ctx context.Context, messages []llms.Message, opti
 
 	res, err := l.client.CreateCompletion(ctx, opts.Model, m, opts)
 	if err != nil {
-		return nil, err
+		return nil, l.providerError(err)
 	}
 	return res, nil
 }
 
+// providerError returns the API error classified by the status code and the exception name,
+// for example ThrottlingException or ValidationException, see llms.ProviderError.
+// The other errors are returned as is.
+func (l *LLM) providerError(err error) error {
+	var apiErr interface {
+		ErrorCode() string
+		ErrorMessage() string
+	}
+	var respErr *awshttp.ResponseError
+	hasAPIErr := errors.As(err, &apiErr)
+	if !errors.As(err, &respErr) && !hasAPIErr {
+		return err
+	}
+	pe := &llms.ProviderError{
+		Provider: l.GetProviderType(),
+		Err:      err,
+	}
+	if respErr != nil && respErr.Response != nil {
+		pe.StatusCode = respErr.HTTPStatusCode()
+		pe.RetryAfter = llms.RetryAfterHeader(respErr.Response.Header)
+	}
+	if hasAPIErr {
+		pe.Code = apiErr.ErrorCode()
+		pe.Message = apiErr.ErrorMessage()
+	}
+	return pe.Classify()
+}
+
 // CreateEmbedding creates embeddings for the given input texts.
 func (l *LLM) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
 	return l.client.CreateEmbedding(ctx, l.modelID, texts)
 }
 
+// GenerateImages implements llms.ImageModel with Amazon Titan Image Generator and Stability SDXL models,
+// if the image model is not specified, ModelAmazonTitanImageGeneratorV2 is used.
+func (l *LLM) GenerateImages(ctx context.Context, prompt string, options ...llms.ImageOption) ([]llms.BinaryContent, error) {
+	opts := llms.NewImageOptions(options...)
+	if opts.Model == "" {
+		opts.Model = defaultImageModel
+	}
+	return l.client.CreateImages(ctx, opts.Model, prompt, opts)
+}
+
 func processMessages(messages []llms.Message) ([]bedrockclient.Message, error) {
 	bedrockMsgs := make([]bedrockclient.Message, 0, len(messages))
 
 	for _, m := range messages {
+		if m.Role == llms.RoleDeveloper {
+			// Bedrock models do not have the developer role
+			m.Role = llms.RoleSystem
+		}
 		for _, part := range m.Parts {
 			switch part := part.(type) {
 			case llms.TextContent:
@@ -119,6 +178,9 @@ func processMessages(messages []llms.Message) ([]bedrockclient.Message, error) {
 					ToolName:   part.FunctionCall.Name,
 					ToolInput:  part.FunctionCall.Arguments, // JSON arguments
 				})
+			case llms.ThinkingContent:
+				// the thinking blocks are not supported
+				continue
 			case llms.ToolCallResponse:
 				// Handle tool call responses
 				bedrockMsgs = append(bedrockMsgs, bedrockclient.Message{
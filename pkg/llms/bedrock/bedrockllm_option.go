@@ -3,6 +3,7 @@ package bedrock
 import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
 )
 
 // Option is an option for the Bedrock LLM.
@@ -12,6 +13,8 @@ type options struct {
 	modelID string
 	client  *bedrockruntime.Client
 	awsCfg  *aws.Config
+
+	httpMiddlewares []llmhttp.Middleware
 }
 
 // WithModel allows setting a custom modelId.
@@ -42,3 +45,14 @@ func WithConfig(cfg *aws.Config) Option {
 		opts.awsCfg = cfg
 	}
 }
+
+// WithHTTPMiddleware wraps the HTTP client of the bedrockruntime.Client with the middlewares,
+// for the proxies or the extra headers, see llmhttp.Middleware.
+// The requests are signed before the middlewares are called,
+// so the middlewares must not modify the signed headers.
+// The first middleware is the outermost one.
+func WithHTTPMiddleware(middlewares ...llmhttp.Middleware) Option {
+	return func(opts *options) {
+		opts.httpMiddlewares = append(opts.httpMiddlewares, middlewares...)
+	}
+}
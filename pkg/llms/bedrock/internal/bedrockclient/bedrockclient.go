@@ -96,6 +96,23 @@ func (c *Client) CreateEmbedding(ctx context.Context,
 	}
 }
 
+// CreateImages generates the images from the prompt.
+func (c *Client) CreateImages(ctx context.Context,
+	modelID string,
+	prompt string,
+	options *llms.ImageOptions,
+) ([]llms.BinaryContent, error) {
+	provider := getProvider(modelID)
+	switch provider {
+	case "amazon":
+		return createAmazonImages(ctx, c.client, modelID, prompt, options)
+	case "stability":
+		return createStabilityImages(ctx, c.client, modelID, prompt, options)
+	default:
+		return nil, errors.New("bedrock: unsupported provider for images")
+	}
+}
+
 // Helper function to process input text chat
 // messages as a single string.
 func processInputMessagesGeneric(messages []Message) string {
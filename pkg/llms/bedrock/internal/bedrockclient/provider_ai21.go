@@ -107,7 +107,7 @@ func createAi21Completion(ctx context.Context, client *bedrockruntime.Client, mo
 		ContentType: aws.String("application/json"),
 	}
 
-	resp, err := client.InvokeModel(ctx, &modelInput)
+	resp, err := client.InvokeModel(ctx, &modelInput, rateLimitOptions(ctx, options)...)
 	if err != nil {
 		return nil, err
 	}
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/x/values"
 )
 
 // Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-titan-text.html
@@ -130,7 +131,7 @@ func createAmazonCompletion(ctx context.Context,
 		ContentType: aws.String("application/json"),
 		Body:        body,
 	}
-	resp, err := client.InvokeModel(ctx, modelInput)
+	resp, err := client.InvokeModel(ctx, modelInput, rateLimitOptions(ctx, options)...)
 	if err != nil {
 		return nil, err
 	}
@@ -163,3 +164,86 @@ func createAmazonCompletion(ctx context.Context,
 		Choices: contentChoices,
 	}, nil
 }
+
+// Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-titan-image.html
+
+// amazonImageRequest is the input for the image generation for Amazon Titan Image models.
+type amazonImageRequest struct {
+	TaskType              string                      `json:"taskType"`
+	TextToImageParams     amazonTextToImageParams     `json:"textToImageParams"`
+	ImageGenerationConfig amazonImageGenerationConfig `json:"imageGenerationConfig"`
+}
+
+type amazonTextToImageParams struct {
+	Text         string `json:"text"`
+	NegativeText string `json:"negativeText,omitempty"`
+}
+
+type amazonImageGenerationConfig struct {
+	// The number of images to generate, 1 to 5.
+	NumberOfImages int `json:"numberOfImages"`
+	Height         int `json:"height"`
+	Width          int `json:"width"`
+	// One of: standard, premium
+	Quality string `json:"quality,omitempty"`
+	Seed    int    `json:"seed,omitempty"`
+}
+
+// amazonImageResponse is the output for the image generation for Amazon Titan Image models.
+type amazonImageResponse struct {
+	// The base64 encoded PNG images
+	Images []string `json:"images"`
+	Error  string   `json:"error,omitempty"`
+}
+
+func newAmazonImageRequest(prompt string, options *llms.ImageOptions) *amazonImageRequest {
+	quality := "standard"
+	if options.Quality == llms.ImageQualityHigh {
+		quality = "premium"
+	}
+	return &amazonImageRequest{
+		TaskType: "TEXT_IMAGE",
+		TextToImageParams: amazonTextToImageParams{
+			Text:         prompt,
+			NegativeText: options.NegativePrompt,
+		},
+		ImageGenerationConfig: amazonImageGenerationConfig{
+			NumberOfImages: max(options.Count, 1),
+			Width:          values.NumbersCoalesce(options.Width, defaultImageSize),
+			Height:         values.NumbersCoalesce(options.Height, defaultImageSize),
+			Quality:        quality,
+			Seed:           options.Seed,
+		},
+	}
+}
+
+func createAmazonImages(ctx context.Context,
+	client *bedrockruntime.Client,
+	modelID string,
+	prompt string,
+	options *llms.ImageOptions,
+) ([]llms.BinaryContent, error) {
+	body, err := json.Marshal(newAmazonImageRequest(prompt, options))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	resp, err := client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelID),
+		Body:        body,
+		Accept:      aws.String("application/json"),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var output amazonImageResponse
+	if err := json.Unmarshal(resp.Body, &output); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if output.Error != "" {
+		return nil, errors.Newf("bedrock: %s", output.Error)
+	}
+	return decodePNGImages(output.Images)
+}
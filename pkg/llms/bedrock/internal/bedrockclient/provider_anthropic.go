@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
@@ -93,6 +94,18 @@ type anthropicTextGenerationInput struct {
 	StopSequences []string `json:"stop_sequences,omitempty"`
 	// Tools to use. Optional
 	Tools []anthropicTool `json:"tools,omitempty"`
+	// How the model should use the tools. Optional
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// anthropicToolChoice represents the choice of the tool to use
+type anthropicToolChoice struct {
+	// One of: "auto", "any", "tool", "none"
+	Type string `json:"type"`
+	// Required if type is "tool"
+	Name string `json:"name,omitempty"`
+	// Optional, the model returns at most one tool use if true
+	DisableParallelToolUse bool `json:"disable_parallel_tool_use,omitempty"`
 }
 
 // anthropicTextGenerationOutputContent represents a content block in the output
@@ -162,10 +175,13 @@ func createAnthropicCompletion(ctx context.Context,
 	messages []Message,
 	options llms.CallOptions,
 ) (*llms.ContentResponse, error) {
-	inputContents, systemPrompt, err := processInputMessagesAnthropic(messages)
+	inputContents, systemPrompt, repair, err := processInputMessagesAnthropic(messages)
 	if err != nil {
 		return nil, err
 	}
+	if repair.Changed() && options.MessageOrderRepairFunc != nil {
+		options.MessageOrderRepairFunc(ctx, repair)
+	}
 
 	// Convert tools to Anthropic format
 	var tools []anthropicTool
@@ -203,6 +219,12 @@ func createAnthropicCompletion(ctx context.Context,
 		StopSequences:    options.StopWords,
 		Tools:            tools,
 	}
+	if len(tools) > 0 && (options.ToolChoice != nil || options.ParallelToolCalls != nil) {
+		input.ToolChoice = anthropicToolChoiceFrom(options.ToolChoice)
+		if input.ToolChoice.Type != "none" && options.ParallelToolCalls != nil {
+			input.ToolChoice.DisableParallelToolUse = !*options.ParallelToolCalls
+		}
+	}
 
 	body, err := json.Marshal(input)
 	if err != nil {
@@ -225,7 +247,7 @@ func createAnthropicCompletion(ctx context.Context,
 		ContentType: aws.String("application/json"),
 		Body:        body,
 	}
-	resp, err := client.InvokeModel(ctx, modelInput)
+	resp, err := client.InvokeModel(ctx, modelInput, rateLimitOptions(ctx, options)...)
 	if err != nil {
 		return nil, err
 	}
@@ -345,7 +367,7 @@ type streamingCompletionResponseChunk struct {
 }
 
 func parseStreamingCompletionResponse(ctx context.Context, client *bedrockruntime.Client, modelInput *bedrockruntime.InvokeModelWithResponseStreamInput, options llms.CallOptions) (*llms.ContentResponse, error) {
-	output, err := client.InvokeModelWithResponseStream(ctx, modelInput)
+	output, err := client.InvokeModelWithResponseStream(ctx, modelInput, rateLimitOptions(ctx, options)...)
 	if err != nil {
 		return nil, err
 	}
@@ -395,60 +417,65 @@ func parseStreamingCompletionResponse(ctx context.Context, client *bedrockruntim
 
 // process the input messages to anthropic supported input
 // returns the input content and system prompt.
-func processInputMessagesAnthropic(messages []Message) ([]*anthropicTextGenerationInputMessage, string, error) {
-	chunkedMessages := make([][]Message, 0, len(messages))
-	currentChunk := make([]Message, 0, len(messages))
-	var lastRole llms.Role
+// processInputMessagesAnthropic converts the messages to the Anthropic messages and the system prompt.
+// The adjacent messages with the same Anthropic role, such as the tool results followed by the user message,
+// are merged, and the placeholder user message is inserted if the conversation starts with the assistant message,
+// as Anthropic requires the user and the assistant messages to alternate.
+func processInputMessagesAnthropic(messages []Message) ([]*anthropicTextGenerationInputMessage, string, llms.MessageOrderRepair, error) {
+	var repair llms.MessageOrderRepair
+	var systemPrompts []string
+	inputContents := make([]*anthropicTextGenerationInputMessage, 0, len(messages)+1)
+	var last *anthropicTextGenerationInputMessage
+	var lastMessage Message
 	for _, message := range messages {
-		if message.Role != lastRole {
-			if len(currentChunk) > 0 {
-				chunkedMessages = append(chunkedMessages, currentChunk)
-			}
-			currentChunk = make([]Message, 0, len(messages))
-		}
-		currentChunk = append(currentChunk, message)
-		lastRole = message.Role
-	}
-	if len(currentChunk) > 0 {
-		chunkedMessages = append(chunkedMessages, currentChunk)
-	}
-
-	inputContents := make([]*anthropicTextGenerationInputMessage, 0, len(messages))
-	var systemPrompt string
-	for _, chunk := range chunkedMessages {
-		role, err := getAnthropicRole(chunk[0].Role)
+		role, err := getAnthropicRole(message.Role)
 		if err != nil {
-			return nil, "", err
+			return nil, "", repair, err
 		}
 		if role == AnthropicSystem {
-			if systemPrompt != "" {
-				return nil, "", errors.New("multiple system prompts")
-			}
-			for _, message := range chunk {
-				c := getAnthropicInputContent(message)
-				if c.Type != AnthropicMessageTypeText {
-					return nil, "", errors.New("system prompt must be text")
-				}
-				systemPrompt += c.Text
+			c := getAnthropicInputContent(message)
+			if c.Type != AnthropicMessageTypeText {
+				return nil, "", repair, errors.New("system prompt must be text")
 			}
+			systemPrompts = append(systemPrompts, c.Text)
 			continue
 		}
-		content := make([]anthropicTextGenerationInputContent, 0, len(chunk))
-		for _, message := range chunk {
-			content = append(content, getAnthropicInputContent(message))
+
+		if last == nil && role == AnthropicRoleAssistant {
+			last = &anthropicTextGenerationInputMessage{
+				Role: AnthropicRoleUser,
+				Content: []anthropicTextGenerationInputContent{{
+					Type: AnthropicMessageTypeText,
+					Text: llms.MessageOrderPlaceholder,
+				}},
+			}
+			inputContents = append(inputContents, last)
+			repair.InsertedMessages++
 		}
-		inputContents = append(inputContents, &anthropicTextGenerationInputMessage{
-			Role:    role,
-			Content: content,
-		})
+
+		if last != nil && last.Role == role {
+			// the parts of the messages with the same role are always sent as one message,
+			// only the different roles mapped to the same Anthropic role are counted as merged
+			if message.Role != lastMessage.Role {
+				repair.MergedMessages++
+			}
+			last.Content = append(last.Content, getAnthropicInputContent(message))
+		} else {
+			last = &anthropicTextGenerationInputMessage{
+				Role:    role,
+				Content: []anthropicTextGenerationInputContent{getAnthropicInputContent(message)},
+			}
+			inputContents = append(inputContents, last)
+		}
+		lastMessage = message
 	}
-	return inputContents, systemPrompt, nil
+	return inputContents, strings.Join(systemPrompts, "\n"), repair, nil
 }
 
 // process the role of the message to anthropic supported role.
 func getAnthropicRole(role llms.Role) (string, error) {
 	switch role {
-	case llms.RoleSystem:
+	case llms.RoleSystem, llms.RoleDeveloper:
 		return AnthropicSystem, nil
 
 	case llms.RoleAI:
@@ -505,3 +532,17 @@ func getAnthropicInputContent(message Message) anthropicTextGenerationInputConte
 	}
 	return c
 }
+
+// anthropicToolChoiceFrom converts the tool choice to the Anthropic format
+func anthropicToolChoiceFrom(choice any) *anthropicToolChoice {
+	switch mode, function := llms.ParseToolChoice(choice); mode {
+	case llms.ToolChoiceNone:
+		return &anthropicToolChoice{Type: "none"}
+	case llms.ToolChoiceRequired:
+		return &anthropicToolChoice{Type: "any"}
+	case llms.ToolChoiceFunction:
+		return &anthropicToolChoice{Type: "tool", Name: function}
+	default:
+		return &anthropicToolChoice{Type: "auto"}
+	}
+}
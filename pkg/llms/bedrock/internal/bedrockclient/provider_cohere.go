@@ -132,7 +132,7 @@ func createCohereCompletion(ctx context.Context,
 		ContentType: aws.String("application/json"),
 		Body:        body,
 	}
-	resp, err := client.InvokeModel(ctx, modelInput)
+	resp, err := client.InvokeModel(ctx, modelInput, rateLimitOptions(ctx, options)...)
 	if err != nil {
 		return nil, err
 	}
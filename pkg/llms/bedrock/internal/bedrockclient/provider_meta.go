@@ -71,7 +71,7 @@ func createMetaCompletion(ctx context.Context,
 		Body:        body,
 	}
 
-	resp, err := client.InvokeModel(ctx, modelInput)
+	resp, err := client.InvokeModel(ctx, modelInput, rateLimitOptions(ctx, options)...)
 	if err != nil {
 		return nil, err
 	}
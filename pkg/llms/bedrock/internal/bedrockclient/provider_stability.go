@@ -0,0 +1,127 @@
+package bedrockclient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/x/values"
+)
+
+// Ref: https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-diffusion-1-0-text-image.html
+
+const defaultImageSize = 1024
+
+// stabilityTextPrompt is the prompt for Stability models,
+// the negative weight describes what should not be in the image.
+type stabilityTextPrompt struct {
+	Text   string  `json:"text"`
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// stabilityImageRequest is the input for the image generation for Stability SDXL models.
+type stabilityImageRequest struct {
+	TextPrompts []stabilityTextPrompt `json:"text_prompts"`
+	Height      int                   `json:"height"`
+	Width       int                   `json:"width"`
+	// The number of diffusion steps, 10 to 50. Optional, default = 30
+	Steps int `json:"steps,omitempty"`
+	Seed  int `json:"seed,omitempty"`
+}
+
+// stabilityImageResponse is the output for the image generation for Stability SDXL models.
+type stabilityImageResponse struct {
+	Result    string `json:"result"`
+	Artifacts []struct {
+		Seed int `json:"seed"`
+		// The base64 encoded PNG image
+		Base64 string `json:"base64"`
+		// One of: SUCCESS, ERROR, CONTENT_FILTERED
+		FinishReason string `json:"finishReason"`
+	} `json:"artifacts"`
+}
+
+// Finish reason for the image generation for Stability models.
+const (
+	StabilityFinishReasonSuccess         = "SUCCESS"
+	StabilityFinishReasonContentFiltered = "CONTENT_FILTERED"
+)
+
+func newStabilityImageRequest(prompt string, options *llms.ImageOptions) *stabilityImageRequest {
+	req := &stabilityImageRequest{
+		TextPrompts: []stabilityTextPrompt{{Text: prompt, Weight: 1}},
+		Width:       values.NumbersCoalesce(options.Width, defaultImageSize),
+		Height:      values.NumbersCoalesce(options.Height, defaultImageSize),
+		Seed:        options.Seed,
+	}
+	if options.NegativePrompt != "" {
+		req.TextPrompts = append(req.TextPrompts, stabilityTextPrompt{Text: options.NegativePrompt, Weight: -1})
+	}
+	switch options.Quality {
+	case llms.ImageQualityLow:
+		req.Steps = 15
+	case llms.ImageQualityHigh:
+		req.Steps = 50
+	}
+	return req
+}
+
+func createStabilityImages(ctx context.Context,
+	client *bedrockruntime.Client,
+	modelID string,
+	prompt string,
+	options *llms.ImageOptions,
+) ([]llms.BinaryContent, error) {
+	body, err := json.Marshal(newStabilityImageRequest(prompt, options))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// SDXL generates one image per request
+	var images []string
+	for range max(options.Count, 1) {
+		resp, err := client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+			ModelId:     aws.String(modelID),
+			Body:        body,
+			Accept:      aws.String("application/json"),
+			ContentType: aws.String("application/json"),
+		})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var output stabilityImageResponse
+		if err := json.Unmarshal(resp.Body, &output); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, artifact := range output.Artifacts {
+			if artifact.FinishReason != "" && artifact.FinishReason != StabilityFinishReasonSuccess {
+				return nil, errors.Newf("bedrock: image generation failed: %s", artifact.FinishReason)
+			}
+			images = append(images, artifact.Base64)
+		}
+	}
+	return decodePNGImages(images)
+}
+
+func decodePNGImages(images []string) ([]llms.BinaryContent, error) {
+	if len(images) == 0 {
+		return nil, errors.New("bedrock: empty response")
+	}
+	parts := make([]llms.BinaryContent, 0, len(images))
+	for _, img := range images {
+		data, err := base64.StdEncoding.DecodeString(img)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode image")
+		}
+		parts = append(parts, llms.BinaryContent{
+			MIMEType: "image/png",
+			Data:     data,
+		})
+	}
+	return parts, nil
+}
@@ -1,9 +1,13 @@
 package bedrockclient
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"testing"
 
+	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetProvider(t *testing.T) {
@@ -61,3 +65,92 @@ func TestGetProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestImageRequests(t *testing.T) {
+	opts := llms.NewImageOptions(
+		llms.WithImageSize(512, 768),
+		llms.WithImageQuality(llms.ImageQualityHigh),
+		llms.WithNegativePrompt("blurry"),
+		llms.WithImageSeed(42),
+		llms.WithImageCount(2),
+	)
+
+	js, err := json.Marshal(newAmazonImageRequest("a gopher", opts))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"taskType": "TEXT_IMAGE",
+		"textToImageParams": {"text": "a gopher", "negativeText": "blurry"},
+		"imageGenerationConfig": {"numberOfImages": 2, "height": 768, "width": 512, "quality": "premium", "seed": 42}
+	}`, string(js))
+
+	js, err = json.Marshal(newStabilityImageRequest("a gopher", opts))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"text_prompts": [{"text": "a gopher", "weight": 1}, {"text": "blurry", "weight": -1}],
+		"height": 768, "width": 512, "steps": 50, "seed": 42
+	}`, string(js))
+
+	js, err = json.Marshal(newAmazonImageRequest("a gopher", llms.NewImageOptions()))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"taskType": "TEXT_IMAGE",
+		"textToImageParams": {"text": "a gopher"},
+		"imageGenerationConfig": {"numberOfImages": 1, "height": 1024, "width": 1024, "quality": "standard"}
+	}`, string(js))
+
+	parts, err := decodePNGImages([]string{base64.StdEncoding.EncodeToString([]byte("png"))})
+	require.NoError(t, err)
+	assert.Equal(t, []llms.BinaryContent{{MIMEType: "image/png", Data: []byte("png")}}, parts)
+
+	_, err = decodePNGImages(nil)
+	assert.EqualError(t, err, "bedrock: empty response")
+}
+
+func TestProcessInputMessagesAnthropic(t *testing.T) {
+	t.Parallel()
+
+	messages := []Message{
+		{Role: llms.RoleSystem, Type: AnthropicMessageTypeText, Content: "prompt"},
+		{Role: llms.RoleAI, Type: AnthropicMessageTypeToolUse, ToolCallID: "call1", ToolName: "search", ToolInput: `{}`},
+		{Role: llms.RoleTool, Type: AnthropicMessageTypeToolResult, ToolCallID: "call1", Content: "result"},
+		{Role: llms.RoleHuman, Type: AnthropicMessageTypeText, Content: "hello"},
+		{Role: llms.RoleHuman, Type: AnthropicMessageTypeText, Content: "again"},
+		{Role: llms.RoleSystem, Type: AnthropicMessageTypeText, Content: "reminder"},
+		{Role: llms.RoleAI, Type: AnthropicMessageTypeText, Content: "hi"},
+	}
+
+	inputs, systemPrompt, repair, err := processInputMessagesAnthropic(messages)
+	require.NoError(t, err)
+	assert.Equal(t, "prompt\nreminder", systemPrompt)
+	assert.Equal(t, llms.MessageOrderRepair{MergedMessages: 1, InsertedMessages: 1}, repair)
+
+	require.Len(t, inputs, 4)
+	assert.Equal(t, AnthropicRoleUser, inputs[0].Role)
+	require.Len(t, inputs[0].Content, 1)
+	assert.Equal(t, llms.MessageOrderPlaceholder, inputs[0].Content[0].Text)
+
+	assert.Equal(t, AnthropicRoleAssistant, inputs[1].Role)
+	require.Len(t, inputs[1].Content, 1)
+	assert.Equal(t, AnthropicMessageTypeToolUse, inputs[1].Content[0].Type)
+
+	assert.Equal(t, AnthropicRoleUser, inputs[2].Role)
+	require.Len(t, inputs[2].Content, 3)
+	assert.Equal(t, AnthropicMessageTypeToolResult, inputs[2].Content[0].Type)
+	assert.Equal(t, "hello", inputs[2].Content[1].Text)
+	assert.Equal(t, "again", inputs[2].Content[2].Text)
+
+	assert.Equal(t, AnthropicRoleAssistant, inputs[3].Role)
+
+	// the valid order is not repaired
+	_, _, repair, err = processInputMessagesAnthropic([]Message{
+		{Role: llms.RoleHuman, Type: AnthropicMessageTypeText, Content: "hello"},
+		{Role: llms.RoleAI, Type: AnthropicMessageTypeText, Content: "hi"},
+	})
+	require.NoError(t, err)
+	assert.False(t, repair.Changed())
+
+	_, _, _, err = processInputMessagesAnthropic([]Message{
+		{Role: llms.RoleSystem, Type: AnthropicMessageTypeImage, Content: "data"},
+	})
+	assert.EqualError(t, err, "system prompt must be text")
+}
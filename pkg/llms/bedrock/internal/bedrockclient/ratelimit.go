@@ -0,0 +1,45 @@
+package bedrockclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// rateLimitOptions returns the options to report the throttled calls,
+// which are retried by the SDK with the backoff.
+func rateLimitOptions(ctx context.Context, options llms.CallOptions) []func(*bedrockruntime.Options) {
+	if options.RateLimitFunc == nil {
+		return nil
+	}
+	return []func(*bedrockruntime.Options){
+		func(o *bedrockruntime.Options) {
+			if o.Retryer != nil {
+				o.Retryer = &rateLimitRetryer{Retryer: o.Retryer, ctx: ctx, notify: options.RateLimitFunc}
+			}
+		},
+	}
+}
+
+// rateLimitRetryer reports the delay of the throttled attempt.
+type rateLimitRetryer struct {
+	aws.Retryer
+	ctx     context.Context
+	notify  func(ctx context.Context, attempt int, wait time.Duration)
+	attempt int
+}
+
+// RetryDelay is called by the SDK only before the attempt is retried,
+// so the final attempt is not reported.
+func (r *rateLimitRetryer) RetryDelay(attempt int, opErr error) (time.Duration, error) {
+	delay, err := r.Retryer.RetryDelay(attempt, opErr)
+	if err == nil && retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(opErr) == aws.TrueTernary {
+		r.attempt++
+		r.notify(r.ctx, r.attempt, delay)
+	}
+	return delay, err
+}
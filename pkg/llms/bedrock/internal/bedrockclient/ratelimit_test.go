@@ -0,0 +1,47 @@
+package bedrockclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type codeError string
+
+func (e codeError) Error() string     { return string(e) }
+func (e codeError) ErrorCode() string { return string(e) }
+
+func TestRateLimitOptions(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, rateLimitOptions(context.Background(), llms.CallOptions{}))
+
+	var attempts []int
+	optFns := rateLimitOptions(context.Background(), llms.CallOptions{
+		RateLimitFunc: func(_ context.Context, attempt int, _ time.Duration) {
+			attempts = append(attempts, attempt)
+		},
+	})
+	require.Len(t, optFns, 1)
+
+	o := bedrockruntime.Options{Retryer: retry.NewStandard()}
+	optFns[0](&o)
+	r, ok := o.Retryer.(*rateLimitRetryer)
+	require.True(t, ok)
+
+	_, err := r.RetryDelay(1, codeError("ThrottlingException"))
+	require.NoError(t, err)
+	_, err = r.RetryDelay(2, errors.New("connection reset"))
+	require.NoError(t, err)
+	_, err = r.RetryDelay(3, codeError("ThrottlingException"))
+	require.NoError(t, err)
+	// only the throttled attempts are reported
+	assert.Equal(t, []int{1, 2}, attempts)
+}
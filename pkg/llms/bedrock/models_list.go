@@ -151,4 +151,22 @@ const (
 	// Max tokens: 4000
 	// Languages: English.
 	ModelCohereEmbedEnglishV3 = "cohere.embed-english-v3"
+
+	// Amazon Titan Image Generator G1 v2 generates the images from the text prompt,
+	// with the negative prompt and the seed support.
+	//
+	// Max prompt: 512 characters
+	// Languages: English.
+	ModelAmazonTitanImageGeneratorV2 = "amazon.titan-image-generator-v2:0"
+
+	// Amazon Titan Image Generator G1 generates the images from the text prompt.
+	//
+	// Max prompt: 512 characters
+	// Languages: English.
+	ModelAmazonTitanImageGeneratorV1 = "amazon.titan-image-generator-v1"
+
+	// Stability AI SDXL 1.0 generates the high quality images from the text prompt.
+	//
+	// Languages: English.
+	ModelStabilitySDXLV1 = "stability.stable-diffusion-xl-v1"
 )
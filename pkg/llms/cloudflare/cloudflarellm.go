@@ -156,7 +156,7 @@ func (o *LLM) CreateEmbedding(ctx context.Context, inputTexts []string) ([][]flo
 
 func typeToRole(typ llms.Role) cloudflareclient.Role {
 	switch typ {
-	case llms.RoleSystem:
+	case llms.RoleSystem, llms.RoleDeveloper:
 		return cloudflareclient.RoleSystem
 	case llms.RoleAI:
 		return cloudflareclient.RoleAssistant
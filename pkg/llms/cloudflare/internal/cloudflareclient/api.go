@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
 )
 
 // CreateEmbedding creates an embedding from the given texts.
@@ -87,7 +88,13 @@ func (c *Client) GenerateContent(ctx context.Context, request *GenerateContentRe
 		}
 
 		if response.StatusCode > 299 {
-			return nil, errors.Errorf("error: %s", body)
+			return nil, (&llms.ProviderError{
+				Provider:   llms.ProviderCloudflare,
+				StatusCode: response.StatusCode,
+				Message:    string(body),
+				RetryAfter: llms.RetryAfterHeader(response.Header),
+				Err:        errors.Errorf("error: %s", body),
+			}).Classify()
 		}
 
 		var generateResponse GenerateContentResponse
@@ -0,0 +1,360 @@
+// Package cohere provides the Cohere Command models with the v2 chat API.
+//
+// The documents passed with WithDocuments ground the response, the cited spans
+// are returned in the GenerationInfo with the llms.GenerationInfoDocumentCitations key,
+// and the plan of the tool calls with the llms.GenerationInfoToolPlan key.
+package cohere
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/cohere/internal/cohereclient"
+	"github.com/effective-security/gogentic/pkg/schema"
+)
+
+const (
+	// Provider is the provider type of the Cohere LLM.
+	Provider = llms.ProviderCohere
+	// TokenEnvVarName is the environment variable with the API key.
+	TokenEnvVarName = "COHERE_API_KEY" //nolint:gosec
+	// DefaultBaseURL is the Cohere API endpoint.
+	DefaultBaseURL = "https://api.cohere.com"
+	// DefaultModel is used when the model is not set.
+	DefaultModel = "command-a-03-2025"
+)
+
+var (
+	// ErrMissingToken is returned when the API key is not set.
+	ErrMissingToken = errors.New("missing the Cohere API key, set it in the COHERE_API_KEY environment variable")
+	// ErrEmptyResponse is returned when the response has no message.
+	ErrEmptyResponse = errors.New("no response")
+)
+
+// Citation is the span of the content grounded in the documents or the tool results.
+type Citation = cohereclient.Citation
+
+// Source is the document or the tool result cited by Citation.
+type Source = cohereclient.Source
+
+// LLM is the Cohere LLM.
+type LLM struct {
+	client  *cohereclient.Client
+	options options
+}
+
+var _ llms.Model = (*LLM)(nil)
+
+// New returns the Cohere LLM.
+func New(opts ...Option) (*LLM, error) {
+	o := options{
+		token:      os.Getenv(TokenEnvVarName),
+		model:      DefaultModel,
+		baseURL:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.token == "" {
+		return nil, ErrMissingToken
+	}
+
+	return &LLM{
+		client:  cohereclient.NewClient(o.httpClient, o.baseURL, o.token),
+		options: o,
+	}, nil
+}
+
+// GetName implements the Model interface.
+func (o *LLM) GetName() string {
+	return o.options.model
+}
+
+// GetProviderType implements the Model interface.
+func (o *LLM) GetProviderType() llms.ProviderType {
+	return llms.ProviderCohere
+}
+
+// GenerateContent implements the Model interface.
+func (o *LLM) GenerateContent(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	req, err := o.buildChatRequest(messages, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := o.client.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Message == nil {
+		return nil, ErrEmptyResponse
+	}
+
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{toContentChoice(res)},
+	}, nil
+}
+
+func (o *LLM) buildChatRequest(messages []llms.Message, opts *llms.CallOptions) (*cohereclient.ChatRequest, error) { //nolint:cyclop
+	chatMsgs := make([]*cohereclient.Message, 0, len(messages))
+	for _, mc := range messages {
+		msgs, err := convertMessage(mc)
+		if err != nil {
+			return nil, err
+		}
+		chatMsgs = append(chatMsgs, msgs...)
+	}
+
+	req := &cohereclient.ChatRequest{
+		Model:            o.options.model,
+		Messages:         chatMsgs,
+		SafetyMode:       string(o.options.safetyMode),
+		MaxTokens:        opts.MaxTokens,
+		StopSequences:    opts.StopWords,
+		Seed:             opts.Seed,
+		FrequencyPenalty: opts.FrequencyPenalty,
+		PresencePenalty:  opts.PresencePenalty,
+		K:                opts.TopK,
+		P:                opts.TopP,
+	}
+	if opts.Model != "" {
+		req.Model = opts.Model
+	}
+	if opts.Temperature > 0 {
+		req.Temperature = &opts.Temperature
+	}
+	if o.options.citationMode != "" {
+		req.CitationOptions = &cohereclient.CitationOptions{Mode: string(o.options.citationMode)}
+	}
+	if docs, ok := opts.Metadata[metadataDocuments].([]Document); ok {
+		for _, d := range docs {
+			req.Documents = append(req.Documents, &cohereclient.Document{ID: d.ID, Data: d.Data})
+		}
+	}
+
+	if rf := opts.ResponseFormat; rf != nil {
+		req.ResponseFormat = &cohereclient.ResponseFormat{Type: schema.ResponseFormatTypeJSONObject}
+		if rf.Type == schema.ResponseFormatTypeJSONSchema && rf.JSONSchema != nil {
+			req.ResponseFormat.JSONSchema = rf.JSONSchema.Schema
+		}
+	}
+
+	mode, function := llms.ParseToolChoice(opts.ToolChoice)
+	for _, t := range opts.Tools {
+		if t.Type != "function" || t.Function == nil {
+			return nil, errors.Errorf("unsupported tool type: %s", t.Type)
+		}
+		if mode == llms.ToolChoiceFunction && t.Function.Name != function {
+			// the API has no choice of the function, send only the forced function
+			continue
+		}
+		fn := &cohereclient.Function{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+		}
+		if t.Function.Parameters != nil {
+			fn.Parameters = t.Function.Parameters
+		}
+		req.Tools = append(req.Tools, &cohereclient.Tool{Type: "function", Function: fn})
+		req.StrictTools = req.StrictTools || t.Function.Strict
+	}
+	if len(req.Tools) > 0 {
+		switch mode {
+		case llms.ToolChoiceNone:
+			req.ToolChoice = "NONE"
+		case llms.ToolChoiceRequired, llms.ToolChoiceFunction:
+			req.ToolChoice = "REQUIRED"
+		}
+	}
+
+	if opts.StreamingFunc != nil || opts.StreamingReasoningFunc != nil || opts.StreamingToolCallFunc != nil {
+		req.Stream = true
+		req.StreamingFunc = streamingFunc(opts)
+	}
+	return req, nil
+}
+
+// convertMessage returns the chat messages of the message,
+// the tool responses are sent as the separate tool messages.
+func convertMessage(mc llms.Message) ([]*cohereclient.Message, error) { //nolint:cyclop
+	msg := &cohereclient.Message{}
+	switch mc.Role {
+	case llms.RoleSystem, llms.RoleDeveloper:
+		msg.Role = cohereclient.RoleSystem
+	case llms.RoleAI:
+		msg.Role = cohereclient.RoleAssistant
+	case llms.RoleHuman, llms.RoleGeneric:
+		msg.Role = cohereclient.RoleUser
+	case llms.RoleTool:
+		msg.Role = cohereclient.RoleTool
+	default:
+		return nil, errors.Wrapf(llms.ErrUnexpectedRole, "role %s", mc.Role)
+	}
+
+	var res []*cohereclient.Message
+	for _, p := range mc.Parts {
+		switch pt := p.(type) {
+		case llms.TextContent:
+			msg.Content = append(msg.Content, &cohereclient.ContentPart{Type: "text", Text: pt.Text})
+		case llms.ImageURLContent:
+			msg.Content = append(msg.Content, &cohereclient.ContentPart{
+				Type:     "image_url",
+				ImageURL: &cohereclient.ImageURL{URL: pt.URL, Detail: pt.Detail},
+			})
+		case llms.BinaryContent:
+			if !strings.HasPrefix(pt.MIMEType, "image/") {
+				return nil, errors.Errorf("unsupported binary content type: %s", pt.MIMEType)
+			}
+			msg.Content = append(msg.Content, &cohereclient.ContentPart{
+				Type: "image_url",
+				ImageURL: &cohereclient.ImageURL{
+					URL: fmt.Sprintf("data:%s;base64,%s", pt.MIMEType, base64.StdEncoding.EncodeToString(pt.Data)),
+				},
+			})
+		case llms.ToolCall:
+			msg.ToolCalls = append(msg.ToolCalls, &cohereclient.ToolCall{
+				ID:   pt.ID,
+				Type: "function",
+				Function: &cohereclient.FunctionCall{
+					Name:      pt.GetFunctionCallName(),
+					Arguments: pt.GetFunctionCallArguments(),
+				},
+			})
+		case llms.ToolCallResponse:
+			res = append(res, &cohereclient.Message{
+				Role:       cohereclient.RoleTool,
+				ToolCallID: pt.ToolCallID,
+				Content:    []*cohereclient.ContentPart{{Type: "text", Text: pt.Content}},
+			})
+		case llms.ThinkingContent:
+			// the thinking is not sent back
+		default:
+			return nil, errors.Errorf("unsupported content part: %T", p)
+		}
+	}
+
+	if len(msg.Content) > 0 || len(msg.ToolCalls) > 0 {
+		res = append([]*cohereclient.Message{msg}, res...)
+	}
+	return res, nil
+}
+
+func toContentChoice(res *cohereclient.ChatResponse) *llms.ContentChoice {
+	var text strings.Builder
+	for _, c := range res.Message.Content {
+		if c.Type == "text" {
+			text.WriteString(c.Text)
+		}
+	}
+
+	choice := &llms.ContentChoice{
+		Content:        text.String(),
+		StopReason:     res.FinishReason,
+		GenerationInfo: map[string]any{},
+	}
+	for _, tc := range res.Message.ToolCalls {
+		call := llms.ToolCall{
+			ID:           tc.ID,
+			Type:         "function",
+			FunctionCall: &llms.FunctionCall{},
+		}
+		if tc.Function != nil {
+			call.FunctionCall.Name = tc.Function.Name
+			call.FunctionCall.Arguments = tc.Function.Arguments
+		}
+		choice.ToolCalls = append(choice.ToolCalls, call)
+	}
+	if len(choice.ToolCalls) > 0 {
+		choice.FuncCall = choice.ToolCalls[0].FunctionCall
+	}
+
+	if res.Message.ToolPlan != "" {
+		choice.GenerationInfo[llms.GenerationInfoToolPlan] = res.Message.ToolPlan
+	}
+	if len(res.Message.Citations) > 0 {
+		choice.GenerationInfo[llms.GenerationInfoDocumentCitations] = res.Message.Citations
+		choice.Citations = documentCitations(res.Message.Citations)
+	}
+
+	if u := res.Usage; u != nil {
+		tokens := u.Tokens
+		if tokens == nil {
+			tokens = u.BilledUnits
+		}
+		if tokens != nil {
+			choice.Usage = llms.Usage{
+				InputTokens:  uint64(tokens.InputTokens),
+				OutputTokens: uint64(tokens.OutputTokens),
+				TotalTokens:  uint64(tokens.InputTokens + tokens.OutputTokens),
+			}
+		}
+	}
+	return choice
+}
+
+// documentCitations returns the cited documents with the url field as the llms.Citation.
+func documentCitations(citations []*Citation) []llms.Citation {
+	var res []llms.Citation
+	for _, c := range citations {
+		for _, s := range c.Sources {
+			url, _ := s.Document["url"].(string)
+			if url == "" {
+				continue
+			}
+			title, _ := s.Document["title"].(string)
+			res = append(res, llms.Citation{URL: url, Title: title})
+		}
+	}
+	return llms.MergeCitations(res)
+}
+
+// streamingFunc returns the function that passes the stream events to the streaming functions of the call.
+func streamingFunc(opts *llms.CallOptions) func(ctx context.Context, event *cohereclient.StreamEvent) error {
+	var toolCall llms.ToolCallDelta
+	return func(ctx context.Context, event *cohereclient.StreamEvent) error {
+		if event.Delta == nil || event.Delta.Message == nil {
+			return nil
+		}
+		msg := event.Delta.Message
+
+		switch event.Type {
+		case cohereclient.EventContentDelta:
+			if opts.StreamingFunc != nil && msg.Content != nil && msg.Content.Text != "" {
+				return opts.StreamingFunc(ctx, []byte(msg.Content.Text))
+			}
+		case cohereclient.EventToolPlanDelta:
+			if opts.StreamingReasoningFunc != nil && msg.ToolPlan != "" {
+				return opts.StreamingReasoningFunc(ctx, []byte(msg.ToolPlan), nil)
+			}
+		case cohereclient.EventToolCallStart, cohereclient.EventToolCallDelta:
+			if opts.StreamingToolCallFunc == nil || msg.ToolCalls == nil {
+				return nil
+			}
+			if event.Type == cohereclient.EventToolCallStart {
+				toolCall = llms.ToolCallDelta{Index: event.Index, ID: msg.ToolCalls.ID}
+				if msg.ToolCalls.Function != nil {
+					toolCall.Name = msg.ToolCalls.Function.Name
+				}
+			}
+			toolCall.ArgumentsDelta = ""
+			if msg.ToolCalls.Function != nil {
+				toolCall.ArgumentsDelta = msg.ToolCalls.Function.Arguments
+				toolCall.Arguments += toolCall.ArgumentsDelta
+			}
+			return opts.StreamingToolCallFunc(ctx, toolCall)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,158 @@
+package cohere_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/cohere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Setenv(cohere.TokenEnvVarName, "")
+
+	_, err := cohere.New()
+	assert.ErrorIs(t, err, cohere.ErrMissingToken)
+
+	llm, err := cohere.New(cohere.WithToken("test-token"))
+	require.NoError(t, err)
+	assert.Equal(t, cohere.DefaultModel, llm.GetName())
+	assert.Equal(t, llms.ProviderCohere, llm.GetProviderType())
+}
+
+func TestGenerateContent_Documents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/chat", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "command-r-plus", req["model"])
+		assert.Equal(t, map[string]any{"mode": "ACCURATE"}, req["citation_options"])
+		assert.Equal(t, []any{
+			map[string]any{"id": "doc1", "data": map[string]any{"title": "Penguins", "url": "https://example.com/penguins", "text": "Emperor penguins are the tallest."}},
+		}, req["documents"])
+		assert.Equal(t, []any{
+			map[string]any{"role": "system", "content": []any{map[string]any{"type": "text", "text": "be brief"}}},
+			map[string]any{"role": "user", "content": []any{map[string]any{"type": "text", "text": "tallest penguin?"}}},
+		}, req["messages"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","finish_reason":"COMPLETE",
+			"message":{"role":"assistant","content":[{"type":"text","text":"The Emperor penguin."}],
+				"citations":[{"start":4,"end":19,"text":"Emperor penguin","type":"TEXT_CONTENT",
+					"sources":[{"type":"document","id":"doc1","document":{"id":"doc1","title":"Penguins","url":"https://example.com/penguins"}}]}]},
+			"usage":{"billed_units":{"input_tokens":20,"output_tokens":5},"tokens":{"input_tokens":120,"output_tokens":5}}}`))
+	}))
+	defer srv.Close()
+
+	llm, err := cohere.New(
+		cohere.WithToken("test-token"),
+		cohere.WithBaseURL(srv.URL),
+		cohere.WithModel("command-r-plus"),
+		cohere.WithCitationMode(cohere.CitationModeAccurate),
+	)
+	require.NoError(t, err)
+
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleSystem, "be brief"),
+		llms.MessageFromTextParts(llms.RoleHuman, "tallest penguin?"),
+	}, cohere.WithDocuments(cohere.Document{
+		ID: "doc1",
+		Data: map[string]any{
+			"title": "Penguins",
+			"url":   "https://example.com/penguins",
+			"text":  "Emperor penguins are the tallest.",
+		},
+	}))
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+
+	choice := resp.Choices[0]
+	assert.Equal(t, "The Emperor penguin.", choice.Content)
+	assert.Equal(t, "COMPLETE", choice.StopReason)
+	assert.Equal(t, uint64(120), choice.Usage.InputTokens)
+	assert.Equal(t, uint64(125), choice.Usage.TotalTokens)
+	assert.Equal(t, []llms.Citation{{URL: "https://example.com/penguins", Title: "Penguins"}}, choice.Citations)
+
+	citations, ok := choice.GenerationInfo[llms.GenerationInfoDocumentCitations].([]*cohere.Citation)
+	require.True(t, ok)
+	require.Len(t, citations, 1)
+	assert.Equal(t, "Emperor penguin", citations[0].Text)
+	assert.Equal(t, "doc1", citations[0].Sources[0].ID)
+}
+
+func TestGenerateContent_StreamingToolCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, true, req["stream"])
+		assert.Equal(t, "REQUIRED", req["tool_choice"])
+		require.Len(t, req["tools"], 1)
+		assert.Equal(t, []any{
+			map[string]any{"role": "user", "content": []any{map[string]any{"type": "text", "text": "weather in Paris?"}}},
+			map[string]any{"role": "assistant", "tool_calls": []any{map[string]any{"id": "call_0", "type": "function",
+				"function": map[string]any{"name": "get_weather", "arguments": `{"city":"London"}`}}}},
+			map[string]any{"role": "tool", "tool_call_id": "call_0", "content": []any{map[string]any{"type": "text", "text": "rain"}}},
+		}, req["messages"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"type":"message-start","id":"2","delta":{"message":{"role":"assistant"}}}`,
+			`{"type":"tool-plan-delta","delta":{"message":{"tool_plan":"I will check "}}}`,
+			`{"type":"tool-plan-delta","delta":{"message":{"tool_plan":"the weather."}}}`,
+			`{"type":"tool-call-start","index":0,"delta":{"message":{"tool_calls":{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}}}}`,
+			`{"type":"tool-call-delta","index":0,"delta":{"message":{"tool_calls":{"function":{"arguments":"{\"city\":"}}}}}`,
+			`{"type":"tool-call-delta","index":0,"delta":{"message":{"tool_calls":{"function":{"arguments":"\"Paris\"}"}}}}}`,
+			`{"type":"tool-call-end","index":0}`,
+			`{"type":"message-end","delta":{"finish_reason":"TOOL_CALL","usage":{"tokens":{"input_tokens":30,"output_tokens":12}}}}`,
+		} {
+			_, _ = w.Write([]byte("event: x\ndata: " + ev + "\n\n"))
+		}
+	}))
+	defer srv.Close()
+
+	llm, err := cohere.New(cohere.WithToken("test-token"), cohere.WithBaseURL(srv.URL))
+	require.NoError(t, err)
+
+	var plan string
+	var deltas []llms.ToolCallDelta
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "weather in Paris?"),
+		{Role: llms.RoleAI, Parts: []llms.ContentPart{llms.ToolCall{ID: "call_0", Type: "function",
+			FunctionCall: &llms.FunctionCall{Name: "get_weather", Arguments: `{"city":"London"}`}}}},
+		{Role: llms.RoleTool, Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "call_0", Name: "get_weather", Content: "rain"}}},
+	},
+		llms.WithTools([]llms.Tool{{Type: "function", Function: &llms.FunctionDefinition{Name: "get_weather", Description: "weather"}}}),
+		llms.WithToolChoice(llms.ToolChoiceRequired),
+		llms.WithStreamingReasoningFunc(func(_ context.Context, reasoning, _ []byte) error {
+			plan += string(reasoning)
+			return nil
+		}),
+		llms.WithStreamingToolCallFunc(func(_ context.Context, delta llms.ToolCallDelta) error {
+			deltas = append(deltas, delta)
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+
+	choice := resp.Choices[0]
+	assert.Equal(t, "TOOL_CALL", choice.StopReason)
+	assert.Equal(t, "I will check the weather.", plan)
+	assert.Equal(t, plan, choice.GenerationInfo[llms.GenerationInfoToolPlan])
+	require.Len(t, choice.ToolCalls, 1)
+	assert.Equal(t, "call_1", choice.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", choice.ToolCalls[0].FunctionCall.Name)
+	assert.Equal(t, `{"city":"Paris"}`, choice.ToolCalls[0].FunctionCall.Arguments)
+	assert.Equal(t, uint64(42), choice.Usage.TotalTokens)
+
+	require.Len(t, deltas, 3)
+	assert.Equal(t, "get_weather", deltas[2].Name)
+	assert.Equal(t, `{"city":"Paris"}`, deltas[2].Arguments)
+}
@@ -0,0 +1,178 @@
+package cohereclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+const maxBufferSize = 512 * 1000
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client is the client of the Cohere v2 API.
+type Client struct {
+	httpClient httpClient
+	baseURL    string
+	token      string
+}
+
+// NewClient returns the client.
+func NewClient(client httpClient, baseURL, token string) *Client {
+	return &Client{
+		httpClient: client,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+	}
+}
+
+// Chat sends the chat request, and returns the response.
+// For the streaming request, the response is combined from the stream events.
+func (c *Client) Chat(ctx context.Context, request *ChatRequest) (*ChatResponse, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v2/chat", bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	if request.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode > 299 {
+		body, _ := io.ReadAll(resp.Body)
+		msg := fmt.Sprintf("API returned unexpected status code: %d", resp.StatusCode)
+		pe := &llms.ProviderError{
+			Provider:   llms.ProviderCohere,
+			StatusCode: resp.StatusCode,
+			RetryAfter: llms.RetryAfterHeader(resp.Header),
+		}
+		var apiErr APIError
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			pe.Message = apiErr.Message
+		} else {
+			pe.Message = string(body)
+		}
+		pe.Err = errors.Errorf("%s: %s", msg, pe.Message)
+		return nil, pe.Classify()
+	}
+
+	if !request.Stream {
+		var chatResponse ChatResponse
+		if err = json.NewDecoder(resp.Body).Decode(&chatResponse); err != nil {
+			return nil, errors.Wrap(err, "failed to decode response")
+		}
+		return &chatResponse, nil
+	}
+
+	return parseStream(ctx, resp.Body, request.StreamingFunc)
+}
+
+// parseStream reads the server-sent events, and combines them into the response.
+func parseStream(ctx context.Context, body io.Reader, fn func(ctx context.Context, event *StreamEvent) error) (*ChatResponse, error) { //nolint:cyclop
+	res := &ChatResponse{
+		Message: &Message{Role: RoleAssistant},
+	}
+	var text strings.Builder
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, maxBufferSize), maxBufferSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			// skip the empty lines and the event names, the type is in the data
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		var event StreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, errors.Wrap(err, "error decoding streaming response")
+		}
+
+		var msg *StreamMessage
+		if event.Delta != nil {
+			msg = event.Delta.Message
+		}
+
+		switch event.Type {
+		case EventMessageStart:
+			res.ID = event.ID
+		case EventContentStart, EventContentDelta:
+			if msg != nil && msg.Content != nil {
+				text.WriteString(msg.Content.Text)
+			}
+		case EventToolPlanDelta:
+			if msg != nil {
+				res.Message.ToolPlan += msg.ToolPlan
+			}
+		case EventToolCallStart:
+			if msg != nil && msg.ToolCalls != nil {
+				tc := *msg.ToolCalls
+				if tc.Function == nil {
+					tc.Function = &FunctionCall{}
+				}
+				res.Message.ToolCalls = append(res.Message.ToolCalls, &tc)
+			}
+		case EventToolCallDelta:
+			if msg != nil && msg.ToolCalls != nil && msg.ToolCalls.Function != nil && len(res.Message.ToolCalls) > 0 {
+				tc := res.Message.ToolCalls[len(res.Message.ToolCalls)-1]
+				tc.Function.Arguments += msg.ToolCalls.Function.Arguments
+			}
+		case EventCitationStart:
+			if msg != nil && msg.Citations != nil {
+				res.Message.Citations = append(res.Message.Citations, msg.Citations)
+			}
+		case EventMessageEnd:
+			if event.Delta != nil {
+				res.FinishReason = event.Delta.FinishReason
+				res.Usage = event.Delta.Usage
+			}
+		}
+
+		if fn != nil {
+			if err := fn(ctx, &event); err != nil {
+				return nil, err
+			}
+		}
+		if event.Type == EventMessageEnd {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "error reading streaming response")
+	}
+
+	if text.Len() > 0 {
+		res.Message.Content = []*ContentPart{{Type: "text", Text: text.String()}}
+	}
+	return res, nil
+}
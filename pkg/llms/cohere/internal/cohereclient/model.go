@@ -0,0 +1,186 @@
+package cohereclient
+
+import "context"
+
+// Role is the role of the chat message.
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+)
+
+// ChatRequest is the request of the v2 chat API.
+type ChatRequest struct {
+	Model            string           `json:"model"`
+	Messages         []*Message       `json:"messages"`
+	Tools            []*Tool          `json:"tools,omitempty"`
+	StrictTools      bool             `json:"strict_tools,omitempty"`
+	ToolChoice       string           `json:"tool_choice,omitempty"`
+	Documents        []*Document      `json:"documents,omitempty"`
+	CitationOptions  *CitationOptions `json:"citation_options,omitempty"`
+	ResponseFormat   *ResponseFormat  `json:"response_format,omitempty"`
+	SafetyMode       string           `json:"safety_mode,omitempty"`
+	MaxTokens        int              `json:"max_tokens,omitempty"`
+	StopSequences    []string         `json:"stop_sequences,omitempty"`
+	Temperature      *float64         `json:"temperature,omitempty"`
+	Seed             int              `json:"seed,omitempty"`
+	FrequencyPenalty float64          `json:"frequency_penalty,omitempty"`
+	PresencePenalty  float64          `json:"presence_penalty,omitempty"`
+	K                int              `json:"k,omitempty"`
+	P                float64          `json:"p,omitempty"`
+	Stream           bool             `json:"stream,omitempty"`
+
+	// StreamingFunc is called for each event of a streaming response.
+	// Return an error to stop streaming early.
+	StreamingFunc func(ctx context.Context, event *StreamEvent) error `json:"-"`
+}
+
+// Message is the chat message.
+type Message struct {
+	Role       Role           `json:"role"`
+	Content    []*ContentPart `json:"content,omitempty"`
+	ToolPlan   string         `json:"tool_plan,omitempty"`
+	ToolCalls  []*ToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	Citations  []*Citation    `json:"citations,omitempty"`
+}
+
+// ContentPart is the part of the message content.
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL is the URL of the image, or the data URL.
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Tool is the tool definition.
+type Tool struct {
+	Type     string    `json:"type"`
+	Function *Function `json:"function"`
+}
+
+// Function is the function definition.
+type Function struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// ToolCall is the tool call of the assistant.
+type ToolCall struct {
+	ID       string        `json:"id,omitempty"`
+	Type     string        `json:"type,omitempty"`
+	Function *FunctionCall `json:"function,omitempty"`
+}
+
+// FunctionCall is the function name and the JSON encoded arguments.
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// Document is the document for the retrieval augmented generation.
+type Document struct {
+	ID   string         `json:"id,omitempty"`
+	Data map[string]any `json:"data"`
+}
+
+// CitationOptions are the options of the citations.
+type CitationOptions struct {
+	Mode string `json:"mode,omitempty"`
+}
+
+// ResponseFormat is the format of the response.
+type ResponseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema any    `json:"json_schema,omitempty"`
+}
+
+// Citation is the span of the response grounded in the sources.
+type Citation struct {
+	Start   int       `json:"start"`
+	End     int       `json:"end"`
+	Text    string    `json:"text"`
+	Sources []*Source `json:"sources,omitempty"`
+	Type    string    `json:"type,omitempty"`
+}
+
+// Source is the document or the tool output cited by the response.
+type Source struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id,omitempty"`
+	Document   map[string]any `json:"document,omitempty"`
+	ToolOutput map[string]any `json:"tool_output,omitempty"`
+}
+
+// ChatResponse is the response of the v2 chat API.
+type ChatResponse struct {
+	ID           string   `json:"id"`
+	FinishReason string   `json:"finish_reason"`
+	Message      *Message `json:"message"`
+	Usage        *Usage   `json:"usage,omitempty"`
+}
+
+// Usage is the usage of the request.
+type Usage struct {
+	BilledUnits *Tokens `json:"billed_units,omitempty"`
+	Tokens      *Tokens `json:"tokens,omitempty"`
+}
+
+// Tokens is the number of the tokens.
+type Tokens struct {
+	InputTokens  float64 `json:"input_tokens"`
+	OutputTokens float64 `json:"output_tokens"`
+	SearchUnits  float64 `json:"search_units,omitempty"`
+}
+
+// Stream event types.
+const (
+	EventMessageStart  = "message-start"
+	EventContentStart  = "content-start"
+	EventContentDelta  = "content-delta"
+	EventContentEnd    = "content-end"
+	EventToolPlanDelta = "tool-plan-delta"
+	EventToolCallStart = "tool-call-start"
+	EventToolCallDelta = "tool-call-delta"
+	EventToolCallEnd   = "tool-call-end"
+	EventCitationStart = "citation-start"
+	EventCitationEnd   = "citation-end"
+	EventMessageEnd    = "message-end"
+)
+
+// StreamEvent is the event of the streaming response.
+type StreamEvent struct {
+	Type  string       `json:"type"`
+	ID    string       `json:"id,omitempty"`
+	Index int          `json:"index"`
+	Delta *StreamDelta `json:"delta,omitempty"`
+}
+
+// StreamDelta is the delta of the stream event.
+type StreamDelta struct {
+	Message      *StreamMessage `json:"message,omitempty"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	Usage        *Usage         `json:"usage,omitempty"`
+}
+
+// StreamMessage is the message delta, only the fields of the event type are set.
+type StreamMessage struct {
+	Content   *ContentPart `json:"content,omitempty"`
+	ToolPlan  string       `json:"tool_plan,omitempty"`
+	ToolCalls *ToolCall    `json:"tool_calls,omitempty"`
+	Citations *Citation    `json:"citations,omitempty"`
+}
+
+// APIError is the error returned by the API.
+type APIError struct {
+	Message string `json:"message"`
+}
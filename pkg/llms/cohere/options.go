@@ -0,0 +1,114 @@
+package cohere
+
+import (
+	"net/http"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// Doer performs a HTTP request.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// CitationMode is the mode of the citations of the documents and the tool results.
+type CitationMode string
+
+const (
+	// CitationModeFast returns the citations while the content is generated.
+	CitationModeFast CitationMode = "FAST"
+	// CitationModeAccurate returns more accurate citations, after the content is generated.
+	CitationModeAccurate CitationMode = "ACCURATE"
+	// CitationModeOff disables the citations.
+	CitationModeOff CitationMode = "OFF"
+)
+
+// SafetyMode is the safety instruction of the model.
+type SafetyMode string
+
+const (
+	SafetyModeContextual SafetyMode = "CONTEXTUAL"
+	SafetyModeStrict     SafetyMode = "STRICT"
+	SafetyModeOff        SafetyMode = "OFF"
+)
+
+type options struct {
+	token        string
+	model        string
+	baseURL      string
+	httpClient   Doer
+	citationMode CitationMode
+	safetyMode   SafetyMode
+}
+
+// Option is a functional option for the Cohere LLM.
+type Option func(*options)
+
+// WithToken passes the API key to the client. If not set, the key
+// is read from the COHERE_API_KEY environment variable.
+func WithToken(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// WithModel sets the model, by default DefaultModel.
+func WithModel(model string) Option {
+	return func(opts *options) {
+		opts.model = model
+	}
+}
+
+// WithBaseURL sets the base URL of the API, by default DefaultBaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(opts *options) {
+		opts.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient allows setting a custom HTTP client. If not set, the default value
+// is http.DefaultClient.
+func WithHTTPClient(client Doer) Option {
+	return func(opts *options) {
+		opts.httpClient = client
+	}
+}
+
+// WithCitationMode sets the citation mode of the requests, by default the provider uses CitationModeFast.
+func WithCitationMode(mode CitationMode) Option {
+	return func(opts *options) {
+		opts.citationMode = mode
+	}
+}
+
+// WithSafetyMode sets the safety mode of the requests, by default the provider uses SafetyModeContextual.
+func WithSafetyMode(mode SafetyMode) Option {
+	return func(opts *options) {
+		opts.safetyMode = mode
+	}
+}
+
+// metadataDocuments is the CallOptions metadata key of the documents.
+const metadataDocuments = "cohere_documents"
+
+// Document is the document used to ground the response.
+// The response cites the documents by ID in the llms.GenerationInfoDocumentCitations.
+type Document struct {
+	// ID is the ID of the document, if not set the provider assigns doc:<index>.
+	ID string `json:"id,omitempty"`
+	// Data is the fields of the document, such as title, snippet, text or url.
+	Data map[string]any `json:"data"`
+}
+
+// WithDocuments sets the documents of the call, used for the retrieval augmented generation.
+func WithDocuments(docs ...Document) llms.CallOption {
+	return func(o *llms.CallOptions) {
+		// copy to not modify the map shared with the other calls
+		md := make(map[string]any, len(o.Metadata)+1)
+		for k, v := range o.Metadata {
+			md[k] = v
+		}
+		md[metadataDocuments] = docs
+		o.Metadata = md
+	}
+}
@@ -0,0 +1,77 @@
+package llms
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ErrContentFiltered is returned when the prompt or the completion is blocked
+// by the content policy of the provider, or the model refused to respond.
+// Use AsContentFilterError to get the details.
+var ErrContentFiltered = errors.New("content filtered")
+
+// Content filter categories reported by the providers.
+const (
+	ContentFilterHate      = "hate"
+	ContentFilterSexual    = "sexual"
+	ContentFilterViolence  = "violence"
+	ContentFilterSelfHarm  = "self_harm"
+	ContentFilterJailbreak = "jailbreak"
+	ContentFilterProfanity = "profanity"
+	// ContentFilterRefusal is the category of the model refusals,
+	// for example the OpenAI refusal or the Anthropic refusal stop reason.
+	ContentFilterRefusal = "refusal"
+)
+
+// ContentFilterError describes the blocked request, it matches ErrContentFiltered with errors.Is.
+type ContentFilterError struct {
+	// Provider is the provider that blocked the request.
+	Provider ProviderType
+	// Categories are the filter categories that triggered the block, see ContentFilter* constants,
+	// the provider specific categories are reported as is.
+	Categories []string
+	// Prompt is true when the prompt is blocked, and false when the completion is blocked.
+	Prompt bool
+	// Message is the refusal of the model, or the error message of the provider.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *ContentFilterError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrContentFiltered.Error())
+	if e.Provider != "" {
+		sb.WriteString(" by ")
+		sb.WriteString(string(e.Provider))
+	}
+	if len(e.Categories) > 0 {
+		sb.WriteString(": ")
+		sb.WriteString(strings.Join(e.Categories, ", "))
+	}
+	if e.Message != "" {
+		sb.WriteString(": ")
+		sb.WriteString(e.Message)
+	}
+	return sb.String()
+}
+
+// Is returns true for ErrContentFiltered.
+func (e *ContentFilterError) Is(target error) bool {
+	return target == ErrContentFiltered
+}
+
+// HasCategory returns true if the category triggered the block.
+func (e *ContentFilterError) HasCategory(category string) bool {
+	return slices.Contains(e.Categories, category)
+}
+
+// AsContentFilterError returns the ContentFilterError from the chain of err.
+func AsContentFilterError(err error) (*ContentFilterError, bool) {
+	var cfe *ContentFilterError
+	if errors.As(err, &cfe) {
+		return cfe, true
+	}
+	return nil, false
+}
@@ -0,0 +1,36 @@
+package llms_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentFilterError(t *testing.T) {
+	t.Parallel()
+
+	cfe := &llms.ContentFilterError{
+		Provider:   llms.ProviderAzure,
+		Categories: []string{llms.ContentFilterHate, llms.ContentFilterViolence},
+		Prompt:     true,
+		Message:    "blocked",
+	}
+	err := errors.Wrap(cfe, "call")
+	assert.EqualError(t, err, "call: content filtered by AZURE: hate, violence: blocked")
+	assert.True(t, errors.Is(err, llms.ErrContentFiltered))
+	assert.False(t, errors.Is(errors.New("other"), llms.ErrContentFiltered))
+
+	got, ok := llms.AsContentFilterError(err)
+	require.True(t, ok)
+	assert.Same(t, cfe, got)
+	assert.True(t, got.HasCategory(llms.ContentFilterHate))
+	assert.False(t, got.HasCategory(llms.ContentFilterSexual))
+
+	_, ok = llms.AsContentFilterError(errors.New("other"))
+	assert.False(t, ok)
+
+	assert.EqualError(t, &llms.ContentFilterError{}, "content filtered")
+}
@@ -0,0 +1,113 @@
+// Package deepseek provides the DeepSeek LLM.
+//
+// DeepSeek serves the models with the OpenAI compatible API,
+// so the LLM is the OpenAI client with the DeepSeek endpoint and provider type.
+// The reasoning_content of deepseek-reasoner is returned as the thinking block of the choice,
+// and the context cache usage is returned in the GenerationInfo, see Cost.
+package deepseek
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai"
+)
+
+const (
+	// Provider is the provider type of the DeepSeek LLM.
+	Provider = llms.ProviderDeepSeek
+	// TokenEnvVarName is the environment variable with the API key.
+	TokenEnvVarName = "DEEPSEEK_API_KEY" //nolint:gosec
+	// DefaultBaseURL is the DeepSeek OpenAI compatible endpoint.
+	DefaultBaseURL = "https://api.deepseek.com"
+	// DefaultModel is used when the model is not set.
+	DefaultModel = ModelChat
+)
+
+const (
+	// ModelChat is the non-thinking mode of DeepSeek-V3.
+	ModelChat = "deepseek-chat"
+	// ModelReasoner is the thinking mode of DeepSeek-V3,
+	// the reasoning is returned in the reasoning_content.
+	ModelReasoner = "deepseek-reasoner"
+)
+
+// Models is the list of the chat models served by DeepSeek.
+var Models = []string{
+	ModelChat,
+	ModelReasoner,
+}
+
+// ErrMissingToken is returned when the API key is not set.
+var ErrMissingToken = errors.New("missing the DeepSeek API key, set it in the DEEPSEEK_API_KEY environment variable")
+
+// Doer performs a HTTP request.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type options struct {
+	token      string
+	model      string
+	baseURL    string
+	httpClient Doer
+}
+
+// Option is a functional option for the DeepSeek LLM.
+type Option func(*options)
+
+// WithToken passes the API key to the client. If not set, the key
+// is read from the DEEPSEEK_API_KEY environment variable.
+func WithToken(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// WithModel sets the model, by default DefaultModel.
+func WithModel(model string) Option {
+	return func(opts *options) {
+		opts.model = model
+	}
+}
+
+// WithBaseURL sets the base URL of the API, by default DefaultBaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(opts *options) {
+		opts.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient allows setting a custom HTTP client. If not set, the default value
+// is http.DefaultClient.
+func WithHTTPClient(client Doer) Option {
+	return func(opts *options) {
+		opts.httpClient = client
+	}
+}
+
+// New returns the DeepSeek LLM.
+func New(opts ...Option) (*openai.LLM, error) {
+	o := &options{
+		token:      os.Getenv(TokenEnvVarName),
+		model:      DefaultModel,
+		baseURL:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.token == "" {
+		return nil, ErrMissingToken
+	}
+
+	return openai.New(
+		openai.WithProvider(openai.ProviderDeepSeek),
+		openai.WithToken(o.token),
+		openai.WithModel(o.model),
+		openai.WithBaseURL(o.baseURL),
+		openai.WithHTTPClient(o.httpClient),
+	)
+}
@@ -0,0 +1,99 @@
+package deepseek_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/deepseek"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Setenv(deepseek.TokenEnvVarName, "")
+
+	_, err := deepseek.New()
+	assert.ErrorIs(t, err, deepseek.ErrMissingToken)
+
+	llm, err := deepseek.New(deepseek.WithToken("test-token"))
+	require.NoError(t, err)
+	assert.Equal(t, deepseek.DefaultModel, llm.GetName())
+	assert.Equal(t, llms.ProviderDeepSeek, llm.GetProviderType())
+	assert.True(t, llm.GetProviderType().Supports(llms.CapabilityFunctionCalling))
+}
+
+func TestGenerateContent(t *testing.T) {
+	var reqs []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		reqs = append(reqs, req)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"deepseek-reasoner",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"42","reasoning_content":"6 times 7"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120,
+				"prompt_cache_hit_tokens":64,"prompt_cache_miss_tokens":36,
+				"completion_tokens_details":{"reasoning_tokens":15}}}`))
+	}))
+	defer srv.Close()
+
+	llm, err := deepseek.New(
+		deepseek.WithToken("test-token"),
+		deepseek.WithBaseURL(srv.URL),
+		deepseek.WithModel(deepseek.ModelReasoner),
+	)
+	require.NoError(t, err)
+
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "what is 6 times 7?"),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+
+	choice := resp.Choices[0]
+	assert.Equal(t, "42", choice.Content)
+	assert.Equal(t, "6 times 7", choice.ReasoningContent)
+	assert.Equal(t, []llms.ThinkingContent{{Thinking: "6 times 7"}}, choice.Thinking)
+	assert.Equal(t, uint64(64), choice.Usage.CacheReadTokens)
+	assert.Equal(t, uint64(15), choice.Usage.ReasoningTokens)
+	assert.Equal(t, 64, choice.GenerationInfo[llms.GenerationInfoCacheHitTokens])
+	assert.Equal(t, 36, choice.GenerationInfo[llms.GenerationInfoCacheMissTokens])
+
+	// the reasoning of the tool calls is passed back
+	_, err = llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "what is the weather?"),
+		{
+			Role: llms.RoleAI,
+			Parts: []llms.ContentPart{
+				llms.ThinkingContent{Thinking: "need the weather tool"},
+				llms.ToolCall{ID: "call_1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "weather", Arguments: "{}"}},
+			},
+		},
+		{
+			Role:  llms.RoleTool,
+			Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "call_1", Name: "weather", Content: "sunny"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+	msgs := reqs[1]["messages"].([]any)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "need the weather tool", msgs[1].(map[string]any)["reasoning_content"])
+	assert.NotContains(t, msgs[0].(map[string]any), "reasoning_content")
+}
+
+func TestCost(t *testing.T) {
+	usage := &llms.Usage{InputTokens: 1_000_000, CacheReadTokens: 400_000, OutputTokens: 1_000_000}
+	assert.InDelta(t, 0.4*0.028+0.6*0.28+0.42, deepseek.Cost(deepseek.ModelChat, usage), 1e-9)
+	assert.InDelta(t, 0.28, deepseek.Cost(deepseek.ModelReasoner, &llms.Usage{InputTokens: 1_000_000}), 1e-9)
+	assert.Zero(t, deepseek.Cost("unknown", usage))
+	assert.Zero(t, deepseek.Cost(deepseek.ModelChat, nil))
+}
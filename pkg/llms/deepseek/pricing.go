@@ -0,0 +1,34 @@
+package deepseek
+
+import (
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// Pricing is the price of the model in USD per 1M tokens.
+type Pricing struct {
+	// InputCacheHit is the price of the prompt tokens served from the context cache.
+	InputCacheHit float64
+	// InputCacheMiss is the price of the prompt tokens not served from the context cache.
+	InputCacheMiss float64
+	// Output is the price of the completion tokens, including the reasoning tokens.
+	Output float64
+}
+
+// Prices is the pricing of the models, the context caching is enabled by default
+// and the cache hit tokens are billed at the discount.
+var Prices = map[string]Pricing{
+	ModelChat:     {InputCacheHit: 0.028, InputCacheMiss: 0.28, Output: 0.42},
+	ModelReasoner: {InputCacheHit: 0.028, InputCacheMiss: 0.28, Output: 0.42},
+}
+
+// Cost returns the cost of the LLM call in USD, or zero for the unknown model.
+// It can be used as the CostFunc of the assistants.
+func Cost(model string, usage *llms.Usage) float64 {
+	p, ok := Prices[model]
+	if !ok || usage == nil {
+		return 0
+	}
+	hit := usage.CacheReadTokens
+	miss := usage.InputTokens - min(hit, usage.InputTokens)
+	return (float64(hit)*p.InputCacheHit + float64(miss)*p.InputCacheMiss + float64(usage.OutputTokens)*p.Output) / 1e6
+}
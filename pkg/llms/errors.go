@@ -0,0 +1,193 @@
+package llms
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// The kinds of the provider errors, the errors returned by the providers
+// match the kind with errors.Is, use AsProviderError to get the details.
+var (
+	// ErrRateLimited is returned when the request is rejected by the rate limit
+	// or the quota of the provider, see RetryAfter.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrContextLengthExceeded is returned when the prompt and the requested
+	// completion do not fit in the context window of the model.
+	ErrContextLengthExceeded = errors.New("context length exceeded")
+	// ErrAuth is returned when the credentials are missing, invalid or not allowed to use the model.
+	ErrAuth = errors.New("authentication failed")
+	// ErrServerOverloaded is returned when the provider is overloaded or failed with the server error,
+	// the request may succeed when retried later or with another model.
+	ErrServerOverloaded = errors.New("server overloaded")
+	// ErrBadRequest is returned when the request is rejected as invalid,
+	// for example the unknown model or the invalid parameters, retrying it does not help.
+	ErrBadRequest = errors.New("bad request")
+)
+
+// ProviderError is the error of the provider API, classified by the Kind.
+// It matches the Kind with errors.Is, and unwraps to the error of the provider SDK.
+type ProviderError struct {
+	// Kind is one of ErrRateLimited, ErrContextLengthExceeded, ErrAuth, ErrServerOverloaded or ErrBadRequest.
+	Kind error
+	// Provider is the provider that returned the error.
+	Provider ProviderType
+	// StatusCode is the HTTP status code of the response, if available.
+	StatusCode int
+	// Code is the provider specific error code or type, for example "rate_limit_exceeded".
+	Code string
+	// Message is the error message of the provider.
+	Message string
+	// RetryAfter is the time to wait before retrying the rate limited request,
+	// as requested by the provider, or zero if not known.
+	RetryAfter time.Duration
+	// Err is the original error of the provider.
+	Err error
+}
+
+// Error implements the error interface,
+// it returns the message of the original error, if set.
+func (e *ProviderError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	var sb strings.Builder
+	if e.Kind != nil {
+		sb.WriteString(e.Kind.Error())
+	} else {
+		sb.WriteString("provider error")
+	}
+	if e.Provider != "" {
+		sb.WriteString(" by ")
+		sb.WriteString(string(e.Provider))
+	}
+	if e.StatusCode != 0 {
+		sb.WriteString(": status code: ")
+		sb.WriteString(strconv.Itoa(e.StatusCode))
+	}
+	if e.Message != "" {
+		sb.WriteString(": ")
+		sb.WriteString(e.Message)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the original error of the provider.
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true for the Kind of the error.
+func (e *ProviderError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// AsProviderError returns the ProviderError from the chain of err.
+func AsProviderError(err error) (*ProviderError, bool) {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe, true
+	}
+	return nil, false
+}
+
+// RetryAfter returns the time to wait before retrying the request failed with err,
+// as requested by the provider, or zero if not known.
+func RetryAfter(err error) time.Duration {
+	if pe, ok := AsProviderError(err); ok {
+		return pe.RetryAfter
+	}
+	return 0
+}
+
+// Classify sets the Kind by ErrorKind of the status code, the error code and the message,
+// if it is not set. It returns the error, or the original error if it is not classified.
+func (e *ProviderError) Classify() error {
+	if e.Kind == nil {
+		e.Kind = ErrorKind(e.StatusCode, e.Code, e.Message)
+	}
+	if e.Kind == nil && e.Err != nil {
+		return e.Err
+	}
+	return e
+}
+
+// rate limit, overloaded, auth and context length codes and types of the providers
+var (
+	rateLimitCodes     = []string{"rate_limit_exceeded", "rate_limit_error", "insufficient_quota", "resource_exhausted", "throttlingexception", "servicequotaexceededexception"}
+	overloadedCodes    = []string{"overloaded_error", "server_error", "api_error", "unavailable", "internal", "serviceunavailableexception", "internalserverexception", "modelnotreadyexception", "modeltimeoutexception"}
+	authCodes          = []string{"authentication_error", "permission_error", "invalid_api_key", "unauthenticated", "permission_denied", "accessdeniedexception", "unrecognizedclientexception"}
+	contextLengthCodes = []string{"context_length_exceeded", "string_above_max_length"}
+)
+
+// contextLengthMessages are the messages of the providers, when the prompt does not fit in the context window
+var contextLengthMessages = []string{
+	"context length",
+	"context window",
+	"context_length_exceeded",
+	"prompt is too long",
+	"input is too long",
+	"too many tokens",
+	"input token count",
+	"maximum number of tokens",
+	"reduce the length of the messages",
+}
+
+// ErrorKind returns the kind of the provider error by the HTTP status code,
+// the provider specific error code or type, and the message,
+// or nil if the error is not classified.
+func ErrorKind(statusCode int, code, message string) error {
+	code = strings.ToLower(code)
+	msg := strings.ToLower(message)
+	switch {
+	case slices.Contains(contextLengthCodes, code),
+		(statusCode == 0 || statusCode == http.StatusBadRequest || statusCode == http.StatusRequestEntityTooLarge ||
+			statusCode == http.StatusUnprocessableEntity) && containsOneOf(msg, contextLengthMessages):
+		return ErrContextLengthExceeded
+	case statusCode == http.StatusTooManyRequests, slices.Contains(rateLimitCodes, code):
+		return ErrRateLimited
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden, slices.Contains(authCodes, code):
+		return ErrAuth
+	case statusCode >= http.StatusInternalServerError, slices.Contains(overloadedCodes, code):
+		return ErrServerOverloaded
+	case statusCode >= http.StatusBadRequest:
+		return ErrBadRequest
+	}
+	return nil
+}
+
+func containsOneOf(s string, substrs []string) bool {
+	return slices.ContainsFunc(substrs, func(sub string) bool {
+		return strings.Contains(s, sub)
+	})
+}
+
+// RetryAfterHeader returns the time to wait from the retry-after-ms
+// or the retry-after header of the response, or zero if not set.
+func RetryAfterHeader(h http.Header) time.Duration {
+	if v := h.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	v := h.Get("retry-after")
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil {
+		if s <= 0 {
+			return 0
+		}
+		return time.Duration(s * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package llms_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKind(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		status  int
+		code    string
+		message string
+		exp     error
+	}{
+		{name: "429", status: http.StatusTooManyRequests, message: "Rate limit reached", exp: llms.ErrRateLimited},
+		{name: "groq_413", status: http.StatusRequestEntityTooLarge, code: "rate_limit_exceeded", message: "Request too large for model", exp: llms.ErrRateLimited},
+		{name: "bedrock_throttling", status: http.StatusBadRequest, code: "ThrottlingException", exp: llms.ErrRateLimited},
+		{name: "gemini_exhausted", code: "RESOURCE_EXHAUSTED", exp: llms.ErrRateLimited},
+		{name: "401", status: http.StatusUnauthorized, exp: llms.ErrAuth},
+		{name: "403", status: http.StatusForbidden, code: "permission_error", exp: llms.ErrAuth},
+		{name: "anthropic_529", status: 529, code: "overloaded_error", message: "Overloaded", exp: llms.ErrServerOverloaded},
+		{name: "500", status: http.StatusInternalServerError, exp: llms.ErrServerOverloaded},
+		{name: "openai_context", status: http.StatusBadRequest, code: "context_length_exceeded", exp: llms.ErrContextLengthExceeded},
+		{name: "anthropic_context", status: http.StatusBadRequest, code: "invalid_request_error", message: "prompt is too long: 210000 tokens > 200000 maximum", exp: llms.ErrContextLengthExceeded},
+		{name: "bedrock_context", status: http.StatusBadRequest, code: "ValidationException", message: "Input is too long for requested model.", exp: llms.ErrContextLengthExceeded},
+		{name: "gemini_context", status: http.StatusBadRequest, code: "INVALID_ARGUMENT", message: "The input token count (1200000) exceeds the maximum number of tokens allowed (1048576).", exp: llms.ErrContextLengthExceeded},
+		{name: "400", status: http.StatusBadRequest, code: "invalid_request_error", message: "max_tokens: Field required", exp: llms.ErrBadRequest},
+		{name: "404", status: http.StatusNotFound, message: "model not found", exp: llms.ErrBadRequest},
+		{name: "unknown", code: "stream_error", message: "unexpected EOF", exp: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.exp, llms.ErrorKind(tt.status, tt.code, tt.message))
+		})
+	}
+}
+
+func TestProviderError(t *testing.T) {
+	t.Parallel()
+
+	cause := errors.New("API returned unexpected status code: 429: slow down")
+	pe := &llms.ProviderError{
+		Provider:   llms.ProviderOpenAI,
+		StatusCode: http.StatusTooManyRequests,
+		Code:       "rate_limit_exceeded",
+		Message:    "slow down",
+		RetryAfter: 2 * time.Second,
+		Err:        cause,
+	}
+	err := errors.Wrap(pe.Classify(), "call")
+	assert.EqualError(t, err, "call: API returned unexpected status code: 429: slow down")
+	assert.ErrorIs(t, err, llms.ErrRateLimited)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, llms.ErrServerOverloaded)
+	assert.Equal(t, 2*time.Second, llms.RetryAfter(err))
+
+	got, ok := llms.AsProviderError(err)
+	require.True(t, ok)
+	assert.Same(t, pe, got)
+
+	_, ok = llms.AsProviderError(cause)
+	assert.False(t, ok)
+	assert.Zero(t, llms.RetryAfter(cause))
+
+	// not classified
+	unknown := errors.New("unexpected EOF")
+	assert.Same(t, unknown, (&llms.ProviderError{Err: unknown}).Classify())
+
+	// the Kind is kept
+	err = (&llms.ProviderError{Kind: llms.ErrAuth, Provider: llms.ProviderBedrock, StatusCode: 400, Message: "expired token"}).Classify()
+	assert.ErrorIs(t, err, llms.ErrAuth)
+	assert.EqualError(t, err, "authentication failed by BEDROCK: status code: 400: expired token")
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	t.Parallel()
+
+	assert.Zero(t, llms.RetryAfterHeader(http.Header{}))
+	assert.Equal(t, 1500*time.Millisecond, llms.RetryAfterHeader(http.Header{"Retry-After": {"1.5"}}))
+	assert.Equal(t, 20*time.Millisecond, llms.RetryAfterHeader(http.Header{"Retry-After-Ms": {"20"}, "Retry-After": {"1"}}))
+	assert.Zero(t, llms.RetryAfterHeader(http.Header{"Retry-After": {"-1"}}))
+
+	d := llms.RetryAfterHeader(http.Header{"Retry-After": {time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}})
+	assert.InDelta(t, float64(time.Minute), float64(d), float64(2*time.Second))
+	assert.Zero(t, llms.RetryAfterHeader(http.Header{"Retry-After": {"Mon, 01 Jan 2001 00:00:00 GMT"}}))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/errors"
 )
@@ -23,6 +24,10 @@ const (
 	RoleHuman Role = "human"
 	// RoleSystem is a message sent by the system.
 	RoleSystem Role = "system"
+	// RoleDeveloper is a message with the developer instructions,
+	// distinguished from the system message by the OpenAI reasoning models.
+	// The providers without the developer role fold it into the system prompt.
+	RoleDeveloper Role = "developer"
 	// RoleGeneric is a message sent by a generic user.
 	RoleGeneric Role = "generic"
 	// RoleTool is a message sent by a tool.
@@ -37,6 +42,8 @@ const (
 	ContentTypeBinary       ContentPartType = "binary"
 	ContentTypeToolCall     ContentPartType = "tool_call"
 	ContentTypeToolResponse ContentPartType = "tool_response"
+	ContentTypeThinking     ContentPartType = "thinking"
+	ContentTypeDocument     ContentPartType = "document"
 )
 
 // Message is the message sent to a LLM. It has a role and a
@@ -51,10 +58,32 @@ type Message struct {
 	// Source is the source of the message.
 	// It's used to identify the source of the message.
 	Source *MessageSource `json:"source,omitempty"`
+
+	// Metadata is the optional annotations of the message,
+	// such as timestamp, author, model, latency or cost, see the Metadata* keys.
+	// The metadata is not sent to LLM, and is preserved by the MessageStore.
+	// Note that after JSON round trip the numbers are float64.
+	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
 type Messages = []Message
 
+// The well-known keys of the Message metadata.
+const (
+	// MetadataTimestamp is the creation time of the message, in RFC3339 format.
+	MetadataTimestamp = "timestamp"
+	// MetadataAuthor is the author of the message, such as user ID or assistant name.
+	MetadataAuthor = "author"
+	// MetadataModel is the name of the model that generated the message.
+	MetadataModel = "model"
+	// MetadataLatency is the latency of the LLM call in milliseconds.
+	MetadataLatency = "latency_ms"
+	// MetadataCost is the cost of the LLM call in USD.
+	MetadataCost = "cost"
+	// MetadataToolLatency is the latency of the tool call in milliseconds.
+	MetadataToolLatency = "tool_latency_ms"
+)
+
 type MessageSource struct {
 	// Name is the name of the source of the message.
 	Name string `json:"name"`
@@ -78,6 +107,25 @@ func BinaryPart(mime string, data []byte) BinaryContent {
 	}
 }
 
+// DocumentPart creates a new DocumentContent from the given MIME type
+// (e.g. "application/pdf"), document data and optional title.
+func DocumentPart(mime string, data []byte, title string) DocumentContent {
+	return DocumentContent{
+		MIMEType: mime,
+		Data:     data,
+		Title:    title,
+	}
+}
+
+// FileDocumentPart creates a new DocumentContent referencing the file
+// uploaded to the provider Files API, and optional title.
+func FileDocumentPart(fileID string, title string) DocumentContent {
+	return DocumentContent{
+		FileID: fileID,
+		Title:  title,
+	}
+}
+
 // ImageURLPart creates a new ImageURLContent from the given URL.
 func ImageURLPart(url string) ImageURLContent {
 	return ImageURLContent{
@@ -161,6 +209,59 @@ func (bc BinaryContent) ContentLength() int {
 	return len(bc.MIMEType) + len(bc.Data)
 }
 
+// DocumentContent is a document, for example PDF, provided as the model input.
+// The document is either inline with the Data and MIMEType,
+// or the FileID of the file uploaded to the provider Files API.
+type DocumentContent struct {
+	MIMEType string `json:"mime_type,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+func (dc DocumentContent) String() string {
+	if dc.FileID != "" {
+		return "file:" + dc.FileID
+	}
+	return "data:" + dc.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(dc.Data)
+}
+
+func (dc DocumentContent) ContentType() ContentPartType {
+	return ContentTypeDocument
+}
+
+func (DocumentContent) isPart() {}
+
+func (dc DocumentContent) ContentLength() int {
+	return len(dc.MIMEType) + len(dc.Data) + len(dc.FileID) + len(dc.Title)
+}
+
+// ThinkingContent is the thinking block returned by the reasoning models,
+// for example Anthropic extended thinking or Gemini thoughts.
+// The thinking blocks must be passed back unmodified with the tool call responses.
+type ThinkingContent struct {
+	// Thinking is the thinking text.
+	Thinking string `json:"thinking"`
+	// Signature is the provider signature of the thinking block.
+	Signature string `json:"signature,omitempty"`
+	// RedactedData is the encrypted thinking, when the thinking is redacted by the provider.
+	RedactedData string `json:"redacted_data,omitempty"`
+}
+
+func (tc ThinkingContent) String() string {
+	return tc.Thinking
+}
+
+func (tc ThinkingContent) ContentType() ContentPartType {
+	return ContentTypeThinking
+}
+
+func (ThinkingContent) isPart() {}
+
+func (tc ThinkingContent) ContentLength() int {
+	return len(tc.Thinking) + len(tc.Signature) + len(tc.RedactedData)
+}
+
 // FunctionCall is the name and arguments of a function call.
 type FunctionCall struct {
 	// The name of the function to call.
@@ -211,6 +312,22 @@ func (tc ToolCall) ContentLength() int {
 	return cl
 }
 
+// ToolCallDelta is the incremental update of the tool call in the streaming response.
+type ToolCallDelta struct {
+	// Index is the position of the tool call in the response,
+	// the parallel tool calls are streamed interleaved.
+	Index int `json:"index"`
+	// ID is the unique identifier of the tool call.
+	ID string `json:"id"`
+	// Name is the name of the function to call,
+	// it is known from the first delta of the tool call.
+	Name string `json:"name"`
+	// ArgumentsDelta is the chunk of the arguments received in this delta.
+	ArgumentsDelta string `json:"arguments_delta,omitempty"`
+	// Arguments is the arguments assembled so far.
+	Arguments string `json:"arguments,omitempty"`
+}
+
 // ToolCallResponse is the response returned by a tool call.
 type ToolCallResponse struct {
 	// ToolCallID is the ID of the tool call this response is for.
@@ -255,6 +372,7 @@ func (r *Usage) Add(other *Usage) {
 	}
 }
 
+// UsageStats is the usage aggregated over the LLM calls of the run.
 type UsageStats struct {
 	Usage
 
@@ -264,8 +382,17 @@ type UsageStats struct {
 	BytesIn uint64
 	// LlmCallCount is the number of GenerateContent calls made.
 	LlmCallCount uint32
+	// ToolCallCount is the number of the executed tool calls.
+	ToolCallCount uint32
+	// ToolRounds is the number of the LLM calls followed by the tool calls.
+	ToolRounds uint32
+	// Duration is the wall time of the run.
+	Duration time.Duration
+	// Cost is the estimated cost of the LLM calls, when the cost function is provided.
+	Cost float64
 }
 
+// Add adds the other usage, the durations are summed as for the sequential runs.
 func (r *UsageStats) Add(other *UsageStats) {
 	if r != nil && other != nil {
 		r.InputTokens += other.InputTokens
@@ -277,6 +404,10 @@ func (r *UsageStats) Add(other *UsageStats) {
 		r.LlmCallCount += other.LlmCallCount
 		r.BytesOut += other.BytesOut
 		r.BytesIn += other.BytesIn
+		r.ToolCallCount += other.ToolCallCount
+		r.ToolRounds += other.ToolRounds
+		r.Duration += other.Duration
+		r.Cost += other.Cost
 	}
 }
 
@@ -311,6 +442,81 @@ type ContentChoice struct {
 
 	// This field is only used with the deepseek-reasoner model and represents the reasoning contents of the assistant message before the final answer.
 	ReasoningContent string `json:"reasoning_content"`
+
+	// Thinking is the list of the thinking blocks returned by the reasoning models.
+	Thinking []ThinkingContent `json:"thinking,omitempty"`
+
+	// Citations is the list of the sources used to ground the content,
+	// returned by the search-grounded models, for example Perplexity.
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// GenerationInfo keys with the search metadata returned by the search-grounded models.
+const (
+	// GenerationInfoCitations is the list of the cited URLs.
+	GenerationInfoCitations = "citations"
+	// GenerationInfoSearchResults is the list of the search results.
+	GenerationInfoSearchResults = "search_results"
+	// GenerationInfoImages is the list of the images from the search results.
+	GenerationInfoImages = "images"
+	// GenerationInfoRelatedQuestions is the list of the related questions.
+	GenerationInfoRelatedQuestions = "related_questions"
+)
+
+// GenerationInfo keys with the grounding metadata returned by the RAG models, for example Cohere.
+const (
+	// GenerationInfoDocumentCitations is the list of the spans of the content
+	// grounded in the documents or the tool results, with the cited sources.
+	GenerationInfoDocumentCitations = "document_citations"
+	// GenerationInfoToolPlan is the plan of the model before the tool calls.
+	GenerationInfoToolPlan = "tool_plan"
+)
+
+// GenerationInfo keys with the context cache usage returned by the providers
+// with the automatic prompt caching, for example DeepSeek.
+const (
+	// GenerationInfoCacheHitTokens is the number of the prompt tokens served from the cache.
+	GenerationInfoCacheHitTokens = "prompt_cache_hit_tokens"
+	// GenerationInfoCacheMissTokens is the number of the prompt tokens not served from the cache.
+	GenerationInfoCacheMissTokens = "prompt_cache_miss_tokens"
+)
+
+// GenerationInfo keys with the routing metadata returned by the aggregators, for example OpenRouter.
+const (
+	// GenerationInfoCost is the cost of the request reported by the provider.
+	GenerationInfoCost = "cost"
+	// GenerationInfoProvider is the name of the upstream provider that served the request.
+	GenerationInfoProvider = "provider"
+)
+
+// GenerationInfoModel is the name of the model that generated the choice,
+// set by the assistants with the fallback models.
+const GenerationInfoModel = "model"
+
+// Citation is a source used to ground the generated content.
+type Citation struct {
+	// URL is the URL of the source.
+	URL string `json:"url" yaml:"url"`
+	// Title is the title of the source, if provided.
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	// Date is the publication date of the source, if provided.
+	Date string `json:"date,omitempty" yaml:"date,omitempty"`
+}
+
+// MergeCitations returns the citations without duplicated URLs, preserving the order.
+func MergeCitations(lists ...[]Citation) []Citation {
+	var res []Citation
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, c := range list {
+			if c.URL == "" || seen[c.URL] {
+				continue
+			}
+			seen[c.URL] = true
+			res = append(res, c)
+		}
+	}
+	return res
 }
 
 func (r *ContentResponse) Usage() *Usage {
@@ -448,6 +654,24 @@ func (m Message) WithSource(src *MessageSource) Message {
 	return res
 }
 
+// WithMetadata returns a copy of the message with the metadata value by key,
+// the metadata of the original message is not modified.
+func (m Message) WithMetadata(key string, value any) Message {
+	res := m
+	res.Metadata = make(map[string]any, len(m.Metadata)+1)
+	for k, v := range m.Metadata {
+		res.Metadata[k] = v
+	}
+	res.Metadata[key] = value
+	return res
+}
+
+// GetMetadata returns the metadata value by key.
+func (m *Message) GetMetadata(key string) (any, bool) {
+	v, ok := m.Metadata[key]
+	return v, ok
+}
+
 // Print is a debugging helper.
 func (m *Message) Print(w io.Writer) {
 	lastNewLine := true
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/effective-security/gogentic/pkg/llmutils"
@@ -192,6 +193,37 @@ func TestContentResponseUsageStats(t *testing.T) {
 	assert.Equal(t, uint64(121), st.TotalTokens)
 }
 
+func TestUsageStatsAdd(t *testing.T) {
+	t.Parallel()
+	st := llms.UsageStats{
+		Usage:         llms.Usage{InputTokens: 10, OutputTokens: 20, TotalTokens: 30},
+		LlmCallCount:  2,
+		ToolCallCount: 3,
+		ToolRounds:    1,
+		Duration:      time.Second,
+		Cost:          0.5,
+	}
+	st.Add(&llms.UsageStats{
+		Usage:         llms.Usage{InputTokens: 1, OutputTokens: 2, TotalTokens: 3},
+		BytesIn:       100,
+		LlmCallCount:  1,
+		ToolCallCount: 1,
+		ToolRounds:    1,
+		Duration:      time.Second,
+		Cost:          0.25,
+	})
+	st.Add(nil)
+	assert.Equal(t, llms.UsageStats{
+		Usage:         llms.Usage{InputTokens: 11, OutputTokens: 22, TotalTokens: 33},
+		BytesIn:       100,
+		LlmCallCount:  3,
+		ToolCallCount: 4,
+		ToolRounds:    2,
+		Duration:      2 * time.Second,
+		Cost:          0.75,
+	}, st)
+}
+
 func TestContentResponseContentSize(t *testing.T) {
 	t.Parallel()
 	cr := &llms.ContentResponse{Choices: []*llms.ContentChoice{{
@@ -206,3 +238,33 @@ func TestContentResponseContentSize(t *testing.T) {
 	}}}
 	assert.Equal(t, uint64(21), cr.ContentSize())
 }
+
+func TestMergeCitations(t *testing.T) {
+	t.Parallel()
+
+	res := llms.MergeCitations(
+		[]llms.Citation{{URL: "https://a", Title: "A"}, {URL: ""}, {URL: "https://b"}},
+		nil,
+		[]llms.Citation{{URL: "https://a"}, {URL: "https://c"}},
+	)
+	assert.Equal(t, []llms.Citation{{URL: "https://a", Title: "A"}, {URL: "https://b"}, {URL: "https://c"}}, res)
+	assert.Nil(t, llms.MergeCitations())
+}
+
+func TestMessageMetadata(t *testing.T) {
+	t.Parallel()
+
+	m := llms.MessageFromTextParts(llms.RoleAI, "Hi")
+	_, ok := m.GetMetadata(llms.MetadataModel)
+	assert.False(t, ok)
+
+	m1 := m.WithMetadata(llms.MetadataModel, "gpt-4o")
+	m2 := m1.WithMetadata(llms.MetadataCost, 0.01)
+	assert.Nil(t, m.Metadata)
+	assert.Equal(t, map[string]any{llms.MetadataModel: "gpt-4o"}, m1.Metadata)
+	assert.Equal(t, map[string]any{llms.MetadataModel: "gpt-4o", llms.MetadataCost: 0.01}, m2.Metadata)
+
+	v, ok := m2.GetMetadata(llms.MetadataCost)
+	assert.True(t, ok)
+	assert.Equal(t, 0.01, v)
+}
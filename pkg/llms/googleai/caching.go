@@ -0,0 +1,45 @@
+package googleai
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"google.golang.org/genai"
+)
+
+// CreateCachedContent caches the messages, such as the large documents or the system prompt,
+// for the default model, and returns the name of the cached content.
+// Use the name with WithCachedContent, the cached tokens are billed at the discount.
+// The cache expires after the ttl, or the default of the provider if the ttl is zero.
+func (g *GoogleAI) CreateCachedContent(ctx context.Context, messages []llms.Message, ttl time.Duration) (string, error) {
+	cfg := &genai.CreateCachedContentConfig{
+		TTL: ttl,
+	}
+	for _, mc := range messages {
+		content, err := convertContent(mc)
+		if err != nil {
+			return "", err
+		}
+		if mc.Role == llms.RoleSystem || mc.Role == llms.RoleDeveloper {
+			cfg.SystemInstruction = content
+			continue
+		}
+		cfg.Contents = append(cfg.Contents, content)
+	}
+
+	cached, err := g.client.Caches.Create(ctx, g.opts.DefaultModel, cfg)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create cached content")
+	}
+	return cached.Name, nil
+}
+
+// DeleteCachedContent deletes the cached content by its name.
+func (g *GoogleAI) DeleteCachedContent(ctx context.Context, name string) error {
+	if _, err := g.client.Caches.Delete(ctx, name, nil); err != nil {
+		return errors.Wrap(err, "failed to delete cached content")
+	}
+	return nil
+}
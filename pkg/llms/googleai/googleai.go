@@ -3,8 +3,12 @@ package googleai
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"math"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/pkg/llms"
@@ -36,6 +40,9 @@ func (g *GoogleAI) GetName() string {
 
 // GetProviderType implements the Model interface.
 func (g *GoogleAI) GetProviderType() llms.ProviderType {
+	if g.opts.Backend == genai.BackendVertexAI {
+		return llms.ProviderVertexAI
+	}
 	return llms.ProviderGoogleAI
 }
 
@@ -77,29 +84,25 @@ func (g *GoogleAI) GenerateContent(
 			ThinkingLevel: genai.ThinkingLevelHigh,
 		}
 	}
+	if opts.ThinkingBudget > 0 {
+		callCfg.ThinkingConfig = &genai.ThinkingConfig{
+			ThinkingBudget:  genaiutils.Int32Ptr(int32(opts.ThinkingBudget)),
+			IncludeThoughts: true,
+		}
+	}
 
-	callCfg.SafetySettings = []*genai.SafetySetting{
-		{
-			Category:  genai.HarmCategoryDangerousContent,
-			Threshold: genai.HarmBlockThreshold(g.opts.HarmThreshold),
-		},
-		{
-			Category:  genai.HarmCategoryHarassment,
-			Threshold: genai.HarmBlockThreshold(g.opts.HarmThreshold),
-		},
-		{
-			Category:  genai.HarmCategoryHateSpeech,
-			Threshold: genai.HarmBlockThreshold(g.opts.HarmThreshold),
-		},
-		{
-			Category:  genai.HarmCategorySexuallyExplicit,
-			Threshold: genai.HarmBlockThreshold(g.opts.HarmThreshold),
-		},
+	callCfg.CachedContent = g.opts.CachedContent
+	callCfg.SafetySettings = g.opts.SafetySettings
+	if len(callCfg.SafetySettings) == 0 {
+		callCfg.SafetySettings = harmSafetySettings(g.opts.HarmThreshold)
 	}
 	var err error
 	if callCfg.Tools, err = genaiutils.ConvertTools(opts.Tools); err != nil {
 		return nil, err
 	}
+	if opts.ToolChoice != nil && hasFunctionTools(callCfg.Tools) {
+		callCfg.ToolConfig = genaiutils.ConvertToolChoice(opts.ToolChoice)
+	}
 
 	if !hasFunctionTools(callCfg.Tools) && opts.ResponseFormat != nil && opts.ResponseFormat.Type == "json_object" {
 		callCfg.ResponseMIMEType = ResponseMIMETypeJson
@@ -111,13 +114,38 @@ func (g *GoogleAI) GenerateContent(
 		}
 	}
 
-	response, err := g.generateFromMessages(ctx, messages, callCfg)
+	// the large images are rejected by the API
+	messages, _ = llmutils.FitImages(messages, llmutils.ImagePolicyFor(g.GetProviderType()))
+
+	response, err := g.generateFromMessages(ctx, messages, callCfg, &opts)
 	if err != nil {
 		return nil, err
 	}
 	return response, nil
 }
 
+// harmSafetySettings returns the safety settings with the threshold for all the categories.
+func harmSafetySettings(threshold genai.HarmBlockThreshold) []*genai.SafetySetting {
+	return []*genai.SafetySetting{
+		{
+			Category:  genai.HarmCategoryDangerousContent,
+			Threshold: threshold,
+		},
+		{
+			Category:  genai.HarmCategoryHarassment,
+			Threshold: threshold,
+		},
+		{
+			Category:  genai.HarmCategoryHateSpeech,
+			Threshold: threshold,
+		},
+		{
+			Category:  genai.HarmCategorySexuallyExplicit,
+			Threshold: threshold,
+		},
+	}
+}
+
 func hasFunctionTools(tools []*genai.Tool) bool {
 	for _, tool := range tools {
 		if tool.FunctionDeclarations != nil {
@@ -134,10 +162,18 @@ func convertCandidates(candidates []*genai.Candidate, usage *genai.GenerateConte
 
 	for _, candidate := range candidates {
 		buf := strings.Builder{}
+		thoughts := strings.Builder{}
+		var thinking []llms.ThinkingContent
 
 		if candidate.Content != nil {
 			for _, part := range candidate.Content.Parts {
 				switch {
+				case part.Thought:
+					thoughts.WriteString(part.Text)
+					thinking = append(thinking, llms.ThinkingContent{
+						Thinking:  part.Text,
+						Signature: base64.StdEncoding.EncodeToString(part.ThoughtSignature),
+					})
 				case part.Text != "":
 					_, err := buf.WriteString(part.Text)
 					if err != nil {
@@ -165,6 +201,9 @@ func convertCandidates(candidates []*genai.Candidate, usage *genai.GenerateConte
 			Content:    buf.String(),
 			StopReason: string(candidate.FinishReason),
 			ToolCalls:  toolCalls,
+			Thinking:   thinking,
+
+			ReasoningContent: thoughts.String(),
 			GenerationInfo: map[string]any{
 				CITATIONS: candidate.CitationMetadata,
 				SAFETY:    candidate.SafetyRatings,
@@ -194,6 +233,11 @@ func convertParts(parts []llms.ContentPart) ([]*genai.Part, error) {
 			out.Text = p.Text
 		case llms.BinaryContent:
 			out.InlineData = &genai.Blob{MIMEType: p.MIMEType, Data: p.Data}
+		case llms.DocumentContent:
+			if p.FileID != "" {
+				return convertedParts, errors.New("document file ID is not supported, use the inline data")
+			}
+			out.InlineData = &genai.Blob{MIMEType: p.MIMEType, Data: p.Data}
 		case llms.ImageURLContent:
 			typ, data, err := llmutils.DownloadImageData(p.URL)
 			if err != nil {
@@ -210,6 +254,16 @@ func convertParts(parts []llms.ContentPart) ([]*genai.Part, error) {
 				Name: fc.Name,
 				Args: argsMap,
 			}
+		case llms.ThinkingContent:
+			out.Text = p.Thinking
+			out.Thought = true
+			if p.Signature != "" {
+				sig, err := base64.StdEncoding.DecodeString(p.Signature)
+				if err != nil {
+					return convertedParts, errors.Wrap(err, "invalid thought signature")
+				}
+				out.ThoughtSignature = sig
+			}
 		case llms.ToolCallResponse:
 			out.FunctionResponse = &genai.FunctionResponse{
 				Name: p.Name,
@@ -236,7 +290,7 @@ func convertContent(content llms.Message) (*genai.Content, error) {
 	}
 
 	switch content.Role {
-	case llms.RoleSystem:
+	case llms.RoleSystem, llms.RoleDeveloper:
 		c.Role = RoleSystem
 	case llms.RoleAI:
 		c.Role = RoleModel
@@ -257,6 +311,7 @@ func (g *GoogleAI) generateFromMessages(
 	ctx context.Context,
 	messages []llms.Message,
 	config *genai.GenerateContentConfig,
+	opts *llms.CallOptions,
 ) (*llms.ContentResponse, error) {
 	if config == nil {
 		config = &genai.GenerateContentConfig{}
@@ -268,7 +323,7 @@ func (g *GoogleAI) generateFromMessages(
 		if err != nil {
 			return nil, err
 		}
-		if mc.Role == llms.RoleSystem {
+		if mc.Role == llms.RoleSystem || mc.Role == llms.RoleDeveloper {
 			config.SystemInstruction = content
 			continue
 		}
@@ -277,9 +332,9 @@ func (g *GoogleAI) generateFromMessages(
 
 	// When no streaming is requested, just call GenerateContent and return
 	// the complete response with a list of candidates.
-	resp, err := g.client.Models.GenerateContent(ctx, g.opts.DefaultModel, history, config)
+	resp, err := g.generateContent(ctx, history, config, opts.RateLimitFunc)
 	if err != nil {
-		return nil, err
+		return nil, g.providerError(err)
 	}
 
 	if len(resp.Candidates) == 0 {
@@ -288,6 +343,73 @@ func (g *GoogleAI) generateFromMessages(
 	return convertCandidates(resp.Candidates, resp.UsageMetadata)
 }
 
+// MaxRetries is the number of the retries of the rate limited call.
+const MaxRetries = 2
+
+// generateContent calls the model, and retries the rate limited calls with the backoff.
+// The notify is called before the retry, the final attempt is not reported.
+func (g *GoogleAI) generateContent(
+	ctx context.Context,
+	history []*genai.Content,
+	config *genai.GenerateContentConfig,
+	notify func(ctx context.Context, attempt int, wait time.Duration),
+) (*genai.GenerateContentResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := g.client.Models.GenerateContent(ctx, g.opts.DefaultModel, history, config)
+		if err == nil || attempt > MaxRetries || !isRateLimited(err) {
+			return resp, err
+		}
+
+		wait := min(time.Duration(float64(time.Second)*math.Pow(2, float64(attempt-1))), 16*time.Second)
+		if notify != nil {
+			notify(ctx, attempt, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isRateLimited returns true for RESOURCE_EXHAUSTED error.
+func isRateLimited(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.Code == http.StatusTooManyRequests
+}
+
+// asAPIError returns the API error from the chain of err,
+// genai returns it by value or by pointer.
+func asAPIError(err error) (*genai.APIError, bool) {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return &apiErr, true
+	}
+	var apiErrPtr *genai.APIError
+	if errors.As(err, &apiErrPtr) {
+		return apiErrPtr, true
+	}
+	return nil, false
+}
+
+// providerError returns the API error classified by the status code and the status,
+// for example RESOURCE_EXHAUSTED, see llms.ProviderError. The other errors are returned as is.
+func (g *GoogleAI) providerError(err error) error {
+	apiErr, ok := asAPIError(err)
+	if !ok {
+		return err
+	}
+	return (&llms.ProviderError{
+		Provider:   g.GetProviderType(),
+		StatusCode: apiErr.Code,
+		Code:       apiErr.Status,
+		Message:    apiErr.Message,
+		Err:        err,
+	}).Classify()
+}
+
 /*
 TODO: implement streaming
 
@@ -66,6 +66,21 @@ func ConvertTools(tools []llms.Tool) ([]*genai.Tool, error) {
 	return genaiTools, nil
 }
 
+// ConvertToolChoice converts the tool choice to the genai function calling config.
+func ConvertToolChoice(choice any) *genai.ToolConfig {
+	cfg := &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeAuto}
+	switch mode, function := llms.ParseToolChoice(choice); mode {
+	case llms.ToolChoiceNone:
+		cfg.Mode = genai.FunctionCallingConfigModeNone
+	case llms.ToolChoiceRequired:
+		cfg.Mode = genai.FunctionCallingConfigModeAny
+	case llms.ToolChoiceFunction:
+		cfg.Mode = genai.FunctionCallingConfigModeAny
+		cfg.AllowedFunctionNames = []string{function}
+	}
+	return &genai.ToolConfig{FunctionCallingConfig: cfg}
+}
+
 // ConvertJSONSchemaDefinition converts a json_schema response format to a genai.Schema.
 func ConvertJResponseFormatJSONSchema(jschema *schema.ResponseFormatJSONSchema) (*genai.Schema, error) {
 	if jschema == nil {
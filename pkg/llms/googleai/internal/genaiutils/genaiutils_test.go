@@ -383,3 +383,19 @@ func TestConvertTools(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertToolChoice(t *testing.T) {
+	cfg := ConvertToolChoice(llms.FunctionToolChoice("search"))
+	assert.Equal(t, genai.FunctionCallingConfigModeAny, cfg.FunctionCallingConfig.Mode)
+	assert.Equal(t, []string{"search"}, cfg.FunctionCallingConfig.AllowedFunctionNames)
+
+	cfg = ConvertToolChoice("required")
+	assert.Equal(t, genai.FunctionCallingConfigModeAny, cfg.FunctionCallingConfig.Mode)
+	assert.Empty(t, cfg.FunctionCallingConfig.AllowedFunctionNames)
+
+	cfg = ConvertToolChoice("none")
+	assert.Equal(t, genai.FunctionCallingConfigModeNone, cfg.FunctionCallingConfig.Mode)
+
+	cfg = ConvertToolChoice("auto")
+	assert.Equal(t, genai.FunctionCallingConfigModeAuto, cfg.FunctionCallingConfig.Mode)
+}
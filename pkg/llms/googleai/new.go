@@ -4,11 +4,19 @@ package googleai
 
 import (
 	"context"
+	"net/http"
 
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
 	"google.golang.org/genai"
 )
 
+// cloudPlatformScope is the OAuth scope of the Vertex AI API.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
 // GoogleAI is a type that represents a Google AI API client.
 type GoogleAI struct {
 	client *genai.Client
@@ -34,13 +42,21 @@ func New(ctx context.Context, opts ...Option) (*GoogleAI, error) {
 		opts: clientOptions,
 	}
 
+	httpClient, err := clientOptions.httpClient()
+	if err != nil {
+		return gi, err
+	}
+
 	cfg := &genai.ClientConfig{
 		Project:     clientOptions.CloudProject,
 		Location:    clientOptions.CloudLocation,
 		APIKey:      clientOptions.APIKey,
 		Credentials: clientOptions.Credentials,
-		HTTPClient:  clientOptions.HTTPClient,
-		Backend:     genai.BackendGeminiAPI,
+		HTTPClient:  httpClient,
+		Backend:     clientOptions.Backend,
+		HTTPOptions: genai.HTTPOptions{
+			BaseURL: clientOptions.BaseURL,
+		},
 	}
 
 	client, err := genai.NewClient(ctx, cfg)
@@ -57,3 +73,26 @@ func New(ctx context.Context, opts ...Option) (*GoogleAI, error) {
 
 	return gi, nil
 }
+
+// httpClient returns the HTTP client wrapped with the middlewares.
+// genai authenticates only the client it creates, so on Vertex AI
+// the authenticated client is created here to be wrapped.
+func (o *Options) httpClient() (*http.Client, error) {
+	if len(o.HTTPMiddlewares) == 0 {
+		return o.HTTPClient, nil
+	}
+	client := o.HTTPClient
+	if client == nil && o.Backend == genai.BackendVertexAI && o.APIKey == "" {
+		var err error
+		client, err = httptransport.NewClient(&httptransport.Options{
+			Credentials: o.Credentials,
+			DetectOpts: &credentials.DetectOptions{
+				Scopes: []string{cloudPlatformScope},
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create the authenticated HTTP client")
+		}
+	}
+	return llmhttp.WrapClient(client, o.HTTPMiddlewares...), nil
+}
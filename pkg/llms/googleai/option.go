@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"cloud.google.com/go/auth"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
 	"google.golang.org/genai"
 )
 
@@ -20,9 +21,14 @@ type Options struct {
 	DefaultTopK           int
 	DefaultTopP           float64
 	HarmThreshold         genai.HarmBlockThreshold
+	SafetySettings        []*genai.SafetySetting
+	CachedContent         string
 	APIKey                string
 	Credentials           *auth.Credentials
 	HTTPClient            *http.Client
+	HTTPMiddlewares       []llmhttp.Middleware
+	Backend               genai.Backend
+	BaseURL               string
 }
 
 func DefaultOptions() Options {
@@ -37,12 +43,14 @@ func DefaultOptions() Options {
 		DefaultTopK:           3,
 		DefaultTopP:           0.95,
 		HarmThreshold:         genai.HarmBlockThresholdBlockNone,
+		Backend:               genai.BackendGeminiAPI,
 	}
 }
 
 // EnsureAuthPresent attempts to ensure that the client has authentication information. If it does not, it will attempt to use the GOOGLE_API_KEY environment variable.
+// The Vertex AI backend uses the Application Default Credentials instead.
 func (o *Options) EnsureAuthPresent() {
-	if o.Credentials == nil {
+	if o.Credentials == nil && o.Backend != genai.BackendVertexAI {
 		if key := os.Getenv("GOOGLE_API_KEY"); key != "" {
 			WithAPIKey(key)(o)
 		}
@@ -80,6 +88,16 @@ func WithHTTPClient(httpClient *http.Client) Option {
 	}
 }
 
+// WithHTTPMiddleware wraps the HTTP client with the middlewares,
+// for the proxies, the request signing or the extra headers, see llmhttp.Middleware.
+// On Vertex AI without the HTTP client, the middlewares wrap the client
+// authenticated with the credentials. The first middleware is the outermost one.
+func WithHTTPMiddleware(middlewares ...llmhttp.Middleware) Option {
+	return func(opts *Options) {
+		opts.HTTPMiddlewares = append(opts.HTTPMiddlewares, middlewares...)
+	}
+}
+
 // WithCloudProject passes the GCP cloud project name to the client. This is
 // useful for vertex clients.
 func WithCloudProject(p string) Option {
@@ -154,3 +172,34 @@ func WithHarmThreshold(ht genai.HarmBlockThreshold) Option {
 		opts.HarmThreshold = ht
 	}
 }
+
+// WithSafetySettings sets the safety settings of the requests,
+// replacing the settings with the HarmThreshold for all the categories.
+func WithSafetySettings(settings ...*genai.SafetySetting) Option {
+	return func(opts *Options) {
+		opts.SafetySettings = settings
+	}
+}
+
+// WithCachedContent sets the name of the cached content used as the prompt prefix,
+// see CreateCachedContent.
+func WithCachedContent(name string) Option {
+	return func(opts *Options) {
+		opts.CachedContent = name
+	}
+}
+
+// WithBackend sets the API backend, by default the Gemini API of Google AI Studio.
+// Use genai.BackendVertexAI with the cloud project and location for Vertex AI.
+func WithBackend(backend genai.Backend) Option {
+	return func(opts *Options) {
+		opts.Backend = backend
+	}
+}
+
+// WithBaseURL overrides the API endpoint, for example the private or the regional endpoint.
+func WithBaseURL(baseURL string) Option {
+	return func(opts *Options) {
+		opts.BaseURL = baseURL
+	}
+}
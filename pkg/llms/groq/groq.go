@@ -0,0 +1,116 @@
+// Package groq provides the Groq LLM.
+//
+// Groq serves the open weight models with the OpenAI compatible API,
+// so the LLM is the OpenAI client with the Groq endpoint and provider type.
+// The rate limits reported in the response headers are available with RateLimits.
+package groq
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai"
+)
+
+const (
+	// Provider is the provider type of the Groq LLM.
+	Provider = llms.ProviderGroq
+	// TokenEnvVarName is the environment variable with the API key.
+	TokenEnvVarName = "GROQ_API_KEY" //nolint:gosec
+	// DefaultBaseURL is the Groq OpenAI compatible endpoint.
+	DefaultBaseURL = "https://api.groq.com/openai/v1"
+	// DefaultModel is used when the model is not set.
+	DefaultModel = ModelLlama33_70BVersatile
+)
+
+// ErrMissingToken is returned when the API key is not set.
+var ErrMissingToken = errors.New("missing the Groq API key, set it in the GROQ_API_KEY environment variable")
+
+type options struct {
+	token      string
+	model      string
+	baseURL    string
+	httpClient Doer
+}
+
+// Option is a functional option for the Groq LLM.
+type Option func(*options)
+
+// WithToken passes the API key to the client. If not set, the key
+// is read from the GROQ_API_KEY environment variable.
+func WithToken(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// WithModel sets the model, by default DefaultModel.
+func WithModel(model string) Option {
+	return func(opts *options) {
+		opts.model = model
+	}
+}
+
+// WithBaseURL sets the base URL of the API, by default DefaultBaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(opts *options) {
+		opts.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient allows setting a custom HTTP client. If not set, the default value
+// is http.DefaultClient.
+func WithHTTPClient(client Doer) Option {
+	return func(opts *options) {
+		opts.httpClient = client
+	}
+}
+
+// LLM is the Groq LLM.
+type LLM struct {
+	*openai.LLM
+
+	limits *rateLimitRecorder
+}
+
+var _ llms.Model = (*LLM)(nil)
+
+// New returns the Groq LLM.
+func New(opts ...Option) (*LLM, error) {
+	o := &options{
+		token:      os.Getenv(TokenEnvVarName),
+		model:      DefaultModel,
+		baseURL:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.token == "" {
+		return nil, ErrMissingToken
+	}
+
+	limits := &rateLimitRecorder{Doer: o.httpClient}
+	llm, err := openai.New(
+		openai.WithProvider(openai.ProviderGroq),
+		openai.WithToken(o.token),
+		openai.WithModel(o.model),
+		openai.WithBaseURL(o.baseURL),
+		openai.WithHTTPClient(limits),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{
+		LLM:    llm,
+		limits: limits,
+	}, nil
+}
+
+// RateLimits returns the rate limits reported by the last response,
+// or the zero value if no response was received yet.
+func (o *LLM) RateLimits() RateLimits {
+	return o.limits.get()
+}
@@ -0,0 +1,90 @@
+package groq_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/groq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	h := http.Header{}
+	h.Set("x-ratelimit-limit-requests", "14400")
+	h.Set("x-ratelimit-limit-tokens", "18000")
+	h.Set("x-ratelimit-remaining-requests", "14370")
+	h.Set("x-ratelimit-remaining-tokens", "17997")
+	h.Set("x-ratelimit-reset-requests", "2m59.56s")
+	h.Set("x-ratelimit-reset-tokens", "7.66s")
+	h.Set("retry-after", "2")
+
+	assert.Equal(t, groq.RateLimits{
+		LimitRequests:     14400,
+		LimitTokens:       18000,
+		RemainingRequests: 14370,
+		RemainingTokens:   17997,
+		ResetRequests:     2*time.Minute + 59560*time.Millisecond,
+		ResetTokens:       7660 * time.Millisecond,
+		RetryAfter:        2 * time.Second,
+	}, groq.ParseRateLimits(h))
+
+	assert.True(t, groq.ParseRateLimits(http.Header{}).IsZero())
+	assert.True(t, groq.ParseRateLimits(http.Header{"X-Ratelimit-Reset-Tokens": {"soon"}}).IsZero())
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv(groq.TokenEnvVarName, "")
+
+	_, err := groq.New()
+	assert.ErrorIs(t, err, groq.ErrMissingToken)
+
+	llm, err := groq.New(groq.WithToken("test-token"))
+	require.NoError(t, err)
+	assert.Equal(t, groq.DefaultModel, llm.GetName())
+	assert.Equal(t, llms.ProviderGroq, llm.GetProviderType())
+	assert.True(t, llm.GetProviderType().Supports(llms.CapabilityFunctionCalling))
+	assert.True(t, llm.RateLimits().IsZero())
+	assert.Contains(t, groq.Models, groq.DefaultModel)
+}
+
+func TestGenerateContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-ratelimit-limit-tokens", "6000")
+		w.Header().Set("x-ratelimit-remaining-tokens", "5990")
+		w.Header().Set("x-ratelimit-reset-tokens", "100ms")
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"qwen/qwen3-32b",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":8,"completion_tokens":2,"total_tokens":10}}`))
+	}))
+	defer srv.Close()
+
+	llm, err := groq.New(
+		groq.WithToken("test-token"),
+		groq.WithBaseURL(srv.URL),
+		groq.WithModel(groq.ModelQwen3_32B),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, groq.ModelQwen3_32B, llm.GetName())
+
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "hello"),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "Hello", resp.Choices[0].Content)
+
+	assert.Equal(t, groq.RateLimits{
+		LimitTokens:     6000,
+		RemainingTokens: 5990,
+		ResetTokens:     100 * time.Millisecond,
+	}, llm.RateLimits())
+}
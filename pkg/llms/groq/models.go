@@ -0,0 +1,49 @@
+package groq
+
+const (
+	// Llama 3.1 8B is the fast and cheap model for the simple tasks.
+	//
+	// Context window: 131072
+	ModelLlama31_8BInstant = "llama-3.1-8b-instant"
+
+	// Llama 3.3 70B is the general purpose model with the tool use.
+	//
+	// Context window: 131072
+	ModelLlama33_70BVersatile = "llama-3.3-70b-versatile"
+
+	// Llama 4 Scout is the multimodal mixture-of-experts model.
+	//
+	// Context window: 131072
+	ModelLlama4Scout = "meta-llama/llama-4-scout-17b-16e-instruct"
+
+	// GPT-OSS 120B is the OpenAI open weight reasoning model.
+	//
+	// Context window: 131072
+	ModelGPTOSS120B = "openai/gpt-oss-120b"
+
+	// GPT-OSS 20B is the smaller OpenAI open weight reasoning model.
+	//
+	// Context window: 131072
+	ModelGPTOSS20B = "openai/gpt-oss-20b"
+
+	// Qwen3 32B is the reasoning model with the tool use.
+	//
+	// Context window: 131072
+	ModelQwen3_32B = "qwen/qwen3-32b"
+
+	// Kimi K2 is the mixture-of-experts model tuned for the agentic tool use.
+	//
+	// Context window: 262144
+	ModelKimiK2Instruct = "moonshotai/kimi-k2-instruct-0905"
+)
+
+// Models is the list of the chat models served by Groq.
+var Models = []string{
+	ModelLlama31_8BInstant,
+	ModelLlama33_70BVersatile,
+	ModelLlama4Scout,
+	ModelGPTOSS120B,
+	ModelGPTOSS20B,
+	ModelQwen3_32B,
+	ModelKimiK2Instruct,
+}
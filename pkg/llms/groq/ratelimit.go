@@ -0,0 +1,94 @@
+package groq
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimits is the state of the rate limits reported by Groq in the response headers.
+// The zero value of the field means the header was not present.
+type RateLimits struct {
+	// LimitRequests is the requests per day limit, x-ratelimit-limit-requests.
+	LimitRequests int
+	// LimitTokens is the tokens per minute limit, x-ratelimit-limit-tokens.
+	LimitTokens int
+	// RemainingRequests is the requests per day left, x-ratelimit-remaining-requests.
+	RemainingRequests int
+	// RemainingTokens is the tokens per minute left, x-ratelimit-remaining-tokens.
+	RemainingTokens int
+	// ResetRequests is the time until the requests limit resets, x-ratelimit-reset-requests.
+	ResetRequests time.Duration
+	// ResetTokens is the time until the tokens limit resets, x-ratelimit-reset-tokens.
+	ResetTokens time.Duration
+	// RetryAfter is the time to wait before retrying the rate limited request, retry-after.
+	RetryAfter time.Duration
+}
+
+// ParseRateLimits returns the rate limits from the response headers.
+// The reset values are durations such as "2m59.56s" or "7.66s",
+// the retry-after value is in seconds.
+func ParseRateLimits(h http.Header) RateLimits {
+	return RateLimits{
+		LimitRequests:     parseInt(h.Get("x-ratelimit-limit-requests")),
+		LimitTokens:       parseInt(h.Get("x-ratelimit-limit-tokens")),
+		RemainingRequests: parseInt(h.Get("x-ratelimit-remaining-requests")),
+		RemainingTokens:   parseInt(h.Get("x-ratelimit-remaining-tokens")),
+		ResetRequests:     parseDuration(h.Get("x-ratelimit-reset-requests")),
+		ResetTokens:       parseDuration(h.Get("x-ratelimit-reset-tokens")),
+		RetryAfter:        parseDuration(h.Get("retry-after")),
+	}
+}
+
+// IsZero returns true if no rate limit header was present.
+func (r RateLimits) IsZero() bool {
+	return r == RateLimits{}
+}
+
+func parseInt(v string) int {
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func parseDuration(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(s * float64(time.Second))
+	}
+	d, _ := time.ParseDuration(v)
+	return d
+}
+
+// Doer performs a HTTP request.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// rateLimitRecorder is the Doer that records the rate limits of the last response.
+type rateLimitRecorder struct {
+	Doer
+
+	lock sync.RWMutex
+	last RateLimits
+}
+
+func (r *rateLimitRecorder) Do(req *http.Request) (*http.Response, error) {
+	resp, err := r.Doer.Do(req)
+	if err == nil {
+		if limits := ParseRateLimits(resp.Header); !limits.IsZero() {
+			r.lock.Lock()
+			r.last = limits
+			r.lock.Unlock()
+		}
+	}
+	return resp, err
+}
+
+func (r *rateLimitRecorder) get() RateLimits {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.last
+}
@@ -0,0 +1,107 @@
+package llms
+
+import (
+	"context"
+)
+
+// ImageQuality is the quality of the generated images,
+// the providers map it to the closest supported value.
+type ImageQuality string
+
+const (
+	// ImageQualityLow is the fastest and the cheapest quality.
+	ImageQualityLow ImageQuality = "low"
+	// ImageQualityMedium is the standard quality.
+	ImageQualityMedium ImageQuality = "medium"
+	// ImageQualityHigh is the best quality.
+	ImageQualityHigh ImageQuality = "high"
+)
+
+// ImageModel is an interface for models that can generate images.
+// Callers obtain an ImageModel by type-asserting against a concrete provider's *LLM,
+// the providers advertise it with CapabilityImageGeneration.
+type ImageModel interface {
+	// GenerateImages generates the images from the prompt,
+	// and returns them as BinaryContent parts.
+	GenerateImages(ctx context.Context, prompt string, options ...ImageOption) ([]BinaryContent, error)
+}
+
+// ImageOptions is a set of options for GenerateImages.
+type ImageOptions struct {
+	// Model is the image model to use, if empty the provider's default image model is used.
+	Model string `json:"model,omitempty"`
+	// Count is the number of images to generate, default is 1.
+	Count int `json:"count,omitempty"`
+	// Width is the width of the images in pixels.
+	Width int `json:"width,omitempty"`
+	// Height is the height of the images in pixels.
+	Height int `json:"height,omitempty"`
+	// Quality is the quality of the images.
+	Quality ImageQuality `json:"quality,omitempty"`
+	// NegativePrompt describes what should not be in the images,
+	// ignored by the providers that do not support it.
+	NegativePrompt string `json:"negative_prompt,omitempty"`
+	// Seed is the seed for the deterministic generation,
+	// ignored by the providers that do not support it.
+	Seed int `json:"seed,omitempty"`
+}
+
+// ImageOption is a function that configures ImageOptions.
+type ImageOption func(*ImageOptions)
+
+// WithImageModel specifies the image model to use.
+func WithImageModel(model string) ImageOption {
+	return func(o *ImageOptions) {
+		o.Model = model
+	}
+}
+
+// WithImageCount specifies the number of images to generate.
+func WithImageCount(count int) ImageOption {
+	return func(o *ImageOptions) {
+		o.Count = count
+	}
+}
+
+// WithImageSize specifies the size of the images in pixels.
+func WithImageSize(width, height int) ImageOption {
+	return func(o *ImageOptions) {
+		o.Width = width
+		o.Height = height
+	}
+}
+
+// WithImageQuality specifies the quality of the images.
+func WithImageQuality(quality ImageQuality) ImageOption {
+	return func(o *ImageOptions) {
+		o.Quality = quality
+	}
+}
+
+// WithNegativePrompt specifies what should not be in the images.
+func WithNegativePrompt(prompt string) ImageOption {
+	return func(o *ImageOptions) {
+		o.NegativePrompt = prompt
+	}
+}
+
+// WithImageSeed specifies the seed for the deterministic generation.
+func WithImageSeed(seed int) ImageOption {
+	return func(o *ImageOptions) {
+		o.Seed = seed
+	}
+}
+
+// NewImageOptions returns ImageOptions with the options applied.
+func NewImageOptions(options ...ImageOption) *ImageOptions {
+	opts := &ImageOptions{
+		Count: 1,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+	if opts.Count < 1 {
+		opts.Count = 1
+	}
+	return opts
+}
@@ -0,0 +1,123 @@
+// Package llmhttp provides the HTTP middleware of the LLM providers,
+// to wrap the HTTP client of the provider with the custom http.RoundTripper,
+// for the corporate proxies, the request signing, the extra headers or the WAF tokens.
+//
+// The openai, anthropic, bedrock, googleai and vertexai providers accept the middlewares
+// with the WithHTTPMiddleware option, the other providers accept the client
+// wrapped with WrapDoer or WrapClient in the WithHTTPClient option.
+package llmhttp
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Middleware wraps the RoundTripper of the provider HTTP client,
+// similar to the middleware of http.Handler.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts the function to http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Doer performs the HTTP request, it is implemented by *http.Client,
+// and accepted by the providers as the HTTP client.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps the RoundTripper with the middlewares,
+// the first middleware is the outermost one.
+// If the base is nil, http.DefaultTransport is used.
+func Chain(base http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			base = middlewares[i](base)
+		}
+	}
+	return base
+}
+
+// WrapClient returns the copy of the client with the transport wrapped with the middlewares,
+// the client is not modified. If the client is nil, http.DefaultClient is used.
+func WrapClient(client *http.Client, middlewares ...Middleware) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(middlewares) == 0 {
+		return client
+	}
+	cp := *client
+	cp.Transport = Chain(client.Transport, middlewares...)
+	return &cp
+}
+
+// WrapDoer returns Doer that sends the requests through the middlewares.
+// *http.Client is wrapped with WrapClient, the other Doers, such as the clients of the SDKs,
+// are called by the innermost RoundTripper. If the doer is nil, http.DefaultClient is used.
+func WrapDoer(doer Doer, middlewares ...Middleware) Doer {
+	switch d := doer.(type) {
+	case nil:
+		return WrapClient(nil, middlewares...)
+	case *http.Client:
+		return WrapClient(d, middlewares...)
+	}
+	if len(middlewares) == 0 {
+		return doer
+	}
+	return doerFunc(Chain(RoundTripperFunc(doer.Do), middlewares...).RoundTrip)
+}
+
+// Headers returns the Middleware that sets the headers on each request,
+// the values replace the headers set by the provider.
+func Headers(headers http.Header) Middleware {
+	return OnRequest(func(req *http.Request) error {
+		for key, values := range headers {
+			req.Header.Del(key)
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+		return nil
+	})
+}
+
+// OnRequest returns the Middleware that calls the fn with the copy of each request
+// before it is sent, to sign the request or to inject the tokens.
+// The error returned by the fn fails the request.
+// The fn that reads the body must restore it, for example with req.GetBody.
+func OnRequest(fn func(req *http.Request) error) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			if err := fn(req); err != nil {
+				if req.Body != nil {
+					_ = req.Body.Close()
+				}
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// ProxyTransport returns the copy of http.DefaultTransport, that sends the requests
+// through the proxy, to be used as the transport of the client passed to WithHTTPClient.
+func ProxyTransport(proxyURL *url.URL) http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return transport
+}
@@ -0,0 +1,131 @@
+package llmhttp_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// order returns the middleware that appends the name to the X-Order header
+func order(name string) llmhttp.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return llmhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Add("X-Order", name)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// doerFunc is the Doer of SDK, that is not *http.Client
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(strings.Join(r.Header.Values("X-Order"), ",") + "|" +
+			r.Header.Get("X-Api-Key") + "|" + r.Header.Get("X-Signature") + "|" + string(body)))
+	}))
+	defer srv.Close()
+
+	middlewares := []llmhttp.Middleware{
+		order("first"),
+		nil,
+		llmhttp.Headers(http.Header{"x-api-key": {"waf-token"}}),
+		llmhttp.OnRequest(func(req *http.Request) error {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			data, _ := io.ReadAll(body)
+			req.Header.Set("X-Signature", "sig:"+string(data))
+			return nil
+		}),
+		order("last"),
+	}
+
+	send := func(t *testing.T, doer llmhttp.Doer) string {
+		req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+		require.NoError(t, err)
+		req.Header.Set("X-Api-Key", "provider-key")
+		res, err := doer.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = res.Body.Close() }()
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		// the request of the caller is not modified
+		assert.Equal(t, "provider-key", req.Header.Get("X-Api-Key"))
+		return string(data)
+	}
+
+	t.Run("client", func(t *testing.T) {
+		client := srv.Client()
+		wrapped := llmhttp.WrapClient(client, middlewares...)
+		assert.NotSame(t, client, wrapped)
+		assert.Equal(t, "first,last|waf-token|sig:payload|payload", send(t, wrapped))
+		// the original client is not modified
+		assert.Equal(t, "|provider-key||payload", send(t, client))
+	})
+
+	t.Run("doer", func(t *testing.T) {
+		calls := 0
+		sdk := doerFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return http.DefaultClient.Do(req)
+		})
+		assert.Equal(t, "first,last|waf-token|sig:payload|payload", send(t, llmhttp.WrapDoer(sdk, middlewares...)))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		assert.Equal(t, "first,last|waf-token|sig:payload|payload", send(t, llmhttp.WrapDoer(nil, middlewares...)))
+		assert.Same(t, http.DefaultClient, llmhttp.WrapDoer(nil))
+		assert.Same(t, http.DefaultClient, llmhttp.WrapClient(nil))
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		client := llmhttp.WrapClient(nil, llmhttp.OnRequest(func(req *http.Request) error {
+			return errors.New("no WAF token")
+		}))
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		_, err = client.Do(req)
+		assert.ErrorContains(t, err, "no WAF token")
+	})
+}
+
+func TestProxyTransport(t *testing.T) {
+	t.Parallel()
+
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		_, _ = w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	client := llmhttp.WrapClient(&http.Client{Transport: llmhttp.ProxyTransport(proxyURL)}, order("first"))
+	res, err := client.Get("http://api.example.com/v1/chat/completions")
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "proxied", string(data))
+	assert.Equal(t, "http://api.example.com/v1/chat/completions", proxied)
+}
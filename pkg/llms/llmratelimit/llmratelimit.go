@@ -0,0 +1,198 @@
+// Package llmratelimit provides llms.Model decorator that enforces
+// requests-per-minute and tokens-per-minute budgets on the client side,
+// so many concurrent assistants sharing the same model do not hit the provider 429s.
+//
+// The budgets are token buckets per model name, the prompt tokens are estimated
+// before the call, and the bucket is charged with the actual usage reported by the provider.
+// The calls over the budget are queued until the budget is available,
+// or rejected with ErrRateLimited, see WithMaxWait.
+// The queued calls are reported to llms.CallOptions.RateLimitFunc.
+package llmratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/tokenizer"
+	"github.com/effective-security/x/values"
+	"github.com/effective-security/xlog"
+	"golang.org/x/time/rate"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "llmratelimit")
+
+// ErrRateLimited is returned when the call would wait for the budget longer than allowed,
+// it matches llms.ErrRateLimited with errors.Is.
+var ErrRateLimited = errors.Mark(errors.New("rate limit exceeded"), llms.ErrRateLimited)
+
+// TokenCounter returns the estimated number of the prompt tokens.
+type TokenCounter func(model string, messages []llms.Message) int
+
+// Option configures the Model.
+type Option func(*Model)
+
+// WithTokenCounter sets the counter of the prompt tokens, by default tokenizer.CountMessageTokens.
+func WithTokenCounter(counter TokenCounter) Option {
+	return func(m *Model) {
+		m.counter = counter
+	}
+}
+
+// WithMaxWait sets the maximum time the call waits for the budget,
+// the calls that would wait longer are rejected with ErrRateLimited.
+// Zero rejects the calls over the budget instead of queueing them.
+// By default, the calls are queued until the context is done.
+func WithMaxWait(d time.Duration) Option {
+	return func(m *Model) {
+		m.maxWait = d
+	}
+}
+
+// Model is llms.Model decorator that enforces the rate limits.
+// It is safe for concurrent use, and should be shared by all the callers of the model.
+type Model struct {
+	llms.Model
+
+	rpm     int
+	tpm     int
+	counter TokenCounter
+	maxWait time.Duration
+
+	lock    sync.Mutex
+	buckets map[string]*buckets
+}
+
+type buckets struct {
+	requests *rate.Limiter
+	tokens   *rate.Limiter
+}
+
+var (
+	_ llms.Model              = (*Model)(nil)
+	_ llms.CapabilityReporter = (*Model)(nil)
+)
+
+// Wrap returns the model that allows up to rpm requests and tpm tokens per minute,
+// per model name. Zero or negative value disables the corresponding limit.
+func Wrap(model llms.Model, rpm, tpm int, opts ...Option) *Model {
+	m := &Model{
+		Model:   model,
+		rpm:     rpm,
+		tpm:     tpm,
+		counter: tokenizer.CountMessageTokens,
+		maxWait: -1,
+		buckets: map[string]*buckets{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Capabilities implements llms.CapabilityReporter, and returns the capabilities of the wrapped model.
+func (m *Model) Capabilities() llms.Capability {
+	return llms.ModelCapabilities(m.Model, m.GetProviderType())
+}
+
+// GenerateContent implements the Model interface.
+func (m *Model) GenerateContent(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	name := values.StringsCoalesce(opts.Model, m.GetName())
+	b := m.bucketsFor(name)
+
+	estimated := 0
+	if b.tokens != nil {
+		estimated = m.counter(name, messages)
+	}
+	if err := m.wait(ctx, name, b, estimated, opts.RateLimitFunc); err != nil {
+		return nil, err
+	}
+
+	resp, err := m.Model.GenerateContent(ctx, messages, options...)
+	if b.tokens != nil && resp != nil {
+		// charge the tokens above the estimate, the bucket can go into debt,
+		// which delays the next calls
+		usage := resp.Usage()
+		if extra := int(usage.InputTokens+usage.OutputTokens) - estimated; extra > 0 {
+			b.tokens.ReserveN(time.Now(), min(extra, b.tokens.Burst()))
+		}
+	}
+	return resp, err
+}
+
+func (m *Model) bucketsFor(name string) *buckets {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	b := m.buckets[name]
+	if b == nil {
+		b = &buckets{}
+		if m.rpm > 0 {
+			b.requests = rate.NewLimiter(rate.Limit(float64(m.rpm)/60), m.rpm)
+		}
+		if m.tpm > 0 {
+			b.tokens = rate.NewLimiter(rate.Limit(float64(m.tpm)/60), m.tpm)
+		}
+		m.buckets[name] = b
+	}
+	return b
+}
+
+func (m *Model) wait(ctx context.Context, name string, b *buckets, tokens int, notify func(context.Context, int, time.Duration)) error {
+	now := time.Now()
+
+	var reservations []*rate.Reservation
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+
+	var delay time.Duration
+	if b.requests != nil {
+		r := b.requests.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		delay = r.DelayFrom(now)
+	}
+	if b.tokens != nil && tokens > 0 {
+		// the prompt larger than the budget is allowed to wait for the full bucket
+		r := b.tokens.ReserveN(now, min(tokens, b.tokens.Burst()))
+		reservations = append(reservations, r)
+		delay = max(delay, r.DelayFrom(now))
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	if m.maxWait >= 0 && delay > m.maxWait {
+		cancel()
+		return errors.Wrapf(ErrRateLimited, "model %s: retry after %s", name, delay.Round(time.Millisecond))
+	}
+
+	logger.ContextKV(ctx, xlog.DEBUG,
+		"status", "waiting",
+		"model", name,
+		"delay", delay,
+		"tokens", tokens,
+	)
+	if notify != nil {
+		notify(ctx, 1, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return errors.WithStack(ctx.Err())
+	}
+}
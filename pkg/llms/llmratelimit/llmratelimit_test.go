@@ -0,0 +1,130 @@
+package llmratelimit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/llmratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeModel struct {
+	usage llms.Usage
+	calls int
+}
+
+func (m *fakeModel) GetName() string                    { return "fake-model" }
+func (m *fakeModel) GetProviderType() llms.ProviderType { return llms.ProviderOpenAI }
+
+func (m *fakeModel) GenerateContent(_ context.Context, _ []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls++
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: "ok", Usage: m.usage}},
+	}, nil
+}
+
+func messages(size int) []llms.Message {
+	// the role is 5 bytes
+	return []llms.Message{llms.MessageFromTextParts(llms.RoleHuman, strings.Repeat("a", size-5))}
+}
+
+func TestRequestsPerMinute(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeModel{}
+	m := llmratelimit.Wrap(llm, 2, 0, llmratelimit.WithMaxWait(0))
+	assert.Equal(t, "fake-model", m.GetName())
+	assert.Equal(t, llms.ProviderCapabilities(llms.ProviderOpenAI), m.Capabilities())
+
+	ctx := context.Background()
+	for range 2 {
+		_, err := m.GenerateContent(ctx, messages(100))
+		require.NoError(t, err)
+	}
+	_, err := m.GenerateContent(ctx, messages(100))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, llmratelimit.ErrRateLimited))
+	assert.Contains(t, err.Error(), "model fake-model: retry after")
+
+	// the budget is per model
+	_, err = m.GenerateContent(ctx, messages(100), llms.WithModel("other-model"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, llm.calls)
+}
+
+func TestTokensPerMinute(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeModel{}
+	m := llmratelimit.Wrap(llm, 0, 1000, llmratelimit.WithMaxWait(0))
+	ctx := context.Background()
+
+	// 1900 bytes is 480 tokens
+	_, err := m.GenerateContent(ctx, messages(1900))
+	require.NoError(t, err)
+	_, err = m.GenerateContent(ctx, messages(1900))
+	require.NoError(t, err)
+	_, err = m.GenerateContent(ctx, messages(1900))
+	assert.True(t, errors.Is(err, llmratelimit.ErrRateLimited))
+
+	// the actual usage is charged
+	llm = &fakeModel{usage: llms.Usage{InputTokens: 400, OutputTokens: 600}}
+	m = llmratelimit.Wrap(llm, 0, 1000,
+		llmratelimit.WithMaxWait(time.Millisecond),
+		llmratelimit.WithTokenCounter(func(model string, _ []llms.Message) int {
+			assert.Equal(t, "fake-model", model)
+			return 10
+		}),
+	)
+	_, err = m.GenerateContent(ctx, messages(100))
+	require.NoError(t, err)
+	_, err = m.GenerateContent(ctx, messages(100))
+	assert.True(t, errors.Is(err, llmratelimit.ErrRateLimited))
+	assert.Equal(t, 1, llm.calls)
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	llm := &fakeModel{}
+	tokens := 600
+	// 10 tokens per second
+	m := llmratelimit.Wrap(llm, 0, 600, llmratelimit.WithTokenCounter(func(string, []llms.Message) int {
+		return tokens
+	}))
+	ctx := context.Background()
+
+	_, err := m.GenerateContent(ctx, messages(100))
+	require.NoError(t, err)
+
+	tokens = 5
+	var waited time.Duration
+	started := time.Now()
+	_, err = m.GenerateContent(ctx, messages(100), llms.WithRateLimitFunc(func(_ context.Context, attempt int, wait time.Duration) {
+		assert.Equal(t, 1, attempt)
+		waited = wait
+	}))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(started), 400*time.Millisecond)
+	assert.Greater(t, waited, 400*time.Millisecond)
+
+	tokens = 600
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = m.GenerateContent(ctx, messages(100))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 2, llm.calls)
+
+	// the cancelled call does not consume the budget
+	time.Sleep(600 * time.Millisecond)
+	tokens = 5
+	started = time.Now()
+	_, err = m.GenerateContent(context.Background(), messages(100))
+	require.NoError(t, err)
+	assert.Less(t, time.Since(started), 300*time.Millisecond)
+}
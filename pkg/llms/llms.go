@@ -18,16 +18,33 @@ const (
 	ProviderAzure ProviderType = "AZURE"
 	// ProviderAzureAD is the type of provider.
 	ProviderAzureAD ProviderType = "AZURE_AD"
+	// ProviderAzureAI is the type of provider, for the Azure AI Foundry models,
+	// such as Llama, Phi and Mistral, served with the Azure AI model inference API.
+	ProviderAzureAI ProviderType = "AZURE_AI"
 	// ProviderBedrock is the type of provider.
 	ProviderBedrock ProviderType = "BEDROCK"
+	// ProviderCohere is the type of provider, for the Cohere Command models.
+	ProviderCohere ProviderType = "COHERE"
 	// ProviderCloudflare is the type of provider.
 	ProviderCloudflare ProviderType = "CLOUDFLARE"
 	// ProviderGoogleAI is the type of provider.
 	ProviderGoogleAI ProviderType = "GOOGLEAI"
+	// ProviderVertexAI is the type of provider, for Gemini on GCP Vertex AI.
+	ProviderVertexAI ProviderType = "VERTEX_AI"
 	// ProviderOpenAI is the type of provider.
 	ProviderOpenAI ProviderType = "OPENAI"
 	// ProviderPerplexity is the type of provider.
 	ProviderPerplexity ProviderType = "PERPLEXITY"
+	// ProviderVLLM is the type of provider, for the OpenAI compatible vLLM server.
+	ProviderVLLM ProviderType = "VLLM"
+	// ProviderLlamaCpp is the type of provider, for the OpenAI compatible llama.cpp server.
+	ProviderLlamaCpp ProviderType = "LLAMACPP"
+	// ProviderGroq is the type of provider, for the OpenAI compatible Groq API.
+	ProviderGroq ProviderType = "GROQ"
+	// ProviderDeepSeek is the type of provider, for the OpenAI compatible DeepSeek API.
+	ProviderDeepSeek ProviderType = "DEEPSEEK"
+	// ProviderOpenRouter is the type of provider, for the OpenAI compatible OpenRouter API.
+	ProviderOpenRouter ProviderType = "OPENROUTER"
 )
 
 // Model is an interface multi-modal models implement.
@@ -85,6 +102,24 @@ const (
 	// Asynchronous batch processing via the provider's Batch API.
 	// Providers that advertise this capability also implement [Batcher].
 	CapabilityBatch
+
+	// Developer role messages, other providers fold them into the system prompt.
+	CapabilityDeveloperRole
+
+	// Constrained decoding with the grammar or the regular expression.
+	CapabilityConstrainedDecoding
+
+	// Reasoning effort control, see WithReasoningEffort.
+	CapabilityReasoningEffort
+
+	// Thinking tokens budget and the thinking blocks in the response, see WithThinkingBudget.
+	CapabilityThinkingBudget
+
+	// Inline images as BinaryContent in the user messages,
+	// the other providers with CapabilityVision take only the image URLs.
+	CapabilityInlineImage
+	// Inline files, like PDF documents, as BinaryContent in the user messages.
+	CapabilityInlineFile
 )
 
 var providerCapabilities = map[ProviderType]Capability{
@@ -99,7 +134,13 @@ var providerCapabilities = map[ProviderType]Capability{
 		CapabilityVision |
 		CapabilityWebSearchTool |
 		CapabilityPromptCaching |
-		CapabilityBatch,
+		CapabilityBatch |
+		CapabilityDeveloperRole |
+		CapabilityReasoningEffort |
+		CapabilityImageGeneration |
+		CapabilityAudioTranscription |
+		CapabilityInlineImage |
+		CapabilityInlineFile,
 
 	ProviderAnthropic: CapabilityText |
 		CapabilityJSONResponse |
@@ -108,14 +149,20 @@ var providerCapabilities = map[ProviderType]Capability{
 		CapabilityMultiToolCalling |
 		CapabilitySystemPrompt |
 		CapabilityWebSearchTool |
-		CapabilityPromptCaching,
+		CapabilityPromptCaching |
+		CapabilityReasoningEffort |
+		CapabilityThinkingBudget |
+		CapabilityInlineImage,
 
 	ProviderAnthropicBedrock: CapabilityText |
 		CapabilityJSONResponse |
 		CapabilityJSONSchema |
 		CapabilityFunctionCalling |
 		CapabilityMultiToolCalling |
-		CapabilitySystemPrompt,
+		CapabilitySystemPrompt |
+		CapabilityReasoningEffort |
+		CapabilityThinkingBudget |
+		CapabilityInlineImage,
 	//CapabilityWebSearchTool |
 	//CapabilityPromptCaching,
 
@@ -126,17 +173,44 @@ var providerCapabilities = map[ProviderType]Capability{
 		CapabilityFunctionCalling |
 		CapabilityMultiToolCalling |
 		CapabilityVision |
-		CapabilityWebSearchTool,
+		CapabilityWebSearchTool |
+		CapabilityReasoningEffort |
+		CapabilityThinkingBudget |
+		CapabilityInlineImage |
+		CapabilityInlineFile,
+
+	ProviderVertexAI: CapabilityText |
+		CapabilitySystemPrompt |
+		CapabilityJSONResponse |
+		CapabilityFunctionCalling |
+		CapabilityMultiToolCalling |
+		CapabilityVision |
+		CapabilityWebSearchTool |
+		CapabilityReasoningEffort |
+		CapabilityThinkingBudget |
+		CapabilityPromptCaching |
+		CapabilityInlineImage |
+		CapabilityInlineFile,
 
 	// Use Bedrock with Anthropic models
 	ProviderBedrock: CapabilityText |
 		CapabilityJSONResponse |
 		CapabilityFunctionCalling |
 		CapabilityMultiToolCalling |
-		CapabilitySystemPrompt,
+		CapabilitySystemPrompt |
+		CapabilityImageGeneration,
 
 	ProviderCloudflare: CapabilityText,
 
+	ProviderCohere: CapabilityText |
+		CapabilityJSONResponse |
+		CapabilityJSONSchema |
+		CapabilityFunctionCalling |
+		CapabilityMultiToolCalling |
+		CapabilityToolCallStreaming |
+		CapabilitySystemPrompt |
+		CapabilityVision,
+
 	ProviderPerplexity: CapabilityText |
 		CapabilitySystemPrompt |
 		CapabilityJSONResponse |
@@ -149,10 +223,68 @@ var providerCapabilities = map[ProviderType]Capability{
 		CapabilityJSONSchemaStrict |
 		CapabilityFunctionCalling |
 		CapabilityMultiToolCalling |
-		CapabilitySystemPrompt,
+		CapabilitySystemPrompt |
+		CapabilityReasoningEffort |
+		CapabilityAudioTranscription,
 	//CapabilityPromptCaching,
 
 	ProviderAzureAD: CapabilityText, // Proxy passthrough
+
+	ProviderAzureAI: CapabilityText |
+		CapabilityJSONResponse |
+		CapabilityFunctionCalling |
+		CapabilityMultiToolCalling |
+		CapabilityToolCallStreaming |
+		CapabilitySystemPrompt,
+
+	ProviderVLLM: CapabilityText |
+		CapabilityJSONResponse |
+		CapabilityJSONSchema |
+		CapabilityFunctionCalling |
+		CapabilitySystemPrompt |
+		CapabilitySelfHosted |
+		CapabilityConstrainedDecoding,
+
+	ProviderLlamaCpp: CapabilityText |
+		CapabilityJSONResponse |
+		CapabilityJSONSchema |
+		CapabilitySystemPrompt |
+		CapabilitySelfHosted |
+		CapabilityConstrainedDecoding,
+
+	ProviderGroq: CapabilityText |
+		CapabilityJSONResponse |
+		CapabilityJSONSchema |
+		CapabilityFunctionCalling |
+		CapabilityMultiToolCalling |
+		CapabilityToolCallStreaming |
+		CapabilitySystemPrompt |
+		CapabilityReasoningEffort,
+
+	ProviderDeepSeek: CapabilityText |
+		CapabilityJSONResponse |
+		CapabilityFunctionCalling |
+		CapabilityMultiToolCalling |
+		CapabilityToolCallStreaming |
+		CapabilitySystemPrompt |
+		CapabilityPromptCaching,
+
+	ProviderOpenRouter: CapabilityText |
+		CapabilityJSONResponse |
+		CapabilityJSONSchema |
+		CapabilityFunctionCalling |
+		CapabilityMultiToolCalling |
+		CapabilityToolCallStreaming |
+		CapabilitySystemPrompt |
+		CapabilityVision |
+		CapabilityReasoningEffort,
+}
+
+// RegisterProviderCapabilities registers the capabilities of the provider
+// implemented outside of this package, replacing the existing ones.
+// It is not safe for concurrent use, and must be called from init().
+func RegisterProviderCapabilities(pt ProviderType, caps Capability) {
+	providerCapabilities[pt] = caps
 }
 
 func ProviderCapabilities(pt ProviderType) Capability {
@@ -163,6 +295,27 @@ func (p ProviderType) Supports(cap Capability) bool {
 	return ProviderCapabilities(p)&cap != 0
 }
 
+// Supports returns true if all the capabilities are set.
+func (c Capability) Supports(cap Capability) bool {
+	return c&cap == cap
+}
+
+// CapabilityReporter is an optional interface of the Model,
+// implemented when the capabilities of the model differ from its provider,
+// for example by the wrappers that emulate the features.
+type CapabilityReporter interface {
+	Capabilities() Capability
+}
+
+// ModelCapabilities returns the capabilities of the model with the provider type,
+// use it to avoid calling GetProviderType more than once.
+func ModelCapabilities(m Model, pt ProviderType) Capability {
+	if r, ok := m.(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+	return ProviderCapabilities(pt)
+}
+
 // GenerateFromSinglePrompt is a convenience function for calling an LLM with
 // a single string prompt, expecting a single string response. It's useful for
 // simple, string-only interactions and provides a slightly more ergonomic API
@@ -0,0 +1,150 @@
+// Package llmtest provides the conformance tests for llms.Model implementations.
+//
+// The provider packages run the suite against the model configured with
+// a stub server, or with the real API when the credentials are available:
+//
+//	func TestConformance(t *testing.T) {
+//		llm, err := New(WithToken("fake"), WithBaseURL(srv.URL))
+//		require.NoError(t, err)
+//		llmtest.Run(t, llm)
+//	}
+package llmtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Option configures the conformance suite.
+type Option func(*config)
+
+type config struct {
+	skip map[string]bool
+}
+
+// WithSkip skips the tests with the names, for example "Streaming".
+func WithSkip(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.skip[name] = true
+		}
+	}
+}
+
+// Tests returns the names of the tests of the suite.
+func Tests() []string {
+	names := make([]string, 0, len(suite))
+	for _, tc := range suite {
+		names = append(names, tc.name)
+	}
+	return names
+}
+
+type testCase struct {
+	name string
+	// capability is required by the test, zero means no requirements
+	capability llms.Capability
+	run        func(t *testing.T, llm llms.Model)
+}
+
+var suite = []testCase{
+	{name: "Metadata", run: testMetadata},
+	{name: "GenerateContent", run: testGenerateContent},
+	{name: "SystemPrompt", capability: llms.CapabilitySystemPrompt, run: testSystemPrompt},
+	{name: "MultiTurn", run: testMultiTurn},
+	{name: "Streaming", run: testStreaming},
+	{name: "Cancelled", run: testCancelled},
+}
+
+// Run runs the conformance tests against the model.
+// The tests that require a capability not supported by the provider are skipped.
+func Run(t *testing.T, llm llms.Model, opts ...Option) {
+	t.Helper()
+
+	cfg := &config{skip: map[string]bool{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	for _, tc := range suite {
+		t.Run(tc.name, func(t *testing.T) {
+			if cfg.skip[tc.name] {
+				t.Skipf("%s is skipped", tc.name)
+			}
+			if tc.capability != 0 && !llm.GetProviderType().Supports(tc.capability) {
+				t.Skipf("%s is not supported by %s", tc.name, llm.GetProviderType())
+			}
+			tc.run(t, llm)
+		})
+	}
+}
+
+func testMetadata(t *testing.T, llm llms.Model) {
+	assert.NotEmpty(t, llm.GetName(), "model name")
+	require.NotEmpty(t, llm.GetProviderType(), "provider type")
+	assert.True(t, llm.GetProviderType().Supports(llms.CapabilityText), "provider capabilities are not registered")
+}
+
+func testGenerateContent(t *testing.T, llm llms.Model) {
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "Reply with a single word: hello"),
+	})
+	require.NoError(t, err)
+	requireContent(t, resp)
+}
+
+func testSystemPrompt(t *testing.T, llm llms.Model) {
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleSystem, "You are a helpful assistant."),
+		llms.MessageFromTextParts(llms.RoleHuman, "Reply with a single word: hello"),
+	})
+	require.NoError(t, err)
+	requireContent(t, resp)
+}
+
+func testMultiTurn(t *testing.T, llm llms.Model) {
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "My name is Bob."),
+		llms.MessageFromTextParts(llms.RoleAI, "Hello Bob!"),
+		llms.MessageFromTextParts(llms.RoleHuman, "What is my name?"),
+	})
+	require.NoError(t, err)
+	requireContent(t, resp)
+}
+
+func testStreaming(t *testing.T, llm llms.Model) {
+	var chunks []string
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "Reply with a single word: hello"),
+	}, llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	}))
+	require.NoError(t, err)
+	requireContent(t, resp)
+	require.NotEmpty(t, chunks, "streaming function was not called")
+	assert.Equal(t, resp.Choices[0].Content, strings.Join(chunks, ""), "streamed chunks do not match the content")
+}
+
+func testCancelled(t *testing.T, llm llms.Model) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := llm.GenerateContent(ctx, []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "Reply with a single word: hello"),
+	})
+	require.Error(t, err)
+}
+
+func requireContent(t *testing.T, resp *llms.ContentResponse) {
+	t.Helper()
+	require.NotNil(t, resp)
+	require.NotEmpty(t, resp.Choices)
+	require.NotNil(t, resp.Choices[0])
+	assert.NotEmpty(t, resp.Choices[0].Content)
+}
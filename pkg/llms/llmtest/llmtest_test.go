@@ -0,0 +1,42 @@
+package llmtest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/llmtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubModel replies "hello" in two chunks
+type stubModel struct{}
+
+func (stubModel) GetName() string { return "stub" }
+
+func (stubModel) GetProviderType() llms.ProviderType { return llms.ProviderCloudflare }
+
+func (stubModel) GenerateContent(ctx context.Context, _ []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.StreamingFunc != nil {
+		for _, chunk := range []string{"hel", "lo"} {
+			if err := opts.StreamingFunc(ctx, []byte(chunk)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "hello"}}}, nil
+}
+
+func TestRun(t *testing.T) {
+	assert.Equal(t, []string{"Metadata", "GenerateContent", "SystemPrompt", "MultiTurn", "Streaming", "Cancelled"}, llmtest.Tests())
+
+	// Cloudflare does not advertise the system prompt, the test is skipped
+	llmtest.Run(t, stubModel{}, llmtest.WithSkip("MultiTurn"))
+}
@@ -11,9 +11,10 @@ import (
 
 // MessageContentJSON represents the JSON structure for MessageContent
 type MessageContentJSON struct {
-	Role   Role           `json:"role"`
-	Text   string         `json:"text,omitempty"`
-	Source *MessageSource `json:"source,omitempty"`
+	Role     Role           `json:"role"`
+	Text     string         `json:"text,omitempty"`
+	Source   *MessageSource `json:"source,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
 // ContentPartJSON represents the JSON structure for content parts
@@ -24,6 +25,21 @@ type ContentPartJSON struct {
 	Binary       *BinaryJSON       `json:"binary,omitempty"`
 	ToolCall     *ToolCallJSON     `json:"tool_call,omitempty"`
 	ToolResponse *ToolResponseJSON `json:"tool_response,omitempty"`
+	Thinking     *ThinkingJSON     `json:"thinking,omitempty"`
+	Document     *DocumentJSON     `json:"document,omitempty"`
+}
+
+// ThinkingJSON represents the JSON structure for thinking content
+type ThinkingJSON struct {
+	Thinking     string `json:"thinking"`
+	Signature    string `json:"signature,omitempty"`
+	RedactedData string `json:"redacted_data,omitempty"`
+}
+
+// ThinkingContentJSON represents the JSON structure for thinking content
+type ThinkingContentJSON struct {
+	Type     string       `json:"type"`
+	Thinking ThinkingJSON `json:"thinking"`
 }
 
 // ImageURLJSON represents the JSON structure for image URL content
@@ -38,6 +54,14 @@ type BinaryJSON struct {
 	MIMEType string `json:"mime_type"`
 }
 
+// DocumentJSON represents the JSON structure for document content
+type DocumentJSON struct {
+	MIMEType string `json:"mime_type,omitempty"`
+	Data     string `json:"data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
 // ToolCallJSON represents the JSON structure for tool call content
 type ToolCallJSON struct {
 	ID           string        `json:"id"`
@@ -70,6 +94,12 @@ type BinaryContentJSON struct {
 	Binary BinaryJSON `json:"binary"`
 }
 
+// DocumentContentJSON represents the JSON structure for document content
+type DocumentContentJSON struct {
+	Type     string       `json:"type"`
+	Document DocumentJSON `json:"document"`
+}
+
 // ToolCallContentJSON represents the JSON structure for tool call content
 type ToolCallContentJSON struct {
 	Type     string       `json:"type"`
@@ -84,17 +114,19 @@ type ToolResponseContentJSON struct {
 
 // MessageContentWithPartsJSON represents the JSON structure for MessageContent with parts
 type MessageContentWithPartsJSON struct {
-	Role   Role           `json:"role"`
-	Parts  []ContentPart  `json:"parts"`
-	Source *MessageSource `json:"source,omitempty"`
+	Role     Role           `json:"role"`
+	Parts    []ContentPart  `json:"parts"`
+	Source   *MessageSource `json:"source,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
 // ToMessageContentWithPartsJSON converts MessageContent to MessageContentWithPartsJSON
 func (mc *Message) ToMessageContentWithPartsJSON() *MessageContentWithPartsJSON {
 	return &MessageContentWithPartsJSON{
-		Role:   mc.Role,
-		Parts:  mc.Parts,
-		Source: mc.Source,
+		Role:     mc.Role,
+		Parts:    mc.Parts,
+		Source:   mc.Source,
+		Metadata: mc.Metadata,
 	}
 }
 
@@ -113,9 +145,10 @@ func (mc Message) MarshalJSON() ([]byte, error) {
 	if len(mc.Parts) == 1 {
 		if tp, hasSingleTextPart := mc.Parts[0].(TextContent); hasSingleTextPart {
 			return json.Marshal(MessageContentJSON{
-				Role:   mc.Role,
-				Text:   tp.Text,
-				Source: mc.Source,
+				Role:     mc.Role,
+				Text:     tp.Text,
+				Source:   mc.Source,
+				Metadata: mc.Metadata,
 			})
 		}
 	}
@@ -133,6 +166,7 @@ func (mc *Message) UnmarshalJSON(data []byte) error {
 
 	mc.Role = msgJSON.Role
 	mc.Source = msgJSON.Source
+	mc.Metadata = msgJSON.Metadata
 
 	// Handle special case: single text field
 	if msgJSON.Text != "" {
@@ -217,6 +251,16 @@ func unmarshalContentPart(partJSON ContentPartJSON) (ContentPart, error) {
 			Name:       partJSON.ToolResponse.Name,
 			Content:    partJSON.ToolResponse.Content,
 		}, nil
+	case "thinking":
+		if partJSON.Thinking == nil {
+			return nil, errors.New("thinking field is required for thinking type")
+		}
+		return ThinkingContent(*partJSON.Thinking), nil
+	case "document":
+		if partJSON.Document == nil {
+			return nil, errors.New("document field is required for document type")
+		}
+		return partJSON.Document.toDocumentContent()
 	default:
 		return nil, errors.Newf("unknown content type: '%s'", partJSON.Type)
 	}
@@ -308,6 +352,61 @@ func (bc *BinaryContent) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements json.Marshaler for DocumentContent
+func (dc DocumentContent) MarshalJSON() ([]byte, error) {
+	doc := DocumentJSON{
+		MIMEType: dc.MIMEType,
+		FileID:   dc.FileID,
+		Title:    dc.Title,
+	}
+	if len(dc.Data) > 0 {
+		doc.Data = base64.StdEncoding.EncodeToString(dc.Data)
+	}
+	return json.Marshal(DocumentContentJSON{
+		Type:     "document",
+		Document: doc,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler for DocumentContent
+func (dc *DocumentContent) UnmarshalJSON(data []byte) error {
+	var docJSON DocumentContentJSON
+	if err := json.Unmarshal(data, &docJSON); err != nil {
+		return err
+	}
+	if docJSON.Type != "document" {
+		return errors.Newf("invalid type for DocumentContent: %v", docJSON.Type)
+	}
+	doc, err := docJSON.Document.toDocumentContent()
+	if err != nil {
+		return err
+	}
+	*dc = doc
+	return nil
+}
+
+func (d *DocumentJSON) toDocumentContent() (DocumentContent, error) {
+	if d.Data == "" && d.FileID == "" {
+		return DocumentContent{}, errors.New("missing data or file_id field in DocumentContent")
+	}
+	if d.Data != "" && d.MIMEType == "" {
+		return DocumentContent{}, errors.New("missing mime_type field in DocumentContent")
+	}
+	doc := DocumentContent{
+		MIMEType: d.MIMEType,
+		FileID:   d.FileID,
+		Title:    d.Title,
+	}
+	if d.Data != "" {
+		decoded, err := base64.StdEncoding.DecodeString(d.Data)
+		if err != nil {
+			return DocumentContent{}, errors.Wrap(err, "error decoding base64 data")
+		}
+		doc.Data = decoded
+	}
+	return doc, nil
+}
+
 // ToolCallJSONOrdered matches the expected field order for marshaling
 // function, id, type
 // This is only for marshaling
@@ -432,3 +531,24 @@ func (tc *ToolCallResponse) UnmarshalJSON(data []byte) error {
 	tc.Content = toolResponseJSON.ToolResponse.Content
 	return nil
 }
+
+// MarshalJSON implements json.Marshaler for ThinkingContent
+func (tc ThinkingContent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ThinkingContentJSON{
+		Type:     "thinking",
+		Thinking: ThinkingJSON(tc),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler for ThinkingContent
+func (tc *ThinkingContent) UnmarshalJSON(data []byte) error {
+	var thinkingJSON ThinkingContentJSON
+	if err := json.Unmarshal(data, &thinkingJSON); err != nil {
+		return err
+	}
+	if thinkingJSON.Type != "thinking" {
+		return errors.Newf("invalid type for ThinkingContent: %v", thinkingJSON.Type)
+	}
+	*tc = ThinkingContent(thinkingJSON.Thinking)
+	return nil
+}
@@ -369,6 +369,34 @@ func TestRoundtripping(t *testing.T) { // nolint:funlen // We make an exception
 			assertedJSON: `{"role":"user","text":"Hello, world!"}`,
 			assertedYAML: "role: user\ntext: Hello, world!\n",
 		},
+		{
+			name: "single text part with metadata",
+			in: Message{
+				Role: "ai",
+				Parts: []ContentPart{
+					TextContent{Text: "Hello, world!"},
+				},
+				Metadata: map[string]any{
+					MetadataModel:   "gpt-4o",
+					MetadataLatency: float64(120),
+				},
+			},
+			assertedJSON: `{"role":"ai","text":"Hello, world!","metadata":{"latency_ms":120,"model":"gpt-4o"}}`,
+			assertedYAML: "metadata:\n  latency_ms: 120\n  model: gpt-4o\nrole: ai\ntext: Hello, world!\n",
+		},
+		{
+			name: "tool response with metadata",
+			in: Message{
+				Role: "tool",
+				Parts: []ContentPart{
+					ToolCallResponse{ToolCallID: "123", Name: "hammer", Content: "hit"},
+				},
+				Metadata: map[string]any{
+					MetadataToolLatency: 1.5,
+				},
+			},
+			assertedJSON: `{"role":"tool","parts":[{"type":"tool_response","tool_response":{"tool_call_id":"123","name":"hammer","content":"hit"}}],"metadata":{"tool_latency_ms":1.5}}`,
+		},
 		{
 			name: "multiple parts",
 			in: Message{
@@ -396,6 +424,28 @@ func TestRoundtripping(t *testing.T) { // nolint:funlen // We make an exception
 role: user
 `,
 		},
+		{
+			name: "thinking",
+			in: Message{
+				Role: "ai",
+				Parts: []ContentPart{
+					ThinkingContent{Thinking: "Let me think.", Signature: "sig"},
+					ThinkingContent{RedactedData: "encrypted"},
+				},
+			},
+			assertedJSON: `{"role":"ai","parts":[{"type":"thinking","thinking":{"thinking":"Let me think.","signature":"sig"}},{"type":"thinking","thinking":{"thinking":"","redacted_data":"encrypted"}}]}`,
+		},
+		{
+			name: "document",
+			in: Message{
+				Role: "user",
+				Parts: []ContentPart{
+					DocumentPart("application/pdf", []byte("%PDF-1.7"), "contract.pdf"),
+					FileDocumentPart("file_01", ""),
+				},
+			},
+			assertedJSON: `{"role":"user","parts":[{"type":"document","document":{"mime_type":"application/pdf","data":"JVBERi0xLjc=","title":"contract.pdf"}},{"type":"document","document":{"file_id":"file_01"}}]}`,
+		},
 		{
 			name: "tool use",
 			in: Message{
@@ -727,6 +777,56 @@ func TestUnmarshalJSONBinaryContent(t *testing.T) {
 	}
 }
 
+func TestUnmarshalJSONDocumentContent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		input string
+		want  DocumentContent
+		err   string
+	}{
+		{
+			name:  "inline",
+			input: `{"type":"document","document":{"mime_type":"application/pdf","data":"JVBERi0xLjc=","title":"contract.pdf"}}`,
+			want:  DocumentContent{MIMEType: "application/pdf", Data: []byte("%PDF-1.7"), Title: "contract.pdf"},
+		},
+		{
+			name:  "file",
+			input: `{"type":"document","document":{"file_id":"file_01"}}`,
+			want:  DocumentContent{FileID: "file_01"},
+		},
+		{
+			name:  "invalid type",
+			input: `{"type":"binary","document":{"file_id":"file_01"}}`,
+			err:   "invalid type for DocumentContent: binary",
+		},
+		{
+			name:  "missing source",
+			input: `{"type":"document","document":{"title":"contract.pdf"}}`,
+			err:   "missing data or file_id field in DocumentContent",
+		},
+		{
+			name:  "missing mime_type",
+			input: `{"type":"document","document":{"data":"JVBERi0xLjc="}}`,
+			err:   "missing mime_type field in DocumentContent",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var dc DocumentContent
+			err := dc.UnmarshalJSON([]byte(tt.input))
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, dc)
+		})
+	}
+}
+
 func TestUnmarshalJSONToolCall(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -0,0 +1,231 @@
+// Package modelinfo provides the registry of the models metadata:
+// the context window, the maximum output tokens, the knowledge cutoff and the pricing.
+//
+// The metadata is used to validate the requested MaxTokens before calling the API,
+// to compute the prompt budget for the history truncation, and to estimate the cost.
+// The well-known models are registered in the Default registry,
+// the applications can register the fine-tuned or self-hosted models.
+package modelinfo
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+var (
+	// ErrUnknownModel is returned when the model is not registered.
+	ErrUnknownModel = errors.New("unknown model")
+	// ErrMaxTokensExceeded is returned when the requested MaxTokens exceeds the model limits.
+	ErrMaxTokensExceeded = errors.New("max tokens exceeded")
+)
+
+// Pricing is the price of the model in USD per 1M tokens.
+type Pricing struct {
+	// Input is the price of the prompt tokens.
+	Input float64 `json:"input" yaml:"input"`
+	// CachedInput is the price of the prompt tokens read from the prompt cache,
+	// zero if the model has no discount for the cached tokens.
+	CachedInput float64 `json:"cached_input,omitempty" yaml:"cached_input,omitempty"`
+	// Output is the price of the completion tokens, including the reasoning tokens.
+	Output float64 `json:"output" yaml:"output"`
+}
+
+// Info is the metadata of the model.
+type Info struct {
+	// Name is the name of the model, the versioned names with the date or version suffix
+	// are resolved to the registered name by Lookup.
+	Name string `json:"name" yaml:"name"`
+	// Provider is the provider of the model.
+	Provider llms.ProviderType `json:"provider,omitempty" yaml:"provider,omitempty"`
+	// ContextWindow is the maximum number of the tokens in the prompt and the completion.
+	ContextWindow int `json:"context_window" yaml:"context_window"`
+	// MaxOutputTokens is the maximum number of the completion tokens.
+	MaxOutputTokens int `json:"max_output_tokens" yaml:"max_output_tokens"`
+	// KnowledgeCutoff is the training data cutoff in YYYY-MM format.
+	KnowledgeCutoff string `json:"knowledge_cutoff,omitempty" yaml:"knowledge_cutoff,omitempty"`
+	// Pricing is the price of the model, nil if unknown.
+	Pricing *Pricing `json:"pricing,omitempty" yaml:"pricing,omitempty"`
+}
+
+// ValidateMaxTokens returns ErrMaxTokensExceeded if the requested maximum number
+// of the completion tokens is larger than the model supports.
+func (i *Info) ValidateMaxTokens(maxTokens int) error {
+	if i.MaxOutputTokens > 0 && maxTokens > i.MaxOutputTokens {
+		return errors.Wrapf(ErrMaxTokensExceeded, "%s: %d exceeds the max output tokens %d", i.Name, maxTokens, i.MaxOutputTokens)
+	}
+	if i.ContextWindow > 0 && maxTokens >= i.ContextWindow {
+		return errors.Wrapf(ErrMaxTokensExceeded, "%s: %d exceeds the context window %d", i.Name, maxTokens, i.ContextWindow)
+	}
+	return nil
+}
+
+// PromptBudget returns the maximum number of the prompt tokens,
+// when the completion is limited by maxTokens, or by MaxOutputTokens if maxTokens is zero.
+func (i *Info) PromptBudget(maxTokens int) int {
+	if maxTokens <= 0 {
+		maxTokens = i.MaxOutputTokens
+	}
+	return max(i.ContextWindow-maxTokens, 0)
+}
+
+// Cost returns the cost of the usage in USD, or zero if the pricing is unknown.
+// The InputTokens of the usage are expected to include the CacheReadTokens.
+func (i *Info) Cost(usage *llms.Usage) float64 {
+	if i.Pricing == nil || usage == nil {
+		return 0
+	}
+	p := i.Pricing
+	cached := min(usage.CacheReadTokens, usage.InputTokens)
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	return (float64(usage.InputTokens-cached)*p.Input +
+		float64(cached)*cachedPrice +
+		float64(usage.OutputTokens)*p.Output) / 1e6
+}
+
+// Registry is the registry of the models metadata, keyed by the model name.
+// It is safe for concurrent use.
+type Registry struct {
+	lock   sync.RWMutex
+	models map[string]*Info
+}
+
+// NewRegistry returns a new Registry with the models.
+func NewRegistry(models ...*Info) *Registry {
+	r := &Registry{
+		models: make(map[string]*Info, len(models)),
+	}
+	for _, m := range models {
+		r.models[strings.ToLower(m.Name)] = m
+	}
+	return r
+}
+
+// Register adds the model, replacing the existing one with the same name.
+func (r *Registry) Register(info *Info) error {
+	if info == nil || info.Name == "" {
+		return errors.New("model name is required")
+	}
+	if info.ContextWindow < 0 || info.MaxOutputTokens < 0 {
+		return errors.Newf("model %s: token limits must not be negative", info.Name)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.models[strings.ToLower(info.Name)] = info
+	return nil
+}
+
+// Lookup returns the metadata of the model, or ErrUnknownModel.
+//
+// The name is matched case-insensitive, first exactly, then without the prefix
+// of the provider, such as openai/ of OpenRouter or the Bedrock inference profile,
+// and then by the longest registered name followed by the version suffix,
+// for example claude-sonnet-4-5-20250929 is resolved to claude-sonnet-4-5.
+func (r *Registry) Lookup(model string) (*Info, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	name := strings.ToLower(model)
+	if info, ok := r.models[name]; ok {
+		return info, nil
+	}
+
+	name = trimProviderPrefix(name)
+	if info, ok := r.models[name]; ok {
+		return info, nil
+	}
+
+	var found *Info
+	matched := 0
+	for key, info := range r.models {
+		if len(key) > matched && len(name) > len(key) && strings.HasPrefix(name, key) && isVersionSeparator(name[len(key)]) {
+			found, matched = info, len(key)
+		}
+	}
+	if found == nil {
+		return nil, errors.Wrap(ErrUnknownModel, model)
+	}
+	return found, nil
+}
+
+// Models returns the registered models sorted by name.
+func (r *Registry) Models() []*Info {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	res := make([]*Info, 0, len(r.models))
+	for _, info := range r.models {
+		res = append(res, info)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// ValidateMaxTokens returns ErrMaxTokensExceeded if the model does not support
+// the requested maxTokens, the unknown models are not validated.
+func (r *Registry) ValidateMaxTokens(model string, maxTokens int) error {
+	info, err := r.Lookup(model)
+	if err != nil {
+		return nil
+	}
+	return info.ValidateMaxTokens(maxTokens)
+}
+
+// Cost returns the cost of the LLM call in USD, or zero for the unknown model.
+// It can be used as the CostFunc of the assistants.
+func (r *Registry) Cost(model string, usage *llms.Usage) float64 {
+	info, err := r.Lookup(model)
+	if err != nil {
+		return 0
+	}
+	return info.Cost(usage)
+}
+
+// trimProviderPrefix returns the model name without the provider/ prefix,
+// and without the region and vendor prefix of the Bedrock model ID.
+func trimProviderPrefix(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "anthropic."); i >= 0 {
+		name = name[i+len("anthropic."):]
+	}
+	return name
+}
+
+func isVersionSeparator(c byte) bool {
+	return c == '-' || c == ':' || c == '@'
+}
+
+// Default is the registry of the well-known models.
+var Default = NewRegistry(knownModels...)
+
+// Register adds the model to the Default registry.
+func Register(info *Info) error {
+	return Default.Register(info)
+}
+
+// Lookup returns the metadata of the model from the Default registry.
+func Lookup(model string) (*Info, error) {
+	return Default.Lookup(model)
+}
+
+// ValidateMaxTokens validates maxTokens of the model with the Default registry.
+func ValidateMaxTokens(model string, maxTokens int) error {
+	return Default.ValidateMaxTokens(model, maxTokens)
+}
+
+// Cost returns the cost of the LLM call in USD with the Default registry.
+// It can be used as the CostFunc of the assistants.
+func Cost(model string, usage *llms.Usage) float64 {
+	return Default.Cost(model, usage)
+}
@@ -0,0 +1,87 @@
+package modelinfo_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/modelinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		model string
+		exp   string
+	}{
+		{model: "gpt-4o", exp: "gpt-4o"},
+		{model: "GPT-4o-mini", exp: "gpt-4o-mini"},
+		{model: "gpt-4o-2024-08-06", exp: "gpt-4o"},
+		{model: "gpt-4o-mini-2024-07-18", exp: "gpt-4o-mini"},
+		{model: "claude-sonnet-4-5-20250929", exp: "claude-sonnet-4-5"},
+		{model: "claude-sonnet-4-20250514", exp: "claude-sonnet-4"},
+		{model: "claude-sonnet-4-5@20250929", exp: "claude-sonnet-4-5"},
+		{model: "us.anthropic.claude-3-5-haiku-20241022-v1:0", exp: "claude-3-5-haiku"},
+		{model: "openai/gpt-oss-120b", exp: "gpt-oss-120b"},
+		{model: "models/gemini-2.5-flash", exp: "gemini-2.5-flash"},
+		{model: "gpt-4", exp: ""},
+		{model: "gpt-4o1", exp: ""},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.model, func(t *testing.T) {
+			info, err := modelinfo.Lookup(tc.model)
+			if tc.exp == "" {
+				assert.ErrorIs(t, err, modelinfo.ErrUnknownModel)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, info.Name)
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := modelinfo.NewRegistry()
+	assert.Error(t, r.Register(&modelinfo.Info{}))
+	assert.Error(t, r.Register(&modelinfo.Info{Name: "bad", ContextWindow: -1}))
+
+	require.NoError(t, r.Register(&modelinfo.Info{
+		Name:            "my-llama",
+		Provider:        llms.ProviderVLLM,
+		ContextWindow:   8192,
+		MaxOutputTokens: 4096,
+		Pricing:         &modelinfo.Pricing{Input: 1, Output: 2},
+	}))
+	require.NoError(t, r.Register(&modelinfo.Info{Name: "other", ContextWindow: 4096}))
+	assert.Len(t, r.Models(), 2)
+	assert.Equal(t, "my-llama", r.Models()[0].Name)
+
+	info, err := r.Lookup("my-llama")
+	require.NoError(t, err)
+	assert.Equal(t, 4096, info.PromptBudget(0))
+	assert.Equal(t, 7192, info.PromptBudget(1000))
+	assert.Equal(t, 0, info.PromptBudget(10000))
+
+	assert.NoError(t, r.ValidateMaxTokens("my-llama", 4096))
+	assert.ErrorIs(t, r.ValidateMaxTokens("my-llama", 4097), modelinfo.ErrMaxTokensExceeded)
+	assert.ErrorIs(t, r.ValidateMaxTokens("other", 4096), modelinfo.ErrMaxTokensExceeded)
+	assert.NoError(t, r.ValidateMaxTokens("unknown", 1000000), "unknown models are not validated")
+
+	usage := &llms.Usage{InputTokens: 1000000, OutputTokens: 500000, CacheReadTokens: 200000}
+	assert.InDelta(t, 2.0, r.Cost("my-llama", usage), 1e-9, "cached tokens are billed at the input price")
+	assert.Zero(t, r.Cost("unknown", usage))
+	assert.Zero(t, r.Cost("other", usage))
+}
+
+func TestCost(t *testing.T) {
+	t.Parallel()
+
+	usage := &llms.Usage{InputTokens: 1000000, OutputTokens: 100000, CacheReadTokens: 400000}
+	// 600K * 2.5 + 400K * 1.25 + 100K * 10
+	assert.InDelta(t, 3.0, modelinfo.Cost("gpt-4o-2024-08-06", usage), 1e-9)
+	assert.Zero(t, modelinfo.Cost("gpt-4o", nil))
+}
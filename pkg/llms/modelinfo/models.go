@@ -0,0 +1,78 @@
+package modelinfo
+
+import "github.com/effective-security/gogentic/pkg/llms"
+
+// knownModels is the metadata of the well-known models, with the list prices at the time of writing.
+var knownModels = []*Info{
+	// OpenAI
+	{Name: "gpt-4o", Provider: llms.ProviderOpenAI, ContextWindow: 128000, MaxOutputTokens: 16384, KnowledgeCutoff: "2023-10",
+		Pricing: &Pricing{Input: 2.5, CachedInput: 1.25, Output: 10}},
+	{Name: "gpt-4o-mini", Provider: llms.ProviderOpenAI, ContextWindow: 128000, MaxOutputTokens: 16384, KnowledgeCutoff: "2023-10",
+		Pricing: &Pricing{Input: 0.15, CachedInput: 0.075, Output: 0.6}},
+	{Name: "gpt-4.1", Provider: llms.ProviderOpenAI, ContextWindow: 1047576, MaxOutputTokens: 32768, KnowledgeCutoff: "2024-06",
+		Pricing: &Pricing{Input: 2, CachedInput: 0.5, Output: 8}},
+	{Name: "gpt-4.1-mini", Provider: llms.ProviderOpenAI, ContextWindow: 1047576, MaxOutputTokens: 32768, KnowledgeCutoff: "2024-06",
+		Pricing: &Pricing{Input: 0.4, CachedInput: 0.1, Output: 1.6}},
+	{Name: "gpt-4.1-nano", Provider: llms.ProviderOpenAI, ContextWindow: 1047576, MaxOutputTokens: 32768, KnowledgeCutoff: "2024-06",
+		Pricing: &Pricing{Input: 0.1, CachedInput: 0.025, Output: 0.4}},
+	{Name: "o3", Provider: llms.ProviderOpenAI, ContextWindow: 200000, MaxOutputTokens: 100000, KnowledgeCutoff: "2024-06",
+		Pricing: &Pricing{Input: 2, CachedInput: 0.5, Output: 8}},
+	{Name: "o3-mini", Provider: llms.ProviderOpenAI, ContextWindow: 200000, MaxOutputTokens: 100000, KnowledgeCutoff: "2023-10",
+		Pricing: &Pricing{Input: 1.1, CachedInput: 0.55, Output: 4.4}},
+	{Name: "o4-mini", Provider: llms.ProviderOpenAI, ContextWindow: 200000, MaxOutputTokens: 100000, KnowledgeCutoff: "2024-06",
+		Pricing: &Pricing{Input: 1.1, CachedInput: 0.275, Output: 4.4}},
+	{Name: "gpt-5", Provider: llms.ProviderOpenAI, ContextWindow: 400000, MaxOutputTokens: 128000, KnowledgeCutoff: "2024-09",
+		Pricing: &Pricing{Input: 1.25, CachedInput: 0.125, Output: 10}},
+	{Name: "gpt-5-mini", Provider: llms.ProviderOpenAI, ContextWindow: 400000, MaxOutputTokens: 128000, KnowledgeCutoff: "2024-05",
+		Pricing: &Pricing{Input: 0.25, CachedInput: 0.025, Output: 2}},
+	{Name: "gpt-5-nano", Provider: llms.ProviderOpenAI, ContextWindow: 400000, MaxOutputTokens: 128000, KnowledgeCutoff: "2024-05",
+		Pricing: &Pricing{Input: 0.05, CachedInput: 0.005, Output: 0.4}},
+	{Name: "gpt-oss-120b", Provider: llms.ProviderOpenAI, ContextWindow: 131072, MaxOutputTokens: 32768, KnowledgeCutoff: "2024-06"},
+	{Name: "gpt-oss-20b", Provider: llms.ProviderOpenAI, ContextWindow: 131072, MaxOutputTokens: 32768, KnowledgeCutoff: "2024-06"},
+
+	// Anthropic
+	{Name: "claude-opus-4-1", Provider: llms.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 32000, KnowledgeCutoff: "2025-03",
+		Pricing: &Pricing{Input: 15, CachedInput: 1.5, Output: 75}},
+	{Name: "claude-opus-4", Provider: llms.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 32000, KnowledgeCutoff: "2025-03",
+		Pricing: &Pricing{Input: 15, CachedInput: 1.5, Output: 75}},
+	{Name: "claude-sonnet-4-5", Provider: llms.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 64000, KnowledgeCutoff: "2025-07",
+		Pricing: &Pricing{Input: 3, CachedInput: 0.3, Output: 15}},
+	{Name: "claude-sonnet-4", Provider: llms.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 64000, KnowledgeCutoff: "2025-03",
+		Pricing: &Pricing{Input: 3, CachedInput: 0.3, Output: 15}},
+	{Name: "claude-haiku-4-5", Provider: llms.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 64000, KnowledgeCutoff: "2025-02",
+		Pricing: &Pricing{Input: 1, CachedInput: 0.1, Output: 5}},
+	{Name: "claude-3-7-sonnet", Provider: llms.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 64000, KnowledgeCutoff: "2024-10",
+		Pricing: &Pricing{Input: 3, CachedInput: 0.3, Output: 15}},
+	{Name: "claude-3-5-haiku", Provider: llms.ProviderAnthropic, ContextWindow: 200000, MaxOutputTokens: 8192, KnowledgeCutoff: "2024-07",
+		Pricing: &Pricing{Input: 0.8, CachedInput: 0.08, Output: 4}},
+
+	// Google
+	{Name: "gemini-2.5-pro", Provider: llms.ProviderGoogleAI, ContextWindow: 1048576, MaxOutputTokens: 65536, KnowledgeCutoff: "2025-01",
+		Pricing: &Pricing{Input: 1.25, CachedInput: 0.31, Output: 10}},
+	{Name: "gemini-2.5-flash", Provider: llms.ProviderGoogleAI, ContextWindow: 1048576, MaxOutputTokens: 65536, KnowledgeCutoff: "2025-01",
+		Pricing: &Pricing{Input: 0.3, CachedInput: 0.075, Output: 2.5}},
+	{Name: "gemini-2.5-flash-lite", Provider: llms.ProviderGoogleAI, ContextWindow: 1048576, MaxOutputTokens: 65536, KnowledgeCutoff: "2025-01",
+		Pricing: &Pricing{Input: 0.1, CachedInput: 0.025, Output: 0.4}},
+	{Name: "gemini-2.0-flash", Provider: llms.ProviderGoogleAI, ContextWindow: 1048576, MaxOutputTokens: 8192, KnowledgeCutoff: "2024-08",
+		Pricing: &Pricing{Input: 0.1, CachedInput: 0.025, Output: 0.4}},
+
+	// DeepSeek
+	{Name: "deepseek-chat", Provider: llms.ProviderDeepSeek, ContextWindow: 128000, MaxOutputTokens: 8192,
+		Pricing: &Pricing{Input: 0.28, CachedInput: 0.028, Output: 0.42}},
+	{Name: "deepseek-reasoner", Provider: llms.ProviderDeepSeek, ContextWindow: 128000, MaxOutputTokens: 65536,
+		Pricing: &Pricing{Input: 0.28, CachedInput: 0.028, Output: 0.42}},
+
+	// Groq
+	{Name: "llama-3.3-70b-versatile", Provider: llms.ProviderGroq, ContextWindow: 131072, MaxOutputTokens: 32768, KnowledgeCutoff: "2023-12",
+		Pricing: &Pricing{Input: 0.59, Output: 0.79}},
+	{Name: "llama-3.1-8b-instant", Provider: llms.ProviderGroq, ContextWindow: 131072, MaxOutputTokens: 131072, KnowledgeCutoff: "2023-12",
+		Pricing: &Pricing{Input: 0.05, Output: 0.08}},
+
+	// Cohere
+	{Name: "command-a-03-2025", Provider: llms.ProviderCohere, ContextWindow: 256000, MaxOutputTokens: 8000, KnowledgeCutoff: "2024-06",
+		Pricing: &Pricing{Input: 2.5, Output: 10}},
+	{Name: "command-r-plus", Provider: llms.ProviderCohere, ContextWindow: 128000, MaxOutputTokens: 4000, KnowledgeCutoff: "2024-03",
+		Pricing: &Pricing{Input: 2.5, Output: 10}},
+	{Name: "command-r", Provider: llms.ProviderCohere, ContextWindow: 128000, MaxOutputTokens: 4000, KnowledgeCutoff: "2024-03",
+		Pricing: &Pricing{Input: 0.15, Output: 0.6}},
+}
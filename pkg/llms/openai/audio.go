@@ -0,0 +1,83 @@
+package openai
+
+import (
+	"context"
+	"mime"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+)
+
+var (
+	_ llms.Transcriber = (*LLM)(nil)
+	_ llms.Speech      = (*LLM)(nil)
+)
+
+// Transcribe implements llms.Transcriber with whisper and gpt-4o-transcribe models,
+// for Azure the model is the name of the deployment.
+func (o *LLM) Transcribe(ctx context.Context, audio llms.BinaryContent, options ...llms.TranscribeOption) (*llms.Transcription, error) {
+	opts := llms.NewTranscribeOptions(options...)
+
+	res, err := o.client.CreateTranscription(ctx, &openaiclient.TranscriptionRequest{
+		Model:       opts.Model,
+		File:        audio.Data,
+		FileName:    "audio" + audioExtension(audio.MIMEType),
+		Language:    opts.Language,
+		Prompt:      opts.Prompt,
+		Temperature: opts.Temperature,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create openai transcription")
+	}
+	return &llms.Transcription{
+		Text:     res.Text,
+		Language: res.Language,
+		Duration: time.Duration(res.Duration * float64(time.Second)),
+	}, nil
+}
+
+// GenerateSpeech implements llms.Speech with tts and gpt-4o-mini-tts models,
+// for Azure the model is the name of the deployment.
+func (o *LLM) GenerateSpeech(ctx context.Context, text string, options ...llms.SpeechOption) (*llms.BinaryContent, error) {
+	opts := llms.NewSpeechOptions(options...)
+
+	data, err := o.client.CreateSpeech(ctx, &openaiclient.SpeechRequest{
+		Model:          opts.Model,
+		Input:          text,
+		Voice:          opts.Voice,
+		ResponseFormat: string(opts.Format),
+		Speed:          opts.Speed,
+		Instructions:   opts.Instructions,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create openai speech")
+	}
+	return &llms.BinaryContent{
+		MIMEType: opts.Format.MIMEType(),
+		Data:     data,
+	}, nil
+}
+
+// audioExtension returns the file extension, the API detects the format by the file name.
+func audioExtension(mimeType string) string {
+	switch mimeType {
+	case "audio/mpeg", "audio/mp3":
+		return ".mp3"
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return ".wav"
+	case "audio/ogg":
+		return ".ogg"
+	case "audio/webm":
+		return ".webm"
+	case "audio/flac":
+		return ".flac"
+	case "audio/mp4", "audio/m4a", "audio/x-m4a":
+		return ".m4a"
+	}
+	if exts, _ := mime.ExtensionsByType(mimeType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ".mp3"
+}
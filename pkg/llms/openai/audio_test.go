@@ -0,0 +1,104 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscribe(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/audio/transcriptions", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		assert.Equal(t, "verbose_json", r.FormValue("response_format"))
+		assert.Equal(t, "en", r.FormValue("language"))
+
+		f, h, err := r.FormFile("file")
+		require.NoError(t, err)
+		assert.Equal(t, "audio.wav", h.Filename)
+		data, _ := io.ReadAll(f)
+		assert.Equal(t, "RIFF", string(data))
+
+		_, _ = w.Write([]byte(`{"text": "Hello world", "language": "english", "duration": 1.5}`))
+	}))
+	defer srv.Close()
+
+	llm, err := New(WithToken("test-token"), WithBaseURL(srv.URL), WithModel("gpt-4o"))
+	require.NoError(t, err)
+
+	res, err := llm.Transcribe(context.Background(),
+		llms.BinaryContent{MIMEType: "audio/wav", Data: []byte("RIFF")},
+		llms.WithTranscribeLanguage("en"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, &llms.Transcription{Text: "Hello world", Language: "english", Duration: 1500 * time.Millisecond}, res)
+}
+
+func TestGenerateSpeech(t *testing.T) {
+	t.Parallel()
+
+	var req map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/tts/audio/speech", r.URL.Path)
+		assert.Equal(t, "2025-03-01-preview", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		_, _ = w.Write([]byte("OggS"))
+	}))
+	defer srv.Close()
+
+	llm, err := New(
+		WithToken("test-token"),
+		WithBaseURL(srv.URL),
+		WithModel("gpt-4o"),
+		WithEmbeddingModel("text-embedding-3-small"),
+		WithProvider(ProviderAzure),
+		WithAPIVersion("2025-03-01-preview"),
+	)
+	require.NoError(t, err)
+
+	res, err := llm.GenerateSpeech(context.Background(), "Hello world",
+		llms.WithSpeechModel("tts"),
+		llms.WithVoice("nova"),
+		llms.WithSpeechFormat(llms.SpeechFormatOpus),
+		llms.WithSpeechSpeed(1.25),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, &llms.BinaryContent{MIMEType: "audio/ogg", Data: []byte("OggS")}, res)
+	assert.Equal(t, map[string]any{
+		"model":           "tts",
+		"input":           "Hello world",
+		"voice":           "nova",
+		"response_format": "opus",
+		"speed":           1.25,
+	}, req)
+}
+
+func TestGenerateSpeechError(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"message": "invalid voice", "type": "invalid_request_error"}}`))
+	}))
+	defer srv.Close()
+
+	llm, err := New(WithToken("test-token"), WithBaseURL(srv.URL), WithModel("gpt-4o"))
+	require.NoError(t, err)
+
+	_, err = llm.GenerateSpeech(context.Background(), "Hello world", llms.WithVoice("unknown"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid voice")
+}
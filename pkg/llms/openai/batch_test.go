@@ -533,3 +533,65 @@ func TestCompletionWindowString(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildRequestBody_DeveloperRole(t *testing.T) {
+	t.Parallel()
+
+	llm, err := New(
+		WithToken("test-token"),
+		WithBaseURL("http://example.test/v1"),
+		WithModel("o3-mini"),
+		WithProvider(ProviderOpenAI),
+		WithHTTPClient(http.DefaultClient),
+	)
+	require.NoError(t, err)
+
+	messages := []llms.Message{
+		{Role: llms.RoleDeveloper, Parts: []llms.ContentPart{llms.TextPart("be brief")}},
+		humanMsg("hello"),
+	}
+
+	chat, err := llm.buildChatRequestBody(messages)
+	require.NoError(t, err)
+	require.Len(t, chat.Messages, 2)
+	assert.Equal(t, RoleDeveloper, chat.Messages[0].Role)
+	assert.Equal(t, RoleUser, chat.Messages[1].Role)
+
+	params, err := llm.buildResponsesRequestBody(messages)
+	require.NoError(t, err)
+	require.Len(t, params.Input.OfInputItemList, 2)
+	assert.Equal(t, "developer", string(params.Input.OfInputItemList[0].OfInputMessage.Role))
+	assert.Equal(t, "user", string(params.Input.OfInputItemList[1].OfInputMessage.Role))
+}
+
+func TestBuildRequestBody_DeveloperRoleFolded(t *testing.T) {
+	t.Parallel()
+
+	for _, provider := range []ProviderType{ProviderPerplexity, ProviderVLLM, ProviderLlamaCpp} {
+		t.Run(string(provider), func(t *testing.T) {
+			t.Parallel()
+			llm := newTestLLM(t, "http://example.test", provider)
+
+			// folded into the preceding system message
+			chat, err := llm.buildChatRequestBody([]llms.Message{
+				{Role: llms.RoleSystem, Parts: []llms.ContentPart{llms.TextPart("you are helpful")}},
+				{Role: llms.RoleDeveloper, Parts: []llms.ContentPart{llms.TextPart("be brief")}},
+				humanMsg("hello"),
+			})
+			require.NoError(t, err)
+			require.Len(t, chat.Messages, 2)
+			assert.Equal(t, RoleSystem, chat.Messages[0].Role)
+			assert.Equal(t, []llms.ContentPart{llms.TextPart("you are helpful\n\nbe brief")}, chat.Messages[0].MultiContent)
+			assert.Equal(t, RoleUser, chat.Messages[1].Role)
+
+			// sent as the system message
+			chat, err = llm.buildChatRequestBody([]llms.Message{
+				{Role: llms.RoleDeveloper, Parts: []llms.ContentPart{llms.TextPart("be brief")}},
+				humanMsg("hello"),
+			})
+			require.NoError(t, err)
+			require.Len(t, chat.Messages, 2)
+			assert.Equal(t, RoleSystem, chat.Messages[0].Role)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package openai
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+)
+
+// applyConstraintToChatRequest sets the provider specific constrained decoding parameters.
+func applyConstraintToChatRequest(req *openaiclient.ChatRequest, provider openaiclient.ProviderType, constraint *llms.Constraint) error {
+	if constraint == nil || constraint.Value == "" {
+		return nil
+	}
+
+	switch provider {
+	case openaiclient.ProviderVLLM:
+		switch constraint.Type {
+		case llms.ConstraintGrammar:
+			req.GuidedGrammar = constraint.Value
+			return nil
+		case llms.ConstraintRegex:
+			req.GuidedRegex = constraint.Value
+			return nil
+		}
+	case openaiclient.ProviderLlamaCpp:
+		if constraint.Type == llms.ConstraintGrammar {
+			req.Grammar = constraint.Value
+			return nil
+		}
+	}
+	return errors.Errorf("%s constraint is not supported by %s provider", constraint.Type, provider)
+}
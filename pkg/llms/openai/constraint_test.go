@@ -0,0 +1,61 @@
+package openai
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyConstraintToChatRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		provider ProviderType
+		option   llms.CallOption
+		grammar  string
+		guided   string
+		regex    string
+		err      string
+	}{
+		{name: "vllm grammar", provider: ProviderVLLM, option: llms.WithGrammar("root ::= \"a\""), guided: "root ::= \"a\""},
+		{name: "vllm regex", provider: ProviderVLLM, option: llms.WithRegex("[a-z]+"), regex: "[a-z]+"},
+		{name: "llama.cpp grammar", provider: ProviderLlamaCpp, option: llms.WithGrammar("root ::= \"a\""), grammar: "root ::= \"a\""},
+		{name: "llama.cpp regex", provider: ProviderLlamaCpp, option: llms.WithRegex("[a-z]+"), err: "regex constraint is not supported by LLAMACPP provider"},
+		{name: "perplexity grammar", provider: ProviderPerplexity, option: llms.WithGrammar("root ::= \"a\""), err: "grammar constraint is not supported by PERPLEXITY provider"},
+		{name: "no constraint", provider: ProviderVLLM, option: llms.WithConstraint(nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			llm, err := New(
+				WithToken("test-token"),
+				WithBaseURL("http://example.test/v1"),
+				WithModel("local"),
+				WithProvider(tt.provider),
+				WithHTTPClient(http.DefaultClient),
+			)
+			require.NoError(t, err)
+
+			req, err := llm.buildChatRequestBody([]llms.Message{humanMsg("hello")}, tt.option)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.grammar, req.Grammar)
+			assert.Equal(t, tt.guided, req.GuidedGrammar)
+			assert.Equal(t, tt.regex, req.GuidedRegex)
+		})
+	}
+
+	llm, err := New(WithToken("test-token"), WithModel("gpt-4o"))
+	require.NoError(t, err)
+	_, err = llm.buildResponsesRequestBody([]llms.Message{humanMsg("hello")}, llms.WithRegex("[a-z]+"))
+	assert.EqualError(t, err, "regex constraint is not supported by the Responses API")
+}
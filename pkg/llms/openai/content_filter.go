@@ -0,0 +1,53 @@
+package openai
+
+import (
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// withErrorProvider sets the provider of the ContentFilterError
+// and the ProviderError returned by the client
+func (o *LLM) withErrorProvider(err error) error {
+	if cfe, ok := llms.AsContentFilterError(err); ok && cfe.Provider == "" {
+		cfe.Provider = o.GetProviderType()
+	}
+	if pe, ok := llms.AsProviderError(err); ok && pe.Provider == "" {
+		pe.Provider = o.GetProviderType()
+	}
+	return err
+}
+
+// chatContentFilterError returns the error of the refused or the filtered choice, or nil
+func (o *LLM) chatContentFilterError(c *openaiclient.ChatCompletionChoice) error {
+	switch {
+	case c.Message.Refusal != "":
+		return &llms.ContentFilterError{
+			Provider:   o.GetProviderType(),
+			Categories: []string{llms.ContentFilterRefusal},
+			Message:    c.Message.Refusal,
+		}
+	case c.FinishReason == openaiclient.FinishReasonContentFilter:
+		return &llms.ContentFilterError{
+			Provider:   o.GetProviderType(),
+			Categories: c.ContentFilterResults.Filtered(),
+		}
+	}
+	return nil
+}
+
+// responsesContentFilterError returns the error of the refused response, or nil
+func (o *LLM) responsesContentFilterError(result *responses.Response) error {
+	for _, item := range result.Output {
+		for _, content := range item.Content {
+			if content.Type == "refusal" {
+				return &llms.ContentFilterError{
+					Provider:   o.GetProviderType(),
+					Categories: []string{llms.ContentFilterRefusal},
+					Message:    content.Refusal,
+				}
+			}
+		}
+	}
+	return nil
+}
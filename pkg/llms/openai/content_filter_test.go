@@ -0,0 +1,99 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentFilter(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		name     string
+		provider ProviderType
+		status   int
+		body     string
+		exp      *llms.ContentFilterError
+	}{
+		{
+			name:     "openai_refusal",
+			provider: ProviderOpenAI,
+			status:   http.StatusOK,
+			body: `{"id":"1","choices":[{"index":0,"finish_reason":"stop",
+				"message":{"role":"assistant","content":null,"refusal":"I can't help with that."}}]}`,
+			exp: &llms.ContentFilterError{
+				Provider:   llms.ProviderOpenAI,
+				Categories: []string{llms.ContentFilterRefusal},
+				Message:    "I can't help with that.",
+			},
+		},
+		{
+			name:     "azure_completion",
+			provider: ProviderAzure,
+			status:   http.StatusOK,
+			body: `{"id":"1","choices":[{"index":0,"finish_reason":"content_filter",
+				"message":{"role":"assistant","content":"Partial"},
+				"content_filter_results":{
+					"hate":{"filtered":false,"severity":"safe"},
+					"violence":{"filtered":true,"severity":"high"},
+					"sexual":{"filtered":true,"severity":"medium"},
+					"custom_blocklists":[]}}]}`,
+			exp: &llms.ContentFilterError{
+				Provider:   llms.ProviderAzure,
+				Categories: []string{llms.ContentFilterSexual, llms.ContentFilterViolence},
+			},
+		},
+		{
+			name:     "azure_prompt",
+			provider: ProviderAzure,
+			status:   http.StatusBadRequest,
+			body: `{"error":{"message":"The response was filtered due to the prompt triggering content management policy.",
+				"type":null,"param":"prompt","code":"content_filter","status":400,
+				"innererror":{"code":"ResponsibleAIPolicyViolation","content_filter_result":{
+					"hate":{"filtered":false,"severity":"safe"},
+					"jailbreak":{"filtered":true,"detected":true}}}}}`,
+			exp: &llms.ContentFilterError{
+				Provider:   llms.ProviderAzure,
+				Categories: []string{llms.ContentFilterJailbreak},
+				Prompt:     true,
+				Message:    "The response was filtered due to the prompt triggering content management policy.",
+			},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			llm, err := New(
+				WithToken("test-token"),
+				WithBaseURL(srv.URL),
+				WithModel("gpt-4o"),
+				WithProvider(tc.provider),
+				WithAPIVersion("2024-10-21"),
+			)
+			require.NoError(t, err)
+
+			_, err = llm.GenerateContent(context.Background(), []llms.Message{humanMsg("hello")})
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, llms.ErrContentFiltered))
+
+			cfe, ok := llms.AsContentFilterError(err)
+			require.True(t, ok)
+			assert.Equal(t, tc.exp, cfe)
+		})
+	}
+}
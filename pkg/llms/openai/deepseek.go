@@ -0,0 +1,44 @@
+package openai
+
+import (
+	"strings"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+)
+
+// applyReasoningContent adds the reasoning_content returned by the reasoning models,
+// for example deepseek-reasoner, to the choice as the thinking block.
+func applyReasoningContent(choice *llms.ContentChoice, reasoning string) {
+	if reasoning == "" {
+		return
+	}
+	choice.ReasoningContent = reasoning
+	choice.Thinking = []llms.ThinkingContent{{Thinking: reasoning}}
+}
+
+// applyContextCache adds the DeepSeek context cache usage to the Usage
+// and the GenerationInfo of the choice, the cache hit tokens are billed at the discount.
+func applyContextCache(choice *llms.ContentChoice, usage *openaiclient.ChatUsage) {
+	if usage.PromptCacheHitTokens == 0 && usage.PromptCacheMissTokens == 0 {
+		return
+	}
+	choice.Usage.CacheReadTokens = uint64(usage.PromptCacheHitTokens)
+
+	if choice.GenerationInfo == nil {
+		choice.GenerationInfo = map[string]any{}
+	}
+	choice.GenerationInfo[llms.GenerationInfoCacheHitTokens] = usage.PromptCacheHitTokens
+	choice.GenerationInfo[llms.GenerationInfoCacheMissTokens] = usage.PromptCacheMissTokens
+}
+
+// reasoningContent returns the text of the thinking parts of the message.
+func reasoningContent(parts []llms.ContentPart) string {
+	var texts []string
+	for _, p := range parts {
+		if tc, ok := p.(llms.ThinkingContent); ok && tc.Thinking != "" {
+			texts = append(texts, tc.Thinking)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,55 @@
+package openai
+
+import (
+	"strings"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+)
+
+// supportsDeveloperRole returns true if the provider accepts the developer role messages,
+// see llms.CapabilityDeveloperRole.
+func supportsDeveloperRole(provider openaiclient.ProviderType) bool {
+	pt := llms.ProviderType(provider)
+	if provider == "OPEN_AI" {
+		pt = llms.ProviderOpenAI
+	}
+	return llms.ProviderCapabilities(pt).Supports(llms.CapabilityDeveloperRole)
+}
+
+// foldDeveloperMessages returns the messages where the developer messages are folded
+// into the preceding system message, or sent as the system messages,
+// for the providers without the developer role.
+func foldDeveloperMessages(messages []llms.Message) []llms.Message {
+	res := make([]llms.Message, 0, len(messages))
+	for _, m := range messages {
+		if m.Role != llms.RoleDeveloper {
+			res = append(res, m)
+			continue
+		}
+		m.Role = llms.RoleSystem
+		if n := len(res); n > 0 && res[n-1].Role == llms.RoleSystem {
+			prev, ok1 := textOnly(res[n-1])
+			text, ok2 := textOnly(m)
+			if ok1 && ok2 {
+				res[n-1].Parts = []llms.ContentPart{llms.TextPart(prev + "\n\n" + text)}
+				continue
+			}
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// textOnly returns the text of the message, if it has only the text parts.
+func textOnly(m llms.Message) (string, bool) {
+	texts := make([]string, 0, len(m.Parts))
+	for _, p := range m.Parts {
+		tc, ok := p.(llms.TextContent)
+		if !ok {
+			return "", false
+		}
+		texts = append(texts, tc.Text)
+	}
+	return strings.Join(texts, "\n"), true
+}
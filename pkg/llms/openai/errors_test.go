@@ -0,0 +1,83 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		kind   error
+		code   string
+		err    string
+	}{
+		{
+			name:   "auth",
+			status: http.StatusUnauthorized,
+			body:   `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			kind:   llms.ErrAuth,
+			code:   "invalid_api_key",
+			err:    "API returned unexpected status code: 401: Incorrect API key provided",
+		},
+		{
+			name:   "context_length",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"message":"This model's maximum context length is 128000 tokens.","type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			kind:   llms.ErrContextLengthExceeded,
+			code:   "context_length_exceeded",
+			err:    "API returned unexpected status code: 400: This model's maximum context length is 128000 tokens.",
+		},
+		{
+			name:   "bad_request",
+			status: http.StatusNotFound,
+			body:   `{"error":{"message":"The model does not exist","type":"invalid_request_error","code":"model_not_found"}}`,
+			kind:   llms.ErrBadRequest,
+			code:   "model_not_found",
+			err:    "API returned unexpected status code: 404: The model does not exist",
+		},
+		{
+			name:   "overloaded",
+			status: http.StatusServiceUnavailable,
+			body:   `not json`,
+			kind:   llms.ErrServerOverloaded,
+			err:    "API returned unexpected status code: 503",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			llm, err := New(WithToken("test-token"), WithBaseURL(srv.URL), WithModel("local"), WithProvider(ProviderVLLM))
+			require.NoError(t, err)
+
+			_, err = llm.GenerateContent(context.Background(), []llms.Message{humanMsg("hello")})
+			require.Error(t, err)
+			assert.EqualError(t, err, tt.err)
+			assert.ErrorIs(t, err, tt.kind)
+
+			pe, ok := llms.AsProviderError(err)
+			require.True(t, ok)
+			assert.Equal(t, llms.ProviderVLLM, pe.Provider)
+			assert.Equal(t, tt.status, pe.StatusCode)
+			assert.Equal(t, tt.code, pe.Code)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package openai
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// FilePurpose is the purpose of the uploaded file.
+type FilePurpose string
+
+const (
+	// FilePurposeAssistants is the purpose of the files for the file_search tool.
+	FilePurposeAssistants FilePurpose = "assistants"
+	// FilePurposeUserData is the purpose of the files used as the model inputs.
+	FilePurposeUserData FilePurpose = "user_data"
+)
+
+// File is the file uploaded to OpenAI.
+type File = openaiclient.File
+
+// VectorStore is the vector store of the files for the file_search tool.
+type VectorStore = openaiclient.VectorStore
+
+// VectorStoreFile is the file attached to the vector store.
+type VectorStoreFile = openaiclient.VectorStoreFile
+
+// VectorStoreSearchResult is the chunk of the file found in the vector store.
+type VectorStoreSearchResult struct {
+	FileID   string  `json:"file_id"`
+	Filename string  `json:"filename"`
+	Score    float64 `json:"score"`
+	Text     string  `json:"text"`
+}
+
+// FileSearchTool returns the built-in file_search tool of the Responses API,
+// to search the files in the vector stores, for example:
+//
+//	llm.GenerateContent(ctx, messages, llms.WithTools([]llms.Tool{openai.FileSearchTool(10, vs.ID)}))
+func FileSearchTool(maxResults int, vectorStoreIDs ...string) llms.Tool {
+	return llms.Tool{
+		Type: string(openaiclient.ToolTypeFileSearch),
+		FileSearchOptions: &llms.FileSearchOptions{
+			VectorStoreIDs: vectorStoreIDs,
+			MaxNumResults:  maxResults,
+		},
+	}
+}
+
+// UploadFile uploads the file with the purpose.
+func (o *LLM) UploadFile(ctx context.Context, name string, content io.Reader, purpose FilePurpose) (*File, error) {
+	return o.client.UploadFile(ctx, name, string(purpose), content)
+}
+
+// ListFiles returns the uploaded files, filtered by the purpose if not empty.
+func (o *LLM) ListFiles(ctx context.Context, purpose FilePurpose) ([]File, error) {
+	return o.client.ListFiles(ctx, string(purpose))
+}
+
+// DeleteFile deletes the uploaded file.
+func (o *LLM) DeleteFile(ctx context.Context, fileID string) error {
+	return o.client.DeleteFile(ctx, fileID)
+}
+
+// CreateVectorStore creates the vector store with the uploaded files,
+// the files are processed asynchronously, see GetVectorStore for the status.
+func (o *LLM) CreateVectorStore(ctx context.Context, name string, fileIDs ...string) (*VectorStore, error) {
+	return o.client.CreateVectorStore(ctx, name, fileIDs)
+}
+
+// GetVectorStore returns the vector store, with the counts of the processed files.
+func (o *LLM) GetVectorStore(ctx context.Context, vectorStoreID string) (*VectorStore, error) {
+	return o.client.GetVectorStore(ctx, vectorStoreID)
+}
+
+// DeleteVectorStore deletes the vector store, the files are not deleted.
+func (o *LLM) DeleteVectorStore(ctx context.Context, vectorStoreID string) error {
+	return o.client.DeleteVectorStore(ctx, vectorStoreID)
+}
+
+// AttachFile attaches the uploaded file to the vector store.
+func (o *LLM) AttachFile(ctx context.Context, vectorStoreID, fileID string) (*VectorStoreFile, error) {
+	return o.client.AttachVectorStoreFile(ctx, vectorStoreID, fileID)
+}
+
+// SearchVectorStore returns the chunks of the files in the vector store relevant to the query,
+// the most relevant first.
+func (o *LLM) SearchVectorStore(ctx context.Context, vectorStoreID, query string, maxResults int) ([]VectorStoreSearchResult, error) {
+	if query == "" {
+		return nil, errors.New("invalid argument: query is required")
+	}
+	res, err := o.client.SearchVectorStore(ctx, vectorStoreID, &openaiclient.VectorStoreSearchRequest{
+		Query:         query,
+		MaxNumResults: maxResults,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]VectorStoreSearchResult, 0, len(res))
+	for _, r := range res {
+		var text strings.Builder
+		for _, c := range r.Content {
+			if c.Type != "text" {
+				continue
+			}
+			if text.Len() > 0 {
+				text.WriteString("\n")
+			}
+			text.WriteString(c.Text)
+		}
+		list = append(list, VectorStoreSearchResult{
+			FileID:   r.FileID,
+			Filename: r.Filename,
+			Score:    r.Score,
+			Text:     text.String(),
+		})
+	}
+	return list, nil
+}
+
+// inputFileFromDocument maps the document to the input file of the Responses API,
+// the FileID is the ID of the file uploaded with FilePurposeUserData.
+func inputFileFromDocument(doc llms.DocumentContent) *responses.ResponseInputFileParam {
+	if doc.FileID != "" {
+		return &responses.ResponseInputFileParam{FileID: param.NewOpt(doc.FileID)}
+	}
+	return &responses.ResponseInputFileParam{
+		FileData: param.NewOpt(doc.String()),
+		Filename: param.NewOpt(cmp.Or(doc.Title, "document")),
+	}
+}
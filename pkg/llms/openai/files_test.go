@@ -0,0 +1,151 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lock  sync.Mutex
+		calls []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		lock.Unlock()
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /files":
+			assert.NoError(t, r.ParseMultipartForm(1<<20))
+			assert.Equal(t, "assistants", r.FormValue("purpose"))
+			f, h, err := r.FormFile("file")
+			assert.NoError(t, err)
+			data, _ := io.ReadAll(f)
+			assert.Equal(t, "handbook.md", h.Filename)
+			assert.Equal(t, "Vacation is 20 days.", string(data))
+			_, _ = w.Write([]byte(`{"id":"file_1","bytes":20,"created_at":1,"filename":"handbook.md","purpose":"assistants"}`))
+		case "GET /files":
+			assert.Equal(t, "assistants", r.URL.Query().Get("purpose"))
+			if r.URL.Query().Get("after") == "" {
+				_, _ = w.Write([]byte(`{"data":[{"id":"file_1"}],"has_more":true,"last_id":"file_1"}`))
+				return
+			}
+			assert.Equal(t, "file_1", r.URL.Query().Get("after"))
+			_, _ = w.Write([]byte(`{"data":[{"id":"file_2"}],"has_more":false}`))
+		case "DELETE /files/file_1", "DELETE /vector_stores/vs_1":
+			_, _ = w.Write([]byte(`{"deleted":true}`))
+		case "POST /vector_stores":
+			var req map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, map[string]any{"name": "docs", "file_ids": []any{"file_1"}}, req)
+			_, _ = w.Write([]byte(`{"id":"vs_1","name":"docs","status":"in_progress","file_counts":{"in_progress":1,"total":1}}`))
+		case "GET /vector_stores/vs_1":
+			_, _ = w.Write([]byte(`{"id":"vs_1","name":"docs","status":"completed","file_counts":{"completed":2,"total":2}}`))
+		case "POST /vector_stores/vs_1/files":
+			var req map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, map[string]any{"file_id": "file_2"}, req)
+			_, _ = w.Write([]byte(`{"id":"file_2","vector_store_id":"vs_1","status":"in_progress"}`))
+		case "POST /vector_stores/vs_1/search":
+			var req map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, map[string]any{"query": "vacation", "max_num_results": float64(3)}, req)
+			_, _ = w.Write([]byte(`{"data":[{"file_id":"file_1","filename":"handbook.md","score":0.8,
+				"content":[{"type":"text","text":"Vacation is"},{"type":"text","text":"20 days."}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"message":"not found"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	llm, err := New(WithToken("test-token"), WithBaseURL(srv.URL), WithModel("gpt-4o"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	file, err := llm.UploadFile(ctx, "handbook.md", strings.NewReader("Vacation is 20 days."), FilePurposeAssistants)
+	require.NoError(t, err)
+	assert.Equal(t, "file_1", file.ID)
+	assert.Equal(t, int64(20), file.Bytes)
+
+	files, err := llm.ListFiles(ctx, FilePurposeAssistants)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "file_2", files[1].ID)
+
+	vs, err := llm.CreateVectorStore(ctx, "docs", file.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "vs_1", vs.ID)
+	assert.Equal(t, int64(1), vs.FileCounts.InProgress)
+
+	vsf, err := llm.AttachFile(ctx, vs.ID, "file_2")
+	require.NoError(t, err)
+	assert.Equal(t, "in_progress", vsf.Status)
+
+	vs, err = llm.GetVectorStore(ctx, vs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", vs.Status)
+
+	res, err := llm.SearchVectorStore(ctx, vs.ID, "vacation", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []VectorStoreSearchResult{
+		{FileID: "file_1", Filename: "handbook.md", Score: 0.8, Text: "Vacation is\n20 days."},
+	}, res)
+
+	require.NoError(t, llm.DeleteVectorStore(ctx, vs.ID))
+	require.NoError(t, llm.DeleteFile(ctx, file.ID))
+
+	err = llm.DeleteFile(ctx, "file_404")
+	assert.EqualError(t, err, "failed to delete file: API returned unexpected status code: 404: not found")
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{
+		"POST /files", "GET /files", "GET /files", "POST /vector_stores", "POST /vector_stores/vs_1/files",
+		"GET /vector_stores/vs_1", "POST /vector_stores/vs_1/search", "DELETE /vector_stores/vs_1",
+		"DELETE /files/file_1", "DELETE /files/file_404",
+	}, calls)
+}
+
+func TestFiles_AzureNotSupported(t *testing.T) {
+	t.Parallel()
+
+	llm, err := New(
+		WithToken("test-token"),
+		WithBaseURL("http://localhost"),
+		WithModel("gpt-4o"),
+		WithProvider(ProviderAzure),
+		WithAPIVersion("2024-12-01-preview"),
+	)
+	require.NoError(t, err)
+
+	_, err = llm.UploadFile(context.Background(), "a.txt", strings.NewReader("a"), FilePurposeAssistants)
+	assert.EqualError(t, err, "files API is not supported for this provider")
+}
+
+func TestFileSearchTool(t *testing.T) {
+	t.Parallel()
+
+	tool, err := responsesToolFromTool(FileSearchTool(5, "vs_1"))
+	require.NoError(t, err)
+	require.NotNil(t, tool.OfFileSearch)
+	assert.Equal(t, []string{"vs_1"}, tool.OfFileSearch.VectorStoreIDs)
+	assert.Equal(t, int64(5), tool.OfFileSearch.MaxNumResults.Value)
+
+	_, err = responsesToolFromTool(llms.Tool{Type: "file_search"})
+	assert.EqualError(t, err, "file_search tool requires vector store IDs")
+}
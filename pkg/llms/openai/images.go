@@ -0,0 +1,60 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+)
+
+var _ llms.ImageModel = (*LLM)(nil)
+
+// GenerateImages implements llms.ImageModel with gpt-image and dall-e models,
+// the images are returned in PNG format.
+func (o *LLM) GenerateImages(ctx context.Context, prompt string, options ...llms.ImageOption) ([]llms.BinaryContent, error) {
+	opts := llms.NewImageOptions(options...)
+
+	req := &openaiclient.ImageRequest{
+		Model:   opts.Model,
+		Prompt:  prompt,
+		N:       opts.Count,
+		Quality: imageQuality(opts.Model, opts.Quality),
+	}
+	if opts.Width > 0 && opts.Height > 0 {
+		req.Size = fmt.Sprintf("%dx%d", opts.Width, opts.Height)
+	}
+
+	images, err := o.client.CreateImages(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create openai images")
+	}
+
+	parts := make([]llms.BinaryContent, 0, len(images))
+	for _, data := range images {
+		parts = append(parts, llms.BinaryContent{
+			MIMEType: "image/png",
+			Data:     data,
+		})
+	}
+	return parts, nil
+}
+
+// imageQuality maps the quality to the values supported by the model.
+func imageQuality(model string, quality llms.ImageQuality) string {
+	if quality == "" {
+		return ""
+	}
+	switch model {
+	case "dall-e-2":
+		// quality is not supported
+		return ""
+	case "dall-e-3":
+		if quality == llms.ImageQualityHigh {
+			return "hd"
+		}
+		return "standard"
+	}
+	return string(quality)
+}
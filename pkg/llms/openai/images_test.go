@@ -0,0 +1,86 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateImages(t *testing.T) {
+	t.Parallel()
+
+	png := []byte("\x89PNG image")
+	tcases := []struct {
+		name    string
+		options []llms.ImageOption
+		exp     map[string]any
+	}{
+		{
+			name: "default",
+			exp:  map[string]any{"model": "gpt-image-1", "prompt": "a gopher", "n": float64(1)},
+		},
+		{
+			name: "gpt-image",
+			options: []llms.ImageOption{
+				llms.WithImageCount(2),
+				llms.WithImageSize(1024, 1536),
+				llms.WithImageQuality(llms.ImageQualityLow),
+			},
+			exp: map[string]any{"model": "gpt-image-1", "prompt": "a gopher", "n": float64(2), "size": "1024x1536", "quality": "low"},
+		},
+		{
+			name: "dall-e-3",
+			options: []llms.ImageOption{
+				llms.WithImageModel("dall-e-3"),
+				llms.WithImageQuality(llms.ImageQualityHigh),
+			},
+			exp: map[string]any{"model": "dall-e-3", "prompt": "a gopher", "n": float64(1), "quality": "hd", "response_format": "b64_json"},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var req map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/images/generations", r.URL.Path)
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				data := base64.StdEncoding.EncodeToString(png)
+				_, _ = w.Write([]byte(`{"created": 1, "data": [{"b64_json": "` + data + `"}]}`))
+			}))
+			defer srv.Close()
+
+			llm, err := New(WithToken("test-token"), WithBaseURL(srv.URL), WithModel("gpt-4o"))
+			require.NoError(t, err)
+
+			images, err := llm.GenerateImages(context.Background(), "a gopher", tc.options...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, req)
+			assert.Equal(t, []llms.BinaryContent{{MIMEType: "image/png", Data: png}}, images)
+		})
+	}
+}
+
+func TestGenerateImagesError(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"message": "content policy violation", "type": "invalid_request_error"}}`))
+	}))
+	defer srv.Close()
+
+	llm, err := New(WithToken("test-token"), WithBaseURL(srv.URL), WithModel("gpt-4o"))
+	require.NoError(t, err)
+
+	_, err = llm.GenerateImages(context.Background(), "a gopher")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "content policy violation")
+}
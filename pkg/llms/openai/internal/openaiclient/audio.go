@@ -0,0 +1,148 @@
+package openaiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	// DefaultTranscriptionModel is the default model for the speech to text.
+	DefaultTranscriptionModel = "whisper-1"
+	// DefaultSpeechModel is the default model for the text to speech.
+	DefaultSpeechModel = "tts-1"
+	// DefaultVoice is the default voice for the text to speech.
+	DefaultVoice = "alloy"
+)
+
+// TranscriptionRequest is a request to transcribe the audio.
+type TranscriptionRequest struct {
+	Model       string
+	File        []byte
+	FileName    string
+	Language    string
+	Prompt      string
+	Temperature float64
+}
+
+// TranscriptionResponse is a response to the transcription request.
+type TranscriptionResponse struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// SpeechRequest is a request to generate the speech.
+type SpeechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	ResponseFormat string  `json:"response_format,omitempty"`
+	Speed          float64 `json:"speed,omitempty"`
+	Instructions   string  `json:"instructions,omitempty"`
+}
+
+// CreateTranscription transcribes the audio.
+func (c *Client) CreateTranscription(ctx context.Context, r *TranscriptionRequest) (*TranscriptionResponse, error) {
+	if r.Model == "" {
+		r.Model = DefaultTranscriptionModel
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", r.FileName)
+	if err != nil {
+		return nil, errors.Wrap(err, "create form file")
+	}
+	if _, err = part.Write(r.File); err != nil {
+		return nil, errors.Wrap(err, "write form file")
+	}
+	fields := map[string]string{
+		"model":    r.Model,
+		"language": r.Language,
+		"prompt":   r.Prompt,
+	}
+	// whisper returns the language and the duration only in verbose_json
+	if r.Model == DefaultTranscriptionModel {
+		fields["response_format"] = "verbose_json"
+	}
+	if r.Temperature > 0 {
+		fields["temperature"] = strconv.FormatFloat(r.Temperature, 'f', -1, 64)
+	}
+	for k, v := range fields {
+		if v == "" {
+			continue
+		}
+		if err = w.WriteField(k, v); err != nil {
+			return nil, errors.Wrap(err, "write form field")
+		}
+	}
+	if err = w.Close(); err != nil {
+		return nil, errors.Wrap(err, "close form")
+	}
+
+	res, err := c.doAudio(ctx, "/audio/transcriptions", r.Model, w.FormDataContentType(), &body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response TranscriptionResponse
+	if err := json.Unmarshal(res, &response); err != nil {
+		return nil, errors.Wrap(err, "decode response")
+	}
+	return &response, nil
+}
+
+// CreateSpeech generates the speech, and returns the audio data.
+func (c *Client) CreateSpeech(ctx context.Context, r *SpeechRequest) ([]byte, error) {
+	if r.Model == "" {
+		r.Model = DefaultSpeechModel
+	}
+	if r.Voice == "" {
+		r.Voice = DefaultVoice
+	}
+
+	payloadBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal payload")
+	}
+	return c.doAudio(ctx, "/audio/speech", r.Model, "application/json", bytes.NewReader(payloadBytes))
+}
+
+func (c *Client) doAudio(ctx context.Context, suffix, model, contentType string, body io.Reader) ([]byte, error) {
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL(suffix, model), body)
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", contentType)
+
+	r, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "send request")
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, apiError(r, fmt.Sprintf("API returned unexpected status code: %d", r.StatusCode))
+	}
+
+	res, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response")
+	}
+	return res, nil
+}
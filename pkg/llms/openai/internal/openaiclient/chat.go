@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/cockroachdb/errors"
@@ -35,6 +36,7 @@ type ChatRequest struct {
 	Temperature         float64        `json:"temperature"`
 	TopP                float64        `json:"top_p,omitempty"`
 	MaxCompletionTokens int            `json:"max_completion_tokens,omitempty"`
+	MaxTokens           int            `json:"max_tokens,omitempty"`
 	N                   int            `json:"n,omitempty"`
 	StopWords           []string       `json:"stop,omitempty"`
 	Stream              bool           `json:"stream,omitempty"`
@@ -58,6 +60,8 @@ type ChatRequest struct {
 	// This can be either a string or a ToolChoice object.
 	// If it is a string, it should be one of 'none', or 'auto', otherwise it should be a ToolChoice object specifying a specific tool to use.
 	ToolChoice any `json:"tool_choice,omitempty"`
+	// ParallelToolCalls enables or disables the parallel function calling during the tool use.
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
 
 	// Options for streaming response. Only set this when you set stream: true.
 	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
@@ -70,6 +74,10 @@ type ChatRequest struct {
 	// Return an error to stop streaming early.
 	StreamingReasoningFunc func(ctx context.Context, reasoningChunk, chunk []byte) error `json:"-"`
 
+	// StreamingToolCallFunc is a function to be called for each tool call delta of a streaming response.
+	// Return an error to stop streaming early.
+	StreamingToolCallFunc func(ctx context.Context, delta llms.ToolCallDelta) error `json:"-"`
+
 	// Metadata allows you to specify additional information that will be passed to the model.
 	Metadata map[string]any `json:"metadata,omitempty"`
 
@@ -77,6 +85,26 @@ type ChatRequest struct {
 	PromptCacheKey string `json:"prompt_cache_key,omitempty"`
 	// PromptCacheRetention controls request-level prompt cache retention.
 	PromptCacheRetention string `json:"prompt_cache_retention,omitempty"`
+
+	// GuidedGrammar is the vLLM constrained decoding with the grammar.
+	GuidedGrammar string `json:"guided_grammar,omitempty"`
+	// GuidedRegex is the vLLM constrained decoding with the regular expression.
+	GuidedRegex string `json:"guided_regex,omitempty"`
+	// Grammar is the llama.cpp constrained decoding with the GBNF grammar.
+	Grammar string `json:"grammar,omitempty"`
+
+	// ReasoningEffort is the reasoning effort of the reasoning models: none, low, medium or high.
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+
+	// ReturnImages is the Perplexity option to return the images from the search results.
+	ReturnImages bool `json:"return_images,omitempty"`
+	// ReturnRelatedQuestions is the Perplexity option to return the related questions.
+	ReturnRelatedQuestions bool `json:"return_related_questions,omitempty"`
+
+	// Provider is the OpenRouter provider routing preferences.
+	Provider *ProviderPreferences `json:"provider,omitempty"`
+	// Usage is the OpenRouter option to return the cost in the usage.
+	Usage *UsageOptions `json:"usage,omitempty"`
 }
 
 // Tool is a tool to use in a chat request.
@@ -123,6 +151,9 @@ type ToolFunction struct {
 
 // ToolCall is a call to a tool.
 type ToolCall struct {
+	// Index is the position of the tool call in the streaming response,
+	// the deltas of the parallel tool calls are interleaved.
+	Index    *int         `json:"index,omitempty"`
 	ID       string       `json:"id,omitempty"`
 	Type     ToolType     `json:"type"`
 	Function ToolFunction `json:"function,omitempty"`
@@ -153,6 +184,9 @@ type ChatMessage struct { //nolint:musttag
 
 	// This field is only used with the deepseek-reasoner model and represents the reasoning contents of the assistant message before the final answer.
 	ReasoningContent string `json:"reasoning_content,omitempty"`
+
+	// Refusal is the refusal message of the model, when it declined to respond.
+	Refusal string `json:"refusal,omitempty"`
 }
 
 func (m ChatMessage) MarshalJSON() ([]byte, error) {
@@ -177,6 +211,7 @@ func (m ChatMessage) MarshalJSON() ([]byte, error) {
 
 			// This field is only used with the deepseek-reasoner model and represents the reasoning contents of the assistant message before the final answer.
 			ReasoningContent string `json:"reasoning_content,omitempty"`
+			Refusal          string `json:"refusal,omitempty"`
 		}(m)
 		return json.Marshal(msg)
 	}
@@ -192,6 +227,7 @@ func (m ChatMessage) MarshalJSON() ([]byte, error) {
 
 		// This field is only used with the deepseek-reasoner model and represents the reasoning contents of the assistant message before the final answer.
 		ReasoningContent string `json:"reasoning_content,omitempty"`
+		Refusal          string `json:"refusal,omitempty"`
 	}(m)
 	return json.Marshal(msg)
 }
@@ -216,6 +252,7 @@ func (m *ChatMessage) UnmarshalJSON(data []byte) error {
 		ToolCallID string `json:"tool_call_id,omitempty"`
 		// This field is only used with the deepseek-reasoner model and represents the reasoning contents of the assistant message before the final answer.
 		ReasoningContent string `json:"reasoning_content,omitempty"`
+		Refusal          string `json:"refusal,omitempty"`
 	}{}
 	err := json.Unmarshal(data, &msg)
 	if err != nil {
@@ -271,6 +308,27 @@ type ChatCompletionChoice struct {
 	Message      ChatMessage  `json:"message"`
 	FinishReason FinishReason `json:"finish_reason"`
 	LogProbs     *LogProbs    `json:"logprobs,omitempty"`
+	// ContentFilterResults are the Azure content filter results of the completion.
+	ContentFilterResults ContentFilterResults `json:"content_filter_results,omitempty"`
+}
+
+// ContentFilterResults are the Azure content filter results keyed by the category.
+// The results are kept raw, as the shape differs by the category and the API version.
+type ContentFilterResults map[string]json.RawMessage
+
+// Filtered returns the sorted categories that blocked the content.
+func (r ContentFilterResults) Filtered() []string {
+	var categories []string
+	for category, raw := range r {
+		var res struct {
+			Filtered bool `json:"filtered"`
+		}
+		if json.Unmarshal(raw, &res) == nil && res.Filtered {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
 }
 
 // ChatUsage is the usage of a chat completion request.
@@ -281,6 +339,12 @@ type ChatUsage struct {
 	CompletionTokensDetails struct {
 		ReasoningTokens int `json:"reasoning_tokens"`
 	} `json:"completion_tokens_details"`
+	// PromptCacheHitTokens is the number of the prompt tokens served from the DeepSeek context cache.
+	PromptCacheHitTokens int `json:"prompt_cache_hit_tokens,omitempty"`
+	// PromptCacheMissTokens is the number of the prompt tokens not served from the DeepSeek context cache.
+	PromptCacheMissTokens int `json:"prompt_cache_miss_tokens,omitempty"`
+	// Cost is the OpenRouter cost of the request in credits.
+	Cost float64 `json:"cost,omitempty"`
 }
 
 // ChatCompletionResponse is a response to a chat request.
@@ -292,6 +356,38 @@ type ChatCompletionResponse struct {
 	Object            string                  `json:"object,omitempty"`
 	Usage             ChatUsage               `json:"usage,omitempty"`
 	SystemFingerprint string                  `json:"system_fingerprint"`
+	// Provider is the name of the upstream provider that served the OpenRouter request.
+	Provider string `json:"provider,omitempty"`
+
+	SearchMetadata
+}
+
+// SearchMetadata is the search metadata returned by Perplexity.
+type SearchMetadata struct {
+	Citations        []string       `json:"citations,omitempty"`
+	SearchResults    []SearchResult `json:"search_results,omitempty"`
+	Images           []SearchImage  `json:"images,omitempty"`
+	RelatedQuestions []string       `json:"related_questions,omitempty"`
+}
+
+// IsEmpty returns true if no search metadata is returned.
+func (m *SearchMetadata) IsEmpty() bool {
+	return len(m.Citations) == 0 && len(m.SearchResults) == 0 && len(m.Images) == 0 && len(m.RelatedQuestions) == 0
+}
+
+// SearchResult is the web search result used to ground the response.
+type SearchResult struct {
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+	Date  string `json:"date,omitempty"`
+}
+
+// SearchImage is the image from the search results.
+type SearchImage struct {
+	ImageURL  string `json:"image_url"`
+	OriginURL string `json:"origin_url,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Width     int    `json:"width,omitempty"`
 }
 
 type Usage struct {
@@ -301,6 +397,12 @@ type Usage struct {
 	CompletionTokensDetails struct {
 		ReasoningTokens int `json:"reasoning_tokens"`
 	} `json:"completion_tokens_details"`
+	// PromptCacheHitTokens is the number of the prompt tokens served from the DeepSeek context cache.
+	PromptCacheHitTokens int `json:"prompt_cache_hit_tokens,omitempty"`
+	// PromptCacheMissTokens is the number of the prompt tokens not served from the DeepSeek context cache.
+	PromptCacheMissTokens int `json:"prompt_cache_miss_tokens,omitempty"`
+	// Cost is the OpenRouter cost of the request in credits.
+	Cost float64 `json:"cost,omitempty"`
 }
 
 // StreamedChatResponsePayload is a chunk from the stream.
@@ -318,8 +420,12 @@ type StreamedChatResponsePayload struct {
 			ToolCalls []*ToolCall `json:"tool_calls,omitempty"`
 			// This field is only used with the deepseek-reasoner model and represents the reasoning contents of the assistant message before the final answer.
 			ReasoningContent string `json:"reasoning_content,omitempty"`
+			// Refusal is the refusal message of the model.
+			Refusal string `json:"refusal,omitempty"`
 		} `json:"delta,omitempty"`
 		FinishReason FinishReason `json:"finish_reason,omitempty"`
+		// ContentFilterResults are the Azure content filter results of the chunk.
+		ContentFilterResults ContentFilterResults `json:"content_filter_results,omitempty"`
 	} `json:"choices,omitempty"`
 	SystemFingerprint string `json:"system_fingerprint"`
 	// An optional field that will only be present when you set stream_options: {"include_usage": true} in your request.
@@ -327,6 +433,10 @@ type StreamedChatResponsePayload struct {
 	// for the entire request.
 	Usage *Usage `json:"usage,omitempty"`
 	Error error  `json:"-"` // use for error handling only
+	// Provider is the name of the upstream provider that served the OpenRouter request.
+	Provider string `json:"provider,omitempty"`
+
+	SearchMetadata
 }
 
 // FunctionDefinition is a definition of a function that can be called by the model.
@@ -361,8 +471,13 @@ type FunctionCall struct {
 	Arguments string `json:"arguments"`
 }
 
+// isStreaming returns true if any of the streaming functions is set
+func (r *ChatRequest) isStreaming() bool {
+	return r.StreamingFunc != nil || r.StreamingReasoningFunc != nil || r.StreamingToolCallFunc != nil
+}
+
 func (c *Client) createChat(ctx context.Context, payload *ChatRequest) (*ChatCompletionResponse, error) {
-	if payload.StreamingFunc != nil || payload.StreamingReasoningFunc != nil {
+	if payload.isStreaming() {
 		payload.Stream = true
 		if payload.StreamOptions == nil {
 			payload.StreamOptions = &StreamOptions{IncludeUsage: true}
@@ -375,17 +490,15 @@ func (c *Client) createChat(ctx context.Context, payload *ChatRequest) (*ChatCom
 		return nil, err
 	}
 
-	// Build request
-	body := bytes.NewReader(payloadBytes)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL("/chat/completions", payload.Model), body)
-	if err != nil {
-		return nil, err
-	}
-
-	c.setHeaders(req)
-
 	// Send request
-	r, err := c.httpClient.Do(req)
+	r, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL("/chat/completions", payload.Model), bytes.NewReader(payloadBytes))
+		if err != nil {
+			return nil, err
+		}
+		c.setHeaders(req)
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -394,18 +507,9 @@ func (c *Client) createChat(ctx context.Context, payload *ChatRequest) (*ChatCom
 	}()
 
 	if r.StatusCode != http.StatusOK {
-		msg := fmt.Sprintf("API returned unexpected status code: %d", r.StatusCode)
-
-		// No need to check the error here: if it fails, we'll just return the
-		// status code.
-		var errResp errorMessage
-		if err := json.NewDecoder(r.Body).Decode(&errResp); err != nil {
-			return nil, errors.New(msg) // nolint:goerr113
-		}
-
-		return nil, errors.Errorf("%s: %s", msg, errResp.Error.Message) // nolint:goerr113
+		return nil, apiError(r, fmt.Sprintf("API returned unexpected status code: %d", r.StatusCode))
 	}
-	if payload.StreamingFunc != nil || payload.StreamingReasoningFunc != nil {
+	if payload.isStreaming() {
 		return parseStreamingChatResponse(ctx, r, payload)
 	}
 	// Parse response
@@ -470,6 +574,16 @@ func combineStreamingChatResponse(
 			response.Usage.PromptTokens = streamResponse.Usage.PromptTokens
 			response.Usage.TotalTokens = streamResponse.Usage.TotalTokens
 			response.Usage.CompletionTokensDetails.ReasoningTokens = streamResponse.Usage.CompletionTokensDetails.ReasoningTokens
+			response.Usage.PromptCacheHitTokens = streamResponse.Usage.PromptCacheHitTokens
+			response.Usage.PromptCacheMissTokens = streamResponse.Usage.PromptCacheMissTokens
+			response.Usage.Cost = streamResponse.Usage.Cost
+		}
+		if streamResponse.Provider != "" {
+			response.Provider = streamResponse.Provider
+		}
+		// the search metadata is repeated in the chunks, the last one is complete
+		if !streamResponse.SearchMetadata.IsEmpty() {
+			response.SearchMetadata = streamResponse.SearchMetadata
 		}
 
 		if len(streamResponse.Choices) == 0 {
@@ -481,10 +595,22 @@ func combineStreamingChatResponse(
 		response.Choices[0].Message.Content += choice.Delta.Content
 		response.Choices[0].FinishReason = choice.FinishReason
 		response.Choices[0].Message.ReasoningContent += choice.Delta.ReasoningContent
+		response.Choices[0].Message.Refusal += choice.Delta.Refusal
+		if len(choice.ContentFilterResults) > 0 {
+			response.Choices[0].ContentFilterResults = choice.ContentFilterResults
+		}
 
 		if len(choice.Delta.ToolCalls) > 0 {
-			chunk, response.Choices[0].Message.ToolCalls = updateToolCalls(response.Choices[0].Message.ToolCalls,
+			var deltas []llms.ToolCallDelta
+			chunk, response.Choices[0].Message.ToolCalls, deltas = updateToolCalls(response.Choices[0].Message.ToolCalls,
 				choice.Delta.ToolCalls)
+			if payload.StreamingToolCallFunc != nil {
+				for _, d := range deltas {
+					if err := payload.StreamingToolCallFunc(ctx, d); err != nil {
+						return nil, errors.Wrap(err, "streaming tool call func returned an error")
+					}
+				}
+			}
 		}
 
 		if payload.StreamingFunc != nil {
@@ -503,53 +629,75 @@ func combineStreamingChatResponse(
 	return &response, nil
 }
 
-func updateToolCalls(tools []ToolCall, delta []*ToolCall) ([]byte, []ToolCall) {
+// updateToolCalls assembles the tool call deltas into the tool calls,
+// and returns the JSON of the deltas as the chunk, and the assembled state of the updated tool calls.
+// The deltas are matched by the index, as the parallel tool calls are streamed interleaved,
+// or by the ID, or appended to the last tool call for the providers that do not send the index.
+func updateToolCalls(tools []ToolCall, delta []*ToolCall) ([]byte, []ToolCall, []llms.ToolCallDelta) {
 	if len(delta) == 0 {
-		return []byte{}, tools
+		return []byte{}, tools, nil
 	}
+	deltas := make([]llms.ToolCallDelta, 0, len(delta))
 	for _, t := range delta {
-		// if we have arguments append to the last Tool call
-		if t.Type == `` && t.Function.Arguments != `` {
-			lindex := len(tools) - 1
-			if lindex < 0 {
-				continue
+		pos := findToolCall(tools, t)
+		if pos < 0 {
+			// this is a new tool call, append that to the stack
+			tools = append(tools, *t)
+			pos = len(tools) - 1
+		} else {
+			tc := &tools[pos]
+			if t.ID != "" {
+				tc.ID = t.ID
 			}
-
-			tools[lindex].Function.Arguments += t.Function.Arguments
-			continue
+			if t.Type != "" {
+				tc.Type = t.Type
+			}
+			if t.Function.Name != "" {
+				tc.Function.Name = t.Function.Name
+			}
+			tc.Function.Arguments += t.Function.Arguments
 		}
-
-		// Otherwise, this is a new tool call, append that to the stack
-		tools = append(tools, *t)
+		deltas = append(deltas, llms.ToolCallDelta{
+			Index:          pos,
+			ID:             tools[pos].ID,
+			Name:           tools[pos].Function.Name,
+			ArgumentsDelta: t.Function.Arguments,
+			Arguments:      tools[pos].Function.Arguments,
+		})
 	}
 
 	chunk, _ := json.Marshal(delta) // nolint:errchkjson
 
-	return chunk, tools
+	return chunk, tools, deltas
 }
 
-// StreamingChatResponseTools is a helper function to append tool calls to the stack.
-func StreamingChatResponseTools(tools []ToolCall, delta []*ToolCall) ([]byte, []ToolCall) {
-	if len(delta) == 0 {
-		return []byte{}, tools
+// findToolCall returns the position of the tool call the delta belongs to, or -1 for a new tool call
+func findToolCall(tools []ToolCall, t *ToolCall) int {
+	if t.Index != nil {
+		for i := range tools {
+			if tools[i].Index != nil && *tools[i].Index == *t.Index {
+				return i
+			}
+		}
+		return -1
 	}
-	for _, t := range delta {
-		// if we have arguments append to the last Tool call
-		if t.Type == `` && t.Function.Arguments != `` {
-			lindex := len(tools) - 1
-			if lindex < 0 {
-				continue
+	if t.ID != "" {
+		for i := range tools {
+			if tools[i].ID == t.ID {
+				return i
 			}
-
-			tools[lindex].Function.Arguments += t.Function.Arguments
-			continue
 		}
-
-		// Otherwise, this is a new tool call, append that to the stack
-		tools = append(tools, *t)
+		return -1
 	}
+	if t.Type == "" && t.Function.Name == "" {
+		// the continuation of the arguments of the last tool call
+		return len(tools) - 1
+	}
+	return -1
+}
 
-	chunk, _ := json.Marshal(delta) // nolint:errchkjson
-
+// StreamingChatResponseTools is a helper function to append tool calls to the stack.
+func StreamingChatResponseTools(tools []ToolCall, delta []*ToolCall) ([]byte, []ToolCall) {
+	chunk, tools, _ := updateToolCalls(tools, delta)
 	return chunk, tools
 }
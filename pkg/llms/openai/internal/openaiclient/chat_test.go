@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/invopop/jsonschema"
 	orderedmap "github.com/pb33f/ordered-map/v2"
 	"github.com/stretchr/testify/assert"
@@ -35,6 +36,36 @@ func TestParseStreamingChatResponse_FinishReason(t *testing.T) {
 	assert.Equal(t, FinishReason("stop"), resp.Choices[0].FinishReason)
 }
 
+func TestParseStreamingChatResponse_SearchMetadata(t *testing.T) {
+	t.Parallel()
+	mockBody := `data: {"choices":[{"index":0,"delta":{"role":"assistant","content":"Go"}}],"citations":["https://go.dev"]}
+
+data: {"choices":[{"index":0,"delta":{"content":" rocks"},"finish_reason":"stop"}],"citations":["https://go.dev"],"search_results":[{"title":"Go","url":"https://go.dev"}],"related_questions":["Why Go?"]}
+
+data: {"choices":[],"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3}}
+
+data: [DONE]`
+	r := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString(mockBody)),
+	}
+
+	req := &ChatRequest{
+		StreamingFunc: func(_ context.Context, _ []byte) error {
+			return nil
+		},
+	}
+
+	resp, err := parseStreamingChatResponse(context.Background(), r, req)
+	require.NoError(t, err)
+	assert.Equal(t, "Go rocks", resp.Choices[0].Message.Content)
+	assert.Equal(t, SearchMetadata{
+		Citations:        []string{"https://go.dev"},
+		SearchResults:    []SearchResult{{Title: "Go", URL: "https://go.dev"}},
+		RelatedQuestions: []string{"Why Go?"},
+	}, resp.SearchMetadata)
+}
+
 func TestParseStreamingChatResponse_ReasoningContent(t *testing.T) {
 	t.Parallel()
 	mockBody := `data: {"choices":[{"index":0,"delta":{"role":"assistant","content":"final answer","reasoning_content":"step-by-step reasoning"},"finish_reason":"stop"}]}`
@@ -144,6 +175,19 @@ func TestChatRequest_Marshal_WithPromptCacheFields(t *testing.T) {
 	assert.Contains(t, string(data), `"prompt_cache_retention":"in_memory"`)
 }
 
+func TestChatRequest_Marshal_ParallelToolCalls(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal(ChatRequest{Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `parallel_tool_calls`)
+
+	parallel := false
+	data, err = json.Marshal(ChatRequest{Model: "gpt-4o", ParallelToolCalls: &parallel})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"parallel_tool_calls":false`)
+}
+
 func TestTool_MarshalUnmarshal(t *testing.T) {
 	t.Parallel()
 	tool1 := Tool{
@@ -192,3 +236,74 @@ func TestTool_MarshalUnmarshal(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, `{"type":"function","function":{"name":"test","description":"test","parameters":{"properties":{"name":{"type":"string"}},"type":"object","required":["name"]}}}`, string(text))
 }
+
+func TestParseStreamingChatResponse_ParallelToolCalls(t *testing.T) {
+	t.Parallel()
+	mockBody := `data: {"choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"search","arguments":""}}]}}]}
+
+data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"weather","arguments":""}}]}}]}
+
+data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"query\":"}}]}}]}
+
+data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"arguments":"{\"city\":\"Paris\"}"}}]}}]}
+
+data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"go\"}"}}]}}]}
+
+data: {"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}
+
+data: [DONE]`
+	r := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString(mockBody)),
+	}
+
+	var deltas []llms.ToolCallDelta
+	req := &ChatRequest{
+		StreamingToolCallFunc: func(_ context.Context, delta llms.ToolCallDelta) error {
+			deltas = append(deltas, delta)
+			return nil
+		},
+	}
+
+	resp, err := parseStreamingChatResponse(context.Background(), r, req)
+	require.NoError(t, err)
+	assert.Equal(t, FinishReason("tool_calls"), resp.Choices[0].FinishReason)
+
+	calls := resp.Choices[0].Message.ToolCalls
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call_1", calls[0].ID)
+	assert.Equal(t, "search", calls[0].Function.Name)
+	assert.Equal(t, `{"query":"go"}`, calls[0].Function.Arguments)
+	assert.Equal(t, "call_2", calls[1].ID)
+	assert.Equal(t, "weather", calls[1].Function.Name)
+	assert.Equal(t, `{"city":"Paris"}`, calls[1].Function.Arguments)
+
+	require.Len(t, deltas, 5)
+	assert.Equal(t, llms.ToolCallDelta{Index: 0, ID: "call_1", Name: "search"}, deltas[0])
+	assert.Equal(t, llms.ToolCallDelta{Index: 1, ID: "call_2", Name: "weather"}, deltas[1])
+	assert.Equal(t, llms.ToolCallDelta{
+		Index:          0,
+		ID:             "call_1",
+		Name:           "search",
+		ArgumentsDelta: `"go"}`,
+		Arguments:      `{"query":"go"}`,
+	}, deltas[4])
+}
+
+func TestStreamingChatResponseTools_NoIndex(t *testing.T) {
+	t.Parallel()
+	_, tools := StreamingChatResponseTools(nil, []*ToolCall{
+		{ID: "call_1", Type: ToolTypeFunction, Function: ToolFunction{Name: "search"}},
+	})
+	_, tools = StreamingChatResponseTools(tools, []*ToolCall{
+		{Function: ToolFunction{Arguments: `{"query":`}},
+	})
+	_, tools = StreamingChatResponseTools(tools, []*ToolCall{
+		{Function: ToolFunction{Arguments: `"go"}`}},
+		{ID: "call_2", Type: ToolTypeFunction, Function: ToolFunction{Name: "weather", Arguments: `{}`}},
+	})
+	require.Len(t, tools, 2)
+	assert.Equal(t, `{"query":"go"}`, tools[0].Function.Arguments)
+	assert.Equal(t, "weather", tools[1].Function.Name)
+	assert.Equal(t, `{}`, tools[1].Function.Arguments)
+}
@@ -65,16 +65,7 @@ func (c *Client) createEmbedding(ctx context.Context, payload *embeddingPayload)
 	}()
 
 	if r.StatusCode != http.StatusOK {
-		msg := fmt.Sprintf("API returned unexpected status code: %d", r.StatusCode)
-
-		// No need to check the error here: if it fails, we'll just return the
-		// status code.
-		var errResp errorMessage
-		if err := json.NewDecoder(r.Body).Decode(&errResp); err != nil {
-			return nil, errors.New(msg) // nolint:goerr113
-		}
-
-		return nil, errors.Errorf("%s: %s", msg, errResp.Error.Message) // nolint:goerr113
+		return nil, apiError(r, fmt.Sprintf("API returned unexpected status code: %d", r.StatusCode))
 	}
 
 	var response embeddingResponsePayload
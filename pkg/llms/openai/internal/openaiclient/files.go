@@ -0,0 +1,283 @@
+package openaiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/cockroachdb/errors"
+)
+
+// File is the file uploaded to OpenAI.
+type File struct {
+	ID        string `json:"id"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+}
+
+// VectorStoreFileCounts are the counts of the files in the vector store by status.
+type VectorStoreFileCounts struct {
+	InProgress int64 `json:"in_progress"`
+	Completed  int64 `json:"completed"`
+	Failed     int64 `json:"failed"`
+	Cancelled  int64 `json:"cancelled"`
+	Total      int64 `json:"total"`
+}
+
+// VectorStore is the vector store of the files for the file_search tool.
+type VectorStore struct {
+	ID         string                `json:"id"`
+	Name       string                `json:"name"`
+	Status     string                `json:"status"`
+	CreatedAt  int64                 `json:"created_at"`
+	FileCounts VectorStoreFileCounts `json:"file_counts"`
+}
+
+// VectorStoreFile is the file attached to the vector store.
+type VectorStoreFile struct {
+	ID            string `json:"id"`
+	VectorStoreID string `json:"vector_store_id"`
+	Status        string `json:"status"`
+	LastError     *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"last_error,omitempty"`
+}
+
+// VectorStoreSearchRequest is the request to search the vector store.
+type VectorStoreSearchRequest struct {
+	Query         string `json:"query"`
+	MaxNumResults int    `json:"max_num_results,omitempty"`
+}
+
+// VectorStoreSearchResult is the chunk of the file found in the vector store.
+type VectorStoreSearchResult struct {
+	FileID     string         `json:"file_id"`
+	Filename   string         `json:"filename"`
+	Score      float64        `json:"score"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Content    []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+type listResponse[T any] struct {
+	Data    []T    `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+// UploadFile uploads the file with the purpose, e.g. assistants or user_data.
+func (c *Client) UploadFile(ctx context.Context, name, purpose string, body io.Reader) (*File, error) {
+	if !c.supportsFilesAPI() {
+		return nil, errors.WithStack(errFilesUnsupported)
+	}
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := w.WriteField("purpose", purpose); err != nil {
+		return nil, errors.Wrap(err, "write form field")
+	}
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		return nil, errors.Wrap(err, "create form file")
+	}
+	if _, err = io.Copy(part, body); err != nil {
+		return nil, errors.Wrap(err, "write form file")
+	}
+	if err = w.Close(); err != nil {
+		return nil, errors.Wrap(err, "close form")
+	}
+
+	var file File
+	if err = c.doFiles(ctx, http.MethodPost, "/files", w.FormDataContentType(), &buf, &file); err != nil {
+		return nil, errors.WithMessage(err, "failed to upload file")
+	}
+	return &file, nil
+}
+
+// ListFiles returns the uploaded files, filtered by the purpose if not empty.
+func (c *Client) ListFiles(ctx context.Context, purpose string) ([]File, error) {
+	if !c.supportsFilesAPI() {
+		return nil, errors.WithStack(errFilesUnsupported)
+	}
+	var files []File
+	after := ""
+	for {
+		q := url.Values{}
+		if purpose != "" {
+			q.Set("purpose", purpose)
+		}
+		if after != "" {
+			q.Set("after", after)
+		}
+		suffix := "/files"
+		if len(q) > 0 {
+			suffix += "?" + q.Encode()
+		}
+		var res listResponse[File]
+		if err := c.doFiles(ctx, http.MethodGet, suffix, "", nil, &res); err != nil {
+			return nil, errors.WithMessage(err, "failed to list files")
+		}
+		files = append(files, res.Data...)
+		if !res.HasMore || len(res.Data) == 0 {
+			return files, nil
+		}
+		after = res.LastID
+		if after == "" {
+			after = res.Data[len(res.Data)-1].ID
+		}
+	}
+}
+
+// DeleteFile deletes the uploaded file.
+func (c *Client) DeleteFile(ctx context.Context, fileID string) error {
+	if !c.supportsFilesAPI() {
+		return errors.WithStack(errFilesUnsupported)
+	}
+	if fileID == "" {
+		return errors.New("invalid argument: fileID is required")
+	}
+	if err := c.doFiles(ctx, http.MethodDelete, "/files/"+url.PathEscape(fileID), "", nil, nil); err != nil {
+		return errors.WithMessage(err, "failed to delete file")
+	}
+	return nil
+}
+
+// CreateVectorStore creates the vector store with the files.
+func (c *Client) CreateVectorStore(ctx context.Context, name string, fileIDs []string) (*VectorStore, error) {
+	if !c.supportsFilesAPI() {
+		return nil, errors.WithStack(errFilesUnsupported)
+	}
+	payload := map[string]any{"name": name}
+	if len(fileIDs) > 0 {
+		payload["file_ids"] = fileIDs
+	}
+	var vs VectorStore
+	if err := c.doFilesJSON(ctx, http.MethodPost, "/vector_stores", payload, &vs); err != nil {
+		return nil, errors.WithMessage(err, "failed to create vector store")
+	}
+	return &vs, nil
+}
+
+// GetVectorStore returns the vector store, with the counts of the processed files.
+func (c *Client) GetVectorStore(ctx context.Context, vectorStoreID string) (*VectorStore, error) {
+	if !c.supportsFilesAPI() {
+		return nil, errors.WithStack(errFilesUnsupported)
+	}
+	if vectorStoreID == "" {
+		return nil, errors.New("invalid argument: vectorStoreID is required")
+	}
+	var vs VectorStore
+	if err := c.doFiles(ctx, http.MethodGet, "/vector_stores/"+url.PathEscape(vectorStoreID), "", nil, &vs); err != nil {
+		return nil, errors.WithMessage(err, "failed to get vector store")
+	}
+	return &vs, nil
+}
+
+// DeleteVectorStore deletes the vector store, the files are not deleted.
+func (c *Client) DeleteVectorStore(ctx context.Context, vectorStoreID string) error {
+	if !c.supportsFilesAPI() {
+		return errors.WithStack(errFilesUnsupported)
+	}
+	if vectorStoreID == "" {
+		return errors.New("invalid argument: vectorStoreID is required")
+	}
+	if err := c.doFiles(ctx, http.MethodDelete, "/vector_stores/"+url.PathEscape(vectorStoreID), "", nil, nil); err != nil {
+		return errors.WithMessage(err, "failed to delete vector store")
+	}
+	return nil
+}
+
+// AttachVectorStoreFile attaches the uploaded file to the vector store,
+// the file is processed asynchronously.
+func (c *Client) AttachVectorStoreFile(ctx context.Context, vectorStoreID, fileID string) (*VectorStoreFile, error) {
+	if !c.supportsFilesAPI() {
+		return nil, errors.WithStack(errFilesUnsupported)
+	}
+	if vectorStoreID == "" || fileID == "" {
+		return nil, errors.New("invalid argument: vectorStoreID and fileID are required")
+	}
+	var vsf VectorStoreFile
+	suffix := "/vector_stores/" + url.PathEscape(vectorStoreID) + "/files"
+	if err := c.doFilesJSON(ctx, http.MethodPost, suffix, map[string]any{"file_id": fileID}, &vsf); err != nil {
+		return nil, errors.WithMessage(err, "failed to attach file")
+	}
+	return &vsf, nil
+}
+
+// SearchVectorStore returns the chunks of the files in the vector store relevant to the query.
+func (c *Client) SearchVectorStore(ctx context.Context, vectorStoreID string, r *VectorStoreSearchRequest) ([]VectorStoreSearchResult, error) {
+	if !c.supportsFilesAPI() {
+		return nil, errors.WithStack(errFilesUnsupported)
+	}
+	if vectorStoreID == "" {
+		return nil, errors.New("invalid argument: vectorStoreID is required")
+	}
+	var res listResponse[VectorStoreSearchResult]
+	suffix := "/vector_stores/" + url.PathEscape(vectorStoreID) + "/search"
+	if err := c.doFilesJSON(ctx, http.MethodPost, suffix, r, &res); err != nil {
+		return nil, errors.WithMessage(err, "failed to search vector store")
+	}
+	return res.Data, nil
+}
+
+func (c *Client) doFilesJSON(ctx context.Context, method, suffix string, payload, out any) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "marshal payload")
+	}
+	return c.doFiles(ctx, method, suffix, "application/json", bytes.NewReader(payloadBytes), out)
+}
+
+func (c *Client) doFiles(ctx context.Context, method, suffix, contentType string, body io.Reader, out any) error {
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.buildURL(suffix, ""), body)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	c.setHeaders(req)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	r, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send request")
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	if r.StatusCode != http.StatusOK {
+		return apiError(r, fmt.Sprintf("API returned unexpected status code: %d", r.StatusCode))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "decode response")
+	}
+	return nil
+}
+
+// errFilesUnsupported is returned when the files API is invoked on a provider,
+// that does not support the OpenAI Files and Vector Stores API.
+var errFilesUnsupported = errors.New("files API is not supported for this provider")
+
+// supportsFilesAPI reports whether the configured provider supports the OpenAI
+// Files and Vector Stores API, Azure deployments are not supported as for the Batch API.
+func (c *Client) supportsFilesAPI() bool {
+	return c.supportsBatchAPI()
+}
@@ -0,0 +1,110 @@
+package openaiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	// DefaultImageModel is the default model for the image generation.
+	DefaultImageModel = "gpt-image-1"
+)
+
+// ImageRequest is a request to generate images.
+type ImageRequest struct {
+	Model   string `json:"model"`
+	Prompt  string `json:"prompt"`
+	N       int    `json:"n,omitempty"`
+	Size    string `json:"size,omitempty"`
+	Quality string `json:"quality,omitempty"`
+	// ResponseFormat is required for dall-e models, gpt-image models always return b64_json.
+	ResponseFormat string `json:"response_format,omitempty"`
+}
+
+// Image is the generated image.
+type Image struct {
+	B64JSON       string `json:"b64_json,omitempty"`
+	URL           string `json:"url,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+// ImageResponse is a response to the image generation request.
+type ImageResponse struct {
+	Created int64   `json:"created"`
+	Data    []Image `json:"data"`
+}
+
+// IsDallE returns true for the legacy dall-e models.
+func IsDallE(model string) bool {
+	return strings.HasPrefix(model, "dall-e")
+}
+
+// CreateImages generates images, and returns the decoded image data.
+func (c *Client) CreateImages(ctx context.Context, r *ImageRequest) ([][]byte, error) {
+	if r.Model == "" {
+		r.Model = DefaultImageModel
+	}
+	if IsDallE(r.Model) {
+		r.ResponseFormat = "b64_json"
+	}
+
+	resp, err := c.createImages(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	images := make([][]byte, 0, len(resp.Data))
+	for _, img := range resp.Data {
+		data, err := base64.StdEncoding.DecodeString(img.B64JSON)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode image")
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+func (c *Client) createImages(ctx context.Context, payload *ImageRequest) (*ImageResponse, error) {
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal payload")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.buildURL("/images/generations", payload.Model), bytes.NewReader(payloadBytes))
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+	c.setHeaders(req)
+
+	r, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "send request")
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, apiError(r, fmt.Sprintf("API returned unexpected status code: %d", r.StatusCode))
+	}
+
+	var response ImageResponse
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return nil, errors.Wrap(err, "decode response")
+	}
+	return &response, nil
+}
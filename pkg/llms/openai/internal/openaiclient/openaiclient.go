@@ -2,6 +2,7 @@ package openaiclient
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/effective-security/gogentic/pkg/schema"
 	openaisdk "github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
@@ -33,14 +35,21 @@ const (
 	ProviderAzure      ProviderType = "AZURE"
 	ProviderAzureAD    ProviderType = "AZURE_AD"
 	ProviderPerplexity ProviderType = "PERPLEXITY"
+	ProviderVLLM       ProviderType = "VLLM"
+	ProviderLlamaCpp   ProviderType = "LLAMACPP"
+	ProviderGroq       ProviderType = "GROQ"
+	ProviderDeepSeek   ProviderType = "DEEPSEEK"
+	ProviderOpenRouter ProviderType = "OPENROUTER"
+	ProviderAzureAI    ProviderType = "AZURE_AI"
 )
 
 // ToolType is the type of a tool.
 type ToolType string
 
 const (
-	ToolTypeFunction  ToolType = "function"
-	ToolTypeWebSearch ToolType = "web_search"
+	ToolTypeFunction   ToolType = "function"
+	ToolTypeWebSearch  ToolType = "web_search"
+	ToolTypeFileSearch ToolType = "file_search"
 )
 
 // Client is a client for the OpenAI API.
@@ -60,6 +69,13 @@ type Client struct {
 
 	ResponseFormat *schema.ResponseFormat
 
+	// ReturnImages requests Perplexity to return the images from the search results.
+	ReturnImages bool
+	// ReturnRelatedQuestions requests Perplexity to return the related questions.
+	ReturnRelatedQuestions bool
+	// ProviderPreferences is the OpenRouter provider routing preferences.
+	ProviderPreferences *ProviderPreferences
+
 	// sdkOnce guards lazy construction of the openai-go SDK client used for
 	// endpoints (Batches, Files) that we do not call via hand-rolled HTTP.
 	sdkOnce sync.Once
@@ -261,9 +277,16 @@ func IsAzure(apiType ProviderType) bool {
 	return apiType == ProviderAzure || apiType == ProviderAzureAD
 }
 
+// IsSelfHosted returns true for the OpenAI compatible self-hosted servers.
+func IsSelfHosted(apiType ProviderType) bool {
+	return apiType == ProviderVLLM || apiType == ProviderLlamaCpp
+}
+
 func (c *Client) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
-	if c.Provider == ProviderOpenAI || c.Provider == ProviderAzure || c.Provider == ProviderAzureAD || c.Provider == "OPEN_AI" {
+	if c.Provider == ProviderOpenAI || c.Provider == ProviderAzure || c.Provider == ProviderAzureAD || c.Provider == "OPEN_AI" ||
+		c.Provider == ProviderGroq || c.Provider == ProviderDeepSeek || c.Provider == ProviderOpenRouter ||
+		IsSelfHosted(c.Provider) {
 		req.Header.Set("Authorization", "Bearer "+c.token)
 	} else {
 		req.Header.Set("api-key", c.token)
@@ -277,6 +300,10 @@ func (c *Client) buildURL(suffix string, model string) string {
 	if IsAzure(c.Provider) {
 		return c.buildAzureURL(suffix, model)
 	}
+	if c.Provider == ProviderAzureAI && c.apiVersion != "" {
+		// Azure AI model inference endpoint, the model is in the request body
+		return fmt.Sprintf("%s%s?api-version=%s", c.baseURL, suffix, c.apiVersion)
+	}
 
 	// open ai implement:
 	return fmt.Sprintf("%s%s", c.baseURL, suffix)
@@ -305,5 +332,61 @@ type errorMessage struct {
 	Error struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
+		// Code is the string or the numeric code of the error, depending on the provider.
+		Code any `json:"code"`
+		// InnerError is the Azure content filter result of the blocked prompt.
+		InnerError *struct {
+			Code                string               `json:"code"`
+			ContentFilterResult ContentFilterResults `json:"content_filter_result"`
+		} `json:"innererror"`
 	} `json:"error"`
 }
+
+// isContentFilter returns true for the Azure error of the blocked prompt,
+// the blocked completions are returned with the content_filter finish reason.
+func (e *errorMessage) isContentFilter() bool {
+	return e.Error.Code == "content_filter" ||
+		(e.Error.InnerError != nil && e.Error.InnerError.Code == "ResponsibleAIPolicyViolation")
+}
+
+func (e *errorMessage) contentFilterError() *llms.ContentFilterError {
+	cfe := &llms.ContentFilterError{
+		Prompt:  true,
+		Message: e.Error.Message,
+	}
+	if e.Error.InnerError != nil {
+		cfe.Categories = e.Error.InnerError.ContentFilterResult.Filtered()
+	}
+	return cfe
+}
+
+// code returns the error code, or the error type if the code is not a string
+func (e *errorMessage) code() string {
+	if code, ok := e.Error.Code.(string); ok && code != "" {
+		return code
+	}
+	return e.Error.Type
+}
+
+// apiError returns the error of the response with the unexpected status code,
+// classified by llms.ErrorKind, the msg is the prefix of the error message.
+func apiError(r *http.Response, msg string) error {
+	pe := &llms.ProviderError{
+		StatusCode: r.StatusCode,
+		RetryAfter: llms.RetryAfterHeader(r.Header),
+	}
+	// No need to check the error here: if it fails, we'll just return the
+	// status code.
+	var errResp errorMessage
+	if err := json.NewDecoder(r.Body).Decode(&errResp); err != nil {
+		pe.Err = errors.New(msg) // nolint:goerr113
+		return pe.Classify()
+	}
+	if errResp.isContentFilter() {
+		return errors.Wrap(errResp.contentFilterError(), msg)
+	}
+	pe.Code = errResp.code()
+	pe.Message = errResp.Error.Message
+	pe.Err = errors.Errorf("%s: %s", msg, errResp.Error.Message) // nolint:goerr113
+	return pe.Classify()
+}
@@ -0,0 +1,39 @@
+package openaiclient
+
+// ProviderPreferences is the OpenRouter provider routing preferences,
+// see https://openrouter.ai/docs/features/provider-routing
+type ProviderPreferences struct {
+	// Order is the list of the provider names to try in order, for example "anthropic" or "together".
+	Order []string `json:"order,omitempty" yaml:"order,omitempty"`
+	// AllowFallbacks allows the backup providers when the preferred ones are unavailable,
+	// by default true.
+	AllowFallbacks *bool `json:"allow_fallbacks,omitempty" yaml:"allow_fallbacks,omitempty"`
+	// RequireParameters routes only to the providers that support all the request parameters.
+	RequireParameters bool `json:"require_parameters,omitempty" yaml:"require_parameters,omitempty"`
+	// DataCollection is "allow" or "deny" the providers that may store the data.
+	DataCollection string `json:"data_collection,omitempty" yaml:"data_collection,omitempty"`
+	// ZDR routes only to the zero data retention endpoints.
+	ZDR bool `json:"zdr,omitempty" yaml:"zdr,omitempty"`
+	// Only is the list of the provider names allowed for the request.
+	Only []string `json:"only,omitempty" yaml:"only,omitempty"`
+	// Ignore is the list of the provider names to skip for the request.
+	Ignore []string `json:"ignore,omitempty" yaml:"ignore,omitempty"`
+	// Quantizations is the list of the allowed quantization levels, for example "fp8".
+	Quantizations []string `json:"quantizations,omitempty" yaml:"quantizations,omitempty"`
+	// Sort is "price", "throughput" or "latency" to sort the providers by.
+	Sort string `json:"sort,omitempty" yaml:"sort,omitempty"`
+	// MaxPrice is the maximum price in USD per 1M tokens.
+	MaxPrice *MaxPrice `json:"max_price,omitempty" yaml:"max_price,omitempty"`
+}
+
+// MaxPrice is the maximum price in USD per 1M tokens the request may be routed to.
+type MaxPrice struct {
+	Prompt     float64 `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	Completion float64 `json:"completion,omitempty" yaml:"completion,omitempty"`
+}
+
+// UsageOptions is the OpenRouter usage accounting option.
+type UsageOptions struct {
+	// Include returns the cost of the request in the usage.
+	Include bool `json:"include"`
+}
@@ -0,0 +1,75 @@
+package openaiclient
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxRetries is the number of the retries of the rate limited request.
+const MaxRetries = 2
+
+type rateLimitFuncKey struct{}
+
+// WithRateLimitFunc returns the context with the function to be called,
+// before the rate limited request is retried.
+func WithRateLimitFunc(ctx context.Context, notify func(ctx context.Context, attempt int, wait time.Duration)) context.Context {
+	return context.WithValue(ctx, rateLimitFuncKey{}, notify)
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns the context with the key,
+// to be sent in the Idempotency-Key header of the request.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// do sends the request, and retries the rate limited responses with the backoff.
+// The newRequest is called for each attempt, as the body is consumed.
+// The response of the final attempt is returned to the caller, and not reported.
+func (c *Client) do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	notify, _ := ctx.Value(rateLimitFuncKey{}).(func(context.Context, int, time.Duration))
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			// the retried attempts use the same key
+			req.Header.Set("Idempotency-Key", key)
+		}
+		r, err := c.httpClient.Do(req)
+		if err != nil || r.StatusCode != http.StatusTooManyRequests || attempt > MaxRetries {
+			return r, err
+		}
+		_ = r.Body.Close()
+
+		wait := retryAfter(r.Header, attempt)
+		if notify != nil {
+			notify(ctx, attempt, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter returns the wait duration from the response headers,
+// or the exponential backoff.
+func retryAfter(h http.Header, attempt int) time.Duration {
+	if v, err := strconv.ParseFloat(h.Get("Retry-After-Ms"), 64); err == nil {
+		return time.Duration(v * float64(time.Millisecond))
+	}
+	if v, err := strconv.ParseFloat(h.Get("Retry-After"), 64); err == nil {
+		return time.Duration(v * float64(time.Second))
+	}
+	return min(time.Duration(float64(500*time.Millisecond)*math.Pow(2, float64(attempt-1))), 8*time.Second)
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llmutils"
 	"github.com/effective-security/xlog"
 	"github.com/openai/openai-go/v3/responses"
 )
@@ -25,15 +26,16 @@ func (c *Client) createResponse(ctx context.Context, payload *responses.Response
 	}
 
 	u := c.buildURL("/responses", payload.Model)
-	logger.ContextKV(ctx, xlog.DEBUG, "url", u)
+	logger.ContextKV(ctx, xlog.DEBUG, "url", llmutils.DefaultRedactor.String(u))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return nil, errors.Wrap(err, "create request")
-	}
-	c.setHeaders(req)
-
-	r, err := c.httpClient.Do(req)
+	r, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(bodyBytes))
+		if err != nil {
+			return nil, errors.Wrap(err, "create request")
+		}
+		c.setHeaders(req)
+		return req, nil
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "send request")
 	}
@@ -44,11 +46,7 @@ func (c *Client) createResponse(ctx context.Context, payload *responses.Response
 		if r.StatusCode == http.StatusNotFound {
 			msg += ": url: " + u
 		}
-		var errResp errorMessage
-		if err := json.NewDecoder(r.Body).Decode(&errResp); err != nil {
-			return nil, errors.New(msg)
-		}
-		return nil, errors.Errorf("%s: %s", msg, errResp.Error.Message)
+		return nil, apiError(r, msg)
 	}
 
 	body, err := io.ReadAll(r.Body)
@@ -86,16 +84,17 @@ func (c *Client) createStreamingResponse( //nolint:cyclop
 	}
 
 	u := c.buildURL("/responses", payload.Model)
-	logger.ContextKV(ctx, xlog.DEBUG, "url", u, "stream", true)
+	logger.ContextKV(ctx, xlog.DEBUG, "url", llmutils.DefaultRedactor.String(u), "stream", true)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return nil, errors.Wrap(err, "create request")
-	}
-	c.setHeaders(req)
-	req.Header.Set("Accept", "text/event-stream")
-
-	r, err := c.httpClient.Do(req)
+	r, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(bodyBytes))
+		if err != nil {
+			return nil, errors.Wrap(err, "create request")
+		}
+		c.setHeaders(req)
+		req.Header.Set("Accept", "text/event-stream")
+		return req, nil
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "send request")
 	}
@@ -106,11 +105,7 @@ func (c *Client) createStreamingResponse( //nolint:cyclop
 		if r.StatusCode == http.StatusNotFound {
 			msg += ": url: " + u
 		}
-		var errResp errorMessage
-		if err := json.NewDecoder(r.Body).Decode(&errResp); err != nil {
-			return nil, errors.New(msg)
-		}
-		return nil, errors.Errorf("%s: %s", msg, errResp.Error.Message)
+		return nil, apiError(r, msg)
 	}
 
 	return parseStreamingResponses(ctx, r.Body, streamFunc)
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
 	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
 )
 
@@ -32,6 +33,9 @@ func newClient(opts ...Option) (*options, *openaiclient.Client, error) {
 	for _, opt := range opts {
 		opt(options)
 	}
+	if len(options.httpMiddlewares) > 0 {
+		options.httpClient = llmhttp.WrapDoer(options.httpClient, options.httpMiddlewares...)
+	}
 
 	// set of options needed for Azure client
 	if openaiclient.IsAzure(openaiclient.ProviderType(options.provider)) && options.apiVersion == "" {
@@ -44,7 +48,10 @@ func newClient(opts ...Option) (*options, *openaiclient.Client, error) {
 		}
 	}
 
-	if len(options.token) == 0 {
+	// the self-hosted servers may run without the API key,
+	// Azure AI may use Microsoft Entra ID auth instead of the key
+	if len(options.token) == 0 && !openaiclient.IsSelfHosted(openaiclient.ProviderType(options.provider)) &&
+		options.provider != ProviderAzureAI {
 		return options, nil, ErrMissingToken
 	}
 
@@ -59,7 +66,13 @@ func newClient(opts ...Option) (*options, *openaiclient.Client, error) {
 		options.embeddingModel,
 		options.responseFormat,
 	)
-	return options, cli, err
+	if err != nil {
+		return options, nil, err
+	}
+	cli.ReturnImages = options.returnImages
+	cli.ReturnRelatedQuestions = options.returnRelatedQuestions
+	cli.ProviderPreferences = options.providerPreferences
+	return options, cli, nil
 }
 
 func getEnvs(keys ...string) string {
@@ -0,0 +1,43 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "waf-token", r.Header.Get("X-Waf-Token"))
+		assert.Equal(t, "GET /files", r.Method+" "+r.URL.Path)
+		_, _ = w.Write([]byte(`{"data":[{"id":"file_1"}],"has_more":false}`))
+	}))
+	defer srv.Close()
+
+	calls := 0
+	llm, err := New(WithToken("test-token"), WithBaseURL(srv.URL), WithModel("gpt-4o"),
+		WithHTTPClient(srv.Client()),
+		WithHTTPMiddleware(
+			llmhttp.Headers(http.Header{"X-Waf-Token": {"waf-token"}}),
+			func(next http.RoundTripper) http.RoundTripper {
+				return llmhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					calls++
+					return next.RoundTrip(req)
+				})
+			},
+		))
+	require.NoError(t, err)
+
+	files, err := llm.ListFiles(context.Background(), FilePurposeAssistants)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, 1, calls)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+	"github.com/effective-security/gogentic/pkg/llmutils"
 	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/effective-security/x/values"
 	"github.com/openai/openai-go/v3/packages/param"
@@ -24,6 +25,7 @@ type LLM struct {
 
 const (
 	RoleSystem    = "system"
+	RoleDeveloper = "developer"
 	RoleAssistant = "assistant"
 	RoleUser      = "user"
 	RoleFunction  = "function"
@@ -53,11 +55,42 @@ func (o *LLM) GetName() string {
 
 // GetProviderType implements the Model interface.
 func (o *LLM) GetProviderType() llms.ProviderType {
-	return llms.ProviderOpenAI
+	switch o.client.Provider {
+	case openaiclient.ProviderVLLM:
+		return llms.ProviderVLLM
+	case openaiclient.ProviderLlamaCpp:
+		return llms.ProviderLlamaCpp
+	case openaiclient.ProviderGroq:
+		return llms.ProviderGroq
+	case openaiclient.ProviderDeepSeek:
+		return llms.ProviderDeepSeek
+	case openaiclient.ProviderOpenRouter:
+		return llms.ProviderOpenRouter
+	case openaiclient.ProviderAzureAI:
+		return llms.ProviderAzureAI
+	default:
+		return llms.ProviderOpenAI
+	}
 }
 
 // GenerateContent implements the Model interface.
 func (o *LLM) GenerateContent(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) { //nolint: lll, cyclop, goerr113, funlen
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.RateLimitFunc != nil {
+		// the rate limited requests are retried by the client
+		ctx = openaiclient.WithRateLimitFunc(ctx, opts.RateLimitFunc)
+	}
+	if opts.IdempotencyKey != "" {
+		ctx = openaiclient.WithIdempotencyKey(ctx, opts.IdempotencyKey)
+	}
+	// the tool call IDs of the conversation started with another provider can be rejected
+	messages, _ = llmutils.RemapToolCallIDs(messages, llmutils.ToolCallIDFormatFor(o.GetProviderType()))
+	// the large images are rejected by the API
+	messages, _ = llmutils.FitImages(messages, llmutils.ImagePolicyFor(o.GetProviderType()))
+
 	if o.client.SupportsResponsesAPI() {
 		return o.generateContentFromResponses(ctx, messages, options...)
 	}
@@ -73,7 +106,7 @@ func (o *LLM) generateContentFromChat(ctx context.Context, messages []llms.Messa
 
 	result, err := o.client.CreateChat(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, o.withErrorProvider(err)
 	}
 	if len(result.Choices) == 0 {
 		return nil, errors.Wrap(ErrEmptyResponse, "empty response from chat")
@@ -81,6 +114,9 @@ func (o *LLM) generateContentFromChat(ctx context.Context, messages []llms.Messa
 
 	choices := make([]*llms.ContentChoice, len(result.Choices))
 	for i, c := range result.Choices {
+		if err := o.chatContentFilterError(c); err != nil {
+			return nil, err
+		}
 		choices[i] = &llms.ContentChoice{
 			Content:    c.Message.Content,
 			StopReason: fmt.Sprint(c.FinishReason),
@@ -91,6 +127,8 @@ func (o *LLM) generateContentFromChat(ctx context.Context, messages []llms.Messa
 				ReasoningTokens: uint64(result.Usage.CompletionTokensDetails.ReasoningTokens),
 			},
 		}
+		applyReasoningContent(choices[i], c.Message.ReasoningContent)
+		applyContextCache(choices[i], &result.Usage)
 
 		for _, tool := range c.Message.ToolCalls {
 			choices[i].ToolCalls = append(choices[i].ToolCalls, llms.ToolCall{
@@ -106,6 +144,8 @@ func (o *LLM) generateContentFromChat(ctx context.Context, messages []llms.Messa
 		if len(choices[i].ToolCalls) > 0 {
 			choices[i].FuncCall = choices[i].ToolCalls[0].FunctionCall
 		}
+		applySearchMetadata(choices[i], &result.SearchMetadata)
+		applyRoutingMetadata(choices[i], result)
 	}
 	response := &llms.ContentResponse{Choices: choices}
 	return response, nil
@@ -120,12 +160,18 @@ func (o *LLM) buildChatRequestBody(messages []llms.Message, options ...llms.Call
 		opt(&opts)
 	}
 
+	if !supportsDeveloperRole(o.client.Provider) {
+		messages = foldDeveloperMessages(messages)
+	}
+
 	chatMsgs := make([]*ChatMessage, 0, len(messages))
 	for _, mc := range messages {
 		msg := &ChatMessage{MultiContent: mc.Parts}
 		switch mc.Role {
 		case llms.RoleSystem:
 			msg.Role = RoleSystem
+		case llms.RoleDeveloper:
+			msg.Role = RoleDeveloper
 		case llms.RoleAI:
 			msg.Role = RoleAssistant
 		case llms.RoleHuman:
@@ -152,6 +198,10 @@ func (o *LLM) buildChatRequestBody(messages []llms.Message, options ...llms.Call
 		newParts, toolCalls := ExtractToolParts(msg)
 		msg.MultiContent = newParts
 		msg.ToolCalls = toolCallsFromToolCalls(toolCalls)
+		if o.client.Provider == openaiclient.ProviderDeepSeek && len(msg.ToolCalls) > 0 {
+			// DeepSeek requires the reasoning of the tool calls to be passed back
+			msg.ReasoningContent = reasoningContent(mc.Parts)
+		}
 
 		chatMsgs = append(chatMsgs, msg)
 	}
@@ -161,6 +211,7 @@ func (o *LLM) buildChatRequestBody(messages []llms.Message, options ...llms.Call
 		Messages:               chatMsgs,
 		StreamingFunc:          opts.StreamingFunc,
 		StreamingReasoningFunc: opts.StreamingReasoningFunc,
+		StreamingToolCallFunc:  opts.StreamingToolCallFunc,
 		Temperature:            opts.Temperature,
 		N:                      opts.N,
 		FrequencyPenalty:       opts.FrequencyPenalty,
@@ -168,12 +219,30 @@ func (o *LLM) buildChatRequestBody(messages []llms.Message, options ...llms.Call
 
 		MaxCompletionTokens: opts.MaxTokens,
 
-		ToolChoice:     opts.ToolChoice,
+		ToolChoice:     chatToolChoice(opts.ToolChoice),
 		Seed:           opts.Seed,
 		Metadata:       opts.Metadata,
 		ResponseFormat: opts.ResponseFormat,
 	}
 	applyPromptCacheToChatRequest(req, o.client.Provider, &opts)
+	if modelSupportsReasoning(values.StringsCoalesce(opts.Model, o.client.Model)) {
+		req.ReasoningEffort = chatReasoningEffort(opts.ReasoningEffort)
+	}
+	if o.client.Provider == openaiclient.ProviderPerplexity {
+		req.ReturnImages = o.client.ReturnImages
+		req.ReturnRelatedQuestions = o.client.ReturnRelatedQuestions
+	}
+	if o.client.Provider == openaiclient.ProviderOpenRouter {
+		req.Provider = o.client.ProviderPreferences
+		req.Usage = &openaiclient.UsageOptions{Include: true}
+	}
+	if o.client.Provider == openaiclient.ProviderAzureAI {
+		// the Azure AI model inference API has no max_completion_tokens
+		req.MaxTokens, req.MaxCompletionTokens = req.MaxCompletionTokens, 0
+	}
+	if err := applyConstraintToChatRequest(req, o.client.Provider, opts.Constraint); err != nil {
+		return nil, err
+	}
 
 	for _, tool := range opts.Tools {
 		t, err := toolFromTool(tool)
@@ -182,6 +251,10 @@ func (o *LLM) buildChatRequestBody(messages []llms.Message, options ...llms.Call
 		}
 		req.Tools = append(req.Tools, t)
 	}
+	if len(req.Tools) > 0 {
+		// the parallel_tool_calls is only allowed with the tools
+		req.ParallelToolCalls = opts.ParallelToolCalls
+	}
 
 	if o.client.ResponseFormat != nil {
 		req.ResponseFormat = o.client.ResponseFormat
@@ -189,6 +262,21 @@ func (o *LLM) buildChatRequestBody(messages []llms.Message, options ...llms.Call
 	return req, nil
 }
 
+// chatToolChoice returns the tool choice in the Chat Completions format,
+// the provider specific choice is returned as is
+func chatToolChoice(choice any) any {
+	switch choice.(type) {
+	case string, llms.ToolChoice, *llms.ToolChoice:
+	default:
+		return choice
+	}
+	mode, function := llms.ParseToolChoice(choice)
+	if function != "" {
+		return llms.FunctionToolChoice(function)
+	}
+	return mode
+}
+
 func (o *LLM) generateContentFromResponses(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
 	opts := llms.CallOptions{}
 	for _, opt := range options {
@@ -207,6 +295,9 @@ func (o *LLM) generateContentFromResponses(ctx context.Context, messages []llms.
 		result, err = o.client.CreateResponse(ctx, req)
 	}
 	if err != nil {
+		return nil, o.withErrorProvider(err)
+	}
+	if err := o.responsesContentFilterError(result); err != nil {
 		return nil, err
 	}
 
@@ -261,13 +352,24 @@ func (o *LLM) buildResponsesRequestBody(messages []llms.Message, options ...llms
 		opt(&opts)
 	}
 
+	if opts.Constraint != nil {
+		return nil, errors.Errorf("%s constraint is not supported by the Responses API", opts.Constraint.Type)
+	}
+
+	if !supportsDeveloperRole(o.client.Provider) {
+		messages = foldDeveloperMessages(messages)
+	}
+
 	var inputItems responses.ResponseInputParam
 	for _, mc := range messages {
 		switch mc.Role {
-		case llms.RoleSystem, llms.RoleHuman:
-			role := "user"
-			if mc.Role == llms.RoleSystem {
-				role = "system"
+		case llms.RoleSystem, llms.RoleDeveloper, llms.RoleHuman:
+			role := RoleUser
+			switch mc.Role {
+			case llms.RoleSystem:
+				role = RoleSystem
+			case llms.RoleDeveloper:
+				role = RoleDeveloper
 			}
 			var contents responses.ResponseInputMessageContentListParam
 			for _, p := range mc.Parts {
@@ -278,6 +380,8 @@ func (o *LLM) buildResponsesRequestBody(messages []llms.Message, options ...llms
 					contents = append(contents, responses.ResponseInputContentUnionParam{OfInputImage: &responses.ResponseInputImageParam{ImageURL: param.NewOpt(v.URL), Detail: responses.ResponseInputImageDetail(v.Detail)}})
 				case llms.BinaryContent:
 					contents = append(contents, responses.ResponseInputContentUnionParam{OfInputFile: &responses.ResponseInputFileParam{FileData: param.NewOpt(v.String())}})
+				case llms.DocumentContent:
+					contents = append(contents, responses.ResponseInputContentUnionParam{OfInputFile: inputFileFromDocument(v)})
 				default:
 					return nil, errors.Errorf("unsupported content part type %T", p)
 				}
@@ -364,8 +468,10 @@ func (o *LLM) buildResponsesRequestBody(messages []llms.Message, options ...llms
 	}
 
 	if opts.ToolChoice != nil {
-		if s, ok := opts.ToolChoice.(string); ok {
-			req.ToolChoice = responses.ResponseNewParamsToolChoiceUnion{OfToolChoiceMode: param.NewOpt(responses.ToolChoiceOptions(s))}
+		if mode, function := llms.ParseToolChoice(opts.ToolChoice); function != "" {
+			req.ToolChoice = responses.ResponseNewParamsToolChoiceUnion{OfFunctionTool: &responses.ToolChoiceFunctionParam{Name: function}}
+		} else {
+			req.ToolChoice = responses.ResponseNewParamsToolChoiceUnion{OfToolChoiceMode: param.NewOpt(responses.ToolChoiceOptions(mode))}
 		}
 	}
 
@@ -376,6 +482,9 @@ func (o *LLM) buildResponsesRequestBody(messages []llms.Message, options ...llms
 		}
 		req.Tools = append(req.Tools, t)
 	}
+	if len(req.Tools) > 0 && opts.ParallelToolCalls != nil {
+		req.ParallelToolCalls = param.NewOpt(*opts.ParallelToolCalls)
+	}
 
 	if o.client.ResponseFormat != nil {
 		req.Text = toResponsesText(o.client.ResponseFormat)
@@ -483,6 +592,17 @@ func responsesToolFromTool(t llms.Tool) (responses.ToolUnionParam, error) {
 				Filters: filters,
 			},
 		}, nil
+	case string(openaiclient.ToolTypeFileSearch):
+		if t.FileSearchOptions == nil || len(t.FileSearchOptions.VectorStoreIDs) == 0 {
+			return responses.ToolUnionParam{}, errors.Errorf("file_search tool requires vector store IDs")
+		}
+		fs := &responses.FileSearchToolParam{
+			VectorStoreIDs: t.FileSearchOptions.VectorStoreIDs,
+		}
+		if t.FileSearchOptions.MaxNumResults > 0 {
+			fs.MaxNumResults = param.NewOpt(int64(t.FileSearchOptions.MaxNumResults))
+		}
+		return responses.ToolUnionParam{OfFileSearch: fs}, nil
 	case string(openaiclient.ToolTypeFunction):
 		if t.Function == nil {
 			return responses.ToolUnionParam{}, errors.Errorf("function tool missing definition")
@@ -520,7 +640,7 @@ func toResponsesText(f *schema.ResponseFormat) responses.ResponseTextConfigParam
 		return responses.ResponseTextConfigParam{Format: responses.ResponseFormatTextConfigUnionParam{OfText: &shared.ResponseFormatTextParam{}}}
 	}
 	switch f.Type {
-	case "json_schema":
+	case schema.ResponseFormatTypeJSONSchema:
 		var schemaMap map[string]any
 		if f.JSONSchema != nil {
 			// Build map from our schema struct
@@ -544,9 +664,8 @@ func toResponsesText(f *schema.ResponseFormat) responses.ResponseTextConfigParam
 				Strict: param.NewOpt(strict),
 			}},
 		}
-	case "json_object":
-		// Older JSON mode; treat as plain text to avoid strict schema
-		fallthrough
+	case schema.ResponseFormatTypeJSONObject:
+		return responses.ResponseTextConfigParam{Format: responses.ResponseFormatTextConfigUnionParam{OfJSONObject: &shared.ResponseFormatJSONObjectParam{}}}
 	default:
 		return responses.ResponseTextConfigParam{Format: responses.ResponseFormatTextConfigUnionParam{OfText: &shared.ResponseFormatTextParam{}}}
 	}
@@ -576,6 +695,22 @@ func enforceStrictSchema(m map[string]any) {
 
 // modelSupportsReasoning returns true for models that accept the reasoning.effort parameter.
 // gpt-4o and older chat models do not support it.
+// chatReasoningEffort returns the reasoning_effort of the chat request,
+// or empty string for the model default.
+func chatReasoningEffort(effort llms.ReasoningEffort) string {
+	switch effort {
+	case llms.ReasoningEffortNone:
+		return "none"
+	case llms.ReasoningEffortLow:
+		return "low"
+	case llms.ReasoningEffortMedium:
+		return "medium"
+	case llms.ReasoningEffortHigh:
+		return "high"
+	}
+	return ""
+}
+
 func modelSupportsReasoning(model string) bool {
 	return strings.HasPrefix(model, "o1") ||
 		strings.HasPrefix(model, "o3") ||
@@ -1,6 +1,7 @@
 package openai
 
 import (
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
 	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
 	"github.com/effective-security/gogentic/pkg/schema"
 )
@@ -20,6 +21,12 @@ const (
 	ProviderAzure      ProviderType = "AZURE"
 	ProviderAzureAD    ProviderType = "AZURE_AD"
 	ProviderPerplexity ProviderType = "PERPLEXITY"
+	ProviderVLLM       ProviderType = "VLLM"
+	ProviderLlamaCpp   ProviderType = "LLAMACPP"
+	ProviderGroq       ProviderType = "GROQ"
+	ProviderDeepSeek   ProviderType = "DEEPSEEK"
+	ProviderOpenRouter ProviderType = "OPENROUTER"
+	ProviderAzureAI    ProviderType = "AZURE_AI"
 )
 const (
 	DefaultAPIVersion = "2023-05-15"
@@ -32,9 +39,18 @@ type options struct {
 	organization string
 	provider     ProviderType
 	httpClient   openaiclient.Doer
+	// httpMiddlewares wrap the httpClient
+	httpMiddlewares []llmhttp.Middleware
 
 	responseFormat *schema.ResponseFormat
 
+	// Perplexity search metadata
+	returnImages           bool
+	returnRelatedQuestions bool
+
+	// OpenRouter provider routing
+	providerPreferences *ProviderPreferences
+
 	// required when provider is APITypeAzure or APITypeAzureAD
 	apiVersion     string
 	embeddingModel string
@@ -108,9 +124,42 @@ func WithHTTPClient(client openaiclient.Doer) Option {
 	}
 }
 
+// WithHTTPMiddleware wraps the HTTP client with the middlewares,
+// for the proxies, the request signing or the extra headers, see llmhttp.Middleware.
+// The first middleware is the outermost one.
+func WithHTTPMiddleware(middlewares ...llmhttp.Middleware) Option {
+	return func(opts *options) {
+		opts.httpMiddlewares = append(opts.httpMiddlewares, middlewares...)
+	}
+}
+
 // WithResponseFormat allows setting a custom response format.
 func WithResponseFormat(responseFormat *schema.ResponseFormat) Option {
 	return func(opts *options) {
 		opts.responseFormat = responseFormat
 	}
 }
+
+// WithReturnImages requests Perplexity to return the images from the search results,
+// the images are returned in the GenerationInfo of the response choice.
+func WithReturnImages() Option {
+	return func(opts *options) {
+		opts.returnImages = true
+	}
+}
+
+// WithReturnRelatedQuestions requests Perplexity to return the related questions,
+// the questions are returned in the GenerationInfo of the response choice.
+func WithReturnRelatedQuestions() Option {
+	return func(opts *options) {
+		opts.returnRelatedQuestions = true
+	}
+}
+
+// WithProviderPreferences sets the OpenRouter provider routing preferences,
+// such as the order of the upstream providers and the fallbacks.
+func WithProviderPreferences(prefs *ProviderPreferences) Option {
+	return func(opts *options) {
+		opts.providerPreferences = prefs
+	}
+}
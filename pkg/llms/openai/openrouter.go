@@ -0,0 +1,30 @@
+package openai
+
+import (
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+)
+
+// ProviderPreferences is the OpenRouter provider routing preferences.
+type ProviderPreferences = openaiclient.ProviderPreferences
+
+// MaxPrice is the maximum price in USD per 1M tokens of ProviderPreferences.
+type MaxPrice = openaiclient.MaxPrice
+
+// applyRoutingMetadata adds the upstream provider and the cost returned by OpenRouter
+// to the GenerationInfo of the choice.
+func applyRoutingMetadata(choice *llms.ContentChoice, result *openaiclient.ChatCompletionResponse) {
+	if result.Provider == "" && result.Usage.Cost == 0 {
+		return
+	}
+
+	if choice.GenerationInfo == nil {
+		choice.GenerationInfo = map[string]any{}
+	}
+	if result.Provider != "" {
+		choice.GenerationInfo[llms.GenerationInfoProvider] = result.Provider
+	}
+	if result.Usage.Cost != 0 {
+		choice.GenerationInfo[llms.GenerationInfoCost] = result.Usage.Cost
+	}
+}
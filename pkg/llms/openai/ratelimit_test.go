@@ -0,0 +1,114 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitFunc(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		limited  int32
+		calls    int32
+		attempts []int
+		err      string
+	}{
+		{name: "retried", limited: 2, calls: 3, attempts: []int{1, 2}},
+		{name: "final attempt", limited: 10, calls: 3, attempts: []int{1, 2}, err: "API returned unexpected status code: 429: rate limited"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if calls.Add(1) <= tt.limited {
+					w.Header().Set("Retry-After-Ms", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+					_, _ = w.Write([]byte(`{"error":{"message":"rate limited"}}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"local",
+					"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}]}`))
+			}))
+			defer srv.Close()
+
+			llm, err := New(
+				WithToken("test-token"),
+				WithBaseURL(srv.URL),
+				WithModel("local"),
+				WithProvider(ProviderVLLM),
+			)
+			require.NoError(t, err)
+
+			var attempts []int
+			resp, err := llm.GenerateContent(context.Background(),
+				[]llms.Message{humanMsg("hello")},
+				llms.WithRateLimitFunc(func(_ context.Context, attempt int, wait time.Duration) {
+					assert.Equal(t, time.Millisecond, wait)
+					attempts = append(attempts, attempt)
+				}),
+			)
+			assert.Equal(t, tt.calls, calls.Load())
+			assert.Equal(t, tt.attempts, attempts)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				assert.ErrorIs(t, err, llms.ErrRateLimited)
+				assert.Equal(t, time.Millisecond, llms.RetryAfter(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Hello", resp.Choices[0].Content)
+		})
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After-Ms", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"rate limited"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"local",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+
+	llm, err := New(
+		WithToken("test-token"),
+		WithBaseURL(srv.URL),
+		WithModel("local"),
+		WithProvider(ProviderVLLM),
+	)
+	require.NoError(t, err)
+
+	_, err = llm.GenerateContent(context.Background(), []llms.Message{humanMsg("hello")}, llms.WithIdempotencyKey("run-1:llm:1"))
+	require.NoError(t, err)
+	// the retried request has the same key
+	assert.Equal(t, []string{"run-1:llm:1", "run-1:llm:1"}, keys)
+
+	keys = nil
+	_, err = llm.GenerateContent(context.Background(), []llms.Message{humanMsg("hello")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{""}, keys)
+}
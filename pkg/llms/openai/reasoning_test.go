@@ -0,0 +1,46 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildChatRequestBody_ReasoningEffort(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		model  string
+		effort llms.ReasoningEffort
+		exp    string
+	}{
+		{model: "o3-mini", effort: llms.ReasoningEffortDefault, exp: ""},
+		{model: "o3-mini", effort: llms.ReasoningEffortLow, exp: "low"},
+		{model: "o4-mini", effort: llms.ReasoningEffortMedium, exp: "medium"},
+		{model: "gpt-5.1", effort: llms.ReasoningEffortNone, exp: "none"},
+		{model: "gpt-5", effort: llms.ReasoningEffortHigh, exp: "high"},
+		// not a reasoning model
+		{model: "gpt-4o", effort: llms.ReasoningEffortHigh, exp: ""},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.model, func(t *testing.T) {
+			t.Parallel()
+
+			llm, err := New(
+				WithToken("test-token"),
+				WithBaseURL("http://example.test/v1"),
+				WithModel(tc.model),
+				WithProvider(ProviderAzure),
+				WithAPIVersion("2024-10-21"),
+				WithEmbeddingModel("text-embedding-3-small"),
+			)
+			require.NoError(t, err)
+
+			req, err := llm.buildChatRequestBody([]llms.Message{humanMsg("hello")}, llms.WithReasoningEffort(tc.effort))
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, req.ReasoningEffort)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package openai
+
+import (
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai/internal/openaiclient"
+)
+
+// SearchResult is the Perplexity search result in the GenerationInfo.
+type SearchResult = openaiclient.SearchResult
+
+// SearchImage is the Perplexity image in the GenerationInfo.
+type SearchImage = openaiclient.SearchImage
+
+// applySearchMetadata adds the search metadata returned by Perplexity
+// to the GenerationInfo and the Citations of the choice.
+func applySearchMetadata(choice *llms.ContentChoice, meta *openaiclient.SearchMetadata) {
+	if meta.IsEmpty() {
+		return
+	}
+
+	if choice.GenerationInfo == nil {
+		choice.GenerationInfo = map[string]any{}
+	}
+	if len(meta.Citations) > 0 {
+		choice.GenerationInfo[llms.GenerationInfoCitations] = meta.Citations
+	}
+	if len(meta.SearchResults) > 0 {
+		choice.GenerationInfo[llms.GenerationInfoSearchResults] = meta.SearchResults
+	}
+	if len(meta.Images) > 0 {
+		choice.GenerationInfo[llms.GenerationInfoImages] = meta.Images
+	}
+	if len(meta.RelatedQuestions) > 0 {
+		choice.GenerationInfo[llms.GenerationInfoRelatedQuestions] = meta.RelatedQuestions
+	}
+
+	// the search results have the titles, the citations are URLs only
+	var citations []llms.Citation
+	for _, r := range meta.SearchResults {
+		citations = append(citations, llms.Citation{URL: r.URL, Title: r.Title, Date: r.Date})
+	}
+	for _, url := range meta.Citations {
+		citations = append(citations, llms.Citation{URL: url})
+	}
+	choice.Citations = llms.MergeCitations(citations)
+}
@@ -0,0 +1,79 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerplexitySearchMetadata(t *testing.T) {
+	t.Parallel()
+
+	var req map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		_, _ = w.Write([]byte(`{
+			"id": "1",
+			"model": "sonar",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Go 1.24 is released [1]."}, "finish_reason": "stop"}],
+			"citations": ["https://go.dev/blog", "https://go.dev/doc"],
+			"search_results": [{"title": "Go Blog", "url": "https://go.dev/blog", "date": "2025-02-11"}],
+			"images": [{"image_url": "https://go.dev/gopher.png", "origin_url": "https://go.dev", "height": 10, "width": 20}],
+			"related_questions": ["What is new in Go 1.24?"]
+		}`))
+	}))
+	defer srv.Close()
+
+	llm, err := New(
+		WithToken("test-token"),
+		WithBaseURL(srv.URL),
+		WithModel("sonar"),
+		WithProvider(ProviderPerplexity),
+		WithReturnImages(),
+		WithReturnRelatedQuestions(),
+	)
+	require.NoError(t, err)
+
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{humanMsg("what is new in Go?")})
+	require.NoError(t, err)
+	assert.Equal(t, true, req["return_images"])
+	assert.Equal(t, true, req["return_related_questions"])
+
+	require.Len(t, resp.Choices, 1)
+	choice := resp.Choices[0]
+	assert.Equal(t, []llms.Citation{
+		{URL: "https://go.dev/blog", Title: "Go Blog", Date: "2025-02-11"},
+		{URL: "https://go.dev/doc"},
+	}, choice.Citations)
+	assert.Equal(t, []string{"https://go.dev/blog", "https://go.dev/doc"}, choice.GenerationInfo[llms.GenerationInfoCitations])
+	assert.Equal(t, []SearchResult{{Title: "Go Blog", URL: "https://go.dev/blog", Date: "2025-02-11"}}, choice.GenerationInfo[llms.GenerationInfoSearchResults])
+	assert.Equal(t, []SearchImage{{ImageURL: "https://go.dev/gopher.png", OriginURL: "https://go.dev", Height: 10, Width: 20}}, choice.GenerationInfo[llms.GenerationInfoImages])
+	assert.Equal(t, []string{"What is new in Go 1.24?"}, choice.GenerationInfo[llms.GenerationInfoRelatedQuestions])
+}
+
+func TestBuildChatRequestBody_SearchOptions(t *testing.T) {
+	t.Parallel()
+
+	// the Perplexity options are not sent to the other providers
+	llm, err := New(
+		WithToken("test-token"),
+		WithBaseURL("http://example.test/v1"),
+		WithModel("gpt-4o"),
+		WithProvider(ProviderOpenAI),
+		WithReturnImages(),
+		WithReturnRelatedQuestions(),
+	)
+	require.NoError(t, err)
+
+	req, err := llm.buildChatRequestBody([]llms.Message{humanMsg("hello")})
+	require.NoError(t, err)
+	assert.False(t, req.ReturnImages)
+	assert.False(t, req.ReturnRelatedQuestions)
+}
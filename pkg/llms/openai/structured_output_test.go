@@ -131,3 +131,23 @@ func TestStructuredOutputFunctionCalling(t *testing.T) {
 	assert.Regexp(t, "\"search_engine\":", c1.ToolCalls[0].FunctionCall.Arguments)
 	assert.Regexp(t, "\"search_query\":", c1.ToolCalls[0].FunctionCall.Arguments)
 }
+
+func TestToResponsesText(t *testing.T) {
+	t.Parallel()
+
+	rf, err := schema.NewResponseFormat(reflect.TypeOf(struct {
+		Answer string `json:"answer"`
+	}{}), true)
+	require.NoError(t, err)
+
+	res := toResponsesText(rf)
+	require.NotNil(t, res.Format.OfJSONSchema)
+	assert.True(t, res.Format.OfJSONSchema.Strict.Value)
+
+	res = toResponsesText(schema.NewResponseFormatJSONObject())
+	assert.NotNil(t, res.Format.OfJSONObject)
+	assert.Nil(t, res.Format.OfJSONSchema)
+
+	res = toResponsesText(nil)
+	assert.NotNil(t, res.Format.OfText)
+}
@@ -0,0 +1,156 @@
+// Package openrouter provides the OpenRouter LLM.
+//
+// OpenRouter routes the requests to the models of many providers with one API key,
+// using the OpenAI compatible API, so the LLM is the OpenAI client
+// with the OpenRouter endpoint and provider type.
+// The upstream provider and the cost of the request are returned in the GenerationInfo
+// with the llms.GenerationInfoProvider and llms.GenerationInfoCost keys.
+package openrouter
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openai"
+)
+
+const (
+	// Provider is the provider type of the OpenRouter LLM.
+	Provider = llms.ProviderOpenRouter
+	// TokenEnvVarName is the environment variable with the API key.
+	TokenEnvVarName = "OPENROUTER_API_KEY" //nolint:gosec
+	// DefaultBaseURL is the OpenRouter OpenAI compatible endpoint.
+	DefaultBaseURL = "https://openrouter.ai/api/v1"
+	// DefaultModel is the auto router, that selects the model for the prompt.
+	DefaultModel = "openrouter/auto"
+)
+
+// ProviderPreferences is the provider routing preferences.
+type ProviderPreferences = openai.ProviderPreferences
+
+// MaxPrice is the maximum price in USD per 1M tokens of ProviderPreferences.
+type MaxPrice = openai.MaxPrice
+
+// ErrMissingToken is returned when the API key is not set.
+var ErrMissingToken = errors.New("missing the OpenRouter API key, set it in the OPENROUTER_API_KEY environment variable")
+
+// Doer performs a HTTP request.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type options struct {
+	token       string
+	model       string
+	baseURL     string
+	httpClient  Doer
+	siteURL     string
+	appName     string
+	preferences *ProviderPreferences
+}
+
+// Option is a functional option for the OpenRouter LLM.
+type Option func(*options)
+
+// WithToken passes the API key to the client. If not set, the key
+// is read from the OPENROUTER_API_KEY environment variable.
+func WithToken(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// WithModel sets the model in the provider/model format, for example anthropic/claude-sonnet-4.5,
+// by default DefaultModel.
+func WithModel(model string) Option {
+	return func(opts *options) {
+		opts.model = model
+	}
+}
+
+// WithBaseURL sets the base URL of the API, by default DefaultBaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(opts *options) {
+		opts.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient allows setting a custom HTTP client. If not set, the default value
+// is http.DefaultClient.
+func WithHTTPClient(client Doer) Option {
+	return func(opts *options) {
+		opts.httpClient = client
+	}
+}
+
+// WithSiteURL sets the HTTP-Referer header, the URL of the application
+// shown in the OpenRouter rankings.
+func WithSiteURL(siteURL string) Option {
+	return func(opts *options) {
+		opts.siteURL = siteURL
+	}
+}
+
+// WithAppName sets the X-Title header, the name of the application
+// shown in the OpenRouter rankings.
+func WithAppName(appName string) Option {
+	return func(opts *options) {
+		opts.appName = appName
+	}
+}
+
+// WithProviderPreferences sets the provider routing preferences of the requests.
+func WithProviderPreferences(prefs *ProviderPreferences) Option {
+	return func(opts *options) {
+		opts.preferences = prefs
+	}
+}
+
+// New returns the OpenRouter LLM.
+func New(opts ...Option) (*openai.LLM, error) {
+	o := &options{
+		token:      os.Getenv(TokenEnvVarName),
+		model:      DefaultModel,
+		baseURL:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.token == "" {
+		return nil, ErrMissingToken
+	}
+
+	client := o.httpClient
+	if o.siteURL != "" || o.appName != "" {
+		client = &headersDoer{Doer: client, siteURL: o.siteURL, appName: o.appName}
+	}
+	return openai.New(
+		openai.WithProvider(openai.ProviderOpenRouter),
+		openai.WithToken(o.token),
+		openai.WithModel(o.model),
+		openai.WithBaseURL(o.baseURL),
+		openai.WithHTTPClient(client),
+		openai.WithProviderPreferences(o.preferences),
+	)
+}
+
+// headersDoer is the Doer that sets the application attribution headers.
+type headersDoer struct {
+	Doer
+
+	siteURL string
+	appName string
+}
+
+func (d *headersDoer) Do(req *http.Request) (*http.Response, error) {
+	if d.siteURL != "" {
+		req.Header.Set("HTTP-Referer", d.siteURL)
+	}
+	if d.appName != "" {
+		req.Header.Set("X-Title", d.appName)
+	}
+	return d.Doer.Do(req)
+}
@@ -0,0 +1,77 @@
+package openrouter_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/openrouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Setenv(openrouter.TokenEnvVarName, "")
+
+	_, err := openrouter.New()
+	assert.ErrorIs(t, err, openrouter.ErrMissingToken)
+
+	llm, err := openrouter.New(openrouter.WithToken("test-token"))
+	require.NoError(t, err)
+	assert.Equal(t, openrouter.DefaultModel, llm.GetName())
+	assert.Equal(t, llms.ProviderOpenRouter, llm.GetProviderType())
+}
+
+func TestGenerateContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "https://example.com", r.Header.Get("HTTP-Referer"))
+		assert.Equal(t, "gogentic", r.Header.Get("X-Title"))
+
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "anthropic/claude-sonnet-4.5", req["model"])
+		assert.Equal(t, map[string]any{"include": true}, req["usage"])
+		assert.Equal(t, map[string]any{
+			"order":           []any{"anthropic", "amazon-bedrock"},
+			"allow_fallbacks": false,
+			"sort":            "latency",
+			"max_price":       map[string]any{"prompt": 3.0},
+		}, req["provider"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"anthropic/claude-sonnet-4.5","provider":"Amazon Bedrock",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":8,"completion_tokens":2,"total_tokens":10,"cost":0.00042}}`))
+	}))
+	defer srv.Close()
+
+	fallbacks := false
+	llm, err := openrouter.New(
+		openrouter.WithToken("test-token"),
+		openrouter.WithBaseURL(srv.URL),
+		openrouter.WithModel("anthropic/claude-sonnet-4.5"),
+		openrouter.WithSiteURL("https://example.com"),
+		openrouter.WithAppName("gogentic"),
+		openrouter.WithProviderPreferences(&openrouter.ProviderPreferences{
+			Order:          []string{"anthropic", "amazon-bedrock"},
+			AllowFallbacks: &fallbacks,
+			Sort:           "latency",
+			MaxPrice:       &openrouter.MaxPrice{Prompt: 3},
+		}),
+	)
+	require.NoError(t, err)
+
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "hello"),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "Hello", resp.Choices[0].Content)
+	assert.Equal(t, "Amazon Bedrock", resp.Choices[0].GenerationInfo[llms.GenerationInfoProvider])
+	assert.Equal(t, 0.00042, resp.Choices[0].GenerationInfo[llms.GenerationInfoCost])
+}
@@ -2,6 +2,7 @@ package llms
 
 import (
 	"context"
+	"time"
 
 	"github.com/effective-security/gogentic/pkg/schema"
 	"github.com/invopop/jsonschema"
@@ -107,6 +108,23 @@ type PromptCachePolicy struct {
 	Breakpoints []PromptCacheBreakpoint
 }
 
+// ConstraintType is the type of the constrained decoding.
+type ConstraintType string
+
+const (
+	// ConstraintGrammar constrains the output to the GBNF grammar.
+	ConstraintGrammar ConstraintType = "grammar"
+	// ConstraintRegex constrains the output to the regular expression.
+	ConstraintRegex ConstraintType = "regex"
+)
+
+// Constraint restricts the generated text during the decoding,
+// supported by the self-hosted providers with CapabilityConstrainedDecoding.
+type Constraint struct {
+	Type  ConstraintType
+	Value string
+}
+
 // CallOptions is a set of options for calling models. Not all models support
 // all options.
 type CallOptions struct {
@@ -126,6 +144,9 @@ type CallOptions struct {
 	// StreamingReasoningFunc is a function to be called for each chunk of a streaming response.
 	// Return an error to stop streaming early.
 	StreamingReasoningFunc func(ctx context.Context, reasoningChunk, chunk []byte) error
+	// StreamingToolCallFunc is a function to be called for each tool call delta of a streaming response.
+	// Return an error to stop streaming early.
+	StreamingToolCallFunc func(ctx context.Context, delta ToolCallDelta) error
 	// TopK is the number of tokens to consider for top-k sampling.
 	TopK int
 	// TopP is the cumulative probability for top-p sampling.
@@ -147,8 +168,12 @@ type CallOptions struct {
 
 	// Tools is a list of tools to use. Each tool can be a specific tool or a function.
 	Tools []Tool
-	// ToolChoice is the choice of tool to use, it can either be "none", "auto" (the default behavior), or a specific tool as described in the ToolChoice type.
+	// ToolChoice is the choice of tool to use, it can either be "none", "auto" (the default behavior), "required",
+	// or a specific tool as described in the ToolChoice type, see ParseToolChoice.
 	ToolChoice any
+	// ParallelToolCalls enables or disables the parallel tool calls in one response,
+	// nil is the provider default.
+	ParallelToolCalls *bool
 
 	// Metadata is a map of metadata to include in the request.
 	// The meaning of this field is specific to the backend in use.
@@ -169,8 +194,46 @@ type CallOptions struct {
 
 	ReasoningEffort ReasoningEffort
 
+	// ThinkingBudget is the maximum number of the thinking tokens,
+	// it takes precedence over ReasoningEffort for the providers with CapabilityThinkingBudget.
+	ThinkingBudget int
+
 	// PromptCachePolicy configures provider-native prompt caching.
 	PromptCachePolicy *PromptCachePolicy
+
+	// Constraint is the constrained decoding of the output.
+	Constraint *Constraint
+
+	// RateLimitFunc is called when the call is delayed by the rate limit,
+	// the client side limiter or the provider backoff, with the attempt number and the wait duration.
+	RateLimitFunc func(ctx context.Context, attempt int, wait time.Duration)
+
+	// MessageOrderRepairFunc is called when the provider repairs the order of the messages,
+	// to satisfy the provider rules instead of failing the request, see MessageOrderRepair.
+	MessageOrderRepairFunc func(ctx context.Context, repair MessageOrderRepair)
+
+	// IdempotencyKey is the unique key of the request, sent in the Idempotency-Key header
+	// by the providers that support it, so the repeated request with the same key is not processed twice.
+	// Other providers ignore the key.
+	IdempotencyKey string
+}
+
+// MessageOrderPlaceholder is the content of the placeholder user message,
+// inserted by the providers that require the conversation to start with the user message.
+const MessageOrderPlaceholder = "Continue."
+
+// MessageOrderRepair describes the changes made by the provider to the messages,
+// for the providers that require the user and the assistant messages to alternate, such as Anthropic.
+type MessageOrderRepair struct {
+	// MergedMessages is the number of the messages merged into the previous message with the same role.
+	MergedMessages int
+	// InsertedMessages is the number of the inserted placeholder user messages.
+	InsertedMessages int
+}
+
+// Changed returns true if the messages were repaired.
+func (r MessageOrderRepair) Changed() bool {
+	return r.MergedMessages > 0 || r.InsertedMessages > 0
 }
 
 // Tool is a tool that can be used by the model.
@@ -182,6 +245,9 @@ type Tool struct {
 	// WebSearchOptions are the options for the web search tool,
 	// For providers and models that support Web Search grounding.
 	WebSearchOptions *WebSearchOptions `json:"-"`
+	// FileSearchOptions are the options for the file_search tool,
+	// for the OpenAI Responses API with the uploaded files in the vector stores.
+	FileSearchOptions *FileSearchOptions `json:"-"`
 }
 
 // FunctionDefinition is a definition of a function that can be called by the model.
@@ -208,6 +274,14 @@ type WebSearchOptions struct {
 	MaxUses int
 }
 
+// FileSearchOptions are the options of the file_search tool.
+type FileSearchOptions struct {
+	// VectorStoreIDs are the IDs of the vector stores to search.
+	VectorStoreIDs []string
+	// MaxNumResults is the maximum number of the results, the provider default if zero.
+	MaxNumResults int
+}
+
 // ToolChoice is a specific tool to use.
 type ToolChoice struct {
 	// Type is the type of the tool.
@@ -216,6 +290,50 @@ type ToolChoice struct {
 	Function *FunctionReference `json:"function,omitempty"`
 }
 
+const (
+	// ToolChoiceAuto lets the model decide whether to call the tools.
+	ToolChoiceAuto = "auto"
+	// ToolChoiceNone prevents the model from calling the tools.
+	ToolChoiceNone = "none"
+	// ToolChoiceRequired forces the model to call at least one of the tools.
+	ToolChoiceRequired = "required"
+	// ToolChoiceFunction forces the model to call the specific function, see FunctionToolChoice.
+	ToolChoiceFunction = "function"
+)
+
+// FunctionToolChoice returns the ToolChoice that forces the model to call the function.
+func FunctionToolChoice(name string) ToolChoice {
+	return ToolChoice{
+		Type:     "function",
+		Function: &FunctionReference{Name: name},
+	}
+}
+
+// ParseToolChoice returns the mode of the tool choice: ToolChoiceAuto, ToolChoiceNone,
+// ToolChoiceRequired or ToolChoiceFunction with the name of the function to call.
+// The choice can be a string, ToolChoice or *ToolChoice, nil or unknown choice is ToolChoiceAuto.
+// The "any" string is accepted as ToolChoiceRequired.
+func ParseToolChoice(choice any) (mode string, function string) {
+	switch c := choice.(type) {
+	case string:
+		switch c {
+		case ToolChoiceNone, ToolChoiceRequired:
+			return c, ""
+		case "any":
+			return ToolChoiceRequired, ""
+		}
+	case ToolChoice:
+		if c.Function != nil && c.Function.Name != "" {
+			return ToolChoiceFunction, c.Function.Name
+		}
+	case *ToolChoice:
+		if c != nil && c.Function != nil && c.Function.Name != "" {
+			return ToolChoiceFunction, c.Function.Name
+		}
+	}
+	return ToolChoiceAuto, ""
+}
+
 // FunctionReference is a reference to a function.
 type FunctionReference struct {
 	// Name is the name of the function.
@@ -282,6 +400,27 @@ func WithStreamingFunc(streamingFunc func(ctx context.Context, chunk []byte) err
 	}
 }
 
+// WithRateLimitFunc specifies the function to be called when the call is delayed by the rate limit.
+func WithRateLimitFunc(rateLimitFunc func(ctx context.Context, attempt int, wait time.Duration)) CallOption {
+	return func(o *CallOptions) {
+		o.RateLimitFunc = rateLimitFunc
+	}
+}
+
+// WithMessageOrderRepairFunc specifies the function to be called when the provider repairs the order of the messages.
+func WithMessageOrderRepairFunc(fn func(ctx context.Context, repair MessageOrderRepair)) CallOption {
+	return func(o *CallOptions) {
+		o.MessageOrderRepairFunc = fn
+	}
+}
+
+// WithIdempotencyKey specifies the idempotency key of the request.
+func WithIdempotencyKey(key string) CallOption {
+	return func(o *CallOptions) {
+		o.IdempotencyKey = key
+	}
+}
+
 // WithStreamingReasoningFunc specifies the streaming reasoning function to use.
 func WithStreamingReasoningFunc(streamingReasoningFunc func(ctx context.Context, reasoningChunk, chunk []byte) error) CallOption {
 	return func(o *CallOptions) {
@@ -289,6 +428,14 @@ func WithStreamingReasoningFunc(streamingReasoningFunc func(ctx context.Context,
 	}
 }
 
+// WithStreamingToolCallFunc specifies the function to be called for each tool call delta,
+// to show which tool the model is about to invoke before the stream finishes.
+func WithStreamingToolCallFunc(streamingToolCallFunc func(ctx context.Context, delta ToolCallDelta) error) CallOption {
+	return func(o *CallOptions) {
+		o.StreamingToolCallFunc = streamingToolCallFunc
+	}
+}
+
 // WithTopK will add an option to use top-k sampling.
 func WithTopK(topK int) CallOption {
 	return func(o *CallOptions) {
@@ -353,14 +500,23 @@ func WithPresencePenalty(presencePenalty float64) CallOption {
 }
 
 // WithToolChoice will add an option to set the choice of tool to use.
-// It can either be "none", "auto" (the default behavior), or a specific tool as described in the ToolChoice type.
+// It can either be "none", "auto" (the default behavior), "required",
+// or a specific tool as described in the ToolChoice type, see FunctionToolChoice.
 func WithToolChoice(choice any) CallOption {
-	// TODO: Add type validation for choice.
 	return func(o *CallOptions) {
 		o.ToolChoice = choice
 	}
 }
 
+// WithParallelToolCalls will add an option to enable or disable the parallel tool calls,
+// when disabled, the model returns at most one tool call in the response.
+// Supported by OpenAI and Anthropic.
+func WithParallelToolCalls(enabled bool) CallOption {
+	return func(o *CallOptions) {
+		o.ParallelToolCalls = &enabled
+	}
+}
+
 // WithTools will add an option to set the tools to use.
 func WithTools(tools []Tool) CallOption {
 	return func(o *CallOptions) {
@@ -392,9 +548,34 @@ func WithReasoningEffort(reasoningEffort ReasoningEffort) CallOption {
 	}
 }
 
+// WithThinkingBudget allows setting the maximum number of the thinking tokens,
+// for example Anthropic extended thinking or Gemini thinking budget.
+func WithThinkingBudget(tokens int) CallOption {
+	return func(o *CallOptions) {
+		o.ThinkingBudget = tokens
+	}
+}
+
 // WithPromptCachePolicy allows setting provider-native prompt cache policy.
 func WithPromptCachePolicy(promptCachePolicy *PromptCachePolicy) CallOption {
 	return func(o *CallOptions) {
 		o.PromptCachePolicy = promptCachePolicy
 	}
 }
+
+// WithConstraint specifies the constrained decoding of the output.
+func WithConstraint(constraint *Constraint) CallOption {
+	return func(o *CallOptions) {
+		o.Constraint = constraint
+	}
+}
+
+// WithGrammar constrains the output to the GBNF grammar.
+func WithGrammar(grammar string) CallOption {
+	return WithConstraint(&Constraint{Type: ConstraintGrammar, Value: grammar})
+}
+
+// WithRegex constrains the output to the regular expression.
+func WithRegex(regex string) CallOption {
+	return WithConstraint(&Constraint{Type: ConstraintRegex, Value: regex})
+}
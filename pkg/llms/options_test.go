@@ -37,6 +37,7 @@ func TestOptions(t *testing.T) {
 			Retention: llms.PromptCacheRetentionInMemory,
 		},
 	}
+	parallel := false
 	opts := []llms.CallOption{
 		llms.WithModel("test"),
 		llms.WithPromptCachePolicy(promptCachePolicy),
@@ -56,6 +57,7 @@ func TestOptions(t *testing.T) {
 		llms.WithPresencePenalty(0.5),
 		llms.WithTools(tools),
 		llms.WithToolChoice("test"),
+		llms.WithParallelToolCalls(false),
 		llms.WithMetadata(meta),
 		llms.WithResponseFormat(rf),
 		llms.WithReasoningEffort(llms.ReasoningEffortLow),
@@ -85,6 +87,7 @@ func TestOptions(t *testing.T) {
 		PresencePenalty:        0.5,
 		Tools:                  tools,
 		ToolChoice:             "test",
+		ParallelToolCalls:      &parallel,
 		Metadata:               meta,
 		ResponseFormat:         rf,
 		ReasoningEffort:        llms.ReasoningEffortLow,
@@ -122,3 +125,27 @@ func TestWithPromptCachePolicy(t *testing.T) {
 	require.Len(t, cfg.PromptCachePolicy.Breakpoints, 1)
 	assert.Equal(t, llms.PromptCacheTargetMessagePart, cfg.PromptCachePolicy.Breakpoints[0].Target.Kind)
 }
+
+func TestParseToolChoice(t *testing.T) {
+	tcases := []struct {
+		choice   any
+		mode     string
+		function string
+	}{
+		{choice: nil, mode: llms.ToolChoiceAuto},
+		{choice: "", mode: llms.ToolChoiceAuto},
+		{choice: "auto", mode: llms.ToolChoiceAuto},
+		{choice: "none", mode: llms.ToolChoiceNone},
+		{choice: "required", mode: llms.ToolChoiceRequired},
+		{choice: "any", mode: llms.ToolChoiceRequired},
+		{choice: llms.FunctionToolChoice("search"), mode: llms.ToolChoiceFunction, function: "search"},
+		{choice: &llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "fetch"}}, mode: llms.ToolChoiceFunction, function: "fetch"},
+		{choice: (*llms.ToolChoice)(nil), mode: llms.ToolChoiceAuto},
+		{choice: llms.ToolChoice{Type: "function"}, mode: llms.ToolChoiceAuto},
+	}
+	for _, tc := range tcases {
+		mode, function := llms.ParseToolChoice(tc.choice)
+		assert.Equal(t, tc.mode, mode, "%v", tc.choice)
+		assert.Equal(t, tc.function, function, "%v", tc.choice)
+	}
+}
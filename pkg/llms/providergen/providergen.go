@@ -0,0 +1,229 @@
+// Package providergen generates the skeleton of a new LLM provider package
+// from a small manifest, with the same structure as anthropic and openai packages:
+// options, the API client, ProcessMessages, streaming, capability flags,
+// and the tests wired to the llmtest conformance suite.
+//
+// The skeleton talks to the OpenAI-compatible chat completions API,
+// update the internal client for the provider specific wire format.
+package providergen
+
+import (
+	"bytes"
+	"embed"
+	"go/format"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/xlog"
+	"gopkg.in/yaml.v3"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "providergen")
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// DefaultModulePath is the import path of the providers in this module.
+const DefaultModulePath = "github.com/effective-security/gogentic/pkg/llms"
+
+// Manifest describes the provider to generate.
+type Manifest struct {
+	// Package is the name of the Go package, for example "mistral".
+	Package string `json:"package" yaml:"package"`
+	// Name is the display name of the provider, for example "Mistral".
+	Name string `json:"name" yaml:"name"`
+	// Provider is the value of llms.ProviderType, for example "MISTRAL".
+	Provider string `json:"provider" yaml:"provider"`
+	// TokenEnv is the environment variable with the API token.
+	TokenEnv string `json:"token_env" yaml:"token_env"`
+	// BaseURL is the default URL of the API.
+	BaseURL string `json:"base_url" yaml:"base_url"`
+	// DefaultModel is the default model.
+	DefaultModel string `json:"default_model" yaml:"default_model"`
+	// Capabilities is the list of llms.Capability names without the prefix,
+	// for example "Text" or "FunctionCalling".
+	Capabilities []string `json:"capabilities" yaml:"capabilities"`
+	// ImportPath is the import path of the generated package,
+	// by default DefaultModulePath/<package>.
+	ImportPath string `json:"import_path,omitempty" yaml:"import_path,omitempty"`
+}
+
+// Capabilities is the list of the supported capability names.
+var Capabilities = []string{
+	"Text",
+	"JSONResponse",
+	"JSONSchema",
+	"JSONSchemaStrict",
+	"FunctionCalling",
+	"MultiToolCalling",
+	"ToolCallStreaming",
+	"Vision",
+	"ImageGeneration",
+	"AudioTranscription",
+	"SelfHosted",
+	"SystemPrompt",
+	"WebSearchTool",
+	"PromptCaching",
+	"Batch",
+	"DeveloperRole",
+	"ConstrainedDecoding",
+	"ReasoningEffort",
+	"ThinkingBudget",
+}
+
+var (
+	rePackage  = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+	reProvider = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	reEnv      = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+)
+
+// LoadManifest loads the manifest from the YAML or JSON file.
+func LoadManifest(file string) (*Manifest, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read manifest")
+	}
+	m := new(Manifest)
+	if err = yaml.Unmarshal(b, m); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse manifest")
+	}
+	return m, nil
+}
+
+// Validate validates the manifest, and sets the default values.
+func (m *Manifest) Validate() error {
+	if !rePackage.MatchString(m.Package) {
+		return errors.Errorf("invalid package: %q", m.Package)
+	}
+	if m.Name == "" {
+		m.Name = m.Package
+	}
+	if m.Provider == "" {
+		m.Provider = strings.ToUpper(m.Package)
+	}
+	if !reProvider.MatchString(m.Provider) {
+		return errors.Errorf("invalid provider: %q", m.Provider)
+	}
+	if m.TokenEnv == "" {
+		m.TokenEnv = m.Provider + "_API_KEY"
+	}
+	if !reEnv.MatchString(m.TokenEnv) {
+		return errors.Errorf("invalid token_env: %q", m.TokenEnv)
+	}
+	if m.BaseURL == "" {
+		return errors.New("base_url is required")
+	}
+	if m.DefaultModel == "" {
+		return errors.New("default_model is required")
+	}
+	if m.ImportPath == "" {
+		m.ImportPath = path.Join(DefaultModulePath, m.Package)
+	}
+	if len(m.Capabilities) == 0 {
+		m.Capabilities = []string{"Text"}
+	}
+	for _, c := range m.Capabilities {
+		if !isCapability(c) {
+			return errors.Errorf("unsupported capability: %q", c)
+		}
+	}
+	return nil
+}
+
+func isCapability(name string) bool {
+	for _, c := range Capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CapabilitiesExpr returns the Go expression of the capabilities.
+func (m *Manifest) CapabilitiesExpr() string {
+	caps := make([]string, len(m.Capabilities))
+	for i, c := range m.Capabilities {
+		caps[i] = "llms.Capability" + c
+	}
+	return strings.Join(caps, " |\n\t")
+}
+
+// File is the generated file.
+type File struct {
+	// Path is relative to the package folder.
+	Path    string
+	Content []byte
+}
+
+// Render returns the generated files of the provider package.
+func Render(m *Manifest) ([]File, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		template string
+		path     string
+	}{
+		{"option.go.tmpl", m.Package + "llm_option.go"},
+		{"llm.go.tmpl", m.Package + "llm.go"},
+		{"llm_test.go.tmpl", m.Package + "llm_test.go"},
+		{"client.go.tmpl", filepath.Join("internal", m.Package+"client", m.Package+"client.go")},
+	}
+
+	var res []File
+	for _, f := range files {
+		tmpl, err := template.ParseFS(templates, "templates/"+f.template)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse template %s", f.template)
+		}
+
+		var buf bytes.Buffer
+		if err = tmpl.Execute(&buf, m); err != nil {
+			return nil, errors.Wrapf(err, "failed to execute template %s", f.template)
+		}
+
+		content, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to format %s", f.path)
+		}
+		res = append(res, File{Path: f.path, Content: content})
+	}
+	return res, nil
+}
+
+// Generate writes the provider package to the folder.
+// The existing files are not overwritten, unless force is true.
+func Generate(m *Manifest, dir string, force bool) ([]string, error) {
+	files, err := Render(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if !force {
+		for _, f := range files {
+			if _, err := os.Stat(filepath.Join(dir, f.Path)); err == nil {
+				return nil, errors.Errorf("file already exists: %s", filepath.Join(dir, f.Path))
+			}
+		}
+	}
+
+	var written []string
+	for _, f := range files {
+		fn := filepath.Join(dir, f.Path)
+		if err = os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+			return nil, errors.Wrapf(err, "failed to create folder")
+		}
+		if err = os.WriteFile(fn, f.Content, 0o644); err != nil {
+			return nil, errors.Wrapf(err, "failed to write file")
+		}
+		logger.KV(xlog.DEBUG, "status", "generated", "file", fn)
+		written = append(written, fn)
+	}
+	return written, nil
+}
@@ -0,0 +1,124 @@
+package providergen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms/providergen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadManifest(t *testing.T) {
+	t.Parallel()
+
+	m, err := providergen.LoadManifest("testdata/manifest.yaml")
+	require.NoError(t, err)
+	require.NoError(t, m.Validate())
+	assert.Equal(t, "acme", m.Package)
+	assert.Equal(t, "ACME", m.Provider)
+	assert.Equal(t, "github.com/effective-security/gogentic/pkg/llms/acme", m.ImportPath)
+	assert.Equal(t, "llms.CapabilityText |\n\tllms.CapabilitySystemPrompt |\n\tllms.CapabilityJSONResponse", m.CapabilitiesExpr())
+
+	_, err = providergen.LoadManifest("testdata/notfound.yaml")
+	require.ErrorContains(t, err, "failed to read manifest")
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		name   string
+		m      providergen.Manifest
+		expErr string
+	}{
+		{
+			name: "defaults",
+			m:    providergen.Manifest{Package: "mistral", BaseURL: "https://api.mistral.ai/v1", DefaultModel: "mistral-large"},
+		},
+		{
+			name:   "invalid package",
+			m:      providergen.Manifest{Package: "Mistral-AI"},
+			expErr: `invalid package: "Mistral-AI"`,
+		},
+		{
+			name:   "invalid provider",
+			m:      providergen.Manifest{Package: "mistral", Provider: "mistral ai"},
+			expErr: `invalid provider: "mistral ai"`,
+		},
+		{
+			name:   "invalid token env",
+			m:      providergen.Manifest{Package: "mistral", TokenEnv: "$KEY"},
+			expErr: `invalid token_env: "$KEY"`,
+		},
+		{
+			name:   "no base url",
+			m:      providergen.Manifest{Package: "mistral"},
+			expErr: "base_url is required",
+		},
+		{
+			name:   "no model",
+			m:      providergen.Manifest{Package: "mistral", BaseURL: "https://api.mistral.ai/v1"},
+			expErr: "default_model is required",
+		},
+		{
+			name:   "unsupported capability",
+			m:      providergen.Manifest{Package: "mistral", BaseURL: "https://api.mistral.ai/v1", DefaultModel: "mistral-large", Capabilities: []string{"Telepathy"}},
+			expErr: `unsupported capability: "Telepathy"`,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.m.Validate()
+			if tc.expErr != "" {
+				require.EqualError(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "mistral", tc.m.Name)
+			assert.Equal(t, "MISTRAL", tc.m.Provider)
+			assert.Equal(t, "MISTRAL_API_KEY", tc.m.TokenEnv)
+			assert.Equal(t, []string{"Text"}, tc.m.Capabilities)
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	m, err := providergen.LoadManifest("testdata/manifest.yaml")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	files, err := providergen.Generate(m, dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "acmellm_option.go"),
+		filepath.Join(dir, "acmellm.go"),
+		filepath.Join(dir, "acmellm_test.go"),
+		filepath.Join(dir, "internal", "acmeclient", "acmeclient.go"),
+	}, files)
+
+	b, err := os.ReadFile(filepath.Join(dir, "acmellm.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `const Provider llms.ProviderType = "ACME"`)
+	assert.Contains(t, string(b), `"github.com/effective-security/gogentic/pkg/llms/acme/internal/acmeclient"`)
+	assert.Contains(t, string(b), "llms.RegisterProviderCapabilities(Provider, Capabilities)")
+
+	b, err = os.ReadFile(filepath.Join(dir, "acmellm_test.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "llmtest.Run(t, llm)")
+
+	_, err = providergen.Generate(m, dir, false)
+	require.ErrorContains(t, err, "file already exists")
+
+	_, err = providergen.Generate(m, dir, true)
+	require.NoError(t, err)
+
+	_, err = providergen.Render(&providergen.Manifest{})
+	require.EqualError(t, err, `invalid package: ""`)
+}
@@ -0,0 +1,319 @@
+// Package replay provides llms.Model decorator that records GenerateContent
+// requests and responses to golden files, and replays them in tests,
+// so the tests of assistants do not require live API keys or hand-written mocks.
+//
+// The fixtures are matched by the hash of the messages and the call options,
+// the message sources are excluded, as they contain random run IDs.
+package replay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/xlog"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "replay")
+
+// ErrFixtureNotFound is returned in ModeReplay when the request was not recorded.
+var ErrFixtureNotFound = errors.New("replay fixture not found")
+
+// EnvMode is the environment variable to override the mode, see ModeFromEnv.
+const EnvMode = "LLM_REPLAY_MODE"
+
+// Mode defines the behavior of the Model.
+type Mode string
+
+const (
+	// ModeReplay returns the recorded responses, and fails if the fixture is not found.
+	ModeReplay Mode = "replay"
+	// ModeRecord calls the model and records the responses, overwriting existing fixtures.
+	ModeRecord Mode = "record"
+	// ModeAuto returns the recorded responses, and records the missing fixtures.
+	ModeAuto Mode = "auto"
+)
+
+// ModeFromEnv returns the mode from LLM_REPLAY_MODE environment variable,
+// or the default mode if the variable is not set or invalid.
+func ModeFromEnv(def Mode) Mode {
+	switch m := Mode(strings.ToLower(os.Getenv(EnvMode))); m {
+	case ModeReplay, ModeRecord, ModeAuto:
+		return m
+	}
+	return def
+}
+
+// Fixture is the golden file content.
+type Fixture struct {
+	Model    string                `json:"model"`
+	Provider llms.ProviderType     `json:"provider"`
+	Request  *Request              `json:"request"`
+	Response *llms.ContentResponse `json:"response"`
+}
+
+// Request is the recorded request.
+type Request struct {
+	Messages []llms.Message `json:"messages"`
+	Options  *Options       `json:"options,omitempty"`
+}
+
+// Options is the recorded llms.CallOptions, except the callbacks and the IdempotencyKey,
+// which is unique for each run and would not match the recorded fixture.
+// Every option is included in the request hash, so the requests that differ
+// only in the options are matched to different fixtures.
+type Options struct {
+	Model             string                  `json:"model,omitempty"`
+	CandidateCount    int                     `json:"candidate_count,omitempty"`
+	MaxTokens         int                     `json:"max_tokens,omitempty"`
+	Temperature       float64                 `json:"temperature,omitempty"`
+	StopWords         []string                `json:"stop_words,omitempty"`
+	TopK              int                     `json:"top_k,omitempty"`
+	TopP              float64                 `json:"top_p,omitempty"`
+	Seed              int                     `json:"seed,omitempty"`
+	MinLength         int                     `json:"min_length,omitempty"`
+	MaxLength         int                     `json:"max_length,omitempty"`
+	N                 int                     `json:"n,omitempty"`
+	RepetitionPenalty float64                 `json:"repetition_penalty,omitempty"`
+	FrequencyPenalty  float64                 `json:"frequency_penalty,omitempty"`
+	PresencePenalty   float64                 `json:"presence_penalty,omitempty"`
+	Tools             []llms.Tool             `json:"tools,omitempty"`
+	ToolChoice        any                     `json:"tool_choice,omitempty"`
+	ParallelToolCalls *bool                   `json:"parallel_tool_calls,omitempty"`
+	Metadata          map[string]any          `json:"metadata,omitempty"`
+	ResponseFormat    *schema.ResponseFormat  `json:"response_format,omitempty"`
+	ReasoningEffort   llms.ReasoningEffort    `json:"reasoning_effort,omitempty"`
+	ThinkingBudget    int                     `json:"thinking_budget,omitempty"`
+	PromptCachePolicy *llms.PromptCachePolicy `json:"prompt_cache_policy,omitempty"`
+	Constraint        *llms.Constraint        `json:"constraint,omitempty"`
+}
+
+// Option configures the Model.
+type Option func(*Model)
+
+// WithMode sets the mode, by default ModeReplay.
+func WithMode(mode Mode) Option {
+	return func(m *Model) {
+		m.mode = mode
+	}
+}
+
+// WithRedactor sets the Redactor to mask the secrets in the recorded request messages,
+// so the fixtures can be committed. The fixtures are still matched by the original request.
+func WithRedactor(r *llmutils.Redactor) Option {
+	return func(m *Model) {
+		m.redactor = r
+	}
+}
+
+// Model is llms.Model decorator that records and replays GenerateContent calls.
+type Model struct {
+	llm      llms.Model
+	name     string
+	provider llms.ProviderType
+	dir      string
+	mode     Mode
+	redactor *llmutils.Redactor
+}
+
+var _ llms.Model = (*Model)(nil)
+
+// New returns a new Model that records the calls to the llm in the dir.
+func New(llm llms.Model, dir string, opts ...Option) *Model {
+	m := &Model{
+		llm:      llm,
+		name:     llm.GetName(),
+		provider: llm.GetProviderType(),
+		dir:      dir,
+		mode:     ModeReplay,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// NewReplay returns a new Model that only replays the fixtures from the dir,
+// without the underlying model.
+func NewReplay(name string, provider llms.ProviderType, dir string) *Model {
+	return &Model{
+		name:     name,
+		provider: provider,
+		dir:      dir,
+		mode:     ModeReplay,
+	}
+}
+
+// GetName returns the name of the model.
+func (m *Model) GetName() string {
+	return m.name
+}
+
+// GetProviderType returns the provider type of the model.
+func (m *Model) GetProviderType() llms.ProviderType {
+	return m.provider
+}
+
+// GenerateContent returns the recorded response, or calls the underlying model
+// and records the response, depending on the mode.
+func (m *Model) GenerateContent(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	req := NewRequest(messages, &opts)
+	key, err := req.Hash()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(m.dir, key+".json")
+
+	if m.mode != ModeRecord {
+		fixture, err := Load(path)
+		if err == nil {
+			logger.ContextKV(ctx, xlog.DEBUG, "status", "replay", "fixture", path)
+			if err := stream(ctx, &opts, fixture.Response); err != nil {
+				return nil, err
+			}
+			return fixture.Response, nil
+		}
+		if !errors.Is(err, ErrFixtureNotFound) || m.mode == ModeReplay || m.llm == nil {
+			return nil, err
+		}
+	}
+
+	if m.llm == nil {
+		return nil, errors.Newf("replay: model is not provided to record %s", path)
+	}
+
+	resp, err := m.llm.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.redactor != nil {
+		recorded := *req
+		recorded.Messages = m.redactor.Messages(req.Messages)
+		req = &recorded
+	}
+	err = Save(path, &Fixture{
+		Model:    m.name,
+		Provider: m.provider,
+		Request:  req,
+		Response: resp,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.ContextKV(ctx, xlog.DEBUG, "status", "recorded", "fixture", path)
+	return resp, nil
+}
+
+// NewRequest returns the request for the messages and the options.
+func NewRequest(messages []llms.Message, opts *llms.CallOptions) *Request {
+	req := &Request{
+		Messages: make([]llms.Message, len(messages)),
+		Options: &Options{
+			Model:             opts.Model,
+			CandidateCount:    opts.CandidateCount,
+			MaxTokens:         opts.MaxTokens,
+			Temperature:       opts.Temperature,
+			StopWords:         opts.StopWords,
+			TopK:              opts.TopK,
+			TopP:              opts.TopP,
+			Seed:              opts.Seed,
+			MinLength:         opts.MinLength,
+			MaxLength:         opts.MaxLength,
+			N:                 opts.N,
+			RepetitionPenalty: opts.RepetitionPenalty,
+			FrequencyPenalty:  opts.FrequencyPenalty,
+			PresencePenalty:   opts.PresencePenalty,
+			Tools:             opts.Tools,
+			ToolChoice:        opts.ToolChoice,
+			ParallelToolCalls: opts.ParallelToolCalls,
+			Metadata:          opts.Metadata,
+			ResponseFormat:    opts.ResponseFormat,
+			ReasoningEffort:   opts.ReasoningEffort,
+			ThinkingBudget:    opts.ThinkingBudget,
+			PromptCachePolicy: opts.PromptCachePolicy,
+			Constraint:        opts.Constraint,
+		},
+	}
+	for i, msg := range messages {
+		msg.Source = nil
+		req.Messages[i] = msg
+	}
+	return req
+}
+
+// Hash returns the hash of the request, used as the fixture name.
+func (r *Request) Hash() (string, error) {
+	js, err := json.Marshal(r)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal request")
+	}
+	h := sha256.Sum256(js)
+	return hex.EncodeToString(h[:16]), nil
+}
+
+// Load loads the fixture from the file.
+func Load(path string) (*Fixture, error) {
+	js, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrapf(ErrFixtureNotFound, "%s", path)
+		}
+		return nil, errors.WithStack(err)
+	}
+	fixture := new(Fixture)
+	if err = json.Unmarshal(js, fixture); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal fixture %s", path)
+	}
+	if fixture.Response == nil {
+		return nil, errors.Newf("invalid fixture %s: missing response", path)
+	}
+	return fixture, nil
+}
+
+// Save saves the fixture to the file.
+func Save(path string, fixture *Fixture) error {
+	js, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal fixture")
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(path, js, 0o644))
+}
+
+// stream sends the recorded content to the streaming functions, if provided.
+func stream(ctx context.Context, opts *llms.CallOptions, resp *llms.ContentResponse) error {
+	if opts.StreamingFunc == nil && opts.StreamingReasoningFunc == nil {
+		return nil
+	}
+	for _, choice := range resp.Choices {
+		if choice == nil {
+			continue
+		}
+		if opts.StreamingReasoningFunc != nil && (choice.ReasoningContent != "" || choice.Content != "") {
+			if err := opts.StreamingReasoningFunc(ctx, []byte(choice.ReasoningContent), []byte(choice.Content)); err != nil {
+				return err
+			}
+		} else if opts.StreamingFunc != nil && choice.Content != "" {
+			if err := opts.StreamingFunc(ctx, []byte(choice.Content)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package replay_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/replay"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeModel struct {
+	calls int
+}
+
+func (m *fakeModel) GetName() string                    { return "fake-model" }
+func (m *fakeModel) GetProviderType() llms.ProviderType { return llms.ProviderOpenAI }
+
+func (m *fakeModel) GenerateContent(_ context.Context, messages []llms.Message, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls++
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				Content:    "answer to: " + messages[len(messages)-1].Parts[0].(llms.TextContent).Text,
+				StopReason: "stop",
+			},
+		},
+	}, nil
+}
+
+func messages(text, runID string) []llms.Message {
+	return []llms.Message{
+		llms.MessageFromTextParts(llms.RoleSystem, "You are helpful."),
+		llms.MessageFromTextParts(llms.RoleHuman, text).WithSource(&llms.MessageSource{RunID: runID}),
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	fake := &fakeModel{}
+	rec := replay.New(fake, dir, replay.WithMode(replay.ModeRecord))
+	assert.Equal(t, "fake-model", rec.GetName())
+	assert.Equal(t, llms.ProviderOpenAI, rec.GetProviderType())
+
+	resp, err := rec.GenerateContent(ctx, messages("hello", "run1"), llms.WithTemperature(0.1))
+	require.NoError(t, err)
+	assert.Equal(t, "answer to: hello", resp.Choices[0].Content)
+	assert.Equal(t, 1, fake.calls)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	fixture, err := replay.Load(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, "fake-model", fixture.Model)
+	require.Len(t, fixture.Request.Messages, 2)
+	assert.Nil(t, fixture.Request.Messages[1].Source)
+
+	// replay without the model, the run ID is ignored
+	rp := replay.NewReplay("fake-model", llms.ProviderOpenAI, dir)
+	var streamed string
+	resp, err = rp.GenerateContent(ctx, messages("hello", "run2"),
+		llms.WithTemperature(0.1),
+		llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			streamed += string(chunk)
+			return nil
+		}))
+	require.NoError(t, err)
+	assert.Equal(t, "answer to: hello", resp.Choices[0].Content)
+	assert.Equal(t, "answer to: hello", streamed)
+
+	// different options do not match
+	_, err = rp.GenerateContent(ctx, messages("hello", "run2"), llms.WithTemperature(0.5))
+	require.ErrorIs(t, err, replay.ErrFixtureNotFound)
+
+	// different messages do not match
+	_, err = rp.GenerateContent(ctx, messages("bye", "run2"), llms.WithTemperature(0.1))
+	require.ErrorIs(t, err, replay.ErrFixtureNotFound)
+}
+
+func TestAutoMode(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "testdata")
+
+	fake := &fakeModel{}
+	m := replay.New(fake, dir, replay.WithMode(replay.ModeAuto))
+
+	for range 3 {
+		resp, err := m.GenerateContent(ctx, messages("hello", "run"))
+		require.NoError(t, err)
+		assert.Equal(t, "answer to: hello", resp.Choices[0].Content)
+	}
+	assert.Equal(t, 1, fake.calls)
+
+	// replay mode with the model does not call it
+	m = replay.New(fake, dir)
+	_, err := m.GenerateContent(ctx, messages("new", "run"))
+	require.ErrorIs(t, err, replay.ErrFixtureNotFound)
+	assert.Equal(t, 1, fake.calls)
+}
+
+func TestRedactor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	fake := &fakeModel{}
+	m := replay.New(fake, dir, replay.WithMode(replay.ModeAuto), replay.WithRedactor(llmutils.NewRedactor()))
+
+	msgs := messages("my key is sk-abcdefghijklmnopqrstuvwxyz", "run")
+	_, err := m.GenerateContent(ctx, msgs)
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	fixture, err := replay.Load(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, llms.TextPart("my key is "+llmutils.DefaultMask), fixture.Request.Messages[1].Parts[0])
+
+	// the fixture is matched by the original request
+	_, err = m.GenerateContent(ctx, msgs)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.calls)
+}
+
+func TestRequestOptions(t *testing.T) {
+	t.Parallel()
+
+	// every CallOptions field, except the callbacks and the idempotency key, must be recorded and hashed
+	recorded := reflect.TypeOf(replay.Options{})
+	callOpts := reflect.TypeOf(llms.CallOptions{})
+	for i := range callOpts.NumField() {
+		f := callOpts.Field(i)
+		if f.Type.Kind() == reflect.Func || f.Name == "IdempotencyKey" {
+			continue
+		}
+		_, ok := recorded.FieldByName(f.Name)
+		assert.True(t, ok, "CallOptions.%s is not recorded in replay.Options", f.Name)
+	}
+
+	hash := func(opts ...llms.CallOption) string {
+		var o llms.CallOptions
+		for _, opt := range opts {
+			opt(&o)
+		}
+		h, err := replay.NewRequest(messages("hi", "run"), &o).Hash()
+		require.NoError(t, err)
+		return h
+	}
+	base := hash()
+	assert.NotEqual(t, base, hash(llms.WithThinkingBudget(1024)))
+	assert.NotEqual(t, base, hash(llms.WithConstraint(&llms.Constraint{Type: llms.ConstraintRegex, Value: "[a-z]+"})))
+	assert.NotEqual(t, base, hash(llms.WithParallelToolCalls(false)))
+	assert.NotEqual(t, hash(llms.WithParallelToolCalls(true)), hash(llms.WithParallelToolCalls(false)))
+	// the replayed run has the new idempotency key
+	assert.Equal(t, base, hash(llms.WithIdempotencyKey("run_1")))
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	_, err := replay.Load(filepath.Join(dir, "missing.json"))
+	require.ErrorIs(t, err, replay.ErrFixtureNotFound)
+
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte(`{"model":"x"}`), 0o644))
+	_, err = replay.Load(invalid)
+	assert.EqualError(t, err, "invalid fixture "+invalid+": missing response")
+}
+
+func TestModeFromEnv(t *testing.T) {
+	t.Setenv(replay.EnvMode, "RECORD")
+	assert.Equal(t, replay.ModeRecord, replay.ModeFromEnv(replay.ModeReplay))
+
+	t.Setenv(replay.EnvMode, "invalid")
+	assert.Equal(t, replay.ModeReplay, replay.ModeFromEnv(replay.ModeReplay))
+}
@@ -0,0 +1,319 @@
+// Package toolemu provides llms.Model decorator that emulates the function calling
+// for the models without the native support, for example Perplexity or some Bedrock models.
+//
+// The tool definitions are converted to the instructions in the system prompt,
+// the model responds with the JSON object with the tool calls,
+// which is parsed back to llms.ToolCall, so llms.WithTools works uniformly for all models.
+// The tool calls and the tool responses in the message history are converted to the text.
+package toolemu
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/x/format"
+	"github.com/effective-security/xlog"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "toolemu")
+
+// ToolResultPrefix is the prefix of the tool response in the message history.
+const ToolResultPrefix = "Tool result"
+
+// Model is llms.Model that emulates the function calling.
+type Model struct {
+	llms.Model
+}
+
+var (
+	_ llms.Model              = (*Model)(nil)
+	_ llms.CapabilityReporter = (*Model)(nil)
+)
+
+// New returns the model that emulates the function calling,
+// if the model supports the function calling natively, the calls are passed through.
+func New(model llms.Model) *Model {
+	return &Model{Model: model}
+}
+
+// Capabilities implements llms.CapabilityReporter,
+// and adds the function calling to the capabilities of the model.
+func (m *Model) Capabilities() llms.Capability {
+	return llms.ModelCapabilities(m.Model, m.GetProviderType()) |
+		llms.CapabilityFunctionCalling |
+		llms.CapabilityMultiToolCalling
+}
+
+// GenerateContent implements the Model interface.
+func (m *Model) GenerateContent(ctx context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	tools := functionTools(opts.Tools)
+	if len(tools) == 0 || llms.ModelCapabilities(m.Model, m.GetProviderType()).Supports(llms.CapabilityFunctionCalling) {
+		return m.Model.GenerateContent(ctx, messages, options...)
+	}
+
+	choice := toolChoice(opts.ToolChoice)
+	// the tools are removed from the call options, as the model does not support them
+	options = append(options, llms.WithTools(nil), llms.WithToolChoice(nil))
+	if choice == "none" {
+		return m.Model.GenerateContent(ctx, ConvertMessages(messages), options...)
+	}
+
+	if opts.StreamingFunc != nil {
+		// the tool calls JSON must not be streamed to the user
+		options = append(options, llms.WithStreamingFunc(newToolCallFilter(opts.StreamingFunc).write))
+	}
+
+	resp, err := m.Model.GenerateContent(ctx, WithToolsPrompt(ConvertMessages(messages), tools, choice), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range resp.Choices {
+		calls := ParseToolCalls(c.Content)
+		if len(calls) == 0 {
+			continue
+		}
+		logger.ContextKV(ctx, xlog.DEBUG,
+			"status", "emulated_tool_calls",
+			"model", m.GetName(),
+			"count", len(calls),
+		)
+		c.Content = ""
+		c.ToolCalls = calls
+		c.FuncCall = calls[0].FunctionCall
+		c.StopReason = "tool_calls"
+	}
+	return resp, nil
+}
+
+func functionTools(tools []llms.Tool) []llms.Tool {
+	var res []llms.Tool
+	for _, t := range tools {
+		if t.Type == "function" && t.Function != nil {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// toolChoice returns "auto", "none", "required", or the name of the tool to call.
+func toolChoice(choice any) string {
+	mode, function := llms.ParseToolChoice(choice)
+	if function != "" {
+		return function
+	}
+	return mode
+}
+
+// ToolsPrompt returns the system prompt instructions for the tools.
+func ToolsPrompt(tools []llms.Tool, choice string) string {
+	var b strings.Builder
+	b.WriteString("# TOOLS\n")
+	b.WriteString("You have access to the following tools:\n")
+	for _, t := range tools {
+		_, _ = fmt.Fprintf(&b, "- Name: %s\n", t.Function.Name)
+		_, _ = fmt.Fprintf(&b, "  Description: %s\n", format.TextOneLine(t.Function.Description))
+		if t.Function.Parameters != nil {
+			_, _ = fmt.Fprintf(&b, "  Parameters: %s\n", llmutils.ToJSON(t.Function.Parameters))
+		}
+	}
+	b.WriteString(`
+To call one or more tools, respond only with a JSON object in the following format, without any other text:
+{"tool_calls":[{"name":"the tool name","arguments":{"the tool parameters"}}]}
+
+The tool results will be provided to you as:
+` + ToolResultPrefix + ` (the tool name, the call id): the tool result
+`)
+	switch choice {
+	case "auto":
+		b.WriteString("\nIf you do not need to call a tool, respond as usual.")
+	case "required", "any":
+		b.WriteString("\nYou must call at least one tool.")
+	default:
+		_, _ = fmt.Fprintf(&b, "\nYou must call the `%s` tool.", choice)
+	}
+	return b.String()
+}
+
+// WithToolsPrompt adds the tools instructions to the system or developer prompt,
+// or inserts the system message if there is none.
+func WithToolsPrompt(messages []llms.Message, tools []llms.Tool, choice string) []llms.Message {
+	prompt := ToolsPrompt(tools, choice)
+	res := make([]llms.Message, 0, len(messages)+1)
+	if len(messages) > 0 && (messages[0].Role == llms.RoleSystem || messages[0].Role == llms.RoleDeveloper) {
+		sys := messages[0]
+		sys.Parts = append(append([]llms.ContentPart{}, sys.Parts...), llms.TextContent{Text: "\n\n" + prompt})
+		res = append(res, sys)
+		messages = messages[1:]
+	} else {
+		res = append(res, llms.MessageFromTextParts(llms.RoleSystem, prompt))
+	}
+	return append(res, messages...)
+}
+
+// ConvertMessages converts the tool calls to the AI text messages,
+// and the consecutive tool responses to the single human text message.
+func ConvertMessages(messages []llms.Message) []llms.Message {
+	res := make([]llms.Message, 0, len(messages))
+	for _, msg := range messages {
+		switch msg.Role {
+		case llms.RoleAI:
+			var calls []toolCall
+			var parts []llms.ContentPart
+			for _, part := range msg.Parts {
+				if tc, ok := part.(llms.ToolCall); ok && tc.FunctionCall != nil {
+					args := llmutils.CleanJSON([]byte(orEmptyObject(tc.FunctionCall.Arguments)))
+					if !json.Valid(args) {
+						args, _ = json.Marshal(string(args))
+					}
+					calls = append(calls, toolCall{
+						ID:        tc.ID,
+						Name:      tc.FunctionCall.Name,
+						Arguments: args,
+					})
+					continue
+				}
+				parts = append(parts, part)
+			}
+			if len(calls) > 0 {
+				js, _ := json.Marshal(toolCalls{ToolCalls: calls})
+				parts = append(parts, llms.TextContent{Text: string(js)})
+			}
+			msg.Parts = parts
+		case llms.RoleTool:
+			var b strings.Builder
+			for _, part := range msg.Parts {
+				if tr, ok := part.(llms.ToolCallResponse); ok {
+					_, _ = fmt.Fprintf(&b, "%s (%s, %s): %s\n", ToolResultPrefix, tr.Name, tr.ToolCallID, tr.Content)
+				}
+			}
+			text := strings.TrimSpace(b.String())
+			if last := len(res) - 1; last >= 0 && res[last].Role == llms.RoleHuman && isToolResult(res[last]) {
+				res[last].Parts = append(res[last].Parts, llms.TextContent{Text: "\n" + text})
+				continue
+			}
+			msg = llms.Message{
+				Role:   llms.RoleHuman,
+				Parts:  []llms.ContentPart{llms.TextContent{Text: text}},
+				Source: msg.Source,
+			}
+		}
+		res = append(res, msg)
+	}
+	return res
+}
+
+func isToolResult(msg llms.Message) bool {
+	if len(msg.Parts) == 0 {
+		return false
+	}
+	text, ok := msg.Parts[0].(llms.TextContent)
+	return ok && strings.HasPrefix(text.Text, ToolResultPrefix)
+}
+
+func orEmptyObject(args string) string {
+	if strings.TrimSpace(args) == "" {
+		return "{}"
+	}
+	return args
+}
+
+type toolCall struct {
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type toolCalls struct {
+	ToolCalls []toolCall `json:"tool_calls"`
+}
+
+// ParseToolCalls returns the tool calls from the model response,
+// or nil if the response is not the tool calls JSON.
+func ParseToolCalls(content string) []llms.ToolCall {
+	text := strings.TrimSpace(content)
+	if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "```") {
+		return nil
+	}
+
+	var parsed toolCalls
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(text)), &parsed); err != nil {
+		return nil
+	}
+
+	var res []llms.ToolCall
+	for _, c := range parsed.ToolCalls {
+		if c.Name == "" {
+			continue
+		}
+		args := "{}"
+		if len(c.Arguments) > 0 && string(c.Arguments) != "null" {
+			args = string(c.Arguments)
+			// some models return the arguments as JSON string
+			var s string
+			if json.Unmarshal(c.Arguments, &s) == nil {
+				args = orEmptyObject(s)
+			}
+		}
+		res = append(res, llms.ToolCall{
+			ID:   newCallID(),
+			Type: "function",
+			FunctionCall: &llms.FunctionCall{
+				Name:      c.Name,
+				Arguments: args,
+			},
+		})
+	}
+	return res
+}
+
+func newCallID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
+
+// toolCallFilter buffers the streamed chunks until it is known
+// that the response is not the tool calls JSON.
+type toolCallFilter struct {
+	fn       func(ctx context.Context, chunk []byte) error
+	buf      []byte
+	decided  bool
+	suppress bool
+}
+
+func newToolCallFilter(fn func(ctx context.Context, chunk []byte) error) *toolCallFilter {
+	return &toolCallFilter{fn: fn}
+}
+
+func (f *toolCallFilter) write(ctx context.Context, chunk []byte) error {
+	if f.decided {
+		if f.suppress {
+			return nil
+		}
+		return f.fn(ctx, chunk)
+	}
+
+	f.buf = append(f.buf, chunk...)
+	text := strings.TrimLeft(string(f.buf), " \t\r\n")
+	if text == "" {
+		return nil
+	}
+	f.decided = true
+	if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "`") {
+		f.suppress = true
+		return nil
+	}
+	return f.fn(ctx, f.buf)
+}
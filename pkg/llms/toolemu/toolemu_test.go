@@ -0,0 +1,254 @@
+package toolemu_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/toolemu"
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var searchTool = llms.Tool{
+	Type: "function",
+	Function: &llms.FunctionDefinition{
+		Name:        "search",
+		Description: "Search the web",
+		Parameters: &jsonschema.Schema{
+			Type: "object",
+		},
+	},
+}
+
+func applyOptions(options []llms.CallOption) llms.CallOptions {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return opts
+}
+
+func TestCapabilities(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderPerplexity).Times(3)
+
+	m := toolemu.New(mockLLM)
+	caps := m.Capabilities()
+	assert.True(t, caps.Supports(llms.CapabilityFunctionCalling|llms.CapabilityMultiToolCalling))
+	assert.True(t, caps.Supports(llms.CapabilityJSONSchema))
+	assert.True(t, llms.ModelCapabilities(m, m.GetProviderType()).Supports(llms.CapabilityFunctionCalling))
+}
+
+func TestGenerateContent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderPerplexity).Times(2)
+	mockLLM.EXPECT().GetName().Return("sonar").Times(1)
+
+	gomock.InOrder(
+		// no tools, passthrough
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+				require.Len(t, messages, 1)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "hello"}}}, nil
+			}),
+		// tool call
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+				opts := applyOptions(options)
+				assert.Empty(t, opts.Tools)
+				assert.Nil(t, opts.ToolChoice)
+
+				require.Len(t, messages, 2)
+				assert.Equal(t, llms.RoleSystem, messages[0].Role)
+				require.Len(t, messages[0].Parts, 2)
+				prompt := messages[0].Parts[1].(llms.TextContent).Text
+				assert.Contains(t, prompt, "- Name: search")
+				assert.Contains(t, prompt, "You must call the `search` tool.")
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+					Content: "```json\n{\"tool_calls\":[{\"name\":\"search\",\"arguments\":{\"q\":\"go\"}}]}\n```",
+				}}}, nil
+			}),
+		// tool choice none
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, messages []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+				assert.Empty(t, applyOptions(options).Tools)
+				require.Len(t, messages, 2)
+				require.Len(t, messages[0].Parts, 1)
+				return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "no tools"}}}, nil
+			}),
+	)
+
+	m := toolemu.New(mockLLM)
+	ctx := context.Background()
+
+	resp, err := m.GenerateContent(ctx, []llms.Message{llms.MessageFromTextParts(llms.RoleHuman, "hi")})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resp.Choices[0].Content)
+
+	messages := []llms.Message{
+		llms.MessageFromTextParts(llms.RoleSystem, "You are helpful."),
+		llms.MessageFromTextParts(llms.RoleHuman, "find go"),
+	}
+	resp, err = m.GenerateContent(ctx, messages,
+		llms.WithTools([]llms.Tool{searchTool}),
+		llms.WithToolChoice(llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "search"}}),
+	)
+	require.NoError(t, err)
+	choice := resp.Choices[0]
+	assert.Empty(t, choice.Content)
+	assert.Equal(t, "tool_calls", choice.StopReason)
+	require.Len(t, choice.ToolCalls, 1)
+	assert.True(t, strings.HasPrefix(choice.ToolCalls[0].ID, "call_"))
+	assert.Equal(t, "search", choice.ToolCalls[0].GetFunctionCallName())
+	assert.Equal(t, `{"q":"go"}`, choice.ToolCalls[0].GetFunctionCallArguments())
+	assert.Equal(t, choice.ToolCalls[0].FunctionCall, choice.FuncCall)
+	// the messages are not modified
+	assert.Len(t, messages[0].Parts, 1)
+
+	resp, err = m.GenerateContent(ctx, messages, llms.WithTools([]llms.Tool{searchTool}), llms.WithToolChoice("none"))
+	require.NoError(t, err)
+	assert.Equal(t, "no tools", resp.Choices[0].Content)
+}
+
+func TestGenerateContent_Streaming(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLLM := mockllms.NewMockModel(ctrl)
+	mockLLM.EXPECT().GetProviderType().Return(llms.ProviderPerplexity).Times(2)
+	mockLLM.EXPECT().GetName().Return("sonar").Times(1)
+
+	stream := func(chunks ...string) func(context.Context, []llms.Message, ...llms.CallOption) (*llms.ContentResponse, error) {
+		return func(ctx context.Context, _ []llms.Message, options ...llms.CallOption) (*llms.ContentResponse, error) {
+			opts := applyOptions(options)
+			for _, chunk := range chunks {
+				require.NoError(t, opts.StreamingFunc(ctx, []byte(chunk)))
+			}
+			return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: strings.Join(chunks, "")}}}, nil
+		}
+	}
+	gomock.InOrder(
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(stream(" ", "{\"tool_calls\":", "[{\"name\":\"search\"}]}")),
+		mockLLM.EXPECT().GenerateContent(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(stream("\n", "Hel", "lo")),
+	)
+
+	var streamed []string
+	streamingFunc := llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+		streamed = append(streamed, string(chunk))
+		return nil
+	})
+
+	m := toolemu.New(mockLLM)
+	msgs := []llms.Message{llms.MessageFromTextParts(llms.RoleHuman, "hi")}
+
+	resp, err := m.GenerateContent(context.Background(), msgs, llms.WithTools([]llms.Tool{searchTool}), streamingFunc)
+	require.NoError(t, err)
+	require.Len(t, resp.Choices[0].ToolCalls, 1)
+	assert.Equal(t, "{}", resp.Choices[0].ToolCalls[0].GetFunctionCallArguments())
+	assert.Empty(t, streamed)
+
+	resp, err = m.GenerateContent(context.Background(), msgs, llms.WithTools([]llms.Tool{searchTool}), streamingFunc)
+	require.NoError(t, err)
+	assert.Equal(t, "\nHello", resp.Choices[0].Content)
+	assert.Equal(t, []string{"\nHel", "lo"}, streamed)
+}
+
+func TestParseToolCalls(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		name    string
+		content string
+		exp     []llms.FunctionCall
+	}{
+		{name: "text", content: "The answer is {42}"},
+		{name: "other json", content: `{"answer":42}`},
+		{name: "invalid json", content: `{"tool_calls":[`},
+		{
+			name:    "multiple",
+			content: `{"tool_calls":[{"name":"a","arguments":{"x":1}},{"name":"b"},{"arguments":{}}]}`,
+			exp:     []llms.FunctionCall{{Name: "a", Arguments: `{"x":1}`}, {Name: "b", Arguments: "{}"}},
+		},
+		{
+			name:    "string arguments",
+			content: `{"tool_calls":[{"name":"a","arguments":"{\"x\":1}"}]}`,
+			exp:     []llms.FunctionCall{{Name: "a", Arguments: `{"x":1}`}},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := toolemu.ParseToolCalls(tc.content)
+			require.Len(t, calls, len(tc.exp))
+			for i, c := range calls {
+				assert.Equal(t, "function", c.Type)
+				assert.Equal(t, tc.exp[i], *c.FunctionCall)
+			}
+		})
+	}
+}
+
+func TestConvertMessages(t *testing.T) {
+	t.Parallel()
+
+	src := &llms.MessageSource{Name: "assistant"}
+	messages := []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "find go and rust"),
+		llms.MessageFromToolCalls(llms.RoleAI,
+			llms.ToolCall{ID: "c1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "search", Arguments: `{"q":"go"}`}},
+			llms.ToolCall{ID: "c2", Type: "function", FunctionCall: &llms.FunctionCall{Name: "search", Arguments: `not json`}},
+		),
+		llms.MessageFromToolResponse(llms.RoleTool, llms.ToolCallResponse{ToolCallID: "c1", Name: "search", Content: "go result"}).WithSource(src),
+		llms.MessageFromToolResponse(llms.RoleTool, llms.ToolCallResponse{ToolCallID: "c2", Name: "search", Content: "rust result"}),
+		llms.MessageFromTextParts(llms.RoleAI, "done"),
+	}
+
+	res := toolemu.ConvertMessages(messages)
+	require.Len(t, res, 4)
+	assert.Equal(t, messages[0], res[0])
+
+	assert.Equal(t, llms.RoleAI, res[1].Role)
+	assert.Equal(t, []llms.ContentPart{
+		llms.TextContent{Text: `{"tool_calls":[{"id":"c1","name":"search","arguments":{"q":"go"}},{"id":"c2","name":"search","arguments":"not json"}]}`},
+	}, res[1].Parts)
+
+	assert.Equal(t, llms.RoleHuman, res[2].Role)
+	assert.Equal(t, src, res[2].Source)
+	assert.Equal(t, []llms.ContentPart{
+		llms.TextContent{Text: "Tool result (search, c1): go result"},
+		llms.TextContent{Text: "\nTool result (search, c2): rust result"},
+	}, res[2].Parts)
+
+	assert.Equal(t, messages[4], res[3])
+}
+
+func TestWithToolsPrompt(t *testing.T) {
+	t.Parallel()
+
+	human := llms.MessageFromTextParts(llms.RoleHuman, "hi")
+	for _, role := range []llms.Role{llms.RoleSystem, llms.RoleDeveloper} {
+		res := toolemu.WithToolsPrompt([]llms.Message{llms.MessageFromTextParts(role, "You are helpful."), human}, []llms.Tool{searchTool}, "auto")
+		require.Len(t, res, 2)
+		assert.Equal(t, role, res[0].Role)
+		require.Len(t, res[0].Parts, 2)
+		assert.Contains(t, res[0].Parts[1].(llms.TextContent).Text, "search")
+		assert.Equal(t, human, res[1])
+	}
+
+	res := toolemu.WithToolsPrompt([]llms.Message{human}, []llms.Tool{searchTool}, "auto")
+	require.Len(t, res, 2)
+	assert.Equal(t, llms.RoleSystem, res[0].Role)
+	assert.Equal(t, human, res[1])
+}
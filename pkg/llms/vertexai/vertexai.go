@@ -0,0 +1,216 @@
+// Package vertexai provides the Gemini models on GCP Vertex AI.
+//
+// Unlike Google AI Studio, Vertex AI authenticates with the service account
+// or the Application Default Credentials, and serves the models from the regional endpoints
+// of the cloud project. The LLM is the googleai client with the Vertex AI backend,
+// see googleai.GoogleAI.CreateCachedContent for the context caching.
+package vertexai
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/googleai"
+	"github.com/effective-security/gogentic/pkg/llms/llmhttp"
+	"google.golang.org/genai"
+)
+
+const (
+	// Provider is the provider type of the Vertex AI LLM.
+	Provider = llms.ProviderVertexAI
+	// ProjectEnvVarName is the environment variable with the cloud project.
+	ProjectEnvVarName = "GOOGLE_CLOUD_PROJECT"
+	// LocationEnvVarName is the environment variable with the cloud location.
+	LocationEnvVarName = "GOOGLE_CLOUD_LOCATION"
+	// DefaultLocation is used when the location is not set.
+	DefaultLocation = "us-central1"
+	// DefaultModel is used when the model is not set.
+	DefaultModel = "gemini-2.5-pro"
+)
+
+// cloudPlatformScope is the OAuth scope of the Vertex AI API.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// ErrMissingProject is returned when the cloud project is not set.
+var ErrMissingProject = errors.New("missing the cloud project, set it in the GOOGLE_CLOUD_PROJECT environment variable")
+
+type options struct {
+	project         string
+	location        string
+	model           string
+	apiKey          string
+	credentials     *auth.Credentials
+	credentialsFile string
+	credentialsJSON []byte
+	baseURL         string
+	httpClient      *http.Client
+	googleai        []googleai.Option
+}
+
+// Option is a functional option for the Vertex AI LLM.
+type Option func(*options)
+
+// WithProject sets the cloud project. If not set, the project
+// is read from the GOOGLE_CLOUD_PROJECT environment variable.
+func WithProject(project string) Option {
+	return func(opts *options) {
+		opts.project = project
+	}
+}
+
+// WithLocation sets the cloud location of the regional endpoint, for example europe-west4,
+// or "global" for the global endpoint. If not set, the location is read
+// from the GOOGLE_CLOUD_LOCATION environment variable, or DefaultLocation is used.
+func WithLocation(location string) Option {
+	return func(opts *options) {
+		opts.location = location
+	}
+}
+
+// WithModel sets the model, by default DefaultModel.
+func WithModel(model string) Option {
+	return func(opts *options) {
+		opts.model = model
+	}
+}
+
+// WithAPIKey sets the API key of the Vertex AI express mode,
+// the project and the location are not used with the API key.
+func WithAPIKey(apiKey string) Option {
+	return func(opts *options) {
+		opts.apiKey = apiKey
+	}
+}
+
+// WithCredentials sets the credentials. If not set,
+// the Application Default Credentials are used.
+func WithCredentials(creds *auth.Credentials) Option {
+	return func(opts *options) {
+		opts.credentials = creds
+	}
+}
+
+// WithCredentialsFile sets the path of the service account JSON key file.
+func WithCredentialsFile(path string) Option {
+	return func(opts *options) {
+		opts.credentialsFile = path
+	}
+}
+
+// WithCredentialsJSON sets the content of the service account JSON key.
+func WithCredentialsJSON(json []byte) Option {
+	return func(opts *options) {
+		opts.credentialsJSON = json
+	}
+}
+
+// WithBaseURL overrides the regional endpoint, for example the Private Service Connect endpoint.
+func WithBaseURL(baseURL string) Option {
+	return func(opts *options) {
+		opts.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient allows setting a custom HTTP client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(opts *options) {
+		opts.httpClient = client
+	}
+}
+
+// WithHTTPMiddleware wraps the HTTP client with the middlewares,
+// for the proxies, the request signing or the extra headers, see llmhttp.Middleware.
+// Without the HTTP client, the middlewares wrap the client authenticated
+// with the credentials. The first middleware is the outermost one.
+func WithHTTPMiddleware(middlewares ...llmhttp.Middleware) Option {
+	return func(opts *options) {
+		opts.googleai = append(opts.googleai, googleai.WithHTTPMiddleware(middlewares...))
+	}
+}
+
+// WithSafetySettings sets the safety settings of the requests.
+func WithSafetySettings(settings ...*genai.SafetySetting) Option {
+	return func(opts *options) {
+		opts.googleai = append(opts.googleai, googleai.WithSafetySettings(settings...))
+	}
+}
+
+// WithCachedContent sets the name of the cached content used as the prompt prefix.
+func WithCachedContent(name string) Option {
+	return func(opts *options) {
+		opts.googleai = append(opts.googleai, googleai.WithCachedContent(name))
+	}
+}
+
+// WithGoogleAIOptions passes the options of the googleai client, such as the generation defaults.
+func WithGoogleAIOptions(opts ...googleai.Option) Option {
+	return func(o *options) {
+		o.googleai = append(o.googleai, opts...)
+	}
+}
+
+// New returns the Gemini LLM on Vertex AI.
+func New(ctx context.Context, opts ...Option) (*googleai.GoogleAI, error) {
+	o := &options{
+		project:  os.Getenv(ProjectEnvVarName),
+		location: os.Getenv(LocationEnvVarName),
+		model:    DefaultModel,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.location == "" {
+		o.location = DefaultLocation
+	}
+
+	gopts := []googleai.Option{
+		googleai.WithBackend(genai.BackendVertexAI),
+		googleai.WithDefaultModel(o.model),
+		googleai.WithBaseURL(o.baseURL),
+	}
+	if o.httpClient != nil {
+		gopts = append(gopts, googleai.WithHTTPClient(o.httpClient))
+	}
+
+	if o.apiKey != "" {
+		// express mode
+		gopts = append(gopts, googleai.WithAPIKey(o.apiKey))
+	} else {
+		if o.project == "" {
+			return nil, ErrMissingProject
+		}
+		creds, err := o.loadCredentials()
+		if err != nil {
+			return nil, err
+		}
+		gopts = append(gopts,
+			googleai.WithCloudProject(o.project),
+			googleai.WithCloudLocation(o.location),
+			googleai.WithCredentials(creds),
+		)
+	}
+
+	return googleai.New(ctx, append(gopts, o.googleai...)...)
+}
+
+// loadCredentials returns the credentials from the key file or JSON,
+// or nil for the Application Default Credentials.
+func (o *options) loadCredentials() (*auth.Credentials, error) {
+	if o.credentials != nil || (o.credentialsFile == "" && len(o.credentialsJSON) == 0) {
+		return o.credentials, nil
+	}
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+		Scopes:          []string{cloudPlatformScope},
+		CredentialsFile: o.credentialsFile,
+		CredentialsJSON: o.credentialsJSON,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the service account credentials")
+	}
+	return creds, nil
+}
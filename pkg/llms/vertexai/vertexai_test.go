@@ -0,0 +1,77 @@
+package vertexai_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/auth"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llms/vertexai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+type staticToken string
+
+func (s staticToken) Token(context.Context) (*auth.Token, error) {
+	return &auth.Token{Value: string(s), Type: "Bearer"}, nil
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv(vertexai.ProjectEnvVarName, "")
+	t.Setenv(vertexai.LocationEnvVarName, "")
+
+	_, err := vertexai.New(context.Background())
+	assert.ErrorIs(t, err, vertexai.ErrMissingProject)
+
+	llm, err := vertexai.New(context.Background(), vertexai.WithAPIKey("test-key"))
+	require.NoError(t, err)
+	assert.Equal(t, vertexai.DefaultModel, llm.GetName())
+	assert.Equal(t, llms.ProviderVertexAI, llm.GetProviderType())
+	assert.True(t, llm.GetProviderType().Supports(llms.CapabilityPromptCaching))
+}
+
+func TestGenerateContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.URL.Path, "projects/my-project/locations/europe-west4/publishers/google/models/gemini-2.5-flash:generateContent")
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "projects/my-project/locations/europe-west4/cachedContents/123", req["cachedContent"])
+		assert.Equal(t, []any{
+			map[string]any{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_LOW_AND_ABOVE"},
+		}, req["safetySettings"])
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hello"}]},"finishReason":"STOP"}],
+			"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":2,"totalTokenCount":12,"cachedContentTokenCount":8}}`))
+	}))
+	defer srv.Close()
+
+	llm, err := vertexai.New(context.Background(),
+		vertexai.WithProject("my-project"),
+		vertexai.WithLocation("europe-west4"),
+		vertexai.WithModel("gemini-2.5-flash"),
+		vertexai.WithBaseURL(srv.URL),
+		vertexai.WithCredentials(auth.NewCredentials(&auth.CredentialsOptions{TokenProvider: staticToken("test-token")})),
+		vertexai.WithCachedContent("projects/my-project/locations/europe-west4/cachedContents/123"),
+		vertexai.WithSafetySettings(&genai.SafetySetting{
+			Category:  genai.HarmCategoryHarassment,
+			Threshold: genai.HarmBlockThresholdBlockLowAndAbove,
+		}),
+	)
+	require.NoError(t, err)
+
+	resp, err := llm.GenerateContent(context.Background(), []llms.Message{
+		llms.MessageFromTextParts(llms.RoleHuman, "hello"),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "Hello", resp.Choices[0].Content)
+	assert.Equal(t, uint64(8), resp.Choices[0].Usage.CacheReadTokens)
+}
@@ -0,0 +1,226 @@
+package llmutils
+
+import (
+	"strings"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// CompactOptions configures CompactHistory.
+type CompactOptions struct {
+	// Provider is the provider of the model, to enforce its message ordering rules.
+	// If empty, the consecutive tool messages are merged, and no other rules are enforced.
+	Provider llms.ProviderType
+	// Repair defines how the orphaned tool calls and responses are repaired,
+	// HistoryRepairNone keeps them as is.
+	Repair HistoryRepairMode
+}
+
+// CompactReport describes the changes made by CompactHistory.
+type CompactReport struct {
+	HistoryRepairReport
+	// MergedMessages is the number of the messages merged into the previous message.
+	MergedMessages int
+	// SplitMessages is the number of the tool messages split into one message per tool response.
+	SplitMessages int
+	// DroppedSystemMessages is the number of the dropped duplicate system messages.
+	DroppedSystemMessages int
+	// RemappedToolCallIDs is the number of the tool call IDs not accepted by the provider,
+	// replaced with the generated IDs, see RemapToolCallIDs.
+	RemappedToolCallIDs int
+}
+
+// Changed returns true if the history was modified.
+func (r *CompactReport) Changed() bool {
+	return r.HistoryRepairReport.Changed() ||
+		r.MergedMessages > 0 ||
+		r.SplitMessages > 0 ||
+		r.DroppedSystemMessages > 0 ||
+		r.RemappedToolCallIDs > 0
+}
+
+// CompactHistory returns the compacted history, that is accepted by the provider:
+//   - the orphaned tool calls and responses are repaired according to opts.Repair, see RepairHistory;
+//   - the tool call IDs not accepted by the provider are remapped, see RemapToolCallIDs;
+//   - the repeated system and developer messages, such as the reminders added on every turn,
+//     are deduplicated: the leading system prompt is kept, and for other duplicates the last one is kept;
+//   - the consecutive tool messages are merged into one message,
+//     or split into one message per tool response for the OpenAI compatible providers;
+//   - the consecutive human or AI messages are merged for the providers that require
+//     alternating roles, such as Anthropic.
+//
+// If opts is nil, the orphaned tool messages are dropped and no provider rules are enforced.
+// The original messages are not modified, and returned as is if no compaction is needed.
+func CompactHistory(msgs []llms.Message, opts *CompactOptions) ([]llms.Message, *CompactReport) {
+	if opts == nil {
+		opts = &CompactOptions{Repair: HistoryRepairDrop}
+	}
+	report := &CompactReport{}
+	if len(msgs) == 0 {
+		return msgs, report
+	}
+
+	res, repairReport := RepairHistory(msgs, opts.Repair)
+	report.HistoryRepairReport = *repairReport
+
+	res, remapped := RemapToolCallIDs(res, ToolCallIDFormatFor(opts.Provider))
+	report.RemappedToolCallIDs = len(remapped)
+
+	res = dedupSystemMessages(res, report)
+
+	if requiresSingleToolResponse(opts.Provider) {
+		res = splitToolMessages(res, report)
+	} else {
+		res = mergeConsecutive(res, report, func(role llms.Role) bool {
+			return role == llms.RoleTool
+		})
+	}
+	if requiresAlternatingRoles(opts.Provider) {
+		res = mergeConsecutive(res, report, func(role llms.Role) bool {
+			return role == llms.RoleHuman || role == llms.RoleAI
+		})
+	}
+
+	if !report.Changed() {
+		return msgs, report
+	}
+	return res, report
+}
+
+// requiresSingleToolResponse returns true for the OpenAI compatible providers,
+// that accept exactly one tool response per tool message.
+func requiresSingleToolResponse(provider llms.ProviderType) bool {
+	switch provider {
+	case llms.ProviderOpenAI,
+		llms.ProviderAzure,
+		llms.ProviderAzureAD,
+		llms.ProviderPerplexity,
+		llms.ProviderVLLM,
+		llms.ProviderLlamaCpp,
+		llms.ProviderGroq,
+		llms.ProviderDeepSeek,
+		llms.ProviderOpenRouter:
+		return true
+	}
+	return false
+}
+
+// requiresAlternatingRoles returns true for the providers,
+// that require the user and the assistant messages to alternate.
+func requiresAlternatingRoles(provider llms.ProviderType) bool {
+	switch provider {
+	case llms.ProviderAnthropic,
+		llms.ProviderAnthropicBedrock,
+		llms.ProviderBedrock:
+		return true
+	}
+	return false
+}
+
+func isSystemRole(role llms.Role) bool {
+	return role == llms.RoleSystem || role == llms.RoleDeveloper
+}
+
+// systemMessageKey returns the key of the text only system message,
+// or empty string if the message is not a system message, or has other parts.
+func systemMessageKey(m llms.Message) string {
+	if !isSystemRole(m.Role) || len(m.Parts) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(string(m.Role))
+	for _, p := range m.Parts {
+		tc, ok := p.(llms.TextContent)
+		if !ok {
+			return ""
+		}
+		sb.WriteString("\x00")
+		sb.WriteString(tc.Text)
+	}
+	return sb.String()
+}
+
+func dedupSystemMessages(msgs []llms.Message, report *CompactReport) []llms.Message {
+	leading := map[string]bool{}
+	start := 0
+	for ; start < len(msgs) && isSystemRole(msgs[start].Role); start++ {
+		if key := systemMessageKey(msgs[start]); key != "" {
+			leading[key] = true
+		}
+	}
+
+	last := map[string]int{}
+	for i := start; i < len(msgs); i++ {
+		if key := systemMessageKey(msgs[i]); key != "" {
+			last[key] = i
+		}
+	}
+
+	var res []llms.Message
+	for i, m := range msgs {
+		if i >= start {
+			if key := systemMessageKey(m); key != "" && (leading[key] || last[key] != i) {
+				if res == nil {
+					res = append(make([]llms.Message, 0, len(msgs)), msgs[:i]...)
+				}
+				report.DroppedSystemMessages++
+				continue
+			}
+		}
+		if res != nil {
+			res = append(res, m)
+		}
+	}
+	if res == nil {
+		return msgs
+	}
+	return res
+}
+
+func splitToolMessages(msgs []llms.Message, report *CompactReport) []llms.Message {
+	var res []llms.Message
+	for i, m := range msgs {
+		if m.Role != llms.RoleTool || len(m.Parts) < 2 {
+			if res != nil {
+				res = append(res, m)
+			}
+			continue
+		}
+		if res == nil {
+			res = append(make([]llms.Message, 0, len(msgs)+len(m.Parts)), msgs[:i]...)
+		}
+		report.SplitMessages++
+		for _, p := range m.Parts {
+			res = append(res, withParts(m, []llms.ContentPart{p}))
+		}
+	}
+	if res == nil {
+		return msgs
+	}
+	return res
+}
+
+// mergeConsecutive merges the consecutive messages with the same role, when merge returns true for the role.
+// The merged message keeps the source and the metadata of the first message.
+func mergeConsecutive(msgs []llms.Message, report *CompactReport, merge func(role llms.Role) bool) []llms.Message {
+	var res []llms.Message
+	for i, m := range msgs {
+		if i > 0 && merge(m.Role) && msgs[i-1].Role == m.Role {
+			if res == nil {
+				res = append(make([]llms.Message, 0, len(msgs)), msgs[:i]...)
+			}
+			prev := &res[len(res)-1]
+			parts := make([]llms.ContentPart, 0, len(prev.Parts)+len(m.Parts))
+			prev.Parts = append(append(parts, prev.Parts...), m.Parts...)
+			report.MergedMessages++
+			continue
+		}
+		if res != nil {
+			res = append(res, m)
+		}
+	}
+	if res == nil {
+		return msgs
+	}
+	return res
+}
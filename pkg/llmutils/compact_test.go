@@ -0,0 +1,113 @@
+package llmutils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// describeMessages returns one entry per message, with the parts separated by "|".
+func describeMessages(msgs []llms.Message) []string {
+	res := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		res = append(res, strings.Join(describe([]llms.Message{m}), "|"))
+	}
+	return res
+}
+
+func Test_CompactHistory(t *testing.T) {
+	t.Parallel()
+
+	system := llms.MessageFromTextParts(llms.RoleSystem, "sys")
+	reminder := llms.MessageFromTextParts(llms.RoleSystem, "be brief")
+	human := llms.MessageFromTextParts(llms.RoleHuman, "hi")
+	human2 := llms.MessageFromTextParts(llms.RoleHuman, "there")
+	ai := llms.MessageFromTextParts(llms.RoleAI, "done")
+	ai2 := llms.MessageFromTextParts(llms.RoleAI, "more")
+
+	tcases := []struct {
+		name    string
+		msgs    []llms.Message
+		opts    *llmutils.CompactOptions
+		exp     []string
+		merged  int
+		split   int
+		dropped int
+	}{
+		{
+			name: "unchanged",
+			msgs: []llms.Message{system, human, ai},
+			exp:  []string{"system:sys", "human:hi", "ai:done"},
+		},
+		{
+			name: "orphans dropped by default",
+			msgs: []llms.Message{system, human, toolCallMsg("1"), ai},
+			exp:  []string{"system:sys", "human:hi", "ai:done"},
+		},
+		{
+			name: "orphans kept",
+			msgs: []llms.Message{system, human, toolCallMsg("1"), ai},
+			opts: &llmutils.CompactOptions{},
+			exp:  []string{"system:sys", "human:hi", "call:1", "ai:done"},
+		},
+		{
+			name:   "tool messages merged",
+			msgs:   []llms.Message{system, human, toolCallMsg("1", "2"), toolResponseMsg("1"), toolResponseMsg("2"), ai},
+			exp:    []string{"system:sys", "human:hi", "call:1|call:2", "resp:1:result 1|resp:2:result 2", "ai:done"},
+			merged: 1,
+		},
+		{
+			name: "tool messages split for openai",
+			msgs: []llms.Message{
+				human,
+				toolCallMsg("1", "2"),
+				{Role: llms.RoleTool, Parts: []llms.ContentPart{
+					llms.ToolCallResponse{ToolCallID: "1", Content: "a"},
+					llms.ToolCallResponse{ToolCallID: "2", Content: "b"},
+				}},
+				ai,
+			},
+			opts:  &llmutils.CompactOptions{Provider: llms.ProviderOpenAI},
+			exp:   []string{"human:hi", "call:1|call:2", "resp:1:a", "resp:2:b", "ai:done"},
+			split: 1,
+		},
+		{
+			name:    "system reminders deduplicated",
+			msgs:    []llms.Message{system, human, reminder, ai, system, human2, reminder, ai2},
+			exp:     []string{"system:sys", "human:hi", "ai:done", "human:there", "system:be brief", "ai:more"},
+			dropped: 2,
+		},
+		{
+			name:   "alternating roles for anthropic",
+			msgs:   []llms.Message{system, human, human2, ai, ai2},
+			opts:   &llmutils.CompactOptions{Provider: llms.ProviderAnthropic},
+			exp:    []string{"system:sys", "human:hi|human:there", "ai:done|ai:more"},
+			merged: 2,
+		},
+		{
+			name: "consecutive roles kept for openai",
+			msgs: []llms.Message{system, human, human2, ai, ai2},
+			opts: &llmutils.CompactOptions{Provider: llms.ProviderOpenAI},
+			exp:  []string{"system:sys", "human:hi", "human:there", "ai:done", "ai:more"},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			orig := describeMessages(tc.msgs)
+			res, report := llmutils.CompactHistory(tc.msgs, tc.opts)
+			assert.Equal(t, tc.exp, describeMessages(res))
+			assert.Equal(t, tc.merged, report.MergedMessages)
+			assert.Equal(t, tc.split, report.SplitMessages)
+			assert.Equal(t, tc.dropped, report.DroppedSystemMessages)
+			// the original messages are not modified
+			assert.Equal(t, orig, describeMessages(tc.msgs))
+			if !report.Changed() {
+				assert.Equal(t, tc.msgs, res)
+			}
+		})
+	}
+}
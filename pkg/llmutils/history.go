@@ -0,0 +1,237 @@
+package llmutils
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// HistoryRepairMode defines how inconsistent tool messages are repaired.
+// Only the pairing and the order of the tool calls and the tool responses are repaired,
+// other provider specific constraints, such as alternating roles, are not enforced.
+type HistoryRepairMode int
+
+const (
+	// HistoryRepairNone disables the history repair.
+	HistoryRepairNone HistoryRepairMode = iota
+	// HistoryRepairDrop drops orphaned tool responses and tool calls without responses.
+	HistoryRepairDrop
+	// HistoryRepairStub keeps orphaned tool messages,
+	// and adds synthetic tool calls or responses to complete the pairs.
+	HistoryRepairStub
+)
+
+// StubToolResponseContent is the content of the synthetic tool response,
+// added for the tool calls without responses in HistoryRepairStub mode.
+const StubToolResponseContent = "Tool call result is not available."
+
+// HistoryRepairReport describes the changes made by RepairHistory.
+type HistoryRepairReport struct {
+	// DroppedToolResponses is the number of dropped orphaned or duplicate tool responses.
+	DroppedToolResponses int
+	// DroppedToolCalls is the number of dropped tool calls without responses.
+	DroppedToolCalls int
+	// StubbedToolCalls is the number of synthetic tool calls added for orphaned tool responses.
+	StubbedToolCalls int
+	// StubbedToolResponses is the number of synthetic tool responses added for tool calls without responses.
+	StubbedToolResponses int
+	// ReorderedToolResponses is the number of tool responses moved next to their tool calls.
+	ReorderedToolResponses int
+	// Dropped is the list of the dropped tool calls and responses.
+	Dropped []DroppedToolPart
+}
+
+// DroppedToolPart describes the tool call or response dropped by RepairHistory.
+type DroppedToolPart struct {
+	// ToolCallID is the ID of the tool call.
+	ToolCallID string
+	// Name is the name of the tool.
+	Name string
+	// Response is true for the dropped tool response, and false for the tool call.
+	Response bool
+}
+
+// Changed returns true if the history was modified.
+func (r *HistoryRepairReport) Changed() bool {
+	return r.DroppedToolResponses > 0 ||
+		r.DroppedToolCalls > 0 ||
+		r.StubbedToolCalls > 0 ||
+		r.StubbedToolResponses > 0 ||
+		r.ReorderedToolResponses > 0
+}
+
+// ValidateHistory checks that every tool response has the originating tool call,
+// every tool call has a response, and the responses immediately follow the tool calls.
+func ValidateHistory(msgs []llms.Message) error {
+	_, report := RepairHistory(msgs, HistoryRepairDrop)
+	switch {
+	case report.DroppedToolResponses > 0:
+		return errors.Newf("history has %d orphaned or duplicate tool responses", report.DroppedToolResponses)
+	case report.DroppedToolCalls > 0:
+		return errors.Newf("history has %d tool calls without responses", report.DroppedToolCalls)
+	case report.ReorderedToolResponses > 0:
+		return errors.Newf("history has %d tool responses out of order", report.ReorderedToolResponses)
+	}
+	return nil
+}
+
+type toolResponseRef struct {
+	msg  int
+	part int
+}
+
+// RepairHistory returns the history where every tool call message is immediately
+// followed by the responses to its tool calls, in the order of the calls.
+// Orphaned tool responses, and tool calls without responses, are dropped or stubbed
+// according to the mode.
+// The original messages are not modified, and returned as is if no repair is needed.
+func RepairHistory(msgs []llms.Message, mode HistoryRepairMode) ([]llms.Message, *HistoryRepairReport) {
+	report := &HistoryRepairReport{}
+	if mode == HistoryRepairNone || len(msgs) == 0 {
+		return msgs, report
+	}
+
+	// index the first response for each tool call ID
+	responses := map[string]toolResponseRef{}
+	for i, m := range msgs {
+		for j, p := range m.Parts {
+			if tr, ok := p.(llms.ToolCallResponse); ok {
+				if _, exists := responses[tr.ToolCallID]; !exists {
+					responses[tr.ToolCallID] = toolResponseRef{msg: i, part: j}
+				}
+			}
+		}
+	}
+
+	consumed := map[toolResponseRef]bool{}
+	res := make([]llms.Message, 0, len(msgs))
+
+	for i, m := range msgs {
+		var (
+			parts   []llms.ContentPart
+			replies []llms.Message
+			orphans []llms.ToolCallResponse
+			hasTool bool
+		)
+		for j, p := range m.Parts {
+			switch pp := p.(type) {
+			case llms.ToolCall:
+				hasTool = true
+				ref, ok := responses[pp.ID]
+				if ok && !consumed[ref] && ref.msg > i {
+					consumed[ref] = true
+					if !onlyToolResponses(msgs[i+1 : ref.msg]) {
+						report.ReorderedToolResponses++
+					}
+					parts = append(parts, pp)
+					src := msgs[ref.msg]
+					replies = append(replies, withParts(src, []llms.ContentPart{src.Parts[ref.part]}))
+					continue
+				}
+				if mode == HistoryRepairStub {
+					report.StubbedToolResponses++
+					parts = append(parts, pp)
+					replies = append(replies, stubToolResponseMessage(m, pp))
+				} else {
+					report.DroppedToolCalls++
+					report.Dropped = append(report.Dropped, DroppedToolPart{ToolCallID: pp.ID, Name: toolCallName(pp)})
+				}
+			case llms.ToolCallResponse:
+				hasTool = true
+				ref := toolResponseRef{msg: i, part: j}
+				if consumed[ref] {
+					// already moved next to its tool call
+					continue
+				}
+				if first := responses[pp.ToolCallID]; first != ref {
+					// duplicate response for the same tool call
+					report.DroppedToolResponses++
+					report.Dropped = append(report.Dropped, DroppedToolPart{ToolCallID: pp.ToolCallID, Name: pp.Name, Response: true})
+					continue
+				}
+				if mode == HistoryRepairStub {
+					report.StubbedToolCalls++
+					orphans = append(orphans, pp)
+				} else {
+					report.DroppedToolResponses++
+					report.Dropped = append(report.Dropped, DroppedToolPart{ToolCallID: pp.ToolCallID, Name: pp.Name, Response: true})
+				}
+			default:
+				parts = append(parts, p)
+			}
+		}
+
+		if !hasTool {
+			res = append(res, m)
+			continue
+		}
+		if len(parts) > 0 {
+			res = append(res, withParts(m, parts))
+		}
+		res = append(res, replies...)
+		for _, tr := range orphans {
+			res = append(res, stubToolCallMessage(m, tr))
+			res = append(res, withParts(m, []llms.ContentPart{tr}))
+		}
+	}
+
+	if !report.Changed() {
+		return msgs, report
+	}
+	return res, report
+}
+
+// onlyToolResponses returns true if the messages contain only tool responses.
+func onlyToolResponses(msgs []llms.Message) bool {
+	for _, m := range msgs {
+		for _, p := range m.Parts {
+			if _, ok := p.(llms.ToolCallResponse); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func withParts(m llms.Message, parts []llms.ContentPart) llms.Message {
+	m.Parts = parts
+	return m
+}
+
+func toolCallName(tc llms.ToolCall) string {
+	if tc.FunctionCall != nil {
+		return tc.FunctionCall.Name
+	}
+	return ""
+}
+
+func stubToolResponseMessage(m llms.Message, tc llms.ToolCall) llms.Message {
+	name := toolCallName(tc)
+	return llms.Message{
+		Role: llms.RoleTool,
+		Parts: []llms.ContentPart{
+			llms.ToolCallResponse{
+				ToolCallID: tc.ID,
+				Name:       name,
+				Content:    StubToolResponseContent,
+			},
+		},
+		Source: m.Source,
+	}
+}
+
+func stubToolCallMessage(m llms.Message, tr llms.ToolCallResponse) llms.Message {
+	return llms.Message{
+		Role: llms.RoleAI,
+		Parts: []llms.ContentPart{
+			llms.ToolCall{
+				ID:   tr.ToolCallID,
+				Type: "function",
+				FunctionCall: &llms.FunctionCall{
+					Name:      tr.Name,
+					Arguments: "{}",
+				},
+			},
+		},
+		Source: m.Source,
+	}
+}
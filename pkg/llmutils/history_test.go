@@ -0,0 +1,160 @@
+package llmutils_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolCallMsg(ids ...string) llms.Message {
+	var calls []llms.ToolCall
+	for _, id := range ids {
+		calls = append(calls, llms.ToolCall{
+			ID:           id,
+			Type:         "function",
+			FunctionCall: &llms.FunctionCall{Name: "tool_" + id, Arguments: "{}"},
+		})
+	}
+	return llms.MessageFromToolCalls(llms.RoleAI, calls...)
+}
+
+func toolResponseMsg(id string) llms.Message {
+	return llms.MessageFromToolResponse(llms.RoleTool, llms.ToolCallResponse{
+		ToolCallID: id,
+		Name:       "tool_" + id,
+		Content:    "result " + id,
+	})
+}
+
+// describe returns a compact representation of the history for assertions.
+func describe(msgs []llms.Message) []string {
+	var res []string
+	for _, m := range msgs {
+		for _, p := range m.Parts {
+			switch pp := p.(type) {
+			case llms.TextContent:
+				res = append(res, string(m.Role)+":"+pp.Text)
+			case llms.ToolCall:
+				res = append(res, "call:"+pp.ID)
+			case llms.ToolCallResponse:
+				res = append(res, "resp:"+pp.ToolCallID+":"+pp.Content)
+			}
+		}
+	}
+	return res
+}
+
+func Test_RepairHistory(t *testing.T) {
+	t.Parallel()
+
+	system := llms.MessageFromTextParts(llms.RoleSystem, "sys")
+	human := llms.MessageFromTextParts(llms.RoleHuman, "hi")
+	ai := llms.MessageFromTextParts(llms.RoleAI, "done")
+
+	tcases := []struct {
+		name     string
+		msgs     []llms.Message
+		mode     llmutils.HistoryRepairMode
+		exp      []string
+		report   llmutils.HistoryRepairReport
+		validErr string
+	}{
+		{
+			name: "valid",
+			msgs: []llms.Message{system, human, toolCallMsg("1", "2"), toolResponseMsg("1"), toolResponseMsg("2"), ai},
+			mode: llmutils.HistoryRepairDrop,
+			exp:  []string{"system:sys", "human:hi", "call:1", "call:2", "resp:1:result 1", "resp:2:result 2", "ai:done"},
+		},
+		{
+			name: "orphaned response dropped",
+			msgs: []llms.Message{system, toolResponseMsg("1"), human, ai},
+			mode: llmutils.HistoryRepairDrop,
+			exp:  []string{"system:sys", "human:hi", "ai:done"},
+			report: llmutils.HistoryRepairReport{
+				DroppedToolResponses: 1,
+				Dropped:              []llmutils.DroppedToolPart{{ToolCallID: "1", Name: "tool_1", Response: true}},
+			},
+			validErr: "history has 1 orphaned or duplicate tool responses",
+		},
+		{
+			name:     "orphaned response stubbed",
+			msgs:     []llms.Message{system, toolResponseMsg("1"), human, ai},
+			mode:     llmutils.HistoryRepairStub,
+			exp:      []string{"system:sys", "call:1", "resp:1:result 1", "human:hi", "ai:done"},
+			report:   llmutils.HistoryRepairReport{StubbedToolCalls: 1},
+			validErr: "history has 1 orphaned or duplicate tool responses",
+		},
+		{
+			name: "call without response dropped",
+			msgs: []llms.Message{system, human, toolCallMsg("1", "2"), toolResponseMsg("2"), ai},
+			mode: llmutils.HistoryRepairDrop,
+			exp:  []string{"system:sys", "human:hi", "call:2", "resp:2:result 2", "ai:done"},
+			report: llmutils.HistoryRepairReport{
+				DroppedToolCalls: 1,
+				Dropped:          []llmutils.DroppedToolPart{{ToolCallID: "1", Name: "tool_1"}},
+			},
+			validErr: "history has 1 tool calls without responses",
+		},
+		{
+			name:     "call without response stubbed",
+			msgs:     []llms.Message{system, human, toolCallMsg("1")},
+			mode:     llmutils.HistoryRepairStub,
+			exp:      []string{"system:sys", "human:hi", "call:1", "resp:1:" + llmutils.StubToolResponseContent},
+			report:   llmutils.HistoryRepairReport{StubbedToolResponses: 1},
+			validErr: "history has 1 tool calls without responses",
+		},
+		{
+			name:     "response out of order",
+			msgs:     []llms.Message{system, toolCallMsg("1"), human, toolResponseMsg("1"), ai},
+			mode:     llmutils.HistoryRepairDrop,
+			exp:      []string{"system:sys", "call:1", "resp:1:result 1", "human:hi", "ai:done"},
+			report:   llmutils.HistoryRepairReport{ReorderedToolResponses: 1},
+			validErr: "history has 1 tool responses out of order",
+		},
+		{
+			name: "duplicate response",
+			msgs: []llms.Message{toolCallMsg("1"), toolResponseMsg("1"), toolResponseMsg("1"), ai},
+			mode: llmutils.HistoryRepairDrop,
+			exp:  []string{"call:1", "resp:1:result 1", "ai:done"},
+			report: llmutils.HistoryRepairReport{
+				DroppedToolResponses: 1,
+				Dropped:              []llmutils.DroppedToolPart{{ToolCallID: "1", Name: "tool_1", Response: true}},
+			},
+			validErr: "history has 1 orphaned or duplicate tool responses",
+		},
+		{
+			name: "none",
+			msgs: []llms.Message{toolResponseMsg("1"), ai},
+			mode: llmutils.HistoryRepairNone,
+			exp:  []string{"resp:1:result 1", "ai:done"},
+			// ValidateHistory always checks
+			validErr: "history has 1 orphaned or duplicate tool responses",
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			orig := describe(tc.msgs)
+			res, report := llmutils.RepairHistory(tc.msgs, tc.mode)
+			require.NotNil(t, report)
+			assert.Equal(t, tc.exp, describe(res))
+			assert.Equal(t, tc.report, *report)
+			// the original history is not modified
+			assert.Equal(t, orig, describe(tc.msgs))
+
+			err := llmutils.ValidateHistory(tc.msgs)
+			if tc.validErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.validErr)
+			}
+
+			if tc.mode != llmutils.HistoryRepairNone {
+				assert.NoError(t, llmutils.ValidateHistory(res))
+			}
+		})
+	}
+}
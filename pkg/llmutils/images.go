@@ -0,0 +1,309 @@
+package llmutils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// ImagePolicy describes the images accepted by the provider.
+type ImagePolicy struct {
+	// MaxWidth is the max width of the image in pixels, zero means no limit.
+	MaxWidth int
+	// MaxHeight is the max height of the image in pixels, zero means no limit.
+	MaxHeight int
+	// MaxBytes is the max size of the encoded image, zero means no limit.
+	MaxBytes int
+	// MIMETypes are the accepted image types, empty accepts any type.
+	MIMETypes []string
+}
+
+const (
+	mimeJPEG = "image/jpeg"
+	mimePNG  = "image/png"
+	mimeGIF  = "image/gif"
+	mimeWebP = "image/webp"
+)
+
+var (
+	anthropicImagePolicy = &ImagePolicy{
+		MaxWidth:  8000,
+		MaxHeight: 8000,
+		MaxBytes:  5 << 20,
+		MIMETypes: []string{mimeJPEG, mimePNG, mimeGIF, mimeWebP},
+	}
+	bedrockImagePolicy = &ImagePolicy{
+		MaxWidth:  8000,
+		MaxHeight: 8000,
+		MaxBytes:  3750 << 10,
+		MIMETypes: []string{mimeJPEG, mimePNG, mimeGIF, mimeWebP},
+	}
+	// OpenAI scales the high detail images to fit 2048x2048,
+	// so the larger images only waste the request size.
+	openAIImagePolicy = &ImagePolicy{
+		MaxWidth:  2048,
+		MaxHeight: 2048,
+		MaxBytes:  20 << 20,
+		MIMETypes: []string{mimeJPEG, mimePNG, mimeGIF, mimeWebP},
+	}
+	// Gemini scales the images to fit 3072x3072,
+	// the inline data is limited by the total request size of 20MB,
+	// so the margin is left for the rest of the request.
+	googleImagePolicy = &ImagePolicy{
+		MaxWidth:  3072,
+		MaxHeight: 3072,
+		MaxBytes:  15 << 20,
+		MIMETypes: []string{mimeJPEG, mimePNG, mimeWebP, "image/heic", "image/heif"},
+	}
+)
+
+// ImagePolicyFor returns the policy of the images accepted by the provider,
+// or nil if the provider limits are not known.
+func ImagePolicyFor(provider llms.ProviderType) *ImagePolicy {
+	switch provider {
+	case llms.ProviderAnthropic, llms.ProviderAnthropicBedrock:
+		return anthropicImagePolicy
+	case llms.ProviderBedrock:
+		return bedrockImagePolicy
+	case llms.ProviderOpenAI, llms.ProviderAzure, llms.ProviderAzureAD:
+		return openAIImagePolicy
+	case llms.ProviderGoogleAI, llms.ProviderVertexAI:
+		return googleImagePolicy
+	}
+	return nil
+}
+
+// Allowed returns true if the image type is accepted by the provider.
+func (p *ImagePolicy) Allowed(mimeType string) bool {
+	return len(p.MIMETypes) == 0 || slices.Contains(p.MIMETypes, mimeType)
+}
+
+func (p *ImagePolicy) fitsSize(size int) bool {
+	return p.MaxBytes == 0 || size <= p.MaxBytes
+}
+
+func (p *ImagePolicy) fitsBounds(width, height int) bool {
+	return (p.MaxWidth == 0 || width <= p.MaxWidth) && (p.MaxHeight == 0 || height <= p.MaxHeight)
+}
+
+// jpegQualities are the qualities tried to fit the image into the max size,
+// before the image is downscaled further.
+var jpegQualities = []int{90, 75, 60}
+
+// maxFitAttempts limits the number of the downscaling steps to fit the max size.
+const maxFitAttempts = 8
+
+// FitImage returns the image resized and converted to fit the policy.
+// The image is returned as is if it's accepted by the provider.
+// The images are downscaled preserving the aspect ratio, and the JPEG and PNG images keep the type if accepted,
+// other images are converted to PNG if the image has transparency, or to JPEG otherwise.
+// The JPEG, PNG and GIF images can be converted, an error is returned for other types.
+func FitImage(img llms.BinaryContent, policy *ImagePolicy) (llms.BinaryContent, error) {
+	if policy == nil || !strings.HasPrefix(img.MIMEType, "image/") {
+		return img, nil
+	}
+	if policy.Allowed(img.MIMEType) && policy.fitsSize(len(img.Data)) {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		// the dimensions of the formats without the decoder can not be checked
+		if err != nil || policy.fitsBounds(cfg.Width, cfg.Height) {
+			return img, nil
+		}
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return img, errors.Wrapf(err, "unable to decode %s image", img.MIMEType)
+	}
+
+	b := src.Bounds()
+	width, height := fitBounds(b.Dx(), b.Dy(), policy.MaxWidth, policy.MaxHeight)
+	mimeType := targetImageType(src, img.MIMEType, policy)
+	if mimeType == "" {
+		return img, errors.Errorf("unable to convert %s image: no supported image type", img.MIMEType)
+	}
+
+	var pixels *image.NRGBA
+	for range maxFitAttempts {
+		scaled := src
+		if width != b.Dx() || height != b.Dy() {
+			if pixels == nil {
+				pixels = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+				draw.Draw(pixels, pixels.Bounds(), src, b.Min, draw.Src)
+			}
+			scaled = resizeImage(pixels, width, height)
+		}
+
+		data, err := encodeImage(scaled, mimeType, policy)
+		if err != nil {
+			return img, err
+		}
+		if data != nil {
+			return llms.BinaryContent{MIMEType: mimeType, Data: data}, nil
+		}
+		// downscale further until the image fits the max size
+		width, height = max(1, width*3/4), max(1, height*3/4)
+	}
+	return img, errors.Errorf("unable to fit %s image into %d bytes", img.MIMEType, policy.MaxBytes)
+}
+
+// FitImages returns the history with the images resized and converted to fit the policy.
+// The images that can not be converted are left as is, the provider returns the error.
+//
+// Returns the history and the number of the converted images.
+// The original messages are not modified, and returned as is if no conversion is needed.
+func FitImages(msgs []llms.Message, policy *ImagePolicy) ([]llms.Message, int) {
+	if policy == nil || len(msgs) == 0 {
+		return msgs, 0
+	}
+
+	var res []llms.Message
+	count := 0
+	for i, m := range msgs {
+		var parts []llms.ContentPart
+		for j, p := range m.Parts {
+			bc, ok := p.(llms.BinaryContent)
+			if !ok {
+				continue
+			}
+			fitted, err := FitImage(bc, policy)
+			if err != nil || fitted.MIMEType == bc.MIMEType && bytes.Equal(fitted.Data, bc.Data) {
+				continue
+			}
+			if parts == nil {
+				parts = append(make([]llms.ContentPart, 0, len(m.Parts)), m.Parts...)
+			}
+			parts[j] = fitted
+			count++
+		}
+		if parts != nil {
+			if res == nil {
+				res = append(make([]llms.Message, 0, len(msgs)), msgs...)
+			}
+			res[i] = withParts(m, parts)
+		}
+	}
+	if res == nil {
+		return msgs, 0
+	}
+	return res, count
+}
+
+// fitBounds returns the dimensions scaled down to fit the max dimensions,
+// preserving the aspect ratio.
+func fitBounds(width, height, maxWidth, maxHeight int) (int, int) {
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && height > maxHeight {
+		scale = min(scale, float64(maxHeight)/float64(height))
+	}
+	if scale == 1.0 {
+		return width, height
+	}
+	return max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+}
+
+// targetImageType returns the type of the converted image,
+// the JPEG and PNG images keep the type if it's accepted,
+// otherwise PNG preserves the transparency, and JPEG is smaller for the photos.
+func targetImageType(img image.Image, mimeType string, policy *ImagePolicy) string {
+	if (mimeType == mimeJPEG || mimeType == mimePNG) && policy.Allowed(mimeType) {
+		return mimeType
+	}
+	preferred := []string{mimeJPEG, mimePNG}
+	if !isOpaque(img) {
+		preferred = []string{mimePNG, mimeJPEG}
+	}
+	for _, t := range append(preferred, mimeGIF) {
+		if policy.Allowed(t) {
+			return t
+		}
+	}
+	return ""
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// encodeImage encodes the image, or returns nil if the image does not fit the max size.
+func encodeImage(img image.Image, mimeType string, policy *ImagePolicy) ([]byte, error) {
+	var buf bytes.Buffer
+	switch mimeType {
+	case mimeJPEG:
+		for _, q := range jpegQualities {
+			buf.Reset()
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: q}); err != nil {
+				return nil, errors.Wrap(err, "unable to encode jpeg image")
+			}
+			if policy.fitsSize(buf.Len()) {
+				return buf.Bytes(), nil
+			}
+		}
+		return nil, nil
+	case mimePNG:
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := enc.Encode(&buf, img); err != nil {
+			return nil, errors.Wrap(err, "unable to encode png image")
+		}
+	case mimeGIF:
+		if err := gif.Encode(&buf, img, nil); err != nil {
+			return nil, errors.Wrap(err, "unable to encode gif image")
+		}
+	default:
+		return nil, errors.Errorf("unsupported image type: %s", mimeType)
+	}
+	if !policy.fitsSize(buf.Len()) {
+		return nil, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// resizeImage downscales the image with the box filter,
+// each target pixel is the average of the source pixels it covers.
+func resizeImage(src *image.NRGBA, width, height int) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0 := y * b.Dy() / height
+		y1 := max(y0+1, (y+1)*b.Dy()/height)
+		for x := range width {
+			x0 := x * b.Dx() / width
+			x1 := max(x0+1, (x+1)*b.Dx()/width)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					px := row[sx*4 : sx*4+4]
+					// premultiply, so the transparent pixels do not bleed the color
+					pa := uint64(px[3])
+					r += uint64(px[0]) * pa
+					g += uint64(px[1]) * pa
+					bl += uint64(px[2]) * pa
+					a += pa
+					n++
+				}
+			}
+			c := color.NRGBA{A: uint8(a / n)}
+			if a > 0 {
+				c.R, c.G, c.B = uint8(r/a), uint8(g/a), uint8(bl/a)
+			}
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
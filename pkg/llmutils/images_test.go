@@ -0,0 +1,186 @@
+package llmutils_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage(t *testing.T, width, height int, alpha uint8, noise bool) *image.NRGBA {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for y := range height {
+		for x := range width {
+			c := color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: alpha}
+			if noise {
+				c.R, c.G, c.B = uint8(rnd.UintN(256)), uint8(rnd.UintN(256)), uint8(rnd.UintN(256))
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func decodeConfig(t *testing.T, data []byte) (image.Config, string) {
+	t.Helper()
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	return cfg, format
+}
+
+func Test_ImagePolicyFor(t *testing.T) {
+	t.Parallel()
+
+	openai := llmutils.ImagePolicyFor(llms.ProviderAzure)
+	require.NotNil(t, openai)
+	assert.Equal(t, 2048, openai.MaxWidth)
+	assert.True(t, openai.Allowed("image/gif"))
+
+	google := llmutils.ImagePolicyFor(llms.ProviderVertexAI)
+	require.NotNil(t, google)
+	assert.False(t, google.Allowed("image/gif"))
+
+	assert.NotNil(t, llmutils.ImagePolicyFor(llms.ProviderAnthropic))
+	assert.NotNil(t, llmutils.ImagePolicyFor(llms.ProviderBedrock))
+	assert.Nil(t, llmutils.ImagePolicyFor(llms.ProviderCohere))
+	assert.True(t, (&llmutils.ImagePolicy{}).Allowed("image/bmp"))
+}
+
+func Test_FitImage(t *testing.T) {
+	t.Parallel()
+
+	screenshot := encodePNG(t, testImage(t, 4000, 1000, 255, false))
+	transparent := encodePNG(t, testImage(t, 100, 50, 128, false))
+	var gifBuf bytes.Buffer
+	require.NoError(t, gif.Encode(&gifBuf, testImage(t, 64, 32, 255, false), nil))
+	noisy := encodePNG(t, testImage(t, 600, 400, 255, true))
+
+	t.Run("as is", func(t *testing.T) {
+		t.Parallel()
+		img := llms.BinaryContent{MIMEType: "image/png", Data: transparent}
+		res, err := llmutils.FitImage(img, llmutils.ImagePolicyFor(llms.ProviderOpenAI))
+		require.NoError(t, err)
+		assert.Equal(t, img, res)
+
+		res, err = llmutils.FitImage(img, nil)
+		require.NoError(t, err)
+		assert.Equal(t, img, res)
+
+		pdf := llms.BinaryContent{MIMEType: "application/pdf", Data: []byte("%PDF-1.7")}
+		res, err = llmutils.FitImage(pdf, llmutils.ImagePolicyFor(llms.ProviderOpenAI))
+		require.NoError(t, err)
+		assert.Equal(t, pdf, res)
+
+		// the dimensions of webp can not be checked
+		webp := llms.BinaryContent{MIMEType: "image/webp", Data: []byte("RIFF....WEBPVP8 ")}
+		res, err = llmutils.FitImage(webp, llmutils.ImagePolicyFor(llms.ProviderGoogleAI))
+		require.NoError(t, err)
+		assert.Equal(t, webp, res)
+	})
+
+	t.Run("downscale", func(t *testing.T) {
+		t.Parallel()
+		res, err := llmutils.FitImage(llms.BinaryContent{MIMEType: "image/png", Data: screenshot},
+			llmutils.ImagePolicyFor(llms.ProviderOpenAI))
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", res.MIMEType)
+		cfg, format := decodeConfig(t, res.Data)
+		assert.Equal(t, "png", format)
+		assert.Equal(t, 2048, cfg.Width)
+		assert.Equal(t, 512, cfg.Height)
+	})
+
+	t.Run("convert", func(t *testing.T) {
+		t.Parallel()
+		google := llmutils.ImagePolicyFor(llms.ProviderGoogleAI)
+
+		res, err := llmutils.FitImage(llms.BinaryContent{MIMEType: "image/gif", Data: gifBuf.Bytes()}, google)
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", res.MIMEType)
+		cfg, _ := decodeConfig(t, res.Data)
+		assert.Equal(t, 64, cfg.Width)
+
+		onlyJPEG := &llmutils.ImagePolicy{MIMETypes: []string{"image/jpeg"}}
+		res, err = llmutils.FitImage(llms.BinaryContent{MIMEType: "image/png", Data: transparent}, onlyJPEG)
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", res.MIMEType)
+		_, format := decodeConfig(t, res.Data)
+		assert.Equal(t, "jpeg", format)
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		t.Parallel()
+		policy := &llmutils.ImagePolicy{MaxBytes: 20 << 10, MIMETypes: []string{"image/jpeg"}}
+		res, err := llmutils.FitImage(llms.BinaryContent{MIMEType: "image/png", Data: noisy}, policy)
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", res.MIMEType)
+		assert.LessOrEqual(t, len(res.Data), policy.MaxBytes)
+		cfg, _ := decodeConfig(t, res.Data)
+		assert.Less(t, cfg.Width, 600)
+		assert.InDelta(t, 1.5, float64(cfg.Width)/float64(cfg.Height), 0.05, "aspect ratio is preserved")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := llmutils.FitImage(llms.BinaryContent{MIMEType: "image/bmp", Data: []byte("BM")},
+			llmutils.ImagePolicyFor(llms.ProviderOpenAI))
+		assert.EqualError(t, err, "unable to decode image/bmp image: image: unknown format")
+
+		_, err = llmutils.FitImage(llms.BinaryContent{MIMEType: "image/png", Data: transparent},
+			&llmutils.ImagePolicy{MIMETypes: []string{"image/webp"}})
+		assert.EqualError(t, err, "unable to convert image/png image: no supported image type")
+
+		_, err = llmutils.FitImage(llms.BinaryContent{MIMEType: "image/png", Data: transparent},
+			&llmutils.ImagePolicy{MaxBytes: 10})
+		assert.EqualError(t, err, "unable to fit image/png image into 10 bytes")
+	})
+}
+
+func Test_FitImages(t *testing.T) {
+	t.Parallel()
+
+	screenshot := llms.BinaryContent{MIMEType: "image/png", Data: encodePNG(t, testImage(t, 3000, 100, 255, false))}
+	small := llms.BinaryContent{MIMEType: "image/png", Data: encodePNG(t, testImage(t, 10, 10, 255, false))}
+	broken := llms.BinaryContent{MIMEType: "image/bmp", Data: []byte("BM")}
+
+	msgs := []llms.Message{
+		{Role: llms.RoleSystem, Parts: []llms.ContentPart{llms.TextPart("You are helpful.")}},
+		{Role: llms.RoleHuman, Parts: []llms.ContentPart{llms.TextPart("Compare."), screenshot, small, broken}},
+	}
+
+	res, count := llmutils.FitImages(msgs, llmutils.ImagePolicyFor(llms.ProviderOpenAI))
+	assert.Equal(t, 1, count)
+	require.Len(t, res, 2)
+	assert.Equal(t, msgs[0], res[0])
+	assert.Equal(t, small, res[1].Parts[2])
+	assert.Equal(t, broken, res[1].Parts[3])
+	cfg, _ := decodeConfig(t, res[1].Parts[1].(llms.BinaryContent).Data)
+	assert.Equal(t, 2048, cfg.Width)
+	// the original messages are not modified
+	assert.Equal(t, screenshot, msgs[1].Parts[1])
+
+	res, count = llmutils.FitImages(msgs[:1], llmutils.ImagePolicyFor(llms.ProviderOpenAI))
+	assert.Equal(t, 0, count)
+	assert.Equal(t, msgs[:1], res)
+
+	res, count = llmutils.FitImages(msgs, nil)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, msgs, res)
+}
@@ -0,0 +1,137 @@
+package llmutils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PostProcessor transforms the LLM response before the output parsing.
+type PostProcessor func(text string) string
+
+// PostProcess applies the processors to the text in order.
+func PostProcess(text string, processors ...PostProcessor) string {
+	for _, p := range processors {
+		text = p(text)
+	}
+	return text
+}
+
+// CodeBlock is the fenced code block of the markdown.
+type CodeBlock struct {
+	// Language is the info string of the fence, such as "json" or "go".
+	Language string
+	// Code is the content of the block.
+	Code string
+}
+
+// ExtractCodeBlocks returns the fenced code blocks of the markdown in order,
+// the unterminated block at the end of the text is returned as well.
+func ExtractCodeBlocks(text string) []CodeBlock {
+	var blocks []CodeBlock
+	var current *CodeBlock
+	var code []string
+	fence := ""
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current == nil {
+			if f := fenceOf(trimmed); f != "" {
+				fence = f
+				current = &CodeBlock{
+					Language: strings.TrimSpace(strings.TrimLeft(trimmed, f[:1])),
+				}
+				code = code[:0]
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			current.Code = strings.Join(code, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+			continue
+		}
+		code = append(code, line)
+	}
+	if current != nil {
+		current.Code = strings.Join(code, "\n")
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// fenceOf returns the fence of the opening line, ``` or ~~~ or longer, or empty
+func fenceOf(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return strings.Repeat(c, n)
+		}
+	}
+	return ""
+}
+
+// ExtractCodeBlock returns the PostProcessor that returns the content of the first code block
+// with the language, or of the first code block if the language is empty.
+// If there is no such block, the text is returned as is.
+func ExtractCodeBlock(language string) PostProcessor {
+	return func(text string) string {
+		for _, b := range ExtractCodeBlocks(text) {
+			if language == "" || strings.EqualFold(b.Language, language) {
+				return b.Code
+			}
+		}
+		return text
+	}
+}
+
+var (
+	reThinkingBlock = regexp.MustCompile(`(?is)<(think|thinking|reasoning|scratchpad)>.*?</(think|thinking|reasoning|scratchpad)>\s*`)
+	reThinkingClose = regexp.MustCompile(`(?is)^.*?</(think|thinking|reasoning|scratchpad)>\s*`)
+)
+
+// StripThinking removes the chain-of-thought blocks, such as <think>...</think>,
+// <thinking>, <reasoning> and <scratchpad>, from the LLM output.
+// The leading text before the dangling closing tag is removed as well,
+// as some models omit the opening tag.
+func StripThinking(text string) string {
+	text = reThinkingBlock.ReplaceAllString(text, "")
+	text = reThinkingClose.ReplaceAllString(text, "")
+	return strings.TrimSpace(text)
+}
+
+// NormalizeMarkdown normalizes the line endings, removes the trailing spaces,
+// collapses the consecutive blank lines and trims the text.
+// The content of the fenced code blocks is preserved.
+func NormalizeMarkdown(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	lines := strings.Split(text, "\n")
+	res := make([]string, 0, len(lines))
+	fence := ""
+	blank := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			res = append(res, line)
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if f := fenceOf(trimmed); f != "" {
+			fence = f
+		}
+
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		res = append(res, line)
+	}
+	return strings.TrimSpace(strings.Join(res, "\n"))
+}
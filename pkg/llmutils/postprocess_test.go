@@ -0,0 +1,55 @@
+package llmutils_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractCodeBlocks(t *testing.T) {
+	t.Parallel()
+
+	text := "Here you go:\n```json\n{\"a\":1}\n```\nand the code:\n~~~~go\nfunc main() {\n\t// ```\n}\n~~~~\n```\nunterminated"
+	assert.Equal(t, []llmutils.CodeBlock{
+		{Language: "json", Code: `{"a":1}`},
+		{Language: "go", Code: "func main() {\n\t// ```\n}"},
+		{Language: "", Code: "unterminated"},
+	}, llmutils.ExtractCodeBlocks(text))
+	assert.Empty(t, llmutils.ExtractCodeBlocks("no code"))
+
+	assert.Equal(t, "func main() {\n\t// ```\n}", llmutils.ExtractCodeBlock("GO")(text))
+	assert.Equal(t, `{"a":1}`, llmutils.ExtractCodeBlock("")(text))
+	assert.Equal(t, text, llmutils.ExtractCodeBlock("python")(text))
+}
+
+func TestStripThinking(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		in  string
+		exp string
+	}{
+		{in: "answer", exp: "answer"},
+		{in: "<think>\nlet me think\n</think>\n\nanswer", exp: "answer"},
+		{in: "<Thinking>a</Thinking>first <reasoning>b</reasoning>second", exp: "first second"},
+		{in: "the opening tag is missing</think>\nanswer", exp: "answer"},
+	}
+	for _, tc := range tcases {
+		assert.Equal(t, tc.exp, llmutils.StripThinking(tc.in), tc.in)
+	}
+}
+
+func TestNormalizeMarkdown(t *testing.T) {
+	t.Parallel()
+
+	in := "\r\n# Title  \r\n\r\n\r\n\r\ntext\t\n\n\n```\ncode  \n\n\n\nmore\n```\n\n\n- item  \n\n"
+	assert.Equal(t, "# Title\n\ntext\n\n```\ncode  \n\n\n\nmore\n```\n\n- item", llmutils.NormalizeMarkdown(in))
+
+	p := llmutils.PostProcess("<think>x</think>\n```json\n{\"a\":1}\n```",
+		llmutils.StripThinking,
+		llmutils.ExtractCodeBlock("json"),
+	)
+	assert.Equal(t, `{"a":1}`, p)
+	assert.Equal(t, "as is", llmutils.PostProcess("as is"))
+}
@@ -0,0 +1,299 @@
+package llmutils
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/invopop/jsonschema"
+)
+
+// DefaultMask replaces the secrets masked by MaskFixed.
+const DefaultMask = "[REDACTED]"
+
+// MaskFunc returns the masked value of the secret.
+type MaskFunc func(secret string) string
+
+// MaskFixed returns MaskFunc that replaces the secret with the mask.
+func MaskFixed(mask string) MaskFunc {
+	return func(string) string {
+		return mask
+	}
+}
+
+// MaskPartial returns MaskFunc that keeps the last keep characters of the secret,
+// for example "****cdef", to tell the secrets apart in the logs.
+// The secrets shorter than 4*keep characters are masked completely,
+// the masked values are returned as is.
+func MaskPartial(keep int) MaskFunc {
+	return func(secret string) string {
+		if strings.HasPrefix(secret, "****") {
+			return secret
+		}
+		if keep <= 0 || len(secret) < 4*keep {
+			return "****"
+		}
+		return "****" + secret[len(secret)-keep:]
+	}
+}
+
+// SecretPattern is a named pattern of the secret in the text.
+// If the Regexp has the "secret" named group, only the group is masked,
+// otherwise the whole match is masked.
+type SecretPattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// DefaultSecretPatterns are the patterns of the API keys and the credentials
+// of the supported providers.
+var DefaultSecretPatterns = []SecretPattern{
+	{
+		Name:   "authorization",
+		Regexp: regexp.MustCompile(`(?i)\b(?:proxy-)?authorization["']?\s*[:=]\s*["']?(?:(?:bearer|basic|token)\s+)?(?P<secret>[^\s"',;]+)`),
+	},
+	{
+		Name:   "bearer",
+		Regexp: regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/=-]{8,})`),
+	},
+	{
+		Name:   "api_key_param",
+		Regexp: regexp.MustCompile(`(?i)\b(?:x-api-key|api-key|api_key|apikey|x-goog-api-key|access_token|secret_access_key|client_secret|password)["']?\s*[:=]\s*["']?(?P<secret>[^\s"'&,;]+)`),
+	},
+	{
+		// OpenAI, Anthropic, DeepSeek and OpenRouter keys
+		Name:   "sk_key",
+		Regexp: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`),
+	},
+	{
+		Name:   "aws_access_key",
+		Regexp: regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`),
+	},
+	{
+		Name:   "google_api_key",
+		Regexp: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),
+	},
+	{
+		Name:   "groq_key",
+		Regexp: regexp.MustCompile(`\bgsk_[A-Za-z0-9]{20,}`),
+	},
+	{
+		Name:   "tavily_key",
+		Regexp: regexp.MustCompile(`\btvly-[A-Za-z0-9_-]{16,}`),
+	},
+}
+
+// SensitiveHeaders are the HTTP headers masked by Redactor.Headers.
+var SensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"X-Api-Key",
+	"Api-Key",
+	"X-Goog-Api-Key",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// DefaultRedactor masks the secrets with DefaultSecretPatterns in the debug logs of the providers.
+var DefaultRedactor = NewRedactor()
+
+// RedactOption configures the Redactor.
+type RedactOption func(*Redactor)
+
+// WithMask sets the mask of the secrets, by default MaskFixed(DefaultMask).
+func WithMask(fn MaskFunc) RedactOption {
+	return func(r *Redactor) {
+		r.mask = fn
+	}
+}
+
+// WithSecretPatterns adds the patterns to DefaultSecretPatterns.
+func WithSecretPatterns(patterns ...SecretPattern) RedactOption {
+	return func(r *Redactor) {
+		r.patterns = append(r.patterns, patterns...)
+	}
+}
+
+// WithToolSchema registers the parameters of the tool,
+// to mask the values of the properties marked with schema.SensitiveExtension
+// in the tool call arguments, see Redactor.ToolArguments.
+func WithToolSchema(name string, params *jsonschema.Schema) RedactOption {
+	return func(r *Redactor) {
+		r.tools[strings.ToLower(name)] = params
+	}
+}
+
+// Redactor masks the API keys, the authorization headers, and the sensitive tool arguments
+// in the text emitted by the logs, callbacks and transcripts.
+// The nil Redactor returns the values as is.
+type Redactor struct {
+	mask     MaskFunc
+	patterns []SecretPattern
+
+	lock  sync.RWMutex
+	tools map[string]*jsonschema.Schema
+}
+
+// NewRedactor returns a new Redactor with DefaultSecretPatterns.
+func NewRedactor(opts ...RedactOption) *Redactor {
+	r := &Redactor{
+		mask:     MaskFixed(DefaultMask),
+		patterns: append([]SecretPattern(nil), DefaultSecretPatterns...),
+		tools:    map[string]*jsonschema.Schema{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RegisterTool registers the parameters of the tool, see WithToolSchema.
+func (r *Redactor) RegisterTool(name string, params *jsonschema.Schema) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.tools[strings.ToLower(name)] = params
+}
+
+// String returns the text with the secrets masked.
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, p := range r.patterns {
+		s = r.replace(p.Regexp, s)
+	}
+	return s
+}
+
+func (r *Redactor) replace(re *regexp.Regexp, s string) string {
+	group := re.SubexpIndex("secret")
+	locs := re.FindAllStringSubmatchIndex(s, -1)
+	if len(locs) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, loc := range locs {
+		start, end := loc[0], loc[1]
+		if group > 0 && loc[2*group] >= 0 {
+			start, end = loc[2*group], loc[2*group+1]
+		}
+		if start < pos {
+			continue
+		}
+		b.WriteString(s[pos:start])
+		b.WriteString(r.mask(s[start:end]))
+		pos = end
+	}
+	b.WriteString(s[pos:])
+	return b.String()
+}
+
+// Arguments returns the JSON arguments with the values of the sensitive properties
+// of the params masked, and the secrets masked in the rest of the text.
+func (r *Redactor) Arguments(params *jsonschema.Schema, args string) string {
+	if r == nil {
+		return args
+	}
+	if redacted, ok := schema.RedactSensitive(params, []byte(args), r.mask); ok {
+		args = string(redacted)
+	}
+	return r.String(args)
+}
+
+// ToolArguments returns the arguments of the tool call with the sensitive values masked,
+// using the parameters of the tool registered with WithToolSchema or RegisterTool.
+func (r *Redactor) ToolArguments(toolName, args string) string {
+	if r == nil {
+		return args
+	}
+	r.lock.RLock()
+	params := r.tools[strings.ToLower(toolName)]
+	r.lock.RUnlock()
+	return r.Arguments(params, args)
+}
+
+// Headers returns the copy of the headers with SensitiveHeaders masked.
+func (r *Redactor) Headers(h http.Header) http.Header {
+	if r == nil || h == nil {
+		return h
+	}
+	res := h.Clone()
+	for _, name := range SensitiveHeaders {
+		values := res.Values(name)
+		for i, v := range values {
+			values[i] = r.mask(v)
+		}
+	}
+	return res
+}
+
+// Messages returns the copy of the messages with the secrets masked
+// in the text parts, the tool call arguments and the tool responses.
+func (r *Redactor) Messages(msgs []llms.Message) []llms.Message {
+	if r == nil || len(msgs) == 0 {
+		return msgs
+	}
+	res := make([]llms.Message, len(msgs))
+	for i, msg := range msgs {
+		res[i] = msg
+		res[i].Parts = make([]llms.ContentPart, len(msg.Parts))
+		for j, part := range msg.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				p.Text = r.String(p.Text)
+				part = p
+			case llms.ToolCall:
+				if p.FunctionCall != nil {
+					fc := *p.FunctionCall
+					fc.Arguments = r.ToolArguments(fc.Name, fc.Arguments)
+					p.FunctionCall = &fc
+				}
+				part = p
+			case llms.ToolCallResponse:
+				p.Content = r.String(p.Content)
+				part = p
+			}
+			res[i].Parts[j] = part
+		}
+	}
+	return res
+}
+
+// Choices returns the copy of the choices with the secrets masked
+// in the content, the reasoning and the tool call arguments.
+func (r *Redactor) Choices(choices []*llms.ContentChoice) []*llms.ContentChoice {
+	if r == nil || len(choices) == 0 {
+		return choices
+	}
+	res := make([]*llms.ContentChoice, 0, len(choices))
+	for _, c := range choices {
+		if c == nil {
+			continue
+		}
+		cc := *c
+		cc.Content = r.String(c.Content)
+		cc.ReasoningContent = r.String(c.ReasoningContent)
+		cc.ToolCalls = nil
+		if len(c.ToolCalls) > 0 {
+			cc.ToolCalls = make([]llms.ToolCall, len(c.ToolCalls))
+		}
+		for i, tc := range c.ToolCalls {
+			if tc.FunctionCall != nil {
+				fc := *tc.FunctionCall
+				fc.Arguments = r.ToolArguments(fc.Name, fc.Arguments)
+				tc.FunctionCall = &fc
+			}
+			cc.ToolCalls[i] = tc
+		}
+		res = append(res, &cc)
+	}
+	return res
+}
@@ -0,0 +1,154 @@
+package llmutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+)
+
+// ToolCallIDFormat describes the tool call IDs accepted by the provider.
+type ToolCallIDFormat struct {
+	// MaxLength is the max length of the ID, zero means no limit.
+	MaxLength int
+	// Pattern is the pattern of the valid ID, nil accepts any characters.
+	Pattern *regexp.Regexp
+	// Prefix is the prefix of the generated IDs.
+	Prefix string
+}
+
+// Valid returns true if the ID is accepted by the provider.
+func (f *ToolCallIDFormat) Valid(id string) bool {
+	if f.MaxLength > 0 && len(id) > f.MaxLength {
+		return false
+	}
+	return f.Pattern == nil || f.Pattern.MatchString(id)
+}
+
+// generatedIDHashLength is the max length of the hash in the generated IDs
+const generatedIDHashLength = 24
+
+var (
+	anthropicToolCallIDFormat = &ToolCallIDFormat{
+		MaxLength: 64,
+		Pattern:   regexp.MustCompile(`^[a-zA-Z0-9_-]+$`),
+		Prefix:    "toolu_",
+	}
+	openAIToolCallIDFormat = &ToolCallIDFormat{
+		MaxLength: 40,
+		Prefix:    "call_",
+	}
+)
+
+// ToolCallIDFormatFor returns the format of the tool call IDs accepted by the provider,
+// or nil if the provider accepts any IDs.
+func ToolCallIDFormatFor(provider llms.ProviderType) *ToolCallIDFormat {
+	switch provider {
+	case llms.ProviderAnthropic,
+		llms.ProviderAnthropicBedrock,
+		llms.ProviderBedrock:
+		return anthropicToolCallIDFormat
+	case llms.ProviderOpenAI,
+		llms.ProviderAzure,
+		llms.ProviderAzureAD:
+		return openAIToolCallIDFormat
+	}
+	return nil
+}
+
+// RemapToolCallIDs returns the history where the tool call IDs not accepted by the format,
+// for example the IDs of the conversation started with another provider,
+// are replaced in the tool calls and in the tool responses consistently.
+// The generated IDs are derived from the original IDs, so the same history is remapped
+// to the same IDs on every call, and the provider prompt cache is not invalidated.
+// The empty IDs are not remapped, as they can not be linked to the responses.
+//
+// Returns the history and the map of the original IDs to the generated IDs, or nil if no IDs were remapped.
+// The original messages are not modified, and returned as is if no remapping is needed.
+func RemapToolCallIDs(msgs []llms.Message, format *ToolCallIDFormat) ([]llms.Message, map[string]string) {
+	if format == nil || len(msgs) == 0 {
+		return msgs, nil
+	}
+
+	var invalid []string
+	used := map[string]bool{}
+	for _, m := range msgs {
+		for _, p := range m.Parts {
+			id := toolPartID(p)
+			if id == "" || used[id] {
+				continue
+			}
+			used[id] = true
+			if !format.Valid(id) {
+				invalid = append(invalid, id)
+			}
+		}
+	}
+	if len(invalid) == 0 {
+		return msgs, nil
+	}
+
+	remapped := make(map[string]string, len(invalid))
+	for _, id := range invalid {
+		newID := format.generateID(id, used)
+		used[newID] = true
+		remapped[id] = newID
+	}
+
+	res := make([]llms.Message, len(msgs))
+	for i, m := range msgs {
+		res[i] = m
+		var parts []llms.ContentPart
+		for j, p := range m.Parts {
+			newID, ok := remapped[toolPartID(p)]
+			if !ok {
+				continue
+			}
+			if parts == nil {
+				parts = append(make([]llms.ContentPart, 0, len(m.Parts)), m.Parts...)
+			}
+			switch pp := p.(type) {
+			case llms.ToolCall:
+				pp.ID = newID
+				parts[j] = pp
+			case llms.ToolCallResponse:
+				pp.ToolCallID = newID
+				parts[j] = pp
+			}
+		}
+		if parts != nil {
+			res[i] = withParts(m, parts)
+		}
+	}
+	return res, remapped
+}
+
+// generateID returns the ID derived from the original ID, that is not used yet
+func (f *ToolCallIDFormat) generateID(id string, used map[string]bool) string {
+	size := generatedIDHashLength
+	if f.MaxLength > 0 && f.MaxLength-len(f.Prefix) < size {
+		size = f.MaxLength - len(f.Prefix)
+	}
+	seed := id
+	for i := 1; ; i++ {
+		sum := sha256.Sum256([]byte(seed))
+		newID := f.Prefix + hex.EncodeToString(sum[:])[:size]
+		if !used[newID] {
+			return newID
+		}
+		seed = id + "#" + strconv.Itoa(i)
+	}
+}
+
+// toolPartID returns the tool call ID of the tool call or the tool response part
+func toolPartID(p llms.ContentPart) string {
+	switch pp := p.(type) {
+	case llms.ToolCall:
+		return pp.ID
+	case llms.ToolCallResponse:
+		return pp.ToolCallID
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package llmutils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ToolCallIDFormat(t *testing.T) {
+	t.Parallel()
+
+	anthropic := llmutils.ToolCallIDFormatFor(llms.ProviderAnthropic)
+	require.NotNil(t, anthropic)
+	assert.True(t, anthropic.Valid("toolu_01A09q90qw90lq917835lq9"))
+	assert.True(t, anthropic.Valid("call_abc-123"))
+	assert.False(t, anthropic.Valid("call.abc"))
+	assert.False(t, anthropic.Valid(""))
+	assert.False(t, anthropic.Valid(strings.Repeat("a", 65)))
+
+	openai := llmutils.ToolCallIDFormatFor(llms.ProviderOpenAI)
+	require.NotNil(t, openai)
+	assert.True(t, openai.Valid("toolu_01A09q90qw90lq917835lq9"))
+	assert.True(t, openai.Valid("call.abc"))
+	assert.False(t, openai.Valid(strings.Repeat("a", 41)))
+
+	assert.Nil(t, llmutils.ToolCallIDFormatFor(llms.ProviderGoogleAI))
+}
+
+func Test_RemapToolCallIDs(t *testing.T) {
+	t.Parallel()
+
+	longID := "call_" + strings.Repeat("x", 60)
+	human := llms.MessageFromTextParts(llms.RoleHuman, "hi")
+	msgs := []llms.Message{
+		human,
+		toolCallMsg("functions.search:0", "toolu_valid"),
+		toolResponseMsg("functions.search:0"),
+		toolResponseMsg("toolu_valid"),
+		toolCallMsg(longID),
+		toolResponseMsg(longID),
+	}
+	orig := describe(msgs)
+
+	res, remapped := llmutils.RemapToolCallIDs(msgs, llmutils.ToolCallIDFormatFor(llms.ProviderAnthropic))
+	require.Len(t, remapped, 2)
+	id1 := remapped["functions.search:0"]
+	id2 := remapped[longID]
+	assert.True(t, strings.HasPrefix(id1, "toolu_"))
+	assert.True(t, strings.HasPrefix(id2, "toolu_"))
+	assert.NotEqual(t, id1, id2)
+
+	assert.Equal(t, []string{
+		"human:hi",
+		"call:" + id1, "call:toolu_valid",
+		"resp:" + id1 + ":result functions.search:0",
+		"resp:toolu_valid:result toolu_valid",
+		"call:" + id2,
+		"resp:" + id2 + ":result " + longID,
+	}, describe(res))
+	// the original messages are not modified
+	assert.Equal(t, orig, describe(msgs))
+	// the links are kept
+	assert.NoError(t, llmutils.ValidateHistory(res))
+
+	// the same history is remapped to the same IDs
+	_, again := llmutils.RemapToolCallIDs(msgs, llmutils.ToolCallIDFormatFor(llms.ProviderAnthropic))
+	assert.Equal(t, remapped, again)
+
+	// the valid IDs are not remapped
+	res, remapped = llmutils.RemapToolCallIDs(msgs, llmutils.ToolCallIDFormatFor(llms.ProviderGoogleAI))
+	assert.Nil(t, remapped)
+	assert.Equal(t, msgs, res)
+
+	// the long IDs are remapped for OpenAI
+	res, remapped = llmutils.RemapToolCallIDs(msgs, llmutils.ToolCallIDFormatFor(llms.ProviderOpenAI))
+	require.Len(t, remapped, 1)
+	assert.True(t, strings.HasPrefix(remapped[longID], "call_"))
+	assert.LessOrEqual(t, len(remapped[longID]), 40)
+	assert.NoError(t, llmutils.ValidateHistory(res))
+
+	// the generated ID fits the max length
+	format := &llmutils.ToolCallIDFormat{MaxLength: 2}
+	res, remapped = llmutils.RemapToolCallIDs([]llms.Message{
+		toolCallMsg("abc"), toolResponseMsg("abc"),
+	}, format)
+	require.Len(t, remapped, 1)
+	assert.Len(t, remapped["abc"], 2)
+	assert.NoError(t, llmutils.ValidateHistory(res))
+
+	// the report of CompactHistory
+	_, report := llmutils.CompactHistory(msgs, &llmutils.CompactOptions{Provider: llms.ProviderBedrock})
+	assert.Equal(t, 2, report.RemappedToolCallIDs)
+	assert.True(t, report.Changed())
+}
@@ -16,6 +16,7 @@ import (
 // ass LLM can reply like,
 // `Here you go: {json}`
 func CleanJSON(bs []byte) []byte {
+	bs = BytesStripFences(bs)
 	trimmedPrefix := trimPrefixBeforeJSON(bs)
 	trimmedJSON := trimPostfixAfterJSON(trimmedPrefix)
 	return trimmedJSON
@@ -99,6 +100,35 @@ func BytesTrimBackticks(bs []byte) []byte {
 	return bytes.TrimSpace(result)
 }
 
+// BytesStripFences returns the content of the markdown code block,
+// if the fence precedes the JSON, for example "Here you go:\n```json\n{json}\n```\nDone".
+// The backticks inside the JSON strings are not treated as the fences,
+// as the closing fence must start on a new line, and JSON strings can not contain new lines.
+func BytesStripFences(bs []byte) []byte {
+	start := bytes.Index(bs, backtick)
+	if start == -1 {
+		return bs
+	}
+	if first := bytes.IndexAny(bs, "{["); first != -1 && first < start {
+		return bs
+	}
+
+	// skip the language of the fence
+	content := bs[start+len(backtick):]
+	if nl := bytes.IndexByte(content, '\n'); nl != -1 {
+		content = content[nl+1:]
+	} else {
+		content = bytes.TrimLeft(content, "`")
+	}
+
+	if end := bytes.Index(content, []byte("\n```")); end != -1 {
+		content = content[:end]
+	} else {
+		content = bytes.TrimSuffix(bytes.TrimSpace(content), backtick)
+	}
+	return bytes.TrimSpace(content)
+}
+
 // StripComments removes <!--  --> comments from the LLM output
 func StripComments(text string) string {
 	// Remove the <!--
@@ -219,6 +249,8 @@ func getMessageRole(m llms.Message) string {
 		role = "AI"
 	case llms.RoleSystem:
 		role = "System"
+	case llms.RoleDeveloper:
+		role = "Developer"
 	case llms.RoleGeneric:
 		role = "Generic"
 	case llms.RoleTool:
@@ -250,6 +282,8 @@ func CountMessagesContentSize(msgs []llms.Message) uint64 {
 			case llms.BinaryContent:
 				size += uint64(len(pp.MIMEType))
 				size += uint64(len(pp.Data))
+			case llms.DocumentContent:
+				size += uint64(pp.ContentLength())
 			case llms.ToolCall:
 				size += uint64(len(pp.ID))
 				size += uint64(len(pp.Type))
@@ -26,6 +26,32 @@ func Test_CleanJSON(t *testing.T) {
 	assert.Equal(t, resp, string(llmutils.CleanJSON([]byte(resp))))
 }
 
+func Test_StripFences(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		name string
+		in   string
+		exp  string
+	}{
+		{name: "no fence", in: `{"a":1}`, exp: `{"a":1}`},
+		{name: "json fence", in: "```json\n{\"a\":1}\n```", exp: `{"a":1}`},
+		{name: "fence without language", in: "```\n[1,2]\n```", exp: `[1,2]`},
+		{name: "single line", in: "```{\"a\":1}```", exp: `{"a":1}`},
+		{name: "unclosed", in: "```json\n{\"a\":1}", exp: `{"a":1}`},
+		{name: "text around", in: "Here you go:\n```json\n{\"a\":1}\n```\nSee [docs] for details {1}.", exp: `{"a":1}`},
+		{name: "backticks in string", in: "{\"content\":\"```go\\nfmt.Println()\\n```\"}", exp: "{\"content\":\"```go\\nfmt.Println()\\n```\"}"},
+		{name: "two blocks", in: "```json\n{\"a\":1}\n```\nand\n```json\n{\"b\":2}\n```", exp: `{"a":1}`},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.exp, string(llmutils.BytesStripFences([]byte(tc.in))))
+			assert.Equal(t, tc.exp, string(llmutils.CleanJSON([]byte(tc.in))))
+		})
+	}
+}
+
 func Test_TrimBackticks(t *testing.T) {
 	expected := "{\"city\": \"Paris\", \"country\": \"France\"}"
 
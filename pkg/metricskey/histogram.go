@@ -0,0 +1,227 @@
+package metricskey
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/effective-security/metrics"
+)
+
+// Default buckets of the histograms, the latency is in milliseconds
+// as reported by MeasureSince.
+var (
+	LatencyBuckets    = []float64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+	SizeBuckets       = []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576}
+	IterationsBuckets = []float64{1, 2, 3, 5, 8, 13, 21, 34}
+)
+
+// Histogram emits the samples of the metric, and aggregates them in-process by the tags,
+// so the snapshots can be read programmatically, for example by the orchestrators
+// to disable the slow tools.
+// It is safe for concurrent use.
+type Histogram struct {
+	desc    *metrics.Describe
+	buckets []float64
+
+	lock   sync.RWMutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	tags   []string
+	count  uint64
+	sum    float64
+	min    float64
+	max    float64
+	counts []uint64
+}
+
+// NewHistogram returns a new Histogram of the metric with the sorted upper bounds of the buckets.
+func NewHistogram(desc *metrics.Describe, buckets []float64) *Histogram {
+	return &Histogram{
+		desc:    desc,
+		buckets: buckets,
+		series:  map[string]*histogramSeries{},
+	}
+}
+
+// Describe returns the metric of the histogram.
+func (h *Histogram) Describe() *metrics.Describe {
+	return h.desc
+}
+
+// Observe emits the sample and records the value with the tags,
+// the tags are in the order of RequiredTags of the metric.
+func (h *Histogram) Observe(val float64, tags ...string) {
+	h.desc.AddSample(val, tags...)
+	h.record(val, tags)
+}
+
+// ObserveSince emits the duration since start and records it in milliseconds.
+func (h *Histogram) ObserveSince(start time.Time, tags ...string) {
+	h.desc.MeasureSince(start, tags...)
+	h.record(float64(time.Since(start))/float64(time.Millisecond), tags)
+}
+
+func (h *Histogram) record(val float64, tags []string) {
+	key := strings.Join(tags, "\x00")
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s := h.series[key]
+	if s == nil {
+		s = &histogramSeries{
+			tags:   append([]string(nil), tags...),
+			min:    val,
+			max:    val,
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+	s.count++
+	s.sum += val
+	s.min = min(s.min, val)
+	s.max = max(s.max, val)
+	if i := sort.SearchFloat64s(h.buckets, val); i < len(h.buckets) {
+		s.counts[i]++
+	}
+}
+
+// Get returns the snapshot of the series with the tags, or nil if nothing is recorded.
+func (h *Histogram) Get(tags ...string) *HistogramSnapshot {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	s := h.series[strings.Join(tags, "\x00")]
+	if s == nil {
+		return nil
+	}
+	return h.snapshot(s)
+}
+
+// Snapshot returns the snapshots of all series, sorted by the tags.
+func (h *Histogram) Snapshot() []*HistogramSnapshot {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	res := make([]*HistogramSnapshot, 0, len(h.series))
+	for _, s := range h.series {
+		res = append(res, h.snapshot(s))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return strings.Join(res[i].tagValues, "\x00") < strings.Join(res[j].tagValues, "\x00")
+	})
+	return res
+}
+
+// Reset drops the recorded series.
+func (h *Histogram) Reset() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.series = map[string]*histogramSeries{}
+}
+
+func (h *Histogram) snapshot(s *histogramSeries) *HistogramSnapshot {
+	snap := &HistogramSnapshot{
+		Name:      h.desc.Name,
+		Tags:      make(map[string]string, len(s.tags)),
+		Count:     s.count,
+		Sum:       s.sum,
+		Min:       s.min,
+		Max:       s.max,
+		Buckets:   make([]Bucket, len(h.buckets)),
+		tagValues: s.tags,
+	}
+	for i, tag := range s.tags {
+		if i < len(h.desc.RequiredTags) {
+			snap.Tags[h.desc.RequiredTags[i]] = tag
+		}
+	}
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += s.counts[i]
+		snap.Buckets[i] = Bucket{UpperBound: bound, Count: cumulative}
+	}
+	return snap
+}
+
+// Bucket is the cumulative count of the values less or equal to UpperBound.
+type Bucket struct {
+	UpperBound float64 `json:"upper_bound"`
+	Count      uint64  `json:"count"`
+}
+
+// HistogramSnapshot is the aggregated values of the histogram series.
+type HistogramSnapshot struct {
+	// Name is the name of the metric.
+	Name string `json:"name"`
+	// Tags are the tags of the series by the name of the tag.
+	Tags    map[string]string `json:"tags"`
+	Count   uint64            `json:"count"`
+	Sum     float64           `json:"sum"`
+	Min     float64           `json:"min"`
+	Max     float64           `json:"max"`
+	Buckets []Bucket          `json:"buckets"`
+
+	tagValues []string
+}
+
+// Mean returns the average of the values.
+func (s *HistogramSnapshot) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// Quantile returns the estimate of the q quantile, 0 < q <= 1,
+// interpolated within the bucket and capped by Max.
+func (s *HistogramSnapshot) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := q * float64(s.Count)
+	lower, below := s.Min, uint64(0)
+	for _, b := range s.Buckets {
+		if b.Count > below && float64(b.Count) >= rank {
+			upper := math.Min(b.UpperBound, s.Max)
+			return lower + (upper-lower)*(rank-float64(below))/float64(b.Count-below)
+		}
+		lower, below = math.Max(b.UpperBound, s.Min), b.Count
+	}
+	return s.Max
+}
+
+// Histograms of the repo, see Snapshot
+var (
+	// ToolLatency is the histogram of the tool call duration, see PerfToolCall.
+	ToolLatency = NewHistogram(&PerfToolCall, LatencyBuckets)
+	// ToolOutputSize is the histogram of the tool output size in bytes, see StatsToolOutputSize.
+	ToolOutputSize = NewHistogram(&StatsToolOutputSize, SizeBuckets)
+	// LLMCallLatency is the histogram of the LLM turn duration, see PerfLLMCall.
+	LLMCallLatency = NewHistogram(&PerfLLMCall, LatencyBuckets)
+	// AssistantLoopIterations is the histogram of the LLM calls per assistant run,
+	// see StatsAssistantLoopIterations.
+	AssistantLoopIterations = NewHistogram(&StatsAssistantLoopIterations, IterationsBuckets)
+)
+
+// Histograms returns slice of histograms from this repo
+var Histograms = []*Histogram{
+	AssistantLoopIterations,
+	LLMCallLatency,
+	ToolLatency,
+	ToolOutputSize,
+}
+
+// Snapshot returns the snapshots of all histograms of this repo.
+func Snapshot() []*HistogramSnapshot {
+	var res []*HistogramSnapshot
+	for _, h := range Histograms {
+		res = append(res, h.Snapshot()...)
+	}
+	return res
+}
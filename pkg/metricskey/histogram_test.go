@@ -0,0 +1,63 @@
+package metricskey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/effective-security/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram(t *testing.T) {
+	t.Parallel()
+
+	h := NewHistogram(&metrics.Describe{
+		Type:         metrics.TypeSample,
+		Name:         "test_histogram",
+		Help:         "test_histogram provides test values",
+		RequiredTags: []string{"tool", "model", "org"},
+	}, []float64{10, 100, 1000})
+
+	assert.Nil(t, h.Get("search", "gpt-4o", "org1"))
+	assert.Empty(t, h.Snapshot())
+
+	for _, v := range []float64{5, 20, 40, 60, 80, 200, 5000} {
+		h.Observe(v, "search", "gpt-4o", "org1")
+	}
+	h.ObserveSince(time.Now().Add(-time.Second), "fetch", "gpt-4o", "org1")
+
+	snap := h.Get("search", "gpt-4o", "org1")
+	require.NotNil(t, snap)
+	assert.Equal(t, "test_histogram", snap.Name)
+	assert.Equal(t, map[string]string{"tool": "search", "model": "gpt-4o", "org": "org1"}, snap.Tags)
+	assert.Equal(t, uint64(7), snap.Count)
+	assert.Equal(t, 5405.0, snap.Sum)
+	assert.Equal(t, 5.0, snap.Min)
+	assert.Equal(t, 5000.0, snap.Max)
+	assert.Equal(t, []Bucket{{10, 1}, {100, 5}, {1000, 6}}, snap.Buckets)
+	assert.InDelta(t, 772.14, snap.Mean(), 0.01)
+
+	assert.Equal(t, 5.0, snap.Quantile(0))
+	assert.InDelta(t, 66.25, snap.Quantile(3.5/7), 0.01)
+	assert.InDelta(t, 1000, snap.Quantile(6.0/7), 0.01)
+	assert.Equal(t, 5000.0, snap.Quantile(1))
+
+	all := h.Snapshot()
+	require.Len(t, all, 2)
+	assert.Equal(t, "fetch", all[0].Tags["tool"])
+	assert.GreaterOrEqual(t, all[0].Min, 1000.0)
+	assert.Equal(t, "search", all[1].Tags["tool"])
+
+	h.Reset()
+	assert.Empty(t, h.Snapshot())
+}
+
+func TestHistograms(t *testing.T) {
+	t.Parallel()
+
+	for _, h := range Histograms {
+		assert.Contains(t, Metrics, h.Describe())
+		assert.Equal(t, metrics.TypeSample, h.Describe().Type)
+	}
+}
@@ -82,6 +82,20 @@ var (
 		RequiredTags: []string{"agent", "model", "org"},
 	}
 
+	StatsAssistantPromptVariantSucceeded = metrics.Describe{
+		Type:         metrics.TypeCounter,
+		Name:         "stats_assistant_prompt_variant_succeeded",
+		Help:         "stats_assistant_prompt_variant_succeeded provides total assistant calls succeeded per prompt variant",
+		RequiredTags: []string{"agent", "prompt", "variant", "org"},
+	}
+
+	StatsAssistantPromptVariantFailed = metrics.Describe{
+		Type:         metrics.TypeCounter,
+		Name:         "stats_assistant_prompt_variant_failed",
+		Help:         "stats_assistant_prompt_variant_failed provides total assistant calls failed per prompt variant",
+		RequiredTags: []string{"agent", "prompt", "variant", "org"},
+	}
+
 	StatsAssistantCallsRetried = metrics.Describe{
 		Type:         metrics.TypeCounter,
 		Name:         "stats_assistant_calls_retried",
@@ -103,6 +117,27 @@ var (
 		RequiredTags: []string{"tool", "model", "org"},
 	}
 
+	StatsToolCallsCancelled = metrics.Describe{
+		Type:         metrics.TypeCounter,
+		Name:         "stats_tool_calls_cancelled",
+		Help:         "stats_tool_calls_cancelled provides total tool calls cancelled",
+		RequiredTags: []string{"tool", "model", "org"},
+	}
+
+	StatsToolCallsRetried = metrics.Describe{
+		Type:         metrics.TypeCounter,
+		Name:         "stats_tool_calls_retried",
+		Help:         "stats_tool_calls_retried provides total tool calls retried on the transient errors",
+		RequiredTags: []string{"tool", "model", "org"},
+	}
+
+	StatsToolCallsDeduped = metrics.Describe{
+		Type:         metrics.TypeCounter,
+		Name:         "stats_tool_calls_deduped",
+		Help:         "stats_tool_calls_deduped provides total tool calls returned from the dedupe store of the repeated runs",
+		RequiredTags: []string{"tool", "model", "org"},
+	}
+
 	StatsToolCallsNotFound = metrics.Describe{
 		Type:         metrics.TypeCounter,
 		Name:         "stats_tool_calls_not_found",
@@ -110,6 +145,20 @@ var (
 		RequiredTags: []string{"tool", "model", "org"},
 	}
 
+	StatsToolOutputSize = metrics.Describe{
+		Type:         metrics.TypeSample,
+		Name:         "stats_tool_output_size",
+		Help:         "stats_tool_output_size provides size of tool output in bytes",
+		RequiredTags: []string{"tool", "model", "org"},
+	}
+
+	StatsAssistantLoopIterations = metrics.Describe{
+		Type:         metrics.TypeSample,
+		Name:         "stats_assistant_loop_iterations",
+		Help:         "stats_assistant_loop_iterations provides number of LLM calls per assistant run",
+		RequiredTags: []string{"agent", "model", "org"},
+	}
+
 	StatsAssistantLLMParseErrors = metrics.Describe{
 		Type:         metrics.TypeCounter,
 		Name:         "stats_assistant_llm_parse_errors",
@@ -127,6 +176,13 @@ var (
 		RequiredTags: []string{"agent", "model", "org"},
 	}
 
+	PerfLLMCall = metrics.Describe{
+		Type:         metrics.TypeSample,
+		Name:         "perf_llm_call",
+		Help:         "perf_llm_call provides duration of LLM call in assistant loop",
+		RequiredTags: []string{"agent", "model", "org"},
+	}
+
 	PerfToolCall = metrics.Describe{
 		Type:         metrics.TypeSample,
 		Name:         "perf_tool_call",
@@ -139,11 +195,15 @@ var (
 // keep sorted by name
 var Metrics = []*metrics.Describe{
 	&PerfAssistantCall,
+	&PerfLLMCall,
 	&PerfToolCall,
 	&StatsAssistantCallsFailed,
 	&StatsAssistantCallsRetried,
 	&StatsAssistantCallsSucceeded,
 	&StatsAssistantLLMParseErrors,
+	&StatsAssistantLoopIterations,
+	&StatsAssistantPromptVariantFailed,
+	&StatsAssistantPromptVariantSucceeded,
 	&StatsLLMBytesReceived,
 	&StatsLLMBytesSent,
 	&StatsLLMBytesTotal,
@@ -153,7 +213,11 @@ var Metrics = []*metrics.Describe{
 	&StatsLLMMessagesSent,
 	&StatsLLMOutputTokens,
 	&StatsLLMTotalTokens,
+	&StatsToolCallsCancelled,
+	&StatsToolCallsDeduped,
 	&StatsToolCallsFailed,
 	&StatsToolCallsNotFound,
+	&StatsToolCallsRetried,
 	&StatsToolCallsSucceeded,
+	&StatsToolOutputSize,
 }
@@ -12,11 +12,15 @@ func TestMetricsDefinitions(t *testing.T) {
 	// Test that all metrics have valid names and help text
 	allMetrics := []*metrics.Describe{
 		&PerfAssistantCall,
+		&PerfLLMCall,
 		&PerfToolCall,
 		&StatsAssistantCallsFailed,
 		&StatsAssistantCallsRetried,
 		&StatsAssistantCallsSucceeded,
 		&StatsAssistantLLMParseErrors,
+		&StatsAssistantLoopIterations,
+		&StatsAssistantPromptVariantFailed,
+		&StatsAssistantPromptVariantSucceeded,
 		&StatsLLMBytesReceived,
 		&StatsLLMBytesSent,
 		&StatsLLMBytesTotal,
@@ -26,9 +30,13 @@ func TestMetricsDefinitions(t *testing.T) {
 		&StatsLLMCachedWriteTokens,
 		&StatsLLMCachedReadTokens,
 		&StatsLLMTotalTokens,
+		&StatsToolCallsCancelled,
+		&StatsToolCallsDeduped,
 		&StatsToolCallsFailed,
 		&StatsToolCallsNotFound,
+		&StatsToolCallsRetried,
 		&StatsToolCallsSucceeded,
+		&StatsToolOutputSize,
 	}
 
 	for _, m := range allMetrics {
@@ -74,6 +82,10 @@ func TestMetricsDefinitions(t *testing.T) {
 			&StatsToolCallsSucceeded,
 			&StatsToolCallsFailed,
 			&StatsToolCallsNotFound,
+			&StatsToolCallsCancelled,
+			&StatsToolCallsRetried,
+			&StatsToolCallsDeduped,
+			&StatsToolOutputSize,
 		}
 		for _, m := range toolMetrics {
 			assert.Contains(t, m.RequiredTags, "tool", "Tool metric should have tool tag: %s", m.Name)
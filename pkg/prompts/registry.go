@@ -0,0 +1,141 @@
+package prompts
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ErrPromptNotFound is returned when the prompt or its version is not registered.
+var ErrPromptNotFound = errors.New("prompt not found")
+
+// Variant is the version of the named prompt.
+type Variant struct {
+	// Name is the name of the prompt.
+	Name string `json:"name" yaml:"name"`
+	// Version is the version of the prompt.
+	Version string `json:"version" yaml:"version"`
+	// Weight is the share of the runs in the experiment,
+	// zero weight excludes the version from the bucketing.
+	Weight int `json:"weight,omitempty" yaml:"weight,omitempty"`
+	// Prompt is the prompt template.
+	Prompt FormatPrompter `json:"-" yaml:"-"`
+}
+
+// Registry stores the named and versioned prompts,
+// and selects the version for the prompt experiments.
+// It is safe for concurrent use.
+type Registry struct {
+	lock     sync.RWMutex
+	variants map[string][]*Variant
+}
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		variants: make(map[string][]*Variant),
+	}
+}
+
+// Register adds the version of the prompt, replacing the existing one with the same version.
+// The weight is the share of the runs selected by Select.
+func (r *Registry) Register(name, version string, prompt FormatPrompter, weight int) error {
+	if name == "" || version == "" {
+		return errors.New("prompt name and version are required")
+	}
+	if prompt == nil {
+		return errors.Newf("prompt %s@%s: template is required", name, version)
+	}
+	if weight < 0 {
+		return errors.Newf("prompt %s@%s: weight must not be negative", name, version)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	v := &Variant{Name: name, Version: version, Weight: weight, Prompt: prompt}
+	list := r.variants[name]
+	for i, existing := range list {
+		if existing.Version == version {
+			list[i] = v
+			return nil
+		}
+	}
+	r.variants[name] = append(list, v)
+	return nil
+}
+
+// Get returns the version of the prompt, or ErrPromptNotFound.
+func (r *Registry) Get(name, version string) (*Variant, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, v := range r.variants[name] {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, errors.Wrapf(ErrPromptNotFound, "%s@%s", name, version)
+}
+
+// Versions returns the registered versions of the prompt, in the registration order.
+func (r *Registry) Versions(name string) []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var versions []string
+	for _, v := range r.variants[name] {
+		versions = append(versions, v.Version)
+	}
+	return versions
+}
+
+// Select returns the version of the prompt for the bucketing key, such as chat ID,
+// proportionally to the weights of the versions.
+// The versions are selected by the weighted rendezvous hashing,
+// so the same key is deterministically assigned to the same version,
+// and adding a version moves the keys only to the new version.
+func (r *Registry) Select(name, key string) (*Variant, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var selected *Variant
+	best := 0.0
+	for _, v := range r.variants[name] {
+		if v.Weight == 0 {
+			continue
+		}
+		if score := variantScore(v, key); selected == nil || score > best {
+			selected, best = v, score
+		}
+	}
+	if selected == nil {
+		return nil, errors.Wrapf(ErrPromptNotFound, "%s: no weighted versions", name)
+	}
+	return selected, nil
+}
+
+// variantScore returns the weighted score of the variant for the key,
+// the score depends only on the variant and the key.
+func variantScore(v *Variant, key string) float64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(v.Name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(v.Version))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+
+	// the splitmix64 finalizer spreads the FNV bits
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	// uniform in (0, 1)
+	u := (float64(x>>11) + 0.5) / (1 << 53)
+	return -float64(v.Weight) / math.Log(u)
+}
@@ -0,0 +1,81 @@
+package prompts
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	require.NoError(t, r.Register("support", "v2", NewPromptTemplate("You are v2.", nil), 1))
+	require.NoError(t, r.Register("support", "v1", NewPromptTemplate("You are v1.", nil), 3))
+	require.NoError(t, r.Register("support", "v3", NewPromptTemplate("You are v3.", nil), 0))
+	assert.EqualError(t, r.Register("", "v1", NewPromptTemplate("", nil), 1), "prompt name and version are required")
+	assert.EqualError(t, r.Register("support", "v4", nil, 1), "prompt support@v4: template is required")
+	assert.EqualError(t, r.Register("support", "v4", NewPromptTemplate("", nil), -1), "prompt support@v4: weight must not be negative")
+
+	assert.Equal(t, []string{"v2", "v1", "v3"}, r.Versions("support"))
+
+	v, err := r.Get("support", "v3")
+	require.NoError(t, err)
+	assert.Equal(t, "v3", v.Version)
+	pv, err := v.Prompt.FormatPrompt(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "You are v3.", pv.String())
+
+	_, err = r.Get("support", "v5")
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+	_, err = r.Select("unknown", "chat1")
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+
+	counts := map[string]int{}
+	for i := range 1000 {
+		key := fmt.Sprintf("chat%d", i)
+		v, err := r.Select("support", key)
+		require.NoError(t, err)
+		counts[v.Version]++
+
+		// deterministic
+		v2, err := r.Select("support", key)
+		require.NoError(t, err)
+		assert.Equal(t, v.Version, v2.Version)
+	}
+	assert.Zero(t, counts["v3"])
+	assert.InDelta(t, 750, counts["v1"], 60)
+	assert.InDelta(t, 250, counts["v2"], 60)
+
+	// the new version takes the keys only from the existing versions
+	selected := map[string]string{}
+	for i := range 1000 {
+		key := fmt.Sprintf("chat%d", i)
+		v, err := r.Select("support", key)
+		require.NoError(t, err)
+		selected[key] = v.Version
+	}
+	require.NoError(t, r.Register("support", "v4", NewPromptTemplate("You are v4.", nil), 1))
+	moved := 0
+	for key, version := range selected {
+		v, err := r.Select("support", key)
+		require.NoError(t, err)
+		if v.Version != version {
+			assert.Equal(t, "v4", v.Version)
+			moved++
+		}
+	}
+	assert.InDelta(t, 200, moved, 60)
+	assert.Equal(t, []string{"v2", "v1", "v3", "v4"}, r.Versions("support"))
+
+	// replace the version
+	require.NoError(t, r.Register("support", "v1", NewPromptTemplate("You are v1.1", nil), 0))
+	require.NoError(t, r.Register("support", "v4", NewPromptTemplate("You are v4.", nil), 0))
+	for i := range 10 {
+		v, err := r.Select("support", fmt.Sprintf("chat%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, "v2", v.Version)
+	}
+}
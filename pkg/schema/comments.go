@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+)
+
+var (
+	comments   = make(map[string]string)
+	commentsMu sync.RWMutex
+)
+
+// RegisterComments registers the doc comments of the types and the fields,
+// used as the descriptions in the schema, unless the description is provided in the tags.
+// The keys are the fully qualified names, like "github.com/org/pkg.Type" and "github.com/org/pkg.Type.Field".
+// The comments are generated by cmd/schemadoc, and registered in the init function of the package.
+func RegisterComments(m map[string]string) {
+	commentsMu.Lock()
+	maps.Copy(comments, m)
+	commentsMu.Unlock()
+
+	// the schemas are rebuilt with the comments
+	cacheMu.Lock()
+	clear(cache)
+	cacheMu.Unlock()
+}
+
+// LookupComment returns the description of the type, or of the field of the struct type,
+// to be used as jsonschema.Reflector.LookupComment.
+// The field description is taken from the description or comment tag,
+// otherwise from the registered doc comments, see RegisterComments.
+func LookupComment(t reflect.Type, field string) string {
+	if field != "" && t.Kind() == reflect.Struct {
+		if f, ok := t.FieldByName(field); ok {
+			for _, tag := range []string{"description", "comment"} {
+				if desc := f.Tag.Get(tag); desc != "" {
+					return desc
+				}
+			}
+		}
+	}
+
+	name := t.PkgPath() + "." + t.Name()
+	if field != "" {
+		name += "." + field
+	}
+	commentsMu.RLock()
+	defer commentsMu.RUnlock()
+	return comments[name]
+}
+
+// AddTagExamples adds the examples from the example tags of the struct fields to the properties,
+// unless the examples are provided in the jsonschema tag.
+// The example is parsed as JSON for the non-string properties, for example `example:"[1,2]"`.
+func AddTagExamples(t reflect.Type, s *jsonschema.Schema) {
+	addTagExamples(t, s, make(map[reflect.Type]bool))
+}
+
+func addTagExamples(t reflect.Type, s *jsonschema.Schema, seen map[reflect.Type]bool) {
+	if s == nil {
+		return
+	}
+	if len(s.OneOf) > 0 && s.Type == "" {
+		// the nullable property
+		s = s.OneOf[0]
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		addTagExamples(t.Elem(), s, seen)
+		return
+	case reflect.Slice, reflect.Array:
+		addTagExamples(t.Elem(), s.Items, seen)
+		return
+	case reflect.Map:
+		addTagExamples(t.Elem(), s.AdditionalProperties, seen)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	if seen[t] || s.Properties == nil {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" {
+			// the embedded struct is expanded
+			addTagExamples(f.Type, s, seen)
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop, ok := s.Properties.Get(name)
+		if !ok {
+			continue
+		}
+		if ex, ok := f.Tag.Lookup("example"); ok && len(prop.Examples) == 0 {
+			prop.Examples = []any{exampleValue(prop, ex)}
+		}
+		addTagExamples(f.Type, prop, seen)
+	}
+}
+
+// exampleValue returns the example as JSON value for the non-string properties
+func exampleValue(s *jsonschema.Schema, ex string) any {
+	if s.Type != "string" {
+		var v any
+		if err := json.Unmarshal([]byte(ex), &v); err == nil {
+			return v
+		}
+	}
+	return ex
+}
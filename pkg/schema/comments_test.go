@@ -0,0 +1,71 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lineItem struct {
+	SKU      string  `json:"sku" example:"A-100"`
+	Quantity int     `json:"quantity" example:"2"`
+	Price    float64 `json:"price"`
+}
+
+// orderRequest creates the order.
+type orderRequest struct {
+	Customer string     `json:"customer" description:"Customer name, as in the contract" example:"Acme, Inc."`
+	Region   string     `json:"region" comment:"Sales region"`
+	Tags     []string   `json:"tags,omitempty" example:"[\"new\",\"vip\"]"`
+	Items    []lineItem `json:"items"`
+	Priority string     `json:"priority" jsonschema:"description=From the tag,example=high" description:"ignored" example:"low"`
+	Notes    *string    `json:"notes,omitempty"`
+}
+
+func TestSchemaComments(t *testing.T) {
+	schema.RegisterComments(map[string]string{
+		"github.com/effective-security/gogentic/pkg/schema_test.orderRequest":       "orderRequest creates the order.",
+		"github.com/effective-security/gogentic/pkg/schema_test.orderRequest.Notes": "Notes for the delivery.",
+		"github.com/effective-security/gogentic/pkg/schema_test.lineItem.Price":     "Unit price in USD.",
+	})
+
+	s, err := schema.New(reflect.TypeOf(orderRequest{}))
+	require.NoError(t, err)
+	assert.Equal(t, "orderRequest creates the order.", s.RawSchema.Description)
+
+	props := s.Parameters.Properties
+	customer, _ := props.Get("customer")
+	assert.Equal(t, "Customer name, as in the contract", customer.Description)
+	assert.Equal(t, []any{"Acme, Inc."}, customer.Examples)
+
+	region, _ := props.Get("region")
+	assert.Equal(t, "Sales region", region.Description)
+	assert.Empty(t, region.Examples)
+
+	tags, _ := props.Get("tags")
+	assert.Equal(t, []any{[]any{"new", "vip"}}, tags.Examples)
+
+	priority, _ := props.Get("priority")
+	assert.Equal(t, "From the tag", priority.Description)
+	assert.Equal(t, []any{"high"}, priority.Examples)
+
+	notes, _ := props.Get("notes")
+	assert.Equal(t, "Notes for the delivery.", notes.Description)
+
+	items, _ := props.Get("items")
+	require.NotNil(t, items.Items)
+	sku, _ := items.Items.Properties.Get("sku")
+	assert.Equal(t, []any{"A-100"}, sku.Examples)
+	quantity, _ := items.Items.Properties.Get("quantity")
+	assert.Equal(t, []any{float64(2)}, quantity.Examples)
+	price, _ := items.Items.Properties.Get("price")
+	assert.Equal(t, "Unit price in USD.", price.Description)
+
+	js, err := json.Marshal(s.Parameters)
+	require.NoError(t, err)
+	assert.Contains(t, string(js), `"examples":[["new","vip"]]`)
+}
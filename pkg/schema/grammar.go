@@ -0,0 +1,246 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/invopop/jsonschema"
+)
+
+// NewGrammar returns the GBNF grammar of the JSON representation of the type,
+// to be used for the constrained decoding by llama.cpp and vLLM.
+func NewGrammar(t reflect.Type) (string, error) {
+	sc, err := New(t)
+	if err != nil {
+		return "", err
+	}
+	return GBNF(sc.RawSchema)
+}
+
+// GBNF converts the JSON schema to the GBNF grammar.
+// The properties are generated in the order of the schema,
+// the properties that are not required can be null.
+// The references are resolved from the definitions of the root schema.
+func GBNF(sc *jsonschema.Schema) (string, error) {
+	if sc == nil {
+		return "", errors.New("schema is nil")
+	}
+	g := &grammar{
+		rules:    map[string]string{},
+		defs:     sc.Definitions,
+		refs:     map[string]string{},
+		reserved: map[string]bool{},
+	}
+	expr, err := g.visit(sc, "root")
+	if err != nil {
+		return "", err
+	}
+	if _, ok := g.rules["root"]; !ok {
+		// the root is a primitive or a reference
+		g.addRule("root", expr)
+	}
+
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "root ::= %s\n", g.rules["root"])
+	for _, name := range g.names {
+		if name != "root" {
+			_, _ = fmt.Fprintf(&b, "%s ::= %s\n", name, g.rules[name])
+		}
+	}
+	for _, name := range gbnfPrimitiveNames {
+		if g.primitives[name] {
+			_, _ = fmt.Fprintf(&b, "%s ::= %s\n", name, gbnfPrimitives[name])
+		}
+	}
+	return b.String(), nil
+}
+
+var gbnfPrimitiveNames = []string{"value", "object", "array", "string", "number", "integer", "boolean", "null", "ws"}
+
+var gbnfPrimitives = map[string]string{
+	"value":   `object | array | string | number | boolean | null`,
+	"object":  `"{" ws ( string ":" ws value ( "," ws string ":" ws value )* )? "}" ws`,
+	"array":   `"[" ws ( value ( "," ws value )* )? "]" ws`,
+	"string":  `"\"" ( [^"\\\x7F\x00-\x1F] | "\\" ( ["\\/bfnrt] | "u" [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] ) )* "\"" ws`,
+	"number":  `"-"? ( [0-9] | [1-9] [0-9]* ) ( "." [0-9]+ )? ( [eE] [-+]? [0-9]+ )? ws`,
+	"integer": `"-"? ( [0-9] | [1-9] [0-9]* ) ws`,
+	"boolean": `( "true" | "false" ) ws`,
+	"null":    `"null" ws`,
+	"ws":      `[ \t\n]*`,
+}
+
+// the primitives that are used by other primitives
+var gbnfPrimitiveDeps = map[string][]string{
+	"value":   {"object", "array", "string", "number", "boolean", "null"},
+	"object":  {"ws", "string", "value"},
+	"array":   {"ws", "value"},
+	"string":  {"ws"},
+	"number":  {"ws"},
+	"integer": {"ws"},
+	"boolean": {"ws"},
+	"null":    {"ws"},
+}
+
+type grammar struct {
+	names      []string
+	rules      map[string]string
+	primitives map[string]bool
+	defs       jsonschema.Definitions
+	// refs maps the reference to the rule name
+	refs map[string]string
+	// reserved rules are defined by the referenced schema,
+	// the name is reserved before the visit to support recursion
+	reserved map[string]bool
+}
+
+var reGBNFName = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+
+func (g *grammar) usePrimitive(name string) string {
+	if g.primitives == nil {
+		g.primitives = map[string]bool{}
+	}
+	if !g.primitives[name] {
+		g.primitives[name] = true
+		for _, dep := range gbnfPrimitiveDeps[name] {
+			g.usePrimitive(dep)
+		}
+	}
+	return name
+}
+
+func (g *grammar) addRule(name, rule string) string {
+	if g.reserved[name] {
+		delete(g.reserved, name)
+		g.rules[name] = rule
+		return name
+	}
+	name = strings.Trim(reGBNFName.ReplaceAllString(name, "-"), "-")
+	unique := name
+	for i := 1; ; i++ {
+		if _, ok := g.rules[unique]; !ok {
+			break
+		}
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names = append(g.names, unique)
+	g.rules[unique] = rule
+	return unique
+}
+
+// visit returns the rule expression of the schema
+func (g *grammar) visit(sc *jsonschema.Schema, name string) (string, error) {
+	if sc.Ref != "" {
+		return g.visitRef(sc.Ref)
+	}
+
+	if len(sc.Enum) > 0 {
+		alts := make([]string, 0, len(sc.Enum))
+		for _, v := range sc.Enum {
+			js, err := json.Marshal(v)
+			if err != nil {
+				return "", errors.Wrap(err, "failed to marshal enum value")
+			}
+			alts = append(alts, gbnfLiteral(string(js)))
+		}
+		g.usePrimitive("ws")
+		return g.ruleName(name, "( "+strings.Join(alts, " | ")+" ) ws"), nil
+	}
+
+	if alts := append(append([]*jsonschema.Schema{}, sc.AnyOf...), sc.OneOf...); len(alts) > 0 {
+		exprs := make([]string, 0, len(alts))
+		for i, alt := range alts {
+			expr, err := g.visit(alt, fmt.Sprintf("%s-%d", name, i))
+			if err != nil {
+				return "", err
+			}
+			exprs = append(exprs, expr)
+		}
+		return g.ruleName(name, "( "+strings.Join(exprs, " | ")+" )"), nil
+	}
+
+	switch sc.Type {
+	case "object":
+		if sc.Properties == nil || sc.Properties.Len() == 0 {
+			return g.usePrimitive("object"), nil
+		}
+		required := map[string]bool{}
+		for _, r := range sc.Required {
+			required[r] = true
+		}
+		g.usePrimitive("ws")
+
+		var parts []string
+		for pair := sc.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			expr, err := g.visit(pair.Value, name+"-"+pair.Key)
+			if err != nil {
+				return "", err
+			}
+			if !required[pair.Key] {
+				expr = "( " + expr + " | " + g.usePrimitive("null") + " )"
+			}
+			key, _ := json.Marshal(pair.Key)
+			parts = append(parts, gbnfLiteral(string(key))+` ws ":" ws `+expr)
+		}
+		return g.addRule(name, `"{" ws `+strings.Join(parts, ` "," ws `)+` "}" ws`), nil
+	case "array":
+		if sc.Items == nil {
+			return g.usePrimitive("array"), nil
+		}
+		item, err := g.visit(sc.Items, name+"-item")
+		if err != nil {
+			return "", err
+		}
+		g.usePrimitive("ws")
+		return g.addRule(name, `"[" ws ( `+item+` ( "," ws `+item+` )* )? "]" ws`), nil
+	case "string", "number", "integer", "boolean", "null":
+		return g.ruleName(name, g.usePrimitive(sc.Type)), nil
+	case "":
+		return g.ruleName(name, g.usePrimitive("value")), nil
+	default:
+		return "", errors.Errorf("unsupported schema type: %s", sc.Type)
+	}
+}
+
+// visitRef returns the rule name of the referenced definition
+func (g *grammar) visitRef(ref string) (string, error) {
+	if name, ok := g.refs[ref]; ok {
+		return name, nil
+	}
+	defName := strings.TrimPrefix(strings.TrimPrefix(ref, "#/$defs/"), "#/definitions/")
+	def, ok := g.defs[defName]
+	if !ok {
+		return "", errors.Errorf("schema reference is not found: %s", ref)
+	}
+
+	name := g.addRule("def-"+defName, "")
+	g.refs[ref] = name
+	g.reserved[name] = true
+
+	expr, err := g.visit(def, name)
+	if err != nil {
+		return "", err
+	}
+	if g.reserved[name] {
+		// the definition is a primitive or a reference
+		delete(g.reserved, name)
+		g.rules[name] = expr
+	}
+	return name, nil
+}
+
+// ruleName returns the expression, or the rule name for the root.
+func (g *grammar) ruleName(name, expr string) string {
+	if name == "root" {
+		return g.addRule(name, expr)
+	}
+	return expr
+}
+
+func gbnfLiteral(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
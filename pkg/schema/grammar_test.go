@@ -0,0 +1,81 @@
+package schema_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGBNF(t *testing.T) {
+	t.Parallel()
+
+	grammar, err := schema.NewGrammar(reflect.TypeOf(Search{}))
+	require.NoError(t, err)
+
+	exp := `root ::= "{" ws "\"topic\"" ws ":" ws ( string | null ) "," ws "\"query\"" ws ":" ws string "," ws "\"type\"" ws ":" ws ( "\"web\"" | "\"image\"" | "\"video\"" ) ws "," ws "\"args\"" ws ":" ws ( root-args | null ) "," ws "\"prov\"" ws ":" ws ( root-prov | null ) "}" ws
+root-args-item ::= "{" ws "\"key\"" ws ":" ws string "," ws "\"value\"" ws ":" ws string "}" ws
+root-args ::= "[" ws ( root-args-item ( "," ws root-args-item )* )? "]" ws
+root-prov ::= "{" ws "\"key\"" ws ":" ws string "," ws "\"value\"" ws ":" ws string "}" ws
+string ::= "\"" ( [^"\\\x7F\x00-\x1F] | "\\" ( ["\\/bfnrt] | "u" [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] ) )* "\"" ws
+null ::= "null" ws
+ws ::= [ \t\n]*
+`
+	assert.Equal(t, exp, grammar)
+
+	grammar, err = schema.GBNF(schema.MustFromAny(map[string]any{"type": "integer"}))
+	require.NoError(t, err)
+	assert.Equal(t, "root ::= integer\ninteger ::= \"-\"? ( [0-9] | [1-9] [0-9]* ) ws\nws ::= [ \\t\\n]*\n", grammar)
+
+	grammar, err = schema.GBNF(schema.MustFromAny(map[string]any{"type": "object"}))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(grammar, "root ::= object\nvalue ::= "), grammar)
+
+	grammar, err = schema.GBNF(schema.MustFromAny(map[string]any{"type": "array"}))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(grammar, "root ::= array\nvalue ::= "), grammar)
+
+	_, err = schema.GBNF(schema.MustFromAny(map[string]any{"$ref": "#/$defs/Missing"}))
+	assert.EqualError(t, err, "schema reference is not found: #/$defs/Missing")
+
+	_, err = schema.GBNF(schema.MustFromAny(map[string]any{"type": "tuple"}))
+	assert.EqualError(t, err, "unsupported schema type: tuple")
+	_, err = schema.GBNF(nil)
+	assert.EqualError(t, err, "schema is nil")
+}
+
+func TestGBNF_Refs(t *testing.T) {
+	t.Parallel()
+
+	sc := schema.MustFromAny(map[string]any{
+		"$ref": "#/$defs/Node",
+		"$defs": map[string]any{
+			"Node": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name": map[string]any{"type": "string"},
+					"children": map[string]any{
+						"type":  "array",
+						"items": map[string]any{"$ref": "#/$defs/Node"},
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+	})
+
+	grammar, err := schema.GBNF(sc)
+	require.NoError(t, err)
+
+	exp := `root ::= def-Node
+def-Node ::= "{" ws "\"children\"" ws ":" ws ( def-Node-children | null ) "," ws "\"name\"" ws ":" ws string "}" ws
+def-Node-children ::= "[" ws ( def-Node ( "," ws def-Node )* )? "]" ws
+string ::= "\"" ( [^"\\\x7F\x00-\x1F] | "\\" ( ["\\/bfnrt] | "u" [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] ) )* "\"" ws
+null ::= "null" ws
+ws ::= [ \t\n]*
+`
+	assert.Equal(t, exp, grammar)
+}
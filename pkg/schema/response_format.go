@@ -2,19 +2,28 @@ package schema
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/invopop/jsonschema"
 )
 
+const (
+	// ResponseFormatTypeJSONSchema is the response format with the JSON schema enforced by the provider.
+	ResponseFormatTypeJSONSchema = "json_schema"
+	// ResponseFormatTypeJSONObject is the JSON mode, the provider returns a valid JSON,
+	// the schema must be described in the prompt.
+	ResponseFormatTypeJSONObject = "json_object"
+)
+
 func NewResponseFormat(t reflect.Type, strict bool) (*ResponseFormat, error) {
 	sc, err := New(t)
 	if err != nil {
 		return nil, err
 	}
 	return &ResponseFormat{
-		Type: "json_schema",
+		Type: ResponseFormatTypeJSONSchema,
 		JSONSchema: &ResponseFormatJSONSchema{
-			Name:   t.Name(),
+			Name:   responseFormatName(t),
 			Strict: strict,
 			Schema: toOpenAISchema(sc.Parameters, strict),
 		},
@@ -22,7 +31,7 @@ func NewResponseFormat(t reflect.Type, strict bool) (*ResponseFormat, error) {
 }
 
 type ResponseFormatJSONSchemaProperty struct {
-	Type                 string                                       `json:"type"`
+	Type                 string                                       `json:"type,omitempty"`
 	Title                string                                       `json:"title,omitempty"`
 	Description          string                                       `json:"description,omitempty"`
 	Enum                 []any                                        `json:"enum,omitempty"`
@@ -33,6 +42,7 @@ type ResponseFormatJSONSchemaProperty struct {
 	AdditionalProperties *bool                                        `json:"additionalProperties,omitempty"`
 	Required             []string                                     `json:"required,omitempty"`
 	Ref                  string                                       `json:"$ref,omitempty"`
+	AnyOf                []*ResponseFormatJSONSchemaProperty          `json:"anyOf,omitempty"`
 }
 
 type ResponseFormatJSONSchema struct {
@@ -41,12 +51,30 @@ type ResponseFormatJSONSchema struct {
 	Schema *ResponseFormatJSONSchemaProperty `json:"schema"`
 }
 
+// responseFormatName returns the name of the type without the type parameters,
+// as the providers accept only letters, digits, underscores and dashes.
+func responseFormatName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
 // ResponseFormat is the format of the response.
 type ResponseFormat struct {
 	Type       string                    `json:"type"`
 	JSONSchema *ResponseFormatJSONSchema `json:"json_schema,omitempty"`
 }
 
+// NewResponseFormatJSONObject returns the response format for the JSON mode,
+// used with the providers that do not support the JSON schema.
+func NewResponseFormatJSONObject() *ResponseFormat {
+	return &ResponseFormat{
+		Type: ResponseFormatTypeJSONObject,
+	}
+}
+
 var (
 	trueVal  = true
 	falseVal = false
@@ -88,5 +116,10 @@ func toOpenAISchema(in *jsonschema.Schema, strict bool) *ResponseFormatJSONSchem
 		result.Items = toOpenAISchema(in.Items, strict)
 	}
 
+	// oneOf is not supported by the providers, the discriminated unions are exclusive as anyOf
+	for _, alt := range append(append([]*jsonschema.Schema{}, in.AnyOf...), in.OneOf...) {
+		result.AnyOf = append(result.AnyOf, toOpenAISchema(alt, strict))
+	}
+
 	return result
 }
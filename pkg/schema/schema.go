@@ -142,6 +142,8 @@ func JSONSchema(t reflect.Type) *jsonschema.Schema {
 	r.ExpandedStruct = true
 	r.DoNotReference = true
 	r.AllowAdditionalProperties = true
+	// the descriptions from the extended tags and the registered doc comments
+	r.LookupComment = LookupComment
 
 	// The Struct name could be same, but the package name is different
 	// For example, all of the notification plugins have the same struct name - `NotifyConfig`
@@ -161,7 +163,9 @@ func JSONSchema(t reflect.Type) *jsonschema.Schema {
 		return name
 	}
 
-	return r.ReflectFromType(t)
+	s := r.ReflectFromType(t)
+	AddTagExamples(t, s)
+	return s
 }
 
 // FromAny creates a json schema from any type.
@@ -436,6 +436,19 @@ func TestSchemaNewResponseFormat(t *testing.T) {
 }`
 		assert.Equal(t, exp, llmutils.ToJSONIndent(rf))
 	})
+
+	t.Run("AnswerWithData", func(t *testing.T) {
+		t.Parallel()
+		rf, err := schema.NewResponseFormat(reflect.TypeOf(chatmodel.AnswerWithData[KVPair]{}), true)
+		require.NoError(t, err)
+		// the type parameters are not allowed in the name
+		assert.Equal(t, "AnswerWithData", rf.JSONSchema.Name)
+		assert.Equal(t, []string{"answer", "data"}, rf.JSONSchema.Schema.Required)
+		require.Contains(t, rf.JSONSchema.Schema.Properties, "data")
+		data := rf.JSONSchema.Schema.Properties["data"]
+		assert.Equal(t, "object", data.Type)
+		assert.Equal(t, []string{"key", "value"}, data.Required)
+	})
 }
 
 type Action struct {
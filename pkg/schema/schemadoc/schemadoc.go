@@ -0,0 +1,175 @@
+// Package schemadoc generates the registration of the doc comments of the Go types,
+// used as the descriptions in the JSON schemas generated by pkg/schema.
+//
+// The doc comments are not available at runtime, so the generated file registers them
+// in the init function with schema.RegisterComments:
+//
+//	//go:generate go run github.com/effective-security/gogentic/cmd/schemadoc
+package schemadoc
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultOutput is the default name of the generated file.
+const DefaultOutput = "schema_comments.gen.go"
+
+// Comments returns the doc comments of the exported struct types and their exported fields
+// in the package folder, keyed by the fully qualified names for schema.RegisterComments,
+// and the name of the package. The test and generated files are skipped.
+func Comments(dir, importPath string) (map[string]string, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") ||
+			strings.HasSuffix(name, "_test.go") || strings.HasSuffix(name, ".gen.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, "", errors.WithMessage(err, "failed to parse the package")
+		}
+		if len(files) > 0 && f.Name.Name != files[0].Name.Name {
+			return nil, "", errors.Errorf("multiple packages in %s: %s and %s", dir, files[0].Name.Name, f.Name.Name)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, "", errors.Errorf("no Go files in %s", dir)
+	}
+
+	docPkg, err := doc.NewFromFiles(fset, files, importPath, doc.PreserveAST)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	res := make(map[string]string)
+	for _, t := range docPkg.Types {
+		st := structType(t.Decl, t.Name)
+		if st == nil {
+			continue
+		}
+		key := importPath + "." + t.Name
+		if text := strings.TrimSpace(docPkg.Synopsis(t.Doc)); text != "" {
+			res[key] = text
+		}
+		for _, f := range st.Fields.List {
+			text := f.Doc.Text()
+			if text == "" {
+				text = f.Comment.Text()
+			}
+			text = strings.Join(strings.Fields(text), " ")
+			if text == "" {
+				continue
+			}
+			for _, n := range f.Names {
+				if n.IsExported() {
+					res[key+"."+n.Name] = text
+				}
+			}
+		}
+	}
+	return res, docPkg.Name, nil
+}
+
+func structType(decl *ast.GenDecl, name string) *ast.StructType {
+	for _, spec := range decl.Specs {
+		if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == name {
+			st, _ := ts.Type.(*ast.StructType)
+			return st
+		}
+	}
+	return nil
+}
+
+// Generate returns the source of the file registering the doc comments of the package,
+// the import path is detected from go.mod, if not provided.
+func Generate(dir, importPath string) ([]byte, error) {
+	if importPath == "" {
+		var err error
+		if importPath, err = ImportPath(dir); err != nil {
+			return nil, err
+		}
+	}
+	comments, pkgName, err := Comments(dir, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(comments))
+	for k := range comments {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by schemadoc. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkgName)
+	b.WriteString("import \"github.com/effective-security/gogentic/pkg/schema\"\n\n")
+	b.WriteString("func init() {\n\tschema.RegisterComments(map[string]string{\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\t\t%s: %s,\n", strconv.Quote(k), strconv.Quote(comments[k]))
+	}
+	b.WriteString("\t})\n}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to format the source")
+	}
+	return src, nil
+}
+
+// ImportPath returns the import path of the folder from the module path in go.mod.
+func ImportPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			module := modulePath(data)
+			if module == "" {
+				return "", errors.Errorf("module path not found in %s", filepath.Join(root, "go.mod"))
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			if rel == "." {
+				return module, nil
+			}
+			return module + "/" + filepath.ToSlash(rel), nil
+		}
+		if filepath.Dir(root) == root {
+			return "", errors.Errorf("go.mod not found for %s", dir)
+		}
+	}
+}
+
+func modulePath(gomod []byte) string {
+	for _, line := range strings.Split(string(gomod), "\n") {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package schemadoc_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/schema/schemadoc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const source = `package tools
+
+// Request is the request of the search tool.
+// It is used by the assistant.
+type Request struct {
+	// Query is the search query,
+	// in the natural language.
+	Query string ` + "`json:\"query\"`" + `
+	Limit int ` + "`json:\"limit\"`" + ` // Limit of the results
+	internal string
+	NoDoc bool
+}
+
+type (
+	// Response is the search response.
+	Response struct {
+		Results []string // Results of the search
+	}
+	// Mode is not a struct.
+	Mode string
+)
+`
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.24\n"), 0o644))
+	pkgDir := filepath.Join(dir, "tools")
+	require.NoError(t, os.Mkdir(pkgDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "tools.go"), []byte(source), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "tools_test.go"), []byte("package tools_test\n"), 0o644))
+
+	importPath, err := schemadoc.ImportPath(pkgDir)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/app/tools", importPath)
+
+	comments, pkgName, err := schemadoc.Comments(pkgDir, importPath)
+	require.NoError(t, err)
+	assert.Equal(t, "tools", pkgName)
+	assert.Equal(t, map[string]string{
+		"example.com/app/tools.Request":          "Request is the request of the search tool.",
+		"example.com/app/tools.Request.Query":    "Query is the search query, in the natural language.",
+		"example.com/app/tools.Request.Limit":    "Limit of the results",
+		"example.com/app/tools.Response":         "Response is the search response.",
+		"example.com/app/tools.Response.Results": "Results of the search",
+	}, comments)
+
+	src, err := schemadoc.Generate(pkgDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, `// Code generated by schemadoc. DO NOT EDIT.
+
+package tools
+
+import "github.com/effective-security/gogentic/pkg/schema"
+
+func init() {
+	schema.RegisterComments(map[string]string{
+		"example.com/app/tools.Request":          "Request is the request of the search tool.",
+		"example.com/app/tools.Request.Limit":    "Limit of the results",
+		"example.com/app/tools.Request.Query":    "Query is the search query, in the natural language.",
+		"example.com/app/tools.Response":         "Response is the search response.",
+		"example.com/app/tools.Response.Results": "Results of the search",
+	})
+}
+`, string(src))
+
+	_, err = schemadoc.Generate(t.TempDir(), "example.com/empty")
+	assert.Error(t, err)
+}
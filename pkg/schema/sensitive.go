@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// SensitiveExtension is the JSON schema extension that marks the value as sensitive,
+// for example with the struct tag `jsonschema_extras:"x-sensitive=true"`.
+// The sensitive values are masked in the logs, callbacks and transcripts.
+const SensitiveExtension = "x-sensitive"
+
+// IsSensitive returns true, if the schema is marked with the x-sensitive extension.
+func IsSensitive(sc *jsonschema.Schema) bool {
+	if sc == nil || sc.Extras == nil {
+		return false
+	}
+	switch v := sc.Extras[SensitiveExtension].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// RedactSensitive returns the JSON with the values of the sensitive properties replaced
+// by the mask of the value, and true if any value is masked.
+// The data is returned as is, if it is not a valid JSON, or no value is masked.
+func RedactSensitive(sc *jsonschema.Schema, data []byte, mask func(string) string) ([]byte, bool) {
+	if sc == nil || len(data) == 0 {
+		return data, false
+	}
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return data, false
+	}
+
+	r := &redactor{defs: sc.Definitions, mask: mask}
+	doc = r.redact(sc, doc)
+	if !r.changed {
+		return data, false
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return data, false
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), true
+}
+
+type redactor struct {
+	defs    jsonschema.Definitions
+	mask    func(string) string
+	changed bool
+}
+
+func (r *redactor) redact(sc *jsonschema.Schema, value any) any {
+	if sc == nil || value == nil {
+		return value
+	}
+	if sc.Ref != "" {
+		defName := strings.TrimPrefix(strings.TrimPrefix(sc.Ref, "#/$defs/"), "#/definitions/")
+		return r.redact(r.defs[defName], value)
+	}
+	if IsSensitive(sc) {
+		r.changed = true
+		if s, ok := value.(string); ok {
+			return r.mask(s)
+		}
+		js, _ := json.Marshal(value)
+		return r.mask(string(js))
+	}
+
+	// the value is masked, if it is sensitive in any of the branches
+	for _, subs := range [][]*jsonschema.Schema{sc.AllOf, sc.AnyOf, sc.OneOf} {
+		for _, sub := range subs {
+			value = r.redact(sub, value)
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		for name, v := range val {
+			var prop *jsonschema.Schema
+			if sc.Properties != nil {
+				prop, _ = sc.Properties.Get(name)
+			}
+			if prop == nil {
+				prop = sc.AdditionalProperties
+			}
+			val[name] = r.redact(prop, v)
+		}
+	case []any:
+		for i, v := range val {
+			item := sc.Items
+			if i < len(sc.PrefixItems) {
+				item = sc.PrefixItems[i]
+			}
+			val[i] = r.redact(item, v)
+		}
+	}
+	return value
+}
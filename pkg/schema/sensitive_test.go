@@ -0,0 +1,50 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+)
+
+type credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password" jsonschema_extras:"x-sensitive=true"`
+}
+
+type loginRequest struct {
+	Site  string        `json:"site"`
+	Creds credentials   `json:"creds"`
+	Keys  []string      `json:"keys" jsonschema_extras:"x-sensitive=true"`
+	Alt   []credentials `json:"alt,omitempty"`
+}
+
+func TestRedactSensitive(t *testing.T) {
+	t.Parallel()
+
+	sc := (&jsonschema.Reflector{}).Reflect(&loginRequest{})
+	mask := func(string) string { return "***" }
+
+	res, ok := schema.RedactSensitive(sc,
+		[]byte(`{"site":"a<b","creds":{"user":"bob","password":"pwd"},"keys":["k1","k2"],"alt":[{"user":"ann","password":"x"}]}`), mask)
+	assert.True(t, ok)
+	assert.Equal(t, `{"alt":[{"password":"***","user":"ann"}],"creds":{"password":"***","user":"bob"},"keys":"***","site":"a<b"}`, string(res))
+
+	in := []byte(`{"site":"a","creds":{"user":"bob"}}`)
+	res, ok = schema.RedactSensitive(sc, in, mask)
+	assert.False(t, ok)
+	assert.Equal(t, in, res)
+
+	res, ok = schema.RedactSensitive(sc, []byte("not json"), mask)
+	assert.False(t, ok)
+	assert.Equal(t, "not json", string(res))
+
+	res, ok = schema.RedactSensitive(nil, in, mask)
+	assert.False(t, ok)
+	assert.Equal(t, in, res)
+
+	assert.True(t, schema.IsSensitive(&jsonschema.Schema{Extras: map[string]any{schema.SensitiveExtension: true}}))
+	assert.False(t, schema.IsSensitive(&jsonschema.Schema{Extras: map[string]any{schema.SensitiveExtension: "false"}}))
+	assert.False(t, schema.IsSensitive(nil))
+}
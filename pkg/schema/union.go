@@ -0,0 +1,214 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/invopop/jsonschema"
+	orderedmap "github.com/pb33f/ordered-map/v2"
+)
+
+// Union describes the implementations of the interface,
+// discriminated by the property with the variant name.
+type Union struct {
+	// Discriminator is the name of the property with the variant name.
+	Discriminator string
+
+	variants map[string]reflect.Type
+	names    map[reflect.Type]string
+	// order is the sorted variant names
+	order []string
+}
+
+var (
+	unions   = make(map[reflect.Type]*Union)
+	unionsMu sync.RWMutex
+)
+
+// RegisterUnion registers the implementations of the interface I,
+// discriminated by the property, to be used with OneOf[I] fields.
+// The union must be registered before the schema of the output type is created,
+// for example:
+//
+//	schema.RegisterUnion[Shape]("type", map[string]Shape{
+//		"circle": Circle{},
+//		"square": &Square{},
+//	})
+func RegisterUnion[I any](discriminator string, variants map[string]I) error {
+	it := reflect.TypeFor[I]()
+	if it.Kind() != reflect.Interface {
+		return errors.Newf("union type must be an interface: %s", it)
+	}
+	if discriminator == "" || len(variants) == 0 {
+		return errors.Newf("union %s: discriminator and variants are required", it)
+	}
+
+	u := &Union{
+		Discriminator: discriminator,
+		variants:      make(map[string]reflect.Type, len(variants)),
+		names:         make(map[reflect.Type]string, len(variants)),
+	}
+	for name, v := range variants {
+		t := reflect.TypeOf(v)
+		if t == nil {
+			return errors.Newf("union %s: variant %q is nil", it, name)
+		}
+		if st := derefType(t); st.Kind() != reflect.Struct {
+			return errors.Newf("union %s: variant %q must be a struct: %s", it, name, t)
+		}
+		u.variants[name] = t
+		u.names[t] = name
+		u.order = append(u.order, name)
+	}
+	slices.Sort(u.order)
+
+	unionsMu.Lock()
+	defer unionsMu.Unlock()
+	unions[it] = u
+	return nil
+}
+
+// GetUnion returns the registered union of the interface type, or nil.
+func GetUnion(t reflect.Type) *Union {
+	unionsMu.RLock()
+	defer unionsMu.RUnlock()
+	return unions[t]
+}
+
+// Variants returns the sorted variant names.
+func (u *Union) Variants() []string {
+	return slices.Clone(u.order)
+}
+
+// Schema returns the anyOf schema of the variants,
+// each variant requires the discriminator property with the variant name.
+func (u *Union) Schema() *jsonschema.Schema {
+	res := &jsonschema.Schema{}
+	for _, name := range u.order {
+		vs := JSONSchema(u.variants[name])
+		vs.Version = ""
+		vs.ID = ""
+
+		props := orderedmap.New[string, *jsonschema.Schema]()
+		props.Set(u.Discriminator, &jsonschema.Schema{
+			Type: "string",
+			Enum: []any{name},
+		})
+		if vs.Properties != nil {
+			for pair := vs.Properties.Oldest(); pair != nil; pair = pair.Next() {
+				if pair.Key != u.Discriminator {
+					props.Set(pair.Key, pair.Value)
+				}
+			}
+		}
+		vs.Properties = props
+		vs.Required = append([]string{u.Discriminator}, slices.DeleteFunc(vs.Required, func(r string) bool {
+			return r == u.Discriminator
+		})...)
+		res.AnyOf = append(res.AnyOf, vs)
+	}
+	return res
+}
+
+// unmarshal returns the variant selected by the discriminator
+func (u *Union) unmarshal(data []byte) (any, error) {
+	var head map[string]json.RawMessage
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal union")
+	}
+	var name string
+	if raw, ok := head[u.Discriminator]; ok {
+		if err := json.Unmarshal(raw, &name); err != nil {
+			return nil, errors.Wrapf(err, "invalid %q property", u.Discriminator)
+		}
+	}
+	t, ok := u.variants[name]
+	if !ok {
+		return nil, errors.Newf("unknown %s %q, expected one of: %s", u.Discriminator, name, strings.Join(u.order, ", "))
+	}
+
+	v := reflect.New(derefType(t))
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal %s", name)
+	}
+	if t.Kind() == reflect.Pointer {
+		return v.Interface(), nil
+	}
+	return v.Elem().Interface(), nil
+}
+
+// marshal returns the JSON of the variant with the discriminator
+func (u *Union) marshal(value any) ([]byte, error) {
+	name, ok := u.names[reflect.TypeOf(value)]
+	if !ok {
+		return nil, errors.Newf("type is not registered in the union: %T", value)
+	}
+	js, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(js, &obj); err != nil {
+		return nil, errors.Wrap(err, "union variant must be a JSON object")
+	}
+	obj[u.Discriminator], _ = json.Marshal(name)
+	return json.Marshal(obj)
+}
+
+// OneOf is the field of the interface type I, registered with RegisterUnion.
+// It generates the discriminated anyOf schema of the variants,
+// and unmarshals the JSON into the variant selected by the discriminator.
+type OneOf[I any] struct {
+	Value I
+}
+
+// JSONSchema implements the custom schema of the union.
+func (o OneOf[I]) JSONSchema() *jsonschema.Schema {
+	if u := GetUnion(reflect.TypeFor[I]()); u != nil {
+		return u.Schema()
+	}
+	return &jsonschema.Schema{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o OneOf[I]) MarshalJSON() ([]byte, error) {
+	value := any(o.Value)
+	if value == nil {
+		return []byte("null"), nil
+	}
+	u := GetUnion(reflect.TypeFor[I]())
+	if u == nil {
+		return json.Marshal(value)
+	}
+	return u.marshal(value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *OneOf[I]) UnmarshalJSON(data []byte) error {
+	var zero I
+	if string(data) == "null" {
+		o.Value = zero
+		return nil
+	}
+	u := GetUnion(reflect.TypeFor[I]())
+	if u == nil {
+		return errors.Newf("union is not registered: %s", reflect.TypeFor[I]())
+	}
+	v, err := u.unmarshal(data)
+	if err != nil {
+		return err
+	}
+	o.Value = v.(I)
+	return nil
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,118 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64 `json:"radius" jsonschema:"description=Radius of the circle"`
+}
+
+func (c Circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type Square struct {
+	Type string  `json:"type"`
+	Side float64 `json:"side" jsonschema:"description=Side of the square"`
+}
+
+func (s *Square) Area() float64 { return s.Side * s.Side }
+
+type Drawing struct {
+	Title  string                `json:"title"`
+	Shapes []schema.OneOf[Shape] `json:"shapes"`
+	Main   *schema.OneOf[Shape]  `json:"main,omitempty"`
+}
+
+func init() {
+	err := schema.RegisterUnion[Shape]("type", map[string]Shape{
+		"circle": Circle{},
+		"square": &Square{},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestUnion_Register(t *testing.T) {
+	t.Parallel()
+
+	assert.EqualError(t, schema.RegisterUnion[Circle]("type", map[string]Circle{"circle": {}}),
+		"union type must be an interface: schema_test.Circle")
+	assert.EqualError(t, schema.RegisterUnion[Shape]("", map[string]Shape{"circle": Circle{}}),
+		"union schema_test.Shape: discriminator and variants are required")
+
+	u := schema.GetUnion(reflect.TypeFor[Shape]())
+	require.NotNil(t, u)
+	assert.Equal(t, "type", u.Discriminator)
+	assert.Equal(t, []string{"circle", "square"}, u.Variants())
+}
+
+func TestUnion_Schema(t *testing.T) {
+	t.Parallel()
+
+	sc, err := schema.New(reflect.TypeOf(Drawing{}))
+	require.NoError(t, err)
+
+	shapes, ok := sc.Parameters.Properties.Get("shapes")
+	require.True(t, ok)
+	require.NotNil(t, shapes.Items)
+	require.Len(t, shapes.Items.AnyOf, 2)
+
+	circle := shapes.Items.AnyOf[0]
+	assert.Equal(t, "object", circle.Type)
+	assert.Equal(t, []string{"type", "radius"}, circle.Required)
+	disc, ok := circle.Properties.Get("type")
+	require.True(t, ok)
+	assert.Equal(t, []any{"circle"}, disc.Enum)
+
+	square := shapes.Items.AnyOf[1]
+	assert.Equal(t, []string{"type", "side"}, square.Required)
+	assert.Equal(t, 2, square.Properties.Len())
+	disc, _ = square.Properties.Get("type")
+	assert.Equal(t, []any{"square"}, disc.Enum)
+
+	rf, err := schema.NewResponseFormat(reflect.TypeOf(Drawing{}), true)
+	require.NoError(t, err)
+	require.Len(t, rf.JSONSchema.Schema.Properties["shapes"].Items.AnyOf, 2)
+	js, err := json.Marshal(rf.JSONSchema.Schema.Properties["shapes"].Items.AnyOf[0].Properties["type"])
+	require.NoError(t, err)
+	assert.Equal(t, `{"type":"string","enum":["circle"]}`, string(js))
+
+	// the output is validated by the discriminated variant
+	require.NoError(t, sc.Validate([]byte(`{"title":"t","shapes":[{"type":"circle","radius":1},{"type":"square","side":2}]}`)))
+	require.Error(t, sc.Validate([]byte(`{"title":"t","shapes":[{"type":"triangle","side":2}]}`)))
+}
+
+func TestUnion_JSON(t *testing.T) {
+	t.Parallel()
+
+	var d Drawing
+	err := json.Unmarshal([]byte(`{"title":"t","shapes":[{"type":"circle","radius":1},{"type":"square","side":2}],"main":{"type":"square","side":3}}`), &d)
+	require.NoError(t, err)
+	require.Len(t, d.Shapes, 2)
+	assert.Equal(t, Circle{Radius: 1}, d.Shapes[0].Value)
+	assert.Equal(t, &Square{Type: "square", Side: 2}, d.Shapes[1].Value)
+	require.NotNil(t, d.Main)
+	assert.Equal(t, 9.0, d.Main.Value.Area())
+
+	js, err := json.Marshal(d.Shapes)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"radius":1,"type":"circle"},{"side":2,"type":"square"}]`, string(js))
+
+	err = json.Unmarshal([]byte(`{"title":"t","shapes":[{"type":"triangle"}]}`), &d)
+	assert.EqualError(t, err, `unknown type "triangle", expected one of: circle, square`)
+
+	js, err = json.Marshal(schema.OneOf[Shape]{})
+	require.NoError(t, err)
+	assert.Equal(t, "null", string(js))
+}
@@ -0,0 +1,389 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/invopop/jsonschema"
+)
+
+// Violation is the mismatch of the JSON value and the schema.
+type Violation struct {
+	// Path is the JSON pointer of the value, for example "/items/0/name".
+	Path string `json:"path"`
+	// Message describes the violated constraint.
+	Message string `json:"message"`
+}
+
+// String returns the violation in "path: message" format.
+func (v Violation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Message
+}
+
+// ValidationError is returned by Validate, when the JSON does not match the schema.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	list := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		list[i] = v.String()
+	}
+	return "schema validation failed: " + strings.Join(list, "; ")
+}
+
+// Details returns the violations, one per line, to be fed back to the LLM.
+func (e *ValidationError) Details() string {
+	var b strings.Builder
+	for _, v := range e.Violations {
+		b.WriteString("- ")
+		b.WriteString(v.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Validate validates the JSON against the RawSchema.
+func (s *Schema) Validate(data []byte) error {
+	return Validate(s.RawSchema, data)
+}
+
+// Validate validates the JSON against the schema,
+// including the enum, const, format, pattern and the length constraints.
+// It returns *ValidationError with all the violations found,
+// or an error if data is not a valid JSON.
+func Validate(sc *jsonschema.Schema, data []byte) error {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return errors.Wrap(err, "invalid JSON")
+	}
+
+	v := &validator{defs: sc.Definitions}
+	v.validate(sc, doc, "")
+	if len(v.violations) > 0 {
+		return &ValidationError{Violations: v.violations}
+	}
+	return nil
+}
+
+type validator struct {
+	defs       jsonschema.Definitions
+	violations []Violation
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.violations = append(v.violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// matches returns true, if the value matches the schema, without reporting the violations
+func (v *validator) matches(sc *jsonschema.Schema, value any, path string) bool {
+	sub := &validator{defs: v.defs}
+	sub.validate(sc, value, path)
+	return len(sub.violations) == 0
+}
+
+func (v *validator) validate(sc *jsonschema.Schema, value any, path string) {
+	if sc == nil {
+		return
+	}
+	if sc.Ref != "" {
+		defName := strings.TrimPrefix(strings.TrimPrefix(sc.Ref, "#/$defs/"), "#/definitions/")
+		def, ok := v.defs[defName]
+		if !ok {
+			v.fail(path, "schema reference is not found: %s", sc.Ref)
+			return
+		}
+		v.validate(def, value, path)
+		return
+	}
+
+	for _, sub := range sc.AllOf {
+		v.validate(sub, value, path)
+	}
+	if len(sc.AnyOf) > 0 && !slices.ContainsFunc(sc.AnyOf, func(sub *jsonschema.Schema) bool {
+		return v.matches(sub, value, path)
+	}) {
+		v.fail(path, "value does not match any of the allowed schemas")
+	}
+	if len(sc.OneOf) > 0 {
+		count := 0
+		for _, sub := range sc.OneOf {
+			if v.matches(sub, value, path) {
+				count++
+			}
+		}
+		if count != 1 {
+			v.fail(path, "value must match exactly one of the allowed schemas, matched %d", count)
+		}
+	}
+
+	if len(sc.Enum) > 0 && !slices.ContainsFunc(sc.Enum, func(e any) bool { return equalJSON(e, value) }) {
+		js, _ := json.Marshal(sc.Enum)
+		v.fail(path, "value must be one of %s", js)
+	}
+	if sc.Const != nil && !equalJSON(sc.Const, value) {
+		js, _ := json.Marshal(sc.Const)
+		v.fail(path, "value must be %s", js)
+	}
+
+	if sc.Type != "" && !isType(sc.Type, value) {
+		v.fail(path, "expected %s, got %s", sc.Type, typeOf(value))
+		return
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateObject(sc, val, path)
+	case []any:
+		v.validateArray(sc, val, path)
+	case string:
+		v.validateString(sc, val, path)
+	case json.Number:
+		v.validateNumber(sc, val, path)
+	}
+}
+
+func (v *validator) validateObject(sc *jsonschema.Schema, obj map[string]any, path string) {
+	for _, name := range sc.Required {
+		if _, ok := obj[name]; !ok {
+			v.fail(path, "missing required property %q", name)
+		}
+	}
+
+	known := map[string]bool{}
+	if sc.Properties != nil {
+		for pair := sc.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			known[pair.Key] = true
+			val, ok := obj[pair.Key]
+			// the optional properties can be null
+			if !ok || (val == nil && !slices.Contains(sc.Required, pair.Key)) {
+				continue
+			}
+			v.validate(pair.Value, val, path+"/"+escapePointer(pair.Key))
+		}
+	}
+
+	extra := make([]string, 0, len(obj))
+	for name := range obj {
+		if !known[name] {
+			extra = append(extra, name)
+		}
+	}
+	slices.Sort(extra)
+	for _, name := range extra {
+		switch {
+		case sc.AdditionalProperties == nil:
+		case isFalseSchema(sc.AdditionalProperties):
+			v.fail(path, "unknown property %q", name)
+		default:
+			v.validate(sc.AdditionalProperties, obj[name], path+"/"+escapePointer(name))
+		}
+	}
+
+	if sc.MinProperties != nil && uint64(len(obj)) < *sc.MinProperties {
+		v.fail(path, "object must have at least %d properties", *sc.MinProperties)
+	}
+	if sc.MaxProperties != nil && uint64(len(obj)) > *sc.MaxProperties {
+		v.fail(path, "object must have at most %d properties", *sc.MaxProperties)
+	}
+}
+
+func (v *validator) validateArray(sc *jsonschema.Schema, arr []any, path string) {
+	if sc.MinItems != nil && uint64(len(arr)) < *sc.MinItems {
+		v.fail(path, "array must have at least %d items", *sc.MinItems)
+	}
+	if sc.MaxItems != nil && uint64(len(arr)) > *sc.MaxItems {
+		v.fail(path, "array must have at most %d items", *sc.MaxItems)
+	}
+	if sc.UniqueItems {
+		for i := range arr {
+			for j := range i {
+				if equalJSON(arr[i], arr[j]) {
+					v.fail(path, "array items %d and %d are equal", j, i)
+				}
+			}
+		}
+	}
+	for i, item := range arr {
+		itemPath := fmt.Sprintf("%s/%d", path, i)
+		if i < len(sc.PrefixItems) {
+			v.validate(sc.PrefixItems[i], item, itemPath)
+			continue
+		}
+		v.validate(sc.Items, item, itemPath)
+	}
+}
+
+func (v *validator) validateString(sc *jsonschema.Schema, s, path string) {
+	length := uint64(utf8.RuneCountInString(s))
+	if sc.MinLength != nil && length < *sc.MinLength {
+		v.fail(path, "string must be at least %d characters long", *sc.MinLength)
+	}
+	if sc.MaxLength != nil && length > *sc.MaxLength {
+		v.fail(path, "string must be at most %d characters long", *sc.MaxLength)
+	}
+	if sc.Pattern != "" {
+		// the invalid patterns are ignored
+		if re, err := regexp.Compile(sc.Pattern); err == nil && !re.MatchString(s) {
+			v.fail(path, "string does not match the pattern %q", sc.Pattern)
+		}
+	}
+	if sc.Format != "" && !isFormat(sc.Format, s) {
+		v.fail(path, "string is not a valid %s", sc.Format)
+	}
+}
+
+func (v *validator) validateNumber(sc *jsonschema.Schema, n json.Number, path string) {
+	f, err := n.Float64()
+	if err != nil {
+		v.fail(path, "invalid number: %s", n)
+		return
+	}
+	if limit, ok := number(sc.Minimum); ok && f < limit {
+		v.fail(path, "value must be >= %s", sc.Minimum)
+	}
+	if limit, ok := number(sc.Maximum); ok && f > limit {
+		v.fail(path, "value must be <= %s", sc.Maximum)
+	}
+	if limit, ok := number(sc.ExclusiveMinimum); ok && f <= limit {
+		v.fail(path, "value must be > %s", sc.ExclusiveMinimum)
+	}
+	if limit, ok := number(sc.ExclusiveMaximum); ok && f >= limit {
+		v.fail(path, "value must be < %s", sc.ExclusiveMaximum)
+	}
+	if div, ok := number(sc.MultipleOf); ok && div > 0 {
+		if q := f / div; math.Abs(q-math.Round(q)) > 1e-9 {
+			v.fail(path, "value must be a multiple of %s", sc.MultipleOf)
+		}
+	}
+}
+
+func number(n json.Number) (float64, bool) {
+	if n == "" {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+func isType(typ string, value any) bool {
+	switch typ {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return typeOf(value) == typ
+	}
+}
+
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+var (
+	reUUID     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	reHostname = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+)
+
+// isFormat returns true, if the string matches the format,
+// the unknown formats are not validated.
+func isFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", s)
+		if err != nil {
+			_, err = time.Parse(time.TimeOnly, s)
+		}
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	case "uuid":
+		return reUUID.MatchString(s)
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	case "ipv6":
+		return net.ParseIP(s) != nil && strings.Contains(s, ":")
+	case "hostname":
+		return len(s) <= 253 && reHostname.MatchString(s)
+	}
+	return true
+}
+
+func isFalseSchema(sc *jsonschema.Schema) bool {
+	js, err := json.Marshal(sc)
+	return err == nil && string(js) == "false"
+}
+
+// equalJSON compares the values by their JSON representation,
+// so the schema values and the decoded numbers are comparable.
+func equalJSON(a, b any) bool {
+	return reflect.DeepEqual(normalizeJSON(a), normalizeJSON(b))
+}
+
+func normalizeJSON(v any) any {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var res any
+	if err := json.Unmarshal(js, &res); err != nil {
+		return v
+	}
+	return res
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
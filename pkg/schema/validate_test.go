@@ -0,0 +1,107 @@
+package schema_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	sc, err := schema.New(reflect.TypeOf(Search{}))
+	require.NoError(t, err)
+
+	tcases := []struct {
+		name string
+		js   string
+		exp  []schema.Violation
+	}{
+		{
+			name: "valid",
+			js:   `{"query":"golang","type":"web","args":[{"key":"k","value":"v"}]}`,
+		},
+		{
+			name: "null optional",
+			js:   `{"query":"golang","type":"web","prov":null}`,
+		},
+		{
+			name: "enum",
+			js:   `{"query":"golang","type":"news"}`,
+			exp:  []schema.Violation{{Path: "/type", Message: `value must be one of ["web","image","video"]`}},
+		},
+		{
+			name: "required and type",
+			js:   `{"query":42,"args":[{"key":"k"}]}`,
+			exp: []schema.Violation{
+				{Path: "", Message: `missing required property "type"`},
+				{Path: "/query", Message: "expected string, got number"},
+				{Path: "/args/0", Message: `missing required property "value"`},
+			},
+		},
+		{
+			name: "root type",
+			js:   `[]`,
+			exp:  []schema.Violation{{Path: "", Message: "expected object, got array"}},
+		},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := sc.Validate([]byte(tc.js))
+			if tc.exp == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *schema.ValidationError
+			require.True(t, errors.As(err, &verr), "%v", err)
+			assert.Equal(t, tc.exp, verr.Violations)
+		})
+	}
+
+	err = sc.Validate([]byte(`not a json`))
+	require.Error(t, err)
+	var verr *schema.ValidationError
+	assert.False(t, errors.As(err, &verr))
+}
+
+func TestValidate_Constraints(t *testing.T) {
+	t.Parallel()
+
+	sc := schema.MustFromAny(map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"email": map[string]any{"type": "string", "format": "email"},
+			"id":    map[string]any{"type": "string", "format": "uuid"},
+			"code":  map[string]any{"type": "string", "pattern": "^[A-Z]{3}$", "maxLength": 3},
+			"count": map[string]any{"type": "integer", "minimum": 1, "maximum": 10},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "maxItems": 2, "uniqueItems": true},
+		},
+	})
+
+	err := schema.Validate(sc, []byte(`{"email":"a@b.com","id":"123e4567-e89b-12d3-a456-426614174000","code":"ABC","count":3,"tags":["a","b"]}`))
+	require.NoError(t, err)
+
+	err = schema.Validate(sc, []byte(`{"email":"not an email","id":"123","code":"abcd","count":1.5,"tags":["a","a","b"],"extra":true}`))
+	var verr *schema.ValidationError
+	require.True(t, errors.As(err, &verr), "%v", err)
+	assert.ElementsMatch(t, []schema.Violation{
+		{Path: "", Message: `unknown property "extra"`},
+		{Path: "/email", Message: "string is not a valid email"},
+		{Path: "/id", Message: "string is not a valid uuid"},
+		{Path: "/code", Message: "string must be at most 3 characters long"},
+		{Path: "/code", Message: `string does not match the pattern "^[A-Z]{3}$"`},
+		{Path: "/count", Message: "expected integer, got number"},
+		{Path: "/tags", Message: "array must have at most 2 items"},
+		{Path: "/tags", Message: "array items 0 and 1 are equal"},
+	}, verr.Violations)
+	assert.Contains(t, verr.Details(), "- /email: string is not a valid email\n")
+
+	err = schema.Validate(sc, []byte(`{"count":11}`))
+	require.True(t, errors.As(err, &verr), "%v", err)
+	assert.Equal(t, []schema.Violation{{Path: "/count", Message: "value must be <= 10"}}, verr.Violations)
+}
@@ -0,0 +1,227 @@
+// Package tokenizer provides local estimation of the tokens count,
+// so the budgeting, truncation and rate limiting can be computed before calling the API.
+//
+// The OpenAI models use tiktoken-compatible BPE encodings,
+// the ranks are downloaded on the first use and cached in TIKTOKEN_CACHE_DIR,
+// use SetBPELoader to load them from the embedded files in the air-gapped environments.
+// Anthropic, Gemini and other models use the characters per token approximations,
+// as their tokenizers are not public.
+package tokenizer
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/xlog"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "tokenizer")
+
+const (
+	// TokensPerMessage is the overhead of the message role and separators.
+	TokensPerMessage = 3
+	// TokensPerReply is the overhead of the assistant reply primer.
+	TokensPerReply = 3
+	// ImageTokens is the estimate of the image tokens, as the image size is not known.
+	ImageTokens = 765
+	// LowDetailImageTokens is the estimate of the image tokens with the low detail.
+	LowDetailImageTokens = 85
+)
+
+// Tokenizer counts the tokens in the text.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// Approximation is the Tokenizer that estimates the tokens by the number of characters.
+type Approximation float64
+
+const (
+	// Default approximation of 4 characters per token.
+	Default Approximation = 4
+	// Anthropic approximation of 3.5 characters per token for Claude models.
+	Anthropic Approximation = 3.5
+	// Gemini approximation of 4 characters per token for Gemini models.
+	Gemini Approximation = 4
+)
+
+// CountTokens implements Tokenizer.
+func (a Approximation) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / float64(a)))
+}
+
+// BPE is the Tokenizer with tiktoken encoding.
+type BPE struct {
+	enc *tiktoken.Tiktoken
+}
+
+// CountTokens implements Tokenizer.
+func (b *BPE) CountTokens(text string) int {
+	return len(b.enc.EncodeOrdinary(text))
+}
+
+// LoadTimeout is the time to wait for the BPE ranks to load,
+// the Default approximation is used until the ranks are loaded.
+var LoadTimeout = 10 * time.Second
+
+// encodings caches the loaded encodings by name
+var encodings sync.Map
+
+// encoding is loaded once, the callers wait for done
+type encoding struct {
+	once sync.Once
+	done chan struct{}
+	t    Tokenizer
+	err  error
+}
+
+func (e *encoding) load(name string) {
+	defer close(e.done)
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		e.err = err
+		return
+	}
+	e.t = &BPE{enc: enc}
+}
+
+// SetBPELoader sets the loader of the BPE ranks,
+// it should be called before the first use, the loaded encodings are reset.
+func SetBPELoader(loader tiktoken.BpeLoader) {
+	tiktoken.SetBpeLoader(loader)
+	encodings.Clear()
+}
+
+// EncodingForModel returns the name of tiktoken encoding for the OpenAI model,
+// or empty string if the model does not use tiktoken.
+func EncodingForModel(model string) string {
+	model = strings.ToLower(model)
+	switch {
+	case strings.HasPrefix(model, "gpt-4o"),
+		strings.HasPrefix(model, "gpt-4.1"),
+		strings.HasPrefix(model, "gpt-4.5"),
+		strings.HasPrefix(model, "gpt-5"),
+		strings.HasPrefix(model, "gpt-oss"),
+		strings.HasPrefix(model, "chatgpt-"),
+		strings.HasPrefix(model, "o1"),
+		strings.HasPrefix(model, "o3"),
+		strings.HasPrefix(model, "o4"):
+		return tiktoken.MODEL_O200K_BASE
+	case strings.HasPrefix(model, "gpt-4"),
+		strings.HasPrefix(model, "gpt-3.5"),
+		strings.HasPrefix(model, "text-embedding-"):
+		return tiktoken.MODEL_CL100K_BASE
+	}
+	return ""
+}
+
+// ForModel returns the Tokenizer for the model.
+// If the BPE ranks can not be loaded, the Default approximation is returned.
+func ForModel(model string) Tokenizer {
+	if enc := EncodingForModel(model); enc != "" {
+		return forEncoding(enc)
+	}
+
+	name := strings.ToLower(model)
+	switch {
+	case strings.Contains(name, "claude"):
+		return Anthropic
+	case strings.Contains(name, "gemini"), strings.Contains(name, "gemma"):
+		return Gemini
+	}
+	return Default
+}
+
+func forEncoding(name string) Tokenizer {
+	v, _ := encodings.LoadOrStore(name, &encoding{done: make(chan struct{})})
+	e := v.(*encoding)
+	// the ranks are downloaded outside of the lock,
+	// the concurrent callers wait for the same load
+	e.once.Do(func() { go e.load(name) })
+
+	select {
+	case <-e.done:
+	case <-time.After(LoadTimeout):
+		logger.KV(xlog.WARNING,
+			"reason", "load_encoding",
+			"encoding", name,
+			"err", "timeout",
+		)
+		return Default
+	}
+
+	if e.err != nil {
+		logger.KV(xlog.WARNING,
+			"reason", "load_encoding",
+			"encoding", name,
+			"err", e.err.Error(),
+		)
+		// the failed encoding is not cached, the next call retries
+		encodings.CompareAndDelete(name, e)
+		return Default
+	}
+	return e.t
+}
+
+// CountTokens returns the number of tokens in the text for the model.
+func CountTokens(model, text string) int {
+	return ForModel(model).CountTokens(text)
+}
+
+// CountMessageTokens returns the estimated number of the prompt tokens
+// in the messages for the model, including the messages overhead.
+func CountMessageTokens(model string, messages []llms.Message) int {
+	if len(messages) == 0 {
+		return 0
+	}
+
+	t := ForModel(model)
+	count := TokensPerReply
+	for _, msg := range messages {
+		count += TokensPerMessage
+		for _, part := range msg.Parts {
+			count += countPart(t, part)
+		}
+	}
+	return count
+}
+
+func countPart(t Tokenizer, part llms.ContentPart) int {
+	switch p := part.(type) {
+	case llms.TextContent:
+		return t.CountTokens(p.Text)
+	case llms.ImageURLContent:
+		if p.Detail == "low" {
+			return LowDetailImageTokens
+		}
+		return ImageTokens
+	case llms.BinaryContent:
+		if strings.HasPrefix(p.MIMEType, "image/") {
+			return ImageTokens
+		}
+		return Default.CountTokens(string(p.Data))
+	case llms.DocumentContent:
+		if p.FileID != "" {
+			return t.CountTokens(p.Title)
+		}
+		return Default.CountTokens(string(p.Data))
+	case llms.ToolCall:
+		if p.FunctionCall == nil {
+			return 0
+		}
+		return t.CountTokens(p.FunctionCall.Name) + t.CountTokens(p.FunctionCall.Arguments)
+	case llms.ToolCallResponse:
+		return t.CountTokens(p.Name) + t.CountTokens(p.Content)
+	case llms.ThinkingContent:
+		return t.CountTokens(p.Thinking)
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package tokenizer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/tokenizer"
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// byteLoader loads the ranks of single bytes, so each byte is a token
+type byteLoader struct {
+	fail bool
+	wait chan struct{}
+}
+
+func (l byteLoader) LoadTiktokenBpe(string) (map[string]int, error) {
+	if l.wait != nil {
+		<-l.wait
+	}
+	if l.fail {
+		return nil, errors.New("offline")
+	}
+	ranks := map[string]int{}
+	for i := range 256 {
+		ranks[string([]byte{byte(i)})] = i
+	}
+	return ranks, nil
+}
+
+func TestEncodingForModel(t *testing.T) {
+	t.Parallel()
+
+	tcases := map[string]string{
+		"gpt-4o-mini":            "o200k_base",
+		"gpt-4.1":                "o200k_base",
+		"gpt-5":                  "o200k_base",
+		"o3-mini":                "o200k_base",
+		"gpt-4-turbo":            "cl100k_base",
+		"gpt-3.5-turbo":          "cl100k_base",
+		"text-embedding-3-small": "cl100k_base",
+		"claude-sonnet-4-5":      "",
+		"gemini-2.5-flash":       "",
+		"sonar":                  "",
+	}
+	for model, exp := range tcases {
+		assert.Equal(t, exp, tokenizer.EncodingForModel(model), model)
+	}
+}
+
+func TestApproximation(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, tokenizer.Anthropic, tokenizer.ForModel("claude-sonnet-4-5"))
+	assert.Equal(t, tokenizer.Anthropic, tokenizer.ForModel("anthropic.claude-3-haiku-20240307-v1:0"))
+	assert.Equal(t, tokenizer.Gemini, tokenizer.ForModel("gemini-2.5-pro"))
+	assert.Equal(t, tokenizer.Default, tokenizer.ForModel("llama-3.1-8b"))
+
+	assert.Equal(t, 0, tokenizer.CountTokens("llama", ""))
+	assert.Equal(t, 3, tokenizer.CountTokens("llama", "hello world"))
+	assert.Equal(t, 4, tokenizer.CountTokens("claude-sonnet-4-5", "hello world"))
+	// runes are counted, not bytes
+	assert.Equal(t, 1, tokenizer.CountTokens("llama", "привет"[:4]+"ab"))
+}
+
+func TestCountMessageTokens(t *testing.T) {
+	// fallback to the approximation, when the ranks can not be loaded
+	tokenizer.SetBPELoader(byteLoader{fail: true})
+	assert.Equal(t, tokenizer.Default, tokenizer.ForModel("gpt-4"))
+	assert.Equal(t, 2, tokenizer.CountTokens("gpt-4", "hello"))
+
+	// the failure is not cached
+	tiktoken.SetBpeLoader(byteLoader{})
+	_, ok := tokenizer.ForModel("gpt-4").(*tokenizer.BPE)
+	assert.True(t, ok)
+
+	// the approximation is used, until the slow loader returns
+	wait := make(chan struct{})
+	timeout := tokenizer.LoadTimeout
+	tokenizer.LoadTimeout = 10 * time.Millisecond
+	tokenizer.SetBPELoader(byteLoader{wait: wait})
+	assert.Equal(t, tokenizer.Default, tokenizer.ForModel("gpt-4o"))
+	close(wait)
+	tokenizer.LoadTimeout = timeout
+	_, ok = tokenizer.ForModel("gpt-4o").(*tokenizer.BPE)
+	assert.True(t, ok)
+	assert.Equal(t, 5, tokenizer.CountTokens("gpt-4o", "hello"))
+
+	assert.Equal(t, 0, tokenizer.CountMessageTokens("gpt-4o", nil))
+
+	messages := []llms.Message{
+		llms.MessageFromTextParts(llms.RoleSystem, "be brief"),
+		{
+			Role: llms.RoleHuman,
+			Parts: []llms.ContentPart{
+				llms.TextContent{Text: "what is it?"},
+				llms.ImageURLContent{URL: "https://example.com/a.png", Detail: "low"},
+				llms.BinaryContent{MIMEType: "image/png", Data: []byte("png")},
+			},
+		},
+		llms.MessageFromToolCalls(llms.RoleAI, llms.ToolCall{
+			ID: "c1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "look", Arguments: "{}"},
+		}),
+		llms.MessageFromToolResponse(llms.RoleTool, llms.ToolCallResponse{ToolCallID: "c1", Name: "look", Content: "a cat"}),
+	}
+	exp := tokenizer.TokensPerReply + 4*tokenizer.TokensPerMessage +
+		8 + // be brief
+		11 + tokenizer.LowDetailImageTokens + tokenizer.ImageTokens +
+		4 + 2 + // look {}
+		4 + 5 // look a cat
+	assert.Equal(t, exp, tokenizer.CountMessageTokens("gpt-4o", messages))
+}
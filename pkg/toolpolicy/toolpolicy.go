@@ -0,0 +1,182 @@
+// Package toolpolicy provides the policies of the tool calls,
+// the CEL expressions evaluated against the parsed tool arguments and the chat context
+// before the tool is called, for example:
+//
+//	args.amount < 1000
+//	chat.tenant_id in ["t1", "t2"]
+//	!has(args.namespace) || args.namespace.startsWith("dev-")
+//
+// The expression has the variables:
+//   - tool: the name of the tool
+//   - args: the map of the tool arguments
+//   - chat: the map with tenant_id, chat_id, org_id, user_id, run_id, locale, timezone and metadata
+//
+// The denied call is reported to the LLM as the permission error with the message of the rule,
+// so the LLM can change the arguments or explain the denial to the user.
+package toolpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/xlog"
+	"github.com/google/cel-go/cel"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "toolpolicy")
+
+// AllTools is the tool name of the rules applied to all tools.
+const AllTools = "*"
+
+// ErrPolicyDenied is returned when the tool call is denied by the policy,
+// the error is also marked with chatmodel.ErrToolPermission.
+var ErrPolicyDenied = errors.New("policy denied")
+
+// Rule is the CEL expression, that must return true to allow the tool call.
+type Rule struct {
+	// Tool is the name of the tool, or AllTools.
+	Tool string `json:"tool" yaml:"tool"`
+	// Expression is the CEL expression, that must return bool.
+	Expression string `json:"expression" yaml:"expression"`
+	// Message is returned to the LLM when the call is denied,
+	// the expression is returned if the message is empty.
+	Message string `json:"message,omitempty" yaml:"message"`
+}
+
+type compiledRule struct {
+	Rule
+	program cel.Program
+}
+
+// Policy checks the tool calls with the rules.
+// It is safe for the concurrent use.
+type Policy struct {
+	rules []compiledRule
+}
+
+// New returns the Policy with the rules,
+// the error is returned if any expression is invalid or does not return bool.
+func New(rules ...Rule) (*Policy, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("tool", cel.StringType),
+		cel.Variable("args", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("chat", cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create CEL environment")
+	}
+
+	p := &Policy{}
+	for _, r := range rules {
+		if r.Tool == "" {
+			return nil, errors.Newf("tool is required for the rule %q", r.Expression)
+		}
+		ast, issues := env.Compile(r.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, errors.Newf("invalid rule %q: %s", r.Expression, issues.Err().Error())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, errors.Newf("invalid rule %q: the result must be bool, got %s", r.Expression, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid rule %q", r.Expression)
+		}
+		p.rules = append(p.rules, compiledRule{Rule: r, program: program})
+	}
+	return p, nil
+}
+
+// Check evaluates the rules of the tool against the JSON arguments,
+// and returns the error marked with ErrPolicyDenied, if any rule does not return true.
+// The rules, which can not be evaluated, deny the call,
+// use has() for the optional arguments.
+func (p *Policy) Check(ctx context.Context, tool string, args string) error {
+	var vars map[string]any
+	for _, r := range p.rules {
+		if r.Tool != AllTools && !strings.EqualFold(r.Tool, tool) {
+			continue
+		}
+		if vars == nil {
+			parsed := map[string]any{}
+			if strings.TrimSpace(args) != "" {
+				if err := json.Unmarshal(llmutils.CleanJSON([]byte(args)), &parsed); err != nil {
+					return errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+				}
+			}
+			vars = map[string]any{
+				"tool": tool,
+				"args": parsed,
+				"chat": chatVars(ctx),
+			}
+		}
+
+		msg := r.Message
+		if msg == "" {
+			msg = r.Expression
+		}
+		out, _, err := r.program.Eval(vars)
+		if err != nil {
+			logger.ContextKV(ctx, xlog.DEBUG,
+				"tool", tool,
+				"status", "policy_eval_failed",
+				"rule", r.Expression,
+				"err", err.Error(),
+			)
+			return denied(msg)
+		}
+		if allowed, ok := out.Value().(bool); !ok || !allowed {
+			logger.ContextKV(ctx, xlog.DEBUG,
+				"tool", tool,
+				"status", "policy_denied",
+				"rule", r.Expression,
+			)
+			return denied(msg)
+		}
+	}
+	return nil
+}
+
+func denied(msg string) error {
+	err := errors.Newf("policy denied: %s", msg)
+	return errors.Mark(errors.Mark(err, ErrPolicyDenied), chatmodel.ErrToolPermission)
+}
+
+// chatVars returns the chat variables of the expression.
+func chatVars(ctx context.Context) map[string]any {
+	vars := map[string]any{
+		"tenant_id": "",
+		"chat_id":   "",
+		"org_id":    "",
+		"user_id":   "",
+		"run_id":    "",
+		"locale":    "",
+		"timezone":  "",
+		"metadata":  map[string]any{},
+	}
+	chatCtx := chatmodel.GetChatContext(ctx)
+	if chatCtx == nil {
+		return vars
+	}
+	vars["tenant_id"] = chatCtx.GetTenantID()
+	vars["chat_id"] = chatCtx.GetChatID()
+	vars["org_id"] = chatCtx.GetOrgID()
+	vars["user_id"] = chatCtx.GetUserID()
+	vars["run_id"] = chatCtx.GetRunID()
+	vars["locale"] = chatCtx.GetLocale()
+	vars["timezone"] = chatCtx.GetTimezone()
+
+	// the metadata is converted to the JSON types, which are supported by CEL
+	if md := chatCtx.Metadata(); len(md) > 0 {
+		var metadata map[string]any
+		if js, err := json.Marshal(md); err == nil && json.Unmarshal(js, &metadata) == nil {
+			vars["metadata"] = metadata
+		}
+	}
+	return vars
+}
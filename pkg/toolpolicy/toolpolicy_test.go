@@ -0,0 +1,90 @@
+package toolpolicy_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/toolpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tcs := []struct {
+		rule toolpolicy.Rule
+		exp  string
+	}{
+		{toolpolicy.Rule{Expression: "true"}, `tool is required for the rule "true"`},
+		{toolpolicy.Rule{Tool: "pay", Expression: "args.amount <"}, `invalid rule "args.amount <": ERROR: <input>:1:14: Syntax error: mismatched input '<EOF>'`},
+		{toolpolicy.Rule{Tool: "pay", Expression: "tool + 'x'"}, `invalid rule "tool + 'x'": the result must be bool, got string`},
+		{toolpolicy.Rule{Tool: "pay", Expression: "unknown == 1"}, `invalid rule "unknown == 1": ERROR: <input>:1:1: undeclared reference to 'unknown'`},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.rule.Expression, func(t *testing.T) {
+			t.Parallel()
+			_, err := toolpolicy.New(tc.rule)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.exp)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	p, err := toolpolicy.New(
+		toolpolicy.Rule{Tool: "payment", Expression: "args.amount < 1000", Message: "the amount must be less than 1000"},
+		toolpolicy.Rule{Tool: "payment", Expression: "!has(args.currency) || args.currency in ['USD', 'EUR']"},
+		toolpolicy.Rule{Tool: toolpolicy.AllTools, Expression: "chat.tenant_id in ['t1', 't2']", Message: "the tenant is not allowed"},
+		toolpolicy.Rule{Tool: "kubectl", Expression: "args.namespace.startsWith('dev-') || chat.metadata.role == 'admin'"},
+	)
+	require.NoError(t, err)
+
+	chatCtx := chatmodel.NewChatContext("t1", "chat1", nil)
+	ctx := chatmodel.WithChatContext(context.Background(), chatCtx)
+
+	tcs := []struct {
+		name string
+		ctx  context.Context
+		tool string
+		args string
+		exp  string
+	}{
+		{"allowed", ctx, "payment", `{"amount": 10, "currency": "USD"}`, ""},
+		{"optional", ctx, "payment", `{"amount": 999.99}`, ""},
+		{"amount", ctx, "payment", `{"amount": 1000}`, "policy denied: the amount must be less than 1000"},
+		{"currency", ctx, "payment", `{"amount": 1, "currency": "BTC"}`, "policy denied: !has(args.currency) || args.currency in ['USD', 'EUR']"},
+		{"missing argument", ctx, "payment", `{}`, "policy denied: the amount must be less than 1000"},
+		{"tool name case", ctx, "Payment", `{"amount": 5000}`, "policy denied: the amount must be less than 1000"},
+		{"other tool", ctx, "search", `{"query": "x"}`, ""},
+		{"no chat context", context.Background(), "search", ``, "policy denied: the tenant is not allowed"},
+		{"namespace", ctx, "kubectl", `{"namespace": "dev-1"}`, ""},
+		{"namespace denied", ctx, "kubectl", `{"namespace": "prod"}`, "policy denied: args.namespace.startsWith('dev-') || chat.metadata.role == 'admin'"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := p.Check(tc.ctx, tc.tool, tc.args)
+			if tc.exp == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.EqualError(t, err, tc.exp)
+			assert.ErrorIs(t, err, toolpolicy.ErrPolicyDenied)
+			assert.Equal(t, chatmodel.ToolErrorPermission, chatmodel.GetToolErrorCategory(err))
+		})
+	}
+
+	err = p.Check(ctx, "payment", `not json`)
+	assert.True(t, errors.Is(err, chatmodel.ErrFailedUnmarshalInput))
+
+	admin := chatmodel.NewChatContext("t2", "chat2", nil)
+	admin.SetMetadata("role", "admin")
+	err = p.Check(chatmodel.WithChatContext(context.Background(), admin), "kubectl", `{"namespace": "prod"}`)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"context"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/encoding"
+	"github.com/effective-security/gogentic/pkg/prompts"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/gogentic/tools/calc"
+	"github.com/effective-security/gogentic/tools/tavily"
+)
+
+// DefaultAssistantKind is the kind of the assistant, when not set in the config.
+const DefaultAssistantKind = "assistant"
+
+// Default is the registry with the built-in tools and assistants,
+// the applications register their own kinds in init functions.
+var Default = newDefault()
+
+// TavilyConfig is the config of the tavily tool.
+type TavilyConfig struct {
+	// APIKey is the Tavily API key, TAVILY_API_KEY environment variable if not set.
+	APIKey string `json:"api_key,omitempty" yaml:"api_key,omitempty"`
+}
+
+// AssistantOptions is the config of the default assistant kind.
+type AssistantOptions struct {
+	Temperature *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+}
+
+func newDefault() *Registry {
+	r := New()
+	_ = RegisterTool(r, "calculator", func(_ context.Context, _ *struct{}, _ *Deps) (tools.ITool, error) {
+		return calc.New(), nil
+	})
+	_ = RegisterTool(r, "tavily", func(_ context.Context, cfg *TavilyConfig, _ *Deps) (tools.ITool, error) {
+		if cfg.APIKey != "" {
+			return tavily.NewWithAPIKey(cfg.APIKey)
+		}
+		return tavily.New()
+	})
+	_ = RegisterAssistant(r, DefaultAssistantKind, NewPlainTextAssistant)
+	return r
+}
+
+// NewPlainTextAssistant creates the assistant with the plain text output.
+func NewPlainTextAssistant(_ context.Context, spec *AssistantSpec, cfg *AssistantOptions, _ *Deps) (assistants.IAssistant, error) {
+	opts := []assistants.Option{assistants.WithMode(encoding.ModePlainText)}
+	if cfg.Temperature != nil {
+		opts = append(opts, assistants.WithTemperature(*cfg.Temperature))
+	}
+	if cfg.MaxTokens > 0 {
+		opts = append(opts, assistants.WithMaxTokens(cfg.MaxTokens))
+	}
+
+	ast := assistants.NewAssistant[chatmodel.String](spec.LLM, prompts.NewPromptTemplate(spec.Prompt, nil), opts...).
+		WithName(spec.Name).
+		WithTools(spec.Tools...)
+	if spec.Description != "" {
+		ast.WithDescription(spec.Description)
+	}
+	return ast, nil
+}
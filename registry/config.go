@@ -0,0 +1,138 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/x/configloader"
+)
+
+// Config is the configuration of the tools and the assistants:
+//
+//	tools:
+//	  - calculator
+//	  - name: search
+//	    kind: tavily
+//	    config:
+//	      api_key: ${TAVILY_API_KEY}
+//	assistants:
+//	  - name: researcher
+//	    prompt: You are a research assistant.
+//	    models: [gpt-4o]
+//	    tools: [search, calculator]
+type Config struct {
+	// Tools are created in order, before the assistants.
+	Tools []*ToolConfig `json:"tools,omitempty" yaml:"tools,omitempty"`
+	// Assistants are created in order, the assistant can use the previously created assistants.
+	Assistants []*AssistantConfig `json:"assistants,omitempty" yaml:"assistants,omitempty"`
+}
+
+// ToolConfig is the configuration of the tool,
+// the string value is the shortcut for the tool with the same name and kind.
+type ToolConfig struct {
+	// Name is the name of the tool referenced by the assistants, the Kind if not set.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Kind is the registered kind of the tool, the Name if not set.
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// Config is decoded into the config struct of the kind.
+	Config map[string]any `json:"config,omitempty" yaml:"config,omitempty"`
+}
+
+type toolConfig ToolConfig
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *ToolConfig) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*c = ToolConfig{Name: name}
+		return nil
+	}
+	return json.Unmarshal(data, (*toolConfig)(c))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *ToolConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*c = ToolConfig{Name: name}
+		return nil
+	}
+	return unmarshal((*toolConfig)(c))
+}
+
+// AssistantConfig is the configuration of the assistant.
+type AssistantConfig struct {
+	// Name is the name of the assistant.
+	Name string `json:"name" yaml:"name"`
+	// Kind is the registered kind of the assistant, DefaultAssistantKind if not set.
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// Description is the description of the assistant, for the other assistants.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Prompt is the system prompt template.
+	Prompt string `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	// Models are the preferred models, see llmfactory.Factory.AssistantModel.
+	Models []string `json:"models,omitempty" yaml:"models,omitempty"`
+	// Tools are the names of the tools of the assistant.
+	Tools []string `json:"tools,omitempty" yaml:"tools,omitempty"`
+	// Config is decoded into the config struct of the kind.
+	Config map[string]any `json:"config,omitempty" yaml:"config,omitempty"`
+}
+
+// LoadConfig from file
+func LoadConfig(file string) (*Config, error) {
+	cfg := new(Config)
+	if file == "" {
+		return cfg, nil
+	}
+
+	err := configloader.UnmarshalAndExpand(file, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// decodeConfig decodes the config section into C, the unknown fields are rejected
+func decodeConfig[C any](section map[string]any) (*C, error) {
+	cfg := new(C)
+	if len(section) == 0 {
+		return cfg, nil
+	}
+	js, err := json.Marshal(normalize(section))
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid config")
+	}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(cfg); err != nil {
+		return nil, errors.WithMessage(err, "invalid config")
+	}
+	return cfg, nil
+}
+
+// normalize converts the map[any]any of YAML v2 to map[string]any
+func normalize(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(val))
+		for k, v := range val {
+			res[k] = normalize(v)
+		}
+		return res
+	case map[any]any:
+		res := make(map[string]any, len(val))
+		for k, v := range val {
+			res[fmt.Sprint(k)] = normalize(v)
+		}
+		return res
+	case []any:
+		res := make([]any, len(val))
+		for i, v := range val {
+			res[i] = normalize(v)
+		}
+		return res
+	}
+	return v
+}
@@ -0,0 +1,3 @@
+// Package registry provides the registries of the tool and assistant constructors,
+// to assemble the agents from the configuration instead of the code-only wiring.
+package registry
@@ -0,0 +1,263 @@
+package registry
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/tools"
+)
+
+// ErrUnknownKind is returned when the kind of the tool or the assistant is not registered.
+var ErrUnknownKind = errors.New("unknown kind")
+
+// ModelFactory creates the LLMs of the assistants, llmfactory.Factory implements it.
+type ModelFactory interface {
+	// AssistantModel returns the model of the assistant by its name.
+	AssistantModel(assistantName string, preferredModels ...string) (llms.Model, error)
+}
+
+// Deps are the dependencies injected into the constructors.
+type Deps struct {
+	// Factory creates the LLMs of the assistants.
+	Factory ModelFactory
+	// Values are the application dependencies, like the stores and the clients, keyed by name,
+	// see Value.
+	Values map[string]any
+	// Agents are the tools and the assistants created so far.
+	Agents *Agents
+}
+
+// Value returns the dependency by name, or error if it is not provided or has a different type.
+func Value[T any](deps *Deps, name string) (T, error) {
+	var zero T
+	v, ok := deps.Values[name]
+	if !ok {
+		return zero, errors.Errorf("dependency %q is not provided", name)
+	}
+	val, ok := v.(T)
+	if !ok {
+		return zero, errors.Errorf("dependency %q is %T, expected %T", name, v, zero)
+	}
+	return val, nil
+}
+
+// Agents are the tools and the assistants created from the config, keyed by name.
+type Agents struct {
+	Tools      map[string]tools.ITool
+	Assistants map[string]assistants.IAssistant
+}
+
+// Tool returns the tool by name.
+func (a *Agents) Tool(name string) (tools.ITool, error) {
+	if t, ok := a.Tools[name]; ok {
+		return t, nil
+	}
+	return nil, errors.Errorf("tool %q not found", name)
+}
+
+// Assistant returns the assistant by name.
+func (a *Agents) Assistant(name string) (assistants.IAssistant, error) {
+	if ast, ok := a.Assistants[name]; ok {
+		return ast, nil
+	}
+	return nil, errors.Errorf("assistant %q not found", name)
+}
+
+// AssistantSpec is the resolved configuration of the assistant passed to the constructor.
+type AssistantSpec struct {
+	Name        string
+	Description string
+	Prompt      string
+	// LLM is the model created by the factory with the preferred models.
+	LLM llms.Model
+	// Tools are the tools of the assistant, in the config order.
+	Tools []tools.ITool
+}
+
+// ToolConstructor creates the tool from the config section decoded into C.
+type ToolConstructor[C any] func(ctx context.Context, cfg *C, deps *Deps) (tools.ITool, error)
+
+// AssistantConstructor creates the assistant from the spec and the config section decoded into C.
+type AssistantConstructor[C any] func(ctx context.Context, spec *AssistantSpec, cfg *C, deps *Deps) (assistants.IAssistant, error)
+
+type toolFactory func(ctx context.Context, section map[string]any, deps *Deps) (tools.ITool, error)
+
+type assistantFactory func(ctx context.Context, spec *AssistantSpec, section map[string]any, deps *Deps) (assistants.IAssistant, error)
+
+// Registry stores the constructors of the tools and the assistants by kind.
+// It is safe for concurrent use.
+type Registry struct {
+	lock       sync.RWMutex
+	tools      map[string]toolFactory
+	assistants map[string]assistantFactory
+}
+
+// New returns a new empty Registry.
+func New() *Registry {
+	return &Registry{
+		tools:      make(map[string]toolFactory),
+		assistants: make(map[string]assistantFactory),
+	}
+}
+
+// RegisterTool adds the constructor of the tool kind, replacing the existing one,
+// the config section of the tool is decoded into C.
+func RegisterTool[C any](r *Registry, kind string, fn ToolConstructor[C]) error {
+	if kind == "" || fn == nil {
+		return errors.New("tool kind and constructor are required")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.tools[kind] = func(ctx context.Context, section map[string]any, deps *Deps) (tools.ITool, error) {
+		cfg, err := decodeConfig[C](section)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ctx, cfg, deps)
+	}
+	return nil
+}
+
+// RegisterAssistant adds the constructor of the assistant kind, replacing the existing one,
+// the config section of the assistant is decoded into C.
+func RegisterAssistant[C any](r *Registry, kind string, fn AssistantConstructor[C]) error {
+	if kind == "" || fn == nil {
+		return errors.New("assistant kind and constructor are required")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.assistants[kind] = func(ctx context.Context, spec *AssistantSpec, section map[string]any, deps *Deps) (assistants.IAssistant, error) {
+		cfg, err := decodeConfig[C](section)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ctx, spec, cfg, deps)
+	}
+	return nil
+}
+
+// ToolKinds returns the registered kinds of the tools, sorted.
+func (r *Registry) ToolKinds() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return sortedKeys(r.tools)
+}
+
+// AssistantKinds returns the registered kinds of the assistants, sorted.
+func (r *Registry) AssistantKinds() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return sortedKeys(r.assistants)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Build creates the tools and then the assistants of the config, in order.
+// The deps are optional, the Factory is required for the assistants.
+func (r *Registry) Build(ctx context.Context, cfg *Config, deps *Deps) (*Agents, error) {
+	agents := &Agents{
+		Tools:      make(map[string]tools.ITool),
+		Assistants: make(map[string]assistants.IAssistant),
+	}
+	d := Deps{Agents: agents}
+	if deps != nil {
+		d.Factory = deps.Factory
+		d.Values = deps.Values
+	}
+
+	for _, tc := range cfg.Tools {
+		name, kind := tc.Name, tc.Kind
+		if name == "" {
+			name = kind
+		}
+		if kind == "" {
+			kind = name
+		}
+		if name == "" {
+			return nil, errors.New("tool name or kind is required")
+		}
+		if _, ok := agents.Tools[name]; ok {
+			return nil, errors.Errorf("tool %s: duplicate name", name)
+		}
+
+		r.lock.RLock()
+		factory := r.tools[kind]
+		r.lock.RUnlock()
+		if factory == nil {
+			return nil, errors.Wrapf(ErrUnknownKind, "tool %s: %q", name, kind)
+		}
+		tool, err := factory(ctx, tc.Config, &d)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "tool %s", name)
+		}
+		agents.Tools[name] = tool
+	}
+
+	for _, ac := range cfg.Assistants {
+		if ac.Name == "" {
+			return nil, errors.New("assistant name is required")
+		}
+		if _, ok := agents.Assistants[ac.Name]; ok {
+			return nil, errors.Errorf("assistant %s: duplicate name", ac.Name)
+		}
+		kind := ac.Kind
+		if kind == "" {
+			kind = DefaultAssistantKind
+		}
+
+		r.lock.RLock()
+		factory := r.assistants[kind]
+		r.lock.RUnlock()
+		if factory == nil {
+			return nil, errors.Wrapf(ErrUnknownKind, "assistant %s: %q", ac.Name, kind)
+		}
+
+		spec := &AssistantSpec{
+			Name:        ac.Name,
+			Description: ac.Description,
+			Prompt:      ac.Prompt,
+		}
+		for _, name := range ac.Tools {
+			tool, err := agents.Tool(name)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "assistant %s", ac.Name)
+			}
+			spec.Tools = append(spec.Tools, tool)
+		}
+		if d.Factory == nil {
+			return nil, errors.Errorf("assistant %s: model factory is required", ac.Name)
+		}
+		llm, err := d.Factory.AssistantModel(ac.Name, ac.Models...)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "assistant %s", ac.Name)
+		}
+		spec.LLM = llm
+
+		ast, err := factory(ctx, spec, ac.Config, &d)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "assistant %s", ac.Name)
+		}
+		agents.Assistants[ac.Name] = ast
+	}
+	return agents, nil
+}
+
+// Load loads the config from the file, and builds the agents with the Default registry.
+func Load(ctx context.Context, file string, deps *Deps) (*Agents, error) {
+	cfg, err := LoadConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	return Default.Build(ctx, cfg, deps)
+}
@@ -0,0 +1,229 @@
+package registry_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/mocks/mockllms"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/registry"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type echoConfig struct {
+	Prefix string `json:"prefix"`
+}
+
+type echoTool struct {
+	name   string
+	prefix string
+}
+
+func (t *echoTool) Name() string                   { return t.name }
+func (t *echoTool) Description() string            { return "Echoes the input." }
+func (t *echoTool) Parameters() *jsonschema.Schema { return &jsonschema.Schema{Type: "object"} }
+func (t *echoTool) Call(_ context.Context, input string) (string, error) {
+	return t.prefix + input, nil
+}
+
+type fakeFactory struct {
+	llm       llms.Model
+	preferred map[string][]string
+}
+
+func (f *fakeFactory) AssistantModel(assistantName string, preferredModels ...string) (llms.Model, error) {
+	if assistantName == "unavailable" {
+		return nil, errors.New("no model")
+	}
+	f.preferred[assistantName] = preferredModels
+	return f.llm, nil
+}
+
+// newModel returns the model that expects the number of the assistants created with it.
+func newModel(t *testing.T, assistants int) llms.Model {
+	ctrl := gomock.NewController(t)
+	llm := mockllms.NewMockModel(ctrl)
+	llm.EXPECT().GetProviderType().Return(llms.ProviderOpenAI).Times(assistants)
+	return llm
+}
+
+func newRegistry(t *testing.T) *registry.Registry {
+	r := registry.New()
+	require.NoError(t, registry.RegisterTool(r, "echo", func(_ context.Context, cfg *echoConfig, deps *registry.Deps) (tools.ITool, error) {
+		if cfg.Prefix == "" {
+			prefix, err := registry.Value[string](deps, "prefix")
+			if err != nil {
+				return nil, err
+			}
+			cfg.Prefix = prefix
+		}
+		return &echoTool{name: "echo", prefix: cfg.Prefix}, nil
+	}))
+	require.NoError(t, registry.RegisterAssistant(r, registry.DefaultAssistantKind, registry.NewPlainTextAssistant))
+	return r
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := registry.LoadConfig("testdata/agents.yaml")
+	require.NoError(t, err)
+	require.Len(t, cfg.Tools, 2)
+	assert.Equal(t, &registry.ToolConfig{Name: "calculator"}, cfg.Tools[0])
+	assert.Equal(t, "lookup", cfg.Tools[1].Name)
+	assert.Equal(t, "echo", cfg.Tools[1].Kind)
+	require.Len(t, cfg.Assistants, 1)
+	assert.Equal(t, []string{"calculator", "lookup"}, cfg.Assistants[0].Tools)
+
+	factory := &fakeFactory{llm: newModel(t, 1), preferred: map[string][]string{}}
+
+	r := newRegistry(t)
+	require.NoError(t, registry.RegisterTool(r, "calculator", func(_ context.Context, _ *struct{}, _ *registry.Deps) (tools.ITool, error) {
+		return &echoTool{name: "calculator"}, nil
+	}))
+	agents, err := r.Build(context.Background(), cfg, &registry.Deps{Factory: factory})
+	require.NoError(t, err)
+
+	lookup, err := agents.Tool("lookup")
+	require.NoError(t, err)
+	res, err := lookup.Call(context.Background(), "Acme")
+	require.NoError(t, err)
+	assert.Equal(t, "found: Acme", res)
+
+	analyst, err := agents.Assistant("analyst")
+	require.NoError(t, err)
+	assert.Equal(t, "analyst", analyst.Name())
+	assert.Equal(t, "Analyzes the numbers.", analyst.Description())
+	assert.Len(t, analyst.GetTools(), 2)
+	assert.Equal(t, []string{"gpt-4o"}, factory.preferred["analyst"])
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	factory := &fakeFactory{llm: newModel(t, 1), preferred: map[string][]string{}}
+
+	var cfg registry.Config
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"tools": ["echo", {"name": "yell", "kind": "echo", "config": {"prefix": "!"}}],
+		"assistants": [{"name": "helper", "prompt": "You are helpful.", "tools": ["yell"], "config": {"temperature": 0.1}}]
+	}`), &cfg))
+
+	r := newRegistry(t)
+	assert.Equal(t, []string{"echo"}, r.ToolKinds())
+	assert.Equal(t, []string{registry.DefaultAssistantKind}, r.AssistantKinds())
+
+	agents, err := r.Build(context.Background(), &cfg, &registry.Deps{
+		Factory: factory,
+		Values:  map[string]any{"prefix": "> "},
+	})
+	require.NoError(t, err)
+	assert.Len(t, agents.Tools, 2)
+
+	yell, err := agents.Tool("yell")
+	require.NoError(t, err)
+	res, err := yell.Call(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "!hi", res)
+
+	echo, err := agents.Tool("echo")
+	require.NoError(t, err)
+	res, err = echo.Call(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "> hi", res)
+
+	helper, err := agents.Assistant("helper")
+	require.NoError(t, err)
+	require.Len(t, helper.GetTools(), 1)
+	assert.Equal(t, "echo", helper.GetTools()[0].Name())
+	assert.Empty(t, factory.preferred["helper"])
+
+	_, err = agents.Assistant("missing")
+	assert.EqualError(t, err, `assistant "missing" not found`)
+}
+
+func TestBuild_Errors(t *testing.T) {
+	t.Parallel()
+
+	factory := &fakeFactory{llm: newModel(t, 0), preferred: map[string][]string{}}
+
+	tests := []struct {
+		name string
+		cfg  *registry.Config
+		exp  string
+	}{
+		{
+			name: "unknown tool kind",
+			cfg:  &registry.Config{Tools: []*registry.ToolConfig{{Name: "search", Kind: "bing"}}},
+			exp:  `tool search: "bing": unknown kind`,
+		},
+		{
+			name: "duplicate tool",
+			cfg: &registry.Config{Tools: []*registry.ToolConfig{
+				{Name: "echo", Config: map[string]any{"prefix": "!"}},
+				{Kind: "echo", Config: map[string]any{"prefix": "?"}},
+			}},
+			exp: "tool echo: duplicate name",
+		},
+		{
+			name: "invalid tool config",
+			cfg: &registry.Config{Tools: []*registry.ToolConfig{{
+				Name:   "echo",
+				Config: map[string]any{"prefix": "!", "suffix": "!"},
+			}}},
+			exp: `tool echo: invalid config: json: unknown field "suffix"`,
+		},
+		{
+			name: "missing tool",
+			cfg:  &registry.Config{Assistants: []*registry.AssistantConfig{{Name: "helper", Tools: []string{"echo"}}}},
+			exp:  `assistant helper: tool "echo" not found`,
+		},
+		{
+			name: "unknown assistant kind",
+			cfg:  &registry.Config{Assistants: []*registry.AssistantConfig{{Name: "helper", Kind: "planner"}}},
+			exp:  `assistant helper: "planner": unknown kind`,
+		},
+		{
+			name: "no model",
+			cfg:  &registry.Config{Assistants: []*registry.AssistantConfig{{Name: "unavailable"}}},
+			exp:  "assistant unavailable: no model",
+		},
+		{
+			name: "no name",
+			cfg:  &registry.Config{Assistants: []*registry.AssistantConfig{{Prompt: "You are helpful."}}},
+			exp:  "assistant name is required",
+		},
+	}
+	r := newRegistry(t)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := r.Build(context.Background(), tc.cfg, &registry.Deps{Factory: factory})
+			assert.EqualError(t, err, tc.exp)
+		})
+	}
+
+	_, err := r.Build(context.Background(), &registry.Config{Tools: []*registry.ToolConfig{{Kind: "bing"}}}, nil)
+	assert.True(t, errors.Is(err, registry.ErrUnknownKind))
+}
+
+func TestValue(t *testing.T) {
+	t.Parallel()
+
+	deps := &registry.Deps{Values: map[string]any{"limit": 10}}
+	v, err := registry.Value[int](deps, "limit")
+	require.NoError(t, err)
+	assert.Equal(t, 10, v)
+
+	_, err = registry.Value[string](deps, "limit")
+	assert.EqualError(t, err, `dependency "limit" is int, expected string`)
+
+	_, err = registry.Value[int](deps, "offset")
+	assert.EqualError(t, err, `dependency "offset" is not provided`)
+}
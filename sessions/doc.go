@@ -0,0 +1,4 @@
+// Package sessions provides the session manager for the chats, that serializes the concurrent runs
+// of the assistants in the same chat, tracks the activity of the chats, and expires the idle sessions,
+// with the hooks to persist the sessions in the external backends.
+package sessions
@@ -0,0 +1,335 @@
+package sessions
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/xlog"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic", "sessions")
+
+// DefaultIdleTimeout is the default timeout after which the session without the runs is expired.
+const DefaultIdleTimeout = 30 * time.Minute
+
+// Info describes the session of the chat
+type Info struct {
+	TenantID string `json:"tenant_id"`
+	ChatID   string `json:"chat_id"`
+	// CreatedAt is the time the session was created
+	CreatedAt time.Time `json:"created_at"`
+	// LastActivity is the time the last run started or finished
+	LastActivity time.Time `json:"last_activity"`
+	// Runs is the number of the completed runs
+	Runs int `json:"runs"`
+}
+
+// Persister is the persistence backend of the sessions,
+// for example to keep the sessions in Redis or in a database across the restarts.
+type Persister interface {
+	// Load returns the persisted session, or nil if the session is not found.
+	// It is called when the session is not in the memory of the Manager.
+	Load(ctx context.Context, tenantID, chatID string) (*Info, error)
+	// Save persists the session, it is called after each run.
+	Save(ctx context.Context, info *Info) error
+	// Expire is called when the idle session is removed from the Manager.
+	Expire(ctx context.Context, info *Info) error
+}
+
+// Option configures the Manager
+type Option func(*Manager)
+
+// WithIdleTimeout sets the timeout after which the session without the runs is expired,
+// zero disables the expiration.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.idleTimeout = timeout
+	}
+}
+
+// WithPersister sets the persistence backend of the sessions.
+func WithPersister(p Persister) Option {
+	return func(m *Manager) {
+		m.persister = p
+	}
+}
+
+type session struct {
+	info Info
+	// sem is the run lock, it is a channel to support the context cancellation while waiting
+	sem chan struct{}
+	// refs is the number of the runs holding or waiting for the lock,
+	// the session is not expired while it is in use
+	refs int
+}
+
+// Manager serializes the concurrent runs in the same chat,
+// so the messages of the runs are not interleaved in the chat history,
+// and expires the sessions without the runs after the idle timeout.
+// The runs in different chats are not blocked.
+type Manager struct {
+	idleTimeout time.Duration
+	persister   Persister
+	now         func() time.Time
+
+	lock     sync.Mutex
+	sessions map[string]*session
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewManager returns a new Manager, use Start to expire the idle sessions in the background.
+func NewManager(opts ...Option) *Manager {
+	m := &Manager{
+		idleTimeout: DefaultIdleTimeout,
+		now:         time.Now,
+		sessions:    map[string]*session{},
+		stop:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func sessionKey(tenantID, chatID string) string {
+	return tenantID + "/" + chatID
+}
+
+// Acquire locks the session of the chat from the ChatContext of the ctx,
+// waiting for the runs of the same chat to finish.
+// The returned release function must be called when the run is finished.
+// Returns error if the ctx does not have ChatContext, or the ctx is done while waiting.
+func (m *Manager) Acquire(ctx context.Context) (release func(), err error) {
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := m.get(ctx, tenantID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		m.unref(s)
+		return nil, errors.Wrapf(ctx.Err(), "session %s: wait cancelled", chatID)
+	}
+
+	m.lock.Lock()
+	s.info.LastActivity = m.now()
+	m.lock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { m.release(ctx, s) })
+	}, nil
+}
+
+// Run runs fn while holding the session of the chat from the ChatContext of the ctx,
+// see Acquire.
+func (m *Manager) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	release, err := m.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(ctx)
+}
+
+// Middleware returns the assistants.Middleware that serializes the runs of the assistants
+// in the same chat.
+// Do not apply it to the assistants called from the run of another assistant
+// in the same chat, for example the assistant tools, as the nested run waits for the outer run to finish.
+func (m *Manager) Middleware() assistants.Middleware {
+	return func(next assistants.Runner) assistants.Runner {
+		return func(ctx context.Context, input *assistants.CallInput, output any) (*assistants.Response, error) {
+			release, err := m.Acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+			return next(ctx, input, output)
+		}
+	}
+}
+
+// Get returns the session of the chat, if it is in the memory of the Manager
+func (m *Manager) Get(tenantID, chatID string) (*Info, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s, ok := m.sessions[sessionKey(tenantID, chatID)]
+	if !ok {
+		return nil, false
+	}
+	info := s.info
+	return &info, true
+}
+
+// List returns the sessions in the memory of the Manager, sorted by the tenant and chat IDs
+func (m *Manager) List() []*Info {
+	m.lock.Lock()
+	list := make([]*Info, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		info := s.info
+		list = append(list, &info)
+	}
+	m.lock.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].TenantID != list[j].TenantID {
+			return list[i].TenantID < list[j].TenantID
+		}
+		return list[i].ChatID < list[j].ChatID
+	})
+	return list
+}
+
+// ExpireIdle removes the sessions without the runs for longer than the idle timeout,
+// and returns the number of the expired sessions.
+// The sessions with the active or waiting runs are not expired.
+func (m *Manager) ExpireIdle(ctx context.Context) int {
+	if m.idleTimeout <= 0 {
+		return 0
+	}
+
+	deadline := m.now().Add(-m.idleTimeout)
+	var expired []Info
+	m.lock.Lock()
+	for key, s := range m.sessions {
+		if s.refs == 0 && s.info.LastActivity.Before(deadline) {
+			expired = append(expired, s.info)
+			delete(m.sessions, key)
+		}
+	}
+	m.lock.Unlock()
+
+	for i := range expired {
+		info := &expired[i]
+		logger.ContextKV(ctx, xlog.DEBUG,
+			"tenant", info.TenantID,
+			"chat", info.ChatID,
+			"runs", info.Runs,
+			"reason", "expired",
+		)
+		if m.persister != nil {
+			if err := m.persister.Expire(ctx, info); err != nil {
+				logger.ContextKV(ctx, xlog.WARNING,
+					"tenant", info.TenantID,
+					"chat", info.ChatID,
+					"reason", "persister_expire",
+					"err", err.Error(),
+				)
+			}
+		}
+	}
+	return len(expired)
+}
+
+// Start expires the idle sessions in the background, until Close is called
+func (m *Manager) Start() {
+	if m.idleTimeout <= 0 {
+		return
+	}
+	interval := m.idleTimeout / 2
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.ExpireIdle(context.Background())
+			}
+		}
+	}()
+}
+
+// Close stops the background expiration
+func (m *Manager) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	return nil
+}
+
+// get returns the session with the reference, loading it from the persister if needed
+func (m *Manager) get(ctx context.Context, tenantID, chatID string) (*session, error) {
+	key := sessionKey(tenantID, chatID)
+
+	m.lock.Lock()
+	if s, ok := m.sessions[key]; ok {
+		s.refs++
+		m.lock.Unlock()
+		return s, nil
+	}
+	m.lock.Unlock()
+
+	now := m.now()
+	info := Info{
+		TenantID:     tenantID,
+		ChatID:       chatID,
+		CreatedAt:    now,
+		LastActivity: now,
+	}
+	if m.persister != nil {
+		loaded, err := m.persister.Load(ctx, tenantID, chatID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "session %s: failed to load", chatID)
+		}
+		if loaded != nil {
+			info.CreatedAt = loaded.CreatedAt
+			info.Runs = loaded.Runs
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	// the session could be created by a concurrent run while loading
+	s, ok := m.sessions[key]
+	if !ok {
+		s = &session{
+			info: info,
+			sem:  make(chan struct{}, 1),
+		}
+		m.sessions[key] = s
+	}
+	s.refs++
+	return s, nil
+}
+
+func (m *Manager) unref(s *session) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s.refs--
+}
+
+func (m *Manager) release(ctx context.Context, s *session) {
+	m.lock.Lock()
+	s.info.Runs++
+	s.info.LastActivity = m.now()
+	info := s.info
+	m.lock.Unlock()
+
+	// save while holding the run lock, so the saves of the chat are ordered
+	if m.persister != nil {
+		if err := m.persister.Save(context.WithoutCancel(ctx), &info); err != nil {
+			logger.ContextKV(ctx, xlog.WARNING,
+				"tenant", info.TenantID,
+				"chat", info.ChatID,
+				"reason", "persister_save",
+				"err", err.Error(),
+			)
+		}
+	}
+
+	m.unref(s)
+	<-s.sem
+}
@@ -0,0 +1,203 @@
+package sessions
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/assistants"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryPersister struct {
+	lock    sync.Mutex
+	saved   map[string]Info
+	expired []string
+}
+
+func newMemoryPersister() *memoryPersister {
+	return &memoryPersister{saved: map[string]Info{}}
+}
+
+func (p *memoryPersister) Load(_ context.Context, tenantID, chatID string) (*Info, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	info, ok := p.saved[sessionKey(tenantID, chatID)]
+	if !ok {
+		return nil, nil
+	}
+	return &info, nil
+}
+
+func (p *memoryPersister) Save(_ context.Context, info *Info) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.saved[sessionKey(info.TenantID, info.ChatID)] = *info
+	return nil
+}
+
+func (p *memoryPersister) Expire(_ context.Context, info *Info) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.expired = append(p.expired, info.ChatID)
+	return nil
+}
+
+func chatCtx(tenantID, chatID string) context.Context {
+	return chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext(tenantID, chatID, nil))
+}
+
+func TestManager_Serializes(t *testing.T) {
+	t.Parallel()
+	m := NewManager()
+
+	var active, maxActive atomic.Int32
+	run := func(ctx context.Context) error {
+		n := active.Add(1)
+		for {
+			cur := maxActive.Load()
+			if n <= cur || maxActive.CompareAndSwap(cur, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		active.Add(-1)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, m.Run(chatCtx("t1", "c1"), run))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), maxActive.Load())
+
+	info, ok := m.Get("t1", "c1")
+	require.True(t, ok)
+	assert.Equal(t, 10, info.Runs)
+	assert.False(t, info.LastActivity.Before(info.CreatedAt))
+
+	_, ok = m.Get("t1", "c2")
+	assert.False(t, ok)
+}
+
+func TestManager_DifferentChats(t *testing.T) {
+	t.Parallel()
+	m := NewManager()
+
+	release1, err := m.Acquire(chatCtx("t1", "c1"))
+	require.NoError(t, err)
+	defer release1()
+
+	// the other chat is not blocked
+	release2, err := m.Acquire(chatCtx("t1", "c2"))
+	require.NoError(t, err)
+	release2()
+	// release is idempotent
+	release2()
+
+	// the same chat waits until the context is done
+	ctx, cancel := context.WithTimeout(chatCtx("t1", "c1"), 20*time.Millisecond)
+	defer cancel()
+	_, err = m.Acquire(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "session c1: wait cancelled")
+
+	list := m.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "c1", list[0].ChatID)
+	assert.Equal(t, 0, list[0].Runs)
+	assert.Equal(t, "c2", list[1].ChatID)
+	assert.Equal(t, 1, list[1].Runs)
+}
+
+func TestManager_NoChatContext(t *testing.T) {
+	t.Parallel()
+	m := NewManager()
+	err := m.Run(context.Background(), func(context.Context) error { return nil })
+	assert.ErrorIs(t, err, chatmodel.ErrInvalidChatContext)
+}
+
+func TestManager_ExpireIdle(t *testing.T) {
+	t.Parallel()
+	p := newMemoryPersister()
+	m := NewManager(WithIdleTimeout(time.Minute), WithPersister(p))
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	noop := func(context.Context) error { return nil }
+	require.NoError(t, m.Run(chatCtx("t1", "idle"), noop))
+	require.NoError(t, m.Run(chatCtx("t1", "idle"), noop))
+
+	release, err := m.Acquire(chatCtx("t1", "busy"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, m.ExpireIdle(context.Background()))
+
+	now = now.Add(2 * time.Minute)
+	// the session with the active run is not expired
+	assert.Equal(t, 1, m.ExpireIdle(context.Background()))
+	assert.Equal(t, []string{"idle"}, p.expired)
+	_, ok := m.Get("t1", "idle")
+	assert.False(t, ok)
+
+	release()
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 1, m.ExpireIdle(context.Background()))
+	assert.Empty(t, m.List())
+
+	// the expired session is loaded from the persister
+	require.NoError(t, m.Run(chatCtx("t1", "idle"), noop))
+	info, ok := m.Get("t1", "idle")
+	require.True(t, ok)
+	assert.Equal(t, 3, info.Runs)
+
+	// zero timeout disables the expiration
+	m2 := NewManager(WithIdleTimeout(0))
+	require.NoError(t, m2.Run(chatCtx("t1", "c1"), noop))
+	assert.Equal(t, 0, m2.ExpireIdle(context.Background()))
+}
+
+func TestManager_Start(t *testing.T) {
+	t.Parallel()
+	m := NewManager(WithIdleTimeout(20 * time.Millisecond))
+	m.Start()
+	defer m.Close()
+
+	require.NoError(t, m.Run(chatCtx("t1", "c1"), func(context.Context) error { return nil }))
+	assert.Eventually(t, func() bool {
+		return len(m.List()) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, m.Close())
+}
+
+func TestManager_Middleware(t *testing.T) {
+	t.Parallel()
+	m := NewManager()
+
+	var calls atomic.Int32
+	runner := assistants.ChainRunner(func(ctx context.Context, input *assistants.CallInput, _ any) (*assistants.Response, error) {
+		calls.Add(1)
+		return &assistants.Response{}, nil
+	}, m.Middleware())
+
+	_, err := runner(chatCtx("t1", "c1"), &assistants.CallInput{Input: "hi"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+
+	info, ok := m.Get("t1", "c1")
+	require.True(t, ok)
+	assert.Equal(t, 1, info.Runs)
+
+	_, err = runner(context.Background(), &assistants.CallInput{Input: "hi"}, nil)
+	assert.ErrorIs(t, err, chatmodel.ErrInvalidChatContext)
+	assert.Equal(t, int32(1), calls.Load())
+}
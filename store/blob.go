@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+)
+
+// ErrBlobNotFound is returned when the blob is not found.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// Blob is the content stored in the BlobStore.
+type Blob struct {
+	ID          string
+	ContentType string
+	Content     []byte
+}
+
+// BlobStore is an interface for storing large content,
+// that is passed by reference in the chat history.
+// The supplied context must have ChatContext with tenantID,
+// the blobs are scoped to the tenant.
+type BlobStore interface {
+	// Put stores the content and returns the blob ID.
+	Put(ctx context.Context, contentType string, content []byte) (string, error)
+	// Get returns the blob by ID, or ErrBlobNotFound.
+	Get(ctx context.Context, id string) (*Blob, error)
+}
+
+// DefaultMaxMemoryBlobsSize is the default maximum total size in bytes
+// of the blobs in the in-memory BlobStore.
+const DefaultMaxMemoryBlobsSize = 64 * 1024 * 1024
+
+type blobKey struct {
+	tenantID string
+	id       string
+}
+
+type inMemoryBlobs struct {
+	mu      sync.RWMutex
+	tenants map[string]map[string]*Blob
+	// order is the order of the blobs for the eviction, the oldest first
+	order   []blobKey
+	size    int
+	maxSize int
+}
+
+// NewMemoryBlobStore returns a new in-memory BlobStore,
+// limited to DefaultMaxMemoryBlobsSize bytes.
+func NewMemoryBlobStore() BlobStore {
+	return NewMemoryBlobStoreWithLimit(DefaultMaxMemoryBlobsSize)
+}
+
+// NewMemoryBlobStoreWithLimit returns a new in-memory BlobStore,
+// that evicts the oldest blobs when the total size exceeds maxSize bytes.
+func NewMemoryBlobStoreWithLimit(maxSize int) BlobStore {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxMemoryBlobsSize
+	}
+	return &inMemoryBlobs{
+		tenants: make(map[string]map[string]*Blob),
+		maxSize: maxSize,
+	}
+}
+
+func (m *inMemoryBlobs) Put(ctx context.Context, contentType string, content []byte) (string, error) {
+	tenantID, _, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(content) > m.maxSize {
+		return "", errors.Newf("blob size %d exceeds the limit %d", len(content), m.maxSize)
+	}
+
+	blob := &Blob{
+		ID:          "artifact_" + chatmodel.NewChatID(),
+		ContentType: contentType,
+		Content:     content,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	blobs, ok := m.tenants[tenantID]
+	if !ok {
+		blobs = make(map[string]*Blob)
+		m.tenants[tenantID] = blobs
+	}
+	blobs[blob.ID] = blob
+	m.order = append(m.order, blobKey{tenantID: tenantID, id: blob.ID})
+	m.size += len(content)
+
+	for m.size > m.maxSize && len(m.order) > 0 {
+		m.evictOldest()
+	}
+	return blob.ID, nil
+}
+
+// evictOldest removes the oldest blob, the caller must hold the lock.
+func (m *inMemoryBlobs) evictOldest() {
+	key := m.order[0]
+	m.order = m.order[1:]
+
+	blobs := m.tenants[key.tenantID]
+	if blob, ok := blobs[key.id]; ok {
+		m.size -= len(blob.Content)
+		delete(blobs, key.id)
+		if len(blobs) == 0 {
+			delete(m.tenants, key.tenantID)
+		}
+	}
+}
+
+func (m *inMemoryBlobs) Get(ctx context.Context, id string) (*Blob, error) {
+	tenantID, _, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	blob, ok := m.tenants[tenantID][id]
+	if !ok {
+		return nil, errors.Wrapf(ErrBlobNotFound, "%s", id)
+	}
+	return blob, nil
+}
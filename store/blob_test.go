@@ -0,0 +1,42 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MemoryBlobStore_Limit(t *testing.T) {
+	t.Parallel()
+
+	blobs := store.NewMemoryBlobStoreWithLimit(10)
+	ctx1 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+	ctx2 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant2", "chat1", nil))
+
+	_, err := blobs.Put(ctx1, "text/plain", []byte("01234567890"))
+	assert.EqualError(t, err, "blob size 11 exceeds the limit 10")
+
+	id1, err := blobs.Put(ctx1, "text/plain", []byte("0123"))
+	require.NoError(t, err)
+	id2, err := blobs.Put(ctx2, "text/plain", []byte("0123"))
+	require.NoError(t, err)
+
+	// the oldest blob is evicted
+	id3, err := blobs.Put(ctx1, "text/plain", []byte("0123"))
+	require.NoError(t, err)
+
+	_, err = blobs.Get(ctx1, id1)
+	assert.ErrorIs(t, err, store.ErrBlobNotFound)
+	blob, err := blobs.Get(ctx2, id2)
+	require.NoError(t, err)
+	assert.Equal(t, "0123", string(blob.Content))
+	_, err = blobs.Get(ctx1, id3)
+	require.NoError(t, err)
+
+	_, err = blobs.Put(context.Background(), "text/plain", []byte("0123"))
+	assert.EqualError(t, err, "invalid chat context")
+}
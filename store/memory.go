@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,7 +32,31 @@ func (t *tenant) messages(chatID string) []llms.Message {
 func (t *tenant) add(chatID string, msgs ...llms.Message) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.addLocked(chatID, msgs...)
+}
+
+func (t *tenant) addIfVersion(chatID string, expected uint64, msgs ...llms.Message) (uint64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var version uint64
+	if chat, ok := t.chats[chatID]; ok {
+		version = uint64(len(chat.Messages))
+	}
+	if version != expected {
+		return 0, &VersionConflictError{
+			TenantID: t.id,
+			ChatID:   chatID,
+			Expected: expected,
+			Actual:   version,
+		}
+	}
+	t.addLocked(chatID, msgs...)
+	return version + uint64(len(msgs)), nil
+}
 
+// addLocked adds the messages, the caller must hold the lock.
+func (t *tenant) addLocked(chatID string, msgs ...llms.Message) {
 	now := time.Now().UTC()
 	chat, ok := t.chats[chatID]
 	if !ok {
@@ -103,6 +128,44 @@ func (m *inMemory) Add(ctx context.Context, msgs ...llms.Message) error {
 	return nil
 }
 
+// Version returns the version of the chat in context, zero if the chat does not exist.
+func (m *inMemory) Version(ctx context.Context) (uint64, error) {
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if tenant, ok := m.tenants[tenantID]; ok {
+		return uint64(len(tenant.messages(chatID))), nil
+	}
+	return 0, nil
+}
+
+// AddIfVersion adds the messages to the chat in context,
+// only if the version of the chat is expected, and returns the new version.
+func (m *inMemory) AddIfVersion(ctx context.Context, expected uint64, msgs ...llms.Message) (uint64, error) {
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tenants[tenantID]
+	if !ok {
+		t = &tenant{
+			id:    tenantID,
+			chats: make(map[string]*ChatInfo),
+		}
+		m.tenants[tenantID] = t
+	}
+	return t.addIfVersion(chatID, expected, msgs...)
+}
+
 func (m *inMemory) Reset(ctx context.Context) error {
 	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
 	if err != nil {
@@ -199,8 +262,8 @@ func (m *inMemory) GetChatInfo(ctx context.Context, id string, withMessages bool
 	if err != nil {
 		return nil, err
 	}
-	if id == "" {
-		id = chatID
+	if id, err = resolveChatID(id, chatID); err != nil {
+		return nil, err
 	}
 
 	m.mu.RLock()
@@ -222,6 +285,87 @@ func (m *inMemory) GetChatInfo(ctx context.Context, id string, withMessages bool
 	return res, nil
 }
 
+// Fork creates a new chat with newChatID from the chat in context,
+// with the first index messages of the parent chat.
+func (m *inMemory) Fork(ctx context.Context, index int, newChatID string) (*ChatInfo, error) {
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if newChatID == "" {
+		newChatID = chatmodel.NewChatID()
+	} else if newChatID, err = chatmodel.NormalizeID(newChatID); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.tenants[tenantID]
+	if !ok {
+		return nil, errors.New("chat not found")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent, ok := t.chats[chatID]
+	if !ok {
+		return nil, errors.New("chat not found")
+	}
+	if index < 0 || index > len(parent.Messages) {
+		return nil, errors.Newf("invalid fork index %d, the chat has %d messages", index, len(parent.Messages))
+	}
+	if _, ok = t.chats[newChatID]; ok {
+		return nil, errors.Newf("chat already exists: %s", newChatID)
+	}
+
+	now := time.Now().UTC()
+	chat := parent.Clone()
+	chat.ChatID = newChatID
+	chat.ParentID = chatID
+	chat.ForkIndex = index
+	chat.CreatedAt = now
+	chat.UpdatedAt = now
+	// the capacity is clipped, so appending to either chat does not affect the other
+	chat.Messages = parent.Messages[:index:index]
+	t.chats[newChatID] = chat
+
+	return chat.Clone(), nil
+}
+
+// ListBranches returns the sorted IDs of the chats forked from the chat with the id,
+// or from the chat in context if id is empty.
+func (m *inMemory) ListBranches(ctx context.Context, id string) ([]string, error) {
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if id, err = resolveChatID(id, chatID); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.tenants[tenantID]
+	if !ok {
+		return nil, nil
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var branches []string
+	for branchID, chat := range t.chats {
+		if chat.ParentID == id {
+			branches = append(branches, branchID)
+		}
+	}
+	sort.Strings(branches)
+	return branches, nil
+}
+
 func NewMemoryStoreManager(store MessageStore) MessageStoreManager {
 	if mgr, ok := store.(MessageStoreManager); ok {
 		return mgr
@@ -241,6 +385,11 @@ func (m *inMemory) ListTenants(ctx context.Context) ([]string, error) {
 }
 
 func (m *inMemory) Cleanup(ctx context.Context, tenantID string, olderThan time.Duration) (uint32, error) {
+	tenantID, err := chatmodel.NormalizeID(tenantID)
+	if err != nil {
+		return 0, err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -2,6 +2,8 @@ package store_test
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -125,6 +127,159 @@ func Test_MemoryStore(t *testing.T) {
 	assert.Equal(t, 0, len(messages))
 }
 
+func Test_MemoryStore_Fork(t *testing.T) {
+	testStoreFork(t, store.NewMemoryStore())
+}
+
+func testStoreFork(t *testing.T, st store.MessageStore) {
+	br, ok := st.(store.MessageStoreBrancher)
+	require.True(t, ok)
+
+	msg1 := llms.MessageFromTextParts(llms.RoleHuman, "Hello")
+	msg2 := llms.MessageFromTextParts(llms.RoleAI, "Hi there!")
+	msg3 := llms.MessageFromTextParts(llms.RoleHuman, "How are you?")
+	msg4 := llms.MessageFromTextParts(llms.RoleAI, "Fine").WithMetadata(llms.MetadataModel, "gpt-4o")
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("fork_tenant", "parent", nil))
+	_, err := br.Fork(ctx, 0, "")
+	assert.EqualError(t, err, "chat not found")
+
+	require.NoError(t, st.Add(ctx, msg1, msg2, msg3))
+	_, err = st.UpdateChat(ctx, "Parent", map[string]any{"key": "value"}, []string{"tag1"})
+	require.NoError(t, err)
+
+	_, err = br.Fork(ctx, 4, "")
+	assert.EqualError(t, err, "invalid fork index 4, the chat has 3 messages")
+	_, err = br.Fork(ctx, 1, "parent")
+	assert.EqualError(t, err, "chat already exists: parent")
+
+	// edit the last human message
+	chi, err := br.Fork(ctx, 2, "branch1")
+	require.NoError(t, err)
+	assert.Equal(t, "branch1", chi.ChatID)
+	assert.Equal(t, "parent", chi.ParentID)
+	assert.Equal(t, 2, chi.ForkIndex)
+	assert.Equal(t, "Parent", chi.Title)
+	assert.Equal(t, []string{"tag1"}, chi.Tags)
+
+	chi, err = br.Fork(ctx, 0, "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, chi.ChatID)
+	branch2 := chi.ChatID
+
+	branchCtx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("fork_tenant", "branch1", nil))
+	require.NoError(t, st.Add(branchCtx, msg4))
+	require.NoError(t, st.Add(ctx, msg4))
+
+	// the parent and the branch are updated independently
+	assert.Equal(t, []llms.Message{msg1, msg2, msg4}, st.Messages(branchCtx))
+	assert.Equal(t, []llms.Message{msg1, msg2, msg3, msg4}, st.Messages(ctx))
+
+	branches, err := br.ListBranches(ctx, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"branch1", branch2}, branches)
+	branches, err = br.ListBranches(ctx, "branch1")
+	require.NoError(t, err)
+	assert.Empty(t, branches)
+
+	// the reset branch is not listed
+	require.NoError(t, st.Reset(branchCtx))
+	branches, err = br.ListBranches(ctx, "parent")
+	require.NoError(t, err)
+	assert.Equal(t, []string{branch2}, branches)
+
+	// the supplied IDs are normalized
+	chi, err = br.Fork(ctx, 1, " branch3 ")
+	require.NoError(t, err)
+	assert.Equal(t, "branch3", chi.ChatID)
+	chi, err = st.GetChatInfo(ctx, " branch3 ", false)
+	require.NoError(t, err)
+	assert.Equal(t, "parent", chi.ParentID)
+	_, err = br.Fork(ctx, 1, " ")
+	assert.ErrorIs(t, err, chatmodel.ErrInvalidID)
+}
+
+func Test_MemoryStore_Version(t *testing.T) {
+	testStoreVersion(t, store.NewMemoryStore())
+}
+
+func testStoreVersion(t *testing.T, st store.MessageStore) {
+	vs, ok := st.(store.MessageStoreVersioner)
+	require.True(t, ok)
+
+	msg1 := llms.MessageFromTextParts(llms.RoleHuman, "Hello")
+	msg2 := llms.MessageFromTextParts(llms.RoleAI, "Hi there!")
+	msg3 := llms.MessageFromTextParts(llms.RoleHuman, "How are you?")
+
+	_, err := vs.Version(context.Background())
+	assert.EqualError(t, err, "invalid chat context")
+	_, err = vs.AddIfVersion(context.Background(), 0, msg1)
+	assert.EqualError(t, err, "invalid chat context")
+
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("version_tenant", "chat1", nil))
+	version, err := vs.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), version)
+
+	version, err = vs.AddIfVersion(ctx, 0, msg1, msg2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), version)
+
+	// another replica added the message
+	require.NoError(t, st.Add(ctx, msg3))
+	version, err = vs.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), version)
+
+	_, err = vs.AddIfVersion(ctx, 2, msg3)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, store.ErrVersionConflict)
+	var conflict *store.VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, uint64(2), conflict.Expected)
+	assert.Equal(t, uint64(3), conflict.Actual)
+	assert.EqualError(t, err, "chat version conflict: chat1, expected 2, actual 3")
+	assert.Equal(t, []llms.Message{msg1, msg2, msg3}, st.Messages(ctx))
+
+	// retry with the actual version
+	version, err = vs.AddIfVersion(ctx, conflict.Actual, msg1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), version)
+
+	// concurrent writers with the same version, only one succeeds
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := vs.AddIfVersion(ctx, version, msg2); err == nil {
+				succeeded.Add(1)
+			} else {
+				assert.ErrorIs(t, err, store.ErrVersionConflict)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), succeeded.Load())
+	assert.Len(t, st.Messages(ctx), 5)
+
+	// the branch starts with the version of the fork index
+	if br, ok := st.(store.MessageStoreBrancher); ok {
+		_, err = br.Fork(ctx, 2, "chat1_branch")
+		require.NoError(t, err)
+		branchCtx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("version_tenant", "chat1_branch", nil))
+		version, err = vs.Version(branchCtx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), version)
+	}
+
+	require.NoError(t, st.Reset(ctx))
+	version, err = vs.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), version)
+}
+
 func Test_MemoryStoreManager(t *testing.T) {
 	tenantID := "tenant1"
 	chatID := "chat1"
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,6 +28,34 @@ import (
 // - `/<prefix>/chatstore/<tenantID>/messages/<chatID>` for storing chat messages
 // - `/<prefix>/chatstore/<tenantID>/info/<chatID>` for storing chat metadata
 // - `/<prefix>/chatstore/<tenantID>/chats` for storing a set of chat IDs associated with a tenant
+// - `/<prefix>/chatstore/<tenantID>/branches/<chatID>` for storing a set of chat IDs forked from the chat
+// - `/<prefix>/chatstore/<tenantID>/version/<chatID>` for storing the version of the chat messages
+
+// maxRedisMessages is the number of the last messages kept in the chat
+const maxRedisMessages = 50
+
+// addMessagesScript appends the messages ARGV[3..] to the list KEYS[1], keeping the last ARGV[2] messages,
+// if the version KEYS[2] is ARGV[1], or unconditionally if ARGV[1] is empty,
+// and returns {1, new version}, or {0, actual version} on conflict.
+// The version of the chats created before the versioning is the number of the messages.
+var addMessagesScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[2])
+if v then
+	v = tonumber(v)
+else
+	v = redis.call('LLEN', KEYS[1])
+end
+if ARGV[1] ~= '' and tonumber(ARGV[1]) ~= v then
+	return {0, v}
+end
+for i = 3, #ARGV do
+	redis.call('RPUSH', KEYS[1], ARGV[i])
+end
+redis.call('LTRIM', KEYS[1], -tonumber(ARGV[2]), -1)
+v = v + #ARGV - 2
+redis.call('SET', KEYS[2], v)
+return {1, v}
+`)
 
 type redisStore struct {
 	client *redis.Client
@@ -52,6 +82,14 @@ func (m *redisStore) getRedisChatListKey(tenantID string) string {
 	return path.Join(m.prefix, "chatstore", tenantID, "chats")
 }
 
+func (m *redisStore) getRedisBranchesKey(tenantID, chatID string) string {
+	return path.Join(m.prefix, "chatstore", tenantID, "branches", chatID)
+}
+
+func (m *redisStore) getRedisVersionKey(tenantID, chatID string) string {
+	return path.Join(m.prefix, "chatstore", tenantID, "version", chatID)
+}
+
 func (m *redisStore) Messages(ctx context.Context) []llms.Message {
 	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
 	if err != nil {
@@ -97,29 +135,89 @@ func (m *redisStore) Add(ctx context.Context, msgs ...llms.Message) error {
 		return nil
 	}
 
-	key := m.getRedisMessagesKey(tenantID, chatID)
-	pipe := m.client.Pipeline()
+	if _, _, err = m.addMessages(ctx, tenantID, chatID, "", msgs); err != nil {
+		return err
+	}
+
+	// Update the time
+	_, err = m.UpdateChat(ctx, "", nil, nil)
+	return err
+}
+
+// Version returns the version of the chat in context, zero if the chat does not exist.
+func (m *redisStore) Version(ctx context.Context) (uint64, error) {
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := m.client.Get(ctx, m.getRedisVersionKey(tenantID, chatID)).Uint64()
+	if err == nil {
+		return version, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return 0, errors.Wrap(err, "failed to get chat version from Redis")
+	}
+	// the chat created before the versioning
+	count, err := m.client.LLen(ctx, m.getRedisMessagesKey(tenantID, chatID)).Result()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get messages count from Redis")
+	}
+	return uint64(count), nil
+}
 
-	// Marshal and add all messages to the pipeline
+// AddIfVersion adds the messages to the chat in context atomically,
+// only if the version of the chat is expected, and returns the new version.
+func (m *redisStore) AddIfVersion(ctx context.Context, expected uint64, msgs ...llms.Message) (uint64, error) {
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ok, version, err := m.addMessages(ctx, tenantID, chatID, strconv.FormatUint(expected, 10), msgs)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, &VersionConflictError{
+			TenantID: tenantID,
+			ChatID:   chatID,
+			Expected: expected,
+			Actual:   version,
+		}
+	}
+
+	// Update the time
+	if _, err = m.UpdateChat(ctx, "", nil, nil); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// addMessages runs addMessagesScript, the expected version is empty for unconditional add
+func (m *redisStore) addMessages(ctx context.Context, tenantID, chatID, expected string, msgs []llms.Message) (bool, uint64, error) {
+	args := make([]any, 0, len(msgs)+2)
+	args = append(args, expected, maxRedisMessages)
 	for _, msg := range msgs {
 		data, err := json.Marshal(msg)
 		if err != nil {
-			return errors.Wrap(err, "failed to marshal message")
+			return false, 0, errors.Wrap(err, "failed to marshal message")
 		}
-		pipe.RPush(ctx, key, data)
+		args = append(args, data)
 	}
 
-	// Keep only the last 50 messages
-	pipe.LTrim(ctx, key, -50, -1)
-
-	_, err = pipe.Exec(ctx)
+	keys := []string{
+		m.getRedisMessagesKey(tenantID, chatID),
+		m.getRedisVersionKey(tenantID, chatID),
+	}
+	res, err := addMessagesScript.Run(ctx, m.client, keys, args...).Int64Slice()
 	if err != nil {
-		return errors.Wrap(err, "failed to store messages in Redis")
+		return false, 0, errors.Wrap(err, "failed to store messages in Redis")
 	}
-
-	// Update the time
-	_, err = m.UpdateChat(ctx, "", nil, nil)
-	return err
+	if len(res) != 2 {
+		return false, 0, errors.Newf("unexpected result of add messages script: %v", res)
+	}
+	return res[0] == 1, uint64(res[1]), nil
 }
 
 func (m *redisStore) Reset(ctx context.Context) error {
@@ -139,6 +237,7 @@ func (m *redisStore) Reset(ctx context.Context) error {
 	pipe := m.client.Pipeline()
 	pipe.Del(ctx, messageKey)
 	pipe.Del(ctx, chatKey)
+	pipe.Del(ctx, m.getRedisVersionKey(tenantID, chatID))
 	pipe.SRem(ctx, chatListKey, chatID)
 	_, err = pipe.Exec(ctx)
 	if err != nil {
@@ -254,8 +353,8 @@ func (m *redisStore) getChatInfo(ctx context.Context, id string) (*ChatInfo, err
 	if err != nil {
 		return nil, err
 	}
-	if id == "" {
-		id = chatID
+	if id, err = resolveChatID(id, chatID); err != nil {
+		return nil, err
 	}
 
 	chatKey := m.getRedisChatInfoKey(tenantID, id)
@@ -294,6 +393,133 @@ func (m *redisStore) getChatInfo(ctx context.Context, id string) (*ChatInfo, err
 	return chat, nil
 }
 
+// Fork creates a new chat with newChatID from the chat in context,
+// with the first index messages of the parent chat.
+// The messages are copied to the new chat.
+func (m *redisStore) Fork(ctx context.Context, index int, newChatID string) (*ChatInfo, error) {
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if newChatID == "" {
+		newChatID = chatmodel.NewChatID()
+	} else if newChatID, err = chatmodel.NormalizeID(newChatID); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := m.client.Get(ctx, m.getRedisChatInfoKey(tenantID, chatID)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errors.New("chat not found")
+		}
+		return nil, errors.Wrap(err, "failed to get chat info from Redis")
+	}
+	parent := &ChatInfo{}
+	if err = json.Unmarshal([]byte(data), parent); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal chat info")
+	}
+
+	exists, err := m.client.Exists(ctx, m.getRedisChatInfoKey(tenantID, newChatID)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check chat in Redis")
+	}
+	if exists > 0 {
+		return nil, errors.Newf("chat already exists: %s", newChatID)
+	}
+
+	count, err := m.client.LLen(ctx, m.getRedisMessagesKey(tenantID, chatID)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get messages count from Redis")
+	}
+	if index < 0 || int64(index) > count {
+		return nil, errors.Newf("invalid fork index %d, the chat has %d messages", index, count)
+	}
+
+	var msgs []string
+	if index > 0 {
+		msgs, err = m.client.LRange(ctx, m.getRedisMessagesKey(tenantID, chatID), 0, int64(index)-1).Result()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get messages from Redis")
+		}
+	}
+
+	now := time.Now().UTC()
+	chat := parent.Clone()
+	chat.ChatID = newChatID
+	chat.ParentID = chatID
+	chat.ForkIndex = index
+	chat.CreatedAt = now
+	chat.UpdatedAt = now
+
+	chatData, err := json.Marshal(chat)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal chat info")
+	}
+
+	pipe := m.client.TxPipeline()
+	if len(msgs) > 0 {
+		items := make([]any, len(msgs))
+		for i, msg := range msgs {
+			items[i] = msg
+		}
+		pipe.RPush(ctx, m.getRedisMessagesKey(tenantID, newChatID), items...)
+	}
+	pipe.Set(ctx, m.getRedisChatInfoKey(tenantID, newChatID), chatData, 0)
+	pipe.Set(ctx, m.getRedisVersionKey(tenantID, newChatID), index, 0)
+	pipe.SAdd(ctx, m.getRedisChatListKey(tenantID), newChatID)
+	pipe.SAdd(ctx, m.getRedisBranchesKey(tenantID, chatID), newChatID)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return nil, errors.Wrap(err, "failed to store forked chat in Redis")
+	}
+
+	return chat.Clone(), nil
+}
+
+// ListBranches returns the sorted IDs of the chats forked from the chat with the id,
+// or from the chat in context if id is empty.
+func (m *redisStore) ListBranches(ctx context.Context, id string) ([]string, error) {
+	tenantID, chatID, err := chatmodel.GetTenantAndChatID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if id, err = resolveChatID(id, chatID); err != nil {
+		return nil, err
+	}
+
+	ids, err := m.client.SMembers(ctx, m.getRedisBranchesKey(tenantID, id)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to list branches from Redis")
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// filter out the branches that were reset or cleaned up
+	items := make([]any, len(ids))
+	for i, branchID := range ids {
+		items[i] = branchID
+	}
+	exists, err := m.client.SMIsMember(ctx, m.getRedisChatListKey(tenantID), items...).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list branches from Redis")
+	}
+
+	var branches []string
+	for i, branchID := range ids {
+		if exists[i] {
+			branches = append(branches, branchID)
+		}
+	}
+	sort.Strings(branches)
+	return branches, nil
+}
+
 func NewRedisStoreManager(client *redis.Client, prefix string) MessageStoreManager {
 	return &redisStore{
 		client: client,
@@ -328,6 +554,11 @@ func (m *redisStore) ListTenants(ctx context.Context) ([]string, error) {
 }
 
 func (m *redisStore) Cleanup(ctx context.Context, tenantID string, olderThan time.Duration) (uint32, error) {
+	tenantID, err := chatmodel.NormalizeID(tenantID)
+	if err != nil {
+		return 0, err
+	}
+
 	chatListKey := m.getRedisChatListKey(tenantID)
 	chatIDs, err := m.client.SMembers(ctx, chatListKey).Result()
 	if err != nil {
@@ -356,6 +587,8 @@ func (m *redisStore) Cleanup(ctx context.Context, tenantID string, olderThan tim
 			pipe.Del(ctx, chatKey)
 			pipe.Del(ctx, m.getRedisMessagesKey(tenantID, chatID))
 			pipe.SRem(ctx, chatListKey, chatID)
+			pipe.Del(ctx, m.getRedisBranchesKey(tenantID, chatID))
+			pipe.Del(ctx, m.getRedisVersionKey(tenantID, chatID))
 			_, err = pipe.Exec(ctx)
 			if err != nil {
 				return 0, errors.Wrap(err, "failed to delete chat info and messages from Redis")
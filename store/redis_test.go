@@ -177,6 +177,9 @@ func Test_RedisStore(t *testing.T) {
 	// Verify that messages are cleared
 	messages = st.Messages(ctx)
 	assert.Equal(t, 0, len(messages))
+
+	testStoreFork(t, st)
+	testStoreVersion(t, st)
 }
 
 func Test_RedisStoreManager(t *testing.T) {
@@ -2,8 +2,11 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
 	"github.com/effective-security/gogentic/pkg/llms"
 	"github.com/effective-security/xlog"
 )
@@ -19,11 +22,16 @@ type ChatInfo struct {
 	UpdatedAt time.Time
 	Metadata  map[string]any
 	Tags      []string
+	// ParentID is the ID of the chat this chat was forked from, if any.
+	ParentID string `json:",omitempty"`
+	// ForkIndex is the number of the parent messages the chat was forked with.
+	ForkIndex int `json:",omitempty"`
 }
 
 // MessageStore is an interface for storing and retrieving chat messages.
 // The supplied context must have ChatContext with tenantID and chatID,
 // created by NewChatContext.
+// The IDs supplied in the arguments are normalized with chatmodel.NormalizeID.
 type MessageStore interface {
 	// Messages returns the messages for a tenant and chat ID from context.
 	Messages(ctx context.Context) []llms.Message
@@ -44,11 +52,75 @@ type MessageStore interface {
 	GetChatInfo(ctx context.Context, id string, withMessages bool) (*ChatInfo, error)
 }
 
+// MessageStoreBrancher is an interface for forking chats,
+// to enable "edit and regenerate" flows.
+// The supplied context must have ChatContext with tenantID and chatID.
+// Both memory and Redis stores implement it.
+type MessageStoreBrancher interface {
+	// Fork creates a new chat with newChatID from the chat in context,
+	// with the first index messages of the parent chat.
+	// The messages are copied on write, so the parent and the branch
+	// are updated independently.
+	// If newChatID is empty, a new ID is generated.
+	Fork(ctx context.Context, index int, newChatID string) (*ChatInfo, error)
+	// ListBranches returns the sorted IDs of the chats forked from the chat with the id,
+	// or from the chat in context if id is empty.
+	ListBranches(ctx context.Context, id string) ([]string, error)
+}
+
+// ErrVersionConflict is matched by VersionConflictError with errors.Is.
+var ErrVersionConflict = errors.New("chat version conflict")
+
+// VersionConflictError is returned by AddIfVersion,
+// when the chat was changed by another writer since the expected version was read.
+type VersionConflictError struct {
+	TenantID string
+	ChatID   string
+	// Expected is the version provided by the caller
+	Expected uint64
+	// Actual is the current version of the chat, to retry with
+	Actual uint64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("chat version conflict: %s, expected %d, actual %d", e.ChatID, e.Expected, e.Actual)
+}
+
+// Is returns true for ErrVersionConflict
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// MessageStoreVersioner is an interface for the optimistic concurrency of the chat history,
+// so the replicas handling the same chat can not silently interleave the messages.
+// The version is the number of the messages added to the chat since it was created, forked or reset,
+// including the messages trimmed by the store.
+// The supplied context must have ChatContext with tenantID and chatID.
+// Both memory and Redis stores implement it.
+type MessageStoreVersioner interface {
+	// Version returns the version of the chat in context, zero if the chat does not exist.
+	Version(ctx context.Context) (uint64, error)
+	// AddIfVersion adds the messages to the chat in context atomically,
+	// only if the version of the chat is expected, and returns the new version.
+	// If the chat was changed, returns *VersionConflictError,
+	// the caller can reload the messages and retry with the actual version.
+	AddIfVersion(ctx context.Context, expected uint64, msgs ...llms.Message) (uint64, error)
+}
+
 type MessageStoreManager interface {
 	ListTenants(ctx context.Context) ([]string, error)
 	Cleanup(ctx context.Context, tenantID string, olderThan time.Duration) (uint32, error)
 }
 
+// resolveChatID returns the normalized id supplied by the caller,
+// or the chat ID from context if the id is empty.
+func resolveChatID(id, chatID string) (string, error) {
+	if id == "" {
+		return chatID, nil
+	}
+	return chatmodel.NormalizeID(id)
+}
+
 func PopulateMemoryStore(ctx context.Context, store MessageStore) (MessageStore, error) {
 	s := NewMemoryStore()
 	if store != nil {
@@ -70,6 +142,8 @@ func (c *ChatInfo) Clone() *ChatInfo {
 		Title:     c.Title,
 		CreatedAt: c.CreatedAt,
 		UpdatedAt: c.UpdatedAt,
+		ParentID:  c.ParentID,
+		ForkIndex: c.ForkIndex,
 	}
 
 	if c.Metadata != nil {
@@ -0,0 +1,158 @@
+// Package artifact provides the fetch_artifact tool,
+// that dereferences the content stored in the blob store,
+// when the large tool or LLM outputs are replaced by references in the chat history.
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+)
+
+// FetchToolName is the name registered with the LLM.
+const FetchToolName = "fetch_artifact"
+
+// DefaultFetchLimit is the default number of bytes returned by the tool.
+const DefaultFetchLimit = 16 * 1024
+
+// FetchRequest is the JSON input expected by the tool.
+type FetchRequest struct {
+	ID     string `json:"id" yaml:"id" jsonschema:"required,title=Artifact ID,description=The ID of the artifact to fetch."`
+	Offset int    `json:"offset,omitempty" yaml:"offset" jsonschema:"title=Offset,description=The byte offset to start reading from. Use next_offset from the previous response to read the next part."`
+	Limit  int    `json:"limit,omitempty" yaml:"limit" jsonschema:"title=Limit,description=The maximum number of bytes to return."`
+}
+
+// FetchResponse is the tool output.
+type FetchResponse struct {
+	ID          string `json:"id" yaml:"id"`
+	ContentType string `json:"content_type,omitempty" yaml:"content_type"`
+	Size        int    `json:"size" yaml:"size"`
+	Offset      int    `json:"offset" yaml:"offset"`
+	NextOffset  int    `json:"next_offset,omitempty" yaml:"next_offset"`
+	Content     string `json:"content" yaml:"content"`
+	Error       string `json:"error,omitempty" yaml:"error"`
+}
+
+// GetContent gets the content of the message for the chat history
+func (r *FetchResponse) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+// FetchTool implements tools.ITool, it returns the content of the artifact from the blob store.
+type FetchTool struct {
+	blobs      store.BlobStore
+	limit      int
+	funcParams *jsonschema.Schema
+}
+
+var _ tools.Tool[FetchRequest, FetchResponse] = (*FetchTool)(nil)
+
+// NewFetchTool returns a new fetch_artifact tool for the blob store.
+func NewFetchTool(blobs store.BlobStore) *FetchTool {
+	sc, _ := schema.New(reflect.TypeOf(FetchRequest{}))
+	return &FetchTool{
+		blobs:      blobs,
+		limit:      DefaultFetchLimit,
+		funcParams: sc.Parameters,
+	}
+}
+
+// WithLimit sets the maximum number of bytes returned by the tool.
+func (t *FetchTool) WithLimit(limit int) *FetchTool {
+	if limit > 0 {
+		t.limit = limit
+	}
+	return t
+}
+
+func (t *FetchTool) Name() string {
+	return FetchToolName
+}
+
+func (t *FetchTool) Description() string {
+	return "Fetch the full content of an artifact by ID, when the content in the conversation was replaced by the artifact reference. Large artifacts are returned in parts, use next_offset to read the next part."
+}
+
+func (t *FetchTool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+func (t *FetchTool) Call(ctx context.Context, input string) (string, error) {
+	var req FetchRequest
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	res, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.GetContent(), nil
+}
+
+// Run returns the requested part of the artifact.
+// The not found artifact is reported in the response, so the LLM can correct the ID.
+func (t *FetchTool) Run(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	blob, err := t.blobs.Get(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrBlobNotFound) {
+			return &FetchResponse{
+				ID:    req.ID,
+				Error: "artifact not found",
+			}, nil
+		}
+		return nil, err
+	}
+
+	size := len(blob.Content)
+	offset := min(max(req.Offset, 0), size)
+	limit := t.limit
+	if req.Limit > 0 && req.Limit < limit {
+		limit = req.Limit
+	}
+	end := min(offset+limit, size)
+	if utf8.Valid(blob.Content) {
+		offset, end = runeBoundaries(blob.Content, offset, end)
+	}
+
+	res := &FetchResponse{
+		ID:          blob.ID,
+		ContentType: blob.ContentType,
+		Size:        size,
+		Offset:      offset,
+		Content:     string(blob.Content[offset:end]),
+	}
+	if end < size {
+		res.NextOffset = end
+	}
+	return res, nil
+}
+
+// runeBoundaries moves the offset and the end back to the nearest UTF-8 character boundaries,
+// so the multi-byte characters are not split between the parts.
+// If the part is shorter than one character, the end is moved forward to include it.
+func runeBoundaries(content []byte, offset, end int) (int, int) {
+	size := len(content)
+	for offset > 0 && offset < size && !utf8.RuneStart(content[offset]) {
+		offset--
+	}
+	e := end
+	for e > offset && e < size && !utf8.RuneStart(content[e]) {
+		e--
+	}
+	if e == offset && end > offset {
+		e = end
+		for e < size && !utf8.RuneStart(content[e]) {
+			e++
+		}
+	}
+	return offset, e
+}
@@ -0,0 +1,88 @@
+package artifact_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/gogentic/tools/artifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchTool(t *testing.T) {
+	t.Parallel()
+
+	blobs := store.NewMemoryBlobStore()
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+
+	id, err := blobs.Put(ctx, "text/plain", []byte("0123456789"))
+	require.NoError(t, err)
+
+	tool := artifact.NewFetchTool(blobs).WithLimit(4)
+	assert.Equal(t, artifact.FetchToolName, tool.Name())
+	assert.NotEmpty(t, tool.Description())
+	require.NotNil(t, tool.Parameters())
+
+	tcases := []struct {
+		input string
+		exp   string
+	}{
+		{`{"id":"` + id + `"}`, `{"id":"` + id + `","content_type":"text/plain","size":10,"offset":0,"next_offset":4,"content":"0123"}`},
+		{`{"id":"` + id + `","offset":8}`, `{"id":"` + id + `","content_type":"text/plain","size":10,"offset":8,"content":"89"}`},
+		{`{"id":"` + id + `","offset":4,"limit":2}`, `{"id":"` + id + `","content_type":"text/plain","size":10,"offset":4,"next_offset":6,"content":"45"}`},
+		{`{"id":"` + id + `","offset":100}`, `{"id":"` + id + `","content_type":"text/plain","size":10,"offset":10,"content":""}`},
+		{`{"id":"missing"}`, `{"id":"missing","size":0,"offset":0,"content":"","error":"artifact not found"}`},
+	}
+	for _, tc := range tcases {
+		out, err := tool.Call(ctx, tc.input)
+		require.NoError(t, err)
+		assert.Equal(t, tc.exp, out)
+	}
+
+	_, err = tool.Call(ctx, `not json`)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+
+	// artifacts are scoped to the tenant
+	ctx2 := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant2", "chat1", nil))
+	out, err := tool.Call(ctx2, `{"id":"`+id+`"}`)
+	require.NoError(t, err)
+	assert.Contains(t, out, "artifact not found")
+
+	_, err = tool.Call(context.Background(), `{"id":"`+id+`"}`)
+	assert.EqualError(t, err, "invalid chat context")
+}
+
+func TestFetchTool_UTF8(t *testing.T) {
+	t.Parallel()
+
+	blobs := store.NewMemoryBlobStore()
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+
+	// "é" and "€" are 2 and 3 bytes
+	id, err := blobs.Put(ctx, "text/plain", []byte("aé€b"))
+	require.NoError(t, err)
+	tool := artifact.NewFetchTool(blobs)
+
+	tcases := []struct {
+		offset  int
+		limit   int
+		content string
+		next    int
+	}{
+		{0, 2, "a", 1},
+		{0, 3, "aé", 3},
+		{1, 3, "é", 3},
+		{2, 3, "é", 3},
+		{3, 1, "€", 6},
+		{4, 2, "€", 6},
+		{3, 4, "€b", 0},
+	}
+	for _, tc := range tcases {
+		res, err := tool.Run(ctx, &artifact.FetchRequest{ID: id, Offset: tc.offset, Limit: tc.limit})
+		require.NoError(t, err)
+		assert.Equal(t, tc.content, res.Content, "offset %d limit %d", tc.offset, tc.limit)
+		assert.Equal(t, tc.next, res.NextOffset, "offset %d limit %d", tc.offset, tc.limit)
+	}
+}
@@ -0,0 +1,263 @@
+// Package browser provides the browser tool, that navigates the web pages,
+// clicks the elements, extracts the text and takes the screenshots with the Driver,
+// so the computer-use agents can work with the web applications.
+//
+// The screenshots are returned as llms.BinaryContent parts,
+// and stored in the blob store, if it is provided.
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mcp"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+)
+
+// ToolName is the name registered with the LLM.
+const ToolName = "browser"
+
+// MaxTextLength is the maximum length of the extracted text.
+const MaxTextLength = 16 * 1024
+
+// ErrElementNotFound is returned by the Driver, if no element matches the selector.
+var ErrElementNotFound = errors.New("element not found")
+
+// Action is the browser action.
+type Action string
+
+const (
+	ActionNavigate   Action = "navigate"
+	ActionClick      Action = "click"
+	ActionExtract    Action = "extract"
+	ActionScreenshot Action = "screenshot"
+)
+
+// Page is the current state of the page.
+type Page struct {
+	URL   string `json:"url" yaml:"url"`
+	Title string `json:"title" yaml:"title"`
+}
+
+// Driver controls the browser page.
+// The methods are called sequentially by the tool.
+type Driver interface {
+	// Navigate opens the URL, and waits for the page to load.
+	Navigate(ctx context.Context, url string) (*Page, error)
+	// Click clicks the element matching the CSS selector.
+	Click(ctx context.Context, selector string) (*Page, error)
+	// Extract returns the text of the element matching the CSS selector,
+	// or the text of the page if the selector is empty.
+	Extract(ctx context.Context, selector string) (string, error)
+	// Screenshot returns the PNG image of the viewport, or of the whole page.
+	Screenshot(ctx context.Context, fullPage bool) ([]byte, error)
+	// Close closes the page.
+	Close() error
+}
+
+// Request is the JSON input expected by the tool.
+type Request struct {
+	Action   Action `json:"action" yaml:"action" jsonschema:"required,enum=navigate,enum=click,enum=extract,enum=screenshot,title=Action,description=The browser action."`
+	URL      string `json:"url,omitempty" yaml:"url" jsonschema:"title=URL,description=The URL to navigate to. Required for navigate."`
+	Selector string `json:"selector,omitempty" yaml:"selector" jsonschema:"title=Selector,description=The CSS selector of the element. Required for click. For extract the whole page text is returned if empty."`
+	FullPage bool   `json:"full_page,omitempty" yaml:"full_page" jsonschema:"title=Full Page,description=Take the screenshot of the whole page instead of the viewport."`
+}
+
+// Screenshot is the reference to the screenshot image.
+type Screenshot struct {
+	ID          string `json:"id,omitempty" yaml:"id"`
+	ContentType string `json:"content_type" yaml:"content_type"`
+	Size        int    `json:"size" yaml:"size"`
+}
+
+// Response is the tool output.
+type Response struct {
+	Action     Action      `json:"action" yaml:"action"`
+	Page       *Page       `json:"page,omitempty" yaml:"page"`
+	Text       string      `json:"text,omitempty" yaml:"text"`
+	Truncated  bool        `json:"truncated,omitempty" yaml:"truncated"`
+	Screenshot *Screenshot `json:"screenshot,omitempty" yaml:"screenshot"`
+	// Parts are the screenshot images, not included in the chat history.
+	Parts []llms.BinaryContent `json:"-" yaml:"-"`
+}
+
+// GetContent gets the content of the message for the chat history
+func (r *Response) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+// ContentParts returns the screenshot as the message parts.
+func (r *Response) ContentParts() []llms.ContentPart {
+	parts := make([]llms.ContentPart, 0, len(r.Parts))
+	for _, p := range r.Parts {
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// Tool implements tools.ITool, it controls the browser page with the Driver.
+type Tool struct {
+	driver     Driver
+	blobs      store.BlobStore
+	hosts      []string
+	funcParams *jsonschema.Schema
+
+	// lock serializes the actions on the page
+	lock sync.Mutex
+}
+
+var _ tools.Tool[Request, Response] = (*Tool)(nil)
+var _ tools.MCPTool[Request] = (*Tool)(nil)
+
+// New returns a new browser tool, the screenshots are stored in the blob store,
+// if it is not nil.
+func New(driver Driver, blobs store.BlobStore) *Tool {
+	sc, _ := schema.New(reflect.TypeOf(Request{}))
+	return &Tool{
+		driver:     driver,
+		blobs:      blobs,
+		funcParams: sc.Parameters,
+	}
+}
+
+// WithAllowedHosts limits the navigation to the hosts,
+// the host ".example.com" allows the subdomains of example.com.
+// All hosts are allowed by default.
+func (t *Tool) WithAllowedHosts(hosts ...string) *Tool {
+	t.hosts = hosts
+	return t
+}
+
+func (t *Tool) Name() string {
+	return ToolName
+}
+
+func (t *Tool) Description() string {
+	return "Control the web browser: navigate to the URL, click the element by the CSS selector, extract the text of the page or the element, and take the screenshot of the page, which is shown to you."
+}
+
+func (t *Tool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+// RegisterMCP registers the tool with the output schema of Response
+func (t *Tool) RegisterMCP(registrator tools.McpServerRegistrator) error {
+	return registrator.RegisterTool(ToolName, t.Description(), t.Run)
+}
+
+func (t *Tool) RunMCP(ctx context.Context, req *Request) (*mcp.ToolResponse, error) {
+	res, err := t.Run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewStructuredToolResponse(res)
+}
+
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	var req Request
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	res, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.GetContent(), nil
+}
+
+// Run runs the browser action.
+func (t *Tool) Run(ctx context.Context, req *Request) (*Response, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	res := &Response{Action: req.Action}
+	var err error
+	switch req.Action {
+	case ActionNavigate:
+		if err = t.checkURL(req.URL); err != nil {
+			return nil, err
+		}
+		res.Page, err = t.driver.Navigate(ctx, req.URL)
+	case ActionClick:
+		if req.Selector == "" {
+			return nil, errors.Mark(errors.New("selector is required for click"), chatmodel.ErrToolInvalidInput)
+		}
+		res.Page, err = t.driver.Click(ctx, req.Selector)
+	case ActionExtract:
+		res.Text, err = t.driver.Extract(ctx, req.Selector)
+		res.Text, res.Truncated = truncate(res.Text, MaxTextLength)
+	case ActionScreenshot:
+		err = t.screenshot(ctx, req.FullPage, res)
+	default:
+		return nil, errors.Mark(errors.Newf("unsupported action %q", req.Action), chatmodel.ErrToolInvalidInput)
+	}
+	if err != nil {
+		if errors.Is(err, ErrElementNotFound) {
+			return nil, errors.Mark(err, chatmodel.ErrToolInvalidInput)
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func (t *Tool) screenshot(ctx context.Context, fullPage bool, res *Response) error {
+	data, err := t.driver.Screenshot(ctx, fullPage)
+	if err != nil {
+		return err
+	}
+	res.Parts = []llms.BinaryContent{{MIMEType: "image/png", Data: data}}
+	res.Screenshot = &Screenshot{
+		ContentType: "image/png",
+		Size:        len(data),
+	}
+	if t.blobs != nil {
+		res.Screenshot.ID, err = t.blobs.Put(ctx, "image/png", data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkURL allows the http and https URLs of the allowed hosts,
+// so the page can not read the local files.
+func (t *Tool) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.Mark(errors.Newf("invalid URL %q, the http or https URL is required", rawURL), chatmodel.ErrToolInvalidInput)
+	}
+	if len(t.hosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range t.hosts {
+		h = strings.ToLower(h)
+		if host == h || (strings.HasPrefix(h, ".") && (strings.HasSuffix(host, h) || host == h[1:])) {
+			return nil
+		}
+	}
+	return errors.Mark(errors.Newf("host %q is not allowed", host), chatmodel.ErrToolPermission)
+}
+
+// truncate returns the text up to size bytes at the rune boundary.
+func truncate(text string, size int) (string, bool) {
+	if len(text) <= size {
+		return text, false
+	}
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return text[:size], true
+}
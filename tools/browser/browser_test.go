@@ -0,0 +1,313 @@
+package browser_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/gogentic/tools/browser"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDriver struct {
+	calls []string
+	text  string
+}
+
+func (d *fakeDriver) Navigate(_ context.Context, url string) (*browser.Page, error) {
+	d.calls = append(d.calls, "navigate "+url)
+	return &browser.Page{URL: url, Title: "Example"}, nil
+}
+
+func (d *fakeDriver) Click(_ context.Context, selector string) (*browser.Page, error) {
+	d.calls = append(d.calls, "click "+selector)
+	if selector == "#missing" {
+		return nil, errors.Wrapf(browser.ErrElementNotFound, "selector %q", selector)
+	}
+	return &browser.Page{URL: "https://example.com/next", Title: "Next"}, nil
+}
+
+func (d *fakeDriver) Extract(_ context.Context, selector string) (string, error) {
+	d.calls = append(d.calls, "extract "+selector)
+	return d.text, nil
+}
+
+func (d *fakeDriver) Screenshot(_ context.Context, fullPage bool) ([]byte, error) {
+	if fullPage {
+		d.calls = append(d.calls, "screenshot full")
+	} else {
+		d.calls = append(d.calls, "screenshot")
+	}
+	return []byte("png"), nil
+}
+
+func (d *fakeDriver) Close() error {
+	return nil
+}
+
+func TestTool(t *testing.T) {
+	t.Parallel()
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+
+	driver := &fakeDriver{text: "Hello"}
+	blobs := store.NewMemoryBlobStore()
+	tool := browser.New(driver, blobs).WithAllowedHosts("example.com", ".example.org")
+	assert.Equal(t, browser.ToolName, tool.Name())
+	assert.NotEmpty(t, tool.Description())
+	assert.NotNil(t, tool.Parameters())
+
+	out, err := tool.Call(ctx, `{"action":"navigate","url":"https://example.com/"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"action":"navigate","page":{"url":"https://example.com/","title":"Example"}}`, out)
+
+	res, err := tool.Run(ctx, &browser.Request{Action: browser.ActionClick, Selector: "a.next"})
+	require.NoError(t, err)
+	assert.Equal(t, "Next", res.Page.Title)
+
+	res, err = tool.Run(ctx, &browser.Request{Action: browser.ActionExtract})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", res.Text)
+	assert.False(t, res.Truncated)
+
+	driver.text = strings.Repeat("é", browser.MaxTextLength)
+	res, err = tool.Run(ctx, &browser.Request{Action: browser.ActionExtract, Selector: "main"})
+	require.NoError(t, err)
+	assert.True(t, res.Truncated)
+	assert.Len(t, res.Text, browser.MaxTextLength)
+
+	res, err = tool.Run(ctx, &browser.Request{Action: browser.ActionScreenshot, FullPage: true})
+	require.NoError(t, err)
+	require.NotNil(t, res.Screenshot)
+	assert.Equal(t, "image/png", res.Screenshot.ContentType)
+	assert.Equal(t, 3, res.Screenshot.Size)
+	blob, err := blobs.Get(ctx, res.Screenshot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("png"), blob.Content)
+	assert.Equal(t, []llms.ContentPart{llms.BinaryContent{MIMEType: "image/png", Data: []byte("png")}}, res.ContentParts())
+	assert.NotContains(t, res.GetContent(), "cG5n")
+
+	assert.Equal(t, []string{
+		"navigate https://example.com/",
+		"click a.next",
+		"extract ",
+		"extract main",
+		"screenshot full",
+	}, driver.calls)
+
+	_, err = tool.Call(ctx, `not json`)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+}
+
+func TestTool_Errors(t *testing.T) {
+	t.Parallel()
+
+	tcs := []struct {
+		name     string
+		req      browser.Request
+		category chatmodel.ToolErrorCategory
+		exp      string
+	}{
+		{
+			name:     "file URL",
+			req:      browser.Request{Action: browser.ActionNavigate, URL: "file:///etc/passwd"},
+			category: chatmodel.ToolErrorInvalidInput,
+			exp:      `invalid URL "file:///etc/passwd", the http or https URL is required`,
+		},
+		{
+			name:     "host",
+			req:      browser.Request{Action: browser.ActionNavigate, URL: "https://evil.com/"},
+			category: chatmodel.ToolErrorPermission,
+			exp:      `host "evil.com" is not allowed`,
+		},
+		{
+			name:     "subdomain",
+			req:      browser.Request{Action: browser.ActionNavigate, URL: "https://www.example.com/"},
+			category: chatmodel.ToolErrorPermission,
+			exp:      `host "www.example.com" is not allowed`,
+		},
+		{
+			name:     "selector required",
+			req:      browser.Request{Action: browser.ActionClick},
+			category: chatmodel.ToolErrorInvalidInput,
+			exp:      "selector is required for click",
+		},
+		{
+			name:     "not found",
+			req:      browser.Request{Action: browser.ActionClick, Selector: "#missing"},
+			category: chatmodel.ToolErrorInvalidInput,
+			exp:      `selector "#missing": element not found`,
+		},
+		{
+			name:     "action",
+			req:      browser.Request{Action: "type"},
+			category: chatmodel.ToolErrorInvalidInput,
+			exp:      `unsupported action "type"`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tool := browser.New(&fakeDriver{}, nil).WithAllowedHosts("example.com", ".example.org")
+			_, err := tool.Run(context.Background(), &tc.req)
+			require.Error(t, err)
+			assert.EqualError(t, err, tc.exp)
+			assert.Equal(t, tc.category, chatmodel.GetToolErrorCategory(err))
+		})
+	}
+
+	tool := browser.New(&fakeDriver{}, nil).WithAllowedHosts(".example.org")
+	_, err := tool.Run(context.Background(), &browser.Request{Action: browser.ActionNavigate, URL: "https://docs.example.org/"})
+	assert.NoError(t, err)
+	_, err = tool.Run(context.Background(), &browser.Request{Action: browser.ActionNavigate, URL: "https://example.org/"})
+	assert.NoError(t, err)
+}
+
+// cdpServer is the fake browser, that responds to the CDP methods.
+type cdpServer struct {
+	lock    sync.Mutex
+	methods []string
+	found   bool
+}
+
+func (s *cdpServer) handle(t *testing.T) http.HandlerFunc {
+	upgrader := websocket.Upgrader{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json/version" {
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"webSocketDebuggerUrl": "ws://" + r.Host + "/devtools/browser/1",
+			})
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var req struct {
+				ID        int64          `json:"id"`
+				Method    string         `json:"method"`
+				Params    map[string]any `json:"params"`
+				SessionID string         `json:"sessionId"`
+			}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			s.lock.Lock()
+			s.methods = append(s.methods, req.Method)
+			found := s.found
+			s.lock.Unlock()
+
+			var result any = map[string]any{}
+			switch req.Method {
+			case "Target.createTarget":
+				result = map[string]any{"targetId": "T1"}
+			case "Target.attachToTarget":
+				result = map[string]any{"sessionId": "S1"}
+			case "Page.navigate":
+				assert.Equal(t, "S1", req.SessionID)
+				if req.Params["url"] == "https://bad.example.com/" {
+					result = map[string]any{"errorText": "net::ERR_NAME_NOT_RESOLVED"}
+				}
+			case "Page.captureScreenshot":
+				result = map[string]any{"data": base64.StdEncoding.EncodeToString([]byte("png"))}
+			case "Runtime.evaluate":
+				expr := req.Params["expression"].(string)
+				switch {
+				case strings.Contains(expr, "readyState"):
+					result = map[string]any{"result": map[string]any{"value": map[string]any{
+						"readyState": "complete", "url": "https://example.com/", "title": "Example",
+					}}}
+				case strings.Contains(expr, "el.click()") && found:
+					result = map[string]any{"result": map[string]any{"value": true}}
+				case strings.Contains(expr, "el.click()"):
+					result = map[string]any{"result": map[string]any{"value": false}}
+				case strings.Contains(expr, "document.body.innerText"):
+					result = map[string]any{"result": map[string]any{"value": "Hello"}}
+				case strings.Contains(expr, `"bad["`):
+					result = map[string]any{
+						"result":           map[string]any{"type": "object"},
+						"exceptionDetails": map[string]any{"text": "Uncaught", "exception": map[string]any{"description": "SyntaxError: 'bad[' is not a valid selector"}},
+					}
+				default:
+					result = map[string]any{"result": map[string]any{"type": "object", "subtype": "null", "value": nil}}
+				}
+			}
+			// the events are ignored by the client
+			_ = conn.WriteJSON(map[string]any{"method": "Page.frameNavigated", "params": map[string]any{}})
+			_ = conn.WriteJSON(map[string]any{"id": req.ID, "result": result})
+		}
+	}
+}
+
+func TestCDP(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	srv := &cdpServer{}
+	ts := httptest.NewServer(srv.handle(t))
+	defer ts.Close()
+
+	_, err := browser.ConnectCDP(ctx, browser.CDPConfig{Endpoint: "localhost:9222"})
+	assert.EqualError(t, err, `invalid CDP endpoint "localhost:9222"`)
+
+	cdp, err := browser.ConnectCDP(ctx, browser.CDPConfig{Endpoint: ts.URL})
+	require.NoError(t, err)
+
+	page, err := cdp.Navigate(ctx, "https://example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, &browser.Page{URL: "https://example.com/", Title: "Example"}, page)
+
+	_, err = cdp.Navigate(ctx, "https://bad.example.com/")
+	assert.EqualError(t, err, "failed to navigate to https://bad.example.com/: net::ERR_NAME_NOT_RESOLVED")
+	assert.Equal(t, chatmodel.ToolErrorInvalidInput, chatmodel.GetToolErrorCategory(err))
+
+	_, err = cdp.Click(ctx, "#submit")
+	assert.ErrorIs(t, err, browser.ErrElementNotFound)
+
+	srv.lock.Lock()
+	srv.found = true
+	srv.lock.Unlock()
+	page, err = cdp.Click(ctx, "#submit")
+	require.NoError(t, err)
+	assert.Equal(t, "Example", page.Title)
+
+	text, err := cdp.Extract(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", text)
+
+	_, err = cdp.Extract(ctx, "#missing")
+	assert.ErrorIs(t, err, browser.ErrElementNotFound)
+
+	_, err = cdp.Extract(ctx, "bad[")
+	assert.EqualError(t, err, "script failed: SyntaxError: 'bad[' is not a valid selector")
+	assert.Equal(t, chatmodel.ToolErrorInvalidInput, chatmodel.GetToolErrorCategory(err))
+
+	data, err := cdp.Screenshot(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("png"), data)
+
+	require.NoError(t, cdp.Close())
+
+	srv.lock.Lock()
+	methods := srv.methods
+	srv.lock.Unlock()
+	assert.Equal(t, []string{"Target.createTarget", "Target.attachToTarget", "Emulation.setDeviceMetricsOverride"}, methods[:3])
+	assert.Contains(t, methods, "Page.getLayoutMetrics")
+	assert.Equal(t, "Target.closeTarget", methods[len(methods)-1])
+
+	_, err = cdp.Navigate(ctx, "https://example.com/")
+	require.Error(t, err)
+	assert.Equal(t, chatmodel.ToolErrorTransient, chatmodel.GetToolErrorCategory(err))
+}
@@ -0,0 +1,406 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DefaultWidth is the default width of the viewport.
+	DefaultWidth = 1280
+	// DefaultHeight is the default height of the viewport.
+	DefaultHeight = 800
+	// DefaultLoadTimeout is the default timeout of the page load.
+	DefaultLoadTimeout = 30 * time.Second
+)
+
+// CDPConfig is the configuration of the Chrome DevTools Protocol driver.
+type CDPConfig struct {
+	// Endpoint is the CDP endpoint of the browser: the HTTP endpoint, like http://localhost:9222,
+	// the same as for Playwright connectOverCDP, or the browser WebSocket URL.
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	// Width and Height are the size of the viewport, DefaultWidth and DefaultHeight if not set.
+	Width  int `json:"width,omitempty" yaml:"width"`
+	Height int `json:"height,omitempty" yaml:"height"`
+	// LoadTimeout is the timeout of the page load, DefaultLoadTimeout if not set.
+	LoadTimeout time.Duration `json:"load_timeout,omitempty" yaml:"load_timeout"`
+	// HTTPClient is the HTTP client to discover the WebSocket URL, http.DefaultClient if not set.
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+}
+
+// CDP is the Driver of the browser page over the Chrome DevTools Protocol,
+// it works with Chrome, Chromium and Edge started with --remote-debugging-port,
+// and with the Chromium browsers launched by Playwright.
+// The page is created on connect, and closed with Close.
+type CDP struct {
+	cfg       CDPConfig
+	conn      *websocket.Conn
+	targetID  string
+	sessionID string
+
+	lastID  atomic.Int64
+	writeMu sync.Mutex
+
+	lock    sync.Mutex
+	pending map[int64]chan *cdpResponse
+	done    chan struct{}
+	err     error
+}
+
+var _ Driver = (*CDP)(nil)
+
+type cdpRequest struct {
+	ID        int64  `json:"id"`
+	Method    string `json:"method"`
+	Params    any    `json:"params,omitempty"`
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+type cdpResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ConnectCDP connects to the browser, and creates the new page.
+func ConnectCDP(ctx context.Context, cfg CDPConfig) (*CDP, error) {
+	if cfg.Width <= 0 {
+		cfg.Width = DefaultWidth
+	}
+	if cfg.Height <= 0 {
+		cfg.Height = DefaultHeight
+	}
+	if cfg.LoadTimeout <= 0 {
+		cfg.LoadTimeout = DefaultLoadTimeout
+	}
+
+	wsURL, err := discoverWebSocketURL(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return nil, errors.Mark(errors.Wrap(err, "failed to connect to the browser"), chatmodel.ErrToolTransient)
+	}
+
+	c := &CDP{
+		cfg:     cfg,
+		conn:    conn,
+		pending: map[int64]chan *cdpResponse{},
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+
+	if err = c.open(ctx); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func discoverWebSocketURL(ctx context.Context, cfg CDPConfig) (string, error) {
+	if strings.HasPrefix(cfg.Endpoint, "ws://") || strings.HasPrefix(cfg.Endpoint, "wss://") {
+		return cfg.Endpoint, nil
+	}
+	if !strings.HasPrefix(cfg.Endpoint, "http://") && !strings.HasPrefix(cfg.Endpoint, "https://") {
+		return "", errors.Newf("invalid CDP endpoint %q", cfg.Endpoint)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.Endpoint, "/")+"/json/version", nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Mark(errors.Wrap(err, "failed to get the browser version"), chatmodel.ErrToolTransient)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Newf("failed to get the browser version: %s", resp.Status)
+	}
+	var version struct {
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&version); err != nil || version.WebSocketDebuggerURL == "" {
+		return "", errors.New("the browser did not return the WebSocket URL")
+	}
+	return version.WebSocketDebuggerURL, nil
+}
+
+// open creates the page, and attaches to it with the flat session.
+func (c *CDP) open(ctx context.Context) error {
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := c.call(ctx, "", "Target.createTarget", map[string]any{"url": "about:blank"}, &target); err != nil {
+		return err
+	}
+	c.targetID = target.TargetID
+
+	var session struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := c.call(ctx, "", "Target.attachToTarget", map[string]any{"targetId": c.targetID, "flatten": true}, &session); err != nil {
+		return err
+	}
+	c.sessionID = session.SessionID
+
+	return c.send(ctx, "Emulation.setDeviceMetricsOverride", map[string]any{
+		"width":             c.cfg.Width,
+		"height":            c.cfg.Height,
+		"deviceScaleFactor": 1,
+		"mobile":            false,
+	}, nil)
+}
+
+// Navigate opens the URL, and waits for the document to load.
+func (c *CDP) Navigate(ctx context.Context, url string) (*Page, error) {
+	var res struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := c.send(ctx, "Page.navigate", map[string]any{"url": url}, &res); err != nil {
+		return nil, err
+	}
+	if res.ErrorText != "" {
+		return nil, errors.Mark(errors.Newf("failed to navigate to %s: %s", url, res.ErrorText), chatmodel.ErrToolInvalidInput)
+	}
+	return c.waitLoad(ctx)
+}
+
+// Click scrolls to the element and clicks it, and waits for the document to load,
+// in case the click started the navigation.
+func (c *CDP) Click(ctx context.Context, selector string) (*Page, error) {
+	var found bool
+	err := c.evaluate(ctx, `(() => {
+	const el = document.querySelector(`+jsString(selector)+`);
+	if (!el) return false;
+	el.scrollIntoView({block: "center"});
+	el.click();
+	return true;
+})()`, &found)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Wrapf(ErrElementNotFound, "selector %q", selector)
+	}
+	return c.waitLoad(ctx)
+}
+
+// Extract returns the rendered text of the element or the page.
+func (c *CDP) Extract(ctx context.Context, selector string) (string, error) {
+	expr := `document.body ? document.body.innerText : ""`
+	if selector != "" {
+		expr = `(() => {
+	const el = document.querySelector(` + jsString(selector) + `);
+	return el ? (el.innerText ?? el.textContent) : null;
+})()`
+	}
+	var text *string
+	if err := c.evaluate(ctx, expr, &text); err != nil {
+		return "", err
+	}
+	if text == nil {
+		return "", errors.Wrapf(ErrElementNotFound, "selector %q", selector)
+	}
+	return *text, nil
+}
+
+// Screenshot captures the PNG image of the viewport or the whole page.
+func (c *CDP) Screenshot(ctx context.Context, fullPage bool) ([]byte, error) {
+	params := map[string]any{"format": "png"}
+	if fullPage {
+		var metrics struct {
+			CSSContentSize struct {
+				Width  float64 `json:"width"`
+				Height float64 `json:"height"`
+			} `json:"cssContentSize"`
+		}
+		if err := c.send(ctx, "Page.getLayoutMetrics", nil, &metrics); err != nil {
+			return nil, err
+		}
+		params["captureBeyondViewport"] = true
+		params["clip"] = map[string]any{
+			"x":      0,
+			"y":      0,
+			"width":  metrics.CSSContentSize.Width,
+			"height": metrics.CSSContentSize.Height,
+			"scale":  1,
+		}
+	}
+	var res struct {
+		Data string `json:"data"`
+	}
+	if err := c.send(ctx, "Page.captureScreenshot", params, &res); err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(res.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode the screenshot")
+	}
+	return data, nil
+}
+
+// Close closes the page and the connection.
+func (c *CDP) Close() error {
+	if c.targetID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = c.call(ctx, "", "Target.closeTarget", map[string]any{"targetId": c.targetID}, nil)
+		cancel()
+		c.targetID = ""
+	}
+	return c.conn.Close()
+}
+
+// waitLoad waits for the document to be loaded, and returns the page.
+func (c *CDP) waitLoad(ctx context.Context) (*Page, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.LoadTimeout)
+	defer cancel()
+
+	for {
+		var state struct {
+			ReadyState string `json:"readyState"`
+			URL        string `json:"url"`
+			Title      string `json:"title"`
+		}
+		err := c.evaluate(ctx, `({readyState: document.readyState, url: location.href, title: document.title})`, &state)
+		if err != nil {
+			return nil, err
+		}
+		if state.ReadyState == "complete" {
+			return &Page{URL: state.URL, Title: state.Title}, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Mark(errors.New("timeout waiting for the page to load"), chatmodel.ErrToolTransient)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// evaluate evaluates the JavaScript expression in the page, and unmarshals the value.
+func (c *CDP) evaluate(ctx context.Context, expression string, value any) error {
+	var res struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	err := c.send(ctx, "Runtime.evaluate", map[string]any{
+		"expression":    expression,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	}, &res)
+	if err != nil {
+		return err
+	}
+	if ex := res.ExceptionDetails; ex != nil {
+		msg := ex.Exception.Description
+		if msg == "" {
+			msg = ex.Text
+		}
+		return errors.Mark(errors.Newf("script failed: %s", msg), chatmodel.ErrToolInvalidInput)
+	}
+	if len(res.Result.Value) == 0 {
+		return nil
+	}
+	return errors.WithStack(json.Unmarshal(res.Result.Value, value))
+}
+
+// send calls the method of the page session.
+func (c *CDP) send(ctx context.Context, method string, params, result any) error {
+	return c.call(ctx, c.sessionID, method, params, result)
+}
+
+func (c *CDP) call(ctx context.Context, sessionID, method string, params, result any) error {
+	id := c.lastID.Add(1)
+	ch := make(chan *cdpResponse, 1)
+
+	c.lock.Lock()
+	if c.err != nil {
+		c.lock.Unlock()
+		return c.err
+	}
+	c.pending[id] = ch
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.pending, id)
+		c.lock.Unlock()
+	}()
+
+	c.writeMu.Lock()
+	err := c.conn.WriteJSON(&cdpRequest{ID: id, Method: method, Params: params, SessionID: sessionID})
+	c.writeMu.Unlock()
+	if err != nil {
+		return errors.Mark(errors.Wrapf(err, "failed to send %s", method), chatmodel.ErrToolTransient)
+	}
+
+	select {
+	case res := <-ch:
+		if res.Error != nil {
+			return errors.Newf("%s failed: %s", method, res.Error.Message)
+		}
+		if result == nil || len(res.Result) == 0 {
+			return nil
+		}
+		return errors.WithStack(json.Unmarshal(res.Result, result))
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return errors.Mark(errors.Wrapf(ctx.Err(), "%s failed", method), chatmodel.ErrToolTransient)
+	}
+}
+
+// readLoop dispatches the responses, the events are ignored.
+func (c *CDP) readLoop() {
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.lock.Lock()
+			c.err = errors.Mark(errors.Wrap(err, "browser connection closed"), chatmodel.ErrToolTransient)
+			c.lock.Unlock()
+			close(c.done)
+			return
+		}
+		var res cdpResponse
+		if json.Unmarshal(data, &res) != nil || res.ID == 0 {
+			continue
+		}
+		c.lock.Lock()
+		ch := c.pending[res.ID]
+		c.lock.Unlock()
+		if ch != nil {
+			ch <- &res
+		}
+	}
+}
+
+// jsString returns the JavaScript string literal.
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,208 @@
+// Package calc provides the deterministic calculator tool:
+// the arithmetic expressions, the unit conversion, the date arithmetic and the time zone conversion,
+// so the numeric questions do not require the code execution or the LLM math.
+package calc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mcp"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+)
+
+// ToolName is the name registered with the LLM.
+const ToolName = "calculator"
+
+// Operation is the calculation to perform.
+type Operation string
+
+const (
+	// OperationEvaluate evaluates the arithmetic expression.
+	OperationEvaluate Operation = "evaluate"
+	// OperationConvert converts the value between the units.
+	OperationConvert Operation = "convert"
+	// OperationDateAdd adds the duration to the date.
+	OperationDateAdd Operation = "date_add"
+	// OperationDateDiff returns the difference between the dates.
+	OperationDateDiff Operation = "date_diff"
+	// OperationTimezone converts the date between the time zones.
+	OperationTimezone Operation = "timezone"
+)
+
+// Request is the JSON input expected by the tool.
+type Request struct {
+	Operation  Operation `json:"operation" yaml:"operation" jsonschema:"required,enum=evaluate,enum=convert,enum=date_add,enum=date_diff,enum=timezone,title=Operation,description=The calculation to perform."`
+	Expression string    `json:"expression,omitempty" yaml:"expression" jsonschema:"title=Expression,description=The arithmetic expression for evaluate\\, for example (2+3)*sqrt(16)^2. Supports + - * / % ^ !\\, parentheses\\, pi\\, e and the functions abs\\, sqrt\\, cbrt\\, exp\\, ln\\, log\\, log2\\, log10\\, sin\\, cos\\, tan\\, asin\\, acos\\, atan\\, atan2\\, floor\\, ceil\\, trunc\\, round\\, pow\\, hypot\\, min and max."`
+	Value      float64   `json:"value,omitempty" yaml:"value" jsonschema:"title=Value,description=The value to convert for convert."`
+	From       string    `json:"from,omitempty" yaml:"from" jsonschema:"title=From,description=The source unit for convert\\, like km\\, lb\\, F\\, GiB or kWh. The time zone of the date for date_add\\, date_diff and timezone\\, as IANA name like America/New_York. Default is UTC."`
+	To         string    `json:"to,omitempty" yaml:"to" jsonschema:"title=To,description=The target unit for convert\\, or the target time zone for timezone."`
+	Date       string    `json:"date,omitempty" yaml:"date" jsonschema:"title=Date,description=The date for date_add\\, date_diff and timezone\\, in RFC 3339 or YYYY-MM-DD format\\, or now or today."`
+	EndDate    string    `json:"end_date,omitempty" yaml:"end_date" jsonschema:"title=End Date,description=The end date for date_diff."`
+	Duration   string    `json:"duration,omitempty" yaml:"duration" jsonschema:"title=Duration,description=The ISO 8601 duration to add for date_add\\, for example P1Y2M10DT2H30M\\, or -P3D to subtract."`
+}
+
+// Response is the tool output.
+type Response struct {
+	Operation Operation `json:"operation" yaml:"operation"`
+	// Result is the formatted result: the number, the value with the unit, or the date in RFC 3339 format.
+	Result string `json:"result" yaml:"result"`
+	// Weekday is the day of the week of the resulting date.
+	Weekday string `json:"weekday,omitempty" yaml:"weekday"`
+	// Diff is the difference between the dates for date_diff.
+	Diff *DateDiff `json:"diff,omitempty" yaml:"diff"`
+}
+
+// GetContent gets the content of the message for the chat history
+func (r *Response) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+// Tool implements tools.ITool, it performs the deterministic calculations.
+type Tool struct {
+	now        func() time.Time
+	funcParams *jsonschema.Schema
+}
+
+var _ tools.Tool[Request, Response] = (*Tool)(nil)
+var _ tools.MCPTool[Request] = (*Tool)(nil)
+
+// New returns a new calculator tool.
+func New() *Tool {
+	sc, _ := schema.New(reflect.TypeOf(Request{}))
+	return &Tool{
+		now:        time.Now,
+		funcParams: sc.Parameters,
+	}
+}
+
+// WithClock sets the clock used for the "now" and "today" dates.
+func (t *Tool) WithClock(now func() time.Time) *Tool {
+	if now != nil {
+		t.now = now
+	}
+	return t
+}
+
+func (t *Tool) Name() string {
+	return ToolName
+}
+
+func (t *Tool) Description() string {
+	return "Deterministic calculator: evaluate arithmetic expressions, convert units (length, mass, time, volume, area, speed, temperature, data, energy, pressure, angle), add a duration to a date, compute the difference between dates, and convert dates between time zones. Use it instead of calculating in your head."
+}
+
+func (t *Tool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+// RegisterMCP registers the tool with the output schema of Response
+func (t *Tool) RegisterMCP(registrator tools.McpServerRegistrator) error {
+	return registrator.RegisterTool(ToolName, t.Description(), t.Run)
+}
+
+func (t *Tool) RunMCP(ctx context.Context, req *Request) (*mcp.ToolResponse, error) {
+	res, err := t.Run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewStructuredToolResponse(res)
+}
+
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	var req Request
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	res, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.GetContent(), nil
+}
+
+// Run performs the calculation, the errors of the input are marked with chatmodel.ErrToolInvalidInput.
+func (t *Tool) Run(_ context.Context, req *Request) (*Response, error) {
+	res, err := t.run(req)
+	if err != nil {
+		return nil, errors.Mark(errors.WithMessagef(err, "%s failed", req.Operation), chatmodel.ErrToolInvalidInput)
+	}
+	res.Operation = req.Operation
+	return res, nil
+}
+
+func (t *Tool) run(req *Request) (*Response, error) {
+	switch req.Operation {
+	case OperationEvaluate:
+		v, err := Evaluate(req.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Result: FormatNumber(v)}, nil
+
+	case OperationConvert:
+		v, err := Convert(req.Value, req.From, req.To)
+		if err != nil {
+			return nil, err
+		}
+		u, _ := FindUnit(req.To)
+		return &Response{Result: FormatNumber(v) + " " + u.Name}, nil
+
+	case OperationDateAdd:
+		date, err := t.parseDate(req.Date, req.From)
+		if err != nil {
+			return nil, err
+		}
+		p, err := ParsePeriod(req.Duration)
+		if err != nil {
+			return nil, err
+		}
+		return dateResponse(AddPeriod(date, p)), nil
+
+	case OperationDateDiff:
+		start, err := t.parseDate(req.Date, req.From)
+		if err != nil {
+			return nil, err
+		}
+		end, err := t.parseDate(req.EndDate, req.From)
+		if err != nil {
+			return nil, err
+		}
+		diff := Diff(start, end)
+		return &Response{Result: diff.Duration, Diff: &diff}, nil
+
+	case OperationTimezone:
+		date, err := t.parseDate(req.Date, req.From)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := LoadLocation(req.To)
+		if err != nil {
+			return nil, err
+		}
+		return dateResponse(date.In(loc)), nil
+	}
+	return nil, errors.Newf("unsupported operation %q", req.Operation)
+}
+
+func (t *Tool) parseDate(date, tz string) (time.Time, error) {
+	loc, err := LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ParseDate(date, t.now(), loc)
+}
+
+func dateResponse(t time.Time) *Response {
+	return &Response{
+		Result:  t.Format(time.RFC3339),
+		Weekday: t.Weekday().String(),
+	}
+}
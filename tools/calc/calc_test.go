@@ -0,0 +1,230 @@
+package calc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/tools/calc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		expr string
+		exp  string
+	}{
+		{"1+2*3", "7"},
+		{"(1+2)*3", "9"},
+		{"0.1+0.2", "0.3"},
+		{"10/4", "2.5"},
+		{"7 % 3", "1"},
+		{"2^3^2", "512"},
+		{"2**10", "1024"},
+		{"-2^2", "-4"},
+		{"2^-1", "0.5"},
+		{"--3", "3"},
+		{"5!", "120"},
+		{"1_000_000 * 3", "3000000"},
+		{"1.5e3 + 1E-1", "1500.1"},
+		{"sqrt(16) + abs(-2)", "6"},
+		{"log(1000)", "3"},
+		{"log(8, 2)", "3"},
+		{"ln(e)", "1"},
+		{"round(pi, 4)", "3.1416"},
+		{"min(3, 1, 2) + max(4, 5)", "6"},
+		{"sin(pi/2)", "1"},
+		{"cos(pi)", "-1"},
+		{"2 * PI", "6.28318530718"},
+		{"1e22", "1e+22"},
+		{"1/3", "0.333333333333"},
+	}
+	for _, tc := range tcases {
+		t.Run(tc.expr, func(t *testing.T) {
+			v, err := calc.Evaluate(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, calc.FormatNumber(v))
+		})
+	}
+
+	errs := []struct {
+		expr string
+		exp  string
+	}{
+		{"", "at position 1: unexpected end of expression"},
+		{"1 +", "at position 4: unexpected end of expression"},
+		{"(1+2", "at position 5: expected )"},
+		{"1 2", `at position 3: unexpected "2"`},
+		{"1/0", "at position 2: division by zero"},
+		{"5 % 0", "at position 3: modulo by zero"},
+		{"foo + 1", `at position 1: unknown identifier "foo"`},
+		{"foo(1)", `at position 1: unknown function "foo"`},
+		{"sqrt", "at position 1: function sqrt requires arguments in parentheses"},
+		{"pow(2)", "at position 1: invalid number of arguments for pow: 1"},
+		{"1.2.3", `at position 1: invalid number "1.2.3"`},
+		{"(-1)!", "at position 5: factorial is supported for the integers from 0 to 170"},
+		{"sqrt(-1)", "result is not a number"},
+		{"10^400", "result is infinite"},
+	}
+	for _, tc := range errs {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := calc.Evaluate(tc.expr)
+			assert.EqualError(t, err, tc.exp)
+		})
+	}
+
+	deep := ""
+	for range 100 {
+		deep += "("
+	}
+	_, err := calc.Evaluate(deep + "1")
+	assert.ErrorContains(t, err, "expression is nested too deep")
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		value    float64
+		from, to string
+		exp      string
+	}{
+		{1, "mi", "km", "1.609344"},
+		{100, "km/h", "mph", "62.1371192237"},
+		{100, "C", "F", "212"},
+		{-40, "fahrenheit", "celsius", "-40"},
+		{0, "K", "°C", "-273.15"},
+		{1, "GiB", "MB", "1073.741824"},
+		{1, "Gbit", "MB", "125"},
+		{2, "lbs", "kg", "0.90718474"},
+		{1, "gallon", "l", "3.785411784"},
+		{1, "kWh", "J", "3600000"},
+		{180, "deg", "rad", "3.14159265359"},
+		{1, "atm", "psi", "14.6959487755"},
+		{1, "acre", "m2", "4046.8564224"},
+		{1, "wk", "h", "168"},
+		// case insensitive aliases
+		{1, "KM", "M", "1000"},
+		{1, "kib", "b", "1024"},
+	}
+	for _, tc := range tcases {
+		v, err := calc.Convert(tc.value, tc.from, tc.to)
+		require.NoError(t, err, "%s to %s", tc.from, tc.to)
+		assert.Equal(t, tc.exp, calc.FormatNumber(v), "%s to %s", tc.from, tc.to)
+	}
+
+	_, err := calc.Convert(1, "km", "kg")
+	assert.EqualError(t, err, "cannot convert km (length) to kg (mass)")
+	_, err = calc.Convert(1, "furlong", "km")
+	assert.ErrorContains(t, err, `unknown unit "furlong", supported units: length: m, km,`)
+}
+
+func TestPeriod(t *testing.T) {
+	t.Parallel()
+
+	tcases := []struct {
+		in  string
+		exp calc.Period
+		str string
+	}{
+		{"P1Y2M10DT2H30M", calc.Period{Years: 1, Months: 2, Days: 10, Hours: 2, Minutes: 30}, "P1Y2M10DT2H30M"},
+		{"-P3D", calc.Period{Days: -3}, "-P3D"},
+		{"p2w", calc.Period{Days: 14}, "P14D"},
+		{"PT45S", calc.Period{Seconds: 45}, "PT45S"},
+		{"P0D", calc.Period{}, "P0D"},
+	}
+	for _, tc := range tcases {
+		p, err := calc.ParsePeriod(tc.in)
+		require.NoError(t, err, tc.in)
+		assert.Equal(t, tc.exp, p, tc.in)
+		assert.Equal(t, tc.str, p.String(), tc.in)
+	}
+
+	for _, in := range []string{"", "P", "PT", "1D", "P1H", "P1.5D", "P99999999D"} {
+		_, err := calc.ParsePeriod(in)
+		assert.Error(t, err, in)
+	}
+
+	date := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2024-02-29T10:00:00Z", calc.AddPeriod(date, calc.Period{Months: 1}).Format(time.RFC3339))
+	assert.Equal(t, "2025-02-28T10:00:00Z", calc.AddPeriod(date, calc.Period{Years: 1, Months: 1}).Format(time.RFC3339))
+	assert.Equal(t, "2023-12-31T10:00:00Z", calc.AddPeriod(date, calc.Period{Months: -1}).Format(time.RFC3339))
+	assert.Equal(t, "2024-02-01T11:30:00Z", calc.AddPeriod(date, calc.Period{Days: 1, Hours: 1, Minutes: 30}).Format(time.RFC3339))
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	diff := calc.Diff(start, end)
+	assert.Equal(t, calc.Period{Months: 1, Days: 1, Hours: 12}, diff.Period)
+	assert.Equal(t, "P1M1DT12H", diff.Duration)
+	assert.Equal(t, 30.5, diff.TotalDays)
+	assert.Equal(t, 732.0, diff.TotalHours)
+	// Jan 31 is Wednesday, Mar 1 is Friday
+	assert.Equal(t, 22, diff.BusinessDays)
+
+	diff = calc.Diff(end, start)
+	assert.Equal(t, "-P1M1DT12H", diff.Duration)
+	assert.Equal(t, -30.5, diff.TotalDays)
+	assert.Equal(t, -22, diff.BusinessDays)
+
+	diff = calc.Diff(start, start)
+	assert.Equal(t, "P0D", diff.Duration)
+	assert.Equal(t, 0, diff.BusinessDays)
+}
+
+func TestTool(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 15, 13, 45, 0, 0, time.UTC)
+	tool := calc.New().WithClock(func() time.Time { return now })
+	assert.Equal(t, calc.ToolName, tool.Name())
+	assert.NotEmpty(t, tool.Description())
+	require.NotNil(t, tool.Parameters())
+
+	ctx := context.Background()
+	tcases := []struct {
+		input string
+		exp   string
+	}{
+		{`{"operation":"evaluate","expression":"(2+3)*sqrt(16)^2"}`, `{"operation":"evaluate","result":"80"}`},
+		{`{"operation":"convert","value":26.2,"from":"miles","to":"km"}`, `{"operation":"convert","result":"42.1648128 km"}`},
+		{`{"operation":"date_add","date":"2024-01-31","duration":"P1M"}`, `{"operation":"date_add","result":"2024-02-29T00:00:00Z","weekday":"Thursday"}`},
+		{`{"operation":"date_add","date":"today","duration":"-P1W"}`, `{"operation":"date_add","result":"2024-06-08T00:00:00Z","weekday":"Saturday"}`},
+		{`{"operation":"date_add","date":"2024-03-09T12:00:00","from":"America/New_York","duration":"P1D"}`, `{"operation":"date_add","result":"2024-03-10T12:00:00-04:00","weekday":"Sunday"}`},
+		{`{"operation":"date_diff","date":"2024-01-01","end_date":"2024-12-25"}`, `{"operation":"date_diff","result":"P11M24D","diff":{"period":{"months":11,"days":24},"duration":"P11M24D","total_days":359,"total_hours":8616,"business_days":257}}`},
+		{`{"operation":"timezone","date":"2024-06-15 09:00","from":"America/New_York","to":"Asia/Tokyo"}`, `{"operation":"timezone","result":"2024-06-15T22:00:00+09:00","weekday":"Saturday"}`},
+		{`{"operation":"timezone","date":"now","to":"Europe/London"}`, `{"operation":"timezone","result":"2024-06-15T14:45:00+01:00","weekday":"Saturday"}`},
+	}
+	for _, tc := range tcases {
+		out, err := tool.Call(ctx, tc.input)
+		require.NoError(t, err, tc.input)
+		assert.Equal(t, tc.exp, out, tc.input)
+	}
+
+	_, err := tool.Call(ctx, `not json`)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+
+	errs := []struct {
+		input string
+		exp   string
+	}{
+		{`{"operation":"evaluate","expression":"1/0"}`, "evaluate failed: at position 2: division by zero"},
+		{`{"operation":"convert","value":1,"from":"km","to":"kg"}`, "convert failed: cannot convert km (length) to kg (mass)"},
+		{`{"operation":"date_add","date":"tomorrow","duration":"P1D"}`, `date_add failed: invalid date "tomorrow", use RFC 3339 or YYYY-MM-DD format`},
+		{`{"operation":"timezone","date":"now","to":"Mars/Olympus"}`, `timezone failed: unknown time zone "Mars/Olympus", use the IANA name like America/New_York`},
+		{`{"operation":"sum"}`, `sum failed: unsupported operation "sum"`},
+	}
+	for _, tc := range errs {
+		_, err := tool.Call(ctx, tc.input)
+		assert.EqualError(t, err, tc.exp, tc.input)
+		assert.Equal(t, chatmodel.ToolErrorInvalidInput, chatmodel.GetToolErrorCategory(err), tc.input)
+	}
+}
@@ -0,0 +1,253 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	// the time zone database is embedded, so the conversions do not depend on the host
+	_ "time/tzdata"
+)
+
+// dateLayouts are the accepted formats of the dates, without the time zone
+// the date is in the location of the request.
+var dateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseDate parses the date in RFC 3339 format, or in one of the formats
+// YYYY-MM-DD, YYYY-MM-DDThh:mm[:ss] in the location.
+// The "now" date is returned as now in the location.
+func ParseDate(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "now") || strings.EqualFold(s, "today") {
+		t := now.In(loc)
+		if strings.EqualFold(s, "today") {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Newf("invalid date %q, use RFC 3339 or YYYY-MM-DD format", s)
+}
+
+// LoadLocation returns the time zone by the IANA name, like America/New_York,
+// or UTC if the name is empty.
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, "UTC") || strings.EqualFold(name, "Z") {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.Newf("unknown time zone %q, use the IANA name like America/New_York", name)
+	}
+	return loc, nil
+}
+
+// Period is the calendar period, like the ISO 8601 duration P1Y2M3DT4H5M6S.
+type Period struct {
+	Years   int `json:"years,omitempty" yaml:"years"`
+	Months  int `json:"months,omitempty" yaml:"months"`
+	Days    int `json:"days,omitempty" yaml:"days"`
+	Hours   int `json:"hours,omitempty" yaml:"hours"`
+	Minutes int `json:"minutes,omitempty" yaml:"minutes"`
+	Seconds int `json:"seconds,omitempty" yaml:"seconds"`
+}
+
+var isoPeriod = regexp.MustCompile(`^([+-])?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// ParsePeriod parses the ISO 8601 duration, like P1Y2M10DT2H30M, or -P3D for the negative period.
+// The weeks are converted to days.
+func ParsePeriod(s string) (Period, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	m := isoPeriod.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "-P" || s == "+P" || strings.HasSuffix(s, "T") {
+		return Period{}, errors.Newf("invalid duration %q, use ISO 8601 format like P1Y2M10DT2H30M", s)
+	}
+
+	n := make([]int, len(m))
+	for i := 2; i < len(m); i++ {
+		if m[i] == "" {
+			continue
+		}
+		v, err := strconv.Atoi(m[i])
+		if err != nil || v > 1_000_000 {
+			return Period{}, errors.Newf("invalid duration %q: the value is too large", s)
+		}
+		n[i] = v
+	}
+	p := Period{
+		Years:   n[2],
+		Months:  n[3],
+		Days:    n[4]*7 + n[5],
+		Hours:   n[6],
+		Minutes: n[7],
+		Seconds: n[8],
+	}
+	if m[1] == "-" {
+		p = p.negate()
+	}
+	return p, nil
+}
+
+func (p Period) negate() Period {
+	return Period{
+		Years:   -p.Years,
+		Months:  -p.Months,
+		Days:    -p.Days,
+		Hours:   -p.Hours,
+		Minutes: -p.Minutes,
+		Seconds: -p.Seconds,
+	}
+}
+
+// String returns the period in ISO 8601 format.
+func (p Period) String() string {
+	sign := ""
+	if p.Years < 0 || p.Months < 0 || p.Days < 0 || p.Hours < 0 || p.Minutes < 0 || p.Seconds < 0 {
+		sign = "-"
+		p = p.negate()
+	}
+
+	var b strings.Builder
+	b.WriteString(sign + "P")
+	for _, part := range []struct {
+		v    int
+		unit string
+	}{{p.Years, "Y"}, {p.Months, "M"}, {p.Days, "D"}} {
+		if part.v != 0 {
+			fmt.Fprintf(&b, "%d%s", part.v, part.unit)
+		}
+	}
+	if p.Hours != 0 || p.Minutes != 0 || p.Seconds != 0 {
+		b.WriteString("T")
+		for _, part := range []struct {
+			v    int
+			unit string
+		}{{p.Hours, "H"}, {p.Minutes, "M"}, {p.Seconds, "S"}} {
+			if part.v != 0 {
+				fmt.Fprintf(&b, "%d%s", part.v, part.unit)
+			}
+		}
+	}
+	if b.Len() == len(sign)+1 {
+		return "P0D"
+	}
+	return b.String()
+}
+
+// AddPeriod adds the period to the date.
+// Unlike time.AddDate, the day is clamped to the end of the month,
+// so Jan 31 plus one month is Feb 28 (or 29).
+func AddPeriod(t time.Time, p Period) time.Time {
+	months := p.Years*12 + p.Months
+	if months != 0 {
+		first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		first = first.AddDate(0, months, 0)
+		day := min(t.Day(), daysIn(first.Year(), first.Month()))
+		t = time.Date(first.Year(), first.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	}
+	if p.Days != 0 {
+		t = t.AddDate(0, 0, p.Days)
+	}
+	return t.Add(time.Duration(p.Hours)*time.Hour + time.Duration(p.Minutes)*time.Minute + time.Duration(p.Seconds)*time.Second)
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// DateDiff is the difference between two dates.
+type DateDiff struct {
+	// Period is the calendar difference, negative if the end date is before the start date.
+	Period Period `json:"period" yaml:"period"`
+	// Duration is the period in ISO 8601 format.
+	Duration string `json:"duration" yaml:"duration"`
+	// TotalDays is the number of days, including the fraction of the day.
+	TotalDays float64 `json:"total_days" yaml:"total_days"`
+	// TotalHours is the number of hours, including the fraction of the hour.
+	TotalHours float64 `json:"total_hours" yaml:"total_hours"`
+	// BusinessDays is the number of the weekdays from Monday to Friday,
+	// from the start date inclusive to the end date exclusive.
+	BusinessDays int `json:"business_days" yaml:"business_days"`
+}
+
+// Diff returns the difference between the dates, the end date is converted to the location of the start date.
+func Diff(start, end time.Time) DateDiff {
+	neg := end.Before(start)
+	if neg {
+		start, end = end, start
+	}
+	end = end.In(start.Location())
+
+	// the calendar difference: the whole months, then the rest
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+	if months > 0 && AddPeriod(start, Period{Months: months}).After(end) {
+		months--
+	}
+	rest := end.Sub(AddPeriod(start, Period{Months: months}))
+	days := int(rest / (24 * time.Hour))
+	rest -= time.Duration(days) * 24 * time.Hour
+
+	p := Period{
+		Years:   months / 12,
+		Months:  months % 12,
+		Days:    days,
+		Hours:   int(rest / time.Hour),
+		Minutes: int(rest % time.Hour / time.Minute),
+		Seconds: int(rest % time.Minute / time.Second),
+	}
+
+	total := end.Sub(start)
+	busy := businessDays(start, end)
+	if neg {
+		p = p.negate()
+		total = -total
+		busy = -busy
+	}
+	return DateDiff{
+		Period:       p,
+		Duration:     p.String(),
+		TotalDays:    round(total.Hours()/24, 6),
+		TotalHours:   round(total.Hours(), 6),
+		BusinessDays: busy,
+	}
+}
+
+func businessDays(start, end time.Time) int {
+	from := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(to.Sub(from).Hours() / 24)
+
+	// the whole weeks have 5 business days, then count the rest
+	res := days / 7 * 5
+	wd := (int(from.Weekday()) + days/7*7) % 7
+	for i := 0; i < days%7; i++ {
+		if d := (wd + i) % 7; d != int(time.Saturday) && d != int(time.Sunday) {
+			res++
+		}
+	}
+	return res
+}
+
+func round(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
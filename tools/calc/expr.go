@@ -0,0 +1,408 @@
+package calc
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cockroachdb/errors"
+)
+
+// MaxExpressionLength is the maximum length of the expression in bytes.
+const MaxExpressionLength = 1024
+
+// maxDepth limits the nesting of the parentheses and the unary operators.
+const maxDepth = 64
+
+var constants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+}
+
+type function struct {
+	minArgs int
+	// maxArgs is -1 for the variadic functions
+	maxArgs int
+	fn      func(args []float64) (float64, error)
+}
+
+func unary(fn func(float64) float64) function {
+	return function{minArgs: 1, maxArgs: 1, fn: func(args []float64) (float64, error) {
+		return fn(args[0]), nil
+	}}
+}
+
+func binary(fn func(float64, float64) float64) function {
+	return function{minArgs: 2, maxArgs: 2, fn: func(args []float64) (float64, error) {
+		return fn(args[0], args[1]), nil
+	}}
+}
+
+var functions = map[string]function{
+	"abs":   unary(math.Abs),
+	"sqrt":  unary(math.Sqrt),
+	"cbrt":  unary(math.Cbrt),
+	"exp":   unary(math.Exp),
+	"ln":    unary(math.Log),
+	"log2":  unary(math.Log2),
+	"log10": unary(math.Log10),
+	"sin":   unary(math.Sin),
+	"cos":   unary(math.Cos),
+	"tan":   unary(math.Tan),
+	"asin":  unary(math.Asin),
+	"acos":  unary(math.Acos),
+	"atan":  unary(math.Atan),
+	"sinh":  unary(math.Sinh),
+	"cosh":  unary(math.Cosh),
+	"tanh":  unary(math.Tanh),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"trunc": unary(math.Trunc),
+	"atan2": binary(math.Atan2),
+	"pow":   binary(math.Pow),
+	"hypot": binary(math.Hypot),
+	"log": {minArgs: 1, maxArgs: 2, fn: func(args []float64) (float64, error) {
+		if len(args) == 2 {
+			return math.Log(args[0]) / math.Log(args[1]), nil
+		}
+		return math.Log10(args[0]), nil
+	}},
+	"round": {minArgs: 1, maxArgs: 2, fn: func(args []float64) (float64, error) {
+		if len(args) == 2 {
+			p := math.Pow(10, math.Trunc(args[1]))
+			return math.Round(args[0]*p) / p, nil
+		}
+		return math.Round(args[0]), nil
+	}},
+	"min": {minArgs: 1, maxArgs: -1, fn: func(args []float64) (float64, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			res = math.Min(res, v)
+		}
+		return res, nil
+	}},
+	"max": {minArgs: 1, maxArgs: -1, fn: func(args []float64) (float64, error) {
+		res := args[0]
+		for _, v := range args[1:] {
+			res = math.Max(res, v)
+		}
+		return res, nil
+	}},
+}
+
+// Evaluate returns the value of the arithmetic expression.
+//
+// The expression supports the numbers, the operators + - * / % ^ (or **), the factorial !,
+// the parentheses, the constants pi, e and tau, and the functions:
+// abs, sqrt, cbrt, exp, ln, log (base 10, or log(x, base)), log2, log10,
+// sin, cos, tan, asin, acos, atan, atan2, sinh, cosh, tanh,
+// floor, ceil, trunc, round (round(x, digits)), pow, hypot, min and max.
+// The trigonometric functions use radians.
+func Evaluate(expression string) (float64, error) {
+	if len(expression) > MaxExpressionLength {
+		return 0, errors.Newf("expression is longer than %d bytes", MaxExpressionLength)
+	}
+	p := &parser{src: expression}
+	p.next()
+	v, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	if p.tok.kind != tokEOF {
+		return 0, p.errorf("unexpected %q", p.tok.text)
+	}
+	if math.IsNaN(v) {
+		return 0, errors.New("result is not a number")
+	}
+	if math.IsInf(v, 0) {
+		return 0, errors.New("result is infinite")
+	}
+	return v, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	num  float64
+	err  error
+}
+
+type parser struct {
+	src   string
+	pos   int
+	tok   token
+	depth int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return errors.Newf("at position %d: "+format, append([]any{p.tok.pos + 1}, args...)...)
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.' || p.src[p.pos] == '_') {
+			p.pos++
+		}
+		// the exponent, like 1e-3
+		if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+			end := p.pos + 1
+			if end < len(p.src) && (p.src[end] == '+' || p.src[end] == '-') {
+				end++
+			}
+			if end < len(p.src) && isDigit(p.src[end]) {
+				for end < len(p.src) && isDigit(p.src[end]) {
+					end++
+				}
+				p.pos = end
+			}
+		}
+		text := p.src[start:p.pos]
+		// the digits may be separated by underscores, like 1_000_000
+		num, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64)
+		p.tok = token{kind: tokNumber, text: text, pos: start, num: num, err: err}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isDigit(p.src[p.pos]) || unicode.IsLetter(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: strings.ToLower(p.src[start:p.pos]), pos: start}
+	case c == '*' && strings.HasPrefix(p.src[p.pos:], "**"):
+		p.pos += 2
+		p.tok = token{kind: tokOp, text: "^", pos: start}
+	default:
+		p.pos++
+		p.tok = token{kind: tokOp, text: string(c), pos: start}
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *parser) isOp(ops string) bool {
+	return p.tok.kind == tokOp && strings.Contains(ops, p.tok.text)
+}
+
+// expr = term { ("+" | "-") term }
+func (p *parser) expr() (float64, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return 0, p.errorf("expression is nested too deep")
+	}
+
+	left, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for p.isOp("+-") {
+		op := p.tok.text
+		p.next()
+		right, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+	return left, nil
+}
+
+// term = unary { ("*" | "/" | "%") unary }
+func (p *parser) term() (float64, error) {
+	left, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for p.isOp("*/%") {
+		op := p.tok
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op.text {
+		case "*":
+			left *= right
+		case "/":
+			if right == 0 {
+				return 0, errors.Newf("at position %d: division by zero", op.pos+1)
+			}
+			left /= right
+		case "%":
+			if right == 0 {
+				return 0, errors.Newf("at position %d: modulo by zero", op.pos+1)
+			}
+			left = math.Mod(left, right)
+		}
+	}
+	return left, nil
+}
+
+// unary = ("-" | "+") unary | power
+func (p *parser) unary() (float64, error) {
+	if p.isOp("+-") {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxDepth {
+			return 0, p.errorf("expression is nested too deep")
+		}
+
+		neg := p.tok.text == "-"
+		p.next()
+		v, err := p.unary()
+		if neg {
+			v = -v
+		}
+		return v, err
+	}
+	return p.power()
+}
+
+// power = postfix [ "^" unary ], right associative, so -2^2 is -4 and 2^-1 is 0.5
+func (p *parser) power() (float64, error) {
+	base, err := p.postfix()
+	if err != nil {
+		return 0, err
+	}
+	if !p.isOp("^") {
+		return base, nil
+	}
+	p.next()
+	exp, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exp), nil
+}
+
+// postfix = primary { "!" }
+func (p *parser) postfix() (float64, error) {
+	v, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	for p.isOp("!") {
+		if v < 0 || v != math.Trunc(v) || v > 170 {
+			return 0, p.errorf("factorial is supported for the integers from 0 to 170")
+		}
+		v = math.Gamma(v + 1)
+		p.next()
+	}
+	return v, nil
+}
+
+// primary = number | constant | function "(" expr { "," expr } ")" | "(" expr ")"
+func (p *parser) primary() (float64, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokEOF:
+		return 0, p.errorf("unexpected end of expression")
+	case tokNumber:
+		if tok.err != nil {
+			return 0, p.errorf("invalid number %q", tok.text)
+		}
+		p.next()
+		return tok.num, nil
+	case tokIdent:
+		p.next()
+		if p.isOp("(") {
+			return p.call(tok)
+		}
+		if v, ok := constants[tok.text]; ok {
+			return v, nil
+		}
+		if _, ok := functions[tok.text]; ok {
+			return 0, errors.Newf("at position %d: function %s requires arguments in parentheses", tok.pos+1, tok.text)
+		}
+		return 0, errors.Newf("at position %d: unknown identifier %q", tok.pos+1, tok.text)
+	}
+
+	if !p.isOp("(") {
+		return 0, p.errorf("unexpected %q", tok.text)
+	}
+	p.next()
+	v, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	if !p.isOp(")") {
+		return 0, p.errorf("expected )")
+	}
+	p.next()
+	return v, nil
+}
+
+func (p *parser) call(name token) (float64, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return 0, errors.Newf("at position %d: unknown function %q", name.pos+1, name.text)
+	}
+
+	// skip "("
+	p.next()
+	var args []float64
+	if !p.isOp(")") {
+		for {
+			v, err := p.expr()
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, v)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if !p.isOp(")") {
+		return 0, p.errorf("expected ) after the arguments of %s", name.text)
+	}
+	p.next()
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return 0, errors.Newf("at position %d: invalid number of arguments for %s: %d", name.pos+1, name.text, len(args))
+	}
+	return fn.fn(args)
+}
+
+// FormatNumber returns the number rounded to 12 significant digits,
+// to hide the floating point errors, like 0.1+0.2.
+func FormatNumber(v float64) string {
+	if v == 0 {
+		return "0"
+	}
+	r, err := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 12, 64), 64)
+	if err != nil {
+		r = v
+	}
+	if abs := math.Abs(r); abs >= 1e21 || abs < 1e-9 {
+		return strconv.FormatFloat(r, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(r, 'f', -1, 64)
+}
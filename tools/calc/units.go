@@ -0,0 +1,234 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Unit is the unit of measure, converted through the base unit of the category:
+// base = (value + Offset) * Factor.
+type Unit struct {
+	// Name is the canonical symbol of the unit.
+	Name string
+	// Category is the quantity measured by the unit, like length or mass.
+	// The units are converted only within the same category.
+	Category string
+	// Factor is the size of the unit in the base units of the category.
+	Factor float64
+	// Offset is added to the value before the scaling, used by the temperature units.
+	Offset float64
+	// Aliases are the alternative names of the unit.
+	Aliases []string
+}
+
+func (u *Unit) toBase(v float64) float64 {
+	return (v + u.Offset) * u.Factor
+}
+
+func (u *Unit) fromBase(v float64) float64 {
+	return v/u.Factor - u.Offset
+}
+
+// Units are the supported units of measure.
+// The US customary units are used for the gallon, the pint and the ton.
+var Units = []*Unit{
+	// length, meter
+	{Name: "m", Category: "length", Factor: 1, Aliases: []string{"meter", "meters", "metre", "metres"}},
+	{Name: "km", Category: "length", Factor: 1000, Aliases: []string{"kilometer", "kilometers", "kilometre", "kilometres"}},
+	{Name: "cm", Category: "length", Factor: 0.01, Aliases: []string{"centimeter", "centimeters", "centimetre", "centimetres"}},
+	{Name: "mm", Category: "length", Factor: 0.001, Aliases: []string{"millimeter", "millimeters", "millimetre", "millimetres"}},
+	{Name: "um", Category: "length", Factor: 1e-6, Aliases: []string{"µm", "micrometer", "micrometers", "micron", "microns"}},
+	{Name: "nm", Category: "length", Factor: 1e-9, Aliases: []string{"nanometer", "nanometers"}},
+	{Name: "mi", Category: "length", Factor: 1609.344, Aliases: []string{"mile", "miles"}},
+	{Name: "yd", Category: "length", Factor: 0.9144, Aliases: []string{"yard", "yards"}},
+	{Name: "ft", Category: "length", Factor: 0.3048, Aliases: []string{"foot", "feet"}},
+	{Name: "in", Category: "length", Factor: 0.0254, Aliases: []string{"inch", "inches"}},
+	{Name: "nmi", Category: "length", Factor: 1852, Aliases: []string{"nautical mile", "nautical miles"}},
+	{Name: "au", Category: "length", Factor: 149597870700, Aliases: []string{"astronomical unit"}},
+	{Name: "ly", Category: "length", Factor: 9460730472580800, Aliases: []string{"light year", "light years"}},
+
+	// mass, kilogram
+	{Name: "kg", Category: "mass", Factor: 1, Aliases: []string{"kilogram", "kilograms", "kilo", "kilos"}},
+	{Name: "g", Category: "mass", Factor: 0.001, Aliases: []string{"gram", "grams"}},
+	{Name: "mg", Category: "mass", Factor: 1e-6, Aliases: []string{"milligram", "milligrams"}},
+	{Name: "ug", Category: "mass", Factor: 1e-9, Aliases: []string{"µg", "microgram", "micrograms"}},
+	{Name: "t", Category: "mass", Factor: 1000, Aliases: []string{"tonne", "tonnes", "metric ton", "metric tons"}},
+	{Name: "lb", Category: "mass", Factor: 0.45359237, Aliases: []string{"lbs", "pound", "pounds"}},
+	{Name: "oz", Category: "mass", Factor: 0.028349523125, Aliases: []string{"ounce", "ounces"}},
+	{Name: "st", Category: "mass", Factor: 6.35029318, Aliases: []string{"stone", "stones"}},
+	{Name: "ton", Category: "mass", Factor: 907.18474, Aliases: []string{"tons", "short ton", "short tons"}},
+
+	// time, second
+	{Name: "s", Category: "time", Factor: 1, Aliases: []string{"sec", "secs", "second", "seconds"}},
+	{Name: "ms", Category: "time", Factor: 1e-3, Aliases: []string{"millisecond", "milliseconds"}},
+	{Name: "us", Category: "time", Factor: 1e-6, Aliases: []string{"µs", "microsecond", "microseconds"}},
+	{Name: "ns", Category: "time", Factor: 1e-9, Aliases: []string{"nanosecond", "nanoseconds"}},
+	{Name: "min", Category: "time", Factor: 60, Aliases: []string{"mins", "minute", "minutes"}},
+	{Name: "h", Category: "time", Factor: 3600, Aliases: []string{"hr", "hrs", "hour", "hours"}},
+	{Name: "d", Category: "time", Factor: 86400, Aliases: []string{"day", "days"}},
+	{Name: "wk", Category: "time", Factor: 604800, Aliases: []string{"week", "weeks"}},
+	// the Julian year of 365.25 days
+	{Name: "yr", Category: "time", Factor: 31557600, Aliases: []string{"year", "years"}},
+
+	// volume, cubic meter
+	{Name: "m3", Category: "volume", Factor: 1, Aliases: []string{"m³", "cubic meter", "cubic meters"}},
+	{Name: "l", Category: "volume", Factor: 1e-3, Aliases: []string{"liter", "liters", "litre", "litres"}},
+	{Name: "ml", Category: "volume", Factor: 1e-6, Aliases: []string{"milliliter", "milliliters", "millilitre", "millilitres", "cm3", "cc"}},
+	{Name: "cl", Category: "volume", Factor: 1e-5, Aliases: []string{"centiliter", "centiliters"}},
+	{Name: "dl", Category: "volume", Factor: 1e-4, Aliases: []string{"deciliter", "deciliters"}},
+	{Name: "gal", Category: "volume", Factor: 3.785411784e-3, Aliases: []string{"gallon", "gallons"}},
+	{Name: "qt", Category: "volume", Factor: 9.46352946e-4, Aliases: []string{"quart", "quarts"}},
+	{Name: "pt", Category: "volume", Factor: 4.73176473e-4, Aliases: []string{"pint", "pints"}},
+	{Name: "cup", Category: "volume", Factor: 2.365882365e-4, Aliases: []string{"cups"}},
+	{Name: "floz", Category: "volume", Factor: 2.95735295625e-5, Aliases: []string{"fl oz", "fluid ounce", "fluid ounces"}},
+	{Name: "tbsp", Category: "volume", Factor: 1.478676478125e-5, Aliases: []string{"tablespoon", "tablespoons"}},
+	{Name: "tsp", Category: "volume", Factor: 4.92892159375e-6, Aliases: []string{"teaspoon", "teaspoons"}},
+	{Name: "ft3", Category: "volume", Factor: 0.028316846592, Aliases: []string{"ft³", "cubic foot", "cubic feet"}},
+	{Name: "in3", Category: "volume", Factor: 1.6387064e-5, Aliases: []string{"in³", "cubic inch", "cubic inches"}},
+
+	// area, square meter
+	{Name: "m2", Category: "area", Factor: 1, Aliases: []string{"m²", "square meter", "square meters"}},
+	{Name: "km2", Category: "area", Factor: 1e6, Aliases: []string{"km²", "square kilometer", "square kilometers"}},
+	{Name: "cm2", Category: "area", Factor: 1e-4, Aliases: []string{"cm²", "square centimeter", "square centimeters"}},
+	{Name: "ha", Category: "area", Factor: 1e4, Aliases: []string{"hectare", "hectares"}},
+	{Name: "acre", Category: "area", Factor: 4046.8564224, Aliases: []string{"acres"}},
+	{Name: "ft2", Category: "area", Factor: 0.09290304, Aliases: []string{"ft²", "sq ft", "square foot", "square feet"}},
+	{Name: "in2", Category: "area", Factor: 6.4516e-4, Aliases: []string{"in²", "sq in", "square inch", "square inches"}},
+	{Name: "yd2", Category: "area", Factor: 0.83612736, Aliases: []string{"yd²", "square yard", "square yards"}},
+	{Name: "mi2", Category: "area", Factor: 2589988.110336, Aliases: []string{"mi²", "square mile", "square miles"}},
+
+	// speed, meter per second
+	{Name: "m/s", Category: "speed", Factor: 1, Aliases: []string{"mps", "meters per second"}},
+	{Name: "km/h", Category: "speed", Factor: 1000.0 / 3600, Aliases: []string{"kph", "kmh", "kilometers per hour"}},
+	{Name: "mph", Category: "speed", Factor: 0.44704, Aliases: []string{"mi/h", "miles per hour"}},
+	{Name: "kn", Category: "speed", Factor: 1852.0 / 3600, Aliases: []string{"kt", "knot", "knots"}},
+	{Name: "ft/s", Category: "speed", Factor: 0.3048, Aliases: []string{"fps", "feet per second"}},
+
+	// temperature, kelvin
+	{Name: "K", Category: "temperature", Factor: 1, Aliases: []string{"kelvin"}},
+	{Name: "C", Category: "temperature", Factor: 1, Offset: 273.15, Aliases: []string{"°C", "degC", "celsius", "centigrade"}},
+	{Name: "F", Category: "temperature", Factor: 5.0 / 9, Offset: 459.67, Aliases: []string{"°F", "degF", "fahrenheit"}},
+
+	// data, byte
+	{Name: "B", Category: "data", Factor: 1, Aliases: []string{"byte", "bytes"}},
+	{Name: "bit", Category: "data", Factor: 0.125, Aliases: []string{"bits"}},
+	{Name: "KB", Category: "data", Factor: 1e3, Aliases: []string{"kilobyte", "kilobytes"}},
+	{Name: "MB", Category: "data", Factor: 1e6, Aliases: []string{"megabyte", "megabytes"}},
+	{Name: "GB", Category: "data", Factor: 1e9, Aliases: []string{"gigabyte", "gigabytes"}},
+	{Name: "TB", Category: "data", Factor: 1e12, Aliases: []string{"terabyte", "terabytes"}},
+	{Name: "PB", Category: "data", Factor: 1e15, Aliases: []string{"petabyte", "petabytes"}},
+	{Name: "KiB", Category: "data", Factor: 1 << 10, Aliases: []string{"kibibyte", "kibibytes"}},
+	{Name: "MiB", Category: "data", Factor: 1 << 20, Aliases: []string{"mebibyte", "mebibytes"}},
+	{Name: "GiB", Category: "data", Factor: 1 << 30, Aliases: []string{"gibibyte", "gibibytes"}},
+	{Name: "TiB", Category: "data", Factor: 1 << 40, Aliases: []string{"tebibyte", "tebibytes"}},
+	{Name: "PiB", Category: "data", Factor: 1 << 50, Aliases: []string{"pebibyte", "pebibytes"}},
+	{Name: "kbit", Category: "data", Factor: 1e3 / 8, Aliases: []string{"kilobit", "kilobits"}},
+	{Name: "Mbit", Category: "data", Factor: 1e6 / 8, Aliases: []string{"megabit", "megabits"}},
+	{Name: "Gbit", Category: "data", Factor: 1e9 / 8, Aliases: []string{"gigabit", "gigabits"}},
+
+	// energy, joule
+	{Name: "J", Category: "energy", Factor: 1, Aliases: []string{"joule", "joules"}},
+	{Name: "kJ", Category: "energy", Factor: 1e3, Aliases: []string{"kilojoule", "kilojoules"}},
+	{Name: "MJ", Category: "energy", Factor: 1e6, Aliases: []string{"megajoule", "megajoules"}},
+	{Name: "cal", Category: "energy", Factor: 4.184, Aliases: []string{"calorie", "calories"}},
+	{Name: "kcal", Category: "energy", Factor: 4184, Aliases: []string{"kilocalorie", "kilocalories"}},
+	{Name: "Wh", Category: "energy", Factor: 3600, Aliases: []string{"watt hour", "watt hours"}},
+	{Name: "kWh", Category: "energy", Factor: 3.6e6, Aliases: []string{"kilowatt hour", "kilowatt hours"}},
+	{Name: "BTU", Category: "energy", Factor: 1055.05585262, Aliases: []string{"btus"}},
+
+	// pressure, pascal
+	{Name: "Pa", Category: "pressure", Factor: 1, Aliases: []string{"pascal", "pascals"}},
+	{Name: "kPa", Category: "pressure", Factor: 1e3, Aliases: []string{"kilopascal", "kilopascals"}},
+	{Name: "MPa", Category: "pressure", Factor: 1e6, Aliases: []string{"megapascal", "megapascals"}},
+	{Name: "bar", Category: "pressure", Factor: 1e5, Aliases: []string{"bars"}},
+	{Name: "mbar", Category: "pressure", Factor: 100, Aliases: []string{"millibar", "millibars", "hPa"}},
+	{Name: "atm", Category: "pressure", Factor: 101325, Aliases: []string{"atmosphere", "atmospheres"}},
+	{Name: "psi", Category: "pressure", Factor: 6894.757293168},
+	{Name: "mmHg", Category: "pressure", Factor: 133.322387415, Aliases: []string{"torr"}},
+
+	// angle, radian
+	{Name: "rad", Category: "angle", Factor: 1, Aliases: []string{"radian", "radians"}},
+	{Name: "deg", Category: "angle", Factor: 0.017453292519943295, Aliases: []string{"°", "degree", "degrees"}},
+	{Name: "grad", Category: "angle", Factor: 0.015707963267948967, Aliases: []string{"gon", "gradian", "gradians"}},
+	{Name: "turn", Category: "angle", Factor: 6.283185307179586, Aliases: []string{"turns", "revolution", "revolutions"}},
+}
+
+// unitsByName indexes the units by the name and the aliases,
+// the units are matched case sensitive first, then case insensitive,
+// unless the name is ambiguous without the case, like mJ and MJ.
+var (
+	unitsByName  = map[string]*Unit{}
+	unitsByLower = map[string]*Unit{}
+	ambiguous    = map[string]bool{}
+)
+
+func init() {
+	for _, u := range Units {
+		for _, name := range append([]string{u.Name}, u.Aliases...) {
+			if other, ok := unitsByName[name]; ok && other != u {
+				panic(fmt.Sprintf("duplicate unit name %q: %s and %s", name, other.Name, u.Name))
+			}
+			unitsByName[name] = u
+
+			lower := strings.ToLower(name)
+			if other, ok := unitsByLower[lower]; ok && other != u {
+				ambiguous[lower] = true
+			}
+			unitsByLower[lower] = u
+		}
+	}
+	for lower := range ambiguous {
+		delete(unitsByLower, lower)
+	}
+}
+
+// FindUnit returns the unit by the name or the alias.
+func FindUnit(name string) (*Unit, bool) {
+	name = strings.TrimSpace(name)
+	if u, ok := unitsByName[name]; ok {
+		return u, true
+	}
+	u, ok := unitsByLower[strings.ToLower(name)]
+	return u, ok
+}
+
+// Convert converts the value between the units of the same category.
+func Convert(value float64, from, to string) (float64, error) {
+	fu, ok := FindUnit(from)
+	if !ok {
+		return 0, errors.Newf("unknown unit %q, supported units: %s", from, supportedUnits())
+	}
+	tu, ok := FindUnit(to)
+	if !ok {
+		return 0, errors.Newf("unknown unit %q, supported units: %s", to, supportedUnits())
+	}
+	if fu.Category != tu.Category {
+		return 0, errors.Newf("cannot convert %s (%s) to %s (%s)", fu.Name, fu.Category, tu.Name, tu.Category)
+	}
+	return tu.fromBase(fu.toBase(value)), nil
+}
+
+// supportedUnits returns the names of the units by category, for the LLM to correct the input.
+func supportedUnits() string {
+	var categories []string
+	names := map[string][]string{}
+	for _, u := range Units {
+		if _, ok := names[u.Category]; !ok {
+			categories = append(categories, u.Category)
+		}
+		names[u.Category] = append(names[u.Category], u.Name)
+	}
+
+	var b strings.Builder
+	for i, c := range categories {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(c)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(names[c], ", "))
+	}
+	return b.String()
+}
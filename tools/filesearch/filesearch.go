@@ -0,0 +1,147 @@
+// Package filesearch provides the file_search tool,
+// that searches the documents uploaded to the OpenAI vector stores,
+// so the assistants can ground the answers in the documents with any LLM.
+// The files are uploaded and attached to the vector stores with openai.LLM,
+// see openai.LLM.UploadFile and openai.LLM.CreateVectorStore.
+package filesearch
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms/openai"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+)
+
+// ToolName is the name registered with the LLM.
+const ToolName = "file_search"
+
+// DefaultMaxResults is the default number of the results.
+const DefaultMaxResults = 5
+
+// Searcher searches the vector store, openai.LLM implements it.
+type Searcher interface {
+	SearchVectorStore(ctx context.Context, vectorStoreID, query string, maxResults int) ([]openai.VectorStoreSearchResult, error)
+}
+
+// Request is the JSON input expected by the tool.
+type Request struct {
+	Query      string `json:"query" yaml:"query" jsonschema:"required,title=Query,description=The search query in natural language."`
+	MaxResults int    `json:"max_results,omitempty" yaml:"max_results" jsonschema:"title=Max Results,description=The maximum number of results. Default is 5."`
+}
+
+// Result is the chunk of the document found by the search.
+type Result struct {
+	FileID   string  `json:"file_id" yaml:"file_id"`
+	Filename string  `json:"filename" yaml:"filename"`
+	Score    float64 `json:"score" yaml:"score"`
+	Text     string  `json:"text" yaml:"text"`
+}
+
+// Response is the tool output.
+type Response struct {
+	Results []Result `json:"results" yaml:"results"`
+}
+
+// GetContent gets the content of the message for the chat history
+func (r *Response) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+// Tool implements tools.ITool, it searches the files in the vector stores.
+type Tool struct {
+	searcher       Searcher
+	vectorStoreIDs []string
+	maxResults     int
+	funcParams     *jsonschema.Schema
+}
+
+var _ tools.Tool[Request, Response] = (*Tool)(nil)
+
+// New returns a new file_search tool, that searches the vector stores.
+func New(searcher Searcher, vectorStoreIDs ...string) *Tool {
+	sc, _ := schema.New(reflect.TypeOf(Request{}))
+	return &Tool{
+		searcher:       searcher,
+		vectorStoreIDs: vectorStoreIDs,
+		maxResults:     DefaultMaxResults,
+		funcParams:     sc.Parameters,
+	}
+}
+
+// WithMaxResults sets the default and the maximum number of the results.
+func (t *Tool) WithMaxResults(maxResults int) *Tool {
+	if maxResults > 0 {
+		t.maxResults = maxResults
+	}
+	return t
+}
+
+func (t *Tool) Name() string {
+	return ToolName
+}
+
+func (t *Tool) Description() string {
+	return "Search the uploaded documents. Returns the most relevant passages with the file names, use them to ground the answer and cite the file names."
+}
+
+func (t *Tool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	var req Request
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	res, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.GetContent(), nil
+}
+
+// Run searches the vector stores, and returns the most relevant results first.
+func (t *Tool) Run(ctx context.Context, req *Request) (*Response, error) {
+	if req.Query == "" {
+		return nil, errors.WithMessage(chatmodel.ErrFailedUnmarshalInput, "query is required")
+	}
+	if len(t.vectorStoreIDs) == 0 {
+		return nil, errors.New("no vector stores to search")
+	}
+	maxResults := t.maxResults
+	if req.MaxResults > 0 {
+		maxResults = min(req.MaxResults, t.maxResults)
+	}
+
+	res := &Response{}
+	for _, id := range t.vectorStoreIDs {
+		list, err := t.searcher.SearchVectorStore(ctx, id, req.Query, maxResults)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range list {
+			res.Results = append(res.Results, Result(r))
+		}
+	}
+	slices.SortStableFunc(res.Results, func(a, b Result) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if len(res.Results) > maxResults {
+		res.Results = res.Results[:maxResults]
+	}
+	return res, nil
+}
@@ -0,0 +1,65 @@
+package filesearch_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms/openai"
+	"github.com/effective-security/gogentic/tools/filesearch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type searcher struct {
+	results map[string][]openai.VectorStoreSearchResult
+	max     int
+}
+
+func (s *searcher) SearchVectorStore(_ context.Context, vectorStoreID, query string, maxResults int) ([]openai.VectorStoreSearchResult, error) {
+	if query == "fail" {
+		return nil, errors.New("rate limited")
+	}
+	s.max = maxResults
+	return s.results[vectorStoreID], nil
+}
+
+func TestTool(t *testing.T) {
+	t.Parallel()
+
+	s := &searcher{results: map[string][]openai.VectorStoreSearchResult{
+		"vs_1": {
+			{FileID: "file_1", Filename: "handbook.pdf", Score: 0.7, Text: "Vacation is 20 days."},
+			{FileID: "file_2", Filename: "benefits.pdf", Score: 0.4, Text: "Dental is covered."},
+		},
+		"vs_2": {
+			{FileID: "file_3", Filename: "policy.md", Score: 0.9, Text: "Vacation requests need approval."},
+		},
+	}}
+	tool := filesearch.New(s, "vs_1", "vs_2").WithMaxResults(2)
+	assert.Equal(t, filesearch.ToolName, tool.Name())
+	assert.NotEmpty(t, tool.Description())
+	require.NotNil(t, tool.Parameters())
+
+	ctx := context.Background()
+	res, err := tool.Run(ctx, &filesearch.Request{Query: "vacation", MaxResults: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.max)
+	require.Len(t, res.Results, 2)
+	assert.Equal(t, "policy.md", res.Results[0].Filename)
+	assert.Equal(t, "handbook.pdf", res.Results[1].Filename)
+
+	out, err := tool.Call(ctx, `{"query":"vacation","max_results":1}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"results":[{"file_id":"file_3","filename":"policy.md","score":0.9,"text":"Vacation requests need approval."}]}`, out)
+
+	_, err = tool.Call(ctx, `not json`)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+	_, err = tool.Call(ctx, `{}`)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+	_, err = tool.Call(ctx, `{"query":"fail"}`)
+	assert.EqualError(t, err, "rate limited")
+	_, err = filesearch.New(s).Call(ctx, `{"query":"vacation"}`)
+	assert.EqualError(t, err, "no vector stores to search")
+}
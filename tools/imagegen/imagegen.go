@@ -0,0 +1,145 @@
+// Package imagegen provides the generate_image tool,
+// that generates the images with llms.ImageModel.
+// The images are stored in the blob store, and referenced by ID in the chat history,
+// the caller can return them to the user as llms.BinaryContent parts.
+package imagegen
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+)
+
+// ToolName is the name registered with the LLM.
+const ToolName = "generate_image"
+
+// MaxImages is the maximum number of images per call.
+const MaxImages = 4
+
+// Request is the JSON input expected by the tool.
+type Request struct {
+	Prompt         string `json:"prompt" yaml:"prompt" jsonschema:"required,title=Prompt,description=The detailed description of the image to generate."`
+	NegativePrompt string `json:"negative_prompt,omitempty" yaml:"negative_prompt" jsonschema:"title=Negative Prompt,description=What should not be in the image."`
+	Count          int    `json:"count,omitempty" yaml:"count" jsonschema:"title=Count,description=The number of images to generate. Default is 1."`
+}
+
+// Image is the reference to the generated image.
+type Image struct {
+	ID          string `json:"id,omitempty" yaml:"id"`
+	ContentType string `json:"content_type" yaml:"content_type"`
+	Size        int    `json:"size" yaml:"size"`
+}
+
+// Response is the tool output.
+type Response struct {
+	Images []Image `json:"images" yaml:"images"`
+	// Parts are the generated images, not included in the chat history.
+	Parts []llms.BinaryContent `json:"-" yaml:"-"`
+}
+
+// GetContent gets the content of the message for the chat history
+func (r *Response) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+// ContentParts returns the generated images as the message parts.
+func (r *Response) ContentParts() []llms.ContentPart {
+	parts := make([]llms.ContentPart, 0, len(r.Parts))
+	for _, p := range r.Parts {
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// Tool implements tools.ITool, it generates the images with the image model.
+type Tool struct {
+	model      llms.ImageModel
+	blobs      store.BlobStore
+	options    []llms.ImageOption
+	funcParams *jsonschema.Schema
+}
+
+var _ tools.Tool[Request, Response] = (*Tool)(nil)
+
+// New returns a new generate_image tool, the images are stored in the blob store,
+// if it is not nil. The options are applied to each GenerateImages call.
+func New(model llms.ImageModel, blobs store.BlobStore, options ...llms.ImageOption) *Tool {
+	sc, _ := schema.New(reflect.TypeOf(Request{}))
+	return &Tool{
+		model:      model,
+		blobs:      blobs,
+		options:    options,
+		funcParams: sc.Parameters,
+	}
+}
+
+func (t *Tool) Name() string {
+	return ToolName
+}
+
+func (t *Tool) Description() string {
+	return "Generate images from the text prompt. Returns the IDs of the generated images, which are shown to the user."
+}
+
+func (t *Tool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	var req Request
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	res, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.GetContent(), nil
+}
+
+// Run generates the images, and stores them in the blob store.
+func (t *Tool) Run(ctx context.Context, req *Request) (*Response, error) {
+	if req.Prompt == "" {
+		return nil, errors.WithMessage(chatmodel.ErrFailedUnmarshalInput, "prompt is required")
+	}
+
+	options := append([]llms.ImageOption{}, t.options...)
+	if req.NegativePrompt != "" {
+		options = append(options, llms.WithNegativePrompt(req.NegativePrompt))
+	}
+	if req.Count > 0 {
+		options = append(options, llms.WithImageCount(min(req.Count, MaxImages)))
+	}
+
+	parts, err := t.model.GenerateImages(ctx, req.Prompt, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Response{
+		Parts: parts,
+	}
+	for _, p := range parts {
+		img := Image{
+			ContentType: p.MIMEType,
+			Size:        len(p.Data),
+		}
+		if t.blobs != nil {
+			img.ID, err = t.blobs.Put(ctx, p.MIMEType, p.Data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		res.Images = append(res.Images, img)
+	}
+	return res, nil
+}
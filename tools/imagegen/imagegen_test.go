@@ -0,0 +1,67 @@
+package imagegen_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llms"
+	"github.com/effective-security/gogentic/store"
+	"github.com/effective-security/gogentic/tools/imagegen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type imageModel struct {
+	opts *llms.ImageOptions
+}
+
+func (m *imageModel) GenerateImages(_ context.Context, prompt string, options ...llms.ImageOption) ([]llms.BinaryContent, error) {
+	if prompt == "fail" {
+		return nil, errors.New("content policy violation")
+	}
+	m.opts = llms.NewImageOptions(options...)
+	var parts []llms.BinaryContent
+	for range m.opts.Count {
+		parts = append(parts, llms.BinaryContent{MIMEType: "image/png", Data: []byte("png")})
+	}
+	return parts, nil
+}
+
+func TestTool(t *testing.T) {
+	t.Parallel()
+
+	blobs := store.NewMemoryBlobStore()
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+
+	model := &imageModel{}
+	tool := imagegen.New(model, blobs, llms.WithImageSize(512, 512))
+	assert.Equal(t, imagegen.ToolName, tool.Name())
+	assert.NotEmpty(t, tool.Description())
+	require.NotNil(t, tool.Parameters())
+
+	res, err := tool.Run(ctx, &imagegen.Request{Prompt: "a gopher", NegativePrompt: "blurry", Count: 10})
+	require.NoError(t, err)
+	assert.Equal(t, imagegen.MaxImages, model.opts.Count)
+	assert.Equal(t, 512, model.opts.Width)
+	assert.Equal(t, "blurry", model.opts.NegativePrompt)
+	require.Len(t, res.Images, imagegen.MaxImages)
+	require.Len(t, res.ContentParts(), imagegen.MaxImages)
+
+	blob, err := blobs.Get(ctx, res.Images[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", blob.ContentType)
+	assert.Equal(t, []byte("png"), blob.Content)
+
+	out, err := imagegen.New(model, nil).Call(ctx, `{"prompt":"a gopher"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"images":[{"content_type":"image/png","size":3}]}`, out)
+
+	_, err = tool.Call(ctx, `not json`)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+	_, err = tool.Call(ctx, `{}`)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+	_, err = tool.Call(ctx, `{"prompt":"fail"}`)
+	assert.EqualError(t, err, "content policy violation")
+}
@@ -0,0 +1,121 @@
+package issues
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+)
+
+// TokenFunc returns the access token for the API calls,
+// for example from the OAuth token source:
+//
+//	func(ctx context.Context) (string, error) {
+//		t, err := ts.Token()
+//		if err != nil {
+//			return "", err
+//		}
+//		return t.AccessToken, nil
+//	}
+type TokenFunc func(ctx context.Context) (string, error)
+
+// StaticToken returns the TokenFunc for the static token, like the personal access token.
+func StaticToken(token string) TokenFunc {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// client calls the JSON REST API of the tracker.
+type client struct {
+	name       string
+	baseURL    string
+	httpClient *http.Client
+	headers    map[string]string
+	// authorize sets the authorization header of the request
+	authorize func(ctx context.Context, req *http.Request) error
+}
+
+// do sends the request, and decodes the response to res, if not nil.
+// The errors are marked with the tool error category by the status code.
+func (c *client) do(ctx context.Context, method, path string, query url.Values, req, res any) error {
+	var body io.Reader
+	if req != nil {
+		js, err := json.Marshal(req)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal request")
+		}
+		body = bytes.NewReader(js)
+	}
+
+	u := strings.TrimRight(c.baseURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if req != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+	if c.authorize != nil {
+		if err = c.authorize(ctx, httpReq); err != nil {
+			return errors.Mark(errors.WithMessage(err, "failed to get access token"), chatmodel.ErrToolPermission)
+		}
+	}
+
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return errors.Mark(errors.Wrapf(err, "failed to call %s", c.name), chatmodel.ErrToolTransient)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err = errors.Newf("%s %s failed with status %d: %s", c.name, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError,
+			// GitHub returns 403 when the rate limit is exceeded
+			resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+			return errors.Mark(err, chatmodel.ErrToolTransient)
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return errors.Mark(err, chatmodel.ErrToolPermission)
+		default:
+			return errors.Mark(err, chatmodel.ErrToolInvalidInput)
+		}
+	}
+
+	if res == nil {
+		return nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return errors.Wrapf(err, "failed to decode %s response", c.name)
+	}
+	return nil
+}
+
+func bearer(token TokenFunc) func(ctx context.Context, req *http.Request) error {
+	return func(ctx context.Context, req *http.Request) error {
+		t, err := token(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+		return nil
+	}
+}
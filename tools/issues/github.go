@@ -0,0 +1,207 @@
+package issues
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultGitHubURL is the base URL of the GitHub API.
+const DefaultGitHubURL = "https://api.github.com"
+
+// GitHubConfig is the configuration of the GitHub repository.
+type GitHubConfig struct {
+	// BaseURL is the API URL, DefaultGitHubURL if not set,
+	// or https://HOST/api/v3 for GitHub Enterprise Server.
+	BaseURL string `json:"base_url,omitempty" yaml:"base_url"`
+	Owner   string `json:"owner" yaml:"owner"`
+	Repo    string `json:"repo" yaml:"repo"`
+	// Token is the personal access token or the GitHub App installation token.
+	Token string `json:"token,omitempty" yaml:"token"`
+	// TokenFunc returns the OAuth access token, it takes precedence over Token.
+	TokenFunc TokenFunc `json:"-" yaml:"-"`
+	// ReadOnly disables the create tool.
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only"`
+	// HTTPClient is the HTTP client of the API calls, http.DefaultClient if not set.
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+}
+
+// GitHub is the Tracker of the issues in the GitHub repository.
+// The idempotency key is stored as the hidden comment in the issue body,
+// and found with the search API, which is eventually consistent.
+type GitHub struct {
+	cfg    GitHubConfig
+	client client
+}
+
+var _ Tracker = (*GitHub)(nil)
+
+// NewGitHub returns the Tracker of the GitHub repository.
+func NewGitHub(cfg GitHubConfig) (*GitHub, error) {
+	if cfg.Owner == "" || cfg.Repo == "" {
+		return nil, errors.New("owner and repo are required")
+	}
+	token := cfg.TokenFunc
+	if token == nil {
+		if cfg.Token == "" {
+			return nil, errors.New("token is required")
+		}
+		token = StaticToken(cfg.Token)
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultGitHubURL
+	}
+	return &GitHub{
+		cfg: cfg,
+		client: client{
+			name:       "GitHub",
+			baseURL:    cfg.BaseURL,
+			httpClient: cfg.HTTPClient,
+			headers: map[string]string{
+				"Accept":               "application/vnd.github+json",
+				"X-GitHub-Api-Version": "2022-11-28",
+			},
+			authorize: bearer(token),
+		},
+	}, nil
+}
+
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+func (g *GitHub) Target() string {
+	return "GitHub repository " + g.repo()
+}
+
+func (g *GitHub) ReadOnly() bool {
+	return g.cfg.ReadOnly
+}
+
+func (g *GitHub) repo() string {
+	return g.cfg.Owner + "/" + g.cfg.Repo
+}
+
+type githubIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+	Body    string `json:"body"`
+	Labels  []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Assignees []struct {
+		Login string `json:"login"`
+	} `json:"assignees"`
+}
+
+func (g *GitHub) toIssue(gi *githubIssue) *Issue {
+	issue := &Issue{
+		Key:   g.repo() + "#" + strconv.Itoa(gi.Number),
+		Title: gi.Title,
+		State: gi.State,
+		URL:   gi.HTMLURL,
+		Body:  stripMarker(gi.Body),
+	}
+	for _, l := range gi.Labels {
+		issue.Labels = append(issue.Labels, l.Name)
+	}
+	for _, a := range gi.Assignees {
+		issue.Assignees = append(issue.Assignees, a.Login)
+	}
+	return issue
+}
+
+// Create creates the issue, the idempotency key is appended to the body as the hidden comment.
+func (g *GitHub) Create(ctx context.Context, req *CreateRequest) (*Issue, error) {
+	if g.cfg.ReadOnly {
+		return nil, errors.WithStack(ErrReadOnly)
+	}
+	body := req.Body
+	if req.IdempotencyKey != "" {
+		body += marker(req.IdempotencyKey)
+	}
+	payload := map[string]any{
+		"title": req.Title,
+		"body":  body,
+	}
+	if len(req.Labels) > 0 {
+		payload["labels"] = req.Labels
+	}
+	if len(req.Assignees) > 0 {
+		payload["assignees"] = req.Assignees
+	}
+
+	var res githubIssue
+	if err := g.client.do(ctx, http.MethodPost, "/repos/"+g.repo()+"/issues", nil, payload, &res); err != nil {
+		return nil, err
+	}
+	return g.toIssue(&res), nil
+}
+
+// FindByIdempotencyKey searches the issue with the key comment in the body.
+func (g *GitHub) FindByIdempotencyKey(ctx context.Context, key string) (*Issue, error) {
+	list, err := g.search(ctx, fmt.Sprintf("repo:%s is:issue in:body %q", g.repo(), markerText(key)), 1)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// Search searches the issues with the GitHub search syntax.
+func (g *GitHub) Search(ctx context.Context, req *SearchRequest) ([]Issue, error) {
+	q := []string{"repo:" + g.repo(), "is:issue"}
+	if req.State == StateOpen || req.State == StateClosed {
+		q = append(q, "is:"+req.State)
+	}
+	for _, l := range req.Labels {
+		q = append(q, fmt.Sprintf("label:%q", l))
+	}
+	if req.Query != "" {
+		q = append(q, req.Query)
+	}
+	return g.search(ctx, strings.Join(q, " "), req.Limit)
+}
+
+func (g *GitHub) search(ctx context.Context, q string, limit int) ([]Issue, error) {
+	query := url.Values{}
+	query.Set("q", q)
+	query.Set("per_page", strconv.Itoa(limit))
+
+	var res struct {
+		Items []githubIssue `json:"items"`
+	}
+	if err := g.client.do(ctx, http.MethodGet, "/search/issues", query, nil, &res); err != nil {
+		return nil, err
+	}
+	list := make([]Issue, 0, len(res.Items))
+	for i := range res.Items {
+		list = append(list, *g.toIssue(&res.Items[i]))
+	}
+	return list, nil
+}
+
+const markerPrefix = "idempotency-key: "
+
+func markerText(key string) string {
+	return markerPrefix + key
+}
+
+func marker(key string) string {
+	return "\n\n<!-- " + markerText(key) + " -->"
+}
+
+// stripMarker removes the idempotency key comment from the body.
+func stripMarker(body string) string {
+	i := strings.LastIndex(body, "\n\n<!-- "+markerPrefix)
+	if i < 0 || !strings.HasSuffix(body, " -->") {
+		return body
+	}
+	return body[:i]
+}
@@ -0,0 +1,328 @@
+// Package issues provides the tools to create and search the issues
+// in GitHub repositories and Jira projects.
+//
+// The create tool is idempotent by the key provided in the request:
+// the issue created with the same key is returned instead of creating a duplicate.
+// The trackers in the read-only mode provide only the search tool.
+package issues
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mcp"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+)
+
+// ErrReadOnly is returned when the issue is created with the tracker in the read-only mode.
+var ErrReadOnly = errors.New("tracker is read-only")
+
+const (
+	// DefaultSearchLimit is the default number of the issues returned by the search.
+	DefaultSearchLimit = 10
+	// MaxSearchLimit is the maximum number of the issues returned by the search.
+	MaxSearchLimit = 50
+	// MaxBodyPreview is the maximum length of the issue body returned by the search.
+	MaxBodyPreview = 500
+)
+
+// States of the issues in the search request.
+const (
+	StateOpen   = "open"
+	StateClosed = "closed"
+	StateAll    = "all"
+)
+
+var idempotencyKeyRegex = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// Issue is the issue or the ticket in the tracker.
+type Issue struct {
+	// Key is the issue reference, like owner/repo#123 or PROJ-123.
+	Key       string   `json:"key" yaml:"key"`
+	Title     string   `json:"title" yaml:"title"`
+	State     string   `json:"state,omitempty" yaml:"state"`
+	URL       string   `json:"url,omitempty" yaml:"url"`
+	Labels    []string `json:"labels,omitempty" yaml:"labels"`
+	Assignees []string `json:"assignees,omitempty" yaml:"assignees"`
+	Body      string   `json:"body,omitempty" yaml:"body"`
+}
+
+// CreateRequest is the JSON input of the create tool.
+type CreateRequest struct {
+	Title          string   `json:"title" yaml:"title" jsonschema:"required,maxLength=255,title=Title,description=The title of the issue."`
+	Body           string   `json:"body,omitempty" yaml:"body" jsonschema:"title=Body,description=The description of the issue in markdown."`
+	Labels         []string `json:"labels,omitempty" yaml:"labels" jsonschema:"title=Labels,description=The labels of the issue."`
+	Assignees      []string `json:"assignees,omitempty" yaml:"assignees" jsonschema:"title=Assignees,description=The users to assign the issue to."`
+	IdempotencyKey string   `json:"idempotency_key,omitempty" yaml:"idempotency_key" jsonschema:"title=Idempotency Key,pattern=^[A-Za-z0-9._:-]{1\\,64}$,description=The unique key of the request. The issue is created once for the same key\\, the repeated request returns the existing issue."`
+}
+
+// CreateResponse is the output of the create tool.
+type CreateResponse struct {
+	Issue Issue `json:"issue" yaml:"issue"`
+	// Existing is true, if the issue was created before with the same idempotency key.
+	Existing bool `json:"existing,omitempty" yaml:"existing"`
+}
+
+// GetContent gets the content of the message for the chat history
+func (r *CreateResponse) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+// SearchRequest is the JSON input of the search tool.
+type SearchRequest struct {
+	Query  string   `json:"query,omitempty" yaml:"query" jsonschema:"title=Query,description=The text to search in the title and the description."`
+	State  string   `json:"state,omitempty" yaml:"state" jsonschema:"enum=open,enum=closed,enum=all,title=State,description=The state of the issues. Default is open."`
+	Labels []string `json:"labels,omitempty" yaml:"labels" jsonschema:"title=Labels,description=The labels the issues must have."`
+	Limit  int      `json:"limit,omitempty" yaml:"limit" jsonschema:"minimum=1,maximum=50,title=Limit,description=The maximum number of issues to return. Default is 10."`
+}
+
+// SearchResponse is the output of the search tool.
+type SearchResponse struct {
+	Issues []Issue `json:"issues" yaml:"issues"`
+}
+
+// GetContent gets the content of the message for the chat history
+func (r *SearchResponse) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+// Tracker is the issue tracker backend.
+type Tracker interface {
+	// Name returns the name of the tracker, like github or jira, used as the prefix of the tool names.
+	Name() string
+	// Target returns the description of the repository or the project, used in the tool descriptions.
+	Target() string
+	// ReadOnly returns true, if the issues can not be created.
+	ReadOnly() bool
+	// Create creates the issue, the idempotency key is stored with the issue,
+	// to be found by FindByIdempotencyKey.
+	Create(ctx context.Context, req *CreateRequest) (*Issue, error)
+	// FindByIdempotencyKey returns the issue created with the key, or nil if not found.
+	FindByIdempotencyKey(ctx context.Context, key string) (*Issue, error)
+	// Search returns the issues matching the request, the request is normalized by the tool.
+	Search(ctx context.Context, req *SearchRequest) ([]Issue, error)
+}
+
+// Tools returns the search tool, and the create tool unless the tracker is read-only.
+func Tools(tracker Tracker) []tools.ITool {
+	list := []tools.ITool{NewSearchTool(tracker)}
+	if !tracker.ReadOnly() {
+		list = append(list, NewCreateTool(tracker))
+	}
+	return list
+}
+
+// CreateTool implements tools.ITool, it creates the issue in the tracker.
+type CreateTool struct {
+	tracker    Tracker
+	funcParams *jsonschema.Schema
+
+	// lock serializes the creates, so the same idempotency key is not created twice
+	lock sync.Mutex
+	// created caches the issues by the tenant and the idempotency key
+	created map[string]*Issue
+}
+
+var _ tools.Tool[CreateRequest, CreateResponse] = (*CreateTool)(nil)
+var _ tools.MCPTool[CreateRequest] = (*CreateTool)(nil)
+
+// NewCreateTool returns a new tool that creates the issues in the tracker.
+func NewCreateTool(tracker Tracker) *CreateTool {
+	sc, _ := schema.New(reflect.TypeOf(CreateRequest{}))
+	return &CreateTool{
+		tracker:    tracker,
+		funcParams: sc.Parameters,
+		created:    map[string]*Issue{},
+	}
+}
+
+func (t *CreateTool) Name() string {
+	return t.tracker.Name() + "_create_issue"
+}
+
+func (t *CreateTool) Description() string {
+	return "Create an issue in " + t.tracker.Target() + ". Provide idempotency_key to avoid duplicates when the request is repeated."
+}
+
+func (t *CreateTool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+// RegisterMCP registers the tool with the output schema of CreateResponse
+func (t *CreateTool) RegisterMCP(registrator tools.McpServerRegistrator) error {
+	return registrator.RegisterTool(t.Name(), t.Description(), t.Run)
+}
+
+func (t *CreateTool) RunMCP(ctx context.Context, req *CreateRequest) (*mcp.ToolResponse, error) {
+	res, err := t.Run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewStructuredToolResponse(res)
+}
+
+func (t *CreateTool) Call(ctx context.Context, input string) (string, error) {
+	var req CreateRequest
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	res, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.GetContent(), nil
+}
+
+// Run creates the issue, or returns the issue created before with the same idempotency key.
+func (t *CreateTool) Run(ctx context.Context, req *CreateRequest) (*CreateResponse, error) {
+	if t.tracker.ReadOnly() {
+		return nil, errors.Mark(errors.WithStack(ErrReadOnly), chatmodel.ErrToolPermission)
+	}
+	if req.Title == "" {
+		return nil, errors.Mark(errors.New("title is required"), chatmodel.ErrToolInvalidInput)
+	}
+	if key := chatmodel.GetIdempotencyKey(ctx); req.IdempotencyKey == "" && idempotencyKeyRegex.MatchString(key) {
+		// the key of the tool call, so the repeated run does not create the issue again
+		withKey := *req
+		withKey.IdempotencyKey = key
+		req = &withKey
+	}
+	if req.IdempotencyKey == "" {
+		issue, err := t.tracker.Create(ctx, req)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to create %s issue", t.tracker.Name())
+		}
+		return &CreateResponse{Issue: *issue}, nil
+	}
+	if !idempotencyKeyRegex.MatchString(req.IdempotencyKey) {
+		return nil, errors.Mark(errors.Newf("invalid idempotency key %q", req.IdempotencyKey), chatmodel.ErrToolInvalidInput)
+	}
+
+	tenantID, _, _ := chatmodel.GetTenantAndChatID(ctx)
+	cacheKey := tenantID + "/" + req.IdempotencyKey
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if issue, ok := t.created[cacheKey]; ok {
+		return &CreateResponse{Issue: *issue, Existing: true}, nil
+	}
+	issue, err := t.tracker.FindByIdempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to find %s issue", t.tracker.Name())
+	}
+	if issue != nil {
+		t.created[cacheKey] = issue
+		return &CreateResponse{Issue: *issue, Existing: true}, nil
+	}
+
+	issue, err = t.tracker.Create(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create %s issue", t.tracker.Name())
+	}
+	t.created[cacheKey] = issue
+	return &CreateResponse{Issue: *issue}, nil
+}
+
+// SearchTool implements tools.ITool, it searches the issues in the tracker.
+type SearchTool struct {
+	tracker    Tracker
+	funcParams *jsonschema.Schema
+}
+
+var _ tools.Tool[SearchRequest, SearchResponse] = (*SearchTool)(nil)
+var _ tools.MCPTool[SearchRequest] = (*SearchTool)(nil)
+
+// NewSearchTool returns a new tool that searches the issues in the tracker.
+func NewSearchTool(tracker Tracker) *SearchTool {
+	sc, _ := schema.New(reflect.TypeOf(SearchRequest{}))
+	return &SearchTool{
+		tracker:    tracker,
+		funcParams: sc.Parameters,
+	}
+}
+
+func (t *SearchTool) Name() string {
+	return t.tracker.Name() + "_search_issues"
+}
+
+func (t *SearchTool) Description() string {
+	return "Search the issues in " + t.tracker.Target() + " by text, state and labels."
+}
+
+func (t *SearchTool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+// RegisterMCP registers the tool with the output schema of SearchResponse
+func (t *SearchTool) RegisterMCP(registrator tools.McpServerRegistrator) error {
+	return registrator.RegisterTool(t.Name(), t.Description(), t.Run)
+}
+
+func (t *SearchTool) RunMCP(ctx context.Context, req *SearchRequest) (*mcp.ToolResponse, error) {
+	res, err := t.Run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewStructuredToolResponse(res)
+}
+
+func (t *SearchTool) Call(ctx context.Context, input string) (string, error) {
+	var req SearchRequest
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	res, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.GetContent(), nil
+}
+
+// Run returns the issues matching the request, the bodies are truncated to MaxBodyPreview.
+func (t *SearchTool) Run(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	normalized := *req
+	switch normalized.State {
+	case "":
+		normalized.State = StateOpen
+	case StateOpen, StateClosed, StateAll:
+	default:
+		return nil, errors.Mark(errors.Newf("invalid state %q", req.State), chatmodel.ErrToolInvalidInput)
+	}
+	if normalized.Limit <= 0 {
+		normalized.Limit = DefaultSearchLimit
+	}
+	normalized.Limit = min(normalized.Limit, MaxSearchLimit)
+
+	list, err := t.tracker.Search(ctx, &normalized)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to search %s issues", t.tracker.Name())
+	}
+	for i := range list {
+		list[i].Body = truncate(list[i].Body, MaxBodyPreview)
+	}
+	if list == nil {
+		list = []Issue{}
+	}
+	return &SearchResponse{Issues: list}, nil
+}
+
+// truncate returns the text up to size bytes at the rune boundary, with the ellipsis.
+func truncate(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return text[:size] + "..."
+}
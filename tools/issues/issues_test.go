@@ -0,0 +1,267 @@
+package issues_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/tools/issues"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the requests, and replies with the handler by "METHOD path".
+type recorder struct {
+	lock     sync.Mutex
+	requests []string
+	bodies   []map[string]any
+	handlers map[string]func(w http.ResponseWriter, r *http.Request)
+}
+
+func newServer(t *testing.T, handlers map[string]func(w http.ResponseWriter, r *http.Request)) (*httptest.Server, *recorder) {
+	rec := &recorder{handlers: handlers}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+		var body map[string]any
+		if r.Body != nil && r.ContentLength > 0 {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		}
+		rec.lock.Lock()
+		rec.requests = append(rec.requests, route+"?"+r.URL.RawQuery)
+		rec.bodies = append(rec.bodies, body)
+		h := rec.handlers[route]
+		rec.lock.Unlock()
+		if h == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, rec
+}
+
+func (r *recorder) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = nil
+	r.bodies = nil
+}
+
+func TestGitHub(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	var created []map[string]any
+	server, rec := newServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /repos/acme/app/issues": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer ghp_test", r.Header.Get("Authorization"))
+			assert.Equal(t, "application/vnd.github+json", r.Header.Get("Accept"))
+			_, _ = w.Write([]byte(`{"number":42,"title":"Crash","state":"open","html_url":"https://github.com/acme/app/issues/42","body":"Stack\n\n<!-- idempotency-key: alert-1 -->","labels":[{"name":"bug"}],"assignees":[{"login":"octocat"}]}`))
+		},
+		"GET /search/issues": func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			n := len(created)
+			lock.Unlock()
+			if strings.Contains(r.URL.Query().Get("q"), "idempotency-key") && n == 0 {
+				_, _ = w.Write([]byte(`{"items":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"items":[{"number":42,"title":"Crash","state":"open","html_url":"https://github.com/acme/app/issues/42","body":"` + strings.Repeat("x", 600) + `\n\n<!-- idempotency-key: alert-1 -->"}]}`))
+		},
+	})
+
+	_, err := issues.NewGitHub(issues.GitHubConfig{Owner: "acme"})
+	assert.EqualError(t, err, "owner and repo are required")
+	_, err = issues.NewGitHub(issues.GitHubConfig{Owner: "acme", Repo: "app"})
+	assert.EqualError(t, err, "token is required")
+
+	gh, err := issues.NewGitHub(issues.GitHubConfig{
+		BaseURL: server.URL,
+		Owner:   "acme",
+		Repo:    "app",
+		Token:   "ghp_test",
+	})
+	require.NoError(t, err)
+
+	list := issues.Tools(gh)
+	require.Len(t, list, 2)
+	assert.Equal(t, "github_search_issues", list[0].Name())
+	assert.Equal(t, "github_create_issue", list[1].Name())
+	assert.Equal(t, "Create an issue in GitHub repository acme/app. Provide idempotency_key to avoid duplicates when the request is repeated.", list[1].Description())
+
+	create := issues.NewCreateTool(gh)
+	ctx := chatmodel.WithChatContext(context.Background(), chatmodel.NewChatContext("tenant1", "chat1", nil))
+	input := `{"title":"Crash","body":"Stack","labels":["bug"],"assignees":["octocat"],"idempotency_key":"alert-1"}`
+	out, err := create.Call(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, `{"issue":{"key":"acme/app#42","title":"Crash","state":"open","url":"https://github.com/acme/app/issues/42","labels":["bug"],"assignees":["octocat"],"body":"Stack"}}`, out)
+	assert.Equal(t, []string{
+		"GET /search/issues?per_page=1&q=repo%3Aacme%2Fapp+is%3Aissue+in%3Abody+%22idempotency-key%3A+alert-1%22",
+		"POST /repos/acme/app/issues?",
+	}, rec.requests)
+	assert.Equal(t, map[string]any{
+		"title":     "Crash",
+		"body":      "Stack\n\n<!-- idempotency-key: alert-1 -->",
+		"labels":    []any{"bug"},
+		"assignees": []any{"octocat"},
+	}, rec.bodies[1])
+	lock.Lock()
+	created = append(created, rec.bodies[1])
+	lock.Unlock()
+
+	// the repeated request is served from the cache
+	rec.reset()
+	out, err = create.Call(ctx, input)
+	require.NoError(t, err)
+	assert.Contains(t, out, `"existing":true`)
+	assert.Empty(t, rec.requests)
+
+	// the new tool finds the issue by the key
+	res, err := issues.NewCreateTool(gh).Run(ctx, &issues.CreateRequest{Title: "Crash", IdempotencyKey: "alert-1"})
+	require.NoError(t, err)
+	assert.True(t, res.Existing)
+	assert.Equal(t, "acme/app#42", res.Issue.Key)
+	assert.Len(t, rec.requests, 1)
+
+	// the key of the tool call is taken from the context
+	rec.reset()
+	res, err = issues.NewCreateTool(gh).Run(chatmodel.WithIdempotencyKey(ctx, "run-1"), &issues.CreateRequest{Title: "Crash"})
+	require.NoError(t, err)
+	assert.True(t, res.Existing)
+	assert.Equal(t, []string{
+		"GET /search/issues?per_page=1&q=repo%3Aacme%2Fapp+is%3Aissue+in%3Abody+%22idempotency-key%3A+run-1%22",
+	}, rec.requests)
+
+	_, err = create.Run(ctx, &issues.CreateRequest{Title: "Crash", IdempotencyKey: "bad key"})
+	assert.EqualError(t, err, `invalid idempotency key "bad key"`)
+	assert.Equal(t, chatmodel.ToolErrorInvalidInput, chatmodel.GetToolErrorCategory(err))
+	_, err = create.Run(ctx, &issues.CreateRequest{})
+	assert.EqualError(t, err, "title is required")
+
+	rec.reset()
+	search := issues.NewSearchTool(gh)
+	sr, err := search.Run(ctx, &issues.SearchRequest{Query: "crash", State: "closed", Labels: []string{"bug"}, Limit: 100})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"GET /search/issues?per_page=50&q=repo%3Aacme%2Fapp+is%3Aissue+is%3Aclosed+label%3A%22bug%22+crash",
+	}, rec.requests)
+	require.Len(t, sr.Issues, 1)
+	assert.Equal(t, strings.Repeat("x", 500)+"...", sr.Issues[0].Body)
+
+	_, err = search.Run(ctx, &issues.SearchRequest{State: "merged"})
+	assert.EqualError(t, err, `invalid state "merged"`)
+}
+
+func TestGitHub_Errors(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"GET /search/issues": func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.Query().Get("q"), "limited") {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+			}
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"forbidden"}`))
+		},
+	})
+	gh, err := issues.NewGitHub(issues.GitHubConfig{
+		BaseURL:  server.URL,
+		Owner:    "acme",
+		Repo:     "app",
+		ReadOnly: true,
+		TokenFunc: func(context.Context) (string, error) {
+			return "oauth", nil
+		},
+	})
+	require.NoError(t, err)
+
+	list := issues.Tools(gh)
+	require.Len(t, list, 1)
+	assert.Equal(t, "github_search_issues", list[0].Name())
+
+	ctx := context.Background()
+	_, err = issues.NewCreateTool(gh).Run(ctx, &issues.CreateRequest{Title: "Crash"})
+	assert.EqualError(t, err, "tracker is read-only")
+	assert.Equal(t, chatmodel.ToolErrorPermission, chatmodel.GetToolErrorCategory(err))
+
+	search := issues.NewSearchTool(gh)
+	_, err = search.Run(ctx, &issues.SearchRequest{Query: "crash"})
+	assert.EqualError(t, err, `failed to search github issues: GitHub /search/issues failed with status 403: {"message":"forbidden"}`)
+	assert.Equal(t, chatmodel.ToolErrorPermission, chatmodel.GetToolErrorCategory(err))
+
+	_, err = search.Run(ctx, &issues.SearchRequest{Query: "limited"})
+	assert.Equal(t, chatmodel.ToolErrorTransient, chatmodel.GetToolErrorCategory(err))
+}
+
+func TestJira(t *testing.T) {
+	t.Parallel()
+
+	server, rec := newServer(t, map[string]func(w http.ResponseWriter, r *http.Request){
+		"POST /rest/api/2/issue": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Basic Ym90QGV4YW1wbGUuY29tOnNlY3JldA==", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"10001","key":"OPS-7"}`))
+		},
+		"GET /rest/api/2/search/jql": func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.Query().Get("jql"), "idempotency-") {
+				_, _ = w.Write([]byte(`{"issues":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"issues":[{"key":"OPS-7","fields":{"summary":"Disk full","description":"On host-1","labels":["infra","idempotency-disk-1"],"status":{"name":"In Progress"},"assignee":{"displayName":"Jane"}}}]}`))
+		},
+	})
+
+	_, err := issues.NewJira(issues.JiraConfig{BaseURL: server.URL})
+	assert.EqualError(t, err, "base URL and project key are required")
+	_, err = issues.NewJira(issues.JiraConfig{BaseURL: server.URL, ProjectKey: "OPS"})
+	assert.EqualError(t, err, "credentials are required")
+
+	jira, err := issues.NewJira(issues.JiraConfig{
+		BaseURL:    server.URL,
+		Cloud:      true,
+		ProjectKey: "OPS",
+		Email:      "bot@example.com",
+		APIToken:   "secret",
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	res, err := issues.NewCreateTool(jira).Run(ctx, &issues.CreateRequest{
+		Title:          "Disk full",
+		Body:           "On host-1",
+		Labels:         []string{"infra"},
+		Assignees:      []string{"5b10ac8d82e05b22cc7d4ef5"},
+		IdempotencyKey: "disk-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &issues.CreateResponse{Issue: issues.Issue{
+		Key:       "OPS-7",
+		Title:     "Disk full",
+		URL:       server.URL + "/browse/OPS-7",
+		Labels:    []string{"infra"},
+		Assignees: []string{"5b10ac8d82e05b22cc7d4ef5"},
+	}}, res)
+	require.Len(t, rec.requests, 2)
+	assert.Equal(t, "GET /rest/api/2/search/jql?fields=summary%2Cdescription%2Clabels%2Cstatus%2Cassignee&jql=project+%3D+%22OPS%22+AND+labels+%3D+%22idempotency-disk-1%22&maxResults=1", rec.requests[0])
+	assert.Equal(t, map[string]any{"fields": map[string]any{
+		"project":     map[string]any{"key": "OPS"},
+		"issuetype":   map[string]any{"name": "Task"},
+		"summary":     "Disk full",
+		"description": "On host-1",
+		"labels":      []any{"infra", "idempotency-disk-1"},
+		"assignee":    map[string]any{"accountId": "5b10ac8d82e05b22cc7d4ef5"},
+	}}, rec.bodies[1])
+
+	rec.reset()
+	out, err := issues.NewSearchTool(jira).Call(ctx, `{"query":"disk \"full\""}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"issues":[{"key":"OPS-7","title":"Disk full","state":"In Progress","url":"`+server.URL+`/browse/OPS-7","labels":["infra"],"assignees":["Jane"],"body":"On host-1"}]}`, out)
+	require.Len(t, rec.requests, 1)
+	assert.Contains(t, rec.requests[0], "jql=project+%3D+%22OPS%22+AND+statusCategory+%21%3D+Done+AND+text+~+%22disk+%5C%22full%5C%22%22+ORDER+BY+updated+DESC&maxResults=10")
+}
@@ -0,0 +1,242 @@
+package issues
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultJiraIssueType is the default type of the created issues.
+const DefaultJiraIssueType = "Task"
+
+// JiraConfig is the configuration of the Jira project.
+type JiraConfig struct {
+	// BaseURL is the URL of the Jira site, like https://example.atlassian.net,
+	// or https://api.atlassian.com/ex/jira/CLOUD_ID for the OAuth 2.0 apps.
+	BaseURL string `json:"base_url" yaml:"base_url"`
+	// Cloud is true for Jira Cloud, which uses the account IDs for the assignees
+	// and the enhanced search API, otherwise Jira Data Center is assumed.
+	Cloud bool `json:"cloud,omitempty" yaml:"cloud"`
+	// ProjectKey is the key of the project, like PROJ.
+	ProjectKey string `json:"project_key" yaml:"project_key"`
+	// IssueType is the type of the created issues, DefaultJiraIssueType if not set.
+	IssueType string `json:"issue_type,omitempty" yaml:"issue_type"`
+	// Email and APIToken are used for the basic authentication with Jira Cloud.
+	Email    string `json:"email,omitempty" yaml:"email"`
+	APIToken string `json:"api_token,omitempty" yaml:"api_token"`
+	// Token is the personal access token of Jira Data Center.
+	Token string `json:"token,omitempty" yaml:"token"`
+	// TokenFunc returns the OAuth access token, it takes precedence over the other credentials.
+	TokenFunc TokenFunc `json:"-" yaml:"-"`
+	// ReadOnly disables the create tool.
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only"`
+	// HTTPClient is the HTTP client of the API calls, http.DefaultClient if not set.
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+}
+
+// Jira is the Tracker of the issues in the Jira project.
+// The idempotency key is stored as the label of the issue.
+type Jira struct {
+	cfg    JiraConfig
+	client client
+}
+
+var _ Tracker = (*Jira)(nil)
+
+// NewJira returns the Tracker of the Jira project.
+func NewJira(cfg JiraConfig) (*Jira, error) {
+	if cfg.BaseURL == "" || cfg.ProjectKey == "" {
+		return nil, errors.New("base URL and project key are required")
+	}
+	if cfg.IssueType == "" {
+		cfg.IssueType = DefaultJiraIssueType
+	}
+
+	var authorize func(ctx context.Context, req *http.Request) error
+	switch {
+	case cfg.TokenFunc != nil:
+		authorize = bearer(cfg.TokenFunc)
+	case cfg.Email != "" && cfg.APIToken != "":
+		basic := "Basic " + base64.StdEncoding.EncodeToString([]byte(cfg.Email+":"+cfg.APIToken))
+		authorize = func(_ context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", basic)
+			return nil
+		}
+	case cfg.Token != "":
+		authorize = bearer(StaticToken(cfg.Token))
+	default:
+		return nil, errors.New("credentials are required")
+	}
+
+	return &Jira{
+		cfg: cfg,
+		client: client{
+			name:       "Jira",
+			baseURL:    cfg.BaseURL,
+			httpClient: cfg.HTTPClient,
+			authorize:  authorize,
+		},
+	}, nil
+}
+
+func (j *Jira) Name() string {
+	return "jira"
+}
+
+func (j *Jira) Target() string {
+	return "Jira project " + j.cfg.ProjectKey
+}
+
+func (j *Jira) ReadOnly() bool {
+	return j.cfg.ReadOnly
+}
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		Labels      []string `json:"labels"`
+		Status      struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		Assignee *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"assignee"`
+	} `json:"fields"`
+}
+
+func (j *Jira) toIssue(ji *jiraIssue) *Issue {
+	issue := &Issue{
+		Key:   ji.Key,
+		Title: ji.Fields.Summary,
+		State: ji.Fields.Status.Name,
+		URL:   j.browseURL(ji.Key),
+		Body:  ji.Fields.Description,
+	}
+	for _, l := range ji.Fields.Labels {
+		if !strings.HasPrefix(l, labelPrefix) {
+			issue.Labels = append(issue.Labels, l)
+		}
+	}
+	if ji.Fields.Assignee != nil {
+		issue.Assignees = []string{ji.Fields.Assignee.DisplayName}
+	}
+	return issue
+}
+
+func (j *Jira) browseURL(key string) string {
+	return strings.TrimRight(j.cfg.BaseURL, "/") + "/browse/" + key
+}
+
+// Create creates the issue, the idempotency key is added as the label.
+// Jira supports one assignee, the first one is used:
+// the account ID for Jira Cloud, or the user name for Jira Data Center.
+func (j *Jira) Create(ctx context.Context, req *CreateRequest) (*Issue, error) {
+	if j.cfg.ReadOnly {
+		return nil, errors.WithStack(ErrReadOnly)
+	}
+	labels := append([]string(nil), req.Labels...)
+	if req.IdempotencyKey != "" {
+		labels = append(labels, idempotencyLabel(req.IdempotencyKey))
+	}
+	fields := map[string]any{
+		"project":     map[string]string{"key": j.cfg.ProjectKey},
+		"issuetype":   map[string]string{"name": j.cfg.IssueType},
+		"summary":     req.Title,
+		"description": req.Body,
+	}
+	if len(labels) > 0 {
+		fields["labels"] = labels
+	}
+	if len(req.Assignees) > 0 {
+		if j.cfg.Cloud {
+			fields["assignee"] = map[string]string{"accountId": req.Assignees[0]}
+		} else {
+			fields["assignee"] = map[string]string{"name": req.Assignees[0]}
+		}
+	}
+
+	var res struct {
+		Key string `json:"key"`
+	}
+	if err := j.client.do(ctx, http.MethodPost, "/rest/api/2/issue", nil, map[string]any{"fields": fields}, &res); err != nil {
+		return nil, err
+	}
+	return &Issue{
+		Key:       res.Key,
+		Title:     req.Title,
+		URL:       j.browseURL(res.Key),
+		Labels:    req.Labels,
+		Assignees: req.Assignees,
+	}, nil
+}
+
+// FindByIdempotencyKey searches the issue with the key label.
+func (j *Jira) FindByIdempotencyKey(ctx context.Context, key string) (*Issue, error) {
+	jql := "project = " + quoteJQL(j.cfg.ProjectKey) + " AND labels = " + quoteJQL(idempotencyLabel(key))
+	list, err := j.search(ctx, jql, 1)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// Search searches the issues with JQL, the open issues are the issues not in the Done category.
+func (j *Jira) Search(ctx context.Context, req *SearchRequest) ([]Issue, error) {
+	clauses := []string{"project = " + quoteJQL(j.cfg.ProjectKey)}
+	switch req.State {
+	case StateOpen:
+		clauses = append(clauses, "statusCategory != Done")
+	case StateClosed:
+		clauses = append(clauses, "statusCategory = Done")
+	}
+	for _, l := range req.Labels {
+		clauses = append(clauses, "labels = "+quoteJQL(l))
+	}
+	if req.Query != "" {
+		clauses = append(clauses, "text ~ "+quoteJQL(req.Query))
+	}
+	return j.search(ctx, strings.Join(clauses, " AND ")+" ORDER BY updated DESC", req.Limit)
+}
+
+func (j *Jira) search(ctx context.Context, jql string, limit int) ([]Issue, error) {
+	query := url.Values{}
+	query.Set("jql", jql)
+	query.Set("maxResults", strconv.Itoa(limit))
+	query.Set("fields", "summary,description,labels,status,assignee")
+
+	// Jira Cloud replaced the search API with the enhanced search
+	path := "/rest/api/2/search"
+	if j.cfg.Cloud {
+		path = "/rest/api/2/search/jql"
+	}
+	var res struct {
+		Issues []jiraIssue `json:"issues"`
+	}
+	if err := j.client.do(ctx, http.MethodGet, path, query, nil, &res); err != nil {
+		return nil, err
+	}
+	list := make([]Issue, 0, len(res.Issues))
+	for i := range res.Issues {
+		list = append(list, *j.toIssue(&res.Issues[i]))
+	}
+	return list, nil
+}
+
+const labelPrefix = "idempotency-"
+
+// idempotencyLabel returns the label of the key, Jira labels can not contain spaces.
+func idempotencyLabel(key string) string {
+	return labelPrefix + key
+}
+
+// quoteJQL returns the JQL string literal.
+func quoteJQL(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
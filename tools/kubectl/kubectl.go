@@ -0,0 +1,463 @@
+// Package kubectl provides the read-only Kubernetes diagnostics tool,
+// that runs kubectl get, describe and logs in the allowed namespaces,
+// and returns the summarized output, so the SRE assistants can investigate the clusters safely.
+//
+// The Secrets are never returned, even if the resource is allowed in the Config.
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mcp"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+)
+
+// ToolName is the name registered with the LLM.
+const ToolName = "kubectl"
+
+// Verb is the kubectl command.
+type Verb string
+
+const (
+	VerbGet      Verb = "get"
+	VerbDescribe Verb = "describe"
+	VerbLogs     Verb = "logs"
+)
+
+const (
+	// DefaultTimeout is the default timeout of the kubectl command.
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxOutput is the default maximum size of the output in bytes.
+	DefaultMaxOutput = 32 * 1024
+	// DefaultTailLines is the default number of the log lines.
+	DefaultTailLines = 100
+	// MaxTailLines is the maximum number of the log lines.
+	MaxTailLines = 1000
+	// MaxListItems is the maximum number of the items in the summarized list.
+	MaxListItems = 100
+)
+
+// DefaultResources are the resources allowed by default.
+var DefaultResources = []string{
+	"pods",
+	"deployments",
+	"replicasets",
+	"statefulsets",
+	"daemonsets",
+	"jobs",
+	"cronjobs",
+	"services",
+	"endpoints",
+	"ingresses",
+	"events",
+	"persistentvolumeclaims",
+	"horizontalpodautoscalers",
+	"nodes",
+	"namespaces",
+}
+
+// deniedResources are never allowed, as they contain the credentials.
+var deniedResources = []string{"secrets"}
+
+// clusterScoped are the resources without the namespace.
+var clusterScoped = []string{"nodes", "namespaces", "persistentvolumes", "storageclasses"}
+
+// resourceAliases maps the short names and the singular names to the resources.
+var resourceAliases = map[string]string{
+	"po":                      "pods",
+	"pod":                     "pods",
+	"deploy":                  "deployments",
+	"deployment":              "deployments",
+	"rs":                      "replicasets",
+	"replicaset":              "replicasets",
+	"sts":                     "statefulsets",
+	"statefulset":             "statefulsets",
+	"ds":                      "daemonsets",
+	"daemonset":               "daemonsets",
+	"job":                     "jobs",
+	"cj":                      "cronjobs",
+	"cronjob":                 "cronjobs",
+	"svc":                     "services",
+	"service":                 "services",
+	"ep":                      "endpoints",
+	"ing":                     "ingresses",
+	"ingress":                 "ingresses",
+	"ev":                      "events",
+	"event":                   "events",
+	"pvc":                     "persistentvolumeclaims",
+	"persistentvolumeclaim":   "persistentvolumeclaims",
+	"hpa":                     "horizontalpodautoscalers",
+	"horizontalpodautoscaler": "horizontalpodautoscalers",
+	"no":                      "nodes",
+	"node":                    "nodes",
+	"ns":                      "namespaces",
+	"namespace":               "namespaces",
+	"cm":                      "configmaps",
+	"configmap":               "configmaps",
+	"pv":                      "persistentvolumes",
+	"persistentvolume":        "persistentvolumes",
+	"sc":                      "storageclasses",
+	"storageclass":            "storageclasses",
+	"secret":                  "secrets",
+}
+
+var (
+	nameRegex     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+	selectorRegex = regexp.MustCompile(`^[A-Za-z0-9._/=!,() -]{1,256}$`)
+	sinceRegex    = regexp.MustCompile(`^[0-9]{1,6}[smh]$`)
+)
+
+// Runner runs kubectl with the arguments, and returns the standard output.
+// The error should include the standard error of the command.
+type Runner func(ctx context.Context, args []string) ([]byte, error)
+
+// Config is the configuration of the tool.
+type Config struct {
+	// Namespaces are the allowed namespaces, required for the namespaced resources.
+	// The first namespace is the default one.
+	Namespaces []string
+	// Verbs are the allowed verbs, all verbs if empty.
+	Verbs []Verb
+	// Resources are the allowed resources, DefaultResources if empty.
+	Resources []string
+	// Kubeconfig is the path to the kubeconfig file, the kubectl default if empty.
+	Kubeconfig string
+	// Context is the kubeconfig context, the current context if empty.
+	Context string
+	// KubectlPath is the path to kubectl, "kubectl" if empty.
+	KubectlPath string
+	// Timeout is the timeout of the command, DefaultTimeout if zero.
+	Timeout time.Duration
+	// MaxOutput is the maximum size of the output in bytes, DefaultMaxOutput if zero.
+	MaxOutput int
+	// Runner runs kubectl, the kubectl process is started if nil.
+	Runner Runner
+}
+
+// Request is the JSON input expected by the tool.
+type Request struct {
+	Verb          Verb   `json:"verb" yaml:"verb" jsonschema:"required,enum=get,enum=describe,enum=logs,title=Verb,description=The kubectl command."`
+	Resource      string `json:"resource,omitempty" yaml:"resource" jsonschema:"title=Resource,description=The resource type for get and describe\\, like pods\\, deployments or events. Logs are for pods."`
+	Name          string `json:"name,omitempty" yaml:"name" jsonschema:"title=Name,description=The name of the resource. Required for logs. Without the name get returns the summary of all resources."`
+	Namespace     string `json:"namespace,omitempty" yaml:"namespace" jsonschema:"title=Namespace,description=The namespace of the resource."`
+	LabelSelector string `json:"label_selector,omitempty" yaml:"label_selector" jsonschema:"title=Label Selector,description=The label selector to filter the resources\\, like app=web."`
+	Output        string `json:"output,omitempty" yaml:"output" jsonschema:"enum=yaml,enum=json,title=Output,description=The output format of get. Default is yaml."`
+	Container     string `json:"container,omitempty" yaml:"container" jsonschema:"title=Container,description=The container of the pod for logs."`
+	TailLines     int    `json:"tail_lines,omitempty" yaml:"tail_lines" jsonschema:"minimum=1,maximum=1000,title=Tail Lines,description=The number of the last log lines. Default is 100."`
+	Since         string `json:"since,omitempty" yaml:"since" jsonschema:"title=Since,description=Return the logs newer than the duration\\, like 10m or 1h."`
+	Previous      bool   `json:"previous,omitempty" yaml:"previous" jsonschema:"title=Previous,description=Return the logs of the previous container instance\\, after a restart."`
+}
+
+// Response is the tool output.
+type Response struct {
+	// Command is the kubectl command, for the audit.
+	Command string `json:"command" yaml:"command"`
+	Output  string `json:"output" yaml:"output"`
+	// Truncated is true, if the output exceeded the limit.
+	Truncated bool `json:"truncated,omitempty" yaml:"truncated"`
+}
+
+// GetContent gets the content of the message for the chat history
+func (r *Response) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+// Tool implements tools.ITool, it runs the read-only kubectl commands.
+type Tool struct {
+	cfg        Config
+	funcParams *jsonschema.Schema
+}
+
+var _ tools.Tool[Request, Response] = (*Tool)(nil)
+var _ tools.MCPTool[Request] = (*Tool)(nil)
+
+// New returns a new kubectl tool.
+func New(cfg Config) (*Tool, error) {
+	if len(cfg.Verbs) == 0 {
+		cfg.Verbs = []Verb{VerbGet, VerbDescribe, VerbLogs}
+	}
+	for _, v := range cfg.Verbs {
+		if v != VerbGet && v != VerbDescribe && v != VerbLogs {
+			return nil, errors.Newf("unsupported verb %q", v)
+		}
+	}
+	if len(cfg.Resources) == 0 {
+		cfg.Resources = DefaultResources
+	}
+	resources := make([]string, 0, len(cfg.Resources))
+	for _, r := range cfg.Resources {
+		r = canonicalResource(r)
+		if slices.Contains(deniedResources, r) {
+			return nil, errors.Newf("resource %q is not allowed", r)
+		}
+		resources = append(resources, r)
+	}
+	cfg.Resources = resources
+	for _, ns := range cfg.Namespaces {
+		if !nameRegex.MatchString(ns) {
+			return nil, errors.Newf("invalid namespace %q", ns)
+		}
+	}
+	if cfg.KubectlPath == "" {
+		cfg.KubectlPath = "kubectl"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxOutput <= 0 {
+		cfg.MaxOutput = DefaultMaxOutput
+	}
+	if cfg.Runner == nil {
+		cfg.Runner = execRunner(cfg.KubectlPath)
+	}
+
+	sc, err := schema.New(reflect.TypeOf(Request{}))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create schema")
+	}
+	return &Tool{
+		cfg:        cfg,
+		funcParams: sc.Parameters,
+	}, nil
+}
+
+func (t *Tool) Name() string {
+	return ToolName
+}
+
+func (t *Tool) Description() string {
+	verbs := make([]string, len(t.cfg.Verbs))
+	for i, v := range t.cfg.Verbs {
+		verbs[i] = string(v)
+	}
+	desc := "Read-only Kubernetes diagnostics with kubectl " + strings.Join(verbs, ", ") +
+		". Resources: " + strings.Join(t.cfg.Resources, ", ") + "."
+	if len(t.cfg.Namespaces) > 0 {
+		desc += " Namespaces: " + strings.Join(t.cfg.Namespaces, ", ") + "."
+	}
+	return desc
+}
+
+func (t *Tool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+// RegisterMCP registers the tool with the output schema of Response
+func (t *Tool) RegisterMCP(registrator tools.McpServerRegistrator) error {
+	return registrator.RegisterTool(ToolName, t.Description(), t.Run)
+}
+
+func (t *Tool) RunMCP(ctx context.Context, req *Request) (*mcp.ToolResponse, error) {
+	res, err := t.Run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewStructuredToolResponse(res)
+}
+
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	var req Request
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	res, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.GetContent(), nil
+}
+
+// Run validates the request against the Config, runs kubectl, and summarizes the output.
+// The requests not allowed by the Config are rejected with chatmodel.ErrToolPermission.
+func (t *Tool) Run(ctx context.Context, req *Request) (*Response, error) {
+	args, err := t.args(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.cfg.Timeout)
+	defer cancel()
+	out, err := t.cfg.Runner(ctx, t.globalArgs(args))
+	if err != nil {
+		return nil, markError(err)
+	}
+
+	res := &Response{Command: "kubectl " + strings.Join(args, " ")}
+	text := string(out)
+	if req.Verb == VerbGet {
+		text, err = summarize(out, req.Name != "", req.Output)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.Verb == VerbLogs {
+		// the latest lines are the most relevant
+		res.Output, res.Truncated = truncateHead(text, t.cfg.MaxOutput)
+	} else {
+		res.Output, res.Truncated = truncateTail(text, t.cfg.MaxOutput)
+	}
+	return res, nil
+}
+
+// args returns the arguments of the command, without the global flags.
+func (t *Tool) args(req *Request) ([]string, error) {
+	if !slices.Contains(t.cfg.Verbs, req.Verb) {
+		return nil, permissionError("verb %q is not allowed", req.Verb)
+	}
+
+	resource := canonicalResource(req.Resource)
+	if req.Verb == VerbLogs {
+		if resource != "" && resource != "pods" {
+			return nil, invalidInput("logs are supported for pods")
+		}
+		resource = "pods"
+		if req.Name == "" {
+			return nil, invalidInput("pod name is required for logs")
+		}
+	}
+	if resource == "" {
+		return nil, invalidInput("resource is required")
+	}
+	if slices.Contains(deniedResources, resource) || !slices.Contains(t.cfg.Resources, resource) {
+		return nil, permissionError("resource %q is not allowed", req.Resource)
+	}
+	if req.Name != "" && !nameRegex.MatchString(req.Name) {
+		return nil, invalidInput("invalid name %q", req.Name)
+	}
+
+	var args []string
+	switch req.Verb {
+	case VerbLogs:
+		args = []string{"logs", req.Name}
+	default:
+		args = []string{string(req.Verb), resource}
+		if req.Name != "" {
+			args = append(args, req.Name)
+		}
+	}
+
+	if !slices.Contains(clusterScoped, resource) {
+		ns := req.Namespace
+		if ns == "" && len(t.cfg.Namespaces) > 0 {
+			ns = t.cfg.Namespaces[0]
+		}
+		if ns == "" || !slices.Contains(t.cfg.Namespaces, ns) {
+			return nil, permissionError("namespace %q is not allowed", ns)
+		}
+		args = append(args, "--namespace", ns)
+	}
+
+	if req.LabelSelector != "" {
+		if req.Verb == VerbLogs {
+			return nil, invalidInput("label selector is not supported for logs")
+		}
+		if !selectorRegex.MatchString(req.LabelSelector) || strings.HasPrefix(req.LabelSelector, "-") {
+			return nil, invalidInput("invalid label selector %q", req.LabelSelector)
+		}
+		args = append(args, "--selector", req.LabelSelector)
+	}
+
+	switch req.Verb {
+	case VerbGet:
+		if req.Output != "" && req.Output != "yaml" && req.Output != "json" {
+			return nil, invalidInput("invalid output %q", req.Output)
+		}
+		args = append(args, "--output", "json")
+	case VerbLogs:
+		if req.Container != "" {
+			if !nameRegex.MatchString(req.Container) {
+				return nil, invalidInput("invalid container %q", req.Container)
+			}
+			args = append(args, "--container", req.Container)
+		}
+		tail := req.TailLines
+		if tail <= 0 {
+			tail = DefaultTailLines
+		}
+		args = append(args, "--tail", strconv.Itoa(min(tail, MaxTailLines)))
+		if req.Since != "" {
+			if !sinceRegex.MatchString(req.Since) {
+				return nil, invalidInput("invalid since %q, use the duration like 10m or 1h", req.Since)
+			}
+			args = append(args, "--since", req.Since)
+		}
+		if req.Previous {
+			args = append(args, "--previous")
+		}
+	}
+	return args, nil
+}
+
+func (t *Tool) globalArgs(args []string) []string {
+	var global []string
+	if t.cfg.Kubeconfig != "" {
+		global = append(global, "--kubeconfig", t.cfg.Kubeconfig)
+	}
+	if t.cfg.Context != "" {
+		global = append(global, "--context", t.cfg.Context)
+	}
+	global = append(global, "--request-timeout", t.cfg.Timeout.String())
+	return append(global, args...)
+}
+
+func canonicalResource(resource string) string {
+	resource = strings.ToLower(strings.TrimSpace(resource))
+	if r, ok := resourceAliases[resource]; ok {
+		return r
+	}
+	return resource
+}
+
+func execRunner(path string) Runner {
+	return func(ctx context.Context, args []string) ([]byte, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, errors.Newf("kubectl failed: %s", msg)
+			}
+			if ctx.Err() != nil {
+				return nil, errors.Wrap(ctx.Err(), "kubectl failed")
+			}
+			return nil, errors.Wrap(err, "kubectl failed")
+		}
+		return stdout.Bytes(), nil
+	}
+}
+
+// markError marks the kubectl error with the tool error category.
+func markError(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "NotFound") || strings.Contains(msg, "not found"):
+		return errors.Mark(err, chatmodel.ErrToolInvalidInput)
+	case strings.Contains(msg, "Forbidden") || strings.Contains(msg, "forbidden") || strings.Contains(msg, "Unauthorized"):
+		return errors.Mark(err, chatmodel.ErrToolPermission)
+	default:
+		return errors.Mark(err, chatmodel.ErrToolTransient)
+	}
+}
+
+func invalidInput(format string, args ...any) error {
+	return errors.Mark(errors.Newf(format, args...), chatmodel.ErrToolInvalidInput)
+}
+
+func permissionError(format string, args ...any) error {
+	return errors.Mark(errors.Newf(format, args...), chatmodel.ErrToolPermission)
+}
@@ -0,0 +1,321 @@
+package kubectl_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/tools/kubectl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const podJSON = `{
+	"kind": "Pod",
+	"metadata": {
+		"name": "web-1",
+		"namespace": "app",
+		"annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}", "team": "sre"},
+		"managedFields": [{"manager": "kubectl"}]
+	},
+	"spec": {"nodeName": "node-1"},
+	"status": {"phase": "Running"}
+}`
+
+const podListJSON = `{
+	"kind": "List",
+	"items": [
+		{
+			"kind": "Pod",
+			"metadata": {"name": "web-1", "namespace": "app"},
+			"spec": {"nodeName": "node-1"},
+			"status": {
+				"phase": "Running",
+				"containerStatuses": [
+					{"ready": true, "restartCount": 2},
+					{"ready": false, "restartCount": 1, "state": {"waiting": {"reason": "CrashLoopBackOff"}}}
+				]
+			}
+		},
+		{
+			"kind": "Event",
+			"metadata": {"name": "web-1.123", "namespace": "app"},
+			"type": "Warning",
+			"reason": "BackOff",
+			"involvedObject": {"kind": "Pod", "name": "web-1"},
+			"message": "Back-off restarting failed container",
+			"count": 5
+		}
+	]
+}`
+
+type fakeRunner struct {
+	args []string
+	out  string
+	err  error
+}
+
+func (f *fakeRunner) run(_ context.Context, args []string) ([]byte, error) {
+	f.args = args
+	return []byte(f.out), f.err
+}
+
+func newTool(t *testing.T, runner *fakeRunner, cfg kubectl.Config) *kubectl.Tool {
+	t.Helper()
+	if cfg.Namespaces == nil {
+		cfg.Namespaces = []string{"app", "monitoring"}
+	}
+	cfg.Runner = runner.run
+	tool, err := kubectl.New(cfg)
+	require.NoError(t, err)
+	return tool
+}
+
+func Test_New(t *testing.T) {
+	t.Parallel()
+
+	_, err := kubectl.New(kubectl.Config{Resources: []string{"pods", "secret"}})
+	assert.EqualError(t, err, `resource "secrets" is not allowed`)
+
+	_, err = kubectl.New(kubectl.Config{Verbs: []kubectl.Verb{"delete"}})
+	assert.EqualError(t, err, `unsupported verb "delete"`)
+
+	_, err = kubectl.New(kubectl.Config{Namespaces: []string{"--all"}})
+	assert.EqualError(t, err, `invalid namespace "--all"`)
+
+	tool := newTool(t, &fakeRunner{}, kubectl.Config{Verbs: []kubectl.Verb{kubectl.VerbGet}, Resources: []string{"po", "ev"}})
+	assert.Equal(t, kubectl.ToolName, tool.Name())
+	assert.Equal(t, "Read-only Kubernetes diagnostics with kubectl get. Resources: pods, events. Namespaces: app, monitoring.", tool.Description())
+	assert.NotNil(t, tool.Parameters())
+}
+
+func Test_Args(t *testing.T) {
+	t.Parallel()
+
+	tcs := []struct {
+		name string
+		req  kubectl.Request
+		exp  string
+	}{
+		{
+			name: "get list",
+			req:  kubectl.Request{Verb: kubectl.VerbGet, Resource: "po", LabelSelector: "app=web"},
+			exp:  "get pods --namespace app --selector app=web --output json",
+		},
+		{
+			name: "get cluster scoped",
+			req:  kubectl.Request{Verb: kubectl.VerbGet, Resource: "nodes", Namespace: "kube-system"},
+			exp:  "get nodes --output json",
+		},
+		{
+			name: "describe",
+			req:  kubectl.Request{Verb: kubectl.VerbDescribe, Resource: "deploy", Name: "web", Namespace: "monitoring"},
+			exp:  "describe deployments web --namespace monitoring",
+		},
+		{
+			name: "logs",
+			req:  kubectl.Request{Verb: kubectl.VerbLogs, Name: "web-1", Container: "app", TailLines: 5000, Since: "10m", Previous: true},
+			exp:  "logs web-1 --namespace app --container app --tail 1000 --since 10m --previous",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			runner := &fakeRunner{out: `{"items": []}`}
+			tool := newTool(t, runner, kubectl.Config{Context: "prod"})
+			res, err := tool.Run(context.Background(), &tc.req)
+			require.NoError(t, err)
+			assert.Equal(t, "kubectl "+tc.exp, res.Command)
+			assert.Equal(t, "--context prod --request-timeout 30s "+tc.exp, strings.Join(runner.args, " "))
+		})
+	}
+}
+
+func Test_Denied(t *testing.T) {
+	t.Parallel()
+
+	tcs := []struct {
+		name     string
+		req      kubectl.Request
+		category chatmodel.ToolErrorCategory
+		exp      string
+	}{
+		{
+			name:     "verb",
+			req:      kubectl.Request{Verb: kubectl.VerbLogs, Name: "web-1"},
+			category: chatmodel.ToolErrorPermission,
+			exp:      `verb "logs" is not allowed`,
+		},
+		{
+			name:     "secrets",
+			req:      kubectl.Request{Verb: kubectl.VerbGet, Resource: "secret"},
+			category: chatmodel.ToolErrorPermission,
+			exp:      `resource "secret" is not allowed`,
+		},
+		{
+			name:     "not configured resource",
+			req:      kubectl.Request{Verb: kubectl.VerbGet, Resource: "configmaps"},
+			category: chatmodel.ToolErrorPermission,
+			exp:      `resource "configmaps" is not allowed`,
+		},
+		{
+			name:     "namespace",
+			req:      kubectl.Request{Verb: kubectl.VerbGet, Resource: "pods", Namespace: "kube-system"},
+			category: chatmodel.ToolErrorPermission,
+			exp:      `namespace "kube-system" is not allowed`,
+		},
+		{
+			name:     "name injection",
+			req:      kubectl.Request{Verb: kubectl.VerbGet, Resource: "pods", Name: "--all-namespaces"},
+			category: chatmodel.ToolErrorInvalidInput,
+			exp:      `invalid name "--all-namespaces"`,
+		},
+		{
+			name:     "selector",
+			req:      kubectl.Request{Verb: kubectl.VerbGet, Resource: "pods", LabelSelector: "app=web;rm"},
+			category: chatmodel.ToolErrorInvalidInput,
+			exp:      `invalid label selector "app=web;rm"`,
+		},
+		{
+			name:     "output",
+			req:      kubectl.Request{Verb: kubectl.VerbGet, Resource: "pods", Output: "wide"},
+			category: chatmodel.ToolErrorInvalidInput,
+			exp:      `invalid output "wide"`,
+		},
+		{
+			name:     "missing resource",
+			req:      kubectl.Request{Verb: kubectl.VerbDescribe},
+			category: chatmodel.ToolErrorInvalidInput,
+			exp:      "resource is required",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			runner := &fakeRunner{}
+			tool := newTool(t, runner, kubectl.Config{Verbs: []kubectl.Verb{kubectl.VerbGet, kubectl.VerbDescribe}})
+			_, err := tool.Run(context.Background(), &tc.req)
+			require.Error(t, err)
+			assert.EqualError(t, err, tc.exp)
+			assert.Equal(t, tc.category, chatmodel.GetToolErrorCategory(err))
+			assert.Nil(t, runner.args)
+		})
+	}
+}
+
+func Test_Get(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	runner := &fakeRunner{out: podJSON}
+	tool := newTool(t, runner, kubectl.Config{})
+
+	res, err := tool.Run(ctx, &kubectl.Request{Verb: kubectl.VerbGet, Resource: "pods", Name: "web-1"})
+	require.NoError(t, err)
+	assert.Contains(t, res.Output, "name: web-1")
+	assert.Contains(t, res.Output, "team: sre")
+	assert.NotContains(t, res.Output, "managedFields")
+	assert.NotContains(t, res.Output, "last-applied-configuration")
+
+	res, err = tool.Run(ctx, &kubectl.Request{Verb: kubectl.VerbGet, Resource: "pods", Name: "web-1", Output: "json"})
+	require.NoError(t, err)
+	var obj map[string]any
+	require.NoError(t, json.Unmarshal([]byte(res.Output), &obj))
+	assert.Equal(t, "Running", obj["status"].(map[string]any)["phase"])
+
+	runner.out = podListJSON
+	res, err = tool.Run(ctx, &kubectl.Request{Verb: kubectl.VerbGet, Resource: "pods", Output: "json"})
+	require.NoError(t, err)
+	var list struct {
+		Count int              `json:"count"`
+		Items []map[string]any `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(res.Output), &list))
+	assert.Equal(t, 2, list.Count)
+	require.Len(t, list.Items, 2)
+	assert.Equal(t, map[string]any{
+		"name":      "web-1",
+		"namespace": "app",
+		"phase":     "Running",
+		"ready":     "1/2",
+		"restarts":  float64(3),
+		"reason":    "CrashLoopBackOff",
+		"node":      "node-1",
+	}, list.Items[0])
+	assert.Equal(t, map[string]any{
+		"namespace": "app",
+		"type":      "Warning",
+		"reason":    "BackOff",
+		"object":    "Pod/web-1",
+		"message":   "Back-off restarting failed container",
+		"count":     float64(5),
+	}, list.Items[1])
+
+	runner.out = "not json"
+	_, err = tool.Run(ctx, &kubectl.Request{Verb: kubectl.VerbGet, Resource: "pods"})
+	require.Error(t, err)
+	assert.Equal(t, chatmodel.ToolErrorFatal, chatmodel.GetToolErrorCategory(err))
+}
+
+func Test_Truncate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var sb strings.Builder
+	for i := range 100 {
+		sb.WriteString("line " + strings.Repeat("x", i%10) + "\n")
+	}
+	runner := &fakeRunner{out: sb.String()}
+	tool := newTool(t, runner, kubectl.Config{MaxOutput: 100})
+
+	res, err := tool.Run(ctx, &kubectl.Request{Verb: kubectl.VerbLogs, Name: "web-1"})
+	require.NoError(t, err)
+	assert.True(t, res.Truncated)
+	assert.LessOrEqual(t, len(res.Output), 100)
+	assert.True(t, strings.HasSuffix(res.Output, "line xxxxxxxxx\n"))
+	assert.True(t, strings.HasPrefix(res.Output, "line"))
+
+	res, err = tool.Run(ctx, &kubectl.Request{Verb: kubectl.VerbDescribe, Resource: "pods", Name: "web-1"})
+	require.NoError(t, err)
+	assert.True(t, res.Truncated)
+	assert.True(t, strings.HasPrefix(res.Output, "line \n"))
+	assert.True(t, strings.HasSuffix(res.Output, "\n"))
+}
+
+func Test_Errors(t *testing.T) {
+	t.Parallel()
+
+	tcs := []struct {
+		err      string
+		category chatmodel.ToolErrorCategory
+	}{
+		{`kubectl failed: Error from server (NotFound): pods "web-2" not found`, chatmodel.ToolErrorInvalidInput},
+		{`kubectl failed: Error from server (Forbidden): pods is forbidden`, chatmodel.ToolErrorPermission},
+		{`kubectl failed: Unable to connect to the server: dial tcp: i/o timeout`, chatmodel.ToolErrorTransient},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.err, func(t *testing.T) {
+			t.Parallel()
+			tool := newTool(t, &fakeRunner{err: errors.New(tc.err)}, kubectl.Config{})
+			_, err := tool.Run(context.Background(), &kubectl.Request{Verb: kubectl.VerbDescribe, Resource: "pods", Name: "web-2"})
+			require.Error(t, err)
+			assert.EqualError(t, err, tc.err)
+			assert.Equal(t, tc.category, chatmodel.GetToolErrorCategory(err))
+		})
+	}
+}
+
+func Test_Call(t *testing.T) {
+	t.Parallel()
+
+	tool := newTool(t, &fakeRunner{out: "Name: web-1\n"}, kubectl.Config{})
+	out, err := tool.Call(context.Background(), `{"verb":"describe","resource":"pod","name":"web-1"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"command":"kubectl describe pods web-1 --namespace app","output":"Name: web-1\n"}`, out)
+
+	_, err = tool.Call(context.Background(), `not json`)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+}
@@ -0,0 +1,223 @@
+package kubectl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"gopkg.in/yaml.v3"
+)
+
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// summarize returns the object without the noisy metadata,
+// or the summary of the list items in the output format.
+func summarize(out []byte, single bool, format string) (string, error) {
+	var obj map[string]any
+	if err := json.Unmarshal(out, &obj); err != nil {
+		return "", errors.Mark(errors.Wrap(err, "failed to parse kubectl output"), chatmodel.ErrToolFatal)
+	}
+
+	var v any
+	if single {
+		cleanObject(obj)
+		v = obj
+	} else {
+		items, _ := obj["items"].([]any)
+		list := make([]map[string]any, 0, min(len(items), MaxListItems))
+		for i, item := range items {
+			if i == MaxListItems {
+				break
+			}
+			if m, ok := item.(map[string]any); ok {
+				list = append(list, summarizeItem(m))
+			}
+		}
+		res := map[string]any{
+			"count": len(items),
+			"items": list,
+		}
+		if len(items) > MaxListItems {
+			res["omitted"] = len(items) - MaxListItems
+		}
+		v = res
+	}
+
+	if format == "json" {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return string(b), nil
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(b), nil
+}
+
+// cleanObject removes the fields, which are not useful for the diagnostics.
+func cleanObject(obj map[string]any) {
+	meta, _ := obj["metadata"].(map[string]any)
+	if meta == nil {
+		return
+	}
+	delete(meta, "managedFields")
+	if ann, ok := meta["annotations"].(map[string]any); ok {
+		delete(ann, lastAppliedAnnotation)
+		if len(ann) == 0 {
+			delete(meta, "annotations")
+		}
+	}
+}
+
+// summarizeItem returns the columns of the item, similar to kubectl get.
+func summarizeItem(item map[string]any) map[string]any {
+	kind := str(item, "kind")
+	res := map[string]any{
+		"name": str(item, "metadata", "name"),
+	}
+	if ns := str(item, "metadata", "namespace"); ns != "" {
+		res["namespace"] = ns
+	}
+	if created := str(item, "metadata", "creationTimestamp"); created != "" {
+		res["age"] = age(created)
+	}
+
+	switch kind {
+	case "Pod":
+		res["phase"] = str(item, "status", "phase")
+		statuses, _ := get(item, "status", "containerStatuses").([]any)
+		var ready, restarts int
+		for _, s := range statuses {
+			cs, _ := s.(map[string]any)
+			if b, _ := cs["ready"].(bool); b {
+				ready++
+			}
+			restarts += num(cs, "restartCount")
+			if reason := str(cs, "state", "waiting", "reason"); reason != "" {
+				res["reason"] = reason
+			}
+		}
+		res["ready"] = fmt.Sprintf("%d/%d", ready, len(statuses))
+		res["restarts"] = restarts
+		if node := str(item, "spec", "nodeName"); node != "" {
+			res["node"] = node
+		}
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		res["ready"] = fmt.Sprintf("%d/%d", num(item, "status", "readyReplicas"), num(item, "spec", "replicas"))
+		if kind == "Deployment" {
+			res["up_to_date"] = num(item, "status", "updatedReplicas")
+			res["available"] = num(item, "status", "availableReplicas")
+		}
+	case "DaemonSet":
+		res["ready"] = fmt.Sprintf("%d/%d", num(item, "status", "numberReady"), num(item, "status", "desiredNumberScheduled"))
+	case "Job":
+		res["succeeded"] = num(item, "status", "succeeded")
+		res["failed"] = num(item, "status", "failed")
+	case "CronJob":
+		res["schedule"] = str(item, "spec", "schedule")
+		if last := str(item, "status", "lastScheduleTime"); last != "" {
+			res["last_schedule"] = last
+		}
+	case "Service":
+		res["type"] = str(item, "spec", "type")
+		res["cluster_ip"] = str(item, "spec", "clusterIP")
+	case "Event":
+		delete(res, "name")
+		res["type"] = str(item, "type")
+		res["reason"] = str(item, "reason")
+		res["object"] = str(item, "involvedObject", "kind") + "/" + str(item, "involvedObject", "name")
+		res["message"] = str(item, "message")
+		if count := num(item, "count"); count > 1 {
+			res["count"] = count
+		}
+		if last := str(item, "lastTimestamp"); last != "" {
+			res["age"] = age(last)
+		}
+	case "Node":
+		conditions, _ := get(item, "status", "conditions").([]any)
+		for _, c := range conditions {
+			cond, _ := c.(map[string]any)
+			if str(cond, "type") == "Ready" {
+				res["ready"] = str(cond, "status")
+			}
+		}
+		res["version"] = str(item, "status", "nodeInfo", "kubeletVersion")
+	default:
+		if phase := str(item, "status", "phase"); phase != "" {
+			res["phase"] = phase
+		}
+	}
+	return res
+}
+
+func get(m map[string]any, path ...string) any {
+	var v any = m
+	for _, p := range path {
+		mm, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = mm[p]
+	}
+	return v
+}
+
+func str(m map[string]any, path ...string) string {
+	s, _ := get(m, path...).(string)
+	return s
+}
+
+func num(m map[string]any, path ...string) int {
+	f, _ := get(m, path...).(float64)
+	return int(f)
+}
+
+// age returns the duration since the timestamp, like kubectl get.
+func age(timestamp string) string {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return timestamp
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return strconv.Itoa(int(d.Seconds())) + "s"
+	case d < time.Hour:
+		return strconv.Itoa(int(d.Minutes())) + "m"
+	case d < 48*time.Hour:
+		return strconv.Itoa(int(d.Hours())) + "h"
+	default:
+		return strconv.Itoa(int(d.Hours()/24)) + "d"
+	}
+}
+
+// truncateTail keeps the beginning of the text.
+func truncateTail(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	s = s[:limit]
+	if i := strings.LastIndexByte(s, '\n'); i > 0 {
+		s = s[:i+1]
+	}
+	return s, true
+}
+
+// truncateHead keeps the end of the text.
+func truncateHead(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	s = s[len(s)-limit:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 && i < len(s)-1 {
+		s = s[i+1:]
+	}
+	return s, true
+}
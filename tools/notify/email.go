@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+)
+
+// EmailToolName is the name of the email tool registered with the LLM.
+const EmailToolName = "send_email"
+
+// DefaultSMTPTimeout is the default timeout of the SMTP session.
+const DefaultSMTPTimeout = 30 * time.Second
+
+// SMTPConfig is the configuration of the SMTP server.
+type SMTPConfig struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string `json:"addr" yaml:"addr"`
+	// From is the sender address, like "Agent <agent@example.com>".
+	From string `json:"from" yaml:"from"`
+	// Username and Password are used for the PLAIN authentication, if provided.
+	// The authentication requires TLS, unless the server is on localhost.
+	Username string `json:"username,omitempty" yaml:"username"`
+	Password string `json:"password,omitempty" yaml:"password"`
+	// Timeout is the timeout of the SMTP session, DefaultSMTPTimeout if not set.
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout"`
+}
+
+// EmailSender sends the messages with the SMTP server.
+// The connection is upgraded with STARTTLS, if the server supports it.
+type EmailSender struct {
+	cfg  SMTPConfig
+	host string
+	from *mail.Address
+}
+
+// NewEmailSender returns the Sender for the SMTP server.
+func NewEmailSender(cfg SMTPConfig) (*EmailSender, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid SMTP address %q", cfg.Addr)
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid sender address %q", cfg.From)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultSMTPTimeout
+	}
+	return &EmailSender{
+		cfg:  cfg,
+		host: host,
+		from: from,
+	}, nil
+}
+
+func (s *EmailSender) Name() string {
+	return "email"
+}
+
+// Send sends the message to all recipients in one email.
+func (s *EmailSender) Send(ctx context.Context, msg *Message) error {
+	to := make([]string, 0, len(msg.To))
+	for _, rcpt := range msg.To {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return errors.Mark(errors.Newf("invalid email address %q", rcpt), chatmodel.ErrToolInvalidInput)
+		}
+		to = append(to, addr.Address)
+	}
+
+	data, err := s.compose(to, msg)
+	if err != nil {
+		return err
+	}
+	if err = s.send(ctx, to, data); err != nil {
+		return markSMTPError(err)
+	}
+	return nil
+}
+
+// compose returns the plain text email with the quoted-printable body.
+func (s *EmailSender) compose(to []string, msg *Message) ([]byte, error) {
+	var b bytes.Buffer
+	headers := [][2]string{
+		{"From", s.from.String()},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, h := range headers {
+		b.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	b.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(msg.Body)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return b.Bytes(), nil
+}
+
+func (s *EmailSender) send(ctx context.Context, to []string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to SMTP server")
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		_ = conn.Close()
+		return errors.WithStack(err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return errors.Wrap(err, "failed to start TLS")
+		}
+	}
+	if s.cfg.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.host)); err != nil {
+			return errors.Wrap(err, "failed to authenticate")
+		}
+	}
+	if err = c.Mail(s.from.Address); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, rcpt := range to {
+		if err = c.Rcpt(rcpt); err != nil {
+			return errors.Wrapf(err, "recipient %s", rcpt)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err = w.Write(data); err != nil {
+		return errors.WithStack(err)
+	}
+	if err = w.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(c.Quit())
+}
+
+// markSMTPError marks the error with the tool error category by the SMTP reply code:
+// 535 is the authentication failure, 5xx are the permanent failures, like unknown mailbox,
+// the other errors are transient.
+func markSMTPError(err error) error {
+	var perr *textproto.Error
+	switch {
+	case errors.As(err, &perr) && perr.Code == 535:
+		return errors.Mark(err, chatmodel.ErrToolPermission)
+	case errors.As(err, &perr) && perr.Code >= 500:
+		return errors.Mark(err, chatmodel.ErrToolInvalidInput)
+	default:
+		return errors.Mark(err, chatmodel.ErrToolTransient)
+	}
+}
+
+// NewEmailTool returns the tool that sends the emails,
+// the recipients must be allowed with WithAllowedRecipients.
+func NewEmailTool(sender *EmailSender) *Tool {
+	return New(EmailToolName,
+		"Send a plain text email to the allowed recipients.",
+		sender,
+	)
+}
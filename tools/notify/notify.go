@@ -0,0 +1,335 @@
+// Package notify provides the tools to deliver the results of the workflow agents
+// to Slack and email.
+//
+// The outbound messages are restricted to the allow-listed recipients,
+// can be rendered from the templates defined by the application,
+// and can be reviewed by the approval hook before they are sent.
+// In the dry-run mode the messages are rendered and returned, but not sent.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mcp"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/xlog"
+	"github.com/invopop/jsonschema"
+)
+
+var logger = xlog.NewPackageLogger("github.com/effective-security/gogentic/tools", "notify")
+
+// ErrNotApproved is returned by the ApprovalFunc when the message is rejected.
+var ErrNotApproved = errors.New("message not approved")
+
+// MaxRecipients is the maximum number of recipients of one message.
+const MaxRecipients = 20
+
+// MaxBodyLength is the maximum length of the message body in bytes.
+const MaxBodyLength = 32 * 1024
+
+// AllowAll is the allow-list pattern that allows any recipient.
+const AllowAll = "*"
+
+// Message is the outbound message delivered by the Sender.
+type Message struct {
+	// Backend is the name of the Sender, like slack or email.
+	Backend string   `json:"backend" yaml:"backend"`
+	To      []string `json:"to" yaml:"to"`
+	Subject string   `json:"subject,omitempty" yaml:"subject"`
+	Body    string   `json:"body" yaml:"body"`
+}
+
+// Sender delivers the messages to the recipients.
+type Sender interface {
+	// Name returns the name of the backend, like slack or email.
+	Name() string
+	// Send delivers the message to all recipients.
+	Send(ctx context.Context, msg *Message) error
+}
+
+// ApprovalFunc reviews the outbound message before it is sent,
+// for example by asking the human operator.
+// It returns nil to send the message, or an error to reject it,
+// the error should wrap ErrNotApproved.
+type ApprovalFunc func(ctx context.Context, msg *Message) error
+
+// Request is the JSON input expected by the tool.
+type Request struct {
+	To       []string       `json:"to" yaml:"to" jsonschema:"required,minItems=1,title=To,description=The recipients of the message."`
+	Subject  string         `json:"subject,omitempty" yaml:"subject" jsonschema:"title=Subject,description=The subject of the message."`
+	Body     string         `json:"body,omitempty" yaml:"body" jsonschema:"title=Body,description=The text of the message."`
+	Template string         `json:"template,omitempty" yaml:"template" jsonschema:"title=Template,description=The name of the template to render the message with the data."`
+	Data     map[string]any `json:"data,omitempty" yaml:"data" jsonschema:"title=Data,description=The values for the template."`
+}
+
+// TemplateData is the data of the template execution.
+type TemplateData struct {
+	Subject string
+	Body    string
+	Data    map[string]any
+}
+
+// Response is the tool output.
+type Response struct {
+	// Status is sent, or dry_run when the message is not sent.
+	Status  string   `json:"status" yaml:"status"`
+	Backend string   `json:"backend" yaml:"backend"`
+	To      []string `json:"to" yaml:"to"`
+	Subject string   `json:"subject,omitempty" yaml:"subject"`
+	// Body is the rendered message, returned in the dry-run mode.
+	Body string `json:"body,omitempty" yaml:"body"`
+}
+
+// GetContent gets the content of the message for the chat history
+func (r *Response) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+const (
+	// StatusSent is the status of the delivered message.
+	StatusSent = "sent"
+	// StatusDryRun is the status of the message rendered in the dry-run mode.
+	StatusDryRun = "dry_run"
+)
+
+// Tool implements tools.ITool, it sends the messages with the Sender.
+type Tool struct {
+	name        string
+	description string
+	sender      Sender
+	allowed     []string
+	templates   *template.Template
+	approve     ApprovalFunc
+	dryRun      bool
+	funcParams  *jsonschema.Schema
+}
+
+var _ tools.Tool[Request, Response] = (*Tool)(nil)
+var _ tools.MCPTool[Request] = (*Tool)(nil)
+
+// New returns a new notification tool with the sender.
+// No recipients are allowed by default, use WithAllowedRecipients.
+func New(name, description string, sender Sender) *Tool {
+	sc, _ := schema.New(reflect.TypeOf(Request{}))
+	return &Tool{
+		name:        name,
+		description: description,
+		sender:      sender,
+		funcParams:  sc.Parameters,
+	}
+}
+
+func (t *Tool) WithName(name string) *Tool {
+	t.name = name
+	return t
+}
+
+func (t *Tool) WithDescription(description string) *Tool {
+	t.description = description
+	return t
+}
+
+// WithAllowedRecipients sets the allow-list of the recipients.
+// The pattern is the exact recipient, case insensitive,
+// the email domain like @example.com, or AllowAll.
+func (t *Tool) WithAllowedRecipients(patterns ...string) *Tool {
+	t.allowed = patterns
+	return t
+}
+
+// WithTemplates sets the templates of the messages, the LLM selects the template by name.
+// The templates are executed with TemplateData.
+func (t *Tool) WithTemplates(templates *template.Template) *Tool {
+	t.templates = templates
+	return t
+}
+
+// WithApproval sets the hook to approve the messages before they are sent.
+func (t *Tool) WithApproval(fn ApprovalFunc) *Tool {
+	t.approve = fn
+	return t
+}
+
+// WithDryRun enables the dry-run mode, the messages are rendered and returned, but not sent.
+func (t *Tool) WithDryRun(dryRun bool) *Tool {
+	t.dryRun = dryRun
+	return t
+}
+
+func (t *Tool) Name() string {
+	return t.name
+}
+
+func (t *Tool) Description() string {
+	if t.templates == nil {
+		return t.description
+	}
+	var names []string
+	for _, tmpl := range t.templates.Templates() {
+		if tmpl.Name() != "" && tmpl.Tree != nil {
+			names = append(names, tmpl.Name())
+		}
+	}
+	if len(names) == 0 {
+		return t.description
+	}
+	sort.Strings(names)
+	return t.description + " Available templates: " + strings.Join(names, ", ") + "."
+}
+
+func (t *Tool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+// RegisterMCP registers the tool with the output schema of Response
+func (t *Tool) RegisterMCP(registrator tools.McpServerRegistrator) error {
+	return registrator.RegisterTool(t.name, t.Description(), t.Run)
+}
+
+func (t *Tool) RunMCP(ctx context.Context, req *Request) (*mcp.ToolResponse, error) {
+	res, err := t.Run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewStructuredToolResponse(res)
+}
+
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	var req Request
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	res, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return res.GetContent(), nil
+}
+
+// Run renders the message, checks the recipients and the approval, and sends the message.
+// The errors are marked with chatmodel.ErrToolInvalidInput for the invalid requests,
+// and with chatmodel.ErrToolPermission for the recipients not allowed and the rejected messages.
+func (t *Tool) Run(ctx context.Context, req *Request) (*Response, error) {
+	msg, err := t.render(req)
+	if err != nil {
+		return nil, errors.Mark(err, chatmodel.ErrToolInvalidInput)
+	}
+	for _, to := range msg.To {
+		if !t.isAllowed(to) {
+			return nil, errors.Mark(errors.Newf("recipient %q is not allowed", to), chatmodel.ErrToolPermission)
+		}
+	}
+
+	res := &Response{
+		Status:  StatusSent,
+		Backend: msg.Backend,
+		To:      msg.To,
+		Subject: msg.Subject,
+	}
+	if t.dryRun {
+		res.Status = StatusDryRun
+		res.Body = msg.Body
+		return res, nil
+	}
+
+	if t.approve != nil {
+		if err := t.approve(ctx, msg); err != nil {
+			return nil, errors.Mark(errors.WithMessage(err, "message rejected"), chatmodel.ErrToolPermission)
+		}
+	}
+	if err := t.sender.Send(ctx, msg); err != nil {
+		return nil, errors.WithMessagef(err, "failed to send %s message", msg.Backend)
+	}
+
+	logger.ContextKV(ctx, xlog.DEBUG,
+		"tool", t.name,
+		"backend", msg.Backend,
+		"recipients", len(msg.To),
+		"status", "sent",
+	)
+	return res, nil
+}
+
+func (t *Tool) render(req *Request) (*Message, error) {
+	if len(req.To) == 0 {
+		return nil, errors.New("recipients are required")
+	}
+	if len(req.To) > MaxRecipients {
+		return nil, errors.Newf("up to %d recipients are supported", MaxRecipients)
+	}
+
+	msg := &Message{
+		Backend: t.sender.Name(),
+		// the subject is one line
+		Subject: strings.Join(strings.Fields(req.Subject), " "),
+		Body:    req.Body,
+	}
+	for _, to := range req.To {
+		if to = strings.TrimSpace(to); to != "" {
+			msg.To = append(msg.To, to)
+		}
+	}
+	if len(msg.To) == 0 {
+		return nil, errors.New("recipients are required")
+	}
+
+	if req.Template != "" {
+		var tmpl *template.Template
+		if t.templates != nil {
+			tmpl = t.templates.Lookup(req.Template)
+		}
+		if tmpl == nil {
+			return nil, errors.Newf("unknown template %q", req.Template)
+		}
+		var b bytes.Buffer
+		err := tmpl.Execute(&b, &TemplateData{
+			Subject: msg.Subject,
+			Body:    req.Body,
+			Data:    req.Data,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to render template %q", req.Template)
+		}
+		msg.Body = b.String()
+	}
+
+	if strings.TrimSpace(msg.Body) == "" {
+		return nil, errors.New("body is required")
+	}
+	if len(msg.Body) > MaxBodyLength {
+		return nil, errors.Newf("body is longer than %d bytes", MaxBodyLength)
+	}
+	return msg, nil
+}
+
+// isAllowed returns true, if the recipient matches the allow-list,
+// the email recipients are matched by the address without the display name.
+func (t *Tool) isAllowed(to string) bool {
+	if addr, err := mail.ParseAddress(to); err == nil {
+		to = addr.Address
+	}
+	for _, pattern := range t.allowed {
+		switch {
+		case pattern == AllowAll:
+			return true
+		case strings.HasPrefix(pattern, "@"):
+			if strings.HasSuffix(strings.ToLower(to), strings.ToLower(pattern)) {
+				return true
+			}
+		case strings.EqualFold(pattern, to):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"text/template"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/tools/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlackTool(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	var posted []string
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		lock.Lock()
+		posted = append(posted, r.URL.Path+": "+req["text"])
+		lock.Unlock()
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	sender := notify.NewSlackSender(map[string]string{
+		"#alerts":  server.URL + "/alerts",
+		"#reports": server.URL + "/reports",
+	}).WithHTTPClient(server.Client())
+	assert.Equal(t, []string{"#alerts", "#reports"}, sender.Channels())
+
+	templates := template.Must(template.New("report").Parse(`Report for {{.Data.team}}: {{.Body}}`))
+	tool := notify.NewSlackTool(sender).WithTemplates(templates)
+	assert.Equal(t, notify.SlackToolName, tool.Name())
+	assert.Equal(t, "Send a message to the Slack channels: #alerts, #reports. The body supports the Slack markdown. Available templates: report.", tool.Description())
+	require.NotNil(t, tool.Parameters())
+
+	ctx := context.Background()
+	out, err := tool.Call(ctx, `{"to":["#alerts","#REPORTS"],"subject":"Build\nfailed","body":"See logs"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":"sent","backend":"slack","to":["#alerts","#REPORTS"],"subject":"Build failed"}`, out)
+
+	out, err = tool.Call(ctx, `{"to":["#reports"],"template":"report","body":"all green","data":{"team":"infra"}}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":"sent","backend":"slack","to":["#reports"]}`, out)
+	assert.Equal(t, []string{
+		"/alerts: *Build failed*\n\nSee logs",
+		"/reports: *Build failed*\n\nSee logs",
+		"/reports: Report for infra: all green",
+	}, posted)
+
+	_, err = tool.Call(ctx, `{"to":["#general"],"body":"hi"}`)
+	assert.EqualError(t, err, `recipient "#general" is not allowed`)
+	assert.Equal(t, chatmodel.ToolErrorPermission, chatmodel.GetToolErrorCategory(err))
+
+	_, err = tool.Call(ctx, `{"to":["#alerts"],"template":"missing"}`)
+	assert.EqualError(t, err, `unknown template "missing"`)
+	assert.Equal(t, chatmodel.ToolErrorInvalidInput, chatmodel.GetToolErrorCategory(err))
+
+	_, err = tool.Call(ctx, `{"to":["#alerts"]}`)
+	assert.EqualError(t, err, "body is required")
+
+	_, err = tool.Call(ctx, `{"to":[" "],"body":"hi"}`)
+	assert.EqualError(t, err, "recipients are required")
+
+	_, err = tool.Call(ctx, `not json`)
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+
+	status.Store(http.StatusTooManyRequests)
+	_, err = tool.Call(ctx, `{"to":["#alerts"],"body":"hi"}`)
+	assert.EqualError(t, err, "failed to send slack message: channel #alerts: Slack webhook failed with status 429")
+	assert.Equal(t, chatmodel.ToolErrorTransient, chatmodel.GetToolErrorCategory(err))
+
+	status.Store(http.StatusGone)
+	_, err = tool.Call(ctx, `{"to":["#alerts"],"body":"hi"}`)
+	assert.Equal(t, chatmodel.ToolErrorPermission, chatmodel.GetToolErrorCategory(err))
+	assert.NotContains(t, err.Error(), server.URL)
+}
+
+func TestTool_DryRunAndApproval(t *testing.T) {
+	t.Parallel()
+
+	sender := &mockSender{}
+	var approved []*notify.Message
+	tool := notify.New("notify", "Send a message.", sender).
+		WithAllowedRecipients("ops@example.com", "@team.example.com").
+		WithApproval(func(_ context.Context, msg *notify.Message) error {
+			approved = append(approved, msg)
+			if strings.Contains(msg.Body, "secret") {
+				return errors.Wrap(notify.ErrNotApproved, "contains secret")
+			}
+			return nil
+		})
+
+	ctx := context.Background()
+	res, err := tool.Run(ctx, &notify.Request{
+		To:   []string{"Ops <ops@example.com>", "dev@team.example.com"},
+		Body: "done",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, notify.StatusSent, res.Status)
+	require.Len(t, sender.sent, 1)
+	require.Len(t, approved, 1)
+	assert.Equal(t, approved[0], sender.sent[0])
+
+	_, err = tool.Run(ctx, &notify.Request{To: []string{"ops@example.com"}, Body: "the secret"})
+	assert.EqualError(t, err, "message rejected: contains secret: message not approved")
+	assert.ErrorIs(t, err, notify.ErrNotApproved)
+	assert.Equal(t, chatmodel.ToolErrorPermission, chatmodel.GetToolErrorCategory(err))
+	assert.Len(t, sender.sent, 1)
+
+	for _, to := range []string{"dev@evil.com", "dev@evilteam.example.com", "ops@example.com.evil.com"} {
+		_, err = tool.Run(ctx, &notify.Request{To: []string{to}, Body: "hi"})
+		assert.EqualError(t, err, `recipient "`+to+`" is not allowed`)
+	}
+
+	// the dry run does not send, and does not ask for the approval
+	tool.WithDryRun(true)
+	res, err = tool.Run(ctx, &notify.Request{To: []string{"ops@example.com"}, Subject: "Status", Body: "the secret"})
+	require.NoError(t, err)
+	assert.Equal(t, &notify.Response{
+		Status:  notify.StatusDryRun,
+		Backend: "mock",
+		To:      []string{"ops@example.com"},
+		Subject: "Status",
+		Body:    "the secret",
+	}, res)
+	assert.Len(t, sender.sent, 1)
+	assert.Len(t, approved, 2)
+
+	// no recipients are allowed by default
+	_, err = notify.New("notify", "", sender).Run(ctx, &notify.Request{To: []string{"ops@example.com"}, Body: "hi"})
+	assert.EqualError(t, err, `recipient "ops@example.com" is not allowed`)
+
+	res, err = notify.New("notify", "", sender).WithAllowedRecipients(notify.AllowAll).
+		Run(ctx, &notify.Request{To: []string{"any@example.org"}, Body: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, notify.StatusSent, res.Status)
+}
+
+func TestEmailTool(t *testing.T) {
+	t.Parallel()
+
+	smtpServer := newFakeSMTP(t)
+	_, err := notify.NewEmailSender(notify.SMTPConfig{Addr: "localhost", From: "agent@example.com"})
+	assert.ErrorContains(t, err, `invalid SMTP address "localhost"`)
+	_, err = notify.NewEmailSender(notify.SMTPConfig{Addr: smtpServer.addr, From: "agent"})
+	assert.ErrorContains(t, err, `invalid sender address "agent"`)
+
+	sender, err := notify.NewEmailSender(notify.SMTPConfig{
+		Addr: smtpServer.addr,
+		From: "Agent <agent@example.com>",
+	})
+	require.NoError(t, err)
+
+	tool := notify.NewEmailTool(sender).WithAllowedRecipients("@example.com")
+	assert.Equal(t, notify.EmailToolName, tool.Name())
+
+	out, err := tool.Call(context.Background(), `{"to":["Bob <bob@example.com>","alice@example.com"],"subject":"Résumé","body":"Line 1\nLine 2"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":"sent","backend":"email","to":["Bob \u003cbob@example.com\u003e","alice@example.com"],"subject":"Résumé"}`, out)
+
+	smtpServer.wait()
+	assert.Equal(t, "agent@example.com", smtpServer.from)
+	assert.Equal(t, []string{"bob@example.com", "alice@example.com"}, smtpServer.rcpt)
+	assert.Contains(t, smtpServer.data, "From: \"Agent\" <agent@example.com>\r\n")
+	assert.Contains(t, smtpServer.data, "To: bob@example.com, alice@example.com\r\n")
+	assert.Contains(t, smtpServer.data, "Subject: =?utf-8?q?R=C3=A9sum=C3=A9?=\r\n")
+	assert.Contains(t, smtpServer.data, "Content-Transfer-Encoding: quoted-printable\r\n")
+	assert.True(t, strings.HasSuffix(smtpServer.data, "\r\n\r\nLine 1\r\nLine 2\r\n"), smtpServer.data)
+
+	_, err = tool.Call(context.Background(), `{"to":["not an address@example.com"],"body":"hi"}`)
+	assert.EqualError(t, err, `failed to send email message: invalid email address "not an address@example.com"`)
+	assert.Equal(t, chatmodel.ToolErrorInvalidInput, chatmodel.GetToolErrorCategory(err))
+}
+
+type mockSender struct {
+	sent []*notify.Message
+}
+
+func (s *mockSender) Name() string {
+	return "mock"
+}
+
+func (s *mockSender) Send(_ context.Context, msg *notify.Message) error {
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+// fakeSMTP accepts one SMTP session without extensions.
+type fakeSMTP struct {
+	addr string
+	done chan struct{}
+	from string
+	rcpt []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	s := &fakeSMTP{addr: l.Addr().String(), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) {
+			_, _ = conn.Write([]byte(line + "\r\n"))
+		}
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				s.from = strings.Trim(strings.Fields(strings.TrimPrefix(cmd, "MAIL FROM:"))[0], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				s.rcpt = append(s.rcpt, strings.Trim(strings.TrimPrefix(cmd, "RCPT TO:"), "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					b.WriteString(l)
+				}
+				s.data = b.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) wait() {
+	<-s.done
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+)
+
+// SlackToolName is the name of the Slack tool registered with the LLM.
+const SlackToolName = "slack_notify"
+
+// SlackSender sends the messages to the Slack incoming webhooks.
+// The incoming webhook posts to the channel it was created for,
+// so the recipients are the names of the configured webhooks.
+type SlackSender struct {
+	webhooks   map[string]string
+	httpClient *http.Client
+}
+
+// NewSlackSender returns the Sender for the Slack incoming webhooks,
+// by the channel name, like #alerts.
+func NewSlackSender(webhooks map[string]string) *SlackSender {
+	return &SlackSender{
+		webhooks:   webhooks,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the webhook calls.
+func (s *SlackSender) WithHTTPClient(client *http.Client) *SlackSender {
+	s.httpClient = client
+	return s
+}
+
+// Channels returns the names of the configured channels.
+func (s *SlackSender) Channels() []string {
+	channels := make([]string, 0, len(s.webhooks))
+	for ch := range s.webhooks {
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+func (s *SlackSender) Name() string {
+	return "slack"
+}
+
+// Send posts the message to the webhook of each channel.
+// The subject is sent in bold above the body.
+func (s *SlackSender) Send(ctx context.Context, msg *Message) error {
+	text := msg.Body
+	if msg.Subject != "" {
+		text = "*" + msg.Subject + "*\n\n" + msg.Body
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal request")
+	}
+
+	for _, ch := range msg.To {
+		url, ok := s.lookup(ch)
+		if !ok {
+			return errors.Mark(errors.Newf("unknown Slack channel %q", ch), chatmodel.ErrToolInvalidInput)
+		}
+		if err = s.post(ctx, url, body); err != nil {
+			return errors.WithMessagef(err, "channel %s", ch)
+		}
+	}
+	return nil
+}
+
+func (s *SlackSender) lookup(channel string) (string, bool) {
+	if url, ok := s.webhooks[channel]; ok {
+		return url, true
+	}
+	for ch, url := range s.webhooks {
+		if strings.EqualFold(ch, channel) {
+			return url, true
+		}
+	}
+	return "", false
+}
+
+// post sends the message to the webhook,
+// the webhook URL is the secret, so it is not included in the errors.
+func (s *SlackSender) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid Slack webhook URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return errors.Mark(errors.New("failed to call Slack webhook"), chatmodel.ErrToolTransient)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = errors.Newf("Slack webhook failed with status %d", resp.StatusCode)
+		if msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024)); len(bytes.TrimSpace(msg)) > 0 {
+			err = errors.Newf("Slack webhook failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+		}
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+			return errors.Mark(err, chatmodel.ErrToolTransient)
+		case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+			// the webhook is revoked or the channel is archived
+			return errors.Mark(err, chatmodel.ErrToolPermission)
+		default:
+			return errors.Mark(err, chatmodel.ErrToolInvalidInput)
+		}
+	}
+	return nil
+}
+
+// NewSlackTool returns the tool that sends the messages to the Slack channels,
+// the channels of the webhooks are allowed.
+func NewSlackTool(sender *SlackSender) *Tool {
+	channels := sender.Channels()
+	return New(SlackToolName,
+		"Send a message to the Slack channels: "+strings.Join(channels, ", ")+". The body supports the Slack markdown.",
+		sender,
+	).WithAllowedRecipients(channels...)
+}
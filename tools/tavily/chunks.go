@@ -0,0 +1,103 @@
+package tavily
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultChunkSize is the maximum size of the content chunk in bytes.
+const DefaultChunkSize = 2000
+
+// Chunk is the part of the page content, to be indexed by the retrieval pipeline.
+type Chunk struct {
+	// URL is the source page.
+	URL string `json:"url" yaml:"URL"`
+	// Title is the title of the page, if known.
+	Title string `json:"title,omitempty" yaml:"Title,omitempty"`
+	// Index is the position of the chunk in the page content.
+	Index int `json:"index" yaml:"Index"`
+	// Content is the text of the chunk.
+	Content string `json:"content" yaml:"Content"`
+}
+
+// SplitChunks splits the text into chunks up to size bytes,
+// at the paragraph boundaries when possible, then at the line and the word boundaries.
+// If size is not positive, DefaultChunkSize is used.
+func SplitChunks(text string, size int) []string {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var chunks []string
+	var b strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		b.Reset()
+	}
+
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if b.Len() > 0 && b.Len()+2+len(para) > size {
+			flush()
+		}
+		for len(para) > size {
+			flush()
+			cut := splitPoint(para, size)
+			chunks = append(chunks, strings.TrimSpace(para[:cut]))
+			para = strings.TrimSpace(para[cut:])
+		}
+		if para == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(para)
+	}
+	flush()
+	return chunks
+}
+
+// splitPoint returns the position to cut the text up to size bytes,
+// at the last new line or space, or at the rune boundary.
+func splitPoint(text string, size int) int {
+	head := text[:size]
+	if i := strings.LastIndexByte(head, '\n'); i > size/2 {
+		return i + 1
+	}
+	if i := strings.LastIndexByte(head, ' '); i > size/2 {
+		return i + 1
+	}
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	if size == 0 {
+		// a single rune is longer than the size
+		_, n := utf8.DecodeRuneInString(text)
+		return n
+	}
+	return size
+}
+
+func pageChunks(url, title, content string, size int) []Chunk {
+	parts := SplitChunks(content, size)
+	res := make([]Chunk, len(parts))
+	for i, p := range parts {
+		res[i] = Chunk{
+			URL:     url,
+			Title:   title,
+			Index:   i,
+			Content: p,
+		}
+	}
+	return res
+}
@@ -0,0 +1,82 @@
+package tavily
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+)
+
+// DefaultBaseURL is the base URL of the Tavily API.
+const DefaultBaseURL = "https://api.tavily.com"
+
+// MaxResponseSize is the maximum size of the API response body in bytes,
+// the larger responses fail without being decoded.
+const MaxResponseSize = 10 << 20
+
+// client calls the Tavily API endpoints.
+type client struct {
+	apikey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// post sends the request to the endpoint, and decodes the response.
+// The errors are marked with the tool error category by the status code.
+func (c *client) post(ctx context.Context, path string, req, res any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal request")
+	}
+
+	baseURL := c.baseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(baseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apikey)
+
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return errors.Mark(errors.Wrapf(err, "failed to call %s", path), chatmodel.ErrToolTransient)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err = errors.Newf("%s failed with status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(msg)))
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return errors.Mark(err, chatmodel.ErrToolPermission)
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+			return errors.Mark(err, chatmodel.ErrToolTransient)
+		default:
+			return errors.Mark(err, chatmodel.ErrToolInvalidInput)
+		}
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseSize+1))
+	if err != nil {
+		return errors.Mark(errors.Wrapf(err, "failed to read %s response", path), chatmodel.ErrToolTransient)
+	}
+	if len(raw) > MaxResponseSize {
+		return errors.Newf("%s response exceeds %d bytes", path, MaxResponseSize)
+	}
+	if err = json.Unmarshal(raw, res); err != nil {
+		return errors.Wrapf(err, "failed to decode %s response", path)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package tavily
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/mcp"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/pkg/schema"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+)
+
+const ExtractToolName = "tavily_extract"
+
+// MaxExtractURLs is the maximum number of URLs in the extract request supported by the API.
+const MaxExtractURLs = 20
+
+// ExtractRequest represents the extract tool input.
+type ExtractRequest struct {
+	URLs []string `json:"URLs" yaml:"URLs" jsonschema:"title=URLs,description=The URLs of the web pages to extract the content from.,minItems=1,maxItems=20"`
+}
+
+// ExtractedPage is the content extracted from a web page.
+type ExtractedPage struct {
+	URL        string `json:"url" yaml:"URL"`
+	RawContent string `json:"raw_content" yaml:"RawContent"`
+}
+
+// FailedExtraction is the web page that failed to be extracted.
+type FailedExtraction struct {
+	URL   string `json:"url" yaml:"URL"`
+	Error string `json:"error" yaml:"Error"`
+}
+
+// ExtractResult represents the structure for an extract response.
+type ExtractResult struct {
+	Results       []ExtractedPage    `json:"results" yaml:"Results" jsonschema:"title=Extracted Pages,description=The content of the web pages."`
+	FailedResults []FailedExtraction `json:"failed_results,omitempty" yaml:"FailedResults,omitempty" jsonschema:"title=Failed Pages,description=The web pages that failed to be extracted."`
+}
+
+func (r *ExtractResult) GetContent() string {
+	return llmutils.ToJSON(r)
+}
+
+// Chunks returns the content of the pages split into chunks up to size bytes.
+func (r *ExtractResult) Chunks(size int) []Chunk {
+	var res []Chunk
+	for _, p := range r.Results {
+		res = append(res, pageChunks(p.URL, "", p.RawContent, size)...)
+	}
+	return res
+}
+
+// ExtractOpts represents the options for the extraction.
+// See: https://docs.tavily.com/documentation/api-reference/endpoint/extract
+type ExtractOpts struct {
+	// Available options: basic, advanced
+	// The advanced extraction retrieves more data, including tables and embedded content.
+	ExtractDepth SearchDepth `json:"extract_depth,omitempty"`
+	// Available options: markdown, text
+	Format string `json:"format,omitempty"`
+}
+
+type extractPayload struct {
+	URLs []string `json:"urls"`
+	ExtractOpts
+}
+
+// ExtractTool is a tool that extracts the content of the web pages.
+type ExtractTool struct {
+	name        string
+	description string
+	funcParams  *jsonschema.Schema
+	client      client
+
+	opts ExtractOpts
+}
+
+// ensure ExtractTool implements the llm.Function interface
+var _ tools.Tool[ExtractRequest, ExtractResult] = (*ExtractTool)(nil)
+var _ tools.MCPTool[ExtractRequest] = (*ExtractTool)(nil)
+
+// NewExtract returns the extract tool with the API key from TAVILY_API_KEY environment variable.
+func NewExtract() (*ExtractTool, error) {
+	apikey := os.Getenv(DefaultAPIKeyEnvName)
+	if apikey == "" {
+		return nil, errors.Errorf("TAVILY_API_KEY is not set")
+	}
+	return NewExtractWithAPIKey(apikey)
+}
+
+func NewExtractWithAPIKey(apikey string) (*ExtractTool, error) {
+	sc, err := schema.New(reflect.TypeOf(ExtractRequest{}))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create schema")
+	}
+
+	tool := &ExtractTool{
+		name:        ExtractToolName,
+		description: "A tool that extracts the content of the web pages by URLs.",
+		client: client{
+			apikey:     apikey,
+			baseURL:    DefaultBaseURL,
+			httpClient: http.DefaultClient,
+		},
+		funcParams: sc.Parameters,
+		opts: ExtractOpts{
+			ExtractDepth: SearchDepthBasic,
+			Format:       "markdown",
+		},
+	}
+	return tool, nil
+}
+
+func (t *ExtractTool) WithName(name string) *ExtractTool {
+	t.name = name
+	return t
+}
+
+func (t *ExtractTool) WithDescription(description string) *ExtractTool {
+	t.description = description
+	return t
+}
+
+func (t *ExtractTool) WithExtractOpts(opts ExtractOpts) *ExtractTool {
+	t.opts = opts
+	return t
+}
+
+func (t *ExtractTool) WithBaseURL(baseURL string) *ExtractTool {
+	t.client.baseURL = baseURL
+	return t
+}
+
+func (t *ExtractTool) WithHTTPClient(client *http.Client) *ExtractTool {
+	t.client.httpClient = client
+	return t
+}
+
+func (t *ExtractTool) Name() string {
+	return t.name
+}
+
+func (t *ExtractTool) Description() string {
+	return t.description
+}
+
+func (t *ExtractTool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+// RegisterMCP registers the tool with the output schema of ExtractResult
+func (t *ExtractTool) RegisterMCP(registrator tools.McpServerRegistrator) error {
+	return registrator.RegisterTool(t.name, t.description, t.Run)
+}
+
+func (t *ExtractTool) RunMCP(ctx context.Context, req *ExtractRequest) (*mcp.ToolResponse, error) {
+	res, err := t.Run(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewStructuredToolResponse(res)
+}
+
+func (t *ExtractTool) Run(ctx context.Context, req *ExtractRequest) (*ExtractResult, error) {
+	if len(req.URLs) == 0 {
+		return nil, errors.Mark(errors.New("invalid request: empty URLs"), chatmodel.ErrToolInvalidInput)
+	}
+	if len(req.URLs) > MaxExtractURLs {
+		return nil, errors.Mark(errors.Newf("invalid request: up to %d URLs are supported", MaxExtractURLs), chatmodel.ErrToolInvalidInput)
+	}
+
+	payload := extractPayload{
+		URLs:        req.URLs,
+		ExtractOpts: t.opts,
+	}
+	res := new(ExtractResult)
+	if err := t.client.post(ctx, "/extract", &payload, res); err != nil {
+		return nil, errors.WithMessage(err, "failed to extract")
+	}
+	return res, nil
+}
+
+func (t *ExtractTool) Call(ctx context.Context, input string) (string, error) {
+	var req ExtractRequest
+	if err := json.Unmarshal(llmutils.CleanJSON([]byte(input)), &req); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+	out, err := t.Run(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	return out.GetContent(), nil
+}
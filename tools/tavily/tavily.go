@@ -11,8 +11,6 @@ import (
 	"reflect"
 
 	"github.com/cockroachdb/errors"
-	tavilygo "github.com/diverged/tavily-go"
-	tavilyModels "github.com/diverged/tavily-go/models"
 	"github.com/effective-security/gogentic/chatmodel"
 	"github.com/effective-security/gogentic/mcp"
 	"github.com/effective-security/gogentic/pkg/llmutils"
@@ -25,15 +23,71 @@ const ToolName = "tavily_web_search"
 
 var DefaultAPIKeyEnvName = "TAVILY_API_KEY"
 
+// MaxResultsLimit is the maximum number of the search results supported by the API.
+const MaxResultsLimit = 20
+
+// SearchDepth is the depth of the search.
+type SearchDepth string
+
+const (
+	// SearchDepthBasic costs 1 API Credit.
+	SearchDepthBasic SearchDepth = "basic"
+	// SearchDepthAdvanced costs 2 API Credits, and returns the most relevant content chunks of the sources.
+	SearchDepthAdvanced SearchDepth = "advanced"
+)
+
+// Topic is the category of the search.
+type Topic string
+
+const (
+	// TopicGeneral is the broader, general-purpose search.
+	TopicGeneral Topic = "general"
+	// TopicNews is the search of the real-time updates from the news sources.
+	TopicNews Topic = "news"
+)
+
 // SearchRequest represents the tool input.
+// The optional fields override SearchOpts of the tool.
 type SearchRequest struct {
-	Query string `json:"Query" yaml:"Query" jsonschema:"title=Search Query,description=The query to search web."`
+	Query          string   `json:"Query" yaml:"Query" jsonschema:"title=Search Query,description=The query to search web."`
+	Topic          Topic    `json:"Topic,omitempty" yaml:"Topic,omitempty" jsonschema:"title=Topic,description=The category of the search: general or news for the recent events.,enum=general,enum=news"`
+	IncludeDomains []string `json:"IncludeDomains,omitempty" yaml:"IncludeDomains,omitempty" jsonschema:"title=Include Domains,description=The domains to limit the search results to."`
+	ExcludeDomains []string `json:"ExcludeDomains,omitempty" yaml:"ExcludeDomains,omitempty" jsonschema:"title=Exclude Domains,description=The domains to exclude from the search results."`
+	MaxResults     int      `json:"MaxResults,omitempty" yaml:"MaxResults,omitempty" jsonschema:"title=Max Results,description=The maximum number of the search results.,minimum=1,maximum=20"`
+}
+
+// Result is the search result of a web page.
+// It replaces models.SearchResult of the github.com/diverged/tavily-go module
+// in SearchResult.Results, the JSON fields are compatible.
+type Result struct {
+	Title   string  `json:"title" yaml:"Title"`
+	URL     string  `json:"url" yaml:"URL"`
+	Content string  `json:"content" yaml:"Content"`
+	Score   float64 `json:"score" yaml:"Score"`
+	// RawContent is the parsed content of the page, see SearchOpts.IncludeRawContent.
+	RawContent string `json:"raw_content,omitempty" yaml:"RawContent,omitempty"`
+	// PublishedDate is the publication date of the news.
+	PublishedDate string `json:"published_date,omitempty" yaml:"PublishedDate,omitempty"`
 }
 
 // SearchResult represents the structure for a search response
 type SearchResult struct {
-	Results []tavilyModels.SearchResult `json:"results" yaml:"Results" jsonschema:"title=Search Results,description=The results from a web pages."`
-	Answer  string                      `json:"answer,omitempty" yaml:"Answer" jsonschema:"title=Final Answer,description=The aggregated answer from a web search."`
+	Results []Result `json:"results" yaml:"Results" jsonschema:"title=Search Results,description=The results from a web pages."`
+	Answer  string   `json:"answer,omitempty" yaml:"Answer" jsonschema:"title=Final Answer,description=The aggregated answer from a web search."`
+}
+
+// Chunks returns the content of the results split into chunks up to size bytes,
+// the raw content is used if available.
+func (i *SearchResult) Chunks(size int) []Chunk {
+	var res []Chunk
+	for _, r := range i.Results {
+		content := r.RawContent
+		if content == "" {
+			content = r.Content
+		}
+		res = append(res, pageChunks(r.URL, r.Title, content, size)...)
+	}
+	return res
 }
 
 func (i *SearchResult) GetContent() string {
@@ -45,9 +99,7 @@ type Tool struct {
 	name        string
 	description string
 	funcParams  *jsonschema.Schema
-	apikey      string
-	baseURL     string
-	httpClient  *http.Client
+	client      client
 
 	opts SearchOpts
 }
@@ -56,14 +108,29 @@ type Tool struct {
 var _ tools.Tool[SearchRequest, SearchResult] = (*Tool)(nil)
 var _ tools.MCPTool[SearchRequest] = (*Tool)(nil)
 
+// searchPayload is the request of the search endpoint.
+type searchPayload struct {
+	Query string `json:"query"`
+	SearchOpts
+}
+
 // SearchOpts represents the options for a web search.
 // See: https://docs.tavily.com/documentation/api-reference/endpoint/search
 type SearchOpts struct {
 	// Available options: basic, advanced
 	// A basic search costs 1 API Credit, while an advanced search costs 2 API Credits.
-	SearchDepth string `json:"search_depth,omitempty"`
+	SearchDepth SearchDepth `json:"search_depth,omitempty"`
 	// Available options: general, news
-	Topic string `json:"topic,omitempty"`
+	Topic Topic `json:"topic,omitempty"`
+	// The number of days back from the current date to include in the results,
+	// available only with TopicNews.
+	Days int `json:"days,omitempty"`
+	// The number of content chunks per source, up to 3,
+	// available only with SearchDepthAdvanced.
+	ChunksPerSource int `json:"chunks_per_source,omitempty"`
+	// Include the cleaned and parsed HTML content of each result,
+	// to be split into chunks for the retrieval pipeline, see SearchResult.Chunks.
+	IncludeRawContent bool `json:"include_raw_content,omitempty"`
 	// Include an LLM-generated answer to the provided query.
 	// `basic` or `true` returns a quick answer.
 	// `advanced` returns a more detailed answer.
@@ -93,16 +160,18 @@ func NewWithAPIKey(apikey string) (*Tool, error) {
 	tool := &Tool{
 		name:        ToolName,
 		description: "A tool that provides a web search functionality.",
-		apikey:      apikey,
-		//baseURL:     "https://api.tavily.com/search",
-		httpClient: http.DefaultClient,
+		client: client{
+			apikey:     apikey,
+			baseURL:    DefaultBaseURL,
+			httpClient: http.DefaultClient,
+		},
 		funcParams: sc.Parameters,
 		opts: SearchOpts{
-			SearchDepth:   "basic",
+			SearchDepth:   SearchDepthBasic,
 			IncludeAnswer: true,
 			MaxResults:    5,
 			UseCache:      true,
-			Topic:         "general",
+			Topic:         TopicGeneral,
 		},
 	}
 	return tool, nil
@@ -124,12 +193,12 @@ func (t *Tool) WithSearchOpts(opts SearchOpts) *Tool {
 }
 
 func (t *Tool) WithBaseURL(baseURL string) *Tool {
-	t.baseURL = baseURL
+	t.client.baseURL = baseURL
 	return t
 }
 
 func (t *Tool) WithHTTPClient(client *http.Client) *Tool {
-	t.httpClient = client
+	t.client.httpClient = client
 	return t
 }
 
@@ -145,8 +214,9 @@ func (t *Tool) Parameters() *jsonschema.Schema {
 	return t.funcParams
 }
 
+// RegisterMCP registers the tool with the output schema of SearchResult
 func (t *Tool) RegisterMCP(registrator tools.McpServerRegistrator) error {
-	return registrator.RegisterTool(t.name, t.description, t.RunMCP)
+	return registrator.RegisterTool(t.name, t.description, t.Run)
 }
 
 func (t *Tool) RunMCP(ctx context.Context, req *SearchRequest) (*mcp.ToolResponse, error) {
@@ -154,7 +224,7 @@ func (t *Tool) RunMCP(ctx context.Context, req *SearchRequest) (*mcp.ToolRespons
 	if err != nil {
 		return nil, err
 	}
-	return mcp.NewToolResponse(mcp.NewTextContent(res.GetContent())), nil
+	return mcp.NewStructuredToolResponse(res)
 }
 
 func (t *Tool) Run(ctx context.Context, req *SearchRequest) (*SearchResult, error) {
@@ -162,38 +232,34 @@ func (t *Tool) Run(ctx context.Context, req *SearchRequest) (*SearchResult, erro
 		return nil, errors.New("invalid request: empty query")
 	}
 
-	// Create a new Tavily client
-	client := tavilygo.NewClient(t.apikey)
-	if t.baseURL != "" {
-		client.BaseURL = t.baseURL
+	payload := searchPayload{
+		Query:      req.Query,
+		SearchOpts: t.opts,
 	}
-	// Set the HTTP client if provided
-	if t.httpClient != nil {
-		client.HTTPClient = t.httpClient
+	if req.Topic != "" {
+		payload.Topic = req.Topic
 	}
-
-	// Perform a search
-	searchReq := tavilyModels.SearchRequest{
-		Query:          req.Query,
-		SearchDepth:    t.opts.SearchDepth,
-		IncludeAnswer:  t.opts.IncludeAnswer,
-		MaxResults:     t.opts.MaxResults,
-		UseCache:       t.opts.UseCache,
-		Topic:          t.opts.Topic,
-		IncludeDomains: t.opts.IncludeDomains,
-		ExcludeDomains: t.opts.ExcludeDomains,
+	if len(req.IncludeDomains) > 0 {
+		payload.IncludeDomains = req.IncludeDomains
 	}
-
-	searchResp, err := tavilygo.Search(client, searchReq)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to perform search")
+	if len(req.ExcludeDomains) > 0 {
+		payload.ExcludeDomains = req.ExcludeDomains
 	}
-
-	res := &SearchResult{
-		Results: searchResp.Results,
-		Answer:  searchResp.Answer,
+	if req.MaxResults > 0 {
+		payload.MaxResults = req.MaxResults
+	}
+	payload.MaxResults = min(payload.MaxResults, MaxResultsLimit)
+	if payload.Topic != TopicNews {
+		payload.Days = 0
+	}
+	if payload.SearchDepth != SearchDepthAdvanced {
+		payload.ChunksPerSource = 0
 	}
 
+	res := new(SearchResult)
+	if err := t.client.post(ctx, "/search", &payload, res); err != nil {
+		return nil, errors.WithMessage(err, "failed to perform search")
+	}
 	return res, nil
 }
 
@@ -1,15 +1,16 @@
 package tavily_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
 
 	"github.com/cockroachdb/errors"
-	tavilyModels "github.com/diverged/tavily-go/models"
 	"github.com/effective-security/gogentic/chatmodel"
 	"github.com/effective-security/gogentic/pkg/llmutils"
 	"github.com/effective-security/gogentic/tools/tavily"
@@ -23,15 +24,26 @@ func Test_Tool(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, http.MethodPost, r.Method)
 		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer testkey", r.Header.Get("Authorization"))
+		assert.Equal(t, "/search", r.URL.Path)
 
-		var req tavilyModels.SearchRequest
+		var req struct {
+			Query         string `json:"query"`
+			Topic         string `json:"topic"`
+			SearchDepth   string `json:"search_depth"`
+			MaxResults    int    `json:"max_results"`
+			IncludeAnswer bool   `json:"include_answer"`
+		}
 		err := json.NewDecoder(r.Body).Decode(&req)
 		assert.NoError(t, err)
 
 		assert.Equal(t, "What is capital of France", req.Query)
+		assert.Equal(t, "general", req.Topic)
+		assert.Equal(t, "basic", req.SearchDepth)
+		assert.Equal(t, 5, req.MaxResults)
 
 		resp := tavily.SearchResult{
-			Results: []tavilyModels.SearchResult{
+			Results: []tavily.Result{
 				{Title: "Test Result", URL: "https://example.com", Content: "Test content", Score: 0.9},
 			},
 		}
@@ -59,6 +71,38 @@ func Test_Tool(t *testing.T) {
       "type": "string",
       "title": "Search Query",
       "description": "The query to search web."
+    },
+    "Topic": {
+      "type": "string",
+      "enum": [
+        "general",
+        "news"
+      ],
+      "title": "Topic",
+      "description": "The category of the search: general or news for the recent events."
+    },
+    "IncludeDomains": {
+      "items": {
+        "type": "string"
+      },
+      "type": "array",
+      "title": "Include Domains",
+      "description": "The domains to limit the search results to."
+    },
+    "ExcludeDomains": {
+      "items": {
+        "type": "string"
+      },
+      "type": "array",
+      "title": "Exclude Domains",
+      "description": "The domains to exclude from the search results."
+    },
+    "MaxResults": {
+      "type": "integer",
+      "maximum": 20,
+      "minimum": 1,
+      "title": "Max Results",
+      "description": "The maximum number of the search results."
     }
   },
   "type": "object",
@@ -93,6 +137,153 @@ func Test_Tool(t *testing.T) {
 	assert.Equal(t, exp, resp2)
 }
 
+func Test_Tool_SearchOverrides(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "news", req["topic"])
+		assert.Equal(t, []any{"reuters.com"}, req["include_domains"])
+		assert.Equal(t, []any{"example.com"}, req["exclude_domains"])
+		assert.EqualValues(t, 20, req["max_results"])
+		assert.EqualValues(t, 3, req["days"])
+		assert.Equal(t, true, req["include_raw_content"])
+		// chunks_per_source is available only with the advanced search
+		assert.NotContains(t, req, "chunks_per_source")
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"title": "News", "url": "https://reuters.com/a", "content": "short", "raw_content": "para 1\n\npara 2", "score": 0.5},
+			},
+		})
+	}))
+	defer server.Close()
+
+	tool, err := tavily.NewWithAPIKey("testkey")
+	require.NoError(t, err)
+	tool.WithBaseURL(server.URL).WithHTTPClient(server.Client()).WithSearchOpts(tavily.SearchOpts{
+		SearchDepth:       tavily.SearchDepthBasic,
+		Topic:             tavily.TopicGeneral,
+		Days:              3,
+		ChunksPerSource:   3,
+		IncludeRawContent: true,
+		MaxResults:        5,
+		ExcludeDomains:    []string{"example.com"},
+	})
+
+	res, err := tool.Run(context.Background(), &tavily.SearchRequest{
+		Query:          "latest news",
+		Topic:          tavily.TopicNews,
+		IncludeDomains: []string{"reuters.com"},
+		MaxResults:     50,
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "para 1\n\npara 2", res.Results[0].RawContent)
+
+	assert.Equal(t, []tavily.Chunk{
+		{URL: "https://reuters.com/a", Title: "News", Index: 0, Content: "para 1"},
+		{URL: "https://reuters.com/a", Title: "News", Index: 1, Content: "para 2"},
+	}, res.Chunks(8))
+}
+
+func Test_Tool_Errors(t *testing.T) {
+	status := http.StatusUnauthorized
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"detail":{"error":"failed"}}`))
+	}))
+	defer server.Close()
+
+	tool, err := tavily.NewWithAPIKey("testkey")
+	require.NoError(t, err)
+	tool.WithBaseURL(server.URL).WithHTTPClient(server.Client())
+
+	_, err = tool.Run(context.Background(), &tavily.SearchRequest{Query: "q"})
+	require.Error(t, err)
+	assert.EqualError(t, err, `failed to perform search: /search failed with status 401: {"detail":{"error":"failed"}}`)
+	assert.Equal(t, chatmodel.ToolErrorPermission, chatmodel.GetToolErrorCategory(err))
+
+	status = http.StatusTooManyRequests
+	_, err = tool.Run(context.Background(), &tavily.SearchRequest{Query: "q"})
+	assert.Equal(t, chatmodel.ToolErrorTransient, chatmodel.GetToolErrorCategory(err))
+
+	status = http.StatusBadRequest
+	_, err = tool.Run(context.Background(), &tavily.SearchRequest{Query: "q"})
+	assert.Equal(t, chatmodel.ToolErrorInvalidInput, chatmodel.GetToolErrorCategory(err))
+}
+
+func Test_Tool_ResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"answer":"`))
+		_, _ = w.Write(bytes.Repeat([]byte("a"), tavily.MaxResponseSize))
+		_, _ = w.Write([]byte(`"}`))
+	}))
+	defer server.Close()
+
+	tool, err := tavily.NewWithAPIKey("testkey")
+	require.NoError(t, err)
+	tool.WithBaseURL(server.URL).WithHTTPClient(server.Client())
+
+	_, err = tool.Run(context.Background(), &tavily.SearchRequest{Query: "q"})
+	assert.EqualError(t, err, fmt.Sprintf("failed to perform search: /search response exceeds %d bytes", tavily.MaxResponseSize))
+}
+
+func Test_ExtractTool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/extract", r.URL.Path)
+		assert.Equal(t, "Bearer testkey", r.Header.Get("Authorization"))
+
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, []any{"https://example.com/a", "https://example.com/b"}, req["urls"])
+		assert.Equal(t, "basic", req["extract_depth"])
+		assert.Equal(t, "markdown", req["format"])
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"url": "https://example.com/a", "raw_content": "# Title\n\nThe content."},
+			},
+			"failed_results": []map[string]any{
+				{"url": "https://example.com/b", "error": "not found"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	tool, err := tavily.NewExtractWithAPIKey("testkey")
+	require.NoError(t, err)
+	tool.WithBaseURL(server.URL).WithHTTPClient(server.Client())
+	assert.Equal(t, tavily.ExtractToolName, tool.Name())
+	assert.Contains(t, tool.Description(), "extracts the content")
+
+	_, err = tool.Call(context.Background(), "plain string")
+	assert.ErrorIs(t, err, chatmodel.ErrFailedUnmarshalInput)
+
+	_, err = tool.Run(context.Background(), &tavily.ExtractRequest{})
+	assert.Equal(t, chatmodel.ToolErrorInvalidInput, chatmodel.GetToolErrorCategory(err))
+
+	out, err := tool.Call(context.Background(), `{"URLs":["https://example.com/a","https://example.com/b"]}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"results":[{"url":"https://example.com/a","raw_content":"# Title\n\nThe content."}],"failed_results":[{"url":"https://example.com/b","error":"not found"}]}`, out)
+
+	res, err := tool.Run(context.Background(), &tavily.ExtractRequest{URLs: []string{"https://example.com/a", "https://example.com/b"}})
+	require.NoError(t, err)
+	assert.Equal(t, []tavily.Chunk{
+		{URL: "https://example.com/a", Index: 0, Content: "# Title\n\nThe content."},
+	}, res.Chunks(0))
+}
+
+func Test_SplitChunks(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, tavily.SplitChunks("  ", 10))
+	assert.Equal(t, []string{"one\n\ntwo", "three"}, tavily.SplitChunks("one\n\ntwo\n\n\nthree", 10))
+	// the long paragraph is split at the words
+	assert.Equal(t, []string{"aaaa bbbb", "cccc dddd", "ee"}, tavily.SplitChunks("aaaa bbbb cccc dddd ee", 10))
+	// the long word is split at the rune boundary
+	assert.Equal(t, []string{"ééé", "ééé"}, tavily.SplitChunks("éééééé", 7))
+}
+
 func Test_Tool_Real(t *testing.T) {
 	// uncomment to run Real Tests
 	t.Skip("skipping real test")
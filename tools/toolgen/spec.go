@@ -0,0 +1,373 @@
+package toolgen
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// maxRefDepth limits the $ref resolution of the recursive schemas.
+const maxRefDepth = 8
+
+// methods are the HTTP methods of the OpenAPI path item, in the order of the generated tools.
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+// spec is the parsed OpenAPI 3 document.
+type spec struct {
+	root map[string]any
+}
+
+// Operation is the API operation, exposed as the tool.
+type Operation struct {
+	ID          string
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tags        []string
+	// Parameters are the path, query and header parameters.
+	Parameters []Parameter
+	// HasBody is true, if the operation accepts the JSON request body.
+	HasBody      bool
+	BodyRequired bool
+	// schema is the JSON schema of the tool parameters
+	schema map[string]any
+}
+
+// Parameter is the parameter of the operation.
+type Parameter struct {
+	Name     string
+	In       string
+	Required bool
+	// Explode is true for the repeated query parameters of the arrays, the OpenAPI default.
+	Explode bool
+}
+
+func parseSpec(data []byte) (*spec, error) {
+	js, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse OpenAPI spec")
+	}
+	var root map[string]any
+	if err = json.Unmarshal(js, &root); err != nil {
+		return nil, errors.Wrap(err, "failed to parse OpenAPI spec")
+	}
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, errors.Newf("unsupported OpenAPI version %q, 3.x is required", version)
+	}
+	return &spec{root: root}, nil
+}
+
+// serverURL returns the URL of the first server, with the default values of the variables.
+func (s *spec) serverURL() string {
+	servers, _ := s.root["servers"].([]any)
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]any)
+	u, _ := server["url"].(string)
+	vars, _ := server["variables"].(map[string]any)
+	for name, v := range vars {
+		def, _ := asMap(v)["default"].(string)
+		u = strings.ReplaceAll(u, "{"+name+"}", def)
+	}
+	return u
+}
+
+// apiKeyLocation returns the name and the location of the first apiKey security scheme.
+func (s *spec) apiKeyLocation() (name, in string) {
+	schemes := asMap(asMap(s.root["components"])["securitySchemes"])
+	keys := sortedKeys(schemes)
+	for _, k := range keys {
+		scheme := asMap(s.deref(schemes[k]))
+		if scheme["type"] == "apiKey" {
+			name, _ = scheme["name"].(string)
+			in, _ = scheme["in"].(string)
+			return name, in
+		}
+	}
+	return "", ""
+}
+
+// operations returns the operations in the order of the paths and the methods.
+func (s *spec) operations() ([]*Operation, error) {
+	paths := asMap(s.root["paths"])
+	var list []*Operation
+	for _, path := range sortedKeys(paths) {
+		item := asMap(s.deref(paths[path]))
+		for _, method := range methods {
+			raw, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			op, err := s.operation(method, path, item, raw)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "%s %s", strings.ToUpper(method), path)
+			}
+			list = append(list, op)
+		}
+	}
+	return list, nil
+}
+
+func (s *spec) operation(method, path string, item, raw map[string]any) (*Operation, error) {
+	op := &Operation{
+		Method: strings.ToUpper(method),
+		Path:   path,
+	}
+	op.ID, _ = raw["operationId"].(string)
+	op.Summary, _ = raw["summary"].(string)
+	op.Description, _ = raw["description"].(string)
+	for _, tag := range asSlice(raw["tags"]) {
+		if t, ok := tag.(string); ok {
+			op.Tags = append(op.Tags, t)
+		}
+	}
+
+	properties := map[string]any{}
+	var required []string
+
+	// the operation parameters override the path item parameters with the same name and location
+	params := map[string]map[string]any{}
+	var order []string
+	for _, p := range append(asSlice(item["parameters"]), asSlice(raw["parameters"])...) {
+		param := asMap(s.deref(p))
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if name == "" {
+			return nil, errors.New("parameter name is required")
+		}
+		key := in + ":" + name
+		if _, ok := params[key]; !ok {
+			order = append(order, key)
+		}
+		params[key] = param
+	}
+	for _, key := range order {
+		param := params[key]
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		// the cookies are not supported, and the credentials are set by Auth
+		if in == "cookie" || (in == "header" && strings.EqualFold(name, "Authorization")) {
+			continue
+		}
+		if in != "path" && in != "query" && in != "header" {
+			return nil, errors.Newf("unsupported parameter location %q", in)
+		}
+		if _, ok := properties[name]; ok {
+			return nil, errors.Newf("duplicate parameter %q", name)
+		}
+		req, _ := param["required"].(bool)
+		explode := true
+		if e, ok := param["explode"].(bool); ok {
+			explode = e
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     name,
+			In:       in,
+			Required: req || in == "path",
+			Explode:  explode,
+		})
+
+		sc := s.schema(param["schema"], 0)
+		if desc, _ := param["description"].(string); desc != "" {
+			sc["description"] = desc
+		}
+		properties[name] = sc
+		if req || in == "path" {
+			required = append(required, name)
+		}
+	}
+
+	if body := asMap(s.deref(raw["requestBody"])); len(body) > 0 {
+		content := asMap(body["content"])
+		media, ok := content["application/json"]
+		if !ok {
+			for _, ct := range sortedKeys(content) {
+				if strings.HasSuffix(ct, "+json") {
+					media, ok = content[ct], true
+					break
+				}
+			}
+		}
+		if ok {
+			if _, dup := properties[bodyParam]; dup {
+				return nil, errors.Newf("parameter %q conflicts with the request body", bodyParam)
+			}
+			op.HasBody = true
+			op.BodyRequired, _ = body["required"].(bool)
+			sc := s.schema(asMap(media)["schema"], 0)
+			if desc, _ := body["description"].(string); desc != "" {
+				sc["description"] = desc
+			}
+			properties[bodyParam] = sc
+			if op.BodyRequired {
+				required = append(required, bodyParam)
+			}
+		}
+	}
+
+	op.schema = map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		op.schema["required"] = required
+	}
+	return op, nil
+}
+
+// deref returns the object referenced by $ref.
+func (s *spec) deref(v any) any {
+	for range maxRefDepth {
+		ref, ok := asMap(v)["$ref"].(string)
+		if !ok {
+			return v
+		}
+		v = s.lookup(ref)
+	}
+	return nil
+}
+
+// lookup returns the object of the local JSON pointer, like #/components/schemas/Pet.
+func (s *spec) lookup(ref string) any {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var v any = s.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token, _ = url.PathUnescape(token)
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		v = asMap(v)[token]
+	}
+	return v
+}
+
+// schemaProps are the keywords with the map of the schemas.
+var schemaProps = []string{"properties", "patternProperties", "$defs", "definitions"}
+
+// schemaLists are the keywords with the list of the schemas.
+var schemaLists = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+
+// schemaValues are the keywords with the schema.
+var schemaValues = []string{"items", "additionalProperties", "not", "contains", "propertyNames"}
+
+// dropped are the OpenAPI keywords, which are not the JSON schema keywords.
+var dropped = []string{"nullable", "discriminator", "xml", "externalDocs", "example"}
+
+// schema returns the JSON schema of the OpenAPI schema, with the references resolved.
+// The recursive references deeper than maxRefDepth are replaced with the empty schema.
+func (s *spec) schema(v any, depth int) map[string]any {
+	src := asMap(v)
+	if ref, ok := src["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			return map[string]any{}
+		}
+		res := s.schema(s.lookup(ref), depth+1)
+		if desc, _ := src["description"].(string); desc != "" {
+			res["description"] = desc
+		}
+		return res
+	}
+
+	res := make(map[string]any, len(src))
+	for k, val := range src {
+		switch {
+		case slices.Contains(dropped, k):
+		case slices.Contains(schemaProps, k):
+			props := map[string]any{}
+			for name, p := range asMap(val) {
+				props[name] = s.schema(p, depth)
+			}
+			res[k] = props
+		case slices.Contains(schemaLists, k):
+			var list []any
+			for _, item := range asSlice(val) {
+				list = append(list, s.schema(item, depth))
+			}
+			res[k] = list
+		case slices.Contains(schemaValues, k):
+			if b, ok := val.(bool); ok {
+				res[k] = b
+			} else {
+				res[k] = s.schema(val, depth)
+			}
+		case k == "type":
+			// OpenAPI 3.1 allows the list of the types with null
+			if types, ok := val.([]any); ok {
+				for _, t := range types {
+					if t != "null" {
+						res[k] = t
+						break
+					}
+				}
+			} else {
+				res[k] = val
+			}
+		case k == "exclusiveMinimum" || k == "exclusiveMaximum":
+			// OpenAPI 3.0 uses the boolean modifiers of minimum and maximum
+			if b, ok := val.(bool); ok {
+				limit := strings.ToLower(strings.TrimPrefix(k, "exclusive"))
+				if b && src[limit] != nil {
+					res[k] = src[limit]
+				}
+			} else {
+				res[k] = val
+			}
+		case k == "examples":
+			if _, ok := val.([]any); ok {
+				res[k] = val
+			}
+		default:
+			res[k] = val
+		}
+	}
+	// the limits are replaced by the exclusive limits
+	if b, _ := src["exclusiveMinimum"].(bool); b {
+		delete(res, "minimum")
+	}
+	if b, _ := src["exclusiveMaximum"].(bool); b {
+		delete(res, "maximum")
+	}
+	return res
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// toolName returns the name of the tool, which is accepted by the LLM providers.
+func toolName(prefix string, op *Operation) string {
+	name := op.ID
+	if name == "" {
+		name = strings.ToLower(op.Method) + "_" + op.Path
+	}
+	name = strings.Trim(invalidNameChars.ReplaceAllString(prefix+name, "_"), "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,379 @@
+// Package toolgen generates the tools from the OpenAPI 3 spec at runtime,
+// so any REST API can be exposed to the assistant without the hand-written wrappers.
+//
+// Each operation of the spec is the tool: the path, query and header parameters
+// are the top-level arguments, and the JSON request body is the "body" argument.
+// The spec can be JSON or YAML, the local $ref references are resolved.
+package toolgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/pkg/llmutils"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/invopop/jsonschema"
+)
+
+const (
+	// DefaultMaxResponseSize is the default maximum size of the response body returned to the LLM.
+	DefaultMaxResponseSize = 64 * 1024
+	// MaxDescriptionLength is the maximum length of the tool description.
+	MaxDescriptionLength = 1024
+)
+
+// bodyParam is the name of the argument with the request body.
+const bodyParam = "body"
+
+// TokenFunc returns the access token for the API calls.
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Auth is the authentication of the API calls.
+type Auth struct {
+	// Token is the bearer token.
+	Token string `json:"token,omitempty" yaml:"token"`
+	// TokenFunc returns the bearer token, like the OAuth access token, it takes precedence over Token.
+	TokenFunc TokenFunc `json:"-" yaml:"-"`
+	// Username and Password are used for the basic authentication.
+	Username string `json:"username,omitempty" yaml:"username"`
+	Password string `json:"password,omitempty" yaml:"password"`
+	// APIKey is sent in the APIKeyHeader header, or in the APIKeyQuery query parameter.
+	// If both are empty, the location is taken from the apiKey security scheme of the spec.
+	APIKey       string `json:"api_key,omitempty" yaml:"api_key"`
+	APIKeyHeader string `json:"api_key_header,omitempty" yaml:"api_key_header"`
+	APIKeyQuery  string `json:"api_key_query,omitempty" yaml:"api_key_query"`
+}
+
+// Config is the configuration of the generated tools.
+type Config struct {
+	// BaseURL is the URL of the API, the first server of the spec if not set.
+	BaseURL string `json:"base_url,omitempty" yaml:"base_url"`
+	// Auth is the authentication of the API calls.
+	Auth Auth `json:"auth" yaml:"auth"`
+	// Prefix is added to the tool names, like "petstore_".
+	Prefix string `json:"prefix,omitempty" yaml:"prefix"`
+	// Operations are the operation IDs to generate, all operations if empty.
+	Operations []string `json:"operations,omitempty" yaml:"operations"`
+	// Methods are the HTTP methods to generate, all methods if empty.
+	// Use GET for the read-only tools.
+	Methods []string `json:"methods,omitempty" yaml:"methods"`
+	// Tags are the tags of the operations to generate, all operations if empty.
+	Tags []string `json:"tags,omitempty" yaml:"tags"`
+	// Headers are added to each request.
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers"`
+	// MaxResponseSize is the maximum size of the response body, DefaultMaxResponseSize if not set.
+	MaxResponseSize int `json:"max_response_size,omitempty" yaml:"max_response_size"`
+	// HTTPClient is the HTTP client of the API calls, http.DefaultClient if not set.
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+}
+
+// Generate returns the tools for the operations of the OpenAPI 3 spec.
+func Generate(specData []byte, cfg Config) ([]tools.ITool, error) {
+	s, err := parseSpec(specData)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = s.serverURL()
+	}
+	if u, err := url.Parse(cfg.BaseURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.Newf("invalid base URL %q", cfg.BaseURL)
+	}
+	if cfg.Auth.APIKey != "" && cfg.Auth.APIKeyHeader == "" && cfg.Auth.APIKeyQuery == "" {
+		name, in := s.apiKeyLocation()
+		switch in {
+		case "header":
+			cfg.Auth.APIKeyHeader = name
+		case "query":
+			cfg.Auth.APIKeyQuery = name
+		default:
+			return nil, errors.New("API key location is not found in the spec")
+		}
+	}
+	if cfg.MaxResponseSize <= 0 {
+		cfg.MaxResponseSize = DefaultMaxResponseSize
+	}
+
+	ops, err := s.operations()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []tools.ITool
+	names := map[string]bool{}
+	for _, op := range ops {
+		if !cfg.include(op) {
+			continue
+		}
+		t, err := newTool(&cfg, op)
+		if err != nil {
+			return nil, err
+		}
+		if names[t.name] {
+			return nil, errors.Newf("duplicate tool name %q", t.name)
+		}
+		names[t.name] = true
+		list = append(list, t)
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no operations found")
+	}
+	return list, nil
+}
+
+func (c *Config) include(op *Operation) bool {
+	if len(c.Operations) > 0 && !slices.Contains(c.Operations, op.ID) {
+		return false
+	}
+	if len(c.Methods) > 0 && !slices.ContainsFunc(c.Methods, func(m string) bool { return strings.EqualFold(m, op.Method) }) {
+		return false
+	}
+	if len(c.Tags) > 0 && !slices.ContainsFunc(op.Tags, func(t string) bool { return slices.Contains(c.Tags, t) }) {
+		return false
+	}
+	return true
+}
+
+// Tool implements tools.ITool, it calls the API operation.
+type Tool struct {
+	cfg         *Config
+	op          *Operation
+	name        string
+	description string
+	funcParams  *jsonschema.Schema
+}
+
+var _ tools.ITool = (*Tool)(nil)
+
+func newTool(cfg *Config, op *Operation) (*Tool, error) {
+	js, err := json.Marshal(op.schema)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var params jsonschema.Schema
+	if err = json.Unmarshal(js, &params); err != nil {
+		return nil, errors.Wrapf(err, "invalid schema of %s %s", op.Method, op.Path)
+	}
+
+	desc := strings.TrimSpace(op.Summary)
+	if d := strings.TrimSpace(op.Description); d != "" && d != desc {
+		if desc != "" {
+			desc += "\n\n"
+		}
+		desc += d
+	}
+	if desc == "" {
+		desc = op.Method + " " + op.Path
+	}
+
+	return &Tool{
+		cfg:         cfg,
+		op:          op,
+		name:        toolName(cfg.Prefix, op),
+		description: truncate(desc, MaxDescriptionLength),
+		funcParams:  &params,
+	}, nil
+}
+
+// Operation returns the API operation of the tool.
+func (t *Tool) Operation() *Operation {
+	return t.op
+}
+
+func (t *Tool) Name() string {
+	return t.name
+}
+
+func (t *Tool) Description() string {
+	return t.description
+}
+
+func (t *Tool) Parameters() *jsonschema.Schema {
+	return t.funcParams
+}
+
+// Call calls the API operation, and returns the response body.
+// The error status codes are marked with the tool error category.
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
+	var args map[string]any
+	dec := json.NewDecoder(bytes.NewReader(llmutils.CleanJSON([]byte(input))))
+	dec.UseNumber()
+	if err := dec.Decode(&args); err != nil {
+		return "", errors.WithStack(chatmodel.ErrFailedUnmarshalInput)
+	}
+
+	req, err := t.request(ctx, args)
+	if err != nil {
+		return "", err
+	}
+
+	client := t.cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Mark(errors.Wrapf(err, "failed to call %s", t.name), chatmodel.ErrToolTransient)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err = errors.Newf("%s %s failed with status %d: %s", t.op.Method, t.op.Path, resp.StatusCode, strings.TrimSpace(string(msg)))
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+			return "", errors.Mark(err, chatmodel.ErrToolTransient)
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return "", errors.Mark(err, chatmodel.ErrToolPermission)
+		default:
+			return "", errors.Mark(err, chatmodel.ErrToolInvalidInput)
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(t.cfg.MaxResponseSize)+1))
+	if err != nil {
+		return "", errors.Mark(errors.Wrap(err, "failed to read response"), chatmodel.ErrToolTransient)
+	}
+	if len(body) == 0 {
+		return llmutils.ToJSON(map[string]int{"status": resp.StatusCode}), nil
+	}
+	if len(body) > t.cfg.MaxResponseSize {
+		return truncate(string(body), t.cfg.MaxResponseSize) + "\n[truncated]", nil
+	}
+	return string(body), nil
+}
+
+// request returns the HTTP request of the operation with the arguments.
+func (t *Tool) request(ctx context.Context, args map[string]any) (*http.Request, error) {
+	path := t.op.Path
+	query := url.Values{}
+	header := http.Header{}
+	for _, p := range t.op.Parameters {
+		v, ok := args[p.Name]
+		if !ok || v == nil {
+			if p.Required {
+				return nil, errors.Mark(errors.Newf("parameter %q is required", p.Name), chatmodel.ErrToolInvalidInput)
+			}
+			continue
+		}
+		switch p.In {
+		case "path":
+			s := paramValue(v)
+			if s == "" || s == "." || s == ".." {
+				return nil, errors.Mark(errors.Newf("invalid value of parameter %q", p.Name), chatmodel.ErrToolInvalidInput)
+			}
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(s))
+		case "query":
+			if list, ok := v.([]any); ok && p.Explode {
+				for _, item := range list {
+					query.Add(p.Name, paramValue(item))
+				}
+			} else {
+				query.Set(p.Name, paramValue(v))
+			}
+		case "header":
+			header.Set(p.Name, paramValue(v))
+		}
+	}
+
+	var body io.Reader
+	if t.op.HasBody {
+		if v, ok := args[bodyParam]; ok && v != nil {
+			js, err := json.Marshal(v)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			body = bytes.NewReader(js)
+			header.Set("Content-Type", "application/json")
+		} else if t.op.BodyRequired {
+			return nil, errors.Mark(errors.Newf("parameter %q is required", bodyParam), chatmodel.ErrToolInvalidInput)
+		}
+	}
+
+	u := strings.TrimRight(t.cfg.BaseURL, "/") + path
+	if t.cfg.Auth.APIKey != "" && t.cfg.Auth.APIKeyQuery != "" {
+		query.Set(t.cfg.Auth.APIKeyQuery, t.cfg.Auth.APIKey)
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, t.op.Method, u, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header = header
+	req.Header.Set("Accept", "application/json")
+	for k, v := range t.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if err = t.cfg.Auth.apply(ctx, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// apply sets the credentials of the request, except the API key in the query.
+func (a *Auth) apply(ctx context.Context, req *http.Request) error {
+	switch {
+	case a.TokenFunc != nil:
+		token, err := a.TokenFunc(ctx)
+		if err != nil {
+			return errors.Mark(errors.WithMessage(err, "failed to get access token"), chatmodel.ErrToolPermission)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case a.Token != "":
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	case a.Username != "":
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+	if a.APIKey != "" && a.APIKeyHeader != "" {
+		req.Header.Set(a.APIKeyHeader, a.APIKey)
+	}
+	return nil
+}
+
+// paramValue returns the parameter value in the simple style,
+// the arrays are separated by comma.
+func paramValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		if val {
+			return "true"
+		}
+		return "false"
+	case []any:
+		items := make([]string, len(val))
+		for i, item := range val {
+			items[i] = paramValue(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		js, _ := json.Marshal(val)
+		return string(js)
+	}
+}
+
+// truncate returns the text up to size bytes at the rune boundary.
+func truncate(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return text[:size]
+}
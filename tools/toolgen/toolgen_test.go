@@ -0,0 +1,302 @@
+package toolgen_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/effective-security/gogentic/chatmodel"
+	"github.com/effective-security/gogentic/tools"
+	"github.com/effective-security/gogentic/tools/toolgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const petstore = `
+openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: https://{region}.petstore.example.com/v1
+    variables:
+      region:
+        default: us
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-Pet-Key
+  parameters:
+    PetID:
+      name: petId
+      in: path
+      required: true
+      description: The ID of the pet.
+      schema:
+        type: integer
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+          nullable: true
+        age:
+          type: integer
+          minimum: 0
+          exclusiveMinimum: true
+        parent:
+          $ref: '#/components/schemas/Pet'
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List the pets.
+      tags: [pets]
+      parameters:
+        - name: tags
+          in: query
+          schema:
+            type: array
+            items:
+              type: string
+        - name: limit
+          in: query
+          schema:
+            type: integer
+        - name: X-Request-ID
+          in: header
+          schema:
+            type: string
+        - name: Authorization
+          in: header
+          schema:
+            type: string
+    post:
+      operationId: createPet
+      summary: Create the pet.
+      tags: [pets]
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+  /pets/{petId}:
+    parameters:
+      - $ref: '#/components/parameters/PetID'
+    get:
+      summary: Get the pet.
+      description: Returns the pet by ID.
+    delete:
+      operationId: deletePet
+      tags: [admin]
+`
+
+type recorder struct {
+	method string
+	uri    string
+	header http.Header
+	body   string
+}
+
+func newServer(t *testing.T, rec *recorder) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.method = r.Method
+		rec.uri = r.URL.RequestURI()
+		rec.header = r.Header
+		rec.body = string(body)
+		switch {
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/404"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"pet not found"}`))
+		case strings.HasSuffix(r.URL.Path, "/401"):
+			w.WriteHeader(http.StatusUnauthorized)
+		case strings.HasSuffix(r.URL.Path, "/503"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func findTool(t *testing.T, list []tools.ITool, name string) tools.ITool {
+	t.Helper()
+	for _, tool := range list {
+		if tool.Name() == name {
+			return tool
+		}
+	}
+	require.Failf(t, "tool not found", "%s", name)
+	return nil
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	list, err := toolgen.Generate([]byte(petstore), toolgen.Config{Prefix: "pet."})
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range list {
+		names = append(names, tool.Name())
+	}
+	assert.Equal(t, []string{"pet_listPets", "pet_createPet", "pet_get__pets_petId", "pet_deletePet"}, names)
+
+	get := list[2].(*toolgen.Tool)
+	assert.Equal(t, "Get the pet.\n\nReturns the pet by ID.", get.Description())
+	assert.Equal(t, "GET", get.Operation().Method)
+	assert.Equal(t, "/pets/{petId}", get.Operation().Path)
+	assert.Equal(t, "DELETE /pets/{petId}", list[3].Description())
+
+	js, err := json.Marshal(list[0].Parameters())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"limit": {"type": "integer"},
+			"X-Request-ID": {"type": "string"}
+		}
+	}`, string(js))
+
+	js, err = json.Marshal(get.Parameters())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {"petId": {"type": "integer", "description": "The ID of the pet."}},
+		"required": ["petId"]
+	}`, string(js))
+
+	// the recursive schema is resolved up to the depth limit
+	params := list[1].Parameters()
+	body, ok := params.Properties.Get("body")
+	require.True(t, ok)
+	assert.Equal(t, []string{"body"}, params.Required)
+	assert.Equal(t, []string{"name"}, body.Required)
+	age, ok := body.Properties.Get("age")
+	require.True(t, ok)
+	assert.Equal(t, json.Number("0"), age.ExclusiveMinimum)
+	assert.Empty(t, age.Minimum)
+	parent, ok := body.Properties.Get("parent")
+	require.True(t, ok)
+	assert.Equal(t, "object", parent.Type)
+
+	list, err = toolgen.Generate([]byte(petstore), toolgen.Config{Methods: []string{"get"}, Tags: []string{"pets"}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "listPets", list[0].Name())
+
+	list, err = toolgen.Generate([]byte(petstore), toolgen.Config{Operations: []string{"deletePet"}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	_, err = toolgen.Generate([]byte(petstore), toolgen.Config{Operations: []string{"unknown"}})
+	assert.EqualError(t, err, "no operations found")
+	_, err = toolgen.Generate([]byte(`{"swagger": "2.0"}`), toolgen.Config{})
+	assert.EqualError(t, err, `unsupported OpenAPI version "", 3.x is required`)
+	_, err = toolgen.Generate([]byte(`{"openapi": "3.1.0", "paths": {}}`), toolgen.Config{})
+	assert.EqualError(t, err, `invalid base URL ""`)
+	_, err = toolgen.Generate([]byte(`{"openapi": "3.1.0", "servers": [{"url": "https://api.example.com"}]}`), toolgen.Config{Auth: toolgen.Auth{APIKey: "key"}})
+	assert.EqualError(t, err, "API key location is not found in the spec")
+}
+
+func TestCall(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	rec := &recorder{}
+	ts := newServer(t, rec)
+	list, err := toolgen.Generate([]byte(petstore), toolgen.Config{
+		BaseURL: ts.URL + "/v1",
+		Auth:    toolgen.Auth{APIKey: "secret", Token: "token"},
+		Headers: map[string]string{"User-Agent": "gogentic"},
+	})
+	require.NoError(t, err)
+
+	out, err := findTool(t, list, "listPets").Call(ctx, `{"tags":["cat","dog"],"limit":10,"X-Request-ID":"r1"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, out)
+	assert.Equal(t, http.MethodGet, rec.method)
+	assert.Equal(t, "/v1/pets?limit=10&tags=cat&tags=dog", rec.uri)
+	assert.Equal(t, "r1", rec.header.Get("X-Request-ID"))
+	assert.Equal(t, "secret", rec.header.Get("X-Pet-Key"))
+	assert.Equal(t, "Bearer token", rec.header.Get("Authorization"))
+	assert.Equal(t, "gogentic", rec.header.Get("User-Agent"))
+
+	create := findTool(t, list, "createPet")
+	_, err = create.Call(ctx, `{"body":{"name":"Rex","age":3}}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, rec.method)
+	assert.JSONEq(t, `{"name":"Rex","age":3}`, rec.body)
+	assert.Equal(t, "application/json", rec.header.Get("Content-Type"))
+
+	_, err = create.Call(ctx, `{}`)
+	assert.EqualError(t, err, `parameter "body" is required`)
+	assert.Equal(t, chatmodel.ToolErrorInvalidInput, chatmodel.GetToolErrorCategory(err))
+
+	out, err = findTool(t, list, "deletePet").Call(ctx, `{"petId": 12345678901}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":204}`, out)
+	assert.Equal(t, "/v1/pets/12345678901", rec.uri)
+
+	get := findTool(t, list, "get__pets_petId")
+	_, err = get.Call(ctx, `{"petId": "a/b"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/pets/a%2Fb", rec.uri)
+
+	tcs := []struct {
+		input    string
+		category chatmodel.ToolErrorCategory
+		exp      string
+	}{
+		{`{}`, chatmodel.ToolErrorInvalidInput, `parameter "petId" is required`},
+		{`{"petId": ".."}`, chatmodel.ToolErrorInvalidInput, `invalid value of parameter "petId"`},
+		{`{"petId": 404}`, chatmodel.ToolErrorInvalidInput, `GET /pets/{petId} failed with status 404: {"message":"pet not found"}`},
+		{`{"petId": 401}`, chatmodel.ToolErrorPermission, `GET /pets/{petId} failed with status 401: `},
+		{`{"petId": 503}`, chatmodel.ToolErrorTransient, `GET /pets/{petId} failed with status 503: `},
+		{`not json`, chatmodel.ToolErrorInvalidInput, chatmodel.ErrFailedUnmarshalInput.Error()},
+	}
+	for _, tc := range tcs {
+		_, err = get.Call(ctx, tc.input)
+		require.Error(t, err, tc.input)
+		assert.EqualError(t, err, tc.exp)
+		assert.Equal(t, tc.category, chatmodel.GetToolErrorCategory(err))
+	}
+}
+
+func TestCall_QueryAPIKey(t *testing.T) {
+	t.Parallel()
+
+	rec := &recorder{}
+	ts := newServer(t, rec)
+	list, err := toolgen.Generate([]byte(petstore), toolgen.Config{
+		BaseURL:         ts.URL,
+		Operations:      []string{"listPets"},
+		Auth:            toolgen.Auth{APIKey: "secret", APIKeyQuery: "key", Username: "user", Password: "pass"},
+		MaxResponseSize: 5,
+	})
+	require.NoError(t, err)
+
+	out, err := list[0].Call(context.Background(), `{"limit": 1}`)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"ok\"\n[truncated]", out)
+	assert.Equal(t, "/pets?key=secret&limit=1", rec.uri)
+	user, pass, ok := (&http.Request{Header: rec.header}).BasicAuth()
+	require.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "pass", pass)
+	assert.Empty(t, rec.header.Get("X-Pet-Key"))
+}
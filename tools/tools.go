@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/effective-security/gogentic/mcp"
 	"github.com/effective-security/gogentic/pkg/llmutils"
@@ -25,17 +26,64 @@ type ITool interface {
 	// Should not exceed LLM model limit.
 	Description() string
 	// Parameters returns the parameters definition of the function, to be used in the prompt.
+	// The assistant validates the LLM arguments against the parameters before the call,
+	// see assistants.WithSkipToolArgsValidation.
 	Parameters() *jsonschema.Schema
 
 	// Call executes the tool with the given input and returns the result.
 	// If the tool fails to parse the input, it should return ErrFailedUnmarshalInput error.
+	// The tool should stop when the context is cancelled, and may return the partial result with the error.
+	// The error marked with chatmodel.ErrToolFatal aborts the other tool calls executed in parallel,
+	// the other errors are reported to the LLM as chatmodel.ToolError, by the category of the error.
 	Call(context.Context, string) (string, error)
 }
 
+// Callback receives the events of the tool calls.
+// The events are shared with the other callbacks, and must not be modified.
 type Callback interface {
-	OnToolStart(ctx context.Context, tool ITool, assistantName, input string)
-	OnToolEnd(ctx context.Context, tool ITool, assistantName, input string, output string)
-	OnToolError(ctx context.Context, tool ITool, assistantName, input string, err error)
+	OnToolStart(ctx context.Context, e *ToolStartEvent)
+	OnToolEnd(ctx context.Context, e *ToolEndEvent)
+	OnToolError(ctx context.Context, e *ToolErrorEvent)
+}
+
+// ToolStartEvent is reported before each attempt of the tool call.
+type ToolStartEvent struct {
+	Tool ITool
+	// Assistant is the name of the assistant calling the tool.
+	Assistant string
+	Input     string
+	// CallID is the ID of the tool call requested by the LLM.
+	CallID string
+	// Attempt starts from 1, and is incremented when the call is retried
+	// after the transient error.
+	Attempt int
+}
+
+// ToolEndEvent is reported when the tool call succeeded.
+type ToolEndEvent struct {
+	Tool      ITool
+	Assistant string
+	Input     string
+	Output    string
+	CallID    string
+	// Attempt is the last attempt of the call.
+	Attempt int
+	// Latency is the duration of all the attempts of the call.
+	Latency time.Duration
+}
+
+// ToolErrorEvent is reported when the tool call failed.
+type ToolErrorEvent struct {
+	Tool      ITool
+	Assistant string
+	Input     string
+	Err       error
+	CallID    string
+	// Attempt is the last attempt of the call,
+	// or zero if the call is rejected by the arguments validation or the policies.
+	Attempt int
+	// Latency is the duration of all the attempts of the call.
+	Latency time.Duration
 }
 
 type Tool[I any, O any] interface {